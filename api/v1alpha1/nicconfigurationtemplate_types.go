@@ -28,18 +28,139 @@ type NicSelectorSpec struct {
 	PciAddresses []string `json:"pciAddresses,omitempty"`
 	// Serial numbers of the NICs to be selected, e.g. MT2116X09299
 	SerialNumbers []string `json:"serialNumbers,omitempty"`
+	// DeviceLabels contains labels required on the NicDevice object, e.g. roles assigned to the NICs by the users
+	DeviceLabels map[string]string `json:"deviceLabels,omitempty"`
 }
 
 // LinkTypeEnum described the link type (Ethernet / Infiniband)
 // +enum
 type LinkTypeEnum string
 
+// DisruptionEnum describes how the new nv configuration is activated on the device (reboot / fwReset / auto)
+// +enum
+type DisruptionEnum string
+
+// RolloutStrategyEnum describes how a change of the template is rolled out to the matching devices (all / fingerprint)
+// +enum
+type RolloutStrategyEnum string
+
+// PciPerformancePresetEnum is a set of recommended PCI performance settings for a platform (default / amd)
+// +enum
+type PciPerformancePresetEnum string
+
+// GpuDirectPresetEnum is a set of recommended settings for a GPU Direct workload (none / rdma)
+// +enum
+type GpuDirectPresetEnum string
+
+// WeekdayEnum is a day of the week, e.g. Monday
+// +enum
+type WeekdayEnum string
+
+// WorkloadKindEnum is the kind of a workload restarted after the configuration (DaemonSet / Deployment)
+// +enum
+type WorkloadKindEnum string
+
+// RestartStrategyEnum describes how a workload is restarted after the configuration (restartPods / rolloutRestart)
+// +enum
+type RestartStrategyEnum string
+
+// FlowSteeringModeEnum describes how the driver manages the flow steering rules of the NIC (smfs / dmfs)
+// +enum
+type FlowSteeringModeEnum string
+
+// EswitchModeEnum describes the eswitch mode of the PFs (legacy / switchdev)
+// +enum
+type EswitchModeEnum string
+
+// RuntimeConfigPolicyEnum describes when the runtime settings are applied to the device (Immediate / OnWorkloadAttach)
+// +enum
+type RuntimeConfigPolicyEnum string
+
+// ActivationWindowSpec is a recurring time window, in which the staged firmware and nv config of the devices are activated
+type ActivationWindowSpec struct {
+	// Start of the window in the HH:MM format, e.g. 22:00
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	Start string `json:"start"`
+	// End of the window in the HH:MM format, e.g. 06:00, the window ends on the next day if End is not after Start
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	End string `json:"end"`
+	// Days of the week when the window starts, every day if empty
+	// +kubebuilder:validation:items:Enum=Monday;Tuesday;Wednesday;Thursday;Friday;Saturday;Sunday
+	Days []WeekdayEnum `json:"days,omitempty"`
+	// TimeZone of the window as an IANA time zone name, e.g. Europe/Berlin, defaults to UTC
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// WorkloadReference references a workload that depends on the configuration of the devices
+type WorkloadReference struct {
+	// Kind of the workload: DaemonSet or Deployment
+	// +kubebuilder:validation:Enum=DaemonSet;Deployment
+	// +kubebuilder:default:=DaemonSet
+	// +optional
+	Kind WorkloadKindEnum `json:"kind,omitempty"`
+	// Name of the workload in the namespace of the template
+	Name string `json:"name"`
+	// Strategy describes how the workload is restarted
+	// * restartPods - pods of the workload on the reconfigured node are deleted and recreated by the workload's controller,
+	// once all devices of the template on the node applied their configuration
+	// * rolloutRestart - pod template of the workload is annotated with the restart time, same as kubectl rollout restart,
+	// once per rollout, after all devices of the template applied their configuration
+	// +kubebuilder:validation:Enum=restartPods;rolloutRestart
+	// +kubebuilder:default:=restartPods
+	// +optional
+	Strategy RestartStrategyEnum `json:"strategy,omitempty"`
+}
+
+// PostConfigurationHookSpec describes the actions performed after the devices of a node are successfully reconfigured
+type PostConfigurationHookSpec struct {
+	// RestartWorkloads lists the workloads restarted to re-enumerate the resources of the devices, e.g. the SR-IOV device plugin
+	RestartWorkloads []WorkloadReference `json:"restartWorkloads,omitempty"`
+}
+
+// VfMsixSpec configures the MSI-X vectors of the VFs
+type VfMsixSpec struct {
+	// Number of MSI-X vectors of each VF, the firmware default is used if omitted
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	NumVfMsix int `json:"numVfMsix,omitempty"`
+	// Dynamic enables the dynamic MSI-X table of the VFs, so that the vectors of each VF can be changed at runtime
+	// +optional
+	Dynamic bool `json:"dynamic,omitempty"`
+	// Number of MSI-X vectors assigned to each VF at runtime from the PF's pool, requires Dynamic
+	// the VF driver is reloaded to change the number of vectors
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RuntimeVfMsix int `json:"runtimeVfMsix,omitempty"`
+}
+
+// PciLinkSpec specifies the PCIe link settings of the device
+type PciLinkSpec struct {
+	// Highest PCIe generation the device trains the link to, the highest generation supported by the device if omitted
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=6
+	Generation int `json:"generation,omitempty"`
+	// Width of the PCIe link in lanes, the widest link supported by the device if omitted
+	// +kubebuilder:validation:Enum=1;2;4;8;16
+	Width int `json:"width,omitempty"`
+}
+
 // PciPerformanceOptimizedSpec specifies PCI performance optimization settings
 type PciPerformanceOptimizedSpec struct {
 	// Specifies whether to enable PCI performance optimization
 	Enabled bool `json:"enabled"`
+	// Preset of the recommended settings for the platform, the explicit fields take precedence over it
+	// * default - the device chooses the outstanding reads, suitable for most platforms
+	// * amd - outstanding reads and relaxed ordering recommended for RoCE on AMD EPYC platforms
+	// +kubebuilder:validation:Enum=default;amd
+	// +kubebuilder:default:=default
+	// +optional
+	Preset PciPerformancePresetEnum `json:"preset,omitempty"`
 	// Specifies the PCIe Max Accumulative Outstanding read bytes
 	MaxAccOutRead int `json:"maxAccOutRead,omitempty"`
+	// Specifies whether the device forces relaxed ordering of the PCIe writes, PCI_WR_ORDERING nv config parameter.
+	// Applied even if the PCI performance optimization is disabled
+	// +optional
+	RelaxedOrdering *bool `json:"relaxedOrdering,omitempty"`
 	// Specifies the size of a single PCI read request in bytes
 	// +kubebuilder:validation:Enum=128;256;512;1024;2048;4096
 	MaxReadRequest int `json:"maxReadRequest,omitempty"`
@@ -52,6 +173,16 @@ type QosSpec struct {
 	// Priority-based Flow Control configuration, e.g. "0,0,0,1,0,0,0,0"
 	// +kubebuilder:validation:Pattern=`^([01],){7}[01]$`
 	PFC string `json:"pfc"`
+	// ETS bandwidth shares of the traffic classes 0-7 in percent, e.g. "0,0,0,60,40,0,0,0", the shares have to sum up to 100.
+	// The current bandwidth allocation is kept if omitted
+	// +kubebuilder:validation:Pattern=`^([0-9]{1,3},){7}[0-9]{1,3}$`
+	// +optional
+	TcBandwidth string `json:"tcBandwidth,omitempty"`
+	// Clear the port and priority counters after the QoS settings change, previous values are archived in an event
+	ResetCounters bool `json:"resetCounters,omitempty"`
+	// Delete the DCB app entries and the root qdisc of the ports that conflict with the QoS settings, e.g. installed by lldpad.
+	// By default the conflicts are only reported in the QosConflict condition
+	TakeOwnership bool `json:"takeOwnership,omitempty"`
 }
 
 // RoceOptimizedSpec specifies RoCE optimization settings
@@ -60,6 +191,135 @@ type RoceOptimizedSpec struct {
 	Enabled bool `json:"enabled"`
 	// Quality of Service settings
 	Qos *QosSpec `json:"qos,omitempty"`
+	// ECN and DCQCN congestion control settings, e.g. for lossy RoCE deployments without PFC
+	CongestionControl *CongestionControlSpec `json:"congestionControl,omitempty"`
+}
+
+// CongestionControlSpec specifies the RoCE congestion control settings of the Ethernet ports
+type CongestionControlSpec struct {
+	// Priorities 0-7 with ECN enabled, both for marking the congestion and for reacting to it, e.g. "0,0,0,1,0,0,0,0"
+	// +kubebuilder:validation:Pattern=`^([01],){7}[01]$`
+	Ecn string `json:"ecn"`
+	// DCQCN algorithm parameters, the current values are kept for the omitted parameters
+	// +optional
+	Dcqcn *DcqcnSpec `json:"dcqcn,omitempty"`
+}
+
+// DcqcnSpec specifies the parameters of the DCQCN congestion control algorithm
+// +kubebuilder:validation:MinProperties=1
+type DcqcnSpec struct {
+	// Rate of the additive increase in Mb/s
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RpgAiRate *int `json:"rpgAiRate,omitempty"`
+	// Rate of the hyper increase in Mb/s
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RpgHaiRate *int `json:"rpgHaiRate,omitempty"`
+	// Time between the rate increases in microseconds if no CNPs are received
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RpgTimeReset *int `json:"rpgTimeReset,omitempty"`
+	// Transmitted data between the rate increases in bytes if no CNPs are received
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RpgByteReset *int `json:"rpgByteReset,omitempty"`
+	// Number of the rate increase periods before the hyper increase
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RpgThreshold *int `json:"rpgThreshold,omitempty"`
+	// Minimal rate limit of the rate reduction in Mb/s
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RpgMinRate *int `json:"rpgMinRate,omitempty"`
+	// Rate reduction factor of the alpha update, the rate is reduced at most by 1/2^rpgGd on each CNP
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	RpgGd *int `json:"rpgGd,omitempty"`
+	// Minimal time between the rate reductions in microseconds
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	RateReduceMonitorPeriod *int `json:"rateReduceMonitorPeriod,omitempty"`
+	// Initial value of alpha, the congestion estimate of the flow, 1023 means full congestion
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=1023
+	// +optional
+	InitialAlphaValue *int `json:"initialAlphaValue,omitempty"`
+	// Minimal time between the CNPs sent for the same flow in microseconds
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinTimeBetweenCnps *int `json:"minTimeBetweenCnps,omitempty"`
+}
+
+// RepresentorsSpec specifies the runtime settings of the VF representors, keeping them consistent with the uplink
+type RepresentorsSpec struct {
+	// Apply the settings to the VF representors of the PFs in switchdev mode
+	Enabled bool `json:"enabled"`
+	// MTU of the representors, the MTU of the uplink is used if omitted
+	// +kubebuilder:validation:Minimum=68
+	// +kubebuilder:validation:Maximum=9978
+	// +optional
+	Mtu int `json:"mtu,omitempty"`
+	// Apply the QoS trust and PFC settings of the uplink to the representors
+	// +optional
+	Qos bool `json:"qos,omitempty"`
+	// Ethtool features to enable on the representors, e.g. hw-tc-offload
+	// +kubebuilder:validation:items:Pattern=`^[a-z0-9-]+$`
+	// +optional
+	Features []string `json:"features,omitempty"`
+}
+
+// AtsSpec specifies the PCIe Address Translation Services settings of the device
+type AtsSpec struct {
+	// Specifies whether the device uses ATS to cache the address translations of the IOMMU
+	Enabled bool `json:"enabled"`
+}
+
+// PtpSpec specifies the Precision Time Protocol settings of the device
+type PtpSpec struct {
+	// Enable the real time clock of the NIC, the PTP hardware clock keeps the time of day in the hardware instead of
+	// a free-running counter translated by the driver, required for the accurate timestamping of the 5G fronthaul
+	RealTimeClock bool `json:"realTimeClock"`
+}
+
+// SteeringSpec specifies the firmware steering settings of the device, e.g. for the gateways with a high number of connections
+// device defaults are used for the fields that are not set
+type SteeringSpec struct {
+	// Flex parser profile of the firmware, selects the packet headers the steering can match on, e.g. 4 for eCPRI
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	FlexParserProfile *int `json:"flexParserProfile,omitempty"`
+	// Enable the programmable parse graph, e.g. for the flex items of DPDK matching on custom protocol headers
+	// +optional
+	ProgrammableParseGraph *bool `json:"programmableParseGraph,omitempty"`
+	// Log2 of the size of the DC responder connection hash table, e.g. to serve more concurrent connections
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	LogDcrHashTableSize *int `json:"logDcrHashTableSize,omitempty"`
+	// Number of the DC responder contexts reserved in the firmware's LIFO
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	DcrLifoSize *int `json:"dcrLifoSize,omitempty"`
+}
+
+// SpectrumXSpec specifies the RoCE transport settings of the Spectrum-X Ethernet AI fabrics
+// the fields that are not set are left untouched, the settings are only available on the NICs and firmware
+// versions supporting the Spectrum-X feature set
+// the ECMP hashing is configured on the fabric switches and the tunnel header parsing with steering.flexParserProfile,
+// they are not part of the spec
+type SpectrumXSpec struct {
+	// Enable the adaptive routing of the RoCE traffic, the fabric spreads the packets of each flow over all of its paths
+	// instead of pinning the flow to a single path with the ECMP hash
+	// +optional
+	AdaptiveRouting *bool `json:"adaptiveRouting,omitempty"`
+	// Enable the selective repeat of the RDMA transport, the NIC places the packets reordered by the adaptive routing
+	// and only retransmits the lost ones, required if the adaptive routing is enabled
+	// +optional
+	SelectiveRepeat *bool `json:"selectiveRepeat,omitempty"`
+	// Enable the programmable congestion control of the NIC, the congestion control algorithm of the fabric
+	// is loaded by the firmware instead of DCQCN
+	// +optional
+	ProgrammableCongestionControl *bool `json:"programmableCongestionControl,omitempty"`
 }
 
 // GpuDirectOptimizedSpec specifies GPU Direct optimization settings
@@ -68,32 +328,309 @@ type GpuDirectOptimizedSpec struct {
 	Enabled bool `json:"enabled"`
 	// GPU direct environment, e.g. Baremetal
 	Env string `json:"env"`
+	// Preset of the recommended settings for the GPU Direct workload
+	// * none - only the GPU Direct settings, pciPerformanceOptimized has to be enabled separately
+	// * rdma - PCI performance optimizations with relaxed ordering recommended for GPUDirect RDMA,
+	//   pciPerformanceOptimized is not required, its explicit fields take precedence over the preset
+	// +kubebuilder:validation:Enum=none;rdma
+	// +kubebuilder:default:=none
+	// +optional
+	Preset GpuDirectPresetEnum `json:"preset,omitempty"`
+}
+
+// BootOptionsSpec specifies the network boot settings of the NIC's expansion ROM
+type BootOptionsSpec struct {
+	// Enable the expansion ROM of the NIC's ports, the NIC is not offered as a boot device if disabled
+	Enabled bool `json:"enabled"`
+	// Enable the legacy PXE boot, left untouched if not set
+	// +optional
+	PXE *bool `json:"pxe,omitempty"`
+	// Enable the UEFI network boot on x86 hosts, left untouched if not set
+	// +optional
+	UEFI *bool `json:"uefi,omitempty"`
+	// VLAN ID used by the ports for the network boot, the boot VLAN settings are left untouched if 0
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=4094
+	// +optional
+	BootVlan int `json:"bootVlan,omitempty"`
+	// Number of the network boot retries of the ports, 7 - retry forever, device default is used if not set
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=7
+	// +optional
+	BootRetryCount *int `json:"bootRetryCount,omitempty"`
 }
 
 type NvConfigParam struct {
-	// Name of the arbitrary nvconfig parameter
+	// Name of the arbitrary nvconfig parameter, as listed by mlxconfig query, e.g. LOG_MAX_QUEUE
+	// +kubebuilder:validation:Pattern=`^[^=\s]+$`
 	Name string `json:"name"`
 	// Value of the arbitrary nvconfig parameter
 	Value string `json:"value"`
 }
 
+// PortConfigurationSpec overrides the configuration of a single port of a dual-port NIC
+type PortConfigurationSpec struct {
+	// Number of the port, 1 or 2
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=2
+	Port int `json:"port"`
+	// LinkType of the port, overrides the linkType of the template, Ethernet|Infiniband
+	// +kubebuilder:validation:Enum=Ethernet;Infiniband
+	LinkType LinkTypeEnum `json:"linkType,omitempty"`
+	// List of port-indexed nv config parameters without the port suffix, e.g. CNP_DSCP is applied as CNP_DSCP_P2 for port 2
+	// takes precedence over the template's rawNvConfig
+	RawNvConfig []NvConfigParam `json:"rawNvConfig,omitempty"`
+	// Channels of the port's network interface, overrides the channels of the template
+	Channels *ChannelsSpec `json:"channels,omitempty"`
+	// MTU of the port's network interface, overrides the MTU of the template
+	Mtu *MtuSpec `json:"mtu,omitempty"`
+}
+
+// MtuSpec configures the MTU of the ports' network interfaces
+type MtuSpec struct {
+	// MTU of the PF network interface, e.g. 9000 for RoCE
+	// +kubebuilder:validation:Minimum=68
+	// +kubebuilder:validation:Maximum=9978
+	Size int `json:"size"`
+	// Sets the same MTU on the network interfaces of the PF's VFs, VFs without a network interface, e.g. bound to vfio-pci, are skipped
+	// +optional
+	PropagateToVfs bool `json:"propagateToVfs,omitempty"`
+}
+
+// ChannelsSpec configures the channel (queue) count of the ports' network interfaces
+type ChannelsSpec struct {
+	// Number of the combined channels, the number of CPUs of the device's NUMA node clamped to the maximum supported
+	// by the device if omitted
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Combined int `json:"combined,omitempty"`
+}
+
+// RingSizeSpec configures the ring buffer sizes of the ports' network interfaces
+// +kubebuilder:validation:MinProperties=1
+type RingSizeSpec struct {
+	// Number of the RX ring entries, clamped to the maximum supported by the device, the current size is kept if omitted
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Rx int `json:"rx,omitempty"`
+	// Number of the TX ring entries, clamped to the maximum supported by the device, the current size is kept if omitted
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Tx int `json:"tx,omitempty"`
+}
+
+// OffloadsSpec toggles the stateless offloads of the ports' network interfaces, the current state is kept for the omitted offloads
+// +kubebuilder:validation:MinProperties=1
+type OffloadsSpec struct {
+	// Enable the large receive offload (large-receive-offload), can't be enabled together with rxGroHw
+	// +optional
+	LRO *bool `json:"lro,omitempty"`
+	// Enable the generic receive offload (generic-receive-offload), required by rxGroHw
+	// +optional
+	GRO *bool `json:"gro,omitempty"`
+	// Enable the TCP segmentation offload (tcp-segmentation-offload)
+	// +optional
+	TSO *bool `json:"tso,omitempty"`
+	// Enable the hardware generic receive offload (rx-gro-hw)
+	// +optional
+	RxGroHw *bool `json:"rxGroHw,omitempty"`
+}
+
+// CoalescingSpec configures the interrupt coalescing of the ports' network interfaces
+// +kubebuilder:validation:MinProperties=1
+type CoalescingSpec struct {
+	// Enables the adaptive RX coalescing, the driver adjusts the RX coalescing to the traffic pattern
+	// +optional
+	AdaptiveRx *bool `json:"adaptiveRx,omitempty"`
+	// Enables the adaptive TX coalescing, the driver adjusts the TX coalescing to the traffic pattern
+	// +optional
+	AdaptiveTx *bool `json:"adaptiveTx,omitempty"`
+	// Microseconds to delay the RX interrupt after a packet arrives, 0 disables the delay
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	RxUsecs *int `json:"rxUsecs,omitempty"`
+	// Number of packets to receive before the RX interrupt, 0 disables the limit
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	RxFrames *int `json:"rxFrames,omitempty"`
+	// Microseconds to delay the TX interrupt after a packet is sent, 0 disables the delay
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	TxUsecs *int `json:"txUsecs,omitempty"`
+	// Number of packets to send before the TX interrupt, 0 disables the limit
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	TxFrames *int `json:"txFrames,omitempty"`
+}
+
+// SysctlSpec is a kernel parameter of the network namespace or of the ports' network interfaces
+type SysctlSpec struct {
+	// Name of the sysctl in the net subtree, e.g. net.ipv4.tcp_ecn. The {interface} placeholder is replaced with
+	// the network interface of each port, e.g. net.ipv4.conf.{interface}.arp_announce
+	// +kubebuilder:validation:Pattern=`^net(\.[A-Za-z0-9_{}-]+)+$`
+	Name string `json:"name"`
+	// Value of the sysctl, e.g. 1
+	Value string `json:"value"`
+}
+
+// DevlinkResourceSpec is a devlink resource size to be configured on each PF of the device
+type DevlinkResourceSpec struct {
+	// Path of the devlink resource as reported by "devlink resource show", e.g. /kvd/linear
+	// +kubebuilder:validation:Pattern=`^(/[a-zA-Z0-9_]+)+$`
+	Path string `json:"path"`
+	// Size of the devlink resource in the units of the resource
+	Size uint64 `json:"size"`
+}
+
+// FirmwarePSIDVersionSpec is a firmware version required for the devices with the given PSID
+type FirmwarePSIDVersionSpec struct {
+	// PSID of the devices, e.g. MT_0000000359
+	PSID string `json:"psid"`
+	// Version of the firmware required for the devices, e.g. 22.41.1000
+	Version string `json:"version"`
+}
+
+// FirmwareTemplateSpec specifies the firmware to be installed on the NICs
+type FirmwareTemplateSpec struct {
+	// NicFirmwareSourceRef is the name of the NicFirmwareSource in the operator's namespace
+	// the image matching the device's PSID is burned if the device's firmware version differs from it
+	NicFirmwareSourceRef string `json:"nicFirmwareSourceRef,omitempty"`
+	// Version of the firmware required for the devices, nv config is not applied to devices running a different version
+	Version string `json:"version,omitempty"`
+	// PSIDVersions are the firmware versions required for the devices with specific PSIDs, they take precedence over Version
+	PSIDVersions []FirmwarePSIDVersionSpec `json:"psidVersions,omitempty"`
+}
+
 // ConfigurationTemplateSpec is a set of configurations for the NICs
 type ConfigurationTemplateSpec struct {
 	// Number of VFs to be configured
 	// +required
 	NumVfs int `json:"numVfs"`
+	// MSI-X vectors of the VFs, e.g. for DPDK and other workloads with a high number of queues per VF
+	VfMsix *VfMsixSpec `json:"vfMsix,omitempty"`
 	// LinkType to be configured, Ethernet|Infiniband
 	// +kubebuilder:validation:Enum=Ethernet;Infiniband
 	// +required
 	LinkType LinkTypeEnum `json:"linkType"`
 	// PCI performance optimization settings
 	PciPerformanceOptimized *PciPerformanceOptimizedSpec `json:"pciPerformanceOptimized,omitempty"`
+	// PCIe link settings
+	PciLink *PciLinkSpec `json:"pciLink,omitempty"`
 	// RoCE optimization settings
 	RoceOptimized *RoceOptimizedSpec `json:"roceOptimized,omitempty"`
 	// GPU Direct optimization settings
 	GpuDirectOptimized *GpuDirectOptimizedSpec `json:"gpuDirectOptimized,omitempty"`
-	// List of arbitrary nv config parameters
+	// Address Translation Services settings, e.g. for virtualized environments with vIOMMU
+	Ats *AtsSpec `json:"ats,omitempty"`
+	// Precision Time Protocol settings, e.g. for the telco deployments synchronized with ptp4l and phc2sys
+	Ptp *PtpSpec `json:"ptp,omitempty"`
+	// Network boot settings of the expansion ROM, e.g. to disable PXE boot from the NICs
+	BootOptions *BootOptionsSpec `json:"bootOptions,omitempty"`
+	// Firmware steering settings, e.g. the flex parser profile and the connection table sizes of the high connection count gateways
+	Steering *SteeringSpec `json:"steering,omitempty"`
+	// RoCE transport settings of the Spectrum-X Ethernet AI fabrics, e.g. the adaptive routing and the packet reordering
+	SpectrumX *SpectrumXSpec `json:"spectrumX,omitempty"`
+	// List of arbitrary nv config parameters, merged with the parameters of the other fields and taking precedence over them
 	RawNvConfig []NvConfigParam `json:"rawNvConfig,omitempty"`
+	// Per-port overrides of the template for dual-port NICs, e.g. to configure the ports with different link types
+	// +kubebuilder:validation:MaxItems=2
+	Ports []PortConfigurationSpec `json:"ports,omitempty"`
+	// List of devlink resource sizes, applied at runtime and activated with a devlink reload of each PF
+	DevlinkResources []DevlinkResourceSpec `json:"devlinkResources,omitempty"`
+	// Ring buffer sizes of the ports' network interfaces, applied at runtime with ethtool, e.g. to absorb traffic bursts
+	RingSize *RingSizeSpec `json:"ringSize,omitempty"`
+	// Interrupt coalescing settings of the ports' network interfaces, applied at runtime with ethtool, e.g. for latency-sensitive workloads
+	Coalescing *CoalescingSpec `json:"coalescing,omitempty"`
+	// Stateless offloads of the ports' network interfaces, applied at runtime with ethtool and re-applied if the driver resets them
+	Offloads *OffloadsSpec `json:"offloads,omitempty"`
+	// Channel count of the ports' network interfaces, applied at runtime with ethtool
+	Channels *ChannelsSpec `json:"channels,omitempty"`
+	// MTU of the ports' network interfaces, applied at runtime and enforced on drift
+	Mtu *MtuSpec `json:"mtu,omitempty"`
+	// List of sysctls of the host network namespace and of the ports' network interfaces, applied at runtime
+	Sysctls []SysctlSpec `json:"sysctls,omitempty"`
+	// Flow steering mode of the ports, applied at runtime with devlink: smfs (software managed) or dmfs (device managed)
+	// smfs is recommended for the OVS hardware offload in switchdev mode, the mode can only be changed in the legacy eswitch mode
+	// +kubebuilder:validation:Enum=smfs;dmfs
+	// +optional
+	FlowSteeringMode FlowSteeringModeEnum `json:"flowSteeringMode,omitempty"`
+	// Eswitch mode of the PFs, applied at runtime with devlink: legacy or switchdev, e.g. switchdev for the OVS hardware offload
+	// in the switchdev mode, the agent waits for the representors of the existing VFs to be created
+	// +kubebuilder:validation:Enum=legacy;switchdev
+	// +optional
+	EswitchMode EswitchModeEnum `json:"eswitchMode,omitempty"`
+	// Runtime settings of the VF representors of the PFs in switchdev mode, applied as the representors appear
+	Representors *RepresentorsSpec `json:"representors,omitempty"`
+	// RuntimeConfigPolicy specifies when the runtime settings are applied to the device
+	// * Immediate - the runtime settings are applied once the nv config is ready
+	// * OnWorkloadAttach - the QoS and congestion control settings of roceOptimized and the representors settings are deferred
+	//   until a pod requesting the RDMA resources is scheduled on the node, e.g. to keep the utility nodes unconfigured
+	// +kubebuilder:validation:Enum=Immediate;OnWorkloadAttach
+	// +optional
+	RuntimeConfigPolicy RuntimeConfigPolicyEnum `json:"runtimeConfigPolicy,omitempty"`
+	// Firmware to be installed on the NICs, new firmware is activated in the same way as the nv config
+	Firmware *FirmwareTemplateSpec `json:"firmware,omitempty"`
+	// SpeedValues override the template fields for the devices whose ports run at the given speed, resolved on each node
+	// values of ValuesFrom take precedence over them
+	SpeedValues []PortSpeedValues `json:"speedValues,omitempty"`
+	// ValuesFrom overrides the template fields with the values of ConfigMap or Secret keys, resolved on each node
+	ValuesFrom []TemplateValueSource `json:"valuesFrom,omitempty"`
+}
+
+// PortSpeedValues sets the template fields for the devices whose ports run at the given speed
+type PortSpeedValues struct {
+	// Speed of the device's ports, e.g. 100G or 2.5G, the highest speed among the ports with an active link is used,
+	// the last known speed of the ports while their links are down
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?G$`
+	Speed string `json:"speed"`
+	// Values of the template fields
+	// +kubebuilder:validation:MinItems=1
+	Values []TemplateFieldValue `json:"values"`
+}
+
+// TemplateFieldValue holds the value of a template field
+type TemplateFieldValue struct {
+	// Field of the template: numVfs, linkType, roceOptimized.qos.trust, roceOptimized.qos.pfc or rawNvConfig.<parameter name>
+	// +kubebuilder:validation:Pattern=`^(numVfs|linkType|roceOptimized\.qos\.trust|roceOptimized\.qos\.pfc|rawNvConfig\..+)$`
+	Field string `json:"field"`
+	// Value of the field
+	Value string `json:"value"`
+}
+
+// TemplateValueSource references a ConfigMap or Secret key holding the value of a template field
+// exactly one of ConfigMapKeyRef and SecretKeyRef has to be set
+type TemplateValueSource struct {
+	// Field of the template set from the referenced key:
+	// numVfs, linkType, roceOptimized.qos.trust, roceOptimized.qos.pfc or rawNvConfig.<parameter name>
+	// +kubebuilder:validation:Pattern=`^(numVfs|linkType|roceOptimized\.qos\.trust|roceOptimized\.qos\.pfc|rawNvConfig\..+)$`
+	Field string `json:"field"`
+	// ConfigMapKeyRef selects a key of a ConfigMap in the operator's namespace
+	ConfigMapKeyRef *TemplateKeySelector `json:"configMapKeyRef,omitempty"`
+	// SecretKeyRef selects a key of a Secret in the operator's namespace
+	SecretKeyRef *TemplateKeySelector `json:"secretKeyRef,omitempty"`
+}
+
+// TemplateKeySelector selects a key of a ConfigMap or Secret
+type TemplateKeySelector struct {
+	// Name of the ConfigMap or Secret
+	Name string `json:"name"`
+	// Key holding the value, used if NodeLabel is not set or the node doesn't have the label
+	Key string `json:"key,omitempty"`
+	// NodeLabel selects the key by the value of the label on the device's node, e.g. topology.kubernetes.io/zone
+	NodeLabel string `json:"nodeLabel,omitempty"`
+}
+
+// RolloutSpec specifies how a change of the template is rolled out to the matching devices
+type RolloutSpec struct {
+	// Strategy of the rollout
+	// * all - the change is applied to all matching devices at once
+	// * fingerprint - nodes are grouped by the hardware fingerprint of their NICs (model, PSID and firmware version),
+	//   the change is applied to a single canary node of each group first and to the rest of the group once the canary is in sync,
+	//   a failed canary halts the rollout to its group
+	// +kubebuilder:validation:Enum=all;fingerprint
+	// +kubebuilder:default:=all
+	// +optional
+	Strategy RolloutStrategyEnum `json:"strategy,omitempty"`
 }
 
 // NicConfigurationTemplateSpec defines the desired state of NicConfigurationTemplate
@@ -114,6 +651,30 @@ type NicConfigurationTemplateSpec struct {
 	// +optional
 	// +kubebuilder:default:=false
 	ResetToDefault bool `json:"resetToDefault,omitempty"`
+	// Disruption specifies how the new nv configuration is activated on the matching devices
+	// * reboot - the node is rebooted, FW reset is never performed
+	// * fwReset - NIC firmware is reset instead of the node reboot, the node is rebooted if FW reset is not possible
+	// * auto - FW reset is only used to unlock advanced PCI settings, the configuration is activated with a node reboot
+	// +kubebuilder:validation:Enum=reboot;fwReset;auto
+	// +kubebuilder:default:=auto
+	// +optional
+	Disruption DisruptionEnum `json:"disruption,omitempty"`
+	// ActivationWindow defers the disruptive activation of the new firmware and nv config (reboot or FW reset) to the window
+	// new firmware is burned right away, the activation happens immediately if not set
+	// +optional
+	ActivationWindow *ActivationWindowSpec `json:"activationWindow,omitempty"`
+	// Rollout specifies how a change of the template is rolled out to the matching devices, all at once if not set
+	// +optional
+	Rollout *RolloutSpec `json:"rollout,omitempty"`
+	// ConsistencyGroup groups the matching devices of each node whose settings must stay consistent, e.g. both NICs of a bond
+	// the changes of the grouped devices are validated together and written atomically, either all or none of the devices
+	// proceed to the reboot
+	// +kubebuilder:validation:MaxLength=63
+	// +optional
+	ConsistencyGroup string `json:"consistencyGroup,omitempty"`
+	// PostConfigurationHook is performed after the new configuration of the matching devices is applied
+	// +optional
+	PostConfigurationHook *PostConfigurationHookSpec `json:"postConfigurationHook,omitempty"`
 	// Configuration template to be applied to matching devices
 	Template *ConfigurationTemplateSpec `json:"template"`
 }
@@ -122,10 +683,43 @@ type NicConfigurationTemplateSpec struct {
 type NicConfigurationTemplateStatus struct {
 	// NicDevice CRs matching this configuration template
 	NicDevices []string `json:"nicDevices"`
+	// Number of NicDevice CRs matching this configuration template
+	MatchedDevices int `json:"matchedDevices"`
+	// Number of matching devices with the current template configuration applied
+	InSync int `json:"inSync"`
+	// Number of matching devices waiting for a reboot to apply the configuration
+	PendingReboot int `json:"pendingReboot"`
+	// Number of matching devices that failed to apply the configuration
+	Failed int `json:"failed"`
+	// Time of the last change of the rollout summary
+	UpdatedAt *metav1.Time `json:"updatedAt,omitempty"`
+	// Rollout state of each hardware fingerprint of the matching nodes, reported with the fingerprint rollout strategy
+	Fingerprints []FingerprintRolloutStatus `json:"fingerprints,omitempty"`
+}
+
+// FingerprintRolloutStatus is the rollout state of the template on the matching nodes sharing a hardware fingerprint
+type FingerprintRolloutStatus struct {
+	// Fingerprint of the nodes, also set in the configuration.net.nvidia.com/hardware-fingerprint label of the nodes
+	Fingerprint string `json:"fingerprint"`
+	// Hardware of the fingerprint, the model and PSID of each kind of NIC on the nodes
+	Hardware []string `json:"hardware"`
+	// Number of the matching nodes with the fingerprint
+	Nodes int `json:"nodes"`
+	// Number of the nodes with the current template generation applied
+	UpdatedNodes int `json:"updatedNodes"`
+	// Canary node of the fingerprint, the current template generation is applied to it first
+	CanaryNode string `json:"canaryNode"`
+	// Phase of the rollout: Canary, RollingOut, Completed or Halted, if the canary failed to apply the configuration
+	Phase string `json:"phase"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Matched",type=integer,JSONPath=`.status.matchedDevices`
+//+kubebuilder:printcolumn:name="In Sync",type=integer,JSONPath=`.status.inSync`
+//+kubebuilder:printcolumn:name="Pending Reboot",type=integer,JSONPath=`.status.pendingReboot`
+//+kubebuilder:printcolumn:name="Failed",type=integer,JSONPath=`.status.failed`
+//+kubebuilder:printcolumn:name="Updated",type=date,JSONPath=`.status.updatedAt`
 
 // NicConfigurationTemplate is the Schema for the nicconfigurationtemplates API
 type NicConfigurationTemplate struct {
@@ -30,6 +30,19 @@ type NicDeviceConfigurationSpec struct {
 	//   - Applies new NIC NV config
 	//   - Will undo any runtime configuration previously performed for the device/driver
 	ResetToDefault bool `json:"resetToDefault,omitempty"`
+	// Disruption specifies how the new nv configuration is activated on the device: reboot, fwReset or auto
+	// +kubebuilder:validation:Enum=reboot;fwReset;auto
+	// +optional
+	Disruption DisruptionEnum `json:"disruption,omitempty"`
+	// ActivationWindow defers the disruptive activation of the new firmware and nv config to the window
+	// +optional
+	ActivationWindow *ActivationWindowSpec `json:"activationWindow,omitempty"`
+	// ConsistencyGroup is the name of the group of the node's devices whose nv config changes are applied atomically
+	// +optional
+	ConsistencyGroup string `json:"consistencyGroup,omitempty"`
+	// PostConfigurationHook is performed after the new configuration of the device is applied
+	// +optional
+	PostConfigurationHook *PostConfigurationHookSpec `json:"postConfigurationHook,omitempty"`
 	// Configuration template applied from the NicConfigurationTemplate CR
 	Template *ConfigurationTemplateSpec `json:"template,omitempty"`
 }
@@ -38,6 +51,12 @@ type NicDeviceConfigurationSpec struct {
 type NicDeviceSpec struct {
 	// Configuration specifies the configuration requested by NicConfigurationTemplate
 	Configuration *NicDeviceConfigurationSpec `json:"configuration,omitempty"`
+	// NvConfigPCIFunction forces the PCI function of the device used for nv config operations, e.g. 1 for 0000:3b:00.1
+	// needed on boards where function 0 is hidden or owned by the BMC
+	// functions of the device's ports are probed if the forced function isn't accessible
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=7
+	NvConfigPCIFunction *int `json:"nvConfigPCIFunction,omitempty"`
 }
 
 // NicDevicePortSpec describes the ports of the NIC
@@ -48,6 +67,239 @@ type NicDevicePortSpec struct {
 	NetworkInterface string `json:"networkInterface,omitempty"`
 	// RdmaInterface is the name of the rdma interface for this port, e.g. mlx5_1
 	RdmaInterface string `json:"rdmaInterface,omitempty"`
+	// PtpClockIndex is the index of the port's PTP hardware clock, e.g. 0 for /dev/ptp0, not set if the port has no PHC
+	PtpClockIndex *int `json:"ptpClockIndex,omitempty"`
+	// EswitchMode is the active eswitch mode of the port, legacy or switchdev, not set if the port isn't the eswitch manager
+	EswitchMode string `json:"eswitchMode,omitempty"`
+	// NumaNode is the NUMA node the port is attached to, not set if the platform doesn't report it
+	NumaNode *int `json:"numaNode,omitempty"`
+	// LocalCPUs is the list of the CPUs local to the port, e.g. 0-15,32-47
+	LocalCPUs string `json:"localCpus,omitempty"`
+	// PciRootComplex is the PCIe root complex the port is connected to, e.g. pci0000:3a
+	PciRootComplex string `json:"pciRootComplex,omitempty"`
+	// Transceiver is the cable or optical module plugged into the port, not set if the port's cage is empty
+	Transceiver *TransceiverStatus `json:"transceiver,omitempty"`
+	// Link is the state of the port's network interface, refreshed more often than the rest of the device status
+	Link *PortLinkStatus `json:"link,omitempty"`
+	// LinkLayer is the link layer of the port's RDMA device, InfiniBand or Ethernet, not set if the port has no RDMA device
+	LinkLayer string `json:"linkLayer,omitempty"`
+	// NodeGUID is the GUID of the port's InfiniBand node, e.g. 0c42:a103:0016:054c, only set for the InfiniBand ports
+	NodeGUID string `json:"nodeGuid,omitempty"`
+	// PortGUID is the GUID of the InfiniBand port, e.g. 0c42:a103:0016:054c, only set for the InfiniBand ports
+	PortGUID string `json:"portGuid,omitempty"`
+	// RdmaLink is the state of the port's InfiniBand link, refreshed together with Link, only set for the InfiniBand ports
+	RdmaLink *RdmaLinkStatus `json:"rdmaLink,omitempty"`
+}
+
+// RdmaLinkStatus describes the InfiniBand link of the port's RDMA device
+type RdmaLinkStatus struct {
+	// State is the logical state of the port, e.g. DOWN, INIT or ACTIVE, a port stays in INIT until the subnet manager configures it
+	State string `json:"state"`
+	// PhysicalState is the physical state of the link, e.g. Polling, LinkUp or Disabled
+	PhysicalState string `json:"physicalState,omitempty"`
+	// Rate is the signaling rate of the link, e.g. 200 Gb/sec (4X HDR)
+	Rate string `json:"rate,omitempty"`
+}
+
+// PortLinkStatus describes the link of the port's network interface
+type PortLinkStatus struct {
+	// State is the operational state of the interface, e.g. up, down or lowerlayerdown
+	State string `json:"state"`
+	// Speed is the negotiated speed of the link, e.g. 100G, not set if the link is down
+	Speed string `json:"speed,omitempty"`
+	// AutoNegotiation is set if the speed of the link is auto-negotiated, not set if the driver doesn't report it
+	AutoNegotiation *bool `json:"autoNegotiation,omitempty"`
+}
+
+// TransceiverStatus describes the cable or optical module plugged into the port as reported by its EEPROM
+type TransceiverStatus struct {
+	// Type is the form factor of the module, e.g. QSFP28
+	Type string `json:"type,omitempty"`
+	// Technology is the transmitter technology of the module, e.g. Copper cable unequalized or 850 nm VCSEL
+	Technology string `json:"technology,omitempty"`
+	// Vendor of the module, e.g. Mellanox
+	Vendor string `json:"vendor,omitempty"`
+	// PartNumber is the vendor part number of the module, e.g. MCP1600-C001
+	PartNumber string `json:"partNumber,omitempty"`
+	// Length of the cable, e.g. 1m, not set for the separable optical modules
+	Length string `json:"length,omitempty"`
+	// SupportedSpeeds are the Ethernet speeds the module is compliant with, e.g. 100G
+	SupportedSpeeds []string `json:"supportedSpeeds,omitempty"`
+}
+
+// NvConfigParameterStatus describes the state of a single non-volatile configuration parameter rendered from the device spec
+type NvConfigParameterStatus struct {
+	// Name of the nv config parameter, e.g. SRIOV_EN
+	Name string `json:"name"`
+	// Value of the parameter rendered from the device spec
+	DesiredValue string `json:"desiredValue"`
+	// Values of the parameter reported by the firmware for the current boot
+	CurrentValues []string `json:"currentValues,omitempty"`
+	// Values of the parameter reported by the firmware for the next boot
+	NextBootValues []string `json:"nextBootValues,omitempty"`
+	// DeviceDefault is set if the desired value is the default value of the device, e.g. restored for an unset template field
+	// +optional
+	DeviceDefault bool `json:"deviceDefault,omitempty"`
+}
+
+// NvConfigParameterDiff describes a nv config parameter whose current value differs from the next boot value
+type NvConfigParameterDiff struct {
+	// Name of the nv config parameter
+	Name string `json:"name"`
+	// Values of the parameter reported by the firmware for the current boot
+	CurrentValues []string `json:"currentValues,omitempty"`
+	// Values of the parameter reported by the firmware for the next boot
+	NextBootValues []string `json:"nextBootValues,omitempty"`
+}
+
+// NvConfigUnconvergedWrite counts the writes of a nv config parameter whose value hasn't taken effect yet
+type NvConfigUnconvergedWrite struct {
+	// Name of the nv config parameter
+	Name string `json:"name"`
+	// Value written to the parameter
+	Value string `json:"value"`
+	// Number of writes since the parameter's current value last matched the written value
+	Writes int `json:"writes"`
+	// Number of reboots after which the next boot value matched the written value but the current value didn't
+	Reboots int `json:"reboots,omitempty"`
+	// BootID of the host the parameter was last written or found unconverged in
+	BootID string `json:"bootID,omitempty"`
+}
+
+// NvConfigWriteStats counts the nv config writes issued by the operator, each write consumes a config flash cycle
+type NvConfigWriteStats struct {
+	// Total number of nv config parameter writes, the writes rolled back after a failed apply are not counted
+	Writes int64 `json:"writes"`
+	// Total number of nv config resets to default
+	Resets int64 `json:"resets"`
+	// Number of nv config updates that wrote to the flash since WindowStart
+	WindowUpdates int `json:"windowUpdates,omitempty"`
+	// Start of the time window used to detect abnormal nv config churn
+	WindowStart *metav1.Time `json:"windowStart,omitempty"`
+	// Parameters written without their current value matching after reboot, sorted by name
+	Unconverged []NvConfigUnconvergedWrite `json:"unconverged,omitempty"`
+}
+
+// PciLinkStatus describes the PCIe link negotiated by the device
+type PciLinkStatus struct {
+	// Negotiated link speed, e.g. 16.0 GT/s PCIe
+	Speed string `json:"speed"`
+	// Negotiated link width in lanes
+	Width int `json:"width"`
+	// Highest link speed supported by the device
+	MaxSpeed string `json:"maxSpeed"`
+	// Widest link supported by the device in lanes
+	MaxWidth int `json:"maxWidth"`
+	// Degraded is set if the link trained below the speed or width supported by the device
+	Degraded bool `json:"degraded"`
+}
+
+// VirtualFunctionStatus describes an SR-IOV VF of the device's port
+type VirtualFunctionStatus struct {
+	// PCI is the PCI address of the VF, e.g. 0000:3b:00.2
+	PCI string `json:"pci"`
+	// PhysicalFunction is the PCI address of the VF's parent PF
+	PhysicalFunction string `json:"physicalFunction"`
+	// Driver bound to the VF, e.g. mlx5_core or vfio-pci, not set if no driver is bound
+	Driver string `json:"driver,omitempty"`
+}
+
+// DeviceHealthStatus describes the temperature and the devlink health reporters of the device
+type DeviceHealthStatus struct {
+	// Temperature of the ASIC in degrees Celsius, not set if the sensor can't be read
+	Temperature *int `json:"temperature,omitempty"`
+	// TemperatureWarningThreshold is the ASIC temperature in degrees Celsius from which the HealthWarning condition is reported,
+	// not set if the temperature warning is disabled
+	TemperatureWarningThreshold int `json:"temperatureWarningThreshold,omitempty"`
+	// Reporters are the devlink health reporters of the device and of its ports, not set if the driver doesn't report them
+	Reporters []HealthReporterStatus `json:"reporters,omitempty"`
+}
+
+// HealthReporterStatus describes a devlink health reporter of the device
+type HealthReporterStatus struct {
+	// Name of the reporter, e.g. fw_fatal
+	Name string `json:"name"`
+	// PCI is the address of the port whose devlink instance reports the reporter, e.g. 0000:3b:00.1
+	// +optional
+	PCI string `json:"pci,omitempty"`
+	// State of the reporter, healthy or error
+	State string `json:"state"`
+	// ErrorCount is the number of errors reported since the driver was loaded
+	ErrorCount int `json:"errorCount,omitempty"`
+	// RecoverCount is the number of successful recoveries since the driver was loaded
+	RecoverCount int `json:"recoverCount,omitempty"`
+}
+
+// FirmwareSecurityStatus describes the firmware signing enforcement of the device
+type FirmwareSecurityStatus struct {
+	// SecureFirmware is set if the device only accepts signed firmware images
+	SecureFirmware bool `json:"secureFirmware"`
+	// Security attributes of the running firmware as reported by mstflint, e.g. secure-fw, dev
+	Attributes []string `json:"attributes,omitempty"`
+	// Security version of the running firmware, the device rejects images with a lower security version
+	SecurityVersion *int `json:"securityVersion,omitempty"`
+}
+
+// BFBStatus describes the BFB bundle installed by the operator to the BlueField DPU
+type BFBStatus struct {
+	// Bundle is the file name of the installed BFB bundle, e.g. bf-bundle-2.7.0-33_24.04_ubuntu-22.04_prod.bfb
+	Bundle string `json:"bundle"`
+	// InstallTime is the time when the installation of the bundle finished
+	InstallTime metav1.Time `json:"installTime"`
+}
+
+// FirmwareUpdateStatus describes the progress of the firmware or BFB bundle update of the device
+type FirmwareUpdateStatus struct {
+	// Phase of the update: Downloading the binaries, Flashing the device or AwaitingActivation after reboot or FW reset
+	// +kubebuilder:validation:Enum=Downloading;Flashing;AwaitingActivation
+	Phase string `json:"phase"`
+	// Progress of the phase in percent, reported if the tools provide it
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Progress int `json:"progress,omitempty"`
+	// LastUpdateTime is the time when the progress was last reported
+	LastUpdateTime metav1.Time `json:"lastUpdateTime"`
+}
+
+// DeviceOperationStatus describes the phase of the ongoing configuration of the device
+// the phase is persisted so that the operation is resumed from it after a restart of the config daemon or a node reboot
+type DeviceOperationStatus struct {
+	// Phase of the operation:
+	// Rendering the nv config from the spec, Applying the nv config or firmware, AwaitingReboot for the activation,
+	// Verifying the activated nv config and applying the runtime config, Done once the device is configured
+	// +kubebuilder:validation:Enum=Rendering;Applying;AwaitingReboot;Verifying;Done
+	Phase string `json:"phase"`
+	// ObservedGeneration is the generation of the device spec the operation applies
+	ObservedGeneration int64 `json:"observedGeneration"`
+	// StartTime is the time when the operation started
+	StartTime metav1.Time `json:"startTime"`
+	// LastTransitionTime is the time when the operation entered the current phase
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+}
+
+// AppliedSysctlStatus is a sysctl set by the operator for the device's template
+type AppliedSysctlStatus struct {
+	// Name of the sysctl in the dotted notation, with the network interface of the port for the per-interface sysctls
+	Name string `json:"name"`
+	// Value set by the operator
+	Value string `json:"value"`
+	// Value of the sysctl before the operator set it, restored once none of the node's devices requests the sysctl
+	OriginalValue string `json:"originalValue"`
+}
+
+// PartialRuntimeConfigStatus records the ports whose QoS runtime settings were applied before the apply failed on another port
+// the next apply in the same boot resumes from the failed port instead of applying the settings to all ports again
+type PartialRuntimeConfigStatus struct {
+	// ObservedGeneration is the generation of the device spec the settings were applied for
+	ObservedGeneration int64 `json:"observedGeneration"`
+	// BootID of the host the settings were applied in, runtime settings don't survive a reboot
+	BootID string `json:"bootID,omitempty"`
+	// PCI addresses of the ports with the applied settings
+	AppliedPorts []string `json:"appliedPorts"`
+	// PCI address of the port the apply failed on
+	FailedPort string `json:"failedPort"`
+	// Error of the failed port
+	Error string `json:"error"`
 }
 
 // NicDeviceStatus defines the observed state of NicDevice
@@ -60,18 +312,65 @@ type NicDeviceStatus struct {
 	SerialNumber string `json:"serialNumber"`
 	// Part number of the device, e.g. MCX713106AEHEA_QP1
 	PartNumber string `json:"partNumber"`
+	// IdentitySource is the fallback the serial and part numbers come from if the VPD of the device couldn't be read:
+	// Flash if the serial number is derived from the base GUID of the device's flash,
+	// PreviousDiscovery if they were kept from the previous discovery of the device's ports
+	// omitted if they were read from the VPD
+	// +kubebuilder:validation:Enum=Flash;PreviousDiscovery
+	IdentitySource string `json:"identitySource,omitempty"`
 	// Product Serial ID of the device, e.g. MT_0000000221
 	PSID string `json:"psid"`
 	// Firmware version currently installed on the device, e.g. 22.31.1014
 	FirmwareVersion string `json:"firmwareVersion"`
+	// Firmware signing enforcement of the device, nil if not reported by the firmware
+	FirmwareSecurity *FirmwareSecurityStatus `json:"firmwareSecurity,omitempty"`
 	// List of ports for the device
 	Ports []NicDevicePortSpec `json:"ports"`
+	// PCIe link negotiated by the device, nil if not reported by the kernel
+	PciLink *PciLinkStatus `json:"pciLink,omitempty"`
+	// SR-IOV VFs of the device's ports ordered by their PF and VF index, only reported if the VF discovery is enabled
+	VirtualFunctions []VirtualFunctionStatus `json:"virtualFunctions,omitempty"`
+	// ConfigurationMode of the device, Restricted if the device is a VF passed through to a VM,
+	// only the VF runtime settings of a restricted device are applied, its nv config and firmware are managed by the hypervisor host
+	// omitted for the devices configured in full
+	// +kubebuilder:validation:Enum=Restricted
+	ConfigurationMode string `json:"configurationMode,omitempty"`
+	// BlueFieldMode is the operating mode of the BlueField DPU: DPU if the ARM side manages the offloads,
+	// NIC if the ARM side is disabled, RestrictedHost if the ARM side owns the nv config and the firmware of the device
+	// omitted for other devices and if the mode can't be determined
+	// +kubebuilder:validation:Enum=DPU;NIC;RestrictedHost
+	BlueFieldMode string `json:"blueFieldMode,omitempty"`
+	// Temperature and health reporters of the device, nil if they can't be read, e.g. for the restricted devices
+	Health *DeviceHealthStatus `json:"health,omitempty"`
 	// List of conditions observed for the device
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// List of nv config parameters rendered from the device spec with their firmware values
+	NvConfigParameters []NvConfigParameterStatus `json:"nvConfigParameters,omitempty"`
+	// List of nv config parameters whose current and next boot values differ, these changes take effect after reboot
+	PendingRebootParameters []NvConfigParameterDiff `json:"pendingRebootParameters,omitempty"`
+	// Cumulative nv config writes and resets issued by the operator to the device
+	NvConfigWriteStats *NvConfigWriteStats `json:"nvConfigWriteStats,omitempty"`
+	// PCI address of the function used for nv config operations, e.g. 0000:3b:00.1
+	NvConfigPCI string `json:"nvConfigPCI,omitempty"`
+	// BFB bundle installed by the operator to the ARM side of the BlueField DPU, nil for other devices
+	BFB *BFBStatus `json:"bfb,omitempty"`
+	// Progress of the ongoing firmware or BFB bundle update, nil if no update is in progress
+	FirmwareUpdate *FirmwareUpdateStatus `json:"firmwareUpdate,omitempty"`
+	// Phase of the ongoing or last configuration of the device, nil if the device was never configured
+	Operation *DeviceOperationStatus `json:"operation,omitempty"`
+	// Ports with the applied QoS runtime settings if the last apply failed on another port, nil otherwise
+	PartialRuntimeConfig *PartialRuntimeConfigStatus `json:"partialRuntimeConfig,omitempty"`
+	// Sysctls set for the device's template with their values before the operator set them
+	AppliedSysctls []AppliedSysctlStatus `json:"appliedSysctls,omitempty"`
+	// Runtime settings of the template postponed until an RDMA workload is scheduled on the node, e.g. mtu,
+	// nil if none are postponed, see the OnWorkloadAttach runtimeConfigPolicy
+	DeferredRuntimeSettings []string `json:"deferredRuntimeSettings,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.operation.phase"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // NicDevice is the Schema for the nicdevices API
 type NicDevice struct {
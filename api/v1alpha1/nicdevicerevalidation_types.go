@@ -0,0 +1,68 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NicDeviceRevalidationSpec selects the NicDevices to revalidate, all devices in the namespace are selected if the spec is empty
+type NicDeviceRevalidationSpec struct {
+	// NodeSelector selects the nodes of the devices by their labels, devices on all nodes are selected if empty
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// PSIDs of the devices to revalidate, e.g. MT_0000000221, devices with any PSID are selected if empty
+	PSIDs []string `json:"psids,omitempty"`
+}
+
+// NicDeviceRevalidationStatus reports the devices requested to revalidate
+type NicDeviceRevalidationStatus struct {
+	// ObservedGeneration of the spec the selected devices were requested to revalidate with
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// DeviceCount is the number of the NicDevice CRs requested to revalidate
+	DeviceCount int `json:"deviceCount,omitempty"`
+	// Devices are the names of the NicDevice CRs requested to revalidate sorted by name, truncated to the first 100 devices
+	// +kubebuilder:validation:MaxItems=100
+	Devices []string `json:"devices,omitempty"`
+	// RequestTime is the time the devices were requested to revalidate
+	RequestTime *metav1.Time `json:"requestTime,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// NicDeviceRevalidation is the Schema for the nicdevicerevalidations API
+// it forces the config daemons to revalidate the selected devices once per generation of its spec,
+// e.g. after a change of the switch-side fabric configuration
+type NicDeviceRevalidation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NicDeviceRevalidationSpec   `json:"spec,omitempty"`
+	Status NicDeviceRevalidationStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// NicDeviceRevalidationList contains a list of NicDeviceRevalidation
+type NicDeviceRevalidationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NicDeviceRevalidation `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NicDeviceRevalidation{}, &NicDeviceRevalidationList{})
+}
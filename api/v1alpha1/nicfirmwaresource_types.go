@@ -0,0 +1,89 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NicFirmwareSourceSpec represents a list of url sources for FW
+// +kubebuilder:validation:XValidation:rule="has(self.binUrlSources) || has(self.bfbUrlSource)",message="binUrlSources or bfbUrlSource is required"
+type NicFirmwareSourceSpec struct {
+	// BinUrlSources represents a list of url sources for FW binaries
+	// each url points to a raw .bin firmware image or to a .zip archive with them
+	// oci://<registry>/<repository>:<tag> or @<digest> urls reference OCI artifacts, e.g. pushed with oras,
+	// the first layer of the artifact titled with a .bin or .zip file name is used
+	// +kubebuilder:validation:MinItems=1
+	// +optional
+	BinUrlSources []string `json:"binUrlSources,omitempty"`
+	// BFBUrlSource is the url of the .bfb bundle installed to the ARM side of the BlueField DPUs via rshim
+	// the bundle is ignored for other devices
+	// +kubebuilder:validation:Pattern=`\.bfb$`
+	// +optional
+	BFBUrlSource string `json:"bfbUrlSource,omitempty"`
+	// ImagePullSecrets are the names of the kubernetes.io/dockerconfigjson secrets in the operator's namespace
+	// used to pull the OCI artifacts
+	// +optional
+	ImagePullSecrets []string `json:"imagePullSecrets,omitempty"`
+	// CABundle in the PEM format verifying the TLS certificates of the source's https servers and OCI registries
+	// in addition to the system CAs, e.g. for a registry with a certificate issued by an internal CA
+	// +optional
+	CABundle string `json:"caBundle,omitempty"`
+	// PlainHTTPRegistries are the hosts of the OCI registries, e.g. registry.example.com:5000, pulled over plain http instead of https
+	// e.g. a registry inside the cluster without TLS
+	// +optional
+	PlainHTTPRegistries []string `json:"plainHTTPRegistries,omitempty"`
+	// Verification of the binaries before they are burned, binaries that fail the verification are never burned
+	// every binary has to be covered by a checksum or a signature
+	Verification *FirmwareVerificationSpec `json:"verification"`
+}
+
+// FirmwareVerificationSpec declares the checksums and signatures of the firmware binaries
+// each binary has to match its checksum, its signature or both if both are declared
+// +kubebuilder:validation:XValidation:rule="has(self.sha256) || has(self.publicKey)",message="sha256 or publicKey is required"
+type FirmwareVerificationSpec struct {
+	// SHA256 checksums of the binaries in the hex format, keyed by the binary url
+	// +optional
+	SHA256 map[string]string `json:"sha256,omitempty"`
+	// PublicKey in the PEM format verifying the detached signatures of the binaries, e.g. from cosign sign-blob
+	// ECDSA and RSA keys are supported, the signature of each binary is downloaded from its url with the .sig suffix
+	// signatures of OCI artifacts are pulled from the layer titled with the binary's file name and the .sig suffix
+	// +optional
+	PublicKey string `json:"publicKey,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// NicFirmwareSource is the Schema for the nicfirmwaresources API
+type NicFirmwareSource struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec NicFirmwareSourceSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// NicFirmwareSourceList contains a list of NicFirmwareSource
+type NicFirmwareSourceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NicFirmwareSource `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NicFirmwareSource{}, &NicFirmwareSourceList{})
+}
@@ -0,0 +1,60 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NicNodeReportDevice describes a single device observed on the node
+type NicNodeReportDevice struct {
+	// Observed status of the device, conditions and nv config parameters are not reported
+	Status NicDeviceStatus `json:"status"`
+	// Firmware version recommended for the device with the node's OFED version, empty if unknown
+	RecommendedFirmwareVersion string `json:"recommendedFirmwareVersion,omitempty"`
+}
+
+// NicNodeReportSpec contains the devices observed on the node
+type NicNodeReportSpec struct {
+	// Node where the devices are located
+	Node string `json:"node"`
+	// List of devices observed on the node, sorted by serial number
+	Devices []NicNodeReportDevice `json:"devices,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// NicNodeReport is the Schema for the nicnodereports API
+// it is published by the config daemon with batch discovery and fanned out into the NicDevice CRs by the operator
+type NicNodeReport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec NicNodeReportSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// NicNodeReportList contains a list of NicNodeReport
+type NicNodeReportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NicNodeReport `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NicNodeReport{}, &NicNodeReportList{})
+}
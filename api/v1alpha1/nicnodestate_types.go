@@ -0,0 +1,98 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DisruptiveOperationEnum is a disruptive operation activating the new configuration of the devices, fwReset or reboot
+// +enum
+type DisruptiveOperationEnum string
+
+// DisruptiveOperationStateEnum is the state of a queued disruptive operation, InProgress or Pending
+// +enum
+type DisruptiveOperationStateEnum string
+
+// DisruptiveOperation describes a single disruptive operation in the node's queue
+type DisruptiveOperation struct {
+	// Operation activating the new configuration: a FW reset of a single device or a reboot of the whole node
+	// +kubebuilder:validation:Enum=fwReset;reboot
+	Operation DisruptiveOperationEnum `json:"operation"`
+	// Names of the NicDevice CRs activated by the operation
+	Devices []string `json:"devices"`
+	// State of the operation: InProgress or Pending
+	// +kubebuilder:validation:Enum=InProgress;Pending
+	State DisruptiveOperationStateEnum `json:"state"`
+	// EstimatedStartTime of the operation, omitted until the node maintenance is allowed
+	EstimatedStartTime *metav1.Time `json:"estimatedStartTime,omitempty"`
+}
+
+// NodeEnvironment describes the virtualization environment of the node detected by the config daemon
+type NodeEnvironment struct {
+	// Type of the environment: BareMetal or VirtualMachine
+	// +kubebuilder:validation:Enum=BareMetal;VirtualMachine
+	Type string `json:"type"`
+	// Hypervisor is the DMI system vendor of the VM, e.g. QEMU, omitted on bare metal or if not reported
+	Hypervisor string `json:"hypervisor,omitempty"`
+	// Mode of the configuration: Full, or Restricted if NVIDIA VFs or virtio-net devices are passed through to the VM,
+	// their nv config and firmware can only be changed from the hypervisor host
+	// +kubebuilder:validation:Enum=Full;Restricted
+	Mode string `json:"mode"`
+	// PCI addresses of the NVIDIA VFs passed through to the VM, they are reported as restricted NicDevices
+	VirtualFunctions []string `json:"virtualFunctions,omitempty"`
+	// PCI addresses of the virtio-net devices backed by NVIDIA NICs, e.g. emulated by a BlueField DPU,
+	// they have no NicDevices as they aren't configurable from the VM
+	VirtioDevices []string `json:"virtioDevices,omitempty"`
+}
+
+// NicNodeStateStatus contains the aggregated configuration state of the node's devices
+type NicNodeStateStatus struct {
+	// Node where the devices are located
+	Node string `json:"node"`
+	// DisruptionQueue lists the disruptive operations of the node in the order they are performed, one at a time
+	// the queue is empty if no disruptive operations are required
+	DisruptionQueue []DisruptiveOperation `json:"disruptionQueue,omitempty"`
+	// EstimatedCompletionTime of the last operation in the queue, omitted until the node maintenance is allowed
+	EstimatedCompletionTime *metav1.Time `json:"estimatedCompletionTime,omitempty"`
+	// Environment of the node detected by the config daemon, nil until the devices are discovered
+	Environment *NodeEnvironment `json:"environment,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+
+// NicNodeState is the Schema for the nicnodestates API
+// it is published by the config daemon of the node it's named after
+type NicNodeState struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status NicNodeStateStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// NicNodeStateList contains a list of NicNodeState
+type NicNodeStateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NicNodeState `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NicNodeState{}, &NicNodeStateList{})
+}
@@ -24,12 +24,178 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActivationWindowSpec) DeepCopyInto(out *ActivationWindowSpec) {
+	*out = *in
+	if in.Days != nil {
+		in, out := &in.Days, &out.Days
+		*out = make([]WeekdayEnum, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActivationWindowSpec.
+func (in *ActivationWindowSpec) DeepCopy() *ActivationWindowSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ActivationWindowSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AppliedSysctlStatus) DeepCopyInto(out *AppliedSysctlStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppliedSysctlStatus.
+func (in *AppliedSysctlStatus) DeepCopy() *AppliedSysctlStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AppliedSysctlStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AtsSpec) DeepCopyInto(out *AtsSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AtsSpec.
+func (in *AtsSpec) DeepCopy() *AtsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AtsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BFBStatus) DeepCopyInto(out *BFBStatus) {
+	*out = *in
+	in.InstallTime.DeepCopyInto(&out.InstallTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BFBStatus.
+func (in *BFBStatus) DeepCopy() *BFBStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BFBStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BootOptionsSpec) DeepCopyInto(out *BootOptionsSpec) {
+	*out = *in
+	if in.PXE != nil {
+		in, out := &in.PXE, &out.PXE
+		*out = new(bool)
+		**out = **in
+	}
+	if in.UEFI != nil {
+		in, out := &in.UEFI, &out.UEFI
+		*out = new(bool)
+		**out = **in
+	}
+	if in.BootRetryCount != nil {
+		in, out := &in.BootRetryCount, &out.BootRetryCount
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BootOptionsSpec.
+func (in *BootOptionsSpec) DeepCopy() *BootOptionsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BootOptionsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChannelsSpec) DeepCopyInto(out *ChannelsSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChannelsSpec.
+func (in *ChannelsSpec) DeepCopy() *ChannelsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ChannelsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CoalescingSpec) DeepCopyInto(out *CoalescingSpec) {
+	*out = *in
+	if in.AdaptiveRx != nil {
+		in, out := &in.AdaptiveRx, &out.AdaptiveRx
+		*out = new(bool)
+		**out = **in
+	}
+	if in.AdaptiveTx != nil {
+		in, out := &in.AdaptiveTx, &out.AdaptiveTx
+		*out = new(bool)
+		**out = **in
+	}
+	if in.RxUsecs != nil {
+		in, out := &in.RxUsecs, &out.RxUsecs
+		*out = new(int)
+		**out = **in
+	}
+	if in.RxFrames != nil {
+		in, out := &in.RxFrames, &out.RxFrames
+		*out = new(int)
+		**out = **in
+	}
+	if in.TxUsecs != nil {
+		in, out := &in.TxUsecs, &out.TxUsecs
+		*out = new(int)
+		**out = **in
+	}
+	if in.TxFrames != nil {
+		in, out := &in.TxFrames, &out.TxFrames
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CoalescingSpec.
+func (in *CoalescingSpec) DeepCopy() *CoalescingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CoalescingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ConfigurationTemplateSpec) DeepCopyInto(out *ConfigurationTemplateSpec) {
 	*out = *in
+	if in.VfMsix != nil {
+		in, out := &in.VfMsix, &out.VfMsix
+		*out = new(VfMsixSpec)
+		**out = **in
+	}
 	if in.PciPerformanceOptimized != nil {
 		in, out := &in.PciPerformanceOptimized, &out.PciPerformanceOptimized
 		*out = new(PciPerformanceOptimizedSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PciLink != nil {
+		in, out := &in.PciLink, &out.PciLink
+		*out = new(PciLinkSpec)
 		**out = **in
 	}
 	if in.RoceOptimized != nil {
@@ -42,376 +208,1892 @@ func (in *ConfigurationTemplateSpec) DeepCopyInto(out *ConfigurationTemplateSpec
 		*out = new(GpuDirectOptimizedSpec)
 		**out = **in
 	}
+	if in.Ats != nil {
+		in, out := &in.Ats, &out.Ats
+		*out = new(AtsSpec)
+		**out = **in
+	}
+	if in.Ptp != nil {
+		in, out := &in.Ptp, &out.Ptp
+		*out = new(PtpSpec)
+		**out = **in
+	}
+	if in.BootOptions != nil {
+		in, out := &in.BootOptions, &out.BootOptions
+		*out = new(BootOptionsSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Steering != nil {
+		in, out := &in.Steering, &out.Steering
+		*out = new(SteeringSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SpectrumX != nil {
+		in, out := &in.SpectrumX, &out.SpectrumX
+		*out = new(SpectrumXSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RawNvConfig != nil {
+		in, out := &in.RawNvConfig, &out.RawNvConfig
+		*out = make([]NvConfigParam, len(*in))
+		copy(*out, *in)
+	}
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make([]PortConfigurationSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DevlinkResources != nil {
+		in, out := &in.DevlinkResources, &out.DevlinkResources
+		*out = make([]DevlinkResourceSpec, len(*in))
+		copy(*out, *in)
+	}
+	if in.RingSize != nil {
+		in, out := &in.RingSize, &out.RingSize
+		*out = new(RingSizeSpec)
+		**out = **in
+	}
+	if in.Coalescing != nil {
+		in, out := &in.Coalescing, &out.Coalescing
+		*out = new(CoalescingSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Offloads != nil {
+		in, out := &in.Offloads, &out.Offloads
+		*out = new(OffloadsSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Channels != nil {
+		in, out := &in.Channels, &out.Channels
+		*out = new(ChannelsSpec)
+		**out = **in
+	}
+	if in.Mtu != nil {
+		in, out := &in.Mtu, &out.Mtu
+		*out = new(MtuSpec)
+		**out = **in
+	}
+	if in.Sysctls != nil {
+		in, out := &in.Sysctls, &out.Sysctls
+		*out = make([]SysctlSpec, len(*in))
+		copy(*out, *in)
+	}
+	if in.Representors != nil {
+		in, out := &in.Representors, &out.Representors
+		*out = new(RepresentorsSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Firmware != nil {
+		in, out := &in.Firmware, &out.Firmware
+		*out = new(FirmwareTemplateSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SpeedValues != nil {
+		in, out := &in.SpeedValues, &out.SpeedValues
+		*out = make([]PortSpeedValues, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ValuesFrom != nil {
+		in, out := &in.ValuesFrom, &out.ValuesFrom
+		*out = make([]TemplateValueSource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigurationTemplateSpec.
+func (in *ConfigurationTemplateSpec) DeepCopy() *ConfigurationTemplateSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigurationTemplateSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CongestionControlSpec) DeepCopyInto(out *CongestionControlSpec) {
+	*out = *in
+	if in.Dcqcn != nil {
+		in, out := &in.Dcqcn, &out.Dcqcn
+		*out = new(DcqcnSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CongestionControlSpec.
+func (in *CongestionControlSpec) DeepCopy() *CongestionControlSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CongestionControlSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DcqcnSpec) DeepCopyInto(out *DcqcnSpec) {
+	*out = *in
+	if in.RpgAiRate != nil {
+		in, out := &in.RpgAiRate, &out.RpgAiRate
+		*out = new(int)
+		**out = **in
+	}
+	if in.RpgHaiRate != nil {
+		in, out := &in.RpgHaiRate, &out.RpgHaiRate
+		*out = new(int)
+		**out = **in
+	}
+	if in.RpgTimeReset != nil {
+		in, out := &in.RpgTimeReset, &out.RpgTimeReset
+		*out = new(int)
+		**out = **in
+	}
+	if in.RpgByteReset != nil {
+		in, out := &in.RpgByteReset, &out.RpgByteReset
+		*out = new(int)
+		**out = **in
+	}
+	if in.RpgThreshold != nil {
+		in, out := &in.RpgThreshold, &out.RpgThreshold
+		*out = new(int)
+		**out = **in
+	}
+	if in.RpgMinRate != nil {
+		in, out := &in.RpgMinRate, &out.RpgMinRate
+		*out = new(int)
+		**out = **in
+	}
+	if in.RpgGd != nil {
+		in, out := &in.RpgGd, &out.RpgGd
+		*out = new(int)
+		**out = **in
+	}
+	if in.RateReduceMonitorPeriod != nil {
+		in, out := &in.RateReduceMonitorPeriod, &out.RateReduceMonitorPeriod
+		*out = new(int)
+		**out = **in
+	}
+	if in.InitialAlphaValue != nil {
+		in, out := &in.InitialAlphaValue, &out.InitialAlphaValue
+		*out = new(int)
+		**out = **in
+	}
+	if in.MinTimeBetweenCnps != nil {
+		in, out := &in.MinTimeBetweenCnps, &out.MinTimeBetweenCnps
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DcqcnSpec.
+func (in *DcqcnSpec) DeepCopy() *DcqcnSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DcqcnSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeviceHealthStatus) DeepCopyInto(out *DeviceHealthStatus) {
+	*out = *in
+	if in.Temperature != nil {
+		in, out := &in.Temperature, &out.Temperature
+		*out = new(int)
+		**out = **in
+	}
+	if in.Reporters != nil {
+		in, out := &in.Reporters, &out.Reporters
+		*out = make([]HealthReporterStatus, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeviceHealthStatus.
+func (in *DeviceHealthStatus) DeepCopy() *DeviceHealthStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DeviceHealthStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeviceOperationStatus) DeepCopyInto(out *DeviceOperationStatus) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeviceOperationStatus.
+func (in *DeviceOperationStatus) DeepCopy() *DeviceOperationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DeviceOperationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DevlinkResourceSpec) DeepCopyInto(out *DevlinkResourceSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DevlinkResourceSpec.
+func (in *DevlinkResourceSpec) DeepCopy() *DevlinkResourceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DevlinkResourceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DisruptiveOperation) DeepCopyInto(out *DisruptiveOperation) {
+	*out = *in
+	if in.Devices != nil {
+		in, out := &in.Devices, &out.Devices
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.EstimatedStartTime != nil {
+		in, out := &in.EstimatedStartTime, &out.EstimatedStartTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DisruptiveOperation.
+func (in *DisruptiveOperation) DeepCopy() *DisruptiveOperation {
+	if in == nil {
+		return nil
+	}
+	out := new(DisruptiveOperation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FingerprintRolloutStatus) DeepCopyInto(out *FingerprintRolloutStatus) {
+	*out = *in
+	if in.Hardware != nil {
+		in, out := &in.Hardware, &out.Hardware
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FingerprintRolloutStatus.
+func (in *FingerprintRolloutStatus) DeepCopy() *FingerprintRolloutStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(FingerprintRolloutStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FirmwarePSIDVersionSpec) DeepCopyInto(out *FirmwarePSIDVersionSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FirmwarePSIDVersionSpec.
+func (in *FirmwarePSIDVersionSpec) DeepCopy() *FirmwarePSIDVersionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FirmwarePSIDVersionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FirmwareSecurityStatus) DeepCopyInto(out *FirmwareSecurityStatus) {
+	*out = *in
+	if in.Attributes != nil {
+		in, out := &in.Attributes, &out.Attributes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SecurityVersion != nil {
+		in, out := &in.SecurityVersion, &out.SecurityVersion
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FirmwareSecurityStatus.
+func (in *FirmwareSecurityStatus) DeepCopy() *FirmwareSecurityStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(FirmwareSecurityStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FirmwareTemplateSpec) DeepCopyInto(out *FirmwareTemplateSpec) {
+	*out = *in
+	if in.PSIDVersions != nil {
+		in, out := &in.PSIDVersions, &out.PSIDVersions
+		*out = make([]FirmwarePSIDVersionSpec, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FirmwareTemplateSpec.
+func (in *FirmwareTemplateSpec) DeepCopy() *FirmwareTemplateSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FirmwareTemplateSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FirmwareUpdateStatus) DeepCopyInto(out *FirmwareUpdateStatus) {
+	*out = *in
+	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FirmwareUpdateStatus.
+func (in *FirmwareUpdateStatus) DeepCopy() *FirmwareUpdateStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(FirmwareUpdateStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FirmwareVerificationSpec) DeepCopyInto(out *FirmwareVerificationSpec) {
+	*out = *in
+	if in.SHA256 != nil {
+		in, out := &in.SHA256, &out.SHA256
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FirmwareVerificationSpec.
+func (in *FirmwareVerificationSpec) DeepCopy() *FirmwareVerificationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FirmwareVerificationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GpuDirectOptimizedSpec) DeepCopyInto(out *GpuDirectOptimizedSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GpuDirectOptimizedSpec.
+func (in *GpuDirectOptimizedSpec) DeepCopy() *GpuDirectOptimizedSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GpuDirectOptimizedSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HealthReporterStatus) DeepCopyInto(out *HealthReporterStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HealthReporterStatus.
+func (in *HealthReporterStatus) DeepCopy() *HealthReporterStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HealthReporterStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MtuSpec) DeepCopyInto(out *MtuSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MtuSpec.
+func (in *MtuSpec) DeepCopy() *MtuSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MtuSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NicConfigurationTemplate) DeepCopyInto(out *NicConfigurationTemplate) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicConfigurationTemplate.
+func (in *NicConfigurationTemplate) DeepCopy() *NicConfigurationTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(NicConfigurationTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NicConfigurationTemplate) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NicConfigurationTemplateList) DeepCopyInto(out *NicConfigurationTemplateList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]NicConfigurationTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicConfigurationTemplateList.
+func (in *NicConfigurationTemplateList) DeepCopy() *NicConfigurationTemplateList {
+	if in == nil {
+		return nil
+	}
+	out := new(NicConfigurationTemplateList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NicConfigurationTemplateList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NicConfigurationTemplateSpec) DeepCopyInto(out *NicConfigurationTemplateSpec) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.NicSelector != nil {
+		in, out := &in.NicSelector, &out.NicSelector
+		*out = new(NicSelectorSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ActivationWindow != nil {
+		in, out := &in.ActivationWindow, &out.ActivationWindow
+		*out = new(ActivationWindowSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		*out = new(RolloutSpec)
+		**out = **in
+	}
+	if in.PostConfigurationHook != nil {
+		in, out := &in.PostConfigurationHook, &out.PostConfigurationHook
+		*out = new(PostConfigurationHookSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Template != nil {
+		in, out := &in.Template, &out.Template
+		*out = new(ConfigurationTemplateSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicConfigurationTemplateSpec.
+func (in *NicConfigurationTemplateSpec) DeepCopy() *NicConfigurationTemplateSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NicConfigurationTemplateSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NicConfigurationTemplateStatus) DeepCopyInto(out *NicConfigurationTemplateStatus) {
+	*out = *in
+	if in.NicDevices != nil {
+		in, out := &in.NicDevices, &out.NicDevices
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.UpdatedAt != nil {
+		in, out := &in.UpdatedAt, &out.UpdatedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Fingerprints != nil {
+		in, out := &in.Fingerprints, &out.Fingerprints
+		*out = make([]FingerprintRolloutStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicConfigurationTemplateStatus.
+func (in *NicConfigurationTemplateStatus) DeepCopy() *NicConfigurationTemplateStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NicConfigurationTemplateStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NicDevice) DeepCopyInto(out *NicDevice) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicDevice.
+func (in *NicDevice) DeepCopy() *NicDevice {
+	if in == nil {
+		return nil
+	}
+	out := new(NicDevice)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NicDevice) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NicDeviceConfigurationSpec) DeepCopyInto(out *NicDeviceConfigurationSpec) {
+	*out = *in
+	if in.ActivationWindow != nil {
+		in, out := &in.ActivationWindow, &out.ActivationWindow
+		*out = new(ActivationWindowSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PostConfigurationHook != nil {
+		in, out := &in.PostConfigurationHook, &out.PostConfigurationHook
+		*out = new(PostConfigurationHookSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Template != nil {
+		in, out := &in.Template, &out.Template
+		*out = new(ConfigurationTemplateSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicDeviceConfigurationSpec.
+func (in *NicDeviceConfigurationSpec) DeepCopy() *NicDeviceConfigurationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NicDeviceConfigurationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NicDeviceList) DeepCopyInto(out *NicDeviceList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]NicDevice, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicDeviceList.
+func (in *NicDeviceList) DeepCopy() *NicDeviceList {
+	if in == nil {
+		return nil
+	}
+	out := new(NicDeviceList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NicDeviceList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NicDevicePortSpec) DeepCopyInto(out *NicDevicePortSpec) {
+	*out = *in
+	if in.PtpClockIndex != nil {
+		in, out := &in.PtpClockIndex, &out.PtpClockIndex
+		*out = new(int)
+		**out = **in
+	}
+	if in.NumaNode != nil {
+		in, out := &in.NumaNode, &out.NumaNode
+		*out = new(int)
+		**out = **in
+	}
+	if in.Transceiver != nil {
+		in, out := &in.Transceiver, &out.Transceiver
+		*out = new(TransceiverStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Link != nil {
+		in, out := &in.Link, &out.Link
+		*out = new(PortLinkStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RdmaLink != nil {
+		in, out := &in.RdmaLink, &out.RdmaLink
+		*out = new(RdmaLinkStatus)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicDevicePortSpec.
+func (in *NicDevicePortSpec) DeepCopy() *NicDevicePortSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NicDevicePortSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NicDeviceRevalidation) DeepCopyInto(out *NicDeviceRevalidation) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicDeviceRevalidation.
+func (in *NicDeviceRevalidation) DeepCopy() *NicDeviceRevalidation {
+	if in == nil {
+		return nil
+	}
+	out := new(NicDeviceRevalidation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NicDeviceRevalidation) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NicDeviceRevalidationList) DeepCopyInto(out *NicDeviceRevalidationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]NicDeviceRevalidation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicDeviceRevalidationList.
+func (in *NicDeviceRevalidationList) DeepCopy() *NicDeviceRevalidationList {
+	if in == nil {
+		return nil
+	}
+	out := new(NicDeviceRevalidationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NicDeviceRevalidationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NicDeviceRevalidationSpec) DeepCopyInto(out *NicDeviceRevalidationSpec) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PSIDs != nil {
+		in, out := &in.PSIDs, &out.PSIDs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicDeviceRevalidationSpec.
+func (in *NicDeviceRevalidationSpec) DeepCopy() *NicDeviceRevalidationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NicDeviceRevalidationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NicDeviceRevalidationStatus) DeepCopyInto(out *NicDeviceRevalidationStatus) {
+	*out = *in
+	if in.Devices != nil {
+		in, out := &in.Devices, &out.Devices
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RequestTime != nil {
+		in, out := &in.RequestTime, &out.RequestTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicDeviceRevalidationStatus.
+func (in *NicDeviceRevalidationStatus) DeepCopy() *NicDeviceRevalidationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NicDeviceRevalidationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NicDeviceSpec) DeepCopyInto(out *NicDeviceSpec) {
+	*out = *in
+	if in.Configuration != nil {
+		in, out := &in.Configuration, &out.Configuration
+		*out = new(NicDeviceConfigurationSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NvConfigPCIFunction != nil {
+		in, out := &in.NvConfigPCIFunction, &out.NvConfigPCIFunction
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicDeviceSpec.
+func (in *NicDeviceSpec) DeepCopy() *NicDeviceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NicDeviceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NicDeviceStatus) DeepCopyInto(out *NicDeviceStatus) {
+	*out = *in
+	if in.FirmwareSecurity != nil {
+		in, out := &in.FirmwareSecurity, &out.FirmwareSecurity
+		*out = new(FirmwareSecurityStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make([]NicDevicePortSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PciLink != nil {
+		in, out := &in.PciLink, &out.PciLink
+		*out = new(PciLinkStatus)
+		**out = **in
+	}
+	if in.VirtualFunctions != nil {
+		in, out := &in.VirtualFunctions, &out.VirtualFunctions
+		*out = make([]VirtualFunctionStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Health != nil {
+		in, out := &in.Health, &out.Health
+		*out = new(DeviceHealthStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NvConfigParameters != nil {
+		in, out := &in.NvConfigParameters, &out.NvConfigParameters
+		*out = make([]NvConfigParameterStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PendingRebootParameters != nil {
+		in, out := &in.PendingRebootParameters, &out.PendingRebootParameters
+		*out = make([]NvConfigParameterDiff, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NvConfigWriteStats != nil {
+		in, out := &in.NvConfigWriteStats, &out.NvConfigWriteStats
+		*out = new(NvConfigWriteStats)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BFB != nil {
+		in, out := &in.BFB, &out.BFB
+		*out = new(BFBStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.FirmwareUpdate != nil {
+		in, out := &in.FirmwareUpdate, &out.FirmwareUpdate
+		*out = new(FirmwareUpdateStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Operation != nil {
+		in, out := &in.Operation, &out.Operation
+		*out = new(DeviceOperationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PartialRuntimeConfig != nil {
+		in, out := &in.PartialRuntimeConfig, &out.PartialRuntimeConfig
+		*out = new(PartialRuntimeConfigStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AppliedSysctls != nil {
+		in, out := &in.AppliedSysctls, &out.AppliedSysctls
+		*out = make([]AppliedSysctlStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.DeferredRuntimeSettings != nil {
+		in, out := &in.DeferredRuntimeSettings, &out.DeferredRuntimeSettings
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicDeviceStatus.
+func (in *NicDeviceStatus) DeepCopy() *NicDeviceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NicDeviceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NicFirmwareSource) DeepCopyInto(out *NicFirmwareSource) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicFirmwareSource.
+func (in *NicFirmwareSource) DeepCopy() *NicFirmwareSource {
+	if in == nil {
+		return nil
+	}
+	out := new(NicFirmwareSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NicFirmwareSource) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NicFirmwareSourceList) DeepCopyInto(out *NicFirmwareSourceList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]NicFirmwareSource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicFirmwareSourceList.
+func (in *NicFirmwareSourceList) DeepCopy() *NicFirmwareSourceList {
+	if in == nil {
+		return nil
+	}
+	out := new(NicFirmwareSourceList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NicFirmwareSourceList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NicFirmwareSourceSpec) DeepCopyInto(out *NicFirmwareSourceSpec) {
+	*out = *in
+	if in.BinUrlSources != nil {
+		in, out := &in.BinUrlSources, &out.BinUrlSources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ImagePullSecrets != nil {
+		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PlainHTTPRegistries != nil {
+		in, out := &in.PlainHTTPRegistries, &out.PlainHTTPRegistries
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Verification != nil {
+		in, out := &in.Verification, &out.Verification
+		*out = new(FirmwareVerificationSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicFirmwareSourceSpec.
+func (in *NicFirmwareSourceSpec) DeepCopy() *NicFirmwareSourceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NicFirmwareSourceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NicNodeReport) DeepCopyInto(out *NicNodeReport) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicNodeReport.
+func (in *NicNodeReport) DeepCopy() *NicNodeReport {
+	if in == nil {
+		return nil
+	}
+	out := new(NicNodeReport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NicNodeReport) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NicNodeReportDevice) DeepCopyInto(out *NicNodeReportDevice) {
+	*out = *in
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicNodeReportDevice.
+func (in *NicNodeReportDevice) DeepCopy() *NicNodeReportDevice {
+	if in == nil {
+		return nil
+	}
+	out := new(NicNodeReportDevice)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NicNodeReportList) DeepCopyInto(out *NicNodeReportList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]NicNodeReport, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicNodeReportList.
+func (in *NicNodeReportList) DeepCopy() *NicNodeReportList {
+	if in == nil {
+		return nil
+	}
+	out := new(NicNodeReportList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NicNodeReportList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NicNodeReportSpec) DeepCopyInto(out *NicNodeReportSpec) {
+	*out = *in
+	if in.Devices != nil {
+		in, out := &in.Devices, &out.Devices
+		*out = make([]NicNodeReportDevice, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicNodeReportSpec.
+func (in *NicNodeReportSpec) DeepCopy() *NicNodeReportSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NicNodeReportSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NicNodeState) DeepCopyInto(out *NicNodeState) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicNodeState.
+func (in *NicNodeState) DeepCopy() *NicNodeState {
+	if in == nil {
+		return nil
+	}
+	out := new(NicNodeState)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NicNodeState) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NicNodeStateList) DeepCopyInto(out *NicNodeStateList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]NicNodeState, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicNodeStateList.
+func (in *NicNodeStateList) DeepCopy() *NicNodeStateList {
+	if in == nil {
+		return nil
+	}
+	out := new(NicNodeStateList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NicNodeStateList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NicNodeStateStatus) DeepCopyInto(out *NicNodeStateStatus) {
+	*out = *in
+	if in.DisruptionQueue != nil {
+		in, out := &in.DisruptionQueue, &out.DisruptionQueue
+		*out = make([]DisruptiveOperation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.EstimatedCompletionTime != nil {
+		in, out := &in.EstimatedCompletionTime, &out.EstimatedCompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Environment != nil {
+		in, out := &in.Environment, &out.Environment
+		*out = new(NodeEnvironment)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicNodeStateStatus.
+func (in *NicNodeStateStatus) DeepCopy() *NicNodeStateStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NicNodeStateStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NicSelectorSpec) DeepCopyInto(out *NicSelectorSpec) {
+	*out = *in
+	if in.PciAddresses != nil {
+		in, out := &in.PciAddresses, &out.PciAddresses
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SerialNumbers != nil {
+		in, out := &in.SerialNumbers, &out.SerialNumbers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DeviceLabels != nil {
+		in, out := &in.DeviceLabels, &out.DeviceLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicSelectorSpec.
+func (in *NicSelectorSpec) DeepCopy() *NicSelectorSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NicSelectorSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeEnvironment) DeepCopyInto(out *NodeEnvironment) {
+	*out = *in
+	if in.VirtualFunctions != nil {
+		in, out := &in.VirtualFunctions, &out.VirtualFunctions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.VirtioDevices != nil {
+		in, out := &in.VirtioDevices, &out.VirtioDevices
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeEnvironment.
+func (in *NodeEnvironment) DeepCopy() *NodeEnvironment {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeEnvironment)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NvConfigParam) DeepCopyInto(out *NvConfigParam) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NvConfigParam.
+func (in *NvConfigParam) DeepCopy() *NvConfigParam {
+	if in == nil {
+		return nil
+	}
+	out := new(NvConfigParam)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NvConfigParameterDiff) DeepCopyInto(out *NvConfigParameterDiff) {
+	*out = *in
+	if in.CurrentValues != nil {
+		in, out := &in.CurrentValues, &out.CurrentValues
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NextBootValues != nil {
+		in, out := &in.NextBootValues, &out.NextBootValues
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NvConfigParameterDiff.
+func (in *NvConfigParameterDiff) DeepCopy() *NvConfigParameterDiff {
+	if in == nil {
+		return nil
+	}
+	out := new(NvConfigParameterDiff)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NvConfigParameterStatus) DeepCopyInto(out *NvConfigParameterStatus) {
+	*out = *in
+	if in.CurrentValues != nil {
+		in, out := &in.CurrentValues, &out.CurrentValues
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NextBootValues != nil {
+		in, out := &in.NextBootValues, &out.NextBootValues
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NvConfigParameterStatus.
+func (in *NvConfigParameterStatus) DeepCopy() *NvConfigParameterStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NvConfigParameterStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NvConfigUnconvergedWrite) DeepCopyInto(out *NvConfigUnconvergedWrite) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NvConfigUnconvergedWrite.
+func (in *NvConfigUnconvergedWrite) DeepCopy() *NvConfigUnconvergedWrite {
+	if in == nil {
+		return nil
+	}
+	out := new(NvConfigUnconvergedWrite)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NvConfigWriteStats) DeepCopyInto(out *NvConfigWriteStats) {
+	*out = *in
+	if in.WindowStart != nil {
+		in, out := &in.WindowStart, &out.WindowStart
+		*out = (*in).DeepCopy()
+	}
+	if in.Unconverged != nil {
+		in, out := &in.Unconverged, &out.Unconverged
+		*out = make([]NvConfigUnconvergedWrite, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NvConfigWriteStats.
+func (in *NvConfigWriteStats) DeepCopy() *NvConfigWriteStats {
+	if in == nil {
+		return nil
+	}
+	out := new(NvConfigWriteStats)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OffloadsSpec) DeepCopyInto(out *OffloadsSpec) {
+	*out = *in
+	if in.LRO != nil {
+		in, out := &in.LRO, &out.LRO
+		*out = new(bool)
+		**out = **in
+	}
+	if in.GRO != nil {
+		in, out := &in.GRO, &out.GRO
+		*out = new(bool)
+		**out = **in
+	}
+	if in.TSO != nil {
+		in, out := &in.TSO, &out.TSO
+		*out = new(bool)
+		**out = **in
+	}
+	if in.RxGroHw != nil {
+		in, out := &in.RxGroHw, &out.RxGroHw
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OffloadsSpec.
+func (in *OffloadsSpec) DeepCopy() *OffloadsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OffloadsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PartialRuntimeConfigStatus) DeepCopyInto(out *PartialRuntimeConfigStatus) {
+	*out = *in
+	if in.AppliedPorts != nil {
+		in, out := &in.AppliedPorts, &out.AppliedPorts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PartialRuntimeConfigStatus.
+func (in *PartialRuntimeConfigStatus) DeepCopy() *PartialRuntimeConfigStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PartialRuntimeConfigStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PciLinkSpec) DeepCopyInto(out *PciLinkSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PciLinkSpec.
+func (in *PciLinkSpec) DeepCopy() *PciLinkSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PciLinkSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PciLinkStatus) DeepCopyInto(out *PciLinkStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PciLinkStatus.
+func (in *PciLinkStatus) DeepCopy() *PciLinkStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PciLinkStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PciPerformanceOptimizedSpec) DeepCopyInto(out *PciPerformanceOptimizedSpec) {
+	*out = *in
+	if in.RelaxedOrdering != nil {
+		in, out := &in.RelaxedOrdering, &out.RelaxedOrdering
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PciPerformanceOptimizedSpec.
+func (in *PciPerformanceOptimizedSpec) DeepCopy() *PciPerformanceOptimizedSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PciPerformanceOptimizedSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PortConfigurationSpec) DeepCopyInto(out *PortConfigurationSpec) {
+	*out = *in
 	if in.RawNvConfig != nil {
 		in, out := &in.RawNvConfig, &out.RawNvConfig
 		*out = make([]NvConfigParam, len(*in))
 		copy(*out, *in)
 	}
+	if in.Channels != nil {
+		in, out := &in.Channels, &out.Channels
+		*out = new(ChannelsSpec)
+		**out = **in
+	}
+	if in.Mtu != nil {
+		in, out := &in.Mtu, &out.Mtu
+		*out = new(MtuSpec)
+		**out = **in
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigurationTemplateSpec.
-func (in *ConfigurationTemplateSpec) DeepCopy() *ConfigurationTemplateSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PortConfigurationSpec.
+func (in *PortConfigurationSpec) DeepCopy() *PortConfigurationSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(ConfigurationTemplateSpec)
+	out := new(PortConfigurationSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *GpuDirectOptimizedSpec) DeepCopyInto(out *GpuDirectOptimizedSpec) {
+func (in *PortLinkStatus) DeepCopyInto(out *PortLinkStatus) {
 	*out = *in
+	if in.AutoNegotiation != nil {
+		in, out := &in.AutoNegotiation, &out.AutoNegotiation
+		*out = new(bool)
+		**out = **in
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GpuDirectOptimizedSpec.
-func (in *GpuDirectOptimizedSpec) DeepCopy() *GpuDirectOptimizedSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PortLinkStatus.
+func (in *PortLinkStatus) DeepCopy() *PortLinkStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(GpuDirectOptimizedSpec)
+	out := new(PortLinkStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *NicConfigurationTemplate) DeepCopyInto(out *NicConfigurationTemplate) {
+func (in *PortSpeedValues) DeepCopyInto(out *PortSpeedValues) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		*out = make([]TemplateFieldValue, len(*in))
+		copy(*out, *in)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicConfigurationTemplate.
-func (in *NicConfigurationTemplate) DeepCopy() *NicConfigurationTemplate {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PortSpeedValues.
+func (in *PortSpeedValues) DeepCopy() *PortSpeedValues {
 	if in == nil {
 		return nil
 	}
-	out := new(NicConfigurationTemplate)
+	out := new(PortSpeedValues)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *NicConfigurationTemplate) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostConfigurationHookSpec) DeepCopyInto(out *PostConfigurationHookSpec) {
+	*out = *in
+	if in.RestartWorkloads != nil {
+		in, out := &in.RestartWorkloads, &out.RestartWorkloads
+		*out = make([]WorkloadReference, len(*in))
+		copy(*out, *in)
 	}
-	return nil
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostConfigurationHookSpec.
+func (in *PostConfigurationHookSpec) DeepCopy() *PostConfigurationHookSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PostConfigurationHookSpec)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *NicConfigurationTemplateList) DeepCopyInto(out *NicConfigurationTemplateList) {
+func (in *PtpSpec) DeepCopyInto(out *PtpSpec) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]NicConfigurationTemplate, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicConfigurationTemplateList.
-func (in *NicConfigurationTemplateList) DeepCopy() *NicConfigurationTemplateList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PtpSpec.
+func (in *PtpSpec) DeepCopy() *PtpSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(NicConfigurationTemplateList)
+	out := new(PtpSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *NicConfigurationTemplateList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *QosSpec) DeepCopyInto(out *QosSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QosSpec.
+func (in *QosSpec) DeepCopy() *QosSpec {
+	if in == nil {
+		return nil
 	}
-	return nil
+	out := new(QosSpec)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *NicConfigurationTemplateSpec) DeepCopyInto(out *NicConfigurationTemplateSpec) {
+func (in *RdmaLinkStatus) DeepCopyInto(out *RdmaLinkStatus) {
 	*out = *in
-	if in.NodeSelector != nil {
-		in, out := &in.NodeSelector, &out.NodeSelector
-		*out = make(map[string]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
-	}
-	if in.NicSelector != nil {
-		in, out := &in.NicSelector, &out.NicSelector
-		*out = new(NicSelectorSpec)
-		(*in).DeepCopyInto(*out)
-	}
-	if in.Template != nil {
-		in, out := &in.Template, &out.Template
-		*out = new(ConfigurationTemplateSpec)
-		(*in).DeepCopyInto(*out)
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicConfigurationTemplateSpec.
-func (in *NicConfigurationTemplateSpec) DeepCopy() *NicConfigurationTemplateSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RdmaLinkStatus.
+func (in *RdmaLinkStatus) DeepCopy() *RdmaLinkStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(NicConfigurationTemplateSpec)
+	out := new(RdmaLinkStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *NicConfigurationTemplateStatus) DeepCopyInto(out *NicConfigurationTemplateStatus) {
+func (in *RepresentorsSpec) DeepCopyInto(out *RepresentorsSpec) {
 	*out = *in
-	if in.NicDevices != nil {
-		in, out := &in.NicDevices, &out.NicDevices
+	if in.Features != nil {
+		in, out := &in.Features, &out.Features
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicConfigurationTemplateStatus.
-func (in *NicConfigurationTemplateStatus) DeepCopy() *NicConfigurationTemplateStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepresentorsSpec.
+func (in *RepresentorsSpec) DeepCopy() *RepresentorsSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(NicConfigurationTemplateStatus)
+	out := new(RepresentorsSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *NicDevice) DeepCopyInto(out *NicDevice) {
+func (in *RingSizeSpec) DeepCopyInto(out *RingSizeSpec) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicDevice.
-func (in *NicDevice) DeepCopy() *NicDevice {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RingSizeSpec.
+func (in *RingSizeSpec) DeepCopy() *RingSizeSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(NicDevice)
+	out := new(RingSizeSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *NicDevice) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RoceOptimizedSpec) DeepCopyInto(out *RoceOptimizedSpec) {
+	*out = *in
+	if in.Qos != nil {
+		in, out := &in.Qos, &out.Qos
+		*out = new(QosSpec)
+		**out = **in
 	}
-	return nil
+	if in.CongestionControl != nil {
+		in, out := &in.CongestionControl, &out.CongestionControl
+		*out = new(CongestionControlSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RoceOptimizedSpec.
+func (in *RoceOptimizedSpec) DeepCopy() *RoceOptimizedSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RoceOptimizedSpec)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *NicDeviceConfigurationSpec) DeepCopyInto(out *NicDeviceConfigurationSpec) {
+func (in *RolloutSpec) DeepCopyInto(out *RolloutSpec) {
 	*out = *in
-	if in.Template != nil {
-		in, out := &in.Template, &out.Template
-		*out = new(ConfigurationTemplateSpec)
-		(*in).DeepCopyInto(*out)
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicDeviceConfigurationSpec.
-func (in *NicDeviceConfigurationSpec) DeepCopy() *NicDeviceConfigurationSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutSpec.
+func (in *RolloutSpec) DeepCopy() *RolloutSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(NicDeviceConfigurationSpec)
+	out := new(RolloutSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *NicDeviceList) DeepCopyInto(out *NicDeviceList) {
+func (in *SpectrumXSpec) DeepCopyInto(out *SpectrumXSpec) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]NicDevice, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	if in.AdaptiveRouting != nil {
+		in, out := &in.AdaptiveRouting, &out.AdaptiveRouting
+		*out = new(bool)
+		**out = **in
+	}
+	if in.SelectiveRepeat != nil {
+		in, out := &in.SelectiveRepeat, &out.SelectiveRepeat
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ProgrammableCongestionControl != nil {
+		in, out := &in.ProgrammableCongestionControl, &out.ProgrammableCongestionControl
+		*out = new(bool)
+		**out = **in
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicDeviceList.
-func (in *NicDeviceList) DeepCopy() *NicDeviceList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SpectrumXSpec.
+func (in *SpectrumXSpec) DeepCopy() *SpectrumXSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(NicDeviceList)
+	out := new(SpectrumXSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *NicDeviceList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SteeringSpec) DeepCopyInto(out *SteeringSpec) {
+	*out = *in
+	if in.FlexParserProfile != nil {
+		in, out := &in.FlexParserProfile, &out.FlexParserProfile
+		*out = new(int)
+		**out = **in
 	}
-	return nil
+	if in.ProgrammableParseGraph != nil {
+		in, out := &in.ProgrammableParseGraph, &out.ProgrammableParseGraph
+		*out = new(bool)
+		**out = **in
+	}
+	if in.LogDcrHashTableSize != nil {
+		in, out := &in.LogDcrHashTableSize, &out.LogDcrHashTableSize
+		*out = new(int)
+		**out = **in
+	}
+	if in.DcrLifoSize != nil {
+		in, out := &in.DcrLifoSize, &out.DcrLifoSize
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SteeringSpec.
+func (in *SteeringSpec) DeepCopy() *SteeringSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SteeringSpec)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *NicDevicePortSpec) DeepCopyInto(out *NicDevicePortSpec) {
+func (in *SysctlSpec) DeepCopyInto(out *SysctlSpec) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicDevicePortSpec.
-func (in *NicDevicePortSpec) DeepCopy() *NicDevicePortSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SysctlSpec.
+func (in *SysctlSpec) DeepCopy() *SysctlSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(NicDevicePortSpec)
+	out := new(SysctlSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *NicDeviceSpec) DeepCopyInto(out *NicDeviceSpec) {
+func (in *TemplateFieldValue) DeepCopyInto(out *TemplateFieldValue) {
 	*out = *in
-	if in.Configuration != nil {
-		in, out := &in.Configuration, &out.Configuration
-		*out = new(NicDeviceConfigurationSpec)
-		(*in).DeepCopyInto(*out)
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicDeviceSpec.
-func (in *NicDeviceSpec) DeepCopy() *NicDeviceSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TemplateFieldValue.
+func (in *TemplateFieldValue) DeepCopy() *TemplateFieldValue {
 	if in == nil {
 		return nil
 	}
-	out := new(NicDeviceSpec)
+	out := new(TemplateFieldValue)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *NicDeviceStatus) DeepCopyInto(out *NicDeviceStatus) {
+func (in *TemplateKeySelector) DeepCopyInto(out *TemplateKeySelector) {
 	*out = *in
-	if in.Ports != nil {
-		in, out := &in.Ports, &out.Ports
-		*out = make([]NicDevicePortSpec, len(*in))
-		copy(*out, *in)
-	}
-	if in.Conditions != nil {
-		in, out := &in.Conditions, &out.Conditions
-		*out = make([]v1.Condition, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicDeviceStatus.
-func (in *NicDeviceStatus) DeepCopy() *NicDeviceStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TemplateKeySelector.
+func (in *TemplateKeySelector) DeepCopy() *TemplateKeySelector {
 	if in == nil {
 		return nil
 	}
-	out := new(NicDeviceStatus)
+	out := new(TemplateKeySelector)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *NicSelectorSpec) DeepCopyInto(out *NicSelectorSpec) {
+func (in *TemplateValueSource) DeepCopyInto(out *TemplateValueSource) {
 	*out = *in
-	if in.PciAddresses != nil {
-		in, out := &in.PciAddresses, &out.PciAddresses
-		*out = make([]string, len(*in))
-		copy(*out, *in)
+	if in.ConfigMapKeyRef != nil {
+		in, out := &in.ConfigMapKeyRef, &out.ConfigMapKeyRef
+		*out = new(TemplateKeySelector)
+		**out = **in
 	}
-	if in.SerialNumbers != nil {
-		in, out := &in.SerialNumbers, &out.SerialNumbers
-		*out = make([]string, len(*in))
-		copy(*out, *in)
+	if in.SecretKeyRef != nil {
+		in, out := &in.SecretKeyRef, &out.SecretKeyRef
+		*out = new(TemplateKeySelector)
+		**out = **in
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicSelectorSpec.
-func (in *NicSelectorSpec) DeepCopy() *NicSelectorSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TemplateValueSource.
+func (in *TemplateValueSource) DeepCopy() *TemplateValueSource {
 	if in == nil {
 		return nil
 	}
-	out := new(NicSelectorSpec)
+	out := new(TemplateValueSource)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *NvConfigParam) DeepCopyInto(out *NvConfigParam) {
+func (in *TransceiverStatus) DeepCopyInto(out *TransceiverStatus) {
 	*out = *in
+	if in.SupportedSpeeds != nil {
+		in, out := &in.SupportedSpeeds, &out.SupportedSpeeds
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NvConfigParam.
-func (in *NvConfigParam) DeepCopy() *NvConfigParam {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TransceiverStatus.
+func (in *TransceiverStatus) DeepCopy() *TransceiverStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(NvConfigParam)
+	out := new(TransceiverStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PciPerformanceOptimizedSpec) DeepCopyInto(out *PciPerformanceOptimizedSpec) {
+func (in *VfMsixSpec) DeepCopyInto(out *VfMsixSpec) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PciPerformanceOptimizedSpec.
-func (in *PciPerformanceOptimizedSpec) DeepCopy() *PciPerformanceOptimizedSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VfMsixSpec.
+func (in *VfMsixSpec) DeepCopy() *VfMsixSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(PciPerformanceOptimizedSpec)
+	out := new(VfMsixSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *QosSpec) DeepCopyInto(out *QosSpec) {
+func (in *VirtualFunctionStatus) DeepCopyInto(out *VirtualFunctionStatus) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QosSpec.
-func (in *QosSpec) DeepCopy() *QosSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VirtualFunctionStatus.
+func (in *VirtualFunctionStatus) DeepCopy() *VirtualFunctionStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(QosSpec)
+	out := new(VirtualFunctionStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RoceOptimizedSpec) DeepCopyInto(out *RoceOptimizedSpec) {
+func (in *WorkloadReference) DeepCopyInto(out *WorkloadReference) {
 	*out = *in
-	if in.Qos != nil {
-		in, out := &in.Qos, &out.Qos
-		*out = new(QosSpec)
-		**out = **in
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RoceOptimizedSpec.
-func (in *RoceOptimizedSpec) DeepCopy() *RoceOptimizedSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkloadReference.
+func (in *WorkloadReference) DeepCopy() *WorkloadReference {
 	if in == nil {
 		return nil
 	}
-	out := new(RoceOptimizedSpec)
+	out := new(WorkloadReference)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -27,16 +27,22 @@ import (
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	configurationnetv1alpha1 "github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
 	"github.com/Mellanox/nic-configuration-operator/internal/controller"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
 	"github.com/Mellanox/nic-configuration-operator/pkg/ncolog"
 	"github.com/Mellanox/nic-configuration-operator/pkg/version"
 	//+kubebuilder:scaffold:imports
@@ -90,6 +96,13 @@ func main() {
 		}
 	}
 
+	// The operator only reads the ConfigMaps of its own namespace
+	namespace := os.Getenv("NAMESPACE")
+	if namespace == "" {
+		setupLog.Error(nil, "NAMESPACE env var required but not set")
+		os.Exit(1)
+	}
+
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
 	// prevent from being vulnerable to the HTTP/2 Stream Cancellation and
@@ -117,6 +130,13 @@ func main() {
 			SecureServing: secureMetrics,
 			TLSOpts:       tlsOpts,
 		},
+		// Only the security advisories ConfigMap of the operator's namespace is watched
+		Cache: cache.Options{ByObject: map[client.Object]cache.ByObject{
+			&v1.ConfigMap{}: {
+				Namespaces: map[string]cache.Config{namespace: {}},
+				Field:      fields.OneTermEqualSelector("metadata.name", consts.SecurityAdvisoriesConfigmap),
+			},
+		}},
 		WebhookServer:          webhookServer,
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
@@ -139,14 +159,51 @@ func main() {
 	}
 
 	if err = (&controller.NicConfigurationTemplateReconciler{
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		APIReader: mgr.GetAPIReader(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "NicConfigurationTemplate")
+		os.Exit(1)
+	}
+	if err = (&controller.NicNodeReportReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "NicConfigurationTemplate")
+		setupLog.Error(err, "unable to create controller", "controller", "NicNodeReport")
+		os.Exit(1)
+	}
+	if err = (&controller.NicDeviceRevalidationReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "NicDeviceRevalidation")
+		os.Exit(1)
+	}
+	if err = (&controller.SecurityAdvisoryReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "SecurityAdvisory")
+		os.Exit(1)
+	}
+	if err = metrics.Registry.Register(controller.NewDeviceStateCollector(mgr.GetClient())); err != nil {
+		setupLog.Error(err, "unable to register metrics collector", "collector", "DeviceState")
 		os.Exit(1)
 	}
 	//+kubebuilder:scaffold:builder
 
+	ctx := ctrl.SetupSignalHandler()
+
+	// NicDevice CRs are listed per node when the node reports are fanned out
+	err = mgr.GetCache().IndexField(ctx, &configurationnetv1alpha1.NicDevice{}, "status.node", func(o client.Object) []string {
+		return []string{o.(*configurationnetv1alpha1.NicDevice).Status.Node}
+	})
+	if err != nil {
+		setupLog.Error(err, "failed to index field for cache")
+		os.Exit(1)
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
@@ -157,7 +214,7 @@ func main() {
 	}
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
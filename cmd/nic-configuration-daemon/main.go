@@ -2,22 +2,33 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	maintenanceoperator "github.com/Mellanox/maintenance-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
 	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
 	"github.com/Mellanox/nic-configuration-operator/internal/controller"
+	"github.com/Mellanox/nic-configuration-operator/pkg/changelog"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
 	"github.com/Mellanox/nic-configuration-operator/pkg/helper"
 	"github.com/Mellanox/nic-configuration-operator/pkg/host"
+	"github.com/Mellanox/nic-configuration-operator/pkg/lifecycle"
 	"github.com/Mellanox/nic-configuration-operator/pkg/maintenance"
 	"github.com/Mellanox/nic-configuration-operator/pkg/ncolog"
 )
@@ -35,27 +46,34 @@ func main() {
 	utilruntime.Must(maintenanceoperator.AddToScheme(scheme))
 	utilruntime.Must(v1alpha1.AddToScheme(scheme))
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme: scheme,
-		// Setting bind address to 0 disables the health probe / metrics server
-		HealthProbeBindAddress: "0",
-		Metrics:                metricsserver.Options{BindAddress: "0"},
-	})
-	if err != nil {
-		log.Log.Error(err, "unable to create manager")
-		os.Exit(1)
-	}
-
 	// Get the pod name and namespace from the environment variables
 	nodeName := os.Getenv("NODE_NAME")
 	if nodeName == "" {
-		log.Log.Error(err, "NODE_NAME env var required but not set")
+		log.Log.Error(nil, "NODE_NAME env var required but not set")
 		os.Exit(1)
 	}
 
 	namespace := os.Getenv("NAMESPACE")
 	if namespace == "" {
-		log.Log.Error(err, "NAMESPACE env var required but not set")
+		log.Log.Error(nil, "NAMESPACE env var required but not set")
+		os.Exit(1)
+	}
+
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+		Scheme: scheme,
+		// Setting bind address to 0 disables the health probe / metrics server
+		HealthProbeBindAddress: "0",
+		Metrics:                metricsserver.Options{BindAddress: "0"},
+		// Only the daemon's own node is cached, so that its readiness and cordon changes don't list every node of the cluster,
+		// only the metadata of the ConfigMaps and Secrets of the operator's namespace is cached for the template values
+		Cache: cache.Options{ByObject: map[client.Object]cache.ByObject{
+			&corev1.Node{}:      {Field: fields.OneTermEqualSelector("metadata.name", nodeName)},
+			&corev1.ConfigMap{}: {Namespaces: map[string]cache.Config{namespace: {}}},
+			&corev1.Secret{}:    {Namespaces: map[string]cache.Config{namespace: {}}},
+		}},
+	})
+	if err != nil {
+		log.Log.Error(err, "unable to create manager")
 		os.Exit(1)
 	}
 
@@ -70,8 +88,33 @@ func main() {
 
 	eventRecorder := mgr.GetEventRecorderFor("NicDeviceReconciler")
 
+	changelogSink, err := newChangelogSink(nodeName, namespace)
+	if err != nil {
+		log.Log.Error(err, "unable to create changelog sink")
+		os.Exit(1)
+	}
+
+	lifecycleSink, err := lifecycle.NewSink(lifecycle.Config{
+		Sink:     os.Getenv("LIFECYCLE_EVENTS_SINK"),
+		Endpoint: os.Getenv("LIFECYCLE_EVENTS_ENDPOINT"),
+		Subject:  os.Getenv("LIFECYCLE_EVENTS_SUBJECT"),
+
+		CredentialsDir: os.Getenv("LIFECYCLE_EVENTS_CREDENTIALS_DIR"),
+	})
+	if err != nil {
+		log.Log.Error(err, "unable to create lifecycle events sink")
+		os.Exit(1)
+	}
+	lifecycleEvents := lifecycle.NewEmitter(lifecycleSink, nodeName)
+
+	firmwareCacheConfig, err := newFirmwareCacheConfig()
+	if err != nil {
+		log.Log.Error(err, "invalid firmware cache configuration")
+		os.Exit(1)
+	}
+
 	hostUtils := host.NewHostUtils()
-	hostManager := host.NewHostManager(nodeName, hostUtils, eventRecorder)
+	hostManager := host.NewHostManager(nodeName, hostUtils, eventRecorder, changelogSink, lifecycleEvents)
 	maintenanceManager := maintenance.New(mgr.GetClient(), hostUtils, nodeName, namespace)
 
 	if err := initNicFwMap(namespace); err != nil {
@@ -80,19 +123,89 @@ func main() {
 	}
 
 	deviceDiscovery := controller.NewDeviceRegistry(mgr.GetClient(), hostManager, nodeName, namespace)
+	deviceDiscovery.IgnoredPCIAddresses = splitEnvList(os.Getenv("IGNORE_PCI_ADDRESSES"))
+	deviceDiscovery.BatchDiscovery = os.Getenv("BATCH_DISCOVERY") == "true"
+	deviceDiscovery.DiscoverVfs = os.Getenv("DISCOVER_VFS") == "true"
+	deviceDiscovery.LifecycleEvents = lifecycleEvents
+	if value := os.Getenv("TEMPERATURE_WARNING_THRESHOLD"); value != "" {
+		deviceDiscovery.TemperatureWarningThreshold, err = strconv.Atoi(value)
+		if err != nil || deviceDiscovery.TemperatureWarningThreshold < 0 {
+			log.Log.Error(err, "invalid TEMPERATURE_WARNING_THRESHOLD", "value", value)
+			os.Exit(1)
+		}
+	}
+	if value := os.Getenv("DEVICE_DISCOVERY_INTERVAL"); value != "" {
+		deviceDiscovery.Interval, err = time.ParseDuration(value)
+		if err != nil || deviceDiscovery.Interval <= 0 {
+			log.Log.Error(err, "invalid DEVICE_DISCOVERY_INTERVAL", "value", value)
+			os.Exit(1)
+		}
+	}
 	if err = mgr.Add(deviceDiscovery); err != nil {
 		log.Log.Error(err, "unable to add device discovery runnable")
 		os.Exit(1)
 	}
 
+	rescanReconciler := controller.RescanReconciler{
+		Client:    mgr.GetClient(),
+		NodeName:  nodeName,
+		Discovery: deviceDiscovery,
+	}
+	if err = rescanReconciler.SetupWithManager(mgr); err != nil {
+		log.Log.Error(err, "unable to create controller", "controller", "RescanReconciler")
+		os.Exit(1)
+	}
+
+	representorWatcher := controller.NewRepresentorWatcher(mgr.GetClient(), hostManager, eventRecorder, nodeName)
+	if err = mgr.Add(representorWatcher); err != nil {
+		log.Log.Error(err, "unable to add representor watcher runnable")
+		os.Exit(1)
+	}
+
+	if socketPath := os.Getenv("LOCAL_API_SOCKET"); socketPath != "" {
+		localAPIServer := controller.NewLocalAPIServer(mgr.GetClient(), nodeName, socketPath, os.Getenv("LOCAL_API_SNAPSHOT"))
+		if err = mgr.Add(localAPIServer); err != nil {
+			log.Log.Error(err, "unable to add local API server runnable")
+			os.Exit(1)
+		}
+	}
+
+	restartSyncWindow := time.Duration(0)
+	if value := os.Getenv("RESTART_SYNC_WINDOW"); value != "" {
+		restartSyncWindow, err = time.ParseDuration(value)
+		if err != nil || restartSyncWindow < 0 {
+			log.Log.Error(err, "invalid RESTART_SYNC_WINDOW", "value", value)
+			os.Exit(1)
+		}
+	}
+
+	deepScanInterval := time.Duration(0)
+	if value := os.Getenv("DEEP_SCAN_INTERVAL"); value != "" {
+		deepScanInterval, err = time.ParseDuration(value)
+		if err != nil || deepScanInterval < 0 {
+			log.Log.Error(err, "invalid DEEP_SCAN_INTERVAL", "value", value)
+			os.Exit(1)
+		}
+	}
+
 	nicDeviceReconciler := controller.NicDeviceReconciler{
-		Client:             mgr.GetClient(),
-		Scheme:             mgr.GetScheme(),
-		NodeName:           nodeName,
-		NamespaceName:      namespace,
-		HostManager:        hostManager,
-		MaintenanceManager: maintenanceManager,
-		EventRecorder:      eventRecorder,
+		Client:               mgr.GetClient(),
+		Scheme:               mgr.GetScheme(),
+		NodeName:             nodeName,
+		NamespaceName:        namespace,
+		HostManager:          hostManager,
+		HostUtils:            hostUtils,
+		FirmwareManager:      host.NewFirmwareManager(firmwareCacheConfig, hostUtils),
+		MaintenanceManager:   maintenanceManager,
+		EventRecorder:        eventRecorder,
+		LifecycleEvents:      lifecycleEvents,
+		ProvisioningTaints:   splitEnvList(os.Getenv("PROVISIONING_TAINTS")),
+		WaitForNodeReady:     os.Getenv("WAIT_FOR_NODE_READY") != "false",
+		StrictConvergence:    os.Getenv("STRICT_CONVERGENCE") == "true",
+		RdmaResourcePrefixes: splitEnvList(os.Getenv("RDMA_RESOURCE_PREFIXES")),
+		APIReader:            mgr.GetAPIReader(),
+		RestartSyncWindow:    restartSyncWindow,
+		DeepScanInterval:     deepScanInterval,
 	}
 	err = nicDeviceReconciler.SetupWithManager(mgr, true)
 	if err != nil {
@@ -116,6 +229,69 @@ func main() {
 	}
 }
 
+// splitEnvList splits a comma-separated env var value into a list, dropping empty entries
+func splitEnvList(value string) []string {
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// newFirmwareCacheConfig creates the firmware cache config from the env vars, the cache is unlimited if they are not set
+func newFirmwareCacheConfig() (host.FirmwareCacheConfig, error) {
+	config := host.FirmwareCacheConfig{Dir: consts.FirmwareCacheDir}
+
+	if value := os.Getenv("FIRMWARE_CACHE_MAX_SIZE"); value != "" {
+		maxSize, err := resource.ParseQuantity(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid FIRMWARE_CACHE_MAX_SIZE %q: %w", value, err)
+		}
+		config.MaxSize = maxSize.Value()
+	}
+
+	if value := os.Getenv("FIRMWARE_CACHE_MAX_RETAINED_VERSIONS"); value != "" {
+		maxRetainedVersions, err := strconv.Atoi(value)
+		if err != nil || maxRetainedVersions < 0 {
+			return config, fmt.Errorf("invalid FIRMWARE_CACHE_MAX_RETAINED_VERSIONS %q", value)
+		}
+		config.MaxRetainedVersions = maxRetainedVersions
+	}
+
+	return config, nil
+}
+
+// newChangelogSink creates the sink for the applied nv config changes from the env vars, returns nil if disabled
+func newChangelogSink(nodeName string, namespace string) (changelog.Sink, error) {
+	config := changelog.Config{
+		Sink:          os.Getenv("CHANGELOG_SINK"),
+		NodeName:      nodeName,
+		Namespace:     namespace,
+		ConfigMapName: os.Getenv("CHANGELOG_CONFIGMAP_NAME"),
+		S3: changelog.S3Config{
+			Endpoint:        os.Getenv("CHANGELOG_S3_ENDPOINT"),
+			Bucket:          os.Getenv("CHANGELOG_S3_BUCKET"),
+			Region:          os.Getenv("CHANGELOG_S3_REGION"),
+			Prefix:          os.Getenv("CHANGELOG_S3_PREFIX"),
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		},
+	}
+	if config.ConfigMapName == "" {
+		config.ConfigMapName = "nic-configuration-changelog"
+	}
+
+	var kubeclient kubernetes.Interface
+	if config.Sink == changelog.SinkConfigMap {
+		kubeclient = kubernetes.NewForConfigOrDie(ctrl.GetConfigOrDie())
+	}
+
+	return changelog.NewSink(config, kubeclient)
+}
+
 func initNicFwMap(namespace string) error {
 	kubeclient := kubernetes.NewForConfigOrDie(ctrl.GetConfigOrDie())
 	if err := helper.InitNicFwMapFromConfigMap(kubeclient, namespace); err != nil {
@@ -0,0 +1,25 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package config embeds the manifests generated by controller-gen from the API types and the kubebuilder markers
+package config
+
+import "embed"
+
+// Generated contains the CRDs in crd/bases and the ClusterRole and the Role of the operator in rbac/role.yaml
+// the files are regenerated with `make manifests`
+//
+//go:embed crd/bases/*.yaml rbac/role.yaml
+var Generated embed.FS
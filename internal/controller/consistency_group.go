@@ -0,0 +1,207 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	v1alpha1 "github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+)
+
+// consistencyGroup returns the name of the device's consistency group, empty if the device isn't grouped
+func consistencyGroup(device *v1alpha1.NicDevice) string {
+	if device.Spec.Configuration == nil {
+		return ""
+	}
+	return device.Spec.Configuration.ConsistencyGroup
+}
+
+// consistencyGroups returns the statuses of the grouped devices by the name of their consistency group
+// returns the sorted names of the groups to process them in the same order in each reconciliation
+func (p nicDeviceConfigurationStatuses) consistencyGroups() (map[string]nicDeviceConfigurationStatuses, []string) {
+	groups := map[string]nicDeviceConfigurationStatuses{}
+	names := []string{}
+	for _, result := range p {
+		name := consistencyGroup(result.device)
+		if name == "" {
+			continue
+		}
+		if _, found := groups[name]; !found {
+			names = append(names, name)
+		}
+		groups[name] = append(groups[name], result)
+	}
+	slices.Sort(names)
+
+	return groups, names
+}
+
+// skippedInThisReconcile returns true if the device is filtered out of the reconciliation
+// because of a stuck host tool or denied nv config ownership
+func (s *nicDeviceConfigurationStatus) skippedInThisReconcile() bool {
+	return s.toolHang || s.ownershipDenied
+}
+
+// holdConsistencyGroups returns the statuses without the devices whose consistency group can't be applied as a whole
+// a group is held if one of its devices is skipped in this reconciliation, with ConsistencyGroupPending status condition
+// applied to the devices waiting for the nv config update, the skipped devices are left to their own filters
+// a group is rejected with IncorrectSpec status condition if its devices render different values of the same nv config parameter
+func (r *NicDeviceReconciler) holdConsistencyGroups(ctx context.Context, statuses nicDeviceConfigurationStatuses) nicDeviceConfigurationStatuses {
+	held := map[*nicDeviceConfigurationStatus]bool{}
+
+	groups, names := statuses.consistencyGroups()
+	for _, name := range names {
+		group := groups[name]
+
+		err := validateConsistencyGroup(group)
+		if err != nil {
+			log.Log.Info("consistency group is inconsistent, skipping its devices", "group", name, "err", err.Error())
+			for _, member := range group {
+				if member.skippedInThisReconcile() {
+					continue
+				}
+				held[member] = true
+				err := r.updateDeviceStatusCondition(ctx, member.device, consts.IncorrectSpecReason, metav1.ConditionFalse, err.Error())
+				if err != nil {
+					log.Log.Error(err, "failed to update device status condition", "device", member.device.Name)
+				}
+			}
+			continue
+		}
+
+		blockerIndex := slices.IndexFunc(group, func(member *nicDeviceConfigurationStatus) bool {
+			return member.skippedInThisReconcile()
+		})
+		if blockerIndex == -1 {
+			continue
+		}
+		blocker := group[blockerIndex]
+
+		for _, member := range group {
+			if member.skippedInThisReconcile() || (!member.nvConfigUpdateRequired && !member.rebootRequired) {
+				continue
+			}
+			log.Log.Info("holding device until its consistency group can be applied", "device", member.device.Name,
+				"group", name, "blockingDevice", blocker.device.Name)
+			held[member] = true
+
+			if !member.nvConfigUpdateRequired {
+				// The PendingReboot condition is kept to detect the nv config not applied after the reboot
+				continue
+			}
+			message := fmt.Sprintf("waiting for device %s of consistency group %s", blocker.device.Name, name)
+			err := r.updateDeviceStatusCondition(ctx, member.device, consts.ConsistencyGroupPendingReason, metav1.ConditionTrue, message)
+			if err != nil {
+				log.Log.Error(err, "failed to update device status condition", "device", member.device.Name)
+			}
+		}
+	}
+
+	filtered := nicDeviceConfigurationStatuses{}
+	for _, result := range statuses {
+		if !held[result] {
+			filtered = append(filtered, result)
+		}
+	}
+
+	return filtered
+}
+
+// validateConsistencyGroup checks that the devices of the group render the same values of their common nv config parameters
+// the devices skipped in this reconciliation are not checked, their nv config parameters might not be rendered
+// only the templated values are compared, the parameters at the device defaults are skipped, the defaults restored
+// for the unset template fields depend on the NIC model and firmware
+func validateConsistencyGroup(group nicDeviceConfigurationStatuses) error {
+	values := map[string]string{}
+	owners := map[string]string{}
+	for _, member := range group {
+		if member.skippedInThisReconcile() {
+			continue
+		}
+		for _, param := range member.device.Status.NvConfigParameters {
+			if param.DeviceDefault {
+				continue
+			}
+			value, found := values[param.Name]
+			if !found {
+				values[param.Name] = param.DesiredValue
+				owners[param.Name] = member.device.Name
+				continue
+			}
+			if value != param.DesiredValue {
+				return fmt.Errorf("devices %s and %s of consistency group %s have different values of parameter %s: %s, %s",
+					owners[param.Name], member.device.Name, consistencyGroup(member.device), param.Name, value, param.DesiredValue)
+			}
+		}
+	}
+
+	return nil
+}
+
+// rollbackIncompleteConsistencyGroups restores the nv config of the devices written in this reconciliation
+// if the nv config update of another device of their consistency group failed, so that none of the group's devices proceed to the reboot
+// applies RolledBack status condition to the restored devices, sets lastStageError of the devices that couldn't be restored
+func (r *NicDeviceReconciler) rollbackIncompleteConsistencyGroups(ctx context.Context, statuses nicDeviceConfigurationStatuses) {
+	groups, names := statuses.consistencyGroups()
+	for _, name := range names {
+		group := groups[name]
+
+		failedIndex := slices.IndexFunc(group, func(member *nicDeviceConfigurationStatus) bool {
+			return member.nvConfigUpdateRequired && (member.lastStageError != nil || member.ownershipDenied)
+		})
+		if failedIndex == -1 {
+			continue
+		}
+		failed := group[failedIndex]
+
+		for _, member := range group {
+			if !member.nvConfigUpdateRequired || member.lastStageError != nil || member.ownershipDenied {
+				continue
+			}
+
+			log.Log.Info("rolling back nv config of device, its consistency group failed to apply", "device", member.device.Name,
+				"group", name, "failedDevice", failed.device.Name)
+			err := r.HostManager.RollbackDeviceNvSpec(ctx, member.device)
+			if err != nil {
+				log.Log.Error(err, "failed to roll back nv config of device", "device", member.device.Name)
+				member.lastStageError = err
+				message := fmt.Sprintf("failed to roll back together with device %s of consistency group %s: %v", failed.device.Name, name, err)
+				err = r.updateDeviceStatusCondition(ctx, member.device, consts.NonVolatileConfigUpdateFailedReason, metav1.ConditionFalse, message)
+				if err != nil {
+					log.Log.Error(err, "failed to update device status condition", "device", member.device.Name)
+				}
+				continue
+			}
+			member.rebootRequired = false
+
+			message := fmt.Sprintf("rolled back together with device %s of consistency group %s", failed.device.Name, name)
+			err = r.updateDeviceStatusCondition(ctx, member.device, consts.RolledBackReason, metav1.ConditionFalse, message)
+			if err != nil {
+				log.Log.Error(err, "failed to update device status condition", "device", member.device.Name)
+			}
+			err = r.setOperationPhase(ctx, member.device, consts.OperationPhaseApplying)
+			if err != nil {
+				log.Log.Error(err, "failed to update device operation phase", "device", member.device.Name)
+			}
+		}
+	}
+}
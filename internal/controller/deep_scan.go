@@ -0,0 +1,97 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	v1alpha1 "github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+	"github.com/Mellanox/nic-configuration-operator/pkg/host"
+)
+
+// deepScanDue returns true if the deep scan of the devices is due at the given time
+// the first deep scan runs DeepScanInterval after the config daemon starts, its first reconciliation validates the devices anyway
+func (r *NicDeviceReconciler) deepScanDue(now time.Time) bool {
+	if r.DeepScanInterval <= 0 {
+		return false
+	}
+	if r.lastDeepScan.IsZero() {
+		r.lastDeepScan = now
+		return false
+	}
+	return !now.Before(r.lastDeepScan.Add(r.DeepScanInterval))
+}
+
+// untilDeepScan returns the shorter of the requeue delay and the time until the next deep scan, 0 delay means no requeue
+func (r *NicDeviceReconciler) untilDeepScan(now time.Time, delay time.Duration) time.Duration {
+	if r.DeepScanInterval <= 0 || r.lastDeepScan.IsZero() {
+		return delay
+	}
+
+	until := max(r.lastDeepScan.Add(r.DeepScanInterval).Sub(now), time.Second)
+	if delay == 0 || until < delay {
+		return until
+	}
+	return delay
+}
+
+// startDeepScan drops the state memoized by the reconciler, so that the devices are fully reconciled in this reconciliation
+// the deferred validations of the converged devices and the denied nv config ownership are forgotten,
+// the firmware version and PSID of the devices are queried again, the nv config and the runtime config are then validated as usual
+func (r *NicDeviceReconciler) startDeepScan(ctx context.Context, statuses nicDeviceConfigurationStatuses, now time.Time) {
+	log.Log.Info("starting deep scan of the devices", "node", r.NodeName, "devices", len(statuses))
+	r.lastDeepScan = now
+	r.configOwnershipDenied = nil
+
+	if r.validationDeferredUntil == nil {
+		r.validationDeferredUntil = map[string]time.Time{}
+	}
+	for _, status := range statuses {
+		r.validationDeferredUntil[status.device.Name] = time.Time{}
+		r.refreshFirmwareInfo(ctx, status.device)
+	}
+}
+
+// refreshFirmwareInfo queries the firmware version and PSID of the device and updates its status if they diverged,
+// e.g. after the firmware was burned by an out-of-band tool, the firmware of the restricted devices isn't queryable
+func (r *NicDeviceReconciler) refreshFirmwareInfo(ctx context.Context, device *v1alpha1.NicDevice) {
+	if device.Status.ConfigurationMode == consts.ConfigurationModeRestricted {
+		return
+	}
+
+	query, err := r.HostUtils.QueryFirmware(host.NvConfigPCIAddress(device))
+	if err != nil {
+		log.Log.Error(err, "failed to query firmware of device", "device", device.Name)
+		return
+	}
+	version, psid := query.Version, query.PSID
+	if version == device.Status.FirmwareVersion && psid == device.Status.PSID {
+		return
+	}
+
+	log.Log.Info("firmware of device changed since its discovery", "device", device.Name,
+		"previousVersion", device.Status.FirmwareVersion, "version", version, "previousPSID", device.Status.PSID, "psid", psid)
+	device.Status.FirmwareVersion = version
+	device.Status.PSID = psid
+	err = r.Client.Status().Update(ctx, device)
+	if err != nil {
+		log.Log.Error(err, "failed to update firmware in device status", "device", device.Name)
+	}
+}
@@ -0,0 +1,112 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+	hostMocks "github.com/Mellanox/nic-configuration-operator/pkg/host/mocks"
+	"github.com/Mellanox/nic-configuration-operator/pkg/types"
+)
+
+var _ = Describe("deep scan", func() {
+	const interval = 168 * time.Hour
+
+	var (
+		reconciler *NicDeviceReconciler
+		hostUtils  *hostMocks.HostUtils
+		device     *v1alpha1.NicDevice
+		start      time.Time
+	)
+
+	BeforeEach(func() {
+		device = &v1alpha1.NicDevice{
+			ObjectMeta: metav1.ObjectMeta{Name: "dev1", Namespace: "default"},
+			Status: v1alpha1.NicDeviceStatus{
+				FirmwareVersion: "28.39.1002",
+				PSID:            "MT_0000000221",
+				Ports:           []v1alpha1.NicDevicePortSpec{{PCI: "0000:3b:00.0"}},
+			},
+		}
+		hostUtils = &hostMocks.HostUtils{}
+		reconciler = &NicDeviceReconciler{
+			Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).
+				WithObjects(device.DeepCopy()).WithStatusSubresource(&v1alpha1.NicDevice{}).Build(),
+			HostUtils:        hostUtils,
+			DeepScanInterval: interval,
+		}
+		Expect(reconciler.Get(context.Background(), client.ObjectKeyFromObject(device), device)).To(Succeed())
+		start = time.Now()
+	})
+
+	It("should run the first deep scan one interval after the start", func() {
+		Expect(reconciler.deepScanDue(start)).To(BeFalse())
+		Expect(reconciler.deepScanDue(start.Add(interval - time.Minute))).To(BeFalse())
+		Expect(reconciler.deepScanDue(start.Add(interval))).To(BeTrue())
+	})
+
+	It("should not run deep scans if the interval is not set", func() {
+		reconciler.DeepScanInterval = 0
+
+		Expect(reconciler.deepScanDue(start)).To(BeFalse())
+		Expect(reconciler.deepScanDue(start.Add(interval))).To(BeFalse())
+		Expect(reconciler.untilDeepScan(start, 0)).To(BeZero())
+	})
+
+	It("should requeue no later than the next deep scan", func() {
+		reconciler.deepScanDue(start)
+
+		Expect(reconciler.untilDeepScan(start.Add(time.Hour), 0)).To(Equal(interval - time.Hour))
+		Expect(reconciler.untilDeepScan(start.Add(time.Hour), time.Minute)).To(Equal(time.Minute))
+		Expect(reconciler.untilDeepScan(start.Add(2*interval), 0)).To(Equal(time.Second))
+	})
+
+	It("should drop the memoized state and update the diverged firmware of the devices", func() {
+		hostUtils.On("QueryFirmware", "0000:3b:00.0").Return(&types.FirmwareQuery{Version: "28.41.1000", PSID: "MT_0000000221"}, nil)
+		reconciler.configOwnershipDenied = map[string]string{"dev1": "1/RESTRICTED"}
+		reconciler.validationDeferredUntil = map[string]time.Time{"dev1": start.Add(time.Hour)}
+
+		reconciler.startDeepScan(context.Background(), nicDeviceConfigurationStatuses{{device: device}}, start)
+
+		Expect(reconciler.lastDeepScan).To(Equal(start))
+		Expect(reconciler.configOwnershipDenied).To(BeEmpty())
+		Expect(reconciler.validationDeferredUntil).To(HaveKeyWithValue("dev1", time.Time{}))
+		Expect(device.Status.FirmwareVersion).To(Equal("28.41.1000"))
+
+		updated := &v1alpha1.NicDevice{}
+		Expect(reconciler.Get(context.Background(), client.ObjectKeyFromObject(device), updated)).To(Succeed())
+		Expect(updated.Status.FirmwareVersion).To(Equal("28.41.1000"))
+	})
+
+	It("should not query the firmware of the restricted devices", func() {
+		device.Status.ConfigurationMode = consts.ConfigurationModeRestricted
+
+		reconciler.startDeepScan(context.Background(), nicDeviceConfigurationStatuses{{device: device}}, start)
+
+		Expect(device.Status.FirmwareVersion).To(Equal("28.39.1002"))
+		hostUtils.AssertNotCalled(GinkgoT(), "QueryFirmware", "0000:3b:00.0")
+	})
+})
@@ -0,0 +1,157 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+	"github.com/Mellanox/nic-configuration-operator/pkg/lifecycle"
+)
+
+// operationTransitions lists the phases each phase of the device operation can move to
+// Rendering starts a new operation and can replace an operation in any phase, e.g. after a spec change
+// leaving Done starts a new operation as well, e.g. when the nv config of the device drifted
+// Verifying moves back to Applying if the device has to be written again, e.g. when its consistency group is rolled back
+var operationTransitions = map[string][]string{
+	consts.OperationPhaseRendering:      {consts.OperationPhaseApplying, consts.OperationPhaseAwaitingReboot, consts.OperationPhaseVerifying},
+	consts.OperationPhaseApplying:       {consts.OperationPhaseAwaitingReboot, consts.OperationPhaseVerifying},
+	consts.OperationPhaseAwaitingReboot: {consts.OperationPhaseApplying, consts.OperationPhaseVerifying},
+	consts.OperationPhaseVerifying:      {consts.OperationPhaseApplying, consts.OperationPhaseAwaitingReboot, consts.OperationPhaseDone},
+	consts.OperationPhaseDone:           {consts.OperationPhaseApplying, consts.OperationPhaseAwaitingReboot, consts.OperationPhaseVerifying},
+}
+
+// errInvalidOperationTransition is returned for the transitions not allowed by operationTransitions
+var errInvalidOperationTransition = errors.New("invalid transition of the device operation")
+
+// applyFailedReasons are the reasons of the ConfigUpdateInProgress condition finishing the operation with a failure
+var applyFailedReasons = []string{
+	consts.SpecValidationFailed,
+	consts.IncorrectSpecReason,
+	consts.NonVolatileConfigUpdateFailedReason,
+	consts.RuntimeConfigUpdateFailedReason,
+	consts.DeviceToolHangReason,
+	consts.RolledBackReason,
+	consts.ConfigOwnershipDeniedReason,
+	consts.EswitchAccessDeniedReason,
+	consts.FirmwareUpdateFailedReason,
+	consts.FirmwareMismatchReason,
+	consts.VerificationFailedReason,
+	consts.FirmwareRejectedReason,
+	consts.FirmwareError,
+	consts.NonConvergingReason,
+	consts.FabricFeatureNotSupportedReason,
+}
+
+// operationOutdated returns true if the device has no operation for the current generation of its spec
+func operationOutdated(device *v1alpha1.NicDevice) bool {
+	return device.Status.Operation == nil || device.Status.Operation.ObservedGeneration != device.Generation
+}
+
+// operationInProgress returns true if the device has an unfinished operation for the current generation of its spec
+func operationInProgress(device *v1alpha1.NicDevice) bool {
+	return !operationOutdated(device) && device.Status.Operation.Phase != consts.OperationPhaseDone
+}
+
+// setOperationPhase moves the operation of the device to the given phase and persists it in the device status
+// transitions not allowed by operationTransitions are rejected with errInvalidOperationTransition, the phase is left unchanged
+func (r *NicDeviceReconciler) setOperationPhase(ctx context.Context, device *v1alpha1.NicDevice, phase string) error {
+	operation := device.Status.Operation
+	now := metav1.Now()
+
+	previousPhase := ""
+	if !operationOutdated(device) {
+		previousPhase = operation.Phase
+	}
+
+	switch {
+	case phase == consts.OperationPhaseRendering || operationOutdated(device):
+		device.Status.Operation = &v1alpha1.DeviceOperationStatus{
+			Phase:              phase,
+			ObservedGeneration: device.Generation,
+			StartTime:          now,
+			LastTransitionTime: now,
+		}
+	case operation.Phase == phase:
+		return nil
+	case !slices.Contains(operationTransitions[operation.Phase], phase):
+		return fmt.Errorf("%w from %s to %s", errInvalidOperationTransition, operation.Phase, phase)
+	default:
+		if operation.Phase == consts.OperationPhaseDone {
+			operation.StartTime = now
+		}
+		operation.Phase = phase
+		operation.LastTransitionTime = now
+	}
+
+	log.Log.V(2).Info("device operation phase changed", "device", device.Name, "phase", phase)
+	err := r.Client.Status().Update(ctx, device)
+	if err != nil {
+		return err
+	}
+
+	r.emitOperationEvents(ctx, device, previousPhase, phase)
+	return nil
+}
+
+// emitOperationEvents publishes the lifecycle events of the device's operation moving from previousPhase to phase
+// previousPhase is empty if the device had no operation for the current generation of its spec
+func (r *NicDeviceReconciler) emitOperationEvents(ctx context.Context, device *v1alpha1.NicDevice, previousPhase string, phase string) {
+	switch {
+	case previousPhase == consts.OperationPhaseDone:
+		r.LifecycleEvents.Emit(ctx, lifecycle.DriftDetectedEvent, device, lifecycle.NvConfigDriftReason,
+			"state of the device no longer matches its spec, reconfiguring")
+	case previousPhase == consts.OperationPhaseAwaitingReboot && phase == consts.OperationPhaseVerifying:
+		r.LifecycleEvents.Emit(ctx, lifecycle.RebootCompletedEvent, device, "", "nv config of the device is active")
+	}
+
+	switch phase {
+	case consts.OperationPhaseApplying:
+		r.LifecycleEvents.Emit(ctx, lifecycle.ApplyStartedEvent, device, "", "")
+	case consts.OperationPhaseDone:
+		r.LifecycleEvents.Emit(ctx, lifecycle.ApplyFinishedEvent, device, consts.UpdateSuccessfulReason, "")
+	}
+}
+
+// reportResumedOperation reports the operations interrupted by the previous run of the config daemon once per device
+// the operation continues from the persisted phase, completed phases are not repeated, e.g. a reboot that already happened
+func (r *NicDeviceReconciler) reportResumedOperation(device *v1alpha1.NicDevice) {
+	if r.operationsObserved == nil {
+		r.operationsObserved = map[string]bool{}
+	}
+	if r.operationsObserved[device.Name] {
+		return
+	}
+	r.operationsObserved[device.Name] = true
+
+	if !operationInProgress(device) {
+		return
+	}
+
+	operation := device.Status.Operation
+	message := fmt.Sprintf("resuming the configuration started at %s in phase %s", operation.StartTime.UTC().Format(time.RFC3339), operation.Phase)
+	log.Log.Info(message, "device", device.Name)
+	r.EventRecorder.Event(device, v1.EventTypeNormal, consts.OperationResumedReason, message)
+}
@@ -0,0 +1,121 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	v1alpha1 "github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+)
+
+// States of the devices reported by the time in state metric
+const (
+	deviceStateUpdateInProgress = "UpdateInProgress"
+	deviceStateRebootPending    = "RebootPending"
+	deviceStateError            = "Error"
+)
+
+// deviceStateListTimeout limits listing the devices on each scrape
+const deviceStateListTimeout = 10 * time.Second
+
+var deviceTimeInStateDesc = prometheus.NewDesc(
+	"nic_configuration_operator_device_time_in_state_seconds",
+	"Time the NIC device has spent in its current state of the configuration update: UpdateInProgress, RebootPending or Error, "+
+		"converged devices are not reported",
+	[]string{"namespace", "node", "device", "state"}, nil,
+)
+
+// deviceStateCollector reports the time each NicDevice has spent in its unconverged state
+// the durations are computed on each scrape from the device status, so they don't depend on the reconciliation of the devices
+type deviceStateCollector struct {
+	reader client.Reader
+	now    func() time.Time
+}
+
+// NewDeviceStateCollector creates a collector of the time in state metric of the NicDevices read from the given reader
+func NewDeviceStateCollector(reader client.Reader) prometheus.Collector {
+	return &deviceStateCollector{reader: reader, now: time.Now}
+}
+
+// Describe implements prometheus.Collector
+func (c *deviceStateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- deviceTimeInStateDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *deviceStateCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), deviceStateListTimeout)
+	defer cancel()
+
+	deviceList := &v1alpha1.NicDeviceList{}
+	err := c.reader.List(ctx, deviceList)
+	if err != nil {
+		log.Log.Error(err, "failed to list NicDevices for the time in state metric")
+		return
+	}
+
+	now := c.now()
+	for i := range deviceList.Items {
+		device := &deviceList.Items[i]
+		state, since, found := deviceState(device)
+		if !found {
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(deviceTimeInStateDesc, prometheus.GaugeValue,
+			max(now.Sub(since).Seconds(), 0), device.Namespace, device.Status.Node, device.Name, state)
+	}
+}
+
+// deviceState returns the unconverged state of the device's configuration update and the time the device entered it
+// returns false if the device is converged or hasn't reported its state yet
+func deviceState(device *v1alpha1.NicDevice) (string, time.Time, bool) {
+	condition := meta.FindStatusCondition(device.Status.Conditions, consts.ConfigUpdateInProgressCondition)
+	if condition == nil {
+		return "", time.Time{}, false
+	}
+
+	// The condition only transitions on the change of its status, the operation records the start of the update and its phases
+	var operation *v1alpha1.DeviceOperationStatus
+	if device.Status.Operation != nil && device.Status.Operation.ObservedGeneration == device.Generation {
+		operation = device.Status.Operation
+	}
+
+	switch {
+	case condition.Reason == consts.PendingRebootReason:
+		if operation != nil && operation.Phase == consts.OperationPhaseAwaitingReboot {
+			return deviceStateRebootPending, operation.LastTransitionTime.Time, true
+		}
+		return deviceStateRebootPending, condition.LastTransitionTime.Time, true
+	case condition.Status != metav1.ConditionFalse:
+		if operation != nil {
+			return deviceStateUpdateInProgress, operation.StartTime.Time, true
+		}
+		return deviceStateUpdateInProgress, condition.LastTransitionTime.Time, true
+	case condition.Reason == consts.UpdateSuccessfulReason || condition.Reason == consts.DeviceConfigSpecEmptyReason:
+		return "", time.Time{}, false
+	default:
+		return deviceStateError, condition.LastTransitionTime.Time, true
+	}
+}
@@ -0,0 +1,85 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	v1alpha1 "github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+)
+
+var _ = Describe("deviceStateCollector", func() {
+	now := time.Date(2024, 10, 14, 12, 0, 0, 0, time.UTC)
+
+	newDevice := func(name string, reason string, status metav1.ConditionStatus, transitioned time.Duration, operation *v1alpha1.DeviceOperationStatus) client.Object {
+		return &v1alpha1.NicDevice{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "nic-configuration-operator", Generation: 2},
+			Status: v1alpha1.NicDeviceStatus{
+				Node: "test-node",
+				Conditions: []metav1.Condition{{
+					Type:               consts.ConfigUpdateInProgressCondition,
+					Status:             status,
+					ObservedGeneration: 2,
+					Reason:             reason,
+					LastTransitionTime: metav1.NewTime(now.Add(-transitioned)),
+				}},
+				Operation: operation,
+			},
+		}
+	}
+
+	It("should report the time in the unconverged states of the devices", func() {
+		devices := []client.Object{
+			newDevice("updating", consts.UpdateStartedReason, metav1.ConditionTrue, time.Minute, &v1alpha1.DeviceOperationStatus{
+				Phase: consts.OperationPhaseApplying, ObservedGeneration: 2, StartTime: metav1.NewTime(now.Add(-time.Hour)),
+			}),
+			newDevice("rebooting", consts.PendingRebootReason, metav1.ConditionTrue, 3*time.Hour, &v1alpha1.DeviceOperationStatus{
+				Phase: consts.OperationPhaseAwaitingReboot, ObservedGeneration: 2,
+				StartTime: metav1.NewTime(now.Add(-3 * time.Hour)), LastTransitionTime: metav1.NewTime(now.Add(-2 * time.Hour)),
+			}),
+			newDevice("failed", consts.NonVolatileConfigUpdateFailedReason, metav1.ConditionFalse, 30*time.Second, nil),
+			// operation of the previous spec generation doesn't reflect the current update
+			newDevice("stale-operation", consts.UpdateStartedReason, metav1.ConditionTrue, 10*time.Second, &v1alpha1.DeviceOperationStatus{
+				Phase: consts.OperationPhaseDone, ObservedGeneration: 1, StartTime: metav1.NewTime(now.Add(-24 * time.Hour)),
+			}),
+			newDevice("converged", consts.UpdateSuccessfulReason, metav1.ConditionFalse, time.Hour, nil),
+			&v1alpha1.NicDevice{ObjectMeta: metav1.ObjectMeta{Name: "new", Namespace: "nic-configuration-operator"}},
+		}
+		collector := &deviceStateCollector{
+			reader: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(devices...).Build(),
+			now:    func() time.Time { return now },
+		}
+
+		Expect(testutil.CollectAndCompare(collector, strings.NewReader(`
+# HELP nic_configuration_operator_device_time_in_state_seconds Time the NIC device has spent in its current state of the configuration update: UpdateInProgress, RebootPending or Error, converged devices are not reported
+# TYPE nic_configuration_operator_device_time_in_state_seconds gauge
+nic_configuration_operator_device_time_in_state_seconds{device="failed",namespace="nic-configuration-operator",node="test-node",state="Error"} 30
+nic_configuration_operator_device_time_in_state_seconds{device="rebooting",namespace="nic-configuration-operator",node="test-node",state="RebootPending"} 7200
+nic_configuration_operator_device_time_in_state_seconds{device="stale-operation",namespace="nic-configuration-operator",node="test-node",state="UpdateInProgress"} 10
+nic_configuration_operator_device_time_in_state_seconds{device="updating",namespace="nic-configuration-operator",node="test-node",state="UpdateInProgress"} 3600
+`))).To(Succeed())
+	})
+})
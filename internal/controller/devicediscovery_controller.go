@@ -17,8 +17,11 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"reflect"
+	"slices"
 	"strings"
 	"time"
 
@@ -36,23 +39,86 @@ import (
 	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
 	"github.com/Mellanox/nic-configuration-operator/pkg/helper"
 	"github.com/Mellanox/nic-configuration-operator/pkg/host"
+	"github.com/Mellanox/nic-configuration-operator/pkg/lifecycle"
 )
 
+// deviceDiscoveryReconcileTime is the default interval of the device discovery, used if DeviceDiscovery.Interval is not set
 var deviceDiscoveryReconcileTime = time.Minute * 5
 
+// linkStateRefreshTime is the interval of refreshing the links of the discovered ports, so that the ports of the configured devices
+// with the link down are visible without waiting for the next discovery
+var linkStateRefreshTime = time.Second * 30
+
+// deviceDiscoveryRetryTime is the time after which a failed discovery of the devices is retried
+var deviceDiscoveryRetryTime = time.Second * 10
+
+// temperatureHysteresis is the change of the ASIC temperature in degrees Celsius from which the discovery publishes the new temperature,
+// smaller changes keep the previously published one, so that the sensor noise doesn't rewrite the status of the devices on each discovery
+const temperatureHysteresis = 3
+
+// deviceHotplugSettleTime is the time without new uevents of the NVIDIA PCI devices after which the devices are discovered,
+// PFs of a device and their VFs are bound in bursts, so they are discovered in a single pass
+var deviceHotplugSettleTime = time.Second * 3
+
+// ueventSubscribeFunc delivers the kernel uevents of the host to the channel until done is closed
+type ueventSubscribeFunc func(ch chan<- host.UEvent, done <-chan struct{}) error
+
 // DeviceDiscovery periodically reconciles devices on the host, creates CRs for new devices,
-// deletes CRs for absent devices, updates the CR when device's status has changed.
+// deletes CRs for devices absent in two consecutive discoveries, updates the CR when device's status has changed.
+// Hot-plugged devices and devices re-bound to the driver, e.g. after a firmware reset, are discovered as their uevents arrive.
 type DeviceDiscovery struct {
 	client.Client
 
+	// IgnoredPCIAddresses is a list of PCI addresses that should never be discovered on this node
+	// it is extended by the addresses from the node's consts.IgnorePCIAddressesAnnotation
+	IgnoredPCIAddresses []string
+	// BatchDiscovery publishes the observed devices in a single NicNodeReport per node instead of
+	// writing each NicDevice CR, the operator fans the report out into the NicDevice CRs
+	BatchDiscovery bool
+	// DiscoverVfs lists the SR-IOV VFs of the discovered devices in their status
+	DiscoverVfs bool
+	// LifecycleEvents publishes the discovered devices, disabled if nil
+	LifecycleEvents *lifecycle.Emitter
+	// TemperatureWarningThreshold is the ASIC temperature in degrees Celsius from which the HealthWarning condition
+	// is reported for the device, the temperature warning is disabled if 0
+	TemperatureWarningThreshold int
+	// Interval of the periodic discovery of the devices, deviceDiscoveryReconcileTime is used if 0
+	Interval time.Duration
+
 	hostManager host.HostManager
 	nodeName    string
 	namespace   string
+	// discoveredDevices are the serial numbers of the devices found by the previous discovery
+	discoveredDevices map[string]bool
+	// reportedDevices are the devices published by the previous discovery, their links are refreshed in between the discoveries
+	reportedDevices map[string]v1alpha1.NicNodeReportDevice
+	// absentDevices are the serial numbers of the devices missing from the host in the previous discovery,
+	// they were published from the discovery before it, see keepAbsentDevices
+	absentDevices map[string]bool
+	subscribe     ueventSubscribeFunc
+	// resetInProgress returns true while a device of the host is reset and its ports are missing from the host,
+	// the discovery is postponed until the reset completes
+	resetInProgress func() bool
+	// rescan triggers the discovery of the devices right away, see Rescan
+	rescan chan struct{}
+}
+
+// Rescan requests the discovery of the devices right away, the requests made while a discovery is pending are coalesced
+func (d *DeviceDiscovery) Rescan() {
+	select {
+	case d.rescan <- struct{}{}:
+	default:
+	}
 }
 
 // Constructs a unique CR name based on the device's type and serial number
 func (d *DeviceDiscovery) getCRName(deviceType string, serialNumber string) string {
-	return strings.ToLower(d.nodeName + "-" + deviceType + "-" + serialNumber)
+	return nicDeviceName(d.nodeName, deviceType, serialNumber)
+}
+
+// nicDeviceName constructs a unique CR name based on the node name, device's type and serial number
+func nicDeviceName(nodeName string, deviceType string, serialNumber string) string {
+	return strings.ToLower(nodeName + "-" + deviceType + "-" + serialNumber)
 }
 
 func setInitialsConditionsForDevice(device *v1alpha1.NicDevice) {
@@ -66,7 +132,11 @@ func setInitialsConditionsForDevice(device *v1alpha1.NicDevice) {
 }
 
 func setFwConfigConditionsForDevice(device *v1alpha1.NicDevice, recommendedFirmware string) {
-	currentFirmware := device.Status.FirmwareVersion
+	setFwConfigConditions(&device.Status, recommendedFirmware)
+}
+
+func setFwConfigConditions(status *v1alpha1.NicDeviceStatus, recommendedFirmware string) {
+	currentFirmware := status.FirmwareVersion
 	log.Log.V(2).Info("setFwConfigConditionsForDevice()", "recommendedFirmware", recommendedFirmware, "currentFirmware", currentFirmware)
 	var condition metav1.Condition
 	switch recommendedFirmware {
@@ -92,137 +162,690 @@ func setFwConfigConditionsForDevice(device *v1alpha1.NicDevice, recommendedFirmw
 			Message: fmt.Sprintf("Device firmware '%s' doesn't match to recommended version '%s'", currentFirmware, recommendedFirmware),
 		}
 	}
-	meta.SetStatusCondition(&device.Status.Conditions, condition)
+	meta.SetStatusCondition(&status.Conditions, condition)
+}
+
+// stableTemperature returns the previously published temperature of the device if the observed one differs from it
+// by less than temperatureHysteresis and both are on the same side of the warning threshold, the observed temperature otherwise
+func stableTemperature(observed *v1alpha1.DeviceHealthStatus, previous *v1alpha1.DeviceHealthStatus) *int {
+	if observed.Temperature == nil || previous == nil || previous.Temperature == nil {
+		return observed.Temperature
+	}
+
+	threshold := observed.TemperatureWarningThreshold
+	crossed := threshold > 0 && (*observed.Temperature >= threshold) != (*previous.Temperature >= threshold)
+	difference := *observed.Temperature - *previous.Temperature
+	if crossed || difference >= temperatureHysteresis || difference <= -temperatureHysteresis {
+		return observed.Temperature
+	}
+	return previous.Temperature
+}
+
+// setHealthConditions reports the HealthWarning condition of the device from its discovered temperature and health reporters
+// the condition is removed if the health of the device isn't reported
+func setHealthConditions(status *v1alpha1.NicDeviceStatus) {
+	health := status.Health
+	if health == nil {
+		meta.RemoveStatusCondition(&status.Conditions, consts.HealthWarningCondition)
+		return
+	}
+
+	failedReporters := []string{}
+	for _, reporter := range health.Reporters {
+		// The PFs of the device report the reporters of the same names
+		if reporter.State == consts.HealthReporterStateError && !slices.Contains(failedReporters, reporter.Name) {
+			failedReporters = append(failedReporters, reporter.Name)
+		}
+	}
+	overheating := health.TemperatureWarningThreshold > 0 && health.Temperature != nil &&
+		*health.Temperature >= health.TemperatureWarningThreshold
+
+	messages := []string{}
+	if overheating {
+		messages = append(messages, fmt.Sprintf("Device temperature %dC reached the warning threshold %dC",
+			*health.Temperature, health.TemperatureWarningThreshold))
+	}
+	if len(failedReporters) != 0 {
+		messages = append(messages, fmt.Sprintf("Health reporters in the error state: %s", strings.Join(failedReporters, ", ")))
+	}
+
+	condition := metav1.Condition{
+		Type:    consts.HealthWarningCondition,
+		Status:  metav1.ConditionTrue,
+		Message: strings.Join(messages, "; "),
+	}
+	switch {
+	case overheating:
+		condition.Reason = consts.OverheatingReason
+	case len(failedReporters) != 0:
+		condition.Reason = consts.HealthReporterErrorReason
+	default:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = consts.DeviceHealthyReason
+		condition.Message = "Device temperature and health reporters are within the limits"
+	}
+	meta.SetStatusCondition(&status.Conditions, condition)
+}
+
+// setIdentityConditions reports the Degraded condition of the device if its VPD couldn't be read
+// and its serial and part numbers come from one of the fallbacks, the condition is removed once the VPD is read
+func setIdentityConditions(status *v1alpha1.NicDeviceStatus) {
+	condition := metav1.Condition{
+		Type:   consts.DegradedCondition,
+		Status: metav1.ConditionTrue,
+		Reason: consts.VpdReadFailedReason,
+	}
+	switch status.IdentitySource {
+	case consts.IdentitySourceFlash:
+		condition.Message = "Device VPD couldn't be read, the serial number is derived from the base GUID of its flash"
+	case consts.IdentitySourcePreviousDiscovery:
+		condition.Message = "Device VPD couldn't be read, the serial and part numbers are kept from the previous discovery"
+	default:
+		meta.RemoveStatusCondition(&status.Conditions, consts.DegradedCondition)
+		return
+	}
+	meta.SetStatusCondition(&status.Conditions, condition)
+}
+
+// keepPreviousIdentities keeps the serial and part numbers found by the previous discovery for the devices identified
+// by their flash, matched by the PCI addresses of their ports, so that their NicDevice CRs aren't recreated after a failed VPD read
+// the devices whose VPD and flash couldn't be read keep the whole status of the previous discovery, they are dropped if there is none
+// the existing NicDevice CRs of the node are the previous discovery after a restart of the config daemon
+func (d *DeviceDiscovery) keepPreviousIdentities(ctx context.Context, observedDevices map[string]v1alpha1.NicDeviceStatus) error {
+	var previousDevices []v1alpha1.NicDeviceStatus
+	for serialNumber, status := range observedDevices {
+		if status.IdentitySource != consts.IdentitySourceFlash && status.IdentitySource != consts.IdentitySourceUnknown {
+			continue
+		}
+
+		if previousDevices == nil {
+			var err error
+			previousDevices, err = d.previousDevices(ctx)
+			if err != nil {
+				return err
+			}
+		}
+		unidentified := status.IdentitySource == consts.IdentitySourceUnknown
+		previous, found := previousDevice(previousDevices, status.Ports, unidentified)
+		if unidentified {
+			delete(observedDevices, serialNumber)
+			if !found {
+				log.Log.Info("device couldn't be identified and wasn't discovered before, skipping it", "ports", status.Ports)
+				continue
+			}
+			if _, taken := observedDevices[previous.SerialNumber]; taken {
+				continue
+			}
+			log.Log.Info("device couldn't be identified, keeping its status from the previous discovery", "serialNumber", previous.SerialNumber)
+			previous.IdentitySource = consts.IdentitySourcePreviousDiscovery
+			observedDevices[previous.SerialNumber] = previous
+			continue
+		}
+		if !found {
+			continue
+		}
+		if _, taken := observedDevices[previous.SerialNumber]; taken {
+			continue
+		}
+
+		log.Log.Info("keeping identity of device from the previous discovery", "serialNumber", previous.SerialNumber, "guid", serialNumber)
+		status.SerialNumber = previous.SerialNumber
+		status.PartNumber = previous.PartNumber
+		status.IdentitySource = consts.IdentitySourcePreviousDiscovery
+		delete(observedDevices, serialNumber)
+		observedDevices[status.SerialNumber] = status
+	}
+	return nil
+}
+
+// keepAbsentDevices keeps the devices of the previous discovery that are missing from the host for the first time
+// with their previous status, so that their NicDevice CRs are only deleted once the devices are missing in two consecutive discoveries,
+// e.g. not while the ports of a device re-enumerate after a reset or are re-bound to the driver
+func (d *DeviceDiscovery) keepAbsentDevices(ctx context.Context, observedDevices map[string]v1alpha1.NicDeviceStatus) error {
+	previousDevices, err := d.previousDevices(ctx)
+	if err != nil {
+		return err
+	}
+
+	absentDevices := map[string]bool{}
+	for _, previous := range previousDevices {
+		if _, found := observedDevices[previous.SerialNumber]; found {
+			continue
+		}
+		if previous.IdentitySource == consts.IdentitySourceFlash && portsObserved(observedDevices, previous.Ports) {
+			// The VPD of the device was read, its NicDevice CR takes the serial number of the VPD
+			continue
+		}
+		if d.absentDevices[previous.SerialNumber] {
+			log.Log.Info("device missing from the host in two discoveries, removing", "serialNumber", previous.SerialNumber)
+			continue
+		}
+
+		log.Log.Info("device missing from the host, keeping it until the next discovery", "serialNumber", previous.SerialNumber)
+		absentDevices[previous.SerialNumber] = true
+		observedDevices[previous.SerialNumber] = previous
+	}
+	d.absentDevices = absentDevices
+	return nil
+}
+
+// previousDevices returns the devices published by the previous discovery,
+// the NicDevice CRs of the node if the config daemon didn't publish any devices since it started
+func (d *DeviceDiscovery) previousDevices(ctx context.Context) ([]v1alpha1.NicDeviceStatus, error) {
+	devices := []v1alpha1.NicDeviceStatus{}
+	if d.reportedDevices != nil {
+		for _, device := range d.reportedDevices {
+			devices = append(devices, device.Status)
+		}
+		return devices, nil
+	}
+
+	list := &v1alpha1.NicDeviceList{}
+	err := d.Client.List(ctx, list, &client.ListOptions{FieldSelector: fields.OneTermEqualSelector("status.node", d.nodeName)})
+	if err != nil {
+		log.Log.Error(err, "failed to list NicDevice CRs")
+		return nil, err
+	}
+	for _, device := range list.Items {
+		devices = append(devices, device.Status)
+	}
+	return devices, nil
+}
+
+// previousDevice returns the previously discovered device with any of the ports
+// the devices identified by their flash are skipped unless includeFlash is set, their identity doesn't depend on the VPD
+func previousDevice(previousDevices []v1alpha1.NicDeviceStatus, ports []v1alpha1.NicDevicePortSpec,
+	includeFlash bool) (v1alpha1.NicDeviceStatus, bool) {
+	for _, device := range previousDevices {
+		if device.IdentitySource == consts.IdentitySourceFlash && !includeFlash {
+			continue
+		}
+		if sharePorts(device.Ports, ports) {
+			return device, true
+		}
+	}
+	return v1alpha1.NicDeviceStatus{}, false
+}
+
+// sharePorts returns true if any of the ports has the same PCI address as one of the other ports
+func sharePorts(ports []v1alpha1.NicDevicePortSpec, otherPorts []v1alpha1.NicDevicePortSpec) bool {
+	for _, port := range ports {
+		if slices.ContainsFunc(otherPorts, func(other v1alpha1.NicDevicePortSpec) bool { return other.PCI == port.PCI }) {
+			return true
+		}
+	}
+	return false
+}
+
+// portsObserved returns true if any of the ports belongs to one of the observed devices
+func portsObserved(observedDevices map[string]v1alpha1.NicDeviceStatus, ports []v1alpha1.NicDevicePortSpec) bool {
+	for _, observed := range observedDevices {
+		if sharePorts(observed.Ports, ports) {
+			return true
+		}
+	}
+	return false
+}
+
+// reidentifiedDevice returns the key and the observed device identified by its VPD that has any of the ports
+// of a NicDevice CR identified by its flash, the devices that have a CR with their serial number are skipped
+func reidentifiedDevice(observedDevices map[string]v1alpha1.NicNodeReportDevice, crSerialNumbers map[string]bool,
+	ports []v1alpha1.NicDevicePortSpec) (string, v1alpha1.NicNodeReportDevice, bool) {
+	for serialNumber, observed := range observedDevices {
+		if observed.Status.IdentitySource == "" && !crSerialNumbers[serialNumber] && sharePorts(observed.Status.Ports, ports) {
+			return serialNumber, observed, true
+		}
+	}
+	return "", v1alpha1.NicNodeReportDevice{}, false
 }
 
 // reconcile reconciles the devices on the host by comparing the observed devices with the existing NicDevice custom resources (CRs).
 // It deletes CRs that do not represent observed devices, updates the CRs if the status of the device changes,
 // and creates new CRs for devices that do not have a CR representation.
+// With batch discovery, the observed devices are published in the node's NicNodeReport instead,
+// the operator fans the report out into the NicDevice CRs.
 func (d *DeviceDiscovery) reconcile(ctx context.Context) error {
-	observedDevices, err := d.hostManager.DiscoverNicDevices()
+	node := &v1.Node{}
+	err := d.Client.Get(ctx, types.NamespacedName{Name: d.nodeName}, node)
 	if err != nil {
+		log.Log.Error(err, "failed to get node object")
 		return err
 	}
 
-	list := &v1alpha1.NicDeviceList{}
+	ignoredPCIAddresses := d.getIgnoredPCIAddresses(node)
+	err = d.reportIgnoredPCIAddresses(ctx, node, ignoredPCIAddresses)
+	if err != nil {
+		log.Log.Error(err, "failed to report ignored PCI addresses")
+		return err
+	}
 
-	selectorFields := fields.OneTermEqualSelector("status.node", d.nodeName)
+	environment := d.hostManager.DiscoverEnvironment(ignoredPCIAddresses)
+	err = d.reportEnvironment(ctx, node, environment)
+	if err != nil {
+		log.Log.Error(err, "failed to report the environment of the node")
+		return err
+	}
 
-	err = d.Client.List(ctx, list, &client.ListOptions{FieldSelector: selectorFields})
+	observedDevices, err := d.hostManager.DiscoverNicDevices(ignoredPCIAddresses)
+	if err != nil {
+		return err
+	}
+	err = d.keepPreviousIdentities(ctx, observedDevices)
 	if err != nil {
-		log.Log.Error(err, "failed to list NicDevice CRs")
 		return err
 	}
 
-	log.Log.V(2).Info("listed devices", "devices", list.Items)
+	d.reportDiscoveredDevices(ctx, observedDevices)
+
+	err = d.keepAbsentDevices(ctx, observedDevices)
+	if err != nil {
+		return err
+	}
+
+	// OFED version is only needed to check the recommended firmware of the observed devices
+	ofedVersion := ""
+	if len(observedDevices) != 0 {
+		ofedVersion = d.hostManager.DiscoverOfedVersion()
+	}
+
+	reportedDevices := map[string]v1alpha1.NicNodeReportDevice{}
+	for serialNumber, deviceStatus := range observedDevices {
+		deviceStatus.Node = d.nodeName
+		// The threshold is published with the temperature, so that the operator can compute the condition of the batch reports
+		if deviceStatus.Health != nil {
+			deviceStatus.Health.TemperatureWarningThreshold = d.TemperatureWarningThreshold
+			deviceStatus.Health.Temperature = stableTemperature(deviceStatus.Health, d.reportedDevices[serialNumber].Status.Health)
+		}
+		if d.DiscoverVfs && !d.absentDevices[serialNumber] {
+			d.hostManager.DiscoverVirtualFunctions(&deviceStatus)
+		}
+		reportedDevices[serialNumber] = v1alpha1.NicNodeReportDevice{
+			Status:                     deviceStatus,
+			RecommendedFirmwareVersion: helper.GetRecommendedFwVersion(deviceStatus.Type, ofedVersion),
+		}
+	}
+
+	d.reportedDevices = reportedDevices
+
+	return d.publish(ctx, node, reportedDevices)
+}
+
+// publish writes the observed devices to the NicDevice CRs or, with batch discovery, to the node's NicNodeReport
+func (d *DeviceDiscovery) publish(ctx context.Context, node *v1.Node, observedDevices map[string]v1alpha1.NicNodeReportDevice) error {
+	if d.BatchDiscovery {
+		return d.reportNode(ctx, node, observedDevices)
+	}
+
+	return syncNicDevices(ctx, d.Client, node, d.namespace, observedDevices)
+}
+
+// refreshLinks refreshes the links of the ports of the devices found by the previous discovery and publishes the changes
+// the rest of the devices' status is taken from their current CRs until the next discovery
+func (d *DeviceDiscovery) refreshLinks(ctx context.Context) error {
+	if len(d.reportedDevices) == 0 {
+		return nil
+	}
 
 	node := &v1.Node{}
-	err = d.Client.Get(ctx, types.NamespacedName{Name: d.nodeName}, node)
+	err := d.Client.Get(ctx, types.NamespacedName{Name: d.nodeName}, node)
 	if err != nil {
 		log.Log.Error(err, "failed to get node object")
 		return err
 	}
 
+	list := &v1alpha1.NicDeviceList{}
+	err = d.Client.List(ctx, list, &client.ListOptions{FieldSelector: fields.OneTermEqualSelector("status.node", d.nodeName)})
+	if err != nil {
+		log.Log.Error(err, "failed to list NicDevice CRs")
+		return err
+	}
+	currentStatuses := map[string]v1alpha1.NicDeviceStatus{}
+	for _, nicDeviceCR := range list.Items {
+		currentStatuses[nicDeviceCR.Status.SerialNumber] = nicDeviceCR.Status
+	}
+
+	for serialNumber, device := range d.reportedDevices {
+		// The ports of the missing devices keep their links until the devices are removed
+		if d.absentDevices[serialNumber] {
+			continue
+		}
+		// The reconciler updates the CRs in between the discoveries, e.g. the interface names of the ports,
+		// the snapshot of the previous discovery would revert these changes
+		currentStatus, found := currentStatuses[serialNumber]
+		if found {
+			device.Status = discoveredStatus(device.Status, currentStatus)
+		} else {
+			device.Status = *device.Status.DeepCopy()
+		}
+		d.hostManager.RefreshPortLinks(&device.Status)
+		d.reportedDevices[serialNumber] = device
+	}
+
+	return d.publish(ctx, node, d.reportedDevices)
+}
+
+// syncNicDevices reconciles the NicDevice CRs of the node with the observed devices, keyed by serial number.
+// It deletes CRs that do not represent observed devices, updates the CRs if the status of the device changes,
+// and creates new CRs for devices that do not have a CR representation.
+// Each CR is written at most once, the CRs that fail to be written don't stop the sync of the others,
+// their errors are returned together
+func syncNicDevices(ctx context.Context, c client.Client, node *v1.Node, namespace string, observedDevices map[string]v1alpha1.NicNodeReportDevice) error {
+	list := &v1alpha1.NicDeviceList{}
+
+	selectorFields := fields.OneTermEqualSelector("status.node", node.Name)
+
+	err := c.List(ctx, list, &client.ListOptions{FieldSelector: selectorFields})
+	if err != nil {
+		log.Log.Error(err, "failed to list NicDevice CRs")
+		return err
+	}
+
+	log.Log.V(2).Info("listed devices", "devices", list.Items)
+
+	// Devices are processed once, even if several CRs have the same serial number
+	remainingDevices := maps.Clone(observedDevices)
+	errs := []error{}
+
+	crSerialNumbers := map[string]bool{}
 	for _, nicDeviceCR := range list.Items {
-		observedDeviceStatus, exists := observedDevices[nicDeviceCR.Status.SerialNumber]
+		crSerialNumbers[nicDeviceCR.Status.SerialNumber] = true
+	}
+
+	for _, nicDeviceCR := range list.Items {
+		serialNumber := nicDeviceCR.Status.SerialNumber
+		observedDevice, exists := remainingDevices[serialNumber]
+		if !exists && nicDeviceCR.Status.IdentitySource == consts.IdentitySourceFlash {
+			// The VPD of the device identified by its flash was read, its CR is kept and takes the serial number of the VPD
+			serialNumber, observedDevice, exists = reidentifiedDevice(remainingDevices, crSerialNumbers, nicDeviceCR.Status.Ports)
+			if exists {
+				log.Log.Info("device identified by its flash was identified by its VPD", "device", nicDeviceCR.Name, "serialNumber", serialNumber)
+			}
+		}
 
 		if !exists {
 			log.Log.V(2).Info("device doesn't exist on the node anymore, deleting", "device", nicDeviceCR.Name)
 			// Need to delete this CR, it doesn't represent the observedDevice on host anymore
-			err = d.Client.Delete(ctx, &nicDeviceCR)
-			if err != nil {
+			err = c.Delete(ctx, &nicDeviceCR)
+			if client.IgnoreNotFound(err) != nil {
 				log.Log.Error(err, "failed  to delete NicDevice CR", "device", nicDeviceCR.Name)
+				errs = append(errs, fmt.Errorf("failed to delete NicDevice CR %s: %w", nicDeviceCR.Name, err))
 			}
 
 			continue
 		}
 
-		ofedVersion := d.hostManager.DiscoverOfedVersion()
-		recommendedFirmware := helper.GetRecommendedFwVersion(nicDeviceCR.Status.Type, ofedVersion)
-		setFwConfigConditionsForDevice(&nicDeviceCR, recommendedFirmware)
-
-		err = d.Client.Status().Update(ctx, &nicDeviceCR)
-		if err != nil {
-			log.Log.Error(err, "failed to update NicDevice CR status", "device", nicDeviceCR.Name)
-			continue
-		}
-
-		// Need to nullify conditions for deep equal
-		observedDeviceStatus.Conditions = nicDeviceCR.Status.Conditions
+		observedDeviceStatus := discoveredStatus(nicDeviceCR.Status, observedDevice.Status)
+		setFwConfigConditions(&observedDeviceStatus, observedDevice.RecommendedFirmwareVersion)
+		setHealthConditions(&observedDeviceStatus)
+		setIdentityConditions(&observedDeviceStatus)
 
 		if !reflect.DeepEqual(nicDeviceCR.Status, observedDeviceStatus) {
 			log.Log.V(2).Info("device status changed, updating", "device", nicDeviceCR.Name, "crStatus", nicDeviceCR.Status, "observedStatus", observedDeviceStatus)
 			// Status of the device changes, need to update the CR
 			nicDeviceCR.Status = observedDeviceStatus
 
-			err := d.Client.Status().Update(ctx, &nicDeviceCR)
+			err := c.Status().Update(ctx, &nicDeviceCR)
 			if err != nil {
 				log.Log.Error(err, "failed to update NicDevice CR status", "device", nicDeviceCR.Name)
+				errs = append(errs, fmt.Errorf("failed to update status of NicDevice CR %s: %w", nicDeviceCR.Name, err))
 			}
 		}
 
 		// Device was processed, cleaning it from the map
-		delete(observedDevices, nicDeviceCR.Status.SerialNumber)
+		delete(remainingDevices, serialNumber)
 	}
 
 	// Remaining devices don't have CR representation, need to create a CR
-	for _, deviceStatus := range observedDevices {
-		deviceName := d.getCRName(deviceStatus.Type, deviceStatus.SerialNumber)
+	for _, observedDevice := range remainingDevices {
+		deviceName := nicDeviceName(node.Name, observedDevice.Status.Type, observedDevice.Status.SerialNumber)
 		device := &v1alpha1.NicDevice{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:      deviceName,
-				Namespace: d.namespace,
+				Namespace: namespace,
 			},
 		}
 
-		err := controllerutil.SetOwnerReference(node, device, d.Client.Scheme())
+		err := controllerutil.SetOwnerReference(node, device, c.Scheme())
 		if err != nil {
 			log.Log.Error(err, "failed to set owner reference for device", "device", device)
+			errs = append(errs, fmt.Errorf("failed to set owner reference of NicDevice CR %s: %w", device.Name, err))
 			continue
 		}
-		err = d.Client.Create(ctx, device)
-		if err != nil {
-			log.Log.Error(err, "failed to create device", "device", device)
-			continue
-		}
-
+		err = c.Create(ctx, device)
 		if apierrors.IsAlreadyExists(err) {
 			// Device already exists but was not matched by SerialNumber, which means the status was not applied properly
-			err = d.Client.Get(ctx, types.NamespacedName{Name: device.Name, Namespace: device.Namespace}, device)
+			err = c.Get(ctx, types.NamespacedName{Name: device.Name, Namespace: device.Namespace}, device)
 			if err != nil {
 				log.Log.Error(err, "failed to get NicDevice obj", "device", device)
+				errs = append(errs, fmt.Errorf("failed to get NicDevice CR %s: %w", device.Name, err))
 				continue
 			}
 		} else if err != nil {
 			log.Log.Error(err, "failed to create NicDevice obj", "device", device)
+			errs = append(errs, fmt.Errorf("failed to create NicDevice CR %s: %w", device.Name, err))
 			continue
 		}
 
-		device.Status = deviceStatus
-		device.Status.Node = d.nodeName
+		// The status of a CR that already exists is kept, except for the discovered fields
+		device.Status = discoveredStatus(device.Status, observedDevice.Status)
+		device.Status.Node = node.Name
 		setInitialsConditionsForDevice(device)
+		setFwConfigConditionsForDevice(device, observedDevice.RecommendedFirmwareVersion)
+		setHealthConditions(&device.Status)
+		setIdentityConditions(&device.Status)
+
+		err = c.Status().Update(ctx, device)
+		if err != nil {
+			log.Log.Error(err, "failed to update NicDevice CR status", "device", device.Name)
+			errs = append(errs, fmt.Errorf("failed to update status of NicDevice CR %s: %w", device.Name, err))
+			continue
+		}
 	}
-	return nil
+	return errors.Join(errs...)
+}
+
+// discoveredStatus returns the status of the device CR with the discovered fields (the identity, firmware, ports, PCIe link,
+// VFs, configuration modes and health of the device) replaced by the observed ones, the rest of the status,
+// e.g. the conditions, nv config parameters, firmware update progress, the operation phase and the partially applied runtime config,
+// is owned by the device reconciler
+func discoveredStatus(crStatus v1alpha1.NicDeviceStatus, observed v1alpha1.NicDeviceStatus) v1alpha1.NicDeviceStatus {
+	status := *crStatus.DeepCopy()
+	observed = *observed.DeepCopy()
+
+	status.Node = observed.Node
+	status.Type = observed.Type
+	status.SerialNumber = observed.SerialNumber
+	status.PartNumber = observed.PartNumber
+	status.IdentitySource = observed.IdentitySource
+	status.PSID = observed.PSID
+	status.FirmwareVersion = observed.FirmwareVersion
+	status.FirmwareSecurity = observed.FirmwareSecurity
+	status.Ports = observed.Ports
+	status.PciLink = observed.PciLink
+	status.VirtualFunctions = observed.VirtualFunctions
+	status.ConfigurationMode = observed.ConfigurationMode
+	status.BlueFieldMode = observed.BlueFieldMode
+	status.Health = observed.Health
+
+	return status
+}
+
+// reportNode publishes the observed devices in the node's NicNodeReport with a single write, creating the report if needed
+// the report is not written if the observed devices didn't change
+func (d *DeviceDiscovery) reportNode(ctx context.Context, node *v1.Node, observedDevices map[string]v1alpha1.NicNodeReportDevice) error {
+	devices := make([]v1alpha1.NicNodeReportDevice, 0, len(observedDevices))
+	for _, device := range observedDevices {
+		devices = append(devices, device)
+	}
+	slices.SortFunc(devices, func(a, b v1alpha1.NicNodeReportDevice) int {
+		return strings.Compare(a.Status.SerialNumber, b.Status.SerialNumber)
+	})
+	spec := v1alpha1.NicNodeReportSpec{Node: d.nodeName, Devices: devices}
+
+	report := &v1alpha1.NicNodeReport{}
+	err := d.Client.Get(ctx, types.NamespacedName{Name: d.nodeName, Namespace: d.namespace}, report)
+	if apierrors.IsNotFound(err) {
+		report = &v1alpha1.NicNodeReport{
+			ObjectMeta: metav1.ObjectMeta{Name: d.nodeName, Namespace: d.namespace},
+			Spec:       spec,
+		}
+		err = controllerutil.SetOwnerReference(node, report, d.Client.Scheme())
+		if err != nil {
+			log.Log.Error(err, "failed to set owner reference for node report")
+			return err
+		}
+
+		log.Log.Info("creating node report", "node", d.nodeName, "devices", len(devices))
+		return d.Client.Create(ctx, report)
+	}
+	if err != nil {
+		log.Log.Error(err, "failed to get node report")
+		return err
+	}
+
+	// Empty and nil device lists are equal after a round trip through the API server
+	if len(report.Spec.Devices) == 0 && len(spec.Devices) == 0 {
+		spec.Devices = report.Spec.Devices
+	}
+	if reflect.DeepEqual(report.Spec, spec) {
+		return nil
+	}
+
+	log.Log.V(2).Info("observed devices changed, updating node report", "node", d.nodeName)
+	report.Spec = spec
+	return d.Client.Update(ctx, report)
+}
+
+// getIgnoredPCIAddresses merges the ignored PCI addresses from the operator config and the node's annotation
+// returns a sorted list without duplicates
+func (d *DeviceDiscovery) getIgnoredPCIAddresses(node *v1.Node) []string {
+	ignoredPCIAddresses := []string{}
+	addAddress := func(address string) {
+		address = strings.ToLower(strings.TrimSpace(address))
+		if address != "" && !slices.Contains(ignoredPCIAddresses, address) {
+			ignoredPCIAddresses = append(ignoredPCIAddresses, address)
+		}
+	}
+
+	for _, address := range d.IgnoredPCIAddresses {
+		addAddress(address)
+	}
+	if annotation, found := node.Annotations[consts.IgnorePCIAddressesAnnotation]; found {
+		for _, address := range strings.Split(annotation, ",") {
+			addAddress(address)
+		}
+	}
+
+	slices.Sort(ignoredPCIAddresses)
+	return ignoredPCIAddresses
+}
+
+// reportIgnoredPCIAddresses publishes the effective list of ignored PCI addresses in the node's consts.IgnoredPCIAddressesAnnotation
+func (d *DeviceDiscovery) reportIgnoredPCIAddresses(ctx context.Context, node *v1.Node, ignoredPCIAddresses []string) error {
+	reported, found := node.Annotations[consts.IgnoredPCIAddressesAnnotation]
+	desired := strings.Join(ignoredPCIAddresses, ",")
+	if reported == desired && found == (desired != "") {
+		return nil
+	}
+
+	patch := client.MergeFrom(node.DeepCopy())
+	if desired == "" {
+		delete(node.Annotations, consts.IgnoredPCIAddressesAnnotation)
+	} else {
+		if node.Annotations == nil {
+			node.Annotations = map[string]string{}
+		}
+		node.Annotations[consts.IgnoredPCIAddressesAnnotation] = desired
+	}
+
+	log.Log.Info("updating ignored PCI addresses on the node", "node", node.Name, "ignoredPCIAddresses", desired)
+	return d.Client.Patch(ctx, node, patch)
+}
+
+// reportEnvironment publishes the detected environment of the node in its NicNodeState, creating the state if needed
+// the state is not written if the environment didn't change
+func (d *DeviceDiscovery) reportEnvironment(ctx context.Context, node *v1.Node, environment v1alpha1.NodeEnvironment) error {
+	state := &v1alpha1.NicNodeState{}
+	err := d.Client.Get(ctx, types.NamespacedName{Name: d.nodeName, Namespace: d.namespace}, state)
+	if apierrors.IsNotFound(err) {
+		state = &v1alpha1.NicNodeState{ObjectMeta: metav1.ObjectMeta{Name: d.nodeName, Namespace: d.namespace}}
+		err = controllerutil.SetOwnerReference(node, state, d.Client.Scheme())
+		if err != nil {
+			log.Log.Error(err, "failed to set owner reference for node state")
+			return err
+		}
+
+		log.Log.Info("creating node state", "node", d.nodeName)
+		err = d.Client.Create(ctx, state)
+		if err != nil {
+			log.Log.Error(err, "failed to create node state", "node", d.nodeName)
+			return err
+		}
+	} else if err != nil {
+		log.Log.Error(err, "failed to get node state", "node", d.nodeName)
+		return err
+	}
+
+	if reflect.DeepEqual(state.Status.Environment, &environment) {
+		return nil
+	}
+
+	log.Log.Info("updating environment of the node", "node", d.nodeName, "type", environment.Type,
+		"hypervisor", environment.Hypervisor, "mode", environment.Mode)
+	state.Status.Node = d.nodeName
+	state.Status.Environment = &environment
+	return d.Client.Status().Update(ctx, state)
 }
 
 // Start starts the device discovery process by reconciling devices on the host.
 //
 // It triggers the first reconciliation manually and then runs it periodically based on the
-// deviceDiscoveryReconcileTime interval until the context is done.
+// Interval, deviceDiscoveryReconcileTime by default, until the context is done.
+// The rescan requests, e.g. from the consts.RescanAnnotation, trigger the reconciliation right away.
+// The uevents of the NVIDIA PCI devices trigger the reconciliation once they settle for deviceHotplugSettleTime.
+// The links of the discovered ports are refreshed every linkStateRefreshTime in between the reconciliations.
 func (d *DeviceDiscovery) Start(ctx context.Context) error {
 	log.Log.Info("Device discovery started")
 
-	t := time.NewTicker(deviceDiscoveryReconcileTime)
+	interval := d.Interval
+	if interval <= 0 {
+		interval = deviceDiscoveryReconcileTime
+	}
+	t := time.NewTicker(interval)
 	defer t.Stop()
 
-	retryChan := make(chan struct{}, 1) // Channel to trigger immediate retries
+	links := time.NewTicker(linkStateRefreshTime)
+	defer links.Stop()
+
+	var uevents chan host.UEvent
+	if d.subscribe != nil {
+		uevents = make(chan host.UEvent)
+		done := make(chan struct{})
+		defer close(done)
+
+		err := d.subscribe(uevents, done)
+		if err != nil {
+			// Hot-plugged devices are still discovered by the periodic reconciliation
+			log.Log.Error(err, "failed to subscribe to uevents, devices are only discovered periodically")
+			uevents = nil
+		}
+	}
+	var settled <-chan time.Time
+
+	var retry <-chan time.Time
 
 	runReconcile := func() {
+		if d.resetInProgress != nil && d.resetInProgress() {
+			// The ports of the device under reset are missing from the host, the discovery is retried once they settle
+			log.Log.Info("device reset in progress, postponing the discovery of the devices")
+			settled = time.After(deviceHotplugSettleTime)
+			return
+		}
 		err := d.reconcile(ctx)
 		if err != nil {
 			log.Log.Error(err, "failed to run reconcile, requeueing")
-			// Retry the request if there's an error
-			retryChan <- struct{}{}
+			// Retry the request if there's an error, the CRs that failed to be written keep failing on an immediate retry
+			retry = time.After(deviceDiscoveryRetryTime)
 		}
 	}
 
@@ -235,7 +858,32 @@ OUTER:
 			break OUTER
 		case <-t.C:
 			runReconcile()
-		case <-retryChan:
+		case <-retry:
+			retry = nil
+			runReconcile()
+		case <-d.rescan:
+			log.Log.Info("rescan requested, discovering devices")
+			runReconcile()
+		case <-links.C:
+			err := d.refreshLinks(ctx)
+			if err != nil {
+				// The links are refreshed again on the next tick
+				log.Log.Error(err, "failed to refresh the links of the devices")
+			}
+		case event, ok := <-uevents:
+			if !ok {
+				log.Log.Info("uevents subscription closed, devices are only discovered periodically")
+				uevents = nil
+				continue
+			}
+			if !d.devicesChanged(event) {
+				continue
+			}
+			log.Log.V(2).Info("NVIDIA PCI device changed", "action", event.Action, "pciAddress", event.PciAddress())
+			settled = time.After(deviceHotplugSettleTime)
+		case <-settled:
+			settled = nil
+			log.Log.Info("NVIDIA PCI devices changed on the host, discovering devices")
 			runReconcile()
 		}
 	}
@@ -243,12 +891,67 @@ OUTER:
 	return nil
 }
 
-// NewDeviceRegistry creates a new instance of DeviceDiscovery with the specified parameters.
+// devicesChanged returns true if the uevent can change the discovered devices: a port of a discovered device changing
+// or an NVIDIA PF appearing on the host, the uevents of the VFs, e.g. created with NUM_OF_VFS or bound to the workloads' drivers, are ignored
+func (d *DeviceDiscovery) devicesChanged(event host.UEvent) bool {
+	if !event.NvidiaPciDeviceChanged() {
+		return false
+	}
+	for _, device := range d.reportedDevices {
+		if slices.ContainsFunc(device.Status.Ports, func(port v1alpha1.NicDevicePortSpec) bool { return port.PCI == event.PciAddress() }) {
+			return true
+		}
+	}
+
+	switch event.Action {
+	case host.UEventActionRemove, host.UEventActionUnbind:
+		// Only the ports of the discovered devices can disappear from them, the VFs aren't discovered as ports
+		return false
+	default:
+		return !event.IsVirtualFunction()
+	}
+}
+
+// reportDiscoveredDevices emits the lifecycle event of each device not found by the previous discovery,
+// all devices of the host are reported after the config daemon starts
+func (d *DeviceDiscovery) reportDiscoveredDevices(ctx context.Context, observedDevices map[string]v1alpha1.NicDeviceStatus) {
+	if d.LifecycleEvents == nil {
+		return
+	}
+
+	discovered := map[string]bool{}
+	for serialNumber, status := range observedDevices {
+		discovered[serialNumber] = true
+		if d.discoveredDevices[serialNumber] {
+			continue
+		}
+
+		pciAddresses := []string{}
+		for _, port := range status.Ports {
+			pciAddresses = append(pciAddresses, port.PCI)
+		}
+		device := &v1alpha1.NicDevice{
+			ObjectMeta: metav1.ObjectMeta{Name: d.getCRName(status.Type, serialNumber), Namespace: d.namespace},
+			Status:     status,
+		}
+		d.LifecycleEvents.Emit(ctx, lifecycle.DeviceDiscoveredEvent, device, "",
+			fmt.Sprintf("device found at %s", strings.Join(pciAddresses, ", ")))
+	}
+	d.discoveredDevices = discovered
+}
+
+// NewDeviceRegistry creates a new instance of DeviceDiscovery with the specified parameters, subscribed to the host's uevents
 func NewDeviceRegistry(client client.Client, hostManager host.HostManager, node string, namespace string) *DeviceDiscovery {
 	return &DeviceDiscovery{
 		Client:      client,
 		hostManager: hostManager,
 		nodeName:    node,
 		namespace:   namespace,
+		subscribe:   host.SubscribeUEvents,
+		rescan:      make(chan struct{}, 1),
+
+		resetInProgress: host.DeviceResetInProgress,
+
+		TemperatureWarningThreshold: consts.DefaultTemperatureWarningThreshold,
 	}
 }
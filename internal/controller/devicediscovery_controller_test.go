@@ -17,22 +17,30 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/mock"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/rand"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/host"
 	"github.com/Mellanox/nic-configuration-operator/pkg/host/mocks"
 )
 
@@ -42,6 +50,7 @@ var _ = Describe("DeviceDiscovery", func() {
 		k8sClient      client.Client
 		deviceRegistry *DeviceDiscovery
 		hostManager    *mocks.HostManager
+		environment    v1alpha1.NodeEnvironment
 		nodeName       = "test-node"
 		ctx            context.Context
 		cancel         context.CancelFunc
@@ -86,6 +95,10 @@ var _ = Describe("DeviceDiscovery", func() {
 
 		deviceDiscoveryReconcileTime = 1 * time.Second
 		hostManager = &mocks.HostManager{}
+		environment = v1alpha1.NodeEnvironment{Type: consts.NodeEnvironmentBareMetal, Mode: consts.ConfigurationModeFull}
+		hostManager.On("DiscoverEnvironment", mock.Anything).Return(func([]string) v1alpha1.NodeEnvironment {
+			return environment
+		})
 
 		deviceRegistry = NewDeviceRegistry(k8sClient, hostManager, nodeName, namespaceName)
 		Expect(mgr.Add(deviceRegistry)).To(Succeed())
@@ -93,6 +106,8 @@ var _ = Describe("DeviceDiscovery", func() {
 
 	AfterEach(func() {
 		Expect(k8sClient.DeleteAllOf(ctx, &v1alpha1.NicDevice{}, client.InNamespace(namespaceName))).To(Succeed())
+		Expect(k8sClient.DeleteAllOf(ctx, &v1alpha1.NicNodeReport{}, client.InNamespace(namespaceName))).To(Succeed())
+		Expect(k8sClient.DeleteAllOf(ctx, &v1alpha1.NicNodeState{}, client.InNamespace(namespaceName))).To(Succeed())
 		Expect(k8sClient.Delete(ctx, &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespaceName}})).To(Succeed())
 		Expect(k8sClient.DeleteAllOf(ctx, &v1.Node{})).To(Succeed())
 		cancel()
@@ -127,7 +142,165 @@ var _ = Describe("DeviceDiscovery", func() {
 		})
 	})
 
+	Describe("setHealthConditions", func() {
+		It("should report the overheating device", func() {
+			status := &v1alpha1.NicDeviceStatus{Health: &v1alpha1.DeviceHealthStatus{
+				Temperature:                 ptr.To(107),
+				TemperatureWarningThreshold: 105,
+				Reporters:                   []v1alpha1.HealthReporterStatus{{Name: "fw_fatal", State: consts.HealthReporterStateError, ErrorCount: 1}},
+			}}
+			setHealthConditions(status)
+
+			condition := meta.FindStatusCondition(status.Conditions, consts.HealthWarningCondition)
+			Expect(condition).ToNot(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal(consts.OverheatingReason))
+			Expect(condition.Message).To(Equal("Device temperature 107C reached the warning threshold 105C; " +
+				"Health reporters in the error state: fw_fatal"))
+		})
+		It("should report the failed health reporters", func() {
+			status := &v1alpha1.NicDeviceStatus{Health: &v1alpha1.DeviceHealthStatus{
+				Temperature:                 ptr.To(60),
+				TemperatureWarningThreshold: 105,
+				Reporters: []v1alpha1.HealthReporterStatus{
+					{Name: "fw", State: "healthy"},
+					{Name: "tx", State: consts.HealthReporterStateError, ErrorCount: 3},
+				},
+			}}
+			setHealthConditions(status)
+
+			condition := meta.FindStatusCondition(status.Conditions, consts.HealthWarningCondition)
+			Expect(condition).ToNot(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal(consts.HealthReporterErrorReason))
+			Expect(condition.Message).To(Equal("Health reporters in the error state: tx"))
+		})
+		It("should list the failed reporters of the same name reported by several ports once", func() {
+			status := &v1alpha1.NicDeviceStatus{Health: &v1alpha1.DeviceHealthStatus{
+				Reporters: []v1alpha1.HealthReporterStatus{
+					{Name: "tx", PCI: "0000:3b:00.0", State: consts.HealthReporterStateError, ErrorCount: 3},
+					{Name: "tx", PCI: "0000:3b:00.1", State: consts.HealthReporterStateError, ErrorCount: 1},
+				},
+			}}
+			setHealthConditions(status)
+
+			condition := meta.FindStatusCondition(status.Conditions, consts.HealthWarningCondition)
+			Expect(condition).ToNot(BeNil())
+			Expect(condition.Message).To(Equal("Health reporters in the error state: tx"))
+		})
+		It("should not warn about the temperature if the threshold is disabled", func() {
+			status := &v1alpha1.NicDeviceStatus{Health: &v1alpha1.DeviceHealthStatus{Temperature: ptr.To(120)}}
+			setHealthConditions(status)
+
+			condition := meta.FindStatusCondition(status.Conditions, consts.HealthWarningCondition)
+			Expect(condition).ToNot(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(consts.DeviceHealthyReason))
+		})
+		It("should remove the condition if the health isn't reported", func() {
+			status := &v1alpha1.NicDeviceStatus{Conditions: []metav1.Condition{{
+				Type: consts.HealthWarningCondition, Status: metav1.ConditionTrue, Reason: consts.OverheatingReason,
+			}}}
+			setHealthConditions(status)
+
+			Expect(meta.FindStatusCondition(status.Conditions, consts.HealthWarningCondition)).To(BeNil())
+		})
+	})
+
+	Describe("stableTemperature", func() {
+		health := func(temperature int) *v1alpha1.DeviceHealthStatus {
+			return &v1alpha1.DeviceHealthStatus{Temperature: ptr.To(temperature), TemperatureWarningThreshold: 105}
+		}
+
+		It("should keep the previous temperature for the small changes", func() {
+			Expect(stableTemperature(health(62), health(60))).To(Equal(ptr.To(60)))
+			Expect(stableTemperature(health(58), health(60))).To(Equal(ptr.To(60)))
+		})
+		It("should publish the temperature that changed by the hysteresis", func() {
+			Expect(stableTemperature(health(63), health(60))).To(Equal(ptr.To(63)))
+			Expect(stableTemperature(health(57), health(60))).To(Equal(ptr.To(57)))
+			Expect(stableTemperature(health(60), nil)).To(Equal(ptr.To(60)))
+			Expect(stableTemperature(&v1alpha1.DeviceHealthStatus{}, health(60))).To(BeNil())
+		})
+		It("should publish the temperature that crossed the warning threshold", func() {
+			Expect(stableTemperature(health(105), health(104))).To(Equal(ptr.To(105)))
+			Expect(stableTemperature(health(104), health(105))).To(Equal(ptr.To(104)))
+		})
+	})
+
+	Describe("setIdentityConditions", func() {
+		It("should report the device identified by its flash as degraded", func() {
+			status := &v1alpha1.NicDeviceStatus{IdentitySource: consts.IdentitySourceFlash}
+			setIdentityConditions(status)
+
+			condition := meta.FindStatusCondition(status.Conditions, consts.DegradedCondition)
+			Expect(condition).ToNot(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal(consts.VpdReadFailedReason))
+			Expect(condition.Message).To(Equal("Device VPD couldn't be read, the serial number is derived from the base GUID of its flash"))
+		})
+		It("should remove the condition once the VPD is read", func() {
+			status := &v1alpha1.NicDeviceStatus{Conditions: []metav1.Condition{{
+				Type: consts.DegradedCondition, Status: metav1.ConditionTrue, Reason: consts.VpdReadFailedReason,
+			}}}
+			setIdentityConditions(status)
+
+			Expect(meta.FindStatusCondition(status.Conditions, consts.DegradedCondition)).To(BeNil())
+		})
+	})
+
+	Describe("getIgnoredPCIAddresses", func() {
+		It("should merge addresses from the config and the node annotation", func() {
+			deviceRegistry.IgnoredPCIAddresses = []string{"0000:D8:00.0", "0000:3b:00.0"}
+			node := &v1.Node{ObjectMeta: metav1.ObjectMeta{
+				Name:        nodeName,
+				Annotations: map[string]string{consts.IgnorePCIAddressesAnnotation: "0000:3b:00.0, 0000:af:00.1,"},
+			}}
+
+			Expect(deviceRegistry.getIgnoredPCIAddresses(node)).To(Equal([]string{"0000:3b:00.0", "0000:af:00.1", "0000:d8:00.0"}))
+		})
+	})
+
 	Describe("reconcile", func() {
+		Context("when the node has ignored PCI addresses", func() {
+			It("should pass them to discovery and report them on the node", func() {
+				node := &v1.Node{}
+				Expect(k8sClient.Get(ctx, client.ObjectKey{Name: nodeName}, node)).To(Succeed())
+				node.Annotations = map[string]string{consts.IgnorePCIAddressesAnnotation: "0000:3b:00.0"}
+				Expect(k8sClient.Update(ctx, node)).To(Succeed())
+
+				hostManager.On("DiscoverNicDevices", []string{"0000:3b:00.0"}).Return(map[string]v1alpha1.NicDeviceStatus{}, nil)
+
+				startManager()
+
+				Eventually(func() (string, error) {
+					node := &v1.Node{}
+					err := k8sClient.Get(ctx, client.ObjectKey{Name: nodeName}, node)
+					return node.Annotations[consts.IgnoredPCIAddressesAnnotation], err
+				}, timeout).Should(Equal("0000:3b:00.0"))
+			})
+		})
+
+		Context("when the node is a VM with passthrough VFs", func() {
+			It("should report the restricted environment in the node state", func() {
+				environment = v1alpha1.NodeEnvironment{
+					Type:             consts.NodeEnvironmentVirtualMachine,
+					Hypervisor:       "QEMU",
+					Mode:             consts.ConfigurationModeRestricted,
+					VirtualFunctions: []string{"0000:00:05.0"},
+				}
+				hostManager.On("DiscoverNicDevices", mock.Anything).Return(map[string]v1alpha1.NicDeviceStatus{}, nil)
+
+				startManager()
+
+				Eventually(func() (*v1alpha1.NodeEnvironment, error) {
+					state := &v1alpha1.NicNodeState{}
+					err := k8sClient.Get(ctx, client.ObjectKey{Name: nodeName, Namespace: namespaceName}, state)
+					return state.Status.Environment, err
+				}, timeout).Should(Equal(&environment))
+			})
+		})
+
 		Context("when there are existing NicDevice CRs", func() {
 			deviceType := "connectx6"
 			serialNumber := "123456"
@@ -155,7 +328,7 @@ var _ = Describe("DeviceDiscovery", func() {
 				partNumber := "test-part-number"
 				fwVersion := "test-fw-version"
 
-				hostManager.On("DiscoverNicDevices").Return(map[string]v1alpha1.NicDeviceStatus{
+				hostManager.On("DiscoverNicDevices", mock.Anything).Return(map[string]v1alpha1.NicDeviceStatus{
 					"123456": {
 						Node:            nodeName,
 						SerialNumber:    "123456",
@@ -198,11 +371,83 @@ var _ = Describe("DeviceDiscovery", func() {
 				}, timeout).Should(Equal(fwVersion))
 			})
 
-			It("should delete CRs if they do not represent observed devices", func() {
-				hostManager.On("DiscoverNicDevices").Return(map[string]v1alpha1.NicDeviceStatus{}, nil)
+			It("should report the health warning of the overheating device", func() {
+				deviceRegistry.TemperatureWarningThreshold = 90
+				hostManager.On("DiscoverNicDevices", mock.Anything).Return(map[string]v1alpha1.NicDeviceStatus{
+					"123456": {
+						Node:         nodeName,
+						SerialNumber: "123456",
+						Type:         "connectx6",
+						Ports:        []v1alpha1.NicDevicePortSpec{{PCI: "0000:3b:00.0"}},
+						Health:       &v1alpha1.DeviceHealthStatus{Temperature: ptr.To(95)},
+					},
+				}, nil)
+				hostManager.On("DiscoverOfedVersion").Return("00.00-0.0.0", nil)
 
 				startManager()
 
+				Eventually(func() (*metav1.Condition, error) {
+					device := &v1alpha1.NicDevice{}
+					err := k8sClient.Get(ctx, client.ObjectKey{Name: deviceName, Namespace: namespaceName}, device)
+					if err != nil {
+						return nil, err
+					}
+					return meta.FindStatusCondition(device.Status.Conditions, consts.HealthWarningCondition), nil
+				}, timeout).Should(And(
+					Not(BeNil()),
+					HaveField("Status", metav1.ConditionTrue),
+					HaveField("Reason", consts.OverheatingReason),
+				))
+
+				device := &v1alpha1.NicDevice{}
+				Expect(k8sClient.Get(ctx, client.ObjectKey{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+				Expect(device.Status.Health.TemperatureWarningThreshold).To(Equal(90))
+			})
+
+			It("should list the VFs of the devices if the VF discovery is enabled", func() {
+				deviceRegistry.DiscoverVfs = true
+				hostManager.On("DiscoverNicDevices", mock.Anything).Return(map[string]v1alpha1.NicDeviceStatus{
+					"123456": {
+						Node:         nodeName,
+						SerialNumber: "123456",
+						Type:         "connectx6",
+						Ports:        []v1alpha1.NicDevicePortSpec{{PCI: "0000:3b:00.0"}},
+					},
+				}, nil)
+				hostManager.On("DiscoverOfedVersion").Return("00.00-0.0.0", nil)
+				hostManager.On("DiscoverVirtualFunctions", mock.Anything).Run(func(args mock.Arguments) {
+					status := args.Get(0).(*v1alpha1.NicDeviceStatus)
+					status.VirtualFunctions = []v1alpha1.VirtualFunctionStatus{
+						{PCI: "0000:3b:00.2", PhysicalFunction: "0000:3b:00.0", Driver: "vfio-pci"},
+					}
+				}).Return()
+
+				startManager()
+
+				Eventually(func() ([]v1alpha1.VirtualFunctionStatus, error) {
+					device := &v1alpha1.NicDevice{}
+					err := k8sClient.Get(ctx, client.ObjectKey{Name: deviceName, Namespace: namespaceName}, device)
+					if err != nil {
+						return nil, err
+					}
+					return device.Status.VirtualFunctions, nil
+				}, timeout).Should(Equal([]v1alpha1.VirtualFunctionStatus{
+					{PCI: "0000:3b:00.2", PhysicalFunction: "0000:3b:00.0", Driver: "vfio-pci"},
+				}))
+			})
+
+			It("should delete CRs if they do not represent observed devices in two discoveries", func() {
+				deviceDiscoveryReconcileTime = time.Hour
+				hostManager.On("DiscoverNicDevices", mock.Anything).Return(map[string]v1alpha1.NicDeviceStatus{}, nil)
+
+				startManager()
+
+				// The missing device is kept until the next discovery
+				Consistently(func() error {
+					return k8sClient.Get(ctx, client.ObjectKey{Name: deviceName, Namespace: namespaceName}, &v1alpha1.NicDevice{})
+				}, time.Second).Should(Succeed())
+				deviceRegistry.Rescan()
+
 				Eventually(func() (int, error) {
 					list := &v1alpha1.NicDeviceList{}
 					err := k8sClient.List(ctx, list, client.InNamespace(namespaceName))
@@ -214,7 +459,7 @@ var _ = Describe("DeviceDiscovery", func() {
 				serialNumber := "new-serial-num"
 
 				// Add a new device that does not have a CR representation
-				hostManager.On("DiscoverNicDevices").Return(map[string]v1alpha1.NicDeviceStatus{
+				hostManager.On("DiscoverNicDevices", mock.Anything).Return(map[string]v1alpha1.NicDeviceStatus{
 					serialNumber: {
 						SerialNumber: serialNumber,
 						Type:         deviceType,
@@ -248,5 +493,550 @@ var _ = Describe("DeviceDiscovery", func() {
 				Expect(device.ObjectMeta.OwnerReferences[0].Name).To(Equal(nodeName))
 			})
 		})
+
+		Context("when a device is hot-plugged", func() {
+			It("should discover the device once its uevents settle", func() {
+				deviceDiscoveryReconcileTime = time.Hour
+				originalSettleTime := deviceHotplugSettleTime
+				deviceHotplugSettleTime = 100 * time.Millisecond
+				DeferCleanup(func() { deviceHotplugSettleTime = originalSettleTime })
+
+				uevents := make(chan chan<- host.UEvent, 1)
+				deviceRegistry.subscribe = func(ch chan<- host.UEvent, done <-chan struct{}) error {
+					uevents <- ch
+					return nil
+				}
+
+				serialNumber := "hotplugged-serial-num"
+				hostManager.On("DiscoverNicDevices", mock.Anything).Return(map[string]v1alpha1.NicDeviceStatus{}, nil).Once()
+				hostManager.On("DiscoverNicDevices", mock.Anything).Return(map[string]v1alpha1.NicDeviceStatus{
+					serialNumber: {
+						SerialNumber: serialNumber,
+						Type:         "connectx6",
+						Ports:        []v1alpha1.NicDevicePortSpec{{PCI: "0000:81:00.0"}},
+					},
+				}, nil)
+				hostManager.On("DiscoverOfedVersion").Return("00.00-0.0.0", nil)
+
+				startManager()
+
+				// The uevents are only received after the initial discovery
+				ch := <-uevents
+				ch <- host.UEvent{Action: host.UEventActionAdd, Subsystem: "net", Env: map[string]string{"INTERFACE": "eth0"}}
+				ch <- host.UEvent{Action: host.UEventActionBind, Subsystem: "pci", Env: map[string]string{
+					"PCI_ID": "15B3:101D", "PCI_SLOT_NAME": "0000:81:00.0"}}
+
+				Eventually(func() error {
+					return k8sClient.Get(ctx, client.ObjectKey{
+						Name:      deviceRegistry.getCRName("connectx6", serialNumber),
+						Namespace: namespaceName,
+					}, &v1alpha1.NicDevice{})
+				}, timeout).Should(Succeed())
+			})
+		})
+
+		Context("when a device is reset", func() {
+			It("should postpone the discovery until the reset completes", func() {
+				deviceDiscoveryReconcileTime = time.Hour
+				originalSettleTime := deviceHotplugSettleTime
+				deviceHotplugSettleTime = 100 * time.Millisecond
+				DeferCleanup(func() { deviceHotplugSettleTime = originalSettleTime })
+
+				resetInProgress := atomic.Bool{}
+				resetInProgress.Store(true)
+				deviceRegistry.resetInProgress = resetInProgress.Load
+
+				discoveries := make(chan struct{}, 1)
+				hostManager.On("DiscoverNicDevices", mock.Anything).Run(func(mock.Arguments) {
+					select {
+					case discoveries <- struct{}{}:
+					default:
+					}
+				}).Return(map[string]v1alpha1.NicDeviceStatus{}, nil)
+
+				startManager()
+
+				Consistently(discoveries, 500*time.Millisecond).ShouldNot(Receive())
+				resetInProgress.Store(false)
+				Eventually(discoveries, timeout).Should(Receive())
+			})
+		})
+
+		Context("when the link of a port changes", func() {
+			It("should refresh the link without discovering the devices again", func() {
+				deviceDiscoveryReconcileTime = time.Hour
+				originalRefreshTime := linkStateRefreshTime
+				linkStateRefreshTime = 100 * time.Millisecond
+				DeferCleanup(func() { linkStateRefreshTime = originalRefreshTime })
+
+				serialNumber := "link-serial-num"
+				hostManager.On("DiscoverNicDevices", mock.Anything).Return(map[string]v1alpha1.NicDeviceStatus{
+					serialNumber: {
+						SerialNumber: serialNumber,
+						Type:         "connectx6",
+						Ports: []v1alpha1.NicDevicePortSpec{{
+							PCI:              "0000:3b:00.0",
+							NetworkInterface: "eth0",
+							Link:             &v1alpha1.PortLinkStatus{State: "up", Speed: "100G"},
+						}},
+					},
+				}, nil)
+				hostManager.On("DiscoverOfedVersion").Return("00.00-0.0.0", nil)
+				hostManager.On("RefreshPortLinks", mock.Anything).Run(func(args mock.Arguments) {
+					status := args.Get(0).(*v1alpha1.NicDeviceStatus)
+					status.Ports[0].Link = &v1alpha1.PortLinkStatus{State: "down"}
+				})
+
+				startManager()
+
+				Eventually(func() *v1alpha1.PortLinkStatus {
+					device := &v1alpha1.NicDevice{}
+					err := k8sClient.Get(ctx, client.ObjectKey{
+						Name:      deviceRegistry.getCRName("connectx6", serialNumber),
+						Namespace: namespaceName,
+					}, device)
+					if err != nil || len(device.Status.Ports) == 0 {
+						return nil
+					}
+					return device.Status.Ports[0].Link
+				}, timeout).Should(Equal(&v1alpha1.PortLinkStatus{State: "down"}))
+
+				hostManager.AssertNumberOfCalls(GinkgoT(), "DiscoverNicDevices", 1)
+			})
+
+			It("should keep the changes of the CRs made after the discovery", func() {
+				deviceDiscoveryReconcileTime = time.Hour
+				originalRefreshTime := linkStateRefreshTime
+				linkStateRefreshTime = 100 * time.Millisecond
+				DeferCleanup(func() { linkStateRefreshTime = originalRefreshTime })
+
+				serialNumber := "renamed-serial-num"
+				hostManager.On("DiscoverNicDevices", mock.Anything).Return(map[string]v1alpha1.NicDeviceStatus{
+					serialNumber: {
+						SerialNumber: serialNumber,
+						Type:         "connectx6",
+						Ports:        []v1alpha1.NicDevicePortSpec{{PCI: "0000:3b:00.0", NetworkInterface: "eth0"}},
+					},
+				}, nil)
+				hostManager.On("DiscoverOfedVersion").Return("00.00-0.0.0", nil)
+				linkRefreshes := atomic.Int32{}
+				hostManager.On("RefreshPortLinks", mock.Anything).Run(func(args mock.Arguments) {
+					status := args.Get(0).(*v1alpha1.NicDeviceStatus)
+					status.Ports[0].Link = &v1alpha1.PortLinkStatus{State: "up"}
+					linkRefreshes.Add(1)
+				})
+
+				startManager()
+
+				device := &v1alpha1.NicDevice{}
+				Eventually(func() error {
+					return k8sClient.Get(ctx, client.ObjectKey{
+						Name:      deviceRegistry.getCRName("connectx6", serialNumber),
+						Namespace: namespaceName,
+					}, device)
+				}, timeout).Should(Succeed())
+
+				// The reconciler renames the interface of the port, e.g. after the device was reset
+				Eventually(func() error {
+					err := k8sClient.Get(ctx, client.ObjectKeyFromObject(device), device)
+					if err != nil {
+						return err
+					}
+					device.Status.Ports[0].NetworkInterface = "enp59s0f0np0"
+					return k8sClient.Status().Update(ctx, device)
+				}, timeout).Should(Succeed())
+
+				refreshes := linkRefreshes.Load()
+				Eventually(linkRefreshes.Load, timeout).Should(BeNumerically(">", refreshes+1))
+				Consistently(func() string {
+					Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(device), device)).To(Succeed())
+					return device.Status.Ports[0].NetworkInterface
+				}, 500*time.Millisecond).Should(Equal("enp59s0f0np0"))
+			})
+		})
+
+		Context("with the discovery interval set", func() {
+			It("should discover the devices at the interval", func() {
+				deviceDiscoveryReconcileTime = time.Hour
+				deviceRegistry.Interval = 100 * time.Millisecond
+
+				discoveries := make(chan struct{}, 1)
+				hostManager.On("DiscoverNicDevices", mock.Anything).Run(func(mock.Arguments) {
+					select {
+					case discoveries <- struct{}{}:
+					default:
+					}
+				}).Return(map[string]v1alpha1.NicDeviceStatus{}, nil)
+
+				startManager()
+
+				// The initial discovery is followed by the periodic ones
+				for i := 0; i < 3; i++ {
+					Eventually(discoveries, timeout).Should(Receive())
+				}
+			})
+		})
+
+		Context("when a rescan is requested", func() {
+			It("should discover the devices right away and remove the annotation", func() {
+				deviceDiscoveryReconcileTime = time.Hour
+				rescanReconciler := &RescanReconciler{Client: k8sClient, NodeName: nodeName, Discovery: deviceRegistry}
+				Expect(rescanReconciler.SetupWithManager(mgr)).To(Succeed())
+
+				discoveries := make(chan struct{}, 1)
+				hostManager.On("DiscoverNicDevices", mock.Anything).Run(func(mock.Arguments) {
+					select {
+					case discoveries <- struct{}{}:
+					default:
+					}
+				}).Return(map[string]v1alpha1.NicDeviceStatus{}, nil)
+
+				startManager()
+				Eventually(discoveries, timeout).Should(Receive())
+
+				node := &v1.Node{}
+				Expect(k8sClient.Get(ctx, client.ObjectKey{Name: nodeName}, node)).To(Succeed())
+				node.Annotations = map[string]string{consts.RescanAnnotation: "now"}
+				Expect(k8sClient.Update(ctx, node)).To(Succeed())
+
+				Eventually(discoveries, timeout).Should(Receive())
+				Eventually(func() map[string]string {
+					node := &v1.Node{}
+					Expect(k8sClient.Get(ctx, client.ObjectKey{Name: nodeName}, node)).To(Succeed())
+					return node.Annotations
+				}, timeout).ShouldNot(HaveKey(consts.RescanAnnotation))
+			})
+		})
+
+		Context("with batch discovery", func() {
+			It("should publish the observed devices in the node report instead of the NicDevice CRs", func() {
+				deviceRegistry.BatchDiscovery = true
+
+				hostManager.On("DiscoverNicDevices", mock.Anything).Return(map[string]v1alpha1.NicDeviceStatus{
+					"serial-b": {SerialNumber: "serial-b", Type: "connectx6", Ports: []v1alpha1.NicDevicePortSpec{{PCI: "0000:81:00.0"}}},
+					"serial-a": {SerialNumber: "serial-a", Type: "connectx6", Ports: []v1alpha1.NicDevicePortSpec{{PCI: "0000:3b:00.0"}}},
+				}, nil)
+				hostManager.On("DiscoverOfedVersion").Return("00.00-0.0.0", nil)
+
+				startManager()
+
+				report := &v1alpha1.NicNodeReport{}
+				Eventually(func() error {
+					return k8sClient.Get(ctx, client.ObjectKey{Name: nodeName, Namespace: namespaceName}, report)
+				}, timeout).Should(Succeed())
+
+				Expect(report.Spec.Node).To(Equal(nodeName))
+				Expect(report.Spec.Devices).To(HaveLen(2))
+				Expect(report.Spec.Devices[0].Status.SerialNumber).To(Equal("serial-a"))
+				Expect(report.Spec.Devices[0].Status.Node).To(Equal(nodeName))
+				Expect(report.Spec.Devices[1].Status.SerialNumber).To(Equal("serial-b"))
+				Expect(report.OwnerReferences).To(HaveLen(1))
+				Expect(report.OwnerReferences[0].Name).To(Equal(nodeName))
+
+				// Report is not rewritten if the observed devices didn't change
+				resourceVersion := report.ResourceVersion
+				Consistently(func() (string, error) {
+					report := &v1alpha1.NicNodeReport{}
+					err := k8sClient.Get(ctx, client.ObjectKey{Name: nodeName, Namespace: namespaceName}, report)
+					return report.ResourceVersion, err
+				}, time.Second*3).Should(Equal(resourceVersion))
+
+				list := &v1alpha1.NicDeviceList{}
+				Expect(k8sClient.List(ctx, list, client.InNamespace(namespaceName))).To(Succeed())
+				Expect(list.Items).To(BeEmpty())
+			})
+		})
+	})
+})
+
+var _ = Describe("syncNicDevices", func() {
+	const namespace = "nic-configuration-operator"
+
+	var (
+		k8sClient client.Client
+		node      *v1.Node
+	)
+
+	observedDevices := func(firmwareVersion string) map[string]v1alpha1.NicNodeReportDevice {
+		return map[string]v1alpha1.NicNodeReportDevice{
+			"serial1": {Status: v1alpha1.NicDeviceStatus{
+				Node:            "test-node",
+				Type:            "1021",
+				SerialNumber:    "serial1",
+				FirmwareVersion: firmwareVersion,
+				Ports:           []v1alpha1.NicDevicePortSpec{{PCI: "0000:3b:00.0"}},
+			}},
+		}
+	}
+
+	BeforeEach(func() {
+		node = &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "test-node"}}
+		device := &v1alpha1.NicDevice{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-node-1021-serial1", Namespace: namespace},
+			Status: v1alpha1.NicDeviceStatus{
+				Node:            "test-node",
+				Type:            "1021",
+				SerialNumber:    "serial1",
+				FirmwareVersion: "28.39.1002",
+				Operation: &v1alpha1.DeviceOperationStatus{
+					Phase:              consts.OperationPhaseAwaitingReboot,
+					ObservedGeneration: 2,
+					StartTime:          metav1.NewTime(time.Now().Add(-time.Minute).Truncate(time.Second)),
+					LastTransitionTime: metav1.NewTime(time.Now().Truncate(time.Second)),
+				},
+				PartialRuntimeConfig: &v1alpha1.PartialRuntimeConfigStatus{
+					ObservedGeneration: 2,
+					BootID:             "boot-1",
+					AppliedPorts:       []string{"0000:3b:00.0"},
+					FailedPort:         "0000:3b:00.1",
+					Error:              "failed to set trust state",
+				},
+			},
+		}
+		k8sClient = fake.NewClientBuilder().
+			WithScheme(scheme.Scheme).
+			WithObjects(node, device).
+			WithStatusSubresource(&v1alpha1.NicDevice{}).
+			WithIndex(&v1alpha1.NicDevice{}, "status.node", func(o client.Object) []string {
+				return []string{o.(*v1alpha1.NicDevice).Status.Node}
+			}).
+			Build()
+	})
+
+	getDevice := func() *v1alpha1.NicDevice {
+		device := &v1alpha1.NicDevice{}
+		Expect(k8sClient.Get(context.Background(), client.ObjectKey{Name: "test-node-1021-serial1", Namespace: namespace}, device)).To(Succeed())
+		return device
+	}
+
+	It("should keep the in-flight operation of the device across the discoveries", func() {
+		operation := getDevice().Status.Operation.DeepCopy()
+
+		Expect(syncNicDevices(context.Background(), k8sClient, node, namespace, observedDevices("28.39.1002"))).To(Succeed())
+		Expect(syncNicDevices(context.Background(), k8sClient, node, namespace, observedDevices("28.41.1000"))).To(Succeed())
+
+		device := getDevice()
+		Expect(device.Status.FirmwareVersion).To(Equal("28.41.1000"))
+		Expect(device.Status.Ports).To(Equal([]v1alpha1.NicDevicePortSpec{{PCI: "0000:3b:00.0"}}))
+		Expect(device.Status.Operation).To(Equal(operation))
+	})
+
+	It("should keep the partially applied runtime config of the device across the discoveries", func() {
+		partialRuntimeConfig := getDevice().Status.PartialRuntimeConfig.DeepCopy()
+
+		Expect(syncNicDevices(context.Background(), k8sClient, node, namespace, observedDevices("28.39.1002"))).To(Succeed())
+		Expect(syncNicDevices(context.Background(), k8sClient, node, namespace, observedDevices("28.41.1000"))).To(Succeed())
+
+		Expect(getDevice().Status.PartialRuntimeConfig).To(Equal(partialRuntimeConfig))
+	})
+
+	It("should sync the rest of the devices and return the errors of the CRs that failed to be written", func() {
+		failingClient := interceptor.NewClient(k8sClient.(client.WithWatch), interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				if obj.GetName() == "test-node-1021-serial2" {
+					return errors.New("admission webhook denied the request")
+				}
+				return c.Create(ctx, obj, opts...)
+			},
+		})
+		devices := observedDevices("28.41.1000")
+		for _, serialNumber := range []string{"serial2", "serial3"} {
+			devices[serialNumber] = v1alpha1.NicNodeReportDevice{Status: v1alpha1.NicDeviceStatus{
+				Node: "test-node", Type: "1021", SerialNumber: serialNumber,
+			}}
+		}
+
+		err := syncNicDevices(context.Background(), failingClient, node, namespace, devices)
+		Expect(err).To(MatchError(ContainSubstring("failed to create NicDevice CR test-node-1021-serial2: admission webhook denied the request")))
+
+		Expect(getDevice().Status.FirmwareVersion).To(Equal("28.41.1000"))
+		device := &v1alpha1.NicDevice{}
+		Expect(k8sClient.Get(context.Background(), client.ObjectKey{Name: "test-node-1021-serial3", Namespace: namespace}, device)).To(Succeed())
+		Expect(device.Status.SerialNumber).To(Equal("serial3"))
+	})
+
+	It("should keep the CR of the device identified by its flash once its VPD is read", func() {
+		flashDevice := &v1alpha1.NicDevice{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-node-1021-guid-b8cef603000a1b2c", Namespace: namespace},
+			Status: v1alpha1.NicDeviceStatus{
+				Node:           "test-node",
+				Type:           "1021",
+				SerialNumber:   "guid-b8cef603000a1b2c",
+				IdentitySource: consts.IdentitySourceFlash,
+				Ports:          []v1alpha1.NicDevicePortSpec{{PCI: "0000:d8:00.0"}, {PCI: "0000:d8:00.1"}},
+			},
+		}
+		Expect(k8sClient.Create(context.Background(), flashDevice)).To(Succeed())
+		Expect(k8sClient.Status().Update(context.Background(), flashDevice)).To(Succeed())
+
+		devices := observedDevices("28.39.1002")
+		devices["serial2"] = v1alpha1.NicNodeReportDevice{Status: v1alpha1.NicDeviceStatus{
+			Node: "test-node", Type: "1021", SerialNumber: "serial2",
+			Ports: []v1alpha1.NicDevicePortSpec{{PCI: "0000:d8:00.0"}, {PCI: "0000:d8:00.1"}},
+		}}
+		Expect(syncNicDevices(context.Background(), k8sClient, node, namespace, devices)).To(Succeed())
+
+		device := &v1alpha1.NicDevice{}
+		Expect(k8sClient.Get(context.Background(), client.ObjectKeyFromObject(flashDevice), device)).To(Succeed())
+		Expect(device.Status.SerialNumber).To(Equal("serial2"))
+		Expect(device.Status.IdentitySource).To(BeEmpty())
+		err := k8sClient.Get(context.Background(), client.ObjectKey{Name: "test-node-1021-serial2", Namespace: namespace}, &v1alpha1.NicDevice{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+})
+
+var _ = Describe("keepPreviousIdentities", func() {
+	var (
+		deviceRegistry  *DeviceDiscovery
+		observedDevices map[string]v1alpha1.NicDeviceStatus
+	)
+
+	previousPorts := []v1alpha1.NicDevicePortSpec{{PCI: "0000:3b:00.0"}, {PCI: "0000:3b:00.1"}}
+
+	BeforeEach(func() {
+		k8sClient := fake.NewClientBuilder().
+			WithScheme(scheme.Scheme).
+			WithObjects(&v1alpha1.NicDevice{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-node-1021-serial-number", Namespace: "nic-configuration-operator"},
+				Status: v1alpha1.NicDeviceStatus{
+					Node:         "test-node",
+					SerialNumber: "serial-number",
+					PartNumber:   "part-number",
+					Ports:        previousPorts,
+				},
+			}).
+			WithIndex(&v1alpha1.NicDevice{}, "status.node", func(o client.Object) []string {
+				return []string{o.(*v1alpha1.NicDevice).Status.Node}
+			}).
+			Build()
+		deviceRegistry = NewDeviceRegistry(k8sClient, nil, "test-node", "nic-configuration-operator")
+		observedDevices = map[string]v1alpha1.NicDeviceStatus{
+			"guid-b8cef603000a1b2c": {
+				SerialNumber:   "guid-b8cef603000a1b2c",
+				IdentitySource: consts.IdentitySourceFlash,
+				Ports:          previousPorts,
+			},
+			"guid-0c42a10300160540": {
+				SerialNumber:   "guid-0c42a10300160540",
+				IdentitySource: consts.IdentitySourceFlash,
+				Ports:          []v1alpha1.NicDevicePortSpec{{PCI: "0000:d8:00.0"}},
+			},
+		}
+	})
+
+	expectPreviousIdentity := func() {
+		Expect(observedDevices).To(HaveLen(2))
+		Expect(observedDevices).To(HaveKey("serial-number"))
+		Expect(observedDevices["serial-number"].SerialNumber).To(Equal("serial-number"))
+		Expect(observedDevices["serial-number"].PartNumber).To(Equal("part-number"))
+		Expect(observedDevices["serial-number"].IdentitySource).To(Equal(consts.IdentitySourcePreviousDiscovery))
+		Expect(observedDevices["guid-0c42a10300160540"].IdentitySource).To(Equal(consts.IdentitySourceFlash))
+	}
+
+	It("should keep the identity of the device found at the same PCI address by the previous discovery", func() {
+		deviceRegistry.reportedDevices = map[string]v1alpha1.NicNodeReportDevice{
+			"serial-number": {Status: v1alpha1.NicDeviceStatus{
+				SerialNumber: "serial-number",
+				PartNumber:   "part-number",
+				Ports:        previousPorts,
+			}},
+		}
+
+		Expect(deviceRegistry.keepPreviousIdentities(context.Background(), observedDevices)).To(Succeed())
+		expectPreviousIdentity()
+	})
+
+	It("should keep the identity of the existing NicDevice CR after a restart of the config daemon", func() {
+		Expect(deviceRegistry.keepPreviousIdentities(context.Background(), observedDevices)).To(Succeed())
+		expectPreviousIdentity()
+	})
+
+	It("should keep the previous status of the device whose VPD and flash can't be read", func() {
+		delete(observedDevices, "guid-b8cef603000a1b2c")
+		observedDevices["unidentified-0000:3b:00.0"] = v1alpha1.NicDeviceStatus{
+			IdentitySource: consts.IdentitySourceUnknown,
+			Ports:          []v1alpha1.NicDevicePortSpec{{PCI: "0000:3b:00.0"}},
+		}
+		observedDevices["unidentified-0000:af:00.0"] = v1alpha1.NicDeviceStatus{
+			IdentitySource: consts.IdentitySourceUnknown,
+			Ports:          []v1alpha1.NicDevicePortSpec{{PCI: "0000:af:00.0"}},
+		}
+
+		Expect(deviceRegistry.keepPreviousIdentities(context.Background(), observedDevices)).To(Succeed())
+		expectPreviousIdentity()
+		Expect(observedDevices["serial-number"].Ports).To(Equal(previousPorts))
+	})
+})
+
+var _ = Describe("keepAbsentDevices", func() {
+	var deviceRegistry *DeviceDiscovery
+
+	previous := v1alpha1.NicDeviceStatus{
+		Node:         "test-node",
+		SerialNumber: "serial-number",
+		Ports:        []v1alpha1.NicDevicePortSpec{{PCI: "0000:3b:00.0"}},
+	}
+
+	BeforeEach(func() {
+		deviceRegistry = NewDeviceRegistry(fake.NewClientBuilder().WithScheme(scheme.Scheme).Build(), nil, "test-node", "nic-configuration-operator")
+		deviceRegistry.reportedDevices = map[string]v1alpha1.NicNodeReportDevice{"serial-number": {Status: previous}}
+	})
+
+	It("should keep the missing device until it is missing in two discoveries", func() {
+		observedDevices := map[string]v1alpha1.NicDeviceStatus{}
+		Expect(deviceRegistry.keepAbsentDevices(context.Background(), observedDevices)).To(Succeed())
+		Expect(observedDevices).To(HaveKeyWithValue("serial-number", previous))
+
+		observedDevices = map[string]v1alpha1.NicDeviceStatus{}
+		Expect(deviceRegistry.keepAbsentDevices(context.Background(), observedDevices)).To(Succeed())
+		Expect(observedDevices).To(BeEmpty())
+	})
+
+	It("should not keep the device identified by its flash once its ports are identified by the VPD", func() {
+		flashDevice := previous
+		flashDevice.SerialNumber = "guid-b8cef603000a1b2c"
+		flashDevice.IdentitySource = consts.IdentitySourceFlash
+		deviceRegistry.reportedDevices = map[string]v1alpha1.NicNodeReportDevice{"guid-b8cef603000a1b2c": {Status: flashDevice}}
+
+		observedDevices := map[string]v1alpha1.NicDeviceStatus{"serial-number": previous}
+		Expect(deviceRegistry.keepAbsentDevices(context.Background(), observedDevices)).To(Succeed())
+		Expect(observedDevices).To(HaveLen(1))
+		Expect(deviceRegistry.absentDevices).To(BeEmpty())
+	})
+
+	It("should forget the missing device once it is found again", func() {
+		Expect(deviceRegistry.keepAbsentDevices(context.Background(), map[string]v1alpha1.NicDeviceStatus{})).To(Succeed())
+
+		observedDevices := map[string]v1alpha1.NicDeviceStatus{"serial-number": previous}
+		Expect(deviceRegistry.keepAbsentDevices(context.Background(), observedDevices)).To(Succeed())
+		Expect(deviceRegistry.absentDevices).To(BeEmpty())
+
+		observedDevices = map[string]v1alpha1.NicDeviceStatus{}
+		Expect(deviceRegistry.keepAbsentDevices(context.Background(), observedDevices)).To(Succeed())
+		Expect(observedDevices).To(HaveKey("serial-number"))
+	})
+})
+
+var _ = Describe("devicesChanged", func() {
+	deviceRegistry := &DeviceDiscovery{reportedDevices: map[string]v1alpha1.NicNodeReportDevice{
+		"serial-number": {Status: v1alpha1.NicDeviceStatus{Ports: []v1alpha1.NicDevicePortSpec{{PCI: "0000:3b:00.0"}}}},
+	}}
+
+	uevent := func(action string, pciAddress string) host.UEvent {
+		return host.UEvent{Action: action, Subsystem: "pci", Env: map[string]string{"PCI_ID": "15B3:101D", "PCI_SLOT_NAME": pciAddress}}
+	}
+
+	It("should report the changes of the ports of the discovered devices", func() {
+		Expect(deviceRegistry.devicesChanged(uevent(host.UEventActionUnbind, "0000:3b:00.0"))).To(BeTrue())
+		Expect(deviceRegistry.devicesChanged(uevent(host.UEventActionBind, "0000:3b:00.0"))).To(BeTrue())
+	})
+
+	It("should report the new PFs", func() {
+		Expect(deviceRegistry.devicesChanged(uevent(host.UEventActionAdd, "0000:81:00.0"))).To(BeTrue())
+	})
+
+	It("should ignore the removal of the devices that weren't discovered, e.g. the VFs", func() {
+		Expect(deviceRegistry.devicesChanged(uevent(host.UEventActionRemove, "0000:3b:00.2"))).To(BeFalse())
+		Expect(deviceRegistry.devicesChanged(uevent(host.UEventActionUnbind, "0000:3b:00.2"))).To(BeFalse())
 	})
 })
@@ -0,0 +1,238 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/mock"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sTypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/config"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+	"github.com/Mellanox/nic-configuration-operator/pkg/host"
+	"github.com/Mellanox/nic-configuration-operator/pkg/host/fakes"
+	maintenanceMocks "github.com/Mellanox/nic-configuration-operator/pkg/maintenance/mocks"
+)
+
+// The integration suite runs device discovery, template and device reconcilers together against a fake host
+var _ = Describe("Integration", func() {
+	var (
+		mgr                manager.Manager
+		fakeHost           *fakes.FakeHostUtils
+		maintenanceManager *maintenanceMocks.MaintenanceManager
+		nodeName           = "integration-node"
+		pciAddress         = "0000:3b:00.0"
+		ctx                context.Context
+		cancel             context.CancelFunc
+		timeout            = time.Second * 30
+		namespaceName      string
+		wg                 sync.WaitGroup
+		err                error
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.TODO())
+		mgr, err = ctrl.NewManager(cfg, ctrl.Options{
+			Scheme:     k8sClient.Scheme(),
+			Metrics:    metricsserver.Options{BindAddress: "0"},
+			Controller: config.Controller{SkipNameValidation: ptr.To(true)},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName, Labels: map[string]string{"integration": "true"}}}
+		Expect(k8sClient.Create(ctx, node)).To(Succeed())
+
+		namespaceName = "nic-configuration-operator-" + rand.String(6)
+		Expect(k8sClient.Create(ctx, &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespaceName}})).To(Succeed())
+
+		err = mgr.GetCache().IndexField(context.Background(), &v1alpha1.NicDevice{}, "status.node", func(o client.Object) []string {
+			return []string{o.(*v1alpha1.NicDevice).Status.Node}
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		fakeHost = fakes.NewFakeHostUtils(&fakes.FakeDevice{
+			Type:            "101b",
+			SerialNumber:    "integration-serial",
+			PartNumber:      "integration-part",
+			PSID:            "integration-psid",
+			FirmwareVersion: "20.42.1000",
+			LinkType:        consts.Ethernet,
+			Ports:           []fakes.FakePort{{PCI: pciAddress, NetworkInterface: "eth0", RdmaInterface: "mlx5_0"}},
+			NvConfig: fakes.NewFakeNvConfig(map[string]string{
+				consts.SriovEnabledParam:        "False(0)",
+				consts.SriovNumOfVfsParam:       "0",
+				consts.LinkTypeP1Param:          "ETH(2)",
+				consts.AdvancedPCISettingsParam: "True(1)",
+			}),
+		})
+
+		maintenanceManager = &maintenanceMocks.MaintenanceManager{}
+		maintenanceManager.On("ScheduleMaintenance", mock.Anything).Return(nil)
+		maintenanceManager.On("MaintenanceAllowed", mock.Anything).Return(true, nil)
+		maintenanceManager.On("ReleaseMaintenance", mock.Anything).Return(nil)
+		maintenanceManager.On("Reboot").Return(nil).Run(func(args mock.Arguments) {
+			_ = fakeHost.ScheduleReboot()
+		})
+
+		hostManager := host.NewHostManager(nodeName, fakeHost, mgr.GetEventRecorderFor("integration"), nil, nil)
+
+		deviceDiscoveryReconcileTime = 1 * time.Second
+		Expect(mgr.Add(NewDeviceRegistry(mgr.GetClient(), hostManager, nodeName, namespaceName))).To(Succeed())
+
+		templateReconciler := &NicConfigurationTemplateReconciler{
+			Client: mgr.GetClient(),
+			Scheme: mgr.GetScheme(),
+		}
+		Expect(templateReconciler.SetupWithManager(mgr)).To(Succeed())
+
+		deviceReconciler := &NicDeviceReconciler{
+			Client:             mgr.GetClient(),
+			Scheme:             mgr.GetScheme(),
+			NodeName:           nodeName,
+			NamespaceName:      namespaceName,
+			HostManager:        hostManager,
+			MaintenanceManager: maintenanceManager,
+			HostUtils:          fakeHost,
+			EventRecorder:      mgr.GetEventRecorderFor("integration"),
+		}
+		Expect(deviceReconciler.SetupWithManager(mgr, false)).To(Succeed())
+
+		wg = sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer GinkgoRecover()
+			Expect(mgr.Start(ctx)).To(Succeed())
+		}()
+	})
+
+	AfterEach(func() {
+		cancel()
+		wg.Wait()
+		Expect(k8sClient.DeleteAllOf(context.Background(), &v1alpha1.NicConfigurationTemplate{}, client.InNamespace(namespaceName))).To(Succeed())
+		Expect(k8sClient.DeleteAllOf(context.Background(), &v1alpha1.NicDevice{}, client.InNamespace(namespaceName))).To(Succeed())
+		Expect(k8sClient.Delete(context.Background(), &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespaceName}})).To(Succeed())
+		Expect(k8sClient.Delete(context.Background(), &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName}})).To(Succeed())
+	})
+
+	It("should discover, configure and verify a device after reboot", func() {
+		deviceName := nodeName + "-101b-integration-serial"
+
+		By("discovering the device")
+		Eventually(func() (string, error) {
+			device := &v1alpha1.NicDevice{}
+			err := k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)
+			return device.Status.SerialNumber, client.IgnoreNotFound(err)
+		}, timeout).Should(Equal("integration-serial"))
+
+		By("matching the device with a template")
+		template := &v1alpha1.NicConfigurationTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "integration-template", Namespace: namespaceName},
+			Spec: v1alpha1.NicConfigurationTemplateSpec{
+				NodeSelector: map[string]string{"integration": "true"},
+				NicSelector:  &v1alpha1.NicSelectorSpec{NicType: "101b"},
+				Template: &v1alpha1.ConfigurationTemplateSpec{
+					NumVfs:   4,
+					LinkType: consts.Ethernet,
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, template)).To(Succeed())
+
+		By("applying nv config and rebooting the node")
+		Eventually(fakeHost.RebootCount, timeout).Should(Equal(1))
+
+		By("verifying the configuration after reboot")
+		// In a real cluster the config daemon starts over after the reboot, triggering the reconcile
+		Eventually(func() error {
+			device := &v1alpha1.NicDevice{}
+			err := k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)
+			if err != nil {
+				return err
+			}
+			device.SetAnnotations(map[string]string{"integration/rebooted": "true"})
+			return k8sClient.Update(ctx, device)
+		}, timeout).Should(Succeed())
+
+		Eventually(func() string {
+			device := &v1alpha1.NicDevice{}
+			Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+			condition := meta.FindStatusCondition(device.Status.Conditions, consts.ConfigUpdateInProgressCondition)
+			if condition == nil {
+				return ""
+			}
+			return condition.Reason
+		}, timeout).Should(Equal(consts.UpdateSuccessfulReason))
+
+		Expect(fakeHost.CurrentNvConfigValue(pciAddress, consts.SriovEnabledParam)).To(ContainElement(consts.NvParamTrue))
+		Expect(fakeHost.CurrentNvConfigValue(pciAddress, consts.SriovNumOfVfsParam)).To(ContainElement("4"))
+		Expect(fakeHost.RebootCount()).To(Equal(1))
+		maintenanceManager.AssertCalled(GinkgoT(), "ReleaseMaintenance", mock.Anything)
+	})
+
+	It("should activate the configuration with a FW reset if the template prefers it", func() {
+		deviceName := nodeName + "-101b-integration-serial"
+
+		Eventually(func() (string, error) {
+			device := &v1alpha1.NicDevice{}
+			err := k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)
+			return device.Status.SerialNumber, client.IgnoreNotFound(err)
+		}, timeout).Should(Equal("integration-serial"))
+
+		template := &v1alpha1.NicConfigurationTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "integration-template", Namespace: namespaceName},
+			Spec: v1alpha1.NicConfigurationTemplateSpec{
+				NodeSelector: map[string]string{"integration": "true"},
+				NicSelector:  &v1alpha1.NicSelectorSpec{NicType: "101b"},
+				Disruption:   consts.DisruptionFwReset,
+				Template: &v1alpha1.ConfigurationTemplateSpec{
+					NumVfs:   4,
+					LinkType: consts.Ethernet,
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, template)).To(Succeed())
+
+		Eventually(func() string {
+			device := &v1alpha1.NicDevice{}
+			Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+			condition := meta.FindStatusCondition(device.Status.Conditions, consts.ConfigUpdateInProgressCondition)
+			if condition == nil {
+				return ""
+			}
+			return condition.Reason
+		}, timeout).Should(Equal(consts.UpdateSuccessfulReason))
+
+		Expect(fakeHost.CurrentNvConfigValue(pciAddress, consts.SriovNumOfVfsParam)).To(ContainElement("4"))
+		Expect(fakeHost.FirmwareResetCount()).To(Equal(1))
+		Expect(fakeHost.RebootCount()).To(Equal(0))
+	})
+})
@@ -0,0 +1,361 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+)
+
+// localAPIShutdownTimeout limits the time the in-flight requests are served after the daemon is stopped
+var localAPIShutdownTimeout = time.Second * 5
+
+// localAPIListTimeout limits the time a request waits for the daemon's cache, the snapshot is served after it
+var localAPIListTimeout = time.Second * 5
+
+// localAPISnapshotInterval is the interval of the snapshot refresh while there are no requests
+var localAPISnapshotInterval = time.Minute
+
+// localAPISnapshotHeader is set to the time of the snapshot if the response is served from it
+const localAPISnapshotHeader = "X-Snapshot-Time"
+
+// localDeviceSnapshot is the last known state of the node's devices persisted on the host
+type localDeviceSnapshot struct {
+	Time    metav1.Time          `json:"time"`
+	Devices []v1alpha1.NicDevice `json:"devices"`
+}
+
+// LocalDeviceState is the state of a NicDevice on the node as served by the local API
+type LocalDeviceState struct {
+	// Name of the NicDevice CR
+	Name string `json:"name"`
+	// Configuration requested for the device, nil if device doesn't match any template
+	Configuration *v1alpha1.NicDeviceConfigurationSpec `json:"configuration,omitempty"`
+	// Last known status of the device
+	Status v1alpha1.NicDeviceStatus `json:"status"`
+}
+
+// LocalPendingOperation is an unfinished configuration of a device on the node as served by the local API
+type LocalPendingOperation struct {
+	// Name of the NicDevice CR
+	Device string `json:"device"`
+	// Phase of the operation, empty if the device doesn't report the operation phase yet
+	Phase string `json:"phase,omitempty"`
+	// Start time of the operation
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// Reason of the ConfigUpdateInProgress condition
+	Reason string `json:"reason,omitempty"`
+	// Message of the ConfigUpdateInProgress condition
+	Message string `json:"message,omitempty"`
+	// Nv config parameters waiting for a reboot to take effect
+	PendingRebootParameters []v1alpha1.NvConfigParameterDiff `json:"pendingRebootParameters,omitempty"`
+}
+
+// LocalAPIServer serves the node's devices and their pending operations on a read-only unix socket API.
+// Host administrators can inspect the operator's intent without cluster credentials, e.g. during network outages,
+// the devices are served from the daemon's cache with their last known state. The state is persisted to the snapshot file
+// and served from it while the cache can't be read, e.g. the daemon restarted after a reboot and can't reach the API server
+type LocalAPIServer struct {
+	client.Reader
+
+	nodeName     string
+	socketPath   string
+	snapshotPath string
+
+	lock          sync.Mutex
+	savedSnapshot []byte
+}
+
+// Start serves the local API on the unix socket until the context is done
+func (s *LocalAPIServer) Start(ctx context.Context) error {
+	log.Log.Info("Local API server started", "socket", s.socketPath)
+
+	err := os.MkdirAll(filepath.Dir(s.socketPath), 0700)
+	if err != nil {
+		return err
+	}
+	// Socket of the previous run of the daemon is left behind if it wasn't stopped gracefully
+	err = os.Remove(s.socketPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	listener, err := net.Listen("unix", s.socketPath)
+	if err != nil {
+		return err
+	}
+	// The API exposes the node's configuration, only root on the host can access it
+	err = os.Chmod(s.socketPath, 0600)
+	if err != nil {
+		listener.Close()
+		return err
+	}
+
+	if s.snapshotPath != "" {
+		go s.refreshSnapshot(ctx)
+	}
+
+	server := &http.Server{Handler: s.handler(), ReadHeaderTimeout: time.Second * 10}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), localAPIShutdownTimeout)
+		defer cancel()
+		err := server.Shutdown(shutdownCtx)
+		if err != nil {
+			log.Log.Error(err, "failed to shut down the local API server")
+		}
+	}()
+
+	err = server.Serve(listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// handler routes the local API requests, only GET requests are served
+func (s *LocalAPIServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/devices", s.listDevices)
+	mux.HandleFunc("GET /v1/devices/{name}", s.getDevice)
+	mux.HandleFunc("GET /v1/operations", s.listOperations)
+	return mux
+}
+
+func (s *LocalAPIServer) listDevices(w http.ResponseWriter, r *http.Request) {
+	devices, err := s.servedDevices(w, r)
+	if err != nil {
+		writeLocalAPIError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+
+	states := make([]LocalDeviceState, 0, len(devices))
+	for i := range devices {
+		states = append(states, localDeviceState(&devices[i]))
+	}
+	writeLocalAPIResponse(w, states)
+}
+
+func (s *LocalAPIServer) getDevice(w http.ResponseWriter, r *http.Request) {
+	devices, err := s.servedDevices(w, r)
+	if err != nil {
+		writeLocalAPIError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+
+	for i := range devices {
+		if devices[i].Name == r.PathValue("name") {
+			writeLocalAPIResponse(w, localDeviceState(&devices[i]))
+			return
+		}
+	}
+	writeLocalAPIError(w, http.StatusNotFound, errors.New("device not found on this node"))
+}
+
+func (s *LocalAPIServer) listOperations(w http.ResponseWriter, r *http.Request) {
+	devices, err := s.servedDevices(w, r)
+	if err != nil {
+		writeLocalAPIError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+
+	operations := []LocalPendingOperation{}
+	for i := range devices {
+		if operation, pending := pendingOperation(&devices[i]); pending {
+			operations = append(operations, operation)
+		}
+	}
+	writeLocalAPIResponse(w, operations)
+}
+
+// servedDevices returns the devices of the node from the daemon's cache or, if the cache can't be read, from the snapshot
+// the time of the snapshot is reported in the X-Snapshot-Time header of the response
+func (s *LocalAPIServer) servedDevices(w http.ResponseWriter, r *http.Request) ([]v1alpha1.NicDevice, error) {
+	devices, err := s.nodeDevices(r.Context())
+	if err == nil || s.snapshotPath == "" {
+		return devices, err
+	}
+
+	snapshot, snapshotErr := s.loadSnapshot()
+	if snapshotErr != nil {
+		log.Log.Error(snapshotErr, "failed to read the local API snapshot", "path", s.snapshotPath)
+		return nil, err
+	}
+	log.Log.Info("serving the local API from the snapshot", "time", snapshot.Time, "reason", err.Error())
+	w.Header().Set(localAPISnapshotHeader, snapshot.Time.UTC().Format(time.RFC3339))
+	return snapshot.Devices, nil
+}
+
+// nodeDevices returns the devices of the node sorted by name and persists them to the snapshot
+// the cache is waited for up to localAPIListTimeout, it blocks until it's synced with the API server
+func (s *LocalAPIServer) nodeDevices(ctx context.Context) ([]v1alpha1.NicDevice, error) {
+	ctx, cancel := context.WithTimeout(ctx, localAPIListTimeout)
+	defer cancel()
+
+	list := &v1alpha1.NicDeviceList{}
+	err := s.List(ctx, list, &client.ListOptions{FieldSelector: fields.OneTermEqualSelector("status.node", s.nodeName)})
+	if err != nil {
+		log.Log.Error(err, "failed to list NicDevice CRs for the local API")
+		return nil, err
+	}
+
+	sort.Slice(list.Items, func(i, j int) bool { return list.Items[i].Name < list.Items[j].Name })
+	if s.snapshotPath != "" {
+		err = s.saveSnapshot(list.Items)
+		if err != nil {
+			log.Log.Error(err, "failed to save the local API snapshot", "path", s.snapshotPath)
+		}
+	}
+	return list.Items, nil
+}
+
+// refreshSnapshot keeps the snapshot up to date while there are no requests until the context is done
+func (s *LocalAPIServer) refreshSnapshot(ctx context.Context) {
+	ticker := time.NewTicker(localAPISnapshotInterval)
+	defer ticker.Stop()
+	for {
+		_, _ = s.nodeDevices(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// saveSnapshot atomically replaces the snapshot file with the devices, the file is kept if the devices didn't change
+func (s *LocalAPIServer) saveSnapshot(devices []v1alpha1.NicDevice) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	data, err := json.Marshal(devices)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(data, s.savedSnapshot) {
+		return nil
+	}
+
+	snapshot, err := json.Marshal(localDeviceSnapshot{Time: metav1.Now(), Devices: devices})
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(s.snapshotPath), 0700)
+	if err != nil {
+		return err
+	}
+	tempPath := s.snapshotPath + ".tmp"
+	err = os.WriteFile(tempPath, snapshot, 0600)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tempPath, s.snapshotPath)
+	if err != nil {
+		return err
+	}
+	s.savedSnapshot = data
+	return nil
+}
+
+func (s *LocalAPIServer) loadSnapshot() (*localDeviceSnapshot, error) {
+	data, err := os.ReadFile(s.snapshotPath)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &localDeviceSnapshot{}
+	err = json.Unmarshal(data, snapshot)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+func localDeviceState(device *v1alpha1.NicDevice) LocalDeviceState {
+	return LocalDeviceState{
+		Name:          device.Name,
+		Configuration: device.Spec.Configuration,
+		Status:        device.Status,
+	}
+}
+
+// pendingOperation returns the unfinished configuration of the device,
+// either an operation in progress or a ConfigUpdateInProgress condition set to true
+func pendingOperation(device *v1alpha1.NicDevice) (LocalPendingOperation, bool) {
+	condition := meta.FindStatusCondition(device.Status.Conditions, consts.ConfigUpdateInProgressCondition)
+	conditionPending := condition != nil && condition.Status == metav1.ConditionTrue
+	if !operationInProgress(device) && !conditionPending {
+		return LocalPendingOperation{}, false
+	}
+
+	operation := LocalPendingOperation{
+		Device:                  device.Name,
+		PendingRebootParameters: device.Status.PendingRebootParameters,
+	}
+	if operationInProgress(device) {
+		operation.Phase = device.Status.Operation.Phase
+		operation.StartTime = &device.Status.Operation.StartTime
+	}
+	if condition != nil {
+		operation.Reason = condition.Reason
+		operation.Message = condition.Message
+	}
+	return operation, true
+}
+
+func writeLocalAPIResponse(w http.ResponseWriter, response any) {
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(response)
+	if err != nil {
+		log.Log.Error(err, "failed to write the local API response")
+	}
+}
+
+func writeLocalAPIError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// NewLocalAPIServer creates a new instance of LocalAPIServer serving the node's devices on the given unix socket
+// the devices are persisted to the snapshot file if its path is not empty
+func NewLocalAPIServer(reader client.Reader, node string, socketPath string, snapshotPath string) *LocalAPIServer {
+	return &LocalAPIServer{
+		Reader:       reader,
+		nodeName:     node,
+		socketPath:   socketPath,
+		snapshotPath: snapshotPath,
+	}
+}
@@ -0,0 +1,204 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+)
+
+var _ = Describe("LocalAPIServer", func() {
+	var server *LocalAPIServer
+
+	BeforeEach(func() {
+		devices := []client.Object{
+			&v1alpha1.NicDevice{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-node-cx7-serial2", Namespace: "nic-configuration-operator"},
+				Status: v1alpha1.NicDeviceStatus{
+					Node:         "test-node",
+					SerialNumber: "serial2",
+					Conditions: []metav1.Condition{{
+						Type:   consts.ConfigUpdateInProgressCondition,
+						Status: metav1.ConditionTrue,
+						Reason: consts.PendingRebootReason,
+					}},
+					PendingRebootParameters: []v1alpha1.NvConfigParameterDiff{
+						{Name: consts.SriovNumOfVfsParam, CurrentValues: []string{"0"}, NextBootValues: []string{"8"}},
+					},
+					Operation: &v1alpha1.DeviceOperationStatus{Phase: consts.OperationPhaseAwaitingReboot},
+				},
+			},
+			&v1alpha1.NicDevice{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-node-cx7-serial1", Namespace: "nic-configuration-operator"},
+				Status: v1alpha1.NicDeviceStatus{
+					Node:         "test-node",
+					SerialNumber: "serial1",
+					Operation:    &v1alpha1.DeviceOperationStatus{Phase: consts.OperationPhaseDone},
+				},
+			},
+			&v1alpha1.NicDevice{
+				ObjectMeta: metav1.ObjectMeta{Name: "other-node-cx7-serial3", Namespace: "nic-configuration-operator"},
+				Status:     v1alpha1.NicDeviceStatus{Node: "other-node", SerialNumber: "serial3"},
+			},
+		}
+
+		reader := fake.NewClientBuilder().
+			WithScheme(scheme.Scheme).
+			WithObjects(devices...).
+			WithIndex(&v1alpha1.NicDevice{}, "status.node", func(o client.Object) []string {
+				return []string{o.(*v1alpha1.NicDevice).Status.Node}
+			}).
+			Build()
+		server = NewLocalAPIServer(reader, "test-node", "", "")
+	})
+
+	get := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		server.handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder
+	}
+
+	It("should list the devices of the node sorted by name", func() {
+		response := get("/v1/devices")
+		Expect(response.Code).To(Equal(http.StatusOK))
+
+		devices := []LocalDeviceState{}
+		Expect(json.Unmarshal(response.Body.Bytes(), &devices)).To(Succeed())
+		Expect(devices).To(HaveLen(2))
+		Expect(devices[0].Name).To(Equal("test-node-cx7-serial1"))
+		Expect(devices[1].Status.SerialNumber).To(Equal("serial2"))
+	})
+
+	It("should return a single device of the node", func() {
+		response := get("/v1/devices/test-node-cx7-serial2")
+		Expect(response.Code).To(Equal(http.StatusOK))
+
+		device := LocalDeviceState{}
+		Expect(json.Unmarshal(response.Body.Bytes(), &device)).To(Succeed())
+		Expect(device.Status.SerialNumber).To(Equal("serial2"))
+
+		Expect(get("/v1/devices/other-node-cx7-serial3").Code).To(Equal(http.StatusNotFound))
+	})
+
+	It("should list the pending operations", func() {
+		response := get("/v1/operations")
+		Expect(response.Code).To(Equal(http.StatusOK))
+
+		operations := []LocalPendingOperation{}
+		Expect(json.Unmarshal(response.Body.Bytes(), &operations)).To(Succeed())
+		Expect(operations).To(HaveLen(1))
+		Expect(operations[0].Device).To(Equal("test-node-cx7-serial2"))
+		Expect(operations[0].Reason).To(Equal(consts.PendingRebootReason))
+		Expect(operations[0].PendingRebootParameters).To(HaveLen(1))
+	})
+
+	Context("when the snapshot is enabled", func() {
+		var unavailableReader client.Reader
+
+		BeforeEach(func() {
+			server.snapshotPath = filepath.Join(GinkgoT().TempDir(), "local-api", "devices.json")
+			unavailableReader = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithInterceptorFuncs(interceptor.Funcs{
+				List: func(context.Context, client.WithWatch, client.ObjectList, ...client.ListOption) error {
+					return errors.New("timed out waiting for cache to be synced")
+				},
+			}).Build()
+		})
+
+		It("should serve the devices from the snapshot if the cache can't be read", func() {
+			Expect(get("/v1/devices").Code).To(Equal(http.StatusOK))
+			Expect(server.snapshotPath).To(BeAnExistingFile())
+
+			// The daemon restarted without access to the API server
+			server = NewLocalAPIServer(unavailableReader, "test-node", "", server.snapshotPath)
+			response := get("/v1/devices")
+			Expect(response.Code).To(Equal(http.StatusOK))
+			Expect(response.Header().Get(localAPISnapshotHeader)).NotTo(BeEmpty())
+
+			devices := []LocalDeviceState{}
+			Expect(json.Unmarshal(response.Body.Bytes(), &devices)).To(Succeed())
+			Expect(devices).To(HaveLen(2))
+			Expect(devices[1].Status.SerialNumber).To(Equal("serial2"))
+
+			operations := []LocalPendingOperation{}
+			response = get("/v1/operations")
+			Expect(json.Unmarshal(response.Body.Bytes(), &operations)).To(Succeed())
+			Expect(operations).To(HaveLen(1))
+		})
+		It("should serve the devices from the cache without the snapshot header", func() {
+			response := get("/v1/devices")
+			Expect(response.Code).To(Equal(http.StatusOK))
+			Expect(response.Header().Get(localAPISnapshotHeader)).To(BeEmpty())
+		})
+		It("should return an error if the cache can't be read and there is no snapshot", func() {
+			server.Reader = unavailableReader
+
+			Expect(get("/v1/devices").Code).To(Equal(http.StatusServiceUnavailable))
+		})
+	})
+
+	It("should reject the requests changing the state", func() {
+		recorder := httptest.NewRecorder()
+		server.handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/v1/devices/test-node-cx7-serial2", nil))
+		Expect(recorder.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+
+	It("should serve the API on the unix socket until stopped", func() {
+		server.socketPath = filepath.Join(GinkgoT().TempDir(), "api.sock")
+		ctx, cancel := context.WithCancel(context.Background())
+
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer GinkgoRecover()
+			Expect(server.Start(ctx)).To(Succeed())
+		}()
+
+		httpClient := http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", server.socketPath)
+			},
+		}}
+		Eventually(func() (int, error) {
+			response, err := httpClient.Get("http://localhost/v1/devices")
+			if err != nil {
+				return 0, err
+			}
+			defer response.Body.Close()
+			return response.StatusCode, nil
+		}).Should(Equal(http.StatusOK))
+
+		cancel()
+		wg.Wait()
+	})
+})
@@ -21,9 +21,12 @@ import (
 	"fmt"
 	"reflect"
 	"slices"
+	"strconv"
 	"strings"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
@@ -36,6 +39,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	v1alpha1 "github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
 )
 
 const nicConfigurationTemplateSyncEventName = "nic-configuration-template-sync-event"
@@ -45,6 +49,9 @@ type NicConfigurationTemplateReconciler struct {
 	client.Client
 	EventRecorder record.EventRecorder
 	Scheme        *runtime.Scheme
+	// APIReader reads the workloads restarted by the post configuration hooks and their pods directly from the API server
+	// the reconciler's client is used if not set
+	APIReader client.Reader
 }
 
 //+kubebuilder:rbac:groups=configuration.net.nvidia.com,resources=nicconfigurationtemplates,verbs=get;list;watch;create;update;patch;delete
@@ -54,9 +61,10 @@ type NicConfigurationTemplateReconciler struct {
 //+kubebuilder:rbac:groups=configuration.net.nvidia.com,resources=nicdevices,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=configuration.net.nvidia.com,resources=nicdevices/finalizers,verbs=update
 //+kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch;update;patch
-//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get
 //+kubebuilder:rbac:groups="",resources=pods,verbs=list
 //+kubebuilder:rbac:groups="",resources=pods/eviction,verbs=create;delete;get;list;patch;update;watch
+//+kubebuilder:rbac:groups="",namespace=system,resources=pods,verbs=delete
+//+kubebuilder:rbac:groups=apps,namespace=system,resources=daemonsets;deployments,verbs=get;patch
 //+kubebuilder:rbac:groups=maintenance.nvidia.com,resources=nodemaintenances,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile reconciles the NicConfigurationTemplate object
@@ -101,7 +109,18 @@ func (r *NicConfigurationTemplateReconciler) Reconcile(ctx context.Context, req
 		templates = append(templates, &template)
 	}
 
+	deviceMap := map[string]*v1alpha1.NicDevice{}
+	nodeDevices := map[string][]*v1alpha1.NicDevice{}
 	for _, device := range deviceList.Items {
+		device := device
+		deviceMap[device.Name] = &device
+		nodeDevices[device.Status.Node] = append(nodeDevices[device.Status.Node], &device)
+	}
+
+	assignedDevices := map[*v1alpha1.NicConfigurationTemplate][]*v1alpha1.NicDevice{}
+	for _, device := range deviceList.Items {
+		device := deviceMap[device.Name]
+
 		node, ok := nodeMap[device.Status.Node]
 		if !ok {
 			log.Log.Info("device doesn't match any node, skipping", "device", device.Name)
@@ -111,7 +130,7 @@ func (r *NicConfigurationTemplateReconciler) Reconcile(ctx context.Context, req
 		var matchingTemplates []*v1alpha1.NicConfigurationTemplate
 
 		for _, template := range templates {
-			if !deviceMatchesSelectors(&device, template, node) {
+			if !deviceMatchesSelectors(device, template, node) {
 				r.dropDeviceFromStatus(device.Name, template)
 
 				continue
@@ -121,9 +140,13 @@ func (r *NicConfigurationTemplateReconciler) Reconcile(ctx context.Context, req
 		}
 
 		if len(matchingTemplates) == 0 {
+			if device.Spec.Configuration == nil && !hasTemplateMetadata(device) {
+				continue
+			}
 			log.Log.V(2).Info("Device doesn't match any configuration template, resetting the spec", "device", device.Name)
 			device.Spec.Configuration = nil
-			err = r.Update(ctx, &device)
+			removeTemplateMetadata(device)
+			err = r.Update(ctx, device)
 			if err != nil {
 				log.Log.Error(err, "Failed to update device's spec", "device", device)
 				return ctrl.Result{}, err
@@ -143,7 +166,7 @@ func (r *NicConfigurationTemplateReconciler) Reconcile(ctx context.Context, req
 			joinedTemplateNames := strings.Join(templateNames, ",")
 			err = fmt.Errorf("device matches several configuration templates: %s, %s", device.Name, joinedTemplateNames)
 			log.Log.Error(err, "Device matches several configuration templates, deleting its spec and emitting an error event", "device", device.Name)
-			err = r.handleErrorSeveralMatchingTemplates(ctx, &device, joinedTemplateNames)
+			err = r.handleErrorSeveralMatchingTemplates(ctx, device, joinedTemplateNames)
 			if err != nil {
 				log.Log.Error(err, "Failed to emit warning about multiple templates matching one device", "templates", joinedTemplateNames)
 				return ctrl.Result{}, err
@@ -156,15 +179,44 @@ func (r *NicConfigurationTemplateReconciler) Reconcile(ctx context.Context, req
 			matchingTemplate.Status.NicDevices = append(matchingTemplate.Status.NicDevices, device.Name)
 		}
 
-		err = r.applyTemplateToDevice(ctx, &device, matchingTemplate)
+		assignedDevices[matchingTemplate] = append(assignedDevices[matchingTemplate], device)
+	}
+
+	for _, template := range templates {
+		var heldDevices map[string]bool
+		if rolloutStrategy(template) == consts.RolloutStrategyFingerprint {
+			heldDevices, err = r.rolloutByFingerprint(ctx, template, assignedDevices[template], nodeMap, nodeDevices)
+			if err != nil {
+				log.Log.Error(err, "failed to roll out template by hardware fingerprint", "template", template.Name)
+				return ctrl.Result{}, err
+			}
+		} else {
+			template.Status.Fingerprints = nil
+		}
+
+		for _, device := range assignedDevices[template] {
+			if heldDevices[device.Name] {
+				log.Log.V(2).Info("Holding the template until the canary node of the hardware fingerprint is in sync", "template", template.Name, "device", device.Name)
+				continue
+			}
+
+			err = r.applyTemplateToDevice(ctx, device, template)
+			if err != nil {
+				log.Log.Error(err, "failed to apply template to device", "template", template.Name, "device", device.Name)
+				return ctrl.Result{}, err
+			}
+		}
+
+		err = r.runPostConfigurationHook(ctx, template, assignedDevices[template])
 		if err != nil {
-			log.Log.Error(err, "failed to apply template to device", "template", matchingTemplate.Name, "device", device.Name)
+			log.Log.Error(err, "failed to run the post configuration hook of the template", "template", template.Name)
 			return ctrl.Result{}, err
 		}
 	}
 
 	// Try to update template's status with added / deleted devices
 	for _, template := range templates {
+		updateRolloutSummary(template, deviceMap)
 		err = r.Status().Update(ctx, template)
 		if err != nil {
 			log.Log.Error(err, "failed to update template status", "template", template.Name)
@@ -183,6 +235,50 @@ func (r *NicConfigurationTemplateReconciler) dropDeviceFromStatus(deviceName str
 	}
 }
 
+// updateRolloutSummary counts the template's devices by the state of their configuration update
+// updatedAt is only bumped when the counters change to not trigger needless status updates
+func updateRolloutSummary(template *v1alpha1.NicConfigurationTemplate, devices map[string]*v1alpha1.NicDevice) {
+	inSync, pendingReboot, failed := 0, 0, 0
+	for _, deviceName := range template.Status.NicDevices {
+		device, found := devices[deviceName]
+		if !found {
+			continue
+		}
+
+		condition := meta.FindStatusCondition(device.Status.Conditions, consts.ConfigUpdateInProgressCondition)
+		if condition == nil {
+			continue
+		}
+
+		switch {
+		case condition.Reason == consts.PendingRebootReason:
+			pendingReboot++
+		case condition.Status != metav1.ConditionFalse:
+			// Update is still in progress
+		case condition.Reason == consts.UpdateSuccessfulReason:
+			// Condition of the previous spec doesn't reflect the current template
+			if condition.ObservedGeneration == device.Generation {
+				inSync++
+			}
+		case condition.Reason != consts.DeviceConfigSpecEmptyReason:
+			failed++
+		}
+	}
+
+	status := &template.Status
+	if status.UpdatedAt != nil && status.MatchedDevices == len(status.NicDevices) &&
+		status.InSync == inSync && status.PendingReboot == pendingReboot && status.Failed == failed {
+		return
+	}
+
+	status.MatchedDevices = len(status.NicDevices)
+	status.InSync = inSync
+	status.PendingReboot = pendingReboot
+	status.Failed = failed
+	now := metav1.Now()
+	status.UpdatedAt = &now
+}
+
 func (r *NicConfigurationTemplateReconciler) applyTemplateToDevice(ctx context.Context, device *v1alpha1.NicDevice, template *v1alpha1.NicConfigurationTemplate) error {
 	log.Log.V(2).Info(fmt.Sprintf("Applying template %s to device %s", template.Name, device.Name))
 
@@ -197,11 +293,36 @@ func (r *NicConfigurationTemplateReconciler) applyTemplateToDevice(ctx context.C
 		device.Spec.Configuration.ResetToDefault = template.Spec.ResetToDefault
 	}
 
+	if device.Spec.Configuration.Disruption != template.Spec.Disruption {
+		updateSpec = true
+		device.Spec.Configuration.Disruption = template.Spec.Disruption
+	}
+
+	if !reflect.DeepEqual(device.Spec.Configuration.ActivationWindow, template.Spec.ActivationWindow) {
+		updateSpec = true
+		device.Spec.Configuration.ActivationWindow = template.Spec.ActivationWindow.DeepCopy()
+	}
+
+	if device.Spec.Configuration.ConsistencyGroup != template.Spec.ConsistencyGroup {
+		updateSpec = true
+		device.Spec.Configuration.ConsistencyGroup = template.Spec.ConsistencyGroup
+	}
+
+	if !reflect.DeepEqual(device.Spec.Configuration.PostConfigurationHook, template.Spec.PostConfigurationHook) {
+		updateSpec = true
+		device.Spec.Configuration.PostConfigurationHook = template.Spec.PostConfigurationHook.DeepCopy()
+	}
+
 	if !reflect.DeepEqual(device.Spec.Configuration.Template, template.Spec.Template) {
 		updateSpec = true
 		device.Spec.Configuration.Template = template.Spec.Template.DeepCopy()
 	}
 
+	// Template edits that don't change the rendered spec still trigger the device's re-validation on the node
+	if setTemplateMetadata(device, template) {
+		updateSpec = true
+	}
+
 	if updateSpec {
 		err := r.Update(ctx, device)
 		if err != nil {
@@ -216,9 +337,41 @@ func (r *NicConfigurationTemplateReconciler) applyTemplateToDevice(ctx context.C
 func (r *NicConfigurationTemplateReconciler) handleErrorSeveralMatchingTemplates(ctx context.Context, device *v1alpha1.NicDevice, matchingTemplates string) error {
 	r.EventRecorder.Event(device, v1.EventTypeWarning, "SpecError", fmt.Sprintf("Several templates matching this device: %s", matchingTemplates))
 	device.Spec.Configuration = nil
+	removeTemplateMetadata(device)
 	return r.Update(ctx, device)
 }
 
+// setTemplateMetadata labels the device with the applied template's name and generation
+// returns true if the device's metadata changed
+func setTemplateMetadata(device *v1alpha1.NicDevice, template *v1alpha1.NicConfigurationTemplate) bool {
+	generation := strconv.FormatInt(template.Generation, 10)
+	if device.Labels[consts.TemplateLabel] == template.Name && device.Annotations[consts.TemplateGenerationAnnotation] == generation {
+		return false
+	}
+
+	if device.Labels == nil {
+		device.Labels = map[string]string{}
+	}
+	if device.Annotations == nil {
+		device.Annotations = map[string]string{}
+	}
+	device.Labels[consts.TemplateLabel] = template.Name
+	device.Annotations[consts.TemplateGenerationAnnotation] = generation
+
+	return true
+}
+
+func hasTemplateMetadata(device *v1alpha1.NicDevice) bool {
+	_, labeled := device.Labels[consts.TemplateLabel]
+	_, annotated := device.Annotations[consts.TemplateGenerationAnnotation]
+	return labeled || annotated
+}
+
+func removeTemplateMetadata(device *v1alpha1.NicDevice) {
+	delete(device.Labels, consts.TemplateLabel)
+	delete(device.Annotations, consts.TemplateGenerationAnnotation)
+}
+
 func nodeMatchesTemplate(node *v1.Node, template *v1alpha1.NicConfigurationTemplate) bool {
 	for k, v := range template.Spec.NodeSelector {
 		if nv, ok := node.Labels[k]; ok && nv == v {
@@ -255,6 +408,16 @@ func deviceMatchesSerialNumberSelector(device *v1alpha1.NicDevice, template *v1a
 	return true
 }
 
+func deviceMatchesLabelSelector(device *v1alpha1.NicDevice, template *v1alpha1.NicConfigurationTemplate) bool {
+	for k, v := range template.Spec.NicSelector.DeviceLabels {
+		if dv, ok := device.Labels[k]; ok && dv == v {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
 func deviceMatchesSelectors(device *v1alpha1.NicDevice, template *v1alpha1.NicConfigurationTemplate, node *v1.Node) bool {
 	if !nodeMatchesTemplate(node, template) {
 		return false
@@ -272,6 +435,10 @@ func deviceMatchesSelectors(device *v1alpha1.NicDevice, template *v1alpha1.NicCo
 		return false
 	}
 
+	if !deviceMatchesLabelSelector(device, template) {
+		return false
+	}
+
 	return true
 }
 
@@ -19,12 +19,15 @@ package controller
 import (
 	"context"
 	"slices"
+	"strconv"
 	"sync"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/rand"
@@ -100,8 +103,9 @@ var _ = Describe("NicConfigurationTemplate Controller", func() {
 		Expect(k8sClient.Create(context.Background(), ns)).To(Succeed())
 
 		reconciler = &NicConfigurationTemplateReconciler{
-			Client: mgr.GetClient(),
-			Scheme: mgr.GetScheme(),
+			Client:    mgr.GetClient(),
+			Scheme:    mgr.GetScheme(),
+			APIReader: mgr.GetAPIReader(),
 		}
 
 		Expect(reconciler.SetupWithManager(mgr)).To(Succeed())
@@ -234,6 +238,61 @@ var _ = Describe("NicConfigurationTemplate Controller", func() {
 		Consistently(getDeviceSpecTemplate(ctx, device6.Name, namespaceName, k8sClient)).Should(BeNil())
 	})
 
+	It("should select devices by their role labels", func() {
+		node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName}}
+		Expect(k8sClient.Create(ctx, node)).To(Succeed())
+
+		newTemplate := func(name string, role string, numVfs int) *v1alpha1.NicConfigurationTemplate {
+			template := &v1alpha1.NicConfigurationTemplate{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespaceName},
+				Spec: v1alpha1.NicConfigurationTemplateSpec{
+					NicSelector: &v1alpha1.NicSelectorSpec{
+						NicType:      "ConnectX6",
+						DeviceLabels: map[string]string{"role": role},
+					},
+					Template: &v1alpha1.ConfigurationTemplateSpec{
+						NumVfs:   numVfs,
+						LinkType: "Ethernet",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, template)).To(Succeed())
+			return template
+		}
+		fabricTemplate := newTemplate("fabric-template", "fabric", 8)
+		storageTemplate := newTemplate("storage-template", "storage", 0)
+
+		newDevice := func(name string, labels map[string]string, serialNumber string) *v1alpha1.NicDevice {
+			device := &v1alpha1.NicDevice{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespaceName, Labels: labels}}
+			Expect(k8sClient.Create(ctx, device)).To(Succeed())
+			device.Status = v1alpha1.NicDeviceStatus{
+				Node:         nodeName,
+				Type:         "ConnectX6",
+				Ports:        []v1alpha1.NicDevicePortSpec{{PCI: "0000:3b:00.0"}},
+				SerialNumber: serialNumber,
+			}
+			Expect(k8sClient.Status().Update(ctx, device)).To(Succeed())
+			return device
+		}
+		fabricDevice := newDevice("fabric-device", map[string]string{"role": "fabric"}, "serialNumber1")
+		storageDevice := newDevice("storage-device", map[string]string{"role": "storage"}, "serialNumber2")
+		// unlabeled device doesn't match any of the role selectors
+		unlabeledDevice := newDevice("unlabeled-device", nil, "serialNumber3")
+
+		Eventually(getDeviceSpecTemplate(ctx, fabricDevice.Name, namespaceName, k8sClient)).WithTimeout(1 * time.Minute).Should(Equal(fabricTemplate.Spec.Template))
+		Eventually(getDeviceSpecTemplate(ctx, storageDevice.Name, namespaceName, k8sClient)).Should(Equal(storageTemplate.Spec.Template))
+		Consistently(getDeviceSpecTemplate(ctx, unlabeledDevice.Name, namespaceName, k8sClient)).Should(BeNil())
+
+		By("changing the device's role")
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: storageDevice.Name, Namespace: namespaceName}, storageDevice)).To(Succeed())
+		storageDevice.Labels["role"] = "fabric"
+		Expect(k8sClient.Update(ctx, storageDevice)).To(Succeed())
+
+		Eventually(getDeviceSpecTemplate(ctx, storageDevice.Name, namespaceName, k8sClient)).Should(Equal(fabricTemplate.Spec.Template))
+		Eventually(getMatchedDevicesFromStatus(ctx, fabricTemplate.Name, namespaceName, k8sClient)).Should(ConsistOf(fabricDevice.Name, storageDevice.Name))
+		Eventually(getMatchedDevicesFromStatus(ctx, storageTemplate.Name, namespaceName, k8sClient)).Should(BeEmpty())
+	})
+
 	It("should update spec if resetToDefault differs", func() {
 		node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName}}
 		Expect(k8sClient.Create(ctx, node)).To(Succeed())
@@ -394,4 +453,380 @@ var _ = Describe("NicConfigurationTemplate Controller", func() {
 		Eventually(getMatchedDevicesFromStatus(ctx, template1.Name, template1.Namespace, k8sClient)).Should(BeEmpty())
 		Eventually(getMatchedDevicesFromStatus(ctx, template2.Name, template2.Namespace, k8sClient)).Should(BeEmpty())
 	})
+
+	It("should relabel devices on template edits and reset devices that no longer match", func() {
+		node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName}}
+		Expect(k8sClient.Create(ctx, node)).To(Succeed())
+
+		template := &v1alpha1.NicConfigurationTemplate{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      templateName,
+				Namespace: namespaceName,
+			},
+			Spec: v1alpha1.NicConfigurationTemplateSpec{
+				NicSelector: &v1alpha1.NicSelectorSpec{
+					NicType: "ConnectX6",
+				},
+				Template: &v1alpha1.ConfigurationTemplateSpec{
+					NumVfs:   8,
+					LinkType: consts.Ethernet,
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, template)).To(Succeed())
+
+		device := &v1alpha1.NicDevice{
+			ObjectMeta: metav1.ObjectMeta{Name: deviceName, Namespace: namespaceName},
+		}
+		Expect(k8sClient.Create(ctx, device)).To(Succeed())
+		device.Status = v1alpha1.NicDeviceStatus{
+			Node:         nodeName,
+			Type:         "ConnectX6",
+			SerialNumber: "sn1",
+			Ports:        []v1alpha1.NicDevicePortSpec{{PCI: "0000:3b:00.0"}},
+		}
+		Expect(k8sClient.Status().Update(ctx, device)).To(Succeed())
+
+		getDeviceMetadata := func() (map[string]string, map[string]string) {
+			device := &v1alpha1.NicDevice{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+			return device.Labels, device.Annotations
+		}
+
+		Eventually(getDeviceSpecTemplate(ctx, deviceName, namespaceName, k8sClient)).Should(Equal(template.Spec.Template))
+		Eventually(func() string {
+			labels, _ := getDeviceMetadata()
+			return labels[consts.TemplateLabel]
+		}).Should(Equal(templateName))
+
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: templateName, Namespace: namespaceName}, template)).To(Succeed())
+		template.Spec.Disruption = consts.DisruptionReboot
+		Expect(k8sClient.Update(ctx, template)).To(Succeed())
+
+		Eventually(func() string {
+			_, annotations := getDeviceMetadata()
+			return annotations[consts.TemplateGenerationAnnotation]
+		}).Should(Equal(strconv.FormatInt(template.Generation, 10)))
+
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: templateName, Namespace: namespaceName}, template)).To(Succeed())
+		template.Spec.NicSelector.SerialNumbers = []string{"sn2"}
+		Expect(k8sClient.Update(ctx, template)).To(Succeed())
+
+		Eventually(getDeviceSpecTemplate(ctx, deviceName, namespaceName, k8sClient)).Should(BeNil())
+		Eventually(func() map[string]string {
+			labels, _ := getDeviceMetadata()
+			return labels
+		}).ShouldNot(HaveKey(consts.TemplateLabel))
+		Eventually(getMatchedDevicesFromStatus(ctx, template.Name, template.Namespace, k8sClient)).Should(BeEmpty())
+	})
+
+	It("should summarize the rollout to the matching devices in the status", func() {
+		node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName}}
+		Expect(k8sClient.Create(ctx, node)).To(Succeed())
+
+		template := &v1alpha1.NicConfigurationTemplate{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      templateName,
+				Namespace: namespaceName,
+			},
+			Spec: v1alpha1.NicConfigurationTemplateSpec{
+				NicSelector: &v1alpha1.NicSelectorSpec{
+					NicType: "ConnectX6",
+				},
+				Template: &v1alpha1.ConfigurationTemplateSpec{
+					NumVfs:   8,
+					LinkType: consts.Ethernet,
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, template)).To(Succeed())
+
+		deviceNames := []string{"device1", "device2", "device3", "device4"}
+		for i, name := range deviceNames {
+			device := &v1alpha1.NicDevice{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespaceName}}
+			Expect(k8sClient.Create(ctx, device)).To(Succeed())
+			device.Status = v1alpha1.NicDeviceStatus{
+				Node:         nodeName,
+				Type:         "ConnectX6",
+				SerialNumber: "sn" + strconv.Itoa(i),
+				Ports:        []v1alpha1.NicDevicePortSpec{{PCI: "0000:3b:00." + strconv.Itoa(i)}},
+			}
+			Expect(k8sClient.Status().Update(ctx, device)).To(Succeed())
+		}
+
+		getSummary := func() v1alpha1.NicConfigurationTemplateStatus {
+			templateObj := &v1alpha1.NicConfigurationTemplate{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: templateName, Namespace: namespaceName}, templateObj)).To(Succeed())
+			status := templateObj.Status
+			status.NicDevices = nil
+			status.UpdatedAt = nil
+			return status
+		}
+
+		Eventually(getSummary).Should(Equal(v1alpha1.NicConfigurationTemplateStatus{MatchedDevices: 4}))
+		for _, name := range deviceNames {
+			Eventually(getDeviceSpecTemplate(ctx, name, namespaceName, k8sClient)).Should(Equal(template.Spec.Template))
+		}
+
+		setCondition := func(name string, reason string, status metav1.ConditionStatus) {
+			device := &v1alpha1.NicDevice{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: namespaceName}, device)).To(Succeed())
+			meta.SetStatusCondition(&device.Status.Conditions, metav1.Condition{
+				Type:               consts.ConfigUpdateInProgressCondition,
+				Status:             status,
+				ObservedGeneration: device.Generation,
+				Reason:             reason,
+			})
+			Expect(k8sClient.Status().Update(ctx, device)).To(Succeed())
+		}
+
+		setCondition("device1", consts.UpdateSuccessfulReason, metav1.ConditionFalse)
+		setCondition("device2", consts.PendingRebootReason, metav1.ConditionTrue)
+		setCondition("device3", consts.NonVolatileConfigUpdateFailedReason, metav1.ConditionFalse)
+		setCondition("device4", consts.UpdateStartedReason, metav1.ConditionTrue)
+
+		Eventually(getSummary).Should(Equal(v1alpha1.NicConfigurationTemplateStatus{
+			MatchedDevices: 4, InSync: 1, PendingReboot: 1, Failed: 1,
+		}))
+
+		templateObj := &v1alpha1.NicConfigurationTemplate{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: templateName, Namespace: namespaceName}, templateObj)).To(Succeed())
+		Expect(templateObj.Status.UpdatedAt).NotTo(BeNil())
+
+		By("template edit invalidates the in sync devices")
+		templateObj.Spec.Template.NumVfs = 4
+		Expect(k8sClient.Update(ctx, templateObj)).To(Succeed())
+
+		Eventually(getSummary).Should(Equal(v1alpha1.NicConfigurationTemplateStatus{
+			MatchedDevices: 4, InSync: 0, PendingReboot: 1, Failed: 1,
+		}))
+	})
+
+	It("should roll out the template to a canary node of each hardware fingerprint first", func() {
+		for _, name := range []string{"node1", "node2", "node3"} {
+			Expect(k8sClient.Create(ctx, &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}})).To(Succeed())
+		}
+
+		template := &v1alpha1.NicConfigurationTemplate{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      templateName,
+				Namespace: namespaceName,
+			},
+			Spec: v1alpha1.NicConfigurationTemplateSpec{
+				NicSelector: &v1alpha1.NicSelectorSpec{
+					NicType: "ConnectX6",
+				},
+				Rollout: &v1alpha1.RolloutSpec{Strategy: consts.RolloutStrategyFingerprint},
+				Template: &v1alpha1.ConfigurationTemplateSpec{
+					NumVfs:   8,
+					LinkType: consts.Ethernet,
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, template)).To(Succeed())
+
+		// node1 and node2 share the hardware with different firmware versions, node3 has NICs of another PSID
+		psids := map[string]string{"node1": "MT_0000000001", "node2": "MT_0000000001", "node3": "MT_0000000002"}
+		firmwareVersions := map[string]string{"node1": "22.41.1000", "node2": "22.42.1000", "node3": "22.41.1000"}
+		for i, nodeName := range []string{"node1", "node2", "node3"} {
+			device := &v1alpha1.NicDevice{ObjectMeta: metav1.ObjectMeta{Name: "device" + strconv.Itoa(i+1), Namespace: namespaceName}}
+			Expect(k8sClient.Create(ctx, device)).To(Succeed())
+			device.Status = v1alpha1.NicDeviceStatus{
+				Node:            nodeName,
+				Type:            "ConnectX6",
+				SerialNumber:    "sn" + strconv.Itoa(i),
+				PSID:            psids[nodeName],
+				FirmwareVersion: firmwareVersions[nodeName],
+				Ports:           []v1alpha1.NicDevicePortSpec{{PCI: "0000:3b:00.0"}},
+			}
+			Expect(k8sClient.Status().Update(ctx, device)).To(Succeed())
+		}
+
+		getFingerprintLabel := func(nodeName string) func() string {
+			return func() string {
+				node := &v1.Node{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: nodeName}, node)).To(Succeed())
+				return node.Labels[consts.HardwareFingerprintLabel]
+			}
+		}
+		getPhases := func() map[string]string {
+			templateObj := &v1alpha1.NicConfigurationTemplate{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: templateName, Namespace: namespaceName}, templateObj)).To(Succeed())
+			phases := map[string]string{}
+			for _, fingerprint := range templateObj.Status.Fingerprints {
+				phases[fingerprint.CanaryNode] = fingerprint.Phase
+			}
+			return phases
+		}
+		setCondition := func(name string, reason string) {
+			device := &v1alpha1.NicDevice{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: namespaceName}, device)).To(Succeed())
+			meta.SetStatusCondition(&device.Status.Conditions, metav1.Condition{
+				Type:               consts.ConfigUpdateInProgressCondition,
+				Status:             metav1.ConditionFalse,
+				ObservedGeneration: device.Generation,
+				Reason:             reason,
+			})
+			Expect(k8sClient.Status().Update(ctx, device)).To(Succeed())
+		}
+
+		Eventually(getFingerprintLabel("node1")).ShouldNot(BeEmpty())
+		Eventually(getFingerprintLabel("node3")).ShouldNot(BeEmpty())
+		Expect(getFingerprintLabel("node2")()).To(Equal(getFingerprintLabel("node1")()))
+		Expect(getFingerprintLabel("node3")()).NotTo(Equal(getFingerprintLabel("node1")()))
+
+		By("canary nodes get the template first")
+		Eventually(getDeviceSpecTemplate(ctx, "device1", namespaceName, k8sClient)).Should(Equal(template.Spec.Template))
+		Eventually(getDeviceSpecTemplate(ctx, "device3", namespaceName, k8sClient)).Should(Equal(template.Spec.Template))
+		Eventually(getPhases).Should(Equal(map[string]string{"node1": consts.RolloutPhaseCanary, "node3": consts.RolloutPhaseCanary}))
+		Consistently(getDeviceSpecTemplate(ctx, "device2", namespaceName, k8sClient)).Should(BeNil())
+
+		By("in sync canary lets the template through to the rest of its fingerprint")
+		setCondition("device1", consts.UpdateSuccessfulReason)
+		Eventually(getDeviceSpecTemplate(ctx, "device2", namespaceName, k8sClient)).Should(Equal(template.Spec.Template))
+		Eventually(getPhases).Should(Equal(map[string]string{"node1": consts.RolloutPhaseRollingOut, "node3": consts.RolloutPhaseCanary}))
+
+		setCondition("device2", consts.UpdateSuccessfulReason)
+		setCondition("device3", consts.UpdateSuccessfulReason)
+		Eventually(getPhases).Should(Equal(map[string]string{"node1": consts.RolloutPhaseCompleted, "node3": consts.RolloutPhaseCompleted}))
+
+		By("failed canary halts the rollout of the template edit")
+		templateObj := &v1alpha1.NicConfigurationTemplate{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Name: templateName, Namespace: namespaceName}, templateObj)).To(Succeed())
+		templateObj.Spec.Template.NumVfs = 4
+		Expect(k8sClient.Update(ctx, templateObj)).To(Succeed())
+
+		Eventually(getDeviceSpecTemplate(ctx, "device1", namespaceName, k8sClient)).Should(Equal(templateObj.Spec.Template))
+		setCondition("device1", consts.NonVolatileConfigUpdateFailedReason)
+		Eventually(getPhases).Should(HaveKeyWithValue("node1", consts.RolloutPhaseHalted))
+		Consistently(getDeviceSpecTemplate(ctx, "device2", namespaceName, k8sClient)).Should(Equal(template.Spec.Template))
+	})
+
+	Describe("post configuration hook", func() {
+		var template *v1alpha1.NicConfigurationTemplate
+
+		getDevice := func(name string) *v1alpha1.NicDevice {
+			device := &v1alpha1.NicDevice{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: namespaceName}, device)).To(Succeed())
+			return device
+		}
+
+		// markReconfigured does what the config daemon does once the device applied its configuration
+		markReconfigured := func(name string, reconfiguredAt string) {
+			Eventually(func() error {
+				device := getDevice(name)
+				device.Annotations[consts.ReconfiguredAtAnnotation] = reconfiguredAt
+				return k8sClient.Update(ctx, device)
+			}).Should(Succeed())
+			Eventually(func() error {
+				device := getDevice(name)
+				meta.SetStatusCondition(&device.Status.Conditions, metav1.Condition{
+					Type:               consts.ConfigUpdateInProgressCondition,
+					Status:             metav1.ConditionFalse,
+					ObservedGeneration: device.Generation,
+					Reason:             consts.UpdateSuccessfulReason,
+				})
+				return k8sClient.Status().Update(ctx, device)
+			}).Should(Succeed())
+		}
+
+		createDevices := func(workload v1alpha1.WorkloadReference) {
+			for i, nodeName := range []string{"node1", "node2"} {
+				Expect(k8sClient.Create(ctx, &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName}})).To(Succeed())
+
+				device := &v1alpha1.NicDevice{ObjectMeta: metav1.ObjectMeta{Name: "device" + strconv.Itoa(i+1), Namespace: namespaceName}}
+				Expect(k8sClient.Create(ctx, device)).To(Succeed())
+				device.Status = v1alpha1.NicDeviceStatus{
+					Node:         nodeName,
+					Type:         "ConnectX6",
+					SerialNumber: "sn" + strconv.Itoa(i),
+					Ports:        []v1alpha1.NicDevicePortSpec{{PCI: "0000:3b:00.0"}},
+				}
+				Expect(k8sClient.Status().Update(ctx, device)).To(Succeed())
+			}
+
+			template = &v1alpha1.NicConfigurationTemplate{
+				ObjectMeta: metav1.ObjectMeta{Name: templateName, Namespace: namespaceName},
+				Spec: v1alpha1.NicConfigurationTemplateSpec{
+					NicSelector:           &v1alpha1.NicSelectorSpec{NicType: "ConnectX6"},
+					PostConfigurationHook: &v1alpha1.PostConfigurationHookSpec{RestartWorkloads: []v1alpha1.WorkloadReference{workload}},
+					Template:              &v1alpha1.ConfigurationTemplateSpec{NumVfs: 8, LinkType: consts.Ethernet},
+				},
+			}
+			Expect(k8sClient.Create(ctx, template)).To(Succeed())
+
+			for _, name := range []string{"device1", "device2"} {
+				Eventually(getDeviceSpecTemplate(ctx, name, namespaceName, k8sClient)).Should(Equal(template.Spec.Template))
+			}
+		}
+
+		It("should restart the pods of the DaemonSet on the nodes once their devices are reconfigured", func() {
+			podLabels := map[string]string{"app": "sriov-device-plugin"}
+			podSpec := v1.PodSpec{Containers: []v1.Container{{Name: "device-plugin", Image: "device-plugin"}}}
+			Expect(k8sClient.Create(ctx, &appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "sriov-device-plugin", Namespace: namespaceName},
+				Spec: appsv1.DaemonSetSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: podLabels},
+					Template: v1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: podLabels}, Spec: podSpec},
+				},
+			})).To(Succeed())
+			for name, node := range map[string]string{"node1-pod": "node1", "node2-pod": "node2"} {
+				pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespaceName, Labels: podLabels}, Spec: *podSpec.DeepCopy()}
+				pod.Spec.NodeName = node
+				Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+			}
+
+			createDevices(v1alpha1.WorkloadReference{Name: "sriov-device-plugin"})
+			markReconfigured("device1", "2024-01-01T00:00:00Z")
+
+			// Pods are not removed without a kubelet, only marked for deletion
+			Eventually(func() *metav1.Time {
+				pod := &v1.Pod{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "node1-pod", Namespace: namespaceName}, pod)).To(Succeed())
+				return pod.DeletionTimestamp
+			}).ShouldNot(BeNil())
+			Eventually(func() map[string]string {
+				return getDevice("device1").Annotations
+			}).Should(HaveKeyWithValue(consts.PostConfigurationHookAnnotation, "2024-01-01T00:00:00Z"))
+
+			pod := &v1.Pod{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "node2-pod", Namespace: namespaceName}, pod)).To(Succeed())
+			Expect(pod.DeletionTimestamp).To(BeNil())
+		})
+
+		It("should roll out the Deployment once all devices of the template are reconfigured", func() {
+			podLabels := map[string]string{"app": "rdma-controller"}
+			Expect(k8sClient.Create(ctx, &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "rdma-controller", Namespace: namespaceName},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: podLabels},
+					Template: v1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: podLabels},
+						Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "controller", Image: "controller"}}},
+					},
+				},
+			})).To(Succeed())
+			getRestartedAt := func() string {
+				deployment := &appsv1.Deployment{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "rdma-controller", Namespace: namespaceName}, deployment)).To(Succeed())
+				return deployment.Spec.Template.Annotations[consts.RestartedAtAnnotation]
+			}
+
+			createDevices(v1alpha1.WorkloadReference{
+				Kind:     consts.WorkloadKindDeployment,
+				Name:     "rdma-controller",
+				Strategy: consts.RestartStrategyRolloutRestart,
+			})
+			markReconfigured("device1", "2024-01-01T00:00:00Z")
+			Consistently(getRestartedAt).Should(BeEmpty())
+
+			markReconfigured("device2", "2024-01-01T00:01:00Z")
+			Eventually(getRestartedAt).ShouldNot(BeEmpty())
+			for _, name := range []string{"device1", "device2"} {
+				Eventually(func() map[string]string {
+					return getDevice(name).Annotations
+				}).Should(HaveKeyWithValue(consts.PostConfigurationHookAnnotation, getDevice(name).Annotations[consts.ReconfiguredAtAnnotation]))
+			}
+		})
+	})
 })
@@ -17,31 +17,47 @@ package controller
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/client-go/tools/record"
 
 	maintenanceoperator "github.com/Mellanox/maintenance-operator/api/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	k8sTypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/json"
 	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	v1alpha1 "github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
 	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
 	"github.com/Mellanox/nic-configuration-operator/pkg/host"
+	"github.com/Mellanox/nic-configuration-operator/pkg/lifecycle"
 	"github.com/Mellanox/nic-configuration-operator/pkg/maintenance"
 	"github.com/Mellanox/nic-configuration-operator/pkg/types"
 )
@@ -50,6 +66,27 @@ const nicDeviceSyncEventName = "nic-device-sync-event-name"
 
 var requeueTime = 1 * time.Minute
 
+// runtimeConfigPendingRequeueTime is the interval of the runtime config retries while it waits for the host,
+// e.g. for the driver to create the representors of the VFs after the PF is moved to the switchdev mode
+var runtimeConfigPendingRequeueTime = 5 * time.Second
+
+// pfcValueRegex matches the PFC values of the QoS settings, same as the validation of the QosSpec field
+var pfcValueRegex = regexp.MustCompile(`^([01],){7}[01]$`)
+
+// nv config updates of a device exceeding nvConfigChurnThreshold in nvConfigChurnWindow are reported as abnormal churn
+// a regular configuration change takes one or two updates, even with retries after failures
+var (
+	nvConfigChurnWindow    = 24 * time.Hour
+	nvConfigChurnThreshold = 5
+)
+
+// expected durations of the disruptive operations, used to estimate the start times in the node's disruption queue
+// the duration of the last FW reset on the node is used instead of the default once observed
+var (
+	firmwareResetEstimate = 2 * time.Minute
+	rebootEstimate        = 10 * time.Minute
+)
+
 // NicDeviceReconciler reconciles a NicDevice object
 type NicDeviceReconciler struct {
 	client.Client
@@ -60,9 +97,75 @@ type NicDeviceReconciler struct {
 
 	HostManager        host.HostManager
 	HostUtils          host.HostUtils
+	FirmwareManager    host.FirmwareManager
 	MaintenanceManager maintenance.MaintenanceManager
 
 	EventRecorder record.EventRecorder
+	// LifecycleEvents publishes the configuration lifecycle events of the devices, disabled if nil
+	LifecycleEvents *lifecycle.Emitter
+
+	// ProvisioningTaints is a list of node taint keys indicating that the node is being provisioned
+	// NIC configuration is held while any of these taints is present on the node
+	ProvisioningTaints []string
+	// WaitForNodeReady specifies whether NIC configuration should be held until the node reaches Ready for the first time
+	WaitForNodeReady bool
+	// StrictConvergence specifies whether the node is tainted with consts.NotConvergedTaintKey when the config daemon starts
+	// the taint is removed once all devices on the node are configured, workloads can't land on the node with drifted NICs
+	StrictConvergence bool
+	// RdmaResourcePrefixes is a list of resource name prefixes of the RDMA and SR-IOV device plugins, e.g. rdma/
+	// disruptive operations are delayed while the PodDisruptionBudgets of the pods on the node requesting these resources
+	// don't allow a disruption, PodDisruptionBudgets are not checked if the list is empty
+	RdmaResourcePrefixes []string
+	// APIReader reads the ConfigMaps and Secrets referenced by the templates directly from the API server
+	// the reconciler's client is used if not set
+	APIReader client.Reader
+	// RestartSyncWindow is the time over which the validation of the devices that converged before the config daemon
+	// restarted is spread, converged states are not tracked and all devices are validated right away if 0
+	RestartSyncWindow time.Duration
+	// DeepScanInterval is the interval of the deep scans of the devices, catching the drifts caused by out-of-band tools,
+	// each deep scan queries the firmware again and validates all devices regardless of the state memoized by the reconciler,
+	// deep scans are disabled if 0
+	DeepScanInterval time.Duration
+
+	// notConvergedTaintApplied is set once the not-converged taint was applied in this run of the config daemon
+	notConvergedTaintApplied bool
+	// convergenceObserved is set once all devices were configured in this run of the config daemon, the taint isn't applied again
+	convergenceObserved bool
+	// lastFirmwareResetDuration is the duration of the last successful FW reset on the node
+	lastFirmwareResetDuration time.Duration
+	// configOwnershipDenied contains devices whose nv config write was denied by the BMC or DPU
+	// with the generation and host privilege level at the time, the write is not retried while they don't change
+	configOwnershipDenied map[string]string
+	// operationsObserved contains devices whose persisted operation was already seen in this run of the config daemon
+	operationsObserved map[string]bool
+	// validationDeferredUntil contains the time of the first validation of each device in this run of the config daemon
+	// zero time means the device is reconciled as usual
+	validationDeferredUntil map[string]time.Time
+	// bootID is the boot ID of the host, the converged states of the devices are bound to it
+	bootID string
+	// lastDeepScan is the time of the last deep scan of the devices, the start of the config daemon before the first one
+	lastDeepScan time.Time
+	// workloadAttachPending is set while runtime settings of any device wait for an RDMA workload to be scheduled on the node
+	// the node is checked for the RDMA workloads every requeueTime only in this case
+	workloadAttachPending atomic.Bool
+	// channelsBlocked is set while the channel changes of any device wait for the disruption budgets of the RDMA workloads
+	channelsBlocked atomic.Bool
+	// bfbInstalls contains the BFB bundle installations of the devices started by installBFB and not yet reported
+	// by the device name, protected by bfbInstallsLock
+	bfbInstalls     map[string]*bfbInstall
+	bfbInstallsLock sync.Mutex
+	// bfbInstallEvents triggers a reconcile once a BFB bundle installation is done
+	bfbInstallEvents chan event.GenericEvent
+}
+
+// bfbInstall is a BFB bundle installation running in the background, reconciles are held until it's done
+type bfbInstall struct {
+	bundlePath string
+	started    time.Time
+	done       chan struct{}
+	// err and bfb are the result of the installation, set before done is closed
+	err error
+	bfb *v1alpha1.BFBStatus
 }
 
 type nicDeviceConfigurationStatuses []*nicDeviceConfigurationStatus
@@ -71,25 +174,88 @@ type nicDeviceConfigurationStatus struct {
 	device                 *v1alpha1.NicDevice
 	nvConfigUpdateRequired bool
 	rebootRequired         bool
-	lastStageError         error
+	// firmwareImage is the path to the firmware image to be burned to the device, empty if no burn is required
+	firmwareImage string
+	// bfbImage is the path to the BFB bundle to be installed to the BlueField DPU, empty if no installation is required
+	bfbImage string
+	// toolHang is set if a host tool got stuck while processing the device
+	toolHang bool
+	// resolvedTemplate is the device's template with the values from the referenced ConfigMaps and Secrets
+	// nil if the template doesn't reference any
+	resolvedTemplate *v1alpha1.ConfigurationTemplateSpec
+	// delegated is set if the device's nv config is owned by another host of a multi-host NIC
+	delegated bool
+	// ownershipDenied is set if the device's nv config is write-protected by the BMC or DPU
+	ownershipDenied bool
+	// runtimeConfigPending is set if the runtime settings wait for the host, e.g. for the representors of the VFs
+	runtimeConfigPending bool
+	// bfbInstallStarted is set if the installation of bfbImage was started in the background in this reconcile
+	bfbInstallStarted bool
+	lastStageError    error
 }
 
+//+kubebuilder:rbac:groups=configuration.net.nvidia.com,resources=nicfirmwaresources,verbs=get;list;watch
+//+kubebuilder:rbac:groups=configuration.net.nvidia.com,resources=nicnodestates,verbs=get;list;watch;create
+//+kubebuilder:rbac:groups=configuration.net.nvidia.com,resources=nicnodestates/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups="",namespace=system,resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",namespace=system,resources=configmaps,verbs=get;list;watch;create;update
+//+kubebuilder:rbac:groups="",resources=pods,verbs=list
+//+kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=list
+
 // Reconcile reconciles the NicConfigurationTemplate object
 func (r *NicDeviceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	err := r.applyNotConvergedTaint(ctx)
+	if err != nil {
+		log.Log.Error(err, "failed to apply the not-converged taint to the node", "node", r.NodeName)
+		return ctrl.Result{}, err
+	}
+
+	if r.bfbInstallsRunning() {
+		// Installation of the bundle resets the DPU, the devices are reconciled again once it's done
+		log.Log.V(2).Info("BFB bundle installation in progress, holding reconcile")
+		return ctrl.Result{RequeueAfter: requeueTime}, nil
+	}
+
 	configStatuses, err := r.getDevices(ctx)
 	if err != nil {
 		log.Log.Error(err, "failed to get devices to reconcile")
 		return ctrl.Result{}, err
 	}
 
+	err = r.acceptRevalidationRequests(ctx, configStatuses)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if now := time.Now(); r.deepScanDue(now) {
+		r.startDeepScan(ctx, configStatuses, now)
+	}
+
+	configStatuses, deferredValidationDelay := r.deferConvergedDevices(configStatuses)
+	deferredValidationDelay = r.untilDeepScan(time.Now(), deferredValidationDelay)
+
 	if len(configStatuses) == 0 {
 		err = r.MaintenanceManager.ReleaseMaintenance(ctx)
 		if err != nil {
 			log.Log.Error(err, "failed to release maintenance")
 			return ctrl.Result{}, err
 		}
-		// Nothing to reconcile
-		return ctrl.Result{}, nil
+		err = r.publishConfigHash(ctx)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		// Nothing to reconcile until the deferred devices are due
+		return ctrl.Result{RequeueAfter: deferredValidationDelay}, r.releaseNotConvergedTaint(ctx)
+	}
+
+	provisioningInProgress, err := r.nodeProvisioningInProgress(ctx)
+	if err != nil {
+		log.Log.Error(err, "failed to get node provisioning state")
+		return ctrl.Result{}, err
+	}
+	if provisioningInProgress {
+		log.Log.Info("node is being provisioned, holding NIC configuration", "node", r.NodeName)
+		return ctrl.Result{RequeueAfter: requeueTime}, nil
 	}
 
 	err = r.handleSpecValidation(ctx, configStatuses)
@@ -98,10 +264,113 @@ func (r *NicDeviceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, err
 	}
 
+	configStatuses = configStatuses.withoutDelegated()
+	if len(configStatuses) == 0 {
+		log.Log.Info("all devices are configured by other hosts")
+		err = r.MaintenanceManager.ReleaseMaintenance(ctx)
+		if err != nil {
+			log.Log.Error(err, "failed to release maintenance")
+			return ctrl.Result{}, err
+		}
+		err = r.publishConfigHash(ctx)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: deferredValidationDelay}, r.releaseNotConvergedTaint(ctx)
+	}
+
+	configStatuses = r.holdConsistencyGroups(ctx, configStatuses)
+	if len(configStatuses) == 0 {
+		log.Log.Info("all devices are held by their consistency groups, retrying later")
+		return ctrl.Result{RequeueAfter: requeueTime}, nil
+	}
+
+	configStatuses, toolHangDetected := configStatuses.withoutToolHangs()
+	if len(configStatuses) == 0 {
+		log.Log.Info("host tools got stuck for all devices, retrying later")
+		return ctrl.Result{RequeueAfter: requeueTime}, nil
+	}
+
+	configStatuses, ownershipDenied := configStatuses.withoutConfigOwnershipDenied()
+	if len(configStatuses) == 0 {
+		log.Log.Info("nv config ownership is denied for all devices, retrying later")
+		return ctrl.Result{RequeueAfter: requeueTime}, nil
+	}
+
+	err = r.validateFirmware(ctx, configStatuses)
+	if err != nil {
+		log.Log.Error(err, "failed to validate device's firmware")
+		return ctrl.Result{}, err
+	}
+
+	if configStatuses.firmwareUpdateRequired() {
+		// Burning the firmware only stages it, maintenance is required for the activation
+		log.Log.V(2).Info("firmware update required, burning firmware")
+
+		err = r.applyFirmware(ctx, configStatuses)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		configStatuses, toolHangDetected = configStatuses.withoutNewToolHangs(toolHangDetected)
+		if len(configStatuses) == 0 {
+			log.Log.Info("host tools got stuck for all devices, retrying later")
+			return ctrl.Result{RequeueAfter: requeueTime}, nil
+		}
+	}
+
+	if installsDone := r.bfbInstallsDone(configStatuses); configStatuses.bfbInstallRequired() || installsDone {
+		// Installation of the bundle reboots the DPU, its ports are down until the installed image boots
+		// the windows and budgets were checked before the installations that are done were started
+		if !installsDone {
+			windowOpen, result, err := r.waitForActivationWindows(ctx, configStatuses)
+			if err != nil || !windowOpen {
+				return result, err
+			}
+			disruptionAllowed, result, err := r.waitForDisruptionBudgets(ctx, configStatuses)
+			if err != nil || !disruptionAllowed {
+				return result, err
+			}
+		}
+
+		log.Log.V(2).Info("BFB bundle installation required, scheduling maintenance")
+
+		result, err := r.ensureMaintenance(ctx)
+		if err != nil {
+			log.Log.V(2).Error(err, "failed to schedule maintenance")
+			return ctrl.Result{}, err
+		}
+		if result.Requeue || result.RequeueAfter != 0 {
+			return result, nil
+		}
+
+		err = r.installBFB(ctx, configStatuses)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if configStatuses.bfbInstallStarted() {
+			log.Log.Info("BFB bundle installation started, the devices are reconciled again once it's done")
+			return ctrl.Result{RequeueAfter: requeueTime}, nil
+		}
+		configStatuses, toolHangDetected = configStatuses.withoutNewToolHangs(toolHangDetected)
+		if len(configStatuses) == 0 {
+			log.Log.Info("host tools got stuck for all devices, retrying later")
+			return ctrl.Result{RequeueAfter: requeueTime}, nil
+		}
+	}
+
 	if configStatuses.nvConfigUpdateRequired() {
+		windowOpen, result, err := r.waitForActivationWindows(ctx, configStatuses)
+		if err != nil || !windowOpen {
+			return result, err
+		}
+		disruptionAllowed, result, err := r.waitForDisruptionBudgets(ctx, configStatuses)
+		if err != nil || !disruptionAllowed {
+			return result, err
+		}
+
 		log.Log.V(2).Info("nv config update required, scheduling maintenance")
 
-		result, err := r.ensureMaintenance(ctx)
+		result, err = r.ensureMaintenance(ctx)
 		if err != nil {
 			log.Log.V(2).Error(err, "failed to schedule maintenance")
 			return ctrl.Result{}, err
@@ -114,6 +383,11 @@ func (r *NicDeviceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		if err != nil {
 			return ctrl.Result{}, err
 		}
+		configStatuses, toolHangDetected = configStatuses.withoutNewToolHangs(toolHangDetected)
+		if len(configStatuses) == 0 {
+			log.Log.Info("host tools got stuck for all devices, retrying later")
+			return ctrl.Result{RequeueAfter: requeueTime}, nil
+		}
 	}
 
 	if configStatuses.rebootRequired() {
@@ -129,16 +403,45 @@ func (r *NicDeviceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, err
 	}
 
+	if configStatuses.runtimeConfigPending() {
+		return ctrl.Result{RequeueAfter: runtimeConfigPendingRequeueTime}, nil
+	}
+
 	if configStatuses.rebootRequired() {
 		return r.handleReboot(ctx, configStatuses)
 	}
 
+	err = r.publishDisruptionQueue(ctx, nil, false)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
 	err = r.MaintenanceManager.ReleaseMaintenance(ctx)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{}, nil
+	err = r.publishConfigHash(ctx)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	err = r.releaseNotConvergedTaint(ctx)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if toolHangDetected || ownershipDenied {
+		// Devices with stuck tools or denied nv config ownership need to be processed again
+		return ctrl.Result{RequeueAfter: requeueTime}, nil
+	}
+
+	if (r.workloadAttachPending.Load() || r.channelsBlocked.Load()) && (deferredValidationDelay == 0 || deferredValidationDelay > requeueTime) {
+		// The pods aren't watched, the node is checked for the RDMA workloads and their budgets periodically while runtime settings are deferred
+		return ctrl.Result{RequeueAfter: requeueTime}, nil
+	}
+
+	return ctrl.Result{RequeueAfter: deferredValidationDelay}, nil
 }
 
 func (r *NicDeviceReconciler) getDevices(ctx context.Context) (nicDeviceConfigurationStatuses, error) {
@@ -177,160 +480,1423 @@ func (r *NicDeviceReconciler) getDevices(ctx context.Context) (nicDeviceConfigur
 			continue
 		}
 
-		configStatuses = append(configStatuses, &nicDeviceConfigurationStatus{
+		status := &nicDeviceConfigurationStatus{
 			device: &devices.Items[i],
-		})
+		}
+		if template := device.Spec.Configuration.Template; template != nil && (len(template.ValuesFrom) != 0 || len(template.SpeedValues) != 0) {
+			status.resolvedTemplate, err = r.resolveTemplateValues(ctx, status.device)
+			if err != nil {
+				log.Log.Error(err, "failed to resolve template values", "device", device.Name)
+				reason := consts.SpecValidationFailed
+				if types.IsIncorrectSpecError(err) {
+					reason = consts.IncorrectSpecReason
+				}
+				err = r.updateDeviceStatusCondition(ctx, status.device, reason, metav1.ConditionFalse, err.Error())
+				if err != nil {
+					log.Log.Error(err, "failed to update status condition", "device", device.Name)
+					return nil, err
+				}
+				continue
+			}
+		}
+
+		configStatuses = append(configStatuses, status)
 	}
 
 	return configStatuses, nil
 }
 
-// ensureMaintenance schedules maintenance if required and requests reschedule if it's not ready yet
-func (r *NicDeviceReconciler) ensureMaintenance(ctx context.Context) (ctrl.Result, error) {
-	err := r.MaintenanceManager.ScheduleMaintenance(ctx)
+// resolveTemplateValues returns a copy of the device's template with the fields overridden by the values for the device's port speed
+// and by the referenced ConfigMap and Secret keys
+// keys selected by node labels are looked up with the labels of the reconciler's node
+// returns types.IncorrectSpecError if a referenced key doesn't exist or its value doesn't fit the field
+func (r *NicDeviceReconciler) resolveTemplateValues(ctx context.Context, device *v1alpha1.NicDevice) (*v1alpha1.ConfigurationTemplateSpec, error) {
+	reader := r.apiReader()
+
+	node := &v1.Node{}
+	err := r.Client.Get(ctx, k8sTypes.NamespacedName{Name: r.NodeName}, node)
 	if err != nil {
-		log.Log.Error(err, "failed to schedule maintenance for node")
-		return ctrl.Result{}, err
+		log.Log.Error(err, "failed to get node object", "node", r.NodeName)
+		return nil, err
 	}
 
-	maintenanceAllowed, err := r.MaintenanceManager.MaintenanceAllowed(ctx)
-	if err != nil {
-		log.Log.Error(err, "failed to get maintenance status")
-		return ctrl.Result{}, err
+	template := device.Spec.Configuration.Template.DeepCopy()
+	if len(template.SpeedValues) != 0 {
+		speed, err := r.devicePortSpeed(ctx, device)
+		if err != nil {
+			log.Log.Error(err, "failed to get port speed", "device", device.Name)
+			return nil, err
+		}
+
+		for _, speedValues := range template.SpeedValues {
+			if speedValues.Speed != speed {
+				continue
+			}
+			for _, value := range speedValues.Values {
+				err = setTemplateField(template, value.Field, strings.TrimSpace(value.Value))
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
 	}
-	if !maintenanceAllowed {
-		log.Log.V(2).Info("maintenance not allowed yet, exiting for now")
-		// Maintenance not yet allowed, waiting until then
-		return ctrl.Result{RequeueAfter: requeueTime}, nil
+
+	for _, source := range template.ValuesFrom {
+		var value string
+		switch {
+		case source.ConfigMapKeyRef != nil && source.SecretKeyRef == nil:
+			configMap := &v1.ConfigMap{}
+			value, err = templateKeyValue(ctx, reader, r.NamespaceName, source.ConfigMapKeyRef, node, configMap, func() map[string]string {
+				return configMap.Data
+			})
+		case source.SecretKeyRef != nil && source.ConfigMapKeyRef == nil:
+			secret := &v1.Secret{}
+			value, err = templateKeyValue(ctx, reader, r.NamespaceName, source.SecretKeyRef, node, secret, func() map[string]string {
+				data := map[string]string{}
+				for key, value := range secret.Data {
+					data[key] = string(value)
+				}
+				return data
+			})
+		default:
+			err = types.IncorrectSpecError(fmt.Sprintf("value of field %s must reference either a ConfigMap or a Secret", source.Field))
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		err = setTemplateField(template, source.Field, strings.TrimSpace(value))
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return ctrl.Result{}, nil
+	return template, nil
 }
 
-// applyRuntimeConfig applies each device's runtime spec in parallel
-// if update is successful, applies status condition UpdateSuccessful, otherwise RuntimeConfigUpdateFailed
-// if rebootRequired, sets status condition PendingReboot
-// if status.rebootRequired == true, skips the device
-// returns nil if all devices' config updates were successful, error otherwise
-func (r *NicDeviceReconciler) applyRuntimeConfig(ctx context.Context, statuses nicDeviceConfigurationStatuses) error {
-	var wg sync.WaitGroup
+// enqueueForTemplateValues enqueues the sync of the node's devices if the template of any of them takes values
+// from the ConfigMap or, if secret is set, the Secret
+func (r *NicDeviceReconciler) enqueueForTemplateValues(ctx context.Context, object client.Object, secret bool,
+	qHandler func(q workqueue.TypedRateLimitingInterface[reconcile.Request]), q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	if object.GetNamespace() != r.NamespaceName {
+		return
+	}
 
-	for i := 0; i < len(statuses); i++ {
-		wg.Add(1)
-		go func(index int) {
-			defer wg.Done()
+	devices := &v1alpha1.NicDeviceList{}
+	err := r.Client.List(ctx, devices, &client.ListOptions{FieldSelector: fields.OneTermEqualSelector("status.node", r.NodeName)})
+	if err != nil {
+		log.Log.Error(err, "failed to list NicDevice CRs")
+		return
+	}
 
-			status := statuses[index]
-			status.lastStageError = nil
-			if status.rebootRequired {
+	for _, device := range devices.Items {
+		if device.Spec.Configuration == nil || device.Spec.Configuration.Template == nil {
+			continue
+		}
+		for _, source := range device.Spec.Configuration.Template.ValuesFrom {
+			selector := source.ConfigMapKeyRef
+			if secret {
+				selector = source.SecretKeyRef
+			}
+			if selector != nil && selector.Name == object.GetName() {
+				log.Log.Info("Enqueuing sync for template values update", "resource", object.GetName(), "device", device.Name)
+				qHandler(q)
 				return
 			}
+		}
+	}
+}
 
-			lastAppliedState, found := status.device.Annotations[consts.LastAppliedStateAnnotation]
-			if found {
-				specJson, err := json.Marshal(status.device.Spec)
-				if err != nil {
-					status.lastStageError = err
-					return
-				}
-
-				if string(specJson) != lastAppliedState {
-					log.Log.V(2).Info("last applied state differs, reboot required", "device", status.device.Name)
-					status.rebootRequired = true
+// devicePortSpeed returns the highest speed among the device's ports in the template format, e.g. 100G or 2.5G
+// the speed is persisted in the device's consts.PortSpeedAnnotation, so that the speed values keep applying while the links are down
+// returns empty string if none of the ports ever had an active link
+func (r *NicDeviceReconciler) devicePortSpeed(ctx context.Context, device *v1alpha1.NicDevice) (string, error) {
+	highest := 0
+	for _, port := range device.Status.Ports {
+		if port.NetworkInterface == "" {
+			continue
+		}
 
-					err := r.updateDeviceStatusCondition(ctx, status.device, consts.PendingRebootReason, metav1.ConditionTrue, "")
-					if err != nil {
-						status.lastStageError = err
-						return
-					}
+		speed, err := r.HostUtils.GetPortSpeed(port.PCI)
+		if err != nil {
+			return "", err
+		}
+		highest = max(highest, speed)
+	}
 
-					return
-				}
-			}
+	if highest == 0 {
+		return device.Annotations[consts.PortSpeedAnnotation], nil
+	}
 
-			err := r.HostManager.ApplyDeviceRuntimeSpec(statuses[index].device)
-			if err != nil {
-				statuses[index].lastStageError = err
-				err = r.updateDeviceStatusCondition(ctx, status.device, consts.RuntimeConfigUpdateFailedReason, metav1.ConditionFalse, err.Error())
-				if err != nil {
-					log.Log.Error(err, "failed to update device status condition", "device", status.device.Name)
-				}
-				return
-			}
+	speed := host.FormatPortSpeed(highest)
+	if device.Annotations[consts.PortSpeedAnnotation] != speed {
+		patch := client.MergeFrom(device.DeepCopy())
+		if device.Annotations == nil {
+			device.SetAnnotations(make(map[string]string))
+		}
+		device.Annotations[consts.PortSpeedAnnotation] = speed
+		err := r.Patch(ctx, device, patch)
+		if err != nil {
+			log.Log.Error(err, "failed to persist the port speed of the device", "device", device.Name)
+			return "", err
+		}
+	}
+	return speed, nil
+}
 
-			specJson, err := json.Marshal(status.device.Spec)
-			if err != nil {
-				status.lastStageError = err
-				return
-			}
+// apiReader returns the reader for the objects that are not watched by the reconciler
+func (r *NicDeviceReconciler) apiReader() client.Reader {
+	if r.APIReader == nil {
+		return r.Client
+	}
+	return r.APIReader
+}
 
-			if status.device.Annotations == nil {
-				status.device.SetAnnotations(make(map[string]string))
-			}
-			status.device.Annotations[consts.LastAppliedStateAnnotation] = string(specJson)
-			err = r.Update(ctx, status.device)
-			if err != nil {
-				status.lastStageError = err
-				return
-			}
+// templateKeyValue reads the value of the selected key of the ConfigMap or Secret
+func templateKeyValue(ctx context.Context, reader client.Reader, namespace string, selector *v1alpha1.TemplateKeySelector,
+	node *v1.Node, object client.Object, data func() map[string]string) (string, error) {
+	key := selector.Key
+	if label, found := node.Labels[selector.NodeLabel]; selector.NodeLabel != "" && found {
+		key = label
+	}
+	if key == "" {
+		return "", types.IncorrectSpecError(fmt.Sprintf("node %s doesn't have label %s to select the key of %s", node.Name, selector.NodeLabel, selector.Name))
+	}
 
-			err = r.updateDeviceStatusCondition(ctx, status.device, consts.UpdateSuccessfulReason, metav1.ConditionFalse, "")
-			if err != nil {
-				status.lastStageError = err
-				return
-			}
-		}(i)
+	err := reader.Get(ctx, k8sTypes.NamespacedName{Name: selector.Name, Namespace: namespace}, object)
+	if apierrors.IsNotFound(err) {
+		return "", types.IncorrectSpecError(fmt.Sprintf("%s %s referenced by the template not found", reflect.TypeOf(object).Elem().Name(), selector.Name))
+	}
+	if err != nil {
+		return "", err
 	}
 
-	wg.Wait()
+	value, found := data()[key]
+	if !found {
+		return "", types.IncorrectSpecError(fmt.Sprintf("key %s not found in %s %s", key, reflect.TypeOf(object).Elem().Name(), selector.Name))
+	}
+	return value, nil
+}
 
-	for _, status := range statuses {
-		if status.lastStageError != nil {
-			return status.lastStageError
+// setTemplateField sets the template field, the field names match the ones of TemplateValueSource
+func setTemplateField(template *v1alpha1.ConfigurationTemplateSpec, field string, value string) error {
+	switch {
+	case field == "numVfs":
+		numVfs, err := strconv.Atoi(value)
+		if err != nil || numVfs < 0 {
+			return types.IncorrectSpecError(fmt.Sprintf("invalid numVfs value %q", value))
+		}
+		template.NumVfs = numVfs
+	case field == "linkType":
+		if value != consts.Ethernet && value != consts.Infiniband {
+			return types.IncorrectSpecError(fmt.Sprintf("invalid linkType value %q, expected %s or %s", value, consts.Ethernet, consts.Infiniband))
+		}
+		template.LinkType = v1alpha1.LinkTypeEnum(value)
+	case field == "roceOptimized.qos.trust" || field == "roceOptimized.qos.pfc":
+		if template.RoceOptimized == nil || template.RoceOptimized.Qos == nil {
+			return types.IncorrectSpecError(fmt.Sprintf("field %s requires roceOptimized.qos in the template", field))
+		}
+		if field == "roceOptimized.qos.trust" {
+			template.RoceOptimized.Qos.Trust = value
+		} else if !pfcValueRegex.MatchString(value) {
+			return types.IncorrectSpecError(fmt.Sprintf("invalid pfc value %q", value))
+		} else {
+			template.RoceOptimized.Qos.PFC = value
+		}
+	case strings.HasPrefix(field, "rawNvConfig."):
+		name := strings.TrimPrefix(field, "rawNvConfig.")
+		index := slices.IndexFunc(template.RawNvConfig, func(param v1alpha1.NvConfigParam) bool { return param.Name == name })
+		if index == -1 {
+			template.RawNvConfig = append(template.RawNvConfig, v1alpha1.NvConfigParam{Name: name, Value: value})
+		} else {
+			template.RawNvConfig[index].Value = value
 		}
+	default:
+		return types.IncorrectSpecError(fmt.Sprintf("field %s can't be set from a ConfigMap or Secret", field))
 	}
 
 	return nil
 }
 
+// useResolvedTemplate replaces the device's template with the resolved one for the host calls
+// returns a function restoring the original template, resolved values are never written to the API server
+func (s *nicDeviceConfigurationStatus) useResolvedTemplate() func() {
+	if s.resolvedTemplate == nil {
+		return func() {}
+	}
+
+	original := s.device.Spec.Configuration.Template
+	s.device.Spec.Configuration.Template = s.resolvedTemplate
+	return func() {
+		s.device.Spec.Configuration.Template = original
+	}
+}
+
+// nodeProvisioningInProgress checks whether the node is still being provisioned by image-provisioning tooling
+// returns true if NIC configuration should be held for now
+func (r *NicDeviceReconciler) nodeProvisioningInProgress(ctx context.Context) (bool, error) {
+	node := &v1.Node{}
+	err := r.Client.Get(ctx, k8sTypes.NamespacedName{Name: r.NodeName}, node)
+	if err != nil {
+		log.Log.Error(err, "failed to get node object", "node", r.NodeName)
+		return false, err
+	}
+
+	if nodeUnderProvisioning(node, r.ProvisioningTaints, r.WaitForNodeReady) {
+		return true, nil
+	}
+
+	// Once the node reached Ready, we don't want to hold the configuration on subsequent NotReady transitions
+	if r.WaitForNodeReady && node.Annotations[consts.NodeReadyObservedAnnotation] != "true" {
+		patch := client.MergeFrom(node.DeepCopy())
+		if node.Annotations == nil {
+			node.Annotations = map[string]string{}
+		}
+		node.Annotations[consts.NodeReadyObservedAnnotation] = "true"
+
+		log.Log.Info("node reached Ready state for the first time", "node", r.NodeName)
+		err = r.Client.Patch(ctx, node, patch)
+		if err != nil {
+			log.Log.Error(err, "failed to annotate the node", "node", r.NodeName)
+			return false, err
+		}
+	}
+
+	return false, nil
+}
+
+// applyNotConvergedTaint taints the node with consts.NotConvergedTaintKey on the first reconciliation in the strict convergence mode
+func (r *NicDeviceReconciler) applyNotConvergedTaint(ctx context.Context) error {
+	if !r.StrictConvergence || r.notConvergedTaintApplied || r.convergenceObserved {
+		return nil
+	}
+
+	err := r.setNotConvergedTaint(ctx, true)
+	if err != nil {
+		return err
+	}
+	r.notConvergedTaintApplied = true
+	return nil
+}
+
+// releaseNotConvergedTaint removes the consts.NotConvergedTaintKey taint from the node in the strict convergence mode
+// if all devices on the node are converged, the taint is not applied again until the config daemon restarts
+func (r *NicDeviceReconciler) releaseNotConvergedTaint(ctx context.Context) error {
+	if !r.StrictConvergence || r.convergenceObserved {
+		return nil
+	}
+
+	devices := &v1alpha1.NicDeviceList{}
+	err := r.Client.List(ctx, devices, &client.ListOptions{FieldSelector: fields.OneTermEqualSelector("status.node", r.NodeName)})
+	if err != nil {
+		log.Log.Error(err, "failed to list NicDevice CRs")
+		return err
+	}
+	for _, device := range devices.Items {
+		if !deviceConverged(&device) {
+			log.Log.V(2).Info("device hasn't converged yet, keeping the not-converged taint", "device", device.Name)
+			return nil
+		}
+	}
+
+	err = r.setNotConvergedTaint(ctx, false)
+	if err != nil {
+		log.Log.Error(err, "failed to remove the not-converged taint from the node", "node", r.NodeName)
+		return err
+	}
+	r.convergenceObserved = true
+	return nil
+}
+
+// deviceConverged returns true if the current spec of the device is applied
+// devices without configuration spec and devices configured by another host are considered converged
+func deviceConverged(device *v1alpha1.NicDevice) bool {
+	if device.Spec.Configuration == nil {
+		return true
+	}
+
+	condition := meta.FindStatusCondition(device.Status.Conditions, consts.ConfigUpdateInProgressCondition)
+	if condition == nil || condition.ObservedGeneration != device.Generation {
+		return false
+	}
+	return condition.Reason == consts.UpdateSuccessfulReason || condition.Reason == consts.DelegatedToOtherHostReason
+}
+
+// setNotConvergedTaint adds or removes the consts.NotConvergedTaintKey NoSchedule taint of the node
+func (r *NicDeviceReconciler) setNotConvergedTaint(ctx context.Context, present bool) error {
+	node := &v1.Node{}
+	err := r.Client.Get(ctx, k8sTypes.NamespacedName{Name: r.NodeName}, node)
+	if err != nil {
+		log.Log.Error(err, "failed to get node object", "node", r.NodeName)
+		return err
+	}
+
+	found := slices.ContainsFunc(node.Spec.Taints, func(taint v1.Taint) bool { return taint.Key == consts.NotConvergedTaintKey })
+	if found == present {
+		return nil
+	}
+
+	// Taints are replaced as a whole list, the optimistic lock prevents overwriting taints set concurrently by others
+	patch := client.MergeFromWithOptions(node.DeepCopy(), client.MergeFromWithOptimisticLock{})
+	if present {
+		node.Spec.Taints = append(node.Spec.Taints, v1.Taint{Key: consts.NotConvergedTaintKey, Effect: v1.TaintEffectNoSchedule})
+	} else {
+		node.Spec.Taints = slices.DeleteFunc(node.Spec.Taints, func(taint v1.Taint) bool { return taint.Key == consts.NotConvergedTaintKey })
+	}
+
+	log.Log.Info("updating the not-converged taint of the node", "node", r.NodeName, "present", present)
+	return r.Client.Patch(ctx, node, patch)
+}
+
+// configHashLength is the number of hex digits of the sha256 digest published in consts.ConfigHashAnnotation
+const configHashLength = 12
+
+// publishConfigHash sets the node's consts.ConfigHashAnnotation to the hash of the configuration applied to its devices
+// the hash covers the serial number, firmware version and the applied state of each configured device,
+// the annotation is removed if no device on the node is configured
+func (r *NicDeviceReconciler) publishConfigHash(ctx context.Context) error {
+	devices := &v1alpha1.NicDeviceList{}
+	err := r.Client.List(ctx, devices, &client.ListOptions{FieldSelector: fields.OneTermEqualSelector("status.node", r.NodeName)})
+	if err != nil {
+		log.Log.Error(err, "failed to list NicDevice CRs")
+		return err
+	}
+
+	desired := configHash(devices.Items)
+
+	node := &v1.Node{}
+	err = r.Client.Get(ctx, k8sTypes.NamespacedName{Name: r.NodeName}, node)
+	if err != nil {
+		log.Log.Error(err, "failed to get node object", "node", r.NodeName)
+		return err
+	}
+
+	reported, found := node.Annotations[consts.ConfigHashAnnotation]
+	if reported == desired && found == (desired != "") {
+		return nil
+	}
+
+	patch := client.MergeFrom(node.DeepCopy())
+	if desired == "" {
+		delete(node.Annotations, consts.ConfigHashAnnotation)
+	} else {
+		if node.Annotations == nil {
+			node.Annotations = map[string]string{}
+		}
+		node.Annotations[consts.ConfigHashAnnotation] = desired
+	}
+
+	log.Log.Info("updating the config hash of the node", "node", r.NodeName, "configHash", desired)
+	return r.Client.Patch(ctx, node, patch)
+}
+
+// configHash returns a short hash of the configuration applied to the devices, independent of their order
+// devices without consts.LastAppliedStateAnnotation are skipped, returns empty string if there are none
+func configHash(devices []v1alpha1.NicDevice) string {
+	applied := []string{}
+	for _, device := range devices {
+		state, found := device.Annotations[consts.LastAppliedStateAnnotation]
+		if !found {
+			continue
+		}
+		applied = append(applied, strings.Join([]string{device.Status.SerialNumber, device.Status.FirmwareVersion, state}, "\n"))
+	}
+	if len(applied) == 0 {
+		return ""
+	}
+	slices.Sort(applied)
+
+	hash := sha256.New()
+	for _, entry := range applied {
+		hash.Write([]byte(entry))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:configHashLength]
+}
+
+// nodeUnderProvisioning returns true if the node has the provisioning annotation, one of the provisioning taints
+// or, if waitForReady is set, hasn't reached the Ready state for the first time yet (consts.NodeReadyObservedAnnotation)
+func nodeUnderProvisioning(node *v1.Node, provisioningTaints []string, waitForReady bool) bool {
+	if strings.EqualFold(node.Annotations[consts.NodeProvisioningAnnotation], "true") {
+		log.Log.V(2).Info("node has provisioning annotation", "node", node.Name)
+		return true
+	}
+
+	for _, taint := range node.Spec.Taints {
+		if slices.Contains(provisioningTaints, taint.Key) {
+			log.Log.V(2).Info("node has provisioning taint", "node", node.Name, "taint", taint.Key)
+			return true
+		}
+	}
+
+	if waitForReady && node.Annotations[consts.NodeReadyObservedAnnotation] != "true" {
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == v1.NodeReady && condition.Status == v1.ConditionTrue {
+				return false
+			}
+		}
+
+		log.Log.V(2).Info("node hasn't reached Ready state yet", "node", node.Name)
+		return true
+	}
+
+	return false
+}
+
+// ensureMaintenance schedules maintenance if required and requests reschedule if it's not ready yet
+func (r *NicDeviceReconciler) ensureMaintenance(ctx context.Context) (ctrl.Result, error) {
+	err := r.MaintenanceManager.ScheduleMaintenance(ctx)
+	if err != nil {
+		log.Log.Error(err, "failed to schedule maintenance for node")
+		return ctrl.Result{}, err
+	}
+
+	maintenanceAllowed, err := r.MaintenanceManager.MaintenanceAllowed(ctx)
+	if err != nil {
+		log.Log.Error(err, "failed to get maintenance status")
+		return ctrl.Result{}, err
+	}
+	if !maintenanceAllowed {
+		log.Log.V(2).Info("maintenance not allowed yet, exiting for now")
+		// Maintenance not yet allowed, waiting until then
+		return ctrl.Result{RequeueAfter: requeueTime}, nil
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// applyRuntimeConfig applies each device's runtime spec in parallel
+// if update is successful, applies status condition UpdateSuccessful, otherwise RuntimeConfigUpdateFailed
+// if rebootRequired, sets status condition PendingReboot
+// if status.rebootRequired == true, skips the device
+// returns nil if all devices' config updates were successful, error otherwise
+func (r *NicDeviceReconciler) applyRuntimeConfig(ctx context.Context, statuses nicDeviceConfigurationStatuses) error {
+	var wg sync.WaitGroup
+
+	bootID := ""
+	if r.RestartSyncWindow > 0 {
+		bootID = r.hostBootID()
+	}
+
+	consumer := ""
+	if slices.ContainsFunc(statuses, func(status *nicDeviceConfigurationStatus) bool {
+		return !status.rebootRequired && r.deferredUntilWorkloadAttach(status.device)
+	}) {
+		var err error
+		consumer, err = r.rdmaConsumer(ctx)
+		if err != nil {
+			log.Log.Error(err, "failed to list the RDMA workloads", "node", r.NodeName)
+			return err
+		}
+	}
+	var workloadAttachPending atomic.Bool
+
+	// Changing the channel count re-creates the queues of the interface and drops its traffic,
+	// the changes wait for the disruption budgets of the RDMA workloads like the nv config updates
+	channelsBlockingBudgets := []string{}
+	if len(r.RdmaResourcePrefixes) != 0 && slices.ContainsFunc(statuses, func(status *nicDeviceConfigurationStatus) bool {
+		return !status.rebootRequired && channelsRequested(status.device)
+	}) {
+		var err error
+		channelsBlockingBudgets, err = r.blockingDisruptionBudgets(ctx)
+		if err != nil {
+			log.Log.Error(err, "failed to check the disruption budgets of the RDMA workloads", "node", r.NodeName)
+			return err
+		}
+	}
+	var channelsBlocked atomic.Bool
+
+	for i := 0; i < len(statuses); i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+
+			status := statuses[index]
+			status.lastStageError = nil
+			status.runtimeConfigPending = false
+			if status.rebootRequired {
+				return
+			}
+
+			lastAppliedState, found := status.device.Annotations[consts.LastAppliedStateAnnotation]
+			if found {
+				specJson, err := appliedState(status.device)
+				if err != nil {
+					status.lastStageError = err
+					return
+				}
+
+				if specJson != lastAppliedState {
+					log.Log.V(2).Info("last applied state differs, reboot required", "device", status.device.Name)
+					status.rebootRequired = true
+
+					err := r.updateDeviceStatusCondition(ctx, status.device, consts.PendingRebootReason, metav1.ConditionTrue, "")
+					if err != nil {
+						status.lastStageError = err
+						return
+					}
+					err = r.setOperationPhase(ctx, status.device, consts.OperationPhaseAwaitingReboot)
+					if err != nil {
+						log.Log.Error(err, "failed to update device operation phase", "device", status.device.Name)
+					}
+
+					return
+				}
+			}
+
+			var err error
+			if operationInProgress(status.device) {
+				// The activated nv config was validated, the runtime config is verified and applied on top of it
+				// configured devices stay Done, runtime config drifts are fixed silently
+				err = r.setOperationPhase(ctx, status.device, consts.OperationPhaseVerifying)
+				if err != nil {
+					log.Log.Error(err, "failed to update device operation phase", "device", status.device.Name)
+				}
+			}
+
+			deferred := consumer == "" && r.deferredUntilWorkloadAttach(status.device)
+			blocked := len(channelsBlockingBudgets) != 0 && !deferred && channelsRequested(status.device)
+			attached := consumer != "" && r.deferredUntilWorkloadAttach(status.device) && runtimeSettingsDeferred(status.device) && !blocked
+			previouslyDeferred := status.device.Status.DeferredRuntimeSettings
+
+			ports := slices.Clone(status.device.Status.Ports)
+			partialRuntimeConfig := status.device.Status.PartialRuntimeConfig.DeepCopy()
+			appliedSysctls := slices.Clone(status.device.Status.AppliedSysctls)
+			qosConflict := meta.FindStatusCondition(status.device.Status.Conditions, consts.QosConflictCondition).DeepCopy()
+			restoreTemplate := status.useResolvedTemplate()
+			restoreDeferred := func() {}
+			var deferredSettings []string
+			if deferred {
+				restoreDeferred, deferredSettings = status.withoutDeferredSettings()
+			}
+			restoreChannels := func() {}
+			if blocked {
+				log.Log.Info("disruption budgets of the RDMA workloads don't allow a disruption, deferring the channel changes",
+					"device", status.device.Name, "budgets", channelsBlockingBudgets)
+				restoreChannels = status.withoutChannels()
+			}
+			started := time.Now()
+			err = r.HostManager.ApplyDeviceRuntimeSpec(statuses[index].device)
+			restoreChannels()
+			restoreDeferred()
+			restoreTemplate()
+			if !slices.Equal(ports, status.device.Status.Ports) ||
+				!reflect.DeepEqual(partialRuntimeConfig, status.device.Status.PartialRuntimeConfig) ||
+				!slices.Equal(appliedSysctls, status.device.Status.AppliedSysctls) ||
+				!reflect.DeepEqual(qosConflict, meta.FindStatusCondition(status.device.Status.Conditions, consts.QosConflictCondition)) {
+				// Renamed network interfaces, the ports with partially applied settings, the original values of the applied sysctls
+				// and the QoS conflicts are published right away, the following spec update resets the in-memory status
+				updateErr := r.Status().Update(ctx, status.device)
+				if updateErr != nil {
+					log.Log.Error(updateErr, "failed to update network interfaces, partial runtime config, applied sysctls and QoS conflicts of device",
+						"device", status.device.Name)
+				}
+			}
+			if types.IsRuntimeConfigPendingError(err) {
+				log.Log.Info("runtime config is pending, retrying later", "device", status.device.Name, "reason", err.Error())
+				status.runtimeConfigPending = true
+				err = r.updateDeviceStatusCondition(ctx, status.device, consts.UpdateStartedReason, metav1.ConditionTrue, err.Error())
+				if err != nil {
+					log.Log.Error(err, "failed to update device status condition", "device", status.device.Name)
+				}
+				return
+			}
+			if err != nil {
+				statuses[index].lastStageError = err
+				reason := consts.RuntimeConfigUpdateFailedReason
+				if types.IsIncorrectSpecError(err) {
+					reason = consts.IncorrectSpecReason
+				} else {
+					r.emitFailureDiagnostics(status.device, started)
+				}
+				err = r.updateDeviceStatusCondition(ctx, status.device, reason, metav1.ConditionFalse, err.Error())
+				if err != nil {
+					log.Log.Error(err, "failed to update device status condition", "device", status.device.Name)
+				}
+				return
+			}
+
+			specJson, err := appliedState(status.device)
+			if err != nil {
+				status.lastStageError = err
+				return
+			}
+
+			if status.device.Annotations == nil {
+				status.device.SetAnnotations(make(map[string]string))
+			}
+			statusCondition := meta.FindStatusCondition(status.device.Status.Conditions, consts.ConfigUpdateInProgressCondition)
+			if statusCondition == nil || statusCondition.Reason != consts.UpdateSuccessfulReason || attached {
+				// The operator runs the post configuration hook of the template for the reconfigured devices
+				status.device.Annotations[consts.ReconfiguredAtAnnotation] = time.Now().UTC().Format(time.RFC3339Nano)
+			}
+			status.device.Annotations[consts.LastAppliedStateAnnotation] = specJson
+			if bootID != "" {
+				state, err := convergedState(status, bootID)
+				if err != nil {
+					status.lastStageError = err
+					return
+				}
+				status.device.Annotations[consts.ConvergedStateAnnotation] = state
+			}
+			err = r.Update(ctx, status.device)
+			if err != nil {
+				status.lastStageError = err
+				return
+			}
+
+			// Updated firmware is active once the device converges
+			r.clearFirmwareUpdatePhase(ctx, status.device)
+
+			message := ""
+			status.device.Status.DeferredRuntimeSettings = nil
+			if len(deferredSettings) != 0 {
+				message = runtimeSettingsDeferredMessage(deferredSettings)
+				status.device.Status.DeferredRuntimeSettings = deferredSettings
+				workloadAttachPending.Store(true)
+			}
+			if blocked {
+				message = fmt.Sprintf("channels settings are deferred while PodDisruptionBudgets %s don't allow a disruption",
+					strings.Join(channelsBlockingBudgets, ", "))
+				status.device.Status.DeferredRuntimeSettings = []string{"channels"}
+				channelsBlocked.Store(true)
+			}
+			err = r.updateDeviceStatusCondition(ctx, status.device, consts.UpdateSuccessfulReason, metav1.ConditionFalse, message)
+			if err != nil {
+				status.lastStageError = err
+				return
+			}
+			if attached {
+				log.Log.Info("RDMA workload scheduled on the node, deferred runtime settings applied", "device", status.device.Name, "pod", consumer)
+				r.EventRecorder.Event(status.device, v1.EventTypeNormal, consts.WorkloadAttachedReason,
+					fmt.Sprintf("Deferred %s settings applied, triggered by pod %s", strings.Join(previouslyDeferred, ", "), consumer))
+			}
+			err = r.setOperationPhase(ctx, status.device, consts.OperationPhaseDone)
+			if err != nil {
+				log.Log.Error(err, "failed to update device operation phase", "device", status.device.Name)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	r.workloadAttachPending.Store(workloadAttachPending.Load())
+	r.channelsBlocked.Store(channelsBlocked.Load())
+
+	for _, status := range statuses {
+		if status.lastStageError != nil {
+			return status.lastStageError
+		}
+	}
+
+	return nil
+}
+
+// appliedState returns the device's spec stored in the consts.LastAppliedStateAnnotation
+// the post configuration hook doesn't affect the device's configuration and is omitted
+func appliedState(device *v1alpha1.NicDevice) (string, error) {
+	spec := device.Spec.DeepCopy()
+	if spec.Configuration != nil {
+		spec.Configuration.PostConfigurationHook = nil
+	}
+
+	specJson, err := json.Marshal(spec)
+	return string(specJson), err
+}
+
+// waitForActivationWindows checks the activation windows of the devices pending nv config update, BFB installation or reboot
+// if a window is closed, applies status condition PendingActivationWindow to the device
+// returns true if all windows are open, otherwise requeues the request until the earliest window opens
+// returns err if the window spec is incorrect or the status update failed
+func (r *NicDeviceReconciler) waitForActivationWindows(ctx context.Context, statuses nicDeviceConfigurationStatuses) (bool, ctrl.Result, error) {
+	now := time.Now()
+	var nextOpening time.Time
+	for _, status := range statuses {
+		window := status.device.Spec.Configuration.ActivationWindow
+		if window == nil || !(status.nvConfigUpdateRequired || status.rebootRequired || status.bfbImage != "") {
+			continue
+		}
+
+		open, start, err := maintenance.ActivationWindowOpen(window, now)
+		if err != nil {
+			log.Log.Error(err, "failed to check the activation window", "device", status.device.Name)
+			updateErr := r.updateDeviceStatusCondition(ctx, status.device, consts.IncorrectSpecReason, metav1.ConditionFalse, err.Error())
+			if updateErr != nil {
+				return false, ctrl.Result{}, updateErr
+			}
+			return false, ctrl.Result{}, err
+		}
+		if open {
+			continue
+		}
+
+		log.Log.V(2).Info("activation window is closed, deferring the activation", "device", status.device.Name, "opens", start)
+		message := fmt.Sprintf("Activation is deferred until the activation window opens at %s", start.Format(time.RFC3339))
+		err = r.updateDeviceStatusCondition(ctx, status.device, consts.PendingActivationWindowReason, metav1.ConditionTrue, message)
+		if err != nil {
+			return false, ctrl.Result{}, err
+		}
+
+		if nextOpening.IsZero() || start.Before(nextOpening) {
+			nextOpening = start
+		}
+	}
+
+	if nextOpening.IsZero() {
+		return true, ctrl.Result{}, nil
+	}
+
+	return false, ctrl.Result{RequeueAfter: nextOpening.Sub(now)}, nil
+}
+
+// waitForDisruptionBudgets checks the PodDisruptionBudgets of the pods on the node requesting the RDMA and SR-IOV resources
+// if a budget doesn't allow a disruption, applies status condition BlockedByPDB to the devices pending nv config update, BFB installation or reboot
+// returns true if the disruption is allowed, otherwise requeues the request
+// returns err if failed to list the pods or budgets or the status update failed
+func (r *NicDeviceReconciler) waitForDisruptionBudgets(ctx context.Context, statuses nicDeviceConfigurationStatuses) (bool, ctrl.Result, error) {
+	if len(r.RdmaResourcePrefixes) == 0 {
+		return true, ctrl.Result{}, nil
+	}
+
+	blockingBudgets, err := r.blockingDisruptionBudgets(ctx)
+	if err != nil {
+		log.Log.Error(err, "failed to check the disruption budgets of the RDMA workloads", "node", r.NodeName)
+		return false, ctrl.Result{}, err
+	}
+	if len(blockingBudgets) == 0 {
+		return true, ctrl.Result{}, nil
+	}
+
+	log.Log.Info("disruption budgets of the RDMA workloads don't allow a disruption, deferring it", "node", r.NodeName, "budgets", blockingBudgets)
+	message := fmt.Sprintf("Disruption is blocked by PodDisruptionBudgets %s", strings.Join(blockingBudgets, ", "))
+	for _, status := range statuses {
+		if !(status.nvConfigUpdateRequired || status.rebootRequired || status.bfbImage != "") {
+			continue
+		}
+
+		err = r.updateDeviceStatusCondition(ctx, status.device, consts.BlockedByPDBReason, metav1.ConditionTrue, message)
+		if err != nil {
+			return false, ctrl.Result{}, err
+		}
+	}
+
+	return false, ctrl.Result{RequeueAfter: requeueTime}, nil
+}
+
+// blockingDisruptionBudgets returns the sorted names (namespace/name) of the PodDisruptionBudgets that don't allow a disruption
+// of the running pods on the node requesting the resources with one of the RdmaResourcePrefixes
+func (r *NicDeviceReconciler) blockingDisruptionBudgets(ctx context.Context) ([]string, error) {
+	pods := &v1.PodList{}
+	err := r.apiReader().List(ctx, pods, client.MatchingFields{"spec.nodeName": r.NodeName})
+	if err != nil {
+		return nil, err
+	}
+
+	budgetsByNamespace := map[string][]policyv1.PodDisruptionBudget{}
+	blocking := map[string]bool{}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.DeletionTimestamp != nil || pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed ||
+			!requestsResources(pod, r.RdmaResourcePrefixes) {
+			continue
+		}
+
+		budgets, found := budgetsByNamespace[pod.Namespace]
+		if !found {
+			budgetList := &policyv1.PodDisruptionBudgetList{}
+			err = r.apiReader().List(ctx, budgetList, client.InNamespace(pod.Namespace))
+			if err != nil {
+				return nil, err
+			}
+			budgets = budgetList.Items
+			budgetsByNamespace[pod.Namespace] = budgets
+		}
+
+		for _, budget := range budgets {
+			if budget.Status.DisruptionsAllowed > 0 {
+				continue
+			}
+
+			selector, err := metav1.LabelSelectorAsSelector(budget.Spec.Selector)
+			if err != nil {
+				log.Log.Error(err, "invalid selector of the PodDisruptionBudget", "name", budget.Name, "namespace", budget.Namespace)
+				continue
+			}
+			if selector.Matches(labels.Set(pod.Labels)) {
+				blocking[budget.Namespace+"/"+budget.Name] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(blocking))
+	for name := range blocking {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names, nil
+}
+
+// requestsResources returns true if any container of the pod requests a resource with one of the name prefixes
+func requestsResources(pod *v1.Pod, prefixes []string) bool {
+	containers := append(slices.Clone(pod.Spec.InitContainers), pod.Spec.Containers...)
+	for _, container := range containers {
+		for _, resources := range []v1.ResourceList{container.Resources.Requests, container.Resources.Limits} {
+			for name := range resources {
+				for _, prefix := range prefixes {
+					if strings.HasPrefix(string(name), prefix) {
+						return true
+					}
+				}
+			}
+		}
+	}
+
+	return false
+}
+
 // handleReboot schedules maintenance and reboots the node if maintenance is allowed
 // Before rebooting the node, strips LastAppliedState annotations from all devices
+// publishes the queue of the disruptive operations in the node's NicNodeState
 // returns true if requeue of the reconcile request is required, false otherwise
 // return err if encountered an error while performing maintenance scheduling / reboot
 func (r *NicDeviceReconciler) handleReboot(ctx context.Context, statuses nicDeviceConfigurationStatuses) (ctrl.Result, error) {
-	err := r.MaintenanceManager.ScheduleMaintenance(ctx)
+	windowOpen, result, err := r.waitForActivationWindows(ctx, statuses)
+	if err != nil || !windowOpen {
+		return result, err
+	}
+	disruptionAllowed, result, err := r.waitForDisruptionBudgets(ctx, statuses)
+	if err != nil || !disruptionAllowed {
+		return result, err
+	}
+
+	err = r.MaintenanceManager.ScheduleMaintenance(ctx)
 	if err != nil {
 		log.Log.Error(err, "failed to schedule maintenance for node")
 		return ctrl.Result{}, err
 	}
 
-	maintenanceAllowed, err := r.MaintenanceManager.MaintenanceAllowed(ctx)
-	if err != nil {
-		log.Log.Error(err, "failed to get maintenance status")
-		return ctrl.Result{}, err
-	}
-	if !maintenanceAllowed {
-		// Maintenance not yet allowed, waiting until then
-		return ctrl.Result{RequeueAfter: requeueTime}, nil
-	}
+	queue := r.disruptionQueue(statuses)
+
+	maintenanceAllowed, err := r.MaintenanceManager.MaintenanceAllowed(ctx)
+	if err != nil {
+		log.Log.Error(err, "failed to get maintenance status")
+		return ctrl.Result{}, err
+	}
+	if !maintenanceAllowed {
+		err = r.publishDisruptionQueue(ctx, queue, false)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		// Maintenance not yet allowed, waiting until then
+		return ctrl.Result{RequeueAfter: requeueTime}, nil
+	}
+
+	// We need to strip last applied state annotation before reboot as it resets the runtime configuration
+	err = r.stripLastAppliedStateAnnotations(ctx, statuses)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if r.resetFirmwareInsteadOfReboot(ctx, statuses, queue) {
+		// New nv config is active, the runtime config needs to be applied again
+		return ctrl.Result{Requeue: true}, nil
+	}
+
+	err = r.publishDisruptionQueue(ctx, []v1alpha1.DisruptiveOperation{rebootOperation(statuses)}, true)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	for _, status := range statuses {
+		if status.rebootRequired {
+			r.LifecycleEvents.Emit(ctx, lifecycle.RebootRequestedEvent, status.device, "", "rebooting the node to activate the nv config")
+		}
+	}
+
+	err = r.MaintenanceManager.Reboot()
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// resetFirmwareInsteadOfReboot activates the new nv config with a FW reset for devices preferring the fwReset disruption
+// FW resets are performed one device at a time in the order of the disruption queue
+// FW reset is skipped if other devices on the node require a reboot anyway, fallbacks are recorded in events
+// returns true if the node reboot is no longer required
+func (r *NicDeviceReconciler) resetFirmwareInsteadOfReboot(ctx context.Context, statuses nicDeviceConfigurationStatuses, queue []v1alpha1.DisruptiveOperation) bool {
+	if len(queue) == 0 || queue[0].Operation == consts.DisruptionReboot {
+		for _, status := range statuses {
+			if !status.rebootRequired || status.device.Spec.Configuration.Disruption != consts.DisruptionFwReset {
+				continue
+			}
+			if firmwareResetDone(status.device) {
+				// FW reset has already been performed for this spec but the config is still not active
+				log.Log.Info("nv config wasn't activated by FW reset, falling back to reboot", "device", status.device.Name)
+				r.EventRecorder.Event(status.device, v1.EventTypeWarning, consts.FirmwareResetFallbackReason,
+					"nv config wasn't activated by FW reset, falling back to node reboot")
+				continue
+			}
+			r.EventRecorder.Event(status.device, v1.EventTypeNormal, consts.FirmwareResetFallbackReason,
+				"FW reset skipped, other devices on the node require a reboot")
+		}
+		return false
+	}
+
+	rebootAvoided := true
+	for i, operation := range queue {
+		index := slices.IndexFunc(statuses, func(status *nicDeviceConfigurationStatus) bool {
+			return status.device.Name == operation.Devices[0]
+		})
+		status := statuses[index]
+
+		err := r.publishDisruptionQueue(ctx, queue[i:], true)
+		if err != nil {
+			log.Log.Error(err, "failed to publish the disruption queue", "node", r.NodeName)
+		}
+
+		started := time.Now()
+		err = r.HostUtils.ResetNicFirmware(ctx, host.NvConfigPCIAddress(status.device))
+		if err != nil {
+			log.Log.Error(err, "failed to reset NIC firmware, falling back to reboot", "device", status.device.Name)
+			r.EventRecorder.Event(status.device, v1.EventTypeWarning, consts.FirmwareResetFallbackReason,
+				fmt.Sprintf("FW reset failed, falling back to node reboot: %v", err))
+			rebootAvoided = false
+			continue
+		}
+		r.lastFirmwareResetDuration = time.Since(started)
+
+		err = r.markFirmwareResetDone(ctx, status.device)
+		if err != nil {
+			log.Log.Error(err, "failed to record the FW reset of the device", "device", status.device.Name)
+		}
+		r.EventRecorder.Event(status.device, v1.EventTypeNormal, consts.FirmwareResetReason, "nv config activated with FW reset")
+		status.rebootRequired = false
+	}
+
+	return rebootAvoided
+}
+
+// firmwareResetDone returns true if the nv config of the device's current spec generation was already activated with a FW reset
+func firmwareResetDone(device *v1alpha1.NicDevice) bool {
+	return device.Annotations[consts.FirmwareResetGenerationAnnotation] == strconv.FormatInt(device.Generation, 10)
+}
+
+// markFirmwareResetDone records the FW reset of the device's current spec generation in the device's annotations
+func (r *NicDeviceReconciler) markFirmwareResetDone(ctx context.Context, device *v1alpha1.NicDevice) error {
+	patch := client.MergeFrom(device.DeepCopy())
+	if device.Annotations == nil {
+		device.SetAnnotations(make(map[string]string))
+	}
+	device.Annotations[consts.FirmwareResetGenerationAnnotation] = strconv.FormatInt(device.Generation, 10)
+	return r.Patch(ctx, device, patch)
+}
+
+// disruptionQueue returns the disruptive operations required to activate the new nv config of the devices
+// devices preferring the fwReset disruption are reset one at a time in the order of their names
+// if any device requires a reboot, FW resets are skipped and a single reboot activates all devices
+func (r *NicDeviceReconciler) disruptionQueue(statuses nicDeviceConfigurationStatuses) []v1alpha1.DisruptiveOperation {
+	devicesToReset := []string{}
+	for _, status := range statuses {
+		if !status.rebootRequired {
+			continue
+		}
+		if status.device.Spec.Configuration.Disruption != consts.DisruptionFwReset || firmwareResetDone(status.device) {
+			return []v1alpha1.DisruptiveOperation{rebootOperation(statuses)}
+		}
+		devicesToReset = append(devicesToReset, status.device.Name)
+	}
+	slices.Sort(devicesToReset)
+
+	queue := []v1alpha1.DisruptiveOperation{}
+	for _, device := range devicesToReset {
+		queue = append(queue, v1alpha1.DisruptiveOperation{
+			Operation: consts.DisruptionFwReset,
+			Devices:   []string{device},
+			State:     consts.DisruptiveOperationPending,
+		})
+	}
+
+	return queue
+}
+
+// rebootOperation returns the node reboot activating all devices that require it
+func rebootOperation(statuses nicDeviceConfigurationStatuses) v1alpha1.DisruptiveOperation {
+	devices := []string{}
+	for _, status := range statuses {
+		if status.rebootRequired {
+			devices = append(devices, status.device.Name)
+		}
+	}
+	slices.Sort(devices)
+
+	return v1alpha1.DisruptiveOperation{
+		Operation: consts.DisruptionReboot,
+		Devices:   devices,
+		State:     consts.DisruptiveOperationPending,
+	}
+}
+
+// publishDisruptionQueue updates the disruption queue in the node's NicNodeState, creating it if needed
+// if started, the first operation is marked InProgress and the start times of the operations are estimated
+// the state is not written if the queue didn't change
+func (r *NicDeviceReconciler) publishDisruptionQueue(ctx context.Context, queue []v1alpha1.DisruptiveOperation, started bool) error {
+	status := v1alpha1.NicNodeStateStatus{Node: r.NodeName}
+	if len(queue) != 0 {
+		status.DisruptionQueue = make([]v1alpha1.DisruptiveOperation, len(queue))
+		copy(status.DisruptionQueue, queue)
+	}
+	if started {
+		startTime := time.Now()
+		for i := range status.DisruptionQueue {
+			operation := &status.DisruptionQueue[i]
+			if i == 0 {
+				operation.State = consts.DisruptiveOperationInProgress
+			}
+			operation.EstimatedStartTime = &metav1.Time{Time: startTime.Truncate(time.Second)}
+			startTime = startTime.Add(r.estimateDuration(operation.Operation))
+		}
+		status.EstimatedCompletionTime = &metav1.Time{Time: startTime.Truncate(time.Second)}
+	}
+
+	state := &v1alpha1.NicNodeState{}
+	err := r.Client.Get(ctx, k8sTypes.NamespacedName{Name: r.NodeName, Namespace: r.NamespaceName}, state)
+	if apierrors.IsNotFound(err) {
+		if len(status.DisruptionQueue) == 0 {
+			return nil
+		}
+
+		node := &v1.Node{}
+		err = r.Client.Get(ctx, k8sTypes.NamespacedName{Name: r.NodeName}, node)
+		if err != nil {
+			log.Log.Error(err, "failed to get node object", "node", r.NodeName)
+			return err
+		}
+
+		state = &v1alpha1.NicNodeState{ObjectMeta: metav1.ObjectMeta{Name: r.NodeName, Namespace: r.NamespaceName}}
+		err = controllerutil.SetOwnerReference(node, state, r.Scheme)
+		if err != nil {
+			log.Log.Error(err, "failed to set owner reference for node state")
+			return err
+		}
+
+		log.Log.Info("creating node state", "node", r.NodeName)
+		err = r.Client.Create(ctx, state)
+		if err != nil {
+			log.Log.Error(err, "failed to create node state", "node", r.NodeName)
+			return err
+		}
+	} else if err != nil {
+		log.Log.Error(err, "failed to get node state", "node", r.NodeName)
+		return err
+	}
+
+	// The environment of the node is published by the device discovery
+	status.Environment = state.Status.Environment
+	if reflect.DeepEqual(state.Status, status) {
+		return nil
+	}
+
+	log.Log.V(2).Info("updating disruption queue", "node", r.NodeName, "queue", status.DisruptionQueue)
+	state.Status = status
+	return r.Client.Status().Update(ctx, state)
+}
+
+// estimateDuration returns the expected duration of the disruptive operation
+func (r *NicDeviceReconciler) estimateDuration(operation v1alpha1.DisruptiveOperationEnum) time.Duration {
+	if operation == consts.DisruptionReboot {
+		return rebootEstimate
+	}
+	if r.lastFirmwareResetDuration != 0 {
+		return r.lastFirmwareResetDuration
+	}
+	return firmwareResetEstimate
+}
+
+// stripLastAppliedStateAnnotations deletes the consts.LastAppliedStateAnnotation from each device in parallel
+// returns error if at least one annotation update failed
+func (r *NicDeviceReconciler) stripLastAppliedStateAnnotations(ctx context.Context, statuses nicDeviceConfigurationStatuses) error {
+	var wg sync.WaitGroup
+
+	for i := 0; i < len(statuses); i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+
+			status := statuses[index]
+			status.lastStageError = nil
+
+			annotations := status.device.GetAnnotations()
+			if _, found := annotations[consts.LastAppliedStateAnnotation]; !found {
+				return
+			}
+
+			delete(annotations, consts.LastAppliedStateAnnotation)
+			status.device.SetAnnotations(annotations)
+			status.lastStageError = r.Update(ctx, status.device)
+		}(i)
+	}
+
+	wg.Wait()
+
+	for _, status := range statuses {
+		if status.lastStageError != nil {
+			return status.lastStageError
+		}
+	}
+
+	return nil
+}
+
+// applyNvConfig applies each device's non-volatile spec in parallel
+// if update is correct, applies status condition PendingReboot, otherwise NonVolatileConfigUpdateFailed
+// or RolledBack if the host manager restored the previous nv config after a failure, or if another device of its consistency group failed
+// sets rebootRequired flags for each device's configuration status
+// if status.nvConfigUpdateRequired == false, skips the device
+// returns nil if all devices' config updates were successful, error otherwise
+func (r *NicDeviceReconciler) applyNvConfig(ctx context.Context, statuses nicDeviceConfigurationStatuses) error {
+	var wg sync.WaitGroup
+
+	for i := 0; i < len(statuses); i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+
+			status := statuses[index]
+			status.lastStageError = nil
+			if !status.nvConfigUpdateRequired {
+				return
+			}
+
+			writesBefore := countNvConfigWrites(status.device)
+			restoreTemplate := status.useResolvedTemplate()
+			started := time.Now()
+			rebootRequired, err := r.HostManager.ApplyDeviceNvSpec(ctx, statuses[index].device)
+			restoreTemplate()
+			if countNvConfigWrites(status.device) != writesBefore {
+				// Writes are counted even if the update failed midway, they have consumed flash cycles anyway
+				statusErr := r.trackNvConfigChurn(ctx, status.device)
+				if statusErr != nil {
+					log.Log.Error(statusErr, "failed to update nv config write counters", "device", status.device.Name)
+				}
+			}
+			if err != nil {
+				statuses[index].lastStageError = err
+				reason := consts.NonVolatileConfigUpdateFailedReason
+				message := err.Error()
+				if types.IsIncorrectSpecError(err) {
+					reason = consts.IncorrectSpecReason
+				} else if types.IsFabricFeatureNotSupportedError(err) {
+					reason = consts.FabricFeatureNotSupportedReason
+				} else if types.IsToolHangError(err) {
+					// A wedged tool shouldn't block the configuration of other devices, skipping this device until the next reconcile
+					reason = consts.DeviceToolHangReason
+					status.toolHang = true
+					status.lastStageError = nil
+				} else if types.IsRolledBackError(err) {
+					reason = consts.RolledBackReason
+				} else if types.IsConfigOwnershipDeniedError(err) {
+					// Retrying the write fails the same way until the ownership is granted to the host
+					reason = consts.ConfigOwnershipDeniedReason
+					message = fmt.Sprintf("%s, %s", message, consts.ConfigOwnershipDeniedHint)
+					status.ownershipDenied = true
+					status.lastStageError = nil
+					r.EventRecorder.Event(status.device, v1.EventTypeWarning, consts.ConfigOwnershipDeniedReason, message)
+				}
+				if reason != consts.IncorrectSpecReason && reason != consts.ConfigOwnershipDeniedReason {
+					r.emitFailureDiagnostics(status.device, started)
+				}
+				err = r.updateDeviceStatusCondition(ctx, status.device, reason, metav1.ConditionFalse, message)
+				if err != nil {
+					log.Log.Error(err, "failed to update device status condition", "device", status.device.Name)
+				}
+				return
+			}
+			err = r.updateDeviceStatusCondition(ctx, status.device, consts.PendingRebootReason, metav1.ConditionTrue, "")
+			if err != nil {
+				status.lastStageError = err
+			}
+			err = r.setOperationPhase(ctx, status.device, consts.OperationPhaseAwaitingReboot)
+			if err != nil {
+				log.Log.Error(err, "failed to update device operation phase", "device", status.device.Name)
+			}
+
+			statuses[index].rebootRequired = rebootRequired
+		}(i)
+	}
+
+	wg.Wait()
+
+	// Devices of a consistency group proceed to the reboot together or not at all
+	r.rollbackIncompleteConsistencyGroups(ctx, statuses)
+
+	for _, status := range statuses {
+		if status.ownershipDenied {
+			if r.configOwnershipDenied == nil {
+				r.configOwnershipDenied = map[string]string{}
+			}
+			r.configOwnershipDenied[status.device.Name] = r.configOwnershipKey(status.device)
+		} else if status.nvConfigUpdateRequired && status.lastStageError == nil {
+			delete(r.configOwnershipDenied, status.device.Name)
+		}
+	}
+
+	for _, status := range statuses {
+		if status.lastStageError != nil {
+			return status.lastStageError
+		}
+	}
+
+	return nil
+}
+
+// configOwnershipKey returns the generation of the device and the privilege level of the host for it
+// the privilege level is empty if it can't be determined, e.g. the tools don't support the query
+func (r *NicDeviceReconciler) configOwnershipKey(device *v1alpha1.NicDevice) string {
+	level, err := r.HostUtils.GetHostPrivilegeLevel(host.NvConfigPCIAddress(device))
+	if err != nil {
+		log.Log.V(2).Info("failed to get host privilege level", "device", device.Name, "err", err.Error())
+	}
+	return fmt.Sprintf("%d/%s", device.Generation, level)
+}
+
+// configOwnershipStillDenied returns true if the device's nv config write was denied by the BMC or DPU
+// and neither its generation nor the host privilege level has changed since
+func (r *NicDeviceReconciler) configOwnershipStillDenied(device *v1alpha1.NicDevice) bool {
+	key, denied := r.configOwnershipDenied[device.Name]
+	if !denied {
+		return false
+	}
+	if key != r.configOwnershipKey(device) {
+		log.Log.Info("nv config ownership or spec might have changed, retrying the update", "device", device.Name)
+		return false
+	}
+	return true
+}
+
+// validateFirmware validates each device's requested firmware in parallel
+// if the device's firmware differs from the image in its NicFirmwareSource, sets firmwareImage of the device's configuration status
+// if the BlueField DPU doesn't have the BFB bundle of its NicFirmwareSource installed, sets bfbImage of the device's configuration status
+// if the source is missing or has no image for the device, applies status condition IncorrectSpec
+// if the device's firmware doesn't match the version pinned in the spec, applies status condition FirmwareMismatch
+// if the source's binaries fail the checksum or signature verification, applies status condition VerificationFailed
+// if the device would reject the source's image, e.g. an unsigned one, applies status condition FirmwareRejected
+// returns nil if all devices' firmware requests are correct, error otherwise
+func (r *NicDeviceReconciler) validateFirmware(ctx context.Context, statuses nicDeviceConfigurationStatuses) error {
+	var wg sync.WaitGroup
+
+	for i := 0; i < len(statuses); i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+
+			status := statuses[index]
+			status.lastStageError = nil
+			status.firmwareImage = ""
+			status.bfbImage = ""
+
+			firmware := status.device.Spec.Configuration.Template.Firmware
+			if firmware == nil {
+				return
+			}
+			if status.device.Status.ConfigurationMode == consts.ConfigurationModeRestricted {
+				// The firmware of the VF passed through to a VM is updated from the hypervisor host
+				log.Log.V(2).Info("skipping firmware of the restricted device", "device", status.device.Name)
+				return
+			}
+
+			var err error
+			if firmware.NicFirmwareSourceRef != "" {
+				source := &v1alpha1.NicFirmwareSource{}
+				err = r.Get(ctx, k8sTypes.NamespacedName{Name: firmware.NicFirmwareSourceRef, Namespace: r.NamespaceName}, source)
+				if apierrors.IsNotFound(err) {
+					err = types.IncorrectSpecError(fmt.Sprintf("NicFirmwareSource %s not found", firmware.NicFirmwareSourceRef))
+				}
+				var credentials types.RegistryCredentials
+				if err == nil {
+					credentials, err = r.registryCredentials(ctx, source)
+				}
+				progressCtx := r.withFirmwareProgress(ctx, status.device)
+				if err == nil {
+					status.firmwareImage, err = r.FirmwareManager.ValidateRequestedFirmware(progressCtx, status.device, source, credentials)
+				}
+				if err == nil && source.Spec.BFBUrlSource != "" {
+					status.bfbImage, err = r.FirmwareManager.ValidateRequestedBFB(progressCtx, status.device, source, credentials)
+				}
+			}
+			// Binaries are in the cache now, flashing is reported once it starts
+			if update := status.device.Status.FirmwareUpdate; update != nil && update.Phase == consts.FirmwareUpdatePhaseDownloading {
+				r.clearFirmwareUpdatePhase(ctx, status.device)
+			}
+			// Pinned version is verified once the source's firmware is burned
+			if err == nil && status.firmwareImage == "" {
+				err = host.ValidateFirmwareVersion(status.device)
+			}
+			if err != nil {
+				status.lastStageError = err
+				reason := consts.FirmwareUpdateFailedReason
+				if types.IsIncorrectSpecError(err) {
+					reason = consts.IncorrectSpecReason
+				} else if types.IsFirmwareMismatchError(err) {
+					reason = consts.FirmwareMismatchReason
+				} else if types.IsVerificationFailedError(err) {
+					reason = consts.VerificationFailedReason
+				} else if types.IsFirmwareRejectedError(err) {
+					reason = consts.FirmwareRejectedReason
+				}
+				err = r.updateDeviceStatusCondition(ctx, status.device, reason, metav1.ConditionFalse, err.Error())
+				if err != nil {
+					log.Log.Error(err, "failed to update device status condition", "device", status.device.Name)
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
 
-	// We need to strip last applied state annotation before reboot as it resets the runtime configuration
-	err = r.stripLastAppliedStateAnnotations(ctx, statuses)
-	if err != nil {
-		return ctrl.Result{}, err
+	for _, status := range statuses {
+		if status.lastStageError != nil {
+			return status.lastStageError
+		}
 	}
 
-	err = r.MaintenanceManager.Reboot()
-	if err != nil {
-		return ctrl.Result{}, err
+	return nil
+}
+
+// registryCredentials reads the registry credentials from the pull secrets of the firmware source
+// returns types.IncorrectSpecError if a secret is missing or isn't a docker config
+func (r *NicDeviceReconciler) registryCredentials(ctx context.Context, source *v1alpha1.NicFirmwareSource) (types.RegistryCredentials, error) {
+	credentials := types.RegistryCredentials{}
+	for _, pullSecret := range source.Spec.ImagePullSecrets {
+		secret := &v1.Secret{}
+		err := r.apiReader().Get(ctx, k8sTypes.NamespacedName{Name: pullSecret, Namespace: r.NamespaceName}, secret)
+		if apierrors.IsNotFound(err) {
+			return nil, types.IncorrectSpecError(fmt.Sprintf("pull secret %s of NicFirmwareSource %s not found", pullSecret, source.Name))
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		data, found := secret.Data[v1.DockerConfigJsonKey]
+		if !found {
+			return nil, types.IncorrectSpecError(fmt.Sprintf("pull secret %s has no %s key", pullSecret, v1.DockerConfigJsonKey))
+		}
+		err = credentials.ParseDockerConfigJSON(data)
+		if err != nil {
+			return nil, types.IncorrectSpecError(fmt.Sprintf("pull secret %s: %v", pullSecret, err))
+		}
 	}
 
-	return ctrl.Result{}, nil
+	return credentials, nil
 }
 
-// stripLastAppliedStateAnnotations deletes the consts.LastAppliedStateAnnotation from each device in parallel
-// returns error if at least one annotation update failed
-func (r *NicDeviceReconciler) stripLastAppliedStateAnnotations(ctx context.Context, statuses nicDeviceConfigurationStatuses) error {
+// applyFirmware burns the requested firmware to each device in parallel
+// if burn is successful, applies status condition PendingReboot, otherwise FirmwareUpdateFailed
+// sets rebootRequired flags for the devices with burned firmware, the new firmware is activated with the nv config
+// if status.firmwareImage is empty, skips the device
+// returns nil if all devices' firmware burns were successful, error otherwise
+func (r *NicDeviceReconciler) applyFirmware(ctx context.Context, statuses nicDeviceConfigurationStatuses) error {
 	var wg sync.WaitGroup
 
 	for i := 0; i < len(statuses); i++ {
@@ -340,15 +1906,54 @@ func (r *NicDeviceReconciler) stripLastAppliedStateAnnotations(ctx context.Conte
 
 			status := statuses[index]
 			status.lastStageError = nil
+			if status.firmwareImage == "" {
+				return
+			}
 
-			annotations := status.device.GetAnnotations()
-			if _, found := annotations[consts.LastAppliedStateAnnotation]; !found {
+			err := r.setOperationPhase(ctx, status.device, consts.OperationPhaseApplying)
+			if err != nil {
+				log.Log.Error(err, "failed to update device operation phase", "device", status.device.Name)
+			}
+
+			started := time.Now()
+			err = r.FirmwareManager.BurnFirmware(r.withFirmwareProgress(ctx, status.device), status.device, status.firmwareImage)
+			if err != nil {
+				status.lastStageError = err
+				reason := consts.FirmwareUpdateFailedReason
+				if types.IsToolHangError(err) {
+					// A wedged tool shouldn't block the configuration of other devices, skipping this device until the next reconcile
+					reason = consts.DeviceToolHangReason
+					status.toolHang = true
+					status.lastStageError = nil
+				}
+				r.emitFailureDiagnostics(status.device, started)
+				r.clearFirmwareUpdatePhase(ctx, status.device)
+				err = r.updateDeviceStatusCondition(ctx, status.device, reason, metav1.ConditionFalse, err.Error())
+				if err != nil {
+					log.Log.Error(err, "failed to update device status condition", "device", status.device.Name)
+				}
 				return
 			}
 
-			delete(annotations, consts.LastAppliedStateAnnotation)
-			status.device.SetAnnotations(annotations)
-			status.lastStageError = r.Update(ctx, status.device)
+			message := fmt.Sprintf("Firmware %s burned, pending activation", status.device.Status.FirmwareVersion)
+			r.EventRecorder.Event(status.device, v1.EventTypeNormal, consts.FirmwareBurnedReason, message)
+			err = r.setFirmwareUpdatePhase(ctx, status.device, consts.FirmwareUpdatePhaseAwaitingActivation, 0)
+			if err != nil {
+				log.Log.Error(err, "failed to update firmware update progress", "device", status.device.Name)
+			}
+			// Burned firmware version is published right away to not burn the image again before the activation
+			err = r.updateDeviceStatusCondition(ctx, status.device, consts.PendingRebootReason, metav1.ConditionTrue, message)
+			if err != nil {
+				status.lastStageError = err
+			}
+
+			err = r.setOperationPhase(ctx, status.device, consts.OperationPhaseAwaitingReboot)
+			if err != nil {
+				log.Log.Error(err, "failed to update device operation phase", "device", status.device.Name)
+			}
+
+			status.firmwareImage = ""
+			status.rebootRequired = true
 		}(i)
 	}
 
@@ -363,12 +1968,14 @@ func (r *NicDeviceReconciler) stripLastAppliedStateAnnotations(ctx context.Conte
 	return nil
 }
 
-// applyNvConfig applies each device's non-volatile spec in parallel
-// if update is correct, applies status condition PendingReboot, otherwise NonVolatileConfigUpdateFailed
-// sets rebootRequired flags for each device's configuration status
-// if status.nvConfigUpdateRequired == false, skips the device
-// returns nil if all devices' config updates were successful, error otherwise
-func (r *NicDeviceReconciler) applyNvConfig(ctx context.Context, statuses nicDeviceConfigurationStatuses) error {
+// installBFB starts the installation of the requested BFB bundle to each BlueField DPU in the background
+// and sets bfbInstallStarted flags, the reconciles are held until the installations are done
+// reports the installations that are done, applies status condition PendingReboot if installation is successful,
+// otherwise FirmwareUpdateFailed, sets rebootRequired flags for the reinstalled DPUs, the NIC firmware of the bundle
+// is activated with the nv config
+// if status.bfbImage is empty and no installation of the device is done, skips the device
+// returns nil if all installations that are done were successful, error otherwise
+func (r *NicDeviceReconciler) installBFB(ctx context.Context, statuses nicDeviceConfigurationStatuses) error {
 	var wg sync.WaitGroup
 
 	for i := 0; i < len(statuses); i++ {
@@ -378,31 +1985,60 @@ func (r *NicDeviceReconciler) applyNvConfig(ctx context.Context, statuses nicDev
 
 			status := statuses[index]
 			status.lastStageError = nil
-			if !status.nvConfigUpdateRequired {
+			install := r.takeBFBInstall(status.device.Name)
+			if install == nil {
+				if status.bfbImage == "" {
+					return
+				}
+
+				err := r.setOperationPhase(ctx, status.device, consts.OperationPhaseApplying)
+				if err != nil {
+					log.Log.Error(err, "failed to update device operation phase", "device", status.device.Name)
+				}
+				r.startBFBInstall(ctx, status.device, status.bfbImage)
+				status.bfbInstallStarted = true
 				return
 			}
 
-			rebootRequired, err := r.HostManager.ApplyDeviceNvSpec(ctx, statuses[index].device)
-			if err != nil {
-				statuses[index].lastStageError = err
-				if types.IsIncorrectSpecError(err) {
-					err = r.updateDeviceStatusCondition(ctx, status.device, consts.IncorrectSpecReason, metav1.ConditionFalse, err.Error())
-					if err != nil {
-						log.Log.Error(err, "failed to update device status condition", "device", status.device.Name)
-					}
-				} else {
-					err = r.updateDeviceStatusCondition(ctx, status.device, consts.NonVolatileConfigUpdateFailedReason, metav1.ConditionFalse, err.Error())
-					if err != nil {
-						log.Log.Error(err, "failed to update device status condition", "device", status.device.Name)
-					}
+			if install.err != nil {
+				err := install.err
+				status.lastStageError = err
+				reason := consts.FirmwareUpdateFailedReason
+				if types.IsToolHangError(err) {
+					// A wedged tool shouldn't block the configuration of other devices, skipping this device until the next reconcile
+					reason = consts.DeviceToolHangReason
+					status.toolHang = true
+					status.lastStageError = nil
+				}
+				r.emitFailureDiagnostics(status.device, install.started)
+				r.clearFirmwareUpdatePhase(ctx, status.device)
+				err = r.updateDeviceStatusCondition(ctx, status.device, reason, metav1.ConditionFalse, err.Error())
+				if err != nil {
+					log.Log.Error(err, "failed to update device status condition", "device", status.device.Name)
 				}
+				return
 			}
-			err = r.updateDeviceStatusCondition(ctx, status.device, consts.PendingRebootReason, metav1.ConditionTrue, "")
+
+			status.device.Status.BFB = install.bfb
+			message := fmt.Sprintf("BFB bundle %s installed, pending activation", status.device.Status.BFB.Bundle)
+			r.EventRecorder.Event(status.device, v1.EventTypeNormal, consts.BFBInstalledReason, message)
+			err := r.setFirmwareUpdatePhase(ctx, status.device, consts.FirmwareUpdatePhaseAwaitingActivation, 0)
+			if err != nil {
+				log.Log.Error(err, "failed to update firmware update progress", "device", status.device.Name)
+			}
+			// Installed bundle is published right away to not install it again before the activation
+			err = r.updateDeviceStatusCondition(ctx, status.device, consts.PendingRebootReason, metav1.ConditionTrue, message)
 			if err != nil {
 				status.lastStageError = err
 			}
 
-			statuses[index].rebootRequired = rebootRequired
+			err = r.setOperationPhase(ctx, status.device, consts.OperationPhaseAwaitingReboot)
+			if err != nil {
+				log.Log.Error(err, "failed to update device operation phase", "device", status.device.Name)
+			}
+
+			status.bfbImage = ""
+			status.rebootRequired = true
 		}(i)
 	}
 
@@ -417,6 +2053,113 @@ func (r *NicDeviceReconciler) applyNvConfig(ctx context.Context, statuses nicDev
 	return nil
 }
 
+// startBFBInstall installs the BFB bundle to the BlueField DPU in the background, the installation is not bound to the reconcile
+// the progress is published in the device status, the result is reported by installBFB once it's done
+func (r *NicDeviceReconciler) startBFBInstall(ctx context.Context, device *v1alpha1.NicDevice, bundlePath string) {
+	install := &bfbInstall{bundlePath: bundlePath, started: time.Now(), done: make(chan struct{})}
+	r.bfbInstallsLock.Lock()
+	if r.bfbInstalls == nil {
+		r.bfbInstalls = map[string]*bfbInstall{}
+	}
+	r.bfbInstalls[device.Name] = install
+	r.bfbInstallsLock.Unlock()
+
+	// The installation updates its own copy of the device, the reconcile keeps using the original one
+	device = device.DeepCopy()
+	go func() {
+		install.err = r.FirmwareManager.InstallBFB(r.withFirmwareProgress(ctx, device), device, bundlePath)
+		install.bfb = device.Status.BFB
+		close(install.done)
+		log.Log.Info("BFB bundle installation done", "device", device.Name, "bundle", bundlePath, "error", install.err)
+
+		if r.bfbInstallEvents != nil {
+			select {
+			case r.bfbInstallEvents <- event.GenericEvent{Object: device}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+}
+
+// bfbInstallsRunning returns true if any BFB bundle installation started by installBFB is not done yet
+func (r *NicDeviceReconciler) bfbInstallsRunning() bool {
+	r.bfbInstallsLock.Lock()
+	defer r.bfbInstallsLock.Unlock()
+
+	for _, install := range r.bfbInstalls {
+		select {
+		case <-install.done:
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// bfbInstallsDone returns true if a BFB bundle installation of any of the devices is done and not yet reported by installBFB
+func (r *NicDeviceReconciler) bfbInstallsDone(statuses nicDeviceConfigurationStatuses) bool {
+	r.bfbInstallsLock.Lock()
+	defer r.bfbInstallsLock.Unlock()
+
+	for _, status := range statuses {
+		if install, found := r.bfbInstalls[status.device.Name]; found {
+			select {
+			case <-install.done:
+				return true
+			default:
+			}
+		}
+	}
+	return false
+}
+
+// takeBFBInstall returns the BFB bundle installation of the device if it's done and forgets it, nil otherwise
+func (r *NicDeviceReconciler) takeBFBInstall(deviceName string) *bfbInstall {
+	r.bfbInstallsLock.Lock()
+	defer r.bfbInstallsLock.Unlock()
+
+	install, found := r.bfbInstalls[deviceName]
+	if !found {
+		return nil
+	}
+	select {
+	case <-install.done:
+		delete(r.bfbInstalls, deviceName)
+		return install
+	default:
+		return nil
+	}
+}
+
+// countNvConfigWrites returns the total number of nv config writes and resets issued to the device
+func countNvConfigWrites(device *v1alpha1.NicDevice) int64 {
+	stats := device.Status.NvConfigWriteStats
+	if stats == nil {
+		return 0
+	}
+	return stats.Writes + stats.Resets
+}
+
+// trackNvConfigChurn counts the nv config update in the churn window, persists the write counters
+// and emits a warning event if the device's nv config is updated abnormally often
+func (r *NicDeviceReconciler) trackNvConfigChurn(ctx context.Context, device *v1alpha1.NicDevice) error {
+	stats := device.Status.NvConfigWriteStats
+	now := time.Now()
+	if stats.WindowStart == nil || now.Sub(stats.WindowStart.Time) > nvConfigChurnWindow {
+		stats.WindowStart = &metav1.Time{Time: now}
+		stats.WindowUpdates = 0
+	}
+	stats.WindowUpdates++
+
+	if stats.WindowUpdates > nvConfigChurnThreshold {
+		r.EventRecorder.Event(device, v1.EventTypeWarning, consts.NvConfigChurnReason,
+			fmt.Sprintf("nv config was updated %d times since %s (%d writes, %d resets in total), check the template for flapping values",
+				stats.WindowUpdates, stats.WindowStart.Format(time.RFC3339), stats.Writes, stats.Resets))
+	}
+
+	return r.Client.Status().Update(ctx, device)
+}
+
 // handleSpecValidation validates each device's spec in parallel
 // if spec is correct, applies status condition UpdateStarted, otherwise IncorrectSpec
 // sets nvConfigUpdateRequired and rebootRequired flags for each device's configuration status
@@ -425,13 +2168,45 @@ func (r *NicDeviceReconciler) handleSpecValidation(ctx context.Context, statuses
 	var wg sync.WaitGroup
 
 	for i := 0; i < len(statuses); i++ {
+		r.reportResumedOperation(statuses[i].device)
+
 		wg.Add(1)
 		go func(index int) {
 			defer wg.Done()
 			status := statuses[index]
 
+			if operationOutdated(status.device) {
+				// The new spec is rendered from scratch, the phases of the previous operation no longer apply
+				err := r.setOperationPhase(ctx, status.device, consts.OperationPhaseRendering)
+				if err != nil {
+					log.Log.Error(err, "failed to update device operation phase", "device", status.device.Name)
+					status.lastStageError = err
+					return
+				}
+			}
+
+			previousNvConfigParameters := status.device.Status.NvConfigParameters
+			previousPendingRebootParameters := status.device.Status.PendingRebootParameters
+			previousNvConfigWriteStats := status.device.Status.NvConfigWriteStats.DeepCopy()
+			previousNvConfigPCI := status.device.Status.NvConfigPCI
+
+			restoreTemplate := status.useResolvedTemplate()
 			nvConfigUpdateRequired, rebootRequired, err := r.HostManager.ValidateDeviceNvSpec(ctx, status.device)
+			restoreTemplate()
 			log.Log.V(2).Info("nv spec validation complete for device", "device", status.device.Name, "nvConfigUpdateRequired", nvConfigUpdateRequired, "rebootRequired", rebootRequired)
+			if err == nil && (!reflect.DeepEqual(previousNvConfigParameters, status.device.Status.NvConfigParameters) ||
+				!reflect.DeepEqual(previousPendingRebootParameters, status.device.Status.PendingRebootParameters) ||
+				!reflect.DeepEqual(previousNvConfigWriteStats, status.device.Status.NvConfigWriteStats) ||
+				previousNvConfigPCI != status.device.Status.NvConfigPCI) {
+				// Rendered nv config parameters, pending reboot changes, write stats and the probed PCI function
+				// are published for troubleshooting purposes
+				err = r.Client.Status().Update(ctx, status.device)
+				if err != nil {
+					log.Log.Error(err, "failed to update nv config parameters in device status", "device", status.device.Name)
+					status.lastStageError = err
+					return
+				}
+			}
 			if err != nil {
 				log.Log.Error(err, "failed to validate spec for device", "device", status.device.Name)
 				status.lastStageError = err
@@ -440,6 +2215,52 @@ func (r *NicDeviceReconciler) handleSpecValidation(ctx context.Context, statuses
 					if err != nil {
 						log.Log.Error(err, "failed to update device status condition", "device", status.device.Name)
 					}
+				} else if types.IsFabricFeatureNotSupportedError(err) {
+					// The template targets a fabric the device can't join, e.g. a NIC or firmware without the Spectrum-X features
+					err = r.updateDeviceStatusCondition(ctx, status.device, consts.FabricFeatureNotSupportedReason, metav1.ConditionFalse, err.Error())
+					if err != nil {
+						log.Log.Error(err, "failed to update device status condition", "device", status.device.Name)
+					}
+				} else if types.IsNonConvergingError(err) {
+					// Writing the same parameters over and over again only wears out the flash, user has to investigate
+					statusCondition := meta.FindStatusCondition(status.device.Status.Conditions, consts.ConfigUpdateInProgressCondition)
+					if statusCondition == nil || statusCondition.Reason != consts.NonConvergingReason {
+						r.EventRecorder.Event(status.device, v1.EventTypeWarning, consts.NonConvergingReason, err.Error())
+					}
+					err = r.updateDeviceStatusCondition(ctx, status.device, consts.NonConvergingReason, metav1.ConditionFalse, err.Error())
+					if err != nil {
+						log.Log.Error(err, "failed to update device status condition", "device", status.device.Name)
+					}
+				} else if types.IsEswitchAccessDeniedError(err) {
+					// The ownership of the nv config is unknown, the device isn't configured until the user investigates
+					statusCondition := meta.FindStatusCondition(status.device.Status.Conditions, consts.ConfigUpdateInProgressCondition)
+					if statusCondition == nil || statusCondition.Reason != consts.EswitchAccessDeniedReason {
+						r.EventRecorder.Event(status.device, v1.EventTypeWarning, consts.EswitchAccessDeniedReason, err.Error())
+					}
+					err = r.updateDeviceStatusCondition(ctx, status.device, consts.EswitchAccessDeniedReason, metav1.ConditionFalse, err.Error())
+					if err != nil {
+						log.Log.Error(err, "failed to update device status condition", "device", status.device.Name)
+					}
+				} else if types.IsDelegatedToOtherHostError(err) {
+					// The owning host's config daemon applies the nv config, this host can't change it
+					status.delegated = true
+					status.lastStageError = nil
+					err = r.updateDeviceStatusCondition(ctx, status.device, consts.DelegatedToOtherHostReason, metav1.ConditionFalse, err.Error())
+					if err != nil {
+						log.Log.Error(err, "failed to update device status condition", "device", status.device.Name)
+						status.lastStageError = err
+					}
+					return
+				} else if types.IsToolHangError(err) {
+					// A wedged tool shouldn't block the configuration of other devices, skipping this device until the next reconcile
+					status.toolHang = true
+					status.lastStageError = nil
+					err = r.updateDeviceStatusCondition(ctx, status.device, consts.DeviceToolHangReason, metav1.ConditionFalse, err.Error())
+					if err != nil {
+						log.Log.Error(err, "failed to update device status condition", "device", status.device.Name)
+						status.lastStageError = err
+					}
+					return
 				} else {
 					err = r.updateDeviceStatusCondition(ctx, status.device, consts.SpecValidationFailed, metav1.ConditionFalse, err.Error())
 					if err != nil {
@@ -451,13 +2272,29 @@ func (r *NicDeviceReconciler) handleSpecValidation(ctx context.Context, statuses
 			status.nvConfigUpdateRequired = nvConfigUpdateRequired
 			status.rebootRequired = rebootRequired
 
+			if nvConfigUpdateRequired && r.configOwnershipStillDenied(status.device) {
+				// The ConfigOwnershipDenied condition is kept, the write would fail the same way
+				status.ownershipDenied = true
+				return
+			}
+
 			if nvConfigUpdateRequired {
 				log.Log.V(2).Info("update started for device", "device", status.device.Name)
 				err = r.updateDeviceStatusCondition(ctx, status.device, consts.UpdateStartedReason, metav1.ConditionTrue, "")
 				if err != nil {
 					status.lastStageError = err
 				}
+				err = r.setOperationPhase(ctx, status.device, consts.OperationPhaseApplying)
+				if err != nil {
+					log.Log.Error(err, "failed to update device operation phase", "device", status.device.Name)
+				}
 			} else if rebootRequired {
+				// The nv config was written before, e.g. by the previous run of the config daemon, only the activation is left
+				err = r.setOperationPhase(ctx, status.device, consts.OperationPhaseAwaitingReboot)
+				if err != nil {
+					log.Log.Error(err, "failed to update device operation phase", "device", status.device.Name)
+				}
+
 				// There might be a case where FW config didn't apply after a reboot because of some error in FW. In this case
 				// we don't want the node to be kept in a reboot loop (FW configured -> reboot -> Config was not applied -> FW configured -> etc.).
 				// To break the reboot loop, we should compare the last time the status was changed to PendingReboot to the node's uptime.
@@ -512,6 +2349,16 @@ func (r *NicDeviceReconciler) handleSpecValidation(ctx context.Context, statuses
 	return nil
 }
 
+// emitFailureDiagnostics emits a warning event with the diagnostics of the device after a failed operation,
+// so that bug reports contain the failed host tool runs and the state of the device
+func (r *NicDeviceReconciler) emitFailureDiagnostics(device *v1alpha1.NicDevice, since time.Time) {
+	diagnostics := r.HostManager.CollectDiagnostics(device, since)
+	if diagnostics == "" {
+		return
+	}
+	r.EventRecorder.Event(device, v1.EventTypeWarning, consts.FailureDiagnosticsReason, diagnostics)
+}
+
 func (r *NicDeviceReconciler) updateDeviceStatusCondition(ctx context.Context, device *v1alpha1.NicDevice, reason string, status metav1.ConditionStatus, message string) error {
 	cond := metav1.Condition{
 		Type:               consts.ConfigUpdateInProgressCondition,
@@ -525,10 +2372,55 @@ func (r *NicDeviceReconciler) updateDeviceStatusCondition(ctx context.Context, d
 	if changed {
 		err = r.Client.Status().Update(ctx, device)
 	}
+	if changed && err == nil && slices.Contains(applyFailedReasons, reason) {
+		r.LifecycleEvents.Emit(ctx, lifecycle.ApplyFinishedEvent, device, reason, message)
+	}
 
 	return err
 }
 
+// firmwareUpdateProgressStep is the minimal progress change in percent published to the device status within a phase
+const firmwareUpdateProgressStep = 10
+
+// withFirmwareProgress returns a context publishing the firmware update progress reported by the host tools to the device status
+// progress within a phase is published in steps of firmwareUpdateProgressStep to not flood the API server
+func (r *NicDeviceReconciler) withFirmwareProgress(ctx context.Context, device *v1alpha1.NicDevice) context.Context {
+	return host.WithFirmwareProgress(ctx, func(phase string, percent int) {
+		if update := device.Status.FirmwareUpdate; update != nil && update.Phase == phase &&
+			(percent == update.Progress || percent < 100 && percent-update.Progress < firmwareUpdateProgressStep) {
+			return
+		}
+
+		err := r.setFirmwareUpdatePhase(ctx, device, phase, percent)
+		if err != nil {
+			log.Log.Error(err, "failed to update firmware update progress", "device", device.Name, "phase", phase, "progress", percent)
+		}
+	})
+}
+
+// setFirmwareUpdatePhase publishes the phase of the firmware update and its progress in the device status
+func (r *NicDeviceReconciler) setFirmwareUpdatePhase(ctx context.Context, device *v1alpha1.NicDevice, phase string, percent int) error {
+	device.Status.FirmwareUpdate = &v1alpha1.FirmwareUpdateStatus{
+		Phase:          phase,
+		Progress:       percent,
+		LastUpdateTime: metav1.Now(),
+	}
+	return r.Client.Status().Update(ctx, device)
+}
+
+// clearFirmwareUpdatePhase removes the firmware update progress from the device status if it's reported
+func (r *NicDeviceReconciler) clearFirmwareUpdatePhase(ctx context.Context, device *v1alpha1.NicDevice) {
+	if device.Status.FirmwareUpdate == nil {
+		return
+	}
+
+	device.Status.FirmwareUpdate = nil
+	err := r.Client.Status().Update(ctx, device)
+	if err != nil {
+		log.Log.Error(err, "failed to clear firmware update progress", "device", device.Name)
+	}
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *NicDeviceReconciler) SetupWithManager(mgr ctrl.Manager, watchForMaintenance bool) error {
 	qHandler := func(q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
@@ -573,9 +2465,43 @@ func (r *NicDeviceReconciler) SetupWithManager(mgr ctrl.Manager, watchForMainten
 		},
 	}
 
+	// Devices are validated against the current binaries of their firmware sources
+	firmwareSourceEventHandler := handler.Funcs{
+		CreateFunc: func(ctx context.Context, e event.CreateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			log.Log.Info("Enqueuing sync for firmware source create event", "resource", e.Object.GetName())
+			qHandler(q)
+		},
+		UpdateFunc: func(ctx context.Context, e event.UpdateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			log.Log.Info("Enqueuing sync for firmware source update event", "resource", e.ObjectNew.GetName())
+			qHandler(q)
+		},
+	}
+
+	// Template values are resolved again once the referenced ConfigMaps and Secrets change
+	templateValuesEventHandler := func(secret bool) handler.Funcs {
+		return handler.Funcs{
+			CreateFunc: func(ctx context.Context, e event.CreateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+				r.enqueueForTemplateValues(ctx, e.Object, secret, qHandler, q)
+			},
+			UpdateFunc: func(ctx context.Context, e event.UpdateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+				r.enqueueForTemplateValues(ctx, e.ObjectNew, secret, qHandler, q)
+			},
+			DeleteFunc: func(ctx context.Context, e event.DeleteEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+				r.enqueueForTemplateValues(ctx, e.Object, secret, qHandler, q)
+			},
+		}
+	}
+
 	controller := ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.NicDevice{}).
-		Watches(&v1alpha1.NicDevice{}, eventHandler)
+		Watches(&v1alpha1.NicDevice{}, eventHandler).
+		Watches(&v1alpha1.NicFirmwareSource{}, firmwareSourceEventHandler).
+		// Only the metadata is cached, the values are read from the API server when the templates are resolved
+		Watches(&v1.ConfigMap{}, templateValuesEventHandler(false), builder.OnlyMetadata).
+		Watches(&v1.Secret{}, templateValuesEventHandler(true), builder.OnlyMetadata)
+
+	r.bfbInstallEvents = make(chan event.GenericEvent)
+	controller.WatchesRawSource(source.Channel(r.bfbInstallEvents, eventHandler))
 
 	if watchForMaintenance {
 		maintenanceEventHandler := handler.Funcs{
@@ -615,6 +2541,50 @@ func (p nicDeviceConfigurationStatuses) nvConfigReadyForAll() bool {
 	return nvConfigReadyForAll
 }
 
+// runtimeConfigPending returns true if the runtime config of at least one device waits for the host
+func (p nicDeviceConfigurationStatuses) runtimeConfigPending() bool {
+	for _, result := range p {
+		if result.runtimeConfigPending {
+			return true
+		}
+	}
+
+	return false
+}
+
+// firmwareUpdateRequired returns true if firmware burn is required for at least one device, false if not required for any device
+func (p nicDeviceConfigurationStatuses) firmwareUpdateRequired() bool {
+	for _, result := range p {
+		if result.firmwareImage != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// bfbInstallStarted returns true if BFB bundle installation was started in this reconcile for at least one device
+func (p nicDeviceConfigurationStatuses) bfbInstallStarted() bool {
+	for _, result := range p {
+		if result.bfbInstallStarted {
+			return true
+		}
+	}
+
+	return false
+}
+
+// bfbInstallRequired returns true if BFB bundle installation is required for at least one device, false if not required for any device
+func (p nicDeviceConfigurationStatuses) bfbInstallRequired() bool {
+	for _, result := range p {
+		if result.bfbImage != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
 // rebootRequired returns true if reboot required for at least one device, false if not required for any device
 func (p nicDeviceConfigurationStatuses) rebootRequired() bool {
 	rebootRequiredForSome := false
@@ -640,3 +2610,54 @@ func (p nicDeviceConfigurationStatuses) nvConfigUpdateRequired() bool {
 	log.Log.V(2).Info("nv config change required for some devices")
 	return nvConfigUpdateRequiredForSome
 }
+
+// withoutConfigOwnershipDenied returns the statuses of devices whose nv config is not write-protected by the BMC or DPU
+// returns true if any device was filtered out
+func (p nicDeviceConfigurationStatuses) withoutConfigOwnershipDenied() (nicDeviceConfigurationStatuses, bool) {
+	filtered := nicDeviceConfigurationStatuses{}
+	for _, result := range p {
+		if result.ownershipDenied {
+			log.Log.V(2).Info("skipping device because its nv config ownership is denied", "device", result.device.Name)
+			continue
+		}
+		filtered = append(filtered, result)
+	}
+
+	return filtered, len(filtered) != len(p)
+}
+
+// withoutNewToolHangs returns the statuses of devices that weren't affected by stuck host tools while being configured,
+// returns true if a tool hang was detected before or at least one device was filtered out
+func (p nicDeviceConfigurationStatuses) withoutNewToolHangs(detected bool) (nicDeviceConfigurationStatuses, bool) {
+	filtered, found := p.withoutToolHangs()
+	return filtered, detected || found
+}
+
+// withoutDelegated returns the statuses of devices whose nv config is owned by this host
+func (p nicDeviceConfigurationStatuses) withoutDelegated() nicDeviceConfigurationStatuses {
+	filtered := nicDeviceConfigurationStatuses{}
+	for _, result := range p {
+		if result.delegated {
+			log.Log.V(2).Info("skipping device configured by another host", "device", result.device.Name)
+			continue
+		}
+		filtered = append(filtered, result)
+	}
+
+	return filtered
+}
+
+// withoutToolHangs returns the statuses of devices that weren't affected by stuck host tools
+// returns true if at least one device was filtered out
+func (p nicDeviceConfigurationStatuses) withoutToolHangs() (nicDeviceConfigurationStatuses, bool) {
+	filtered := nicDeviceConfigurationStatuses{}
+	for _, result := range p {
+		if result.toolHang {
+			log.Log.V(2).Info("skipping device because of a stuck host tool", "device", result.device.Name)
+			continue
+		}
+		filtered = append(filtered, result)
+	}
+
+	return filtered, len(filtered) != len(p)
+}
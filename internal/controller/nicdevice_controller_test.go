@@ -18,6 +18,8 @@ package controller
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strconv"
 	"sync"
 	"time"
 
@@ -27,20 +29,25 @@ import (
 	. "github.com/onsi/gomega"
 	"github.com/stretchr/testify/mock"
 	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8sTypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/json"
 	"k8s.io/apimachinery/pkg/util/rand"
 	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/config"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
 	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
 	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
 	hostMocks "github.com/Mellanox/nic-configuration-operator/pkg/host/mocks"
+	"github.com/Mellanox/nic-configuration-operator/pkg/lifecycle"
 	maintenanceMocks "github.com/Mellanox/nic-configuration-operator/pkg/maintenance/mocks"
 	"github.com/Mellanox/nic-configuration-operator/pkg/testutils"
 	"github.com/Mellanox/nic-configuration-operator/pkg/types"
@@ -48,6 +55,35 @@ import (
 
 const specValidationFailed = "spec validation failed"
 
+// lifecycleEventsSink records the lifecycle events published by the reconcilers
+type lifecycleEventsSink struct {
+	lock   sync.Mutex
+	events []lifecycle.Event
+}
+
+func (s *lifecycleEventsSink) Send(_ context.Context, event lifecycle.Event) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *lifecycleEventsSink) sent() []lifecycle.Event {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]lifecycle.Event{}, s.events...)
+}
+
+func (s *lifecycleEventsSink) types() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	eventTypes := []string{}
+	for _, event := range s.events {
+		eventTypes = append(eventTypes, event.Type)
+	}
+	return eventTypes
+}
+
 var _ = Describe("NicDeviceReconciler", func() {
 	var (
 		mgr                manager.Manager
@@ -55,6 +91,7 @@ var _ = Describe("NicDeviceReconciler", func() {
 		hostManager        *hostMocks.HostManager
 		maintenanceManager *maintenanceMocks.MaintenanceManager
 		hostUtils          *hostMocks.HostUtils
+		firmwareManager    *hostMocks.FirmwareManager
 		nodeName           = "test-node"
 		deviceName         = "test-device"
 		ctx                context.Context
@@ -101,8 +138,10 @@ var _ = Describe("NicDeviceReconciler", func() {
 
 		deviceDiscoveryReconcileTime = 1 * time.Second
 		hostManager = &hostMocks.HostManager{}
+		hostManager.On("CollectDiagnostics", mock.Anything, mock.Anything).Return(`{"serialNumber":"serial"}`).Maybe()
 		maintenanceManager = &maintenanceMocks.MaintenanceManager{}
 		hostUtils = &hostMocks.HostUtils{}
+		firmwareManager = &hostMocks.FirmwareManager{}
 
 		reconciler = &NicDeviceReconciler{
 			Client:             mgr.GetClient(),
@@ -112,7 +151,9 @@ var _ = Describe("NicDeviceReconciler", func() {
 			HostManager:        hostManager,
 			MaintenanceManager: maintenanceManager,
 			HostUtils:          hostUtils,
+			FirmwareManager:    firmwareManager,
 			EventRecorder:      mgr.GetEventRecorderFor("testReconciler"),
+			APIReader:          mgr.GetAPIReader(),
 		}
 		Expect(reconciler.SetupWithManager(mgr, false)).To(Succeed())
 	})
@@ -171,6 +212,149 @@ var _ = Describe("NicDeviceReconciler", func() {
 			}
 			Expect(statuses.nvConfigReadyForAll()).To(Equal(true))
 		})
+
+		It("should queue FW resets one device at a time and a single reboot otherwise", func() {
+			newStatus := func(name string, disruption v1alpha1.DisruptionEnum) *nicDeviceConfigurationStatus {
+				device := &v1alpha1.NicDevice{ObjectMeta: metav1.ObjectMeta{Name: name}}
+				device.Spec.Configuration = &v1alpha1.NicDeviceConfigurationSpec{Disruption: disruption}
+				return &nicDeviceConfigurationStatus{device: device, rebootRequired: true}
+			}
+			statuses := nicDeviceConfigurationStatuses{
+				newStatus("device-b", consts.DisruptionFwReset),
+				newStatus("device-a", consts.DisruptionFwReset),
+				{device: &v1alpha1.NicDevice{ObjectMeta: metav1.ObjectMeta{Name: "device-c"}}},
+			}
+			Expect(reconciler.disruptionQueue(statuses)).To(Equal([]v1alpha1.DisruptiveOperation{
+				{Operation: consts.DisruptionFwReset, Devices: []string{"device-a"}, State: consts.DisruptiveOperationPending},
+				{Operation: consts.DisruptionFwReset, Devices: []string{"device-b"}, State: consts.DisruptiveOperationPending},
+			}))
+
+			statuses = append(statuses, newStatus("device-d", consts.DisruptionReboot))
+			Expect(reconciler.disruptionQueue(statuses)).To(Equal([]v1alpha1.DisruptiveOperation{
+				{Operation: consts.DisruptionReboot, Devices: []string{"device-a", "device-b", "device-d"}, State: consts.DisruptiveOperationPending},
+			}))
+		})
+
+		It("should filter out devices with stuck host tools", func() {
+			statuses := nicDeviceConfigurationStatuses{
+				{device: &v1alpha1.NicDevice{}, toolHang: true},
+				{device: &v1alpha1.NicDevice{}, rebootRequired: true},
+			}
+			filtered, toolHangDetected := statuses.withoutToolHangs()
+			Expect(toolHangDetected).To(BeTrue())
+			Expect(filtered).To(HaveLen(1))
+			Expect(filtered[0].rebootRequired).To(BeTrue())
+
+			filtered, toolHangDetected = filtered.withoutToolHangs()
+			Expect(toolHangDetected).To(BeFalse())
+			Expect(filtered).To(HaveLen(1))
+		})
+
+		It("should filter out devices configured by other hosts", func() {
+			statuses := nicDeviceConfigurationStatuses{
+				{device: &v1alpha1.NicDevice{}, delegated: true},
+				{device: &v1alpha1.NicDevice{}, rebootRequired: true},
+			}
+			filtered := statuses.withoutDelegated()
+			Expect(filtered).To(HaveLen(1))
+			Expect(filtered[0].rebootRequired).To(BeTrue())
+		})
+	})
+
+	Describe("setTemplateField", func() {
+		It("should set the template fields from string values", func() {
+			template := &v1alpha1.ConfigurationTemplateSpec{
+				LinkType:      consts.Ethernet,
+				RoceOptimized: &v1alpha1.RoceOptimizedSpec{Enabled: true, Qos: &v1alpha1.QosSpec{Trust: "dscp", PFC: "0,0,0,1,0,0,0,0"}},
+				RawNvConfig:   []v1alpha1.NvConfigParam{{Name: "CUSTOM_PARAM", Value: "true"}},
+			}
+
+			Expect(setTemplateField(template, "numVfs", "8")).To(Succeed())
+			Expect(setTemplateField(template, "linkType", consts.Infiniband)).To(Succeed())
+			Expect(setTemplateField(template, "roceOptimized.qos.pfc", "0,0,0,0,1,0,0,0")).To(Succeed())
+			Expect(setTemplateField(template, "rawNvConfig.CUSTOM_PARAM", "false")).To(Succeed())
+			Expect(setTemplateField(template, "rawNvConfig.OTHER_PARAM", "1")).To(Succeed())
+
+			Expect(template.NumVfs).To(Equal(8))
+			Expect(template.LinkType).To(Equal(v1alpha1.LinkTypeEnum(consts.Infiniband)))
+			Expect(template.RoceOptimized.Qos.PFC).To(Equal("0,0,0,0,1,0,0,0"))
+			Expect(template.RawNvConfig).To(Equal([]v1alpha1.NvConfigParam{
+				{Name: "CUSTOM_PARAM", Value: "false"},
+				{Name: "OTHER_PARAM", Value: "1"},
+			}))
+		})
+
+		It("should reject values that don't fit the field", func() {
+			template := &v1alpha1.ConfigurationTemplateSpec{}
+
+			for field, value := range map[string]string{
+				"numVfs":                  "many",
+				"linkType":                "Token Ring",
+				"roceOptimized.qos.trust": "dscp",
+				"pciPerformanceOptimized": "true",
+			} {
+				err := setTemplateField(template, field, value)
+				Expect(types.IsIncorrectSpecError(err)).To(BeTrue(), field)
+			}
+		})
+	})
+
+	Describe("nodeUnderProvisioning", func() {
+		It("should hold configuration if node has the provisioning annotation", func() {
+			node := &v1.Node{ObjectMeta: metav1.ObjectMeta{
+				Name:        nodeName,
+				Annotations: map[string]string{consts.NodeProvisioningAnnotation: "true"},
+			}}
+			Expect(nodeUnderProvisioning(node, nil, false)).To(BeTrue())
+		})
+		It("should hold configuration if node has one of the provisioning taints", func() {
+			node := &v1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+				Spec:       v1.NodeSpec{Taints: []v1.Taint{{Key: "provisioning", Effect: v1.TaintEffectNoSchedule}}},
+			}
+			Expect(nodeUnderProvisioning(node, []string{"provisioning"}, false)).To(BeTrue())
+			Expect(nodeUnderProvisioning(node, []string{"another-taint"}, false)).To(BeFalse())
+		})
+		It("should hold configuration until node is ready if requested", func() {
+			node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName}}
+			Expect(nodeUnderProvisioning(node, nil, true)).To(BeTrue())
+			Expect(nodeUnderProvisioning(node, nil, false)).To(BeFalse())
+
+			node.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}}
+			Expect(nodeUnderProvisioning(node, nil, true)).To(BeFalse())
+		})
+		It("should not hold configuration if node was ready before", func() {
+			node := &v1.Node{ObjectMeta: metav1.ObjectMeta{
+				Name:        nodeName,
+				Annotations: map[string]string{consts.NodeReadyObservedAnnotation: "true"},
+			}}
+			node.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionFalse}}
+			Expect(nodeUnderProvisioning(node, nil, true)).To(BeFalse())
+		})
+	})
+	Describe("nodeProvisioningInProgress", func() {
+		It("should persist the first Ready state of the node across restarts of the config daemon", func() {
+			node := &v1.Node{}
+			Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: nodeName}, node)).To(Succeed())
+			node.Status.Conditions = []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionFalse}}
+			Expect(k8sClient.Status().Update(ctx, node)).To(Succeed())
+
+			reconciler := &NicDeviceReconciler{Client: k8sClient, NodeName: nodeName, WaitForNodeReady: true}
+			Expect(reconciler.nodeProvisioningInProgress(ctx)).To(BeTrue())
+
+			node.Status.Conditions[0].Status = v1.ConditionTrue
+			Expect(k8sClient.Status().Update(ctx, node)).To(Succeed())
+			Expect(reconciler.nodeProvisioningInProgress(ctx)).To(BeFalse())
+
+			Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: nodeName}, node)).To(Succeed())
+			Expect(node.Annotations).To(HaveKeyWithValue(consts.NodeReadyObservedAnnotation, "true"))
+
+			node.Status.Conditions[0].Status = v1.ConditionFalse
+			Expect(k8sClient.Status().Update(ctx, node)).To(Succeed())
+
+			restarted := &NicDeviceReconciler{Client: k8sClient, NodeName: nodeName, WaitForNodeReady: true}
+			Expect(restarted.nodeProvisioningInProgress(ctx)).To(BeFalse())
+		})
 	})
 
 	Describe("updateDeviceStatusCondition", func() {
@@ -217,6 +401,65 @@ var _ = Describe("NicDeviceReconciler", func() {
 		})
 	})
 
+	Describe("setOperationPhase", func() {
+		It("should persist the phase transitions of the device operation", func() {
+			device := &v1alpha1.NicDevice{ObjectMeta: metav1.ObjectMeta{Name: deviceName, Namespace: namespaceName}}
+			Expect(k8sClient.Create(ctx, device)).To(Succeed())
+			Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+
+			Expect(reconciler.setOperationPhase(ctx, device, consts.OperationPhaseRendering)).To(Succeed())
+			Expect(device.Status.Operation).NotTo(BeNil())
+			Expect(device.Status.Operation.ObservedGeneration).To(Equal(device.Generation))
+			startTime := device.Status.Operation.StartTime
+
+			Expect(reconciler.setOperationPhase(ctx, device, consts.OperationPhaseApplying)).To(Succeed())
+			Expect(reconciler.setOperationPhase(ctx, device, consts.OperationPhaseAwaitingReboot)).To(Succeed())
+			// Done is only reachable after the verification
+			Expect(reconciler.setOperationPhase(ctx, device, consts.OperationPhaseDone)).To(MatchError(errInvalidOperationTransition))
+			Expect(device.Status.Operation.Phase).To(Equal(consts.OperationPhaseAwaitingReboot))
+
+			Expect(reconciler.setOperationPhase(ctx, device, consts.OperationPhaseVerifying)).To(Succeed())
+			Expect(reconciler.setOperationPhase(ctx, device, consts.OperationPhaseDone)).To(Succeed())
+
+			persisted := &v1alpha1.NicDevice{}
+			Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, persisted)).To(Succeed())
+			Expect(persisted.Status.Operation).NotTo(BeNil())
+			Expect(persisted.Status.Operation.Phase).To(Equal(consts.OperationPhaseDone))
+			Expect(persisted.Status.Operation.StartTime.Equal(&startTime)).To(BeTrue())
+		})
+
+		It("should publish the lifecycle events of the transitions", func() {
+			sink := &lifecycleEventsSink{}
+			reconciler.LifecycleEvents = lifecycle.NewEmitter(sink, nodeName)
+			device := &v1alpha1.NicDevice{ObjectMeta: metav1.ObjectMeta{Name: deviceName, Namespace: namespaceName}}
+			Expect(k8sClient.Create(ctx, device)).To(Succeed())
+			Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+
+			for _, phase := range []string{consts.OperationPhaseRendering, consts.OperationPhaseApplying, consts.OperationPhaseAwaitingReboot,
+				consts.OperationPhaseVerifying, consts.OperationPhaseDone, consts.OperationPhaseApplying} {
+				Expect(reconciler.setOperationPhase(ctx, device, phase)).To(Succeed())
+			}
+			Expect(reconciler.updateDeviceStatusCondition(ctx, device, consts.NonVolatileConfigUpdateFailedReason, metav1.ConditionFalse, "mlxconfig failed")).To(Succeed())
+			// The same failure is only reported once
+			Expect(reconciler.updateDeviceStatusCondition(ctx, device, consts.NonVolatileConfigUpdateFailedReason, metav1.ConditionFalse, "mlxconfig failed")).To(Succeed())
+
+			Eventually(sink.types).Should(Equal([]string{
+				lifecycle.ApplyStartedEvent,
+				lifecycle.RebootCompletedEvent,
+				lifecycle.ApplyFinishedEvent,
+				lifecycle.DriftDetectedEvent,
+				lifecycle.ApplyStartedEvent,
+				lifecycle.ApplyFinishedEvent,
+			}))
+			events := sink.sent()
+			Expect(events[2].Data.Reason).To(Equal(consts.UpdateSuccessfulReason))
+			Expect(events[3].Data.Reason).To(Equal(lifecycle.NvConfigDriftReason))
+			Expect(events[5].Data.Reason).To(Equal(consts.NonVolatileConfigUpdateFailedReason))
+			Expect(events[5].Data.Message).To(Equal("mlxconfig failed"))
+			Expect(events[5].Subject).To(Equal(deviceName))
+		})
+	})
+
 	Describe("reconcile a single device", func() {
 		var createDevice = func(setLastSpecAnnotation bool) *v1alpha1.NicDevice {
 			device := &v1alpha1.NicDevice{
@@ -312,6 +555,136 @@ var _ = Describe("NicDeviceReconciler", func() {
 				Message: errorText,
 			}))
 		})
+		It("Should result in NonConverging status if nv config doesn't take effect", func() {
+			err := types.NonConvergingError("parameter SRIOV_EN was written 3 times with value 1")
+			errorText := err.Error()
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(false, false, err)
+
+			createDevice(false)
+			startManager()
+
+			Eventually(func() []metav1.Condition {
+				device := &v1alpha1.NicDevice{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+				return device.Status.Conditions
+			}, timeout).Should(testutils.MatchCondition(metav1.Condition{
+				Type:    consts.ConfigUpdateInProgressCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  consts.NonConvergingReason,
+				Message: errorText,
+			}))
+
+			hostManager.AssertNotCalled(GinkgoT(), "ApplyDeviceNvSpec", mock.Anything, mock.Anything)
+		})
+		It("Should finish the operation of the device in the Done phase", func() {
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(false, false, nil)
+			hostManager.On("ApplyDeviceRuntimeSpec", mock.Anything).Return(nil)
+			maintenanceManager.On("ReleaseMaintenance", mock.Anything).Return(nil)
+
+			createDevice(false)
+			startManager()
+
+			Eventually(func() *v1alpha1.DeviceOperationStatus {
+				device := &v1alpha1.NicDevice{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+				return device.Status.Operation
+			}, timeout).Should(And(Not(BeNil()), HaveField("Phase", consts.OperationPhaseDone)))
+		})
+		It("Should resume the persisted operation of the device", func() {
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(false, false, nil)
+			hostManager.On("ApplyDeviceRuntimeSpec", mock.Anything).Return(nil)
+			maintenanceManager.On("ReleaseMaintenance", mock.Anything).Return(nil)
+
+			device := createDevice(false)
+			Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+			startTime := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+			device.Status.Operation = &v1alpha1.DeviceOperationStatus{
+				Phase:              consts.OperationPhaseAwaitingReboot,
+				ObservedGeneration: device.Generation,
+				StartTime:          startTime,
+				LastTransitionTime: startTime,
+			}
+			Expect(k8sClient.Status().Update(ctx, device)).To(Succeed())
+
+			startManager()
+
+			Eventually(func() *v1alpha1.DeviceOperationStatus {
+				device := &v1alpha1.NicDevice{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+				return device.Status.Operation
+			}, timeout).Should(And(Not(BeNil()), HaveField("Phase", consts.OperationPhaseDone)))
+
+			device = &v1alpha1.NicDevice{}
+			Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+			// The operation isn't started over after the restart
+			Expect(device.Status.Operation.StartTime.Equal(&startTime)).To(BeTrue())
+		})
+		It("Should remove the not-converged taint in the strict mode once the devices are configured", func() {
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(false, false, nil)
+			hostManager.On("ApplyDeviceRuntimeSpec", mock.Anything).Return(nil)
+			maintenanceManager.On("ReleaseMaintenance", mock.Anything).Return(nil)
+			reconciler.StrictConvergence = true
+
+			createDevice(false)
+			startManager()
+
+			Eventually(func() bool {
+				device := &v1alpha1.NicDevice{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+				return deviceConverged(device)
+			}, timeout).Should(BeTrue())
+			Eventually(func() []v1.Taint {
+				node := &v1.Node{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: nodeName}, node)).To(Succeed())
+				return node.Spec.Taints
+			}, timeout).ShouldNot(ContainElement(HaveField("Key", consts.NotConvergedTaintKey)))
+		})
+		It("Should keep the not-converged taint in the strict mode while a device fails to apply", func() {
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(false, false, nil)
+			hostManager.On("ApplyDeviceRuntimeSpec", mock.Anything).Return(errors.New("failed to set trust"))
+			reconciler.StrictConvergence = true
+
+			createDevice(false)
+			startManager()
+
+			Eventually(func() []v1.Taint {
+				node := &v1.Node{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: nodeName}, node)).To(Succeed())
+				return node.Spec.Taints
+			}, timeout).Should(ContainElement(v1.Taint{Key: consts.NotConvergedTaintKey, Effect: v1.TaintEffectNoSchedule}))
+			Consistently(func() []v1.Taint {
+				node := &v1.Node{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: nodeName}, node)).To(Succeed())
+				return node.Spec.Taints
+			}, time.Second).Should(ContainElement(HaveField("Key", consts.NotConvergedTaintKey)))
+		})
+		It("Should publish the hash of the applied configuration on the node", func() {
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(false, false, nil)
+			hostManager.On("ApplyDeviceRuntimeSpec", mock.Anything).Return(nil)
+			maintenanceManager.On("ReleaseMaintenance", mock.Anything).Return(nil)
+
+			createDevice(false)
+			startManager()
+
+			var hash string
+			Eventually(func() string {
+				node := &v1.Node{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: nodeName}, node)).To(Succeed())
+				hash = node.Annotations[consts.ConfigHashAnnotation]
+				return hash
+			}, timeout).Should(HaveLen(12))
+
+			device := &v1alpha1.NicDevice{}
+			Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+			device.Spec.Configuration.Template.NumVfs = 16
+			Expect(k8sClient.Update(ctx, device)).To(Succeed())
+
+			Eventually(func() string {
+				node := &v1.Node{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: nodeName}, node)).To(Succeed())
+				return node.Annotations[consts.ConfigHashAnnotation]
+			}, timeout).ShouldNot(Or(Equal(hash), BeEmpty()))
+		})
 		It("Should result in UpdateSuccessful status if nv config updates or reboot are not required", func() {
 			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(false, false, nil)
 			hostManager.On("ApplyDeviceRuntimeSpec", mock.Anything).Return(nil)
@@ -346,6 +719,22 @@ var _ = Describe("NicDeviceReconciler", func() {
 			maintenanceManager.AssertCalled(GinkgoT(), "ReleaseMaintenance", mock.Anything)
 			maintenanceManager.AssertExpectations(GinkgoT())
 		})
+		It("Should publish network interfaces renamed during runtime config apply", func() {
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(false, false, nil)
+			hostManager.On("ApplyDeviceRuntimeSpec", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+				args.Get(0).(*v1alpha1.NicDevice).Status.Ports[0].NetworkInterface = "enp59s0f0np0"
+			})
+			maintenanceManager.On("ReleaseMaintenance", mock.Anything).Return(nil)
+
+			createDevice(false)
+			startManager()
+
+			Eventually(func() []v1alpha1.NicDevicePortSpec {
+				device := &v1alpha1.NicDevice{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+				return device.Status.Ports
+			}, timeout).Should(Equal([]v1alpha1.NicDevicePortSpec{{PCI: "0000:3b:00.0", NetworkInterface: "enp59s0f0np0"}}))
+		})
 		It("Should keep in UpdateStarted status if maintenance fails to schedule", func() {
 			errorText := "maintenance request failed"
 			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(true, false, nil)
@@ -438,42 +827,69 @@ var _ = Describe("NicDeviceReconciler", func() {
 				Message: errorText,
 			}))
 		})
-		It("Should result in Pending status and not apply runtime spec if failed to reboot", func() {
-			errorText := "reboot request failed"
-			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(true, true, nil)
+		It("Should result in ConfigOwnershipDenied status and not retry if nv config is locked by the BMC or DPU", func() {
+			ownershipErr := types.ConfigOwnershipDeniedError("nv config of device 0000:3b:00.0 is locked by the BMC or DPU: Operation not permitted")
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(true, false, nil)
+			hostUtils.On("GetHostPrivilegeLevel", mock.Anything).Return("RESTRICTED", nil)
 			maintenanceManager.On("ScheduleMaintenance", mock.Anything).Return(nil)
 			maintenanceManager.On("MaintenanceAllowed", mock.Anything).Return(true, nil)
-			hostManager.On("ApplyDeviceNvSpec", mock.Anything, mock.Anything).Return(true, nil)
-			maintenanceManager.On("Reboot").Return(errors.New(errorText))
+			hostManager.On("ApplyDeviceNvSpec", mock.Anything, mock.Anything).Return(false, ownershipErr)
 
-			createDevice(true)
+			createDevice(false)
 			startManager()
 
+			expectedCondition := metav1.Condition{
+				Type:    consts.ConfigUpdateInProgressCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  consts.ConfigOwnershipDeniedReason,
+				Message: ownershipErr.Error() + ", " + consts.ConfigOwnershipDeniedHint,
+			}
 			Eventually(func() []metav1.Condition {
 				device := &v1alpha1.NicDevice{}
 				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
 				return device.Status.Conditions
-			}, timeout).Should(testutils.MatchCondition(metav1.Condition{
-				Type:   consts.ConfigUpdateInProgressCondition,
-				Status: metav1.ConditionTrue,
-				Reason: consts.PendingRebootReason,
-			}))
+			}, timeout).Should(testutils.MatchCondition(expectedCondition))
 
-			// Should reset last applied state annotation if tried to reboot but failed
-			Eventually(func() bool {
+			// Trigger another reconciliation, the write is not retried while the host is restricted
+			device := &v1alpha1.NicDevice{}
+			Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+			device.SetAnnotations(map[string]string{"test": "retry"})
+			Expect(k8sClient.Update(ctx, device)).To(Succeed())
+
+			Consistently(func() []metav1.Condition {
 				device := &v1alpha1.NicDevice{}
 				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
-				_, found := device.Annotations[consts.LastAppliedStateAnnotation]
-				return found
-			}).Should(BeFalse())
+				return device.Status.Conditions
+			}, time.Second*2).Should(testutils.MatchCondition(expectedCondition))
+			hostManager.AssertNumberOfCalls(GinkgoT(), "ApplyDeviceNvSpec", 1)
+		})
+		It("Should emit the failure diagnostics if nv config fails to apply", func() {
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(true, false, nil)
+			maintenanceManager.On("ScheduleMaintenance", mock.Anything).Return(nil)
+			maintenanceManager.On("MaintenanceAllowed", mock.Anything).Return(true, nil)
+			hostManager.On("ApplyDeviceNvSpec", mock.Anything, mock.Anything).Return(false, errors.New("failed to set param2"))
 
-			maintenanceManager.AssertNotCalled(GinkgoT(), "ApplyDeviceRuntimeSpec", mock.Anything)
-			maintenanceManager.AssertExpectations(GinkgoT())
+			createDevice(false)
+			startManager()
+
+			Eventually(func() []string {
+				events := &v1.EventList{}
+				Expect(k8sClient.List(ctx, events, client.InNamespace(namespaceName))).To(Succeed())
+				messages := []string{}
+				for _, event := range events.Items {
+					if event.Reason == consts.FailureDiagnosticsReason && event.Type == v1.EventTypeWarning {
+						messages = append(messages, event.Message)
+					}
+				}
+				return messages
+			}, timeout).Should(ContainElement(`{"serialNumber":"serial"}`))
 		})
-		It("Should not release maintenance if runtime config failed to apply", func() {
-			errorText := "runtime config update failed"
-			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(false, false, nil)
-			hostManager.On("ApplyDeviceRuntimeSpec", mock.Anything).Return(errors.New(errorText))
+		It("Should result in RolledBack status if nv config was rolled back after a failure", func() {
+			rollbackErr := types.RolledBackError("restored previous values of 1 parameters after: failed to set param2")
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(true, false, nil)
+			maintenanceManager.On("ScheduleMaintenance", mock.Anything).Return(nil)
+			maintenanceManager.On("MaintenanceAllowed", mock.Anything).Return(true, nil)
+			hostManager.On("ApplyDeviceNvSpec", mock.Anything, mock.Anything).Return(false, rollbackErr)
 
 			createDevice(false)
 			startManager()
@@ -485,24 +901,47 @@ var _ = Describe("NicDeviceReconciler", func() {
 			}, timeout).Should(testutils.MatchCondition(metav1.Condition{
 				Type:    consts.ConfigUpdateInProgressCondition,
 				Status:  metav1.ConditionFalse,
-				Reason:  consts.RuntimeConfigUpdateFailedReason,
-				Message: errorText,
+				Reason:  consts.RolledBackReason,
+				Message: rollbackErr.Error(),
 			}))
-
-			maintenanceManager.AssertNotCalled(GinkgoT(), "ScheduleMaintenance", mock.Anything)
-			maintenanceManager.AssertNotCalled(GinkgoT(), "MaintenanceAllowed", mock.Anything)
-			hostManager.AssertNotCalled(GinkgoT(), "ApplyDeviceNvSpec", mock.Anything, mock.Anything)
 			maintenanceManager.AssertNotCalled(GinkgoT(), "Reboot")
-			maintenanceManager.AssertNotCalled(GinkgoT(), "ReleaseMaintenance", mock.Anything)
-			hostManager.AssertExpectations(GinkgoT())
-			maintenanceManager.AssertExpectations(GinkgoT())
 		})
-		It("Should request maintenance if runtime config needs to be reset", func() {
-			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(false, false, nil)
+		It("Should persist nv config write counters even if nv config fails to apply", func() {
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(true, false, nil)
 			maintenanceManager.On("ScheduleMaintenance", mock.Anything).Return(nil)
-			maintenanceManager.On("MaintenanceAllowed", mock.Anything).Return(false, nil)
+			maintenanceManager.On("MaintenanceAllowed", mock.Anything).Return(true, nil)
+			hostManager.On("ApplyDeviceNvSpec", mock.Anything, mock.Anything).Return(false, errors.New("second write failed")).
+				Run(func(args mock.Arguments) {
+					device := args.Get(1).(*v1alpha1.NicDevice)
+					if device.Status.NvConfigWriteStats == nil {
+						device.Status.NvConfigWriteStats = &v1alpha1.NvConfigWriteStats{}
+					}
+					device.Status.NvConfigWriteStats.Writes++
+				})
 
-			createDevice(true) // lastAppliedSpec will not match the current resulting in need to reboot
+			createDevice(false)
+			startManager()
+
+			Eventually(func() *v1alpha1.NvConfigWriteStats {
+				device := &v1alpha1.NicDevice{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+				return device.Status.NvConfigWriteStats
+			}, timeout).Should(And(
+				Not(BeNil()),
+				HaveField("Writes", BeNumerically(">=", 1)),
+				HaveField("WindowUpdates", BeNumerically(">=", 1)),
+				HaveField("WindowStart", Not(BeNil())),
+			))
+		})
+		It("Should result in Pending status and not apply runtime spec if failed to reboot", func() {
+			errorText := "reboot request failed"
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(true, true, nil)
+			maintenanceManager.On("ScheduleMaintenance", mock.Anything).Return(nil)
+			maintenanceManager.On("MaintenanceAllowed", mock.Anything).Return(true, nil)
+			hostManager.On("ApplyDeviceNvSpec", mock.Anything, mock.Anything).Return(true, nil)
+			maintenanceManager.On("Reboot").Return(errors.New(errorText))
+
+			createDevice(true)
 			startManager()
 
 			Eventually(func() []metav1.Condition {
@@ -515,39 +954,771 @@ var _ = Describe("NicDeviceReconciler", func() {
 				Reason: consts.PendingRebootReason,
 			}))
 
-			hostManager.AssertNotCalled(GinkgoT(), "ApplyDeviceNvSpec", mock.Anything, mock.Anything)
-			maintenanceManager.AssertNotCalled(GinkgoT(), "ReleaseMaintenance", mock.Anything)
-			hostManager.AssertExpectations(GinkgoT())
+			// Should reset last applied state annotation if tried to reboot but failed
+			Eventually(func() bool {
+				device := &v1alpha1.NicDevice{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+				_, found := device.Annotations[consts.LastAppliedStateAnnotation]
+				return found
+			}).Should(BeFalse())
+
+			maintenanceManager.AssertNotCalled(GinkgoT(), "ApplyDeviceRuntimeSpec", mock.Anything)
 			maintenanceManager.AssertExpectations(GinkgoT())
 		})
-		It("Should not request another reboot if nv config failed to apply after the first one", func() {
+		It("Should fall back to reboot if FW reset fails for a device preferring FW reset", func() {
 			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(false, true, nil)
-			hostUtils.On("GetHostUptimeSeconds").Return(time.Second*0, nil)
+			maintenanceManager.On("ScheduleMaintenance", mock.Anything).Return(nil)
+			maintenanceManager.On("MaintenanceAllowed", mock.Anything).Return(true, nil)
+			hostUtils.On("ResetNicFirmware", mock.Anything, "0000:3b:00.0").Return(errors.New("fw reset failed"))
+			rebooted := make(chan struct{}, 1)
+			maintenanceManager.On("Reboot").Return(nil).Run(func(args mock.Arguments) {
+				select {
+				case rebooted <- struct{}{}:
+				default:
+				}
+			})
 
 			device := createDevice(false)
+			device.Spec.Configuration.Disruption = consts.DisruptionFwReset
+			Expect(k8sClient.Update(ctx, device)).To(Succeed())
+			startManager()
 
-			cond := metav1.Condition{
-				Type:               consts.ConfigUpdateInProgressCondition,
-				Status:             metav1.ConditionTrue,
-				ObservedGeneration: device.Generation,
-				Reason:             consts.PendingRebootReason,
-				Message:            "",
-			}
-			meta.SetStatusCondition(&device.Status.Conditions, cond)
-			Expect(k8sClient.Status().Update(ctx, device)).To(Succeed())
+			Eventually(rebooted, timeout).Should(Receive())
+			hostUtils.AssertCalled(GinkgoT(), "ResetNicFirmware", mock.Anything, "0000:3b:00.0")
+		})
+		It("Should record the FW reset of the spec generation in the device annotations", func() {
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(false, true, nil)
+			maintenanceManager.On("ScheduleMaintenance", mock.Anything).Return(nil)
+			maintenanceManager.On("MaintenanceAllowed", mock.Anything).Return(true, nil)
+			maintenanceManager.On("Reboot").Return(nil)
+			hostUtils.On("ResetNicFirmware", mock.Anything, "0000:3b:00.0").Return(nil)
 
+			device := createDevice(false)
+			device.Spec.Configuration.Disruption = consts.DisruptionFwReset
+			Expect(k8sClient.Update(ctx, device)).To(Succeed())
 			startManager()
 
-			Eventually(func() []metav1.Condition {
+			Eventually(func() string {
 				device := &v1alpha1.NicDevice{}
 				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
-				return device.Status.Conditions
-			}, timeout).Should(testutils.MatchCondition(metav1.Condition{
-				Type:    consts.ConfigUpdateInProgressCondition,
-				Status:  metav1.ConditionFalse,
-				Reason:  consts.FirmwareError,
-				Message: consts.FwConfigNotAppliedAfterRebootErrorMsg,
-			}))
+				return device.Annotations[consts.FirmwareResetGenerationAnnotation]
+			}, timeout).Should(Equal(strconv.FormatInt(device.Generation, 10)))
+		})
+		It("Should not repeat the FW reset recorded for the spec generation after a restart", func() {
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(false, true, nil)
+			maintenanceManager.On("ScheduleMaintenance", mock.Anything).Return(nil)
+			maintenanceManager.On("MaintenanceAllowed", mock.Anything).Return(true, nil)
+			rebooted := make(chan struct{}, 1)
+			maintenanceManager.On("Reboot").Return(nil).Run(func(args mock.Arguments) {
+				select {
+				case rebooted <- struct{}{}:
+				default:
+				}
+			})
+
+			device := createDevice(false)
+			device.Spec.Configuration.Disruption = consts.DisruptionFwReset
+			Expect(k8sClient.Update(ctx, device)).To(Succeed())
+			if device.Annotations == nil {
+				device.Annotations = map[string]string{}
+			}
+			device.Annotations[consts.FirmwareResetGenerationAnnotation] = strconv.FormatInt(device.Generation, 10)
+			Expect(k8sClient.Update(ctx, device)).To(Succeed())
+			startManager()
+
+			Eventually(rebooted, timeout).Should(Receive())
+			hostUtils.AssertNotCalled(GinkgoT(), "ResetNicFirmware", mock.Anything, mock.Anything)
+		})
+
+		It("Should publish the pending reboot in the node's disruption queue while maintenance is not allowed", func() {
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(false, true, nil)
+			maintenanceManager.On("ScheduleMaintenance", mock.Anything).Return(nil)
+			maintenanceManager.On("MaintenanceAllowed", mock.Anything).Return(false, nil)
+
+			createDevice(false)
+			startManager()
+
+			Eventually(func() v1alpha1.NicNodeStateStatus {
+				state := &v1alpha1.NicNodeState{}
+				_ = k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: nodeName, Namespace: namespaceName}, state)
+				return state.Status
+			}, timeout).Should(Equal(v1alpha1.NicNodeStateStatus{
+				Node: nodeName,
+				DisruptionQueue: []v1alpha1.DisruptiveOperation{
+					{Operation: consts.DisruptionReboot, Devices: []string{deviceName}, State: consts.DisruptiveOperationPending},
+				},
+			}))
+			maintenanceManager.AssertNotCalled(GinkgoT(), "Reboot")
+		})
+		It("Should publish the operation in progress in the node's disruption queue", func() {
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(false, true, nil)
+			maintenanceManager.On("ScheduleMaintenance", mock.Anything).Return(nil)
+			maintenanceManager.On("MaintenanceAllowed", mock.Anything).Return(true, nil)
+			getQueue := func() []v1alpha1.DisruptiveOperation {
+				state := &v1alpha1.NicNodeState{}
+				if k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: nodeName, Namespace: namespaceName}, state) != nil {
+					return nil
+				}
+				return state.Status.DisruptionQueue
+			}
+			resetQueue := make(chan []v1alpha1.DisruptiveOperation, 1)
+			hostUtils.On("ResetNicFirmware", mock.Anything, "0000:3b:00.0").Return(errors.New("fw reset failed")).Run(func(args mock.Arguments) {
+				select {
+				case resetQueue <- getQueue():
+				default:
+				}
+			})
+			rebootQueue := make(chan []v1alpha1.DisruptiveOperation, 1)
+			maintenanceManager.On("Reboot").Return(nil).Run(func(args mock.Arguments) {
+				select {
+				case rebootQueue <- getQueue():
+				default:
+				}
+			})
+
+			device := createDevice(false)
+			device.Spec.Configuration.Disruption = consts.DisruptionFwReset
+			Expect(k8sClient.Update(ctx, device)).To(Succeed())
+			startManager()
+
+			var queue []v1alpha1.DisruptiveOperation
+			Eventually(resetQueue, timeout).Should(Receive(&queue))
+			Expect(queue).To(HaveLen(1))
+			Expect(queue[0].Operation).To(BeEquivalentTo(consts.DisruptionFwReset))
+			Expect(queue[0].State).To(BeEquivalentTo(consts.DisruptiveOperationInProgress))
+			Expect(queue[0].EstimatedStartTime).NotTo(BeNil())
+
+			Eventually(rebootQueue, timeout).Should(Receive(&queue))
+			Expect(queue).To(HaveLen(1))
+			Expect(queue[0].Operation).To(BeEquivalentTo(consts.DisruptionReboot))
+			Expect(queue[0].Devices).To(Equal([]string{deviceName}))
+			Expect(queue[0].State).To(BeEquivalentTo(consts.DisruptiveOperationInProgress))
+		})
+		It("Should burn the requested firmware and reboot to activate it", func() {
+			source := &v1alpha1.NicFirmwareSource{
+				ObjectMeta: metav1.ObjectMeta{Name: "fw-source", Namespace: namespaceName},
+				Spec: v1alpha1.NicFirmwareSourceSpec{
+					BinUrlSources: []string{"http://fw.example.com/fw.bin"},
+					Verification:  &v1alpha1.FirmwareVerificationSpec{SHA256: map[string]string{"http://fw.example.com/fw.bin": "3b1f0c"}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, source)).To(Succeed())
+
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(false, false, nil)
+			firmwareManager.On("ValidateRequestedFirmware", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("/cache/fw.bin", nil).Once()
+			firmwareManager.On("ValidateRequestedFirmware", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("", nil)
+			hostManager.On("ApplyDeviceRuntimeSpec", mock.Anything).Return(nil)
+			maintenanceManager.On("ReleaseMaintenance", mock.Anything).Return(nil)
+			firmwareManager.On("BurnFirmware", mock.Anything, mock.Anything, "/cache/fw.bin").Return(nil).Run(func(args mock.Arguments) {
+				args.Get(1).(*v1alpha1.NicDevice).Status.FirmwareVersion = "22.41.1000"
+			})
+			maintenanceManager.On("ScheduleMaintenance", mock.Anything).Return(nil)
+			maintenanceManager.On("MaintenanceAllowed", mock.Anything).Return(true, nil)
+			// Device's status is captured at the time of reboot, the reconciler proceeds after the mocked reboot
+			rebooted := make(chan v1alpha1.NicDeviceStatus, 1)
+			maintenanceManager.On("Reboot").Return(nil).Run(func(args mock.Arguments) {
+				device := &v1alpha1.NicDevice{}
+				if k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device) != nil {
+					return
+				}
+				select {
+				case rebooted <- device.Status:
+				default:
+				}
+			})
+
+			device := createDevice(false)
+			device.Spec.Configuration.Template.Firmware = &v1alpha1.FirmwareTemplateSpec{NicFirmwareSourceRef: source.Name}
+			Expect(k8sClient.Update(ctx, device)).To(Succeed())
+			startManager()
+
+			var status v1alpha1.NicDeviceStatus
+			Eventually(rebooted, timeout).Should(Receive(&status))
+			Expect(status.FirmwareVersion).To(Equal("22.41.1000"))
+			Expect(status.Conditions).To(testutils.MatchCondition(metav1.Condition{
+				Type:    consts.ConfigUpdateInProgressCondition,
+				Status:  metav1.ConditionTrue,
+				Reason:  consts.PendingRebootReason,
+				Message: "Firmware 22.41.1000 burned, pending activation",
+			}))
+			Expect(status.FirmwareUpdate).NotTo(BeNil())
+			Expect(status.FirmwareUpdate.Phase).To(Equal(consts.FirmwareUpdatePhaseAwaitingActivation))
+			hostManager.AssertNotCalled(GinkgoT(), "ApplyDeviceNvSpec", mock.Anything, mock.Anything)
+		})
+		It("Should install the requested BFB bundle to the BlueField DPU and reboot to activate it", func() {
+			source := &v1alpha1.NicFirmwareSource{
+				ObjectMeta: metav1.ObjectMeta{Name: "fw-source", Namespace: namespaceName},
+				Spec: v1alpha1.NicFirmwareSourceSpec{
+					BFBUrlSource: "http://fw.example.com/bf-bundle.bfb",
+					Verification: &v1alpha1.FirmwareVerificationSpec{SHA256: map[string]string{"http://fw.example.com/bf-bundle.bfb": "3b1f0c"}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, source)).To(Succeed())
+
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(false, false, nil)
+			firmwareManager.On("ValidateRequestedFirmware", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("", nil)
+			firmwareManager.On("ValidateRequestedBFB", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("/cache/bf-bundle.bfb", nil).Once()
+			firmwareManager.On("ValidateRequestedBFB", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("", nil)
+			firmwareManager.On("InstallBFB", mock.Anything, mock.Anything, "/cache/bf-bundle.bfb").Return(nil).Run(func(args mock.Arguments) {
+				args.Get(1).(*v1alpha1.NicDevice).Status.BFB = &v1alpha1.BFBStatus{Bundle: "bf-bundle.bfb", InstallTime: metav1.Now()}
+			})
+			hostManager.On("ApplyDeviceRuntimeSpec", mock.Anything).Return(nil)
+			maintenanceManager.On("ReleaseMaintenance", mock.Anything).Return(nil)
+			maintenanceManager.On("ScheduleMaintenance", mock.Anything).Return(nil)
+			maintenanceManager.On("MaintenanceAllowed", mock.Anything).Return(true, nil)
+			rebooted := make(chan v1alpha1.NicDeviceStatus, 1)
+			maintenanceManager.On("Reboot").Return(nil).Run(func(args mock.Arguments) {
+				device := &v1alpha1.NicDevice{}
+				if k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device) != nil {
+					return
+				}
+				select {
+				case rebooted <- device.Status:
+				default:
+				}
+			})
+
+			device := createDevice(false)
+			device.Spec.Configuration.Template.Firmware = &v1alpha1.FirmwareTemplateSpec{NicFirmwareSourceRef: source.Name}
+			Expect(k8sClient.Update(ctx, device)).To(Succeed())
+			startManager()
+
+			var status v1alpha1.NicDeviceStatus
+			Eventually(rebooted, timeout).Should(Receive(&status))
+			Expect(status.BFB).NotTo(BeNil())
+			Expect(status.BFB.Bundle).To(Equal("bf-bundle.bfb"))
+			Expect(status.Conditions).To(testutils.MatchCondition(metav1.Condition{
+				Type:    consts.ConfigUpdateInProgressCondition,
+				Status:  metav1.ConditionTrue,
+				Reason:  consts.PendingRebootReason,
+				Message: "BFB bundle bf-bundle.bfb installed, pending activation",
+			}))
+			maintenanceManager.AssertCalled(GinkgoT(), "ScheduleMaintenance", mock.Anything)
+			firmwareManager.AssertNotCalled(GinkgoT(), "BurnFirmware", mock.Anything, mock.Anything, mock.Anything)
+		})
+		It("Should burn the requested firmware and defer the activation until the activation window opens", func() {
+			source := &v1alpha1.NicFirmwareSource{
+				ObjectMeta: metav1.ObjectMeta{Name: "fw-source", Namespace: namespaceName},
+				Spec: v1alpha1.NicFirmwareSourceSpec{
+					BinUrlSources: []string{"http://fw.example.com/fw.bin"},
+					Verification:  &v1alpha1.FirmwareVerificationSpec{SHA256: map[string]string{"http://fw.example.com/fw.bin": "3b1f0c"}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, source)).To(Succeed())
+
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(false, false, nil)
+			firmwareManager.On("ValidateRequestedFirmware", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("/cache/fw.bin", nil).Once()
+			firmwareManager.On("ValidateRequestedFirmware", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("", nil)
+			firmwareManager.On("BurnFirmware", mock.Anything, mock.Anything, "/cache/fw.bin").Return(nil).Run(func(args mock.Arguments) {
+				args.Get(1).(*v1alpha1.NicDevice).Status.FirmwareVersion = "22.41.1000"
+			})
+
+			// The window opens in two hours
+			windowStart := time.Now().UTC().Add(2 * time.Hour)
+			device := createDevice(false)
+			device.Spec.Configuration.Template.Firmware = &v1alpha1.FirmwareTemplateSpec{NicFirmwareSourceRef: source.Name}
+			device.Spec.Configuration.ActivationWindow = &v1alpha1.ActivationWindowSpec{
+				Start: windowStart.Format("15:04"),
+				End:   windowStart.Add(time.Hour).Format("15:04"),
+			}
+			Expect(k8sClient.Update(ctx, device)).To(Succeed())
+			startManager()
+
+			Eventually(func() []metav1.Condition {
+				device := &v1alpha1.NicDevice{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+				return device.Status.Conditions
+			}, timeout).Should(testutils.MatchCondition(metav1.Condition{
+				Type:    consts.ConfigUpdateInProgressCondition,
+				Status:  metav1.ConditionTrue,
+				Reason:  consts.PendingActivationWindowReason,
+				Message: "Activation is deferred until the activation window opens at " + windowStart.Truncate(time.Minute).Format(time.RFC3339),
+			}))
+
+			firmwareManager.AssertCalled(GinkgoT(), "BurnFirmware", mock.Anything, mock.Anything, "/cache/fw.bin")
+			maintenanceManager.AssertNotCalled(GinkgoT(), "ScheduleMaintenance", mock.Anything)
+			maintenanceManager.AssertNotCalled(GinkgoT(), "Reboot")
+		})
+		It("Should defer the reboot while a PodDisruptionBudget of an RDMA workload doesn't allow a disruption", func() {
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(false, true, nil)
+			reconciler.RdmaResourcePrefixes = []string{"rdma/"}
+
+			podLabels := map[string]string{"app": "rdma-workload"}
+			for name, resourceName := range map[string]v1.ResourceName{"rdma-pod": "rdma/rdma_shared_device_a", "cpu-pod": v1.ResourceCPU} {
+				pod := &v1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespaceName, Labels: podLabels},
+					Spec: v1.PodSpec{
+						NodeName: nodeName,
+						Containers: []v1.Container{{
+							Name:      "workload",
+							Image:     "workload",
+							Resources: v1.ResourceRequirements{Limits: v1.ResourceList{resourceName: resource.MustParse("1")}},
+						}},
+					},
+				}
+				Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+			}
+			// Budgets don't allow disruptions until their status is calculated by the disruption controller
+			Expect(k8sClient.Create(ctx, &policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Name: "rdma-workload", Namespace: namespaceName},
+				Spec: policyv1.PodDisruptionBudgetSpec{
+					MinAvailable: ptr.To(intstr.FromInt32(1)),
+					Selector:     &metav1.LabelSelector{MatchLabels: podLabels},
+				},
+			})).To(Succeed())
+
+			createDevice(false)
+			startManager()
+
+			Eventually(func() []metav1.Condition {
+				device := &v1alpha1.NicDevice{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+				return device.Status.Conditions
+			}, timeout).Should(testutils.MatchCondition(metav1.Condition{
+				Type:    consts.ConfigUpdateInProgressCondition,
+				Status:  metav1.ConditionTrue,
+				Reason:  consts.BlockedByPDBReason,
+				Message: "Disruption is blocked by PodDisruptionBudgets " + namespaceName + "/rdma-workload",
+			}))
+
+			maintenanceManager.AssertNotCalled(GinkgoT(), "ScheduleMaintenance", mock.Anything)
+			maintenanceManager.AssertNotCalled(GinkgoT(), "Reboot")
+		})
+		It("Should result in FirmwareMismatch status if the device doesn't run the pinned firmware", func() {
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(true, true, nil)
+
+			device := createDevice(false)
+			device.Spec.Configuration.Template.Firmware = &v1alpha1.FirmwareTemplateSpec{Version: "22.41.1000"}
+			Expect(k8sClient.Update(ctx, device)).To(Succeed())
+			device.Status.FirmwareVersion = "22.39.1002"
+			Expect(k8sClient.Status().Update(ctx, device)).To(Succeed())
+			startManager()
+
+			Eventually(func() []metav1.Condition {
+				device := &v1alpha1.NicDevice{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+				return device.Status.Conditions
+			}, timeout).Should(testutils.MatchCondition(metav1.Condition{
+				Type:    consts.ConfigUpdateInProgressCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  consts.FirmwareMismatchReason,
+				Message: types.FirmwareMismatchError("device " + deviceName + " runs firmware 22.39.1002, version 22.41.1000 is required").Error(),
+			}))
+
+			hostManager.AssertNotCalled(GinkgoT(), "ApplyDeviceNvSpec", mock.Anything, mock.Anything)
+			maintenanceManager.AssertNotCalled(GinkgoT(), "ScheduleMaintenance", mock.Anything)
+		})
+		It("Should result in VerificationFailed status and not burn the firmware if the binary fails the verification", func() {
+			source := &v1alpha1.NicFirmwareSource{
+				ObjectMeta: metav1.ObjectMeta{Name: "fw-source", Namespace: namespaceName},
+				Spec: v1alpha1.NicFirmwareSourceSpec{
+					BinUrlSources: []string{"http://fw.example.com/fw.bin"},
+					Verification:  &v1alpha1.FirmwareVerificationSpec{SHA256: map[string]string{"http://fw.example.com/fw.bin": "3b1f0c"}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, source)).To(Succeed())
+
+			verificationErr := types.VerificationFailedError("firmware binary http://fw.example.com/fw.bin doesn't match its sha256 checksum")
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(false, false, nil)
+			firmwareManager.On("ValidateRequestedFirmware", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("", verificationErr)
+
+			device := createDevice(false)
+			device.Spec.Configuration.Template.Firmware = &v1alpha1.FirmwareTemplateSpec{NicFirmwareSourceRef: source.Name}
+			Expect(k8sClient.Update(ctx, device)).To(Succeed())
+			startManager()
+
+			Eventually(func() []metav1.Condition {
+				device := &v1alpha1.NicDevice{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+				return device.Status.Conditions
+			}, timeout).Should(testutils.MatchCondition(metav1.Condition{
+				Type:    consts.ConfigUpdateInProgressCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  consts.VerificationFailedReason,
+				Message: verificationErr.Error(),
+			}))
+			firmwareManager.AssertNotCalled(GinkgoT(), "BurnFirmware", mock.Anything, mock.Anything, mock.Anything)
+		})
+		It("Should result in FirmwareRejected status and not burn the firmware if the device would reject the image", func() {
+			source := &v1alpha1.NicFirmwareSource{
+				ObjectMeta: metav1.ObjectMeta{Name: "fw-source", Namespace: namespaceName},
+				Spec: v1alpha1.NicFirmwareSourceSpec{
+					BinUrlSources: []string{"http://fw.example.com/fw.bin"},
+					Verification:  &v1alpha1.FirmwareVerificationSpec{SHA256: map[string]string{"http://fw.example.com/fw.bin": "3b1f0c"}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, source)).To(Succeed())
+
+			rejectedErr := types.FirmwareRejectedError("device " + deviceName + " only accepts signed firmware, image fw.bin is not signed")
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(false, false, nil)
+			firmwareManager.On("ValidateRequestedFirmware", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("", rejectedErr)
+
+			device := createDevice(false)
+			device.Spec.Configuration.Template.Firmware = &v1alpha1.FirmwareTemplateSpec{NicFirmwareSourceRef: source.Name}
+			Expect(k8sClient.Update(ctx, device)).To(Succeed())
+			startManager()
+
+			Eventually(func() []metav1.Condition {
+				device := &v1alpha1.NicDevice{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+				return device.Status.Conditions
+			}, timeout).Should(testutils.MatchCondition(metav1.Condition{
+				Type:    consts.ConfigUpdateInProgressCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  consts.FirmwareRejectedReason,
+				Message: rejectedErr.Error(),
+			}))
+			firmwareManager.AssertNotCalled(GinkgoT(), "BurnFirmware", mock.Anything, mock.Anything, mock.Anything)
+		})
+		It("Should result in IncorrectSpec status if the firmware source doesn't exist", func() {
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(false, false, nil)
+
+			device := createDevice(false)
+			device.Spec.Configuration.Template.Firmware = &v1alpha1.FirmwareTemplateSpec{NicFirmwareSourceRef: "missing-source"}
+			Expect(k8sClient.Update(ctx, device)).To(Succeed())
+			startManager()
+
+			Eventually(func() []metav1.Condition {
+				device := &v1alpha1.NicDevice{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+				return device.Status.Conditions
+			}, timeout).Should(testutils.MatchCondition(metav1.Condition{
+				Type:    consts.ConfigUpdateInProgressCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  consts.IncorrectSpecReason,
+				Message: types.IncorrectSpecError("NicFirmwareSource missing-source not found").Error(),
+			}))
+
+			firmwareManager.AssertNotCalled(GinkgoT(), "BurnFirmware", mock.Anything, mock.Anything, mock.Anything)
+			maintenanceManager.AssertNotCalled(GinkgoT(), "ScheduleMaintenance", mock.Anything)
+		})
+		It("Should result in IncorrectSpec status if the pull secret of the firmware source doesn't exist", func() {
+			source := &v1alpha1.NicFirmwareSource{
+				ObjectMeta: metav1.ObjectMeta{Name: "fw-source", Namespace: namespaceName},
+				Spec: v1alpha1.NicFirmwareSourceSpec{
+					BinUrlSources:    []string{"oci://registry.example.com/firmware/cx6:22.41.1000"},
+					ImagePullSecrets: []string{"missing-secret"},
+					Verification:     &v1alpha1.FirmwareVerificationSpec{SHA256: map[string]string{"oci://registry.example.com/firmware/cx6:22.41.1000": "3b1f0c"}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, source)).To(Succeed())
+
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(false, false, nil)
+
+			device := createDevice(false)
+			device.Spec.Configuration.Template.Firmware = &v1alpha1.FirmwareTemplateSpec{NicFirmwareSourceRef: source.Name}
+			Expect(k8sClient.Update(ctx, device)).To(Succeed())
+			startManager()
+
+			Eventually(func() []metav1.Condition {
+				device := &v1alpha1.NicDevice{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+				return device.Status.Conditions
+			}, timeout).Should(testutils.MatchCondition(metav1.Condition{
+				Type:    consts.ConfigUpdateInProgressCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  consts.IncorrectSpecReason,
+				Message: types.IncorrectSpecError("pull secret missing-secret of NicFirmwareSource fw-source not found").Error(),
+			}))
+
+			firmwareManager.AssertNotCalled(GinkgoT(), "ValidateRequestedFirmware", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+		It("Should apply template values from the ConfigMap key selected by the node label", func() {
+			node := &v1.Node{}
+			Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: nodeName}, node)).To(Succeed())
+			node.Labels = map[string]string{"topology.example.com/rack": "rack-2"}
+			Expect(k8sClient.Update(ctx, node)).To(Succeed())
+			Expect(k8sClient.Create(ctx, &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "rack-values", Namespace: namespaceName},
+				Data:       map[string]string{"rack-1": "8", "rack-2": "16"},
+			})).To(Succeed())
+
+			matchResolvedTemplate := mock.MatchedBy(func(input *v1alpha1.NicDevice) bool {
+				return input.Spec.Configuration.Template.NumVfs == 16
+			})
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, matchResolvedTemplate).Return(false, false, nil)
+			hostManager.On("ApplyDeviceRuntimeSpec", matchResolvedTemplate).Return(nil)
+			maintenanceManager.On("ReleaseMaintenance", mock.Anything).Return(nil)
+
+			device := createDevice(false)
+			device.Spec.Configuration.Template.ValuesFrom = []v1alpha1.TemplateValueSource{{
+				Field:           "numVfs",
+				ConfigMapKeyRef: &v1alpha1.TemplateKeySelector{Name: "rack-values", NodeLabel: "topology.example.com/rack"},
+			}}
+			Expect(k8sClient.Update(ctx, device)).To(Succeed())
+			startManager()
+
+			Eventually(func() []metav1.Condition {
+				device := &v1alpha1.NicDevice{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+				return device.Status.Conditions
+			}, timeout).Should(testutils.MatchCondition(metav1.Condition{
+				Type:   consts.ConfigUpdateInProgressCondition,
+				Status: metav1.ConditionFalse,
+				Reason: consts.UpdateSuccessfulReason,
+			}))
+
+			// Resolved values should not leak to the spec
+			Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+			Expect(device.Spec.Configuration.Template.NumVfs).To(Equal(4))
+		})
+		It("Should apply template values for the speed of the device's ports", func() {
+			hostUtils.On("GetPortSpeed", "0000:3b:00.0").Return(400000, nil)
+
+			matchResolvedTemplate := mock.MatchedBy(func(input *v1alpha1.NicDevice) bool {
+				return input.Spec.Configuration.Template.RawNvConfig[0].Value == "400g-value"
+			})
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, matchResolvedTemplate).Return(false, false, nil)
+			hostManager.On("ApplyDeviceRuntimeSpec", matchResolvedTemplate).Return(nil)
+			maintenanceManager.On("ReleaseMaintenance", mock.Anything).Return(nil)
+
+			device := createDevice(false)
+			device.Spec.Configuration.Template.SpeedValues = []v1alpha1.PortSpeedValues{
+				{Speed: "100G", Values: []v1alpha1.TemplateFieldValue{{Field: "rawNvConfig.CUSTOM_PARAM", Value: "100g-value"}}},
+				{Speed: "400G", Values: []v1alpha1.TemplateFieldValue{{Field: "rawNvConfig.CUSTOM_PARAM", Value: "400g-value"}}},
+			}
+			Expect(k8sClient.Update(ctx, device)).To(Succeed())
+			device.Status.Ports[0].NetworkInterface = "enp59s0f0np0"
+			Expect(k8sClient.Status().Update(ctx, device)).To(Succeed())
+			startManager()
+
+			Eventually(func() []metav1.Condition {
+				device := &v1alpha1.NicDevice{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+				return device.Status.Conditions
+			}, timeout).Should(testutils.MatchCondition(metav1.Condition{
+				Type:   consts.ConfigUpdateInProgressCondition,
+				Status: metav1.ConditionFalse,
+				Reason: consts.UpdateSuccessfulReason,
+			}))
+
+			// Resolved values should not leak to the spec
+			Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+			Expect(device.Spec.Configuration.Template.RawNvConfig[0].Value).To(Equal("true"))
+			Expect(device.Annotations).To(HaveKeyWithValue(consts.PortSpeedAnnotation, "400G"))
+		})
+		It("Should apply template values for the last known speed of the device's ports while the links are down", func() {
+			hostUtils.On("GetPortSpeed", "0000:3b:00.0").Return(0, nil)
+
+			matchResolvedTemplate := mock.MatchedBy(func(input *v1alpha1.NicDevice) bool {
+				return input.Spec.Configuration.Template.RawNvConfig[0].Value == "2.5g-value"
+			})
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, matchResolvedTemplate).Return(false, false, nil)
+			hostManager.On("ApplyDeviceRuntimeSpec", matchResolvedTemplate).Return(nil)
+			maintenanceManager.On("ReleaseMaintenance", mock.Anything).Return(nil)
+
+			device := createDevice(false)
+			metav1.SetMetaDataAnnotation(&device.ObjectMeta, consts.PortSpeedAnnotation, "2.5G")
+			device.Spec.Configuration.Template.SpeedValues = []v1alpha1.PortSpeedValues{
+				{Speed: "2G", Values: []v1alpha1.TemplateFieldValue{{Field: "rawNvConfig.CUSTOM_PARAM", Value: "2g-value"}}},
+				{Speed: "2.5G", Values: []v1alpha1.TemplateFieldValue{{Field: "rawNvConfig.CUSTOM_PARAM", Value: "2.5g-value"}}},
+			}
+			Expect(k8sClient.Update(ctx, device)).To(Succeed())
+			device.Status.Ports[0].NetworkInterface = "enp59s0f0np0"
+			Expect(k8sClient.Status().Update(ctx, device)).To(Succeed())
+			startManager()
+
+			Eventually(func() []metav1.Condition {
+				device := &v1alpha1.NicDevice{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+				return device.Status.Conditions
+			}, timeout).Should(testutils.MatchCondition(metav1.Condition{
+				Type:   consts.ConfigUpdateInProgressCondition,
+				Status: metav1.ConditionFalse,
+				Reason: consts.UpdateSuccessfulReason,
+			}))
+		})
+		It("Should result in IncorrectSpec status if the ConfigMap referenced by the template doesn't exist", func() {
+			device := createDevice(false)
+			device.Spec.Configuration.Template.ValuesFrom = []v1alpha1.TemplateValueSource{{
+				Field:           "linkType",
+				ConfigMapKeyRef: &v1alpha1.TemplateKeySelector{Name: "missing-values", Key: "linkType"},
+			}}
+			Expect(k8sClient.Update(ctx, device)).To(Succeed())
+			startManager()
+
+			Eventually(func() []metav1.Condition {
+				device := &v1alpha1.NicDevice{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+				return device.Status.Conditions
+			}, timeout).Should(testutils.MatchCondition(metav1.Condition{
+				Type:    consts.ConfigUpdateInProgressCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  consts.IncorrectSpecReason,
+				Message: types.IncorrectSpecError("ConfigMap missing-values referenced by the template not found").Error(),
+			}))
+
+			hostManager.AssertNotCalled(GinkgoT(), "ValidateDeviceNvSpec", mock.Anything, mock.Anything)
+		})
+		It("Should resolve the template values once the referenced Secret is created", func() {
+			matchResolvedTemplate := mock.MatchedBy(func(input *v1alpha1.NicDevice) bool {
+				return input.Spec.Configuration.Template.NumVfs == 16
+			})
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, matchResolvedTemplate).Return(false, false, nil)
+			hostManager.On("ApplyDeviceRuntimeSpec", matchResolvedTemplate).Return(nil)
+			maintenanceManager.On("ReleaseMaintenance", mock.Anything).Return(nil)
+
+			device := createDevice(false)
+			device.Spec.Configuration.Template.ValuesFrom = []v1alpha1.TemplateValueSource{{
+				Field:        "numVfs",
+				SecretKeyRef: &v1alpha1.TemplateKeySelector{Name: "vf-values", Key: "numVfs"},
+			}}
+			Expect(k8sClient.Update(ctx, device)).To(Succeed())
+			startManager()
+
+			getConditions := func() []metav1.Condition {
+				device := &v1alpha1.NicDevice{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+				return device.Status.Conditions
+			}
+			Eventually(getConditions, timeout).Should(testutils.MatchCondition(metav1.Condition{
+				Type:    consts.ConfigUpdateInProgressCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  consts.IncorrectSpecReason,
+				Message: types.IncorrectSpecError("Secret vf-values referenced by the template not found").Error(),
+			}))
+
+			// The Secret is picked up without waiting for the periodic reconciliation
+			Expect(k8sClient.Create(ctx, &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "vf-values", Namespace: namespaceName},
+				Data:       map[string][]byte{"numVfs": []byte("16")},
+			})).To(Succeed())
+			Eventually(getConditions, timeout).Should(testutils.MatchCondition(metav1.Condition{
+				Type:   consts.ConfigUpdateInProgressCondition,
+				Status: metav1.ConditionFalse,
+				Reason: consts.UpdateSuccessfulReason,
+			}))
+		})
+
+		It("Should mark the device reconfigured after the configuration is applied", func() {
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(false, false, nil)
+			hostManager.On("ApplyDeviceRuntimeSpec", mock.Anything).Return(nil)
+			maintenanceManager.On("ReleaseMaintenance", mock.Anything).Return(nil)
+
+			device := createDevice(false)
+			device.Spec.Configuration.PostConfigurationHook = &v1alpha1.PostConfigurationHookSpec{
+				RestartWorkloads: []v1alpha1.WorkloadReference{{Name: "sriov-device-plugin"}},
+			}
+			Expect(k8sClient.Update(ctx, device)).To(Succeed())
+			startManager()
+
+			// The operator runs the post configuration hook for the reconfigured devices
+			Eventually(func() map[string]string {
+				device := &v1alpha1.NicDevice{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+				return device.Annotations
+			}, timeout).Should(HaveKey(consts.ReconfiguredAtAnnotation))
+
+			// Last applied state doesn't depend on the hook
+			Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+			device.Spec.Configuration.PostConfigurationHook = nil
+			spec, err := json.Marshal(device.Spec)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(device.Annotations[consts.LastAppliedStateAnnotation]).To(Equal(string(spec)))
+		})
+
+		It("Should not release maintenance if runtime config failed to apply", func() {
+			errorText := "runtime config update failed"
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(false, false, nil)
+			hostManager.On("ApplyDeviceRuntimeSpec", mock.Anything).Return(errors.New(errorText))
+
+			createDevice(false)
+			startManager()
+
+			Eventually(func() []metav1.Condition {
+				device := &v1alpha1.NicDevice{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+				return device.Status.Conditions
+			}, timeout).Should(testutils.MatchCondition(metav1.Condition{
+				Type:    consts.ConfigUpdateInProgressCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  consts.RuntimeConfigUpdateFailedReason,
+				Message: errorText,
+			}))
+
+			maintenanceManager.AssertNotCalled(GinkgoT(), "ScheduleMaintenance", mock.Anything)
+			maintenanceManager.AssertNotCalled(GinkgoT(), "MaintenanceAllowed", mock.Anything)
+			hostManager.AssertNotCalled(GinkgoT(), "ApplyDeviceNvSpec", mock.Anything, mock.Anything)
+			maintenanceManager.AssertNotCalled(GinkgoT(), "Reboot")
+			maintenanceManager.AssertNotCalled(GinkgoT(), "ReleaseMaintenance", mock.Anything)
+			hostManager.AssertExpectations(GinkgoT())
+			maintenanceManager.AssertExpectations(GinkgoT())
+		})
+		It("Should retry the runtime config while it waits for the host", func() {
+			pendingText := "waiting for the representors of the VFs of port 0000:3b:00.0 in the switchdev mode, 1 of 2 created"
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(false, false, nil)
+			hostManager.On("ApplyDeviceRuntimeSpec", mock.Anything).Return(types.RuntimeConfigPendingError(pendingText)).Once()
+			hostManager.On("ApplyDeviceRuntimeSpec", mock.Anything).Return(nil)
+			maintenanceManager.On("ReleaseMaintenance", mock.Anything).Return(nil)
+
+			originalRequeueTime := runtimeConfigPendingRequeueTime
+			runtimeConfigPendingRequeueTime = 100 * time.Millisecond
+			DeferCleanup(func() { runtimeConfigPendingRequeueTime = originalRequeueTime })
+
+			createDevice(false)
+			startManager()
+
+			Eventually(func() []metav1.Condition {
+				device := &v1alpha1.NicDevice{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+				return device.Status.Conditions
+			}, timeout).Should(testutils.MatchCondition(metav1.Condition{
+				Type:   consts.ConfigUpdateInProgressCondition,
+				Status: metav1.ConditionFalse,
+				Reason: consts.UpdateSuccessfulReason,
+			}))
+		})
+		It("Should request maintenance if runtime config needs to be reset", func() {
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(false, false, nil)
+			maintenanceManager.On("ScheduleMaintenance", mock.Anything).Return(nil)
+			maintenanceManager.On("MaintenanceAllowed", mock.Anything).Return(false, nil)
+
+			createDevice(true) // lastAppliedSpec will not match the current resulting in need to reboot
+			startManager()
+
+			Eventually(func() []metav1.Condition {
+				device := &v1alpha1.NicDevice{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+				return device.Status.Conditions
+			}, timeout).Should(testutils.MatchCondition(metav1.Condition{
+				Type:   consts.ConfigUpdateInProgressCondition,
+				Status: metav1.ConditionTrue,
+				Reason: consts.PendingRebootReason,
+			}))
+
+			hostManager.AssertNotCalled(GinkgoT(), "ApplyDeviceNvSpec", mock.Anything, mock.Anything)
+			maintenanceManager.AssertNotCalled(GinkgoT(), "ReleaseMaintenance", mock.Anything)
+			hostManager.AssertExpectations(GinkgoT())
+			maintenanceManager.AssertExpectations(GinkgoT())
+		})
+		It("Should not request another reboot if nv config failed to apply after the first one", func() {
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(false, true, nil)
+			hostUtils.On("GetHostUptimeSeconds").Return(time.Second*0, nil)
+
+			device := createDevice(false)
+
+			cond := metav1.Condition{
+				Type:               consts.ConfigUpdateInProgressCondition,
+				Status:             metav1.ConditionTrue,
+				ObservedGeneration: device.Generation,
+				Reason:             consts.PendingRebootReason,
+				Message:            "",
+			}
+			meta.SetStatusCondition(&device.Status.Conditions, cond)
+			Expect(k8sClient.Status().Update(ctx, device)).To(Succeed())
+
+			startManager()
+
+			Eventually(func() []metav1.Condition {
+				device := &v1alpha1.NicDevice{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+				return device.Status.Conditions
+			}, timeout).Should(testutils.MatchCondition(metav1.Condition{
+				Type:    consts.ConfigUpdateInProgressCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  consts.FirmwareError,
+				Message: consts.FwConfigNotAppliedAfterRebootErrorMsg,
+			}))
 
 			hostManager.AssertNotCalled(GinkgoT(), "ApplyDeviceRuntimeSpec", mock.Anything)
 			maintenanceManager.AssertNotCalled(GinkgoT(), "ScheduleMaintenance", mock.Anything)
@@ -628,6 +1799,15 @@ var _ = Describe("NicDeviceReconciler", func() {
 			matchSecondDevice = mock.MatchedBy(func(input *v1alpha1.NicDevice) bool {
 				return input.Name == secondDeviceName
 			})
+
+			setConsistencyGroup = func(group string) {
+				for _, name := range []string{deviceName, secondDeviceName} {
+					device := &v1alpha1.NicDevice{}
+					Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: name, Namespace: namespaceName}, device)).To(Succeed())
+					device.Spec.Configuration.ConsistencyGroup = group
+					Expect(k8sClient.Update(ctx, device)).To(Succeed())
+				}
+			}
 		)
 
 		It("Should not begin maintenance and apply spec for device if spec validation failed for other device", func() {
@@ -720,5 +1900,208 @@ var _ = Describe("NicDeviceReconciler", func() {
 			hostManager.AssertNotCalled(GinkgoT(), "ApplyDeviceRuntimeSpec", mock.Anything)
 			maintenanceManager.AssertExpectations(GinkgoT())
 		})
+
+		It("Should continue with other devices if a host tool got stuck for one of them", func() {
+			toolHangErr := types.ToolHangError("mstconfig -d 0000:3b:00.0 -e query didn't finish in 10m0s and was killed")
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, matchFirstDevice).Return(false, false, toolHangErr)
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, matchSecondDevice).Return(true, true, nil)
+			maintenanceManager.On("ScheduleMaintenance", mock.Anything).Return(nil)
+			maintenanceManager.On("MaintenanceAllowed", mock.Anything).Return(true, nil)
+			hostManager.On("ApplyDeviceNvSpec", mock.Anything, matchSecondDevice).Return(true, nil)
+			maintenanceManager.On("Reboot").Return(nil)
+
+			createDevices()
+			startManager()
+
+			Eventually(func() []metav1.Condition {
+				device := &v1alpha1.NicDevice{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+				return device.Status.Conditions
+			}, timeout).Should(testutils.MatchCondition(metav1.Condition{
+				Type:    consts.ConfigUpdateInProgressCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  consts.DeviceToolHangReason,
+				Message: toolHangErr.Error(),
+			}))
+
+			Eventually(func() []metav1.Condition {
+				device := &v1alpha1.NicDevice{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: secondDeviceName, Namespace: namespaceName}, device)).To(Succeed())
+				return device.Status.Conditions
+			}, timeout).Should(testutils.MatchCondition(metav1.Condition{
+				Type:   consts.ConfigUpdateInProgressCondition,
+				Status: metav1.ConditionTrue,
+				Reason: consts.PendingRebootReason,
+			}))
+
+			hostManager.AssertNotCalled(GinkgoT(), "ApplyDeviceNvSpec", mock.Anything, matchFirstDevice)
+		})
+
+		It("Should continue with other devices if a host tool got stuck while applying the nv config of one of them", func() {
+			toolHangErr := types.ToolHangError("mstconfig -d 0000:3b:00.0 -y set didn't finish in 10m0s and was killed")
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(true, true, nil)
+			maintenanceManager.On("ScheduleMaintenance", mock.Anything).Return(nil)
+			maintenanceManager.On("MaintenanceAllowed", mock.Anything).Return(true, nil)
+			hostManager.On("ApplyDeviceNvSpec", mock.Anything, matchFirstDevice).Return(false, toolHangErr)
+			hostManager.On("ApplyDeviceNvSpec", mock.Anything, matchSecondDevice).Return(true, nil)
+			rebooted := make(chan struct{}, 1)
+			maintenanceManager.On("Reboot").Run(func(mock.Arguments) {
+				select {
+				case rebooted <- struct{}{}:
+				default:
+				}
+			}).Return(nil)
+
+			createDevices()
+			startManager()
+
+			Eventually(func() []metav1.Condition {
+				device := &v1alpha1.NicDevice{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+				return device.Status.Conditions
+			}, timeout).Should(testutils.MatchCondition(metav1.Condition{
+				Type:    consts.ConfigUpdateInProgressCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  consts.DeviceToolHangReason,
+				Message: toolHangErr.Error(),
+			}))
+
+			// The reboot activates the nv config of the other device
+			Eventually(rebooted, timeout).Should(Receive())
+		})
+
+		It("Should report devices configured by other hosts without applying them", func() {
+			delegatedErr := types.DelegatedToOtherHostError("nv config of device is owned by the host of the eswitch manager PF")
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, matchFirstDevice).Return(false, false, delegatedErr)
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, matchSecondDevice).Return(true, true, nil)
+			maintenanceManager.On("ScheduleMaintenance", mock.Anything).Return(nil)
+			maintenanceManager.On("MaintenanceAllowed", mock.Anything).Return(true, nil)
+			hostManager.On("ApplyDeviceNvSpec", mock.Anything, matchSecondDevice).Return(true, nil)
+			maintenanceManager.On("Reboot").Return(nil)
+
+			createDevices()
+			startManager()
+
+			Eventually(func() []metav1.Condition {
+				device := &v1alpha1.NicDevice{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: deviceName, Namespace: namespaceName}, device)).To(Succeed())
+				return device.Status.Conditions
+			}, timeout).Should(testutils.MatchCondition(metav1.Condition{
+				Type:    consts.ConfigUpdateInProgressCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  consts.DelegatedToOtherHostReason,
+				Message: delegatedErr.Error(),
+			}))
+
+			Eventually(func() []metav1.Condition {
+				device := &v1alpha1.NicDevice{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: secondDeviceName, Namespace: namespaceName}, device)).To(Succeed())
+				return device.Status.Conditions
+			}, timeout).Should(testutils.MatchCondition(metav1.Condition{
+				Type:   consts.ConfigUpdateInProgressCondition,
+				Status: metav1.ConditionTrue,
+				Reason: consts.PendingRebootReason,
+			}))
+
+			hostManager.AssertNotCalled(GinkgoT(), "ApplyDeviceNvSpec", mock.Anything, matchFirstDevice)
+			hostManager.AssertNotCalled(GinkgoT(), "ApplyDeviceRuntimeSpec", matchFirstDevice)
+		})
+
+		It("Should hold the devices of a consistency group if a host tool got stuck for one of them", func() {
+			toolHangErr := types.ToolHangError("mstconfig -d 0000:3b:00.0 -e query didn't finish in 10m0s and was killed")
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, matchFirstDevice).Return(false, false, toolHangErr)
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, matchSecondDevice).Return(true, true, nil)
+
+			createDevices()
+			setConsistencyGroup("bond0")
+			startManager()
+
+			Eventually(func() []metav1.Condition {
+				device := &v1alpha1.NicDevice{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: secondDeviceName, Namespace: namespaceName}, device)).To(Succeed())
+				return device.Status.Conditions
+			}, timeout).Should(testutils.MatchCondition(metav1.Condition{
+				Type:    consts.ConfigUpdateInProgressCondition,
+				Status:  metav1.ConditionTrue,
+				Reason:  consts.ConsistencyGroupPendingReason,
+				Message: fmt.Sprintf("waiting for device %s of consistency group bond0", deviceName),
+			}))
+
+			hostManager.AssertNotCalled(GinkgoT(), "ApplyDeviceNvSpec", mock.Anything, mock.Anything)
+			maintenanceManager.AssertNotCalled(GinkgoT(), "ScheduleMaintenance", mock.Anything)
+		})
+
+		It("Should roll back the devices of a consistency group if nv config fails to apply for one of them", func() {
+			hostManager.On("ValidateDeviceNvSpec", mock.Anything, mock.Anything).Return(true, false, nil)
+			maintenanceManager.On("ScheduleMaintenance", mock.Anything).Return(nil)
+			maintenanceManager.On("MaintenanceAllowed", mock.Anything).Return(true, nil)
+			hostManager.On("ApplyDeviceNvSpec", mock.Anything, matchFirstDevice).Return(false, errors.New("failed to set LAG_RESOURCE_ALLOCATION"))
+			hostManager.On("ApplyDeviceNvSpec", mock.Anything, matchSecondDevice).Return(true, nil)
+			hostManager.On("RollbackDeviceNvSpec", mock.Anything, matchSecondDevice).Return(nil)
+
+			createDevices()
+			setConsistencyGroup("bond0")
+			startManager()
+
+			Eventually(func() []metav1.Condition {
+				device := &v1alpha1.NicDevice{}
+				Expect(k8sClient.Get(ctx, k8sTypes.NamespacedName{Name: secondDeviceName, Namespace: namespaceName}, device)).To(Succeed())
+				return device.Status.Conditions
+			}, timeout).Should(testutils.MatchCondition(metav1.Condition{
+				Type:    consts.ConfigUpdateInProgressCondition,
+				Status:  metav1.ConditionFalse,
+				Reason:  consts.RolledBackReason,
+				Message: fmt.Sprintf("rolled back together with device %s of consistency group bond0", deviceName),
+			}))
+
+			hostManager.AssertNotCalled(GinkgoT(), "RollbackDeviceNvSpec", mock.Anything, matchFirstDevice)
+			maintenanceManager.AssertNotCalled(GinkgoT(), "Reboot")
+		})
+	})
+})
+
+var _ = Describe("startBFBInstall", func() {
+	var (
+		reconciler      *NicDeviceReconciler
+		firmwareManager *hostMocks.FirmwareManager
+		device          *v1alpha1.NicDevice
+		installed       chan struct{}
+	)
+
+	BeforeEach(func() {
+		installed = make(chan struct{})
+		firmwareManager = &hostMocks.FirmwareManager{}
+		firmwareManager.On("InstallBFB", mock.Anything, mock.Anything, "/cache/bf-bundle.bfb").Return(nil).Run(func(args mock.Arguments) {
+			<-installed
+			args.Get(1).(*v1alpha1.NicDevice).Status.BFB = &v1alpha1.BFBStatus{Bundle: "bf-bundle.bfb", InstallTime: metav1.Now()}
+		})
+		reconciler = &NicDeviceReconciler{FirmwareManager: firmwareManager, bfbInstallEvents: make(chan event.GenericEvent, 1)}
+		device = &v1alpha1.NicDevice{ObjectMeta: metav1.ObjectMeta{Name: "dpu"}}
+	})
+
+	It("should install the bundle in the background and report the result once it's done", func() {
+		statuses := nicDeviceConfigurationStatuses{{device: device}}
+		reconciler.startBFBInstall(context.Background(), device, "/cache/bf-bundle.bfb")
+
+		Expect(reconciler.bfbInstallsRunning()).To(BeTrue())
+		Expect(reconciler.bfbInstallsDone(statuses)).To(BeFalse())
+		Expect(reconciler.takeBFBInstall("dpu")).To(BeNil())
+
+		close(installed)
+		var triggered event.GenericEvent
+		Eventually(reconciler.bfbInstallEvents).Should(Receive(&triggered))
+		Expect(triggered.Object.GetName()).To(Equal("dpu"))
+		Expect(reconciler.bfbInstallsRunning()).To(BeFalse())
+		Expect(reconciler.bfbInstallsDone(statuses)).To(BeTrue())
+
+		install := reconciler.takeBFBInstall("dpu")
+		Expect(install).NotTo(BeNil())
+		Expect(install.err).NotTo(HaveOccurred())
+		Expect(install.bfb.Bundle).To(Equal("bf-bundle.bfb"))
+		// The installation updates its own copy of the device
+		Expect(device.Status.BFB).To(BeNil())
+		// The result is reported once
+		Expect(reconciler.takeBFBInstall("dpu")).To(BeNil())
+		Expect(reconciler.bfbInstallsDone(statuses)).To(BeFalse())
 	})
 })
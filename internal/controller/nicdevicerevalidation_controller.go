@@ -0,0 +1,191 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	v1alpha1 "github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+)
+
+// revalidationStatusDevicesLimit is the number of the device names reported in the status of the revalidation,
+// the status of the revalidations selecting large fleets only lists the first devices by name
+const revalidationStatusDevicesLimit = 100
+
+// NicDeviceRevalidationReconciler requests the revalidation of the NicDevices selected by the NicDeviceRevalidations
+// the selected devices are annotated with consts.RevalidateAnnotation once per generation of the revalidation's spec
+type NicDeviceRevalidationReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=configuration.net.nvidia.com,resources=nicdevicerevalidations,verbs=get;list;watch
+//+kubebuilder:rbac:groups=configuration.net.nvidia.com,resources=nicdevicerevalidations/status,verbs=get;update;patch
+
+// Reconcile annotates the NicDevices in the namespace of the revalidation that match its node selector and PSIDs
+// and reports them in its status, the devices are annotated again only after the spec of the revalidation changes
+func (r *NicDeviceRevalidationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	revalidation := &v1alpha1.NicDeviceRevalidation{}
+	err := r.Get(ctx, req.NamespacedName, revalidation)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Log.Error(err, "failed to get device revalidation", "revalidation", req.NamespacedName)
+		return ctrl.Result{}, err
+	}
+
+	if revalidation.Status.ObservedGeneration == revalidation.Generation {
+		// The devices were already requested to revalidate with this spec
+		return ctrl.Result{}, nil
+	}
+
+	deviceList := &v1alpha1.NicDeviceList{}
+	err = r.List(ctx, deviceList, client.InNamespace(req.Namespace))
+	if err != nil {
+		log.Log.Error(err, "failed to list NicDevices", "namespace", req.Namespace)
+		return ctrl.Result{}, err
+	}
+
+	selector := labels.SelectorFromSet(revalidation.Spec.NodeSelector)
+	nodeMatches := map[string]bool{}
+	request := fmt.Sprintf("%s/%d", revalidation.Name, revalidation.Generation)
+	devices := []string{}
+
+	for i := range deviceList.Items {
+		device := &deviceList.Items[i]
+		if len(revalidation.Spec.PSIDs) != 0 && !slices.Contains(revalidation.Spec.PSIDs, device.Status.PSID) {
+			continue
+		}
+
+		matches, found := nodeMatches[device.Status.Node]
+		if !found {
+			matches, err = r.nodeMatches(ctx, device.Status.Node, selector)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			nodeMatches[device.Status.Node] = matches
+		}
+		if !matches {
+			continue
+		}
+
+		if device.Annotations[consts.RevalidateAnnotation] != request {
+			patch := client.MergeFrom(device.DeepCopy())
+			if device.Annotations == nil {
+				device.Annotations = map[string]string{}
+			}
+			device.Annotations[consts.RevalidateAnnotation] = request
+			err = r.Patch(ctx, device, patch)
+			if err != nil {
+				log.Log.Error(err, "failed to request revalidation of device", "device", device.Name)
+				return ctrl.Result{}, err
+			}
+		}
+		devices = append(devices, device.Name)
+	}
+
+	log.Log.Info("requested revalidation of devices", "revalidation", req.NamespacedName, "devices", len(devices))
+	count := len(devices)
+	slices.Sort(devices)
+	if count > revalidationStatusDevicesLimit {
+		devices = devices[:revalidationStatusDevicesLimit]
+	}
+	now := metav1.Now()
+	revalidation.Status = v1alpha1.NicDeviceRevalidationStatus{
+		ObservedGeneration: revalidation.Generation,
+		DeviceCount:        count,
+		Devices:            devices,
+		RequestTime:        &now,
+	}
+	err = r.Status().Update(ctx, revalidation)
+	if err != nil {
+		log.Log.Error(err, "failed to update device revalidation status", "revalidation", req.NamespacedName)
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// nodeMatches returns true if the labels of the node match the selector, the devices of the missing nodes don't match
+func (r *NicDeviceRevalidationReconciler) nodeMatches(ctx context.Context, nodeName string, selector labels.Selector) (bool, error) {
+	if selector.Empty() {
+		return true, nil
+	}
+
+	node := &v1.Node{}
+	err := r.Get(ctx, types.NamespacedName{Name: nodeName}, node)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		log.Log.Error(err, "failed to get node object", "node", nodeName)
+		return false, err
+	}
+	return selector.Matches(labels.Set(node.Labels)), nil
+}
+
+// acceptRevalidationRequests drops the state memoized for the devices annotated with consts.RevalidateAnnotation,
+// so that they are validated in this reconciliation, and removes the annotation from them
+func (r *NicDeviceReconciler) acceptRevalidationRequests(ctx context.Context, statuses nicDeviceConfigurationStatuses) error {
+	for _, status := range statuses {
+		request, found := status.device.Annotations[consts.RevalidateAnnotation]
+		if !found {
+			continue
+		}
+
+		log.Log.Info("revalidation of device requested", "device", status.device.Name, "request", request)
+		delete(r.configOwnershipDenied, status.device.Name)
+		if r.validationDeferredUntil == nil {
+			r.validationDeferredUntil = map[string]time.Time{}
+		}
+		r.validationDeferredUntil[status.device.Name] = time.Time{}
+
+		patch := client.MergeFrom(status.device.DeepCopy())
+		delete(status.device.Annotations, consts.RevalidateAnnotation)
+		err := r.Patch(ctx, status.device, patch)
+		if err != nil {
+			log.Log.Error(err, "failed to remove revalidate annotation from device", "device", status.device.Name)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *NicDeviceRevalidationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.NicDeviceRevalidation{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Named("nicDeviceRevalidationReconciler").
+		Complete(r)
+}
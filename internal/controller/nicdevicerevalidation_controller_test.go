@@ -0,0 +1,155 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+)
+
+var _ = Describe("NicDeviceRevalidationReconciler", func() {
+	const namespace = "nic-configuration-operator"
+
+	var (
+		k8sClient    client.Client
+		reconciler   *NicDeviceRevalidationReconciler
+		revalidation *v1alpha1.NicDeviceRevalidation
+	)
+
+	nicDevice := func(name string, node string, psid string) *v1alpha1.NicDevice {
+		return &v1alpha1.NicDevice{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Status:     v1alpha1.NicDeviceStatus{Node: node, PSID: psid},
+		}
+	}
+
+	deviceAnnotations := func(name string) map[string]string {
+		device := &v1alpha1.NicDevice{}
+		Expect(k8sClient.Get(context.Background(), client.ObjectKey{Name: name, Namespace: namespace}, device)).To(Succeed())
+		return device.Annotations
+	}
+
+	reconcileRevalidation := func() {
+		_, err := reconciler.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(revalidation)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k8sClient.Get(context.Background(), client.ObjectKeyFromObject(revalidation), revalidation)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		revalidation = &v1alpha1.NicDeviceRevalidation{
+			ObjectMeta: metav1.ObjectMeta{Name: "fabric-change", Namespace: namespace, Generation: 1},
+			Spec: v1alpha1.NicDeviceRevalidationSpec{
+				NodeSelector: map[string]string{"fabric": "spectrum-x"},
+				PSIDs:        []string{"MT_0000000221"},
+			},
+		}
+		k8sClient = fake.NewClientBuilder().WithScheme(scheme.Scheme).
+			WithObjects(
+				&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1", Labels: map[string]string{"fabric": "spectrum-x"}}},
+				&v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-2"}},
+				nicDevice("node-1-dev1", "node-1", "MT_0000000221"),
+				nicDevice("node-1-dev2", "node-1", "MT_0000000228"),
+				nicDevice("node-2-dev1", "node-2", "MT_0000000221"),
+				revalidation,
+			).
+			WithStatusSubresource(&v1alpha1.NicDeviceRevalidation{}).
+			Build()
+		reconciler = &NicDeviceRevalidationReconciler{Client: k8sClient, Scheme: scheme.Scheme}
+	})
+
+	It("should request the revalidation of the selected devices", func() {
+		reconcileRevalidation()
+
+		Expect(deviceAnnotations("node-1-dev1")).To(HaveKeyWithValue(consts.RevalidateAnnotation, "fabric-change/1"))
+		Expect(deviceAnnotations("node-1-dev2")).NotTo(HaveKey(consts.RevalidateAnnotation))
+		Expect(deviceAnnotations("node-2-dev1")).NotTo(HaveKey(consts.RevalidateAnnotation))
+		Expect(revalidation.Status.ObservedGeneration).To(Equal(int64(1)))
+		Expect(revalidation.Status.Devices).To(ConsistOf("node-1-dev1"))
+		Expect(revalidation.Status.DeviceCount).To(Equal(1))
+		Expect(revalidation.Status.RequestTime).NotTo(BeNil())
+	})
+
+	It("should report the count of the devices and truncate their list", func() {
+		for i := 0; i < revalidationStatusDevicesLimit+1; i++ {
+			Expect(k8sClient.Create(context.Background(), nicDevice(fmt.Sprintf("node-1-dev%03d", i), "node-1", "MT_0000000221"))).To(Succeed())
+		}
+
+		reconcileRevalidation()
+
+		Expect(revalidation.Status.DeviceCount).To(Equal(revalidationStatusDevicesLimit + 2))
+		Expect(revalidation.Status.Devices).To(HaveLen(revalidationStatusDevicesLimit))
+		Expect(revalidation.Status.Devices[0]).To(Equal("node-1-dev000"))
+		Expect(revalidation.Status.Devices).NotTo(ContainElement("node-1-dev1"))
+	})
+
+	It("should select all devices if the spec is empty", func() {
+		revalidation.Spec = v1alpha1.NicDeviceRevalidationSpec{}
+		Expect(k8sClient.Update(context.Background(), revalidation)).To(Succeed())
+
+		reconcileRevalidation()
+
+		Expect(revalidation.Status.Devices).To(ConsistOf("node-1-dev1", "node-1-dev2", "node-2-dev1"))
+	})
+
+	It("should request the revalidation only once per generation", func() {
+		reconcileRevalidation()
+		device := &v1alpha1.NicDevice{}
+		Expect(k8sClient.Get(context.Background(), client.ObjectKey{Name: "node-1-dev1", Namespace: namespace}, device)).To(Succeed())
+		delete(device.Annotations, consts.RevalidateAnnotation)
+		Expect(k8sClient.Update(context.Background(), device)).To(Succeed())
+
+		reconcileRevalidation()
+
+		Expect(deviceAnnotations("node-1-dev1")).NotTo(HaveKey(consts.RevalidateAnnotation))
+	})
+})
+
+var _ = Describe("acceptRevalidationRequests", func() {
+	It("should drop the memoized state of the annotated devices and remove the annotation", func() {
+		annotated := &v1alpha1.NicDevice{ObjectMeta: metav1.ObjectMeta{Name: "dev1", Namespace: "default",
+			Annotations: map[string]string{consts.RevalidateAnnotation: "fabric-change/1"}}}
+		other := &v1alpha1.NicDevice{ObjectMeta: metav1.ObjectMeta{Name: "dev2", Namespace: "default"}}
+		k8sClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(annotated, other).Build()
+		reconciler := &NicDeviceReconciler{
+			Client:                  k8sClient,
+			configOwnershipDenied:   map[string]string{"dev1": "1/RESTRICTED", "dev2": "1/RESTRICTED"},
+			validationDeferredUntil: map[string]time.Time{"dev1": time.Now().Add(time.Hour)},
+		}
+
+		err := reconciler.acceptRevalidationRequests(context.Background(),
+			nicDeviceConfigurationStatuses{{device: annotated}, {device: other}})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(reconciler.configOwnershipDenied).To(Equal(map[string]string{"dev2": "1/RESTRICTED"}))
+		Expect(reconciler.validationDeferredUntil).To(HaveKeyWithValue("dev1", time.Time{}))
+		device := &v1alpha1.NicDevice{}
+		Expect(k8sClient.Get(context.Background(), client.ObjectKeyFromObject(annotated), device)).To(Succeed())
+		Expect(device.Annotations).NotTo(HaveKey(consts.RevalidateAnnotation))
+	})
+})
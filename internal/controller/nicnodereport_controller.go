@@ -0,0 +1,106 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	v1alpha1 "github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+)
+
+// NicNodeReportReconciler fans the NicNodeReports published by the config daemons out into the NicDevice CRs
+type NicNodeReportReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=configuration.net.nvidia.com,resources=nicnodereports,verbs=get;list;watch;create;update
+
+// Reconcile creates, updates and deletes the NicDevice CRs of the node according to its NicNodeReport
+func (r *NicNodeReportReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	report := &v1alpha1.NicNodeReport{}
+	err := r.Get(ctx, req.NamespacedName, report)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			// Report was deleted together with its node, NicDevice CRs are garbage collected by their owner reference
+			return ctrl.Result{}, nil
+		}
+		log.Log.Error(err, "failed to get node report", "report", req.NamespacedName)
+		return ctrl.Result{}, err
+	}
+
+	node := &v1.Node{}
+	err = r.Get(ctx, types.NamespacedName{Name: report.Spec.Node}, node)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Log.Info("node report doesn't match any node, skipping", "report", req.NamespacedName, "node", report.Spec.Node)
+			return ctrl.Result{}, nil
+		}
+		log.Log.Error(err, "failed to get node object", "node", report.Spec.Node)
+		return ctrl.Result{}, err
+	}
+
+	log.Log.V(2).Info("Reconciling node report", "report", req.NamespacedName, "devices", len(report.Spec.Devices))
+
+	observedDevices := map[string]v1alpha1.NicNodeReportDevice{}
+	for _, device := range report.Spec.Devices {
+		device.Status.Node = node.Name
+		observedDevices[device.Status.SerialNumber] = device
+	}
+
+	err = syncNicDevices(ctx, r.Client, node, req.Namespace, observedDevices)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *NicNodeReportReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// NicDevice CRs deleted by users are recreated from the node's report
+	nicDeviceEventHandler := handler.Funcs{
+		DeleteFunc: func(ctx context.Context, e event.DeleteEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			device, ok := e.Object.(*v1alpha1.NicDevice)
+			if !ok || device.Status.Node == "" {
+				return
+			}
+			log.Log.V(2).Info("Enqueuing node report sync for device delete event", "device", device.Name)
+			q.Add(reconcile.Request{NamespacedName: types.NamespacedName{
+				Namespace: device.Namespace,
+				Name:      device.Status.Node,
+			}})
+		},
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.NicNodeReport{}).
+		Watches(&v1alpha1.NicDevice{}, nicDeviceEventHandler).
+		Named("nicNodeReportReconciler").
+		Complete(r)
+}
@@ -0,0 +1,215 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+)
+
+var _ = Describe("NicNodeReportReconciler", func() {
+	var (
+		mgr           manager.Manager
+		k8sClient     client.Client
+		nodeName      = "report-node"
+		ctx           context.Context
+		cancel        context.CancelFunc
+		timeout       = time.Second * 10
+		namespaceName string
+		wg            sync.WaitGroup
+		err           error
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.TODO())
+		mgr, err = ctrl.NewManager(cfg, ctrl.Options{
+			Scheme: scheme.Scheme,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		k8sClient = mgr.GetClient()
+
+		node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName}}
+		Expect(k8sClient.Create(ctx, node)).To(Succeed())
+
+		namespaceName = "nic-configuration-operator-" + rand.String(6)
+		ns := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name: namespaceName,
+		}}
+		Expect(k8sClient.Create(context.Background(), ns)).To(Succeed())
+
+		err = mgr.GetCache().IndexField(context.Background(), &v1alpha1.NicDevice{}, "status.node", func(o client.Object) []string {
+			return []string{o.(*v1alpha1.NicDevice).Status.Node}
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect((&NicNodeReportReconciler{
+			Client: k8sClient,
+			Scheme: mgr.GetScheme(),
+		}).SetupWithManager(mgr)).To(Succeed())
+
+		wg = sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer GinkgoRecover()
+			Expect(mgr.Start(ctx)).To(Succeed())
+		}()
+	})
+
+	AfterEach(func() {
+		Expect(k8sClient.DeleteAllOf(ctx, &v1alpha1.NicNodeReport{}, client.InNamespace(namespaceName))).To(Succeed())
+		Expect(k8sClient.DeleteAllOf(ctx, &v1alpha1.NicDevice{}, client.InNamespace(namespaceName))).To(Succeed())
+		Expect(k8sClient.Delete(ctx, &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespaceName}})).To(Succeed())
+		Expect(k8sClient.DeleteAllOf(ctx, &v1.Node{})).To(Succeed())
+		cancel()
+		wg.Wait()
+	})
+
+	getDevice := func(name string) (*v1alpha1.NicDevice, error) {
+		device := &v1alpha1.NicDevice{}
+		err := k8sClient.Get(ctx, client.ObjectKey{Name: name, Namespace: namespaceName}, device)
+		return device, err
+	}
+
+	It("should fan the report out into the NicDevice CRs", func() {
+		report := &v1alpha1.NicNodeReport{
+			ObjectMeta: metav1.ObjectMeta{Name: nodeName, Namespace: namespaceName},
+			Spec: v1alpha1.NicNodeReportSpec{
+				Node: nodeName,
+				Devices: []v1alpha1.NicNodeReportDevice{
+					{
+						Status: v1alpha1.NicDeviceStatus{
+							SerialNumber:    "serial-a",
+							Type:            "connectx6",
+							FirmwareVersion: "22.41.1000",
+							Ports:           []v1alpha1.NicDevicePortSpec{{PCI: "0000:3b:00.0"}},
+						},
+						RecommendedFirmwareVersion: "22.41.1000",
+					},
+					{
+						Status: v1alpha1.NicDeviceStatus{
+							SerialNumber: "serial-b",
+							Type:         "connectx6",
+							Ports:        []v1alpha1.NicDevicePortSpec{{PCI: "0000:81:00.0"}},
+						},
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, report)).To(Succeed())
+
+		Eventually(func() (string, error) {
+			device, err := getDevice("report-node-connectx6-serial-a")
+			return device.Status.SerialNumber, err
+		}, timeout).Should(Equal("serial-a"))
+
+		device, err := getDevice("report-node-connectx6-serial-a")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(device.Status.Node).To(Equal(nodeName))
+		Expect(device.OwnerReferences).To(HaveLen(1))
+		Expect(device.OwnerReferences[0].Name).To(Equal(nodeName))
+		Expect(meta.IsStatusConditionTrue(device.Status.Conditions, consts.FimwareConfigMatchCondition)).To(BeTrue())
+		Expect(meta.FindStatusCondition(device.Status.Conditions, consts.ConfigUpdateInProgressCondition)).NotTo(BeNil())
+
+		Eventually(func() (string, error) {
+			device, err := getDevice("report-node-connectx6-serial-b")
+			return device.Status.SerialNumber, err
+		}, timeout).Should(Equal("serial-b"))
+
+		By("removing a device and updating the other one in the report")
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(report), report)).To(Succeed())
+		report.Spec.Devices = report.Spec.Devices[:1]
+		report.Spec.Devices[0].Status.PartNumber = "MCX623106AN-CDAT"
+		Expect(k8sClient.Update(ctx, report)).To(Succeed())
+
+		Eventually(func() (string, error) {
+			device, err := getDevice("report-node-connectx6-serial-a")
+			return device.Status.PartNumber, err
+		}, timeout).Should(Equal("MCX623106AN-CDAT"))
+
+		Eventually(func() (int, error) {
+			list := &v1alpha1.NicDeviceList{}
+			err := k8sClient.List(ctx, list, client.InNamespace(namespaceName))
+			return len(list.Items), err
+		}, timeout).Should(Equal(1))
+	})
+
+	It("should preserve the status reported by the device reconciler", func() {
+		device := &v1alpha1.NicDevice{
+			ObjectMeta: metav1.ObjectMeta{Name: "report-node-connectx6-serial-a", Namespace: namespaceName},
+		}
+		Expect(k8sClient.Create(ctx, device)).To(Succeed())
+		device.Status = v1alpha1.NicDeviceStatus{
+			Node:               nodeName,
+			SerialNumber:       "serial-a",
+			Type:               "connectx6",
+			Ports:              []v1alpha1.NicDevicePortSpec{{PCI: "0000:3b:00.0"}},
+			NvConfigPCI:        "0000:3b:00.1",
+			NvConfigParameters: []v1alpha1.NvConfigParameterStatus{{Name: "NUM_OF_VFS", DesiredValue: "8"}},
+			Conditions: []metav1.Condition{{
+				Type:               consts.ConfigUpdateInProgressCondition,
+				Status:             metav1.ConditionFalse,
+				Reason:             consts.UpdateSuccessfulReason,
+				LastTransitionTime: metav1.Now(),
+			}},
+		}
+		Expect(k8sClient.Status().Update(ctx, device)).To(Succeed())
+
+		report := &v1alpha1.NicNodeReport{
+			ObjectMeta: metav1.ObjectMeta{Name: nodeName, Namespace: namespaceName},
+			Spec: v1alpha1.NicNodeReportSpec{
+				Node: nodeName,
+				Devices: []v1alpha1.NicNodeReportDevice{{
+					Status: v1alpha1.NicDeviceStatus{
+						SerialNumber:    "serial-a",
+						Type:            "connectx6",
+						FirmwareVersion: "22.41.1000",
+						Ports:           []v1alpha1.NicDevicePortSpec{{PCI: "0000:3b:00.0"}},
+					},
+				}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, report)).To(Succeed())
+
+		Eventually(func() (string, error) {
+			device, err := getDevice("report-node-connectx6-serial-a")
+			return device.Status.FirmwareVersion, err
+		}, timeout).Should(Equal("22.41.1000"))
+
+		device, err := getDevice("report-node-connectx6-serial-a")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(device.Status.NvConfigPCI).To(Equal("0000:3b:00.1"))
+		Expect(device.Status.NvConfigParameters).To(HaveLen(1))
+		Expect(meta.IsStatusConditionFalse(device.Status.Conditions, consts.ConfigUpdateInProgressCondition)).To(BeTrue())
+		Expect(meta.FindStatusCondition(device.Status.Conditions, consts.FimwareConfigMatchCondition)).NotTo(BeNil())
+	})
+})
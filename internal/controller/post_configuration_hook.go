@@ -0,0 +1,188 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sTypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	v1alpha1 "github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+)
+
+// runPostConfigurationHook restarts the workloads declared by the template's hook for the devices reconfigured by the config daemons
+// the hook runs for a node once all of the template's devices on the node applied the current template generation or failed to,
+// if the hook restarts a workload with the rolloutRestart strategy, once all of the template's devices did, so that each node
+// restarts the pods of a workload once per rollout and each workload is rolled out once per rollout
+// the results are reported with events of the devices, failures don't fail the reconciliation
+func (r *NicConfigurationTemplateReconciler) runPostConfigurationHook(ctx context.Context, template *v1alpha1.NicConfigurationTemplate,
+	devices []*v1alpha1.NicDevice) error {
+	hook := template.Spec.PostConfigurationHook
+	if hook == nil || len(hook.RestartWorkloads) == 0 {
+		return nil
+	}
+
+	workloads := []v1alpha1.WorkloadReference{}
+	rolloutRestart := false
+	for _, workload := range hook.RestartWorkloads {
+		if workload.Kind == "" {
+			workload.Kind = consts.WorkloadKindDaemonSet
+		}
+		if workload.Strategy == "" {
+			workload.Strategy = consts.RestartStrategyRestartPods
+		}
+		rolloutRestart = rolloutRestart || workload.Strategy == consts.RestartStrategyRolloutRestart
+		workloads = append(workloads, workload)
+	}
+
+	settledNodes := map[string]bool{}
+	allSettled := true
+	for _, device := range devices {
+		settled := deviceConvergedOnTemplate(device, template) || deviceFailedOnTemplate(device, template)
+		nodeSettled, found := settledNodes[device.Status.Node]
+		settledNodes[device.Status.Node] = settled && (nodeSettled || !found)
+		allSettled = allSettled && settled
+	}
+
+	pendingDevices := []*v1alpha1.NicDevice{}
+	nodes := []string{}
+	for _, device := range devices {
+		if !postConfigurationHookPending(device) || !settledNodes[device.Status.Node] || rolloutRestart && !allSettled {
+			continue
+		}
+		pendingDevices = append(pendingDevices, device)
+		if !slices.Contains(nodes, device.Status.Node) {
+			nodes = append(nodes, device.Status.Node)
+		}
+	}
+	if len(pendingDevices) == 0 {
+		return nil
+	}
+
+	for _, workload := range workloads {
+		description := fmt.Sprintf("%s %s/%s", workload.Kind, template.Namespace, workload.Name)
+		err := r.restartWorkload(ctx, template.Namespace, workload, nodes)
+		for _, device := range pendingDevices {
+			if err != nil {
+				log.Log.Error(err, "failed to restart dependent workload", "workload", description, "device", device.Name)
+				r.EventRecorder.Event(device, v1.EventTypeWarning, consts.WorkloadRestartFailedReason,
+					fmt.Sprintf("failed to restart %s: %v", description, err))
+				continue
+			}
+			r.EventRecorder.Event(device, v1.EventTypeNormal, consts.WorkloadRestartedReason,
+				fmt.Sprintf("restarted %s with the %s strategy", description, workload.Strategy))
+		}
+	}
+
+	// The hook isn't retried for the same configuration, the failures are reported with the events
+	for _, device := range pendingDevices {
+		patch := client.MergeFrom(device.DeepCopy())
+		device.Annotations[consts.PostConfigurationHookAnnotation] = device.Annotations[consts.ReconfiguredAtAnnotation]
+		err := r.Patch(ctx, device, patch)
+		if err != nil {
+			log.Log.Error(err, "failed to mark the post configuration hook of the device as done", "device", device.Name)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// postConfigurationHookPending returns true if the config daemon applied a new configuration to the device
+// since the post configuration hook last ran for it
+func postConfigurationHookPending(device *v1alpha1.NicDevice) bool {
+	reconfiguredAt := device.Annotations[consts.ReconfiguredAtAnnotation]
+	return reconfiguredAt != "" && device.Annotations[consts.PostConfigurationHookAnnotation] != reconfiguredAt
+}
+
+// restartWorkload restarts the workload in the namespace according to its strategy
+// restartPods deletes the workload's pods on the nodes, rolloutRestart annotates the workload's pod template
+func (r *NicConfigurationTemplateReconciler) restartWorkload(ctx context.Context, namespace string, workload v1alpha1.WorkloadReference,
+	nodes []string) error {
+	var object client.Object
+	switch workload.Kind {
+	case consts.WorkloadKindDaemonSet:
+		object = &appsv1.DaemonSet{}
+	case consts.WorkloadKindDeployment:
+		object = &appsv1.Deployment{}
+	default:
+		return fmt.Errorf("unsupported workload kind %s", workload.Kind)
+	}
+
+	err := r.apiReader().Get(ctx, k8sTypes.NamespacedName{Name: workload.Name, Namespace: namespace}, object)
+	if err != nil {
+		return err
+	}
+
+	var podTemplate *v1.PodTemplateSpec
+	var selector *metav1.LabelSelector
+	switch object := object.(type) {
+	case *appsv1.DaemonSet:
+		podTemplate, selector = &object.Spec.Template, object.Spec.Selector
+	case *appsv1.Deployment:
+		podTemplate, selector = &object.Spec.Template, object.Spec.Selector
+	}
+
+	if workload.Strategy == consts.RestartStrategyRolloutRestart {
+		patch := client.MergeFrom(object.DeepCopyObject().(client.Object))
+		if podTemplate.Annotations == nil {
+			podTemplate.Annotations = map[string]string{}
+		}
+		podTemplate.Annotations[consts.RestartedAtAnnotation] = time.Now().Format(time.RFC3339)
+		return r.Patch(ctx, object, patch)
+	}
+
+	podSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return err
+	}
+
+	pods := &v1.PodList{}
+	err = r.apiReader().List(ctx, pods, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: podSelector})
+	if err != nil {
+		return err
+	}
+
+	for i := range pods.Items {
+		if !slices.Contains(nodes, pods.Items[i].Spec.NodeName) {
+			continue
+		}
+		log.Log.Info("deleting pod of dependent workload", "pod", pods.Items[i].Name, "namespace", namespace, "node", pods.Items[i].Spec.NodeName)
+		err = r.Delete(ctx, &pods.Items[i])
+		if client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// apiReader returns the reader for the workloads and pods that are not watched by the reconciler
+func (r *NicConfigurationTemplateReconciler) apiReader() client.Reader {
+	if r.APIReader == nil {
+		return r.Client
+	}
+	return r.APIReader
+}
@@ -0,0 +1,143 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"syscall"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+	"github.com/Mellanox/nic-configuration-operator/pkg/host"
+)
+
+// representorSettleTime is the time without new network interfaces after which the representors are configured,
+// representors of all VFs appear shortly after the VFs are created, so they are configured in a single pass
+var representorSettleTime = time.Second * 2
+
+// linkSubscribeFunc delivers the network interface updates of the host to the channel until done is closed
+type linkSubscribeFunc func(ch chan<- netlink.LinkUpdate, done <-chan struct{}) error
+
+// RepresentorWatcher applies the representors settings of the devices' templates to the VF representors
+// of the PFs in switchdev mode as they appear on the host
+type RepresentorWatcher struct {
+	client.Client
+
+	hostManager   host.HostManager
+	eventRecorder record.EventRecorder
+	nodeName      string
+	subscribe     linkSubscribeFunc
+}
+
+// Start configures the representors each time new network interfaces appear on the host, until the context is done.
+// Existing network interfaces are reported as new on start, so the representors are configured on start as well
+func (w *RepresentorWatcher) Start(ctx context.Context) error {
+	log.Log.Info("Representor watcher started")
+
+	updates := make(chan netlink.LinkUpdate)
+	done := make(chan struct{})
+	defer close(done)
+
+	err := w.subscribe(updates, done)
+	if err != nil {
+		// Representors are still configured with the rest of the runtime config
+		log.Log.Error(err, "failed to subscribe to network interface updates, representors are not watched")
+		return nil
+	}
+
+	knownLinks := map[int32]bool{}
+	var settled <-chan time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case update, ok := <-updates:
+			if !ok {
+				log.Log.Info("network interface updates subscription closed, representors are not watched")
+				return nil
+			}
+
+			switch update.Header.Type {
+			case syscall.RTM_DELLINK:
+				delete(knownLinks, update.Index)
+			case syscall.RTM_NEWLINK:
+				// Changes of the known interfaces, including the ones applied by the watcher, are ignored
+				if knownLinks[update.Index] {
+					continue
+				}
+				knownLinks[update.Index] = true
+				settled = time.After(representorSettleTime)
+			}
+		case <-settled:
+			settled = nil
+			w.configureRepresentors(ctx)
+		}
+	}
+}
+
+// configureRepresentors applies the representors settings of the node's devices, failures are reported in the devices' events
+func (w *RepresentorWatcher) configureRepresentors(ctx context.Context) {
+	list := &v1alpha1.NicDeviceList{}
+	err := w.List(ctx, list, &client.ListOptions{FieldSelector: fields.OneTermEqualSelector("status.node", w.nodeName)})
+	if err != nil {
+		log.Log.Error(err, "failed to list NicDevice CRs")
+		return
+	}
+
+	for i := range list.Items {
+		device := &list.Items[i]
+		// Deferred representors settings are applied by the reconciler once an RDMA workload is scheduled on the node
+		if !representorsEnabled(device) || runtimeSettingsDeferred(device) {
+			continue
+		}
+
+		err = w.hostManager.ApplyRepresentorsRuntimeSpec(device)
+		if err != nil {
+			log.Log.Error(err, "failed to configure VF representors", "device", device.Name)
+			w.eventRecorder.Event(device, v1.EventTypeWarning, consts.RepresentorConfigFailedReason, err.Error())
+		}
+	}
+}
+
+func representorsEnabled(device *v1alpha1.NicDevice) bool {
+	if device.Spec.Configuration == nil || device.Spec.Configuration.Template == nil {
+		return false
+	}
+	representors := device.Spec.Configuration.Template.Representors
+	return representors != nil && representors.Enabled
+}
+
+// NewRepresentorWatcher creates a new instance of RepresentorWatcher subscribed to the host's netlink updates
+func NewRepresentorWatcher(client client.Client, hostManager host.HostManager, eventRecorder record.EventRecorder, node string) *RepresentorWatcher {
+	return &RepresentorWatcher{
+		Client:        client,
+		hostManager:   hostManager,
+		eventRecorder: eventRecorder,
+		nodeName:      node,
+		subscribe: func(ch chan<- netlink.LinkUpdate, done <-chan struct{}) error {
+			return netlink.LinkSubscribeWithOptions(ch, done, netlink.LinkSubscribeOptions{ListExisting: true})
+		},
+	}
+}
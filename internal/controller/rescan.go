@@ -0,0 +1,103 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+)
+
+// RescanReconciler triggers the discovery of the devices on the node as soon as the consts.RescanAnnotation
+// is set on the node or on one of its NicDevices, e.g. after a NIC was replaced, the annotation is removed once the discovery is triggered
+type RescanReconciler struct {
+	client.Client
+
+	NodeName  string
+	Discovery *DeviceDiscovery
+}
+
+// Reconcile removes the rescan annotation from the node or the NicDevice and triggers the discovery of the devices
+// the requests of the node have no namespace, the NicDevices are namespaced
+func (r *RescanReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var object client.Object = &v1alpha1.NicDevice{}
+	if req.Namespace == "" {
+		object = &v1.Node{}
+	}
+
+	err := r.Get(ctx, req.NamespacedName, object)
+	if apierrors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	}
+	if err != nil {
+		log.Log.Error(err, "failed to get rescan object", "name", req.Name)
+		return ctrl.Result{}, err
+	}
+
+	value, found := object.GetAnnotations()[consts.RescanAnnotation]
+	if !found {
+		return ctrl.Result{}, nil
+	}
+
+	// The annotation is removed first, so that it can be set again while the discovery is running
+	patch := client.MergeFrom(object.DeepCopyObject().(client.Object))
+	annotations := object.GetAnnotations()
+	delete(annotations, consts.RescanAnnotation)
+	object.SetAnnotations(annotations)
+	err = r.Patch(ctx, object, patch)
+	if err != nil {
+		log.Log.Error(err, "failed to remove rescan annotation", "name", req.Name)
+		return ctrl.Result{}, err
+	}
+
+	log.Log.Info("rescan requested, discovering devices", "name", req.Name, "value", value)
+	r.Discovery.Rescan()
+	return ctrl.Result{}, nil
+}
+
+// rescanRequested returns true if the object carries the rescan annotation
+func rescanRequested(object client.Object) bool {
+	_, found := object.GetAnnotations()[consts.RescanAnnotation]
+	return found
+}
+
+// SetupWithManager sets up the controller with the Manager.
+// Only the reconciler's node and the NicDevices discovered on it are watched for the rescan annotation
+func (r *RescanReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	nodePredicate := predicate.NewPredicateFuncs(func(object client.Object) bool {
+		return object.GetName() == r.NodeName && rescanRequested(object)
+	})
+	devicePredicate := predicate.NewPredicateFuncs(func(object client.Object) bool {
+		device, ok := object.(*v1alpha1.NicDevice)
+		return ok && device.Status.Node == r.NodeName && rescanRequested(device)
+	})
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.Node{}, builder.WithPredicates(nodePredicate)).
+		Watches(&v1alpha1.NicDevice{}, &handler.EnqueueRequestForObject{}, builder.WithPredicates(devicePredicate)).
+		Named("rescanReconciler").
+		Complete(r)
+}
@@ -0,0 +1,127 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math/rand"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+)
+
+// convergedState returns a hash of the state the device converged in: its applied spec, the resolved template values,
+// the firmware version and the boot of the host, the runtime config doesn't survive a reboot
+func convergedState(status *nicDeviceConfigurationStatus, bootID string) (string, error) {
+	spec, err := appliedState(status.device)
+	if err != nil {
+		return "", err
+	}
+
+	resolvedTemplate := []byte{}
+	if status.resolvedTemplate != nil {
+		resolvedTemplate, err = json.Marshal(status.resolvedTemplate)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	hash := sha256.New()
+	for _, part := range []string{spec, string(resolvedTemplate), status.device.Status.FirmwareVersion, bootID} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:configHashLength], nil
+}
+
+// convergedStateUnchanged returns true if the device is converged and its state matches the persisted consts.ConvergedStateAnnotation
+func convergedStateUnchanged(status *nicDeviceConfigurationStatus, bootID string) bool {
+	// An RDMA workload might have been scheduled since the runtime settings of the device were deferred
+	if bootID == "" || !deviceConverged(status.device) || operationInProgress(status.device) || runtimeSettingsDeferred(status.device) {
+		return false
+	}
+
+	persisted, found := status.device.Annotations[consts.ConvergedStateAnnotation]
+	if !found {
+		return false
+	}
+
+	state, err := convergedState(status, bootID)
+	if err != nil {
+		log.Log.Error(err, "failed to calculate converged state of device", "device", status.device.Name)
+		return false
+	}
+	return state == persisted
+}
+
+// hostBootID returns the boot ID of the host, it is read once per run of the config daemon
+// returns empty string if the boot ID is not available, converged states are not tracked in this case
+func (r *NicDeviceReconciler) hostBootID() string {
+	if r.bootID == "" {
+		bootID, err := r.HostUtils.GetHostBootID()
+		if err != nil {
+			log.Log.Error(err, "failed to get boot ID of the host, converged devices are re-validated right away")
+			return ""
+		}
+		r.bootID = bootID
+	}
+	return r.bootID
+}
+
+// deferConvergedDevices returns the devices to be reconciled now and the delay until the next deferred device is due, 0 if none
+// devices that converged before the config daemon restarted and whose state hasn't changed since are not re-validated right away,
+// their first validation is spread randomly over the RestartSyncWindow, so that the host tools don't run for all devices at once
+func (r *NicDeviceReconciler) deferConvergedDevices(statuses nicDeviceConfigurationStatuses) (nicDeviceConfigurationStatuses, time.Duration) {
+	if r.RestartSyncWindow <= 0 {
+		return statuses, 0
+	}
+	if r.validationDeferredUntil == nil {
+		r.validationDeferredUntil = map[string]time.Time{}
+	}
+
+	bootID := r.hostBootID()
+	now := time.Now()
+	remaining := nicDeviceConfigurationStatuses{}
+	var delay time.Duration
+
+	for _, status := range statuses {
+		validateAt, seen := r.validationDeferredUntil[status.device.Name]
+		if !seen {
+			validateAt = now.Add(time.Duration(rand.Int63n(int64(r.RestartSyncWindow))))
+		}
+
+		if validateAt.IsZero() || !now.Before(validateAt) || !convergedStateUnchanged(status, bootID) {
+			// The device is reconciled as usual from now on, e.g. after its spec changed
+			r.validationDeferredUntil[status.device.Name] = time.Time{}
+			remaining = append(remaining, status)
+			continue
+		}
+
+		if !seen {
+			log.Log.V(2).Info("device converged before restart, deferring its validation", "device", status.device.Name, "validateAt", validateAt)
+		}
+		r.validationDeferredUntil[status.device.Name] = validateAt
+		if until := validateAt.Sub(now); delay == 0 || until < delay {
+			delay = until
+		}
+	}
+
+	return remaining, delay
+}
@@ -0,0 +1,114 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+	hostMocks "github.com/Mellanox/nic-configuration-operator/pkg/host/mocks"
+)
+
+var _ = Describe("deferConvergedDevices", func() {
+	const window = 10 * time.Minute
+
+	var (
+		reconciler *NicDeviceReconciler
+		hostUtils  *hostMocks.HostUtils
+	)
+
+	// convergedDevice returns the status of a device that converged in the boot-1 boot of the host
+	convergedDevice := func(name string) *nicDeviceConfigurationStatus {
+		status := &nicDeviceConfigurationStatus{device: &v1alpha1.NicDevice{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Generation: 2},
+			Spec: v1alpha1.NicDeviceSpec{Configuration: &v1alpha1.NicDeviceConfigurationSpec{
+				Template: &v1alpha1.ConfigurationTemplateSpec{NumVfs: 8, LinkType: consts.Ethernet},
+			}},
+			Status: v1alpha1.NicDeviceStatus{
+				FirmwareVersion: "28.39.1002",
+				Conditions: []metav1.Condition{{
+					Type:               consts.ConfigUpdateInProgressCondition,
+					Status:             metav1.ConditionFalse,
+					Reason:             consts.UpdateSuccessfulReason,
+					ObservedGeneration: 2,
+				}},
+			},
+		}}
+		state, err := convergedState(status, "boot-1")
+		Expect(err).NotTo(HaveOccurred())
+		status.device.Annotations = map[string]string{consts.ConvergedStateAnnotation: state}
+		return status
+	}
+
+	BeforeEach(func() {
+		hostUtils = &hostMocks.HostUtils{}
+		hostUtils.On("GetHostBootID").Return("boot-1", nil)
+		reconciler = &NicDeviceReconciler{HostUtils: hostUtils, RestartSyncWindow: window}
+	})
+
+	It("should defer the validation of the devices converged in an unchanged state", func() {
+		statuses, delay := reconciler.deferConvergedDevices(nicDeviceConfigurationStatuses{convergedDevice("dev1"), convergedDevice("dev2")})
+		Expect(statuses).To(BeEmpty())
+		Expect(delay).To(BeNumerically(">", 0))
+		Expect(delay).To(BeNumerically("<=", window))
+	})
+
+	It("should validate the device once it is due and reconcile it as usual after that", func() {
+		device := convergedDevice("dev1")
+		reconciler.validationDeferredUntil = map[string]time.Time{"dev1": time.Now().Add(-time.Second)}
+
+		statuses, delay := reconciler.deferConvergedDevices(nicDeviceConfigurationStatuses{device})
+		Expect(statuses).To(HaveLen(1))
+		Expect(delay).To(BeZero())
+
+		statuses, _ = reconciler.deferConvergedDevices(nicDeviceConfigurationStatuses{device})
+		Expect(statuses).To(HaveLen(1))
+	})
+
+	It("should validate the device right away if its state changed", func() {
+		changed := convergedDevice("dev1")
+		changed.device.Spec.Configuration.Template.NumVfs = 16
+		upgraded := convergedDevice("dev2")
+		upgraded.device.Status.FirmwareVersion = "28.41.1000"
+
+		statuses, delay := reconciler.deferConvergedDevices(nicDeviceConfigurationStatuses{changed, upgraded, convergedDevice("dev3")})
+		Expect(statuses).To(ConsistOf(changed, upgraded))
+		Expect(delay).To(BeNumerically(">", 0))
+	})
+
+	It("should validate the devices right away after a reboot", func() {
+		hostUtils.ExpectedCalls = nil
+		hostUtils.On("GetHostBootID").Return("boot-2", nil)
+
+		statuses, delay := reconciler.deferConvergedDevices(nicDeviceConfigurationStatuses{convergedDevice("dev1")})
+		Expect(statuses).To(HaveLen(1))
+		Expect(delay).To(BeZero())
+	})
+
+	It("should validate the devices right away if the window is not set", func() {
+		reconciler.RestartSyncWindow = 0
+
+		statuses, delay := reconciler.deferConvergedDevices(nicDeviceConfigurationStatuses{convergedDevice("dev1")})
+		Expect(statuses).To(HaveLen(1))
+		Expect(delay).To(BeZero())
+		hostUtils.AssertNotCalled(GinkgoT(), "GetHostBootID")
+	})
+})
@@ -0,0 +1,246 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	v1alpha1 "github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+)
+
+// hardwareFingerprint identifies the nodes with identical NICs, failures of a configuration change are usually
+// correlated within such batches of hardware
+type hardwareFingerprint struct {
+	id string
+	// hardware lists the model and PSID of each kind of NIC on the node
+	hardware []string
+}
+
+// computeHardwareFingerprint computes the fingerprint of a node from all of its devices
+// the firmware version is left out, so that the nodes keep their fingerprint while a template upgrades their firmware
+func computeHardwareFingerprint(devices []*v1alpha1.NicDevice) hardwareFingerprint {
+	hardware := []string{}
+	for _, device := range devices {
+		entry := fmt.Sprintf("%s/%s", device.Status.Type, device.Status.PSID)
+		if !slices.Contains(hardware, entry) {
+			hardware = append(hardware, entry)
+		}
+	}
+	slices.Sort(hardware)
+
+	sum := sha256.Sum256([]byte(strings.Join(hardware, ",")))
+	return hardwareFingerprint{id: hex.EncodeToString(sum[:])[:12], hardware: hardware}
+}
+
+func rolloutStrategy(template *v1alpha1.NicConfigurationTemplate) string {
+	if template.Spec.Rollout == nil || template.Spec.Rollout.Strategy == "" {
+		return consts.RolloutStrategyAll
+	}
+	return string(template.Spec.Rollout.Strategy)
+}
+
+// fingerprintGroup is the set of the template's devices on the nodes sharing a hardware fingerprint
+type fingerprintGroup struct {
+	fingerprint hardwareFingerprint
+	// nodes are sorted by name to pick the same canary in each reconciliation
+	nodes   []string
+	devices map[string][]*v1alpha1.NicDevice
+}
+
+// rolloutByFingerprint rolls out the current generation of the template to a single canary node of each hardware fingerprint first
+// and to the rest of the nodes with the fingerprint once the canary is in sync, a failed canary halts the rollout to its fingerprint
+// returns the names of the devices that the template must not be applied to yet
+func (r *NicConfigurationTemplateReconciler) rolloutByFingerprint(ctx context.Context, template *v1alpha1.NicConfigurationTemplate,
+	devices []*v1alpha1.NicDevice, nodes map[string]*v1.Node, nodeDevices map[string][]*v1alpha1.NicDevice) (map[string]bool, error) {
+	groups := map[string]*fingerprintGroup{}
+	for _, device := range devices {
+		nodeName := device.Status.Node
+		fingerprint := computeHardwareFingerprint(nodeDevices[nodeName])
+
+		group, found := groups[fingerprint.id]
+		if !found {
+			group = &fingerprintGroup{fingerprint: fingerprint, devices: map[string][]*v1alpha1.NicDevice{}}
+			groups[fingerprint.id] = group
+		}
+		if _, found := group.devices[nodeName]; !found {
+			group.nodes = append(group.nodes, nodeName)
+
+			err := r.labelNodeFingerprint(ctx, nodes[nodeName], fingerprint.id)
+			if err != nil {
+				log.Log.Error(err, "failed to label the node with its hardware fingerprint", "node", nodeName)
+				return nil, err
+			}
+		}
+		group.devices[nodeName] = append(group.devices[nodeName], device)
+	}
+
+	previousPhases := map[string]string{}
+	previousCanaries := map[string]string{}
+	for _, status := range template.Status.Fingerprints {
+		previousPhases[status.Fingerprint] = status.Phase
+		previousCanaries[status.Fingerprint] = status.CanaryNode
+	}
+
+	heldDevices := map[string]bool{}
+	fingerprints := []v1alpha1.FingerprintRolloutStatus{}
+	ids := []string{}
+	for id := range groups {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		group := groups[id]
+		slices.Sort(group.nodes)
+
+		canary := pickCanaryNode(group, template, previousCanaries[id])
+		status := v1alpha1.FingerprintRolloutStatus{
+			Fingerprint: id,
+			Hardware:    group.fingerprint.hardware,
+			Nodes:       len(group.nodes),
+			CanaryNode:  canary,
+		}
+
+		converged := true
+		for _, nodeName := range group.nodes {
+			updated := true
+			for _, device := range group.devices[nodeName] {
+				updated = updated && deviceHasCurrentTemplate(device, template)
+				converged = converged && deviceConvergedOnTemplate(device, template)
+			}
+			if updated {
+				status.UpdatedNodes++
+			}
+		}
+
+		canaryConverged := true
+		canaryFailed := false
+		for _, device := range group.devices[canary] {
+			canaryConverged = canaryConverged && deviceConvergedOnTemplate(device, template)
+			canaryFailed = canaryFailed || deviceFailedOnTemplate(device, template)
+		}
+
+		switch {
+		case canaryFailed:
+			status.Phase = consts.RolloutPhaseHalted
+			if previousPhases[id] != consts.RolloutPhaseHalted {
+				log.Log.Info("canary node failed to apply the template, halting the rollout to its hardware fingerprint",
+					"template", template.Name, "node", canary, "fingerprint", id)
+				r.EventRecorder.Event(template, v1.EventTypeWarning, consts.RolloutHaltedReason,
+					fmt.Sprintf("Canary node %s failed to apply the configuration, halting the rollout to the nodes with hardware fingerprint %s (%s)",
+						canary, id, strings.Join(group.fingerprint.hardware, ", ")))
+			}
+		case !canaryConverged:
+			status.Phase = consts.RolloutPhaseCanary
+		case converged:
+			status.Phase = consts.RolloutPhaseCompleted
+		default:
+			status.Phase = consts.RolloutPhaseRollingOut
+		}
+
+		if status.Phase == consts.RolloutPhaseHalted || status.Phase == consts.RolloutPhaseCanary {
+			for _, nodeName := range group.nodes {
+				if nodeName == canary {
+					continue
+				}
+				for _, device := range group.devices[nodeName] {
+					if !deviceHasCurrentTemplate(device, template) {
+						heldDevices[device.Name] = true
+					}
+				}
+			}
+		}
+
+		fingerprints = append(fingerprints, status)
+	}
+
+	template.Status.Fingerprints = fingerprints
+	return heldDevices, nil
+}
+
+// pickCanaryNode keeps the canary of the ongoing rollout, otherwise picks the first node the current template generation
+// was already applied to, or the first node of the group
+func pickCanaryNode(group *fingerprintGroup, template *v1alpha1.NicConfigurationTemplate, previousCanary string) string {
+	if slices.Contains(group.nodes, previousCanary) {
+		return previousCanary
+	}
+
+	for _, nodeName := range group.nodes {
+		for _, device := range group.devices[nodeName] {
+			if deviceHasCurrentTemplate(device, template) {
+				return nodeName
+			}
+		}
+	}
+
+	return group.nodes[0]
+}
+
+func deviceHasCurrentTemplate(device *v1alpha1.NicDevice, template *v1alpha1.NicConfigurationTemplate) bool {
+	return device.Labels[consts.TemplateLabel] == template.Name &&
+		device.Annotations[consts.TemplateGenerationAnnotation] == strconv.FormatInt(template.Generation, 10)
+}
+
+// deviceConvergedOnTemplate returns true if the device successfully applied the current template generation
+func deviceConvergedOnTemplate(device *v1alpha1.NicDevice, template *v1alpha1.NicConfigurationTemplate) bool {
+	if !deviceHasCurrentTemplate(device, template) {
+		return false
+	}
+
+	condition := meta.FindStatusCondition(device.Status.Conditions, consts.ConfigUpdateInProgressCondition)
+	return condition != nil && condition.Status == metav1.ConditionFalse &&
+		condition.Reason == consts.UpdateSuccessfulReason && condition.ObservedGeneration == device.Generation
+}
+
+// deviceFailedOnTemplate returns true if the device failed to apply the current template generation
+func deviceFailedOnTemplate(device *v1alpha1.NicDevice, template *v1alpha1.NicConfigurationTemplate) bool {
+	if !deviceHasCurrentTemplate(device, template) {
+		return false
+	}
+
+	condition := meta.FindStatusCondition(device.Status.Conditions, consts.ConfigUpdateInProgressCondition)
+	return condition != nil && condition.Status == metav1.ConditionFalse && condition.ObservedGeneration == device.Generation &&
+		condition.Reason != consts.UpdateSuccessfulReason && condition.Reason != consts.DeviceConfigSpecEmptyReason &&
+		condition.Reason != consts.PendingRebootReason
+}
+
+// labelNodeFingerprint publishes the hardware fingerprint of the node in its consts.HardwareFingerprintLabel
+func (r *NicConfigurationTemplateReconciler) labelNodeFingerprint(ctx context.Context, node *v1.Node, fingerprint string) error {
+	if node == nil || node.Labels[consts.HardwareFingerprintLabel] == fingerprint {
+		return nil
+	}
+
+	patch := client.MergeFrom(node.DeepCopy())
+	if node.Labels == nil {
+		node.Labels = map[string]string{}
+	}
+	node.Labels[consts.HardwareFingerprintLabel] = fingerprint
+
+	log.Log.Info("labeling the node with its hardware fingerprint", "node", node.Name, "fingerprint", fingerprint)
+	return r.Patch(ctx, node, patch)
+}
@@ -0,0 +1,197 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	v1alpha1 "github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+)
+
+// securityAdvisoryMetric reports the security advisories affecting the running firmware of each device
+var securityAdvisoryMetric = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "nic_configuration_operator_device_security_advisory",
+	Help: "Security advisory affecting the running firmware of the NIC device, set to 1 for each affecting advisory",
+}, []string{"namespace", "node", "device", "firmware_version", "advisory"})
+
+func init() {
+	metrics.Registry.MustRegister(securityAdvisoryMetric)
+}
+
+// SecurityAdvisoryReconciler flags the NicDevices running firmware versions listed in the advisories ConfigMap of their namespace
+type SecurityAdvisoryReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups="",namespace=system,resources=configmaps,verbs=get;list;watch
+
+// Reconcile sets the SecurityAdvisory condition of the NicDevices in the namespace of the advisories ConfigMap
+// the condition is removed from the devices if the ConfigMap doesn't exist
+func (r *SecurityAdvisoryReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	configMapFound := true
+	configMap := &v1.ConfigMap{}
+	err := r.Get(ctx, req.NamespacedName, configMap)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			log.Log.Error(err, "failed to get security advisories", "configMap", req.NamespacedName)
+			return ctrl.Result{}, err
+		}
+		configMapFound = false
+	}
+	advisories := parseSecurityAdvisories(configMap.Data)
+
+	deviceList := &v1alpha1.NicDeviceList{}
+	err = r.List(ctx, deviceList, client.InNamespace(req.Namespace))
+	if err != nil {
+		log.Log.Error(err, "failed to list NicDevices", "namespace", req.Namespace)
+		return ctrl.Result{}, err
+	}
+
+	log.Log.V(2).Info("Reconciling security advisories", "namespace", req.Namespace, "devices", len(deviceList.Items), "firmwareVersions", len(advisories))
+
+	securityAdvisoryMetric.DeletePartialMatch(prometheus.Labels{"namespace": req.Namespace})
+
+	for i := range deviceList.Items {
+		device := &deviceList.Items[i]
+		deviceAdvisories := advisories[strings.ToLower(device.Status.FirmwareVersion)]
+
+		for _, advisory := range deviceAdvisories {
+			securityAdvisoryMetric.WithLabelValues(device.Namespace, device.Status.Node, device.Name, device.Status.FirmwareVersion, advisory).Set(1)
+		}
+
+		var changed bool
+		if configMapFound {
+			changed = meta.SetStatusCondition(&device.Status.Conditions, securityAdvisoryCondition(device, deviceAdvisories))
+		} else {
+			changed = meta.RemoveStatusCondition(&device.Status.Conditions, consts.SecurityAdvisoryCondition)
+		}
+		if !changed {
+			continue
+		}
+
+		err = r.Status().Update(ctx, device)
+		if err != nil {
+			log.Log.Error(err, "failed to update security advisory condition of device", "device", device.Name)
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// securityAdvisoryCondition returns the SecurityAdvisory condition of the device affected by the given advisories
+func securityAdvisoryCondition(device *v1alpha1.NicDevice, advisories []string) metav1.Condition {
+	if len(advisories) == 0 {
+		return metav1.Condition{
+			Type:               consts.SecurityAdvisoryCondition,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: device.Generation,
+			Reason:             consts.NoKnownAdvisoriesReason,
+			Message:            fmt.Sprintf("No known advisories affect firmware %s", device.Status.FirmwareVersion),
+		}
+	}
+
+	return metav1.Condition{
+		Type:               consts.SecurityAdvisoryCondition,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: device.Generation,
+		Reason:             consts.AffectedByAdvisoryReason,
+		Message:            fmt.Sprintf("Firmware %s is affected by advisories: %s", device.Status.FirmwareVersion, strings.Join(advisories, ", ")),
+	}
+}
+
+// parseSecurityAdvisories parses the advisories ConfigMap, each key is a firmware version and
+// its value is a comma or whitespace separated list of advisories, e.g. 22.39.1002: "CVE-2024-0001, CVE-2024-0002"
+// returns the sorted advisories keyed by the lowercased firmware version
+func parseSecurityAdvisories(data map[string]string) map[string][]string {
+	advisories := map[string][]string{}
+
+	for version, value := range data {
+		version = strings.ToLower(strings.TrimSpace(version))
+		for _, advisory := range strings.FieldsFunc(value, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t' || r == '\n'
+		}) {
+			if !slices.Contains(advisories[version], advisory) {
+				advisories[version] = append(advisories[version], advisory)
+			}
+		}
+		slices.Sort(advisories[version])
+	}
+
+	return advisories
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *SecurityAdvisoryReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	qHandler := func(namespace string, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+		q.Add(reconcile.Request{NamespacedName: types.NamespacedName{
+			Namespace: namespace,
+			Name:      consts.SecurityAdvisoriesConfigmap,
+		}})
+	}
+
+	// Devices are re-evaluated when they appear or their firmware version changes
+	nicDeviceEventHandler := handler.Funcs{
+		CreateFunc: func(ctx context.Context, e event.CreateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			qHandler(e.Object.GetNamespace(), q)
+		},
+		UpdateFunc: func(ctx context.Context, e event.UpdateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			oldDevice, oldOk := e.ObjectOld.(*v1alpha1.NicDevice)
+			newDevice, newOk := e.ObjectNew.(*v1alpha1.NicDevice)
+			if !oldOk || !newOk || oldDevice.Status.FirmwareVersion == newDevice.Status.FirmwareVersion {
+				return
+			}
+			log.Log.V(2).Info("Enqueuing security advisories sync for firmware version change", "device", newDevice.Name)
+			qHandler(newDevice.Namespace, q)
+		},
+		DeleteFunc: func(ctx context.Context, e event.DeleteEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			qHandler(e.Object.GetNamespace(), q)
+		},
+	}
+
+	advisoriesConfigMapPredicate := predicate.NewPredicateFuncs(func(object client.Object) bool {
+		return object.GetName() == consts.SecurityAdvisoriesConfigmap
+	})
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.ConfigMap{}, builder.WithPredicates(advisoriesConfigMapPredicate)).
+		Watches(&v1alpha1.NicDevice{}, nicDeviceEventHandler).
+		Named("securityAdvisoryReconciler").
+		Complete(r)
+}
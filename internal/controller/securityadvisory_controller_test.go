@@ -0,0 +1,159 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/config"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+)
+
+var _ = Describe("SecurityAdvisoryReconciler", func() {
+	var (
+		mgr           manager.Manager
+		k8sClient     client.Client
+		ctx           context.Context
+		cancel        context.CancelFunc
+		timeout       = time.Second * 10
+		namespaceName string
+		wg            sync.WaitGroup
+		err           error
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.TODO())
+		mgr, err = ctrl.NewManager(cfg, ctrl.Options{
+			Scheme:     scheme.Scheme,
+			Metrics:    metricsserver.Options{BindAddress: "0"},
+			Controller: config.Controller{SkipNameValidation: ptr.To(true)},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		k8sClient = mgr.GetClient()
+
+		namespaceName = "nic-configuration-operator-" + rand.String(6)
+		ns := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name: namespaceName,
+		}}
+		Expect(k8sClient.Create(context.Background(), ns)).To(Succeed())
+
+		Expect((&SecurityAdvisoryReconciler{
+			Client: k8sClient,
+			Scheme: mgr.GetScheme(),
+		}).SetupWithManager(mgr)).To(Succeed())
+
+		wg = sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer GinkgoRecover()
+			Expect(mgr.Start(ctx)).To(Succeed())
+		}()
+	})
+
+	AfterEach(func() {
+		Expect(k8sClient.DeleteAllOf(ctx, &v1alpha1.NicDevice{}, client.InNamespace(namespaceName))).To(Succeed())
+		Expect(k8sClient.DeleteAllOf(ctx, &v1.ConfigMap{}, client.InNamespace(namespaceName))).To(Succeed())
+		Expect(k8sClient.Delete(ctx, &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespaceName}})).To(Succeed())
+		cancel()
+		wg.Wait()
+	})
+
+	createDevice := func(name string, firmwareVersion string) {
+		device := &v1alpha1.NicDevice{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespaceName}}
+		Expect(k8sClient.Create(ctx, device)).To(Succeed())
+		device.Status = v1alpha1.NicDeviceStatus{
+			Node:            "test-node",
+			FirmwareVersion: firmwareVersion,
+			Ports:           []v1alpha1.NicDevicePortSpec{{PCI: "0000:3b:00.0"}},
+		}
+		Expect(k8sClient.Status().Update(ctx, device)).To(Succeed())
+	}
+
+	getCondition := func(name string) func() *metav1.Condition {
+		return func() *metav1.Condition {
+			device := &v1alpha1.NicDevice{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Name: name, Namespace: namespaceName}, device)).To(Succeed())
+			return meta.FindStatusCondition(device.Status.Conditions, consts.SecurityAdvisoryCondition)
+		}
+	}
+
+	It("should flag devices running affected firmware", func() {
+		createDevice("affected-device", "22.39.1002")
+		createDevice("patched-device", "22.41.1000")
+
+		Consistently(getCondition("affected-device"), time.Second).Should(BeNil())
+
+		configMap := &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: consts.SecurityAdvisoriesConfigmap, Namespace: namespaceName},
+			Data:       map[string]string{"22.39.1002": "CVE-2024-0002, CVE-2024-0001"},
+		}
+		Expect(k8sClient.Create(ctx, configMap)).To(Succeed())
+
+		Eventually(getCondition("affected-device"), timeout).Should(And(
+			HaveField("Status", metav1.ConditionTrue),
+			HaveField("Reason", consts.AffectedByAdvisoryReason),
+			HaveField("Message", "Firmware 22.39.1002 is affected by advisories: CVE-2024-0001, CVE-2024-0002"),
+		))
+		Eventually(getCondition("patched-device"), timeout).Should(And(
+			HaveField("Status", metav1.ConditionFalse),
+			HaveField("Reason", consts.NoKnownAdvisoriesReason),
+		))
+		Expect(testutil.ToFloat64(securityAdvisoryMetric.WithLabelValues(
+			namespaceName, "test-node", "affected-device", "22.39.1002", "CVE-2024-0001"))).To(Equal(float64(1)))
+
+		By("updating the device's firmware")
+		device := &v1alpha1.NicDevice{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Name: "affected-device", Namespace: namespaceName}, device)).To(Succeed())
+		device.Status.FirmwareVersion = "22.41.1000"
+		Expect(k8sClient.Status().Update(ctx, device)).To(Succeed())
+
+		Eventually(getCondition("affected-device"), timeout).Should(HaveField("Status", metav1.ConditionFalse))
+
+		By("deleting the advisories")
+		Expect(k8sClient.Delete(ctx, configMap)).To(Succeed())
+		Eventually(getCondition("patched-device"), timeout).Should(BeNil())
+	})
+
+	It("should parse the advisories of the firmware versions", func() {
+		Expect(parseSecurityAdvisories(map[string]string{
+			"22.39.1002": "CVE-2024-0002,CVE-2024-0001\nCVE-2024-0002",
+			"20.41.1000": "NVIDIA-SA-5555",
+		})).To(Equal(map[string][]string{
+			"22.39.1002": {"CVE-2024-0001", "CVE-2024-0002"},
+			"20.41.1000": {"NVIDIA-SA-5555"},
+		}))
+		Expect(parseSecurityAdvisories(nil)).To(BeEmpty())
+	})
+})
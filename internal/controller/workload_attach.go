@@ -0,0 +1,147 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+)
+
+// deferredUntilWorkloadAttach returns true if the device's disruptive runtime settings are applied once a pod
+// requesting the RDMA resources is scheduled on the node, the settings are applied right away if RdmaResourcePrefixes is empty
+func (r *NicDeviceReconciler) deferredUntilWorkloadAttach(device *v1alpha1.NicDevice) bool {
+	if len(r.RdmaResourcePrefixes) == 0 || device.Spec.Configuration == nil || device.Spec.Configuration.Template == nil {
+		return false
+	}
+	return device.Spec.Configuration.Template.RuntimeConfigPolicy == consts.RuntimeConfigPolicyOnWorkloadAttach
+}
+
+// runtimeSettingsDeferred returns true if the device reports runtime settings waiting for an RDMA workload
+func runtimeSettingsDeferred(device *v1alpha1.NicDevice) bool {
+	return len(device.Status.DeferredRuntimeSettings) != 0
+}
+
+// runtimeSettingsDeferredMessage returns the message of the UpdateSuccessful condition of the device with the deferred settings
+func runtimeSettingsDeferredMessage(deferred []string) string {
+	return fmt.Sprintf("%s settings are deferred until an RDMA workload is scheduled on the node", strings.Join(deferred, ", "))
+}
+
+// channelsRequested returns true if the device's template sets the channels, including the per-port overrides
+func channelsRequested(device *v1alpha1.NicDevice) bool {
+	template := device.Spec.Configuration.Template
+	return template.Channels != nil || slices.ContainsFunc(template.Ports, func(port v1alpha1.PortConfigurationSpec) bool {
+		return port.Channels != nil
+	})
+}
+
+// withoutChannels replaces the device's template with a copy without the channels, including the per-port overrides, for the host calls
+// returns a function restoring the original template
+func (s *nicDeviceConfigurationStatus) withoutChannels() func() {
+	original := s.device.Spec.Configuration.Template
+	template := original.DeepCopy()
+	template.Channels = nil
+	for i := range template.Ports {
+		template.Ports[i].Channels = nil
+	}
+
+	s.device.Spec.Configuration.Template = template
+	return func() {
+		s.device.Spec.Configuration.Template = original
+	}
+}
+
+// rdmaConsumer returns the name (namespace/name) of the first running pod on the node requesting the resources
+// with one of the RdmaResourcePrefixes, returns empty string if there is none
+func (r *NicDeviceReconciler) rdmaConsumer(ctx context.Context) (string, error) {
+	pods := &v1.PodList{}
+	err := r.apiReader().List(ctx, pods, client.MatchingFields{"spec.nodeName": r.NodeName})
+	if err != nil {
+		return "", err
+	}
+
+	consumers := []string{}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if rdmaConsumerPod(pod, r.RdmaResourcePrefixes) {
+			consumers = append(consumers, pod.Namespace+"/"+pod.Name)
+		}
+	}
+	if len(consumers) == 0 {
+		return "", nil
+	}
+
+	slices.Sort(consumers)
+	return consumers[0], nil
+}
+
+// rdmaConsumerPod returns true if the pod is not terminated and requests a resource with one of the name prefixes
+func rdmaConsumerPod(pod *v1.Pod, prefixes []string) bool {
+	return pod.DeletionTimestamp == nil && pod.Status.Phase != v1.PodSucceeded && pod.Status.Phase != v1.PodFailed &&
+		requestsResources(pod, prefixes)
+}
+
+// withoutDeferredSettings replaces the device's template with a copy without the settings disrupting the traffic of the workloads:
+// the roceOptimized QoS and congestion control settings, the eswitch mode, the representors, the flow steering mode,
+// the MTU, the ring sizes, the interrupt coalescing and the channels, including the per-port overrides, for the host calls
+// returns a function restoring the original template and the names of the deferred settings set in the template
+// the eswitch mode change re-creates the PF's network interface, so it's deferred with the settings depending on it
+func (s *nicDeviceConfigurationStatus) withoutDeferredSettings() (func(), []string) {
+	original := s.device.Spec.Configuration.Template
+	template := original.DeepCopy()
+
+	deferred := []string{}
+	deferIf := func(name string, set bool) {
+		if set {
+			deferred = append(deferred, name)
+		}
+	}
+	deferIf("roceOptimized", template.RoceOptimized != nil)
+	deferIf("eswitchMode", template.EswitchMode != "")
+	deferIf("representors", template.Representors != nil)
+	deferIf("flowSteeringMode", template.FlowSteeringMode != "")
+	deferIf("mtu", template.Mtu != nil || slices.ContainsFunc(template.Ports, func(port v1alpha1.PortConfigurationSpec) bool {
+		return port.Mtu != nil
+	}))
+	deferIf("ringSize", template.RingSize != nil)
+	deferIf("coalescing", template.Coalescing != nil)
+	deferIf("channels", channelsRequested(s.device))
+
+	template.RoceOptimized = nil
+	template.EswitchMode = ""
+	template.Representors = nil
+	template.FlowSteeringMode = ""
+	template.Mtu = nil
+	template.RingSize = nil
+	template.Coalescing = nil
+	template.Channels = nil
+	for i := range template.Ports {
+		template.Ports[i].Mtu = nil
+		template.Ports[i].Channels = nil
+	}
+
+	s.device.Spec.Configuration.Template = template
+	return func() {
+		s.device.Spec.Configuration.Template = original
+	}, deferred
+}
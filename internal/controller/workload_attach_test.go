@@ -0,0 +1,174 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+)
+
+var _ = Describe("workload attach", func() {
+	var reconciler *NicDeviceReconciler
+
+	rdmaPod := func(name string, node string, phase v1.PodPhase) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: v1.PodSpec{
+				NodeName: node,
+				Containers: []v1.Container{{
+					Name: "app",
+					Resources: v1.ResourceRequirements{
+						Limits: v1.ResourceList{"rdma/rdma_shared_device_a": resource.MustParse("1")},
+					},
+				}},
+			},
+			Status: v1.PodStatus{Phase: phase},
+		}
+	}
+
+	newReconciler := func(pods ...client.Object) *NicDeviceReconciler {
+		reader := fake.NewClientBuilder().
+			WithScheme(scheme.Scheme).
+			WithObjects(pods...).
+			WithIndex(&v1.Pod{}, "spec.nodeName", func(o client.Object) []string {
+				return []string{o.(*v1.Pod).Spec.NodeName}
+			}).
+			Build()
+		return &NicDeviceReconciler{APIReader: reader, NodeName: "test-node", RdmaResourcePrefixes: []string{"rdma/"}}
+	}
+
+	device := func(policy v1alpha1.RuntimeConfigPolicyEnum) *v1alpha1.NicDevice {
+		return &v1alpha1.NicDevice{Spec: v1alpha1.NicDeviceSpec{Configuration: &v1alpha1.NicDeviceConfigurationSpec{
+			Template: &v1alpha1.ConfigurationTemplateSpec{
+				NumVfs:              8,
+				LinkType:            consts.Ethernet,
+				RoceOptimized:       &v1alpha1.RoceOptimizedSpec{Enabled: true, Qos: &v1alpha1.QosSpec{Trust: "dscp", PFC: "0,0,0,1,0,0,0,0"}},
+				Representors:        &v1alpha1.RepresentorsSpec{Enabled: true},
+				Mtu:                 &v1alpha1.MtuSpec{Size: 9000},
+				Ports:               []v1alpha1.PortConfigurationSpec{{Port: 2, Channels: &v1alpha1.ChannelsSpec{Combined: 16}}},
+				RuntimeConfigPolicy: policy,
+			},
+		}}}
+	}
+
+	BeforeEach(func() {
+		reconciler = newReconciler()
+	})
+
+	Describe("rdmaConsumer", func() {
+		It("should return the first running RDMA pod on the node", func() {
+			plain := rdmaPod("plain", "test-node", v1.PodRunning)
+			plain.Spec.Containers[0].Resources = v1.ResourceRequirements{}
+			reconciler = newReconciler(
+				rdmaPod("worker-b", "test-node", v1.PodRunning),
+				rdmaPod("worker-a", "test-node", v1.PodPending),
+				rdmaPod("completed", "test-node", v1.PodSucceeded),
+				rdmaPod("other", "other-node", v1.PodRunning),
+				plain,
+			)
+
+			consumer, err := reconciler.rdmaConsumer(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(consumer).To(Equal("default/worker-a"))
+		})
+
+		It("should return empty string if no RDMA pod is scheduled on the node", func() {
+			reconciler = newReconciler(rdmaPod("other", "other-node", v1.PodRunning))
+
+			consumer, err := reconciler.rdmaConsumer(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(consumer).To(BeEmpty())
+		})
+	})
+
+	Describe("deferredUntilWorkloadAttach", func() {
+		It("should defer the settings only with the OnWorkloadAttach policy", func() {
+			Expect(reconciler.deferredUntilWorkloadAttach(device(consts.RuntimeConfigPolicyOnWorkloadAttach))).To(BeTrue())
+			Expect(reconciler.deferredUntilWorkloadAttach(device(consts.RuntimeConfigPolicyImmediate))).To(BeFalse())
+			Expect(reconciler.deferredUntilWorkloadAttach(device(""))).To(BeFalse())
+		})
+
+		It("should not defer the settings if the RDMA resources are not known", func() {
+			reconciler.RdmaResourcePrefixes = nil
+			Expect(reconciler.deferredUntilWorkloadAttach(device(consts.RuntimeConfigPolicyOnWorkloadAttach))).To(BeFalse())
+		})
+	})
+
+	It("should apply the device's template without the deferred settings and restore it", func() {
+		status := &nicDeviceConfigurationStatus{device: device(consts.RuntimeConfigPolicyOnWorkloadAttach)}
+		original := status.device.Spec.Configuration.Template
+
+		restore, deferred := status.withoutDeferredSettings()
+		Expect(deferred).To(Equal([]string{"roceOptimized", "representors", "mtu", "channels"}))
+		Expect(status.device.Spec.Configuration.Template.RoceOptimized).To(BeNil())
+		Expect(status.device.Spec.Configuration.Template.EswitchMode).To(BeEmpty())
+		Expect(status.device.Spec.Configuration.Template.Representors).To(BeNil())
+		Expect(status.device.Spec.Configuration.Template.Mtu).To(BeNil())
+		Expect(status.device.Spec.Configuration.Template.Ports[0].Channels).To(BeNil())
+		Expect(status.device.Spec.Configuration.Template.NumVfs).To(Equal(8))
+
+		restore()
+		Expect(status.device.Spec.Configuration.Template).To(BeIdenticalTo(original))
+		Expect(original.RoceOptimized.Qos.Trust).To(Equal("dscp"))
+		Expect(original.Ports[0].Channels.Combined).To(Equal(16))
+	})
+
+	It("should defer nothing if the template doesn't set the disruptive settings", func() {
+		status := &nicDeviceConfigurationStatus{device: &v1alpha1.NicDevice{Spec: v1alpha1.NicDeviceSpec{Configuration: &v1alpha1.NicDeviceConfigurationSpec{
+			Template: &v1alpha1.ConfigurationTemplateSpec{NumVfs: 8, RuntimeConfigPolicy: consts.RuntimeConfigPolicyOnWorkloadAttach},
+		}}}}
+
+		restore, deferred := status.withoutDeferredSettings()
+		restore()
+		Expect(deferred).To(BeEmpty())
+	})
+
+	It("should apply the device's template without the channels while the disruption budgets block them", func() {
+		status := &nicDeviceConfigurationStatus{device: device(consts.RuntimeConfigPolicyImmediate)}
+		status.device.Spec.Configuration.Template.Channels = &v1alpha1.ChannelsSpec{}
+		original := status.device.Spec.Configuration.Template
+		Expect(channelsRequested(status.device)).To(BeTrue())
+
+		restore := status.withoutChannels()
+		Expect(channelsRequested(status.device)).To(BeFalse())
+		Expect(status.device.Spec.Configuration.Template.Mtu).NotTo(BeNil())
+
+		restore()
+		Expect(status.device.Spec.Configuration.Template).To(BeIdenticalTo(original))
+		Expect(original.Ports[0].Channels.Combined).To(Equal(16))
+	})
+
+	It("should report the deferred settings in the device status", func() {
+		deferred := device(consts.RuntimeConfigPolicyOnWorkloadAttach)
+		Expect(runtimeSettingsDeferred(deferred)).To(BeFalse())
+
+		deferred.Status.DeferredRuntimeSettings = []string{"mtu"}
+		Expect(runtimeSettingsDeferred(deferred)).To(BeTrue())
+		Expect(runtimeSettingsDeferredMessage([]string{"roceOptimized", "mtu"})).To(
+			Equal("roceOptimized, mtu settings are deferred until an RDMA workload is scheduled on the node"))
+	})
+})
@@ -0,0 +1,130 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package changelog records machine-readable records of the nv config changes applied to the devices,
+// so that change-management systems can ingest them
+package changelog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// SinkLog writes the records as JSON lines to the log stream of the config daemon
+	SinkLog = "log"
+	// SinkConfigMap stores the records in a per-node ConfigMap
+	SinkConfigMap = "configmap"
+	// SinkS3 uploads each record as an object to an S3-compatible endpoint
+	SinkS3 = "s3"
+)
+
+// Change describes a single nv config parameter change
+type Change struct {
+	// Name of the nv config parameter
+	Parameter string `json:"parameter"`
+	// Next boot values of the parameter before the change
+	PreviousValues []string `json:"previousValues,omitempty"`
+	// Value applied to the parameter
+	Value string `json:"value"`
+}
+
+// Record describes the nv config changes applied to a single device
+type Record struct {
+	// Time when the changes were applied
+	Time time.Time `json:"time"`
+	// Node where the device is located
+	Node string `json:"node"`
+	// Name of the NicDevice CR
+	Device string `json:"device"`
+	// Serial number of the device
+	SerialNumber string `json:"serialNumber"`
+	// Part number of the device
+	PartNumber string `json:"partNumber"`
+	// Firmware version of the device
+	FirmwareVersion string `json:"firmwareVersion"`
+	// ResetToDefault is set if the device's nv config was reset to defaults
+	ResetToDefault bool `json:"resetToDefault,omitempty"`
+	// Changes applied to the nv config parameters
+	Changes []Change `json:"changes,omitempty"`
+}
+
+// Sink stores the changelog records
+type Sink interface {
+	// Write stores a single changelog record
+	Write(ctx context.Context, record Record) error
+}
+
+// Config describes the changelog sink of the config daemon
+type Config struct {
+	// Sink type: log, configmap or s3, changelog is disabled if empty
+	Sink string
+	// Node where the config daemon runs
+	NodeName string
+	// Namespace for the changelog ConfigMaps
+	Namespace string
+	// Name prefix of the changelog ConfigMaps, node name is appended to it
+	ConfigMapName string
+	// S3-compatible endpoint configuration
+	S3 S3Config
+}
+
+// NewSink creates a changelog sink according to the config
+// returns nil if the changelog is disabled
+func NewSink(config Config, kubeClient kubernetes.Interface) (Sink, error) {
+	switch config.Sink {
+	case "":
+		return nil, nil
+	case SinkLog:
+		return NewLogSink(os.Stdout), nil
+	case SinkConfigMap:
+		return NewConfigMapSink(kubeClient, config.Namespace, config.ConfigMapName+"-"+config.NodeName), nil
+	case SinkS3:
+		return NewS3Sink(config.S3)
+	}
+
+	return nil, fmt.Errorf("unsupported changelog sink %q", config.Sink)
+}
+
+type logSink struct {
+	lock sync.Mutex
+	w    io.Writer
+}
+
+// NewLogSink creates a sink writing each record as a single JSON line to the given writer
+func NewLogSink(w io.Writer) Sink {
+	return &logSink{w: w}
+}
+
+// Write writes the record as a single JSON line
+func (s *logSink) Write(_ context.Context, record Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	_, err = fmt.Fprintf(s.w, "%s\n", line)
+	return err
+}
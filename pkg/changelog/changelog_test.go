@@ -0,0 +1,252 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package changelog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Changelog", func() {
+	var (
+		ctx    context.Context
+		record Record
+	)
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		record = Record{
+			Time:            time.Date(2024, 10, 14, 11, 3, 27, 0, time.UTC),
+			Node:            "node-1",
+			Device:          "node-1-cx6-sn1",
+			SerialNumber:    "sn1",
+			PartNumber:      "pn1",
+			FirmwareVersion: "22.42.1000",
+			Changes:         []Change{{Parameter: "NUM_OF_VFS", PreviousValues: []string{"0"}, Value: "8"}},
+		}
+	})
+
+	Describe("NewSink", func() {
+		It("should return nil if the changelog is disabled", func() {
+			sink, err := NewSink(Config{}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sink).To(BeNil())
+		})
+
+		It("should return error for an unsupported sink", func() {
+			_, err := NewSink(Config{Sink: "kafka"}, nil)
+			Expect(err).To(MatchError(ContainSubstring("unsupported changelog sink")))
+		})
+
+		It("should return error if the s3 sink is not configured", func() {
+			_, err := NewSink(Config{Sink: SinkS3}, nil)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should name the ConfigMap after the node", func() {
+			sink, err := NewSink(Config{Sink: SinkConfigMap, NodeName: "node-1", Namespace: "ns", ConfigMapName: "changelog"}, fake.NewSimpleClientset())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sink.(*configMapSink).name).To(Equal("changelog-node-1"))
+		})
+	})
+
+	Describe("logSink", func() {
+		It("should write each record as a JSON line", func() {
+			buffer := &bytes.Buffer{}
+			sink := NewLogSink(buffer)
+
+			Expect(sink.Write(ctx, record)).To(Succeed())
+			Expect(sink.Write(ctx, record)).To(Succeed())
+
+			lines := strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n")
+			Expect(lines).To(HaveLen(2))
+
+			recorded := Record{}
+			Expect(json.Unmarshal([]byte(lines[0]), &recorded)).To(Succeed())
+			Expect(recorded).To(Equal(record))
+		})
+	})
+
+	Describe("configMapSink", func() {
+		It("should create the ConfigMap and append records to it", func() {
+			client := fake.NewSimpleClientset()
+			sink := NewConfigMapSink(client, "ns", "changelog-node-1")
+
+			Expect(sink.Write(ctx, record)).To(Succeed())
+			record.Time = record.Time.Add(time.Minute)
+			Expect(sink.Write(ctx, record)).To(Succeed())
+
+			configMap, err := client.CoreV1().ConfigMaps("ns").Get(ctx, "changelog-node-1", metav1.GetOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(configMap.Data).To(HaveLen(2))
+
+			recorded := Record{}
+			Expect(json.Unmarshal([]byte(configMap.Data["20241014T110427.000000000Z.node-1-cx6-sn1"]), &recorded)).To(Succeed())
+			Expect(recorded).To(Equal(record))
+		})
+
+		It("should drop the oldest records", func() {
+			defaultMaxRecords := configMapMaxRecords
+			configMapMaxRecords = 2
+			DeferCleanup(func() { configMapMaxRecords = defaultMaxRecords })
+
+			client := fake.NewSimpleClientset()
+			sink := NewConfigMapSink(client, "ns", "changelog-node-1")
+
+			for i := 0; i < 3; i++ {
+				record.Time = record.Time.Add(time.Minute)
+				Expect(sink.Write(ctx, record)).To(Succeed())
+			}
+
+			configMap, err := client.CoreV1().ConfigMaps("ns").Get(ctx, "changelog-node-1", metav1.GetOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(configMap.Data).To(HaveLen(2))
+			Expect(configMap.Data).NotTo(HaveKey("20241014T110427.000000000Z.node-1-cx6-sn1"))
+			Expect(configMap.Data).To(HaveKey("20241014T110627.000000000Z.node-1-cx6-sn1"))
+		})
+	})
+
+	Describe("s3Sink", func() {
+		var (
+			server   *httptest.Server
+			lock     sync.Mutex
+			requests []*http.Request
+			bodies   [][]byte
+			statuses []int
+			config   S3Config
+		)
+
+		uploaded := func() [][]byte {
+			lock.Lock()
+			defer lock.Unlock()
+			return slices.Clone(bodies)
+		}
+
+		BeforeEach(func() {
+			requests = nil
+			bodies = nil
+			statuses = nil
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				lock.Lock()
+				defer lock.Unlock()
+				if len(statuses) != 0 {
+					status := statuses[0]
+					statuses = statuses[1:]
+					w.WriteHeader(status)
+					return
+				}
+				requests = append(requests, r)
+				bodies = append(bodies, body)
+			}))
+			DeferCleanup(server.Close)
+
+			retryInterval := s3RetryInterval
+			s3RetryInterval = time.Millisecond
+			DeferCleanup(func() { s3RetryInterval = retryInterval })
+
+			config = S3Config{
+				Endpoint:        server.URL,
+				Bucket:          "changelog",
+				Prefix:          "clusters/cluster-1",
+				AccessKeyID:     "AKIDEXAMPLE",
+				SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+			}
+		})
+
+		It("should upload the record as a signed object in the background", func() {
+			sink, err := NewS3Sink(config)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(sink.Write(ctx, record)).To(Succeed())
+
+			Eventually(uploaded).Should(HaveLen(1))
+			lock.Lock()
+			defer lock.Unlock()
+			Expect(requests[0].Method).To(Equal(http.MethodPut))
+			Expect(requests[0].URL.Path).To(Equal("/changelog/clusters/cluster-1/node-1/node-1-cx6-sn1/20241014T110327.000000000Z.json"))
+			Expect(requests[0].Header.Get("Content-Type")).To(Equal("application/json"))
+			Expect(requests[0].Header.Get("Authorization")).To(HavePrefix(
+				fmt.Sprintf("AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/%s/us-east-1/s3/aws4_request,", time.Now().UTC().Format("20060102"))))
+
+			recorded := Record{}
+			Expect(json.Unmarshal(bodies[0], &recorded)).To(Succeed())
+			Expect(recorded).To(Equal(record))
+		})
+
+		It("should retry the rejected uploads in order", func() {
+			statuses = []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable}
+			sink, err := NewS3Sink(config)
+			Expect(err).NotTo(HaveOccurred())
+
+			second := record
+			second.Time = record.Time.Add(time.Minute)
+			Expect(sink.Write(ctx, record)).To(Succeed())
+			Expect(sink.Write(ctx, second)).To(Succeed())
+
+			Eventually(uploaded).Should(HaveLen(2))
+			recorded := Record{}
+			Expect(json.Unmarshal(uploaded()[0], &recorded)).To(Succeed())
+			Expect(recorded.Time).To(Equal(record.Time))
+		})
+
+		It("should drop the record after the last attempt", func() {
+			for range s3UploadAttempts {
+				statuses = append(statuses, http.StatusForbidden)
+			}
+			sink, err := NewS3Sink(config)
+			Expect(err).NotTo(HaveOccurred())
+
+			second := record
+			second.Time = record.Time.Add(time.Minute)
+			Expect(sink.Write(ctx, record)).To(Succeed())
+			Expect(sink.Write(ctx, second)).To(Succeed())
+
+			Eventually(uploaded).Should(HaveLen(1))
+			recorded := Record{}
+			Expect(json.Unmarshal(uploaded()[0], &recorded)).To(Succeed())
+			Expect(recorded.Time).To(Equal(second.Time))
+		})
+
+		It("should return error if the upload is rejected", func() {
+			statuses = []int{http.StatusForbidden}
+			sink, err := NewS3Sink(config)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(sink.(*s3Sink).upload(ctx, record)).To(MatchError(ContainSubstring("StatusCode: 403")))
+		})
+
+		It("should reject an invalid endpoint", func() {
+			config.Endpoint = "minio.storage:9000"
+			_, err := NewS3Sink(config)
+			Expect(err).To(MatchError(ContainSubstring("invalid s3 endpoint")))
+		})
+	})
+})
@@ -0,0 +1,100 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package changelog
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// configMapMaxRecords limits the number of records kept in the ConfigMap, the oldest records are dropped
+// ConfigMaps are limited to 1MiB, a single record is usually well below 4KiB
+var configMapMaxRecords = 100
+
+// recordKeyTimeFormat is used in the ConfigMap keys, keys are sorted chronologically
+const recordKeyTimeFormat = "20060102T150405.000000000Z"
+
+type configMapSink struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+}
+
+// NewConfigMapSink creates a sink storing records in the given ConfigMap, each record in a separate key
+func NewConfigMapSink(client kubernetes.Interface, namespace string, name string) Sink {
+	return &configMapSink{client: client, namespace: namespace, name: name}
+}
+
+// Write adds the record to the ConfigMap, creating it if needed
+func (s *configMapSink) Write(ctx context.Context, record Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	key := record.Time.UTC().Format(recordKeyTimeFormat) + "." + record.Device
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configMap, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(ctx, s.name, metav1.GetOptions{})
+		if apiErrors.IsNotFound(err) {
+			configMap = &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: s.name, Namespace: s.namespace},
+				Data:       map[string]string{key: string(data)},
+			}
+			_, err = s.client.CoreV1().ConfigMaps(s.namespace).Create(ctx, configMap, metav1.CreateOptions{})
+			if apiErrors.IsAlreadyExists(err) {
+				// Retry with an update
+				return apiErrors.NewConflict(v1.Resource("configmaps"), s.name, err)
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		if configMap.Data == nil {
+			configMap.Data = map[string]string{}
+		}
+		configMap.Data[key] = string(data)
+		trimRecords(configMap.Data, configMapMaxRecords)
+
+		_, err = s.client.CoreV1().ConfigMaps(s.namespace).Update(ctx, configMap, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// trimRecords drops the oldest records to keep at most maxRecords
+func trimRecords(data map[string]string, maxRecords int) {
+	if len(data) <= maxRecords {
+		return
+	}
+
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys[:len(keys)-maxRecords] {
+		delete(data, key)
+	}
+}
@@ -0,0 +1,166 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package changelog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	s3DefaultRegion  = "us-east-1"
+	s3RequestTimeout = 30 * time.Second
+	// s3UploadAttempts is the number of the upload attempts of a record before it is dropped
+	s3UploadAttempts = 5
+)
+
+var (
+	// s3QueueSize is the number of the records waiting for the upload, new records are dropped while the queue is full
+	s3QueueSize = 256
+	// s3RetryInterval is the interval before the second upload attempt of a record, doubled after each failed attempt
+	s3RetryInterval = time.Second
+	// s3MaxRetryInterval limits the interval between the upload attempts of a record
+	s3MaxRetryInterval = time.Minute
+)
+
+// S3Config describes an S3-compatible endpoint for the changelog records
+type S3Config struct {
+	// Endpoint URL, e.g. https://s3.us-east-1.amazonaws.com or http://minio.storage:9000
+	Endpoint string
+	// Bucket for the changelog objects, path-style addressing is used
+	Bucket string
+	// Region used for request signing, defaults to us-east-1
+	Region string
+	// Prefix of the object keys
+	Prefix string
+	// Credentials used for request signing
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+type s3Sink struct {
+	config S3Config
+	client *s3.Client
+	// queue holds the records waiting for the upload
+	queue chan Record
+}
+
+// NewS3Sink creates a sink uploading each record as a separate object to an S3-compatible endpoint in the background
+// object keys have the <prefix>/<node>/<device>/<time>.json format
+func NewS3Sink(config S3Config) (Sink, error) {
+	if config.Endpoint == "" || config.Bucket == "" {
+		return nil, errors.New("endpoint and bucket are required for the s3 changelog sink")
+	}
+	if config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, errors.New("credentials are required for the s3 changelog sink")
+	}
+	if config.Region == "" {
+		config.Region = s3DefaultRegion
+	}
+
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", config.Endpoint)
+	}
+
+	client := s3.New(s3.Options{
+		BaseEndpoint: aws.String(config.Endpoint),
+		Region:       config.Region,
+		Credentials:  credentials.NewStaticCredentialsProvider(config.AccessKeyID, config.SecretAccessKey, ""),
+		UsePathStyle: true,
+		HTTPClient:   &http.Client{Timeout: s3RequestTimeout},
+		// Failed uploads are retried by the sink with a longer backoff than the client's
+		Retryer: aws.NopRetryer{},
+	})
+
+	sink := &s3Sink{config: config, client: client, queue: make(chan Record, s3QueueSize)}
+	go sink.run()
+	return sink, nil
+}
+
+func (s *s3Sink) objectKey(record Record) string {
+	return path.Join(s.config.Prefix, record.Node, record.Device, record.Time.UTC().Format(recordKeyTimeFormat)+".json")
+}
+
+// Write queues the record for the upload, so that an unreachable endpoint doesn't delay the configuration
+// returns error if the queue is full, failures to upload the record are logged
+func (s *s3Sink) Write(_ context.Context, record Record) error {
+	select {
+	case s.queue <- record:
+		return nil
+	default:
+		return errors.New("s3 changelog upload queue is full")
+	}
+}
+
+// run uploads the queued records for the lifetime of the config daemon
+func (s *s3Sink) run() {
+	for record := range s.queue {
+		s.uploadWithRetries(record)
+	}
+}
+
+// uploadWithRetries uploads the record, retrying with a growing interval, the record is dropped after s3UploadAttempts failed attempts
+func (s *s3Sink) uploadWithRetries(record Record) {
+	interval := s3RetryInterval
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), s3RequestTimeout)
+		err := s.upload(ctx, record)
+		cancel()
+		if err == nil {
+			return
+		}
+		if attempt == s3UploadAttempts {
+			log.Log.Error(err, "failed to upload nv config changelog entry, dropping it", "device", record.Device, "attempts", attempt)
+			return
+		}
+
+		log.Log.V(2).Info("failed to upload nv config changelog entry, retrying", "device", record.Device, "reason", err.Error())
+		time.Sleep(interval)
+		interval = min(2*interval, s3MaxRetryInterval)
+	}
+}
+
+// upload stores the record with a PutObject request
+func (s *s3Sink) upload(ctx context.Context, record Record) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.config.Bucket),
+		Key:         aws.String(s.objectKey(record)),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload changelog record to s3: %w", err)
+	}
+	return nil
+}
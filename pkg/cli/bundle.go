@@ -0,0 +1,343 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sTypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+)
+
+const (
+	// ConfigurationBundleKind is the kind of the portable configuration bundle
+	ConfigurationBundleKind = "NicConfigurationBundle"
+	// ConfigurationBundleVersion is the version of the bundle format, bundles of other versions are rejected on import
+	ConfigurationBundleVersion = 1
+)
+
+// ConfigurationBundle is a portable snapshot of the NIC configuration of a cluster,
+// e.g. to promote the configuration validated in a staging cluster to production
+type ConfigurationBundle struct {
+	metav1.TypeMeta `json:",inline"`
+	// Version of the bundle format
+	Version int `json:"version"`
+	// Time the bundle was exported at
+	ExportedAt metav1.Time `json:"exportedAt"`
+	// Templates sorted by name
+	Templates []BundleTemplate `json:"templates"`
+	// Labels of the devices selected by the deviceLabels of the templates, sorted by serial number
+	DeviceLabels []BundleDeviceLabels `json:"deviceLabels,omitempty"`
+}
+
+// BundleTemplate is a NicConfigurationTemplate in the bundle, without the namespace and the status
+type BundleTemplate struct {
+	// Name of the NicConfigurationTemplate
+	Name string `json:"name"`
+	// Spec of the NicConfigurationTemplate
+	Spec v1alpha1.NicConfigurationTemplateSpec `json:"spec"`
+}
+
+// BundleDeviceLabels are the labels of a device referenced by the templates, the device is identified by its serial number
+type BundleDeviceLabels struct {
+	// Serial number of the device
+	SerialNumber string `json:"serialNumber"`
+	// Labels of the device used by the deviceLabels selectors of the templates
+	Labels map[string]string `json:"labels"`
+}
+
+// BundleValidation is the result of the validation of a bundle against the devices of the target cluster
+type BundleValidation struct {
+	// Errors prevent the bundle from being imported
+	Errors []string
+	// Warnings are reported, but don't prevent the import
+	Warnings []string
+}
+
+// BuildBundle builds the bundle of the given templates and the labels of the devices their deviceLabels selectors refer to
+func BuildBundle(templates []v1alpha1.NicConfigurationTemplate, devices []v1alpha1.NicDevice, now time.Time) ConfigurationBundle {
+	bundle := ConfigurationBundle{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: v1alpha1.GroupVersion.String(),
+			Kind:       ConfigurationBundleKind,
+		},
+		Version:    ConfigurationBundleVersion,
+		ExportedAt: metav1.NewTime(now.UTC().Truncate(time.Second)),
+		Templates:  make([]BundleTemplate, 0, len(templates)),
+	}
+
+	labelKeys := map[string]bool{}
+	for _, template := range templates {
+		bundle.Templates = append(bundle.Templates, BundleTemplate{Name: template.Name, Spec: *template.Spec.DeepCopy()})
+		if template.Spec.NicSelector != nil {
+			for key := range template.Spec.NicSelector.DeviceLabels {
+				labelKeys[key] = true
+			}
+		}
+	}
+	sort.Slice(bundle.Templates, func(i, j int) bool { return bundle.Templates[i].Name < bundle.Templates[j].Name })
+
+	for _, device := range devices {
+		labels := map[string]string{}
+		for key, value := range device.Labels {
+			if labelKeys[key] {
+				labels[key] = value
+			}
+		}
+		if len(labels) != 0 && device.Status.SerialNumber != "" {
+			bundle.DeviceLabels = append(bundle.DeviceLabels, BundleDeviceLabels{SerialNumber: device.Status.SerialNumber, Labels: labels})
+		}
+	}
+	sort.Slice(bundle.DeviceLabels, func(i, j int) bool {
+		return bundle.DeviceLabels[i].SerialNumber < bundle.DeviceLabels[j].SerialNumber
+	})
+
+	return bundle
+}
+
+// ReadBundle reads the bundle in the YAML or JSON format, bundles of other kinds or versions are rejected
+func ReadBundle(r io.Reader) (ConfigurationBundle, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return ConfigurationBundle{}, err
+	}
+
+	bundle := ConfigurationBundle{}
+	err = yaml.UnmarshalStrict(data, &bundle)
+	if err != nil {
+		return ConfigurationBundle{}, fmt.Errorf("invalid bundle: %w", err)
+	}
+	if bundle.Kind != ConfigurationBundleKind {
+		return ConfigurationBundle{}, fmt.Errorf("unexpected kind %q, expected %s", bundle.Kind, ConfigurationBundleKind)
+	}
+	if bundle.Version != ConfigurationBundleVersion {
+		return ConfigurationBundle{}, fmt.Errorf("unsupported bundle version %d, expected %d", bundle.Version, ConfigurationBundleVersion)
+	}
+	return bundle, nil
+}
+
+// ValidateBundle checks that the bundle is compatible with the devices of the target cluster:
+// each template has to match at least one device of the cluster once the bundle's device labels are applied,
+// no device may match several templates, including the cluster's templates missing in the bundle,
+// and the NicFirmwareSources referenced by the templates have to exist.
+// Node selectors are not evaluated, the devices are matched by their types, PCI addresses, serial numbers and labels
+func ValidateBundle(bundle ConfigurationBundle, devices []v1alpha1.NicDevice, templates []v1alpha1.NicConfigurationTemplate,
+	firmwareSources []v1alpha1.NicFirmwareSource) BundleValidation {
+	validation := BundleValidation{}
+
+	devices = labeledDevices(bundle, devices, &validation)
+
+	sources := map[string]bool{}
+	for _, source := range firmwareSources {
+		sources[source.Name] = true
+	}
+
+	// Templates of the cluster missing in the bundle are left in place and select the devices as well
+	specs := map[string]*v1alpha1.NicConfigurationTemplateSpec{}
+	for i := range bundle.Templates {
+		specs[bundle.Templates[i].Name] = &bundle.Templates[i].Spec
+	}
+	for i := range templates {
+		if _, found := specs[templates[i].Name]; !found {
+			specs[templates[i].Name] = &templates[i].Spec
+			validation.Warnings = append(validation.Warnings,
+				fmt.Sprintf("template %s exists in the cluster but not in the bundle, it is left unchanged", templates[i].Name))
+		}
+	}
+
+	for _, template := range bundle.Templates {
+		if template.Spec.NicSelector == nil || template.Spec.Template == nil {
+			validation.Errors = append(validation.Errors, fmt.Sprintf("template %s requires nicSelector and template", template.Name))
+			continue
+		}
+
+		if firmware := template.Spec.Template.Firmware; firmware != nil && firmware.NicFirmwareSourceRef != "" && !sources[firmware.NicFirmwareSourceRef] {
+			validation.Errors = append(validation.Errors,
+				fmt.Sprintf("template %s references NicFirmwareSource %s missing in the cluster", template.Name, firmware.NicFirmwareSourceRef))
+		}
+
+		if !slices.ContainsFunc(devices, func(device v1alpha1.NicDevice) bool { return templateSelectsDevice(&template.Spec, &device) }) {
+			validation.Errors = append(validation.Errors, fmt.Sprintf("template %s doesn't match any device in the cluster", template.Name))
+		}
+	}
+
+	names := make([]string, 0, len(specs))
+	for name := range specs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i := range devices {
+		matching := []string{}
+		for _, name := range names {
+			if templateSelectsDevice(specs[name], &devices[i]) {
+				matching = append(matching, name)
+			}
+		}
+		if len(matching) > 1 {
+			validation.Errors = append(validation.Errors,
+				fmt.Sprintf("device %s matches several templates: %s", devices[i].Name, strings.Join(matching, ", ")))
+		}
+	}
+
+	sort.Strings(validation.Warnings)
+	return validation
+}
+
+// labeledDevices returns copies of the devices with the bundle's device labels applied, sorted by name
+// labels of the serial numbers missing in the cluster are reported as warnings
+func labeledDevices(bundle ConfigurationBundle, devices []v1alpha1.NicDevice, validation *BundleValidation) []v1alpha1.NicDevice {
+	labeled := make([]v1alpha1.NicDevice, 0, len(devices))
+	for i := range devices {
+		labeled = append(labeled, *devices[i].DeepCopy())
+	}
+	sort.Slice(labeled, func(i, j int) bool { return labeled[i].Name < labeled[j].Name })
+
+	for _, entry := range bundle.DeviceLabels {
+		index := slices.IndexFunc(labeled, func(device v1alpha1.NicDevice) bool { return device.Status.SerialNumber == entry.SerialNumber })
+		if index == -1 {
+			validation.Warnings = append(validation.Warnings,
+				fmt.Sprintf("device with serial number %s not found in the cluster, its labels are not applied", entry.SerialNumber))
+			continue
+		}
+		if labeled[index].Labels == nil {
+			labeled[index].Labels = map[string]string{}
+		}
+		maps.Copy(labeled[index].Labels, entry.Labels)
+	}
+
+	return labeled
+}
+
+// templateSelectsDevice returns true if the device matches the type, PCI addresses, serial numbers and labels of the template's nicSelector
+func templateSelectsDevice(spec *v1alpha1.NicConfigurationTemplateSpec, device *v1alpha1.NicDevice) bool {
+	selector := spec.NicSelector
+	if selector == nil || selector.NicType != device.Status.Type {
+		return false
+	}
+
+	if len(selector.PciAddresses) != 0 && !slices.ContainsFunc(device.Status.Ports, func(port v1alpha1.NicDevicePortSpec) bool {
+		return slices.Contains(selector.PciAddresses, port.PCI)
+	}) {
+		return false
+	}
+
+	if len(selector.SerialNumbers) != 0 && !slices.Contains(selector.SerialNumbers, device.Status.SerialNumber) {
+		return false
+	}
+
+	for key, value := range selector.DeviceLabels {
+		if device.Labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// ImportBundle creates or updates the bundle's templates in the namespace and applies the bundle's device labels,
+// each change is reported in the kubectl apply format, nothing is changed if dryRun is set
+func ImportBundle(ctx context.Context, c client.Client, namespace string, bundle ConfigurationBundle, dryRun bool, w io.Writer) error {
+	suffix := ""
+	if dryRun {
+		suffix = " (dry run)"
+	}
+
+	for _, bundleTemplate := range bundle.Templates {
+		template := &v1alpha1.NicConfigurationTemplate{}
+		err := c.Get(ctx, k8sTypes.NamespacedName{Name: bundleTemplate.Name, Namespace: namespace}, template)
+		switch {
+		case apierrors.IsNotFound(err):
+			template = &v1alpha1.NicConfigurationTemplate{
+				ObjectMeta: metav1.ObjectMeta{Name: bundleTemplate.Name, Namespace: namespace},
+				Spec:       bundleTemplate.Spec,
+			}
+			if !dryRun {
+				err = c.Create(ctx, template)
+				if err != nil {
+					return err
+				}
+			}
+			fmt.Fprintf(w, "nicconfigurationtemplate/%s created%s\n", bundleTemplate.Name, suffix)
+		case err != nil:
+			return err
+		case reflect.DeepEqual(template.Spec, bundleTemplate.Spec):
+			fmt.Fprintf(w, "nicconfigurationtemplate/%s unchanged\n", bundleTemplate.Name)
+		default:
+			template.Spec = bundleTemplate.Spec
+			if !dryRun {
+				err = c.Update(ctx, template)
+				if err != nil {
+					return err
+				}
+			}
+			fmt.Fprintf(w, "nicconfigurationtemplate/%s configured%s\n", bundleTemplate.Name, suffix)
+		}
+	}
+
+	if len(bundle.DeviceLabels) == 0 {
+		return nil
+	}
+
+	devices := &v1alpha1.NicDeviceList{}
+	err := c.List(ctx, devices, client.InNamespace(namespace))
+	if err != nil {
+		return err
+	}
+	sort.Slice(devices.Items, func(i, j int) bool { return devices.Items[i].Name < devices.Items[j].Name })
+
+	for i := range devices.Items {
+		device := &devices.Items[i]
+		index := slices.IndexFunc(bundle.DeviceLabels, func(entry BundleDeviceLabels) bool {
+			return entry.SerialNumber == device.Status.SerialNumber
+		})
+		if index == -1 {
+			continue
+		}
+
+		labels := maps.Clone(device.Labels)
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		maps.Copy(labels, bundle.DeviceLabels[index].Labels)
+		if maps.Equal(labels, device.Labels) {
+			continue
+		}
+
+		if !dryRun {
+			device.Labels = labels
+			err = c.Update(ctx, device)
+			if err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(w, "nicdevice/%s labeled%s\n", device.Name, suffix)
+	}
+
+	return nil
+}
@@ -0,0 +1,224 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sTypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+)
+
+var _ = Describe("bundle", func() {
+	const namespace = "nic-configuration-operator"
+
+	var (
+		templates []v1alpha1.NicConfigurationTemplate
+		devices   []v1alpha1.NicDevice
+	)
+
+	newTemplate := func(name string, selector *v1alpha1.NicSelectorSpec) v1alpha1.NicConfigurationTemplate {
+		return v1alpha1.NicConfigurationTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: v1alpha1.NicConfigurationTemplateSpec{
+				NicSelector: selector,
+				Template:    &v1alpha1.ConfigurationTemplateSpec{NumVfs: 8, LinkType: consts.Ethernet},
+			},
+		}
+	}
+
+	newDevice := func(name string, nicType string, serialNumber string, labels map[string]string) v1alpha1.NicDevice {
+		return v1alpha1.NicDevice{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+			Status:     v1alpha1.NicDeviceStatus{Type: nicType, SerialNumber: serialNumber},
+		}
+	}
+
+	BeforeEach(func() {
+		templates = []v1alpha1.NicConfigurationTemplate{
+			newTemplate("storage", &v1alpha1.NicSelectorSpec{NicType: "1021", DeviceLabels: map[string]string{"role": "storage"}}),
+			newTemplate("compute", &v1alpha1.NicSelectorSpec{NicType: "101d"}),
+		}
+		devices = []v1alpha1.NicDevice{
+			newDevice("node-a-cx7-serial1", "1021", "serial1", map[string]string{"role": "storage", "team": "infra"}),
+			newDevice("node-a-cx6-serial2", "101d", "serial2", nil),
+		}
+	})
+
+	Describe("BuildBundle", func() {
+		It("should export the templates sorted by name with the device labels they select by", func() {
+			now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+			bundle := BuildBundle(templates, devices, now)
+
+			Expect(bundle.Kind).To(Equal(ConfigurationBundleKind))
+			Expect(bundle.Version).To(Equal(ConfigurationBundleVersion))
+			Expect(bundle.ExportedAt.Time).To(Equal(now))
+			Expect(bundle.Templates).To(HaveLen(2))
+			Expect(bundle.Templates[0].Name).To(Equal("compute"))
+			Expect(bundle.Templates[1].Spec).To(Equal(templates[0].Spec))
+			Expect(bundle.DeviceLabels).To(Equal([]BundleDeviceLabels{
+				{SerialNumber: "serial1", Labels: map[string]string{"role": "storage"}},
+			}))
+		})
+	})
+
+	Describe("ReadBundle", func() {
+		It("should reject bundles of other versions", func() {
+			_, err := ReadBundle(strings.NewReader("apiVersion: configuration.net.nvidia.com/v1alpha1\nkind: NicConfigurationBundle\nversion: 2\n"))
+			Expect(err).To(MatchError(ContainSubstring("unsupported bundle version 2")))
+		})
+		It("should reject other kinds", func() {
+			_, err := ReadBundle(strings.NewReader("apiVersion: v1\nkind: ConfigMap\nversion: 1\n"))
+			Expect(err).To(MatchError(ContainSubstring("unexpected kind")))
+		})
+	})
+
+	Describe("ValidateBundle", func() {
+		var bundle ConfigurationBundle
+
+		BeforeEach(func() {
+			bundle = BuildBundle(templates, devices, time.Now())
+		})
+
+		It("should accept the bundle matching the devices of the cluster", func() {
+			// Production devices have other serial numbers, the exported labels don't apply to them
+			target := []v1alpha1.NicDevice{
+				newDevice("prod-cx7-serial10", "1021", "serial10", map[string]string{"role": "storage"}),
+				newDevice("prod-cx6-serial11", "101d", "serial11", nil),
+			}
+
+			validation := ValidateBundle(bundle, target, nil, nil)
+			Expect(validation.Errors).To(BeEmpty())
+			Expect(validation.Warnings).To(ConsistOf(ContainSubstring("serial number serial1 not found")))
+		})
+
+		It("should report the templates not matching any device of the cluster", func() {
+			target := []v1alpha1.NicDevice{newDevice("prod-cx6-serial11", "101d", "serial11", nil)}
+
+			validation := ValidateBundle(bundle, target, nil, nil)
+			Expect(validation.Errors).To(ConsistOf("template storage doesn't match any device in the cluster"))
+		})
+
+		It("should report the devices matching a template of the bundle and a template of the cluster", func() {
+			existing := []v1alpha1.NicConfigurationTemplate{newTemplate("legacy", &v1alpha1.NicSelectorSpec{NicType: "101d"})}
+
+			validation := ValidateBundle(bundle, devices, existing, nil)
+			Expect(validation.Errors).To(ConsistOf("device node-a-cx6-serial2 matches several templates: compute, legacy"))
+			Expect(validation.Warnings).To(ConsistOf(ContainSubstring("template legacy exists in the cluster but not in the bundle")))
+		})
+
+		It("should report the missing firmware sources", func() {
+			bundle.Templates[0].Spec.Template.Firmware = &v1alpha1.FirmwareTemplateSpec{NicFirmwareSourceRef: "cx6-firmware"}
+
+			validation := ValidateBundle(bundle, devices, nil, nil)
+			Expect(validation.Errors).To(ConsistOf("template compute references NicFirmwareSource cx6-firmware missing in the cluster"))
+
+			sources := []v1alpha1.NicFirmwareSource{{ObjectMeta: metav1.ObjectMeta{Name: "cx6-firmware", Namespace: namespace}}}
+			Expect(ValidateBundle(bundle, devices, nil, sources).Errors).To(BeEmpty())
+		})
+	})
+
+	Describe("Run", func() {
+		var (
+			stdout *bytes.Buffer
+			opts   *globalOptions
+		)
+
+		newOptions := func(objects ...client.Object) *globalOptions {
+			scheme := runtime.NewScheme()
+			Expect(v1alpha1.AddToScheme(scheme)).To(Succeed())
+
+			return &globalOptions{
+				stdout: stdout,
+				client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build(),
+			}
+		}
+
+		BeforeEach(func() {
+			stdout = &bytes.Buffer{}
+			opts = newOptions(&templates[0], &templates[1], &devices[0], &devices[1])
+		})
+
+		It("should import the exported bundle into another cluster", func() {
+			Expect(runExport(context.Background(), opts, []string{"-n", namespace})).To(Succeed())
+			bundlePath := filepath.Join(GinkgoT().TempDir(), "bundle.yaml")
+			Expect(os.WriteFile(bundlePath, stdout.Bytes(), 0600)).To(Succeed())
+
+			stdout = &bytes.Buffer{}
+			changed := newTemplate("compute", &v1alpha1.NicSelectorSpec{NicType: "101d"})
+			changed.Spec.Template.NumVfs = 16
+			target := newDevice("node-a-cx7-serial1", "1021", "serial1", nil)
+			opts = newOptions(&changed, &target, &devices[1])
+
+			Expect(runImport(context.Background(), opts, []string{"-f", bundlePath, "-n", namespace})).To(Succeed())
+			Expect(stdout.String()).To(Equal("nicconfigurationtemplate/compute configured\n" +
+				"nicconfigurationtemplate/storage created\n" +
+				"nicdevice/node-a-cx7-serial1 labeled\n"))
+
+			template := &v1alpha1.NicConfigurationTemplate{}
+			Expect(opts.client.Get(context.Background(), k8sTypes.NamespacedName{Name: "compute", Namespace: namespace}, template)).To(Succeed())
+			Expect(template.Spec.Template.NumVfs).To(Equal(8))
+
+			device := &v1alpha1.NicDevice{}
+			Expect(opts.client.Get(context.Background(), k8sTypes.NamespacedName{Name: "node-a-cx7-serial1", Namespace: namespace}, device)).To(Succeed())
+			Expect(device.Labels).To(Equal(map[string]string{"role": "storage"}))
+		})
+
+		It("should not change the cluster in the dry run mode", func() {
+			bundle := BuildBundle(templates, devices, time.Now())
+			bundle.Templates[0].Spec.Template.NumVfs = 16
+			opts = newOptions(&templates[0], &templates[1], &devices[0], &devices[1])
+
+			Expect(ImportBundle(context.Background(), opts.client, namespace, bundle, true, stdout)).To(Succeed())
+			Expect(stdout.String()).To(Equal("nicconfigurationtemplate/compute configured (dry run)\n" +
+				"nicconfigurationtemplate/storage unchanged\n"))
+
+			template := &v1alpha1.NicConfigurationTemplate{}
+			Expect(opts.client.Get(context.Background(), k8sTypes.NamespacedName{Name: "compute", Namespace: namespace}, template)).To(Succeed())
+			Expect(template.Spec.Template.NumVfs).To(Equal(8))
+		})
+
+		It("should not import the bundle incompatible with the cluster", func() {
+			bundle := BuildBundle(templates, devices, time.Now())
+			bundle.Templates[0].Spec.NicSelector.NicType = "a2dc"
+			opts = newOptions(&devices[0], &devices[1])
+			data, err := yaml.Marshal(bundle)
+			Expect(err).NotTo(HaveOccurred())
+			opts.stdin = bytes.NewReader(data)
+
+			err = runImport(context.Background(), opts, []string{"-f", "-", "-n", namespace})
+			Expect(err).To(MatchError(ContainSubstring("template compute doesn't match any device in the cluster")))
+
+			list := &v1alpha1.NicConfigurationTemplateList{}
+			Expect(opts.client.List(context.Background(), list)).To(Succeed())
+			Expect(list.Items).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,651 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cli implements the kubectl nic-config plugin
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sTypes "k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/host"
+)
+
+const (
+	outputText = "text"
+	outputJSON = "json"
+	outputYAML = "yaml"
+)
+
+const usage = `kubectl nic-config is a helper for inspecting NIC configuration operator resources
+
+Usage:
+  kubectl nic-config <command> [flags]
+
+Commands:
+  explain <device>   Show spec, rendered nv config parameters, firmware values and conditions of a NicDevice
+  convert            Convert mlxconfig set commands or a mlxconfig query dump into a NicConfigurationTemplate
+  manifest           Export a signed JSON manifest of the NICs, their firmware and applied templates
+  verify-manifest    Verify the signature of a hardware manifest and print its devices
+  export             Export the templates and the device labels they select by into a portable bundle
+  import             Validate a bundle against the devices of the cluster and apply it
+  capture            Capture nv config snapshots of the node's devices for dry-run, runs on the node
+  dry-run            Validate a template against nv config snapshots of the devices and report the changes and reboots
+  manifests          Render the CRDs, RBAC and workloads of the operator deployment without Helm
+`
+
+// command is a single subcommand of the CLI
+type command func(ctx context.Context, opts *globalOptions, args []string) error
+
+// globalOptions contains flags and clients shared between subcommands
+type globalOptions struct {
+	kubeconfig string
+	namespace  string
+	stdin      io.Reader
+	stdout     io.Writer
+
+	client client.Client
+	// hostUtils queries the devices of the node the CLI runs on
+	hostUtils host.HostUtils
+}
+
+var commands = map[string]command{
+	"explain":         runExplain,
+	"convert":         runConvert,
+	"manifest":        runManifest,
+	"verify-manifest": runVerifyManifest,
+	"export":          runExport,
+	"import":          runImport,
+	"capture":         runCapture,
+	"dry-run":         runDryRun,
+	"manifests":       runManifests,
+}
+
+// Run parses the arguments and executes the requested subcommand
+func Run(ctx context.Context, args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprint(stdout, usage)
+		return errors.New("command is required")
+	}
+
+	cmd, found := commands[args[0]]
+	if !found {
+		fmt.Fprint(stdout, usage)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+
+	return cmd(ctx, &globalOptions{stdin: os.Stdin, stdout: stdout}, args[1:])
+}
+
+// bindGlobalFlags registers flags shared between subcommands
+func (o *globalOptions) bindGlobalFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.kubeconfig, "kubeconfig", "", "Path to the kubeconfig file")
+	fs.StringVar(&o.namespace, "n", "", "Namespace of the operator resources, defaults to the namespace of the current context")
+}
+
+// initClient creates a k8s client according to the kubeconfig rules used by kubectl
+func (o *globalOptions) initClient() error {
+	if o.client != nil {
+		return nil
+	}
+
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = o.kubeconfig
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{})
+
+	if o.namespace == "" {
+		namespace, _, err := clientConfig.Namespace()
+		if err != nil {
+			return err
+		}
+		o.namespace = namespace
+	}
+
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return err
+	}
+
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v1alpha1.AddToScheme(scheme))
+
+	o.client, err = client.New(restConfig, client.Options{Scheme: scheme})
+	return err
+}
+
+func runExplain(ctx context.Context, opts *globalOptions, args []string) error {
+	fs := flag.NewFlagSet("explain", flag.ContinueOnError)
+	fs.SetOutput(opts.stdout)
+	opts.bindGlobalFlags(fs)
+	output := fs.String("o", outputText, "Output format: text or json")
+
+	// Allow flags both before and after the device name
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+
+	if len(positional) != 1 {
+		return errors.New("usage: kubectl nic-config explain <device> [-n namespace] [-o text|json]")
+	}
+	if *output != outputText && *output != outputJSON {
+		return fmt.Errorf("unsupported output format %q", *output)
+	}
+
+	if err := opts.initClient(); err != nil {
+		return err
+	}
+
+	device := &v1alpha1.NicDevice{}
+	err := opts.client.Get(ctx, k8sTypes.NamespacedName{Name: positional[0], Namespace: opts.namespace}, device)
+	if err != nil {
+		return err
+	}
+
+	explanation := ExplainDevice(device)
+	if *output == outputJSON {
+		return explanation.WriteJSON(opts.stdout)
+	}
+	return explanation.WriteText(opts.stdout)
+}
+
+// templateManifest is a NicConfigurationTemplate manifest without the status and server-populated metadata
+type templateManifest struct {
+	metav1.TypeMeta `json:",inline"`
+	Metadata        templateMetadata                      `json:"metadata"`
+	Spec            v1alpha1.NicConfigurationTemplateSpec `json:"spec"`
+}
+
+type templateMetadata struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+func runConvert(_ context.Context, opts *globalOptions, args []string) error {
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	fs.SetOutput(opts.stdout)
+	file := fs.String("f", "-", "File with mlxconfig set commands or a mlxconfig query dump, - for stdin")
+	name := fs.String("name", "converted-template", "Name of the NicConfigurationTemplate")
+	fs.StringVar(&opts.namespace, "n", "", "Namespace of the NicConfigurationTemplate")
+	nicType := fs.String("nic-type", "", "Type of the NICs selected by the template, e.g. 101d")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 || *nicType == "" {
+		return errors.New("usage: kubectl nic-config convert --nic-type <type> [-f file] [--name name] [-n namespace]")
+	}
+
+	input := opts.stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		input = f
+	}
+
+	conversion, err := ConvertMlxconfig(input)
+	if err != nil {
+		return err
+	}
+
+	manifest := templateManifest{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: v1alpha1.GroupVersion.String(),
+			Kind:       "NicConfigurationTemplate",
+		},
+		Metadata: templateMetadata{Name: *name, Namespace: opts.namespace},
+		Spec: v1alpha1.NicConfigurationTemplateSpec{
+			NicSelector: &v1alpha1.NicSelectorSpec{NicType: *nicType},
+			Template:    conversion.Template,
+		},
+	}
+	data, err := yaml.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+
+	for _, warning := range conversion.Warnings {
+		fmt.Fprintf(opts.stdout, "# WARNING: %s\n", warning)
+	}
+	_, err = opts.stdout.Write(data)
+	return err
+}
+
+func runManifest(ctx context.Context, opts *globalOptions, args []string) error {
+	fs := flag.NewFlagSet("manifest", flag.ContinueOnError)
+	fs.SetOutput(opts.stdout)
+	opts.bindGlobalFlags(fs)
+	signingKey := fs.String("signing-key", "", "Path to the PEM encoded ECDSA or RSA private key to sign the manifest with")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 || *signingKey == "" {
+		return errors.New("usage: kubectl nic-config manifest --signing-key <key.pem> [-n namespace]")
+	}
+
+	privateKey, err := os.ReadFile(*signingKey)
+	if err != nil {
+		return err
+	}
+
+	if err := opts.initClient(); err != nil {
+		return err
+	}
+
+	list := &v1alpha1.NicDeviceList{}
+	err = opts.client.List(ctx, list, client.InNamespace(opts.namespace))
+	if err != nil {
+		return err
+	}
+
+	signed, err := SignManifest(BuildManifest(list.Items, opts.namespace, time.Now()), privateKey)
+	if err != nil {
+		return err
+	}
+	return signed.WriteJSON(opts.stdout)
+}
+
+func runVerifyManifest(_ context.Context, opts *globalOptions, args []string) error {
+	fs := flag.NewFlagSet("verify-manifest", flag.ContinueOnError)
+	fs.SetOutput(opts.stdout)
+	file := fs.String("f", "-", "File with the signed manifest, - for stdin")
+	publicKey := fs.String("public-key", "", "Path to the PEM encoded ECDSA or RSA public key the manifest is signed with")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 || *publicKey == "" {
+		return errors.New("usage: kubectl nic-config verify-manifest --public-key <key.pub> [-f manifest.json]")
+	}
+
+	publicKeyPEM, err := os.ReadFile(*publicKey)
+	if err != nil {
+		return err
+	}
+
+	input := opts.stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		input = f
+	}
+
+	signed, err := ReadSignedManifest(input)
+	if err != nil {
+		return err
+	}
+	manifest, err := VerifyManifest(signed, publicKeyPEM)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(opts.stdout, "Manifest signature verified")
+	return manifest.WriteText(opts.stdout)
+}
+
+func runExport(ctx context.Context, opts *globalOptions, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(opts.stdout)
+	opts.bindGlobalFlags(fs)
+	output := fs.String("o", outputYAML, "Output format: yaml or json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 {
+		return errors.New("usage: kubectl nic-config export [-n namespace] [-o yaml|json]")
+	}
+	if *output != outputYAML && *output != outputJSON {
+		return fmt.Errorf("unsupported output format %q", *output)
+	}
+
+	if err := opts.initClient(); err != nil {
+		return err
+	}
+
+	templates := &v1alpha1.NicConfigurationTemplateList{}
+	err := opts.client.List(ctx, templates, client.InNamespace(opts.namespace))
+	if err != nil {
+		return err
+	}
+	devices := &v1alpha1.NicDeviceList{}
+	err = opts.client.List(ctx, devices, client.InNamespace(opts.namespace))
+	if err != nil {
+		return err
+	}
+
+	bundle := BuildBundle(templates.Items, devices.Items, time.Now())
+	var data []byte
+	if *output == outputJSON {
+		data, err = json.MarshalIndent(bundle, "", "  ")
+		data = append(data, '\n')
+	} else {
+		data, err = yaml.Marshal(bundle)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = opts.stdout.Write(data)
+	return err
+}
+
+func runImport(ctx context.Context, opts *globalOptions, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.SetOutput(opts.stdout)
+	opts.bindGlobalFlags(fs)
+	file := fs.String("f", "", "File with the bundle exported by kubectl nic-config export, - for stdin")
+	dryRun := fs.Bool("dry-run", false, "Validate the bundle and print the changes without applying them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 || *file == "" {
+		return errors.New("usage: kubectl nic-config import -f <bundle> [-n namespace] [--dry-run]")
+	}
+
+	input := opts.stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		input = f
+	}
+
+	bundle, err := ReadBundle(input)
+	if err != nil {
+		return err
+	}
+
+	if err := opts.initClient(); err != nil {
+		return err
+	}
+
+	devices := &v1alpha1.NicDeviceList{}
+	err = opts.client.List(ctx, devices, client.InNamespace(opts.namespace))
+	if err != nil {
+		return err
+	}
+	templates := &v1alpha1.NicConfigurationTemplateList{}
+	err = opts.client.List(ctx, templates, client.InNamespace(opts.namespace))
+	if err != nil {
+		return err
+	}
+	firmwareSources := &v1alpha1.NicFirmwareSourceList{}
+	err = opts.client.List(ctx, firmwareSources, client.InNamespace(opts.namespace))
+	if err != nil {
+		return err
+	}
+
+	validation := ValidateBundle(bundle, devices.Items, templates.Items, firmwareSources.Items)
+	for _, warning := range validation.Warnings {
+		fmt.Fprintf(opts.stdout, "# WARNING: %s\n", warning)
+	}
+	if len(validation.Errors) != 0 {
+		return fmt.Errorf("bundle is not compatible with the cluster:\n  %s", strings.Join(validation.Errors, "\n  "))
+	}
+
+	return ImportBundle(ctx, opts.client, opts.namespace, bundle, *dryRun, opts.stdout)
+}
+
+func runCapture(ctx context.Context, opts *globalOptions, args []string) error {
+	fs := flag.NewFlagSet("capture", flag.ContinueOnError)
+	fs.SetOutput(opts.stdout)
+	opts.bindGlobalFlags(fs)
+	node := fs.String("node", "", "Node the CLI runs on, defaults to the hostname")
+
+	// Allow flags both before and after the device names
+	var names []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		names = append(names, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+
+	if *node == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return err
+		}
+		*node = hostname
+	}
+
+	if err := opts.initClient(); err != nil {
+		return err
+	}
+	if opts.hostUtils == nil {
+		opts.hostUtils = host.NewHostUtils()
+	}
+
+	list := &v1alpha1.NicDeviceList{}
+	err := opts.client.List(ctx, list, client.InNamespace(opts.namespace))
+	if err != nil {
+		return err
+	}
+
+	snapshots := []DeviceSnapshot{}
+	for i := range list.Items {
+		device := &list.Items[i]
+		if device.Status.Node != *node || len(names) != 0 && !slices.Contains(names, device.Name) {
+			continue
+		}
+		if len(device.Status.Ports) == 0 {
+			return fmt.Errorf("device %s has no ports", device.Name)
+		}
+
+		nvConfig, err := opts.hostUtils.QueryNvConfig(ctx, device.Status.Ports[0].PCI)
+		if err != nil {
+			return fmt.Errorf("failed to query nv config of device %s: %w", device.Name, err)
+		}
+		snapshots = append(snapshots, CaptureSnapshot(device, nvConfig, time.Now()))
+	}
+
+	if len(snapshots) == 0 {
+		return fmt.Errorf("no NicDevices of node %s found in namespace %s", *node, opts.namespace)
+	}
+	if len(names) != len(snapshots) && len(names) != 0 {
+		return fmt.Errorf("only %d of the %d devices found on node %s", len(snapshots), len(names), *node)
+	}
+	return WriteSnapshots(opts.stdout, snapshots)
+}
+
+func runDryRun(_ context.Context, opts *globalOptions, args []string) error {
+	fs := flag.NewFlagSet("dry-run", flag.ContinueOnError)
+	fs.SetOutput(opts.stdout)
+	file := fs.String("f", "", "File with the NicConfigurationTemplate manifest")
+	snapshotsFile := fs.String("snapshots", "", "File with the NicDeviceSnapshot documents, - for stdin")
+	output := fs.String("o", outputText, "Output format: text or json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 || *file == "" || *snapshotsFile == "" {
+		return errors.New("usage: kubectl nic-config dry-run -f <template> --snapshots <snapshots> [-o text|json]")
+	}
+	if *output != outputText && *output != outputJSON {
+		return fmt.Errorf("unsupported output format %q", *output)
+	}
+	if *file == "-" && *snapshotsFile == "-" {
+		return errors.New("only one of the template and the snapshots can be read from stdin")
+	}
+
+	templateInput, err := openInput(opts, *file)
+	if err != nil {
+		return err
+	}
+	defer templateInput.Close()
+	template, err := ReadTemplate(templateInput)
+	if err != nil {
+		return err
+	}
+
+	snapshotsInput, err := openInput(opts, *snapshotsFile)
+	if err != nil {
+		return err
+	}
+	defer snapshotsInput.Close()
+	snapshots, err := ReadSnapshots(snapshotsInput)
+	if err != nil {
+		return err
+	}
+
+	report := DryRunTemplate(template, snapshots)
+	if *output == outputJSON {
+		err = report.WriteJSON(opts.stdout)
+	} else {
+		err = report.WriteText(opts.stdout)
+	}
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, snapshot := range report.Snapshots {
+		if snapshot.Result == DryRunSpecError {
+			failed++
+		}
+	}
+	if failed != 0 {
+		return fmt.Errorf("template %s is invalid for %d of %d snapshots", template.Name, failed, len(report.Snapshots))
+	}
+	return nil
+}
+
+func runManifests(_ context.Context, opts *globalOptions, args []string) error {
+	options := DefaultDeploymentOptions()
+
+	fs := flag.NewFlagSet("manifests", flag.ContinueOnError)
+	fs.SetOutput(opts.stdout)
+	fs.StringVar(&options.Namespace, "n", options.Namespace, "Namespace of the operator deployment")
+	fs.StringVar(&options.Name, "name", options.Name, "Name of the operator Deployment, ServiceAccount and RBAC resources")
+	fs.StringVar(&options.OperatorImage, "operator-image", options.OperatorImage, "Image of the operator")
+	fs.StringVar(&options.ConfigDaemonImage, "config-daemon-image", options.ConfigDaemonImage, "Image of the config daemon")
+	fs.Var(listFlag{&options.ImagePullSecrets}, "image-pull-secrets", "Comma-separated image pull secrets of the operator and the config daemon")
+	fs.StringVar(&options.LogLevel, "log-level", options.LogLevel, "Log level of the operator and the config daemon: debug or info")
+	fs.BoolVar(&options.WaitForNodeReady, "wait-for-node-ready", options.WaitForNodeReady, "Hold NIC configuration until the node reaches Ready for the first time")
+	fs.BoolVar(&options.BatchDiscovery, "batch-discovery", options.BatchDiscovery, "Publish the discovered devices in a single NicNodeReport per node")
+	fs.BoolVar(&options.DiscoverVfs, "discover-vfs", options.DiscoverVfs, "List the SR-IOV VFs of each device in the NicDevice status")
+	fs.BoolVar(&options.StrictConvergence, "strict-convergence", options.StrictConvergence, "Taint the nodes until all of their devices are configured")
+	fs.BoolVar(&options.Privileged, "privileged", options.Privileged, "Run the config daemon in the privileged mode")
+	fs.BoolVar(&options.HostUsers, "host-users", options.HostUsers, "Run the config daemon in the host's user namespace, otherwise in its own one")
+	fs.StringVar(&options.RestartSyncWindow, "restart-sync-window", options.RestartSyncWindow, "Time over which the validation of the converged devices is spread after the config daemon restarts, e.g. 10m")
+	fs.StringVar(&options.DeviceDiscoveryInterval, "device-discovery-interval", options.DeviceDiscoveryInterval, "Interval of the periodic discovery of the devices on the nodes, e.g. 1m, 5m if empty")
+	fs.StringVar(&options.DeepScanInterval, "deep-scan-interval", options.DeepScanInterval, "Interval of the deep scans re-querying the firmware and validating all devices, e.g. 168h")
+	fs.IntVar(&options.TemperatureWarningThreshold, "temperature-warning-threshold", options.TemperatureWarningThreshold, "ASIC temperature in degrees Celsius from which the HealthWarning condition is reported, disabled if 0")
+	fs.Var(listFlag{&options.ProvisioningTaints}, "provisioning-taints", "Comma-separated node taint keys indicating that the node is being provisioned")
+	fs.Var(listFlag{&options.RdmaResourcePrefixes}, "rdma-resource-prefixes", "Comma-separated resource name prefixes of the RDMA and SR-IOV device plugins")
+	fs.Var(listFlag{&options.IgnorePCIAddresses}, "ignore-pci-addresses", "Comma-separated PCI addresses that are never discovered or configured")
+	fs.StringVar(&options.ChangelogSink, "changelog-sink", options.ChangelogSink, "Sink of the changelog of the applied nv config changes: log, configmap or s3, disabled if empty")
+	fs.StringVar(&options.ChangelogConfigMapName, "changelog-configmap-name", options.ChangelogConfigMapName, "Name prefix of the per-node changelog ConfigMaps, used with the configmap sink")
+	fs.StringVar(&options.ChangelogS3Endpoint, "changelog-s3-endpoint", options.ChangelogS3Endpoint, "S3-compatible endpoint URL, used with the s3 sink")
+	fs.StringVar(&options.ChangelogS3Bucket, "changelog-s3-bucket", options.ChangelogS3Bucket, "Bucket of the changelog objects")
+	fs.StringVar(&options.ChangelogS3Region, "changelog-s3-region", options.ChangelogS3Region, "Region used for the request signing")
+	fs.StringVar(&options.ChangelogS3Prefix, "changelog-s3-prefix", options.ChangelogS3Prefix, "Prefix of the changelog object keys")
+	fs.StringVar(&options.ChangelogS3CredentialsSecret, "changelog-s3-credentials-secret", options.ChangelogS3CredentialsSecret, "Name of the secret with the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY keys")
+	fs.StringVar(&options.LifecycleEventsSink, "lifecycle-events-sink", options.LifecycleEventsSink, "Sink of the CloudEvents of the configuration lifecycle of the devices: http or nats, disabled if empty")
+	fs.StringVar(&options.LifecycleEventsEndpoint, "lifecycle-events-endpoint", options.LifecycleEventsEndpoint, "URL of the HTTP receiver or the NATS server of the lifecycle events")
+	fs.StringVar(&options.LifecycleEventsSubject, "lifecycle-events-subject", options.LifecycleEventsSubject, "NATS subject of the lifecycle events, used with the nats sink")
+	fs.StringVar(&options.LifecycleEventsCredentialsSecret, "lifecycle-events-credentials-secret", options.LifecycleEventsCredentialsSecret,
+		"Name of the secret with the NATS credentials and TLS files of the lifecycle events, used with the nats sink")
+	fs.StringVar(&options.FirmwareCacheHostPath, "firmware-cache-host-path", options.FirmwareCacheHostPath, "Host directory of the firmware cache")
+	fs.StringVar(&options.FirmwareCacheMaxSize, "firmware-cache-max-size", options.FirmwareCacheMaxSize, "Size limit of the firmware cache, e.g. 10Gi, unlimited if empty")
+	fs.IntVar(&options.FirmwareCacheMaxRetainedVersions, "firmware-cache-max-retained-versions", options.FirmwareCacheMaxRetainedVersions, "Number of binaries kept per NicFirmwareSource after their urls are removed from it")
+	fs.StringVar(&options.FirmwareCachePersistentVolumeClaim, "firmware-cache-pvc", options.FirmwareCachePersistentVolumeClaim, "PVC of the firmware cache instead of the host directory, each node uses its own subdirectory of the volume")
+	fs.BoolVar(&options.LocalAPI, "local-api", options.LocalAPI, "Serve the read-only state of the node's devices on a unix socket")
+	fs.StringVar(&options.LocalAPIHostPath, "local-api-host-path", options.LocalAPIHostPath, "Host directory of the local API socket")
+	fs.StringVar(&options.LocalAPISnapshotHostPath, "local-api-snapshot-host-path", options.LocalAPISnapshotHostPath, "Host directory of the snapshot of the node's devices served by the local API")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 {
+		return errors.New("usage: kubectl nic-config manifests [-n namespace] [--operator-image image] [--config-daemon-image image] [flags]")
+	}
+
+	objects, err := RenderDeployment(options)
+	if err != nil {
+		return err
+	}
+	return WriteManifests(opts.stdout, objects)
+}
+
+// listFlag is a comma-separated list flag, an empty value clears the list
+type listFlag struct {
+	values *[]string
+}
+
+func (f listFlag) String() string {
+	if f.values == nil {
+		return ""
+	}
+	return strings.Join(*f.values, ",")
+}
+
+func (f listFlag) Set(value string) error {
+	*f.values = nil
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*f.values = append(*f.values, item)
+		}
+	}
+	return nil
+}
+
+// openInput opens the file, - stands for stdin
+func openInput(opts *globalOptions, file string) (io.ReadCloser, error) {
+	if file == "-" {
+		return io.NopCloser(opts.stdin), nil
+	}
+	return os.Open(file)
+}
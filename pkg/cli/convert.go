@@ -0,0 +1,435 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/utils/ptr"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+)
+
+// Values recommended for RoCE optimization, rendered by the operator for roceOptimized templates
+var roceOptimizedParams = map[string]string{
+	consts.RoceCcPrioMaskP1Param: consts.RoceCcPrioMaskRoceOptimized,
+	consts.CnpDscpP1Param:        consts.CnpDscpRoceOptimized,
+	consts.Cnp802pPrioP1Param:    consts.Cnp802pPrioRoceOptimized,
+}
+
+var (
+	// A few parameter names contain lowercase letters, e.g. EXP_ROM_UEFI_x86_ENABLE
+	nvParamNameRegex     = regexp.MustCompile(`^[A-Z][A-Za-z0-9_]*(\[\d+(\.\.\d+)?\])?$`)
+	valueInBracketsRegex = regexp.MustCompile(`^(.*?)\(([^)]*)\)$`)
+	columnSeparatorRegex = regexp.MustCompile(`\s{2,}`)
+	// Jinja expressions used in Ansible tasks, e.g. {{ item.pci }}
+	templateExprRegex = regexp.MustCompile(`\{\{.*?\}\}`)
+)
+
+// Conversion is the result of converting mlxconfig commands or query dumps
+type Conversion struct {
+	// Template equivalent to the converted nv config parameters
+	Template *v1alpha1.ConfigurationTemplateSpec
+	// Warnings about parameters that couldn't be converted exactly
+	Warnings []string
+}
+
+// ConvertMlxconfig converts a list of `mlxconfig set` commands, e.g. taken from shell or Ansible scripts,
+// or a dump of `mlxconfig query` into an equivalent configuration template
+// parameters matching the high-level template fields are converted to them, the rest are kept as raw nv config
+func ConvertMlxconfig(r io.Reader) (Conversion, error) {
+	params, warnings, err := parseMlxconfig(r)
+	if err != nil {
+		return Conversion{}, err
+	}
+	if len(params) == 0 {
+		return Conversion{}, fmt.Errorf("no nv config parameters found in the input")
+	}
+
+	template, templateWarnings := templateFromNvParams(params)
+	return Conversion{Template: template, Warnings: append(warnings, templateWarnings...)}, nil
+}
+
+// parseMlxconfig collects nv config parameters from mlxconfig / mstconfig set commands and query dumps
+// for `query -e` dumps, only parameters whose next boot value differs from the default are collected
+func parseMlxconfig(r io.Reader) (map[string]string, []string, error) {
+	params := map[string]string{}
+	warnings := []string{}
+
+	setParam := func(name string, value string) {
+		if previous, found := params[name]; found && previous != value {
+			warnings = append(warnings, fmt.Sprintf("%s is set to different values (%s, %s), using %s", name, previous, value, value))
+		}
+		params[name] = value
+	}
+
+	inDump := false
+	continued := ""
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		// Join shell line continuations
+		if strings.HasSuffix(line, "\\") {
+			continued += strings.TrimSuffix(line, "\\") + " "
+			continue
+		}
+		line = continued + line
+		continued = ""
+
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "Configurations:") {
+			inDump = true
+			continue
+		}
+		if strings.HasPrefix(line, "Device #") {
+			inDump = false
+			continue
+		}
+
+		if command, found := mlxconfigCommand(line); found {
+			inDump = false
+			if command.name == "reset" || command.name == "r" {
+				warnings = append(warnings, "reset commands aren't converted, use resetToDefault in the template instead")
+			}
+			for _, param := range command.params {
+				setParam(param[0], param[1])
+			}
+			continue
+		}
+
+		if inDump {
+			name, value, found := parseQueryDumpLine(line)
+			if found {
+				setParam(name, value)
+			}
+		}
+	}
+
+	return params, warnings, scanner.Err()
+}
+
+type mlxconfigInvocation struct {
+	name   string
+	params [][2]string
+}
+
+// mlxconfigCommand parses a mlxconfig / mstconfig invocation in the line, e.g.
+// sudo mlxconfig -d 0000:3b:00.0 -y set SRIOV_EN=1 NUM_OF_VFS=8
+// returns false if the line doesn't invoke mlxconfig
+func mlxconfigCommand(line string) (mlxconfigInvocation, bool) {
+	fields := strings.Fields(templateExprRegex.ReplaceAllString(line, "<expr>"))
+	for i := range fields {
+		fields[i] = strings.Trim(fields[i], `"'`)
+	}
+
+	start := -1
+	for i, field := range fields {
+		tool := path.Base(field)
+		if tool == "mlxconfig" || tool == "mstconfig" {
+			start = i + 1
+			break
+		}
+	}
+	if start == -1 {
+		return mlxconfigInvocation{}, false
+	}
+
+	invocation := mlxconfigInvocation{}
+	for i := start; i < len(fields); i++ {
+		field := fields[i]
+		if strings.ContainsAny(field, ";|&") {
+			// End of the command
+			break
+		}
+		if invocation.name == "" {
+			switch field {
+			case "-d", "--dev", "-b", "--db", "-f", "--file":
+				// Flags with values
+				i++
+			default:
+				if !strings.HasPrefix(field, "-") {
+					invocation.name = field
+				}
+			}
+			continue
+		}
+
+		name, value, found := strings.Cut(field, "=")
+		if found && nvParamNameRegex.MatchString(name) {
+			invocation.params = append(invocation.params, [2]string{name, normalizeDumpValue(value)})
+		}
+	}
+
+	if invocation.name != "set" && invocation.name != "s" {
+		invocation.params = nil
+	}
+	return invocation, true
+}
+
+// parseQueryDumpLine parses a parameter line of the `mlxconfig query` output
+// for `query -e` output, returns false if the next boot value matches the default
+func parseQueryDumpLine(line string) (string, string, bool) {
+	line = strings.TrimSpace(strings.TrimPrefix(line, "*"))
+	fields := columnSeparatorRegex.Split(line, -1)
+	if !nvParamNameRegex.MatchString(fields[0]) {
+		return "", "", false
+	}
+
+	var value string
+	switch len(fields) {
+	case 2:
+		value = fields[1]
+	case 4:
+		// Default, current and next boot values
+		if normalizeDumpValue(fields[1]) == normalizeDumpValue(fields[3]) {
+			return "", "", false
+		}
+		value = fields[3]
+	default:
+		return "", "", false
+	}
+
+	// Array parameters are queried separately by their indices
+	if strings.HasPrefix(value, "Array[") {
+		return "", "", false
+	}
+
+	return fields[0], normalizeDumpValue(value), true
+}
+
+// normalizeDumpValue extracts the numeric value from values with string aliases, e.g. True(1) -> 1
+func normalizeDumpValue(value string) string {
+	match := valueInBracketsRegex.FindStringSubmatch(value)
+	if len(match) == 3 {
+		return match[2]
+	}
+	return value
+}
+
+func isNvParamTrue(value string) bool {
+	return value == consts.NvParamTrue || strings.EqualFold(value, "true")
+}
+
+func isNvParamFalse(value string) bool {
+	return value == consts.NvParamFalse || strings.EqualFold(value, "false")
+}
+
+func linkTypeFromNvParam(value string) v1alpha1.LinkTypeEnum {
+	switch strings.ToLower(value) {
+	case consts.NvParamLinkTypeEthernet, "eth":
+		return consts.Ethernet
+	case consts.NvParamLinkTypeInfiniband, "ib":
+		return consts.Infiniband
+	}
+	return ""
+}
+
+// templateFromNvParams converts the nv config parameters into the template fields
+// converted parameters are removed from the map
+func templateFromNvParams(params map[string]string) (*v1alpha1.ConfigurationTemplateSpec, []string) {
+	template := &v1alpha1.ConfigurationTemplateSpec{}
+	warnings := []string{}
+
+	sriovEnabled, sriovFound := params[consts.SriovEnabledParam]
+	numVfs, numVfsFound := params[consts.SriovNumOfVfsParam]
+	if sriovFound && isNvParamFalse(sriovEnabled) {
+		if numVfsFound && numVfs != "0" {
+			warnings = append(warnings, fmt.Sprintf("%s=%s is ignored because SR-IOV is disabled", consts.SriovNumOfVfsParam, numVfs))
+		}
+		delete(params, consts.SriovEnabledParam)
+		delete(params, consts.SriovNumOfVfsParam)
+	} else if numVfsFound {
+		if vfs, err := strconv.Atoi(numVfs); err == nil {
+			template.NumVfs = vfs
+			if vfs == 0 && sriovFound {
+				warnings = append(warnings, fmt.Sprintf("%s is 0, SR-IOV will be disabled", consts.SriovNumOfVfsParam))
+			}
+			delete(params, consts.SriovEnabledParam)
+			delete(params, consts.SriovNumOfVfsParam)
+		}
+	} else if sriovFound {
+		warnings = append(warnings, fmt.Sprintf("%s is set without %s, it is kept as raw nv config", consts.SriovEnabledParam, consts.SriovNumOfVfsParam))
+	} else {
+		warnings = append(warnings, fmt.Sprintf("%s isn't set, numVfs is 0 and SR-IOV will be disabled", consts.SriovNumOfVfsParam))
+	}
+
+	linkTypeParam := consts.LinkTypeP1Param
+	if _, found := params[linkTypeParam]; !found {
+		linkTypeParam = consts.LinkTypeP2Param
+	}
+	template.LinkType = linkTypeFromNvParam(params[linkTypeParam])
+	if template.LinkType == "" {
+		template.LinkType = consts.Ethernet
+		warnings = append(warnings, "link type isn't set, defaulting to Ethernet")
+	} else {
+		delete(params, linkTypeParam)
+		if linkTypeFromNvParam(params[consts.LinkTypeP2Param]) == template.LinkType {
+			delete(params, consts.LinkTypeP2Param)
+		} else if _, found := params[consts.LinkTypeP2Param]; found {
+			warnings = append(warnings, "ports have different link types, second port's link type is kept as raw nv config")
+		}
+	}
+
+	if maxAccOutRead, found := params[consts.MaxAccOutReadParam]; found {
+		if value, err := strconv.Atoi(maxAccOutRead); err == nil {
+			template.PciPerformanceOptimized = &v1alpha1.PciPerformanceOptimizedSpec{Enabled: true, MaxAccOutRead: value}
+			delete(params, consts.MaxAccOutReadParam)
+		}
+	}
+
+	if ordering, found := params[consts.PciWrOrderingParam]; found {
+		pciPerformance := template.PciPerformanceOptimized
+		if pciPerformance == nil {
+			// Relaxed ordering is applied without the rest of the PCI performance optimizations
+			pciPerformance = &v1alpha1.PciPerformanceOptimizedSpec{}
+		}
+		switch strings.ToLower(ordering) {
+		case "1", "force_relax":
+			if pciPerformance.MaxAccOutRead == consts.AmdMaxAccOutRead {
+				// Both settings match the recommendations for AMD platforms
+				pciPerformance.Preset = consts.PciPerformancePresetAmd
+				pciPerformance.MaxAccOutRead = 0
+			} else {
+				pciPerformance.RelaxedOrdering = ptr.To(true)
+			}
+			delete(params, consts.PciWrOrderingParam)
+		case "0", "per_mkey":
+			pciPerformance.RelaxedOrdering = ptr.To(false)
+			delete(params, consts.PciWrOrderingParam)
+		}
+		if pciPerformance.RelaxedOrdering != nil || pciPerformance.Enabled {
+			template.PciPerformanceOptimized = pciPerformance
+		}
+	}
+
+	if template.LinkType == consts.Ethernet && hasNvParams(params, roceOptimizedParams, "") {
+		template.RoceOptimized = &v1alpha1.RoceOptimizedSpec{Enabled: true}
+		deleteNvParams(params, roceOptimizedParams, "")
+		if hasNvParams(params, roceOptimizedParams, consts.SecondPortPrefix) {
+			deleteNvParams(params, roceOptimizedParams, consts.SecondPortPrefix)
+		}
+	}
+
+	if atsEnabled, found := params[consts.AtsEnabledParam]; found && isNvParamFalse(atsEnabled) && template.PciPerformanceOptimized != nil {
+		template.GpuDirectOptimized = &v1alpha1.GpuDirectOptimizedSpec{Enabled: true, Env: consts.EnvBaremetal}
+		delete(params, consts.AtsEnabledParam)
+	} else if found && (isNvParamTrue(atsEnabled) || isNvParamFalse(atsEnabled)) {
+		template.Ats = &v1alpha1.AtsSpec{Enabled: isNvParamTrue(atsEnabled)}
+		delete(params, consts.AtsEnabledParam)
+	}
+
+	if romEnabled, found := params[consts.BootOptionRomEnP1Param]; found && (isNvParamTrue(romEnabled) || isNvParamFalse(romEnabled)) {
+		template.BootOptions = bootOptionsFromNvParams(params)
+	}
+
+	if value, found := params[consts.AdvancedPCISettingsParam]; found {
+		if !isNvParamTrue(value) {
+			warnings = append(warnings, fmt.Sprintf("%s is always enabled by the operator", consts.AdvancedPCISettingsParam))
+		}
+		delete(params, consts.AdvancedPCISettingsParam)
+	}
+
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		template.RawNvConfig = append(template.RawNvConfig, v1alpha1.NvConfigParam{Name: name, Value: params[name]})
+	}
+
+	return template, warnings
+}
+
+// bootOptionsFromNvParams converts the expansion ROM parameters into the boot options
+// second port parameters are converted only if they match the first port, converted parameters are removed from the map
+func bootOptionsFromNvParams(params map[string]string) *v1alpha1.BootOptionsSpec {
+	bootOptions := &v1alpha1.BootOptionsSpec{Enabled: isNvParamTrue(params[consts.BootOptionRomEnP1Param])}
+	deletePortParams := func(p1Param string, p2Param string) {
+		if params[p2Param] == params[p1Param] {
+			delete(params, p2Param)
+		}
+		delete(params, p1Param)
+	}
+	deletePortParams(consts.BootOptionRomEnP1Param, consts.BootOptionRomEnP2Param)
+
+	if !bootOptions.Enabled {
+		// The template doesn't write the PXE and UEFI parameters with the expansion ROM disabled, they are kept as raw parameters
+		return bootOptions
+	}
+
+	bootOptions.PXE = boolFromNvParam(params, consts.ExpRomPxeEnableParam)
+	bootOptions.UEFI = boolFromNvParam(params, consts.ExpRomUefiX86EnableParam)
+
+	if vlan, err := strconv.Atoi(params[consts.BootVlanP1Param]); err == nil && isNvParamTrue(params[consts.BootVlanEnP1Param]) {
+		bootOptions.BootVlan = vlan
+		deletePortParams(consts.BootVlanEnP1Param, consts.BootVlanEnP2Param)
+		deletePortParams(consts.BootVlanP1Param, consts.BootVlanP2Param)
+	}
+	if retries, err := strconv.Atoi(params[consts.BootRetryCntP1Param]); err == nil {
+		bootOptions.BootRetryCount = &retries
+		deletePortParams(consts.BootRetryCntP1Param, consts.BootRetryCntP2Param)
+	}
+
+	return bootOptions
+}
+
+// boolFromNvParam converts the bool parameter and removes it from the map, returns nil if it's not set or not a bool
+func boolFromNvParam(params map[string]string, param string) *bool {
+	value, found := params[param]
+	if !found || !(isNvParamTrue(value) || isNvParamFalse(value)) {
+		return nil
+	}
+
+	delete(params, param)
+	enabled := isNvParamTrue(value)
+	return &enabled
+}
+
+// hasNvParams checks that all the expected parameters are set, port suffix P1 is replaced with the given one
+func hasNvParams(params map[string]string, expected map[string]string, portSuffix string) bool {
+	for name, value := range expected {
+		if params[withPortSuffix(name, portSuffix)] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func deleteNvParams(params map[string]string, expected map[string]string, portSuffix string) {
+	for name := range expected {
+		delete(params, withPortSuffix(name, portSuffix))
+	}
+}
+
+func withPortSuffix(name string, portSuffix string) string {
+	if portSuffix == "" {
+		return name
+	}
+	return strings.TrimSuffix(name, "P1") + portSuffix
+}
@@ -0,0 +1,204 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/yaml"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+)
+
+const queryDump = `
+Device #1:
+----------
+
+Device type:        ConnectX6DX
+Name:               MCX623106AN-CDA_Ax
+Description:        ConnectX-6 Dx EN adapter card; 100GbE; Dual-port QSFP56; PCIe 4.0/3.0 x16;
+Device:             0000:3b:00.0
+
+Configurations:                                          Default         Current         Next Boot
+*       NUM_OF_VFS                                  0               8               16
+*       SRIOV_EN                                    False(0)        True(1)         True(1)
+        LINK_TYPE_P1                                ETH(2)          ETH(2)          ETH(2)
+        LINK_TYPE_P2                                ETH(2)          ETH(2)          ETH(2)
+*       ATS_ENABLED                                 True(1)         True(1)         False(0)
+        PF_TOTAL_SF                                 Array[0..1]     Array[0..1]     Array[0..1]
+*       MAX_ACC_OUT_READ                            0               0               44
+*       LOG_MAX_QUEUE                               17              17              20
+`
+
+var _ = Describe("convert", func() {
+	Describe("ConvertMlxconfig", func() {
+		It("should convert set commands into high-level fields and raw nv config", func() {
+			input := `#!/bin/bash
+mlxconfig -d 0000:3b:00.0 -y set SRIOV_EN=1 NUM_OF_VFS=8
+sudo /usr/bin/mstconfig -d 0000:3b:00.0 --yes set LINK_TYPE_P1=IB \
+	LINK_TYPE_P2=IB LOG_MAX_QUEUE=17 && reboot
+mlxconfig -d 0000:3b:00.0 -y set ADVANCED_PCI_SETTINGS=1
+`
+			conversion, err := ConvertMlxconfig(strings.NewReader(input))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(conversion.Warnings).To(BeEmpty())
+			Expect(conversion.Template).To(Equal(&v1alpha1.ConfigurationTemplateSpec{
+				NumVfs:      8,
+				LinkType:    consts.Infiniband,
+				RawNvConfig: []v1alpha1.NvConfigParam{{Name: "LOG_MAX_QUEUE", Value: "17"}},
+			}))
+		})
+
+		It("should convert Ansible tasks", func() {
+			input := `- name: Configure NIC
+  ansible.builtin.shell: "mlxconfig -d {{ pci }} -y set NUM_OF_VFS=4 LINK_TYPE_P1=2 ROCE_CC_PRIO_MASK_P1=255 CNP_DSCP_P1=4 CNP_802P_PRIO_P1=6"
+`
+			conversion, err := ConvertMlxconfig(strings.NewReader(input))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(conversion.Template.NumVfs).To(Equal(4))
+			Expect(conversion.Template.LinkType).To(Equal(v1alpha1.LinkTypeEnum(consts.Ethernet)))
+			Expect(conversion.Template.RoceOptimized).To(Equal(&v1alpha1.RoceOptimizedSpec{Enabled: true}))
+			Expect(conversion.Template.RawNvConfig).To(BeEmpty())
+		})
+
+		It("should convert only the changed parameters of a query dump", func() {
+			conversion, err := ConvertMlxconfig(strings.NewReader(queryDump))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(conversion.Template).To(Equal(&v1alpha1.ConfigurationTemplateSpec{
+				NumVfs:                  16,
+				LinkType:                consts.Ethernet,
+				PciPerformanceOptimized: &v1alpha1.PciPerformanceOptimizedSpec{Enabled: true, MaxAccOutRead: 44},
+				GpuDirectOptimized:      &v1alpha1.GpuDirectOptimizedSpec{Enabled: true, Env: consts.EnvBaremetal},
+				RawNvConfig:             []v1alpha1.NvConfigParam{{Name: "LOG_MAX_QUEUE", Value: "20"}},
+			}))
+			Expect(conversion.Warnings).To(ConsistOf("link type isn't set, defaulting to Ethernet"))
+		})
+
+		It("should convert ATS setting without GPU Direct optimizations", func() {
+			input := `mlxconfig -d 0000:3b:00.0 -y set NUM_OF_VFS=0 LINK_TYPE_P1=ETH ATS_ENABLED=1`
+			conversion, err := ConvertMlxconfig(strings.NewReader(input))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(conversion.Template.Ats).To(Equal(&v1alpha1.AtsSpec{Enabled: true}))
+			Expect(conversion.Template.GpuDirectOptimized).To(BeNil())
+			Expect(conversion.Template.RawNvConfig).To(BeEmpty())
+		})
+
+		It("should convert the PCI performance settings of AMD platforms to the preset", func() {
+			input := `mlxconfig -d 0000:3b:00.0 -y set NUM_OF_VFS=0 LINK_TYPE_P1=ETH MAX_ACC_OUT_READ=44 PCI_WR_ORDERING=1`
+			conversion, err := ConvertMlxconfig(strings.NewReader(input))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(conversion.Template.PciPerformanceOptimized).To(Equal(&v1alpha1.PciPerformanceOptimizedSpec{
+				Enabled: true,
+				Preset:  consts.PciPerformancePresetAmd,
+			}))
+			Expect(conversion.Template.RawNvConfig).To(BeEmpty())
+
+			input = `mlxconfig -d 0000:3b:00.0 -y set NUM_OF_VFS=0 LINK_TYPE_P1=ETH MAX_ACC_OUT_READ=32 PCI_WR_ORDERING=force_relax`
+			conversion, err = ConvertMlxconfig(strings.NewReader(input))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(conversion.Template.PciPerformanceOptimized.MaxAccOutRead).To(Equal(32))
+			Expect(conversion.Template.PciPerformanceOptimized.RelaxedOrdering).To(HaveValue(BeTrue()))
+
+			input = `mlxconfig -d 0000:3b:00.0 -y set NUM_OF_VFS=0 LINK_TYPE_P1=ETH PCI_WR_ORDERING=1`
+			conversion, err = ConvertMlxconfig(strings.NewReader(input))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(conversion.Template.PciPerformanceOptimized).To(Equal(&v1alpha1.PciPerformanceOptimizedSpec{
+				RelaxedOrdering: ptr.To(true),
+			}))
+			Expect(conversion.Template.RawNvConfig).To(BeEmpty())
+		})
+
+		It("should keep parameters that don't map to the template fields as raw nv config", func() {
+			input := `mlxconfig -d 0000:3b:00.0 -y set SRIOV_EN=1 LINK_TYPE_P1=ETH LINK_TYPE_P2=IB CNP_DSCP_P1=4
+mlxconfig -d 0000:3b:00.0 -y set CNP_DSCP_P1=5
+mlxconfig -d 0000:3b:00.0 -y reset`
+			conversion, err := ConvertMlxconfig(strings.NewReader(input))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(conversion.Template.LinkType).To(Equal(v1alpha1.LinkTypeEnum(consts.Ethernet)))
+			Expect(conversion.Template.RoceOptimized).To(BeNil())
+			Expect(conversion.Template.RawNvConfig).To(Equal([]v1alpha1.NvConfigParam{
+				{Name: "CNP_DSCP_P1", Value: "5"},
+				{Name: "LINK_TYPE_P2", Value: "IB"},
+				{Name: "SRIOV_EN", Value: "1"},
+			}))
+			Expect(conversion.Warnings).To(ConsistOf(
+				"CNP_DSCP_P1 is set to different values (4, 5), using 5",
+				"reset commands aren't converted, use resetToDefault in the template instead",
+				"SRIOV_EN is set without NUM_OF_VFS, it is kept as raw nv config",
+				"ports have different link types, second port's link type is kept as raw nv config",
+			))
+		})
+
+		It("should convert the expansion ROM parameters into boot options", func() {
+			input := `mlxconfig -d 0000:3b:00.0 -y set NUM_OF_VFS=0 LINK_TYPE_P1=ETH BOOT_OPTION_ROM_EN_P1=True BOOT_OPTION_ROM_EN_P2=True
+mlxconfig -d 0000:3b:00.0 -y set EXP_ROM_PXE_ENABLE=0 EXP_ROM_UEFI_x86_ENABLE=1 BOOT_VLAN_EN_P1=1 BOOT_VLAN_P1=100 BOOT_RETRY_CNT_P1=3`
+			conversion, err := ConvertMlxconfig(strings.NewReader(input))
+			Expect(err).NotTo(HaveOccurred())
+			pxe, uefi, retries := false, true, 3
+			Expect(conversion.Template.BootOptions).To(Equal(&v1alpha1.BootOptionsSpec{
+				Enabled:        true,
+				PXE:            &pxe,
+				UEFI:           &uefi,
+				BootVlan:       100,
+				BootRetryCount: &retries,
+			}))
+			Expect(conversion.Template.RawNvConfig).To(BeEmpty())
+		})
+
+		It("should convert the disabled expansion ROM", func() {
+			input := `mlxconfig -d 0000:3b:00.0 -y set NUM_OF_VFS=0 BOOT_OPTION_ROM_EN_P1=0 BOOT_OPTION_ROM_EN_P2=1 EXP_ROM_PXE_ENABLE=0`
+			conversion, err := ConvertMlxconfig(strings.NewReader(input))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(conversion.Template.BootOptions).To(Equal(&v1alpha1.BootOptionsSpec{Enabled: false}))
+			Expect(conversion.Template.RawNvConfig).To(Equal([]v1alpha1.NvConfigParam{
+				{Name: "BOOT_OPTION_ROM_EN_P2", Value: "1"}, {Name: "EXP_ROM_PXE_ENABLE", Value: "0"}}))
+		})
+
+		It("should fail if the input has no nv config parameters", func() {
+			_, err := ConvertMlxconfig(strings.NewReader("mlxconfig -d 0000:3b:00.0 query\n"))
+			Expect(err).To(MatchError(ContainSubstring("no nv config parameters")))
+		})
+	})
+
+	Describe("Run", func() {
+		It("should print the template manifest with warnings", func() {
+			stdout := &bytes.Buffer{}
+			opts := &globalOptions{stdin: strings.NewReader(queryDump), stdout: stdout}
+
+			Expect(runConvert(context.Background(), opts, []string{"--nic-type", "101d", "--name", "migrated", "-n", "nic-configuration-operator"})).To(Succeed())
+			Expect(stdout.String()).To(HavePrefix("# WARNING: link type isn't set, defaulting to Ethernet\n"))
+
+			template := &v1alpha1.NicConfigurationTemplate{}
+			Expect(yaml.UnmarshalStrict(stdout.Bytes(), template)).To(Succeed())
+			Expect(template.Kind).To(Equal("NicConfigurationTemplate"))
+			Expect(template.Name).To(Equal("migrated"))
+			Expect(template.Namespace).To(Equal("nic-configuration-operator"))
+			Expect(template.Spec.NicSelector.NicType).To(Equal("101d"))
+			Expect(template.Spec.Template.NumVfs).To(Equal(16))
+		})
+
+		It("should require the nic type", func() {
+			opts := &globalOptions{stdin: strings.NewReader(queryDump), stdout: &bytes.Buffer{}}
+			Expect(runConvert(context.Background(), opts, []string{})).To(MatchError(ContainSubstring("usage")))
+		})
+	})
+})
@@ -0,0 +1,559 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/Mellanox/nic-configuration-operator/config"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+)
+
+const (
+	defaultDeploymentName         = "nic-configuration-operator"
+	configDaemonName              = "nic-configuration-daemon"
+	localAPISocketDir             = "/run/nic-configuration-operator"
+	localAPISnapshotDir           = "/var/lib/nic-configuration-operator/local-api"
+	lifecycleEventsCredentialsDir = "/etc/nic-configuration-operator/lifecycle-events"
+	firmwareCacheMountPath        = "/var/lib/nic-configuration-operator/firmware"
+	mstDevicePath                 = "/dev/mst"
+	generatedCRDsDir              = "crd/bases"
+	generatedRolesYAML            = "rbac/role.yaml"
+)
+
+// configDaemonCapabilities are granted to the config daemon when it doesn't run in the privileged mode
+var configDaemonCapabilities = []corev1.Capability{"SYS_ADMIN", "SYS_RAWIO", "NET_ADMIN", "SYS_CHROOT", "SYS_BOOT"}
+
+// DeploymentOptions selects the images and the features of the rendered operator deployment,
+// the options and their defaults mirror the values of the Helm chart
+type DeploymentOptions struct {
+	// Name of the operator Deployment, ServiceAccount and RBAC resources
+	Name      string
+	Namespace string
+
+	OperatorImage     string
+	ConfigDaemonImage string
+	ImagePullSecrets  []string
+	// LogLevel of the operator and the config daemon, debug or info
+	LogLevel string
+
+	WaitForNodeReady        bool
+	BatchDiscovery          bool
+	DiscoverVfs             bool
+	StrictConvergence       bool
+	Privileged              bool
+	HostUsers               bool
+	RestartSyncWindow       string
+	DeepScanInterval        string
+	DeviceDiscoveryInterval string
+	ProvisioningTaints      []string
+	RdmaResourcePrefixes    []string
+	IgnorePCIAddresses      []string
+	// TemperatureWarningThreshold in degrees Celsius, the temperature warning is disabled if 0
+	TemperatureWarningThreshold int
+
+	// ChangelogSink of the applied nv config changes, log, configmap or s3, the changelog is disabled if empty
+	ChangelogSink          string
+	ChangelogConfigMapName string
+	ChangelogS3Endpoint    string
+	ChangelogS3Bucket      string
+	ChangelogS3Region      string
+	ChangelogS3Prefix      string
+	// ChangelogS3CredentialsSecret is the name of the secret with the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY keys
+	ChangelogS3CredentialsSecret string
+
+	// LifecycleEventsSink of the CloudEvents of the devices' configuration lifecycle, http or nats, the events are disabled if empty
+	LifecycleEventsSink     string
+	LifecycleEventsEndpoint string
+	LifecycleEventsSubject  string
+	// LifecycleEventsCredentialsSecret is the name of the secret with the NATS credentials and TLS files, mounted to the config daemon
+	LifecycleEventsCredentialsSecret string
+
+	FirmwareCacheHostPath            string
+	FirmwareCacheMaxSize             string
+	FirmwareCacheMaxRetainedVersions int
+	// FirmwareCachePersistentVolumeClaim stores the firmware cache on the PVC instead of the host directory,
+	// each node uses its own subdirectory of the volume
+	FirmwareCachePersistentVolumeClaim string
+
+	LocalAPI                 bool
+	LocalAPIHostPath         string
+	LocalAPISnapshotHostPath string
+}
+
+// DefaultDeploymentOptions returns the options matching the default values of the Helm chart
+func DefaultDeploymentOptions() DeploymentOptions {
+	return DeploymentOptions{
+		Name:                             defaultDeploymentName,
+		Namespace:                        defaultDeploymentName,
+		OperatorImage:                    "ghcr.io/mellanox/nic-configuration-operator:latest",
+		ConfigDaemonImage:                "ghcr.io/mellanox/nic-configuration-operator-daemon:latest",
+		LogLevel:                         "info",
+		WaitForNodeReady:                 true,
+		Privileged:                       true,
+		HostUsers:                        true,
+		TemperatureWarningThreshold:      consts.DefaultTemperatureWarningThreshold,
+		ProvisioningTaints:               []string{"node.cloudprovider.kubernetes.io/uninitialized"},
+		RdmaResourcePrefixes:             []string{"rdma/", "nvidia.com/"},
+		ChangelogConfigMapName:           "nic-configuration-changelog",
+		ChangelogS3Region:                "us-east-1",
+		LifecycleEventsSubject:           "nic-configuration.events",
+		FirmwareCacheHostPath:            firmwareCacheMountPath,
+		FirmwareCacheMaxRetainedVersions: 1,
+		LocalAPIHostPath:                 localAPISocketDir,
+		LocalAPISnapshotHostPath:         localAPISnapshotDir,
+	}
+}
+
+// RenderDeployment builds the CRDs, RBAC and workloads of the operator deployment without Helm
+// the CRDs, the ClusterRole and the Role are the ones generated by controller-gen from the API types and the kubebuilder markers,
+// the operator doesn't serve admission webhooks so no webhook configurations are rendered
+func RenderDeployment(options DeploymentOptions) ([]client.Object, error) {
+	if options.Name == "" || options.Namespace == "" {
+		return nil, fmt.Errorf("name and namespace of the deployment are required")
+	}
+	if options.OperatorImage == "" || options.ConfigDaemonImage == "" {
+		return nil, fmt.Errorf("operator and config daemon images are required")
+	}
+
+	objects, err := generatedCRDs()
+	if err != nil {
+		return nil, err
+	}
+
+	clusterRole, role, err := generatedRoles(options)
+	if err != nil {
+		return nil, err
+	}
+
+	objects = append(objects,
+		&corev1.ServiceAccount{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ServiceAccount"},
+			ObjectMeta: options.objectMeta(options.Name, "rbac"),
+		},
+		clusterRole,
+		&rbacv1.ClusterRoleBinding{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRoleBinding"},
+			ObjectMeta: options.clusterObjectMeta(options.Name+"-rolebinding", "rbac"),
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: clusterRole.Name},
+			Subjects: []rbacv1.Subject{
+				{Kind: rbacv1.ServiceAccountKind, Name: options.Name, Namespace: options.Namespace},
+			},
+		},
+		role,
+		&rbacv1.RoleBinding{
+			TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "RoleBinding"},
+			ObjectMeta: options.objectMeta(options.Name+"-rolebinding", "rbac"),
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: role.Name},
+			Subjects: []rbacv1.Subject{
+				{Kind: rbacv1.ServiceAccountKind, Name: options.Name, Namespace: options.Namespace},
+			},
+		},
+		options.operatorDeployment(),
+		options.configDaemonSet(),
+	)
+
+	return objects, nil
+}
+
+// WriteManifests writes the objects as a multi-document YAML stream
+func WriteManifests(w io.Writer, objects []client.Object) error {
+	for i, object := range objects {
+		data, err := yaml.Marshal(object)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			if _, err := io.WriteString(w, "---\n"); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generatedCRDs parses the CRDs embedded from config/crd/bases
+func generatedCRDs() ([]client.Object, error) {
+	files, err := fs.Glob(config.Generated, path.Join(generatedCRDsDir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(files)
+
+	objects := []client.Object{}
+	for _, file := range files {
+		crd := &unstructured.Unstructured{}
+		if err := readGeneratedYAML(file, &crd.Object); err != nil {
+			return nil, err
+		}
+		objects = append(objects, crd)
+	}
+	return objects, nil
+}
+
+// generatedRoles parses the ClusterRole and the Role of the operator namespace embedded from config/rbac/role.yaml
+// and names them after the deployment
+func generatedRoles(options DeploymentOptions) (*rbacv1.ClusterRole, *rbacv1.Role, error) {
+	data, err := fs.ReadFile(config.Generated, generatedRolesYAML)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	clusterRole := &rbacv1.ClusterRole{}
+	role := &rbacv1.Role{}
+	for _, document := range strings.Split(string(data), "\n---\n") {
+		typeMeta := metav1.TypeMeta{}
+		if err := yaml.Unmarshal([]byte(document), &typeMeta); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse generated manifest %s: %w", generatedRolesYAML, err)
+		}
+
+		var into interface{}
+		switch typeMeta.Kind {
+		case "ClusterRole":
+			into = clusterRole
+		case "Role":
+			into = role
+		default:
+			return nil, nil, fmt.Errorf("unexpected %s in generated manifest %s", typeMeta.Kind, generatedRolesYAML)
+		}
+		if err := yaml.Unmarshal([]byte(document), into); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse generated manifest %s: %w", generatedRolesYAML, err)
+		}
+	}
+	if clusterRole.Kind == "" || role.Kind == "" {
+		return nil, nil, fmt.Errorf("generated manifest %s must contain a ClusterRole and a Role", generatedRolesYAML)
+	}
+
+	clusterRole.ObjectMeta = options.clusterObjectMeta(options.Name+"-role", "rbac")
+	role.ObjectMeta = options.objectMeta(options.Name+"-role", "rbac")
+	return clusterRole, role, nil
+}
+
+func readGeneratedYAML(file string, into interface{}) error {
+	data, err := fs.ReadFile(config.Generated, file)
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(data, into); err != nil {
+		return fmt.Errorf("failed to parse generated manifest %s: %w", file, err)
+	}
+	return nil
+}
+
+func (o DeploymentOptions) clusterObjectMeta(name, component string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name: name,
+		Labels: map[string]string{
+			"app.kubernetes.io/component":  component,
+			"app.kubernetes.io/created-by": defaultDeploymentName,
+			"app.kubernetes.io/part-of":    defaultDeploymentName,
+		},
+	}
+}
+
+func (o DeploymentOptions) objectMeta(name, component string) metav1.ObjectMeta {
+	meta := o.clusterObjectMeta(name, component)
+	meta.Namespace = o.Namespace
+	return meta
+}
+
+func (o DeploymentOptions) imagePullSecrets() []corev1.LocalObjectReference {
+	secrets := []corev1.LocalObjectReference{}
+	for _, name := range o.ImagePullSecrets {
+		secrets = append(secrets, corev1.LocalObjectReference{Name: name})
+	}
+	return secrets
+}
+
+func (o DeploymentOptions) operatorDeployment() *appsv1.Deployment {
+	selector := map[string]string{"control-plane": o.Name + "-controller-manager"}
+
+	env := []corev1.EnvVar{
+		{Name: "NAMESPACE", ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.namespace"}}},
+	}
+	if o.LogLevel != "" {
+		env = append(env, corev1.EnvVar{Name: "LOG_LEVEL", Value: o.LogLevel})
+	}
+
+	probe := func(path string, initialDelay int32, period int32) *corev1.Probe {
+		return &corev1.Probe{
+			ProbeHandler: corev1.ProbeHandler{
+				HTTPGet: &corev1.HTTPGetAction{Path: path, Port: intstr.FromInt32(8081)},
+			},
+			InitialDelaySeconds: initialDelay,
+			PeriodSeconds:       period,
+		}
+	}
+
+	return &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: appsv1.SchemeGroupVersion.String(), Kind: "Deployment"},
+		ObjectMeta: o.objectMeta(o.Name, "manager"),
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.To[int32](1),
+			Selector: &metav1.LabelSelector{MatchLabels: selector},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      selector,
+					Annotations: map[string]string{"kubectl.kubernetes.io/default-container": "manager"},
+				},
+				Spec: corev1.PodSpec{
+					Tolerations: []corev1.Toleration{
+						{Key: "node-role.kubernetes.io/master", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+						{Key: "node-role.kubernetes.io/control-plane", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+					},
+					ImagePullSecrets:              o.imagePullSecrets(),
+					SecurityContext:               &corev1.PodSecurityContext{RunAsNonRoot: ptr.To(true)},
+					ServiceAccountName:            o.Name,
+					TerminationGracePeriodSeconds: ptr.To[int64](10),
+					Containers: []corev1.Container{{
+						Name:    "manager",
+						Command: []string{"/manager"},
+						Image:   o.OperatorImage,
+						SecurityContext: &corev1.SecurityContext{
+							AllowPrivilegeEscalation: ptr.To(false),
+							Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+						},
+						Env:            env,
+						LivenessProbe:  probe("/healthz", 15, 20),
+						ReadinessProbe: probe("/readyz", 5, 10),
+						Resources:      defaultResources(),
+					}},
+				},
+			},
+		},
+	}
+}
+
+func (o DeploymentOptions) configDaemonSet() *appsv1.DaemonSet {
+	selector := map[string]string{"control-plane": configDaemonName}
+
+	env := []corev1.EnvVar{
+		{Name: "NODE_NAME", ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "spec.nodeName"}}},
+		{Name: "NAMESPACE", ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.namespace"}}},
+	}
+	if o.LogLevel != "" {
+		env = append(env, corev1.EnvVar{Name: "LOG_LEVEL", Value: o.LogLevel})
+	}
+	env = append(env,
+		corev1.EnvVar{Name: "WAIT_FOR_NODE_READY", Value: strconv.FormatBool(o.WaitForNodeReady)},
+		corev1.EnvVar{Name: "BATCH_DISCOVERY", Value: strconv.FormatBool(o.BatchDiscovery)},
+		corev1.EnvVar{Name: "DISCOVER_VFS", Value: strconv.FormatBool(o.DiscoverVfs)},
+		corev1.EnvVar{Name: "STRICT_CONVERGENCE", Value: strconv.FormatBool(o.StrictConvergence)},
+		corev1.EnvVar{Name: "TEMPERATURE_WARNING_THRESHOLD", Value: strconv.Itoa(o.TemperatureWarningThreshold)},
+	)
+	if o.RestartSyncWindow != "" {
+		env = append(env, corev1.EnvVar{Name: "RESTART_SYNC_WINDOW", Value: o.RestartSyncWindow})
+	}
+	if o.DeviceDiscoveryInterval != "" {
+		env = append(env, corev1.EnvVar{Name: "DEVICE_DISCOVERY_INTERVAL", Value: o.DeviceDiscoveryInterval})
+	}
+	if o.DeepScanInterval != "" {
+		env = append(env, corev1.EnvVar{Name: "DEEP_SCAN_INTERVAL", Value: o.DeepScanInterval})
+	}
+	if len(o.ProvisioningTaints) != 0 {
+		env = append(env, corev1.EnvVar{Name: "PROVISIONING_TAINTS", Value: strings.Join(o.ProvisioningTaints, ",")})
+	}
+	if len(o.RdmaResourcePrefixes) != 0 {
+		env = append(env, corev1.EnvVar{Name: "RDMA_RESOURCE_PREFIXES", Value: strings.Join(o.RdmaResourcePrefixes, ",")})
+	}
+	if len(o.IgnorePCIAddresses) != 0 {
+		env = append(env, corev1.EnvVar{Name: "IGNORE_PCI_ADDRESSES", Value: strings.Join(o.IgnorePCIAddresses, ",")})
+	}
+	if o.ChangelogSink != "" {
+		env = append(env,
+			corev1.EnvVar{Name: "CHANGELOG_SINK", Value: o.ChangelogSink},
+			corev1.EnvVar{Name: "CHANGELOG_CONFIGMAP_NAME", Value: o.ChangelogConfigMapName},
+		)
+		if o.ChangelogSink == "s3" {
+			env = append(env,
+				corev1.EnvVar{Name: "CHANGELOG_S3_ENDPOINT", Value: o.ChangelogS3Endpoint},
+				corev1.EnvVar{Name: "CHANGELOG_S3_BUCKET", Value: o.ChangelogS3Bucket},
+				corev1.EnvVar{Name: "CHANGELOG_S3_REGION", Value: o.ChangelogS3Region},
+				corev1.EnvVar{Name: "CHANGELOG_S3_PREFIX", Value: o.ChangelogS3Prefix},
+				secretEnvVar("AWS_ACCESS_KEY_ID", o.ChangelogS3CredentialsSecret),
+				secretEnvVar("AWS_SECRET_ACCESS_KEY", o.ChangelogS3CredentialsSecret),
+			)
+		}
+	}
+	if o.LifecycleEventsSink != "" {
+		env = append(env,
+			corev1.EnvVar{Name: "LIFECYCLE_EVENTS_SINK", Value: o.LifecycleEventsSink},
+			corev1.EnvVar{Name: "LIFECYCLE_EVENTS_ENDPOINT", Value: o.LifecycleEventsEndpoint},
+			corev1.EnvVar{Name: "LIFECYCLE_EVENTS_SUBJECT", Value: o.LifecycleEventsSubject},
+		)
+		if o.LifecycleEventsCredentialsSecret != "" {
+			env = append(env, corev1.EnvVar{Name: "LIFECYCLE_EVENTS_CREDENTIALS_DIR", Value: lifecycleEventsCredentialsDir})
+		}
+	}
+	if o.FirmwareCacheMaxSize != "" {
+		env = append(env, corev1.EnvVar{Name: "FIRMWARE_CACHE_MAX_SIZE", Value: o.FirmwareCacheMaxSize})
+	}
+	env = append(env, corev1.EnvVar{Name: "FIRMWARE_CACHE_MAX_RETAINED_VERSIONS", Value: strconv.Itoa(o.FirmwareCacheMaxRetainedVersions)})
+
+	// The privileged mode can't be used in a user namespace
+	privileged := o.Privileged && o.HostUsers
+	securityContext := &corev1.SecurityContext{Privileged: ptr.To(privileged)}
+	if !privileged {
+		securityContext.Capabilities = &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}, Add: configDaemonCapabilities}
+	}
+
+	firmwareCacheMount := corev1.VolumeMount{Name: "firmware-cache", MountPath: firmwareCacheMountPath}
+	firmwareCacheVolume := hostPathVolume("firmware-cache", o.FirmwareCacheHostPath, ptr.To(corev1.HostPathDirectoryOrCreate))
+	if o.FirmwareCachePersistentVolumeClaim != "" {
+		// Each node uses its own subdirectory of the shared volume
+		firmwareCacheMount.SubPathExpr = "$(NODE_NAME)"
+		firmwareCacheVolume.VolumeSource = corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: o.FirmwareCachePersistentVolumeClaim},
+		}
+	}
+
+	// The host's processes are not visible in a user namespace, the devices are accessed through the mstflint_access driver instead
+	hostMount := corev1.VolumeMount{Name: "proc", MountPath: "/proc"}
+	hostVolume := hostPathVolume("proc", "/proc", nil)
+	if !o.HostUsers {
+		hostMount = corev1.VolumeMount{Name: "mst", MountPath: mstDevicePath}
+		hostVolume = hostPathVolume("mst", mstDevicePath, ptr.To(corev1.HostPathDirectory))
+	}
+
+	mounts := []corev1.VolumeMount{
+		{Name: "sys", MountPath: "/sys"},
+		hostMount,
+		{Name: "host", MountPath: "/host", ReadOnly: true},
+		firmwareCacheMount,
+	}
+	volumes := []corev1.Volume{
+		hostPathVolume("sys", "/sys", nil),
+		hostVolume,
+		hostPathVolume("host", "/", nil),
+		firmwareCacheVolume,
+	}
+	if o.LifecycleEventsSink != "" && o.LifecycleEventsCredentialsSecret != "" {
+		mounts = append(mounts, corev1.VolumeMount{Name: "lifecycle-events-credentials", MountPath: lifecycleEventsCredentialsDir, ReadOnly: true})
+		volumes = append(volumes, corev1.Volume{
+			Name:         "lifecycle-events-credentials",
+			VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: o.LifecycleEventsCredentialsSecret}},
+		})
+	}
+	if o.LocalAPI {
+		env = append(env,
+			corev1.EnvVar{Name: "LOCAL_API_SOCKET", Value: path.Join(localAPISocketDir, "api.sock")},
+			corev1.EnvVar{Name: "LOCAL_API_SNAPSHOT", Value: path.Join(localAPISnapshotDir, "devices.json")})
+		mounts = append(mounts,
+			corev1.VolumeMount{Name: "local-api", MountPath: localAPISocketDir},
+			corev1.VolumeMount{Name: "local-api-snapshot", MountPath: localAPISnapshotDir})
+		volumes = append(volumes,
+			hostPathVolume("local-api", o.LocalAPIHostPath, ptr.To(corev1.HostPathDirectoryOrCreate)),
+			hostPathVolume("local-api-snapshot", o.LocalAPISnapshotHostPath, ptr.To(corev1.HostPathDirectoryOrCreate)))
+	}
+
+	var tolerations []corev1.Toleration
+	if o.StrictConvergence {
+		tolerations = []corev1.Toleration{
+			{Key: consts.NotConvergedTaintKey, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+		}
+	}
+
+	var hostUsers *bool
+	if !o.HostUsers {
+		hostUsers = ptr.To(false)
+	}
+
+	meta := o.objectMeta(configDaemonName, "config-daemon")
+	meta.Labels["app.kubernetes.io/name"] = configDaemonName
+
+	return &appsv1.DaemonSet{
+		TypeMeta:   metav1.TypeMeta{APIVersion: appsv1.SchemeGroupVersion.String(), Kind: "DaemonSet"},
+		ObjectMeta: meta,
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: selector},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      selector,
+					Annotations: map[string]string{"kubectl.kubernetes.io/default-container": configDaemonName},
+				},
+				Spec: corev1.PodSpec{
+					ServiceAccountName:            o.Name,
+					ImagePullSecrets:              o.imagePullSecrets(),
+					TerminationGracePeriodSeconds: ptr.To[int64](10),
+					HostNetwork:                   o.HostUsers,
+					HostPID:                       o.HostUsers,
+					HostUsers:                     hostUsers,
+					PriorityClassName:             "system-node-critical",
+					Tolerations:                   tolerations,
+					Containers: []corev1.Container{{
+						Name:            configDaemonName,
+						Image:           o.ConfigDaemonImage,
+						SecurityContext: securityContext,
+						Resources:       defaultResources(),
+						Env:             env,
+						VolumeMounts:    mounts,
+					}},
+					Volumes: volumes,
+				},
+			},
+		},
+	}
+}
+
+// secretEnvVar returns the environment variable set from the key of the same name in the secret
+func secretEnvVar(name, secret string) corev1.EnvVar {
+	return corev1.EnvVar{Name: name, ValueFrom: &corev1.EnvVarSource{
+		SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: secret}, Key: name},
+	}}
+}
+
+func hostPathVolume(name, hostPath string, hostPathType *corev1.HostPathType) corev1.Volume {
+	return corev1.Volume{
+		Name: name,
+		VolumeSource: corev1.VolumeSource{
+			HostPath: &corev1.HostPathVolumeSource{Path: hostPath, Type: hostPathType},
+		},
+	}
+}
+
+// defaultResources returns the default requests and limits of both the operator and the config daemon in the Helm chart
+func defaultResources() corev1.ResourceRequirements {
+	return corev1.ResourceRequirements{
+		Limits: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("500m"),
+			corev1.ResourceMemory: resource.MustParse("128Mi"),
+		},
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("10m"),
+			corev1.ResourceMemory: resource.MustParse("64Mi"),
+		},
+	}
+}
@@ -0,0 +1,351 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+)
+
+func findObject[T client.Object](objects []client.Object) T {
+	for _, object := range objects {
+		if typed, ok := object.(T); ok {
+			return typed
+		}
+	}
+	Fail("object not rendered")
+	var empty T
+	return empty
+}
+
+func envValue(container corev1.Container, name string) (string, bool) {
+	for _, env := range container.Env {
+		if env.Name == name {
+			return env.Value, true
+		}
+	}
+	return "", false
+}
+
+const chartDir = "../../deployment/nic-configuration-operator-chart"
+
+// mergeValues merges the overrides into the chart values the way helm merges the --set values
+func mergeValues(values, overrides map[string]interface{}) {
+	for key, override := range overrides {
+		if overrideMap, ok := override.(map[string]interface{}); ok {
+			if valueMap, ok := values[key].(map[string]interface{}); ok {
+				mergeValues(valueMap, overrideMap)
+				continue
+			}
+		}
+		values[key] = override
+	}
+}
+
+// renderChartTemplate renders the template of the chart with its default values merged with the overrides,
+// only the template functions used by the chart are implemented
+func renderChartTemplate(name string, overrides map[string]interface{}) []byte {
+	data, err := os.ReadFile(filepath.Join(chartDir, "values.yaml"))
+	Expect(err).NotTo(HaveOccurred())
+	values := map[string]interface{}{}
+	Expect(yaml.Unmarshal(data, &values)).To(Succeed())
+	mergeValues(values, overrides)
+
+	tmpl := template.New(name)
+	tmpl.Funcs(template.FuncMap{
+		"include": func(name string, data interface{}) (string, error) {
+			out := &strings.Builder{}
+			err := tmpl.ExecuteTemplate(out, name, data)
+			return out.String(), err
+		},
+		"default": func(defaultValue interface{}, value interface{}) interface{} {
+			if value == nil || value == "" {
+				return defaultValue
+			}
+			return value
+		},
+		"toYaml": func(value interface{}) (string, error) {
+			out, err := yaml.Marshal(value)
+			return strings.TrimSuffix(string(out), "\n"), err
+		},
+		"nindent": func(spaces int, value string) string {
+			padding := strings.Repeat(" ", spaces)
+			return "\n" + padding + strings.ReplaceAll(value, "\n", "\n"+padding)
+		},
+		"quote": func(value interface{}) string {
+			return fmt.Sprintf("%q", fmt.Sprint(value))
+		},
+		"join": func(separator string, values []interface{}) string {
+			items := []string{}
+			for _, value := range values {
+				items = append(items, fmt.Sprint(value))
+			}
+			return strings.Join(items, separator)
+		},
+		"trunc": func(length int, value string) string {
+			return value[:min(length, len(value))]
+		},
+		"trimSuffix": func(suffix, value string) string { return strings.TrimSuffix(value, suffix) },
+		"replace":    func(old, new, value string) string { return strings.ReplaceAll(value, old, new) },
+		"contains":   func(substr, value string) bool { return strings.Contains(value, substr) },
+	})
+	for _, file := range []string{"_helpers.tpl", name} {
+		content, err := os.ReadFile(filepath.Join(chartDir, "templates", file))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = tmpl.New(file).Parse(string(content))
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	out := &bytes.Buffer{}
+	Expect(tmpl.ExecuteTemplate(out, name, map[string]interface{}{
+		"Values":  values,
+		"Release": map[string]interface{}{"Name": defaultDeploymentName, "Namespace": defaultDeploymentName, "Service": "Helm"},
+		"Chart":   map[string]interface{}{"Name": "nic-configuration-operator-chart", "Version": "0.0.1", "AppVersion": "latest"},
+	})).To(Succeed())
+	return out.Bytes()
+}
+
+var _ = Describe("manifests", func() {
+	It("should render the generated CRDs and the RBAC of the operator", func() {
+		options := DefaultDeploymentOptions()
+		options.Name = "nic-config"
+		options.Namespace = "network-operator"
+
+		objects, err := RenderDeployment(options)
+		Expect(err).NotTo(HaveOccurred())
+
+		crds := []string{}
+		for _, object := range objects {
+			if object.GetObjectKind().GroupVersionKind().Kind == "CustomResourceDefinition" {
+				crds = append(crds, object.GetName())
+			}
+		}
+		Expect(crds).To(ContainElements(
+			"nicdevices.configuration.net.nvidia.com",
+			"nicconfigurationtemplates.configuration.net.nvidia.com",
+			"nicfirmwaresources.configuration.net.nvidia.com",
+		))
+
+		role := findObject[*rbacv1.ClusterRole](objects)
+		Expect(role.Name).To(Equal("nic-config-role"))
+		Expect(role.Rules).NotTo(BeEmpty())
+
+		binding := findObject[*rbacv1.ClusterRoleBinding](objects)
+		Expect(binding.RoleRef.Name).To(Equal("nic-config-role"))
+		Expect(binding.Subjects).To(ConsistOf(rbacv1.Subject{Kind: "ServiceAccount", Name: "nic-config", Namespace: "network-operator"}))
+
+		// Workloads restarted by the post configuration hooks are limited to the operator namespace
+		namespacedRole := findObject[*rbacv1.Role](objects)
+		Expect(namespacedRole.Name).To(Equal("nic-config-role"))
+		Expect(namespacedRole.Namespace).To(Equal("network-operator"))
+		Expect(namespacedRole.Rules).To(ContainElement(HaveField("Resources", ContainElement("daemonsets"))))
+		Expect(role.Rules).NotTo(ContainElement(HaveField("Resources", ContainElement("daemonsets"))))
+		// ConfigMaps and Secrets are only read in the operator namespace
+		Expect(namespacedRole.Rules).To(ContainElement(HaveField("Resources", ContainElement("secrets"))))
+		Expect(role.Rules).NotTo(ContainElement(HaveField("Resources", ContainElement("secrets"))))
+		Expect(role.Rules).NotTo(ContainElement(HaveField("Resources", ContainElement("configmaps"))))
+
+		namespacedBinding := findObject[*rbacv1.RoleBinding](objects)
+		Expect(namespacedBinding.Namespace).To(Equal("network-operator"))
+		Expect(namespacedBinding.RoleRef).To(Equal(rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: "nic-config-role"}))
+		Expect(namespacedBinding.Subjects).To(ConsistOf(rbacv1.Subject{Kind: "ServiceAccount", Name: "nic-config", Namespace: "network-operator"}))
+
+		deployment := findObject[*appsv1.Deployment](objects)
+		Expect(deployment.Namespace).To(Equal("network-operator"))
+		Expect(deployment.Spec.Template.Spec.ServiceAccountName).To(Equal("nic-config"))
+		Expect(deployment.Spec.Template.Spec.Containers[0].Env).To(ContainElement(HaveField("Name", "NAMESPACE")))
+	})
+	It("should render the config daemon with the selected features", func() {
+		options := DefaultDeploymentOptions()
+		options.StrictConvergence = true
+		options.BatchDiscovery = true
+		options.Privileged = false
+		options.LocalAPI = true
+		options.IgnorePCIAddresses = []string{"0000:3b:00.0", "0000:3b:00.1"}
+
+		objects, err := RenderDeployment(options)
+		Expect(err).NotTo(HaveOccurred())
+
+		daemonSet := findObject[*appsv1.DaemonSet](objects)
+		spec := daemonSet.Spec.Template.Spec
+		Expect(spec.Tolerations).To(ConsistOf(corev1.Toleration{
+			Key: consts.NotConvergedTaintKey, Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}))
+
+		container := spec.Containers[0]
+		Expect(*container.SecurityContext.Privileged).To(BeFalse())
+		Expect(container.SecurityContext.Capabilities.Add).To(ContainElement(corev1.Capability("SYS_ADMIN")))
+
+		value, _ := envValue(container, "STRICT_CONVERGENCE")
+		Expect(value).To(Equal("true"))
+		value, _ = envValue(container, "BATCH_DISCOVERY")
+		Expect(value).To(Equal("true"))
+		value, _ = envValue(container, "IGNORE_PCI_ADDRESSES")
+		Expect(value).To(Equal("0000:3b:00.0,0000:3b:00.1"))
+		value, _ = envValue(container, "LOCAL_API_SOCKET")
+		Expect(value).To(Equal("/run/nic-configuration-operator/api.sock"))
+		value, _ = envValue(container, "LOCAL_API_SNAPSHOT")
+		Expect(value).To(Equal("/var/lib/nic-configuration-operator/local-api/devices.json"))
+		Expect(spec.Volumes).To(ContainElement(HaveField("Name", "local-api")))
+		Expect(spec.Volumes).To(ContainElement(HaveField("Name", "local-api-snapshot")))
+	})
+	It("should omit the disabled features", func() {
+		objects, err := RenderDeployment(DefaultDeploymentOptions())
+		Expect(err).NotTo(HaveOccurred())
+
+		spec := findObject[*appsv1.DaemonSet](objects).Spec.Template.Spec
+		Expect(spec.Tolerations).To(BeEmpty())
+		Expect(*spec.Containers[0].SecurityContext.Privileged).To(BeTrue())
+		Expect(spec.HostUsers).To(BeNil())
+		_, found := envValue(spec.Containers[0], "LOCAL_API_SOCKET")
+		Expect(found).To(BeFalse())
+		Expect(spec.Volumes).NotTo(ContainElement(HaveField("Name", "local-api")))
+	})
+	It("should render the config daemon in a user namespace without the host namespaces and the privileged mode", func() {
+		options := DefaultDeploymentOptions()
+		options.HostUsers = false
+		objects, err := RenderDeployment(options)
+		Expect(err).NotTo(HaveOccurred())
+
+		spec := findObject[*appsv1.DaemonSet](objects).Spec.Template.Spec
+		Expect(*spec.HostUsers).To(BeFalse())
+		Expect(spec.HostNetwork).To(BeFalse())
+		Expect(spec.HostPID).To(BeFalse())
+		Expect(*spec.Containers[0].SecurityContext.Privileged).To(BeFalse())
+		Expect(spec.Containers[0].SecurityContext.Capabilities.Add).To(ContainElement(corev1.Capability("SYS_ADMIN")))
+		Expect(spec.Containers[0].VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: "mst", MountPath: "/dev/mst"}))
+		Expect(spec.Volumes).NotTo(ContainElement(HaveField("Name", "proc")))
+	})
+	DescribeTable("should render the config daemon matching the chart",
+		func(configDaemonValues map[string]interface{}, configure func(options *DeploymentOptions)) {
+			chartDaemonSet := &appsv1.DaemonSet{}
+			Expect(yaml.Unmarshal(renderChartTemplate("config-daemon.yaml", map[string]interface{}{
+				"configDaemon": configDaemonValues,
+			}), chartDaemonSet)).To(Succeed())
+
+			options := DefaultDeploymentOptions()
+			configure(&options)
+			objects, err := RenderDeployment(options)
+			Expect(err).NotTo(HaveOccurred())
+			daemonSet := findObject[*appsv1.DaemonSet](objects)
+
+			chartSpec := chartDaemonSet.Spec.Template.Spec
+			spec := daemonSet.Spec.Template.Spec
+			Expect(spec.Containers[0].Env).To(Equal(chartSpec.Containers[0].Env))
+			Expect(spec.Containers[0].VolumeMounts).To(Equal(chartSpec.Containers[0].VolumeMounts))
+			Expect(spec.Volumes).To(Equal(chartSpec.Volumes))
+			Expect(spec.Containers[0].SecurityContext).To(Equal(chartSpec.Containers[0].SecurityContext))
+			Expect(spec.Tolerations).To(Equal(chartSpec.Tolerations))
+			Expect(spec.HostNetwork).To(Equal(chartSpec.HostNetwork))
+			Expect(spec.HostPID).To(Equal(chartSpec.HostPID))
+			Expect(spec.HostUsers).To(Equal(chartSpec.HostUsers))
+		},
+		Entry("with the default values", map[string]interface{}{}, func(options *DeploymentOptions) {}),
+		Entry("with all the features enabled", map[string]interface{}{
+			"strictConvergence":       true,
+			"batchDiscovery":          true,
+			"privileged":              false,
+			"restartSyncWindow":       "10m",
+			"deviceDiscoveryInterval": "1m",
+			"deepScanInterval":        "168h",
+			"ignorePCIAddresses":      []interface{}{"0000:3b:00.0", "0000:3b:00.1"},
+			"changelog": map[string]interface{}{
+				"sink": "s3",
+				"s3": map[string]interface{}{
+					"endpoint":          "https://s3.example.com",
+					"bucket":            "changelog",
+					"prefix":            "cluster-1/",
+					"credentialsSecret": "changelog-credentials",
+				},
+			},
+			"lifecycleEvents": map[string]interface{}{"sink": "nats", "endpoint": "tls://nats:4222", "credentialsSecret": "nats-credentials"},
+			"firmwareCache":   map[string]interface{}{"maxSize": "10Gi", "persistentVolumeClaim": "firmware-cache"},
+			"localAPI":        map[string]interface{}{"enabled": true},
+		}, func(options *DeploymentOptions) {
+			options.StrictConvergence = true
+			options.BatchDiscovery = true
+			options.Privileged = false
+			options.RestartSyncWindow = "10m"
+			options.DeviceDiscoveryInterval = "1m"
+			options.DeepScanInterval = "168h"
+			options.IgnorePCIAddresses = []string{"0000:3b:00.0", "0000:3b:00.1"}
+			options.ChangelogSink = "s3"
+			options.ChangelogS3Endpoint = "https://s3.example.com"
+			options.ChangelogS3Bucket = "changelog"
+			options.ChangelogS3Prefix = "cluster-1/"
+			options.ChangelogS3CredentialsSecret = "changelog-credentials"
+			options.LifecycleEventsSink = "nats"
+			options.LifecycleEventsEndpoint = "tls://nats:4222"
+			options.LifecycleEventsCredentialsSecret = "nats-credentials"
+			options.FirmwareCacheMaxSize = "10Gi"
+			options.FirmwareCachePersistentVolumeClaim = "firmware-cache"
+			options.LocalAPI = true
+		}),
+		Entry("in a user namespace", map[string]interface{}{"hostUsers": false}, func(options *DeploymentOptions) {
+			options.HostUsers = false
+		}),
+		Entry("with the configmap changelog and the http lifecycle events", map[string]interface{}{
+			"changelog":       map[string]interface{}{"sink": "configmap"},
+			"lifecycleEvents": map[string]interface{}{"sink": "http", "endpoint": "http://event-gateway:8080"},
+		}, func(options *DeploymentOptions) {
+			options.ChangelogSink = "configmap"
+			options.LifecycleEventsSink = "http"
+			options.LifecycleEventsEndpoint = "http://event-gateway:8080"
+		}),
+	)
+	It("should reject the deployment without images", func() {
+		options := DefaultDeploymentOptions()
+		options.OperatorImage = ""
+
+		_, err := RenderDeployment(options)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should write the manifests as a multi-document YAML stream from the command flags", func() {
+		out := &bytes.Buffer{}
+		err := Run(context.Background(), []string{"manifests", "-n", "network-operator",
+			"--operator-image", "registry.local/operator:v1", "--ignore-pci-addresses", "0000:3b:00.0"}, out)
+		Expect(err).NotTo(HaveOccurred())
+
+		documents := strings.Split(out.String(), "\n---\n")
+		objects, err := RenderDeployment(DefaultDeploymentOptions())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(documents).To(HaveLen(len(objects)))
+
+		deployment := &appsv1.Deployment{}
+		Expect(yaml.Unmarshal([]byte(documents[len(documents)-2]), deployment)).To(Succeed())
+		Expect(deployment.Namespace).To(Equal("network-operator"))
+		Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(Equal("registry.local/operator:v1"))
+
+		daemonSet := &appsv1.DaemonSet{}
+		Expect(yaml.Unmarshal([]byte(documents[len(documents)-1]), daemonSet)).To(Succeed())
+		value, _ := envValue(daemonSet.Spec.Template.Spec.Containers[0], "IGNORE_PCI_ADDRESSES")
+		Expect(value).To(Equal("0000:3b:00.0"))
+	})
+})
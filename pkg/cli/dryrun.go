@@ -0,0 +1,380 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/host"
+	"github.com/Mellanox/nic-configuration-operator/pkg/types"
+)
+
+const (
+	// DeviceSnapshotKind is the kind of the nv config snapshot of a single device
+	DeviceSnapshotKind = "NicDeviceSnapshot"
+	// DryRunReportKind is the kind of the structured dry-run report
+	DryRunReportKind = "NicConfigurationDryRun"
+
+	// DryRunInSync means that the nv config of the snapshot already matches the template
+	DryRunInSync = "InSync"
+	// DryRunPendingReboot means that the next boot config of the snapshot matches the template, only a reboot is required
+	DryRunPendingReboot = "PendingReboot"
+	// DryRunRebootRequired means that the nv config would be written and the device rebooted
+	DryRunRebootRequired = "RebootRequired"
+	// DryRunNotSelected means that the template's nicSelector doesn't select the device of the snapshot
+	DryRunNotSelected = "NotSelected"
+	// DryRunSpecError means that the template is invalid for the device of the snapshot
+	DryRunSpecError = "SpecError"
+)
+
+// DeviceSnapshot is the nv config of a single device captured at some point in time,
+// e.g. before a firmware upgrade, to validate templates against the previous state of the device
+type DeviceSnapshot struct {
+	metav1.TypeMeta `json:",inline"`
+	// Name of the NicDevice CR
+	Name string `json:"name"`
+	// Labels of the NicDevice CR, used by the deviceLabels selectors of the templates
+	Labels map[string]string `json:"labels,omitempty"`
+	// Time the snapshot was captured at
+	CapturedAt *metav1.Time `json:"capturedAt,omitempty"`
+	// Observed device information: type, firmware version, serial number, ports
+	Device v1alpha1.NicDeviceStatus `json:"device"`
+	// Default, current and next boot nv config of the device
+	NvConfig *SnapshotNvConfig `json:"nvConfig,omitempty"`
+	// Output of `mlxconfig -d <device> -e query`, can be given instead of nvConfig
+	QueryDump string `json:"queryDump,omitempty"`
+}
+
+// SnapshotNvConfig is the nv config of a device, values can contain both the string alias and the numeric value
+type SnapshotNvConfig struct {
+	Default  map[string][]string `json:"default"`
+	Current  map[string][]string `json:"current"`
+	NextBoot map[string][]string `json:"nextBoot"`
+}
+
+// DryRunReport is the outcome of validating a template against the device snapshots
+type DryRunReport struct {
+	metav1.TypeMeta `json:",inline"`
+	// Name of the NicConfigurationTemplate
+	Template string `json:"template"`
+	// Results in the order of the snapshots
+	Snapshots []SnapshotDryRun `json:"snapshots"`
+}
+
+// SnapshotDryRun is the outcome of validating a template against a single device snapshot
+type SnapshotDryRun struct {
+	// Name of the NicDevice CR of the snapshot
+	Name string `json:"name"`
+	// Time the snapshot was captured at
+	CapturedAt *metav1.Time `json:"capturedAt,omitempty"`
+	// Firmware version of the device at the capture time
+	FirmwareVersion string `json:"firmwareVersion"`
+	// Result: InSync, PendingReboot, RebootRequired, NotSelected or SpecError
+	Result string `json:"result"`
+	// Reason of the NotSelected and SpecError results
+	Message string `json:"message,omitempty"`
+	// ConfigUpdateNeeded is set if the nv config of the device would be written
+	ConfigUpdateNeeded bool `json:"configUpdateNeeded"`
+	// RebootNeeded is set if the device would require a reboot
+	RebootNeeded bool `json:"rebootNeeded"`
+	// Nv config parameters rendered from the template with their values in the snapshot
+	Parameters []ExplainedParameter `json:"parameters"`
+}
+
+// ReadTemplate reads a NicConfigurationTemplate manifest in the YAML or JSON format
+func ReadTemplate(r io.Reader) (*v1alpha1.NicConfigurationTemplate, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	template := &v1alpha1.NicConfigurationTemplate{}
+	err = yaml.Unmarshal(data, template)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	if template.Kind != "NicConfigurationTemplate" {
+		return nil, fmt.Errorf("unexpected kind %q, expected NicConfigurationTemplate", template.Kind)
+	}
+	if template.Spec.NicSelector == nil || template.Spec.Template == nil {
+		return nil, fmt.Errorf("template %s requires nicSelector and template", template.Name)
+	}
+	return template, nil
+}
+
+// ReadSnapshots reads the device snapshots, several snapshots can be given as YAML documents separated by ---
+// query dumps of the snapshots are parsed into their nv config
+func ReadSnapshots(r io.Reader) ([]DeviceSnapshot, error) {
+	snapshots := []DeviceSnapshot{}
+
+	decoder := utilyaml.NewYAMLOrJSONDecoder(r, 4096)
+	for {
+		snapshot := DeviceSnapshot{}
+		err := decoder.Decode(&snapshot)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid snapshot: %w", err)
+		}
+		// Empty documents, e.g. a leading ---
+		if snapshot.Kind == "" && snapshot.Name == "" {
+			continue
+		}
+
+		if snapshot.Kind != DeviceSnapshotKind {
+			return nil, fmt.Errorf("unexpected kind %q, expected %s", snapshot.Kind, DeviceSnapshotKind)
+		}
+		if snapshot.Name == "" || snapshot.Device.Type == "" {
+			return nil, errors.New("snapshot requires name and device type")
+		}
+		if snapshot.NvConfig == nil {
+			if snapshot.QueryDump == "" {
+				return nil, fmt.Errorf("snapshot %s requires nvConfig or queryDump", snapshot.Name)
+			}
+			snapshot.NvConfig, err = parseQueryDump(snapshot.QueryDump)
+			if err != nil {
+				return nil, fmt.Errorf("invalid query dump of snapshot %s: %w", snapshot.Name, err)
+			}
+		}
+
+		snapshots = append(snapshots, snapshot)
+	}
+
+	if len(snapshots) == 0 {
+		return nil, errors.New("no snapshots found in the input")
+	}
+	return snapshots, nil
+}
+
+// parseQueryDump parses the default, current and next boot values of the `mlxconfig -e query` output
+// values with string aliases are stored as both, e.g. True(1) -> [true 1], as reported by the config daemon
+func parseQueryDump(dump string) (*SnapshotNvConfig, error) {
+	nvConfig := &SnapshotNvConfig{Default: map[string][]string{}, Current: map[string][]string{}, NextBoot: map[string][]string{}}
+
+	inDump := false
+	scanner := bufio.NewScanner(strings.NewReader(dump))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if strings.HasPrefix(line, "Configurations:") {
+			inDump = true
+			continue
+		}
+		if strings.HasPrefix(line, "Device #") {
+			inDump = false
+			continue
+		}
+		if !inDump || line == "" {
+			continue
+		}
+
+		fields := columnSeparatorRegex.Split(strings.TrimSpace(strings.TrimPrefix(line, "*")), -1)
+		if len(fields) != 4 || !nvParamNameRegex.MatchString(fields[0]) {
+			continue
+		}
+		// Array parameters are listed by their indices in the dumps of the subsequent queries, e.g. `query PARAM[0..7]`
+		if strings.HasPrefix(fields[1], "Array[") {
+			continue
+		}
+
+		nvConfig.Default[fields[0]] = queryDumpValues(fields[1])
+		nvConfig.Current[fields[0]] = queryDumpValues(fields[2])
+		nvConfig.NextBoot[fields[0]] = queryDumpValues(fields[3])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(nvConfig.Current) == 0 {
+		return nil, errors.New("no nv config parameters found, the dump has to contain the default, current and next boot values of `mlxconfig -e query`")
+	}
+	return nvConfig, nil
+}
+
+func queryDumpValues(value string) []string {
+	match := valueInBracketsRegex.FindStringSubmatch(value)
+	if len(match) == 3 {
+		return []string{strings.ToLower(match[1]), strings.ToLower(match[2])}
+	}
+	return []string{value}
+}
+
+// CaptureSnapshot returns the snapshot of the device with the nv config queried from its firmware
+func CaptureSnapshot(device *v1alpha1.NicDevice, nvConfig types.NvConfigQuery, capturedAt time.Time) DeviceSnapshot {
+	return DeviceSnapshot{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: v1alpha1.GroupVersion.String(),
+			Kind:       DeviceSnapshotKind,
+		},
+		Name:       device.Name,
+		Labels:     device.Labels,
+		CapturedAt: &metav1.Time{Time: capturedAt},
+		Device:     *device.Status.DeepCopy(),
+		NvConfig: &SnapshotNvConfig{
+			Default:  maps.Clone(nvConfig.DefaultConfig),
+			Current:  maps.Clone(nvConfig.CurrentConfig),
+			NextBoot: maps.Clone(nvConfig.NextBootConfig),
+		},
+	}
+}
+
+// WriteSnapshots writes the snapshots as YAML documents separated by ---, as read by ReadSnapshots
+func WriteSnapshots(w io.Writer, snapshots []DeviceSnapshot) error {
+	for _, snapshot := range snapshots {
+		data, err := yaml.Marshal(snapshot)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "---\n%s", data)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nvConfigQuery returns a copy of the snapshot's nv config in the format of the config daemon's queries
+func (s *SnapshotNvConfig) nvConfigQuery() types.NvConfigQuery {
+	query := types.NewNvConfigQuery()
+	maps.Copy(query.DefaultConfig, s.Default)
+	maps.Copy(query.CurrentConfig, s.Current)
+	maps.Copy(query.NextBootConfig, s.NextBoot)
+	return query
+}
+
+// DryRunTemplate validates the template against each snapshot as if the snapshot was the current state of the device,
+// the devices are selected by the type, PCI addresses, serial numbers and labels of the template's nicSelector
+// node selectors are not evaluated
+func DryRunTemplate(template *v1alpha1.NicConfigurationTemplate, snapshots []DeviceSnapshot) DryRunReport {
+	report := DryRunReport{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: v1alpha1.GroupVersion.String(),
+			Kind:       DryRunReportKind,
+		},
+		Template:  template.Name,
+		Snapshots: make([]SnapshotDryRun, 0, len(snapshots)),
+	}
+
+	for _, snapshot := range snapshots {
+		report.Snapshots = append(report.Snapshots, dryRunSnapshot(template, snapshot))
+	}
+	return report
+}
+
+func dryRunSnapshot(template *v1alpha1.NicConfigurationTemplate, snapshot DeviceSnapshot) SnapshotDryRun {
+	result := SnapshotDryRun{
+		Name:            snapshot.Name,
+		CapturedAt:      snapshot.CapturedAt,
+		FirmwareVersion: snapshot.Device.FirmwareVersion,
+		Parameters:      []ExplainedParameter{},
+	}
+
+	device := &v1alpha1.NicDevice{
+		ObjectMeta: metav1.ObjectMeta{Name: snapshot.Name, Labels: snapshot.Labels},
+		Spec: v1alpha1.NicDeviceSpec{Configuration: &v1alpha1.NicDeviceConfigurationSpec{
+			ResetToDefault: template.Spec.ResetToDefault,
+			Template:       template.Spec.Template.DeepCopy(),
+		}},
+		Status: *snapshot.Device.DeepCopy(),
+	}
+
+	if !templateSelectsDevice(&template.Spec, device) {
+		result.Result = DryRunNotSelected
+		result.Message = fmt.Sprintf("nicSelector of template %s doesn't select device %s of type %s", template.Name, device.Name, device.Status.Type)
+		return result
+	}
+
+	dryRun, err := host.DryRunNvSpec(device, snapshot.NvConfig.nvConfigQuery())
+	if err != nil {
+		result.Result = DryRunSpecError
+		result.Message = err.Error()
+		return result
+	}
+
+	result.ConfigUpdateNeeded = dryRun.ConfigUpdateNeeded
+	result.RebootNeeded = dryRun.RebootNeeded
+	for _, param := range dryRun.Parameters {
+		result.Parameters = append(result.Parameters, ExplainedParameter{NvConfigParameterStatus: param, State: parameterState(param)})
+	}
+
+	switch {
+	case dryRun.ConfigUpdateNeeded:
+		result.Result = DryRunRebootRequired
+	case dryRun.RebootNeeded:
+		result.Result = DryRunPendingReboot
+	default:
+		result.Result = DryRunInSync
+	}
+	return result
+}
+
+// WriteJSON writes the report in the machine-readable JSON format
+func (r DryRunReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteText writes the report in the human-readable format, only the parameters that would change are listed
+func (r DryRunReport) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Template:\t%s\n\n", r.Template)
+	fmt.Fprintln(tw, "SNAPSHOT\tCAPTURED\tFIRMWARE\tRESULT\tMESSAGE")
+	for _, snapshot := range r.Snapshots {
+		capturedAt := "<unknown>"
+		if snapshot.CapturedAt != nil {
+			capturedAt = snapshot.CapturedAt.UTC().Format("2006-01-02T15:04:05Z")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", snapshot.Name, capturedAt, snapshot.FirmwareVersion, snapshot.Result, snapshot.Message)
+	}
+
+	for _, snapshot := range r.Snapshots {
+		if snapshot.Result != DryRunRebootRequired && snapshot.Result != DryRunPendingReboot {
+			continue
+		}
+
+		fmt.Fprintf(tw, "\nChanges of %s:\n", snapshot.Name)
+		if len(snapshot.Parameters) == 0 {
+			fmt.Fprintln(tw, "  <none>, nv config is reset to default")
+			continue
+		}
+		fmt.Fprintln(tw, "  NAME\tDESIRED\tCURRENT\tNEXT BOOT\tSTATE")
+		for _, param := range snapshot.Parameters {
+			if param.State == ParameterApplied {
+				continue
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", param.Name, param.DesiredValue,
+				formatValues(param.CurrentValues), formatValues(param.NextBootValues), param.State)
+		}
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,376 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/mock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+	"github.com/Mellanox/nic-configuration-operator/pkg/host/mocks"
+	"github.com/Mellanox/nic-configuration-operator/pkg/types"
+)
+
+const dryRunTemplate = `apiVersion: configuration.net.nvidia.com/v1alpha1
+kind: NicConfigurationTemplate
+metadata:
+  name: cx6dx-sriov
+spec:
+  nicSelector:
+    nicType: 101d
+  template:
+    numVfs: 8
+    linkType: Ethernet
+`
+
+const dryRunSnapshots = `---
+apiVersion: configuration.net.nvidia.com/v1alpha1
+kind: NicDeviceSnapshot
+name: node-a-101d-sn1
+capturedAt: "2024-09-14T10:00:00Z"
+device:
+  type: 101d
+  serialNumber: sn1
+  firmwareVersion: 22.41.1000
+  ports:
+  - pci: "0000:3b:00.0"
+    networkInterface: eth0
+queryDump: |
+  Device #1:
+  ----------
+
+  Device type:        ConnectX6DX
+  Name:               MCX623106AN-CDA_Ax
+
+  Configurations:                              Default         Current         Next Boot
+          SRIOV_EN                             False(0)        False(0)        False(0)
+          NUM_OF_VFS                           0               0               0
+          LINK_TYPE_P1                         ETH(2)          ETH(2)          ETH(2)
+  *       MAX_ACC_OUT_READ                     0               44              44
+---
+apiVersion: configuration.net.nvidia.com/v1alpha1
+kind: NicDeviceSnapshot
+name: node-a-101d-sn1
+capturedAt: "2024-10-14T10:00:00Z"
+device:
+  type: 101d
+  serialNumber: sn1
+  firmwareVersion: 22.42.1000
+  ports:
+  - pci: "0000:3b:00.0"
+nvConfig:
+  default:
+    SRIOV_EN: ["false", "0"]
+    NUM_OF_VFS: ["0"]
+    LINK_TYPE_P1: ["eth", "2"]
+  current:
+    SRIOV_EN: ["true", "1"]
+    NUM_OF_VFS: ["8"]
+    LINK_TYPE_P1: ["eth", "2"]
+  nextBoot:
+    SRIOV_EN: ["true", "1"]
+    NUM_OF_VFS: ["8"]
+    LINK_TYPE_P1: ["eth", "2"]
+---
+apiVersion: configuration.net.nvidia.com/v1alpha1
+kind: NicDeviceSnapshot
+name: node-b-1021-sn2
+device:
+  type: "1021"
+  serialNumber: sn2
+  firmwareVersion: 28.42.1000
+nvConfig:
+  default: {}
+  current:
+    NUM_OF_VFS: ["0"]
+  nextBoot:
+    NUM_OF_VFS: ["0"]
+`
+
+var _ = Describe("dry-run", func() {
+	var (
+		template  *v1alpha1.NicConfigurationTemplate
+		snapshots []DeviceSnapshot
+	)
+
+	BeforeEach(func() {
+		var err error
+		template, err = ReadTemplate(strings.NewReader(dryRunTemplate))
+		Expect(err).NotTo(HaveOccurred())
+		snapshots, err = ReadSnapshots(strings.NewReader(dryRunSnapshots))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("ReadSnapshots", func() {
+		It("should read the snapshots and parse their query dumps", func() {
+			Expect(snapshots).To(HaveLen(3))
+			Expect(snapshots[0].CapturedAt.Time).To(BeTemporally("==", time.Date(2024, 9, 14, 10, 0, 0, 0, time.UTC)))
+			Expect(snapshots[0].NvConfig.Default).To(Equal(map[string][]string{
+				"SRIOV_EN":         {"false", "0"},
+				"NUM_OF_VFS":       {"0"},
+				"LINK_TYPE_P1":     {"eth", "2"},
+				"MAX_ACC_OUT_READ": {"0"},
+			}))
+			Expect(snapshots[0].NvConfig.Current["MAX_ACC_OUT_READ"]).To(Equal([]string{"44"}))
+			Expect(snapshots[1].NvConfig.Current["SRIOV_EN"]).To(Equal([]string{"true", "1"}))
+		})
+
+		It("should reject invalid snapshots", func() {
+			_, err := ReadSnapshots(strings.NewReader("kind: NicDevice\nname: device\n"))
+			Expect(err).To(MatchError(ContainSubstring("unexpected kind")))
+
+			_, err = ReadSnapshots(strings.NewReader("kind: NicDeviceSnapshot\nname: device\ndevice:\n  type: 101d\n"))
+			Expect(err).To(MatchError("snapshot device requires nvConfig or queryDump"))
+
+			_, err = ReadSnapshots(strings.NewReader("kind: NicDeviceSnapshot\nname: device\ndevice:\n  type: 101d\nqueryDump: |\n  SRIOV_EN=1\n"))
+			Expect(err).To(MatchError(ContainSubstring("no nv config parameters found")))
+
+			_, err = ReadSnapshots(strings.NewReader("---\n"))
+			Expect(err).To(MatchError("no snapshots found in the input"))
+		})
+	})
+
+	Describe("DryRunTemplate", func() {
+		It("should report the changes and reboots of each snapshot", func() {
+			report := DryRunTemplate(template, snapshots)
+			Expect(report.Kind).To(Equal(DryRunReportKind))
+			Expect(report.Template).To(Equal("cx6dx-sriov"))
+			Expect(report.Snapshots).To(HaveLen(3))
+
+			previous := report.Snapshots[0]
+			Expect(previous.Result).To(Equal(DryRunRebootRequired))
+			Expect(previous.FirmwareVersion).To(Equal("22.41.1000"))
+			Expect(previous.ConfigUpdateNeeded).To(BeTrue())
+			Expect(previous.RebootNeeded).To(BeTrue())
+			Expect(previous.Parameters).To(ConsistOf(
+				ExplainedParameter{
+					NvConfigParameterStatus: v1alpha1.NvConfigParameterStatus{
+						Name: consts.LinkTypeP1Param, DesiredValue: "2", CurrentValues: []string{"eth", "2"}, NextBootValues: []string{"eth", "2"},
+						DeviceDefault: true},
+					State: ParameterApplied,
+				},
+				ExplainedParameter{
+					NvConfigParameterStatus: v1alpha1.NvConfigParameterStatus{
+						Name: consts.SriovNumOfVfsParam, DesiredValue: "8", CurrentValues: []string{"0"}, NextBootValues: []string{"0"}},
+					State: ParameterPendingApply,
+				},
+				ExplainedParameter{
+					NvConfigParameterStatus: v1alpha1.NvConfigParameterStatus{
+						Name: consts.SriovEnabledParam, DesiredValue: "1", CurrentValues: []string{"false", "0"}, NextBootValues: []string{"false", "0"}},
+					State: ParameterPendingApply,
+				},
+			))
+
+			Expect(report.Snapshots[1].Result).To(Equal(DryRunInSync))
+			Expect(report.Snapshots[1].RebootNeeded).To(BeFalse())
+
+			Expect(report.Snapshots[2].Result).To(Equal(DryRunNotSelected))
+			Expect(report.Snapshots[2].Message).To(ContainSubstring("of type 1021"))
+			Expect(report.Snapshots[2].Parameters).To(BeEmpty())
+		})
+
+		It("should report the snapshots whose next boot config already matches the template", func() {
+			snapshots[0].NvConfig.NextBoot[consts.SriovEnabledParam] = []string{"true", "1"}
+			snapshots[0].NvConfig.NextBoot[consts.SriovNumOfVfsParam] = []string{"8"}
+
+			result := DryRunTemplate(template, snapshots).Snapshots[0]
+			Expect(result.Result).To(Equal(DryRunPendingReboot))
+			Expect(result.ConfigUpdateNeeded).To(BeFalse())
+			Expect(result.RebootNeeded).To(BeTrue())
+		})
+
+		It("should report the spec errors", func() {
+			template.Spec.Template.Ports = []v1alpha1.PortConfigurationSpec{{Port: 2, LinkType: consts.Ethernet}}
+
+			result := DryRunTemplate(template, snapshots[:1]).Snapshots[0]
+			Expect(result.Result).To(Equal(DryRunSpecError))
+			Expect(result.Message).To(ContainSubstring("port count mismatch: template configures port 2 but device has 1 port(s)"))
+		})
+
+		It("should validate the link type of the devices that can't change it by the reported link layer", func() {
+			delete(snapshots[1].NvConfig.Default, consts.LinkTypeP1Param)
+			snapshots[1].Device.Ports[0].NetworkInterface = "ib0"
+
+			result := DryRunTemplate(template, snapshots[1:2]).Snapshots[0]
+			Expect(result.Result).To(Equal(DryRunInSync))
+
+			snapshots[1].Device.Ports[0].LinkLayer = consts.RdmaLinkLayerInfiniband
+			result = DryRunTemplate(template, snapshots[1:2]).Snapshots[0]
+			Expect(result.Result).To(Equal(DryRunSpecError))
+			Expect(result.Message).To(ContainSubstring("should be: " + consts.Infiniband))
+		})
+
+		It("should not modify the snapshots", func() {
+			template.Spec.ResetToDefault = true
+
+			result := DryRunTemplate(template, snapshots[:1]).Snapshots[0]
+			Expect(result.Result).To(Equal(DryRunRebootRequired))
+			Expect(result.Parameters).To(BeEmpty())
+			Expect(snapshots[0].NvConfig.Default).To(HaveLen(4))
+			Expect(snapshots[0].Device.Ports[0].NetworkInterface).To(Equal("eth0"))
+		})
+	})
+
+	Describe("report", func() {
+		It("should list only the parameters that would change", func() {
+			out := &bytes.Buffer{}
+			Expect(DryRunTemplate(template, snapshots).WriteText(out)).To(Succeed())
+
+			text := out.String()
+			Expect(text).To(ContainSubstring("Template:  cx6dx-sriov"))
+			Expect(text).To(MatchRegexp(`node-a-101d-sn1\s+2024-09-14T10:00:00Z\s+22.41.1000\s+RebootRequired`))
+			Expect(text).To(MatchRegexp(`node-a-101d-sn1\s+2024-10-14T10:00:00Z\s+22.42.1000\s+InSync`))
+			Expect(text).To(MatchRegexp(`node-b-1021-sn2\s+<unknown>\s+28.42.1000\s+NotSelected`))
+			Expect(text).To(MatchRegexp(`NUM_OF_VFS\s+8\s+0\s+0\s+PendingApply`))
+			Expect(text).To(MatchRegexp(`SRIOV_EN\s+1\s+false/0\s+false/0\s+PendingApply`))
+			Expect(text).NotTo(ContainSubstring("LINK_TYPE_P1"))
+			Expect(strings.Count(text, "Changes of")).To(Equal(1))
+		})
+	})
+
+	Describe("runCapture", func() {
+		var (
+			out       *bytes.Buffer
+			hostUtils *mocks.HostUtils
+			opts      *globalOptions
+		)
+
+		BeforeEach(func() {
+			scheme := runtime.NewScheme()
+			Expect(v1alpha1.AddToScheme(scheme)).To(Succeed())
+
+			devices := []client.Object{}
+			for _, device := range []struct{ name, node, pci string }{
+				{"node-a-101d-sn1", "node-a", "0000:3b:00.0"},
+				{"node-a-101d-sn3", "node-a", "0000:d8:00.0"},
+				{"node-b-101d-sn2", "node-b", "0000:3b:00.0"},
+			} {
+				devices = append(devices, &v1alpha1.NicDevice{
+					ObjectMeta: metav1.ObjectMeta{Name: device.name, Namespace: "nic-configuration-operator", Labels: map[string]string{"role": "storage"}},
+					Status: v1alpha1.NicDeviceStatus{
+						Node: device.node, Type: "101d", FirmwareVersion: "22.41.1000",
+						Ports: []v1alpha1.NicDevicePortSpec{{PCI: device.pci, NetworkInterface: "eth0"}},
+					},
+				})
+			}
+
+			out = &bytes.Buffer{}
+			hostUtils = &mocks.HostUtils{}
+			opts = &globalOptions{
+				stdout:    out,
+				client:    fake.NewClientBuilder().WithScheme(scheme).WithObjects(devices...).Build(),
+				hostUtils: hostUtils,
+			}
+		})
+
+		It("should capture the snapshots of the node's devices readable by dry-run", func() {
+			query := types.NewNvConfigQuery()
+			query.DefaultConfig[consts.SriovNumOfVfsParam] = []string{"0"}
+			query.CurrentConfig[consts.SriovNumOfVfsParam] = []string{"0"}
+			query.NextBootConfig[consts.SriovNumOfVfsParam] = []string{"8"}
+			hostUtils.On("QueryNvConfig", mock.Anything, "0000:3b:00.0").Return(query, nil)
+			hostUtils.On("QueryNvConfig", mock.Anything, "0000:d8:00.0").Return(types.NewNvConfigQuery(), nil)
+
+			Expect(runCapture(context.TODO(), opts, []string{"-n", "nic-configuration-operator", "--node", "node-a"})).To(Succeed())
+
+			captured, err := ReadSnapshots(out)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(captured).To(HaveLen(2))
+			Expect(captured[0].Name).To(Equal("node-a-101d-sn1"))
+			Expect(captured[0].Labels).To(Equal(map[string]string{"role": "storage"}))
+			Expect(captured[0].CapturedAt).NotTo(BeNil())
+			Expect(captured[0].Device.FirmwareVersion).To(Equal("22.41.1000"))
+			Expect(captured[0].NvConfig.NextBoot).To(Equal(map[string][]string{consts.SriovNumOfVfsParam: {"8"}}))
+			Expect(captured[1].Name).To(Equal("node-a-101d-sn3"))
+		})
+
+		It("should capture only the given devices", func() {
+			hostUtils.On("QueryNvConfig", mock.Anything, "0000:d8:00.0").Return(types.NewNvConfigQuery(), nil)
+
+			Expect(runCapture(context.TODO(), opts, []string{"node-a-101d-sn3", "-n", "nic-configuration-operator", "--node", "node-a"})).To(Succeed())
+			Expect(strings.Count(out.String(), "kind: "+DeviceSnapshotKind)).To(Equal(1))
+
+			out.Reset()
+			err := runCapture(context.TODO(), opts, []string{"node-a-101d-sn3", "node-b-101d-sn2", "-n", "nic-configuration-operator", "--node", "node-a"})
+			Expect(err).To(MatchError("only 1 of the 2 devices found on node node-a"))
+		})
+
+		It("should fail if the nv config can't be queried", func() {
+			hostUtils.On("QueryNvConfig", mock.Anything, "0000:3b:00.0").Return(types.NewNvConfigQuery(), errors.New("mstconfig failed"))
+
+			err := runCapture(context.TODO(), opts, []string{"-n", "nic-configuration-operator", "--node", "node-b"})
+			Expect(err).To(MatchError("failed to query nv config of device node-b-101d-sn2: mstconfig failed"))
+			Expect(out.String()).To(BeEmpty())
+
+			err = runCapture(context.TODO(), opts, []string{"-n", "nic-configuration-operator", "--node", "node-c"})
+			Expect(err).To(MatchError("no NicDevices of node node-c found in namespace nic-configuration-operator"))
+		})
+	})
+
+	Describe("runDryRun", func() {
+		var (
+			dir string
+			out *bytes.Buffer
+		)
+
+		BeforeEach(func() {
+			dir = GinkgoT().TempDir()
+			Expect(os.WriteFile(filepath.Join(dir, "template.yaml"), []byte(dryRunTemplate), 0600)).To(Succeed())
+			out = &bytes.Buffer{}
+		})
+
+		It("should print the report in the JSON format", func() {
+			opts := &globalOptions{stdin: strings.NewReader(dryRunSnapshots), stdout: out}
+			Expect(runDryRun(context.TODO(), opts, []string{"-f", filepath.Join(dir, "template.yaml"), "--snapshots", "-", "-o", "json"})).To(Succeed())
+
+			report := DryRunReport{}
+			Expect(json.Unmarshal(out.Bytes(), &report)).To(Succeed())
+			Expect(report.Snapshots).To(HaveLen(3))
+			Expect(report.Snapshots[0].Result).To(Equal(DryRunRebootRequired))
+		})
+
+		It("should fail if the template is invalid for some of the snapshots", func() {
+			invalid := strings.Replace(dryRunTemplate, "linkType: Ethernet", "linkType: Ethernet\n    ports: [{port: 2, linkType: Ethernet}]", 1)
+			Expect(os.WriteFile(filepath.Join(dir, "template.yaml"), []byte(invalid), 0600)).To(Succeed())
+
+			opts := &globalOptions{stdin: strings.NewReader(dryRunSnapshots), stdout: out}
+			err := runDryRun(context.TODO(), opts, []string{"-f", filepath.Join(dir, "template.yaml"), "--snapshots", "-"})
+			Expect(err).To(MatchError("template cx6dx-sriov is invalid for 2 of 3 snapshots"))
+			Expect(out.String()).To(ContainSubstring(DryRunSpecError))
+		})
+
+		It("should require the template and the snapshots", func() {
+			opts := &globalOptions{stdin: strings.NewReader(""), stdout: out}
+			Expect(runDryRun(context.TODO(), opts, []string{"-f", "-"})).To(MatchError(ContainSubstring("usage")))
+			Expect(runDryRun(context.TODO(), opts, []string{"-f", "-", "--snapshots", "-"})).To(MatchError(ContainSubstring("only one")))
+		})
+	})
+})
@@ -0,0 +1,325 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	structuralschema "k8s.io/apiextensions-apiserver/pkg/apiserver/schema"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/schema/pruning"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/host"
+)
+
+const (
+	// DeviceExplanationKind is the kind of the structured explain report
+	DeviceExplanationKind = "NicDeviceExplanation"
+
+	// ParameterApplied means that desired value of the parameter matches the current firmware configuration
+	ParameterApplied = "Applied"
+	// ParameterPendingReboot means that desired value of the parameter is set for the next boot only
+	ParameterPendingReboot = "PendingReboot"
+	// ParameterPendingApply means that desired value of the parameter is not yet set in the firmware
+	ParameterPendingApply = "PendingApply"
+)
+
+// DeviceExplanation is a report merging the device spec, rendered nv config parameters and conditions
+type DeviceExplanation struct {
+	metav1.TypeMeta `json:",inline"`
+	// Name of the NicDevice CR
+	Name string `json:"name"`
+	// Namespace of the NicDevice CR
+	Namespace string `json:"namespace"`
+	// Observed device information, nv config parameters and conditions are reported separately
+	Device v1alpha1.NicDeviceStatus `json:"device"`
+	// Configuration spec of the device, nil if device doesn't match any template
+	Spec *v1alpha1.NicDeviceConfigurationSpec `json:"spec,omitempty"`
+	// Nv config parameters rendered from the spec with their firmware values
+	Parameters []ExplainedParameter `json:"parameters"`
+	// Nv config parameters whose current and next boot values differ, the reason for a pending reboot
+	PendingReboot []v1alpha1.NvConfigParameterDiff `json:"pendingReboot"`
+	// Conditions observed for the device
+	Conditions []metav1.Condition `json:"conditions"`
+}
+
+// ExplainedParameter describes the state of a single rendered nv config parameter
+type ExplainedParameter struct {
+	v1alpha1.NvConfigParameterStatus `json:",inline"`
+	// State of the parameter: Applied, PendingReboot or PendingApply
+	State string `json:"state"`
+}
+
+// ExplainDevice builds the explain report for the given device
+func ExplainDevice(device *v1alpha1.NicDevice) DeviceExplanation {
+	explanation := DeviceExplanation{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: v1alpha1.GroupVersion.String(),
+			Kind:       DeviceExplanationKind,
+		},
+		Name:          device.Name,
+		Namespace:     device.Namespace,
+		Device:        *device.Status.DeepCopy(),
+		Spec:          device.Spec.Configuration,
+		Parameters:    []ExplainedParameter{},
+		PendingReboot: device.Status.PendingRebootParameters,
+		Conditions:    device.Status.Conditions,
+	}
+	explanation.Device.Conditions = nil
+	explanation.Device.NvConfigParameters = nil
+	explanation.Device.PendingRebootParameters = nil
+
+	if explanation.PendingReboot == nil {
+		explanation.PendingReboot = []v1alpha1.NvConfigParameterDiff{}
+	}
+
+	if explanation.Conditions == nil {
+		explanation.Conditions = []metav1.Condition{}
+	}
+
+	for _, param := range device.Status.NvConfigParameters {
+		explanation.Parameters = append(explanation.Parameters, ExplainedParameter{
+			NvConfigParameterStatus: param,
+			State:                   parameterState(param),
+		})
+	}
+
+	return explanation
+}
+
+func parameterState(param v1alpha1.NvConfigParameterStatus) string {
+	if !host.NvParamValueMatches(param.Name, param.DesiredValue, param.NextBootValues) {
+		return ParameterPendingApply
+	}
+	if !host.NvParamValueMatches(param.Name, param.DesiredValue, param.CurrentValues) {
+		return ParameterPendingReboot
+	}
+	return ParameterApplied
+}
+
+// WriteJSON writes the report in the machine-readable JSON format, validated against the schema of the NicDevice CRD
+func (e DeviceExplanation) WriteJSON(w io.Writer) error {
+	err := e.Validate()
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(e)
+}
+
+// Validate returns an error if the JSON report doesn't match its schema built from the NicDevice CRD embedded in the CLI,
+// fields missing from the schema are reported as well
+func (e DeviceExplanation) Validate() error {
+	schema, err := explanationSchema()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	report := map[string]interface{}{}
+	err = json.Unmarshal(data, &report)
+	if err != nil {
+		return err
+	}
+
+	validator, _, err := validation.NewSchemaValidator(schema)
+	if err != nil {
+		return err
+	}
+	errs := validation.ValidateCustomResource(nil, report, validator)
+
+	structural, err := structuralschema.NewStructural(schema)
+	if err != nil {
+		return err
+	}
+	for _, unknownField := range pruning.PruneWithOptions(report, structural, false,
+		structuralschema.UnknownFieldPathOptions{TrackUnknownFieldPaths: true}) {
+		errs = append(errs, field.Forbidden(field.NewPath(unknownField), "unknown field"))
+	}
+
+	if len(errs) != 0 {
+		return fmt.Errorf("explain report doesn't match the schema of the NicDevice CRD: %w", errs.ToAggregate())
+	}
+	return nil
+}
+
+// explanationSchema builds the schema of the explain report from the NicDevice CRD embedded from config/crd/bases,
+// the report embeds the device's status and configuration spec, parameters extend the nv config parameters with their state
+func explanationSchema() (*apiextensions.JSONSchemaProps, error) {
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	err := readGeneratedYAML(path.Join(generatedCRDsDir, "configuration.net.nvidia.com_nicdevices.yaml"), crd)
+	if err != nil {
+		return nil, err
+	}
+	if len(crd.Spec.Versions) != 1 || crd.Spec.Versions[0].Schema == nil {
+		return nil, fmt.Errorf("unexpected versions of the NicDevice CRD")
+	}
+
+	crdSchema := &apiextensions.JSONSchemaProps{}
+	err = apiextensionsv1.Convert_v1_JSONSchemaProps_To_apiextensions_JSONSchemaProps(
+		crd.Spec.Versions[0].Schema.OpenAPIV3Schema, crdSchema, nil)
+	if err != nil {
+		return nil, err
+	}
+	status := crdSchema.Properties["status"]
+	configuration := crdSchema.Properties["spec"].Properties["configuration"]
+
+	parameter := *status.Properties["nvConfigParameters"].Items.Schema
+	parameter.Properties = maps.Clone(parameter.Properties)
+	parameter.Properties["state"] = apiextensions.JSONSchemaProps{
+		Type: "string",
+		Enum: []apiextensions.JSON{ParameterApplied, ParameterPendingReboot, ParameterPendingApply},
+	}
+	parameter.Required = append(slices.Clone(parameter.Required), "state")
+
+	stringSchema := apiextensions.JSONSchemaProps{Type: "string"}
+	return &apiextensions.JSONSchemaProps{
+		Type:     "object",
+		Required: []string{"apiVersion", "kind", "name", "namespace", "device", "parameters", "pendingReboot", "conditions"},
+		Properties: map[string]apiextensions.JSONSchemaProps{
+			"apiVersion":    stringSchema,
+			"kind":          stringSchema,
+			"name":          stringSchema,
+			"namespace":     stringSchema,
+			"device":        status,
+			"spec":          configuration,
+			"parameters":    {Type: "array", Items: &apiextensions.JSONSchemaPropsOrArray{Schema: &parameter}},
+			"pendingReboot": status.Properties["pendingRebootParameters"],
+			"conditions":    status.Properties["conditions"],
+		},
+	}, nil
+}
+
+// WriteText writes the report in the human-readable format
+func (e DeviceExplanation) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Name:\t%s\n", e.Name)
+	fmt.Fprintf(tw, "Namespace:\t%s\n", e.Namespace)
+	fmt.Fprintf(tw, "Node:\t%s\n", e.Device.Node)
+	fmt.Fprintf(tw, "Type:\t%s\n", e.Device.Type)
+	fmt.Fprintf(tw, "Serial Number:\t%s\n", e.Device.SerialNumber)
+	fmt.Fprintf(tw, "Part Number:\t%s\n", e.Device.PartNumber)
+	fmt.Fprintf(tw, "PSID:\t%s\n", e.Device.PSID)
+	fmt.Fprintf(tw, "Firmware Version:\t%s\n", e.Device.FirmwareVersion)
+	fmt.Fprintf(tw, "Firmware Security:\t%s\n", formatFirmwareSecurity(e.Device.FirmwareSecurity))
+	fmt.Fprintf(tw, "PCIe Link:\t%s\n", formatPciLink(e.Device.PciLink))
+
+	fmt.Fprintln(tw, "\nPorts:")
+	fmt.Fprintln(tw, "  PCI\tNETWORK INTERFACE\tRDMA INTERFACE")
+	for _, port := range e.Device.Ports {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\n", port.PCI, port.NetworkInterface, port.RdmaInterface)
+	}
+
+	fmt.Fprintln(tw, "\nSpec:")
+	if e.Spec == nil {
+		fmt.Fprintln(tw, "  <none>, device doesn't match any configuration template")
+	} else {
+		spec, err := json.MarshalIndent(e.Spec, "  ", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(tw, "  %s\n", spec)
+	}
+
+	fmt.Fprintln(tw, "\nParameters:")
+	if len(e.Parameters) == 0 {
+		fmt.Fprintln(tw, "  <none>, parameters are reported by the config daemon after spec validation")
+	} else {
+		fmt.Fprintln(tw, "  NAME\tDESIRED\tCURRENT\tNEXT BOOT\tSTATE")
+		for _, param := range e.Parameters {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", param.Name, param.DesiredValue,
+				formatValues(param.CurrentValues), formatValues(param.NextBootValues), param.State)
+		}
+	}
+
+	fmt.Fprintln(tw, "\nPending Reboot:")
+	if len(e.PendingReboot) == 0 {
+		fmt.Fprintln(tw, "  <none>")
+	} else {
+		fmt.Fprintln(tw, "  NAME\tCURRENT\tNEXT BOOT")
+		for _, param := range e.PendingReboot {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", param.Name, formatValues(param.CurrentValues), formatValues(param.NextBootValues))
+		}
+	}
+
+	fmt.Fprintln(tw, "\nConditions:")
+	if len(e.Conditions) == 0 {
+		fmt.Fprintln(tw, "  <none>")
+	} else {
+		fmt.Fprintln(tw, "  TYPE\tSTATUS\tREASON\tLAST TRANSITION\tMESSAGE")
+		for _, condition := range e.Conditions {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", condition.Type, condition.Status, condition.Reason,
+				condition.LastTransitionTime.UTC().Format("2006-01-02T15:04:05Z"), condition.Message)
+		}
+	}
+
+	return tw.Flush()
+}
+
+func formatValues(values []string) string {
+	if len(values) == 0 {
+		return "<unset>"
+	}
+	return strings.Join(values, "/")
+}
+
+func formatPciLink(link *v1alpha1.PciLinkStatus) string {
+	if link == nil {
+		return "<unknown>"
+	}
+
+	description := fmt.Sprintf("%s x%d", link.Speed, link.Width)
+	if link.Degraded {
+		description += fmt.Sprintf(" (degraded, device supports %s x%d)", link.MaxSpeed, link.MaxWidth)
+	}
+	return description
+}
+
+func formatFirmwareSecurity(security *v1alpha1.FirmwareSecurityStatus) string {
+	if security == nil {
+		return "<unknown>"
+	}
+
+	description := "unsigned firmware accepted"
+	if security.SecureFirmware {
+		description = "signed firmware only"
+	}
+	if len(security.Attributes) != 0 {
+		description += fmt.Sprintf(" (%s)", strings.Join(security.Attributes, ", "))
+	}
+	if security.SecurityVersion != nil {
+		description += fmt.Sprintf(", security version %d", *security.SecurityVersion)
+	}
+	return description
+}
@@ -0,0 +1,157 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+)
+
+var _ = Describe("explain", func() {
+	var device *v1alpha1.NicDevice
+
+	BeforeEach(func() {
+		device = &v1alpha1.NicDevice{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-cx7-serial", Namespace: "nic-configuration-operator"},
+			Spec: v1alpha1.NicDeviceSpec{Configuration: &v1alpha1.NicDeviceConfigurationSpec{
+				Template: &v1alpha1.ConfigurationTemplateSpec{NumVfs: 8, LinkType: consts.Ethernet},
+			}},
+			Status: v1alpha1.NicDeviceStatus{
+				Node:         "node",
+				Type:         "1021",
+				SerialNumber: "serial",
+				FirmwareSecurity: &v1alpha1.FirmwareSecurityStatus{
+					SecureFirmware:  true,
+					Attributes:      []string{"secure-fw"},
+					SecurityVersion: ptr.To(1),
+				},
+				Ports: []v1alpha1.NicDevicePortSpec{{PCI: "0000:3b:00.0", NetworkInterface: "eth0"}},
+				PciLink: &v1alpha1.PciLinkStatus{
+					Speed: "8.0 GT/s PCIe", Width: 16, MaxSpeed: "16.0 GT/s PCIe", MaxWidth: 16, Degraded: true,
+				},
+				Conditions: []metav1.Condition{{
+					Type:               consts.ConfigUpdateInProgressCondition,
+					Status:             metav1.ConditionTrue,
+					Reason:             consts.PendingRebootReason,
+					LastTransitionTime: metav1.Now().Rfc3339Copy(),
+				}},
+				NvConfigParameters: []v1alpha1.NvConfigParameterStatus{
+					{Name: consts.LinkTypeP1Param, DesiredValue: "2", CurrentValues: []string{"eth", "2"}, NextBootValues: []string{"eth", "2"}},
+					{Name: consts.SriovNumOfVfsParam, DesiredValue: "8", CurrentValues: []string{"0"}, NextBootValues: []string{"8"}},
+					{Name: consts.SriovEnabledParam, DesiredValue: "1", CurrentValues: []string{"false", "0"}, NextBootValues: []string{"false", "0"}},
+				},
+				PendingRebootParameters: []v1alpha1.NvConfigParameterDiff{
+					{Name: consts.SriovNumOfVfsParam, CurrentValues: []string{"0"}, NextBootValues: []string{"8"}},
+				},
+			},
+		}
+	})
+
+	Describe("ExplainDevice", func() {
+		It("should report state of each rendered parameter", func() {
+			explanation := ExplainDevice(device)
+
+			Expect(explanation.Kind).To(Equal(DeviceExplanationKind))
+			Expect(explanation.APIVersion).To(Equal(v1alpha1.GroupVersion.String()))
+			Expect(explanation.Spec).To(Equal(device.Spec.Configuration))
+			Expect(explanation.Conditions).To(Equal(device.Status.Conditions))
+			Expect(explanation.Device.NvConfigParameters).To(BeNil())
+			Expect(explanation.Device.Conditions).To(BeNil())
+			Expect(explanation.Device.PendingRebootParameters).To(BeNil())
+			Expect(explanation.PendingReboot).To(Equal(device.Status.PendingRebootParameters))
+
+			Expect(explanation.Parameters).To(HaveLen(3))
+			Expect(explanation.Parameters[0].State).To(Equal(ParameterApplied))
+			Expect(explanation.Parameters[1].State).To(Equal(ParameterPendingReboot))
+			Expect(explanation.Parameters[2].State).To(Equal(ParameterPendingApply))
+		})
+	})
+
+	Describe("Run", func() {
+		var (
+			stdout *bytes.Buffer
+			opts   *globalOptions
+		)
+
+		BeforeEach(func() {
+			scheme := runtime.NewScheme()
+			Expect(v1alpha1.AddToScheme(scheme)).To(Succeed())
+
+			stdout = &bytes.Buffer{}
+			opts = &globalOptions{
+				stdout: stdout,
+				client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(device).Build(),
+			}
+		})
+
+		It("should print the report in text format", func() {
+			Expect(runExplain(context.Background(), opts, []string{"node-cx7-serial", "-n", "nic-configuration-operator"})).To(Succeed())
+
+			Expect(stdout.String()).To(ContainSubstring("Serial Number:"))
+			Expect(stdout.String()).To(MatchRegexp(`Firmware Security:\s+signed firmware only \(secure-fw\), security version 1\n`))
+			Expect(stdout.String()).To(MatchRegexp(`PCIe Link:\s+8.0 GT/s PCIe x16 \(degraded, device supports 16.0 GT/s PCIe x16\)\n`))
+			Expect(stdout.String()).To(MatchRegexp(`NUM_OF_VFS\s+8\s+0\s+8\s+PendingReboot`))
+			Expect(stdout.String()).To(ContainSubstring(consts.PendingRebootReason))
+			Expect(stdout.String()).To(MatchRegexp(`Pending Reboot:\n\s+NAME\s+CURRENT\s+NEXT BOOT\n\s+NUM_OF_VFS\s+0\s+8`))
+		})
+
+		It("should print the report in json format", func() {
+			Expect(runExplain(context.Background(), opts, []string{"-n", "nic-configuration-operator", "-o", "json", "node-cx7-serial"})).To(Succeed())
+
+			explanation := DeviceExplanation{}
+			Expect(json.Unmarshal(stdout.Bytes(), &explanation)).To(Succeed())
+			Expect(explanation.Name).To(Equal("node-cx7-serial"))
+			Expect(explanation.Parameters).To(HaveLen(3))
+			Expect(explanation.Parameters[1].Name).To(Equal(consts.SriovNumOfVfsParam))
+			Expect(explanation.Parameters[1].State).To(Equal(ParameterPendingReboot))
+		})
+
+		It("should print the json report matching the schema of the NicDevice CRD", func() {
+			Expect(runExplain(context.Background(), opts, []string{"-n", "nic-configuration-operator", "-o", "json", "node-cx7-serial"})).To(Succeed())
+
+			explanation := DeviceExplanation{}
+			Expect(json.Unmarshal(stdout.Bytes(), &explanation)).To(Succeed())
+			Expect(explanation.Validate()).To(Succeed())
+		})
+
+		It("should not print the json report not matching the schema", func() {
+			explanation := ExplainDevice(device)
+			explanation.Parameters[0].State = "Unknown"
+
+			Expect(explanation.WriteJSON(stdout)).To(MatchError(ContainSubstring("explain report doesn't match the schema of the NicDevice CRD")))
+			Expect(stdout.String()).To(BeEmpty())
+		})
+
+		It("should fail on unknown output format", func() {
+			Expect(runExplain(context.Background(), opts, []string{"node-cx7-serial", "-o", "yaml"})).To(MatchError(ContainSubstring("unsupported output format")))
+		})
+
+		It("should fail if device doesn't exist", func() {
+			Expect(runExplain(context.Background(), opts, []string{"missing", "-n", "nic-configuration-operator"})).NotTo(Succeed())
+		})
+	})
+})
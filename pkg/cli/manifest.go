@@ -0,0 +1,313 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+)
+
+const (
+	// HardwareManifestKind is the kind of the hardware inventory manifest
+	HardwareManifestKind = "NicHardwareManifest"
+	// HardwareManifestPayloadType is the DSSE payload type of the signed hardware manifest
+	HardwareManifestPayloadType = "application/vnd.nvidia.nic-hardware-manifest+json"
+)
+
+// HardwareManifest is an inventory of the NICs in the cluster with their firmware and applied configuration
+type HardwareManifest struct {
+	metav1.TypeMeta `json:",inline"`
+	// Time the manifest was generated at
+	GeneratedAt metav1.Time `json:"generatedAt"`
+	// Namespace of the NicDevice CRs the manifest was generated from
+	Namespace string `json:"namespace"`
+	// Devices sorted by node and serial number
+	Devices []HardwareManifestEntry `json:"devices"`
+}
+
+// HardwareManifestEntry describes a single NIC in the manifest
+type HardwareManifestEntry struct {
+	// Node the device is installed on
+	Node string `json:"node"`
+	// Name of the NicDevice CR
+	Name string `json:"name"`
+	// Type of the device, e.g. 1021
+	Type string `json:"type"`
+	// Part number of the device, identifies the model
+	PartNumber string `json:"partNumber"`
+	// Serial number of the device
+	SerialNumber string `json:"serialNumber"`
+	// PSID of the device
+	PSID string `json:"psid"`
+	// Firmware version running on the device
+	FirmwareVersion string `json:"firmwareVersion"`
+	// PCI addresses of the device's ports
+	PCIAddresses []string `json:"pciAddresses"`
+	// Applied NicConfigurationTemplate, nil if no template is applied to the device
+	Template *HardwareManifestTemplate `json:"template,omitempty"`
+}
+
+// HardwareManifestTemplate identifies the revision of the template applied to the device
+type HardwareManifestTemplate struct {
+	// Name of the NicConfigurationTemplate
+	Name string `json:"name"`
+	// Generation of the NicConfigurationTemplate applied to the device
+	Generation string `json:"generation,omitempty"`
+}
+
+// SignedHardwareManifest is the hardware manifest signed in a DSSE envelope, see
+// https://github.com/secure-systems-lab/dsse/blob/master/envelope.md
+// the payload is the JSON encoding of the HardwareManifest, the signatures cover its pre-authentication encoding
+type SignedHardwareManifest struct {
+	// PayloadType is HardwareManifestPayloadType
+	PayloadType string `json:"payloadType"`
+	// Payload is the base64 encoded manifest
+	Payload string `json:"payload"`
+	// Signatures of the payload
+	Signatures []ManifestSignature `json:"signatures"`
+}
+
+// ManifestSignature is a signature of the DSSE envelope
+type ManifestSignature struct {
+	// KeyID is the hex encoded sha256 digest of the PKIX encoding of the public key
+	KeyID string `json:"keyid,omitempty"`
+	// Sig is the base64 encoded signature, ASN.1 encoded for ECDSA keys and PKCS #1 v1.5 for RSA keys
+	Sig string `json:"sig"`
+}
+
+// BuildManifest builds the hardware manifest of the given devices
+func BuildManifest(devices []v1alpha1.NicDevice, namespace string, now time.Time) HardwareManifest {
+	manifest := HardwareManifest{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: v1alpha1.GroupVersion.String(),
+			Kind:       HardwareManifestKind,
+		},
+		GeneratedAt: metav1.NewTime(now.UTC().Truncate(time.Second)),
+		Namespace:   namespace,
+		Devices:     make([]HardwareManifestEntry, 0, len(devices)),
+	}
+
+	for _, device := range devices {
+		entry := HardwareManifestEntry{
+			Node:            device.Status.Node,
+			Name:            device.Name,
+			Type:            device.Status.Type,
+			PartNumber:      device.Status.PartNumber,
+			SerialNumber:    device.Status.SerialNumber,
+			PSID:            device.Status.PSID,
+			FirmwareVersion: device.Status.FirmwareVersion,
+			PCIAddresses:    make([]string, 0, len(device.Status.Ports)),
+		}
+		for _, port := range device.Status.Ports {
+			entry.PCIAddresses = append(entry.PCIAddresses, port.PCI)
+		}
+		if templateName := device.Labels[consts.TemplateLabel]; templateName != "" {
+			entry.Template = &HardwareManifestTemplate{
+				Name:       templateName,
+				Generation: device.Annotations[consts.TemplateGenerationAnnotation],
+			}
+		}
+		manifest.Devices = append(manifest.Devices, entry)
+	}
+
+	sort.Slice(manifest.Devices, func(i, j int) bool {
+		if manifest.Devices[i].Node != manifest.Devices[j].Node {
+			return manifest.Devices[i].Node < manifest.Devices[j].Node
+		}
+		return manifest.Devices[i].SerialNumber < manifest.Devices[j].SerialNumber
+	})
+
+	return manifest
+}
+
+// SignManifest signs the manifest with the PEM encoded ECDSA or RSA private key
+func SignManifest(manifest HardwareManifest, privateKeyPEM []byte) (SignedHardwareManifest, error) {
+	signer, err := parseSigningKey(privateKeyPEM)
+	if err != nil {
+		return SignedHardwareManifest{}, err
+	}
+	keyID, err := manifestKeyID(signer.Public())
+	if err != nil {
+		return SignedHardwareManifest{}, err
+	}
+
+	payload, err := json.Marshal(manifest)
+	if err != nil {
+		return SignedHardwareManifest{}, err
+	}
+	digest := sha256.Sum256(preAuthEncoding(HardwareManifestPayloadType, payload))
+
+	signature, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return SignedHardwareManifest{}, fmt.Errorf("failed to sign the manifest: %w", err)
+	}
+
+	return SignedHardwareManifest{
+		PayloadType: HardwareManifestPayloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures:  []ManifestSignature{{KeyID: keyID, Sig: base64.StdEncoding.EncodeToString(signature)}},
+	}, nil
+}
+
+// VerifyManifest verifies the signed manifest with the PEM encoded ECDSA or RSA public key and returns the manifest
+// the manifest is verified if any of its signatures is made with the key
+func VerifyManifest(signed SignedHardwareManifest, publicKeyPEM []byte) (HardwareManifest, error) {
+	block, _ := pem.Decode(publicKeyPEM)
+	if block == nil {
+		return HardwareManifest{}, errors.New("verification key is not in the PEM format")
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return HardwareManifest{}, fmt.Errorf("invalid verification key: %w", err)
+	}
+
+	if signed.PayloadType != HardwareManifestPayloadType {
+		return HardwareManifest{}, fmt.Errorf("unexpected payload type %q of the signed manifest", signed.PayloadType)
+	}
+	payload, err := base64.StdEncoding.DecodeString(signed.Payload)
+	if err != nil {
+		return HardwareManifest{}, fmt.Errorf("invalid payload of the signed manifest: %w", err)
+	}
+	digest := sha256.Sum256(preAuthEncoding(signed.PayloadType, payload))
+
+	verified := false
+	for _, signature := range signed.Signatures {
+		sig, err := base64.StdEncoding.DecodeString(signature.Sig)
+		if err != nil {
+			continue
+		}
+		switch key := publicKey.(type) {
+		case *ecdsa.PublicKey:
+			verified = ecdsa.VerifyASN1(key, digest[:], sig)
+		case *rsa.PublicKey:
+			verified = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) == nil
+		default:
+			return HardwareManifest{}, fmt.Errorf("unsupported verification key type %T", key)
+		}
+		if verified {
+			break
+		}
+	}
+	if !verified {
+		return HardwareManifest{}, errors.New("manifest is not signed with the verification key")
+	}
+
+	manifest := HardwareManifest{}
+	err = json.Unmarshal(payload, &manifest)
+	if err != nil {
+		return HardwareManifest{}, fmt.Errorf("invalid payload of the signed manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// preAuthEncoding returns the DSSE pre-authentication encoding of the payload, which is signed instead of the payload itself
+func preAuthEncoding(payloadType string, payload []byte) []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+}
+
+// manifestKeyID returns the hex encoded sha256 digest of the PKIX encoding of the public key
+func manifestKeyID(publicKey crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256(der)
+	return hex.EncodeToString(digest[:]), nil
+}
+
+// parseSigningKey parses the PKCS #8, SEC 1 or PKCS #1 encoded private key
+func parseSigningKey(privateKeyPEM []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(privateKeyPEM)
+	if block == nil {
+		return nil, errors.New("signing key is not in the PEM format")
+	}
+
+	var key any
+	var err error
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid signing key: %w", err)
+	}
+
+	switch key := key.(type) {
+	case *ecdsa.PrivateKey:
+		return key, nil
+	case *rsa.PrivateKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported signing key type %T", key)
+	}
+}
+
+// WriteJSON writes the signed manifest in the indented JSON format
+func (m SignedHardwareManifest) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(m)
+}
+
+// WriteText writes the manifest's devices in the human-readable format
+func (m HardwareManifest) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Namespace:\t%s\nGenerated:\t%s\n\n", m.Namespace, m.GeneratedAt.UTC().Format("2006-01-02T15:04:05Z"))
+	fmt.Fprintln(tw, "NODE\tNAME\tPART NUMBER\tSERIAL NUMBER\tFIRMWARE\tTEMPLATE")
+	for _, device := range m.Devices {
+		template := "<none>"
+		if device.Template != nil {
+			template = device.Template.Name
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", device.Node, device.Name, device.PartNumber, device.SerialNumber,
+			device.FirmwareVersion, template)
+	}
+	return tw.Flush()
+}
+
+// ReadSignedManifest reads the signed manifest written by WriteJSON
+func ReadSignedManifest(r io.Reader) (SignedHardwareManifest, error) {
+	signed := SignedHardwareManifest{}
+	err := json.NewDecoder(r).Decode(&signed)
+	if err != nil {
+		return SignedHardwareManifest{}, fmt.Errorf("failed to read the signed manifest: %w", err)
+	}
+	return signed, nil
+}
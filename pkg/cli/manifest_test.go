@@ -0,0 +1,282 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+)
+
+// manifestDigest returns the sha256 digest of the DSSE pre-authentication encoding of the envelope's payload
+func manifestDigest(signed SignedHardwareManifest) []byte {
+	payload, err := base64.StdEncoding.DecodeString(signed.Payload)
+	Expect(err).NotTo(HaveOccurred())
+	digest := sha256.Sum256([]byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(signed.PayloadType), signed.PayloadType, len(payload), payload)))
+	return digest[:]
+}
+
+// publicKeyPEM returns the PKIX encoding of the public key in the PEM format
+func publicKeyPEM(key crypto.PublicKey) []byte {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	Expect(err).NotTo(HaveOccurred())
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+var _ = Describe("manifest", func() {
+	const namespace = "nic-configuration-operator"
+
+	var devices []v1alpha1.NicDevice
+
+	BeforeEach(func() {
+		devices = []v1alpha1.NicDevice{
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "node-b-cx7-serial2",
+					Namespace:   namespace,
+					Labels:      map[string]string{consts.TemplateLabel: "cx7-template"},
+					Annotations: map[string]string{consts.TemplateGenerationAnnotation: "3"},
+				},
+				Status: v1alpha1.NicDeviceStatus{
+					Node:            "node-b",
+					Type:            "1021",
+					SerialNumber:    "serial2",
+					PartNumber:      "MCX755106AS-HEAT",
+					PSID:            "MT_0000000834",
+					FirmwareVersion: "28.39.1002",
+					Ports:           []v1alpha1.NicDevicePortSpec{{PCI: "0000:3b:00.0"}, {PCI: "0000:3b:00.1"}},
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "node-a-cx6-serial1", Namespace: namespace},
+				Status: v1alpha1.NicDeviceStatus{
+					Node:            "node-a",
+					Type:            "101d",
+					SerialNumber:    "serial1",
+					PartNumber:      "MCX623106AN-CDAT",
+					PSID:            "MT_0000000359",
+					FirmwareVersion: "22.39.1002",
+					Ports:           []v1alpha1.NicDevicePortSpec{{PCI: "0000:af:00.0"}},
+				},
+			},
+		}
+	})
+
+	Describe("BuildManifest", func() {
+		It("should report devices sorted by node with their applied templates", func() {
+			now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+			manifest := BuildManifest(devices, namespace, now)
+
+			Expect(manifest.Kind).To(Equal(HardwareManifestKind))
+			Expect(manifest.APIVersion).To(Equal(v1alpha1.GroupVersion.String()))
+			Expect(manifest.GeneratedAt.Time).To(Equal(now))
+			Expect(manifest.Namespace).To(Equal(namespace))
+
+			Expect(manifest.Devices).To(HaveLen(2))
+			Expect(manifest.Devices[0].Node).To(Equal("node-a"))
+			Expect(manifest.Devices[0].Template).To(BeNil())
+			Expect(manifest.Devices[1]).To(Equal(HardwareManifestEntry{
+				Node:            "node-b",
+				Name:            "node-b-cx7-serial2",
+				Type:            "1021",
+				PartNumber:      "MCX755106AS-HEAT",
+				SerialNumber:    "serial2",
+				PSID:            "MT_0000000834",
+				FirmwareVersion: "28.39.1002",
+				PCIAddresses:    []string{"0000:3b:00.0", "0000:3b:00.1"},
+				Template:        &HardwareManifestTemplate{Name: "cx7-template", Generation: "3"},
+			}))
+		})
+	})
+
+	Describe("SignManifest", func() {
+		var manifest HardwareManifest
+
+		BeforeEach(func() {
+			manifest = BuildManifest(devices, namespace, time.Now())
+		})
+
+		It("should sign the manifest with an ECDSA key in a DSSE envelope", func() {
+			key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+			der, err := x509.MarshalPKCS8PrivateKey(key)
+			Expect(err).NotTo(HaveOccurred())
+
+			signed, err := SignManifest(manifest, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(signed.PayloadType).To(Equal(HardwareManifestPayloadType))
+			payload, err := base64.StdEncoding.DecodeString(signed.Payload)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(json.Marshal(manifest)).To(Equal(payload))
+
+			Expect(signed.Signatures).To(HaveLen(1))
+			publicKeyDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+			Expect(err).NotTo(HaveOccurred())
+			keyID := sha256.Sum256(publicKeyDER)
+			Expect(signed.Signatures[0].KeyID).To(Equal(hex.EncodeToString(keyID[:])))
+			signature, err := base64.StdEncoding.DecodeString(signed.Signatures[0].Sig)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ecdsa.VerifyASN1(&key.PublicKey, manifestDigest(signed), signature)).To(BeTrue())
+		})
+
+		It("should sign the manifest with an RSA key", func() {
+			key, err := rsa.GenerateKey(rand.Reader, 2048)
+			Expect(err).NotTo(HaveOccurred())
+
+			signed, err := SignManifest(manifest, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+			Expect(err).NotTo(HaveOccurred())
+
+			signature, err := base64.StdEncoding.DecodeString(signed.Signatures[0].Sig)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, manifestDigest(signed), signature)).To(Succeed())
+		})
+
+		It("should fail if the key is not in the PEM format", func() {
+			_, err := SignManifest(manifest, []byte("not a key"))
+			Expect(err).To(MatchError(ContainSubstring("not in the PEM format")))
+		})
+	})
+
+	Describe("VerifyManifest", func() {
+		var (
+			key    *ecdsa.PrivateKey
+			signed SignedHardwareManifest
+		)
+
+		BeforeEach(func() {
+			var err error
+			key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+			der, err := x509.MarshalECPrivateKey(key)
+			Expect(err).NotTo(HaveOccurred())
+			signed, err = SignManifest(BuildManifest(devices, namespace, time.Now()), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should return the manifest signed with the key", func() {
+			manifest, err := VerifyManifest(signed, publicKeyPEM(&key.PublicKey))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(manifest.Devices).To(HaveLen(2))
+			Expect(manifest.Devices[1].SerialNumber).To(Equal("serial2"))
+		})
+		It("should refuse the manifest signed with another key", func() {
+			otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = VerifyManifest(signed, publicKeyPEM(&otherKey.PublicKey))
+			Expect(err).To(MatchError("manifest is not signed with the verification key"))
+		})
+		It("should refuse the changed manifest", func() {
+			payload, err := base64.StdEncoding.DecodeString(signed.Payload)
+			Expect(err).NotTo(HaveOccurred())
+			signed.Payload = base64.StdEncoding.EncodeToString(bytes.Replace(payload, []byte("28.39.1002"), []byte("28.40.1000"), 1))
+
+			_, err = VerifyManifest(signed, publicKeyPEM(&key.PublicKey))
+			Expect(err).To(MatchError("manifest is not signed with the verification key"))
+		})
+		It("should refuse the envelope of another payload type", func() {
+			signed.PayloadType = "application/vnd.in-toto+json"
+
+			_, err := VerifyManifest(signed, publicKeyPEM(&key.PublicKey))
+			Expect(err).To(MatchError(ContainSubstring("unexpected payload type")))
+		})
+	})
+
+	Describe("Run", func() {
+		var (
+			stdout  *bytes.Buffer
+			opts    *globalOptions
+			key     *ecdsa.PrivateKey
+			keyPath string
+		)
+
+		BeforeEach(func() {
+			scheme := runtime.NewScheme()
+			Expect(v1alpha1.AddToScheme(scheme)).To(Succeed())
+
+			stdout = &bytes.Buffer{}
+			opts = &globalOptions{
+				stdout: stdout,
+				client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(&devices[0], &devices[1]).Build(),
+			}
+
+			var err error
+			key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+			der, err := x509.MarshalECPrivateKey(key)
+			Expect(err).NotTo(HaveOccurred())
+			keyPath = filepath.Join(GinkgoT().TempDir(), "key.pem")
+			Expect(os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600)).To(Succeed())
+		})
+
+		It("should print the signed manifest of all devices in the namespace", func() {
+			Expect(runManifest(context.Background(), opts, []string{"--signing-key", keyPath, "-n", namespace})).To(Succeed())
+
+			signed, err := ReadSignedManifest(stdout)
+			Expect(err).NotTo(HaveOccurred())
+			manifest, err := VerifyManifest(signed, publicKeyPEM(&key.PublicKey))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(manifest.Devices).To(HaveLen(2))
+			Expect(manifest.Devices[1].Template.Name).To(Equal("cx7-template"))
+		})
+
+		It("should verify the exported manifest", func() {
+			Expect(runManifest(context.Background(), opts, []string{"--signing-key", keyPath, "-n", namespace})).To(Succeed())
+			manifestPath := filepath.Join(GinkgoT().TempDir(), "manifest.json")
+			Expect(os.WriteFile(manifestPath, stdout.Bytes(), 0644)).To(Succeed())
+			publicKeyPath := filepath.Join(GinkgoT().TempDir(), "key.pub")
+			Expect(os.WriteFile(publicKeyPath, publicKeyPEM(&key.PublicKey), 0644)).To(Succeed())
+
+			stdout.Reset()
+			Expect(runVerifyManifest(context.Background(), opts, []string{"--public-key", publicKeyPath, "-f", manifestPath})).To(Succeed())
+			Expect(stdout.String()).To(ContainSubstring("Manifest signature verified"))
+			Expect(stdout.String()).To(MatchRegexp(`node-b\s+node-b-cx7-serial2\s+MCX755106AS-HEAT\s+serial2\s+28.39.1002\s+cx7-template`))
+
+			otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(publicKeyPath, publicKeyPEM(&otherKey.PublicKey), 0644)).To(Succeed())
+			Expect(runVerifyManifest(context.Background(), opts, []string{"--public-key", publicKeyPath, "-f", manifestPath})).
+				To(MatchError("manifest is not signed with the verification key"))
+		})
+
+		It("should fail without a signing key", func() {
+			Expect(runManifest(context.Background(), opts, []string{"-n", namespace})).To(MatchError(ContainSubstring("usage")))
+		})
+	})
+})
@@ -17,10 +17,57 @@ package consts
 
 const (
 	MellanoxVendor = "15b3"
+	// VirtioVendor is the PCI vendor of the virtio devices, the virtio-net devices emulated by NVIDIA NICs have the Mellanox subsystem vendor
+	VirtioVendor = "1af4"
 
 	Ethernet   = "Ethernet"
 	Infiniband = "Infiniband"
 
+	// RdmaLinkLayerInfiniband is the link layer of the InfiniBand ports reported by the RDMA devices
+	RdmaLinkLayerInfiniband = "InfiniBand"
+
+	DisruptionReboot  = "reboot"
+	DisruptionFwReset = "fwReset"
+	DisruptionAuto    = "auto"
+
+	RolloutStrategyAll         = "all"
+	RolloutStrategyFingerprint = "fingerprint"
+
+	RolloutPhaseCanary     = "Canary"
+	RolloutPhaseRollingOut = "RollingOut"
+	RolloutPhaseCompleted  = "Completed"
+	RolloutPhaseHalted     = "Halted"
+
+	PciPerformancePresetDefault = "default"
+	PciPerformancePresetAmd     = "amd"
+	// AmdMaxAccOutRead is the MAX_ACC_OUT_READ value recommended for RoCE on AMD EPYC platforms
+	AmdMaxAccOutRead = 44
+
+	GpuDirectPresetNone = "none"
+	GpuDirectPresetRdma = "rdma"
+
+	DisruptiveOperationInProgress = "InProgress"
+	DisruptiveOperationPending    = "Pending"
+
+	WorkloadKindDaemonSet  = "DaemonSet"
+	WorkloadKindDeployment = "Deployment"
+
+	RestartStrategyRestartPods    = "restartPods"
+	RestartStrategyRolloutRestart = "rolloutRestart"
+
+	RuntimeConfigPolicyImmediate        = "Immediate"
+	RuntimeConfigPolicyOnWorkloadAttach = "OnWorkloadAttach"
+
+	FirmwareUpdatePhaseDownloading        = "Downloading"
+	FirmwareUpdatePhaseFlashing           = "Flashing"
+	FirmwareUpdatePhaseAwaitingActivation = "AwaitingActivation"
+
+	OperationPhaseRendering      = "Rendering"
+	OperationPhaseApplying       = "Applying"
+	OperationPhaseAwaitingReboot = "AwaitingReboot"
+	OperationPhaseVerifying      = "Verifying"
+	OperationPhaseDone           = "Done"
+
 	ConfigUpdateInProgressCondition     = "ConfigUpdateInProgress"
 	FimwareConfigMatchCondition         = "FirmwareConfigMatch"
 	IncorrectSpecReason                 = "IncorrectSpec"
@@ -29,8 +76,60 @@ const (
 	NonVolatileConfigUpdateFailedReason = "NonVolatileConfigUpdateFailed"
 	RuntimeConfigUpdateFailedReason     = "RuntimeConfigUpdateFailed"
 	UpdateSuccessfulReason              = "UpdateSuccessful"
+	DeviceToolHangReason                = "DeviceToolHang"
 	SpecValidationFailed                = "SpecValidationFailed"
 	FirmwareError                       = "FirmwareError"
+	FirmwareResetReason                 = "FirmwareReset"
+	FirmwareResetFallbackReason         = "FirmwareResetFallback"
+	DelegatedToOtherHostReason          = "DelegatedToOtherHost"
+	EswitchAccessDeniedReason           = "EswitchAccessDenied"
+	NvConfigChurnReason                 = "NvConfigChurn"
+	RepresentorConfigFailedReason       = "RepresentorConfigFailed"
+	OperationResumedReason              = "OperationResumed"
+	AtsNotSupportedReason               = "AtsNotSupported"
+	NonConvergingReason                 = "NonConverging"
+	PortCountersResetReason             = "PortCountersReset"
+	FirmwareUpdateFailedReason          = "FirmwareUpdateFailed"
+	FirmwareBurnedReason                = "FirmwareBurned"
+	BFBInstalledReason                  = "BFBInstalled"
+	NetworkInterfaceRenamedReason       = "NetworkInterfaceRenamed"
+	FirmwareMismatchReason              = "FirmwareMismatch"
+	PendingActivationWindowReason       = "PendingActivationWindow"
+	BlockedByPDBReason                  = "BlockedByPDB"
+	ConfigOwnershipDeniedReason         = "ConfigOwnershipDenied"
+	RolledBackReason                    = "RolledBack"
+	ConsistencyGroupPendingReason       = "ConsistencyGroupPending"
+	VerificationFailedReason            = "VerificationFailed"
+	FirmwareRejectedReason              = "FirmwareRejected"
+	WorkloadRestartedReason             = "WorkloadRestarted"
+	WorkloadRestartFailedReason         = "WorkloadRestartFailed"
+	FailureDiagnosticsReason            = "FailureDiagnostics"
+	WorkloadAttachedReason              = "WorkloadAttached"
+	NvParamNotSupportedReason           = "NvParamNotSupported"
+	RolloutHaltedReason                 = "RolloutHalted"
+	FabricFeatureNotSupportedReason     = "FabricFeatureNotSupported"
+
+	SecurityAdvisoryCondition = "SecurityAdvisory"
+	AffectedByAdvisoryReason  = "AffectedByAdvisory"
+	NoKnownAdvisoriesReason   = "NoKnownAdvisories"
+
+	HealthWarningCondition    = "HealthWarning"
+	OverheatingReason         = "Overheating"
+	HealthReporterErrorReason = "HealthReporterError"
+	DeviceHealthyReason       = "DeviceHealthy"
+
+	DegradedCondition   = "Degraded"
+	VpdReadFailedReason = "VpdReadFailed"
+
+	QosConflictCondition       = "QosConflict"
+	ConflictingQosConfigReason = "ConflictingQosConfig"
+	QosConflictClearedReason   = "QosConflictCleared"
+	NoQosConflictReason        = "NoQosConflict"
+
+	HealthReporterStateError = "error"
+	// DefaultTemperatureWarningThreshold is the ASIC temperature in degrees Celsius from which the HealthWarning condition is reported,
+	// below the thermal shutdown of the ConnectX adapters
+	DefaultTemperatureWarningThreshold = 105
 
 	DeviceConfigSpecEmptyReason = "DeviceConfigSpecEmpty"
 	DeviceFwMatchReason         = "DeviceFirmwareConfigMatch"
@@ -40,14 +139,94 @@ const (
 	SerialNumberPrefix    = "sn:"
 	FirmwareVersionPrefix = "fw version:"
 	PSIDPrefix            = "psid:"
+	FlashPartNumberPrefix = "part number:"
+	BaseGUIDPrefix        = "base guid:"
+	SecurityAttrsPrefix   = "security attributes:"
+	SecurityVerPrefix     = "security ver:"
 	LinkStatsPrefix       = "lnksta"
 	MaxReadReqPrefix      = "maxreadreq"
 	TrustStatePrefix      = "priority trust state:"
 	PfcEnabledPrefix      = "enabled"
+	TcPrefix              = "tc:"
+	TcBandwidthPrefix     = "bw:"
+
+	// TrafficClassCount is the number of traffic classes configured by mlnx_qos
+	TrafficClassCount = 8
+
+	// ECN reaction point and notification point directories of the network interface's sysfs ecn directory
+	EcnReactionPointDir     = "roce_rp"
+	EcnNotificationPointDir = "roce_np"
+
+	// DCQCN parameters, relative to the network interface's sysfs ecn directory
+	DcqcnRpgAiRateParam               = "roce_rp/rpg_ai_rate"
+	DcqcnRpgHaiRateParam              = "roce_rp/rpg_hai_rate"
+	DcqcnRpgTimeResetParam            = "roce_rp/rpg_time_reset"
+	DcqcnRpgByteResetParam            = "roce_rp/rpg_byte_reset"
+	DcqcnRpgThresholdParam            = "roce_rp/rpg_threshold"
+	DcqcnRpgMinRateParam              = "roce_rp/rpg_min_rate"
+	DcqcnRpgGdParam                   = "roce_rp/rpg_gd"
+	DcqcnRateReduceMonitorPeriodParam = "roce_rp/rate_reduce_monitor_period"
+	DcqcnInitialAlphaValueParam       = "roce_rp/initial_alpha_value"
+	DcqcnMinTimeBetweenCnpsParam      = "roce_np/min_time_between_cnps"
 
 	NetClass = 0x02
 
+	EswitchModeLegacy    = "legacy"
+	EswitchModeSwitchdev = "switchdev"
+
+	DevlinkParamFlowSteeringMode = "flow_steering_mode"
+	FlowSteeringModeSmfs         = "smfs"
+	FlowSteeringModeDmfs         = "dmfs"
+
+	EthtoolFeatureLro     = "large-receive-offload"
+	EthtoolFeatureGro     = "generic-receive-offload"
+	EthtoolFeatureTso     = "tcp-segmentation-offload"
+	EthtoolFeatureRxGroHw = "rx-gro-hw"
+
 	LastAppliedStateAnnotation = "lastAppliedState"
+	NodeProvisioningAnnotation = "configuration.net.nvidia.com/provisioning"
+	// NodeReadyObservedAnnotation is set on the node by the config daemon once the node reached Ready for the first time,
+	// the configuration isn't held on the subsequent NotReady transitions, even after the config daemon restarts
+	NodeReadyObservedAnnotation = "configuration.net.nvidia.com/node-ready-observed"
+	// IgnorePCIAddressesAnnotation contains a comma-separated list of PCI addresses on the node that should never be discovered or configured
+	IgnorePCIAddressesAnnotation = "configuration.net.nvidia.com/ignore-pci-addresses"
+	// IgnoredPCIAddressesAnnotation is set by the config daemon and reports the effective list of PCI addresses excluded from discovery
+	IgnoredPCIAddressesAnnotation = "configuration.net.nvidia.com/ignored-pci-addresses"
+	// RescanAnnotation set on the node or on one of its NicDevices, e.g. to now, makes the config daemon discover the devices of the node
+	// right away, the config daemon removes it once the discovery is triggered
+	RescanAnnotation = "configuration.net.nvidia.com/rescan"
+	// RevalidateAnnotation is set on the NicDevice by the operator to the name and generation of the NicDeviceRevalidation selecting it,
+	// the config daemon validates the device regardless of the state memoized for it and removes the annotation
+	RevalidateAnnotation = "configuration.net.nvidia.com/revalidate"
+	// NotConvergedTaintKey is set on the node by the config daemon in the strict convergence mode
+	// until all devices on the node are configured according to their spec
+	NotConvergedTaintKey = "nic-config.nvidia.com/not-converged"
+	// ConfigHashAnnotation is set on the node by the config daemon to a short hash of the configuration applied to its devices,
+	// it changes with every applied change
+	ConfigHashAnnotation = "configuration.net.nvidia.com/config-hash"
+	// ConvergedStateAnnotation is set on the NicDevice by the config daemon to a hash of the state the device converged in,
+	// converged devices with an unchanged state are re-validated gradually after the config daemon restarts
+	ConvergedStateAnnotation = "configuration.net.nvidia.com/converged-state"
+	// TemplateLabel is set on the NicDevice to the name of the NicConfigurationTemplate applied to it
+	TemplateLabel = "configuration.net.nvidia.com/template"
+	// TemplateGenerationAnnotation is set on the NicDevice to the generation of the applied NicConfigurationTemplate,
+	// every template edit updates the device and triggers its re-validation on the node
+	TemplateGenerationAnnotation = "configuration.net.nvidia.com/template-generation"
+	// HardwareFingerprintLabel is set on the node to the fingerprint of the model, PSID and firmware version of its NICs
+	HardwareFingerprintLabel = "configuration.net.nvidia.com/hardware-fingerprint"
+	// RestartedAtAnnotation is set on the pod template of a workload to restart it, same as kubectl rollout restart does
+	RestartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+	// ReconfiguredAtAnnotation is set on the NicDevice by the config daemon to the time the new configuration of the device was applied
+	ReconfiguredAtAnnotation = "configuration.net.nvidia.com/reconfigured-at"
+	// PortSpeedAnnotation is set on the NicDevice by the config daemon to the last known speed of its ports, e.g. 100G,
+	// the speed values of the template are resolved by it while the links of the ports are down
+	PortSpeedAnnotation = "configuration.net.nvidia.com/port-speed"
+	// PostConfigurationHookAnnotation is set on the NicDevice by the operator to the consts.ReconfiguredAtAnnotation of the device
+	// once the post configuration hook of its template ran for the new configuration
+	PostConfigurationHookAnnotation = "configuration.net.nvidia.com/post-configuration-hook"
+	// FirmwareResetGenerationAnnotation is set on the NicDevice by the config daemon to the spec generation activated with a FW reset,
+	// the FW reset isn't repeated for the same generation, also after a restart of the config daemon
+	FirmwareResetGenerationAnnotation = "configuration.net.nvidia.com/firmware-reset-generation"
 
 	NvParamFalse              = "0"
 	NvParamTrue               = "1"
@@ -55,11 +234,19 @@ const (
 	NvParamLinkTypeEthernet   = "2"
 	NvParamZero               = "0"
 
+	// RedactedNvParamValue replaces the values of the nv config parameters set from Secrets in the device status
+	RedactedNvParamValue = "<redacted>"
+
 	SriovEnabledParam        = "SRIOV_EN"
 	SriovNumOfVfsParam       = "NUM_OF_VFS"
+	NumVfMsixParam           = "NUM_VF_MSIX"
+	DynamicVfMsixTableParam  = "DYNAMIC_VF_MSIX_TABLE"
 	LinkTypeP1Param          = "LINK_TYPE_P1"
 	LinkTypeP2Param          = "LINK_TYPE_P2"
 	MaxAccOutReadParam       = "MAX_ACC_OUT_READ"
+	PciWrOrderingParam       = "PCI_WR_ORDERING"
+	PciGenParam              = "PCI_GEN"
+	PciWidthParam            = "PCI_WIDTH"
 	RoceCcPrioMaskP1Param    = "ROCE_CC_PRIO_MASK_P1"
 	RoceCcPrioMaskP2Param    = "ROCE_CC_PRIO_MASK_P2"
 	CnpDscpP1Param           = "CNP_DSCP_P1"
@@ -68,18 +255,108 @@ const (
 	Cnp802pPrioP2Param       = "CNP_802P_PRIO_P2"
 	AtsEnabledParam          = "ATS_ENABLED"
 	AdvancedPCISettingsParam = "ADVANCED_PCI_SETTINGS"
+	BootOptionRomEnP1Param   = "BOOT_OPTION_ROM_EN_P1"
+	BootOptionRomEnP2Param   = "BOOT_OPTION_ROM_EN_P2"
+	ExpRomPxeEnableParam     = "EXP_ROM_PXE_ENABLE"
+	ExpRomUefiX86EnableParam = "EXP_ROM_UEFI_x86_ENABLE"
+	BootVlanEnP1Param        = "BOOT_VLAN_EN_P1"
+	BootVlanEnP2Param        = "BOOT_VLAN_EN_P2"
+	BootVlanP1Param          = "BOOT_VLAN_P1"
+	BootVlanP2Param          = "BOOT_VLAN_P2"
+	BootRetryCntP1Param      = "BOOT_RETRY_CNT_P1"
+	BootRetryCntP2Param      = "BOOT_RETRY_CNT_P2"
+	RealTimeClockEnableParam = "REAL_TIME_CLOCK_ENABLE"
+	FlexParserProfileParam   = "FLEX_PARSER_PROFILE_ENABLE"
+	ProgParseGraphParam      = "PROG_PARSE_GRAPH"
+	LogDcrHashTableSizeParam = "LOG_DCR_HASH_TABLE_SIZE"
+	DcrLifoSizeParam         = "DCR_LIFO_SIZE"
+
+	// Parameter names of the Spectrum-X feature set of the ConnectX-7 and later firmware
+	RoceAdaptiveRoutingEnParam = "ROCE_ADAPTIVE_ROUTING_EN"
+	RdmaSelectiveRepeatEnParam = "RDMA_SELECTIVE_REPEAT_EN"
+	UserProgrammableCcParam    = "USER_PROGRAMMABLE_CC"
+
+	// Parameter names of the BlueField firmware selecting the operating mode of the DPU
+	InternalCpuModelParam         = "INTERNAL_CPU_MODEL"
+	InternalCpuOffloadEngineParam = "INTERNAL_CPU_OFFLOAD_ENGINE"
 
 	SecondPortPrefix = "P2"
 
+	ConnectX6DxDeviceID = "101d"
+	ConnectX7DeviceID   = "1021"
+	ConnectX8DeviceID   = "1023"
+	BlueField2DeviceID  = "a2d6"
+	BlueField3DeviceID  = "a2dc"
+
+	// SecureFirmwareAttribute is reported by mstflint for devices accepting only signed firmware images
+	SecureFirmwareAttribute = "secure-fw"
+	// DevFirmwareAttribute is reported by mstflint for firmware signed with the development keys
+	DevFirmwareAttribute = "dev"
+
 	EnvBaremetal = "Baremetal"
 
+	// Types of the node environment detected by the config daemon
+	NodeEnvironmentBareMetal      = "BareMetal"
+	NodeEnvironmentVirtualMachine = "VirtualMachine"
+
+	// ConfigurationModeFull is the mode of the nodes whose devices are configured in full
+	ConfigurationModeFull = "Full"
+	// ConfigurationModeRestricted is the mode of the devices passed through to a VM, only their runtime settings are applied
+	ConfigurationModeRestricted = "Restricted"
+
+	// IdentitySourceFlash is the identity source of the devices whose serial number is derived from the base GUID of their flash
+	IdentitySourceFlash = "Flash"
+	// IdentitySourcePreviousDiscovery is the identity source of the devices whose serial and part numbers were kept
+	// from the previous discovery of their ports
+	IdentitySourcePreviousDiscovery = "PreviousDiscovery"
+	// IdentitySourceUnknown is the identity source of the devices whose VPD and flash couldn't be read,
+	// they are reported without the serial and part numbers
+	IdentitySourceUnknown = "Unknown"
+	// UnidentifiedDevicePrefix prefixes the PCI address in the discovery key of the devices with the unknown identity
+	UnidentifiedDevicePrefix = "unidentified-"
+	// FlashSerialNumberPrefix prefixes the base GUID in the serial number of the devices identified by their flash
+	FlashSerialNumberPrefix = "guid-"
+
+	// Operating modes of the BlueField DPUs
+	// DPU: the ARM side manages the embedded switch and the offloads of the host's PFs
+	BlueFieldModeDPU = "DPU"
+	// NIC: the ARM side is disabled, the device is configured from the host like a ConnectX NIC
+	BlueFieldModeNIC = "NIC"
+	// RestrictedHost: DPU mode with the nv config and the firmware owned by the ARM side, the host can't change them
+	BlueFieldModeRestrictedHost = "RestrictedHost"
+
+	// HostPrivilegeLevelRestricted is reported by mstprivhost if the host's nv config access is restricted by the DPU
+	HostPrivilegeLevelRestricted = "RESTRICTED"
+
 	MaintenanceRequestor   = "configuration.nic.mellanox.com"
 	MaintenanceRequestName = "nic-configuration-operator-maintenance"
 
 	HostPath = "/host"
+	// FirmwareCacheDir contains the firmware binaries downloaded from the NicFirmwareSources, mounted from the host
+	FirmwareCacheDir = "/var/lib/nic-configuration-operator/firmware"
 
 	SupportedNicFirmwareConfigmap = "supported-nic-firmware"
 	Mlx5ModuleVersionPath         = "/sys/bus/pci/drivers/mlx5_core/module/version"
+	// Mlx5CoreDriver is the kernel driver of the host's network interfaces of the PFs and VFs
+	Mlx5CoreDriver = "mlx5_core"
+	// DefaultEthernetMtu is the MTU of the network interfaces created by the driver, e.g. of the new VFs
+	DefaultEthernetMtu = 1500
+
+	// SecurityAdvisoriesConfigmap maps the firmware versions to the security advisories affecting them
+	SecurityAdvisoriesConfigmap = "nic-firmware-advisories"
 
 	FwConfigNotAppliedAfterRebootErrorMsg = "firmware configuration failed to apply after reboot"
+	// ConfigOwnershipDeniedHint is appended to the ConfigOwnershipDenied condition message
+	ConfigOwnershipDeniedHint = "grant the nv config ownership to the host, e.g. with mstprivhost on the DPU's ARM side or in the NIC settings of the BMC, " +
+		"the update is retried once the host privilege level or the spec changes"
+)
+
+// Values of the RoCE congestion control parameters rendered for the Ethernet ports of the roceOptimized templates
+const (
+	// RoceCcPrioMaskRoceOptimized enables the congestion control on all 8 priorities
+	RoceCcPrioMaskRoceOptimized = "255"
+	// CnpDscpRoceOptimized is the DSCP value of the congestion notification packets
+	CnpDscpRoceOptimized = "4"
+	// Cnp802pPrioRoceOptimized is the 802.1p priority of the congestion notification packets
+	Cnp802pPrioRoceOptimized = "6"
 )
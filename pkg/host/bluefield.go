@@ -0,0 +1,58 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package host
+
+import (
+	"context"
+	"slices"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+)
+
+// Current values of the BlueField mode parameters, e.g. INTERNAL_CPU_MODEL = EMBEDDED_CPU(1)
+const (
+	internalCpuModelEmbeddedCpu      = "1"
+	internalCpuOffloadEngineDisabled = "1"
+)
+
+// blueFieldMode returns the operating mode of the BlueField DPU with the given PCI address
+// the host's privilege level is checked first, the mode parameters are read from the current nv config
+// returns empty string if the mode can't be determined, e.g. the DPU runs in the legacy separated host mode
+func (h hostManager) blueFieldMode(pciAddr string) string {
+	level, err := h.hostUtils.GetHostPrivilegeLevel(pciAddr)
+	if err != nil {
+		log.Log.V(2).Info("failed to get host privilege level of BlueField device", "address", pciAddr, "err", err.Error())
+	} else if level == consts.HostPrivilegeLevelRestricted {
+		return consts.BlueFieldModeRestrictedHost
+	}
+
+	query, err := h.hostUtils.QueryNvConfig(context.Background(), pciAddr)
+	if err != nil {
+		log.Log.Error(err, "failed to query nv config of BlueField device, its mode is unknown", "address", pciAddr)
+		return ""
+	}
+
+	switch {
+	case slices.Contains(query.CurrentConfig[consts.InternalCpuOffloadEngineParam], internalCpuOffloadEngineDisabled):
+		return consts.BlueFieldModeNIC
+	case slices.Contains(query.CurrentConfig[consts.InternalCpuModelParam], internalCpuModelEmbeddedCpu):
+		return consts.BlueFieldModeDPU
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,91 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package host
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+	"github.com/Mellanox/nic-configuration-operator/pkg/host/mocks"
+	"github.com/Mellanox/nic-configuration-operator/pkg/types"
+)
+
+var _ = Describe("blueFieldMode", func() {
+	const pciAddr = "0000:03:00.0"
+
+	var (
+		mockHostUtils mocks.HostUtils
+		manager       hostManager
+	)
+
+	BeforeEach(func() {
+		mockHostUtils = mocks.HostUtils{}
+		manager = hostManager{hostUtils: &mockHostUtils}
+	})
+
+	nvConfig := func(cpuModel []string, offloadEngine []string) types.NvConfigQuery {
+		query := types.NewNvConfigQuery()
+		query.CurrentConfig[consts.InternalCpuModelParam] = cpuModel
+		query.CurrentConfig[consts.InternalCpuOffloadEngineParam] = offloadEngine
+		return query
+	}
+
+	It("should report the restricted host without querying the nv config", func() {
+		mockHostUtils.On("GetHostPrivilegeLevel", pciAddr).Return(consts.HostPrivilegeLevelRestricted, nil)
+
+		Expect(manager.blueFieldMode(pciAddr)).To(Equal(consts.BlueFieldModeRestrictedHost))
+		mockHostUtils.AssertNotCalled(GinkgoT(), "QueryNvConfig", mock.Anything, mock.Anything)
+	})
+	It("should report the DPU mode", func() {
+		mockHostUtils.On("GetHostPrivilegeLevel", pciAddr).Return("PRIVILEGED", nil)
+		mockHostUtils.On("QueryNvConfig", mock.Anything, pciAddr).
+			Return(nvConfig([]string{"embedded_cpu", "1"}, []string{"enabled", "0"}), nil)
+
+		Expect(manager.blueFieldMode(pciAddr)).To(Equal(consts.BlueFieldModeDPU))
+	})
+	It("should report the NIC mode", func() {
+		mockHostUtils.On("GetHostPrivilegeLevel", pciAddr).Return("PRIVILEGED", nil)
+		mockHostUtils.On("QueryNvConfig", mock.Anything, pciAddr).
+			Return(nvConfig([]string{"embedded_cpu", "1"}, []string{"disabled", "1"}), nil)
+
+		Expect(manager.blueFieldMode(pciAddr)).To(Equal(consts.BlueFieldModeNIC))
+	})
+	It("should detect the mode from the nv config if the privilege level can't be read", func() {
+		mockHostUtils.On("GetHostPrivilegeLevel", pciAddr).Return("", errors.New("mstprivhost not found"))
+		mockHostUtils.On("QueryNvConfig", mock.Anything, pciAddr).
+			Return(nvConfig([]string{"embedded_cpu", "1"}, []string{"enabled", "0"}), nil)
+
+		Expect(manager.blueFieldMode(pciAddr)).To(Equal(consts.BlueFieldModeDPU))
+	})
+	It("should not report the separated host mode", func() {
+		mockHostUtils.On("GetHostPrivilegeLevel", pciAddr).Return("PRIVILEGED", nil)
+		mockHostUtils.On("QueryNvConfig", mock.Anything, pciAddr).
+			Return(nvConfig([]string{"separated_host", "0"}, nil), nil)
+
+		Expect(manager.blueFieldMode(pciAddr)).To(BeEmpty())
+	})
+	It("should not report the mode if the nv config can't be queried", func() {
+		mockHostUtils.On("GetHostPrivilegeLevel", pciAddr).Return("PRIVILEGED", nil)
+		mockHostUtils.On("QueryNvConfig", mock.Anything, pciAddr).
+			Return(types.NvConfigQuery{}, errors.New("mstconfig failed"))
+
+		Expect(manager.blueFieldMode(pciAddr)).To(BeEmpty())
+	})
+})
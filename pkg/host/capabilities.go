@@ -0,0 +1,207 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package host
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Linux capability numbers, see include/uapi/linux/capability.h
+const (
+	capNetAdmin  = 12
+	capSysRawio  = 17
+	capSysChroot = 18
+	capSysAdmin  = 21
+	capSysBoot   = 22
+)
+
+var capabilityNames = map[int]string{
+	capNetAdmin:  "CAP_NET_ADMIN",
+	capSysRawio:  "CAP_SYS_RAWIO",
+	capSysChroot: "CAP_SYS_CHROOT",
+	capSysAdmin:  "CAP_SYS_ADMIN",
+	capSysBoot:   "CAP_SYS_BOOT",
+}
+
+// rebootOperation is the registry key of the host reboot, which is requested from the host's root
+const rebootOperation = "reboot"
+
+// Operations of the config daemon done without host tools, which only need the root user of the host
+const (
+	// sysfsWriteOperation is the change of the device settings in sysfs: ECN, DCQCN and VF MSI-X count
+	sysfsWriteOperation = "sysfs write"
+	// sysctlWriteOperation is the change of a sysctl of the host
+	sysctlWriteOperation = "sysctl write"
+	// bfbInstallOperation is the push of the BFB bundle to the rshim device of the host's /dev
+	bfbInstallOperation = "BFB installation"
+)
+
+// requiredCapabilities is the registry of capabilities needed by the host tools and operations of the config daemon.
+// When the daemon doesn't run in the privileged mode, these capabilities have to be granted explicitly.
+// Tools missing from the registry don't require any capabilities.
+var requiredCapabilities = map[string][]int{
+	// mstflint tools access the PCI config space and BARs of the device through sysfs, /dev/mst is only required in a user namespace
+	"mstconfig":    {capSysAdmin, capSysRawio},
+	"mstvpd":       {capSysAdmin, capSysRawio},
+	"mstflint":     {capSysAdmin, capSysRawio},
+	"mlxfwreset":   {capSysAdmin, capSysRawio},
+	"mstlink":      {capSysAdmin, capSysRawio},
+	"mstprivhost":  {capSysAdmin, capSysRawio},
+	"mstmget_temp": {capSysAdmin, capSysRawio},
+	// PCI config space beyond the first 64 bytes (link status, device control) is only available with CAP_SYS_ADMIN
+	"lspci":  {capSysAdmin},
+	"setpci": {capSysAdmin},
+	// QoS and devlink settings are configured over netlink
+	"mlnx_qos": {capNetAdmin},
+	"devlink":  {capNetAdmin},
+	// Reboot is requested after chroot to the host's root
+	rebootOperation: {capSysChroot, capSysBoot},
+}
+
+// mstflintTools reach the device through the mstflint_access driver of the host if they can't access its PCI config space,
+// the driver creates <PCI address>_mstconf device nodes in mstDevicePath
+var mstflintTools = []string{"mstconfig", "mstvpd", "mstflint", "mlxfwreset", "mstlink", "mstprivhost", "mstmget_temp"}
+
+// userNamespaceUnsupported are the host tools and operations that can't be done from a user namespace with any capabilities:
+// the root user of the user namespace doesn't own the host's sysfs and procfs files, the host's network interfaces
+// are not seen from the pod network namespace and the rshim devices are not exposed to the config daemon
+var userNamespaceUnsupported = []string{"ethtool", "dcb", "tc", sysfsWriteOperation, sysctlWriteOperation, bfbInstallOperation}
+
+// mstDevicePath is the directory of the device nodes of the mstflint_access driver, exposed to the config daemon
+// running in a user namespace
+var mstDevicePath = "/dev/mst"
+
+var pciAddressRegex = regexp.MustCompile(`^[0-9a-fA-F]{4}:[0-9a-fA-F]{2}:[0-9a-fA-F]{2}\.[0-7]$`)
+
+// hostUIDMap is the UID mapping of the initial user namespace, all the host's UIDs are mapped to themselves
+const hostUIDMap = "0 0 4294967295"
+
+// inUserNamespace returns true if the config daemon runs in a user namespace (hostUsers: false),
+// the capabilities granted there are effective for the resources of the pod only, not for the host's devices
+var inUserNamespace = func() (bool, error) {
+	uidMap, err := os.ReadFile("/proc/self/uid_map")
+	if err != nil {
+		return false, err
+	}
+
+	return strings.Join(strings.Fields(string(uidMap)), " ") != hostUIDMap, nil
+}
+
+// effectiveCapabilities returns the bitmask of the effective capabilities of the config daemon
+var effectiveCapabilities = func() (uint64, error) {
+	file, err := os.Open("/proc/self/status")
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		value, found := strings.CutPrefix(scanner.Text(), "CapEff:")
+		if found {
+			return strconv.ParseUint(strings.TrimSpace(value), 16, 64)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	return 0, fmt.Errorf("effective capabilities not found in /proc/self/status")
+}
+
+// missingCapabilities returns the names of capabilities required for the operation but not granted to the config daemon
+func missingCapabilities(operation string, effective uint64) []string {
+	missing := []string{}
+	for _, capability := range requiredCapabilities[operation] {
+		if effective&(1<<capability) == 0 {
+			missing = append(missing, capabilityNames[capability])
+		}
+	}
+	return missing
+}
+
+// checkCapabilities returns an error if the config daemon lacks capabilities required for the operation or host tool
+// the arguments of the host tool are used to find the device it accesses from a user namespace
+func checkCapabilities(operation string, args ...string) error {
+	operation = filepath.Base(operation)
+	if _, found := requiredCapabilities[operation]; !found && !slices.Contains(userNamespaceUnsupported, operation) {
+		return nil
+	}
+
+	userNamespace, err := inUserNamespace()
+	if err != nil {
+		log.Log.Error(err, "failed to read the user namespace of the config daemon", "operation", operation)
+	}
+	if userNamespace {
+		return checkUserNamespaceAccess(operation, args)
+	}
+
+	effective, err := effectiveCapabilities()
+	if err != nil {
+		// Let the operation fail on its own if capabilities can't be determined
+		log.Log.Error(err, "failed to read effective capabilities", "operation", operation)
+		return nil
+	}
+
+	missing := missingCapabilities(operation, effective)
+	if len(missing) != 0 {
+		return fmt.Errorf("%s requires %s, which are not granted to the config daemon", operation, strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// checkUserNamespaceAccess returns an error if the operation can't be done from the user namespace of the config daemon
+// the mstflint tools access the device through the mstflint_access driver of the host, other operations need
+// the capabilities in the host's user namespace, which can't be granted to the pod
+func checkUserNamespaceAccess(operation string, args []string) error {
+	if slices.Contains(userNamespaceUnsupported, operation) {
+		return fmt.Errorf("%s is not supported when the config daemon runs in a user namespace, it requires the root user and network namespace of the host",
+			operation)
+	}
+	if !slices.Contains(mstflintTools, operation) {
+		names := []string{}
+		for _, capability := range requiredCapabilities[operation] {
+			names = append(names, capabilityNames[capability])
+		}
+		return fmt.Errorf("%s requires %s of the host, which can't be granted to the config daemon running in a user namespace",
+			operation, strings.Join(names, ", "))
+	}
+
+	index := slices.IndexFunc(args, pciAddressRegex.MatchString)
+	if index == -1 {
+		// The tool doesn't access a device, e.g. queries its version
+		return nil
+	}
+
+	devicePath := filepath.Join(mstDevicePath, args[index]+"_mstconf")
+	_, err := os.Stat(devicePath)
+	if err != nil {
+		return fmt.Errorf("%s requires the mstflint_access driver of the host when the config daemon runs in a user namespace, %s is not available: %w",
+			operation, devicePath, err)
+	}
+
+	return nil
+}
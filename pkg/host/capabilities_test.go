@@ -0,0 +1,128 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package host
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("capabilities", func() {
+	var (
+		originalEffectiveCapabilities func() (uint64, error)
+		originalInUserNamespace       func() (bool, error)
+	)
+
+	BeforeEach(func() {
+		originalEffectiveCapabilities = effectiveCapabilities
+		originalInUserNamespace = inUserNamespace
+		inUserNamespace = func() (bool, error) { return false, nil }
+	})
+
+	AfterEach(func() {
+		effectiveCapabilities = originalEffectiveCapabilities
+		inUserNamespace = originalInUserNamespace
+	})
+
+	It("should read effective capabilities of the process", func() {
+		_, err := effectiveCapabilities()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should report missing capabilities of the operation", func() {
+		Expect(missingCapabilities("mstconfig", 1<<capSysAdmin)).To(Equal([]string{"CAP_SYS_RAWIO"}))
+		Expect(missingCapabilities("mstconfig", 1<<capSysAdmin|1<<capSysRawio)).To(BeEmpty())
+		Expect(missingCapabilities("echo", 0)).To(BeEmpty())
+	})
+
+	It("should fail the operation if capabilities are not granted", func() {
+		effectiveCapabilities = func() (uint64, error) { return 1 << capSysAdmin, nil }
+
+		Expect(checkCapabilities("/usr/bin/devlink")).To(MatchError("devlink requires CAP_NET_ADMIN, which are not granted to the config daemon"))
+		Expect(checkCapabilities(rebootOperation)).To(MatchError(ContainSubstring("CAP_SYS_CHROOT, CAP_SYS_BOOT")))
+		Expect(checkCapabilities("lspci")).To(Succeed())
+	})
+
+	It("should not fail the operation if capabilities can't be determined", func() {
+		effectiveCapabilities = func() (uint64, error) { return 0, errors.New("no proc") }
+
+		Expect(checkCapabilities("devlink")).To(Succeed())
+	})
+
+	It("should read the user namespace of the process", func() {
+		_, err := originalInUserNamespace()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("in a user namespace", func() {
+		BeforeEach(func() {
+			inUserNamespace = func() (bool, error) { return true, nil }
+			effectiveCapabilities = func() (uint64, error) { return 1<<capSysAdmin | 1<<capSysRawio | 1<<capNetAdmin, nil }
+
+			originalMstDevicePath := mstDevicePath
+			mstDevicePath = GinkgoT().TempDir()
+			DeferCleanup(func() { mstDevicePath = originalMstDevicePath })
+		})
+
+		It("should access the device through the mstflint_access driver", func() {
+			Expect(os.WriteFile(filepath.Join(mstDevicePath, "0000:3b:00.0_mstconf"), nil, 0600)).To(Succeed())
+
+			Expect(checkCapabilities("/usr/bin/mstconfig", "-d", "0000:3b:00.0", "-e", "q")).To(Succeed())
+			Expect(checkCapabilities("mstvpd", "0000:3b:00.0")).To(Succeed())
+			Expect(checkCapabilities("mstflint", "--version")).To(Succeed())
+		})
+		It("should fail the mstflint tools if the device of the mstflint_access driver is missing", func() {
+			Expect(checkCapabilities("mstflint", "-d", "0000:3b:00.1", "q")).To(MatchError(
+				ContainSubstring("mstflint requires the mstflint_access driver of the host when the config daemon runs in a user namespace")))
+		})
+		It("should fail the operations requiring the capabilities of the host", func() {
+			Expect(checkCapabilities("devlink", "dev", "reload", "pci/0000:3b:00.0")).To(MatchError(
+				"devlink requires CAP_NET_ADMIN of the host, which can't be granted to the config daemon running in a user namespace"))
+			Expect(checkCapabilities(rebootOperation)).To(MatchError(ContainSubstring("CAP_SYS_CHROOT, CAP_SYS_BOOT of the host")))
+			Expect(checkCapabilities("echo")).To(Succeed())
+		})
+		It("should fail the operations requiring the root user and network namespace of the host", func() {
+			Expect(checkCapabilities("/usr/sbin/ethtool", "-K", "eth0", "rx", "on")).To(MatchError(
+				"ethtool is not supported when the config daemon runs in a user namespace, it requires the root user and network namespace of the host"))
+			Expect(checkCapabilities(sysfsWriteOperation)).To(MatchError(ContainSubstring("sysfs write is not supported")))
+			Expect(checkCapabilities(bfbInstallOperation)).To(MatchError(ContainSubstring("BFB installation is not supported")))
+		})
+		It("should fail the host's sysctl writes", func() {
+			Expect(NewHostUtils().SetSysctl("net.ipv4.tcp_ecn", "1")).To(MatchError(ContainSubstring("sysctl write is not supported")))
+		})
+	})
+
+	It("should not gate the operations unsupported in a user namespace outside of it", func() {
+		effectiveCapabilities = func() (uint64, error) { return 0, nil }
+
+		Expect(checkCapabilities("ethtool", "-i", "eth0")).To(Succeed())
+		Expect(checkCapabilities(sysctlWriteOperation)).To(Succeed())
+	})
+
+	It("should not start a host tool without required capabilities", func() {
+		effectiveCapabilities = func() (uint64, error) { return 0, nil }
+		watchdog := newToolWatchdog(time.Minute)
+
+		_, err := watchdog.Command("mlnx_qos", "-i", "eth0").Output()
+		Expect(err).To(MatchError(ContainSubstring("mlnx_qos requires CAP_NET_ADMIN")))
+		Expect(watchdog.runningTools()).To(BeEmpty())
+	})
+})
@@ -16,15 +16,18 @@ limitations under the License.
 package host
 
 import (
-	"errors"
 	"fmt"
 	"reflect"
+	"regexp"
 	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
@@ -56,6 +59,10 @@ type configValidation interface {
 type configValidationImpl struct {
 	utils         HostUtils
 	eventRecorder record.EventRecorder
+
+	// skippedNvParamsLock protects skippedNvParams, the last warning about the skipped nv config parameters by the device name
+	skippedNvParamsLock sync.Mutex
+	skippedNvParams     map[string]string
 }
 
 func nvParamLinkTypeFromName(linkType string) string {
@@ -68,6 +75,112 @@ func nvParamLinkTypeFromName(linkType string) string {
 	return ""
 }
 
+// portLinkType returns the desired link type of the device's port with the given index
+// the port's override in the template takes precedence over the template's link type
+func portLinkType(template *v1alpha1.ConfigurationTemplateSpec, index int) string {
+	for _, port := range template.Ports {
+		if port.Port == index+1 && port.LinkType != "" {
+			return string(port.LinkType)
+		}
+	}
+
+	return string(template.LinkType)
+}
+
+// portChannels returns the desired channels of the device's port with the given index
+// the port's override in the template takes precedence over the template's channels
+func portChannels(template *v1alpha1.ConfigurationTemplateSpec, index int) *v1alpha1.ChannelsSpec {
+	for _, port := range template.Ports {
+		if port.Port == index+1 && port.Channels != nil {
+			return port.Channels
+		}
+	}
+
+	return template.Channels
+}
+
+// portMtu returns the desired MTU of the device's port with the given index
+// the port's override in the template takes precedence over the template's MTU
+func portMtu(template *v1alpha1.ConfigurationTemplateSpec, index int) *v1alpha1.MtuSpec {
+	for _, port := range template.Ports {
+		if port.Port == index+1 && port.Mtu != nil {
+			return port.Mtu
+		}
+	}
+
+	return template.Mtu
+}
+
+// ethernetPortPresent returns true if at least one of the device's ports isn't configured with the Infiniband link type
+func ethernetPortPresent(template *v1alpha1.ConfigurationTemplateSpec, portCount int) bool {
+	for i := 0; i < max(portCount, 1); i++ {
+		if portLinkType(template, i) != consts.Infiniband {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validatePortOverrides checks that the per-port overrides of the template fit the device
+func validatePortOverrides(template *v1alpha1.ConfigurationTemplateSpec, portCount int) error {
+	configured := map[int]bool{}
+	for _, port := range template.Ports {
+		if configured[port.Port] {
+			return types.IncorrectSpecError(fmt.Sprintf("port %d is configured twice in the template", port.Port))
+		}
+		configured[port.Port] = true
+
+		if port.Port > portCount {
+			return types.IncorrectSpecError(fmt.Sprintf(
+				"port count mismatch: template configures port %d but device has %d port(s)", port.Port, portCount))
+		}
+	}
+
+	return nil
+}
+
+// nvParamPciWrOrderingForceRelax is the PCI_WR_ORDERING value forcing relaxed ordering of all PCIe writes
+const nvParamPciWrOrderingForceRelax = "1"
+
+// pciPerformanceSettings returns the MAX_ACC_OUT_READ value and relaxed ordering of the PCI performance settings
+// explicit fields take precedence over the preset, zero MAX_ACC_OUT_READ means the device default
+func pciPerformanceSettings(spec *v1alpha1.PciPerformanceOptimizedSpec) (int, bool) {
+	maxAccOutRead := spec.MaxAccOutRead
+	relaxedOrdering := false
+
+	if spec.Preset == consts.PciPerformancePresetAmd {
+		if maxAccOutRead == 0 {
+			maxAccOutRead = consts.AmdMaxAccOutRead
+		}
+		relaxedOrdering = true
+	}
+	if spec.RelaxedOrdering != nil {
+		relaxedOrdering = *spec.RelaxedOrdering
+	}
+
+	return maxAccOutRead, relaxedOrdering
+}
+
+// pciPerformanceSpec returns the PCI performance settings of the template, including the ones of the GPUDirect RDMA preset
+// the preset enables the PCI performance optimizations with relaxed ordering, explicit relaxedOrdering takes precedence
+func pciPerformanceSpec(template *v1alpha1.ConfigurationTemplateSpec) *v1alpha1.PciPerformanceOptimizedSpec {
+	gpuDirect := template.GpuDirectOptimized
+	if gpuDirect == nil || !gpuDirect.Enabled || gpuDirect.Preset != consts.GpuDirectPresetRdma {
+		return template.PciPerformanceOptimized
+	}
+
+	spec := v1alpha1.PciPerformanceOptimizedSpec{}
+	if template.PciPerformanceOptimized != nil {
+		spec = *template.PciPerformanceOptimized
+	}
+	spec.Enabled = true
+	if spec.RelaxedOrdering == nil {
+		spec.RelaxedOrdering = ptr.To(true)
+	}
+	return &spec
+}
+
 func applyDefaultNvConfigValueIfExists(
 	paramName string, desiredParameters map[string]string, query types.NvConfigQuery) {
 	defaultValues, found := query.DefaultConfig[paramName]
@@ -87,6 +200,48 @@ func (v *configValidationImpl) ConstructNvParamMapFromTemplate(
 	template := device.Spec.Configuration.Template
 	secondPortPresent := len(device.Status.Ports) > 1
 
+	err := validatePortOverrides(template, len(device.Status.Ports))
+	if err != nil {
+		log.Log.Error(err, "incorrect spec", "device", device.Name)
+		return desiredParameters, err
+	}
+
+	err = validateDeviceFamilySpec(device)
+	if err != nil {
+		log.Log.Error(err, "incorrect spec", "device", device.Name)
+		return desiredParameters, err
+	}
+
+	if tcBandwidth := desiredTcBandwidth(device); tcBandwidth != "" {
+		err = validateTcBandwidth(tcBandwidth)
+		if err != nil {
+			log.Log.Error(err, "incorrect spec", "device", device.Name)
+			return desiredParameters, err
+		}
+	}
+
+	err = validateSysctls(template.Sysctls)
+	if err != nil {
+		log.Log.Error(err, "incorrect spec", "device", device.Name)
+		return desiredParameters, err
+	}
+
+	err = validateOffloads(template.Offloads)
+	if err != nil {
+		log.Log.Error(err, "incorrect spec", "device", device.Name)
+		return desiredParameters, err
+	}
+
+	if template.EswitchMode == consts.EswitchModeSwitchdev {
+		for i := range device.Status.Ports {
+			if portLinkType(template, i) == consts.Infiniband {
+				err = types.IncorrectSpecError("switchdev eswitch mode is only supported for Ethernet ports")
+				log.Log.Error(err, "incorrect spec", "device", device.Name)
+				return desiredParameters, err
+			}
+		}
+	}
+
 	desiredParameters[consts.SriovEnabledParam] = consts.NvParamFalse
 	desiredParameters[consts.SriovNumOfVfsParam] = "0"
 	if template.NumVfs > 0 {
@@ -94,19 +249,24 @@ func (v *configValidationImpl) ConstructNvParamMapFromTemplate(
 		desiredParameters[consts.SriovNumOfVfsParam] = strconv.Itoa(template.NumVfs)
 	}
 
+	if template.VfMsix != nil {
+		err = constructVfMsixParams(template, query, desiredParameters)
+		if err != nil {
+			log.Log.Error(err, "incorrect spec", "device", device.Name)
+			return desiredParameters, err
+		}
+	}
+
 	// Link type change is not allowed on some devices
 	_, canChangeLinkType := query.DefaultConfig[consts.LinkTypeP1Param]
 	if canChangeLinkType {
-		linkType := nvParamLinkTypeFromName(string(template.LinkType))
-		desiredParameters[consts.LinkTypeP1Param] = linkType
+		desiredParameters[consts.LinkTypeP1Param] = nvParamLinkTypeFromName(portLinkType(template, 0))
 		if secondPortPresent {
-			desiredParameters[consts.LinkTypeP2Param] = linkType
+			desiredParameters[consts.LinkTypeP2Param] = nvParamLinkTypeFromName(portLinkType(template, 1))
 		}
 	} else {
-		desiredLinkType := string(device.Spec.Configuration.Template.LinkType)
-
-		for _, port := range device.Status.Ports {
-			if port.NetworkInterface != "" && v.utils.GetLinkType(port.NetworkInterface) != desiredLinkType {
+		for i, port := range device.Status.Ports {
+			if port.NetworkInterface != "" && v.utils.GetLinkType(port.NetworkInterface) != portLinkType(template, i) {
 				err := types.IncorrectSpecError(
 					fmt.Sprintf(
 						"device does not support link type change, wrong link type provided in the template, should be: %s",
@@ -117,9 +277,12 @@ func (v *configValidationImpl) ConstructNvParamMapFromTemplate(
 		}
 	}
 
-	if template.PciPerformanceOptimized != nil && template.PciPerformanceOptimized.Enabled {
-		if template.PciPerformanceOptimized.MaxAccOutRead != 0 {
-			desiredParameters[consts.MaxAccOutReadParam] = strconv.Itoa(template.PciPerformanceOptimized.MaxAccOutRead)
+	pciPerformance := pciPerformanceSpec(template)
+	if pciPerformance != nil && pciPerformance.Enabled {
+		maxAccOutRead, _ := pciPerformanceSettings(pciPerformance)
+
+		if maxAccOutRead != 0 {
+			desiredParameters[consts.MaxAccOutReadParam] = strconv.Itoa(maxAccOutRead)
 		} else {
 			// MAX_ACC_OUT_READ parameter is hidden if ADVANCED_PCI_SETTINGS is disabled
 			if v.AdvancedPCISettingsEnabled(query) {
@@ -144,7 +307,7 @@ func (v *configValidationImpl) ConstructNvParamMapFromTemplate(
 					if v.eventRecorder != nil {
 						v.eventRecorder.Event(device, v1.EventTypeWarning, "FirmwareError", warning)
 					}
-					log.Log.Error(errors.New(warning), "device", device.Name, "fw version", device.Status.FirmwareVersion)
+					log.Log.Info(warning, "device", device.Name, "fw version", device.Status.FirmwareVersion)
 				}
 			}
 		}
@@ -152,22 +315,67 @@ func (v *configValidationImpl) ConstructNvParamMapFromTemplate(
 		// maxReadRequest is applied as runtime configuration
 	}
 
+	// GPUDirect and storage workloads often need relaxed ordering without the rest of the PCI optimizations,
+	// so explicit relaxedOrdering is applied even if the PCI performance optimization is disabled
+	if pciPerformance != nil && (pciPerformance.Enabled || pciPerformance.RelaxedOrdering != nil) {
+		_, relaxedOrdering := pciPerformanceSettings(pciPerformance)
+		if relaxedOrdering {
+			if _, found := query.DefaultConfig[consts.PciWrOrderingParam]; !found {
+				err := types.IncorrectSpecError("Device does not support relaxed ordering nv config parameter")
+				log.Log.Error(err, "incorrect spec", "device", device.Name, "parameter", consts.PciWrOrderingParam)
+				return desiredParameters, err
+			}
+			desiredParameters[consts.PciWrOrderingParam] = nvParamPciWrOrderingForceRelax
+		} else {
+			applyDefaultNvConfigValueIfExists(consts.PciWrOrderingParam, desiredParameters, query)
+		}
+	}
+
+	if template.PciLink != nil {
+		pciLinkParams := map[string]int{
+			consts.PciGenParam:   template.PciLink.Generation,
+			consts.PciWidthParam: template.PciLink.Width,
+		}
+		for paramName, value := range pciLinkParams {
+			if value == 0 {
+				continue
+			}
+			if _, found := query.DefaultConfig[paramName]; !found {
+				err := types.IncorrectSpecError("Device does not support PCI link nv config parameters")
+				log.Log.Error(err, "incorrect spec", "device", device.Name, "parameter", paramName)
+				return desiredParameters, err
+			}
+			desiredParameters[paramName] = strconv.Itoa(value)
+		}
+	}
+
 	if template.RoceOptimized != nil && template.RoceOptimized.Enabled {
-		if template.LinkType == consts.Infiniband {
+		if !ethernetPortPresent(template, len(device.Status.Ports)) {
 			err := types.IncorrectSpecError(
 				"RoceOptimized settings can only be used with link type Ethernet")
 			log.Log.Error(err, "incorrect spec", "device", device.Name)
 			return desiredParameters, err
 		}
 
-		desiredParameters[consts.RoceCcPrioMaskP1Param] = "255"
-		desiredParameters[consts.CnpDscpP1Param] = "4"
-		desiredParameters[consts.Cnp802pPrioP1Param] = "6"
+		// Infiniband ports of a mixed device keep the defaults
+		if portLinkType(template, 0) != consts.Infiniband {
+			desiredParameters[consts.RoceCcPrioMaskP1Param] = consts.RoceCcPrioMaskRoceOptimized
+			desiredParameters[consts.CnpDscpP1Param] = consts.CnpDscpRoceOptimized
+			desiredParameters[consts.Cnp802pPrioP1Param] = consts.Cnp802pPrioRoceOptimized
+		} else {
+			applyDefaultNvConfigValueIfExists(consts.RoceCcPrioMaskP1Param, desiredParameters, query)
+			applyDefaultNvConfigValueIfExists(consts.CnpDscpP1Param, desiredParameters, query)
+			applyDefaultNvConfigValueIfExists(consts.Cnp802pPrioP1Param, desiredParameters, query)
+		}
 
-		if secondPortPresent {
-			desiredParameters[consts.RoceCcPrioMaskP2Param] = "255"
-			desiredParameters[consts.CnpDscpP2Param] = "4"
-			desiredParameters[consts.Cnp802pPrioP2Param] = "6"
+		if secondPortPresent && portLinkType(template, 1) != consts.Infiniband {
+			desiredParameters[consts.RoceCcPrioMaskP2Param] = consts.RoceCcPrioMaskRoceOptimized
+			desiredParameters[consts.CnpDscpP2Param] = consts.CnpDscpRoceOptimized
+			desiredParameters[consts.Cnp802pPrioP2Param] = consts.Cnp802pPrioRoceOptimized
+		} else if secondPortPresent {
+			applyDefaultNvConfigValueIfExists(consts.RoceCcPrioMaskP2Param, desiredParameters, query)
+			applyDefaultNvConfigValueIfExists(consts.CnpDscpP2Param, desiredParameters, query)
+			applyDefaultNvConfigValueIfExists(consts.Cnp802pPrioP2Param, desiredParameters, query)
 		}
 
 		// qos settings are applied as runtime configuration
@@ -190,28 +398,330 @@ func (v *configValidationImpl) ConstructNvParamMapFromTemplate(
 		}
 
 		desiredParameters[consts.AtsEnabledParam] = consts.NvParamFalse
-		if template.PciPerformanceOptimized == nil || !template.PciPerformanceOptimized.Enabled {
+		if pciPerformance == nil || !pciPerformance.Enabled {
 			err := types.IncorrectSpecError(
 				"GpuDirectOptimized should only be enabled together with PciPerformanceOptimized")
 			log.Log.Error(err, "incorrect spec", "device", device.Name)
 			return desiredParameters, err
 		}
+		if template.Ats != nil && template.Ats.Enabled {
+			err := types.IncorrectSpecError(
+				"ATS can't be enabled together with GpuDirectOptimized, which disables ATS on Baremetal")
+			log.Log.Error(err, "incorrect spec", "device", device.Name)
+			return desiredParameters, err
+		}
+	} else if template.Ats != nil {
+		if _, found := query.DefaultConfig[consts.AtsEnabledParam]; found {
+			desiredParameters[consts.AtsEnabledParam] = consts.NvParamFalse
+			if template.Ats.Enabled {
+				desiredParameters[consts.AtsEnabledParam] = consts.NvParamTrue
+			}
+		} else {
+			// The rest of the spec can still be applied, ATS is reported as unsupported instead of failing the device
+			warning := fmt.Sprintf("device does not support ATS, %s nv config parameter is not available, skipping it", consts.AtsEnabledParam)
+			if v.eventRecorder != nil {
+				v.eventRecorder.Event(device, v1.EventTypeWarning, consts.AtsNotSupportedReason, warning)
+			}
+			log.Log.Info(warning, "device", device.Name, "fw version", device.Status.FirmwareVersion)
+		}
 	} else {
 		applyDefaultNvConfigValueIfExists(consts.AtsEnabledParam, desiredParameters, query)
 	}
 
+	err = constructBootOptionParams(device, query, desiredParameters, secondPortPresent)
+	if err != nil {
+		return desiredParameters, err
+	}
+
+	err = constructPtpParams(device, query, desiredParameters)
+	if err != nil {
+		return desiredParameters, err
+	}
+
+	err = constructSteeringParams(device, query, desiredParameters)
+	if err != nil {
+		return desiredParameters, err
+	}
+
+	err = constructSpectrumXParams(device, query, desiredParameters)
+	if err != nil {
+		return desiredParameters, err
+	}
+
+	v.skipUnsupportedNvParams(device, query, desiredParameters)
+
+	rawParams := map[string]string{}
 	for _, rawParam := range template.RawNvConfig {
-		// Ignore second port params if device has a single port
+		if value, found := rawParams[rawParam.Name]; found && !NvParamValueMatches(rawParam.Name, rawParam.Value, []string{value}) {
+			err := types.IncorrectSpecError(fmt.Sprintf(
+				"rawNvConfig sets parameter %s twice with different values %s and %s", rawParam.Name, value, rawParam.Value))
+			log.Log.Error(err, "incorrect spec", "device", device.Name)
+			return desiredParameters, err
+		}
+		rawParams[rawParam.Name] = rawParam.Value
+
+		// Second port params can't be applied to a single port device, the template doesn't fit the device
 		if strings.HasSuffix(rawParam.Name, consts.SecondPortPrefix) && !secondPortPresent {
-			continue
+			err := types.IncorrectSpecError(fmt.Sprintf(
+				"port count mismatch: template sets second port parameter %s but device has a single port", rawParam.Name))
+			log.Log.Error(err, "incorrect spec", "device", device.Name)
+			return desiredParameters, err
 		}
 
 		desiredParameters[rawParam.Name] = rawParam.Value
 	}
 
+	// rawNvConfig can override the SR-IOV parameters derived from numVfs, VFs can't be allocated with SR-IOV disabled
+	if !NvParamValueMatches(consts.SriovNumOfVfsParam, desiredParameters[consts.SriovNumOfVfsParam], []string{"0"}) &&
+		!NvParamValueMatches(consts.SriovEnabledParam, desiredParameters[consts.SriovEnabledParam], []string{consts.NvParamTrue}) {
+		err := types.IncorrectSpecError(fmt.Sprintf("%s=%s requires %s to be enabled",
+			consts.SriovNumOfVfsParam, desiredParameters[consts.SriovNumOfVfsParam], consts.SriovEnabledParam))
+		log.Log.Error(err, "incorrect spec", "device", device.Name)
+		return desiredParameters, err
+	}
+
+	for _, port := range template.Ports {
+		for _, rawParam := range port.RawNvConfig {
+			desiredParameters[fmt.Sprintf("%s_P%d", rawParam.Name, port.Port)] = rawParam.Value
+		}
+	}
+
 	return desiredParameters, nil
 }
 
+// bootOptionParams are reset to the device defaults if not set in the template
+// the EXP_ROM_* and BOOT_VLAN_* parameters are only written if their fields are set, they are often provisioned out of band
+var bootOptionParams = []string{
+	consts.BootOptionRomEnP1Param,
+	consts.BootRetryCntP1Param,
+}
+
+var bootOptionSecondPortParams = []string{
+	consts.BootOptionRomEnP2Param,
+	consts.BootRetryCntP2Param,
+}
+
+// constructVfMsixParams translates the VF MSI-X settings of the template into the nv config parameters
+func constructVfMsixParams(template *v1alpha1.ConfigurationTemplateSpec, query types.NvConfigQuery, desiredParameters map[string]string) error {
+	vfMsix := template.VfMsix
+
+	if template.NumVfs == 0 {
+		return types.IncorrectSpecError("vfMsix settings require numVfs to be set")
+	}
+	if vfMsix.RuntimeVfMsix != 0 && !vfMsix.Dynamic {
+		return types.IncorrectSpecError("runtimeVfMsix requires the dynamic VF MSI-X table to be enabled")
+	}
+
+	if vfMsix.NumVfMsix != 0 {
+		if _, found := query.DefaultConfig[consts.NumVfMsixParam]; !found {
+			return types.IncorrectSpecError("Device does not support VF MSI-X nv config parameters")
+		}
+		desiredParameters[consts.NumVfMsixParam] = strconv.Itoa(vfMsix.NumVfMsix)
+	}
+
+	_, dynamicSupported := query.DefaultConfig[consts.DynamicVfMsixTableParam]
+	if dynamicSupported {
+		desiredParameters[consts.DynamicVfMsixTableParam] = consts.NvParamFalse
+		if vfMsix.Dynamic {
+			desiredParameters[consts.DynamicVfMsixTableParam] = consts.NvParamTrue
+		}
+	} else if vfMsix.Dynamic {
+		return types.IncorrectSpecError("Device does not support dynamic VF MSI-X table")
+	}
+
+	return nil
+}
+
+// constructPtpParams renders the PTP settings of the template, the real time clock is left untouched if ptp is not specified
+// so that the clock mode provisioned for the PTP deployments isn't reset by the templates unaware of PTP
+func constructPtpParams(device *v1alpha1.NicDevice, query types.NvConfigQuery, desiredParameters map[string]string) error {
+	ptp := device.Spec.Configuration.Template.Ptp
+	if ptp == nil {
+		return nil
+	}
+
+	// PTP deployments rely on the clock mode, the device fails instead of silently running the free-running clock
+	if _, found := query.DefaultConfig[consts.RealTimeClockEnableParam]; !found {
+		err := types.IncorrectSpecError("Device does not support the real time clock nv config parameter")
+		log.Log.Error(err, "incorrect spec", "device", device.Name, "parameter", consts.RealTimeClockEnableParam)
+		return err
+	}
+
+	desiredParameters[consts.RealTimeClockEnableParam] = consts.NvParamFalse
+	if ptp.RealTimeClock {
+		desiredParameters[consts.RealTimeClockEnableParam] = consts.NvParamTrue
+	}
+	return nil
+}
+
+// constructSteeringParams renders the firmware steering settings of the template, unset settings are left untouched
+// the parameters depend on the NIC model and firmware, the parameters the device doesn't report can't be requested
+func constructSteeringParams(device *v1alpha1.NicDevice, query types.NvConfigQuery, desiredParameters map[string]string) error {
+	steering := device.Spec.Configuration.Template.Steering
+	if steering == nil {
+		steering = &v1alpha1.SteeringSpec{}
+	}
+
+	nvParamInt := func(value *int) string {
+		if value == nil {
+			return ""
+		}
+		return strconv.Itoa(*value)
+	}
+	nvParamBool := func(value *bool) string {
+		switch {
+		case value == nil:
+			return ""
+		case *value:
+			return consts.NvParamTrue
+		default:
+			return consts.NvParamFalse
+		}
+	}
+
+	steeringParams := []struct {
+		name  string
+		value string
+	}{
+		{consts.FlexParserProfileParam, nvParamInt(steering.FlexParserProfile)},
+		{consts.ProgParseGraphParam, nvParamBool(steering.ProgrammableParseGraph)},
+		{consts.LogDcrHashTableSizeParam, nvParamInt(steering.LogDcrHashTableSize)},
+		{consts.DcrLifoSizeParam, nvParamInt(steering.DcrLifoSize)},
+	}
+	for _, param := range steeringParams {
+		if param.value == "" {
+			continue
+		}
+
+		if _, found := query.DefaultConfig[param.name]; !found {
+			err := types.IncorrectSpecError(fmt.Sprintf("Device does not support steering nv config parameter %s", param.name))
+			log.Log.Error(err, "incorrect spec", "device", device.Name, "parameter", param.name)
+			return err
+		}
+		desiredParameters[param.name] = param.value
+	}
+
+	return nil
+}
+
+// constructSpectrumXParams renders the Spectrum-X settings of the template, unset settings are left untouched
+// returns types.FabricFeatureNotSupportedError if the device or its firmware lacks the parameters of the requested settings
+func constructSpectrumXParams(device *v1alpha1.NicDevice, query types.NvConfigQuery, desiredParameters map[string]string) error {
+	spectrumX := device.Spec.Configuration.Template.SpectrumX
+	if spectrumX == nil {
+		spectrumX = &v1alpha1.SpectrumXSpec{}
+	}
+
+	// The reordered packets would be retransmitted with go-back-N, collapsing the throughput of the fabric
+	if spectrumX.AdaptiveRouting != nil && *spectrumX.AdaptiveRouting &&
+		spectrumX.SelectiveRepeat != nil && !*spectrumX.SelectiveRepeat {
+		err := types.IncorrectSpecError("adaptive routing reorders the packets, selective repeat can't be disabled together with it")
+		log.Log.Error(err, "incorrect spec", "device", device.Name)
+		return err
+	}
+
+	spectrumXParams := []struct {
+		name  string
+		value *bool
+	}{
+		{consts.RoceAdaptiveRoutingEnParam, spectrumX.AdaptiveRouting},
+		{consts.RdmaSelectiveRepeatEnParam, spectrumX.SelectiveRepeat},
+		{consts.UserProgrammableCcParam, spectrumX.ProgrammableCongestionControl},
+	}
+	missing := []string{}
+	for _, param := range spectrumXParams {
+		if param.value == nil {
+			continue
+		}
+
+		if _, found := query.DefaultConfig[param.name]; !found {
+			missing = append(missing, param.name)
+			continue
+		}
+		desiredParameters[param.name] = consts.NvParamFalse
+		if *param.value {
+			desiredParameters[param.name] = consts.NvParamTrue
+		}
+	}
+
+	// All missing parameters are reported at once, the feature set is usually absent as a whole
+	if len(missing) != 0 {
+		err := types.FabricFeatureNotSupportedError(fmt.Sprintf(
+			"device with firmware %s does not support the Spectrum-X feature set, nv config parameters %s are not available",
+			device.Status.FirmwareVersion, strings.Join(missing, ", ")))
+		log.Log.Error(err, "unsupported spec", "device", device.Name)
+		return err
+	}
+
+	return nil
+}
+
+// constructBootOptionParams translates the boot options of the template into the expansion ROM nv config parameters
+// the expansion ROM and boot retries are set to device defaults if not set in the template, the PXE, UEFI and boot VLAN
+// parameters are left untouched
+func constructBootOptionParams(device *v1alpha1.NicDevice, query types.NvConfigQuery, desiredParameters map[string]string, secondPortPresent bool) error {
+	params := bootOptionParams
+	if secondPortPresent {
+		params = append(slices.Clone(bootOptionParams), bootOptionSecondPortParams...)
+	}
+	for _, param := range params {
+		applyDefaultNvConfigValueIfExists(param, desiredParameters, query)
+	}
+
+	bootOptions := device.Spec.Configuration.Template.BootOptions
+	if bootOptions == nil {
+		return nil
+	}
+
+	// Boot options are hidden on devices without an expansion ROM
+	if _, found := query.DefaultConfig[consts.BootOptionRomEnP1Param]; !found {
+		err := types.IncorrectSpecError("Device does not support boot option nv config parameters")
+		log.Log.Error(err, "incorrect spec", "device", device.Name, "parameter", consts.BootOptionRomEnP1Param)
+		return err
+	}
+
+	setPortParam := func(p1Param string, p2Param string, value string) {
+		desiredParameters[p1Param] = value
+		if secondPortPresent {
+			desiredParameters[p2Param] = value
+		}
+	}
+	nvParamBool := func(value bool) string {
+		if value {
+			return consts.NvParamTrue
+		}
+		return consts.NvParamFalse
+	}
+
+	if !bootOptions.Enabled {
+		if (bootOptions.PXE != nil && *bootOptions.PXE) || (bootOptions.UEFI != nil && *bootOptions.UEFI) || bootOptions.BootVlan != 0 {
+			err := types.IncorrectSpecError("BootOptions pxe, uefi and bootVlan can only be used with the expansion ROM enabled")
+			log.Log.Error(err, "incorrect spec", "device", device.Name)
+			return err
+		}
+
+		setPortParam(consts.BootOptionRomEnP1Param, consts.BootOptionRomEnP2Param, consts.NvParamFalse)
+		return nil
+	}
+
+	setPortParam(consts.BootOptionRomEnP1Param, consts.BootOptionRomEnP2Param, consts.NvParamTrue)
+	if bootOptions.PXE != nil {
+		desiredParameters[consts.ExpRomPxeEnableParam] = nvParamBool(*bootOptions.PXE)
+	}
+	if bootOptions.UEFI != nil {
+		desiredParameters[consts.ExpRomUefiX86EnableParam] = nvParamBool(*bootOptions.UEFI)
+	}
+	if bootOptions.BootVlan != 0 {
+		setPortParam(consts.BootVlanEnP1Param, consts.BootVlanEnP2Param, consts.NvParamTrue)
+		setPortParam(consts.BootVlanP1Param, consts.BootVlanP2Param, strconv.Itoa(bootOptions.BootVlan))
+	}
+	if bootOptions.BootRetryCount != nil {
+		setPortParam(consts.BootRetryCntP1Param, consts.BootRetryCntP2Param, strconv.Itoa(*bootOptions.BootRetryCount))
+	}
+
+	return nil
+}
+
 // ValidateResetToDefault checks if device's nv config has been reset to default in current and next boots
 // returns bool - need to perform reset
 // returns bool - reboot required
@@ -285,12 +795,197 @@ func (v *configValidationImpl) RuntimeConfigApplied(device *v1alpha1.NicDevice)
 		}
 	}
 
+	desiredDevlinkResources := device.Spec.Configuration.Template.DevlinkResources
+	if len(desiredDevlinkResources) != 0 {
+		for _, port := range ports {
+			resources, err := v.utils.GetDevlinkResources(port.PCI)
+			if err != nil {
+				log.Log.Error(err, "can't validate devlink resources", "device", device.Name, "port", port.PCI)
+				return false, err
+			}
+			for _, desired := range desiredDevlinkResources {
+				resource, found := resources[desired.Path]
+				if !found || !devlinkResourceApplied(resource, desired.Size) {
+					return false, nil
+				}
+			}
+		}
+	}
+
+	vfMsix := device.Spec.Configuration.Template.VfMsix
+	if vfMsix != nil && vfMsix.RuntimeVfMsix != 0 {
+		for _, port := range ports {
+			vfs, err := v.utils.GetVfPciAddresses(port.PCI)
+			if err != nil {
+				log.Log.Error(err, "can't validate VF MSI-X vectors", "device", device.Name, "port", port.PCI)
+				return false, err
+			}
+			for _, vf := range vfs {
+				count, err := v.utils.GetVfMsixCount(vf)
+				if err != nil {
+					log.Log.Error(err, "can't validate VF MSI-X vectors", "device", device.Name, "vf", vf)
+					return false, err
+				}
+				if count == vfMsix.RuntimeVfMsix {
+					continue
+				}
+				// The vectors of the VFs in use are assigned once they are released
+				inUse, err := vfInUse(v.utils, vf)
+				if err != nil {
+					log.Log.Error(err, "can't validate VF MSI-X vectors", "device", device.Name, "vf", vf)
+					return false, err
+				}
+				if !inUse {
+					return false, nil
+				}
+			}
+		}
+	}
+
+	if ringSize := device.Spec.Configuration.Template.RingSize; ringSize != nil {
+		for i, port := range ports {
+			if !netdevSettingsApply(device, i, port, "ring sizes") {
+				continue
+			}
+			current, err := v.utils.GetRingSizes(port.NetworkInterface)
+			if err != nil {
+				log.Log.Error(err, "cannot validate ring sizes", "device", device.Name, "port", port.PCI)
+				return false, err
+			}
+			if rx, tx := desiredRingSizes(ringSize, current); current.Rx != rx || current.Tx != tx {
+				return false, nil
+			}
+		}
+	}
+
+	if coalescing := device.Spec.Configuration.Template.Coalescing; coalescing != nil {
+		for i, port := range ports {
+			if !netdevSettingsApply(device, i, port, "coalescing settings") {
+				continue
+			}
+			current, err := v.utils.GetCoalescing(port.NetworkInterface)
+			if err != nil {
+				log.Log.Error(err, "cannot validate coalescing settings", "device", device.Name, "port", port.PCI)
+				return false, err
+			}
+			if desiredCoalescing(coalescing, current) != current {
+				return false, nil
+			}
+		}
+	}
+
+	if offloads := desiredOffloads(device.Spec.Configuration.Template.Offloads); len(offloads) != 0 {
+		for i, port := range ports {
+			if !netdevSettingsApply(device, i, port, "offloads") {
+				continue
+			}
+			current, err := v.utils.GetEthtoolFeatures(port.NetworkInterface)
+			if err != nil {
+				log.Log.Error(err, "cannot validate offloads", "device", device.Name, "port", port.PCI)
+				return false, err
+			}
+			for _, offload := range offloads {
+				if current[offload.feature].Enabled != offload.enabled {
+					return false, nil
+				}
+			}
+		}
+	}
+
+	for i, port := range ports {
+		channels := portChannels(device.Spec.Configuration.Template, i)
+		if channels == nil || !netdevSettingsApply(device, i, port, "channels") {
+			continue
+		}
+		current, err := v.utils.GetChannels(port.NetworkInterface)
+		if err != nil {
+			log.Log.Error(err, "cannot validate channels", "device", device.Name, "port", port.PCI)
+			return false, err
+		}
+		combined, err := desiredCombinedChannels(v.utils, channels, port, current)
+		if err != nil {
+			log.Log.Error(err, "cannot validate channels", "device", device.Name, "port", port.PCI)
+			return false, err
+		}
+		if current.Combined != combined {
+			return false, nil
+		}
+	}
+
+	for i, port := range ports {
+		mtu := portMtu(device.Spec.Configuration.Template, i)
+		if mtu == nil || !netdevSettingsApply(device, i, port, "MTU") {
+			continue
+		}
+		interfaces, err := mtuInterfaces(v.utils, device, port, mtu)
+		if err != nil {
+			log.Log.Error(err, "cannot validate MTU", "device", device.Name, "port", port.PCI)
+			return false, err
+		}
+		for _, interfaceName := range interfaces {
+			current, err := v.utils.GetMtu(interfaceName)
+			if err != nil {
+				log.Log.Error(err, "cannot validate MTU", "device", device.Name, "interface", interfaceName)
+				return false, err
+			}
+			if current != mtu.Size {
+				return false, nil
+			}
+		}
+	}
+
+	sysctls, err := desiredSysctls(device)
+	if err != nil {
+		log.Log.Error(err, "cannot validate sysctls", "device", device.Name)
+		return false, err
+	}
+	for _, sysctl := range sysctls {
+		value, err := v.utils.GetSysctl(sysctl.Name)
+		if err != nil {
+			log.Log.Error(err, "cannot validate sysctls", "device", device.Name, "sysctl", sysctl.Name)
+			return false, err
+		}
+		if value != normalizeSysctlValue(sysctl.Value) {
+			return false, nil
+		}
+	}
+
+	if flowSteeringMode := string(device.Spec.Configuration.Template.FlowSteeringMode); flowSteeringMode != "" {
+		for _, port := range ports {
+			current, err := v.utils.GetDevlinkParam(port.PCI, consts.DevlinkParamFlowSteeringMode)
+			if err != nil {
+				log.Log.Error(err, "cannot validate flow steering mode", "device", device.Name, "port", port.PCI)
+				return false, err
+			}
+			if current != flowSteeringMode {
+				return false, nil
+			}
+		}
+	}
+
+	if eswitchMode := string(device.Spec.Configuration.Template.EswitchMode); eswitchMode != "" {
+		for _, port := range ports {
+			current, err := v.utils.GetEswitchMode(port.PCI)
+			if err != nil {
+				log.Log.Error(err, "cannot validate eswitch mode", "device", device.Name, "port", port.PCI)
+				return false, err
+			}
+			// Ports that aren't the eswitch manager can't change the mode
+			if current != "" && current != eswitchMode {
+				return false, nil
+			}
+		}
+	}
+
 	// Don't validate QoS settings if neither trust nor pfc changes are requested
 	if desiredTrust == "" && desiredPfc == "" {
 		return true, nil
 	}
 
-	for _, port := range ports {
+	for i, port := range ports {
+		if portLinkType(device.Spec.Configuration.Template, i) == consts.Infiniband {
+			continue
+		}
 		if port.NetworkInterface == "" {
 			err := fmt.Errorf("cannot apply QoS settings for device port %s, network interface is missing", port.PCI)
 			log.Log.Error(err, "cannot validate QoS settings", "device", device.Name, "port", port.PCI)
@@ -304,6 +999,42 @@ func (v *configValidationImpl) RuntimeConfigApplied(device *v1alpha1.NicDevice)
 		if actualTrust != desiredTrust || actualPfc != desiredPfc {
 			return false, nil
 		}
+
+		if tcBandwidth := desiredTcBandwidth(device); tcBandwidth != "" {
+			actualTcBandwidth, err := v.utils.GetTcBandwidth(port.NetworkInterface)
+			if err != nil {
+				log.Log.Error(err, "cannot validate ETS settings", "device", device.Name, "port", port.PCI)
+				return false, err
+			}
+			if actualTcBandwidth != tcBandwidth {
+				return false, nil
+			}
+		}
+
+		if ecn, dcqcnParameters := desiredCongestionControl(device); ecn != "" {
+			if !v.utils.IsCongestionControlSupported(port.NetworkInterface) {
+				// The apply reports the settings as unsupported
+				return false, nil
+			}
+			actualEcn, err := v.utils.GetEcn(port.NetworkInterface)
+			if err != nil {
+				log.Log.Error(err, "cannot validate congestion control settings", "device", device.Name, "port", port.PCI)
+				return false, err
+			}
+			if actualEcn != ecn {
+				return false, nil
+			}
+			for name, value := range dcqcnParameters {
+				actualValue, err := v.utils.GetDcqcnParameter(port.NetworkInterface, name)
+				if err != nil {
+					log.Log.Error(err, "cannot validate congestion control settings", "device", device.Name, "port", port.PCI)
+					return false, err
+				}
+				if actualValue != value {
+					return false, nil
+				}
+			}
+		}
 	}
 
 	return true, nil
@@ -318,16 +1049,16 @@ func (v *configValidationImpl) CalculateDesiredRuntimeConfig(device *v1alpha1.Ni
 
 	template := device.Spec.Configuration.Template
 
-	if template.PciPerformanceOptimized != nil && template.PciPerformanceOptimized.Enabled {
-		if template.PciPerformanceOptimized.MaxReadRequest != 0 {
-			maxReadRequestSize = template.PciPerformanceOptimized.MaxReadRequest
+	if pciPerformance := pciPerformanceSpec(template); pciPerformance != nil && pciPerformance.Enabled {
+		if pciPerformance.MaxReadRequest != 0 {
+			maxReadRequestSize = pciPerformance.MaxReadRequest
 		} else {
 			maxReadRequestSize = 4096
 		}
 	}
 
-	// QoS settings are not available for IB devices
-	if template.LinkType == consts.Infiniband {
+	// QoS settings are not available for IB devices, IB ports of a mixed device are skipped when applied
+	if !ethernetPortPresent(template, len(device.Status.Ports)) {
 		return maxReadRequestSize, "", ""
 	}
 
@@ -346,6 +1077,306 @@ func (v *configValidationImpl) CalculateDesiredRuntimeConfig(device *v1alpha1.Ni
 	return maxReadRequestSize, trust, pfc
 }
 
+// desiredRingSizes returns the RX and TX ring sizes requested for a network interface, clamped to the maximums reported by the device
+// the current sizes are kept for the omitted rings
+func desiredRingSizes(ringSize *v1alpha1.RingSizeSpec, current types.RingSizes) (int, int) {
+	rx, tx := current.Rx, current.Tx
+	if ringSize.Rx != 0 {
+		rx = ringSize.Rx
+		if current.RxMax != 0 {
+			rx = min(rx, current.RxMax)
+		}
+	}
+	if ringSize.Tx != 0 {
+		tx = ringSize.Tx
+		if current.TxMax != 0 {
+			tx = min(tx, current.TxMax)
+		}
+	}
+	return rx, tx
+}
+
+// desiredCoalescing returns the interrupt coalescing settings requested for a network interface
+// the current settings are kept for the omitted parameters
+func desiredCoalescing(spec *v1alpha1.CoalescingSpec, current types.Coalescing) types.Coalescing {
+	desired := current
+	if spec.AdaptiveRx != nil {
+		desired.AdaptiveRx = *spec.AdaptiveRx
+	}
+	if spec.AdaptiveTx != nil {
+		desired.AdaptiveTx = *spec.AdaptiveTx
+	}
+	if spec.RxUsecs != nil {
+		desired.RxUsecs = *spec.RxUsecs
+	}
+	if spec.RxFrames != nil {
+		desired.RxFrames = *spec.RxFrames
+	}
+	if spec.TxUsecs != nil {
+		desired.TxUsecs = *spec.TxUsecs
+	}
+	if spec.TxFrames != nil {
+		desired.TxFrames = *spec.TxFrames
+	}
+	return desired
+}
+
+// ethtoolOffload is the requested state of an ethtool offload feature
+type ethtoolOffload struct {
+	feature string
+	enabled bool
+}
+
+// desiredOffloads returns the ethtool offload features requested by the spec, the disabled ones go first
+// so that the offloads the kernel doesn't allow together, e.g. LRO and HW GRO, can be swapped
+func desiredOffloads(spec *v1alpha1.OffloadsSpec) []ethtoolOffload {
+	if spec == nil {
+		return nil
+	}
+
+	offloads := []ethtoolOffload{}
+	for feature, enabled := range map[string]*bool{
+		consts.EthtoolFeatureLro:     spec.LRO,
+		consts.EthtoolFeatureGro:     spec.GRO,
+		consts.EthtoolFeatureTso:     spec.TSO,
+		consts.EthtoolFeatureRxGroHw: spec.RxGroHw,
+	} {
+		if enabled != nil {
+			offloads = append(offloads, ethtoolOffload{feature: feature, enabled: *enabled})
+		}
+	}
+	sort.Slice(offloads, func(i, j int) bool {
+		if offloads[i].enabled != offloads[j].enabled {
+			return !offloads[i].enabled
+		}
+		return offloads[i].feature < offloads[j].feature
+	})
+	return offloads
+}
+
+// validateOffloads checks that the requested offloads can be enabled together, the kernel silently drops HW GRO
+// without GRO and LRO together with HW GRO, so such a spec would never converge
+func validateOffloads(spec *v1alpha1.OffloadsSpec) error {
+	if spec == nil || spec.RxGroHw == nil || !*spec.RxGroHw {
+		return nil
+	}
+	if spec.LRO != nil && *spec.LRO {
+		return types.IncorrectSpecError("lro and rxGroHw offloads can't be enabled together")
+	}
+	if spec.GRO != nil && !*spec.GRO {
+		return types.IncorrectSpecError("rxGroHw offload requires the gro offload")
+	}
+	return nil
+}
+
+// desiredCombinedChannels returns the combined channel count requested for the port's network interface,
+// defaults to the number of CPUs of the device's NUMA node clamped to the maximum reported by the device
+// returns types.IncorrectSpecError if the requested count exceeds the maximum
+func desiredCombinedChannels(utils HostUtils, channels *v1alpha1.ChannelsSpec, port v1alpha1.NicDevicePortSpec, current types.Channels) (int, error) {
+	if channels.Combined != 0 {
+		if current.CombinedMax != 0 && channels.Combined > current.CombinedMax {
+			return 0, types.IncorrectSpecError(fmt.Sprintf("%d combined channels requested for interface %s, the device supports at most %d",
+				channels.Combined, port.NetworkInterface, current.CombinedMax))
+		}
+		return channels.Combined, nil
+	}
+
+	numaCPUs, err := utils.GetNumaNodeCPUCount(port.PCI)
+	if err != nil {
+		return 0, err
+	}
+	// Devices without the NUMA affinity, e.g. in VMs, keep the driver default
+	if numaCPUs == 0 {
+		return current.Combined, nil
+	}
+	if current.CombinedMax != 0 {
+		return min(numaCPUs, current.CombinedMax), nil
+	}
+	return numaCPUs, nil
+}
+
+// mtuInterfaces returns the network interfaces of the port getting the MTU: the PF's interface and, if the MTU is propagated,
+// the interfaces of its VFs that have the MTU of the PF or the default MTU of the new VFs
+// the VFs with another MTU are owned by another component, e.g. the SriovNetworkNodePolicy of the SR-IOV network operator, and are skipped
+func mtuInterfaces(utils HostUtils, device *v1alpha1.NicDevice, port v1alpha1.NicDevicePortSpec, mtu *v1alpha1.MtuSpec) ([]string, error) {
+	interfaces := []string{port.NetworkInterface}
+	if !mtu.PropagateToVfs {
+		return interfaces, nil
+	}
+
+	pfMtu, err := utils.GetMtu(port.NetworkInterface)
+	if err != nil {
+		return nil, err
+	}
+	vfs, err := utils.GetVfPciAddresses(port.PCI)
+	if err != nil {
+		return nil, err
+	}
+	for _, vf := range vfs {
+		// VFs bound to vfio-pci or the other userspace drivers don't have a network interface
+		interfaceName := utils.GetInterfaceName(vf)
+		if interfaceName == "" {
+			continue
+		}
+		vfMtu, err := utils.GetMtu(interfaceName)
+		if err != nil {
+			return nil, err
+		}
+		if vfMtu != pfMtu && vfMtu != mtu.Size && vfMtu != consts.DefaultEthernetMtu {
+			log.Log.V(2).Info("VF has its own MTU, skipping it", "device", device.Name, "vf", vf, "mtu", vfMtu)
+			continue
+		}
+		interfaces = append(interfaces, interfaceName)
+	}
+	return interfaces, nil
+}
+
+// netdevSettingsApply returns true if the netdev settings of the template, e.g. the ring sizes or the MTU, apply to the port
+// the InfiniBand ports are skipped, their IPoIB interfaces are managed with the IB fabric settings, e.g. the IPoIB MTU is at most 4092,
+// the ports without a network interface, e.g. bound to a userspace driver, are skipped with a log entry
+func netdevSettingsApply(device *v1alpha1.NicDevice, index int, port v1alpha1.NicDevicePortSpec, settings string) bool {
+	if portLinkType(device.Spec.Configuration.Template, index) == consts.Infiniband {
+		return false
+	}
+	if port.NetworkInterface == "" {
+		log.Log.Info("port has no network interface, skipping its "+settings, "device", device.Name, "port", port.PCI)
+		return false
+	}
+	return true
+}
+
+// sysctlInterfacePlaceholder is replaced with the network interface of each port in the sysctl names of the template
+const sysctlInterfacePlaceholder = "{interface}"
+
+// sysctlNameRegex matches the sysctl names of the net subtree allowed in the template
+var sysctlNameRegex = regexp.MustCompile(`^net(\.[A-Za-z0-9_{}-]+)+$`)
+
+// validateSysctls checks that the template only sets the sysctls of the net subtree, each of them once
+func validateSysctls(sysctls []v1alpha1.SysctlSpec) error {
+	seen := map[string]bool{}
+	for _, sysctl := range sysctls {
+		if !sysctlNameRegex.MatchString(sysctl.Name) {
+			return types.IncorrectSpecError(fmt.Sprintf("sysctl %s is not in the net subtree", sysctl.Name))
+		}
+		if strings.Count(sysctl.Name, "{")+strings.Count(sysctl.Name, "}") != 2*strings.Count(sysctl.Name, sysctlInterfacePlaceholder) {
+			return types.IncorrectSpecError(fmt.Sprintf("sysctl %s has an unknown placeholder, only %s is supported",
+				sysctl.Name, sysctlInterfacePlaceholder))
+		}
+		if seen[sysctl.Name] {
+			return types.IncorrectSpecError(fmt.Sprintf("sysctl %s is set twice in the template", sysctl.Name))
+		}
+		seen[sysctl.Name] = true
+	}
+	return nil
+}
+
+// desiredSysctls returns the sysctls of the template with the interface placeholders replaced with the network interfaces
+// of the device's ports, dots of the interface names are escaped as slashes as in sysctl(8)
+func desiredSysctls(device *v1alpha1.NicDevice) ([]v1alpha1.SysctlSpec, error) {
+	sysctls := []v1alpha1.SysctlSpec{}
+	for _, sysctl := range device.Spec.Configuration.Template.Sysctls {
+		if !strings.Contains(sysctl.Name, sysctlInterfacePlaceholder) {
+			sysctls = append(sysctls, sysctl)
+			continue
+		}
+
+		for _, port := range device.Status.Ports {
+			if port.NetworkInterface == "" {
+				return nil, fmt.Errorf("cannot apply sysctl %s for device port %s, network interface is missing", sysctl.Name, port.PCI)
+			}
+			name := strings.ReplaceAll(sysctl.Name, sysctlInterfacePlaceholder, strings.ReplaceAll(port.NetworkInterface, ".", "/"))
+			sysctls = append(sysctls, v1alpha1.SysctlSpec{Name: name, Value: sysctl.Value})
+		}
+	}
+	return sysctls, nil
+}
+
+// normalizeSysctlValue normalizes the whitespace-separated values of a sysctl to single spaces, as reported by GetSysctl
+func normalizeSysctlValue(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// desiredTcBandwidth returns the ETS bandwidth shares of the traffic classes requested for the device's Ethernet ports,
+// empty if the current allocation should be kept
+func desiredTcBandwidth(device *v1alpha1.NicDevice) string {
+	template := device.Spec.Configuration.Template
+	if template.RoceOptimized == nil || !template.RoceOptimized.Enabled || template.RoceOptimized.Qos == nil {
+		return ""
+	}
+	if !ethernetPortPresent(template, len(device.Status.Ports)) {
+		return ""
+	}
+	return template.RoceOptimized.Qos.TcBandwidth
+}
+
+// desiredCongestionControl returns the ECN priorities and the DCQCN parameters requested for the device's Ethernet ports,
+// empty if the current congestion control settings should be kept
+func desiredCongestionControl(device *v1alpha1.NicDevice) (string, map[string]int) {
+	template := device.Spec.Configuration.Template
+	if template.RoceOptimized == nil || !template.RoceOptimized.Enabled || template.RoceOptimized.CongestionControl == nil {
+		return "", nil
+	}
+	if !ethernetPortPresent(template, len(device.Status.Ports)) {
+		return "", nil
+	}
+
+	congestionControl := template.RoceOptimized.CongestionControl
+	parameters := map[string]int{}
+	if dcqcn := congestionControl.Dcqcn; dcqcn != nil {
+		for name, value := range map[string]*int{
+			consts.DcqcnRpgAiRateParam:               dcqcn.RpgAiRate,
+			consts.DcqcnRpgHaiRateParam:              dcqcn.RpgHaiRate,
+			consts.DcqcnRpgTimeResetParam:            dcqcn.RpgTimeReset,
+			consts.DcqcnRpgByteResetParam:            dcqcn.RpgByteReset,
+			consts.DcqcnRpgThresholdParam:            dcqcn.RpgThreshold,
+			consts.DcqcnRpgMinRateParam:              dcqcn.RpgMinRate,
+			consts.DcqcnRpgGdParam:                   dcqcn.RpgGd,
+			consts.DcqcnRateReduceMonitorPeriodParam: dcqcn.RateReduceMonitorPeriod,
+			consts.DcqcnInitialAlphaValueParam:       dcqcn.InitialAlphaValue,
+			consts.DcqcnMinTimeBetweenCnpsParam:      dcqcn.MinTimeBetweenCnps,
+		} {
+			if value != nil {
+				parameters[name] = *value
+			}
+		}
+	}
+	return congestionControl.Ecn, parameters
+}
+
+// validateTcBandwidth checks that the ETS bandwidth shares of the traffic classes sum up to 100 percent
+func validateTcBandwidth(tcBandwidth string) error {
+	total := 0
+	for _, share := range strings.Split(tcBandwidth, ",") {
+		value, err := strconv.Atoi(share)
+		if err != nil || value > 100 {
+			return types.IncorrectSpecError(fmt.Sprintf("invalid ETS bandwidth share %q of a traffic class", share))
+		}
+		total += value
+	}
+	if total != 100 {
+		return types.IncorrectSpecError(fmt.Sprintf("ETS bandwidth shares of the traffic classes sum up to %d%%, 100%% is required", total))
+	}
+	return nil
+}
+
+// devlinkResourceApplied returns true if the devlink resource has the desired size and no other size is pending reload
+func devlinkResourceApplied(resource types.DevlinkResource, desiredSize uint64) bool {
+	return resource.Size == desiredSize && (resource.SizeNew == nil || *resource.SizeNew == desiredSize)
+}
+
+// validateDevlinkResourceSize checks that the desired size fits the limits of the devlink resource
+func validateDevlinkResourceSize(pciAddr string, path string, resource types.DevlinkResource, size uint64) error {
+	if size < resource.SizeMin || size > resource.SizeMax {
+		return types.IncorrectSpecError(
+			fmt.Sprintf("size %d of devlink resource %s is out of range [%d, %d] for device %s", size, path, resource.SizeMin, resource.SizeMax, pciAddr))
+	}
+	if resource.SizeGranularity > 1 && size%resource.SizeGranularity != 0 {
+		return types.IncorrectSpecError(
+			fmt.Sprintf("size %d of devlink resource %s is not a multiple of %d for device %s", size, path, resource.SizeGranularity, pciAddr))
+	}
+	return nil
+}
+
 func newConfigValidation(utils HostUtils, eventRecorder record.EventRecorder) configValidation {
 	return &configValidationImpl{utils: utils, eventRecorder: eventRecorder}
 }
@@ -23,6 +23,7 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/stretchr/testify/mock"
+	"k8s.io/utils/ptr"
 
 	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
 	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
@@ -176,6 +177,157 @@ var _ = Describe("ConfigValidationImpl", func() {
 			Expect(nvParams).To(HaveKeyWithValue(consts.Cnp802pPrioP2Param, "6"))
 		})
 
+		It("should apply the ATS setting if the device supports it", func() {
+			device := &v1alpha1.NicDevice{
+				Spec: v1alpha1.NicDeviceSpec{
+					Configuration: &v1alpha1.NicDeviceConfigurationSpec{
+						Template: &v1alpha1.ConfigurationTemplateSpec{
+							NumVfs:   0,
+							LinkType: consts.Ethernet,
+							Ats:      &v1alpha1.AtsSpec{Enabled: true},
+						},
+					},
+				},
+				Status: v1alpha1.NicDeviceStatus{
+					Ports: []v1alpha1.NicDevicePortSpec{
+						{PCI: "0000:03:00.0"},
+					},
+				},
+			}
+
+			query := types.NewNvConfigQuery()
+			query.DefaultConfig[consts.AtsEnabledParam] = []string{"False", "0"}
+
+			nvParams, err := validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(nvParams).To(HaveKeyWithValue(consts.AtsEnabledParam, consts.NvParamTrue))
+
+			// Unsupported ATS doesn't fail the rest of the spec
+			delete(query.DefaultConfig, consts.AtsEnabledParam)
+			nvParams, err = validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(nvParams).NotTo(HaveKey(consts.AtsEnabledParam))
+			Expect(nvParams).To(HaveKeyWithValue(consts.SriovEnabledParam, consts.NvParamFalse))
+		})
+		It("should reject ATS enabled together with GPU Direct optimizations", func() {
+			device := &v1alpha1.NicDevice{
+				Spec: v1alpha1.NicDeviceSpec{
+					Configuration: &v1alpha1.NicDeviceConfigurationSpec{
+						Template: &v1alpha1.ConfigurationTemplateSpec{
+							NumVfs:                  0,
+							LinkType:                consts.Ethernet,
+							PciPerformanceOptimized: &v1alpha1.PciPerformanceOptimizedSpec{Enabled: true, MaxAccOutRead: 44},
+							GpuDirectOptimized:      &v1alpha1.GpuDirectOptimizedSpec{Enabled: true, Env: consts.EnvBaremetal},
+							Ats:                     &v1alpha1.AtsSpec{Enabled: true},
+						},
+					},
+				},
+				Status: v1alpha1.NicDeviceStatus{
+					Ports: []v1alpha1.NicDevicePortSpec{
+						{PCI: "0000:03:00.0"},
+					},
+				},
+			}
+
+			_, err := validator.ConstructNvParamMapFromTemplate(device, types.NewNvConfigQuery())
+			Expect(types.IsIncorrectSpecError(err)).To(BeTrue())
+		})
+
+		It("should apply the PCI performance preset for AMD platforms", func() {
+			mockHostUtils.On("GetPCILinkSpeed", mock.Anything).Return(16, nil)
+
+			device := &v1alpha1.NicDevice{
+				Spec: v1alpha1.NicDeviceSpec{
+					Configuration: &v1alpha1.NicDeviceConfigurationSpec{
+						Template: &v1alpha1.ConfigurationTemplateSpec{
+							NumVfs:   0,
+							LinkType: consts.Ethernet,
+							PciPerformanceOptimized: &v1alpha1.PciPerformanceOptimizedSpec{
+								Enabled: true,
+								Preset:  consts.PciPerformancePresetAmd,
+							},
+						},
+					},
+				},
+				Status: v1alpha1.NicDeviceStatus{
+					Ports: []v1alpha1.NicDevicePortSpec{
+						{PCI: "0000:03:00.0"},
+					},
+				},
+			}
+
+			query := types.NewNvConfigQuery()
+			query.DefaultConfig[consts.PciWrOrderingParam] = []string{"per_mkey", "0"}
+
+			nvParams, err := validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(nvParams).To(HaveKeyWithValue(consts.MaxAccOutReadParam, "44"))
+			Expect(nvParams).To(HaveKeyWithValue(consts.PciWrOrderingParam, "1"))
+
+			// Explicit fields take precedence over the preset
+			device.Spec.Configuration.Template.PciPerformanceOptimized.MaxAccOutRead = 32
+			relaxedOrdering := false
+			device.Spec.Configuration.Template.PciPerformanceOptimized.RelaxedOrdering = &relaxedOrdering
+			nvParams, err = validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(nvParams).To(HaveKeyWithValue(consts.MaxAccOutReadParam, "32"))
+			Expect(nvParams).To(HaveKeyWithValue(consts.PciWrOrderingParam, "0"))
+
+			device.Spec.Configuration.Template.PciPerformanceOptimized.RelaxedOrdering = nil
+			delete(query.DefaultConfig, consts.PciWrOrderingParam)
+			_, err = validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).To(MatchError("incorrect spec: Device does not support relaxed ordering nv config parameter"))
+		})
+
+		It("should apply relaxed ordering without the rest of the PCI performance optimizations", func() {
+			relaxedOrdering := true
+			device := &v1alpha1.NicDevice{
+				Spec: v1alpha1.NicDeviceSpec{
+					Configuration: &v1alpha1.NicDeviceConfigurationSpec{
+						Template: &v1alpha1.ConfigurationTemplateSpec{
+							NumVfs:   0,
+							LinkType: consts.Ethernet,
+							PciPerformanceOptimized: &v1alpha1.PciPerformanceOptimizedSpec{
+								Enabled:         false,
+								RelaxedOrdering: &relaxedOrdering,
+							},
+						},
+					},
+				},
+				Status: v1alpha1.NicDeviceStatus{
+					Ports: []v1alpha1.NicDevicePortSpec{
+						{PCI: "0000:03:00.0"},
+					},
+				},
+			}
+
+			query := types.NewNvConfigQuery()
+			query.DefaultConfig[consts.PciWrOrderingParam] = []string{"per_mkey", "0"}
+			query.DefaultConfig[consts.MaxAccOutReadParam] = []string{"0"}
+
+			nvParams, err := validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(nvParams).To(HaveKeyWithValue(consts.PciWrOrderingParam, "1"))
+			Expect(nvParams).NotTo(HaveKey(consts.MaxAccOutReadParam))
+
+			relaxedOrdering = false
+			nvParams, err = validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(nvParams).To(HaveKeyWithValue(consts.PciWrOrderingParam, "0"))
+
+			// Without the explicit field the parameter is left as is
+			device.Spec.Configuration.Template.PciPerformanceOptimized.RelaxedOrdering = nil
+			nvParams, err = validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(nvParams).NotTo(HaveKey(consts.PciWrOrderingParam))
+
+			relaxedOrdering = true
+			device.Spec.Configuration.Template.PciPerformanceOptimized.RelaxedOrdering = &relaxedOrdering
+			delete(query.DefaultConfig, consts.PciWrOrderingParam)
+			_, err = validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).To(MatchError("incorrect spec: Device does not support relaxed ordering nv config parameter"))
+		})
+
 		It("should skip the MaxAccOutRead if the default is not 0", func() {
 			mockHostUtils.On("GetPCILinkSpeed", mock.Anything).Return(16, nil)
 
@@ -304,7 +456,78 @@ var _ = Describe("ConfigValidationImpl", func() {
 			_, err := validator.ConstructNvParamMapFromTemplate(device, query)
 			Expect(err).To(MatchError("incorrect spec: GpuDirectOptimized should only be enabled together with PciPerformanceOptimized"))
 		})
-		It("should ignore raw config for the second port if device is single port", func() {
+		It("should expand the GPUDirect RDMA preset without PciPerformanceOptimized", func() {
+			device := &v1alpha1.NicDevice{
+				Spec: v1alpha1.NicDeviceSpec{
+					Configuration: &v1alpha1.NicDeviceConfigurationSpec{
+						Template: &v1alpha1.ConfigurationTemplateSpec{
+							NumVfs:   0,
+							LinkType: consts.Ethernet,
+							GpuDirectOptimized: &v1alpha1.GpuDirectOptimizedSpec{
+								Enabled: true,
+								Env:     consts.EnvBaremetal,
+								Preset:  consts.GpuDirectPresetRdma,
+							},
+						},
+					},
+				},
+				Status: v1alpha1.NicDeviceStatus{
+					Ports: []v1alpha1.NicDevicePortSpec{
+						{PCI: "0000:03:00.0"},
+					},
+				},
+			}
+
+			query := types.NewNvConfigQuery()
+			query.CurrentConfig[consts.AdvancedPCISettingsParam] = []string{consts.NvParamTrue}
+			query.DefaultConfig[consts.MaxAccOutReadParam] = []string{consts.NvParamZero}
+			query.DefaultConfig[consts.PciWrOrderingParam] = []string{"per_mkey", "0"}
+
+			nvParams, err := validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(nvParams).To(HaveKeyWithValue(consts.AtsEnabledParam, consts.NvParamFalse))
+			Expect(nvParams).To(HaveKeyWithValue(consts.MaxAccOutReadParam, consts.NvParamZero))
+			Expect(nvParams).To(HaveKeyWithValue(consts.PciWrOrderingParam, "1"))
+
+			// Explicit pciPerformanceOptimized fields take precedence over the preset
+			device.Spec.Configuration.Template.PciPerformanceOptimized = &v1alpha1.PciPerformanceOptimizedSpec{
+				MaxAccOutRead:   32,
+				RelaxedOrdering: ptr.To(false),
+			}
+			nvParams, err = validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(nvParams).To(HaveKeyWithValue(consts.MaxAccOutReadParam, "32"))
+			Expect(nvParams).To(HaveKeyWithValue(consts.PciWrOrderingParam, "0"))
+		})
+		It("should reject ETS bandwidth shares that don't sum up to 100 percent", func() {
+			device := &v1alpha1.NicDevice{
+				Spec: v1alpha1.NicDeviceSpec{
+					Configuration: &v1alpha1.NicDeviceConfigurationSpec{
+						Template: &v1alpha1.ConfigurationTemplateSpec{
+							NumVfs:   0,
+							LinkType: consts.Ethernet,
+							RoceOptimized: &v1alpha1.RoceOptimizedSpec{
+								Enabled: true,
+								Qos:     &v1alpha1.QosSpec{Trust: "dscp", PFC: "0,0,0,1,0,0,0,0", TcBandwidth: "0,0,0,60,30,0,0,0"},
+							},
+						},
+					},
+				},
+				Status: v1alpha1.NicDeviceStatus{
+					Ports: []v1alpha1.NicDevicePortSpec{
+						{PCI: "0000:03:00.0"},
+					},
+				},
+			}
+
+			_, err := validator.ConstructNvParamMapFromTemplate(device, types.NewNvConfigQuery())
+			Expect(err).To(MatchError("incorrect spec: ETS bandwidth shares of the traffic classes sum up to 90%, 100% is required"))
+
+			device.Spec.Configuration.Template.RoceOptimized.Qos.TcBandwidth = "0,0,0,60,40,0,0,0"
+			_, err = validator.ConstructNvParamMapFromTemplate(device, types.NewNvConfigQuery())
+			Expect(err).NotTo(HaveOccurred())
+		})
+		It("should fail on raw config for the second port if device is single port", func() {
 			mockHostUtils.On("GetPCILinkSpeed", mock.Anything).Return(16, nil)
 
 			device := &v1alpha1.NicDevice{
@@ -335,10 +558,9 @@ var _ = Describe("ConfigValidationImpl", func() {
 
 			query := types.NewNvConfigQuery()
 
-			nvParams, err := validator.ConstructNvParamMapFromTemplate(device, query)
-			Expect(err).NotTo(HaveOccurred())
-			Expect(nvParams).To(HaveKeyWithValue("TEST_P1", "test"))
-			Expect(nvParams).NotTo(HaveKey("TEST_P2"))
+			_, err := validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).To(MatchError("incorrect spec: port count mismatch: template sets second port parameter TEST_P2 but device has a single port"))
+			Expect(types.IsIncorrectSpecError(err)).To(BeTrue())
 		})
 		It("should apply raw config for the second port if device is dual port", func() {
 			mockHostUtils.On("GetPCILinkSpeed", mock.Anything).Return(16, nil)
@@ -377,6 +599,112 @@ var _ = Describe("ConfigValidationImpl", func() {
 			Expect(nvParams).To(HaveKeyWithValue("TEST_P1", "test"))
 			Expect(nvParams).To(HaveKeyWithValue("TEST_P2", "test"))
 		})
+		It("should let raw config take precedence over the template fields", func() {
+			mockHostUtils.On("GetPCILinkSpeed", mock.Anything).Return(16, nil)
+
+			device := &v1alpha1.NicDevice{
+				Spec: v1alpha1.NicDeviceSpec{
+					Configuration: &v1alpha1.NicDeviceConfigurationSpec{
+						Template: &v1alpha1.ConfigurationTemplateSpec{
+							NumVfs:   8,
+							LinkType: consts.Ethernet,
+							RawNvConfig: []v1alpha1.NvConfigParam{
+								{Name: consts.MaxAccOutReadParam, Value: "44"},
+								{Name: "LOG_MAX_QUEUE", Value: "17"},
+								{Name: "LOG_MAX_QUEUE", Value: "0x11"},
+							},
+						},
+					},
+				},
+				Status: v1alpha1.NicDeviceStatus{
+					Ports: []v1alpha1.NicDevicePortSpec{
+						{PCI: "0000:03:00.0"},
+					},
+				},
+			}
+
+			query := types.NewNvConfigQuery()
+			query.DefaultConfig[consts.MaxAccOutReadParam] = []string{"0"}
+
+			nvParams, err := validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(nvParams).To(HaveKeyWithValue(consts.SriovEnabledParam, consts.NvParamTrue))
+			Expect(nvParams).To(HaveKeyWithValue(consts.SriovNumOfVfsParam, "8"))
+			Expect(nvParams).To(HaveKeyWithValue(consts.MaxAccOutReadParam, "44"))
+			Expect(nvParams).To(HaveKeyWithValue("LOG_MAX_QUEUE", "0x11"))
+		})
+		It("should fail if raw config sets a parameter twice with different values", func() {
+			mockHostUtils.On("GetPCILinkSpeed", mock.Anything).Return(16, nil)
+
+			device := &v1alpha1.NicDevice{
+				Spec: v1alpha1.NicDeviceSpec{
+					Configuration: &v1alpha1.NicDeviceConfigurationSpec{
+						Template: &v1alpha1.ConfigurationTemplateSpec{
+							NumVfs:   0,
+							LinkType: consts.Ethernet,
+							RawNvConfig: []v1alpha1.NvConfigParam{
+								{Name: "LOG_MAX_QUEUE", Value: "17"},
+								{Name: "LOG_MAX_QUEUE", Value: "20"},
+							},
+						},
+					},
+				},
+				Status: v1alpha1.NicDeviceStatus{
+					Ports: []v1alpha1.NicDevicePortSpec{
+						{PCI: "0000:03:00.0"},
+					},
+				},
+			}
+
+			query := types.NewNvConfigQuery()
+
+			_, err := validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).To(MatchError("incorrect spec: rawNvConfig sets parameter LOG_MAX_QUEUE twice with different values 17 and 20"))
+		})
+		It("should fail if raw config sets NUM_OF_VFS without enabling SR-IOV", func() {
+			mockHostUtils.On("GetPCILinkSpeed", mock.Anything).Return(16, nil)
+
+			device := &v1alpha1.NicDevice{
+				Spec: v1alpha1.NicDeviceSpec{
+					Configuration: &v1alpha1.NicDeviceConfigurationSpec{
+						Template: &v1alpha1.ConfigurationTemplateSpec{
+							NumVfs:   0,
+							LinkType: consts.Ethernet,
+							RawNvConfig: []v1alpha1.NvConfigParam{
+								{Name: consts.SriovNumOfVfsParam, Value: "8"},
+							},
+						},
+					},
+				},
+				Status: v1alpha1.NicDeviceStatus{
+					Ports: []v1alpha1.NicDevicePortSpec{
+						{PCI: "0000:03:00.0"},
+					},
+				},
+			}
+
+			query := types.NewNvConfigQuery()
+
+			_, err := validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).To(MatchError("incorrect spec: NUM_OF_VFS=8 requires SRIOV_EN to be enabled"))
+
+			device.Spec.Configuration.Template.NumVfs = 4
+			device.Spec.Configuration.Template.RawNvConfig = []v1alpha1.NvConfigParam{
+				{Name: consts.SriovEnabledParam, Value: "False"},
+			}
+			_, err = validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).To(MatchError("incorrect spec: NUM_OF_VFS=4 requires SRIOV_EN to be enabled"))
+
+			device.Spec.Configuration.Template.NumVfs = 0
+			device.Spec.Configuration.Template.RawNvConfig = []v1alpha1.NvConfigParam{
+				{Name: consts.SriovEnabledParam, Value: "True"},
+				{Name: consts.SriovNumOfVfsParam, Value: "16"},
+			}
+			nvParams, err := validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(nvParams).To(HaveKeyWithValue(consts.SriovEnabledParam, "True"))
+			Expect(nvParams).To(HaveKeyWithValue(consts.SriovNumOfVfsParam, "16"))
+		})
 		It("should report an error when LinkType cannot be changed and template differs from the actual status", func() {
 			mockHostUtils.On("GetLinkType", mock.Anything).Return(consts.Ethernet)
 			mockHostUtils.On("GetPCILinkSpeed", mock.Anything).Return(16, nil)
@@ -412,18 +740,17 @@ var _ = Describe("ConfigValidationImpl", func() {
 			_, err := validator.ConstructNvParamMapFromTemplate(device, query)
 			Expect(err).To(MatchError("incorrect spec: device does not support link type change, wrong link type provided in the template, should be: Ethernet"))
 		})
-		It("should not report an error when LinkType can be changed and template differs from the actual status", func() {
+		It("should report an error when LinkType cannot be changed and a port override differs from the actual status", func() {
 			mockHostUtils.On("GetLinkType", mock.Anything).Return(consts.Ethernet)
-			mockHostUtils.On("GetPCILinkSpeed", mock.Anything).Return(16, nil)
 
 			device := &v1alpha1.NicDevice{
 				Spec: v1alpha1.NicDeviceSpec{
 					Configuration: &v1alpha1.NicDeviceConfigurationSpec{
 						Template: &v1alpha1.ConfigurationTemplateSpec{
 							NumVfs:   0,
-							LinkType: consts.Infiniband,
-							PciPerformanceOptimized: &v1alpha1.PciPerformanceOptimizedSpec{
-								Enabled: true,
+							LinkType: consts.Ethernet,
+							Ports: []v1alpha1.PortConfigurationSpec{
+								{Port: 2, LinkType: consts.Infiniband},
 							},
 						},
 					},
@@ -442,17 +769,15 @@ var _ = Describe("ConfigValidationImpl", func() {
 				},
 			}
 
-			defaultValues := map[string][]string{
-				consts.LinkTypeP1Param: {consts.Ethernet},
-			}
 			query := types.NewNvConfigQuery()
-			query.DefaultConfig = defaultValues
 
-			_, err := validator.ConstructNvParamMapFromTemplate(device, query)
-			Expect(err).NotTo(HaveOccurred())
+			nvParams, err := validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).To(MatchError("incorrect spec: device does not support link type change, wrong link type provided in the template, should be: Ethernet"))
+			Expect(nvParams).NotTo(HaveKey(consts.LinkTypeP1Param))
+			Expect(nvParams).NotTo(HaveKey(consts.LinkTypeP2Param))
 		})
-		It("should not report an error when LinkType cannot be changed and template matches the actual status", func() {
-			mockHostUtils.On("GetLinkType", mock.Anything).Return(consts.Infiniband)
+		It("should not report an error when LinkType can be changed and template differs from the actual status", func() {
+			mockHostUtils.On("GetLinkType", mock.Anything).Return(consts.Ethernet)
 			mockHostUtils.On("GetPCILinkSpeed", mock.Anything).Return(16, nil)
 
 			device := &v1alpha1.NicDevice{
@@ -468,50 +793,730 @@ var _ = Describe("ConfigValidationImpl", func() {
 					},
 				},
 				Status: v1alpha1.NicDeviceStatus{
-					Ports: []v1alpha1.NicDevicePortSpec{
-						{
-							PCI:              "0000:03:00.0",
-							NetworkInterface: "enp3s0f0np0",
-						},
-						{
-							PCI:              "0000:03:00.1",
-							NetworkInterface: "enp3s0f1np1",
-						},
-					},
+					Ports: []v1alpha1.NicDevicePortSpec{
+						{
+							PCI:              "0000:03:00.0",
+							NetworkInterface: "enp3s0f0np0",
+						},
+						{
+							PCI:              "0000:03:00.1",
+							NetworkInterface: "enp3s0f1np1",
+						},
+					},
+				},
+			}
+
+			defaultValues := map[string][]string{
+				consts.LinkTypeP1Param: {consts.Ethernet},
+			}
+			query := types.NewNvConfigQuery()
+			query.DefaultConfig = defaultValues
+
+			_, err := validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).NotTo(HaveOccurred())
+		})
+		It("should not report an error when LinkType cannot be changed and template matches the actual status", func() {
+			mockHostUtils.On("GetLinkType", mock.Anything).Return(consts.Infiniband)
+			mockHostUtils.On("GetPCILinkSpeed", mock.Anything).Return(16, nil)
+
+			device := &v1alpha1.NicDevice{
+				Spec: v1alpha1.NicDeviceSpec{
+					Configuration: &v1alpha1.NicDeviceConfigurationSpec{
+						Template: &v1alpha1.ConfigurationTemplateSpec{
+							NumVfs:   0,
+							LinkType: consts.Infiniband,
+							PciPerformanceOptimized: &v1alpha1.PciPerformanceOptimizedSpec{
+								Enabled: true,
+							},
+						},
+					},
+				},
+				Status: v1alpha1.NicDeviceStatus{
+					Ports: []v1alpha1.NicDevicePortSpec{
+						{
+							PCI:              "0000:03:00.0",
+							NetworkInterface: "enp3s0f0np0",
+						},
+						{
+							PCI:              "0000:03:00.1",
+							NetworkInterface: "enp3s0f1np1",
+						},
+					},
+				},
+			}
+
+			query := types.NewNvConfigQuery()
+
+			_, err := validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).NotTo(HaveOccurred())
+		})
+		It("should return an error when RoceOptimized is enabled with linkType Infiniband", func() {
+			mockHostUtils.On("GetPCILinkSpeed", mock.Anything).Return(16, nil)
+
+			device := &v1alpha1.NicDevice{
+				Spec: v1alpha1.NicDeviceSpec{
+					Configuration: &v1alpha1.NicDeviceConfigurationSpec{
+						Template: &v1alpha1.ConfigurationTemplateSpec{
+							NumVfs:   0,
+							LinkType: consts.Infiniband,
+							RoceOptimized: &v1alpha1.RoceOptimizedSpec{
+								Enabled: true,
+							},
+						},
+					},
+				},
+				Status: v1alpha1.NicDeviceStatus{
+					Ports: []v1alpha1.NicDevicePortSpec{
+						{PCI: "0000:03:00.0"},
+					},
+				},
+			}
+
+			query := types.NewNvConfigQuery()
+
+			_, err := validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).To(MatchError("incorrect spec: RoceOptimized settings can only be used with link type Ethernet"))
+		})
+		It("should return an error when the switchdev eswitch mode is requested with linkType Infiniband", func() {
+			mockHostUtils.On("GetPCILinkSpeed", mock.Anything).Return(16, nil)
+
+			device := &v1alpha1.NicDevice{
+				Spec: v1alpha1.NicDeviceSpec{
+					Configuration: &v1alpha1.NicDeviceConfigurationSpec{
+						Template: &v1alpha1.ConfigurationTemplateSpec{
+							NumVfs:      0,
+							LinkType:    consts.Infiniband,
+							EswitchMode: consts.EswitchModeSwitchdev,
+						},
+					},
+				},
+				Status: v1alpha1.NicDeviceStatus{
+					Ports: []v1alpha1.NicDevicePortSpec{
+						{PCI: "0000:03:00.0"},
+					},
+				},
+			}
+
+			query := types.NewNvConfigQuery()
+
+			_, err := validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).To(MatchError("incorrect spec: switchdev eswitch mode is only supported for Ethernet ports"))
+		})
+		It("should return an error when the requested offloads can't be enabled together", func() {
+			mockHostUtils.On("GetPCILinkSpeed", mock.Anything).Return(16, nil)
+
+			device := &v1alpha1.NicDevice{
+				Spec: v1alpha1.NicDeviceSpec{
+					Configuration: &v1alpha1.NicDeviceConfigurationSpec{
+						Template: &v1alpha1.ConfigurationTemplateSpec{
+							NumVfs:   0,
+							LinkType: consts.Ethernet,
+							Offloads: &v1alpha1.OffloadsSpec{LRO: ptr.To(true), RxGroHw: ptr.To(true)},
+						},
+					},
+				},
+				Status: v1alpha1.NicDeviceStatus{
+					Ports: []v1alpha1.NicDevicePortSpec{
+						{PCI: "0000:03:00.0"},
+					},
+				},
+			}
+
+			query := types.NewNvConfigQuery()
+
+			_, err := validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).To(MatchError("incorrect spec: lro and rxGroHw offloads can't be enabled together"))
+
+			device.Spec.Configuration.Template.Offloads = &v1alpha1.OffloadsSpec{GRO: ptr.To(false), RxGroHw: ptr.To(true)}
+			_, err = validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).To(MatchError("incorrect spec: rxGroHw offload requires the gro offload"))
+		})
+		It("should apply the link type of the port overrides on a dual port device", func() {
+			mockHostUtils.On("GetPCILinkSpeed", mock.Anything).Return(16, nil)
+
+			device := &v1alpha1.NicDevice{
+				Spec: v1alpha1.NicDeviceSpec{
+					Configuration: &v1alpha1.NicDeviceConfigurationSpec{
+						Template: &v1alpha1.ConfigurationTemplateSpec{
+							NumVfs:   0,
+							LinkType: consts.Ethernet,
+							Ports: []v1alpha1.PortConfigurationSpec{
+								{Port: 2, LinkType: consts.Infiniband},
+							},
+						},
+					},
+				},
+				Status: v1alpha1.NicDeviceStatus{
+					Ports: []v1alpha1.NicDevicePortSpec{
+						{PCI: "0000:03:00.0"},
+						{PCI: "0000:03:00.1"},
+					},
+				},
+			}
+
+			query := types.NewNvConfigQuery()
+			query.DefaultConfig[consts.LinkTypeP1Param] = []string{consts.NvParamLinkTypeEthernet}
+
+			nvParams, err := validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(nvParams).To(HaveKeyWithValue(consts.LinkTypeP1Param, consts.NvParamLinkTypeEthernet))
+			Expect(nvParams).To(HaveKeyWithValue(consts.LinkTypeP2Param, consts.NvParamLinkTypeInfiniband))
+		})
+		It("should apply RoceOptimized settings only to the Ethernet port of a mixed device", func() {
+			mockHostUtils.On("GetPCILinkSpeed", mock.Anything).Return(16, nil)
+
+			device := &v1alpha1.NicDevice{
+				Spec: v1alpha1.NicDeviceSpec{
+					Configuration: &v1alpha1.NicDeviceConfigurationSpec{
+						Template: &v1alpha1.ConfigurationTemplateSpec{
+							NumVfs:   0,
+							LinkType: consts.Ethernet,
+							RoceOptimized: &v1alpha1.RoceOptimizedSpec{
+								Enabled: true,
+							},
+							Ports: []v1alpha1.PortConfigurationSpec{
+								{Port: 2, LinkType: consts.Infiniband},
+							},
+						},
+					},
+				},
+				Status: v1alpha1.NicDeviceStatus{
+					Ports: []v1alpha1.NicDevicePortSpec{
+						{PCI: "0000:03:00.0"},
+						{PCI: "0000:03:00.1"},
+					},
+				},
+			}
+
+			query := types.NewNvConfigQuery()
+			query.DefaultConfig = map[string][]string{
+				consts.RoceCcPrioMaskP2Param: {"testRoceCcP2"},
+				consts.CnpDscpP2Param:        {"testDscpP2"},
+				consts.Cnp802pPrioP2Param:    {"test802PrioP2"},
+			}
+
+			nvParams, err := validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(nvParams).To(HaveKeyWithValue(consts.RoceCcPrioMaskP1Param, "255"))
+			Expect(nvParams).To(HaveKeyWithValue(consts.CnpDscpP1Param, "4"))
+			Expect(nvParams).To(HaveKeyWithValue(consts.Cnp802pPrioP1Param, "6"))
+			Expect(nvParams).To(HaveKeyWithValue(consts.RoceCcPrioMaskP2Param, "testRoceCcP2"))
+			Expect(nvParams).To(HaveKeyWithValue(consts.CnpDscpP2Param, "testDscpP2"))
+			Expect(nvParams).To(HaveKeyWithValue(consts.Cnp802pPrioP2Param, "test802PrioP2"))
+		})
+		It("should apply raw config of the port overrides with the port suffix", func() {
+			mockHostUtils.On("GetPCILinkSpeed", mock.Anything).Return(16, nil)
+
+			device := &v1alpha1.NicDevice{
+				Spec: v1alpha1.NicDeviceSpec{
+					Configuration: &v1alpha1.NicDeviceConfigurationSpec{
+						Template: &v1alpha1.ConfigurationTemplateSpec{
+							NumVfs:   0,
+							LinkType: consts.Ethernet,
+							RawNvConfig: []v1alpha1.NvConfigParam{
+								{
+									Name:  "TEST_P2",
+									Value: "template",
+								},
+							},
+							Ports: []v1alpha1.PortConfigurationSpec{
+								{
+									Port: 2,
+									RawNvConfig: []v1alpha1.NvConfigParam{
+										{
+											Name:  "TEST",
+											Value: "port",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+				Status: v1alpha1.NicDeviceStatus{
+					Ports: []v1alpha1.NicDevicePortSpec{
+						{PCI: "0000:03:00.0"},
+						{PCI: "0000:03:00.1"},
+					},
+				},
+			}
+
+			query := types.NewNvConfigQuery()
+
+			nvParams, err := validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(nvParams).To(HaveKeyWithValue("TEST_P2", "port"))
+			Expect(nvParams).NotTo(HaveKey("TEST_P1"))
+		})
+		It("should fail on a port override for the second port if device is single port", func() {
+			device := &v1alpha1.NicDevice{
+				Spec: v1alpha1.NicDeviceSpec{
+					Configuration: &v1alpha1.NicDeviceConfigurationSpec{
+						Template: &v1alpha1.ConfigurationTemplateSpec{
+							NumVfs:   0,
+							LinkType: consts.Ethernet,
+							Ports: []v1alpha1.PortConfigurationSpec{
+								{Port: 2, LinkType: consts.Infiniband},
+							},
+						},
+					},
+				},
+				Status: v1alpha1.NicDeviceStatus{
+					Ports: []v1alpha1.NicDevicePortSpec{
+						{PCI: "0000:03:00.0"},
+					},
+				},
+			}
+
+			query := types.NewNvConfigQuery()
+
+			_, err := validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).To(MatchError("incorrect spec: port count mismatch: template configures port 2 but device has 1 port(s)"))
+			Expect(types.IsIncorrectSpecError(err)).To(BeTrue())
+		})
+		It("should fail if the same port is configured twice", func() {
+			device := &v1alpha1.NicDevice{
+				Spec: v1alpha1.NicDeviceSpec{
+					Configuration: &v1alpha1.NicDeviceConfigurationSpec{
+						Template: &v1alpha1.ConfigurationTemplateSpec{
+							NumVfs:   0,
+							LinkType: consts.Ethernet,
+							Ports: []v1alpha1.PortConfigurationSpec{
+								{Port: 1, LinkType: consts.Infiniband},
+								{Port: 1, LinkType: consts.Ethernet},
+							},
+						},
+					},
+				},
+				Status: v1alpha1.NicDeviceStatus{
+					Ports: []v1alpha1.NicDevicePortSpec{
+						{PCI: "0000:03:00.0"},
+						{PCI: "0000:03:00.1"},
+					},
+				},
+			}
+
+			query := types.NewNvConfigQuery()
+
+			_, err := validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).To(MatchError("incorrect spec: port 1 is configured twice in the template"))
+		})
+
+		Describe("boot options", func() {
+			var (
+				device *v1alpha1.NicDevice
+				query  types.NvConfigQuery
+			)
+
+			BeforeEach(func() {
+				device = &v1alpha1.NicDevice{
+					Spec: v1alpha1.NicDeviceSpec{
+						Configuration: &v1alpha1.NicDeviceConfigurationSpec{
+							Template: &v1alpha1.ConfigurationTemplateSpec{
+								NumVfs:   0,
+								LinkType: consts.Ethernet,
+							},
+						},
+					},
+					Status: v1alpha1.NicDeviceStatus{
+						Ports: []v1alpha1.NicDevicePortSpec{
+							{PCI: "0000:03:00.0"},
+							{PCI: "0000:03:00.1"},
+						},
+					},
+				}
+				query = types.NewNvConfigQuery()
+				query.DefaultConfig = map[string][]string{
+					consts.BootOptionRomEnP1Param:   {"true", "1"},
+					consts.BootOptionRomEnP2Param:   {"true", "1"},
+					consts.ExpRomPxeEnableParam:     {"true", "1"},
+					consts.ExpRomUefiX86EnableParam: {"true", "1"},
+					consts.BootVlanEnP1Param:        {"false", "0"},
+					consts.BootVlanEnP2Param:        {"false", "0"},
+					consts.BootVlanP1Param:          {"1"},
+					consts.BootVlanP2Param:          {"1"},
+					consts.BootRetryCntP1Param:      {"none", "0"},
+					consts.BootRetryCntP2Param:      {"none", "0"},
+				}
+			})
+
+			It("should apply the defaults if boot options are not set", func() {
+				nvParams, err := validator.ConstructNvParamMapFromTemplate(device, query)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(nvParams).To(HaveKeyWithValue(consts.BootOptionRomEnP1Param, "1"))
+				Expect(nvParams).To(HaveKeyWithValue(consts.BootRetryCntP1Param, "0"))
+				Expect(nvParams).NotTo(HaveKey(consts.ExpRomPxeEnableParam))
+				Expect(nvParams).NotTo(HaveKey(consts.ExpRomUefiX86EnableParam))
+				Expect(nvParams).NotTo(HaveKey(consts.BootVlanEnP2Param))
+				Expect(nvParams).NotTo(HaveKey(consts.BootVlanP1Param))
+			})
+			It("should disable the expansion ROM on all ports", func() {
+				device.Spec.Configuration.Template.BootOptions = &v1alpha1.BootOptionsSpec{Enabled: false}
+
+				nvParams, err := validator.ConstructNvParamMapFromTemplate(device, query)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(nvParams).To(HaveKeyWithValue(consts.BootOptionRomEnP1Param, consts.NvParamFalse))
+				Expect(nvParams).To(HaveKeyWithValue(consts.BootOptionRomEnP2Param, consts.NvParamFalse))
+				Expect(nvParams).NotTo(HaveKey(consts.ExpRomPxeEnableParam))
+				Expect(nvParams).NotTo(HaveKey(consts.ExpRomUefiX86EnableParam))
+			})
+			It("should apply the boot settings of the template", func() {
+				pxe, retries := false, 7
+				device.Spec.Configuration.Template.BootOptions = &v1alpha1.BootOptionsSpec{
+					Enabled:        true,
+					PXE:            &pxe,
+					BootVlan:       100,
+					BootRetryCount: &retries,
+				}
+
+				nvParams, err := validator.ConstructNvParamMapFromTemplate(device, query)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(nvParams).To(HaveKeyWithValue(consts.BootOptionRomEnP2Param, consts.NvParamTrue))
+				Expect(nvParams).To(HaveKeyWithValue(consts.ExpRomPxeEnableParam, consts.NvParamFalse))
+				Expect(nvParams).NotTo(HaveKey(consts.ExpRomUefiX86EnableParam))
+				Expect(nvParams).To(HaveKeyWithValue(consts.BootVlanEnP1Param, consts.NvParamTrue))
+				Expect(nvParams).To(HaveKeyWithValue(consts.BootVlanP2Param, "100"))
+				Expect(nvParams).To(HaveKeyWithValue(consts.BootRetryCntP1Param, "7"))
+			})
+			It("should omit the boot settings of the second port if device is single port", func() {
+				device.Status.Ports = device.Status.Ports[:1]
+				device.Spec.Configuration.Template.BootOptions = &v1alpha1.BootOptionsSpec{Enabled: true, BootVlan: 100}
+
+				nvParams, err := validator.ConstructNvParamMapFromTemplate(device, query)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(nvParams).To(HaveKeyWithValue(consts.BootVlanP1Param, "100"))
+				Expect(nvParams).NotTo(HaveKey(consts.BootVlanP2Param))
+				Expect(nvParams).NotTo(HaveKey(consts.BootOptionRomEnP2Param))
+			})
+			It("should return an error when PXE boot is enabled with the expansion ROM disabled", func() {
+				pxe := true
+				device.Spec.Configuration.Template.BootOptions = &v1alpha1.BootOptionsSpec{Enabled: false, PXE: &pxe}
+
+				_, err := validator.ConstructNvParamMapFromTemplate(device, query)
+				Expect(types.IsIncorrectSpecError(err)).To(BeTrue())
+			})
+			It("should return an error if the device doesn't support boot options", func() {
+				device.Spec.Configuration.Template.BootOptions = &v1alpha1.BootOptionsSpec{Enabled: false}
+				query.DefaultConfig = map[string][]string{}
+
+				_, err := validator.ConstructNvParamMapFromTemplate(device, query)
+				Expect(err).To(MatchError(ContainSubstring("Device does not support boot option nv config parameters")))
+			})
+		})
+
+		Describe("ptp", func() {
+			var (
+				device *v1alpha1.NicDevice
+				query  types.NvConfigQuery
+			)
+
+			BeforeEach(func() {
+				device = &v1alpha1.NicDevice{
+					Spec: v1alpha1.NicDeviceSpec{
+						Configuration: &v1alpha1.NicDeviceConfigurationSpec{
+							Template: &v1alpha1.ConfigurationTemplateSpec{
+								NumVfs:   0,
+								LinkType: consts.Ethernet,
+							},
+						},
+					},
+					Status: v1alpha1.NicDeviceStatus{
+						Ports: []v1alpha1.NicDevicePortSpec{{PCI: "0000:03:00.0"}},
+					},
+				}
+				query = types.NewNvConfigQuery()
+				query.DefaultConfig = map[string][]string{
+					consts.RealTimeClockEnableParam: {"false", "0"},
+				}
+			})
+
+			It("should leave the clock mode untouched if ptp is not set", func() {
+				nvParams, err := validator.ConstructNvParamMapFromTemplate(device, query)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(nvParams).NotTo(HaveKey(consts.RealTimeClockEnableParam))
+			})
+			It("should enable the real time clock", func() {
+				device.Spec.Configuration.Template.Ptp = &v1alpha1.PtpSpec{RealTimeClock: true}
+
+				nvParams, err := validator.ConstructNvParamMapFromTemplate(device, query)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(nvParams).To(HaveKeyWithValue(consts.RealTimeClockEnableParam, consts.NvParamTrue))
+			})
+			It("should return an error if the device doesn't support the real time clock", func() {
+				device.Spec.Configuration.Template.Ptp = &v1alpha1.PtpSpec{RealTimeClock: true}
+				query.DefaultConfig = map[string][]string{}
+
+				_, err := validator.ConstructNvParamMapFromTemplate(device, query)
+				Expect(types.IsIncorrectSpecError(err)).To(BeTrue())
+			})
+		})
+
+		Describe("steering", func() {
+			var (
+				device *v1alpha1.NicDevice
+				query  types.NvConfigQuery
+			)
+
+			BeforeEach(func() {
+				device = &v1alpha1.NicDevice{
+					Spec: v1alpha1.NicDeviceSpec{
+						Configuration: &v1alpha1.NicDeviceConfigurationSpec{
+							Template: &v1alpha1.ConfigurationTemplateSpec{
+								NumVfs:   0,
+								LinkType: consts.Ethernet,
+							},
+						},
+					},
+					Status: v1alpha1.NicDeviceStatus{
+						Ports: []v1alpha1.NicDevicePortSpec{{PCI: "0000:03:00.0"}},
+					},
+				}
+				query = types.NewNvConfigQuery()
+				query.DefaultConfig = map[string][]string{
+					consts.FlexParserProfileParam:   {"0"},
+					consts.ProgParseGraphParam:      {"false", "0"},
+					consts.LogDcrHashTableSizeParam: {"11"},
+				}
+			})
+
+			It("should leave the steering parameters untouched if steering is not set", func() {
+				nvParams, err := validator.ConstructNvParamMapFromTemplate(device, query)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(nvParams).NotTo(HaveKey(consts.FlexParserProfileParam))
+				Expect(nvParams).NotTo(HaveKey(consts.ProgParseGraphParam))
+				Expect(nvParams).NotTo(HaveKey(consts.LogDcrHashTableSizeParam))
+				Expect(nvParams).NotTo(HaveKey(consts.DcrLifoSizeParam))
+			})
+			It("should apply the requested settings and leave the rest untouched", func() {
+				device.Spec.Configuration.Template.Steering = &v1alpha1.SteeringSpec{
+					FlexParserProfile:      ptr.To(4),
+					ProgrammableParseGraph: ptr.To(true),
+				}
+
+				nvParams, err := validator.ConstructNvParamMapFromTemplate(device, query)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(nvParams).To(HaveKeyWithValue(consts.FlexParserProfileParam, "4"))
+				Expect(nvParams).To(HaveKeyWithValue(consts.ProgParseGraphParam, consts.NvParamTrue))
+				Expect(nvParams).NotTo(HaveKey(consts.LogDcrHashTableSizeParam))
+			})
+			It("should return an error if the device doesn't support the requested parameter", func() {
+				device.Spec.Configuration.Template.Steering = &v1alpha1.SteeringSpec{
+					LogDcrHashTableSize: ptr.To(16),
+					DcrLifoSize:         ptr.To(65536),
+				}
+
+				_, err := validator.ConstructNvParamMapFromTemplate(device, query)
+				Expect(err).To(MatchError("incorrect spec: Device does not support steering nv config parameter DCR_LIFO_SIZE"))
+			})
+		})
+
+		Describe("spectrumX", func() {
+			var (
+				device *v1alpha1.NicDevice
+				query  types.NvConfigQuery
+			)
+
+			BeforeEach(func() {
+				device = &v1alpha1.NicDevice{
+					Spec: v1alpha1.NicDeviceSpec{
+						Configuration: &v1alpha1.NicDeviceConfigurationSpec{
+							Template: &v1alpha1.ConfigurationTemplateSpec{
+								NumVfs:   0,
+								LinkType: consts.Ethernet,
+							},
+						},
+					},
+					Status: v1alpha1.NicDeviceStatus{
+						FirmwareVersion: "28.39.1002",
+						Ports:           []v1alpha1.NicDevicePortSpec{{PCI: "0000:03:00.0"}},
+					},
+				}
+				query = types.NewNvConfigQuery()
+				query.DefaultConfig = map[string][]string{
+					consts.RoceAdaptiveRoutingEnParam: {"false", "0"},
+					consts.RdmaSelectiveRepeatEnParam: {"false", "0"},
+				}
+			})
+
+			It("should leave the Spectrum-X parameters untouched if spectrumX is not set", func() {
+				nvParams, err := validator.ConstructNvParamMapFromTemplate(device, query)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(nvParams).NotTo(HaveKey(consts.RoceAdaptiveRoutingEnParam))
+				Expect(nvParams).NotTo(HaveKey(consts.RdmaSelectiveRepeatEnParam))
+				Expect(nvParams).NotTo(HaveKey(consts.UserProgrammableCcParam))
+			})
+			It("should apply the requested settings", func() {
+				device.Spec.Configuration.Template.SpectrumX = &v1alpha1.SpectrumXSpec{
+					AdaptiveRouting: ptr.To(true),
+					SelectiveRepeat: ptr.To(true),
+				}
+
+				nvParams, err := validator.ConstructNvParamMapFromTemplate(device, query)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(nvParams).To(HaveKeyWithValue(consts.RoceAdaptiveRoutingEnParam, consts.NvParamTrue))
+				Expect(nvParams).To(HaveKeyWithValue(consts.RdmaSelectiveRepeatEnParam, consts.NvParamTrue))
+			})
+			It("should reject the adaptive routing without the selective repeat", func() {
+				device.Spec.Configuration.Template.SpectrumX = &v1alpha1.SpectrumXSpec{
+					AdaptiveRouting: ptr.To(true),
+					SelectiveRepeat: ptr.To(false),
+				}
+
+				_, err := validator.ConstructNvParamMapFromTemplate(device, query)
+				Expect(err).To(HaveOccurred())
+				Expect(types.IsIncorrectSpecError(err)).To(BeTrue())
+			})
+			It("should report all parameters of the feature set missing from the firmware", func() {
+				query.DefaultConfig = map[string][]string{}
+				device.Spec.Configuration.Template.SpectrumX = &v1alpha1.SpectrumXSpec{
+					AdaptiveRouting:               ptr.To(true),
+					ProgrammableCongestionControl: ptr.To(true),
+				}
+
+				_, err := validator.ConstructNvParamMapFromTemplate(device, query)
+				Expect(err).To(MatchError("fabric feature not supported: device with firmware 28.39.1002 does not support the Spectrum-X feature set, " +
+					"nv config parameters ROCE_ADAPTIVE_ROUTING_EN, USER_PROGRAMMABLE_CC are not available"))
+				Expect(types.IsFabricFeatureNotSupportedError(err)).To(BeTrue())
+			})
+		})
+
+		It("should apply the PCIe link settings of the template", func() {
+			device := &v1alpha1.NicDevice{
+				Spec: v1alpha1.NicDeviceSpec{
+					Configuration: &v1alpha1.NicDeviceConfigurationSpec{
+						Template: &v1alpha1.ConfigurationTemplateSpec{
+							NumVfs:   0,
+							LinkType: consts.Ethernet,
+							PciLink:  &v1alpha1.PciLinkSpec{Generation: 4},
+						},
+					},
+				},
+				Status: v1alpha1.NicDeviceStatus{
+					Ports: []v1alpha1.NicDevicePortSpec{{PCI: "0000:03:00.0"}},
+				},
+			}
+			query := types.NewNvConfigQuery()
+			query.DefaultConfig = map[string][]string{
+				consts.PciGenParam:   {"5"},
+				consts.PciWidthParam: {"16"},
+			}
+
+			nvParams, err := validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(nvParams).To(HaveKeyWithValue(consts.PciGenParam, "4"))
+			Expect(nvParams).NotTo(HaveKey(consts.PciWidthParam))
+
+			device.Spec.Configuration.Template.PciLink.Width = 8
+			nvParams, err = validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(nvParams).To(HaveKeyWithValue(consts.PciWidthParam, "8"))
+
+			delete(query.DefaultConfig, consts.PciWidthParam)
+			_, err = validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).To(MatchError("incorrect spec: Device does not support PCI link nv config parameters"))
+		})
+
+		It("should only accept the sysctls of the net subtree set once", func() {
+			device := &v1alpha1.NicDevice{
+				Spec: v1alpha1.NicDeviceSpec{
+					Configuration: &v1alpha1.NicDeviceConfigurationSpec{
+						Template: &v1alpha1.ConfigurationTemplateSpec{
+							LinkType: consts.Ethernet,
+							Sysctls: []v1alpha1.SysctlSpec{
+								{Name: "net.ipv4.tcp_ecn", Value: "1"},
+								{Name: "net.ipv4.conf.{interface}.arp_announce", Value: "2"},
+							},
+						},
+					},
+				},
+				Status: v1alpha1.NicDeviceStatus{
+					Ports: []v1alpha1.NicDevicePortSpec{{PCI: "0000:03:00.0"}},
+				},
+			}
+			query := types.NewNvConfigQuery()
+
+			_, err := validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).NotTo(HaveOccurred())
+
+			device.Spec.Configuration.Template.Sysctls[0].Name = "kernel.panic"
+			_, err = validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).To(MatchError("incorrect spec: sysctl kernel.panic is not in the net subtree"))
+
+			device.Spec.Configuration.Template.Sysctls[0].Name = "net.ipv4.conf.{port}.arp_announce"
+			_, err = validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).To(MatchError(ContainSubstring("unknown placeholder")))
+
+			device.Spec.Configuration.Template.Sysctls[0].Name = "net.ipv4.conf.{interface}.arp_announce"
+			_, err = validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).To(MatchError("incorrect spec: sysctl net.ipv4.conf.{interface}.arp_announce is set twice in the template"))
+		})
+
+		It("should apply the VF MSI-X settings of the template", func() {
+			device := &v1alpha1.NicDevice{
+				Spec: v1alpha1.NicDeviceSpec{
+					Configuration: &v1alpha1.NicDeviceConfigurationSpec{
+						Template: &v1alpha1.ConfigurationTemplateSpec{
+							NumVfs:   8,
+							LinkType: consts.Ethernet,
+							VfMsix:   &v1alpha1.VfMsixSpec{NumVfMsix: 32},
+						},
+					},
+				},
+				Status: v1alpha1.NicDeviceStatus{
+					Ports: []v1alpha1.NicDevicePortSpec{{PCI: "0000:03:00.0"}},
 				},
 			}
-
 			query := types.NewNvConfigQuery()
+			query.DefaultConfig = map[string][]string{
+				consts.NumVfMsixParam:          {"11"},
+				consts.DynamicVfMsixTableParam: {"False", "0"},
+			}
 
-			_, err := validator.ConstructNvParamMapFromTemplate(device, query)
+			nvParams, err := validator.ConstructNvParamMapFromTemplate(device, query)
 			Expect(err).NotTo(HaveOccurred())
-		})
-		It("should return an error when RoceOptimized is enabled with linkType Infiniband", func() {
-			mockHostUtils.On("GetPCILinkSpeed", mock.Anything).Return(16, nil)
+			Expect(nvParams).To(HaveKeyWithValue(consts.NumVfMsixParam, "32"))
+			Expect(nvParams).To(HaveKeyWithValue(consts.DynamicVfMsixTableParam, consts.NvParamFalse))
+
+			device.Spec.Configuration.Template.VfMsix = &v1alpha1.VfMsixSpec{Dynamic: true, RuntimeVfMsix: 64}
+			nvParams, err = validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(nvParams).NotTo(HaveKey(consts.NumVfMsixParam))
+			Expect(nvParams).To(HaveKeyWithValue(consts.DynamicVfMsixTableParam, consts.NvParamTrue))
 
+			delete(query.DefaultConfig, consts.DynamicVfMsixTableParam)
+			_, err = validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).To(MatchError("incorrect spec: Device does not support dynamic VF MSI-X table"))
+		})
+		It("should reject inconsistent VF MSI-X settings", func() {
 			device := &v1alpha1.NicDevice{
 				Spec: v1alpha1.NicDeviceSpec{
 					Configuration: &v1alpha1.NicDeviceConfigurationSpec{
 						Template: &v1alpha1.ConfigurationTemplateSpec{
 							NumVfs:   0,
-							LinkType: consts.Infiniband,
-							RoceOptimized: &v1alpha1.RoceOptimizedSpec{
-								Enabled: true,
-							},
+							LinkType: consts.Ethernet,
+							VfMsix:   &v1alpha1.VfMsixSpec{NumVfMsix: 32},
 						},
 					},
 				},
 				Status: v1alpha1.NicDeviceStatus{
-					Ports: []v1alpha1.NicDevicePortSpec{
-						{PCI: "0000:03:00.0"},
-					},
+					Ports: []v1alpha1.NicDevicePortSpec{{PCI: "0000:03:00.0"}},
 				},
 			}
-
 			query := types.NewNvConfigQuery()
+			query.DefaultConfig = map[string][]string{
+				consts.DynamicVfMsixTableParam: {"False", "0"},
+			}
 
 			_, err := validator.ConstructNvParamMapFromTemplate(device, query)
-			Expect(err).To(MatchError("incorrect spec: RoceOptimized settings can only be used with link type Ethernet"))
+			Expect(err).To(MatchError("incorrect spec: vfMsix settings require numVfs to be set"))
+
+			device.Spec.Configuration.Template.NumVfs = 8
+			_, err = validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).To(MatchError("incorrect spec: Device does not support VF MSI-X nv config parameters"))
+
+			device.Spec.Configuration.Template.VfMsix = &v1alpha1.VfMsixSpec{RuntimeVfMsix: 64}
+			_, err = validator.ConstructNvParamMapFromTemplate(device, query)
+			Expect(err).To(MatchError("incorrect spec: runtimeVfMsix requires the dynamic VF MSI-X table to be enabled"))
 		})
 
 		It("should take numeric values when both numeric values and string aliases are present in nv config query", func() {
@@ -660,6 +1665,29 @@ var _ = Describe("ConfigValidationImpl", func() {
 			Expect(pfc).To(BeEmpty())
 		})
 
+		It("should default maxReadReqSize to 4096 for the GPUDirect RDMA preset", func() {
+			device := &v1alpha1.NicDevice{
+				Spec: v1alpha1.NicDeviceSpec{
+					Configuration: &v1alpha1.NicDeviceConfigurationSpec{
+						Template: &v1alpha1.ConfigurationTemplateSpec{
+							GpuDirectOptimized: &v1alpha1.GpuDirectOptimizedSpec{
+								Enabled: true,
+								Env:     consts.EnvBaremetal,
+								Preset:  consts.GpuDirectPresetRdma,
+							},
+						},
+					},
+				},
+			}
+
+			maxReadRequestSize, _, _ := validator.CalculateDesiredRuntimeConfig(device)
+			Expect(maxReadRequestSize).To(Equal(4096))
+
+			device.Spec.Configuration.Template.GpuDirectOptimized.Preset = consts.GpuDirectPresetNone
+			maxReadRequestSize, _, _ = validator.CalculateDesiredRuntimeConfig(device)
+			Expect(maxReadRequestSize).To(BeZero())
+		})
+
 		It("should calculate trust and pfc when RoceOptimized is enabled with Qos", func() {
 			device := &v1alpha1.NicDevice{
 				Spec: v1alpha1.NicDeviceSpec{
@@ -886,6 +1914,331 @@ var _ = Describe("ConfigValidationImpl", func() {
 			})
 		})
 
+		Context("when ETS bandwidth shares do not match on the second port", func() {
+			BeforeEach(func() {
+				device.Spec.Configuration.Template.RoceOptimized.Qos = &v1alpha1.QosSpec{
+					Trust: "dscp", PFC: "0,0,0,1,0,0,0,0", TcBandwidth: "0,0,0,60,40,0,0,0",
+				}
+
+				mockHostUtils.On("GetTrustAndPFC", "interface0").Return("dscp", "0,0,0,1,0,0,0,0", nil)
+				mockHostUtils.On("GetTrustAndPFC", "interface1").Return("dscp", "0,0,0,1,0,0,0,0", nil)
+				mockHostUtils.On("GetTcBandwidth", "interface0").Return("0,0,0,60,40,0,0,0", nil)
+				mockHostUtils.On("GetTcBandwidth", "interface1").Return("0,0,0,0,0,0,0,0", nil)
+			})
+
+			It("should return false with no error", func() {
+				applied, err = validator.RuntimeConfigApplied(device)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(applied).To(BeFalse())
+			})
+		})
+
+		Context("when ring sizes are requested", func() {
+			BeforeEach(func() {
+				device.Spec.Configuration.Template.RingSize = &v1alpha1.RingSizeSpec{Rx: 16384}
+				desiredMaxReadReqSize, desiredTrust, desiredPfc := validator.CalculateDesiredRuntimeConfig(device)
+				mockHostUtils.On("GetMaxReadRequestSize", mock.Anything).Return(desiredMaxReadReqSize, nil)
+				mockHostUtils.On("GetTrustAndPFC", mock.Anything).Return(desiredTrust, desiredPfc, nil)
+			})
+
+			It("should compare the sizes clamped to the maximums of the device", func() {
+				mockHostUtils.On("GetRingSizes", "interface0").Return(types.RingSizes{Rx: 8192, Tx: 512, RxMax: 8192, TxMax: 8192}, nil)
+				mockHostUtils.On("GetRingSizes", "interface1").Return(types.RingSizes{Rx: 8192, Tx: 1024, RxMax: 8192, TxMax: 8192}, nil)
+
+				applied, err = validator.RuntimeConfigApplied(device)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(applied).To(BeTrue())
+			})
+			It("should return false if the sizes differ on the second port", func() {
+				mockHostUtils.On("GetRingSizes", "interface0").Return(types.RingSizes{Rx: 8192, Tx: 1024, RxMax: 8192, TxMax: 8192}, nil)
+				mockHostUtils.On("GetRingSizes", "interface1").Return(types.RingSizes{Rx: 1024, Tx: 1024, RxMax: 8192, TxMax: 8192}, nil)
+
+				applied, err = validator.RuntimeConfigApplied(device)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(applied).To(BeFalse())
+			})
+			It("should return an error if the ring sizes can't be read", func() {
+				mockHostUtils.On("GetRingSizes", "interface0").Return(types.RingSizes{}, fmt.Errorf("ethtool failed"))
+
+				applied, err = validator.RuntimeConfigApplied(device)
+				Expect(err).To(MatchError("ethtool failed"))
+				Expect(applied).To(BeFalse())
+			})
+		})
+
+		Context("when channels are requested", func() {
+			BeforeEach(func() {
+				device.Spec.Configuration.Template.Channels = &v1alpha1.ChannelsSpec{}
+				device.Spec.Configuration.Template.Ports = []v1alpha1.PortConfigurationSpec{
+					{Port: 2, Channels: &v1alpha1.ChannelsSpec{Combined: 4}},
+				}
+				desiredMaxReadReqSize, desiredTrust, desiredPfc := validator.CalculateDesiredRuntimeConfig(device)
+				mockHostUtils.On("GetMaxReadRequestSize", mock.Anything).Return(desiredMaxReadReqSize, nil)
+				mockHostUtils.On("GetTrustAndPFC", mock.Anything).Return(desiredTrust, desiredPfc, nil)
+			})
+
+			It("should compare the NUMA-local default and the port's override", func() {
+				mockHostUtils.On("GetNumaNodeCPUCount", "0000:03:00.0").Return(32, nil)
+				mockHostUtils.On("GetChannels", "interface0").Return(types.Channels{Combined: 32, CombinedMax: 63}, nil)
+				mockHostUtils.On("GetChannels", "interface1").Return(types.Channels{Combined: 4, CombinedMax: 63}, nil)
+
+				applied, err = validator.RuntimeConfigApplied(device)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(applied).To(BeTrue())
+			})
+			It("should clamp the NUMA-local default to the maximum of the device", func() {
+				mockHostUtils.On("GetNumaNodeCPUCount", "0000:03:00.0").Return(128, nil)
+				mockHostUtils.On("GetChannels", "interface0").Return(types.Channels{Combined: 32, CombinedMax: 63}, nil)
+
+				applied, err = validator.RuntimeConfigApplied(device)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(applied).To(BeFalse())
+			})
+			It("should return IncorrectSpec error if the count exceeds the maximum of the device", func() {
+				device.Spec.Configuration.Template.Ports[0].Channels.Combined = 128
+				mockHostUtils.On("GetNumaNodeCPUCount", "0000:03:00.0").Return(32, nil)
+				mockHostUtils.On("GetChannels", "interface0").Return(types.Channels{Combined: 32, CombinedMax: 63}, nil)
+				mockHostUtils.On("GetChannels", "interface1").Return(types.Channels{Combined: 4, CombinedMax: 63}, nil)
+
+				applied, err = validator.RuntimeConfigApplied(device)
+				Expect(types.IsIncorrectSpecError(err)).To(BeTrue())
+				Expect(err).To(MatchError(ContainSubstring("128 combined channels requested for interface interface1, the device supports at most 63")))
+				Expect(applied).To(BeFalse())
+			})
+		})
+
+		Context("when MTU is requested", func() {
+			BeforeEach(func() {
+				device.Spec.Configuration.Template.Mtu = &v1alpha1.MtuSpec{Size: 9000, PropagateToVfs: true}
+				desiredMaxReadReqSize, desiredTrust, desiredPfc := validator.CalculateDesiredRuntimeConfig(device)
+				mockHostUtils.On("GetMaxReadRequestSize", mock.Anything).Return(desiredMaxReadReqSize, nil)
+				mockHostUtils.On("GetTrustAndPFC", mock.Anything).Return(desiredTrust, desiredPfc, nil)
+				mockHostUtils.On("GetVfPciAddresses", "0000:03:00.0").Return([]string{"0000:03:00.2"}, nil)
+				mockHostUtils.On("GetVfPciAddresses", "0000:03:00.1").Return([]string{}, nil)
+				mockHostUtils.On("GetInterfaceName", "0000:03:00.2").Return("interface0v0")
+				mockHostUtils.On("GetMtu", "interface0").Return(9000, nil)
+				mockHostUtils.On("GetMtu", "interface1").Return(9000, nil)
+			})
+
+			It("should compare the MTU of the PFs and their VFs", func() {
+				mockHostUtils.On("GetMtu", "interface0v0").Return(9000, nil)
+
+				applied, err = validator.RuntimeConfigApplied(device)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(applied).To(BeTrue())
+			})
+			It("should return false if the MTU of a VF drifted", func() {
+				mockHostUtils.On("GetMtu", "interface0v0").Return(1500, nil)
+
+				applied, err = validator.RuntimeConfigApplied(device)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(applied).To(BeFalse())
+			})
+			It("should skip the VFs with their own MTU", func() {
+				mockHostUtils.On("GetMtu", "interface0v0").Return(4000, nil)
+
+				applied, err = validator.RuntimeConfigApplied(device)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(applied).To(BeTrue())
+			})
+			It("should skip the InfiniBand ports and the ports without a network interface", func() {
+				device.Spec.Configuration.Template.RoceOptimized = nil
+				device.Spec.Configuration.Template.Ports = []v1alpha1.PortConfigurationSpec{{Port: 1, LinkType: consts.Infiniband}}
+				device.Status.Ports[1].NetworkInterface = ""
+
+				applied, err = validator.RuntimeConfigApplied(device)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(applied).To(BeTrue())
+				mockHostUtils.AssertNotCalled(GinkgoT(), "GetMtu", mock.Anything)
+			})
+		})
+
+		Context("when sysctls are requested", func() {
+			BeforeEach(func() {
+				device.Spec.Configuration.Template.Sysctls = []v1alpha1.SysctlSpec{
+					{Name: "net.ipv4.tcp_ecn", Value: "1"},
+					{Name: "net.ipv4.conf.{interface}.arp_announce", Value: "2"},
+				}
+				desiredMaxReadReqSize, desiredTrust, desiredPfc := validator.CalculateDesiredRuntimeConfig(device)
+				mockHostUtils.On("GetMaxReadRequestSize", mock.Anything).Return(desiredMaxReadReqSize, nil)
+				mockHostUtils.On("GetTrustAndPFC", mock.Anything).Return(desiredTrust, desiredPfc, nil)
+			})
+
+			It("should compare the sysctls of the namespace and of each interface", func() {
+				mockHostUtils.On("GetSysctl", "net.ipv4.tcp_ecn").Return("1", nil)
+				mockHostUtils.On("GetSysctl", "net.ipv4.conf.interface0.arp_announce").Return("2", nil)
+				mockHostUtils.On("GetSysctl", "net.ipv4.conf.interface1.arp_announce").Return("2", nil)
+
+				applied, err = validator.RuntimeConfigApplied(device)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(applied).To(BeTrue())
+			})
+			It("should return false if the sysctl of an interface drifted", func() {
+				device.Status.Ports[1].NetworkInterface = "interface1.100"
+				mockHostUtils.On("GetSysctl", "net.ipv4.tcp_ecn").Return("1", nil)
+				mockHostUtils.On("GetSysctl", "net.ipv4.conf.interface0.arp_announce").Return("2", nil)
+				mockHostUtils.On("GetSysctl", "net.ipv4.conf.interface1/100.arp_announce").Return("0", nil)
+
+				applied, err = validator.RuntimeConfigApplied(device)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(applied).To(BeFalse())
+			})
+		})
+
+		Context("when flow steering mode is requested", func() {
+			BeforeEach(func() {
+				device.Spec.Configuration.Template.FlowSteeringMode = consts.FlowSteeringModeSmfs
+				desiredMaxReadReqSize, desiredTrust, desiredPfc := validator.CalculateDesiredRuntimeConfig(device)
+				mockHostUtils.On("GetMaxReadRequestSize", mock.Anything).Return(desiredMaxReadReqSize, nil)
+				mockHostUtils.On("GetTrustAndPFC", mock.Anything).Return(desiredTrust, desiredPfc, nil)
+			})
+
+			It("should compare the flow steering mode of each PF", func() {
+				mockHostUtils.On("GetDevlinkParam", "0000:03:00.0", consts.DevlinkParamFlowSteeringMode).Return("smfs", nil)
+				mockHostUtils.On("GetDevlinkParam", "0000:03:00.1", consts.DevlinkParamFlowSteeringMode).Return("smfs", nil)
+
+				applied, err = validator.RuntimeConfigApplied(device)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(applied).To(BeTrue())
+			})
+			It("should return false if a PF runs in another mode", func() {
+				mockHostUtils.On("GetDevlinkParam", "0000:03:00.0", consts.DevlinkParamFlowSteeringMode).Return("smfs", nil)
+				mockHostUtils.On("GetDevlinkParam", "0000:03:00.1", consts.DevlinkParamFlowSteeringMode).Return("dmfs", nil)
+
+				applied, err = validator.RuntimeConfigApplied(device)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(applied).To(BeFalse())
+			})
+		})
+
+		Context("when eswitch mode is requested", func() {
+			BeforeEach(func() {
+				device.Spec.Configuration.Template.EswitchMode = consts.EswitchModeSwitchdev
+				desiredMaxReadReqSize, desiredTrust, desiredPfc := validator.CalculateDesiredRuntimeConfig(device)
+				mockHostUtils.On("GetMaxReadRequestSize", mock.Anything).Return(desiredMaxReadReqSize, nil)
+				mockHostUtils.On("GetTrustAndPFC", mock.Anything).Return(desiredTrust, desiredPfc, nil)
+			})
+
+			It("should ignore the PFs that are not the eswitch manager", func() {
+				mockHostUtils.On("GetEswitchMode", "0000:03:00.0").Return(consts.EswitchModeSwitchdev, nil)
+				mockHostUtils.On("GetEswitchMode", "0000:03:00.1").Return("", nil)
+
+				applied, err = validator.RuntimeConfigApplied(device)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(applied).To(BeTrue())
+			})
+			It("should return false if a PF runs in another mode", func() {
+				mockHostUtils.On("GetEswitchMode", "0000:03:00.0").Return(consts.EswitchModeSwitchdev, nil)
+				mockHostUtils.On("GetEswitchMode", "0000:03:00.1").Return(consts.EswitchModeLegacy, nil)
+
+				applied, err = validator.RuntimeConfigApplied(device)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(applied).To(BeFalse())
+			})
+		})
+
+		Context("when coalescing settings are requested", func() {
+			BeforeEach(func() {
+				device.Spec.Configuration.Template.Coalescing = &v1alpha1.CoalescingSpec{AdaptiveRx: ptr.To(false), RxUsecs: ptr.To(0)}
+				desiredMaxReadReqSize, desiredTrust, desiredPfc := validator.CalculateDesiredRuntimeConfig(device)
+				mockHostUtils.On("GetMaxReadRequestSize", mock.Anything).Return(desiredMaxReadReqSize, nil)
+				mockHostUtils.On("GetTrustAndPFC", mock.Anything).Return(desiredTrust, desiredPfc, nil)
+			})
+
+			It("should only compare the requested parameters", func() {
+				current := types.Coalescing{AdaptiveTx: true, RxFrames: 128, TxUsecs: 16, TxFrames: 32}
+				mockHostUtils.On("GetCoalescing", mock.Anything).Return(current, nil)
+
+				applied, err = validator.RuntimeConfigApplied(device)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(applied).To(BeTrue())
+			})
+			It("should return false if the adaptive mode is still enabled", func() {
+				mockHostUtils.On("GetCoalescing", mock.Anything).Return(types.Coalescing{AdaptiveRx: true}, nil)
+
+				applied, err = validator.RuntimeConfigApplied(device)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(applied).To(BeFalse())
+			})
+			It("should return an error if the coalescing settings can't be read", func() {
+				mockHostUtils.On("GetCoalescing", "interface0").Return(types.Coalescing{}, fmt.Errorf("ethtool failed"))
+
+				applied, err = validator.RuntimeConfigApplied(device)
+				Expect(err).To(MatchError("ethtool failed"))
+				Expect(applied).To(BeFalse())
+			})
+		})
+
+		Context("when offloads are requested", func() {
+			BeforeEach(func() {
+				device.Spec.Configuration.Template.Offloads = &v1alpha1.OffloadsSpec{LRO: ptr.To(false), RxGroHw: ptr.To(true)}
+				desiredMaxReadReqSize, desiredTrust, desiredPfc := validator.CalculateDesiredRuntimeConfig(device)
+				mockHostUtils.On("GetMaxReadRequestSize", mock.Anything).Return(desiredMaxReadReqSize, nil)
+				mockHostUtils.On("GetTrustAndPFC", mock.Anything).Return(desiredTrust, desiredPfc, nil)
+			})
+
+			It("should only compare the requested offloads", func() {
+				mockHostUtils.On("GetEthtoolFeatures", mock.Anything).Return(map[string]types.EthtoolFeature{
+					consts.EthtoolFeatureLro: {}, consts.EthtoolFeatureRxGroHw: {Enabled: true}, consts.EthtoolFeatureTso: {}}, nil)
+
+				applied, err = validator.RuntimeConfigApplied(device)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(applied).To(BeTrue())
+			})
+			It("should return false if the driver reset an offload", func() {
+				mockHostUtils.On("GetEthtoolFeatures", "interface0").Return(map[string]types.EthtoolFeature{
+					consts.EthtoolFeatureLro: {}, consts.EthtoolFeatureRxGroHw: {Enabled: true}}, nil)
+				mockHostUtils.On("GetEthtoolFeatures", "interface1").Return(map[string]types.EthtoolFeature{
+					consts.EthtoolFeatureLro: {}, consts.EthtoolFeatureRxGroHw: {}}, nil)
+
+				applied, err = validator.RuntimeConfigApplied(device)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(applied).To(BeFalse())
+			})
+		})
+
+		Context("when congestion control is requested", func() {
+			BeforeEach(func() {
+				device.Spec.Configuration.Template.RoceOptimized.Qos = &v1alpha1.QosSpec{Trust: "dscp", PFC: "0,0,0,0,0,0,0,0"}
+				device.Spec.Configuration.Template.RoceOptimized.CongestionControl = &v1alpha1.CongestionControlSpec{
+					Ecn:   "0,0,0,1,0,0,0,0",
+					Dcqcn: &v1alpha1.DcqcnSpec{RpgMinRate: ptr.To(100)},
+				}
+
+				mockHostUtils.On("GetTrustAndPFC", "interface0").Return("dscp", "0,0,0,0,0,0,0,0", nil)
+				mockHostUtils.On("GetTrustAndPFC", "interface1").Return("dscp", "0,0,0,0,0,0,0,0", nil)
+				mockHostUtils.On("GetEcn", "interface0").Return("0,0,0,1,0,0,0,0", nil)
+				mockHostUtils.On("GetEcn", "interface1").Return("0,0,0,1,0,0,0,0", nil)
+				mockHostUtils.On("GetDcqcnParameter", "interface0", consts.DcqcnRpgMinRateParam).Return(100, nil)
+			})
+
+			It("should return false without reading the settings if the driver doesn't support congestion control", func() {
+				mockHostUtils.On("IsCongestionControlSupported", "interface0").Return(false)
+
+				applied, err = validator.RuntimeConfigApplied(device)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(applied).To(BeFalse())
+				mockHostUtils.AssertNotCalled(GinkgoT(), "GetEcn", mock.Anything)
+			})
+
+			It("should return true if ECN and DCQCN parameters match", func() {
+				mockHostUtils.On("IsCongestionControlSupported", mock.Anything).Return(true)
+				mockHostUtils.On("GetDcqcnParameter", "interface1", consts.DcqcnRpgMinRateParam).Return(100, nil)
+
+				applied, err = validator.RuntimeConfigApplied(device)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(applied).To(BeTrue())
+			})
+			It("should return false if a DCQCN parameter does not match on the second port", func() {
+				mockHostUtils.On("IsCongestionControlSupported", mock.Anything).Return(true)
+				mockHostUtils.On("GetDcqcnParameter", "interface1", consts.DcqcnRpgMinRateParam).Return(1, nil)
+
+				applied, err = validator.RuntimeConfigApplied(device)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(applied).To(BeFalse())
+			})
+		})
+
 		Context("when trust setting does not match on the first port", func() {
 			BeforeEach(func() {
 				desiredMaxReadReqSize, _, desiredPfc := validator.CalculateDesiredRuntimeConfig(device)
@@ -1026,5 +2379,27 @@ var _ = Describe("ConfigValidationImpl", func() {
 				Expect(applied).To(BeFalse())
 			})
 		})
+
+		Context("when devlink resource size is pending reload on the second port", func() {
+			BeforeEach(func() {
+				device.Spec.Configuration.Template.DevlinkResources = []v1alpha1.DevlinkResourceSpec{{Path: "/kvd/linear", Size: 1024}}
+				desiredMaxReadReqSize, _, _ := validator.CalculateDesiredRuntimeConfig(device)
+				sizeNew := uint64(1024)
+
+				mockHostUtils.On("GetMaxReadRequestSize", "0000:03:00.0").Return(desiredMaxReadReqSize, nil)
+				mockHostUtils.On("GetMaxReadRequestSize", "0000:03:00.1").Return(desiredMaxReadReqSize, nil)
+				mockHostUtils.On("GetDevlinkResources", "0000:03:00.0").
+					Return(map[string]types.DevlinkResource{"/kvd/linear": {Size: 1024}}, nil)
+				mockHostUtils.On("GetDevlinkResources", "0000:03:00.1").
+					Return(map[string]types.DevlinkResource{"/kvd/linear": {Size: 2048, SizeNew: &sizeNew}}, nil)
+			})
+
+			It("should return false with no error", func() {
+				applied, err = validator.RuntimeConfigApplied(device)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(applied).To(BeFalse())
+				mockHostUtils.AssertNotCalled(GinkgoT(), "GetTrustAndPFC", mock.Anything)
+			})
+		})
 	})
 })
@@ -0,0 +1,143 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package host
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+	"github.com/Mellanox/nic-configuration-operator/pkg/types"
+)
+
+// deviceFamily describes the nv config parameter set of a NIC family
+type deviceFamily struct {
+	name string
+	// maxPciGeneration is the highest PCIe generation the family supports
+	maxPciGeneration int
+	// maxFlexParserProfile is the highest flex parser profile the family's firmware supports,
+	// the profiles are validated by the firmware if 0
+	maxFlexParserProfile int
+	// optionalNvParams are skipped with a warning if the firmware doesn't report them, templates written
+	// for the older families keep working on the family without the corresponding knobs
+	optionalNvParams []string
+}
+
+// roceNvParams are the RoCE nv config parameters set by the roceOptimized settings
+var roceNvParams = []string{
+	consts.RoceCcPrioMaskP1Param,
+	consts.RoceCcPrioMaskP2Param,
+	consts.CnpDscpP1Param,
+	consts.CnpDscpP2Param,
+	consts.Cnp802pPrioP1Param,
+	consts.Cnp802pPrioP2Param,
+}
+
+// deviceFamilies contains the supported NIC families by the PCI device ID
+var deviceFamilies = map[string]deviceFamily{
+	// Profile 4 of the ConnectX-6 Dx generation parses eCPRI, the ConnectX-7 generation adds the profiles up to 8,
+	// e.g. for the GENEVE TLV options, see the mlx5 guide of DPDK
+	consts.ConnectX6DxDeviceID: {name: "ConnectX-6 Dx", maxPciGeneration: 4, maxFlexParserProfile: 4},
+	consts.ConnectX7DeviceID:   {name: "ConnectX-7", maxPciGeneration: 5, maxFlexParserProfile: 8},
+	consts.BlueField2DeviceID:  {name: "BlueField-2", maxPciGeneration: 4, maxFlexParserProfile: 4},
+	consts.ConnectX8DeviceID: {
+		name:             "ConnectX-8",
+		maxPciGeneration: 6,
+		optionalNvParams: roceNvParams,
+	},
+	consts.BlueField3DeviceID: {
+		name:                 "BlueField-3",
+		maxPciGeneration:     5,
+		maxFlexParserProfile: 8,
+		optionalNvParams:     roceNvParams,
+	},
+}
+
+// getDeviceFamily returns the family of the device with the given PCI device ID, returns false if the family is not known
+func getDeviceFamily(deviceType string) (deviceFamily, bool) {
+	family, found := deviceFamilies[strings.ToLower(deviceType)]
+	return family, found
+}
+
+// validateDeviceFamilySpec checks the template settings depending on the capabilities of the device's family
+func validateDeviceFamilySpec(device *v1alpha1.NicDevice) error {
+	family, found := getDeviceFamily(device.Status.Type)
+	if !found {
+		return nil
+	}
+
+	template := device.Spec.Configuration.Template
+	if template.PciLink != nil && template.PciLink.Generation > family.maxPciGeneration {
+		return types.IncorrectSpecError(fmt.Sprintf("PCIe generation %d is not supported by %s devices, the highest generation is %d",
+			template.PciLink.Generation, family.name, family.maxPciGeneration))
+	}
+	if template.Steering != nil && template.Steering.FlexParserProfile != nil && family.maxFlexParserProfile != 0 &&
+		*template.Steering.FlexParserProfile > family.maxFlexParserProfile {
+		return types.IncorrectSpecError(fmt.Sprintf("flex parser profile %d is not supported by %s devices, the highest profile is %d",
+			*template.Steering.FlexParserProfile, family.name, family.maxFlexParserProfile))
+	}
+
+	return nil
+}
+
+// skipUnsupportedNvParams drops the optional parameters of the device's family the firmware doesn't report
+// the warning event is emitted once per device and set of skipped parameters, not on every reconcile
+func (v *configValidationImpl) skipUnsupportedNvParams(
+	device *v1alpha1.NicDevice, query types.NvConfigQuery, desiredParameters map[string]string) {
+	family, found := getDeviceFamily(device.Status.Type)
+	if !found {
+		return
+	}
+
+	skipped := []string{}
+	for paramName := range desiredParameters {
+		if _, found := query.DefaultConfig[paramName]; found || !slices.Contains(family.optionalNvParams, paramName) {
+			continue
+		}
+		delete(desiredParameters, paramName)
+		skipped = append(skipped, paramName)
+	}
+
+	sort.Strings(skipped)
+	warning := ""
+	if len(skipped) != 0 {
+		warning = fmt.Sprintf("%s devices don't support nv config parameters %s, skipping them", family.name, strings.Join(skipped, ", "))
+	}
+
+	v.skippedNvParamsLock.Lock()
+	defer v.skippedNvParamsLock.Unlock()
+	if v.skippedNvParams == nil {
+		v.skippedNvParams = map[string]string{}
+	}
+	if v.skippedNvParams[device.Name] == warning {
+		return
+	}
+	v.skippedNvParams[device.Name] = warning
+	if warning == "" {
+		return
+	}
+
+	log.Log.Info(warning, "device", device.Name, "fw version", device.Status.FirmwareVersion)
+	if v.eventRecorder != nil {
+		v.eventRecorder.Event(device, v1.EventTypeWarning, consts.NvParamNotSupportedReason, warning)
+	}
+}
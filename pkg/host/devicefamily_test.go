@@ -0,0 +1,157 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package host
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+	"github.com/Mellanox/nic-configuration-operator/pkg/host/mocks"
+	"github.com/Mellanox/nic-configuration-operator/pkg/types"
+)
+
+var _ = Describe("device families", func() {
+	var (
+		validator     *configValidationImpl
+		eventRecorder *record.FakeRecorder
+	)
+
+	newDevice := func(deviceType string, template *v1alpha1.ConfigurationTemplateSpec) *v1alpha1.NicDevice {
+		return &v1alpha1.NicDevice{
+			Spec: v1alpha1.NicDeviceSpec{
+				Configuration: &v1alpha1.NicDeviceConfigurationSpec{Template: template},
+			},
+			Status: v1alpha1.NicDeviceStatus{
+				Type:  deviceType,
+				Ports: []v1alpha1.NicDevicePortSpec{{PCI: "0000:03:00.0"}, {PCI: "0000:03:00.1"}},
+			},
+		}
+	}
+
+	roceTemplate := func() *v1alpha1.ConfigurationTemplateSpec {
+		return &v1alpha1.ConfigurationTemplateSpec{
+			LinkType:      consts.Ethernet,
+			RoceOptimized: &v1alpha1.RoceOptimizedSpec{Enabled: true},
+		}
+	}
+
+	BeforeEach(func() {
+		eventRecorder = record.NewFakeRecorder(10)
+		validator = &configValidationImpl{utils: &mocks.HostUtils{}, eventRecorder: eventRecorder}
+	})
+
+	It("should detect the device families by the PCI device ID", func() {
+		family, found := getDeviceFamily("1023")
+		Expect(found).To(BeTrue())
+		Expect(family.name).To(Equal("ConnectX-8"))
+
+		family, found = getDeviceFamily("A2DC")
+		Expect(found).To(BeTrue())
+		Expect(family.name).To(Equal("BlueField-3"))
+
+		_, found = getDeviceFamily("ffff")
+		Expect(found).To(BeFalse())
+	})
+
+	It("should skip the RoCE parameters the firmware of the new families doesn't report", func() {
+		query := types.NewNvConfigQuery()
+		query.DefaultConfig = map[string][]string{consts.CnpDscpP1Param: {"0"}}
+
+		nvParams, err := validator.ConstructNvParamMapFromTemplate(newDevice(consts.BlueField3DeviceID, roceTemplate()), query)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nvParams).To(HaveKeyWithValue(consts.CnpDscpP1Param, "4"))
+		Expect(nvParams).NotTo(HaveKey(consts.RoceCcPrioMaskP1Param))
+		Expect(nvParams).NotTo(HaveKey(consts.Cnp802pPrioP2Param))
+		Expect(eventRecorder.Events).To(Receive(And(
+			ContainSubstring(consts.NvParamNotSupportedReason),
+			ContainSubstring("BlueField-3 devices don't support nv config parameters CNP_802P_PRIO_P1, CNP_802P_PRIO_P2, "+
+				"CNP_DSCP_P2, ROCE_CC_PRIO_MASK_P1, ROCE_CC_PRIO_MASK_P2"))))
+	})
+
+	It("should report the skipped RoCE parameters once per device until they change", func() {
+		query := types.NewNvConfigQuery()
+		query.DefaultConfig = map[string][]string{consts.CnpDscpP1Param: {"0"}}
+		device := newDevice(consts.ConnectX8DeviceID, roceTemplate())
+		device.Name = "cx8"
+
+		_, err := validator.ConstructNvParamMapFromTemplate(device, query)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(eventRecorder.Events).To(Receive())
+
+		// Next reconcile skips the same parameters
+		_, err = validator.ConstructNvParamMapFromTemplate(device, query)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(eventRecorder.Events).To(BeEmpty())
+
+		// Updated firmware reports another parameter
+		query.DefaultConfig[consts.CnpDscpP2Param] = []string{"0"}
+		_, err = validator.ConstructNvParamMapFromTemplate(device, query)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(eventRecorder.Events).To(Receive(Not(ContainSubstring("CNP_DSCP_P2"))))
+	})
+
+	It("should keep the unsupported RoCE parameters of the older families", func() {
+		query := types.NewNvConfigQuery()
+
+		nvParams, err := validator.ConstructNvParamMapFromTemplate(newDevice(consts.ConnectX7DeviceID, roceTemplate()), query)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nvParams).To(HaveKeyWithValue(consts.CnpDscpP1Param, "4"))
+		Expect(eventRecorder.Events).To(BeEmpty())
+	})
+
+	It("should only allow the PCIe generations supported by the family", func() {
+		query := types.NewNvConfigQuery()
+		query.DefaultConfig = map[string][]string{consts.PciGenParam: {"6"}}
+		template := &v1alpha1.ConfigurationTemplateSpec{LinkType: consts.Ethernet, PciLink: &v1alpha1.PciLinkSpec{Generation: 6}}
+
+		nvParams, err := validator.ConstructNvParamMapFromTemplate(newDevice(consts.ConnectX8DeviceID, template), query)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nvParams).To(HaveKeyWithValue(consts.PciGenParam, "6"))
+
+		_, err = validator.ConstructNvParamMapFromTemplate(newDevice(consts.BlueField3DeviceID, template), query)
+		Expect(err).To(MatchError("incorrect spec: PCIe generation 6 is not supported by BlueField-3 devices, the highest generation is 5"))
+
+		// Generation of the devices of unknown families is validated by the firmware
+		_, err = validator.ConstructNvParamMapFromTemplate(newDevice("ffff", template), query)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should only allow the flex parser profiles supported by the family", func() {
+		query := types.NewNvConfigQuery()
+		query.DefaultConfig = map[string][]string{consts.FlexParserProfileParam: {"0"}}
+		template := &v1alpha1.ConfigurationTemplateSpec{
+			LinkType: consts.Ethernet, Steering: &v1alpha1.SteeringSpec{FlexParserProfile: ptr.To(8)}}
+
+		nvParams, err := validator.ConstructNvParamMapFromTemplate(newDevice(consts.ConnectX7DeviceID, template), query)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nvParams).To(HaveKeyWithValue(consts.FlexParserProfileParam, "8"))
+
+		_, err = validator.ConstructNvParamMapFromTemplate(newDevice(consts.ConnectX6DxDeviceID, template), query)
+		Expect(err).To(MatchError("incorrect spec: flex parser profile 8 is not supported by ConnectX-6 Dx devices, the highest profile is 4"))
+
+		template.Steering.FlexParserProfile = ptr.To(9)
+		_, err = validator.ConstructNvParamMapFromTemplate(newDevice(consts.BlueField3DeviceID, template), query)
+		Expect(err).To(MatchError("incorrect spec: flex parser profile 9 is not supported by BlueField-3 devices, the highest profile is 8"))
+
+		// Profiles of the families without a known limit are validated by the firmware
+		_, err = validator.ConstructNvParamMapFromTemplate(newDevice(consts.ConnectX8DeviceID, template), query)
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
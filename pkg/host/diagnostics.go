@@ -0,0 +1,69 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package host
+
+import (
+	"encoding/json"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/types"
+)
+
+// maxDiagnosticsToolFailures is the number of the last tool failures included in the diagnostics of a device
+const maxDiagnosticsToolFailures = 3
+
+// deviceDiagnostics is the diagnostic blob of a device after a failed operation
+type deviceDiagnostics struct {
+	SerialNumber    string `json:"serialNumber"`
+	PartNumber      string `json:"partNumber"`
+	FirmwareVersion string `json:"firmwareVersion"`
+	// ToolFailures are the last failed host tool runs on the device's ports
+	ToolFailures []types.ToolFailure `json:"toolFailures,omitempty"`
+	// Sysfs state of the device's ports, keyed by the PCI address
+	Sysfs map[string]map[string]string `json:"sysfs,omitempty"`
+}
+
+// CollectDiagnostics returns a compact JSON blob describing the device's state after a failed operation
+// includes the host tool runs on the device's ports that failed after the given time and the sysfs state of the ports
+func (h hostManager) CollectDiagnostics(device *v1alpha1.NicDevice, since time.Time) string {
+	diagnostics := deviceDiagnostics{
+		SerialNumber:    device.Status.SerialNumber,
+		PartNumber:      device.Status.PartNumber,
+		FirmwareVersion: device.Status.FirmwareVersion,
+		Sysfs:           map[string]map[string]string{},
+	}
+
+	identifiers := []string{}
+	for _, port := range device.Status.Ports {
+		identifiers = append(identifiers, port.PCI, port.NetworkInterface, port.RdmaInterface)
+		diagnostics.Sysfs[port.PCI] = h.hostUtils.GetPCIDeviceSysfsState(port.PCI)
+	}
+
+	diagnostics.ToolFailures = h.hostUtils.GetToolFailures(since, identifiers)
+	if len(diagnostics.ToolFailures) > maxDiagnosticsToolFailures {
+		diagnostics.ToolFailures = diagnostics.ToolFailures[len(diagnostics.ToolFailures)-maxDiagnosticsToolFailures:]
+	}
+
+	blob, err := json.Marshal(diagnostics)
+	if err != nil {
+		log.Log.Error(err, "failed to marshal device diagnostics", "device", device.Name)
+		return ""
+	}
+	return string(blob)
+}
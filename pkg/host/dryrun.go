@@ -0,0 +1,82 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package host
+
+import (
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+	"github.com/Mellanox/nic-configuration-operator/pkg/types"
+)
+
+// NvSpecDryRun is the outcome of validating the nv config spec of a device against a nv config query
+type NvSpecDryRun struct {
+	// Nv config parameters rendered from the spec with their values in the query, empty for resetToDefault specs
+	Parameters []v1alpha1.NvConfigParameterStatus
+	// ConfigUpdateNeeded is set if the nv config of the device would be written
+	ConfigUpdateNeeded bool
+	// RebootNeeded is set if the device would require a reboot to apply the spec
+	RebootNeeded bool
+}
+
+// DryRunNvSpec validates the nv config spec of the device against the given nv config query without accessing the host,
+// e.g. against a nv config snapshot of the device captured on a previous firmware
+// the link types of the ports are taken from their reported RDMA link layers, the link type of the ports without it isn't checked
+func DryRunNvSpec(device *v1alpha1.NicDevice, nvConfig types.NvConfigQuery) (NvSpecDryRun, error) {
+	device = device.DeepCopy()
+	utils := &offlineHostUtils{linkTypes: map[string]string{}}
+	for i, port := range device.Status.Ports {
+		switch port.LinkLayer {
+		case consts.RdmaLinkLayerInfiniband:
+			utils.linkTypes[port.NetworkInterface] = consts.Infiniband
+		case consts.Ethernet:
+			utils.linkTypes[port.NetworkInterface] = consts.Ethernet
+		default:
+			device.Status.Ports[i].NetworkInterface = ""
+		}
+	}
+	device.Status.NvConfigWriteStats = nil
+
+	validation := &configValidationImpl{utils: utils}
+
+	if device.Spec.Configuration.ResetToDefault {
+		configUpdateNeeded, rebootNeeded, err := validation.ValidateResetToDefault(nvConfig)
+		return NvSpecDryRun{ConfigUpdateNeeded: configUpdateNeeded, RebootNeeded: rebootNeeded}, err
+	}
+
+	desiredConfig, err := validation.ConstructNvParamMapFromTemplate(device, nvConfig)
+	if err != nil {
+		return NvSpecDryRun{}, err
+	}
+
+	result := NvSpecDryRun{Parameters: renderNvConfigParametersStatus(desiredConfig, nvConfig, secretSourcedNvParams(device.Spec.Configuration.Template))}
+	result.ConfigUpdateNeeded, result.RebootNeeded, err = nvConfigChangesNeeded(
+		device, desiredConfig, nvConfig, validation.AdvancedPCISettingsEnabled(nvConfig), "")
+	return result, err
+}
+
+// offlineHostUtils answers the host queries of the nv config validation from the reported device status
+// the other queries access the host and are not implemented, calling them panics
+type offlineHostUtils struct {
+	HostUtils
+
+	// linkTypes are the link types of the ports by their network interfaces
+	linkTypes map[string]string
+}
+
+// GetLinkType returns the link type of the port reported for the network interface
+func (u *offlineHostUtils) GetLinkType(name string) string {
+	return u.linkTypes[name]
+}
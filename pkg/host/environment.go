@@ -0,0 +1,142 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package host
+
+import (
+	"slices"
+	"strconv"
+
+	"github.com/jaypipes/ghw/pkg/pci"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+)
+
+// virtualFunctionDeviceIDs are the PCI device IDs of the VFs of the ConnectX and BlueField families
+var virtualFunctionDeviceIDs = []string{"1014", "1016", "1018", "101a", "101c", "101e", "a2d3"}
+
+// isNetworkDevice returns true if the PCI device is of the network controller class
+func isNetworkDevice(device *pci.Device) bool {
+	devClass, err := strconv.ParseInt(device.Class.ID, 16, 64)
+	return err == nil && devClass == consts.NetClass
+}
+
+// isNvidiaVF returns true if the device ID of the NVIDIA device is one of a VF
+// a VF without the physfn link was passed through to a VM, VFs on the hypervisor host always link to their PF
+func isNvidiaVF(device *pci.Device) bool {
+	return device.Vendor.ID == consts.MellanoxVendor && slices.Contains(virtualFunctionDeviceIDs, device.Product.ID)
+}
+
+// isNvidiaVirtioNetDevice returns true if the device is a virtio-net device emulated by an NVIDIA NIC, e.g. by a BlueField DPU
+func isNvidiaVirtioNetDevice(device *pci.Device) bool {
+	return device.Vendor.ID == consts.VirtioVendor && device.Subsystem != nil && device.Subsystem.VendorID == consts.MellanoxVendor
+}
+
+// restrictedDevice returns true if only the VF runtime settings of the device can be applied
+func restrictedDevice(device *v1alpha1.NicDevice) bool {
+	return device.Status.ConfigurationMode == consts.ConfigurationModeRestricted
+}
+
+// DiscoverEnvironment detects whether the host is a VM and which of its NVIDIA devices can't be configured in full
+// the VFs and virtio-net devices passed through to the VM switch the node to the restricted mode
+// devices located in one of the ignored PCI slots are skipped
+func (h hostManager) DiscoverEnvironment(ignoredPCIAddresses []string) v1alpha1.NodeEnvironment {
+	environment := v1alpha1.NodeEnvironment{Type: consts.NodeEnvironmentBareMetal, Mode: consts.ConfigurationModeFull}
+
+	hypervisor, err := h.hostUtils.GetHypervisor()
+	if err != nil {
+		log.Log.Error(err, "failed to detect the hypervisor, assuming bare metal")
+	}
+	if hypervisor != "" {
+		environment.Type = consts.NodeEnvironmentVirtualMachine
+		environment.Hypervisor = hypervisor
+	}
+
+	pciDevices, err := h.hostUtils.GetPCIDevices()
+	if err != nil {
+		log.Log.Error(err, "failed to get PCI devices, devices passed through to the VM are not detected")
+		return environment
+	}
+
+	for _, device := range pciDevices {
+		if !isNetworkDevice(device) || pciAddressIgnored(device.Address, ignoredPCIAddresses) {
+			continue
+		}
+		switch {
+		case isNvidiaVirtioNetDevice(device):
+			environment.VirtioDevices = append(environment.VirtioDevices, device.Address)
+		case isNvidiaVF(device) && !h.hostUtils.IsSriovVF(device.Address):
+			environment.VirtualFunctions = append(environment.VirtualFunctions, device.Address)
+		}
+	}
+
+	if len(environment.VirtualFunctions) != 0 || len(environment.VirtioDevices) != 0 {
+		// The devices can't be passed through to a bare metal host, the hypervisor is just hidden from it
+		environment.Type = consts.NodeEnvironmentVirtualMachine
+		environment.Mode = consts.ConfigurationModeRestricted
+		slices.Sort(environment.VirtualFunctions)
+		slices.Sort(environment.VirtioDevices)
+	}
+
+	return environment
+}
+
+// applyRestrictedRuntimeSpec applies the runtime settings of the template that the VFs passed through to a VM support:
+// ring sizes, interrupt coalescing, channels, offloads, MTU and sysctls
+// the PCI, QoS, eswitch and devlink settings can only be changed from the hypervisor host and are skipped
+func (h hostManager) applyRestrictedRuntimeSpec(device *v1alpha1.NicDevice) error {
+	log.Log.V(2).Info("applying the VF runtime settings of the restricted device", "device", device.Name)
+
+	err := h.applyRingSizes(device)
+	if err != nil {
+		log.Log.Error(err, "failed to apply ring sizes", "device", device)
+		return err
+	}
+
+	err = h.applyCoalescing(device)
+	if err != nil {
+		log.Log.Error(err, "failed to apply coalescing settings", "device", device)
+		return err
+	}
+
+	err = h.applyChannels(device)
+	if err != nil {
+		log.Log.Error(err, "failed to apply channels", "device", device)
+		return err
+	}
+
+	err = h.applyOffloads(device)
+	if err != nil {
+		log.Log.Error(err, "failed to apply offloads", "device", device)
+		return err
+	}
+
+	err = h.applyMtu(device)
+	if err != nil {
+		log.Log.Error(err, "failed to apply MTU", "device", device)
+		return err
+	}
+
+	err = h.applySysctls(device)
+	if err != nil {
+		log.Log.Error(err, "failed to apply sysctls", "device", device)
+		return err
+	}
+
+	device.Status.PartialRuntimeConfig = nil
+	return nil
+}
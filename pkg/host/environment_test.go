@@ -0,0 +1,189 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package host
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jaypipes/ghw/pkg/pci"
+	"github.com/jaypipes/pcidb"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+	"github.com/Mellanox/nic-configuration-operator/pkg/host/mocks"
+)
+
+var _ = Describe("environment", func() {
+	var (
+		mockHostUtils mocks.HostUtils
+		manager       hostManager
+	)
+
+	vf := &pci.Device{
+		Address: "0000:00:05.0",
+		Vendor:  &pcidb.Vendor{ID: consts.MellanoxVendor},
+		Product: &pcidb.Product{ID: "101e", Name: "ConnectX Family mlx5Gen Virtual Function"},
+		Class:   &pcidb.Class{ID: "02"},
+	}
+	virtioNet := &pci.Device{
+		Address:   "0000:00:06.0",
+		Vendor:    &pcidb.Vendor{ID: consts.VirtioVendor},
+		Product:   &pcidb.Product{ID: "1041", Name: "Virtio 1.0 network device"},
+		Subsystem: &pcidb.Product{VendorID: consts.MellanoxVendor, ID: "0001"},
+		Class:     &pcidb.Class{ID: "02"},
+	}
+	pf := &pci.Device{
+		Address: "0000:3b:00.0",
+		Vendor:  &pcidb.Vendor{ID: consts.MellanoxVendor},
+		Product: &pcidb.Product{ID: consts.ConnectX6DxDeviceID, Name: "MT2892 Family [ConnectX-6 Dx]"},
+		Class:   &pcidb.Class{ID: "02"},
+	}
+
+	BeforeEach(func() {
+		mockHostUtils = mocks.HostUtils{}
+		manager = hostManager{hostUtils: &mockHostUtils}
+	})
+
+	Describe("DiscoverEnvironment", func() {
+		It("should report the bare metal host with the PFs in the full mode", func() {
+			mockHostUtils.On("GetHypervisor").Return("", nil)
+			mockHostUtils.On("GetPCIDevices").Return([]*pci.Device{pf}, nil)
+
+			Expect(manager.DiscoverEnvironment(nil)).To(Equal(v1alpha1.NodeEnvironment{
+				Type: consts.NodeEnvironmentBareMetal,
+				Mode: consts.ConfigurationModeFull,
+			}))
+		})
+		It("should not count the VFs of the hypervisor host's PFs", func() {
+			mockHostUtils.On("GetHypervisor").Return("", nil)
+			mockHostUtils.On("GetPCIDevices").Return([]*pci.Device{pf, vf}, nil)
+			mockHostUtils.On("IsSriovVF", vf.Address).Return(true)
+
+			Expect(manager.DiscoverEnvironment(nil).Mode).To(Equal(consts.ConfigurationModeFull))
+		})
+		It("should report the VM with the passthrough VFs and virtio-net devices in the restricted mode", func() {
+			mockHostUtils.On("GetHypervisor").Return("QEMU", nil)
+			mockHostUtils.On("GetPCIDevices").Return([]*pci.Device{virtioNet, vf}, nil)
+			mockHostUtils.On("IsSriovVF", vf.Address).Return(false)
+
+			Expect(manager.DiscoverEnvironment(nil)).To(Equal(v1alpha1.NodeEnvironment{
+				Type:             consts.NodeEnvironmentVirtualMachine,
+				Hypervisor:       "QEMU",
+				Mode:             consts.ConfigurationModeRestricted,
+				VirtualFunctions: []string{vf.Address},
+				VirtioDevices:    []string{virtioNet.Address},
+			}))
+		})
+		It("should detect the VM by the passthrough devices if the hypervisor is hidden", func() {
+			mockHostUtils.On("GetHypervisor").Return("", errors.New("no cpuinfo"))
+			mockHostUtils.On("GetPCIDevices").Return([]*pci.Device{vf}, nil)
+			mockHostUtils.On("IsSriovVF", vf.Address).Return(false)
+
+			environment := manager.DiscoverEnvironment(nil)
+			Expect(environment.Type).To(Equal(consts.NodeEnvironmentVirtualMachine))
+			Expect(environment.Mode).To(Equal(consts.ConfigurationModeRestricted))
+		})
+		It("should skip the ignored devices", func() {
+			mockHostUtils.On("GetHypervisor").Return("QEMU", nil)
+			mockHostUtils.On("GetPCIDevices").Return([]*pci.Device{vf}, nil)
+
+			Expect(manager.DiscoverEnvironment([]string{"0000:00:05"})).To(Equal(v1alpha1.NodeEnvironment{
+				Type:       consts.NodeEnvironmentVirtualMachine,
+				Hypervisor: "QEMU",
+				Mode:       consts.ConfigurationModeFull,
+			}))
+		})
+	})
+
+	Describe("restricted devices", func() {
+		It("should report the passthrough VF without querying its firmware", func() {
+			mockHostUtils.On("GetPCIDevices").Return([]*pci.Device{vf}, nil)
+			mockHostUtils.On("IsSriovVF", vf.Address).Return(false)
+			mockHostUtils.On("GetPartAndSerialNumber", vf.Address).Return("part-number", "serial-number", nil)
+			mockHostUtils.On("QueryFirmware", vf.Address).Return(nil, errors.New("mstflint failed"))
+			mockHostUtils.On("GetPCILinkStatus", vf.Address).Return(nil, nil)
+			mockHostUtils.On("GetInterfaceName", vf.Address).Return("")
+			mockHostUtils.On("GetRDMADeviceName", vf.Address).Return("")
+			mockHostUtils.On("GetEswitchMode", vf.Address).Return("", nil)
+			mockHostUtils.On("GetPtpClockIndex", vf.Address).Return(-1, nil)
+			mockHostUtils.On("GetPCITopology", vf.Address).Return(nil, nil)
+
+			devices, err := manager.DiscoverNicDevices(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(devices).To(HaveKey("serial-number"))
+			Expect(devices["serial-number"].ConfigurationMode).To(Equal(consts.ConfigurationModeRestricted))
+			Expect(devices["serial-number"].Type).To(Equal("101e"))
+			Expect(devices["serial-number"].FirmwareSecurity).To(BeNil())
+		})
+		It("should skip the passthrough VF without the VPD instead of failing the discovery", func() {
+			mockHostUtils.On("GetPCIDevices").Return([]*pci.Device{vf}, nil)
+			mockHostUtils.On("IsSriovVF", vf.Address).Return(false)
+			mockHostUtils.On("GetPartAndSerialNumber", vf.Address).Return("", "", errors.New("no VPD"))
+
+			devices, err := manager.DiscoverNicDevices(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(devices).To(BeEmpty())
+		})
+		It("should skip the nv config of the restricted device", func() {
+			device := &v1alpha1.NicDevice{
+				Spec: v1alpha1.NicDeviceSpec{Configuration: &v1alpha1.NicDeviceConfigurationSpec{
+					Template: &v1alpha1.ConfigurationTemplateSpec{NumVfs: 8, LinkType: consts.Ethernet},
+				}},
+				Status: v1alpha1.NicDeviceStatus{
+					ConfigurationMode:  consts.ConfigurationModeRestricted,
+					Ports:              []v1alpha1.NicDevicePortSpec{{PCI: vf.Address}},
+					NvConfigParameters: []v1alpha1.NvConfigParameterStatus{{Name: consts.SriovNumOfVfsParam}},
+				},
+			}
+
+			nvConfigUpdateRequired, rebootRequired, err := manager.ValidateDeviceNvSpec(context.Background(), device)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(nvConfigUpdateRequired).To(BeFalse())
+			Expect(rebootRequired).To(BeFalse())
+			Expect(device.Status.NvConfigParameters).To(BeNil())
+			mockHostUtils.AssertExpectations(GinkgoT())
+		})
+		It("should only apply the VF runtime settings of the restricted device", func() {
+			device := &v1alpha1.NicDevice{
+				Spec: v1alpha1.NicDeviceSpec{Configuration: &v1alpha1.NicDeviceConfigurationSpec{
+					Template: &v1alpha1.ConfigurationTemplateSpec{
+						NumVfs:                  8,
+						LinkType:                consts.Ethernet,
+						PciPerformanceOptimized: &v1alpha1.PciPerformanceOptimizedSpec{Enabled: true, MaxReadRequest: 4096},
+						Mtu:                     &v1alpha1.MtuSpec{Size: 9000},
+						EswitchMode:             consts.EswitchModeSwitchdev,
+					},
+				}},
+				Status: v1alpha1.NicDeviceStatus{
+					ConfigurationMode: consts.ConfigurationModeRestricted,
+					Ports:             []v1alpha1.NicDevicePortSpec{{PCI: vf.Address, NetworkInterface: "eth1"}},
+				},
+			}
+			mockHostUtils.On("GetInterfaceName", vf.Address).Return("eth1")
+			mockHostUtils.On("GetMtu", "eth1").Return(1500, nil)
+			mockHostUtils.On("SetMtu", "eth1", 9000).Return(nil)
+
+			Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+			mockHostUtils.AssertExpectations(GinkgoT())
+			mockHostUtils.AssertNotCalled(GinkgoT(), "SetMaxReadRequestSize", mock.Anything, mock.Anything)
+			mockHostUtils.AssertNotCalled(GinkgoT(), "SetEswitchMode", mock.Anything, mock.Anything)
+		})
+	})
+})
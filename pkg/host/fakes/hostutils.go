@@ -0,0 +1,1571 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fakes contains stateful in-memory fakes of the host interfaces.
+// Unlike mocks, fakes emulate the behavior of the firmware (next boot config becoming current after reboot, etc.)
+// and can be used to exercise the full reconcile flow in integration tests.
+package fakes
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jaypipes/ghw/pkg/pci"
+	"github.com/jaypipes/pcidb"
+
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+	"github.com/Mellanox/nic-configuration-operator/pkg/host"
+	"github.com/Mellanox/nic-configuration-operator/pkg/types"
+)
+
+// FakePort describes a single PF of a fake NIC
+type FakePort struct {
+	PCI              string
+	NetworkInterface string
+	RdmaInterface    string
+	// Speed of the port in Mb/s, 0 if the link is down
+	Speed int
+	// PtpClockIndex is the index of the PF's PTP hardware clock, nil emulates a PF without a PHC
+	PtpClockIndex *int
+	// Topology is reported as is, nil emulates a kernel without the PCI locality attributes
+	Topology *types.PCITopology
+	// Transceiver is reported as is, nil emulates an empty cage
+	Transceiver *types.Transceiver
+	// Link is reported as is, nil emulates an auto-negotiated link that is up at Speed, or down if Speed is 0
+	Link *types.LinkStatus
+	// RdmaPort is reported as is for the port's RDMA interface, nil emulates a kernel without the RDMA port attributes
+	RdmaPort *types.RdmaPort
+	// DevlinkResources are the devlink resources of the PF after boot, keyed by the resource path
+	DevlinkResources map[string]types.DevlinkResource
+	// Switchdev emulates the PF in the switchdev eswitch mode
+	Switchdev bool
+	// Representors are the network interfaces of the PF's VF representors in switchdev mode
+	Representors []string
+	// Vfs are the PCI addresses of the PF's VFs
+	Vfs []string
+	// VfDriver is the driver bound to the PF's VFs, empty emulates VFs without a driver
+	VfDriver string
+	// VfMsix is the number of MSI-X vectors of each VF after boot
+	VfMsix int
+	// VfTotalMsix is the pool of MSI-X vectors assignable to the VFs, 0 emulates a PF without dynamic VF MSI-X support
+	VfTotalMsix int
+	// DcbAppEntries are the DCB app entries of the PF's network interface after boot, e.g. installed by lldpad
+	DcbAppEntries []string
+	// RootQdisc is the root qdisc of the PF's network interface after boot, empty emulates the default mq qdisc
+	RootQdisc string
+}
+
+// FakeDevice describes a fake NIC with its ports and nv config
+type FakeDevice struct {
+	Type            string
+	SerialNumber    string
+	PartNumber      string
+	PSID            string
+	FirmwareVersion string
+	LinkType        string
+	Ports           []FakePort
+	// BaseGUID is reported by the firmware query, the flash identity of the device can't be read if empty
+	BaseGUID string
+	// NvConfig is shared by all ports of the device
+	NvConfig types.NvConfigQuery
+	// ManagedByOtherHost emulates a multi-host NIC whose eswitch manager PF belongs to another host
+	ManagedByOtherHost bool
+	// NvConfigLocked emulates the nv config ownership held by the BMC or DPU, nv config writes are rejected
+	NvConfigLocked bool
+	// FirmwareSecurity is reported as is, nil emulates firmware without security attributes
+	FirmwareSecurity *types.FirmwareSecurity
+	// PciLink is reported as is, nil emulates a kernel without the PCIe link attributes
+	PciLink *types.PCILinkStatus
+	// Temperature is the ASIC temperature in degrees Celsius, 0 emulates a device whose sensor can't be read
+	Temperature int
+	// HealthReporters are reported as is, nil emulates a driver without devlink health reporters
+	HealthReporters []types.HealthReporter
+	// RshimDevice is the rshim device of a BlueField DPU, e.g. rshim0, empty if the device has no rshim
+	RshimDevice string
+	// InstalledBFB is the path of the last BFB bundle installed to the DPU
+	InstalledBFB string
+}
+
+// FakeFirmwareImage describes a firmware image file known to the fake host
+type FakeFirmwareImage struct {
+	Version string
+	PSID    string
+	// Security is reported as is, nil emulates an unsigned image
+	Security *types.FirmwareSecurity
+}
+
+type fakeRuntimeConfig struct {
+	maxReadRequestSize int
+	trust              string
+	pfc                string
+	tcBandwidth        string
+	ecn                string
+	dcqcnParameters    map[string]int
+	flowSteeringMode   string
+	eswitchMode        string
+}
+
+// fakeDefaultTcBandwidth is the ETS bandwidth allocation of the fake network interfaces after boot
+const fakeDefaultTcBandwidth = "0,0,0,0,0,0,0,0"
+
+// fakeDefaultEcn is the ECN setting of the fake network interfaces after boot
+const fakeDefaultEcn = "0,0,0,0,0,0,0,0"
+
+// fakeDefaultDcqcnParameter is the value of the DCQCN parameters of the fake network interfaces after boot
+const fakeDefaultDcqcnParameter = 1
+
+// fakeDefaultFlowSteeringMode is the flow steering mode of the fake PFs after boot
+const fakeDefaultFlowSteeringMode = consts.FlowSteeringModeDmfs
+
+// fakeDefaultRootQdisc is the root qdisc of the fake network interfaces after boot
+const fakeDefaultRootQdisc = "mq"
+
+// fakeNetdevDefaultMtu is the MTU of the fake network interfaces after boot
+const fakeNetdevDefaultMtu = 1500
+
+// fakeDefaultRingSize and fakeMaxRingSize are the ring buffer sizes of the fake network interfaces after boot and their maximum
+const (
+	fakeDefaultRingSize = 1024
+	fakeMaxRingSize     = 8192
+)
+
+// fakeDefaultFeatures are the ethtool offload features of the fake network interfaces after boot
+var fakeDefaultFeatures = map[string]bool{
+	consts.EthtoolFeatureLro:     false,
+	consts.EthtoolFeatureGro:     true,
+	consts.EthtoolFeatureTso:     true,
+	consts.EthtoolFeatureRxGroHw: false,
+}
+
+// fakeDefaultCoalescing is the interrupt coalescing of the fake network interfaces after boot
+var fakeDefaultCoalescing = types.Coalescing{AdaptiveRx: true, AdaptiveTx: true, RxUsecs: 8, RxFrames: 128, TxUsecs: 16, TxFrames: 32}
+
+// fakeDefaultChannels and fakeMaxChannels are the combined channel count of the fake network interfaces after boot and its maximum,
+// fakeNumaNodeCPUCount is the number of CPUs of the NUMA node of the fake devices
+const (
+	fakeDefaultChannels  = 8
+	fakeMaxChannels      = 63
+	fakeNumaNodeCPUCount = 16
+)
+
+type fakeNetdevConfig struct {
+	mtu        int
+	features   map[string]bool
+	rxRing     int
+	txRing     int
+	coalescing types.Coalescing
+	channels   int
+	// trust and pfc are only used for the representors, QoS settings of the uplinks are part of the runtime config
+	trust string
+	pfc   string
+	// dcbAppEntries and rootQdisc are the host QoS settings managed outside the operator, e.g. by lldpad
+	dcbAppEntries []string
+	rootQdisc     string
+}
+
+// FakeHostUtils is a stateful in-memory implementation of host.HostUtils
+type FakeHostUtils struct {
+	mu sync.Mutex
+
+	devices     []*FakeDevice
+	pciToDevice map[string]*FakeDevice
+
+	runtimeConfig    map[string]*fakeRuntimeConfig
+	devlinkResources map[string]map[string]types.DevlinkResource
+	// netdevs are the settings of the ports' network interfaces and representors, keyed by the interface name
+	netdevs map[string]*fakeNetdevConfig
+	// vfMsix are the MSI-X vector counts of the VFs, keyed by the VF PCI address
+	vfMsix map[string]int
+	// sysctls are the sysctls set on the fake host, keyed by the name in the dotted notation, unset sysctls are 0
+	sysctls map[string]string
+
+	bootTime       time.Time
+	rebootCount    int
+	fwResets       int
+	devlinkReloads int
+	counterResets  int
+	firmwareBurns  int
+	bfbInstalls    int
+
+	// FirmwareImages are the firmware image files known to the fake host, keyed by the file path
+	FirmwareImages map[string]FakeFirmwareImage
+	// OfedVersion is returned by GetOfedVersion
+	OfedVersion string
+	// FirmwareResetError, if set, is returned by ResetNicFirmware
+	FirmwareResetError error
+	// Hypervisor is returned by GetHypervisor, the fake host is bare metal if empty
+	Hypervisor string
+}
+
+// NewFakeHostUtils creates a new fake host with the given devices
+func NewFakeHostUtils(devices ...*FakeDevice) *FakeHostUtils {
+	f := &FakeHostUtils{
+		pciToDevice:      map[string]*FakeDevice{},
+		runtimeConfig:    map[string]*fakeRuntimeConfig{},
+		devlinkResources: map[string]map[string]types.DevlinkResource{},
+		netdevs:          map[string]*fakeNetdevConfig{},
+		vfMsix:           map[string]int{},
+		sysctls:          map[string]string{},
+		bootTime:         time.Now(),
+		FirmwareImages:   map[string]FakeFirmwareImage{},
+	}
+
+	for _, device := range devices {
+		f.AddDevice(device)
+	}
+
+	return f
+}
+
+// NewFakeNvConfig creates a nv config query where default, current and next boot configs are equal to the given values
+// values can be provided in the mstconfig format, e.g. "ETH(2)"
+func NewFakeNvConfig(values map[string]string) types.NvConfigQuery {
+	query := types.NewNvConfigQuery()
+	for param, value := range values {
+		query.DefaultConfig[param] = fakeNvValues(value)
+		query.CurrentConfig[param] = fakeNvValues(value)
+		query.NextBootConfig[param] = fakeNvValues(value)
+	}
+	return query
+}
+
+// fakeNvValues converts a value in the mstconfig format to the list of its string alias and numeric value
+func fakeNvValues(value string) []string {
+	value = strings.ToLower(value)
+	if alias, numeric, found := strings.Cut(strings.TrimSuffix(value, ")"), "("); found {
+		return []string{alias, numeric}
+	}
+	return []string{value}
+}
+
+// AddDevice adds a device to the fake host
+func (f *FakeHostUtils) AddDevice(device *FakeDevice) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if device.NvConfig.DefaultConfig == nil {
+		device.NvConfig = types.NewNvConfigQuery()
+	}
+
+	f.devices = append(f.devices, device)
+	for _, port := range device.Ports {
+		f.pciToDevice[port.PCI] = device
+		f.runtimeConfig[port.PCI] = &fakeRuntimeConfig{}
+		f.devlinkResources[port.PCI] = copyDevlinkResources(port.DevlinkResources)
+		f.resetNetdevs(port)
+		f.resetVfMsix(port)
+	}
+}
+
+// resetNetdevs restores the boot settings of the port's network interface and representors
+func (f *FakeHostUtils) resetNetdevs(port FakePort) {
+	for _, name := range append([]string{port.NetworkInterface}, port.Representors...) {
+		if name != "" {
+			f.netdevs[name] = &fakeNetdevConfig{
+				mtu: fakeNetdevDefaultMtu, features: maps.Clone(fakeDefaultFeatures), rxRing: fakeDefaultRingSize, txRing: fakeDefaultRingSize,
+				coalescing: fakeDefaultCoalescing, channels: fakeDefaultChannels, rootQdisc: fakeDefaultRootQdisc,
+			}
+		}
+	}
+	if netdev, found := f.netdevs[port.NetworkInterface]; found {
+		netdev.dcbAppEntries = slices.Clone(port.DcbAppEntries)
+		if port.RootQdisc != "" {
+			netdev.rootQdisc = port.RootQdisc
+		}
+	}
+}
+
+// resetVfMsix restores the boot MSI-X vector counts of the port's VFs
+func (f *FakeHostUtils) resetVfMsix(port FakePort) {
+	for _, vf := range port.Vfs {
+		f.vfMsix[vf] = port.VfMsix
+	}
+}
+
+// VfMsixCount returns the number of MSI-X vectors of the VF, 0 if the VF is unknown
+func (f *FakeHostUtils) VfMsixCount(vfPciAddr string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.vfMsix[vfPciAddr]
+}
+
+// InterfaceMtu returns the MTU of the network interface, 0 if the interface is unknown
+func (f *FakeHostUtils) InterfaceMtu(interfaceName string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	netdev, found := f.netdevs[interfaceName]
+	if !found {
+		return 0
+	}
+	return netdev.mtu
+}
+
+// RebootCount returns the number of simulated host reboots
+func (f *FakeHostUtils) RebootCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rebootCount
+}
+
+// FirmwareResetCount returns the number of simulated firmware resets
+func (f *FakeHostUtils) FirmwareResetCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.fwResets
+}
+
+// DevlinkReloadCount returns the number of simulated devlink reloads
+func (f *FakeHostUtils) DevlinkReloadCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.devlinkReloads
+}
+
+// FirmwareBurnCount returns the number of simulated firmware burns
+func (f *FakeHostUtils) FirmwareBurnCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.firmwareBurns
+}
+
+// BFBInstallCount returns the number of simulated BFB bundle installations
+func (f *FakeHostUtils) BFBInstallCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.bfbInstalls
+}
+
+// PortCounterResetCount returns the number of simulated port counter resets
+func (f *FakeHostUtils) PortCounterResetCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.counterResets
+}
+
+// CurrentNvConfigValue returns the current value of the nv config parameter for the device with the given PCI address
+func (f *FakeHostUtils) CurrentNvConfigValue(pciAddr string, paramName string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	device, found := f.pciToDevice[pciAddr]
+	if !found {
+		return nil
+	}
+	return device.NvConfig.CurrentConfig[paramName]
+}
+
+func (f *FakeHostUtils) getDevice(pciAddr string) (*FakeDevice, error) {
+	device, found := f.pciToDevice[pciAddr]
+	if !found {
+		return nil, fmt.Errorf("device %s not found", pciAddr)
+	}
+	return device, nil
+}
+
+func (f *FakeHostUtils) getPort(pciAddr string) (FakePort, error) {
+	device, err := f.getDevice(pciAddr)
+	if err != nil {
+		return FakePort{}, err
+	}
+	for _, port := range device.Ports {
+		if port.PCI == pciAddr {
+			return port, nil
+		}
+	}
+	return FakePort{}, fmt.Errorf("port %s not found", pciAddr)
+}
+
+func copyNvConfigMap(src map[string][]string) map[string][]string {
+	dst := make(map[string][]string, len(src))
+	for k, v := range src {
+		dst[k] = append([]string{}, v...)
+	}
+	return dst
+}
+
+func copyDevlinkResources(src map[string]types.DevlinkResource) map[string]types.DevlinkResource {
+	dst := make(map[string]types.DevlinkResource, len(src))
+	for path, resource := range src {
+		if resource.SizeNew != nil {
+			sizeNew := *resource.SizeNew
+			resource.SizeNew = &sizeNew
+		}
+		dst[path] = resource
+	}
+	return dst
+}
+
+// activateNextBootConfig emulates the firmware applying the next boot config after reset / reboot
+func (f *FakeHostUtils) activateNextBootConfig(device *FakeDevice) {
+	device.NvConfig.CurrentConfig = copyNvConfigMap(device.NvConfig.NextBootConfig)
+}
+
+// GetPCIDevices returns a list of PCI devices on the host
+func (f *FakeHostUtils) GetPCIDevices() ([]*pci.Device, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	devices := []*pci.Device{}
+	for _, device := range f.devices {
+		for _, port := range device.Ports {
+			devices = append(devices, &pci.Device{
+				Address: port.PCI,
+				Vendor:  &pcidb.Vendor{ID: consts.MellanoxVendor},
+				Product: &pcidb.Product{ID: device.Type, Name: "Fake Mellanox Device"},
+				Class:   &pcidb.Class{ID: "02"},
+			})
+		}
+	}
+
+	return devices, nil
+}
+
+// GetPartAndSerialNumber returns Part and Serial numbers of the PCI device
+func (f *FakeHostUtils) GetPartAndSerialNumber(pciAddr string) (string, string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	device, err := f.getDevice(pciAddr)
+	if err != nil {
+		return "", "", err
+	}
+	return strings.ToLower(device.PartNumber), strings.ToLower(device.SerialNumber), nil
+}
+
+// QueryFirmware returns the firmware version, PSID, security attributes and flash identity of the device
+func (f *FakeHostUtils) QueryFirmware(pciAddr string) (*types.FirmwareQuery, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	device, err := f.getDevice(pciAddr)
+	if err != nil {
+		return nil, err
+	}
+	return &types.FirmwareQuery{
+		Version:    device.FirmwareVersion,
+		PSID:       strings.ToLower(device.PSID),
+		Security:   device.FirmwareSecurity,
+		BaseGUID:   strings.ToLower(device.BaseGUID),
+		PartNumber: strings.ToLower(device.PartNumber),
+	}, nil
+}
+
+// QueryFirmwareImage returns the firmware version, PSID and security attributes of a known firmware image file
+func (f *FakeHostUtils) QueryFirmwareImage(imagePath string) (*types.FirmwareQuery, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	image, found := f.FirmwareImages[imagePath]
+	if !found {
+		return nil, fmt.Errorf("firmware image %s not found", imagePath)
+	}
+	return &types.FirmwareQuery{Version: image.Version, PSID: strings.ToLower(image.PSID), Security: image.Security}, nil
+}
+
+// GetPCILinkSpeed return PCI bus speed in GT/s
+func (f *FakeHostUtils) GetPCILinkSpeed(pciAddr string) (int, error) {
+	return 16, nil
+}
+
+// GetMaxReadRequestSize returns MaxReadRequest size for PCI device
+func (f *FakeHostUtils) GetMaxReadRequestSize(pciAddr string) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	config, found := f.runtimeConfig[pciAddr]
+	if !found {
+		return -1, fmt.Errorf("device %s not found", pciAddr)
+	}
+	return config.maxReadRequestSize, nil
+}
+
+// GetTrustAndPFC returns trust and pfc settings for network interface
+func (f *FakeHostUtils) GetTrustAndPFC(interfaceName string) (string, string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, device := range f.devices {
+		for _, port := range device.Ports {
+			if port.NetworkInterface == interfaceName {
+				config := f.runtimeConfig[port.PCI]
+				return config.trust, config.pfc, nil
+			}
+			if slices.Contains(port.Representors, interfaceName) {
+				netdev := f.netdevs[interfaceName]
+				return netdev.trust, netdev.pfc, nil
+			}
+		}
+	}
+	return "", "", fmt.Errorf("interface %s not found", interfaceName)
+}
+
+// GetPortSpeed returns the speed of the PF's network port in Mb/s
+func (f *FakeHostUtils) GetPortSpeed(pciAddr string) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	device, found := f.pciToDevice[pciAddr]
+	if !found {
+		return 0, fmt.Errorf("device %s not found", pciAddr)
+	}
+	for _, port := range device.Ports {
+		if port.PCI == pciAddr {
+			return port.Speed, nil
+		}
+	}
+	return 0, nil
+}
+
+// GetPtpClockIndex returns the index of the PF's PTP hardware clock, -1 if the PF has no PHC
+func (f *FakeHostUtils) GetPtpClockIndex(pciAddr string) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, port := range f.pciToDevice[pciAddr].Ports {
+		if port.PCI == pciAddr && port.PtpClockIndex != nil {
+			return *port.PtpClockIndex, nil
+		}
+	}
+	return -1, nil
+}
+
+// GetPCITopology returns the locality of the PF
+func (f *FakeHostUtils) GetPCITopology(pciAddr string) (*types.PCITopology, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, port := range f.pciToDevice[pciAddr].Ports {
+		if port.PCI == pciAddr {
+			return port.Topology, nil
+		}
+	}
+	return nil, nil
+}
+
+// GetRDMADeviceName returns a RDMA device name for the given PCI address
+func (f *FakeHostUtils) GetRDMADeviceName(pciAddr string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, port := range f.pciToDevice[pciAddr].Ports {
+		if port.PCI == pciAddr {
+			return port.RdmaInterface
+		}
+	}
+	return ""
+}
+
+// GetInterfaceName returns a network interface name for the given PCI address
+func (f *FakeHostUtils) GetInterfaceName(pciAddr string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// VFs of the fake devices don't have network interfaces
+	device, found := f.pciToDevice[pciAddr]
+	if !found {
+		return ""
+	}
+	for _, port := range device.Ports {
+		if port.PCI == pciAddr {
+			return port.NetworkInterface
+		}
+	}
+	return ""
+}
+
+// GetLinkType return the link type of the net device (Ethernet / Infiniband)
+func (f *FakeHostUtils) GetLinkType(name string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, device := range f.devices {
+		for _, port := range device.Ports {
+			if port.NetworkInterface == name {
+				return device.LinkType
+			}
+		}
+	}
+	return ""
+}
+
+// IsSriovVF return true if the device is a SRIOV VF, false otherwise
+func (f *FakeHostUtils) IsSriovVF(pciAddr string) bool {
+	return false
+}
+
+// QueryNvConfig returns default, current and next boot configs of the device
+func (f *FakeHostUtils) QueryNvConfig(ctx context.Context, pciAddr string) (types.NvConfigQuery, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	device, err := f.getDevice(pciAddr)
+	if err != nil {
+		return types.NvConfigQuery{}, err
+	}
+
+	return types.NvConfigQuery{
+		DefaultConfig:  copyNvConfigMap(device.NvConfig.DefaultConfig),
+		CurrentConfig:  copyNvConfigMap(device.NvConfig.CurrentConfig),
+		NextBootConfig: copyNvConfigMap(device.NvConfig.NextBootConfig),
+	}, nil
+}
+
+// SetNvConfigParameter sets a nv config parameter for the next boot
+func (f *FakeHostUtils) SetNvConfigParameter(pciAddr string, paramName string, paramValue string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	device, err := f.getDevice(pciAddr)
+	if err != nil {
+		return err
+	}
+
+	if device.ManagedByOtherHost {
+		return fmt.Errorf("-E- Failed to set configuration: operation not permitted on this host")
+	}
+
+	if device.NvConfigLocked {
+		return types.ConfigOwnershipDeniedError(fmt.Sprintf("nv config of device %s is locked by the BMC or DPU", pciAddr))
+	}
+
+	if _, found := device.NvConfig.NextBootConfig[paramName]; !found && paramName != consts.AdvancedPCISettingsParam {
+		return fmt.Errorf("-E- The Device doesn't support %s parameter", paramName)
+	}
+
+	device.NvConfig.NextBootConfig[paramName] = fakeNvValues(paramValue)
+	return nil
+}
+
+// SimulateNvConfigParameters accepts the values of the parameters supported by the device without changing the nv config
+func (f *FakeHostUtils) SimulateNvConfigParameters(pciAddr string, params map[string]string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	device, err := f.getDevice(pciAddr)
+	if err != nil {
+		return true, err
+	}
+
+	for paramName := range params {
+		if _, found := device.NvConfig.NextBootConfig[paramName]; !found && paramName != consts.AdvancedPCISettingsParam {
+			return true, types.IncorrectSpecError(fmt.Sprintf("nv config values rejected by the firmware of device %s: -E- The Device doesn't support %s parameter", pciAddr, paramName))
+		}
+	}
+
+	return true, nil
+}
+
+// ResetNvConfig resets next boot nv config to default
+func (f *FakeHostUtils) ResetNvConfig(pciAddr string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	device, err := f.getDevice(pciAddr)
+	if err != nil {
+		return err
+	}
+
+	if device.NvConfigLocked {
+		return types.ConfigOwnershipDeniedError(fmt.Sprintf("nv config of device %s is locked by the BMC or DPU", pciAddr))
+	}
+
+	device.NvConfig.NextBootConfig = copyNvConfigMap(device.NvConfig.DefaultConfig)
+	return nil
+}
+
+// GetHostPrivilegeLevel returns RESTRICTED if the nv config of the device is locked, PRIVILEGED otherwise
+func (f *FakeHostUtils) GetHostPrivilegeLevel(pciAddr string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	device, err := f.getDevice(pciAddr)
+	if err != nil {
+		return "", err
+	}
+
+	if device.NvConfigLocked {
+		return "RESTRICTED", nil
+	}
+	return "PRIVILEGED", nil
+}
+
+// ResetNicFirmware emulates a firmware reset, next boot config becomes current for the device
+func (f *FakeHostUtils) ResetNicFirmware(ctx context.Context, pciAddr string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.FirmwareResetError != nil {
+		return f.FirmwareResetError
+	}
+
+	device, err := f.getDevice(pciAddr)
+	if err != nil {
+		return err
+	}
+
+	f.fwResets++
+	f.activateNextBootConfig(device)
+	return nil
+}
+
+// BurnFirmware simulates burning a known firmware image, mstflint reports the burned version right away
+func (f *FakeHostUtils) BurnFirmware(ctx context.Context, pciAddr string, imagePath string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	device, err := f.getDevice(pciAddr)
+	if err != nil {
+		return err
+	}
+
+	image, found := f.FirmwareImages[imagePath]
+	if !found {
+		return fmt.Errorf("firmware image %s not found", imagePath)
+	}
+	if !strings.EqualFold(image.PSID, device.PSID) {
+		return fmt.Errorf("firmware image %s PSID %s doesn't match device PSID %s", imagePath, image.PSID, device.PSID)
+	}
+
+	f.firmwareBurns++
+	device.FirmwareVersion = image.Version
+	return nil
+}
+
+// GetRshimDevice returns the rshim device of the fake device with the given PCI address
+func (f *FakeHostUtils) GetRshimDevice(pciAddr string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	device, err := f.getDevice(pciAddr)
+	if err != nil {
+		return "", err
+	}
+	return device.RshimDevice, nil
+}
+
+// InstallBFB simulates the installation of a BFB bundle, the DPU boots the installed image right away
+func (f *FakeHostUtils) InstallBFB(ctx context.Context, rshimDevice string, bfbPath string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, device := range f.devices {
+		if device.RshimDevice != "" && device.RshimDevice == rshimDevice {
+			f.bfbInstalls++
+			device.InstalledBFB = bfbPath
+			return nil
+		}
+	}
+	return fmt.Errorf("rshim device %s not found", rshimDevice)
+}
+
+// SetMaxReadRequestSize sets max read request size for PCI device
+func (f *FakeHostUtils) SetMaxReadRequestSize(pciAddr string, maxReadRequestSize int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	config, found := f.runtimeConfig[pciAddr]
+	if !found {
+		return fmt.Errorf("device %s not found", pciAddr)
+	}
+	config.maxReadRequestSize = maxReadRequestSize
+	return nil
+}
+
+// SetTrustAndPFC sets trust and PFC settings for a network interface
+func (f *FakeHostUtils) SetTrustAndPFC(interfaceName string, trust string, pfc string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, device := range f.devices {
+		for _, port := range device.Ports {
+			if port.NetworkInterface == interfaceName {
+				f.runtimeConfig[port.PCI].trust = trust
+				f.runtimeConfig[port.PCI].pfc = pfc
+				return nil
+			}
+			if slices.Contains(port.Representors, interfaceName) {
+				f.netdevs[interfaceName].trust = trust
+				f.netdevs[interfaceName].pfc = pfc
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("interface %s not found", interfaceName)
+}
+
+// GetTcBandwidth returns the ETS bandwidth shares of the traffic classes of the PF's network interface
+func (f *FakeHostUtils) GetTcBandwidth(interfaceName string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, device := range f.devices {
+		for _, port := range device.Ports {
+			if port.NetworkInterface == interfaceName {
+				if tcBandwidth := f.runtimeConfig[port.PCI].tcBandwidth; tcBandwidth != "" {
+					return tcBandwidth, nil
+				}
+				return fakeDefaultTcBandwidth, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("interface %s not found", interfaceName)
+}
+
+// SetTcBandwidth allocates the ETS bandwidth shares to the traffic classes of the PF's network interface
+func (f *FakeHostUtils) SetTcBandwidth(interfaceName string, tcBandwidth string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, device := range f.devices {
+		for _, port := range device.Ports {
+			if port.NetworkInterface == interfaceName {
+				f.runtimeConfig[port.PCI].tcBandwidth = tcBandwidth
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("interface %s not found", interfaceName)
+}
+
+// IsCongestionControlSupported returns true for the PF's network interfaces, the fake emulates the MOFED driver
+func (f *FakeHostUtils) IsCongestionControlSupported(interfaceName string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, device := range f.devices {
+		for _, port := range device.Ports {
+			if port.NetworkInterface == interfaceName {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GetEcn returns the priorities of the PF's network interface with ECN enabled
+func (f *FakeHostUtils) GetEcn(interfaceName string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, device := range f.devices {
+		for _, port := range device.Ports {
+			if port.NetworkInterface == interfaceName {
+				if ecn := f.runtimeConfig[port.PCI].ecn; ecn != "" {
+					return ecn, nil
+				}
+				return fakeDefaultEcn, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("interface %s not found", interfaceName)
+}
+
+// SetEcn enables ECN for the priorities of the PF's network interface
+func (f *FakeHostUtils) SetEcn(interfaceName string, ecn string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, device := range f.devices {
+		for _, port := range device.Ports {
+			if port.NetworkInterface == interfaceName {
+				f.runtimeConfig[port.PCI].ecn = ecn
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("interface %s not found", interfaceName)
+}
+
+// GetDcqcnParameter returns the value of a DCQCN parameter of the PF's network interface
+func (f *FakeHostUtils) GetDcqcnParameter(interfaceName string, name string) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, device := range f.devices {
+		for _, port := range device.Ports {
+			if port.NetworkInterface == interfaceName {
+				if value, found := f.runtimeConfig[port.PCI].dcqcnParameters[name]; found {
+					return value, nil
+				}
+				return fakeDefaultDcqcnParameter, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("interface %s not found", interfaceName)
+}
+
+// SetDcqcnParameter sets the value of a DCQCN parameter of the PF's network interface
+func (f *FakeHostUtils) SetDcqcnParameter(interfaceName string, name string, value int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, device := range f.devices {
+		for _, port := range device.Ports {
+			if port.NetworkInterface == interfaceName {
+				runtimeConfig := f.runtimeConfig[port.PCI]
+				if runtimeConfig.dcqcnParameters == nil {
+					runtimeConfig.dcqcnParameters = map[string]int{}
+				}
+				runtimeConfig.dcqcnParameters[name] = value
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("interface %s not found", interfaceName)
+}
+
+// GetDcbAppEntries returns the DCB app entries of the network interface
+func (f *FakeHostUtils) GetDcbAppEntries(interfaceName string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	netdev, found := f.netdevs[interfaceName]
+	if !found {
+		return nil, fmt.Errorf("interface %s not found", interfaceName)
+	}
+	return slices.Clone(netdev.dcbAppEntries), nil
+}
+
+// DeleteDcbAppEntry deletes the DCB app entry of the network interface
+func (f *FakeHostUtils) DeleteDcbAppEntry(interfaceName string, entry string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	netdev, found := f.netdevs[interfaceName]
+	if !found {
+		return fmt.Errorf("interface %s not found", interfaceName)
+	}
+	index := slices.Index(netdev.dcbAppEntries, entry)
+	if index == -1 {
+		return fmt.Errorf("DCB app entry %s of interface %s not found", entry, interfaceName)
+	}
+	netdev.dcbAppEntries = slices.Delete(netdev.dcbAppEntries, index, index+1)
+	return nil
+}
+
+// GetRootQdisc returns the kind of the root qdisc of the network interface
+func (f *FakeHostUtils) GetRootQdisc(interfaceName string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	netdev, found := f.netdevs[interfaceName]
+	if !found {
+		return "", fmt.Errorf("interface %s not found", interfaceName)
+	}
+	return netdev.rootQdisc, nil
+}
+
+// DeleteRootQdisc restores the default root qdisc of the network interface
+func (f *FakeHostUtils) DeleteRootQdisc(interfaceName string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	netdev, found := f.netdevs[interfaceName]
+	if !found {
+		return fmt.Errorf("interface %s not found", interfaceName)
+	}
+	netdev.rootQdisc = fakeDefaultRootQdisc
+	return nil
+}
+
+// GetMtu returns the MTU of the network interface
+func (f *FakeHostUtils) GetMtu(interfaceName string) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	netdev, found := f.netdevs[interfaceName]
+	if !found {
+		return 0, fmt.Errorf("interface %s not found", interfaceName)
+	}
+	return netdev.mtu, nil
+}
+
+// SetMtu sets the MTU of the network interface
+func (f *FakeHostUtils) SetMtu(interfaceName string, mtu int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	netdev, found := f.netdevs[interfaceName]
+	if !found {
+		return fmt.Errorf("interface %s not found", interfaceName)
+	}
+	netdev.mtu = mtu
+	return nil
+}
+
+// GetEthtoolFeatures returns the ethtool features of the network interface, none of them are fixed
+func (f *FakeHostUtils) GetEthtoolFeatures(interfaceName string) (map[string]types.EthtoolFeature, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	netdev, found := f.netdevs[interfaceName]
+	if !found {
+		return nil, fmt.Errorf("interface %s not found", interfaceName)
+	}
+	features := map[string]types.EthtoolFeature{}
+	for name, enabled := range netdev.features {
+		features[name] = types.EthtoolFeature{Enabled: enabled}
+	}
+	return features, nil
+}
+
+// SetEthtoolFeature enables or disables the ethtool feature of the network interface
+func (f *FakeHostUtils) SetEthtoolFeature(interfaceName string, feature string, enabled bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	netdev, found := f.netdevs[interfaceName]
+	if !found {
+		return fmt.Errorf("interface %s not found", interfaceName)
+	}
+	netdev.features[feature] = enabled
+	return nil
+}
+
+// GetTransceiver returns the module plugged into the port of the network interface
+func (f *FakeHostUtils) GetTransceiver(interfaceName string) (*types.Transceiver, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, found := f.netdevs[interfaceName]; !found {
+		return nil, fmt.Errorf("interface %s not found", interfaceName)
+	}
+	for _, device := range f.pciToDevice {
+		for _, port := range device.Ports {
+			if port.NetworkInterface == interfaceName {
+				return port.Transceiver, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// GetLinkStatus returns the link of the port of the network interface
+func (f *FakeHostUtils) GetLinkStatus(interfaceName string) (*types.LinkStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, found := f.netdevs[interfaceName]; !found {
+		return nil, fmt.Errorf("interface %s not found", interfaceName)
+	}
+	for _, device := range f.pciToDevice {
+		for _, port := range device.Ports {
+			if port.NetworkInterface != interfaceName {
+				continue
+			}
+			if port.Link != nil {
+				return port.Link, nil
+			}
+			autoNegotiation := true
+			link := &types.LinkStatus{OperState: "down", AutoNegotiation: &autoNegotiation}
+			if port.Speed > 0 {
+				link.OperState = "up"
+				link.Speed = port.Speed
+			}
+			return link, nil
+		}
+	}
+	return nil, fmt.Errorf("interface %s not found", interfaceName)
+}
+
+// GetRdmaPort returns the RDMA port of the fake port with the given RDMA interface, nil if no port has it
+func (f *FakeHostUtils) GetRdmaPort(rdmaDevice string) (*types.RdmaPort, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, device := range f.devices {
+		for _, port := range device.Ports {
+			if port.RdmaInterface == rdmaDevice {
+				return port.RdmaPort, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// GetRingSizes returns the ring buffer sizes of the network interface
+func (f *FakeHostUtils) GetRingSizes(interfaceName string) (types.RingSizes, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	netdev, found := f.netdevs[interfaceName]
+	if !found {
+		return types.RingSizes{}, fmt.Errorf("interface %s not found", interfaceName)
+	}
+	return types.RingSizes{Rx: netdev.rxRing, Tx: netdev.txRing, RxMax: fakeMaxRingSize, TxMax: fakeMaxRingSize}, nil
+}
+
+// SetRingSizes sets the ring buffer sizes of the network interface, sizes above the maximum are rejected
+func (f *FakeHostUtils) SetRingSizes(interfaceName string, rx int, tx int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	netdev, found := f.netdevs[interfaceName]
+	if !found {
+		return fmt.Errorf("interface %s not found", interfaceName)
+	}
+	if rx > fakeMaxRingSize || tx > fakeMaxRingSize {
+		return fmt.Errorf("ring sizes %d/%d of interface %s exceed the maximum %d", rx, tx, interfaceName, fakeMaxRingSize)
+	}
+	netdev.rxRing = rx
+	netdev.txRing = tx
+	return nil
+}
+
+// GetCoalescing returns the interrupt coalescing settings of the network interface
+func (f *FakeHostUtils) GetCoalescing(interfaceName string) (types.Coalescing, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	netdev, found := f.netdevs[interfaceName]
+	if !found {
+		return types.Coalescing{}, fmt.Errorf("interface %s not found", interfaceName)
+	}
+	return netdev.coalescing, nil
+}
+
+// SetCoalescing sets the interrupt coalescing settings of the network interface
+func (f *FakeHostUtils) SetCoalescing(interfaceName string, coalescing types.Coalescing) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	netdev, found := f.netdevs[interfaceName]
+	if !found {
+		return fmt.Errorf("interface %s not found", interfaceName)
+	}
+	netdev.coalescing = coalescing
+	return nil
+}
+
+// GetChannels returns the combined channel count of the network interface
+func (f *FakeHostUtils) GetChannels(interfaceName string) (types.Channels, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	netdev, found := f.netdevs[interfaceName]
+	if !found {
+		return types.Channels{}, fmt.Errorf("interface %s not found", interfaceName)
+	}
+	return types.Channels{Combined: netdev.channels, CombinedMax: fakeMaxChannels}, nil
+}
+
+// SetCombinedChannels sets the combined channel count of the network interface, counts above the maximum are rejected
+func (f *FakeHostUtils) SetCombinedChannels(interfaceName string, combined int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	netdev, found := f.netdevs[interfaceName]
+	if !found {
+		return fmt.Errorf("interface %s not found", interfaceName)
+	}
+	if combined > fakeMaxChannels {
+		return fmt.Errorf("combined channels %d of interface %s exceed the maximum %d", combined, interfaceName, fakeMaxChannels)
+	}
+	netdev.channels = combined
+	return nil
+}
+
+// GetNumaNodeCPUCount returns the same number of CPUs for all devices
+func (f *FakeHostUtils) GetNumaNodeCPUCount(pciAddr string) (int, error) {
+	return fakeNumaNodeCPUCount, nil
+}
+
+// GetSysctl returns the value of the sysctl, unset sysctls are 0
+func (f *FakeHostUtils) GetSysctl(name string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	value, found := f.sysctls[name]
+	if !found {
+		return "0", nil
+	}
+	return value, nil
+}
+
+// SetSysctl sets the value of the sysctl, whitespace-separated values are normalized to single spaces
+func (f *FakeHostUtils) SetSysctl(name string, value string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.sysctls[name] = strings.Join(strings.Fields(value), " ")
+	return nil
+}
+
+// switchdev returns true if the PF is in the switchdev eswitch mode, the mode set at runtime takes precedence over the boot mode
+func (f *FakeHostUtils) switchdev(port FakePort) bool {
+	if mode := f.runtimeConfig[port.PCI].eswitchMode; mode != "" {
+		return mode == consts.EswitchModeSwitchdev
+	}
+	return port.Switchdev
+}
+
+// GetEswitchMode returns switchdev for the PFs in switchdev mode and legacy for the other PFs
+func (f *FakeHostUtils) GetEswitchMode(pciAddr string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	port, err := f.getPort(pciAddr)
+	if err != nil {
+		return "", err
+	}
+	if f.switchdev(port) {
+		return consts.EswitchModeSwitchdev, nil
+	}
+	return consts.EswitchModeLegacy, nil
+}
+
+// SetEswitchMode sets the eswitch mode of the PF until the next reboot, the representors appear immediately in switchdev mode
+func (f *FakeHostUtils) SetEswitchMode(pciAddr string, mode string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, err := f.getPort(pciAddr); err != nil {
+		return err
+	}
+	if mode != consts.EswitchModeLegacy && mode != consts.EswitchModeSwitchdev {
+		return fmt.Errorf("unsupported eswitch mode %s", mode)
+	}
+	f.runtimeConfig[pciAddr].eswitchMode = mode
+	return nil
+}
+
+// GetDevlinkParam returns the runtime value of the devlink parameter of the PF, only flow_steering_mode is supported
+func (f *FakeHostUtils) GetDevlinkParam(pciAddr string, name string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, err := f.getPort(pciAddr); err != nil {
+		return "", err
+	}
+	if name != consts.DevlinkParamFlowSteeringMode {
+		return "", fmt.Errorf("devlink param %s not supported", name)
+	}
+	if mode := f.runtimeConfig[pciAddr].flowSteeringMode; mode != "" {
+		return mode, nil
+	}
+	return fakeDefaultFlowSteeringMode, nil
+}
+
+// SetDevlinkParam sets the runtime value of the devlink parameter of the PF,
+// the flow steering mode of the PF in switchdev mode can't be changed like on the real devices
+func (f *FakeHostUtils) SetDevlinkParam(pciAddr string, name string, value string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	port, err := f.getPort(pciAddr)
+	if err != nil {
+		return err
+	}
+	if name != consts.DevlinkParamFlowSteeringMode {
+		return fmt.Errorf("devlink param %s not supported", name)
+	}
+	if f.switchdev(port) {
+		return fmt.Errorf("flow steering mode of device %s can't be changed in switchdev mode", pciAddr)
+	}
+	f.runtimeConfig[pciAddr].flowSteeringMode = value
+	return nil
+}
+
+// GetVfRepresentors returns the representors of the PF in switchdev mode
+func (f *FakeHostUtils) GetVfRepresentors(pciAddr string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	port, err := f.getPort(pciAddr)
+	if err != nil {
+		return nil, err
+	}
+	if !f.switchdev(port) {
+		return []string{}, nil
+	}
+	return slices.Clone(port.Representors), nil
+}
+
+// GetVfPciAddresses returns the VFs of the PF
+func (f *FakeHostUtils) GetVfPciAddresses(pciAddr string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	port, err := f.getPort(pciAddr)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(port.Vfs), nil
+}
+
+// GetPCIDriver returns the driver of the PF's VFs for a VF and mlx5_core for a PF
+func (f *FakeHostUtils) GetPCIDriver(pciAddr string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, device := range f.devices {
+		for _, port := range device.Ports {
+			if port.PCI == pciAddr {
+				return "mlx5_core", nil
+			}
+			if slices.Contains(port.Vfs, pciAddr) {
+				return port.VfDriver, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("device %s not found", pciAddr)
+}
+
+// GetVfMsixCount returns the number of MSI-X vectors of the VF
+func (f *FakeHostUtils) GetVfMsixCount(vfPciAddr string) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	count, found := f.vfMsix[vfPciAddr]
+	if !found {
+		return -1, fmt.Errorf("VF %s not found", vfPciAddr)
+	}
+	return count, nil
+}
+
+// GetVfTotalMsix returns the pool of MSI-X vectors assignable to the PF's VFs
+func (f *FakeHostUtils) GetVfTotalMsix(pciAddr string) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	port, err := f.getPort(pciAddr)
+	if err != nil {
+		return 0, err
+	}
+	if port.VfTotalMsix == 0 {
+		return 0, fmt.Errorf("device %s doesn't support dynamic VF MSI-X assignment", pciAddr)
+	}
+	return port.VfTotalMsix, nil
+}
+
+// SetVfMsixCount sets the number of MSI-X vectors of the VF
+func (f *FakeHostUtils) SetVfMsixCount(vfPciAddr string, count int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, found := f.vfMsix[vfPciAddr]; !found {
+		return fmt.Errorf("VF %s not found", vfPciAddr)
+	}
+	f.vfMsix[vfPciAddr] = count
+	return nil
+}
+
+// GetPortCounters returns no counters for the known network interfaces, counters are not simulated
+func (f *FakeHostUtils) GetPortCounters(interfaceName string) (map[string]uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, device := range f.devices {
+		for _, port := range device.Ports {
+			if port.NetworkInterface == interfaceName {
+				return map[string]uint64{}, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("interface %s not found", interfaceName)
+}
+
+// ResetPortCounters simulates clearing the port counters of the PCI device
+func (f *FakeHostUtils) ResetPortCounters(pciAddr string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, err := f.getDevice(pciAddr)
+	if err != nil {
+		return err
+	}
+	f.counterResets++
+	return nil
+}
+
+// GetDevlinkResources returns devlink resources of the PCI device, keyed by the resource path
+func (f *FakeHostUtils) GetDevlinkResources(pciAddr string) (map[string]types.DevlinkResource, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	resources, found := f.devlinkResources[pciAddr]
+	if !found {
+		return nil, fmt.Errorf("device %s not found", pciAddr)
+	}
+	return copyDevlinkResources(resources), nil
+}
+
+// SetDevlinkResourceSize sets the size of the devlink resource to be applied after devlink reload
+func (f *FakeHostUtils) SetDevlinkResourceSize(pciAddr string, path string, size uint64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	resource, found := f.devlinkResources[pciAddr][path]
+	if !found {
+		return fmt.Errorf("resource %s of device %s not found", path, pciAddr)
+	}
+	resource.SizeNew = &size
+	f.devlinkResources[pciAddr][path] = resource
+	return nil
+}
+
+// ReloadDevlinkDevice emulates devlink reload, pending resource sizes take effect
+func (f *FakeHostUtils) ReloadDevlinkDevice(pciAddr string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	resources, found := f.devlinkResources[pciAddr]
+	if !found {
+		return fmt.Errorf("device %s not found", pciAddr)
+	}
+
+	f.devlinkReloads++
+	for path, resource := range resources {
+		if resource.SizeNew != nil {
+			resource.Size = *resource.SizeNew
+			resource.SizeNew = nil
+			resources[path] = resource
+		}
+	}
+	// The driver re-initialization drops the QoS settings
+	f.runtimeConfig[pciAddr].trust = ""
+	f.runtimeConfig[pciAddr].pfc = ""
+	return nil
+}
+
+// GetPCILinkStatus returns the PCIe link attributes of the device
+func (f *FakeHostUtils) GetPCILinkStatus(pciAddr string) (*types.PCILinkStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	device, err := f.getDevice(pciAddr)
+	if err != nil {
+		return nil, err
+	}
+	return device.PciLink, nil
+}
+
+// GetTemperature returns the ASIC temperature of the device
+func (f *FakeHostUtils) GetTemperature(pciAddr string) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	device, err := f.getDevice(pciAddr)
+	if err != nil {
+		return 0, err
+	}
+	if device.Temperature == 0 {
+		return 0, fmt.Errorf("temperature sensor of device %s can't be read", pciAddr)
+	}
+	return device.Temperature, nil
+}
+
+// GetHealthReporters returns the devlink health reporters of the device
+func (f *FakeHostUtils) GetHealthReporters(pciAddr string) ([]types.HealthReporter, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	device, err := f.getDevice(pciAddr)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(device.HealthReporters), nil
+}
+
+// IsEswitchManager returns false for the devices managed by another host
+func (f *FakeHostUtils) IsEswitchManager(pciAddr string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	device, err := f.getDevice(pciAddr)
+	if err != nil {
+		return false, err
+	}
+
+	return !device.ManagedByOtherHost, nil
+}
+
+// ScheduleReboot emulates a host reboot: next boot nv config becomes current, runtime config and devlink resources are reset
+func (f *FakeHostUtils) ScheduleReboot() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.rebootCount++
+	f.bootTime = time.Now()
+
+	for _, device := range f.devices {
+		f.activateNextBootConfig(device)
+	}
+	for pciAddr := range f.runtimeConfig {
+		f.runtimeConfig[pciAddr] = &fakeRuntimeConfig{}
+	}
+	f.sysctls = map[string]string{}
+	for _, device := range f.devices {
+		for _, port := range device.Ports {
+			f.devlinkResources[port.PCI] = copyDevlinkResources(port.DevlinkResources)
+			f.resetNetdevs(port)
+			f.resetVfMsix(port)
+		}
+	}
+
+	return nil
+}
+
+// GetOfedVersion retrieves installed OFED version
+func (f *FakeHostUtils) GetOfedVersion() string {
+	return f.OfedVersion
+}
+
+// GetHostUptimeSeconds returns the time since the last simulated boot
+func (f *FakeHostUtils) GetHostUptimeSeconds() (time.Duration, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return time.Since(f.bootTime), nil
+}
+
+// GetHostBootID returns the ID of the current fake boot, it changes with every reboot
+func (f *FakeHostUtils) GetHostBootID() (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return fmt.Sprintf("fake-boot-%d", f.rebootCount), nil
+}
+
+// GetHypervisor returns the configured Hypervisor of the fake host
+func (f *FakeHostUtils) GetHypervisor() (string, error) {
+	return f.Hypervisor, nil
+}
+
+// GetToolFailures returns no failures, fake operations don't run host tools
+func (f *FakeHostUtils) GetToolFailures(_ time.Time, _ []string) []types.ToolFailure {
+	return nil
+}
+
+// GetPCIDeviceSysfsState returns the driver bound to the fake PCI device
+func (f *FakeHostUtils) GetPCIDeviceSysfsState(pciAddr string) map[string]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, err := f.getDevice(pciAddr)
+	if err != nil {
+		return map[string]string{}
+	}
+	return map[string]string{"driver": "mlx5_core"}
+}
+
+var _ host.HostUtils = &FakeHostUtils{}
@@ -0,0 +1,744 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package host
+
+import (
+	"archive/zip"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+	"github.com/Mellanox/nic-configuration-operator/pkg/types"
+)
+
+// firmwareDownloadTimeout limits the download of a single firmware binary
+var firmwareDownloadTimeout = 10 * time.Minute
+
+const firmwareImageExtension = ".bin"
+const firmwareArchiveExtension = ".zip"
+const firmwareSignatureExtension = ".sig"
+const firmwareBundleExtension = ".bfb"
+
+// FirmwareManager contains logic for burning firmware from the NicFirmwareSources to the NIC devices
+type FirmwareManager interface {
+	// ValidateRequestedFirmware downloads the binaries of the firmware source and finds the image matching the device's PSID
+	// OCI artifacts are pulled with the registry credentials of the source's pull secrets
+	// returns string - path to the image to burn, empty if the device already has the image's firmware version
+	// returns error - the source has no image for the device, the device would reject the image or its binaries couldn't be processed
+	ValidateRequestedFirmware(ctx context.Context, device *v1alpha1.NicDevice, source *v1alpha1.NicFirmwareSource, credentials types.RegistryCredentials) (string, error)
+	// BurnFirmware burns the firmware image to the device, new firmware is activated after reboot or FW reset
+	// the device's status is updated with the burned firmware version
+	BurnFirmware(ctx context.Context, device *v1alpha1.NicDevice, imagePath string) error
+	// ValidateRequestedBFB downloads the BFB bundle of the firmware source for the BlueField DPU
+	// returns string - path to the bundle to install, empty if the device is not a DPU or already has the bundle installed
+	// returns error - the bundle couldn't be downloaded or verified
+	ValidateRequestedBFB(ctx context.Context, device *v1alpha1.NicDevice, source *v1alpha1.NicFirmwareSource, credentials types.RegistryCredentials) (string, error)
+	// InstallBFB installs the BFB bundle to the BlueField DPU via its rshim device
+	// the device's status is updated with the installed bundle
+	InstallBFB(ctx context.Context, device *v1alpha1.NicDevice, bundlePath string) error
+}
+
+// RequiredFirmwareVersion returns the firmware version pinned for the device in its spec
+// the version for the device's PSID takes precedence over the common version
+// returns empty string if no version is pinned
+func RequiredFirmwareVersion(device *v1alpha1.NicDevice) string {
+	if device.Spec.Configuration == nil || device.Spec.Configuration.Template == nil || device.Spec.Configuration.Template.Firmware == nil {
+		return ""
+	}
+
+	firmware := device.Spec.Configuration.Template.Firmware
+	for _, psidVersion := range firmware.PSIDVersions {
+		if strings.EqualFold(psidVersion.PSID, device.Status.PSID) {
+			return psidVersion.Version
+		}
+	}
+	return firmware.Version
+}
+
+// ValidateFirmwareVersion checks that the running firmware of the device matches the version pinned in its spec
+// returns types.FirmwareMismatchError if the versions differ
+func ValidateFirmwareVersion(device *v1alpha1.NicDevice) error {
+	requiredVersion := RequiredFirmwareVersion(device)
+	if requiredVersion == "" || strings.EqualFold(strings.TrimSpace(requiredVersion), device.Status.FirmwareVersion) {
+		return nil
+	}
+
+	return types.FirmwareMismatchError(fmt.Sprintf("device %s runs firmware %s, version %s is required",
+		device.Name, device.Status.FirmwareVersion, requiredVersion))
+}
+
+// firmwareImage describes a firmware image in the cache
+type firmwareImage struct {
+	path     string
+	version  string
+	psid     string
+	security *types.FirmwareSecurity
+}
+
+type firmwareManager struct {
+	cacheConfig FirmwareCacheConfig
+	hostUtils   HostUtils
+	client      *http.Client
+
+	// lock serializes the cache updates, devices are processed in parallel
+	lock sync.Mutex
+	// images contains the queried firmware images, keyed by the file path
+	images map[string]firmwareImage
+	// inUse contains the cached files being burned or installed, with the number of the devices using them,
+	// they are not evicted from the cache, protected by inUseLock
+	inUse     map[string]int
+	inUseLock sync.Mutex
+}
+
+// NewFirmwareManager creates a FirmwareManager caching the firmware binaries according to the cache config
+func NewFirmwareManager(cacheConfig FirmwareCacheConfig, hostUtils HostUtils) FirmwareManager {
+	return &firmwareManager{
+		cacheConfig: cacheConfig,
+		hostUtils:   hostUtils,
+		client:      &http.Client{Timeout: firmwareDownloadTimeout},
+		images:      map[string]firmwareImage{},
+	}
+}
+
+// ValidateRequestedFirmware downloads the binaries of the firmware source and finds the image matching the device's PSID
+// OCI artifacts are pulled with the registry credentials of the source's pull secrets
+// returns string - path to the image to burn, empty if the device already has the image's firmware version
+// returns error - the source has no image or several images for the device, the device's PSID is unknown,
+// types.FirmwareRejectedError if the device would reject the image, or the source's binaries couldn't be processed
+func (f *firmwareManager) ValidateRequestedFirmware(ctx context.Context, device *v1alpha1.NicDevice, source *v1alpha1.NicFirmwareSource, credentials types.RegistryCredentials) (string, error) {
+	log.Log.Info("FirmwareManager.ValidateRequestedFirmware()", "device", device.Name, "source", source.Name)
+
+	images, _, err := f.cacheSource(ctx, source, credentials)
+	if err != nil {
+		log.Log.Error(err, "failed to process firmware source", "source", source.Name)
+		return "", err
+	}
+
+	if len(source.Spec.BinUrlSources) == 0 {
+		if !IsBlueField(device.Status.Type) {
+			return "", types.IncorrectSpecError(
+				fmt.Sprintf("firmware source %s has only a BFB bundle, device %s is not a BlueField DPU", source.Name, device.Name))
+		}
+		return "", nil
+	}
+
+	if device.Status.PSID == "" {
+		// Never select an image without knowing which board it's built for
+		return "", fmt.Errorf("PSID of device %s is unknown, firmware image can't be selected", device.Name)
+	}
+
+	image, err := selectFirmwareImage(images, device.Status.PSID)
+	if err != nil {
+		return "", types.IncorrectSpecError(fmt.Sprintf("firmware source %s: %v for device %s", source.Name, err, device.Name))
+	}
+
+	if image.version == device.Status.FirmwareVersion {
+		log.Log.V(2).Info("device already has the requested firmware", "device", device.Name, "version", image.version)
+		return "", nil
+	}
+
+	err = checkFirmwareSecurity(device, image)
+	if err != nil {
+		log.Log.Error(err, "device would reject the firmware image", "device", device.Name, "image", image.path)
+		return "", err
+	}
+
+	log.Log.Info("device firmware differs from the requested one", "device", device.Name, "psid", image.psid,
+		"currentVersion", device.Status.FirmwareVersion, "requestedVersion", image.version)
+	return image.path, nil
+}
+
+// selectFirmwareImage returns the image built for the PSID, several matching images are allowed only if they have the same version
+// returns an error listing the PSIDs of the images if none matches, or the matching images if their versions differ
+func selectFirmwareImage(images []firmwareImage, psid string) (firmwareImage, error) {
+	matching := []firmwareImage{}
+	psids := []string{}
+	for _, image := range images {
+		if image.psid != "" && !slices.Contains(psids, image.psid) {
+			psids = append(psids, image.psid)
+		}
+		if strings.EqualFold(image.psid, psid) {
+			matching = append(matching, image)
+		}
+	}
+
+	if len(matching) == 0 {
+		return firmwareImage{}, fmt.Errorf("no image for PSID %s, images are built for PSIDs [%s]", psid, strings.Join(psids, ", "))
+	}
+
+	conflicting := false
+	descriptions := []string{}
+	for _, image := range matching {
+		conflicting = conflicting || image.version != matching[0].version
+		descriptions = append(descriptions, filepath.Base(image.path)+" "+image.version)
+	}
+	if conflicting {
+		return firmwareImage{}, fmt.Errorf("images with different versions for PSID %s: [%s]", psid, strings.Join(descriptions, ", "))
+	}
+	return matching[0], nil
+}
+
+// checkFirmwareSecurity checks that the device would accept the image according to the security attributes of its running firmware:
+// the devices enforcing signed firmware reject the unsigned images and, unless they run development firmware, the development-signed ones,
+// the images with a lower security version than the running firmware are rejected by the anti-rollback protection
+// returns types.FirmwareRejectedError if the device would reject the image, the check is skipped if the device doesn't report the attributes
+func checkFirmwareSecurity(device *v1alpha1.NicDevice, image firmwareImage) error {
+	deviceSecurity := device.Status.FirmwareSecurity
+	if deviceSecurity == nil {
+		return nil
+	}
+
+	imageAttributes := []string{}
+	var imageSecurityVersion *int
+	if image.security != nil {
+		imageAttributes = image.security.Attributes
+		imageSecurityVersion = image.security.SecurityVersion
+	}
+
+	if deviceSecurity.SecureFirmware && !slices.Contains(imageAttributes, consts.SecureFirmwareAttribute) {
+		return types.FirmwareRejectedError(fmt.Sprintf("device %s only accepts signed firmware, image %s is not signed",
+			device.Name, filepath.Base(image.path)))
+	}
+	if deviceSecurity.SecureFirmware && slices.Contains(imageAttributes, consts.DevFirmwareAttribute) &&
+		!slices.Contains(deviceSecurity.Attributes, consts.DevFirmwareAttribute) {
+		return types.FirmwareRejectedError(fmt.Sprintf("device %s runs production firmware, image %s is signed with the development keys",
+			device.Name, filepath.Base(image.path)))
+	}
+	if deviceSecurity.SecurityVersion != nil && imageSecurityVersion != nil && *imageSecurityVersion < *deviceSecurity.SecurityVersion {
+		return types.FirmwareRejectedError(fmt.Sprintf("image %s has security version %d, lower than version %d of the firmware running on device %s",
+			filepath.Base(image.path), *imageSecurityVersion, *deviceSecurity.SecurityVersion, device.Name))
+	}
+
+	return nil
+}
+
+// BurnFirmware burns the firmware image to the device, new firmware is activated after reboot or FW reset
+// the device's status is updated with the burned firmware version
+func (f *firmwareManager) BurnFirmware(ctx context.Context, device *v1alpha1.NicDevice, imagePath string) error {
+	log.Log.Info("FirmwareManager.BurnFirmware()", "device", device.Name, "imagePath", imagePath)
+
+	if len(device.Status.Ports) == 0 {
+		return fmt.Errorf("device %s has no ports", device.Name)
+	}
+
+	pciAddr := NvConfigPCIAddress(device)
+	defer f.useCachedFile(imagePath)()
+	err := f.hostUtils.BurnFirmware(ctx, pciAddr, imagePath)
+	if err != nil {
+		return err
+	}
+
+	// mstflint reports the burned version before the activation
+	query, err := f.hostUtils.QueryFirmware(pciAddr)
+	if err != nil {
+		log.Log.Error(err, "failed to query the burned firmware version", "device", device.Name)
+		return nil
+	}
+	device.Status.FirmwareVersion = query.Version
+
+	return nil
+}
+
+// ValidateRequestedBFB downloads the BFB bundle of the firmware source for the BlueField DPU
+// returns string - path to the bundle to install, empty if the device is not a DPU or already has the bundle installed
+// returns error - the bundle couldn't be downloaded or verified
+func (f *firmwareManager) ValidateRequestedBFB(ctx context.Context, device *v1alpha1.NicDevice, source *v1alpha1.NicFirmwareSource, credentials types.RegistryCredentials) (string, error) {
+	log.Log.Info("FirmwareManager.ValidateRequestedBFB()", "device", device.Name, "source", source.Name)
+
+	if source.Spec.BFBUrlSource == "" || !IsBlueField(device.Status.Type) {
+		return "", nil
+	}
+
+	bundle := BFBBundleName(source.Spec.BFBUrlSource)
+	if device.Status.BFB != nil && device.Status.BFB.Bundle == bundle {
+		log.Log.V(2).Info("device already has the requested BFB bundle", "device", device.Name, "bundle", bundle)
+		return "", nil
+	}
+
+	_, bundlePath, err := f.cacheSource(ctx, source, credentials)
+	if err != nil {
+		log.Log.Error(err, "failed to process firmware source", "source", source.Name)
+		return "", err
+	}
+
+	log.Log.Info("device BFB bundle differs from the requested one", "device", device.Name, "requestedBundle", bundle)
+	return bundlePath, nil
+}
+
+// InstallBFB installs the BFB bundle to the BlueField DPU via its rshim device
+// the device's status is updated with the installed bundle
+func (f *firmwareManager) InstallBFB(ctx context.Context, device *v1alpha1.NicDevice, bundlePath string) error {
+	log.Log.Info("FirmwareManager.InstallBFB()", "device", device.Name, "bundlePath", bundlePath)
+
+	if len(device.Status.Ports) == 0 {
+		return fmt.Errorf("device %s has no ports", device.Name)
+	}
+
+	rshimDevice, err := f.hostUtils.GetRshimDevice(device.Status.Ports[0].PCI)
+	if err != nil {
+		return err
+	}
+	if rshimDevice == "" {
+		return fmt.Errorf("rshim device of %s not found, make sure the rshim driver runs on the host", device.Name)
+	}
+
+	defer f.useCachedFile(bundlePath)()
+	err = f.hostUtils.InstallBFB(ctx, rshimDevice, bundlePath)
+	if err != nil {
+		return err
+	}
+
+	// Url hash prefix of the cache file name is dropped
+	_, bundle, _ := strings.Cut(filepath.Base(bundlePath), "-")
+	device.Status.BFB = &v1alpha1.BFBStatus{Bundle: bundle, InstallTime: metav1.Now()}
+	return nil
+}
+
+// BFBBundleName returns the name of the BFB bundle reported in the device status, the file name of its url
+func BFBBundleName(bfbUrl string) string {
+	parsedUrl, err := url.Parse(bfbUrl)
+	if err != nil {
+		return path.Base(bfbUrl)
+	}
+	return path.Base(parsedUrl.Path)
+}
+
+// cacheSource downloads the missing binaries of the firmware source, extracts the archives and queries the images
+// binaries already cached for other sources are reused, binaries of urls removed from the source are evicted from the cache
+// returns the images of the source sorted by path and the path of its BFB bundle, empty if the source has none
+func (f *firmwareManager) cacheSource(ctx context.Context, source *v1alpha1.NicFirmwareSource, credentials types.RegistryCredentials) ([]firmwareImage, string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	ctx, err := f.withSourceTransport(ctx, source)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sourceDir := filepath.Join(f.cacheConfig.Dir, source.Name)
+	err = os.MkdirAll(sourceDir, 0755)
+	if err != nil {
+		return nil, "", err
+	}
+
+	cachedFiles := map[string]bool{}
+	imagePaths := []string{}
+	for _, binUrl := range source.Spec.BinUrlSources {
+		fileName, err := f.binaryFileName(ctx, binUrl, credentials)
+		if err != nil {
+			return nil, "", err
+		}
+		extension := strings.ToLower(filepath.Ext(fileName))
+		if extension != firmwareImageExtension && extension != firmwareArchiveExtension {
+			return nil, "", types.IncorrectSpecError(fmt.Sprintf("unsupported firmware binary %s, expected %s or %s", binUrl, firmwareImageExtension, firmwareArchiveExtension))
+		}
+		cachedFiles[fileName] = true
+
+		filePath, err := f.cacheBinary(ctx, source, binUrl, filepath.Join(sourceDir, fileName), credentials)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if extension == firmwareImageExtension {
+			imagePaths = append(imagePaths, filePath)
+			continue
+		}
+
+		extractDir := filePath + firmwareExtractDirExtension
+		extracted, err := extractFirmwareArchive(filePath, extractDir)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to extract firmware archive %s: %w", binUrl, err)
+		}
+		imagePaths = append(imagePaths, extracted...)
+	}
+
+	bundlePath := ""
+	if source.Spec.BFBUrlSource != "" {
+		fileName, err := firmwareFileName(source.Spec.BFBUrlSource)
+		if err != nil {
+			return nil, "", types.IncorrectSpecError(err.Error())
+		}
+		if !strings.EqualFold(filepath.Ext(fileName), firmwareBundleExtension) {
+			return nil, "", types.IncorrectSpecError(fmt.Sprintf("unsupported BFB bundle %s, expected %s", source.Spec.BFBUrlSource, firmwareBundleExtension))
+		}
+		cachedFiles[fileName] = true
+
+		bundlePath, err = f.cacheBinary(ctx, source, source.Spec.BFBUrlSource, filepath.Join(sourceDir, fileName), credentials)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	err = f.evictCache(sourceDir, cachedFiles)
+	if err != nil {
+		log.Log.Error(err, "failed to clean up the firmware cache", "source", source.Name)
+	}
+	for imagePath := range f.images {
+		if strings.HasPrefix(imagePath, sourceDir+string(filepath.Separator)) && !slices.Contains(imagePaths, imagePath) {
+			delete(f.images, imagePath)
+		}
+	}
+
+	images := make([]firmwareImage, 0, len(imagePaths))
+	for _, imagePath := range imagePaths {
+		image, found := f.images[imagePath]
+		if !found {
+			query, err := f.hostUtils.QueryFirmwareImage(imagePath)
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to query firmware image %s: %w", imagePath, err)
+			}
+			image = firmwareImage{path: imagePath, version: query.Version, psid: query.PSID, security: query.Security}
+			f.images[imagePath] = image
+		}
+		images = append(images, image)
+	}
+	slices.SortFunc(images, func(a, b firmwareImage) int {
+		return strings.Compare(a.path, b.path)
+	})
+
+	return images, bundlePath, nil
+}
+
+// cacheBinary downloads the binary to the file path if it's not cached yet and verifies it
+// returns the file path, returns types.VerificationFailedError without downloading the binary if the source declares no verification,
+// e.g. if it was created before the verification became required
+func (f *firmwareManager) cacheBinary(ctx context.Context, source *v1alpha1.NicFirmwareSource, binUrl string, filePath string, credentials types.RegistryCredentials) (string, error) {
+	if source.Spec.Verification == nil {
+		return "", types.VerificationFailedError(fmt.Sprintf("no checksum or signature declared for firmware binary %s", binUrl))
+	}
+
+	_, err := os.Stat(filePath)
+	if os.IsNotExist(err) {
+		err = f.fetchBinary(ctx, binUrl, filepath.Base(filePath), filePath, credentials)
+	} else if err == nil {
+		touchCachedBinary(filePath)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	err = f.verifyBinary(ctx, source.Spec.Verification, binUrl, filePath)
+	if err != nil {
+		// Binary is downloaded again on the next attempt, it might have been replaced at the url
+		_ = os.RemoveAll(filePath + firmwareExtractDirExtension)
+		_ = os.Remove(filePath + firmwareSignatureExtension)
+		_ = os.Remove(filePath)
+		for imagePath := range f.images {
+			if strings.HasPrefix(imagePath, filePath) {
+				delete(f.images, imagePath)
+			}
+		}
+		return "", err
+	}
+
+	return filePath, nil
+}
+
+// verifyBinary checks the cached binary against its checksum and detached signature declared in the source
+// returns types.VerificationFailedError if the binary doesn't match them or neither of them is declared for the binary
+func (f *firmwareManager) verifyBinary(ctx context.Context, verification *v1alpha1.FirmwareVerificationSpec, binUrl string, filePath string) error {
+	digest, err := fileSHA256(filePath)
+	if err != nil {
+		return err
+	}
+
+	checksum, found := verification.SHA256[binUrl]
+	if !found && verification.PublicKey == "" {
+		return types.VerificationFailedError(fmt.Sprintf("no checksum declared for firmware binary %s", binUrl))
+	}
+
+	if found && !strings.EqualFold(strings.TrimSpace(checksum), hex.EncodeToString(digest)) {
+		return types.VerificationFailedError(fmt.Sprintf("firmware binary %s doesn't match its sha256 checksum", binUrl))
+	}
+
+	if verification.PublicKey == "" {
+		return nil
+	}
+
+	signaturePath := filePath + firmwareSignatureExtension
+	_, err = os.Stat(signaturePath)
+	if os.IsNotExist(err) {
+		if isOCIReference(binUrl) {
+			// Signature of an OCI artifact is pulled together with the binary
+			err = fmt.Errorf("OCI artifact has no layer titled with the %s suffix", firmwareSignatureExtension)
+		} else {
+			err = f.download(ctx, signatureUrl(binUrl), signaturePath)
+		}
+	}
+	if err != nil {
+		return types.VerificationFailedError(fmt.Sprintf("failed to get the signature of firmware binary %s: %v", binUrl, err))
+	}
+
+	signature, err := os.ReadFile(signaturePath)
+	if err != nil {
+		return err
+	}
+
+	return verifySignature(verification.PublicKey, digest, signature, binUrl)
+}
+
+// verifySignature verifies the detached signature of the binary's sha256 digest with the PEM encoded public key
+// signatures are accepted both raw and base64 encoded, as written by cosign sign-blob
+func verifySignature(publicKeyPEM string, digest []byte, signature []byte, binUrl string) error {
+	block, _ := pem.Decode([]byte(publicKeyPEM))
+	if block == nil {
+		return types.IncorrectSpecError("firmware verification public key is not in the PEM format")
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return types.IncorrectSpecError(fmt.Sprintf("invalid firmware verification public key: %v", err))
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err == nil {
+		signature = decoded
+	}
+
+	verified := false
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		verified = ecdsa.VerifyASN1(key, digest, signature)
+	case *rsa.PublicKey:
+		verified = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest, signature) == nil
+	default:
+		return types.IncorrectSpecError(fmt.Sprintf("unsupported firmware verification public key type %T", publicKey))
+	}
+
+	if !verified {
+		return types.VerificationFailedError(fmt.Sprintf("signature of firmware binary %s is invalid", binUrl))
+	}
+	return nil
+}
+
+// signatureUrl returns the url of the binary's detached signature
+func signatureUrl(binUrl string) string {
+	parsedUrl, err := url.Parse(binUrl)
+	if err != nil {
+		return binUrl + firmwareSignatureExtension
+	}
+	parsedUrl.Path += firmwareSignatureExtension
+	return parsedUrl.String()
+}
+
+func fileSHA256(filePath string) ([]byte, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return nil, err
+	}
+	return hash.Sum(nil), nil
+}
+
+// download saves the binary from the url to the file path, partial downloads are never left at the path
+func (f *firmwareManager) download(ctx context.Context, binUrl string, filePath string) error {
+	log.Log.Info("downloading firmware binary", "url", binUrl)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, binUrl, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := sourceTransportFrom(ctx, f.client).client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download firmware binary %s, status %d", binUrl, resp.StatusCode)
+	}
+
+	body := &progressReader{reader: resp.Body, total: resp.ContentLength, report: func(percent int) {
+		reportFirmwareProgress(ctx, consts.FirmwareUpdatePhaseDownloading, percent)
+	}}
+	err = saveBinary(filePath, body, "")
+	if err != nil {
+		return fmt.Errorf("failed to download firmware binary %s: %w", binUrl, err)
+	}
+	return nil
+}
+
+// saveBinary writes the binary to the file path, partial writes are never left at the path
+// if the sha256 digest in the hex format is provided, the binary has to match it
+func saveBinary(filePath string, body io.Reader, sha256Digest string) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(filePath), filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmpFile, hash), body)
+	closeErr := tmpFile.Close()
+	if err != nil {
+		return err
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+
+	if sha256Digest != "" && !strings.EqualFold(sha256Digest, hex.EncodeToString(hash.Sum(nil))) {
+		return fmt.Errorf("binary doesn't match its digest sha256:%s", sha256Digest)
+	}
+
+	return os.Rename(tmpFile.Name(), filePath)
+}
+
+// binaryFileName returns the cache file name of the binary url
+// the name of the binary in an OCI artifact is resolved from its layers, unless the artifact is already cached
+func (f *firmwareManager) binaryFileName(ctx context.Context, binUrl string, credentials types.RegistryCredentials) (string, error) {
+	if isOCIReference(binUrl) {
+		return f.ociFileName(ctx, binUrl, credentials)
+	}
+
+	fileName, err := firmwareFileName(binUrl)
+	if err != nil {
+		return "", types.IncorrectSpecError(err.Error())
+	}
+	return fileName, nil
+}
+
+// firmwareFileName returns the cache file name of the firmware binary url
+// the url hash prefix keeps binaries with the same name from different urls apart
+func firmwareFileName(binUrl string) (string, error) {
+	parsedUrl, err := url.Parse(binUrl)
+	if err != nil {
+		return "", fmt.Errorf("invalid firmware binary url %s: %w", binUrl, err)
+	}
+
+	baseName := path.Base(parsedUrl.Path)
+	if baseName == "." || baseName == "/" {
+		return "", fmt.Errorf("firmware binary url %s doesn't point to a file", binUrl)
+	}
+
+	return binaryUrlHash(binUrl) + "-" + baseName, nil
+}
+
+// binaryUrlHash returns the prefix of the binary's cache file name identifying its url
+func binaryUrlHash(binUrl string) string {
+	hash := sha256.Sum256([]byte(binUrl))
+	return hex.EncodeToString(hash[:])[:12]
+}
+
+// extractFirmwareArchive extracts the firmware images from the zip archive, if not extracted yet
+// returns the paths of the extracted images
+func extractFirmwareArchive(archivePath string, extractDir string) ([]string, error) {
+	_, err := os.Stat(extractDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if os.IsNotExist(err) {
+		err = unzipFirmwareImages(archivePath, extractDir)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	entries, err := os.ReadDir(extractDir)
+	if err != nil {
+		return nil, err
+	}
+
+	images := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.EqualFold(filepath.Ext(entry.Name()), firmwareImageExtension) {
+			images = append(images, filepath.Join(extractDir, entry.Name()))
+		}
+	}
+	if len(images) == 0 {
+		return nil, fmt.Errorf("archive has no %s firmware images", firmwareImageExtension)
+	}
+
+	return images, nil
+}
+
+// unzipFirmwareImages extracts the firmware images of the archive into a flat directory
+// the directory is created only after all images are extracted
+func unzipFirmwareImages(archivePath string, extractDir string) error {
+	archive, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	tmpDir, err := os.MkdirTemp(filepath.Dir(extractDir), filepath.Base(extractDir)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for _, file := range archive.File {
+		name := path.Base(file.Name)
+		if file.FileInfo().IsDir() || !strings.EqualFold(filepath.Ext(name), firmwareImageExtension) {
+			continue
+		}
+
+		err = extractZipFile(file, filepath.Join(tmpDir, name))
+		if err != nil {
+			return err
+		}
+	}
+
+	return os.Rename(tmpDir, extractDir)
+}
+
+func extractZipFile(file *zip.File, destination string) error {
+	reader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	writer, err := os.Create(destination)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(writer, reader)
+	closeErr := writer.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
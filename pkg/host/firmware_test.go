@@ -0,0 +1,649 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package host
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/mock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/host/mocks"
+	"github.com/Mellanox/nic-configuration-operator/pkg/types"
+)
+
+var _ = Describe("FirmwareManager", func() {
+	var (
+		mockHostUtils *mocks.HostUtils
+		manager       *firmwareManager
+		server        *httptest.Server
+		downloads     atomic.Int32
+		cacheDir      string
+		device        *v1alpha1.NicDevice
+		// binaries served by the server, keyed by their path
+		binaries map[string][]byte
+	)
+
+	zipArchive := func(files map[string]string) []byte {
+		buffer := &strings.Builder{}
+		writer := zip.NewWriter(buffer)
+		for name, content := range files {
+			file, err := writer.Create(name)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = file.Write([]byte(content))
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(writer.Close()).To(Succeed())
+		return []byte(buffer.String())
+	}
+
+	// newSource returns the source of the binaries declaring the checksums of all the served binaries
+	newSource := func(urls ...string) *v1alpha1.NicFirmwareSource {
+		checksums := map[string]string{}
+		for path, content := range binaries {
+			digest := sha256.Sum256(content)
+			checksums[server.URL+path] = hex.EncodeToString(digest[:])
+		}
+		return &v1alpha1.NicFirmwareSource{
+			ObjectMeta: metav1.ObjectMeta{Name: "fw-source"},
+			Spec: v1alpha1.NicFirmwareSourceSpec{
+				BinUrlSources: urls,
+				Verification:  &v1alpha1.FirmwareVerificationSpec{SHA256: checksums},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		downloads.Store(0)
+		binaries = map[string][]byte{
+			"/fw-cx6.bin":    []byte("cx6 image"),
+			"/fw-cx7.bin":    []byte("cx7 image"),
+			"/bf-bundle.bfb": []byte("bf3 bundle"),
+			"/fw-bundle.zip": zipArchive(map[string]string{
+				"bundle/fw-cx6.bin": "cx6 image",
+				"bundle/README.txt": "release notes",
+			}),
+		}
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			downloads.Add(1)
+			content, found := binaries[r.URL.Path]
+			if !found {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(content)
+		}))
+		DeferCleanup(server.Close)
+
+		cacheDir = GinkgoT().TempDir()
+		mockHostUtils = &mocks.HostUtils{}
+		manager = NewFirmwareManager(FirmwareCacheConfig{Dir: cacheDir}, mockHostUtils).(*firmwareManager)
+
+		device = &v1alpha1.NicDevice{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-device"},
+			Status: v1alpha1.NicDeviceStatus{
+				PSID:            "mt_0000000222",
+				FirmwareVersion: "22.39.1002",
+				Ports:           []v1alpha1.NicDevicePortSpec{{PCI: pciAddress}},
+			},
+		}
+	})
+
+	Describe("ValidateRequestedFirmware", func() {
+		BeforeEach(func() {
+			mockHostUtils.On("QueryFirmwareImage", mock.MatchedBy(func(path string) bool {
+				return strings.HasSuffix(path, "fw-cx6.bin")
+			})).Return(&types.FirmwareQuery{Version: "22.41.1000", PSID: "mt_0000000222"}, nil)
+			mockHostUtils.On("QueryFirmwareImage", mock.MatchedBy(func(path string) bool {
+				return strings.HasSuffix(path, "fw-cx7.bin")
+			})).Return(&types.FirmwareQuery{Version: "28.41.1000", PSID: "mt_0000000833"}, nil)
+		})
+
+		It("should return the image matching the device's PSID", func() {
+			source := newSource(server.URL+"/fw-cx7.bin", server.URL+"/fw-cx6.bin")
+
+			imagePath, err := manager.ValidateRequestedFirmware(context.Background(), device, source, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(imagePath).To(HaveSuffix("fw-cx6.bin"))
+			Expect(imagePath).To(HavePrefix(filepath.Join(cacheDir, source.Name)))
+			Expect(os.ReadFile(imagePath)).To(Equal([]byte("cx6 image")))
+		})
+		It("should return empty path if the device already has the requested version", func() {
+			device.Status.FirmwareVersion = "22.41.1000"
+
+			imagePath, err := manager.ValidateRequestedFirmware(context.Background(), device, newSource(server.URL+"/fw-cx6.bin"), nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(imagePath).To(BeEmpty())
+		})
+		It("should return IncorrectSpec error if there is no image for the device's PSID", func() {
+			_, err := manager.ValidateRequestedFirmware(context.Background(), device, newSource(server.URL+"/fw-cx7.bin"), nil)
+			Expect(types.IsIncorrectSpecError(err)).To(BeTrue())
+			Expect(err).To(MatchError(ContainSubstring("no image for PSID mt_0000000222, images are built for PSIDs [mt_0000000833]")))
+		})
+		Describe("devices enforcing signed firmware", func() {
+			signedImage := func(securityVersion int, attributes ...string) {
+				mockHostUtils.ExpectedCalls = nil
+				mockHostUtils.On("QueryFirmwareImage", mock.Anything).Return(&types.FirmwareQuery{
+					Version:  "22.41.1000",
+					PSID:     "mt_0000000222",
+					Security: &types.FirmwareSecurity{Attributes: attributes, SecurityVersion: &securityVersion},
+				}, nil)
+			}
+
+			BeforeEach(func() {
+				securityVersion := 2
+				device.Status.FirmwareSecurity = &v1alpha1.FirmwareSecurityStatus{
+					SecureFirmware:  true,
+					Attributes:      []string{"secure-fw"},
+					SecurityVersion: &securityVersion,
+				}
+			})
+
+			It("should accept the signed image with the same or a higher security version", func() {
+				signedImage(2, "secure-fw")
+
+				imagePath, err := manager.ValidateRequestedFirmware(context.Background(), device, newSource(server.URL+"/fw-cx6.bin"), nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(imagePath).To(HaveSuffix("fw-cx6.bin"))
+			})
+			It("should return FirmwareRejected error for the unsigned image", func() {
+				_, err := manager.ValidateRequestedFirmware(context.Background(), device, newSource(server.URL+"/fw-cx6.bin"), nil)
+				Expect(types.IsFirmwareRejectedError(err)).To(BeTrue())
+				Expect(err).To(MatchError(ContainSubstring("fw-cx6.bin is not signed")))
+			})
+			It("should return FirmwareRejected error for the development image on the production firmware", func() {
+				signedImage(2, "secure-fw", "dev")
+
+				_, err := manager.ValidateRequestedFirmware(context.Background(), device, newSource(server.URL+"/fw-cx6.bin"), nil)
+				Expect(types.IsFirmwareRejectedError(err)).To(BeTrue())
+				Expect(err).To(MatchError(ContainSubstring("signed with the development keys")))
+			})
+			It("should accept the development image on the development firmware", func() {
+				signedImage(2, "secure-fw", "dev")
+				device.Status.FirmwareSecurity.Attributes = []string{"secure-fw", "dev"}
+
+				_, err := manager.ValidateRequestedFirmware(context.Background(), device, newSource(server.URL+"/fw-cx6.bin"), nil)
+				Expect(err).NotTo(HaveOccurred())
+			})
+			It("should return FirmwareRejected error for the image with a lower security version", func() {
+				signedImage(1, "secure-fw")
+
+				_, err := manager.ValidateRequestedFirmware(context.Background(), device, newSource(server.URL+"/fw-cx6.bin"), nil)
+				Expect(types.IsFirmwareRejectedError(err)).To(BeTrue())
+				Expect(err).To(MatchError(ContainSubstring("fw-cx6.bin has security version 1, lower than version 2")))
+			})
+			It("should not check the security of the device that doesn't report it", func() {
+				device.Status.FirmwareSecurity = nil
+
+				_, err := manager.ValidateRequestedFirmware(context.Background(), device, newSource(server.URL+"/fw-cx6.bin"), nil)
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+		It("should select the image matching the device's PSID among several images", func() {
+			imagePath, err := manager.ValidateRequestedFirmware(context.Background(), device, newSource(server.URL+"/fw-cx7.bin", server.URL+"/fw-cx6.bin"), nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(imagePath).To(HaveSuffix("fw-cx6.bin"))
+		})
+		It("should accept several images with the same version for the device's PSID", func() {
+			imagePath, err := manager.ValidateRequestedFirmware(context.Background(), device, newSource(server.URL+"/fw-cx6.bin", server.URL+"/fw-bundle.zip"), nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(imagePath).To(HaveSuffix("fw-cx6.bin"))
+		})
+		It("should return IncorrectSpec error if several images with different versions match the device's PSID", func() {
+			mockHostUtils.ExpectedCalls = nil
+			mockHostUtils.On("QueryFirmwareImage", mock.MatchedBy(func(path string) bool {
+				return strings.HasSuffix(path, "fw-cx6.bin")
+			})).Return(&types.FirmwareQuery{Version: "22.41.1000", PSID: "mt_0000000222"}, nil)
+			mockHostUtils.On("QueryFirmwareImage", mock.MatchedBy(func(path string) bool {
+				return strings.HasSuffix(path, "fw-cx7.bin")
+			})).Return(&types.FirmwareQuery{Version: "22.42.1000", PSID: "MT_0000000222"}, nil)
+
+			_, err := manager.ValidateRequestedFirmware(context.Background(), device, newSource(server.URL+"/fw-cx6.bin", server.URL+"/fw-cx7.bin"), nil)
+			Expect(types.IsIncorrectSpecError(err)).To(BeTrue())
+			Expect(err).To(MatchError(ContainSubstring("images with different versions for PSID mt_0000000222")))
+		})
+		It("should refuse to select an image if the device's PSID is unknown", func() {
+			device.Status.PSID = ""
+
+			_, err := manager.ValidateRequestedFirmware(context.Background(), device, newSource(server.URL+"/fw-cx6.bin"), nil)
+			Expect(err).To(MatchError(ContainSubstring("PSID of device test-device is unknown")))
+			Expect(types.IsIncorrectSpecError(err)).To(BeFalse())
+		})
+		It("should return IncorrectSpec error for unsupported binaries", func() {
+			_, err := manager.ValidateRequestedFirmware(context.Background(), device, newSource(server.URL+"/fw.tgz"), nil)
+			Expect(types.IsIncorrectSpecError(err)).To(BeTrue())
+		})
+		It("should fail if the binary can't be downloaded", func() {
+			_, err := manager.ValidateRequestedFirmware(context.Background(), device, newSource(server.URL+"/missing.bin"), nil)
+			Expect(err).To(MatchError(ContainSubstring("status 404")))
+			Expect(types.IsIncorrectSpecError(err)).To(BeFalse())
+
+			entries, err := os.ReadDir(filepath.Join(cacheDir, "fw-source"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(BeEmpty())
+		})
+		It("should download and query the images only once", func() {
+			source := newSource(server.URL + "/fw-cx6.bin")
+
+			for range 3 {
+				imagePath, err := manager.ValidateRequestedFirmware(context.Background(), device, source, nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(imagePath).NotTo(BeEmpty())
+			}
+
+			Expect(downloads.Load()).To(Equal(int32(1)))
+			mockHostUtils.AssertNumberOfCalls(GinkgoT(), "QueryFirmwareImage", 1)
+		})
+		It("should report the download progress only if the binary is downloaded", func() {
+			source := newSource(server.URL + "/fw-cx6.bin")
+
+			reported := []string{}
+			ctx := WithFirmwareProgress(context.Background(), func(phase string, percent int) {
+				reported = append(reported, fmt.Sprintf("%s %d", phase, percent))
+			})
+
+			for range 2 {
+				_, err := manager.ValidateRequestedFirmware(ctx, device, source, nil)
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(reported).To(Equal([]string{"Downloading 0", "Downloading 100"}))
+		})
+		It("should extract the images from zip archives", func() {
+			imagePath, err := manager.ValidateRequestedFirmware(context.Background(), device, newSource(server.URL+"/fw-bundle.zip"), nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(filepath.Base(filepath.Dir(imagePath))).To(HaveSuffix("fw-bundle.zip.d"))
+			Expect(os.ReadFile(imagePath)).To(Equal([]byte("cx6 image")))
+
+			entries, err := os.ReadDir(filepath.Dir(imagePath))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+		})
+		It("should remove the binaries of urls no longer in the source", func() {
+			_, err := manager.ValidateRequestedFirmware(context.Background(), device, newSource(server.URL+"/fw-cx6.bin", server.URL+"/fw-bundle.zip"), nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = manager.ValidateRequestedFirmware(context.Background(), device, newSource(server.URL+"/fw-cx7.bin"), nil)
+			Expect(types.IsIncorrectSpecError(err)).To(BeTrue())
+
+			entries, err := os.ReadDir(filepath.Join(cacheDir, "fw-source"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Name()).To(HaveSuffix("fw-cx7.bin"))
+			Expect(manager.images).To(HaveLen(1))
+		})
+
+		Context("when the source declares verification", func() {
+			var (
+				source     *v1alpha1.NicFirmwareSource
+				privateKey *ecdsa.PrivateKey
+				imageUrl   string
+			)
+
+			BeforeEach(func() {
+				var err error
+				privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+				Expect(err).NotTo(HaveOccurred())
+
+				imageUrl = server.URL + "/fw-cx6.bin"
+				source = newSource(imageUrl)
+				source.Spec.Verification = &v1alpha1.FirmwareVerificationSpec{}
+			})
+
+			publicKeyPEM := func() string {
+				der, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+				Expect(err).NotTo(HaveOccurred())
+				return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+			}
+			sign := func(content string) string {
+				digest := sha256.Sum256([]byte(content))
+				signature, err := ecdsa.SignASN1(rand.Reader, privateKey, digest[:])
+				Expect(err).NotTo(HaveOccurred())
+				return base64.StdEncoding.EncodeToString(signature)
+			}
+			serveSignature := func(signature string) {
+				signatureServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					switch r.URL.Path {
+					case "/fw-cx6.bin":
+						_, _ = w.Write([]byte("cx6 image"))
+					case "/fw-cx6.bin.sig":
+						_, _ = w.Write([]byte(signature))
+					default:
+						w.WriteHeader(http.StatusNotFound)
+					}
+				}))
+				DeferCleanup(signatureServer.Close)
+				imageUrl = signatureServer.URL + "/fw-cx6.bin"
+				source.Spec.BinUrlSources = []string{imageUrl}
+			}
+
+			It("should accept the binary matching its checksum", func() {
+				digest := sha256.Sum256([]byte("cx6 image"))
+				source.Spec.Verification.SHA256 = map[string]string{imageUrl: hex.EncodeToString(digest[:])}
+
+				imagePath, err := manager.ValidateRequestedFirmware(context.Background(), device, source, nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(imagePath).To(HaveSuffix("fw-cx6.bin"))
+			})
+			It("should refuse the binary not matching its checksum and remove it from the cache", func() {
+				digest := sha256.Sum256([]byte("other image"))
+				source.Spec.Verification.SHA256 = map[string]string{imageUrl: hex.EncodeToString(digest[:])}
+
+				_, err := manager.ValidateRequestedFirmware(context.Background(), device, source, nil)
+				Expect(types.IsVerificationFailedError(err)).To(BeTrue())
+
+				entries, err := os.ReadDir(filepath.Join(cacheDir, "fw-source"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(entries).To(BeEmpty())
+				mockHostUtils.AssertNotCalled(GinkgoT(), "QueryFirmwareImage", mock.Anything)
+			})
+			It("should refuse the binary without a checksum or a signature", func() {
+				_, err := manager.ValidateRequestedFirmware(context.Background(), device, source, nil)
+				Expect(types.IsVerificationFailedError(err)).To(BeTrue())
+			})
+			It("should refuse the binaries of the source without verification without downloading them", func() {
+				source.Spec.Verification = nil
+
+				_, err := manager.ValidateRequestedFirmware(context.Background(), device, source, nil)
+				Expect(types.IsVerificationFailedError(err)).To(BeTrue())
+				Expect(err).To(MatchError(ContainSubstring("no checksum or signature declared for firmware binary " + imageUrl)))
+				Expect(downloads.Load()).To(BeZero())
+
+				source.Spec.BinUrlSources = nil
+				source.Spec.BFBUrlSource = server.URL + "/bf-bundle.bfb"
+				device.Status.Type = "a2dc"
+				_, err = manager.ValidateRequestedBFB(context.Background(), device, source, nil)
+				Expect(types.IsVerificationFailedError(err)).To(BeTrue())
+				Expect(downloads.Load()).To(BeZero())
+			})
+			It("should accept the binary with a valid detached signature", func() {
+				serveSignature(sign("cx6 image"))
+				source.Spec.Verification.PublicKey = publicKeyPEM()
+
+				imagePath, err := manager.ValidateRequestedFirmware(context.Background(), device, source, nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(imagePath).To(HaveSuffix("fw-cx6.bin"))
+			})
+			It("should refuse the binary with an invalid detached signature", func() {
+				serveSignature(sign("other image"))
+				source.Spec.Verification.PublicKey = publicKeyPEM()
+
+				_, err := manager.ValidateRequestedFirmware(context.Background(), device, source, nil)
+				Expect(types.IsVerificationFailedError(err)).To(BeTrue())
+			})
+			It("should refuse the binary if its signature is missing", func() {
+				source.Spec.Verification.PublicKey = publicKeyPEM()
+
+				_, err := manager.ValidateRequestedFirmware(context.Background(), device, source, nil)
+				Expect(types.IsVerificationFailedError(err)).To(BeTrue())
+			})
+			It("should return IncorrectSpec error for an invalid public key", func() {
+				serveSignature(sign("cx6 image"))
+				source.Spec.Verification.PublicKey = "not a key"
+
+				_, err := manager.ValidateRequestedFirmware(context.Background(), device, source, nil)
+				Expect(types.IsIncorrectSpecError(err)).To(BeTrue())
+			})
+		})
+
+		Context("when the cache is shared and limited", func() {
+			sourceFiles := func(name string) []string {
+				entries, err := os.ReadDir(filepath.Join(cacheDir, name))
+				Expect(err).NotTo(HaveOccurred())
+				files := []string{}
+				for _, entry := range entries {
+					files = append(files, entry.Name()[strings.Index(entry.Name(), "-")+1:])
+				}
+				return files
+			}
+
+			It("should reuse the binary cached for another source", func() {
+				otherSource := newSource(server.URL + "/fw-cx6.bin")
+				otherSource.Name = "other-source"
+
+				_, err := manager.ValidateRequestedFirmware(context.Background(), device, otherSource, nil)
+				Expect(err).NotTo(HaveOccurred())
+				imagePath, err := manager.ValidateRequestedFirmware(context.Background(), device, newSource(server.URL+"/fw-cx6.bin"), nil)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(imagePath).To(HavePrefix(filepath.Join(cacheDir, "fw-source")))
+				Expect(os.ReadFile(imagePath)).To(Equal([]byte("cx6 image")))
+				Expect(downloads.Load()).To(Equal(int32(1)))
+			})
+			It("should retain the most recently used binaries removed from the source", func() {
+				manager.cacheConfig.MaxRetainedVersions = 1
+
+				for _, binUrl := range []string{"/fw-cx6.bin", "/fw-cx7.bin", "/fw-bundle.zip"} {
+					_, err := manager.ValidateRequestedFirmware(context.Background(), device, newSource(server.URL+binUrl), nil)
+					Expect(err).To(Or(Not(HaveOccurred()), Satisfy(types.IsIncorrectSpecError)))
+				}
+				Expect(sourceFiles("fw-source")).To(ConsistOf("fw-bundle.zip", "fw-bundle.zip.d", "fw-cx7.bin"))
+
+				// Switching back to the retained binary doesn't download it again
+				_, err := manager.ValidateRequestedFirmware(context.Background(), device, newSource(server.URL+"/fw-cx7.bin"), nil)
+				Expect(types.IsIncorrectSpecError(err)).To(BeTrue())
+				Expect(downloads.Load()).To(Equal(int32(3)))
+				Expect(sourceFiles("fw-source")).To(ConsistOf("fw-bundle.zip", "fw-bundle.zip.d", "fw-cx7.bin"))
+			})
+			It("should evict binaries of other sources to fit the size limit", func() {
+				manager.cacheConfig.MaxSize = int64(len("cx6 image")) + 1
+				otherSource := newSource(server.URL + "/fw-cx7.bin")
+				otherSource.Name = "other-source"
+
+				_, err := manager.ValidateRequestedFirmware(context.Background(), device, otherSource, nil)
+				Expect(types.IsIncorrectSpecError(err)).To(BeTrue())
+				imagePath, err := manager.ValidateRequestedFirmware(context.Background(), device, newSource(server.URL+"/fw-cx6.bin"), nil)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(imagePath).To(BeAnExistingFile())
+				Expect(filepath.Join(cacheDir, "other-source")).NotTo(BeADirectory())
+			})
+			It("should not evict the binaries being burned", func() {
+				manager.cacheConfig.MaxSize = int64(len("cx6 image")) + 1
+				otherSource := newSource(server.URL + "/fw-cx7.bin")
+				otherSource.Name = "other-source"
+
+				_, err := manager.ValidateRequestedFirmware(context.Background(), device, otherSource, nil)
+				Expect(types.IsIncorrectSpecError(err)).To(BeTrue())
+				burned, err := filepath.Glob(filepath.Join(cacheDir, "other-source", "*-fw-cx7.bin"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(burned).To(HaveLen(1))
+				release := manager.useCachedFile(burned[0])
+
+				_, err = manager.ValidateRequestedFirmware(context.Background(), device, newSource(server.URL+"/fw-cx6.bin"), nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(burned[0]).To(BeAnExistingFile())
+
+				// The binary is evicted once the burn is done
+				release()
+				_, err = manager.ValidateRequestedFirmware(context.Background(), device, newSource(server.URL+"/fw-cx6.bin"), nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(filepath.Join(cacheDir, "other-source")).NotTo(BeADirectory())
+			})
+			It("should count the binaries shared by the sources once", func() {
+				manager.cacheConfig.MaxSize = int64(len("cx6 image")) + 1
+				otherSource := newSource(server.URL + "/fw-cx6.bin")
+				otherSource.Name = "other-source"
+
+				_, err := manager.ValidateRequestedFirmware(context.Background(), device, otherSource, nil)
+				Expect(err).NotTo(HaveOccurred())
+				_, err = manager.ValidateRequestedFirmware(context.Background(), device, newSource(server.URL+"/fw-cx6.bin"), nil)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(sourceFiles("other-source")).To(ConsistOf("fw-cx6.bin"))
+				Expect(downloads.Load()).To(Equal(int32(1)))
+			})
+			It("should keep the binaries of the source even if they exceed the size limit", func() {
+				manager.cacheConfig.MaxSize = 1
+
+				imagePath, err := manager.ValidateRequestedFirmware(context.Background(), device, newSource(server.URL+"/fw-cx6.bin"), nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(imagePath).To(BeAnExistingFile())
+			})
+		})
+	})
+
+	Describe("ValidateRequestedBFB", func() {
+		var source *v1alpha1.NicFirmwareSource
+
+		BeforeEach(func() {
+			device.Status.Type = "a2dc"
+			source = newSource()
+			source.Spec.BinUrlSources = nil
+			source.Spec.BFBUrlSource = server.URL + "/bf-bundle.bfb"
+		})
+
+		It("should return the bundle for the BlueField DPU", func() {
+			bundlePath, err := manager.ValidateRequestedBFB(context.Background(), device, source, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bundlePath).To(BeAnExistingFile())
+			Expect(os.ReadFile(bundlePath)).To(Equal([]byte("bf3 bundle")))
+		})
+		It("should return empty path if the bundle is already installed", func() {
+			device.Status.BFB = &v1alpha1.BFBStatus{Bundle: "bf-bundle.bfb"}
+
+			bundlePath, err := manager.ValidateRequestedBFB(context.Background(), device, source, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bundlePath).To(BeEmpty())
+			Expect(downloads.Load()).To(BeZero())
+		})
+		It("should ignore the bundle for devices other than BlueField DPUs", func() {
+			device.Status.Type = "1021"
+
+			bundlePath, err := manager.ValidateRequestedBFB(context.Background(), device, source, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bundlePath).To(BeEmpty())
+			Expect(downloads.Load()).To(BeZero())
+
+			_, err = manager.ValidateRequestedFirmware(context.Background(), device, source, nil)
+			Expect(types.IsIncorrectSpecError(err)).To(BeTrue())
+		})
+		It("should keep the bundle in the cache with the firmware images of the source", func() {
+			mockHostUtils.On("QueryFirmwareImage", mock.Anything).Return(&types.FirmwareQuery{Version: "32.41.1000", PSID: "mt_0000000222"}, nil)
+			source.Spec.BinUrlSources = []string{server.URL + "/fw-cx6.bin"}
+
+			bundlePath, err := manager.ValidateRequestedBFB(context.Background(), device, source, nil)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = manager.ValidateRequestedFirmware(context.Background(), device, source, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(bundlePath).To(BeAnExistingFile())
+		})
+		It("should return IncorrectSpec error for unsupported bundles", func() {
+			source.Spec.BFBUrlSource = server.URL + "/bf-bundle.tar"
+
+			_, err := manager.ValidateRequestedBFB(context.Background(), device, source, nil)
+			Expect(types.IsIncorrectSpecError(err)).To(BeTrue())
+		})
+	})
+
+	Describe("InstallBFB", func() {
+		BeforeEach(func() {
+			device.Status.Type = "a2dc"
+		})
+
+		It("should install the bundle via the rshim device and update the device's status", func() {
+			mockHostUtils.On("GetRshimDevice", pciAddress).Return("rshim0", nil)
+			mockHostUtils.On("InstallBFB", mock.Anything, "rshim0", "/cache/0123456789ab-bf-bundle.bfb").Return(nil)
+
+			Expect(manager.InstallBFB(context.Background(), device, "/cache/0123456789ab-bf-bundle.bfb")).To(Succeed())
+			Expect(device.Status.BFB).NotTo(BeNil())
+			Expect(device.Status.BFB.Bundle).To(Equal("bf-bundle.bfb"))
+		})
+		It("should fail if the DPU has no rshim device", func() {
+			mockHostUtils.On("GetRshimDevice", pciAddress).Return("", nil)
+
+			err := manager.InstallBFB(context.Background(), device, "/cache/0123456789ab-bf-bundle.bfb")
+			Expect(err).To(MatchError(ContainSubstring("rshim device of test-device not found")))
+			Expect(device.Status.BFB).To(BeNil())
+			mockHostUtils.AssertNotCalled(GinkgoT(), "InstallBFB", mock.Anything, mock.Anything, mock.Anything)
+		})
+	})
+
+	Describe("ValidateFirmwareVersion", func() {
+		BeforeEach(func() {
+			device.Status.PSID = "mt_0000000359"
+			device.Spec.Configuration = &v1alpha1.NicDeviceConfigurationSpec{
+				Template: &v1alpha1.ConfigurationTemplateSpec{Firmware: &v1alpha1.FirmwareTemplateSpec{}},
+			}
+		})
+
+		It("should accept any firmware if no version is pinned", func() {
+			Expect(ValidateFirmwareVersion(device)).To(Succeed())
+
+			device.Spec.Configuration = nil
+			Expect(ValidateFirmwareVersion(device)).To(Succeed())
+		})
+		It("should accept the pinned version", func() {
+			device.Spec.Configuration.Template.Firmware.Version = "22.39.1002"
+			Expect(ValidateFirmwareVersion(device)).To(Succeed())
+		})
+		It("should return FirmwareMismatch error if the running firmware differs from the pinned version", func() {
+			device.Spec.Configuration.Template.Firmware.Version = "22.41.1000"
+
+			err := ValidateFirmwareVersion(device)
+			Expect(types.IsFirmwareMismatchError(err)).To(BeTrue())
+			Expect(err).To(MatchError(ContainSubstring("runs firmware 22.39.1002, version 22.41.1000 is required")))
+		})
+		It("should prefer the version pinned for the device's PSID", func() {
+			device.Spec.Configuration.Template.Firmware.Version = "22.41.1000"
+			device.Spec.Configuration.Template.Firmware.PSIDVersions = []v1alpha1.FirmwarePSIDVersionSpec{
+				{PSID: "MT_0000000222", Version: "22.41.1000"},
+				{PSID: "MT_0000000359", Version: "22.39.1002"},
+			}
+			Expect(ValidateFirmwareVersion(device)).To(Succeed())
+
+			device.Status.PSID = "mt_0000000222"
+			Expect(types.IsFirmwareMismatchError(ValidateFirmwareVersion(device))).To(BeTrue())
+		})
+	})
+
+	Describe("BurnFirmware", func() {
+		It("should burn the image and update the device's firmware version", func() {
+			mockHostUtils.On("BurnFirmware", mock.Anything, pciAddress, "/cache/fw.bin").Return(nil)
+			mockHostUtils.On("QueryFirmware", pciAddress).Return(&types.FirmwareQuery{Version: "22.41.1000", PSID: "mt_0000000222"}, nil)
+
+			Expect(manager.BurnFirmware(context.Background(), device, "/cache/fw.bin")).To(Succeed())
+			Expect(device.Status.FirmwareVersion).To(Equal("22.41.1000"))
+		})
+		It("should keep the firmware version if burn fails", func() {
+			mockHostUtils.On("BurnFirmware", mock.Anything, pciAddress, "/cache/fw.bin").Return(types.ToolHangError("stuck"))
+
+			err := manager.BurnFirmware(context.Background(), device, "/cache/fw.bin")
+			Expect(types.IsToolHangError(err)).To(BeTrue())
+			Expect(device.Status.FirmwareVersion).To(Equal("22.39.1002"))
+			mockHostUtils.AssertNotCalled(GinkgoT(), "QueryFirmware", mock.Anything)
+		})
+	})
+})
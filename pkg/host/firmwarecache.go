@@ -0,0 +1,336 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package host
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+	"github.com/Mellanox/nic-configuration-operator/pkg/types"
+)
+
+const firmwareExtractDirExtension = ".d"
+const firmwareTempFileExtension = ".tmp"
+
+// FirmwareCacheConfig describes the node-local cache of the firmware binaries
+type FirmwareCacheConfig struct {
+	// Dir where the binaries are cached, in a subdirectory per NicFirmwareSource
+	Dir string
+	// MaxSize limits the total size of the cache in bytes, unlimited if 0
+	// binaries of the source being processed are never evicted
+	MaxSize int64
+	// MaxRetainedVersions is the number of binaries kept per source after their urls are removed from it,
+	// so that switching back to a previous firmware version doesn't require a new download
+	MaxRetainedVersions int
+}
+
+// firmwareCacheEntry groups a cached binary with its extracted images and signature
+type firmwareCacheEntry struct {
+	dir   string
+	name  string
+	paths []string
+	// files contains the sizes of the entry's files on the disk, binaries shared by several sources are hardlinked
+	files    map[fileID]int64
+	lastUsed time.Time
+}
+
+// fileID identifies a file on the disk regardless of its links
+type fileID struct {
+	device uint64
+	inode  uint64
+}
+
+// firmwareCacheEntryName returns the name of the binary the cache file belongs to
+func firmwareCacheEntryName(fileName string) string {
+	fileName = strings.TrimSuffix(fileName, firmwareExtractDirExtension)
+	return strings.TrimSuffix(fileName, firmwareSignatureExtension)
+}
+
+// touchCachedBinary marks the cached binary as used, least recently used binaries are evicted first
+func touchCachedBinary(filePath string) {
+	now := time.Now()
+	err := os.Chtimes(filePath, now, now)
+	if err != nil {
+		log.Log.Error(err, "failed to update the last use time of the firmware binary", "path", filePath)
+	}
+}
+
+// useCachedFile marks the cached file as in use, so that it's not evicted, until the returned function is called
+func (f *firmwareManager) useCachedFile(filePath string) func() {
+	f.inUseLock.Lock()
+	defer f.inUseLock.Unlock()
+	if f.inUse == nil {
+		f.inUse = map[string]int{}
+	}
+	f.inUse[filePath]++
+
+	return func() {
+		f.inUseLock.Lock()
+		defer f.inUseLock.Unlock()
+		f.inUse[filePath]--
+		if f.inUse[filePath] == 0 {
+			delete(f.inUse, filePath)
+		}
+	}
+}
+
+// cacheEntryInUse returns true if any file of the cache entry, e.g. an image extracted from its binary, is in use
+func (f *firmwareManager) cacheEntryInUse(entry *firmwareCacheEntry) bool {
+	f.inUseLock.Lock()
+	defer f.inUseLock.Unlock()
+
+	for inUsePath := range f.inUse {
+		for _, entryPath := range entry.paths {
+			if inUsePath == entryPath || strings.HasPrefix(inUsePath, entryPath+string(filepath.Separator)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fetchBinary links the binary if it's already cached for another source, downloads or pulls it otherwise
+func (f *firmwareManager) fetchBinary(ctx context.Context, binUrl string, fileName string, filePath string, credentials types.RegistryCredentials) error {
+	if f.linkCachedBinary(fileName, filePath) {
+		return nil
+	}
+	reportFirmwareProgress(ctx, consts.FirmwareUpdatePhaseDownloading, 0)
+	if isOCIReference(binUrl) {
+		return f.pullOCIArtifact(ctx, binUrl, filePath, credentials)
+	}
+	return f.download(ctx, binUrl, filePath)
+}
+
+// linkCachedBinary links the binary already downloaded for another source to the file path
+// returns false if no other source has the binary, the url hash in the file name identifies the binary
+func (f *firmwareManager) linkCachedBinary(fileName string, filePath string) bool {
+	matches, err := filepath.Glob(filepath.Join(f.cacheConfig.Dir, "*", fileName))
+	if err != nil {
+		return false
+	}
+
+	for _, match := range matches {
+		if match == filePath {
+			continue
+		}
+		err = os.Link(match, filePath)
+		if err != nil {
+			log.Log.V(2).Info("failed to link the cached firmware binary", "path", match, "error", err)
+			continue
+		}
+		// Detached signature is reused too, signatures of OCI artifacts can only be pulled together with the binary
+		_ = os.Link(match+firmwareSignatureExtension, filePath+firmwareSignatureExtension)
+		log.Log.Info("reusing firmware binary cached for another source", "path", match)
+		return true
+	}
+
+	return false
+}
+
+// listCacheEntries returns the cached binaries of the source directory
+// leftovers of interrupted downloads and extractions are removed
+func listCacheEntries(sourceDir string) ([]*firmwareCacheEntry, error) {
+	files, err := os.ReadDir(sourceDir)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := map[string]*firmwareCacheEntry{}
+	for _, file := range files {
+		filePath := filepath.Join(sourceDir, file.Name())
+		if strings.HasSuffix(file.Name(), firmwareTempFileExtension) {
+			err = os.RemoveAll(filePath)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		name := firmwareCacheEntryName(file.Name())
+		entry, found := entries[name]
+		if !found {
+			entry = &firmwareCacheEntry{dir: sourceDir, name: name, files: map[fileID]int64{}}
+			entries[name] = entry
+		}
+		entry.paths = append(entry.paths, filePath)
+
+		modTime, err := diskUsage(filePath, entry.files)
+		if err != nil {
+			return nil, err
+		}
+		// Extracted images and signatures don't change after the binary is used
+		if name == file.Name() || entry.lastUsed.IsZero() {
+			entry.lastUsed = modTime
+		}
+	}
+
+	result := make([]*firmwareCacheEntry, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, entry)
+	}
+	return result, nil
+}
+
+// diskUsage adds the sizes of the file or the files of the directory to files and returns its modification time
+func diskUsage(filePath string, files map[fileID]int64) (time.Time, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	err = filepath.WalkDir(filePath, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		entryInfo, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		id := fileID{}
+		if stat, ok := entryInfo.Sys().(*syscall.Stat_t); ok {
+			id = fileID{device: uint64(stat.Dev), inode: stat.Ino}
+		}
+		files[id] = entryInfo.Size()
+		return nil
+	})
+	return info.ModTime(), err
+}
+
+func (e *firmwareCacheEntry) remove() error {
+	log.Log.Info("evicting firmware binary from the cache", "path", filepath.Join(e.dir, e.name))
+	for _, entryPath := range e.paths {
+		err := os.RemoveAll(entryPath)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// evictCache deletes the binaries removed from the source beyond the retained versions,
+// then the least recently used binaries of all sources until the cache fits its size limit
+// cachedFiles contains the files of the source's current urls, they are never evicted
+func (f *firmwareManager) evictCache(sourceDir string, cachedFiles map[string]bool) error {
+	entries, err := listCacheEntries(sourceDir)
+	if err != nil {
+		return err
+	}
+
+	retained := []*firmwareCacheEntry{}
+	for _, entry := range entries {
+		if !cachedFiles[entry.name] && !f.cacheEntryInUse(entry) {
+			retained = append(retained, entry)
+		}
+	}
+	slices.SortFunc(retained, func(a, b *firmwareCacheEntry) int {
+		return b.lastUsed.Compare(a.lastUsed)
+	})
+	for len(retained) > f.cacheConfig.MaxRetainedVersions {
+		err = retained[len(retained)-1].remove()
+		if err != nil {
+			return err
+		}
+		retained = retained[:len(retained)-1]
+	}
+
+	if f.cacheConfig.MaxSize <= 0 {
+		return nil
+	}
+
+	return f.evictBySize(sourceDir, cachedFiles)
+}
+
+// evictBySize deletes the least recently used binaries of all sources until the cache fits its size limit
+// binaries being burned or installed are kept, binaries hardlinked by several sources are counted once
+// and free the disk space once evicted for all of them
+// directories of the sources left without binaries are removed too
+func (f *firmwareManager) evictBySize(sourceDir string, cachedFiles map[string]bool) error {
+	sourceDirs, err := os.ReadDir(f.cacheConfig.Dir)
+	if err != nil {
+		return err
+	}
+
+	var totalSize int64
+	links := map[fileID]int{}
+	candidates := []*firmwareCacheEntry{}
+	for _, dir := range sourceDirs {
+		if !dir.IsDir() {
+			continue
+		}
+		entries, err := listCacheEntries(filepath.Join(f.cacheConfig.Dir, dir.Name()))
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			for id, size := range entry.files {
+				if links[id] == 0 {
+					totalSize += size
+				}
+				links[id]++
+			}
+			if (entry.dir != sourceDir || !cachedFiles[entry.name]) && !f.cacheEntryInUse(entry) {
+				candidates = append(candidates, entry)
+			}
+		}
+	}
+
+	slices.SortFunc(candidates, func(a, b *firmwareCacheEntry) int {
+		return a.lastUsed.Compare(b.lastUsed)
+	})
+	for _, entry := range candidates {
+		if totalSize <= f.cacheConfig.MaxSize {
+			break
+		}
+		err = entry.remove()
+		if err != nil {
+			return err
+		}
+		for id, size := range entry.files {
+			links[id]--
+			if links[id] == 0 {
+				totalSize -= size
+			}
+		}
+	}
+
+	for _, dir := range sourceDirs {
+		dirPath := filepath.Join(f.cacheConfig.Dir, dir.Name())
+		if !dir.IsDir() || dirPath == sourceDir {
+			continue
+		}
+		// Fails for non-empty directories
+		_ = os.Remove(dirPath)
+	}
+
+	if totalSize > f.cacheConfig.MaxSize {
+		log.Log.Info("firmware binaries of the source exceed the cache size limit", "source", filepath.Base(sourceDir),
+			"size", totalSize, "limit", f.cacheConfig.MaxSize)
+	}
+
+	return nil
+}
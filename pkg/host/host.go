@@ -17,12 +17,22 @@ package host
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
 	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/Mellanox/nic-configuration-operator/pkg/changelog"
+	"github.com/Mellanox/nic-configuration-operator/pkg/lifecycle"
 	"github.com/Mellanox/nic-configuration-operator/pkg/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
@@ -30,37 +40,71 @@ import (
 	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
 )
 
+// nvConfigMaxUnconvergedWrites limits the writes of a nv config parameter whose value doesn't take effect after reboot
+// and the reboots after which the written value still doesn't take effect
+// the device is reported as non-converging instead of writing the parameter or rebooting again
+var nvConfigMaxUnconvergedWrites = 3
+
 // HostManager contains logic for managing NIC devices on the host
 type HostManager interface {
 	// DiscoverNicDevices discovers Nvidia NIC devices on the host and returns back a map of serial numbers to device statuses
-	DiscoverNicDevices() (map[string]v1alpha1.NicDeviceStatus, error)
+	// devices located in one of the ignored PCI slots are skipped, the discovery fails if a device can't be identified
+	DiscoverNicDevices(ignoredPCIAddresses []string) (map[string]v1alpha1.NicDeviceStatus, error)
+	// RefreshPortLinks updates the link state, negotiated speed and auto-negotiation of the device's ports in the discovered status
+	// cheaper than the discovery, the links are refreshed more often than the rest of the device status
+	RefreshPortLinks(status *v1alpha1.NicDeviceStatus)
+	// DiscoverEnvironment detects whether the host is a VM and lists the NVIDIA VFs and virtio-net devices passed through to it
+	// the node is configured in the restricted mode if there are any, devices located in one of the ignored PCI slots are skipped
+	DiscoverEnvironment(ignoredPCIAddresses []string) v1alpha1.NodeEnvironment
+	// DiscoverVirtualFunctions lists the SR-IOV VFs of the device's ports with their bound drivers in the discovered status
+	DiscoverVirtualFunctions(status *v1alpha1.NicDeviceStatus)
 	// ValidateDeviceNvSpec will validate device's non-volatile spec against already applied configuration on the host
 	// returns bool - nv config update required
 	// returns bool - reboot required
 	// returns error - there are errors in device's spec
 	ValidateDeviceNvSpec(ctx context.Context, device *v1alpha1.NicDevice) (bool, bool, error)
 	// ApplyDeviceNvSpec calculates device's missing nv spec configuration and applies it to the device on the host
+	// if applying fails midway, restores the previous next boot values of the changed parameters
 	// returns bool - reboot required
 	// returns error - there were errors while applying nv configuration
 	ApplyDeviceNvSpec(ctx context.Context, device *v1alpha1.NicDevice) (bool, error)
+	// RollbackDeviceNvSpec restores the next boot values of the device's nv config parameters validated before the nv spec was applied
+	// used to undo the changes of a device whose consistency group couldn't be applied entirely
+	// returns error - the previous values couldn't be restored
+	RollbackDeviceNvSpec(ctx context.Context, device *v1alpha1.NicDevice) error
 	// ApplyDeviceRuntimeSpec calculates device's missing runtime spec configuration and applies it to the device on the host
+	// renamed network interfaces of the device's ports are updated in its status
 	// returns error - there were errors while applying nv configuration
 	ApplyDeviceRuntimeSpec(device *v1alpha1.NicDevice) error
+	// ApplyRepresentorsRuntimeSpec applies the representors settings of the device's template to the VF representors
+	// of its PFs in switchdev mode, only the differing settings are changed
+	// returns error - there were errors while configuring the representors
+	ApplyRepresentorsRuntimeSpec(device *v1alpha1.NicDevice) error
 	// DiscoverOfedVersion retrieves installed OFED version
 	// returns string - installed OFED version
 	// returns empty string - OFED isn't installed or version couldn't be determined
 	DiscoverOfedVersion() string
+	// CollectDiagnostics returns a compact JSON blob describing the device's state after a failed operation
+	// includes the host tool runs on the device's ports that failed after the given time and the sysfs state of the ports
+	CollectDiagnostics(device *v1alpha1.NicDevice, since time.Time) string
 }
 
 type hostManager struct {
 	nodeName         string
 	hostUtils        HostUtils
 	configValidation configValidation
+	// changelog records the applied nv config changes, disabled if nil
+	changelog changelog.Sink
+	// lifecycleEvents publishes the runtime config drifts, disabled if nil
+	lifecycleEvents *lifecycle.Emitter
+	// eventRecorder archives the port counters before they are reset, events are not emitted if nil
+	eventRecorder record.EventRecorder
 }
 
 // DiscoverNicDevices uses host utils to discover Nvidia NIC devices on the host and returns back a map of serial numbers to device statuses
-func (h hostManager) DiscoverNicDevices() (map[string]v1alpha1.NicDeviceStatus, error) {
-	log.Log.Info("HostManager.DiscoverNicDevices()")
+// devices located in one of the ignored PCI slots are skipped without querying them
+func (h hostManager) DiscoverNicDevices(ignoredPCIAddresses []string) (map[string]v1alpha1.NicDeviceStatus, error) {
+	log.Log.Info("HostManager.DiscoverNicDevices()", "ignoredPCIAddresses", ignoredPCIAddresses)
 
 	pciDevices, err := h.hostUtils.GetPCIDevices()
 	if err != nil {
@@ -87,36 +131,85 @@ func (h hostManager) DiscoverNicDevices() (map[string]v1alpha1.NicDeviceStatus,
 			continue
 		}
 
+		if pciAddressIgnored(device.Address, ignoredPCIAddresses) {
+			log.Log.Info("Device is in the ignore list, skipping", "address", device.Address)
+			continue
+		}
+
 		if h.hostUtils.IsSriovVF(device.Address) {
 			log.Log.V(2).Info("Device is an SRIOV VF, skipping", "address", device.Address)
 			continue
 		}
 
-		log.Log.Info("Found Mellanox device", "address", device.Address, "type", device.Product.Name)
+		// The VFs without the physfn link were passed through to a VM, they are only reported and their runtime settings are applied,
+		// the rest is up to the hypervisor host
+		restricted := isNvidiaVF(device)
 
-		partNumber, serialNumber, err := h.hostUtils.GetPartAndSerialNumber(device.Address)
+		log.Log.Info("Found Mellanox device", "address", device.Address, "type", device.Product.Name, "restricted", restricted)
+
+		partNumber, serialNumber, identitySource, firmwareQuery, err := h.deviceIdentity(device.Address, restricted)
 		if err != nil {
-			log.Log.Error(err, "Failed to get device's part and serial numbers", "address", device.Address)
-			return nil, err
+			// The device is reported without its identity instead of failing the discovery of the other devices,
+			// its NicDevice CR is kept with the Degraded condition
+			log.Log.Error(err, "Failed to identify device", "address", device.Address)
+			key, status := h.unidentifiedDevice(device.Address, device.Product.ID)
+			devices[key] = status
+			continue
+		}
+		if serialNumber == "" {
+			log.Log.Info("VF doesn't expose its part and serial numbers, skipping", "address", device.Address)
+			continue
 		}
 
 		// Devices with the same serial number are ports of the same NIC, so grouping them
 		deviceStatus, ok := devices[serialNumber]
 
 		if !ok {
-			firmwareVersion, psid, err := h.hostUtils.GetFirmwareVersionAndPSID(device.Address)
-			if err != nil {
+			// The firmware is queried once per device, together with its flash identity if the VPD couldn't be read
+			if firmwareQuery == nil {
+				firmwareQuery, err = h.hostUtils.QueryFirmware(device.Address)
+			}
+			if err != nil && !restricted {
 				log.Log.Error(err, "Failed to get device's firmware and PSID", "address", device.Address)
 				return nil, err
 			}
+			if err != nil {
+				// The firmware of the VF might not be queryable from the VM, it isn't updated from there anyway
+				log.Log.Error(err, "Failed to get VF's firmware and PSID", "address", device.Address)
+				firmwareQuery = &types.FirmwareQuery{}
+			}
+
+			// Older mstflint versions don't report the security attributes, these devices are still configured
+			var firmwareSecurity *types.FirmwareSecurity
+			if !restricted {
+				firmwareSecurity = firmwareQuery.Security
+			}
+
+			// PCIe link status is informational, devices are still configured without it
+			pciLink, err := h.hostUtils.GetPCILinkStatus(device.Address)
+			if err != nil {
+				log.Log.Error(err, "Failed to get device's PCIe link status", "address", device.Address)
+			}
 
 			deviceStatus = v1alpha1.NicDeviceStatus{
-				Type:            device.Product.ID,
-				SerialNumber:    serialNumber,
-				PartNumber:      partNumber,
-				PSID:            psid,
-				FirmwareVersion: firmwareVersion,
-				Ports:           []v1alpha1.NicDevicePortSpec{},
+				Type:             device.Product.ID,
+				SerialNumber:     serialNumber,
+				PartNumber:       partNumber,
+				IdentitySource:   identitySource,
+				PSID:             firmwareQuery.PSID,
+				FirmwareVersion:  firmwareQuery.Version,
+				FirmwareSecurity: firmwareSecurityStatus(firmwareSecurity),
+				Ports:            []v1alpha1.NicDevicePortSpec{},
+				PciLink:          pciLinkStatus(pciLink),
+			}
+			if restricted {
+				deviceStatus.ConfigurationMode = consts.ConfigurationModeRestricted
+			} else {
+				// The sensors of the NIC are only accessible through its PF, the health reporters are read from each port below
+				deviceStatus.Health = h.deviceHealth(device.Address)
+				if IsBlueField(device.Product.ID) {
+					deviceStatus.BlueFieldMode = h.blueFieldMode(device.Address)
+				}
 			}
 
 			devices[serialNumber] = deviceStatus
@@ -125,19 +218,279 @@ func (h hostManager) DiscoverNicDevices() (map[string]v1alpha1.NicDeviceStatus,
 		networkInterface := h.hostUtils.GetInterfaceName(device.Address)
 		rdmaInterface := h.hostUtils.GetRDMADeviceName(device.Address)
 
-		deviceStatus.Ports = append(deviceStatus.Ports, v1alpha1.NicDevicePortSpec{
+		port := v1alpha1.NicDevicePortSpec{
 			PCI:              device.Address,
 			NetworkInterface: networkInterface,
 			RdmaInterface:    rdmaInterface,
-		})
+		}
+		// Eswitch mode is informational, it's empty if the port isn't the eswitch manager
+		eswitchMode, err := h.hostUtils.GetEswitchMode(device.Address)
+		if err != nil {
+			log.Log.Error(err, "failed to get eswitch mode of device", "address", device.Address)
+		}
+		port.EswitchMode = eswitchMode
+		// PHC index is reported for phc2sys, discovery doesn't fail if the clock can't be resolved
+		ptpClockIndex, err := h.hostUtils.GetPtpClockIndex(device.Address)
+		if err != nil {
+			log.Log.Error(err, "failed to get PTP hardware clock of device", "address", device.Address)
+		} else if ptpClockIndex >= 0 {
+			port.PtpClockIndex = &ptpClockIndex
+		}
+		// Locality is informational, it's reported for the workload schedulers
+		topology, err := h.hostUtils.GetPCITopology(device.Address)
+		if err != nil {
+			log.Log.Error(err, "failed to get PCI topology of device", "address", device.Address)
+		} else if topology != nil {
+			if topology.NumaNode >= 0 {
+				port.NumaNode = &topology.NumaNode
+			}
+			port.LocalCPUs = topology.LocalCPUs
+			port.PciRootComplex = topology.RootComplex
+		}
+		// Module is informational, e.g. for spotting mismatched optics
+		if networkInterface != "" {
+			transceiver, err := h.hostUtils.GetTransceiver(networkInterface)
+			if err != nil {
+				log.Log.Error(err, "failed to get transceiver of device", "address", device.Address)
+			}
+			port.Transceiver = transceiverStatus(transceiver)
+			port.Link = h.portLink(networkInterface)
+		}
+		// GUIDs are informational, e.g. for the inventory of the InfiniBand fabrics
+		if rdmaInterface != "" {
+			h.discoverRdmaPort(&port)
+		}
+		deviceStatus.Ports = append(deviceStatus.Ports, port)
+		// Each PF has its own devlink instance with the health reporters of the function and of its ports
+		if deviceStatus.ConfigurationMode != consts.ConfigurationModeRestricted {
+			deviceStatus.Health = h.addHealthReporters(deviceStatus.Health, device.Address)
+		}
 
 		deviceStatus.Node = h.nodeName
 		devices[deviceStatus.SerialNumber] = deviceStatus
 	}
 
+	mergeFlashIdentifiedDevices(devices)
+
 	return devices, nil
 }
 
+// pciAddressIgnored returns true if the slot of the PCI address is present in the ignore list
+// all functions of the slot are ignored, as they belong to the same NIC and share its nv config,
+// so both 0000:3b:00 and 0000:3b:00.0 entries match 0000:3b:00.1
+func pciAddressIgnored(pciAddress string, ignoredPCIAddresses []string) bool {
+	slot := pciSlot(pciAddress)
+	for _, ignored := range ignoredPCIAddresses {
+		ignored = strings.TrimSpace(ignored)
+		if ignored != "" && strings.EqualFold(slot, pciSlot(ignored)) {
+			return true
+		}
+	}
+	return false
+}
+
+// pciSlot strips the function number from the PCI address, e.g. 0000:3b:00.1 -> 0000:3b:00
+func pciSlot(pciAddress string) string {
+	slot, _, _ := strings.Cut(pciAddress, ".")
+	return slot
+}
+
+// firmwareSecurityStatus converts the security attributes reported by mstflint into the device status
+func firmwareSecurityStatus(security *types.FirmwareSecurity) *v1alpha1.FirmwareSecurityStatus {
+	if security == nil {
+		return nil
+	}
+
+	status := &v1alpha1.FirmwareSecurityStatus{
+		SecureFirmware:  slices.Contains(security.Attributes, consts.SecureFirmwareAttribute),
+		SecurityVersion: security.SecurityVersion,
+	}
+	if len(security.Attributes) != 0 {
+		status.Attributes = security.Attributes
+	}
+	return status
+}
+
+// DiscoverVirtualFunctions lists the SR-IOV VFs of the device's ports with their bound drivers in the discovered status
+// VFs are informational, the ports whose VFs can't be listed are skipped
+func (h hostManager) DiscoverVirtualFunctions(status *v1alpha1.NicDeviceStatus) {
+	var virtualFunctions []v1alpha1.VirtualFunctionStatus
+	for _, port := range status.Ports {
+		vfs, err := h.hostUtils.GetVfPciAddresses(port.PCI)
+		if err != nil {
+			log.Log.Error(err, "failed to get VFs of device", "address", port.PCI)
+			continue
+		}
+
+		for _, vf := range vfs {
+			driver, err := h.hostUtils.GetPCIDriver(vf)
+			if err != nil {
+				log.Log.Error(err, "failed to get driver of VF", "address", vf)
+			}
+			virtualFunctions = append(virtualFunctions, v1alpha1.VirtualFunctionStatus{
+				PCI:              vf,
+				PhysicalFunction: port.PCI,
+				Driver:           driver,
+			})
+		}
+	}
+
+	status.VirtualFunctions = virtualFunctions
+}
+
+// deviceHealth returns the ASIC temperature of the device, nil if it can't be read
+func (h hostManager) deviceHealth(pciAddr string) *v1alpha1.DeviceHealthStatus {
+	// Health is informational, e.g. for the early warning of overheating adapters
+	temperature, err := h.hostUtils.GetTemperature(pciAddr)
+	if err != nil {
+		log.Log.Error(err, "failed to get temperature of device", "address", pciAddr)
+		return nil
+	}
+	return &v1alpha1.DeviceHealthStatus{Temperature: &temperature}
+}
+
+// addHealthReporters adds the devlink health reporters of the PF to the health of the device, health is created if the PF reports any
+func (h hostManager) addHealthReporters(health *v1alpha1.DeviceHealthStatus, pciAddr string) *v1alpha1.DeviceHealthStatus {
+	reporters, err := h.hostUtils.GetHealthReporters(pciAddr)
+	if err != nil {
+		log.Log.Error(err, "failed to get health reporters of device", "address", pciAddr)
+	}
+	if len(reporters) == 0 {
+		return health
+	}
+
+	if health == nil {
+		health = &v1alpha1.DeviceHealthStatus{}
+	}
+	for _, reporter := range reporters {
+		health.Reporters = append(health.Reporters, v1alpha1.HealthReporterStatus{
+			Name:         reporter.Name,
+			PCI:          pciAddr,
+			State:        reporter.State,
+			ErrorCount:   reporter.ErrorCount,
+			RecoverCount: reporter.RecoverCount,
+		})
+	}
+	return health
+}
+
+// RefreshPortLinks updates the link state, negotiated speed and auto-negotiation of the device's ports in the discovered status
+// the InfiniBand ports also update the state of their RDMA link, ports without a network interface are left as is otherwise
+func (h hostManager) RefreshPortLinks(status *v1alpha1.NicDeviceStatus) {
+	for i := range status.Ports {
+		port := &status.Ports[i]
+		if port.LinkLayer == consts.RdmaLinkLayerInfiniband {
+			h.discoverRdmaPort(port)
+		}
+		if port.NetworkInterface == "" {
+			continue
+		}
+		port.Link = h.portLink(port.NetworkInterface)
+	}
+}
+
+// discoverRdmaPort sets the link layer of the port's RDMA device, and the GUIDs and the RDMA link of the InfiniBand ports
+// the RDMA attributes are cleared if they can't be read, e.g. the RDMA device was renamed since the discovery
+func (h hostManager) discoverRdmaPort(port *v1alpha1.NicDevicePortSpec) {
+	port.LinkLayer, port.NodeGUID, port.PortGUID, port.RdmaLink = "", "", "", nil
+
+	rdmaPort, err := h.hostUtils.GetRdmaPort(port.RdmaInterface)
+	if err != nil {
+		log.Log.Error(err, "failed to get RDMA port of device", "address", port.PCI, "rdmaInterface", port.RdmaInterface)
+		return
+	}
+	if rdmaPort == nil {
+		return
+	}
+
+	port.LinkLayer = rdmaPort.LinkLayer
+	if rdmaPort.LinkLayer != consts.RdmaLinkLayerInfiniband {
+		return
+	}
+	port.NodeGUID = rdmaPort.NodeGUID
+	port.PortGUID = rdmaPort.PortGUID
+	port.RdmaLink = &v1alpha1.RdmaLinkStatus{
+		State:         rdmaPort.State,
+		PhysicalState: rdmaPort.PhysicalState,
+		Rate:          rdmaPort.Rate,
+	}
+}
+
+// portLink returns the link of the network interface, nil if it can't be determined, e.g. the interface was renamed since the discovery
+func (h hostManager) portLink(interfaceName string) *v1alpha1.PortLinkStatus {
+	// Link is informational, e.g. for spotting the configured devices with the link down
+	link, err := h.hostUtils.GetLinkStatus(interfaceName)
+	if err != nil {
+		log.Log.Error(err, "failed to get link status of interface", "interfaceName", interfaceName)
+		return nil
+	}
+	return portLinkStatus(link)
+}
+
+func portLinkStatus(link *types.LinkStatus) *v1alpha1.PortLinkStatus {
+	if link == nil {
+		return nil
+	}
+
+	return &v1alpha1.PortLinkStatus{State: link.OperState, AutoNegotiation: link.AutoNegotiation, Speed: FormatPortSpeed(link.Speed)}
+}
+
+// FormatPortSpeed formats the port speed in Mb/s as reported in the status and matched by the template, e.g. 100G, 2.5G or 100M
+// returns empty string for an unknown speed
+func FormatPortSpeed(speed int) string {
+	switch {
+	case speed <= 0:
+		return ""
+	case speed < 1000:
+		return fmt.Sprintf("%dM", speed)
+	default:
+		return strconv.FormatFloat(float64(speed)/1000, 'f', -1, 64) + "G"
+	}
+}
+
+func transceiverStatus(transceiver *types.Transceiver) *v1alpha1.TransceiverStatus {
+	if transceiver == nil {
+		return nil
+	}
+
+	return &v1alpha1.TransceiverStatus{
+		Type:            transceiver.Type,
+		Technology:      transceiver.Technology,
+		Vendor:          transceiver.Vendor,
+		PartNumber:      transceiver.PartNumber,
+		Length:          transceiver.Length,
+		SupportedSpeeds: transceiver.SupportedSpeeds,
+	}
+}
+
+func pciLinkStatus(link *types.PCILinkStatus) *v1alpha1.PciLinkStatus {
+	if link == nil {
+		return nil
+	}
+
+	// Unknown speeds can't be compared, only the width is checked then
+	speed, speedKnown := parsePCILinkSpeed(link.Speed)
+	maxSpeed, maxSpeedKnown := parsePCILinkSpeed(link.MaxSpeed)
+
+	return &v1alpha1.PciLinkStatus{
+		Speed:    link.Speed,
+		Width:    link.Width,
+		MaxSpeed: link.MaxSpeed,
+		MaxWidth: link.MaxWidth,
+		Degraded: speedKnown && maxSpeedKnown && speed < maxSpeed || link.Width < link.MaxWidth,
+	}
+}
+
+// parsePCILinkSpeed parses the link speed in GT/s as reported by sysfs, e.g. 16.0 GT/s PCIe
+func parsePCILinkSpeed(speed string) (float64, bool) {
+	value, _, found := strings.Cut(speed, " GT/s")
+	if !found {
+		return 0, false
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	return parsed, err == nil
+}
+
 // ValidateDeviceNvSpec will validate device's non-volatile spec against already applied configuration on the host
 // returns bool - nv config update required
 // returns bool - reboot required
@@ -148,13 +501,37 @@ func (h hostManager) DiscoverNicDevices() (map[string]v1alpha1.NicDeviceStatus,
 func (h hostManager) ValidateDeviceNvSpec(ctx context.Context, device *v1alpha1.NicDevice) (bool, bool, error) {
 	log.Log.Info("hostManager.ValidateDeviceNvSpec", "device", device.Name)
 
-	nvConfig, err := h.hostUtils.QueryNvConfig(ctx, device.Status.Ports[0].PCI)
+	if restrictedDevice(device) {
+		// The nv config of the VF passed through to a VM can only be changed from the hypervisor host
+		log.Log.V(2).Info("skipping nv config of the restricted device", "device", device.Name)
+		device.Status.NvConfigParameters = nil
+		device.Status.PendingRebootParameters = nil
+		return false, false, nil
+	}
+
+	// On multi-host NICs, only the host owning the eswitch manager PF can change the nv config
+	eswitchManager, err := h.hostUtils.IsEswitchManager(NvConfigPCIAddress(device))
+	if types.IsEswitchAccessDeniedError(err) {
+		return false, false, err
+	} else if err != nil {
+		// Devices without eswitch support are configured as usual
+		log.Log.V(2).Info("failed to determine eswitch manager, assuming the host owns the nv config", "device", device.Name, "err", err.Error())
+	} else if !eswitchManager {
+		err = types.DelegatedToOtherHostError(fmt.Sprintf("nv config of device %s is owned by the host of the eswitch manager PF", device.Name))
+		log.Log.Info("skipping nv config of device", "device", device.Name, "reason", err.Error())
+		return false, false, err
+	}
+
+	nvConfig, err := h.queryNvConfig(ctx, device)
 	if err != nil {
 		log.Log.Error(err, "failed to query nv config", "device", device.Name)
 		return false, false, err
 	}
 
+	device.Status.PendingRebootParameters = diffCurrentAndNextBootConfig(nvConfig)
+
 	if device.Spec.Configuration.ResetToDefault {
+		device.Status.NvConfigParameters = nil
 		return h.configValidation.ValidateResetToDefault(nvConfig)
 	}
 
@@ -164,26 +541,74 @@ func (h hostManager) ValidateDeviceNvSpec(ctx context.Context, device *v1alpha1.
 		return false, false, err
 	}
 
-	configUpdateNeeded := false
-	rebootNeeded := false
+	device.Status.NvConfigParameters = renderNvConfigParametersStatus(desiredConfig, nvConfig, secretSourcedNvParams(device.Spec.Configuration.Template))
+	pruneUnconvergedWrites(device, desiredConfig, nvConfig)
 
 	// If ADVANCED_PCI_SETTINGS are enabled in current config, unknown parameters are treated as spec error
 	advancedPciSettingsEnabled := h.configValidation.AdvancedPCISettingsEnabled(nvConfig)
 
+	return nvConfigChangesNeeded(device, desiredConfig, nvConfig, advancedPciSettingsEnabled, h.unconvergedWritesBootID(device))
+}
+
+// unconvergedWritesBootID returns the boot ID of the host if some of the written parameters haven't taken effect yet,
+// the reboots after the writes are not counted without the boot ID
+func (h hostManager) unconvergedWritesBootID(device *v1alpha1.NicDevice) string {
+	if device.Status.NvConfigWriteStats == nil || len(device.Status.NvConfigWriteStats.Unconverged) == 0 {
+		return ""
+	}
+
+	bootID, err := h.hostUtils.GetHostBootID()
+	if err != nil {
+		log.Log.Error(err, "failed to get host boot ID", "device", device.Name)
+		return ""
+	}
+	return bootID
+}
+
+// nvConfigChangesNeeded compares the desired nv config parameters with the queried nv config of the device
+// returns bool - nv config update is needed
+// returns bool - reboot is needed
+// returns error - if some of the parameters are unsupported or don't converge
+// the reboots after which the written parameters still don't take effect are counted by the boot ID of the host
+func nvConfigChangesNeeded(device *v1alpha1.NicDevice, desiredConfig map[string]string, nvConfig types.NvConfigQuery,
+	advancedPciSettingsEnabled bool, bootID string) (bool, bool, error) {
+	configUpdateNeeded := false
+	rebootNeeded := false
+
 	for parameter, desiredValue := range desiredConfig {
 		currentValues, foundInCurrent := nvConfig.CurrentConfig[parameter]
 		nextValues, foundInNextBoot := nvConfig.NextBootConfig[parameter]
-		if advancedPciSettingsEnabled && !foundInCurrent {
-			err = types.IncorrectSpecError(fmt.Sprintf("Parameter %s unsupported for device %s", parameter, device.Name))
+		if advancedPciSettingsEnabled && !foundInCurrent && !prerequisitesPending(parameter, desiredConfig, nvConfig) {
+			err := types.IncorrectSpecError(fmt.Sprintf("Parameter %s unsupported for device %s", parameter, device.Name))
 			log.Log.Error(err, "can't set nv config parameter for device")
 			return false, false, err
 		}
 
-		if foundInNextBoot && slices.Contains(nextValues, strings.ToLower(desiredValue)) {
-			if !foundInCurrent || !slices.Contains(currentValues, strings.ToLower(desiredValue)) {
+		if foundInNextBoot && NvParamValueMatches(parameter, desiredValue, nextValues) {
+			if !foundInCurrent || !NvParamValueMatches(parameter, desiredValue, currentValues) {
+				// Rebooting again won't help if the firmware keeps the old value after reboot
+				reboots := countUnconvergedReboot(device, parameter, bootID)
+				if reboots >= nvConfigMaxUnconvergedWrites {
+					err := types.NonConvergingError(fmt.Sprintf(
+						"parameter %s has next boot value %s, but the firmware reports current values %v after %d reboots, the firmware may be ignoring the setting",
+						parameter, desiredValue, currentValues, reboots))
+					log.Log.Error(err, "nv config of device doesn't converge", "device", device.Name)
+					return false, false, err
+				}
+
 				rebootNeeded = true
 			}
 		} else {
+			// Writing the parameter again won't help if the firmware keeps ignoring it
+			writes := unconvergedWrites(device, parameter)
+			if writes >= nvConfigMaxUnconvergedWrites {
+				err := types.NonConvergingError(fmt.Sprintf(
+					"parameter %s was written %d times with value %s, but the firmware reports current values %v and next boot values %v, the firmware may be ignoring the setting",
+					parameter, writes, desiredValue, currentValues, nextValues))
+				log.Log.Error(err, "nv config of device doesn't converge", "device", device.Name)
+				return false, false, err
+			}
+
 			configUpdateNeeded = true
 			rebootNeeded = true
 		}
@@ -192,13 +617,207 @@ func (h hostManager) ValidateDeviceNvSpec(ctx context.Context, device *v1alpha1.
 	return configUpdateNeeded, rebootNeeded, nil
 }
 
+// unconvergedWrites returns the number of writes of the parameter that haven't taken effect yet
+func unconvergedWrites(device *v1alpha1.NicDevice, param string) int {
+	if device.Status.NvConfigWriteStats == nil {
+		return 0
+	}
+	for _, write := range device.Status.NvConfigWriteStats.Unconverged {
+		if write.Name == param {
+			return write.Writes
+		}
+	}
+	return 0
+}
+
+// countUnconvergedReboot counts the reboot of the host if the parameter was written before it, but its current value doesn't match
+// returns the number of reboots after which the written value didn't take effect
+func countUnconvergedReboot(device *v1alpha1.NicDevice, param string, bootID string) int {
+	if device.Status.NvConfigWriteStats == nil {
+		return 0
+	}
+	for i, write := range device.Status.NvConfigWriteStats.Unconverged {
+		if write.Name != param {
+			continue
+		}
+		if bootID != "" && write.BootID != "" && write.BootID != bootID {
+			device.Status.NvConfigWriteStats.Unconverged[i].Reboots++
+			device.Status.NvConfigWriteStats.Unconverged[i].BootID = bootID
+		}
+		return device.Status.NvConfigWriteStats.Unconverged[i].Reboots
+	}
+	return 0
+}
+
+// countUnconvergedWrite counts the write of the parameter until its current value matches the written value
+// the boot ID of the write is recorded to count the reboots after which the value doesn't take effect
+func countUnconvergedWrite(device *v1alpha1.NicDevice, param string, value string, bootID string) {
+	stats := writeStats(device)
+	for i, write := range stats.Unconverged {
+		if write.Name == param {
+			if !NvParamValueMatches(param, value, []string{write.Value}) {
+				// New desired value, previous writes and reboots don't count
+				stats.Unconverged[i].Value = value
+				stats.Unconverged[i].Writes = 0
+				stats.Unconverged[i].Reboots = 0
+			}
+			stats.Unconverged[i].Writes++
+			stats.Unconverged[i].BootID = bootID
+			return
+		}
+	}
+
+	stats.Unconverged = append(stats.Unconverged, v1alpha1.NvConfigUnconvergedWrite{Name: param, Value: value, Writes: 1, BootID: bootID})
+	sort.Slice(stats.Unconverged, func(i, j int) bool { return stats.Unconverged[i].Name < stats.Unconverged[j].Name })
+}
+
+// pruneUnconvergedWrites drops the write counters of parameters that took effect, are no longer desired or have a new desired value
+func pruneUnconvergedWrites(device *v1alpha1.NicDevice, desiredConfig map[string]string, nvConfig types.NvConfigQuery) {
+	stats := device.Status.NvConfigWriteStats
+	if stats == nil || len(stats.Unconverged) == 0 {
+		return
+	}
+
+	stats.Unconverged = slices.DeleteFunc(stats.Unconverged, func(write v1alpha1.NvConfigUnconvergedWrite) bool {
+		desiredValue, desired := desiredConfig[write.Name]
+		if !desired || !NvParamValueMatches(write.Name, desiredValue, []string{write.Value}) {
+			return true
+		}
+		currentValues, found := nvConfig.CurrentConfig[write.Name]
+		return found && NvParamValueMatches(write.Name, desiredValue, currentValues)
+	})
+	if len(stats.Unconverged) == 0 {
+		stats.Unconverged = nil
+	}
+}
+
+// writeStats returns the nv config write counters of the device, initializing them if needed
+func writeStats(device *v1alpha1.NicDevice) *v1alpha1.NvConfigWriteStats {
+	if device.Status.NvConfigWriteStats == nil {
+		device.Status.NvConfigWriteStats = &v1alpha1.NvConfigWriteStats{}
+	}
+	return device.Status.NvConfigWriteStats
+}
+
+// prerequisitesPending returns true if some of the parameters the given parameter depends on are not applied yet,
+// such parameter can become available after its prerequisites are applied
+func prerequisitesPending(param string, desiredConfig map[string]string, nvConfig types.NvConfigQuery) bool {
+	for _, prerequisite := range nvParamPrerequisites(param, desiredConfig) {
+		currentValues, found := nvConfig.CurrentConfig[prerequisite]
+		if !found || !NvParamValueMatches(prerequisite, desiredConfig[prerequisite], currentValues) {
+			return true
+		}
+	}
+	return false
+}
+
+// renderNvConfigParametersStatus combines the desired nv config parameters with their current and next boot values
+// and marks the parameters whose desired value is the device default
+// the values of the redacted parameters are replaced with consts.RedactedNvParamValue
+// returns the list sorted by parameter name
+func renderNvConfigParametersStatus(desiredConfig map[string]string, nvConfig types.NvConfigQuery, redacted []string) []v1alpha1.NvConfigParameterStatus {
+	params := make([]v1alpha1.NvConfigParameterStatus, 0, len(desiredConfig))
+	for name, desiredValue := range desiredConfig {
+		param := v1alpha1.NvConfigParameterStatus{
+			Name:           name,
+			DesiredValue:   desiredValue,
+			CurrentValues:  nvConfig.CurrentConfig[name],
+			NextBootValues: nvConfig.NextBootConfig[name],
+		}
+		if defaultValues, found := nvConfig.DefaultConfig[name]; found {
+			param.DeviceDefault = NvParamValueMatches(name, desiredValue, defaultValues)
+		}
+		if slices.Contains(redacted, name) {
+			param.DesiredValue = consts.RedactedNvParamValue
+			param.DeviceDefault = false
+			param.CurrentValues = redactedValues(param.CurrentValues)
+			param.NextBootValues = redactedValues(param.NextBootValues)
+		}
+		params = append(params, param)
+	}
+
+	sort.Slice(params, func(i, j int) bool {
+		return params[i].Name < params[j].Name
+	})
+
+	return params
+}
+
+// secretSourcedNvParams returns the nv config parameters rendered from the template fields set from Secrets,
+// their values are not published in the device status
+func secretSourcedNvParams(template *v1alpha1.ConfigurationTemplateSpec) []string {
+	params := []string{}
+	if template == nil {
+		return params
+	}
+	for _, source := range template.ValuesFrom {
+		if source.SecretKeyRef == nil {
+			continue
+		}
+		switch {
+		case source.Field == "numVfs":
+			params = append(params, consts.SriovNumOfVfsParam)
+		case source.Field == "linkType":
+			params = append(params, consts.LinkTypeP1Param, consts.LinkTypeP2Param)
+		case strings.HasPrefix(source.Field, "rawNvConfig."):
+			params = append(params, strings.TrimPrefix(source.Field, "rawNvConfig."))
+		}
+	}
+	return params
+}
+
+// redactedValues replaces the reported values of a parameter with consts.RedactedNvParamValue
+func redactedValues(values []string) []string {
+	if len(values) == 0 {
+		return values
+	}
+	return []string{consts.RedactedNvParamValue}
+}
+
+// diffCurrentAndNextBootConfig lists nv config parameters whose current and next boot values differ
+// the values are compared as in NvParamValueMatches, so that the same value reported with another alias or format isn't listed
+// returns the list sorted by parameter name, nil if there are no differences
+func diffCurrentAndNextBootConfig(nvConfig types.NvConfigQuery) []v1alpha1.NvConfigParameterDiff {
+	var diff []v1alpha1.NvConfigParameterDiff
+
+	names := map[string]struct{}{}
+	for name := range nvConfig.CurrentConfig {
+		names[name] = struct{}{}
+	}
+	for name := range nvConfig.NextBootConfig {
+		names[name] = struct{}{}
+	}
+
+	for name := range names {
+		currentValues := nvConfig.CurrentConfig[name]
+		nextBootValues := nvConfig.NextBootConfig[name]
+		if len(currentValues) != 0 && len(nextBootValues) != 0 && slices.ContainsFunc(currentValues, func(value string) bool {
+			return NvParamValueMatches(name, value, nextBootValues)
+		}) {
+			continue
+		}
+		diff = append(diff, v1alpha1.NvConfigParameterDiff{
+			Name:           name,
+			CurrentValues:  currentValues,
+			NextBootValues: nextBootValues,
+		})
+	}
+
+	sort.Slice(diff, func(i, j int) bool {
+		return diff[i].Name < diff[j].Name
+	})
+
+	return diff
+}
+
 // ApplyDeviceNvSpec calculates device's missing nv spec configuration and applies it to the device on the host
+// if applying fails midway, restores the previous next boot values of the changed parameters
 // returns bool - reboot required
 // returns error - there were errors while applying nv configuration
 func (h hostManager) ApplyDeviceNvSpec(ctx context.Context, device *v1alpha1.NicDevice) (bool, error) {
 	log.Log.Info("hostManager.ApplyDeviceNvSpec", "device", device.Name)
 
-	pciAddr := device.Status.Ports[0].PCI
+	pciAddr := NvConfigPCIAddress(device)
 
 	if device.Spec.Configuration.ResetToDefault {
 		log.Log.Info("resetting nv config to default", "device", device.Name) // todo
@@ -207,21 +826,26 @@ func (h hostManager) ApplyDeviceNvSpec(ctx context.Context, device *v1alpha1.Nic
 			log.Log.Error(err, "Failed to reset nv config", "device", device.Name)
 			return false, err
 		}
+		writeStats(device).Resets++
 
 		err = h.hostUtils.SetNvConfigParameter(pciAddr, consts.AdvancedPCISettingsParam, consts.NvParamTrue)
 		if err != nil {
 			log.Log.Error(err, "Failed to apply nv config parameter", "device", device.Name, "param", consts.AdvancedPCISettingsParam, "value", consts.NvParamTrue)
 			return false, err
 		}
+		writeStats(device).Writes++
+
+		h.recordChangelog(ctx, device, true, []changelog.Change{{Parameter: consts.AdvancedPCISettingsParam, Value: consts.NvParamTrue}})
 
 		return true, err
 	}
 
-	nvConfig, err := h.hostUtils.QueryNvConfig(ctx, device.Status.Ports[0].PCI)
+	nvConfig, err := h.queryNvConfig(ctx, device)
 	if err != nil {
 		log.Log.Error(err, "failed to query nv config", "device", device.Name)
 		return false, err
 	}
+	pciAddr = device.Status.NvConfigPCI
 
 	// if ADVANCED_PCI_SETTINGS == 0, not all nv config parameters are available for configuration
 	// we enable this parameter first to unlock them
@@ -232,6 +856,18 @@ func (h hostManager) ApplyDeviceNvSpec(ctx context.Context, device *v1alpha1.Nic
 			log.Log.Error(err, "Failed to apply nv config parameter", "device", device.Name, "param", consts.AdvancedPCISettingsParam, "value", consts.NvParamTrue)
 			return false, err
 		}
+		writeStats(device).Writes++
+
+		h.recordChangelog(ctx, device, false, []changelog.Change{{
+			Parameter:      consts.AdvancedPCISettingsParam,
+			PreviousValues: nvConfig.NextBootConfig[consts.AdvancedPCISettingsParam],
+			Value:          consts.NvParamTrue,
+		}})
+
+		if device.Spec.Configuration.Disruption == consts.DisruptionReboot {
+			log.Log.V(2).Info("FW reset is disabled for device, reboot required to apply ADVANCED_PCI_SETTINGS", "device", device.Name)
+			return true, nil
+		}
 
 		err = h.hostUtils.ResetNicFirmware(ctx, pciAddr)
 		if err != nil {
@@ -243,63 +879,267 @@ func (h hostManager) ApplyDeviceNvSpec(ctx context.Context, device *v1alpha1.Nic
 		}
 
 		// Query nv config again, additional options could become available
-		nvConfig, err = h.hostUtils.QueryNvConfig(ctx, device.Status.Ports[0].PCI)
+		nvConfig, err = h.hostUtils.QueryNvConfig(ctx, pciAddr)
 		if err != nil {
 			log.Log.Error(err, "failed to query nv config", "device", device.Name)
 			return false, err
 		}
 	}
 
-	desiredConfig, err := h.configValidation.ConstructNvParamMapFromTemplate(device, nvConfig)
+	paramsToApply, unknownParams, paramsMissing, err := h.diffNextBootConfig(device, nvConfig)
+	if paramsMissing {
+		// The next boot config can be reported incomplete right after ADVANCED_PCI_SETTINGS is changed,
+		// query it once again before reporting the missing parameters, other spec errors are deterministic and not retried
+		log.Log.Info("nv config parameters unsupported, querying nv config again", "device", device.Name, "err", err.Error())
+		nvConfig, err = h.hostUtils.QueryNvConfig(ctx, pciAddr)
+		if err != nil {
+			log.Log.Error(err, "failed to query nv config", "device", device.Name)
+			return false, err
+		}
+		paramsToApply, unknownParams, _, err = h.diffNextBootConfig(device, nvConfig)
+	}
 	if err != nil {
-		log.Log.Error(err, "failed to calculate desired nvconfig parameters", "device", device.Name)
 		return false, err
 	}
 
-	paramsToApply := map[string]string{}
+	err = h.simulateNvConfig(device, pciAddr, paramsToApply, unknownParams)
+	if err != nil {
+		return false, err
+	}
 
-	for param, value := range desiredConfig {
-		nextValues, found := nvConfig.NextBootConfig[param]
-		if !found {
-			err = types.IncorrectSpecError(fmt.Sprintf("Parameter %s unsupported for device %s", param, device.Name))
-			log.Log.Error(err, "can't set nv config parameter for device")
-			return false, err
-		}
+	log.Log.V(2).Info("applying nv config to device", "device", device.Name, "config", paramsToApply)
 
-		if !slices.Contains(nextValues, value) {
-			paramsToApply[param] = value
-		}
+	// Reboots after which the written values don't take effect are counted by the boot ID of the host
+	bootID, err := h.hostUtils.GetHostBootID()
+	if err != nil {
+		log.Log.Error(err, "failed to get host boot ID", "device", device.Name)
 	}
 
-	log.Log.V(2).Info("applying nv config to device", "device", device.Name, "config", paramsToApply)
+	changes := []changelog.Change{}
+	previousConfig := nvConfig
+	for i, stage := range orderNvParams(paramsToApply) {
+		if i != 0 && slices.ContainsFunc(stage, func(param string) bool { return slices.Contains(unknownParams, param) }) {
+			// Query nv config again, parameters of this stage were unlocked by the previous stages
+			nvConfig, err = h.hostUtils.QueryNvConfig(ctx, pciAddr)
+			if err != nil {
+				log.Log.Error(err, "failed to query nv config", "device", device.Name)
+				return false, h.rollbackNvConfig(device, pciAddr, changes, err)
+			}
+		}
 
-	for param, value := range paramsToApply {
-		err = h.hostUtils.SetNvConfigParameter(pciAddr, param, value)
-		if err != nil {
-			log.Log.Error(err, "Failed to apply nv config parameter", "device", device.Name, "param", param, "value", value)
-			return false, err
+		for _, param := range stage {
+			value := paramsToApply[param]
+			nextValues, found := nvConfig.NextBootConfig[param]
+			if !found {
+				err = types.IncorrectSpecError(fmt.Sprintf("Parameter %s unsupported for device %s", param, device.Name))
+				log.Log.Error(err, "can't set nv config parameter for device")
+				return false, h.rollbackNvConfig(device, pciAddr, changes, err)
+			}
+			if slices.Contains(unknownParams, param) && NvParamValueMatches(param, value, nextValues) {
+				continue
+			}
+
+			err = h.hostUtils.SetNvConfigParameter(pciAddr, param, value)
+			if err != nil {
+				log.Log.Error(err, "Failed to apply nv config parameter", "device", device.Name, "param", param, "value", value)
+				return false, h.rollbackNvConfig(device, pciAddr, changes, err)
+			}
+			changes = append(changes, changelog.Change{Parameter: param, PreviousValues: previousConfig.NextBootConfig[param], Value: value})
 		}
 	}
 
 	log.Log.V(2).Info("nv config successfully applied to device", "device", device.Name)
 
+	// Writes are counted once all of them are applied, the writes of a rolled back apply are not counted
+	for _, change := range changes {
+		writeStats(device).Writes++
+		countUnconvergedWrite(device, change.Parameter, change.Value, bootID)
+	}
+
+	if len(changes) != 0 {
+		h.recordChangelog(ctx, device, false, changes)
+	}
+
 	return true, nil
 }
 
-// ApplyDeviceRuntimeSpec calculates device's missing runtime spec configuration and applies it to the device on the host
-// returns error - there were errors while applying nv configuration
-func (h hostManager) ApplyDeviceRuntimeSpec(device *v1alpha1.NicDevice) error {
-	log.Log.Info("hostManager.ApplyDeviceRuntimeSpec", "device", device.Name)
-
-	alreadyApplied, err := h.configValidation.RuntimeConfigApplied(device)
+// diffNextBootConfig renders the desired nv config of the device and compares it to the next boot config
+// returns the parameters to apply and the ones not yet available in the next boot config
+// returns types.IncorrectSpecError if a desired parameter is missing in the next boot config and is not unlocked by the other parameters
+// returns missing true if the error is caused by the parameters missing in the queried nv config, which can be incomplete
+func (h hostManager) diffNextBootConfig(device *v1alpha1.NicDevice, nvConfig types.NvConfigQuery) (map[string]string, []string, bool, error) {
+	desiredConfig, err := h.configValidation.ConstructNvParamMapFromTemplate(device, nvConfig)
 	if err != nil {
-		log.Log.Error(err, "failed to verify runtime configuration", "device", device)
+		log.Log.Error(err, "failed to calculate desired nvconfig parameters", "device", device.Name)
+		return nil, nil, types.IsFabricFeatureNotSupportedError(err), err
 	}
 
-	if alreadyApplied {
-		log.Log.V(2).Info("runtime config already applied", "device", device)
-		return nil
-	}
+	paramsToApply := map[string]string{}
+	unknownParams := []string{}
+
+	for param, value := range desiredConfig {
+		nextValues, found := nvConfig.NextBootConfig[param]
+		if !found {
+			unknownParams = append(unknownParams, param)
+			continue
+		}
+
+		if !NvParamValueMatches(param, value, nextValues) {
+			paramsToApply[param] = value
+		}
+	}
+
+	// Some parameters only become available after the parameters they depend on are set
+	for _, param := range unknownParams {
+		if !slices.ContainsFunc(nvParamPrerequisites(param, desiredConfig), func(prerequisite string) bool {
+			_, applied := paramsToApply[prerequisite]
+			return applied
+		}) {
+			err = types.IncorrectSpecError(fmt.Sprintf("Parameter %s unsupported for device %s", param, device.Name))
+			log.Log.Error(err, "can't set nv config parameter for device")
+			return nil, nil, true, err
+		}
+		paramsToApply[param] = desiredConfig[param]
+	}
+
+	return paramsToApply, unknownParams, false, nil
+}
+
+// simulateNvConfig lets the firmware pre-check the values and interdependencies of the parameters before any of them is written
+// parameters unlocked by the other parameters are not available yet and are not simulated
+// simulation is skipped if the tooling doesn't support it
+// returns types.IncorrectSpecError if the firmware rejects the values, the nv config is left untouched in this case
+func (h hostManager) simulateNvConfig(device *v1alpha1.NicDevice, pciAddr string, paramsToApply map[string]string, unknownParams []string) error {
+	params := map[string]string{}
+	for param, value := range paramsToApply {
+		if !slices.Contains(unknownParams, param) {
+			params[param] = value
+		}
+	}
+	if len(params) == 0 {
+		return nil
+	}
+
+	simulated, err := h.hostUtils.SimulateNvConfigParameters(pciAddr, params)
+	if err != nil {
+		log.Log.Error(err, "simulated nv config set failed", "device", device.Name)
+		return err
+	}
+	if simulated {
+		log.Log.V(2).Info("nv config values accepted by the firmware", "device", device.Name)
+	}
+
+	return nil
+}
+
+// rollbackNvConfig restores the next boot values of the parameters changed before applying the nv config failed
+// parameters without previous values, e.g. unlocked by the applied parameters, are left as is
+// neither the rolled back writes nor the rollback writes are counted in the nv config write stats
+// returns types.RolledBackError if the previous values were restored, the original error otherwise
+// incorrect spec errors are returned as is after the rollback, the spec needs to be fixed before the nv config is applied again
+func (h hostManager) rollbackNvConfig(device *v1alpha1.NicDevice, pciAddr string, changes []changelog.Change, cause error) error {
+	if len(changes) == 0 || types.IsToolHangError(cause) || types.IsConfigOwnershipDeniedError(cause) {
+		// Nothing to roll back, the device doesn't respond to the tools or its nv config can't be written anymore
+		return cause
+	}
+
+	log.Log.Info("rolling back nv config changes", "device", device.Name, "changes", len(changes))
+
+	for i := len(changes) - 1; i >= 0; i-- {
+		change := changes[i]
+		if len(change.PreviousValues) == 0 {
+			continue
+		}
+		// Values with a string alias are reported as [alias, numeric value]
+		value := change.PreviousValues[len(change.PreviousValues)-1]
+
+		err := h.hostUtils.SetNvConfigParameter(pciAddr, change.Parameter, value)
+		if err != nil {
+			log.Log.Error(err, "failed to roll back nv config parameter", "device", device.Name, "param", change.Parameter, "value", value)
+			return fmt.Errorf("%w, failed to roll back parameter %s: %v", cause, change.Parameter, err)
+		}
+	}
+
+	if types.IsIncorrectSpecError(cause) {
+		return fmt.Errorf("%w, restored previous values of %d parameters", cause, len(changes))
+	}
+	return types.RolledBackError(fmt.Sprintf("restored previous values of %d parameters after: %v", len(changes), cause))
+}
+
+// RollbackDeviceNvSpec restores the next boot values of the device's nv config parameters validated before the nv spec was applied
+// the values are taken from the nv config parameters published in the device status by ValidateDeviceNvSpec
+// the rollback is best effort, mlxconfig has no transactions, the parameters set from Secrets are not restored
+// and a failed restore leaves the device with a partially applied spec
+// returns error - the previous values couldn't be restored
+func (h hostManager) RollbackDeviceNvSpec(ctx context.Context, device *v1alpha1.NicDevice) error {
+	log.Log.Info("hostManager.RollbackDeviceNvSpec", "device", device.Name)
+
+	if device.Spec.Configuration.ResetToDefault {
+		return fmt.Errorf("nv config reset of device %s can't be rolled back", device.Name)
+	}
+
+	pciAddr := NvConfigPCIAddress(device)
+
+	changes := []changelog.Change{}
+	for _, param := range device.Status.NvConfigParameters {
+		if len(param.NextBootValues) == 0 || NvParamValueMatches(param.Name, param.DesiredValue, param.NextBootValues) {
+			// The parameter was unlocked by the applied parameters or wasn't changed
+			continue
+		}
+		if param.DesiredValue == consts.RedactedNvParamValue {
+			// The previous values of the parameters set from Secrets are not published
+			log.Log.Info("nv config parameter set from a Secret can't be rolled back", "device", device.Name, "param", param.Name)
+			continue
+		}
+		// Values with a string alias are reported as [alias, numeric value]
+		value := param.NextBootValues[len(param.NextBootValues)-1]
+
+		err := h.hostUtils.SetNvConfigParameter(pciAddr, param.Name, value)
+		if err != nil {
+			log.Log.Error(err, "failed to roll back nv config parameter", "device", device.Name, "param", param.Name, "value", value)
+			return fmt.Errorf("failed to roll back parameter %s: %w", param.Name, err)
+		}
+		changes = append(changes, changelog.Change{Parameter: param.Name, PreviousValues: []string{param.DesiredValue}, Value: value})
+	}
+
+	log.Log.V(2).Info("nv config changes rolled back", "device", device.Name, "changes", len(changes))
+
+	if len(changes) != 0 {
+		h.recordChangelog(ctx, device, false, changes)
+	}
+
+	return nil
+}
+
+// ApplyDeviceRuntimeSpec calculates device's missing runtime spec configuration and applies it to the device on the host
+// renamed network interfaces of the device's ports are updated in its status
+// returns error - there were errors while applying nv configuration
+func (h hostManager) ApplyDeviceRuntimeSpec(device *v1alpha1.NicDevice) error {
+	log.Log.Info("hostManager.ApplyDeviceRuntimeSpec", "device", device.Name)
+
+	// Runtime config is applied by the interface names, they might have changed since the last discovery
+	h.refreshInterfaceNames(device)
+
+	if restrictedDevice(device) {
+		return h.applyRestrictedRuntimeSpec(device)
+	}
+
+	alreadyApplied, err := h.configValidation.RuntimeConfigApplied(device)
+	if err != nil {
+		log.Log.Error(err, "failed to verify runtime configuration", "device", device)
+	}
+
+	if alreadyApplied {
+		log.Log.V(2).Info("runtime config already applied", "device", device)
+		device.Status.PartialRuntimeConfig = nil
+		// Representors of the new VFs might have appeared since the last run
+		return h.ApplyRepresentorsRuntimeSpec(device)
+	}
+	if err == nil && runtimeConfigConverged(device) {
+		// The runtime settings were applied before and changed on the host since then, e.g. by another agent
+		h.lifecycleEvents.Emit(context.Background(), lifecycle.DriftDetectedEvent, device, lifecycle.RuntimeConfigDriftReason,
+			"runtime settings of the device no longer match its spec, applying them again")
+	}
 
 	desiredMaxReadReqSize, desiredTrust, desiredPfc := h.configValidation.CalculateDesiredRuntimeConfig(device)
 
@@ -315,24 +1155,1127 @@ func (h hostManager) ApplyDeviceRuntimeSpec(device *v1alpha1.NicDevice) error {
 		}
 	}
 
-	for _, port := range ports {
-		err = h.hostUtils.SetTrustAndPFC(port.NetworkInterface, desiredTrust, desiredPfc)
+	err = h.applyVfMsix(device)
+	if err != nil {
+		log.Log.Error(err, "failed to apply VF MSI-X vectors", "device", device)
+		return err
+	}
+
+	// Devlink reload re-initializes the driver and drops the QoS settings, so it has to happen first
+	err = h.applyDevlinkResources(device)
+	if err != nil {
+		log.Log.Error(err, "failed to apply devlink resources", "device", device)
+		return err
+	}
+
+	// Flow steering mode can only be changed in the legacy eswitch mode, and the change of the eswitch mode re-creates
+	// the PF's network interface and drops its ethtool settings, so both are applied before the netdev settings
+	err = h.applyFlowSteeringMode(device)
+	if err != nil {
+		log.Log.Error(err, "failed to apply flow steering mode", "device", device)
+		return err
+	}
+
+	err = h.applyEswitchMode(device)
+	if err != nil {
+		log.Log.Error(err, "failed to apply eswitch mode", "device", device)
+		return err
+	}
+
+	err = h.applyRingSizes(device)
+	if err != nil {
+		log.Log.Error(err, "failed to apply ring sizes", "device", device)
+		return err
+	}
+
+	err = h.applyCoalescing(device)
+	if err != nil {
+		log.Log.Error(err, "failed to apply coalescing settings", "device", device)
+		return err
+	}
+
+	err = h.applyChannels(device)
+	if err != nil {
+		log.Log.Error(err, "failed to apply channels", "device", device)
+		return err
+	}
+
+	err = h.applyOffloads(device)
+	if err != nil {
+		log.Log.Error(err, "failed to apply offloads", "device", device)
+		return err
+	}
+
+	err = h.applyMtu(device)
+	if err != nil {
+		log.Log.Error(err, "failed to apply MTU", "device", device)
+		return err
+	}
+
+	err = h.applySysctls(device)
+	if err != nil {
+		log.Log.Error(err, "failed to apply sysctls", "device", device)
+		return err
+	}
+
+	resetCounters := desiredTrust != "" && portCountersResetRequested(device)
+	tcBandwidth := desiredTcBandwidth(device)
+	ecn, dcqcnParameters := desiredCongestionControl(device)
+	if ecn != "" {
+		err = h.checkCongestionControlSupported(device, ports)
+		if err != nil {
+			log.Log.Error(err, "failed to apply congestion control settings", "device", device.Name)
+			return err
+		}
+	}
+
+	resumed := h.resumedRuntimeConfigPorts(device)
+	applied := []string{}
+	conflicts := []string{}
+	for i, port := range ports {
+		if portLinkType(device.Spec.Configuration.Template, i) == consts.Infiniband {
+			// QoS settings are not available for IB ports
+			continue
+		}
+		// Conflicts are checked on the resumed ports too, so that they are kept in the QosConflict condition
+		if desiredTrust != "" {
+			portConflicts, err := h.resolveQosConflicts(device, port)
+			if err != nil {
+				h.recordPartialRuntimeConfig(device, applied, port.PCI, err)
+				return err
+			}
+			conflicts = append(conflicts, portConflicts...)
+		}
+
+		if slices.Contains(resumed, port.PCI) {
+			log.Log.V(2).Info("QoS settings were applied to the port by the interrupted apply, skipping it", "device", device.Name, "port", port.PCI)
+			applied = append(applied, port.PCI)
+			continue
+		}
+
+		err = h.applyPortQos(device, port, desiredTrust, desiredPfc, resetCounters, tcBandwidth, ecn, dcqcnParameters)
+		if err != nil {
+			h.recordPartialRuntimeConfig(device, applied, port.PCI, err)
+			return err
+		}
+		applied = append(applied, port.PCI)
+	}
+	device.Status.PartialRuntimeConfig = nil
+	if setQosConflictCondition(device, desiredTrust != "", conflicts) && len(conflicts) != 0 && h.eventRecorder != nil {
+		h.eventRecorder.Event(device, v1.EventTypeWarning, consts.ConflictingQosConfigReason, strings.Join(conflicts, "; "))
+	}
+
+	// Representors follow the uplink settings, so they are configured last
+	return h.ApplyRepresentorsRuntimeSpec(device)
+}
+
+// applyPortQos applies the trust, PFC, ETS and congestion control settings to the port's network interface
+// the port counters are reset if requested and the settings changed
+func (h hostManager) applyPortQos(device *v1alpha1.NicDevice, port v1alpha1.NicDevicePortSpec, desiredTrust string, desiredPfc string,
+	resetCounters bool, tcBandwidth string, ecn string, dcqcnParameters map[string]int) error {
+	qosChanged := true
+	if resetCounters {
+		trust, pfc, err := h.hostUtils.GetTrustAndPFC(port.NetworkInterface)
+		qosChanged = err != nil || trust != desiredTrust || pfc != desiredPfc
+		if !qosChanged && tcBandwidth != "" {
+			currentTcBandwidth, err := h.hostUtils.GetTcBandwidth(port.NetworkInterface)
+			qosChanged = err != nil || currentTcBandwidth != tcBandwidth
+		}
+	}
+
+	err := h.hostUtils.SetTrustAndPFC(port.NetworkInterface, desiredTrust, desiredPfc)
+	if err != nil {
+		log.Log.Error(err, "failed to apply runtime configuration", "device", device)
+		return err
+	}
+
+	if tcBandwidth != "" {
+		err = h.hostUtils.SetTcBandwidth(port.NetworkInterface, tcBandwidth)
+		if err != nil {
+			log.Log.Error(err, "failed to apply ETS settings", "device", device)
+			return err
+		}
+	}
+
+	if ecn != "" {
+		err = h.applyCongestionControl(port.NetworkInterface, ecn, dcqcnParameters)
 		if err != nil {
-			log.Log.Error(err, "failed to apply runtime configuration", "device", device)
+			log.Log.Error(err, "failed to apply congestion control settings", "device", device)
 			return err
 		}
 	}
 
+	if resetCounters && qosChanged {
+		h.resetPortCounters(device, port)
+	}
+
 	return nil
 }
 
-// DiscoverOfedVersion retrieves installed OFED version
-// returns string - installed OFED version
-// returns error - OFED isn't installed or version couldn't be determined
-func (h hostManager) DiscoverOfedVersion() string {
-	return h.hostUtils.GetOfedVersion()
+// conflictingQdiscs are the root qdiscs scheduling the traffic classes on their own, they override the ETS settings of mlnx_qos
+var conflictingQdiscs = []string{"mqprio", "taprio", "ets"}
+
+// qosOwnershipRequested returns true if the device's template requests to clear the host settings conflicting with its QoS settings
+func qosOwnershipRequested(device *v1alpha1.NicDevice) bool {
+	template := device.Spec.Configuration.Template
+	return template.RoceOptimized != nil && template.RoceOptimized.Qos != nil && template.RoceOptimized.Qos.TakeOwnership
+}
+
+// qosConflicts returns the DCB app entries and the root qdisc of the network interface that conflict with the QoS settings
+// DSCP entries are maintained by the driver for the dscp trust mode and don't conflict, the rest is installed by other agents, e.g. lldpad
+// the settings that can't be read are logged and skipped, the tools might be missing on the host
+func (h hostManager) qosConflicts(interfaceName string) ([]string, string) {
+	conflictingEntries := []string{}
+	entries, err := h.hostUtils.GetDcbAppEntries(interfaceName)
+	if err != nil {
+		log.Log.V(2).Info("failed to get DCB app entries, skipping the conflict check", "interface", interfaceName, "err", err.Error())
+	}
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry, dcbAppDscpCategory) {
+			conflictingEntries = append(conflictingEntries, entry)
+		}
+	}
+
+	qdisc, err := h.hostUtils.GetRootQdisc(interfaceName)
+	if err != nil {
+		log.Log.V(2).Info("failed to get root qdisc, skipping the conflict check", "interface", interfaceName, "err", err.Error())
+	}
+	if !slices.Contains(conflictingQdiscs, qdisc) {
+		qdisc = ""
+	}
+
+	return conflictingEntries, qdisc
+}
+
+// resolveQosConflicts finds the host settings of the port conflicting with its QoS settings before they are applied
+// the conflicts are cleared if the template takes the ownership of them, otherwise they are left to the QosConflict condition
+// returns the descriptions of the conflicts left on the port
+func (h hostManager) resolveQosConflicts(device *v1alpha1.NicDevice, port v1alpha1.NicDevicePortSpec) ([]string, error) {
+	entries, qdisc := h.qosConflicts(port.NetworkInterface)
+	conflicts := []string{}
+	for _, entry := range entries {
+		conflicts = append(conflicts, fmt.Sprintf("DCB app entry %s", entry))
+	}
+	if qdisc != "" {
+		conflicts = append(conflicts, fmt.Sprintf("root qdisc %s", qdisc))
+	}
+	if len(conflicts) == 0 {
+		return nil, nil
+	}
+
+	if !qosOwnershipRequested(device) {
+		message := fmt.Sprintf("Port %s (%s) has settings conflicting with the QoS settings: %s",
+			port.PCI, port.NetworkInterface, strings.Join(conflicts, ", "))
+		log.Log.Info(message, "device", device.Name)
+		return []string{message}, nil
+	}
+
+	for _, entry := range entries {
+		err := h.hostUtils.DeleteDcbAppEntry(port.NetworkInterface, entry)
+		if err != nil {
+			log.Log.Error(err, "failed to delete conflicting DCB app entry", "device", device.Name, "port", port.PCI, "entry", entry)
+			return nil, err
+		}
+	}
+	if qdisc != "" {
+		err := h.hostUtils.DeleteRootQdisc(port.NetworkInterface)
+		if err != nil {
+			log.Log.Error(err, "failed to delete conflicting root qdisc", "device", device.Name, "port", port.PCI, "qdisc", qdisc)
+			return nil, err
+		}
+	}
+
+	message := fmt.Sprintf("Settings of port %s (%s) conflicting with the QoS settings were cleared: %s",
+		port.PCI, port.NetworkInterface, strings.Join(conflicts, ", "))
+	log.Log.Info(message, "device", device.Name)
+	if h.eventRecorder != nil {
+		h.eventRecorder.Event(device, v1.EventTypeNormal, consts.QosConflictClearedReason, message)
+	}
+	return nil, nil
+}
+
+// setQosConflictCondition reports the QoS conflicts left on the device's ports in the QosConflict status condition
+// the condition is removed if the device has no QoS settings, returns true if the status, reason or message of the condition changed
+func setQosConflictCondition(device *v1alpha1.NicDevice, qosApplied bool, conflicts []string) bool {
+	if !qosApplied {
+		return meta.RemoveStatusCondition(&device.Status.Conditions, consts.QosConflictCondition)
+	}
+
+	condition := metav1.Condition{
+		Type:               consts.QosConflictCondition,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: device.Generation,
+		Reason:             consts.NoQosConflictReason,
+	}
+	if len(conflicts) != 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = consts.ConflictingQosConfigReason
+		condition.Message = strings.Join(conflicts, "; ")
+	}
+	previous := meta.FindStatusCondition(device.Status.Conditions, consts.QosConflictCondition)
+	changed := previous == nil || previous.Status != condition.Status || previous.Reason != condition.Reason || previous.Message != condition.Message
+	meta.SetStatusCondition(&device.Status.Conditions, condition)
+	return changed
+}
+
+// resumedRuntimeConfigPorts returns the ports whose QoS settings were applied by the interrupted apply of the same spec
+// generation in the current boot, returns nil if the settings have to be applied to all ports
+func (h hostManager) resumedRuntimeConfigPorts(device *v1alpha1.NicDevice) []string {
+	partial := device.Status.PartialRuntimeConfig
+	if partial == nil || partial.ObservedGeneration != device.Generation || partial.BootID == "" {
+		return nil
+	}
+
+	bootID, err := h.hostUtils.GetHostBootID()
+	if err != nil || bootID != partial.BootID {
+		return nil
+	}
+
+	log.Log.Info("resuming the interrupted apply of the QoS settings", "device", device.Name,
+		"appliedPorts", partial.AppliedPorts, "failedPort", partial.FailedPort)
+	return partial.AppliedPorts
+}
+
+// recordPartialRuntimeConfig records the ports with the applied QoS settings in the device status if the apply failed on another port
+func (h hostManager) recordPartialRuntimeConfig(device *v1alpha1.NicDevice, applied []string, failedPort string, cause error) {
+	if len(applied) == 0 {
+		device.Status.PartialRuntimeConfig = nil
+		return
+	}
+
+	// Apply is not resumed without the boot ID, the settings are applied to all ports again
+	bootID, err := h.hostUtils.GetHostBootID()
+	if err != nil {
+		log.Log.Error(err, "failed to get host boot ID", "device", device.Name)
+	}
+
+	log.Log.Info("QoS settings are applied partially", "device", device.Name, "appliedPorts", applied, "failedPort", failedPort)
+	device.Status.PartialRuntimeConfig = &v1alpha1.PartialRuntimeConfigStatus{
+		ObservedGeneration: device.Generation,
+		BootID:             bootID,
+		AppliedPorts:       applied,
+		FailedPort:         failedPort,
+		Error:              cause.Error(),
+	}
+}
+
+// checkCongestionControlSupported returns IncorrectSpecError if the driver of a port's network interface doesn't expose the ECN
+// and DCQCN settings, the ports are probed before any setting is applied
+func (h hostManager) checkCongestionControlSupported(device *v1alpha1.NicDevice, ports []v1alpha1.NicDevicePortSpec) error {
+	for i, port := range ports {
+		if portLinkType(device.Spec.Configuration.Template, i) == consts.Infiniband {
+			continue
+		}
+		if !h.hostUtils.IsCongestionControlSupported(port.NetworkInterface) {
+			return types.IncorrectSpecError(fmt.Sprintf(
+				"congestion control is not supported by the driver of network interface %s, ECN and DCQCN settings require the MOFED driver",
+				port.NetworkInterface))
+		}
+	}
+	return nil
+}
+
+// applyCongestionControl enables ECN for the priorities of the network interface and sets its DCQCN parameters
+func (h hostManager) applyCongestionControl(interfaceName string, ecn string, dcqcnParameters map[string]int) error {
+	err := h.hostUtils.SetEcn(interfaceName, ecn)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(dcqcnParameters))
+	for name := range dcqcnParameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		err = h.hostUtils.SetDcqcnParameter(interfaceName, name, dcqcnParameters[name])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func NewHostManager(nodeName string, hostUtils HostUtils, eventRecorder record.EventRecorder) HostManager {
-	return hostManager{nodeName: nodeName, hostUtils: hostUtils, configValidation: newConfigValidation(hostUtils, eventRecorder)}
+// ApplyRepresentorsRuntimeSpec applies the representors settings of the device's template to the VF representors
+// of its PFs in switchdev mode, only the differing settings are changed
+// returns error - there were errors while configuring the representors
+func (h hostManager) ApplyRepresentorsRuntimeSpec(device *v1alpha1.NicDevice) error {
+	// Representors of the VFs are created on the hypervisor host
+	if device.Spec.Configuration == nil || device.Spec.Configuration.Template == nil || restrictedDevice(device) {
+		return nil
+	}
+	template := device.Spec.Configuration.Template
+	spec := template.Representors
+	if spec == nil || !spec.Enabled {
+		return nil
+	}
+
+	for i, port := range device.Status.Ports {
+		// Representors are only created for Ethernet ports
+		if portLinkType(template, i) == consts.Infiniband || port.NetworkInterface == "" {
+			continue
+		}
+
+		mode, err := h.hostUtils.GetEswitchMode(port.PCI)
+		if err != nil {
+			return err
+		}
+		if mode != consts.EswitchModeSwitchdev {
+			log.Log.V(2).Info("PF is not in switchdev mode, skipping representors", "device", device.Name, "port", port.PCI, "mode", mode)
+			continue
+		}
+
+		representors, err := h.hostUtils.GetVfRepresentors(port.PCI)
+		if err != nil {
+			return err
+		}
+		if len(representors) == 0 {
+			continue
+		}
+
+		mtu := spec.Mtu
+		if mtu == 0 {
+			mtu, err = h.hostUtils.GetMtu(port.NetworkInterface)
+			if err != nil {
+				return err
+			}
+		}
+
+		trust, pfc := "", ""
+		if spec.Qos {
+			trust, pfc, err = h.hostUtils.GetTrustAndPFC(port.NetworkInterface)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, representor := range representors {
+			err = h.configureRepresentor(representor, mtu, trust, pfc, spec.Features)
+			if err != nil {
+				return fmt.Errorf("failed to configure VF representor %s of port %s: %w", representor, port.PCI, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// configureRepresentor sets the MTU, the QoS settings and the ethtool features of the VF representor if they differ
+// QoS settings are skipped if trust is empty
+func (h hostManager) configureRepresentor(representor string, mtu int, trust string, pfc string, features []string) error {
+	currentMtu, err := h.hostUtils.GetMtu(representor)
+	if err != nil {
+		return err
+	}
+	if currentMtu != mtu {
+		err = h.hostUtils.SetMtu(representor, mtu)
+		if err != nil {
+			return err
+		}
+	}
+
+	if trust != "" {
+		currentTrust, currentPfc, err := h.hostUtils.GetTrustAndPFC(representor)
+		if err != nil || currentTrust != trust || currentPfc != pfc {
+			err = h.hostUtils.SetTrustAndPFC(representor, trust, pfc)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(features) == 0 {
+		return nil
+	}
+	currentFeatures, err := h.hostUtils.GetEthtoolFeatures(representor)
+	if err != nil {
+		return err
+	}
+	for _, feature := range features {
+		if currentFeatures[feature].Enabled {
+			continue
+		}
+		err = h.hostUtils.SetEthtoolFeature(representor, feature, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// refreshInterfaceNames re-resolves the network interface names of the device's ports from their PCI addresses
+// renamed interfaces are updated in the device's status and reported in its events
+// ports without a network interface keep their last known name
+func (h hostManager) refreshInterfaceNames(device *v1alpha1.NicDevice) {
+	for i, port := range device.Status.Ports {
+		networkInterface := h.hostUtils.GetInterfaceName(port.PCI)
+		if networkInterface == "" || networkInterface == port.NetworkInterface {
+			continue
+		}
+
+		message := fmt.Sprintf("Network interface of port %s was renamed from %s to %s", port.PCI, port.NetworkInterface, networkInterface)
+		log.Log.Info(message, "device", device.Name)
+		if h.eventRecorder != nil {
+			h.eventRecorder.Event(device, v1.EventTypeNormal, consts.NetworkInterfaceRenamedReason, message)
+		}
+		device.Status.Ports[i].NetworkInterface = networkInterface
+	}
+}
+
+// portCountersResetRequested returns true if the device's template requests to clear the port counters after the QoS change
+func portCountersResetRequested(device *v1alpha1.NicDevice) bool {
+	template := device.Spec.Configuration.Template
+	return template.RoceOptimized != nil && template.RoceOptimized.Qos != nil && template.RoceOptimized.Qos.ResetCounters
+}
+
+// resetPortCounters archives the QoS related counters of the port in an event and clears the port counters
+// counters are not cleared if they couldn't be archived, failures are logged and don't affect the configuration flow
+func (h hostManager) resetPortCounters(device *v1alpha1.NicDevice, port v1alpha1.NicDevicePortSpec) {
+	counters, err := h.hostUtils.GetPortCounters(port.NetworkInterface)
+	if err != nil {
+		log.Log.Error(err, "failed to archive port counters, skipping the reset", "device", device.Name, "port", port.PCI)
+		return
+	}
+
+	err = h.hostUtils.ResetPortCounters(port.PCI)
+	if err != nil {
+		log.Log.Error(err, "failed to reset port counters", "device", device.Name, "port", port.PCI)
+		return
+	}
+
+	message := fmt.Sprintf("Counters of port %s (%s) were reset after the QoS change, previous values: %s",
+		port.PCI, port.NetworkInterface, formatPortCounters(counters))
+	log.Log.Info(message, "device", device.Name)
+	if h.eventRecorder != nil {
+		h.eventRecorder.Event(device, v1.EventTypeNormal, consts.PortCountersResetReason, message)
+	}
+}
+
+// formatPortCounters returns the non-zero counters sorted by name, e.g. "rx_prio3_pause=12, tx_prio3_pause=7"
+func formatPortCounters(counters map[string]uint64) string {
+	names := make([]string, 0, len(counters))
+	for name, value := range counters {
+		if value != 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	slices.Sort(names)
+
+	values := make([]string, 0, len(names))
+	for _, name := range names {
+		values = append(values, fmt.Sprintf("%s=%d", name, counters[name]))
+	}
+	return strings.Join(values, ", ")
+}
+
+// applyDevlinkResources sets the desired devlink resource sizes for each PF of the device
+// PFs with pending size changes are reloaded, sizes are read back afterwards to make sure the reload applied them
+func (h hostManager) applyDevlinkResources(device *v1alpha1.NicDevice) error {
+	desiredResources := device.Spec.Configuration.Template.DevlinkResources
+	if len(desiredResources) == 0 {
+		return nil
+	}
+
+	for _, port := range device.Status.Ports {
+		resources, err := h.hostUtils.GetDevlinkResources(port.PCI)
+		if err != nil {
+			return err
+		}
+
+		// Validate all resources first to not leave the PF partially configured
+		for _, desired := range desiredResources {
+			resource, found := resources[desired.Path]
+			if !found {
+				return types.IncorrectSpecError(fmt.Sprintf("devlink resource %s is not available for device %s", desired.Path, port.PCI))
+			}
+			err = validateDevlinkResourceSize(port.PCI, desired.Path, resource, desired.Size)
+			if err != nil {
+				return err
+			}
+		}
+
+		reloadRequired := false
+		for _, desired := range desiredResources {
+			resource := resources[desired.Path]
+			if devlinkResourceApplied(resource, desired.Size) {
+				continue
+			}
+
+			if resource.SizeNew == nil || *resource.SizeNew != desired.Size {
+				err = h.hostUtils.SetDevlinkResourceSize(port.PCI, desired.Path, desired.Size)
+				if err != nil {
+					return err
+				}
+			}
+			reloadRequired = true
+		}
+
+		if !reloadRequired {
+			continue
+		}
+
+		err = h.hostUtils.ReloadDevlinkDevice(port.PCI)
+		if err != nil {
+			return err
+		}
+
+		resources, err = h.hostUtils.GetDevlinkResources(port.PCI)
+		if err != nil {
+			return err
+		}
+		for _, desired := range desiredResources {
+			resource := resources[desired.Path]
+			if !devlinkResourceApplied(resource, desired.Size) {
+				return fmt.Errorf("devlink resource %s of device %s has size %d after reload, expected %d", desired.Path, port.PCI, resource.Size, desired.Size)
+			}
+		}
+	}
+
+	return nil
+}
+
+// applyRingSizes sets the ring buffer sizes of the ports' network interfaces, clamped to the maximums reported by the device
+func (h hostManager) applyRingSizes(device *v1alpha1.NicDevice) error {
+	ringSize := device.Spec.Configuration.Template.RingSize
+	if ringSize == nil {
+		return nil
+	}
+
+	for i, port := range device.Status.Ports {
+		if !netdevSettingsApply(device, i, port, "ring sizes") {
+			continue
+		}
+
+		current, err := h.hostUtils.GetRingSizes(port.NetworkInterface)
+		if err != nil {
+			return err
+		}
+		rx, tx := desiredRingSizes(ringSize, current)
+		if rx == current.Rx && tx == current.Tx {
+			continue
+		}
+		if ringSize.Rx > rx || ringSize.Tx > tx {
+			log.Log.Info("requested ring sizes exceed the maximums of the device, clamping them", "device", device.Name,
+				"interface", port.NetworkInterface, "rxMax", current.RxMax, "txMax", current.TxMax)
+		}
+
+		err = h.hostUtils.SetRingSizes(port.NetworkInterface, rx, tx)
+		if err != nil {
+			return fmt.Errorf("failed to set ring sizes of interface %s of port %s: %w", port.NetworkInterface, port.PCI, err)
+		}
+	}
+
+	return nil
+}
+
+// applyCoalescing sets the interrupt coalescing settings of the ports' network interfaces
+func (h hostManager) applyCoalescing(device *v1alpha1.NicDevice) error {
+	coalescing := device.Spec.Configuration.Template.Coalescing
+	if coalescing == nil {
+		return nil
+	}
+
+	for i, port := range device.Status.Ports {
+		if !netdevSettingsApply(device, i, port, "coalescing settings") {
+			continue
+		}
+
+		current, err := h.hostUtils.GetCoalescing(port.NetworkInterface)
+		if err != nil {
+			return err
+		}
+		desired := desiredCoalescing(coalescing, current)
+		if desired == current {
+			continue
+		}
+
+		err = h.hostUtils.SetCoalescing(port.NetworkInterface, desired)
+		if err != nil {
+			return fmt.Errorf("failed to set coalescing settings of interface %s of port %s: %w", port.NetworkInterface, port.PCI, err)
+		}
+	}
+
+	return nil
+}
+
+// applyOffloads toggles the stateless offloads of the ports' network interfaces, offloads already in the requested state are kept
+// returns types.IncorrectSpecError if the interface doesn't expose the offload or the driver fixed it in the other state
+func (h hostManager) applyOffloads(device *v1alpha1.NicDevice) error {
+	offloads := desiredOffloads(device.Spec.Configuration.Template.Offloads)
+	if len(offloads) == 0 {
+		return nil
+	}
+
+	for i, port := range device.Status.Ports {
+		if !netdevSettingsApply(device, i, port, "offloads") {
+			continue
+		}
+
+		current, err := h.hostUtils.GetEthtoolFeatures(port.NetworkInterface)
+		if err != nil {
+			return err
+		}
+		// The offloads are checked before any of them is changed, so that the interface isn't left half-configured
+		for _, offload := range offloads {
+			feature, found := current[offload.feature]
+			if !found {
+				return types.IncorrectSpecError(fmt.Sprintf("interface %s of port %s does not support the %s offload",
+					port.NetworkInterface, port.PCI, offload.feature))
+			}
+			if feature.Fixed && feature.Enabled != offload.enabled {
+				return types.IncorrectSpecError(fmt.Sprintf("%s offload of interface %s of port %s is fixed by the driver and can't be changed",
+					offload.feature, port.NetworkInterface, port.PCI))
+			}
+		}
+		for _, offload := range offloads {
+			if current[offload.feature].Enabled == offload.enabled {
+				continue
+			}
+
+			err = h.hostUtils.SetEthtoolFeature(port.NetworkInterface, offload.feature, offload.enabled)
+			if err != nil {
+				return fmt.Errorf("failed to set offload %s of interface %s of port %s: %w", offload.feature, port.NetworkInterface, port.PCI, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// applyChannels sets the combined channel count of the ports' network interfaces
+func (h hostManager) applyChannels(device *v1alpha1.NicDevice) error {
+	for i, port := range device.Status.Ports {
+		channels := portChannels(device.Spec.Configuration.Template, i)
+		if channels == nil || !netdevSettingsApply(device, i, port, "channels") {
+			continue
+		}
+
+		current, err := h.hostUtils.GetChannels(port.NetworkInterface)
+		if err != nil {
+			return err
+		}
+		combined, err := desiredCombinedChannels(h.hostUtils, channels, port, current)
+		if err != nil {
+			return err
+		}
+		if combined == current.Combined {
+			continue
+		}
+
+		err = h.hostUtils.SetCombinedChannels(port.NetworkInterface, combined)
+		if err != nil {
+			return fmt.Errorf("failed to set channels of interface %s of port %s: %w", port.NetworkInterface, port.PCI, err)
+		}
+	}
+
+	return nil
+}
+
+// applyMtu sets the MTU of the ports' network interfaces and, if requested, of their VFs' network interfaces that follow the PF's MTU
+// the VFs can't have larger MTU than the PF, so the PF's MTU is changed first when it grows and last when it shrinks
+func (h hostManager) applyMtu(device *v1alpha1.NicDevice) error {
+	for i, port := range device.Status.Ports {
+		mtu := portMtu(device.Spec.Configuration.Template, i)
+		if mtu == nil || !netdevSettingsApply(device, i, port, "MTU") {
+			continue
+		}
+
+		interfaces, err := mtuInterfaces(h.hostUtils, device, port, mtu)
+		if err != nil {
+			return err
+		}
+		current, err := h.hostUtils.GetMtu(port.NetworkInterface)
+		if err != nil {
+			return err
+		}
+		if mtu.Size < current {
+			slices.Reverse(interfaces)
+		}
+
+		for _, interfaceName := range interfaces {
+			current, err := h.hostUtils.GetMtu(interfaceName)
+			if err != nil {
+				return err
+			}
+			if current == mtu.Size {
+				continue
+			}
+			err = h.hostUtils.SetMtu(interfaceName, mtu.Size)
+			if err != nil {
+				return fmt.Errorf("failed to set MTU of interface %s of port %s: %w", interfaceName, port.PCI, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// sysctlClaim is the value of a sysctl applied for a device of the node
+type sysctlClaim struct {
+	device string
+	value  string
+	// original is the value of the sysctl before the first of the node's devices set it
+	original string
+}
+
+var (
+	// sysctlClaimsLock guards sysctlClaims
+	sysctlClaimsLock sync.Mutex
+	// sysctlClaims are the sysctls applied for the node's devices by the sysctl name, the sysctls of the network namespace
+	// are shared by all devices, so a sysctl can't be applied with different values for two devices
+	sysctlClaims = map[string][]sysctlClaim{}
+)
+
+// releaseSysctlClaim removes the device's claim of the sysctl, returns true if no other device claims the sysctl
+// sysctlClaimsLock has to be held
+func releaseSysctlClaim(name string, device string) bool {
+	sysctlClaims[name] = slices.DeleteFunc(sysctlClaims[name], func(claim sysctlClaim) bool {
+		return claim.device == device
+	})
+	if len(sysctlClaims[name]) == 0 {
+		delete(sysctlClaims, name)
+		return true
+	}
+	return false
+}
+
+// applySysctls sets the sysctls of the template differing from the current values and restores the original values
+// of the sysctls the template no longer sets, once no other device of the node requests them
+// the original values are recorded in the device's appliedSysctls status so that they survive the daemon restarts
+// returns types.IncorrectSpecError if another device of the node requests a sysctl with another value, or if the sysctl
+// doesn't exist in the host network namespace, e.g. of an interface moved to the network namespace of a pod
+func (h hostManager) applySysctls(device *v1alpha1.NicDevice) error {
+	sysctls, err := desiredSysctls(device)
+	if err != nil {
+		return err
+	}
+
+	sysctlClaimsLock.Lock()
+	defer sysctlClaimsLock.Unlock()
+
+	// Nothing is written if one of the sysctls conflicts with another device
+	desired := map[string]string{}
+	for _, sysctl := range sysctls {
+		value := normalizeSysctlValue(sysctl.Value)
+		for _, claim := range sysctlClaims[sysctl.Name] {
+			if claim.device != device.Name && claim.value != value {
+				return types.IncorrectSpecError(fmt.Sprintf("sysctl %s is set to %q for device %s, it can't be set to %q for this device",
+					sysctl.Name, claim.value, claim.device, value))
+			}
+		}
+		desired[sysctl.Name] = value
+	}
+
+	applied := map[string]v1alpha1.AppliedSysctlStatus{}
+	for _, sysctl := range device.Status.AppliedSysctls {
+		applied[sysctl.Name] = sysctl
+	}
+	// The original values of the sysctls written so far are kept even if one of the writes fails
+	defer func() {
+		device.Status.AppliedSysctls = nil
+		for _, name := range sortedSysctlNames(applied) {
+			device.Status.AppliedSysctls = append(device.Status.AppliedSysctls, applied[name])
+		}
+	}()
+
+	for _, sysctl := range sysctls {
+		value := desired[sysctl.Name]
+		current, err := h.hostUtils.GetSysctl(sysctl.Name)
+		if errors.Is(err, os.ErrNotExist) {
+			return types.IncorrectSpecError(fmt.Sprintf(
+				"sysctl %s doesn't exist in the host network namespace, sysctls of the other network namespaces are not supported", sysctl.Name))
+		}
+		if err != nil {
+			return err
+		}
+
+		entry, found := applied[sysctl.Name]
+		if !found {
+			entry = v1alpha1.AppliedSysctlStatus{Name: sysctl.Name, OriginalValue: current}
+			if claims := sysctlClaims[sysctl.Name]; len(claims) != 0 {
+				// Another device of the node already replaced the original value
+				entry.OriginalValue = claims[0].original
+			}
+		}
+		entry.Value = value
+		releaseSysctlClaim(sysctl.Name, device.Name)
+		sysctlClaims[sysctl.Name] = append(sysctlClaims[sysctl.Name], sysctlClaim{device: device.Name, value: value, original: entry.OriginalValue})
+		applied[sysctl.Name] = entry
+		if current == value {
+			continue
+		}
+
+		err = h.hostUtils.SetSysctl(sysctl.Name, sysctl.Value)
+		if err != nil {
+			return fmt.Errorf("failed to set sysctl %s: %w", sysctl.Name, err)
+		}
+	}
+
+	for _, name := range sortedSysctlNames(applied) {
+		if _, found := desired[name]; found {
+			continue
+		}
+		entry := applied[name]
+		if !releaseSysctlClaim(name, device.Name) {
+			log.Log.V(2).Info("sysctl is still set for another device, keeping its value", "device", device.Name, "sysctl", name)
+			delete(applied, name)
+			continue
+		}
+
+		log.Log.Info("restoring the original value of sysctl", "device", device.Name, "sysctl", name, "value", entry.OriginalValue)
+		err := h.hostUtils.SetSysctl(name, entry.OriginalValue)
+		if errors.Is(err, os.ErrNotExist) {
+			log.Log.Info("sysctl no longer exists, e.g. its network interface was removed, skipping it", "device", device.Name, "sysctl", name)
+		} else if err != nil {
+			sysctlClaims[name] = append(sysctlClaims[name], sysctlClaim{device: device.Name, value: entry.Value, original: entry.OriginalValue})
+			return fmt.Errorf("failed to restore sysctl %s: %w", name, err)
+		}
+		delete(applied, name)
+	}
+
+	return nil
+}
+
+// sortedSysctlNames returns the names of the applied sysctls in order
+func sortedSysctlNames(applied map[string]v1alpha1.AppliedSysctlStatus) []string {
+	names := make([]string, 0, len(applied))
+	for name := range applied {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// runtimeConfigConverged returns true if the whole runtime config of the device's current spec was applied successfully
+func runtimeConfigConverged(device *v1alpha1.NicDevice) bool {
+	condition := meta.FindStatusCondition(device.Status.Conditions, consts.ConfigUpdateInProgressCondition)
+	// Deferred runtime settings are reported in the condition's message until they are applied
+	return condition != nil && condition.ObservedGeneration == device.Generation &&
+		condition.Reason == consts.UpdateSuccessfulReason && condition.Message == ""
+}
+
+// applyFlowSteeringMode sets the flow steering mode of the template on the device's PFs
+// the driver only allows changing the mode in the legacy eswitch mode, so it has to be applied before the PF is moved to switchdev
+func (h hostManager) applyFlowSteeringMode(device *v1alpha1.NicDevice) error {
+	flowSteeringMode := string(device.Spec.Configuration.Template.FlowSteeringMode)
+	if flowSteeringMode == "" {
+		return nil
+	}
+
+	for _, port := range device.Status.Ports {
+		current, err := h.hostUtils.GetDevlinkParam(port.PCI, consts.DevlinkParamFlowSteeringMode)
+		if err != nil {
+			return err
+		}
+		if current == flowSteeringMode {
+			continue
+		}
+
+		eswitchMode, err := h.hostUtils.GetEswitchMode(port.PCI)
+		if err != nil {
+			return err
+		}
+		if eswitchMode == consts.EswitchModeSwitchdev {
+			return fmt.Errorf("cannot change flow steering mode of port %s from %s to %s in the switchdev eswitch mode, "+
+				"the PF has to be moved to legacy mode first", port.PCI, current, flowSteeringMode)
+		}
+
+		err = h.hostUtils.SetDevlinkParam(port.PCI, consts.DevlinkParamFlowSteeringMode, flowSteeringMode)
+		if err != nil {
+			return fmt.Errorf("failed to set flow steering mode of port %s: %w", port.PCI, err)
+		}
+	}
+
+	return nil
+}
+
+// applyEswitchMode moves the device's PFs to the eswitch mode of the template and reports the active mode in the status
+// PFs that aren't the eswitch manager, e.g. on multi-host NICs, are skipped
+// returns RuntimeConfigPendingError while the driver creates the representors of the VFs of the PFs in the switchdev mode
+func (h hostManager) applyEswitchMode(device *v1alpha1.NicDevice) error {
+	eswitchMode := string(device.Spec.Configuration.Template.EswitchMode)
+	if eswitchMode == "" {
+		return nil
+	}
+
+	changed := false
+	for i, port := range device.Status.Ports {
+		current, err := h.hostUtils.GetEswitchMode(port.PCI)
+		if err != nil {
+			return err
+		}
+		if current == "" {
+			log.Log.V(2).Info("PF is not the eswitch manager, skipping eswitch mode", "device", device.Name, "port", port.PCI)
+			continue
+		}
+
+		if current != eswitchMode {
+			log.Log.Info("changing eswitch mode of port", "device", device.Name, "port", port.PCI, "from", current, "to", eswitchMode)
+			err = h.hostUtils.SetEswitchMode(port.PCI, eswitchMode)
+			if err != nil {
+				return fmt.Errorf("failed to set eswitch mode of port %s: %w", port.PCI, err)
+			}
+			changed = true
+		}
+		device.Status.Ports[i].EswitchMode = eswitchMode
+	}
+
+	if changed {
+		// The driver re-creates the PF's network interface in the new mode, its name might change
+		h.refreshInterfaceNames(device)
+	}
+
+	if eswitchMode != consts.EswitchModeSwitchdev {
+		return nil
+	}
+	for _, port := range device.Status.Ports {
+		if port.EswitchMode != consts.EswitchModeSwitchdev {
+			continue
+		}
+		err := h.vfRepresentorsCreated(port.PCI)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// vfRepresentorsCreated returns RuntimeConfigPendingError until the driver creates the representors of all VFs
+// of the PF in the switchdev mode, the settings following the eswitch mode are applied once they are created
+func (h hostManager) vfRepresentorsCreated(pciAddr string) error {
+	vfs, err := h.hostUtils.GetVfPciAddresses(pciAddr)
+	if err != nil {
+		return err
+	}
+
+	representors, err := h.hostUtils.GetVfRepresentors(pciAddr)
+	if err != nil {
+		return err
+	}
+	if len(representors) < len(vfs) {
+		return types.RuntimeConfigPendingError(fmt.Sprintf("waiting for the representors of the VFs of port %s in the switchdev mode, %d of %d created",
+			pciAddr, len(representors), len(vfs)))
+	}
+
+	log.Log.V(2).Info("representors of the VFs are created", "port", pciAddr, "representors", representors)
+	return nil
+}
+
+// applyVfMsix assigns the runtime number of MSI-X vectors of the template to the VFs of each PF
+// VFs are created outside of the operator, e.g. by the SR-IOV network operator, PFs without VFs are skipped
+// the change rebinds the VF driver, so the VFs in use are skipped until they are released, see vfInUse
+func (h hostManager) applyVfMsix(device *v1alpha1.NicDevice) error {
+	vfMsix := device.Spec.Configuration.Template.VfMsix
+	if vfMsix == nil || vfMsix.RuntimeVfMsix == 0 {
+		return nil
+	}
+
+	for _, port := range device.Status.Ports {
+		vfs, err := h.hostUtils.GetVfPciAddresses(port.PCI)
+		if err != nil {
+			return err
+		}
+		if len(vfs) == 0 {
+			continue
+		}
+
+		totalMsix, err := h.hostUtils.GetVfTotalMsix(port.PCI)
+		if err != nil {
+			return err
+		}
+		if vfMsix.RuntimeVfMsix*len(vfs) > totalMsix {
+			return types.IncorrectSpecError(fmt.Sprintf(
+				"runtimeVfMsix %d for %d VFs of device %s exceeds the pool of %d MSI-X vectors", vfMsix.RuntimeVfMsix, len(vfs), port.PCI, totalMsix))
+		}
+
+		for _, vf := range vfs {
+			count, err := h.hostUtils.GetVfMsixCount(vf)
+			if err != nil {
+				return err
+			}
+			if count == vfMsix.RuntimeVfMsix {
+				continue
+			}
+			inUse, err := vfInUse(h.hostUtils, vf)
+			if err != nil {
+				return err
+			}
+			if inUse {
+				log.Log.Info("VF is in use, MSI-X vectors are assigned once it is released", "device", device.Name, "vf", vf)
+				continue
+			}
+			err = h.hostUtils.SetVfMsixCount(vf, vfMsix.RuntimeVfMsix)
+			if err != nil {
+				return fmt.Errorf("failed to set MSI-X vectors of VF %s of port %s: %w", vf, port.PCI, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// vfInUse returns true if the VF has a consumer: it is bound to a driver other than mlx5_core, e.g. vfio-pci for a VM,
+// or its network interface was moved out of the host's network namespace, e.g. into a pod
+func vfInUse(utils HostUtils, vfPciAddr string) (bool, error) {
+	driver, err := utils.GetPCIDriver(vfPciAddr)
+	if err != nil {
+		return false, err
+	}
+	switch driver {
+	case "":
+		return false, nil
+	case consts.Mlx5CoreDriver:
+		return utils.GetInterfaceName(vfPciAddr) == "", nil
+	default:
+		return true, nil
+	}
+}
+
+// DiscoverOfedVersion retrieves installed OFED version
+// returns string - installed OFED version
+// returns error - OFED isn't installed or version couldn't be determined
+func (h hostManager) DiscoverOfedVersion() string {
+	return h.hostUtils.GetOfedVersion()
+}
+
+// recordChangelog stores a changelog entry of the nv config changes applied to the device
+// failures to record the entry are logged and don't affect the configuration flow
+func (h hostManager) recordChangelog(ctx context.Context, device *v1alpha1.NicDevice, resetToDefault bool, changes []changelog.Change) {
+	if h.changelog == nil {
+		return
+	}
+
+	entry := changelog.Record{
+		Time:            time.Now(),
+		Node:            h.nodeName,
+		Device:          device.Name,
+		SerialNumber:    device.Status.SerialNumber,
+		PartNumber:      device.Status.PartNumber,
+		FirmwareVersion: device.Status.FirmwareVersion,
+		ResetToDefault:  resetToDefault,
+		Changes:         changes,
+	}
+
+	err := h.changelog.Write(ctx, entry)
+	if err != nil {
+		log.Log.Error(err, "failed to record nv config changelog entry", "device", device.Name)
+	}
+}
+
+// NewHostManager creates a HostManager, changelogSink records the applied nv config changes and can be nil,
+// lifecycleEvents publishes the runtime config drifts and can be nil
+func NewHostManager(nodeName string, hostUtils HostUtils, eventRecorder record.EventRecorder, changelogSink changelog.Sink,
+	lifecycleEvents *lifecycle.Emitter) HostManager {
+	return hostManager{
+		nodeName:         nodeName,
+		hostUtils:        hostUtils,
+		configValidation: newConfigValidation(hostUtils, eventRecorder),
+		changelog:        changelogSink,
+		lifecycleEvents:  lifecycleEvents,
+		eventRecorder:    eventRecorder,
+	}
 }
@@ -17,19 +17,48 @@ package host
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"sync"
+	"time"
 
 	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/changelog"
 	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
 	"github.com/Mellanox/nic-configuration-operator/pkg/host/mocks"
+	"github.com/Mellanox/nic-configuration-operator/pkg/lifecycle"
 	"github.com/Mellanox/nic-configuration-operator/pkg/types"
 	"github.com/jaypipes/ghw/pkg/pci"
 	"github.com/jaypipes/pcidb"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/mock"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
 )
 
+type lifecycleEventsSink struct {
+	lock   sync.Mutex
+	events []lifecycle.Event
+}
+
+func (s *lifecycleEventsSink) Send(_ context.Context, event lifecycle.Event) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *lifecycleEventsSink) sent() []lifecycle.Event {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]lifecycle.Event{}, s.events...)
+}
+
 var _ = Describe("HostManager", func() {
 	var (
 		mockHostUtils mocks.HostUtils
@@ -39,6 +68,10 @@ var _ = Describe("HostManager", func() {
 	BeforeEach(func() {
 		mockHostUtils = mocks.HostUtils{}
 		manager = hostManager{hostUtils: &mockHostUtils}
+
+		originalBackoff := vpdReadBackoff
+		vpdReadBackoff = 0
+		DeferCleanup(func() { vpdReadBackoff = originalBackoff })
 	})
 
 	Describe("DiscoverNicDevices", func() {
@@ -47,7 +80,7 @@ var _ = Describe("HostManager", func() {
 				mockHostUtils.On("GetPCIDevices").
 					Return(nil, errors.New("get PCI devices error"))
 
-				devices, err := manager.DiscoverNicDevices()
+				devices, err := manager.DiscoverNicDevices(nil)
 				Expect(err).To(HaveOccurred())
 				Expect(devices).To(BeNil())
 				mockHostUtils.AssertExpectations(GinkgoT())
@@ -65,7 +98,7 @@ var _ = Describe("HostManager", func() {
 					},
 				}, nil)
 
-				devices, err := manager.DiscoverNicDevices()
+				devices, err := manager.DiscoverNicDevices(nil)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(devices).To(BeEmpty())
 				mockHostUtils.AssertExpectations(GinkgoT())
@@ -83,7 +116,7 @@ var _ = Describe("HostManager", func() {
 					},
 				}, nil)
 
-				devices, err := manager.DiscoverNicDevices()
+				devices, err := manager.DiscoverNicDevices(nil)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(devices).To(BeEmpty())
 				mockHostUtils.AssertExpectations(GinkgoT())
@@ -105,31 +138,43 @@ var _ = Describe("HostManager", func() {
 			It("should log and skip devices if IsSriovVF returns true", func() {
 				mockHostUtils.On("IsSriovVF", "0000:00:00.0").Return(true)
 
-				devices, err := manager.DiscoverNicDevices()
+				devices, err := manager.DiscoverNicDevices(nil)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(devices).To(BeEmpty())
 				mockHostUtils.AssertExpectations(GinkgoT())
 			})
 
-			It("should fails if GetPartAndSerialNumber fails", func() {
+			It("should report the device without its identity if neither the VPD nor the flash of the device can be read", func() {
 				mockHostUtils.On("IsSriovVF", "0000:00:00.0").Return(false)
 				mockHostUtils.On("GetPartAndSerialNumber", "0000:00:00.0").
 					Return("", "", errors.New("serial number error"))
+				mockHostUtils.On("QueryFirmware", "0000:00:00.0").
+					Return(nil, errors.New("flash query error"))
+				mockHostUtils.On("GetInterfaceName", "0000:00:00.0").Return("eth0")
+				mockHostUtils.On("GetRDMADeviceName", "0000:00:00.0").Return("mlx5_0")
 
-				devices, err := manager.DiscoverNicDevices()
-				Expect(err).To(HaveOccurred())
-				Expect(devices).To(BeNil())
+				devices, err := manager.DiscoverNicDevices(nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(devices).To(HaveLen(1))
+				Expect(devices).To(HaveKey("unidentified-0000:00:00.0"))
+				device := devices["unidentified-0000:00:00.0"]
+				Expect(device.IdentitySource).To(Equal(consts.IdentitySourceUnknown))
+				Expect(device.SerialNumber).To(BeEmpty())
+				Expect(device.Ports).To(Equal([]v1alpha1.NicDevicePortSpec{
+					{PCI: "0000:00:00.0", NetworkInterface: "eth0", RdmaInterface: "mlx5_0"},
+				}))
+				mockHostUtils.AssertNumberOfCalls(GinkgoT(), "GetPartAndSerialNumber", vpdReadAttempts)
 				mockHostUtils.AssertExpectations(GinkgoT())
 			})
 
-			It("should log and skip devices if GetFirmwareVersionAndPSID fails", func() {
+			It("should log and skip devices if QueryFirmware fails", func() {
 				mockHostUtils.On("IsSriovVF", "0000:00:00.0").Return(false)
 				mockHostUtils.On("GetPartAndSerialNumber", "0000:00:00.0").
 					Return("part-number", "serial-number", nil)
-				mockHostUtils.On("GetFirmwareVersionAndPSID", "0000:00:00.0").
-					Return("", "", errors.New("firmware error"))
+				mockHostUtils.On("QueryFirmware", "0000:00:00.0").
+					Return(nil, errors.New("firmware error"))
 
-				devices, err := manager.DiscoverNicDevices()
+				devices, err := manager.DiscoverNicDevices(nil)
 				Expect(err).To(HaveOccurred())
 				Expect(devices).To(BeNil())
 				mockHostUtils.AssertExpectations(GinkgoT())
@@ -139,14 +184,37 @@ var _ = Describe("HostManager", func() {
 				mockHostUtils.On("IsSriovVF", "0000:00:00.0").Return(false)
 				mockHostUtils.On("GetPartAndSerialNumber", "0000:00:00.0").
 					Return("part-number", "serial-number", nil)
-				mockHostUtils.On("GetFirmwareVersionAndPSID", "0000:00:00.0").
-					Return("fw-version", "psid", nil)
+				securityVersion := 2
+				mockHostUtils.On("QueryFirmware", "0000:00:00.0").
+					Return(&types.FirmwareQuery{
+						Version:  "fw-version",
+						PSID:     "psid",
+						Security: &types.FirmwareSecurity{Attributes: []string{"secure-fw", "dev"}, SecurityVersion: &securityVersion},
+					}, nil)
+				mockHostUtils.On("GetPCILinkStatus", "0000:00:00.0").
+					Return(&types.PCILinkStatus{Speed: "8.0 GT/s PCIe", Width: 16, MaxSpeed: "16.0 GT/s PCIe", MaxWidth: 16}, nil)
+				mockHostUtils.On("GetTemperature", "0000:00:00.0").
+					Return(62, nil)
+				mockHostUtils.On("GetHealthReporters", "0000:00:00.0").
+					Return([]types.HealthReporter{{Name: "fw_fatal", State: "healthy", ErrorCount: 1, RecoverCount: 1}}, nil)
 				mockHostUtils.On("GetInterfaceName", "0000:00:00.0").
 					Return("eth0")
 				mockHostUtils.On("GetRDMADeviceName", "0000:00:00.0").
 					Return("mlx5_0")
-
-				devices, err := manager.DiscoverNicDevices()
+				mockHostUtils.On("GetRdmaPort", "mlx5_0").
+					Return(nil, nil)
+				mockHostUtils.On("GetPtpClockIndex", "0000:00:00.0").
+					Return(0, nil)
+				mockHostUtils.On("GetPCITopology", "0000:00:00.0").
+					Return(&types.PCITopology{NumaNode: 1, LocalCPUs: "16-31", RootComplex: "pci0000:00"}, nil)
+				mockHostUtils.On("GetTransceiver", "eth0").
+					Return(&types.Transceiver{Type: "QSFP28", Vendor: "Mellanox", PartNumber: "MCP1600-C001", Length: "1m", SupportedSpeeds: []string{"100G"}}, nil)
+				mockHostUtils.On("GetLinkStatus", "eth0").
+					Return(&types.LinkStatus{OperState: "up", Speed: 100000, AutoNegotiation: ptr.To(true)}, nil)
+				mockHostUtils.On("GetEswitchMode", "0000:00:00.0").
+					Return(consts.EswitchModeSwitchdev, nil)
+
+				devices, err := manager.DiscoverNicDevices(nil)
 				Expect(err).NotTo(HaveOccurred())
 
 				expectedDeviceStatus := v1alpha1.NicDeviceStatus{
@@ -155,18 +223,44 @@ var _ = Describe("HostManager", func() {
 					PartNumber:      "part-number",
 					PSID:            "psid",
 					FirmwareVersion: "fw-version",
+					FirmwareSecurity: &v1alpha1.FirmwareSecurityStatus{
+						SecureFirmware:  true,
+						Attributes:      []string{"secure-fw", "dev"},
+						SecurityVersion: &securityVersion,
+					},
 					Ports: []v1alpha1.NicDevicePortSpec{
 						{
 							PCI:              "0000:00:00.0",
 							NetworkInterface: "eth0",
 							RdmaInterface:    "mlx5_0",
+							PtpClockIndex:    ptr.To(0),
+							EswitchMode:      consts.EswitchModeSwitchdev,
+							NumaNode:         ptr.To(1),
+							LocalCPUs:        "16-31",
+							PciRootComplex:   "pci0000:00",
+							Transceiver: &v1alpha1.TransceiverStatus{
+								Type: "QSFP28", Vendor: "Mellanox", PartNumber: "MCP1600-C001", Length: "1m", SupportedSpeeds: []string{"100G"},
+							},
+							Link: &v1alpha1.PortLinkStatus{State: "up", Speed: "100G", AutoNegotiation: ptr.To(true)},
 						},
 					},
+					PciLink: &v1alpha1.PciLinkStatus{
+						Speed:    "8.0 GT/s PCIe",
+						Width:    16,
+						MaxSpeed: "16.0 GT/s PCIe",
+						MaxWidth: 16,
+						Degraded: true,
+					},
+					Health: &v1alpha1.DeviceHealthStatus{
+						Temperature: ptr.To(62),
+						Reporters:   []v1alpha1.HealthReporterStatus{{Name: "fw_fatal", PCI: "0000:00:00.0", State: "healthy", ErrorCount: 1, RecoverCount: 1}},
+					},
 				}
 
 				Expect(devices).To(HaveKey("serial-number"))
 				Expect(devices["serial-number"]).To(Equal(expectedDeviceStatus))
 
+				mockHostUtils.AssertNumberOfCalls(GinkgoT(), "QueryFirmware", 1)
 				mockHostUtils.AssertExpectations(GinkgoT())
 			})
 		})
@@ -195,16 +289,34 @@ var _ = Describe("HostManager", func() {
 				Return(false)
 			mockHostUtils.On("GetPartAndSerialNumber", "0000:00:00.0").
 				Return("part-number", "serial-number", nil)
-			mockHostUtils.On("GetFirmwareVersionAndPSID", "0000:00:00.0").
-				Return("fw-version", "psid", nil)
+			mockHostUtils.On("QueryFirmware", "0000:00:00.0").
+				Return(&types.FirmwareQuery{Version: "fw-version", PSID: "psid"}, nil)
+			mockHostUtils.On("GetPCILinkStatus", "0000:00:00.0").
+				Return(nil, nil)
+			mockHostUtils.On("GetTemperature", "0000:00:00.0").
+				Return(0, errors.New("mstmget_temp failed"))
+			mockHostUtils.On("GetHealthReporters", "0000:00:00.0").
+				Return(nil, nil)
 			mockHostUtils.On("GetInterfaceName", "0000:00:00.0").
 				Return("eth0")
 			mockHostUtils.On("GetRDMADeviceName", "0000:00:00.0").
 				Return("mlx5_0")
+			mockHostUtils.On("GetRdmaPort", "mlx5_0").
+				Return(nil, nil)
+			mockHostUtils.On("GetPtpClockIndex", "0000:00:00.0").
+				Return(-1, nil)
+			mockHostUtils.On("GetPCITopology", "0000:00:00.0").
+				Return(nil, nil)
+			mockHostUtils.On("GetTransceiver", "eth0").
+				Return(nil, nil)
+			mockHostUtils.On("GetLinkStatus", "eth0").
+				Return(nil, nil)
+			mockHostUtils.On("GetEswitchMode", "0000:00:00.0").
+				Return("", nil)
 
 			mockHostUtils.On("IsSriovVF", "0000:00:00.1").Return(true)
 
-			devices, err := manager.DiscoverNicDevices()
+			devices, err := manager.DiscoverNicDevices(nil)
 			Expect(err).NotTo(HaveOccurred())
 			expectedDeviceStatus := v1alpha1.NicDeviceStatus{
 				Type:            "test-id",
@@ -226,49 +338,105 @@ var _ = Describe("HostManager", func() {
 			mockHostUtils.AssertExpectations(GinkgoT())
 		})
 
+		It("should skip all functions of the ignored PCI slot without querying them", func() {
+			devices, err := manager.DiscoverNicDevices([]string{"0000:00:00.1"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(devices).To(BeEmpty())
+			mockHostUtils.AssertNotCalled(GinkgoT(), "IsSriovVF", mock.Anything)
+			mockHostUtils.AssertNotCalled(GinkgoT(), "GetPartAndSerialNumber", mock.Anything)
+		})
+
 		It("should log and skip only a faulty device if GetPartAndSerialNumber fails", func() {
 			mockHostUtils.On("IsSriovVF", "0000:00:00.0").
 				Return(false)
 			mockHostUtils.On("GetPartAndSerialNumber", "0000:00:00.0").
 				Return("part-number", "serial-number", nil)
-			mockHostUtils.On("GetFirmwareVersionAndPSID", "0000:00:00.0").
-				Return("fw-version", "psid", nil)
+			mockHostUtils.On("QueryFirmware", "0000:00:00.0").
+				Return(&types.FirmwareQuery{Version: "fw-version", PSID: "psid"}, nil)
+			mockHostUtils.On("GetPCILinkStatus", "0000:00:00.0").
+				Return(nil, nil)
+			mockHostUtils.On("GetTemperature", "0000:00:00.0").
+				Return(0, errors.New("mstmget_temp failed"))
+			mockHostUtils.On("GetHealthReporters", "0000:00:00.0").
+				Return(nil, nil)
 			mockHostUtils.On("GetInterfaceName", "0000:00:00.0").
 				Return("eth0")
 			mockHostUtils.On("GetRDMADeviceName", "0000:00:00.0").
 				Return("mlx5_0")
+			mockHostUtils.On("GetRdmaPort", "mlx5_0").
+				Return(nil, nil)
+			mockHostUtils.On("GetPtpClockIndex", "0000:00:00.0").
+				Return(-1, nil)
+			mockHostUtils.On("GetPCITopology", "0000:00:00.0").
+				Return(nil, nil)
+			mockHostUtils.On("GetTransceiver", "eth0").
+				Return(nil, nil)
+			mockHostUtils.On("GetLinkStatus", "eth0").
+				Return(nil, nil)
+			mockHostUtils.On("GetEswitchMode", "0000:00:00.0").
+				Return("", nil)
 
 			mockHostUtils.On("IsSriovVF", "0000:00:00.1").
 				Return(false)
 			mockHostUtils.On("GetPartAndSerialNumber", "0000:00:00.1").
 				Return("", "", errors.New("serial number error"))
+			mockHostUtils.On("QueryFirmware", "0000:00:00.1").
+				Return(nil, errors.New("flash query error"))
+			mockHostUtils.On("GetInterfaceName", "0000:00:00.1").
+				Return("eth1")
+			mockHostUtils.On("GetRDMADeviceName", "0000:00:00.1").
+				Return("mlx5_1")
 
-			devices, err := manager.DiscoverNicDevices()
-			Expect(err).To(HaveOccurred())
-			Expect(devices).To(BeNil())
+			devices, err := manager.DiscoverNicDevices(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(devices).To(HaveLen(1))
+			Expect(devices).To(HaveKey("serial-number"))
+			Expect(devices["serial-number"].FirmwareVersion).To(Equal("fw-version"))
+			Expect(devices["serial-number"].Ports).To(Equal([]v1alpha1.NicDevicePortSpec{
+				{PCI: "0000:00:00.0", NetworkInterface: "eth0", RdmaInterface: "mlx5_0"},
+				{PCI: "0000:00:00.1", NetworkInterface: "eth1", RdmaInterface: "mlx5_1"},
+			}))
 			mockHostUtils.AssertExpectations(GinkgoT())
 		})
 
-		It("should log and skip only a faulty device if GetFirmwareVersionAndPSID fails", func() {
+		It("should log and skip only a faulty device if QueryFirmware fails", func() {
 			mockHostUtils.On("IsSriovVF", "0000:00:00.0").
 				Return(false)
 			mockHostUtils.On("GetPartAndSerialNumber", "0000:00:00.0").
 				Return("part-number", "serial-number", nil)
-			mockHostUtils.On("GetFirmwareVersionAndPSID", "0000:00:00.0").
-				Return("fw-version", "psid", nil)
+			mockHostUtils.On("QueryFirmware", "0000:00:00.0").
+				Return(&types.FirmwareQuery{Version: "fw-version", PSID: "psid"}, nil)
+			mockHostUtils.On("GetPCILinkStatus", "0000:00:00.0").
+				Return(nil, nil)
+			mockHostUtils.On("GetTemperature", "0000:00:00.0").
+				Return(0, errors.New("mstmget_temp failed"))
+			mockHostUtils.On("GetHealthReporters", "0000:00:00.0").
+				Return(nil, nil)
 			mockHostUtils.On("GetInterfaceName", "0000:00:00.0").
 				Return("eth0")
 			mockHostUtils.On("GetRDMADeviceName", "0000:00:00.0").
 				Return("mlx5_0")
+			mockHostUtils.On("GetRdmaPort", "mlx5_0").
+				Return(nil, nil)
+			mockHostUtils.On("GetPtpClockIndex", "0000:00:00.0").
+				Return(-1, nil)
+			mockHostUtils.On("GetPCITopology", "0000:00:00.0").
+				Return(nil, nil)
+			mockHostUtils.On("GetTransceiver", "eth0").
+				Return(nil, nil)
+			mockHostUtils.On("GetLinkStatus", "eth0").
+				Return(nil, nil)
+			mockHostUtils.On("GetEswitchMode", "0000:00:00.0").
+				Return("", nil)
 
 			mockHostUtils.On("IsSriovVF", "0000:00:00.1").
 				Return(false)
 			mockHostUtils.On("GetPartAndSerialNumber", "0000:00:00.1").
 				Return("part-number", "serial-number-2", nil)
-			mockHostUtils.On("GetFirmwareVersionAndPSID", "0000:00:00.1").
-				Return("", "", errors.New("firmware error"))
+			mockHostUtils.On("QueryFirmware", "0000:00:00.1").
+				Return(nil, errors.New("firmware error"))
 
-			devices, err := manager.DiscoverNicDevices()
+			devices, err := manager.DiscoverNicDevices(nil)
 			Expect(err).To(HaveOccurred())
 			Expect(devices).To(BeNil())
 			mockHostUtils.AssertExpectations(GinkgoT())
@@ -279,25 +447,61 @@ var _ = Describe("HostManager", func() {
 				Return(false)
 			mockHostUtils.On("GetPartAndSerialNumber", "0000:00:00.0").
 				Return("part-number", "serial-number", nil)
-			mockHostUtils.On("GetFirmwareVersionAndPSID", "0000:00:00.0").
-				Return("fw-version", "psid", nil)
+			mockHostUtils.On("QueryFirmware", "0000:00:00.0").
+				Return(&types.FirmwareQuery{Version: "fw-version", PSID: "psid"}, nil)
+			mockHostUtils.On("GetPCILinkStatus", "0000:00:00.0").
+				Return(nil, nil)
+			mockHostUtils.On("GetTemperature", "0000:00:00.0").
+				Return(0, errors.New("mstmget_temp failed"))
+			mockHostUtils.On("GetHealthReporters", "0000:00:00.0").
+				Return(nil, nil)
 			mockHostUtils.On("GetInterfaceName", "0000:00:00.0").
 				Return("eth0")
 			mockHostUtils.On("GetRDMADeviceName", "0000:00:00.0").
 				Return("mlx5_0")
+			mockHostUtils.On("GetRdmaPort", "mlx5_0").
+				Return(nil, nil)
+			mockHostUtils.On("GetPtpClockIndex", "0000:00:00.0").
+				Return(-1, nil)
+			mockHostUtils.On("GetPCITopology", "0000:00:00.0").
+				Return(nil, nil)
+			mockHostUtils.On("GetTransceiver", "eth0").
+				Return(nil, nil)
+			mockHostUtils.On("GetLinkStatus", "eth0").
+				Return(nil, nil)
+			mockHostUtils.On("GetEswitchMode", "0000:00:00.0").
+				Return("", nil)
 
 			mockHostUtils.On("IsSriovVF", "0000:00:00.1").
 				Return(false)
 			mockHostUtils.On("GetPartAndSerialNumber", "0000:00:00.1").
 				Return("part-number", "serial-number-2", nil)
-			mockHostUtils.On("GetFirmwareVersionAndPSID", "0000:00:00.1").
-				Return("fw-version", "psid", nil)
+			mockHostUtils.On("QueryFirmware", "0000:00:00.1").
+				Return(&types.FirmwareQuery{Version: "fw-version", PSID: "psid"}, nil)
+			mockHostUtils.On("GetPCILinkStatus", "0000:00:00.1").
+				Return(nil, nil)
+			mockHostUtils.On("GetTemperature", "0000:00:00.1").
+				Return(0, errors.New("mstmget_temp failed"))
+			mockHostUtils.On("GetHealthReporters", "0000:00:00.1").
+				Return(nil, nil)
 			mockHostUtils.On("GetInterfaceName", "0000:00:00.1").
 				Return("eth1")
 			mockHostUtils.On("GetRDMADeviceName", "0000:00:00.1").
 				Return("mlx5_1")
-
-			devices, err := manager.DiscoverNicDevices()
+			mockHostUtils.On("GetRdmaPort", "mlx5_1").
+				Return(nil, nil)
+			mockHostUtils.On("GetPtpClockIndex", "0000:00:00.1").
+				Return(-1, nil)
+			mockHostUtils.On("GetPCITopology", "0000:00:00.1").
+				Return(nil, nil)
+			mockHostUtils.On("GetTransceiver", "eth1").
+				Return(nil, nil)
+			mockHostUtils.On("GetLinkStatus", "eth1").
+				Return(nil, nil)
+			mockHostUtils.On("GetEswitchMode", "0000:00:00.1").
+				Return("", nil)
+
+			devices, err := manager.DiscoverNicDevices(nil)
 			Expect(err).NotTo(HaveOccurred())
 
 			expectedDeviceStatus1 := v1alpha1.NicDeviceStatus{
@@ -361,24 +565,56 @@ var _ = Describe("HostManager", func() {
 				Return(false)
 			mockHostUtils.On("GetPartAndSerialNumber", "0000:00:00.0").
 				Return("part-number", sameSerialNumber, nil)
-			mockHostUtils.On("GetFirmwareVersionAndPSID", "0000:00:00.0").
-				Return("fw-version", "psid", nil)
+			mockHostUtils.On("QueryFirmware", "0000:00:00.0").
+				Return(&types.FirmwareQuery{Version: "fw-version", PSID: "psid"}, nil)
+			mockHostUtils.On("GetPCILinkStatus", "0000:00:00.0").
+				Return(nil, nil)
+			mockHostUtils.On("GetTemperature", "0000:00:00.0").
+				Return(0, errors.New("mstmget_temp failed"))
+			mockHostUtils.On("GetHealthReporters", "0000:00:00.0").
+				Return([]types.HealthReporter{{Name: "fw_fatal", State: "healthy"}}, nil)
 			mockHostUtils.On("GetInterfaceName", "0000:00:00.0").
 				Return("eth0")
 			mockHostUtils.On("GetRDMADeviceName", "0000:00:00.0").
 				Return("mlx5_0")
+			mockHostUtils.On("GetRdmaPort", "mlx5_0").
+				Return(nil, nil)
+			mockHostUtils.On("GetPtpClockIndex", "0000:00:00.0").
+				Return(-1, nil)
+			mockHostUtils.On("GetPCITopology", "0000:00:00.0").
+				Return(nil, nil)
+			mockHostUtils.On("GetTransceiver", "eth0").
+				Return(nil, nil)
+			mockHostUtils.On("GetLinkStatus", "eth0").
+				Return(nil, nil)
+			mockHostUtils.On("GetEswitchMode", "0000:00:00.0").
+				Return(consts.EswitchModeLegacy, nil)
 
 			mockHostUtils.On("IsSriovVF", "0000:00:00.1").
 				Return(false)
 			mockHostUtils.On("GetPartAndSerialNumber", "0000:00:00.1").
 				Return("part-number", sameSerialNumber, nil)
-			mockHostUtils.AssertNotCalled(GinkgoT(), "GetFirmwareVersionAndPSID", "0000:00:00.1")
+			mockHostUtils.AssertNotCalled(GinkgoT(), "QueryFirmware", "0000:00:00.1")
 			mockHostUtils.On("GetInterfaceName", "0000:00:00.1").
 				Return("eth1")
 			mockHostUtils.On("GetRDMADeviceName", "0000:00:00.1").
 				Return("mlx5_1")
-
-			devices, err := manager.DiscoverNicDevices()
+			mockHostUtils.On("GetRdmaPort", "mlx5_1").
+				Return(nil, nil)
+			mockHostUtils.On("GetPtpClockIndex", "0000:00:00.1").
+				Return(-1, nil)
+			mockHostUtils.On("GetPCITopology", "0000:00:00.1").
+				Return(nil, nil)
+			mockHostUtils.On("GetTransceiver", "eth1").
+				Return(nil, nil)
+			mockHostUtils.On("GetLinkStatus", "eth1").
+				Return(nil, nil)
+			mockHostUtils.On("GetEswitchMode", "0000:00:00.1").
+				Return("", nil)
+			mockHostUtils.On("GetHealthReporters", "0000:00:00.1").
+				Return([]types.HealthReporter{{Name: "tx", State: consts.HealthReporterStateError, ErrorCount: 2}}, nil)
+
+			devices, err := manager.DiscoverNicDevices(nil)
 			Expect(err).NotTo(HaveOccurred())
 			expectedDeviceStatus := v1alpha1.NicDeviceStatus{
 				Type:            "test-id",
@@ -391,6 +627,7 @@ var _ = Describe("HostManager", func() {
 						PCI:              "0000:00:00.0",
 						NetworkInterface: "eth0",
 						RdmaInterface:    "mlx5_0",
+						EswitchMode:      consts.EswitchModeLegacy,
 					},
 					{
 						PCI:              "0000:00:00.1",
@@ -398,6 +635,12 @@ var _ = Describe("HostManager", func() {
 						RdmaInterface:    "mlx5_1",
 					},
 				},
+				Health: &v1alpha1.DeviceHealthStatus{
+					Reporters: []v1alpha1.HealthReporterStatus{
+						{Name: "fw_fatal", PCI: "0000:00:00.0", State: "healthy"},
+						{Name: "tx", PCI: "0000:00:00.1", State: consts.HealthReporterStateError, ErrorCount: 2},
+					},
+				},
 			}
 
 			Expect(devices).To(HaveKey("serial-number"))
@@ -406,6 +649,216 @@ var _ = Describe("HostManager", func() {
 			mockHostUtils.AssertExpectations(GinkgoT())
 		})
 	})
+
+	Context("when the VPD of a NIC can't be read", func() {
+		mockPort := func(pciAddr string, networkInterface string, rdmaInterface string) {
+			mockHostUtils.On("IsSriovVF", pciAddr).Return(false)
+			mockHostUtils.On("GetInterfaceName", pciAddr).Return(networkInterface)
+			mockHostUtils.On("GetRDMADeviceName", pciAddr).Return(rdmaInterface)
+			mockHostUtils.On("GetRdmaPort", rdmaInterface).Return(nil, nil)
+			mockHostUtils.On("GetPtpClockIndex", pciAddr).Return(-1, nil)
+			mockHostUtils.On("GetPCITopology", pciAddr).Return(nil, nil)
+			mockHostUtils.On("GetTransceiver", networkInterface).Return(nil, nil)
+			mockHostUtils.On("GetLinkStatus", networkInterface).Return(nil, nil)
+			mockHostUtils.On("GetEswitchMode", pciAddr).Return("", nil)
+			mockHostUtils.On("GetHealthReporters", pciAddr).Return(nil, nil)
+		}
+		mockFirmware := func(pciAddr string) {
+			mockHostUtils.On("QueryFirmware", pciAddr).Return(&types.FirmwareQuery{Version: "fw-version", PSID: "psid"}, nil)
+			mockHostUtils.On("GetPCILinkStatus", pciAddr).Return(nil, nil)
+			mockHostUtils.On("GetTemperature", pciAddr).Return(0, errors.New("mstmget_temp failed"))
+		}
+		ports := []v1alpha1.NicDevicePortSpec{
+			{PCI: "0000:00:00.0", NetworkInterface: "eth0", RdmaInterface: "mlx5_0"},
+			{PCI: "0000:00:00.1", NetworkInterface: "eth1", RdmaInterface: "mlx5_1"},
+		}
+
+		BeforeEach(func() {
+			mockHostUtils.On("GetPCIDevices").Return([]*pci.Device{
+				{
+					Address: "0000:00:00.0",
+					Vendor:  &pcidb.Vendor{ID: consts.MellanoxVendor},
+					Product: &pcidb.Product{ID: "test-id", Name: "Mellanox Device"},
+					Class:   &pcidb.Class{ID: "02"},
+				},
+				{
+					Address: "0000:00:00.1",
+					Vendor:  &pcidb.Vendor{ID: consts.MellanoxVendor},
+					Product: &pcidb.Product{ID: "test-id", Name: "Mellanox Device"},
+					Class:   &pcidb.Class{ID: "02"},
+				},
+			}, nil)
+			mockPort("0000:00:00.0", "eth0", "mlx5_0")
+			mockPort("0000:00:00.1", "eth1", "mlx5_1")
+		})
+
+		It("should retry the VPD read", func() {
+			mockHostUtils.On("GetPartAndSerialNumber", "0000:00:00.0").
+				Return("", "", errors.New("serial number error")).Once()
+			mockHostUtils.On("GetPartAndSerialNumber", "0000:00:00.0").
+				Return("part-number", "serial-number", nil)
+			mockHostUtils.On("GetPartAndSerialNumber", "0000:00:00.1").
+				Return("part-number", "serial-number", nil)
+			mockFirmware("0000:00:00.0")
+
+			devices, err := manager.DiscoverNicDevices(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(devices).To(HaveKey("serial-number"))
+			Expect(devices["serial-number"].IdentitySource).To(BeEmpty())
+			Expect(devices["serial-number"].Ports).To(Equal(ports))
+			mockHostUtils.AssertNumberOfCalls(GinkgoT(), "QueryFirmware", 1)
+		})
+
+		It("should identify the NIC by the base GUID of its flash", func() {
+			mockHostUtils.On("GetPartAndSerialNumber", mock.Anything).
+				Return("", "", errors.New("serial number error"))
+			mockHostUtils.On("QueryFirmware", mock.Anything).
+				Return(&types.FirmwareQuery{Version: "fw-version", PSID: "psid", PartNumber: "MCX713106AC-VEA_Ax", BaseGUID: "b8cef603000a1b2c"}, nil)
+			mockFirmware("0000:00:00.0")
+
+			devices, err := manager.DiscoverNicDevices(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(devices).To(HaveLen(1))
+			Expect(devices).To(HaveKey("guid-b8cef603000a1b2c"))
+			device := devices["guid-b8cef603000a1b2c"]
+			Expect(device.SerialNumber).To(Equal("guid-b8cef603000a1b2c"))
+			// The part number is reported in the format of the VPD
+			Expect(device.PartNumber).To(Equal("mcx713106ac-vea"))
+			Expect(device.IdentitySource).To(Equal(consts.IdentitySourceFlash))
+			Expect(device.Ports).To(Equal(ports))
+			Expect(device.FirmwareVersion).To(Equal("fw-version"))
+			Expect(device.PSID).To(Equal("psid"))
+			// The flash is queried once per function, the query of the fallback is reused for the firmware version
+			mockHostUtils.AssertNumberOfCalls(GinkgoT(), "QueryFirmware", 2)
+		})
+
+		It("should merge the port identified by its flash into the NIC identified by its VPD", func() {
+			mockHostUtils.On("GetPartAndSerialNumber", "0000:00:00.0").
+				Return("", "", errors.New("serial number error"))
+			mockHostUtils.On("QueryFirmware", "0000:00:00.0").
+				Return(&types.FirmwareQuery{Version: "fw-version", PSID: "psid", PartNumber: "part-number", BaseGUID: "b8cef603000a1b2c"}, nil)
+			mockHostUtils.On("GetPartAndSerialNumber", "0000:00:00.1").
+				Return("part-number", "serial-number", nil)
+			mockFirmware("0000:00:00.0")
+			mockFirmware("0000:00:00.1")
+
+			devices, err := manager.DiscoverNicDevices(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(devices).To(HaveLen(1))
+			Expect(devices).To(HaveKey("serial-number"))
+			Expect(devices["serial-number"].IdentitySource).To(BeEmpty())
+			Expect(devices["serial-number"].Ports).To(Equal(ports))
+		})
+
+		It("should merge the port that can't be identified into the NIC identified by its VPD", func() {
+			mockHostUtils.On("GetPartAndSerialNumber", "0000:00:00.0").
+				Return("", "", errors.New("serial number error"))
+			mockHostUtils.On("QueryFirmware", "0000:00:00.0").
+				Return(nil, errors.New("flash query error"))
+			mockHostUtils.On("GetPartAndSerialNumber", "0000:00:00.1").
+				Return("part-number", "serial-number", nil)
+			mockFirmware("0000:00:00.1")
+
+			devices, err := manager.DiscoverNicDevices(nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(devices).To(HaveLen(1))
+			Expect(devices).To(HaveKey("serial-number"))
+			Expect(devices["serial-number"].IdentitySource).To(BeEmpty())
+			Expect(devices["serial-number"].Ports).To(Equal(ports))
+		})
+	})
+	Describe("RefreshPortLinks", func() {
+		It("should update the links of the ports with a network interface", func() {
+			mockHostUtils.On("GetLinkStatus", "eth0").
+				Return(&types.LinkStatus{OperState: "up", Speed: 25000, AutoNegotiation: ptr.To(false)}, nil)
+			mockHostUtils.On("GetLinkStatus", "eth1").
+				Return(nil, errors.New("interface eth1 not found"))
+
+			status := &v1alpha1.NicDeviceStatus{
+				Ports: []v1alpha1.NicDevicePortSpec{
+					{PCI: "0000:00:00.0", NetworkInterface: "eth0", Link: &v1alpha1.PortLinkStatus{State: "down"}},
+					{PCI: "0000:00:00.1", NetworkInterface: "eth1", Link: &v1alpha1.PortLinkStatus{State: "up", Speed: "25G"}},
+					{PCI: "0000:00:00.2"},
+				},
+			}
+			manager.RefreshPortLinks(status)
+
+			Expect(status.Ports[0].Link).To(Equal(&v1alpha1.PortLinkStatus{State: "up", Speed: "25G", AutoNegotiation: ptr.To(false)}))
+			Expect(status.Ports[1].Link).To(BeNil())
+			Expect(status.Ports[2].Link).To(BeNil())
+			mockHostUtils.AssertExpectations(GinkgoT())
+		})
+		It("should update the RDMA links of the InfiniBand ports", func() {
+			mockHostUtils.On("GetRdmaPort", "mlx5_0").Return(&types.RdmaPort{
+				LinkLayer: consts.RdmaLinkLayerInfiniband, NodeGUID: "0c42:a103:0016:054c", PortGUID: "0c42:a103:0016:054c",
+				State: "ACTIVE", PhysicalState: "LinkUp", Rate: "200 Gb/sec (4X HDR)",
+			}, nil)
+			mockHostUtils.On("GetRdmaPort", "mlx5_1").Return(nil, errors.New("failed to read node GUID"))
+
+			status := &v1alpha1.NicDeviceStatus{
+				Ports: []v1alpha1.NicDevicePortSpec{
+					{PCI: "0000:00:00.0", RdmaInterface: "mlx5_0", LinkLayer: consts.RdmaLinkLayerInfiniband,
+						RdmaLink: &v1alpha1.RdmaLinkStatus{State: "INIT", PhysicalState: "LinkUp"}},
+					{PCI: "0000:00:00.1", RdmaInterface: "mlx5_1", LinkLayer: consts.RdmaLinkLayerInfiniband, NodeGUID: "0c42:a103:0016:054d",
+						RdmaLink: &v1alpha1.RdmaLinkStatus{State: "ACTIVE"}},
+					{PCI: "0000:00:00.2", RdmaInterface: "mlx5_2", LinkLayer: "Ethernet"},
+				},
+			}
+			manager.RefreshPortLinks(status)
+
+			Expect(status.Ports[0]).To(Equal(v1alpha1.NicDevicePortSpec{
+				PCI: "0000:00:00.0", RdmaInterface: "mlx5_0", LinkLayer: consts.RdmaLinkLayerInfiniband,
+				NodeGUID: "0c42:a103:0016:054c", PortGUID: "0c42:a103:0016:054c",
+				RdmaLink: &v1alpha1.RdmaLinkStatus{State: "ACTIVE", PhysicalState: "LinkUp", Rate: "200 Gb/sec (4X HDR)"},
+			}))
+			Expect(status.Ports[1]).To(Equal(v1alpha1.NicDevicePortSpec{PCI: "0000:00:00.1", RdmaInterface: "mlx5_1"}))
+			Expect(status.Ports[2].LinkLayer).To(Equal("Ethernet"))
+			mockHostUtils.AssertNotCalled(GinkgoT(), "GetRdmaPort", "mlx5_2")
+		})
+	})
+	Describe("FormatPortSpeed", func() {
+		It("should format the speed in the template format", func() {
+			Expect(FormatPortSpeed(400000)).To(Equal("400G"))
+			Expect(FormatPortSpeed(2500)).To(Equal("2.5G"))
+			Expect(FormatPortSpeed(1000)).To(Equal("1G"))
+			Expect(FormatPortSpeed(100)).To(Equal("100M"))
+			Expect(FormatPortSpeed(0)).To(BeEmpty())
+		})
+	})
+
+	Describe("DiscoverVirtualFunctions", func() {
+		It("should list the VFs of the ports with their drivers", func() {
+			mockHostUtils.On("GetVfPciAddresses", "0000:00:00.0").Return([]string{"0000:00:00.2", "0000:00:00.3"}, nil)
+			mockHostUtils.On("GetVfPciAddresses", "0000:00:00.1").Return([]string{"0000:00:01.2"}, nil)
+			mockHostUtils.On("GetPCIDriver", "0000:00:00.2").Return("mlx5_core", nil)
+			mockHostUtils.On("GetPCIDriver", "0000:00:00.3").Return("", nil)
+			mockHostUtils.On("GetPCIDriver", "0000:00:01.2").Return("vfio-pci", nil)
+
+			status := &v1alpha1.NicDeviceStatus{
+				Ports: []v1alpha1.NicDevicePortSpec{{PCI: "0000:00:00.0"}, {PCI: "0000:00:00.1"}},
+			}
+			manager.DiscoverVirtualFunctions(status)
+
+			Expect(status.VirtualFunctions).To(Equal([]v1alpha1.VirtualFunctionStatus{
+				{PCI: "0000:00:00.2", PhysicalFunction: "0000:00:00.0", Driver: "mlx5_core"},
+				{PCI: "0000:00:00.3", PhysicalFunction: "0000:00:00.0"},
+				{PCI: "0000:00:01.2", PhysicalFunction: "0000:00:00.1", Driver: "vfio-pci"},
+			}))
+			mockHostUtils.AssertExpectations(GinkgoT())
+		})
+		It("should skip the ports whose VFs can't be listed", func() {
+			mockHostUtils.On("GetVfPciAddresses", "0000:00:00.0").Return(nil, errors.New("no such device"))
+			mockHostUtils.On("GetVfPciAddresses", "0000:00:00.1").Return([]string{}, nil)
+
+			status := &v1alpha1.NicDeviceStatus{
+				Ports:            []v1alpha1.NicDevicePortSpec{{PCI: "0000:00:00.0"}, {PCI: "0000:00:00.1"}},
+				VirtualFunctions: []v1alpha1.VirtualFunctionStatus{{PCI: "0000:00:00.2", PhysicalFunction: "0000:00:00.0"}},
+			}
+			manager.DiscoverVirtualFunctions(status)
+
+			Expect(status.VirtualFunctions).To(BeNil())
+		})
+	})
 	Describe("hostManager.ValidateDeviceNvSpec", func() {
 		var (
 			mockHostUtils        mocks.HostUtils
@@ -440,7 +893,54 @@ var _ = Describe("HostManager", func() {
 			}
 		})
 
+		BeforeEach(func() {
+			mockHostUtils.On("IsEswitchManager", pciAddress).Return(true, nil)
+			mockHostUtils.On("GetHostBootID").Return("boot-2", nil).Maybe()
+		})
+
 		Describe("ValidateDeviceNvSpec", func() {
+			Context("when the nv config is owned by another host", func() {
+				It("should return DelegatedToOtherHost error without querying nv config", func() {
+					mockHostUtils.ExpectedCalls = nil
+					mockHostUtils.On("IsEswitchManager", pciAddress).Return(false, nil)
+
+					configUpdate, reboot, err := manager.ValidateDeviceNvSpec(ctx, device)
+					Expect(configUpdate).To(BeFalse())
+					Expect(reboot).To(BeFalse())
+					Expect(types.IsDelegatedToOtherHostError(err)).To(BeTrue())
+
+					mockHostUtils.AssertExpectations(GinkgoT())
+					mockHostUtils.AssertNotCalled(GinkgoT(), "QueryNvConfig", mock.Anything, mock.Anything)
+				})
+			})
+
+			Context("when the eswitch manager can't be determined", func() {
+				It("should validate the spec as usual", func() {
+					mockHostUtils.ExpectedCalls = nil
+					mockHostUtils.On("IsEswitchManager", pciAddress).Return(false, errors.New("devlink failed"))
+					queryErr := errors.New("failed to query nv config")
+					mockHostUtils.On("QueryNvConfig", ctx, pciAddress).
+						Return(types.NewNvConfigQuery(), queryErr)
+
+					_, _, err := manager.ValidateDeviceNvSpec(ctx, device)
+					Expect(err).To(MatchError(queryErr))
+
+					mockHostUtils.AssertExpectations(GinkgoT())
+				})
+			})
+
+			Context("when the eswitch query isn't permitted", func() {
+				It("should return the error without validating the spec", func() {
+					mockHostUtils.ExpectedCalls = nil
+					deniedErr := types.EswitchAccessDeniedError("devlink isn't permitted to query the eswitch")
+					mockHostUtils.On("IsEswitchManager", pciAddress).Return(false, deniedErr)
+
+					_, _, err := manager.ValidateDeviceNvSpec(ctx, device)
+					Expect(err).To(MatchError(deniedErr))
+					mockHostUtils.AssertNotCalled(GinkgoT(), "QueryNvConfig", mock.Anything, mock.Anything)
+				})
+			})
+
 			Context("when QueryNvConfig returns an error", func() {
 				It("should return false, false, and the error", func() {
 					queryErr := errors.New("failed to query nv config")
@@ -456,6 +956,83 @@ var _ = Describe("HostManager", func() {
 				})
 			})
 
+			Context("when the device has several PCI functions", func() {
+				var nvConfig types.NvConfigQuery
+
+				BeforeEach(func() {
+					device.Status.Ports = append(device.Status.Ports, v1alpha1.NicDevicePortSpec{PCI: "0000:3b:00.1"})
+					nvConfig = types.NvConfigQuery{
+						CurrentConfig:  map[string][]string{"param1": {"value1"}},
+						NextBootConfig: map[string][]string{"param1": {"value1"}},
+						DefaultConfig:  map[string][]string{"param1": {"default1"}},
+					}
+					mockConfigValidation.On("ConstructNvParamMapFromTemplate", device, nvConfig).
+						Return(map[string]string{"param1": "value1"}, nil)
+					mockConfigValidation.On("AdvancedPCISettingsEnabled", nvConfig).
+						Return(true)
+				})
+
+				It("should fall back to the next function if the first one doesn't respond", func() {
+					mockHostUtils.On("QueryNvConfig", ctx, pciAddress).
+						Return(types.NewNvConfigQuery(), errors.New("function is hidden"))
+					mockHostUtils.On("QueryNvConfig", ctx, "0000:3b:00.1").
+						Return(nvConfig, nil)
+
+					_, _, err := manager.ValidateDeviceNvSpec(ctx, device)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(device.Status.NvConfigPCI).To(Equal("0000:3b:00.1"))
+
+					mockHostUtils.AssertExpectations(GinkgoT())
+				})
+
+				It("should use the forced function first", func() {
+					function := 1
+					device.Spec.NvConfigPCIFunction = &function
+					mockHostUtils.ExpectedCalls = nil
+					mockHostUtils.On("IsEswitchManager", "0000:3b:00.1").Return(true, nil)
+					mockHostUtils.On("QueryNvConfig", ctx, "0000:3b:00.1").
+						Return(nvConfig, nil)
+
+					_, _, err := manager.ValidateDeviceNvSpec(ctx, device)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(device.Status.NvConfigPCI).To(Equal("0000:3b:00.1"))
+
+					mockHostUtils.AssertExpectations(GinkgoT())
+					mockHostUtils.AssertNotCalled(GinkgoT(), "QueryNvConfig", ctx, pciAddress)
+				})
+
+				It("should return the error of the forced function if none of the functions respond", func() {
+					function := 2
+					device.Spec.NvConfigPCIFunction = &function
+					mockHostUtils.ExpectedCalls = nil
+					mockHostUtils.On("IsEswitchManager", "0000:3b:00.2").Return(true, nil)
+					forcedErr := errors.New("forced function failed")
+					mockHostUtils.On("QueryNvConfig", ctx, "0000:3b:00.2").
+						Return(types.NewNvConfigQuery(), forcedErr)
+					mockHostUtils.On("QueryNvConfig", ctx, pciAddress).
+						Return(types.NewNvConfigQuery(), errors.New("function is hidden"))
+					mockHostUtils.On("QueryNvConfig", ctx, "0000:3b:00.1").
+						Return(types.NewNvConfigQuery(), errors.New("function is owned by the BMC"))
+
+					_, _, err := manager.ValidateDeviceNvSpec(ctx, device)
+					Expect(err).To(MatchError(forcedErr))
+					Expect(device.Status.NvConfigPCI).To(BeEmpty())
+
+					mockHostUtils.AssertExpectations(GinkgoT())
+				})
+
+				It("should not probe other functions if the host tool hangs", func() {
+					hangErr := types.ToolHangError("mstconfig didn't finish")
+					mockHostUtils.On("QueryNvConfig", ctx, pciAddress).
+						Return(types.NewNvConfigQuery(), hangErr)
+
+					_, _, err := manager.ValidateDeviceNvSpec(ctx, device)
+					Expect(err).To(MatchError(hangErr))
+
+					mockHostUtils.AssertNotCalled(GinkgoT(), "QueryNvConfig", ctx, "0000:3b:00.1")
+				})
+			})
+
 			Context("when ResetToDefault is true", func() {
 				BeforeEach(func() {
 					device.Spec.Configuration.ResetToDefault = true
@@ -578,11 +1155,68 @@ var _ = Describe("HostManager", func() {
 					Expect(reboot).To(BeTrue())
 					Expect(err).To(BeNil())
 
+					Expect(device.Status.NvConfigParameters).To(Equal([]v1alpha1.NvConfigParameterStatus{
+						{Name: "param1", DesiredValue: "value1", CurrentValues: []string{"oldValue1"}, NextBootValues: []string{"value1"}},
+						{Name: "param2", DesiredValue: "value2", CurrentValues: []string{"value2"}, NextBootValues: []string{"value2"}},
+					}))
+					Expect(device.Status.PendingRebootParameters).To(Equal([]v1alpha1.NvConfigParameterDiff{
+						{Name: "param1", CurrentValues: []string{"oldValue1"}, NextBootValues: []string{"value1"}},
+					}))
+
 					mockHostUtils.AssertExpectations(GinkgoT())
 					mockConfigValidation.AssertExpectations(GinkgoT())
 				})
 			})
 
+			Context("when a parameter is set from a Secret", func() {
+				It("should redact its values in the status", func() {
+					device.Spec.Configuration.Template = &v1alpha1.ConfigurationTemplateSpec{
+						ValuesFrom: []v1alpha1.TemplateValueSource{{
+							Field:        "rawNvConfig.param1",
+							SecretKeyRef: &v1alpha1.TemplateKeySelector{Name: "nv-config-values", Key: "param1"},
+						}},
+					}
+					nvConfig := types.NvConfigQuery{
+						CurrentConfig:  map[string][]string{"param1": {"oldValue1"}, "param2": {"value2"}},
+						NextBootConfig: map[string][]string{"param1": {"value1"}, "param2": {"value2"}},
+						DefaultConfig:  map[string][]string{"param1": {"default1"}, "param2": {"default2"}},
+					}
+					mockHostUtils.On("QueryNvConfig", ctx, pciAddress).Return(nvConfig, nil)
+					mockConfigValidation.On("ConstructNvParamMapFromTemplate", device, nvConfig).
+						Return(map[string]string{"param1": "value1", "param2": "value2"}, nil)
+					mockConfigValidation.On("AdvancedPCISettingsEnabled", nvConfig).Return(false)
+
+					_, _, err := manager.ValidateDeviceNvSpec(ctx, device)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(device.Status.NvConfigParameters).To(Equal([]v1alpha1.NvConfigParameterStatus{
+						{Name: "param1", DesiredValue: consts.RedactedNvParamValue,
+							CurrentValues: []string{consts.RedactedNvParamValue}, NextBootValues: []string{consts.RedactedNvParamValue}},
+						{Name: "param2", DesiredValue: "value2", CurrentValues: []string{"value2"}, NextBootValues: []string{"value2"}},
+					}))
+				})
+			})
+
+			Context("when a parameter is at the device default", func() {
+				It("should mark it in the status", func() {
+					nvConfig := types.NvConfigQuery{
+						CurrentConfig:  map[string][]string{"param1": {"value1"}, "param2": {"value2"}},
+						NextBootConfig: map[string][]string{"param1": {"value1"}, "param2": {"value2"}},
+						DefaultConfig:  map[string][]string{"param1": {"default1"}, "param2": {"value2"}},
+					}
+					mockHostUtils.On("QueryNvConfig", ctx, pciAddress).Return(nvConfig, nil)
+					mockConfigValidation.On("ConstructNvParamMapFromTemplate", device, nvConfig).
+						Return(map[string]string{"param1": "value1", "param2": "value2"}, nil)
+					mockConfigValidation.On("AdvancedPCISettingsEnabled", nvConfig).Return(false)
+
+					_, _, err := manager.ValidateDeviceNvSpec(ctx, device)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(device.Status.NvConfigParameters).To(Equal([]v1alpha1.NvConfigParameterStatus{
+						{Name: "param1", DesiredValue: "value1", CurrentValues: []string{"value1"}, NextBootValues: []string{"value1"}},
+						{Name: "param2", DesiredValue: "value2", CurrentValues: []string{"value2"}, NextBootValues: []string{"value2"}, DeviceDefault: true},
+					}))
+				})
+			})
+
 			//nolint:dupl
 			Context("when desiredConfig does not fully match next boot config", func() {
 				It("should return true, true, nil", func() {
@@ -610,68 +1244,210 @@ var _ = Describe("HostManager", func() {
 				})
 			})
 
-			Context("when AdvancedPCISettingsEnabled is true and a parameter is missing in CurrentConfig", func() {
-				It("should return an IncorrectSpecError", func() {
-					nvConfig := types.NvConfigQuery{
-						CurrentConfig:  map[string][]string{"param2": {"value2"}},
-						NextBootConfig: map[string][]string{"param1": {"value1"}, "param2": {"value2"}},
+			Context("when a parameter was written several times without taking effect", func() {
+				var nvConfig types.NvConfigQuery
+
+				BeforeEach(func() {
+					nvConfig = types.NvConfigQuery{
+						CurrentConfig:  map[string][]string{"param1": {"oldValue1"}, "param2": {"value2"}},
+						NextBootConfig: map[string][]string{"param1": {"oldValue1"}, "param2": {"value2"}},
 						DefaultConfig:  map[string][]string{"param1": {"default1"}, "param2": {"default2"}},
 					}
-					desiredConfig := map[string]string{"param1": "value1", "param2": "value2"}
 
 					mockHostUtils.On("QueryNvConfig", ctx, pciAddress).
 						Return(nvConfig, nil)
-					mockConfigValidation.On("ConstructNvParamMapFromTemplate", device, nvConfig).
-						Return(desiredConfig, nil)
 					mockConfigValidation.On("AdvancedPCISettingsEnabled", nvConfig).
-						Return(true)
+						Return(false)
+				})
 
-					expectedErr := types.IncorrectSpecError(
-						fmt.Sprintf("Parameter %s unsupported for device %s", "param1", device.Name))
+				It("should return a NonConvergingError with diagnostic data", func() {
+					device.Status.NvConfigWriteStats = &v1alpha1.NvConfigWriteStats{
+						Unconverged: []v1alpha1.NvConfigUnconvergedWrite{{Name: "param1", Value: "value1", Writes: 3}},
+					}
+					mockConfigValidation.On("ConstructNvParamMapFromTemplate", device, nvConfig).
+						Return(map[string]string{"param1": "value1", "param2": "value2"}, nil)
 
 					configUpdate, reboot, err := manager.ValidateDeviceNvSpec(ctx, device)
 					Expect(configUpdate).To(BeFalse())
 					Expect(reboot).To(BeFalse())
-					Expect(err).To(MatchError(expectedErr))
+					Expect(types.IsNonConvergingError(err)).To(BeTrue())
+					Expect(err.Error()).To(ContainSubstring("parameter param1 was written 3 times with value value1"))
+					Expect(err.Error()).To(ContainSubstring("current values [oldValue1] and next boot values [oldValue1]"))
+				})
 
-					mockHostUtils.AssertExpectations(GinkgoT())
-					mockConfigValidation.AssertExpectations(GinkgoT())
+				It("should keep writing the parameter below the limit", func() {
+					device.Status.NvConfigWriteStats = &v1alpha1.NvConfigWriteStats{
+						Unconverged: []v1alpha1.NvConfigUnconvergedWrite{{Name: "param1", Value: "value1", Writes: 2}},
+					}
+					mockConfigValidation.On("ConstructNvParamMapFromTemplate", device, nvConfig).
+						Return(map[string]string{"param1": "value1", "param2": "value2"}, nil)
+
+					configUpdate, reboot, err := manager.ValidateDeviceNvSpec(ctx, device)
+					Expect(configUpdate).To(BeTrue())
+					Expect(reboot).To(BeTrue())
+					Expect(err).NotTo(HaveOccurred())
+				})
+
+				It("should drop the write counters of converged, changed and no longer desired parameters", func() {
+					device.Status.NvConfigWriteStats = &v1alpha1.NvConfigWriteStats{
+						Unconverged: []v1alpha1.NvConfigUnconvergedWrite{
+							{Name: "param1", Value: "value1", Writes: 3},
+							{Name: "param2", Value: "value2", Writes: 3},
+							{Name: "param3", Value: "value3", Writes: 3},
+						},
+					}
+					mockConfigValidation.On("ConstructNvParamMapFromTemplate", device, nvConfig).
+						Return(map[string]string{"param1": "anotherValue", "param2": "value2"}, nil)
+
+					configUpdate, reboot, err := manager.ValidateDeviceNvSpec(ctx, device)
+					Expect(configUpdate).To(BeTrue())
+					Expect(reboot).To(BeTrue())
+					Expect(err).NotTo(HaveOccurred())
+					Expect(device.Status.NvConfigWriteStats.Unconverged).To(BeNil())
 				})
 			})
 
-			Context("when desired config contains string aliases", func() {
-				It("should accept lowercase parameters", func() {
-					nvConfig := types.NvConfigQuery{
-						CurrentConfig:  map[string][]string{"param1": {"value1", "1"}, "param2": {"value2", "2"}},
-						NextBootConfig: map[string][]string{"param1": {"value1", "1"}, "param2": {"value2", "2"}},
-						DefaultConfig:  map[string][]string{"param1": {"default1", "1"}, "param2": {"default2", "2"}},
+			Context("when a written parameter doesn't take effect after reboot", func() {
+				var nvConfig types.NvConfigQuery
+
+				BeforeEach(func() {
+					nvConfig = types.NvConfigQuery{
+						CurrentConfig:  map[string][]string{"param1": {"oldValue1"}, "param2": {"value2"}},
+						NextBootConfig: map[string][]string{"param1": {"value1"}, "param2": {"value2"}},
+						DefaultConfig:  map[string][]string{"param1": {"default1"}, "param2": {"default2"}},
 					}
-					desiredConfig := map[string]string{"param1": "value1", "param2": "2"}
 
 					mockHostUtils.On("QueryNvConfig", ctx, pciAddress).
 						Return(nvConfig, nil)
-					mockConfigValidation.On("ConstructNvParamMapFromTemplate", device, nvConfig).
-						Return(desiredConfig, nil)
 					mockConfigValidation.On("AdvancedPCISettingsEnabled", nvConfig).
-						Return(true)
+						Return(false)
+					mockConfigValidation.On("ConstructNvParamMapFromTemplate", device, nvConfig).
+						Return(map[string]string{"param1": "value1", "param2": "value2"}, nil)
+				})
+
+				It("should count the reboot and require another one below the limit", func() {
+					device.Status.NvConfigWriteStats = &v1alpha1.NvConfigWriteStats{
+						Unconverged: []v1alpha1.NvConfigUnconvergedWrite{{Name: "param1", Value: "value1", Writes: 1, Reboots: 1, BootID: "boot-1"}},
+					}
 
 					configUpdate, reboot, err := manager.ValidateDeviceNvSpec(ctx, device)
 					Expect(configUpdate).To(BeFalse())
-					Expect(reboot).To(BeFalse())
-					Expect(err).To(BeNil())
-
-					mockHostUtils.AssertExpectations(GinkgoT())
-					mockConfigValidation.AssertExpectations(GinkgoT())
+					Expect(reboot).To(BeTrue())
+					Expect(err).NotTo(HaveOccurred())
+					Expect(device.Status.NvConfigWriteStats.Unconverged).To(Equal([]v1alpha1.NvConfigUnconvergedWrite{
+						{Name: "param1", Value: "value1", Writes: 1, Reboots: 2, BootID: "boot-2"},
+					}))
 				})
-				It("should accept mixed-case parameters", func() {
-					nvConfig := types.NvConfigQuery{
-						CurrentConfig:  map[string][]string{"param1": {"value1", "1"}, "param2": {"value2", "2"}},
-						NextBootConfig: map[string][]string{"param1": {"value1", "1"}, "param2": {"value2", "2"}},
-						DefaultConfig:  map[string][]string{"param1": {"default1", "1"}, "param2": {"default2", "2"}},
+
+				It("should not count the same boot twice", func() {
+					device.Status.NvConfigWriteStats = &v1alpha1.NvConfigWriteStats{
+						Unconverged: []v1alpha1.NvConfigUnconvergedWrite{{Name: "param1", Value: "value1", Writes: 1, Reboots: 2, BootID: "boot-2"}},
 					}
-					desiredConfig := map[string]string{"param1": "VaLuE1", "param2": "valUE2"}
 
-					mockHostUtils.On("QueryNvConfig", ctx, pciAddress).
+					_, reboot, err := manager.ValidateDeviceNvSpec(ctx, device)
+					Expect(reboot).To(BeTrue())
+					Expect(err).NotTo(HaveOccurred())
+					Expect(device.Status.NvConfigWriteStats.Unconverged[0].Reboots).To(Equal(2))
+				})
+
+				It("should return a NonConvergingError after too many reboots", func() {
+					device.Status.NvConfigWriteStats = &v1alpha1.NvConfigWriteStats{
+						Unconverged: []v1alpha1.NvConfigUnconvergedWrite{{Name: "param1", Value: "value1", Writes: 1, Reboots: 2, BootID: "boot-1"}},
+					}
+
+					configUpdate, reboot, err := manager.ValidateDeviceNvSpec(ctx, device)
+					Expect(configUpdate).To(BeFalse())
+					Expect(reboot).To(BeFalse())
+					Expect(types.IsNonConvergingError(err)).To(BeTrue())
+					Expect(err.Error()).To(ContainSubstring("parameter param1 has next boot value value1, but the firmware reports current values [oldValue1] after 3 reboots"))
+				})
+			})
+
+			Context("when AdvancedPCISettingsEnabled is true and a parameter is missing in CurrentConfig", func() {
+				It("should return an IncorrectSpecError", func() {
+					nvConfig := types.NvConfigQuery{
+						CurrentConfig:  map[string][]string{"param2": {"value2"}},
+						NextBootConfig: map[string][]string{"param1": {"value1"}, "param2": {"value2"}},
+						DefaultConfig:  map[string][]string{"param1": {"default1"}, "param2": {"default2"}},
+					}
+					desiredConfig := map[string]string{"param1": "value1", "param2": "value2"}
+
+					mockHostUtils.On("QueryNvConfig", ctx, pciAddress).
+						Return(nvConfig, nil)
+					mockConfigValidation.On("ConstructNvParamMapFromTemplate", device, nvConfig).
+						Return(desiredConfig, nil)
+					mockConfigValidation.On("AdvancedPCISettingsEnabled", nvConfig).
+						Return(true)
+
+					expectedErr := types.IncorrectSpecError(
+						fmt.Sprintf("Parameter %s unsupported for device %s", "param1", device.Name))
+
+					configUpdate, reboot, err := manager.ValidateDeviceNvSpec(ctx, device)
+					Expect(configUpdate).To(BeFalse())
+					Expect(reboot).To(BeFalse())
+					Expect(err).To(MatchError(expectedErr))
+
+					mockHostUtils.AssertExpectations(GinkgoT())
+					mockConfigValidation.AssertExpectations(GinkgoT())
+				})
+			})
+
+			Context("when a parameter missing in CurrentConfig depends on a pending enable flag", func() {
+				It("should require a config update", func() {
+					nvConfig := types.NvConfigQuery{
+						CurrentConfig:  map[string][]string{"PF_BAR2_ENABLE": {"0"}},
+						NextBootConfig: map[string][]string{"PF_BAR2_ENABLE": {"0"}},
+						DefaultConfig:  map[string][]string{"PF_BAR2_ENABLE": {"0"}},
+					}
+					desiredConfig := map[string]string{"PF_BAR2_ENABLE": "1", "PF_BAR2_SIZE": "4"}
+
+					mockHostUtils.On("QueryNvConfig", ctx, pciAddress).
+						Return(nvConfig, nil)
+					mockConfigValidation.On("ConstructNvParamMapFromTemplate", device, nvConfig).
+						Return(desiredConfig, nil)
+					mockConfigValidation.On("AdvancedPCISettingsEnabled", nvConfig).
+						Return(true)
+
+					configUpdate, reboot, err := manager.ValidateDeviceNvSpec(ctx, device)
+					Expect(configUpdate).To(BeTrue())
+					Expect(reboot).To(BeTrue())
+					Expect(err).NotTo(HaveOccurred())
+				})
+			})
+
+			Context("when desired config contains string aliases", func() {
+				It("should accept lowercase parameters", func() {
+					nvConfig := types.NvConfigQuery{
+						CurrentConfig:  map[string][]string{"param1": {"value1", "1"}, "param2": {"value2", "2"}},
+						NextBootConfig: map[string][]string{"param1": {"value1", "1"}, "param2": {"value2", "2"}},
+						DefaultConfig:  map[string][]string{"param1": {"default1", "1"}, "param2": {"default2", "2"}},
+					}
+					desiredConfig := map[string]string{"param1": "value1", "param2": "2"}
+
+					mockHostUtils.On("QueryNvConfig", ctx, pciAddress).
+						Return(nvConfig, nil)
+					mockConfigValidation.On("ConstructNvParamMapFromTemplate", device, nvConfig).
+						Return(desiredConfig, nil)
+					mockConfigValidation.On("AdvancedPCISettingsEnabled", nvConfig).
+						Return(true)
+
+					configUpdate, reboot, err := manager.ValidateDeviceNvSpec(ctx, device)
+					Expect(configUpdate).To(BeFalse())
+					Expect(reboot).To(BeFalse())
+					Expect(err).To(BeNil())
+
+					mockHostUtils.AssertExpectations(GinkgoT())
+					mockConfigValidation.AssertExpectations(GinkgoT())
+				})
+				It("should accept mixed-case parameters", func() {
+					nvConfig := types.NvConfigQuery{
+						CurrentConfig:  map[string][]string{"param1": {"value1", "1"}, "param2": {"value2", "2"}},
+						NextBootConfig: map[string][]string{"param1": {"value1", "1"}, "param2": {"value2", "2"}},
+						DefaultConfig:  map[string][]string{"param1": {"default1", "1"}, "param2": {"default2", "2"}},
+					}
+					desiredConfig := map[string]string{"param1": "VaLuE1", "param2": "valUE2"}
+
+					mockHostUtils.On("QueryNvConfig", ctx, pciAddress).
 						Return(nvConfig, nil)
 					mockConfigValidation.On("ConstructNvParamMapFromTemplate", device, nvConfig).
 						Return(desiredConfig, nil)
@@ -731,6 +1507,8 @@ var _ = Describe("HostManager", func() {
 			}
 			ctx = context.TODO()
 			pciAddress = "0000:3b:00.0"
+			mockHostUtils.On("SimulateNvConfigParameters", mock.Anything, mock.Anything).Return(false, nil).Maybe()
+			mockHostUtils.On("GetHostBootID").Return("boot-1", nil).Maybe()
 
 			device = &v1alpha1.NicDevice{
 				Spec: v1alpha1.NicDeviceSpec{
@@ -858,6 +1636,28 @@ var _ = Describe("HostManager", func() {
 						mockConfigValidation.AssertExpectations(GinkgoT())
 					})
 
+					It("should request reboot without FW reset if disruption is set to reboot", func() {
+						device.Spec.Configuration.Disruption = consts.DisruptionReboot
+						nvConfig := types.NvConfigQuery{
+							CurrentConfig:  map[string][]string{"param1": {"value1"}},
+							NextBootConfig: map[string][]string{"param1": {"value1"}},
+							DefaultConfig:  map[string][]string{"param1": {"default1"}},
+						}
+						mockHostUtils.On("QueryNvConfig", ctx, pciAddress).Return(nvConfig, nil)
+						mockConfigValidation.On("AdvancedPCISettingsEnabled", nvConfig).
+							Return(false)
+						mockHostUtils.
+							On("SetNvConfigParameter", pciAddress, consts.AdvancedPCISettingsParam, consts.NvParamTrue).
+							Return(nil)
+
+						reboot, err := manager.ApplyDeviceNvSpec(ctx, device)
+						Expect(reboot).To(BeTrue())
+						Expect(err).To(BeNil())
+
+						mockHostUtils.AssertNotCalled(GinkgoT(), "ResetNicFirmware", mock.Anything, mock.Anything)
+						mockHostUtils.AssertExpectations(GinkgoT())
+					})
+
 					It("should request reboot if ResetNicFirmware fails", func() {
 						nvConfig := types.NvConfigQuery{
 							CurrentConfig:  map[string][]string{"param1": {"value1"}},
@@ -957,6 +1757,63 @@ var _ = Describe("HostManager", func() {
 						mockConfigValidation.AssertExpectations(GinkgoT())
 					})
 
+					It("should let the firmware validate the values before applying them", func() {
+						nvConfig := types.NvConfigQuery{
+							CurrentConfig:  map[string][]string{"param1": {"value1"}},
+							NextBootConfig: map[string][]string{"param1": {"value1"}, "param2": {"value2"}},
+							DefaultConfig:  map[string][]string{"param1": {"default1"}},
+						}
+						desiredConfig := map[string]string{"param1": "value2", "param2": "value2"}
+
+						mockHostUtils.ExpectedCalls = nil
+						mockHostUtils.On("QueryNvConfig", ctx, pciAddress).
+							Return(nvConfig, nil)
+						mockConfigValidation.On("AdvancedPCISettingsEnabled", nvConfig).
+							Return(true)
+						mockConfigValidation.On("ConstructNvParamMapFromTemplate", device, nvConfig).
+							Return(desiredConfig, nil)
+						mockHostUtils.On("SimulateNvConfigParameters", pciAddress, map[string]string{"param1": "value2"}).
+							Return(true, nil).Once()
+						mockHostUtils.On("GetHostBootID").Return("boot-1", nil)
+						mockHostUtils.On("SetNvConfigParameter", pciAddress, "param1", "value2").
+							Return(nil)
+
+						reboot, err := manager.ApplyDeviceNvSpec(ctx, device)
+						Expect(reboot).To(BeTrue())
+						Expect(err).To(BeNil())
+
+						mockHostUtils.AssertExpectations(GinkgoT())
+						mockConfigValidation.AssertExpectations(GinkgoT())
+					})
+
+					It("should not change the nv config if the firmware rejects the values", func() {
+						nvConfig := types.NvConfigQuery{
+							CurrentConfig:  map[string][]string{"param1": {"value1"}, "param2": {"value1"}},
+							NextBootConfig: map[string][]string{"param1": {"value1"}, "param2": {"value1"}},
+							DefaultConfig:  map[string][]string{"param1": {"default1"}, "param2": {"default1"}},
+						}
+						desiredConfig := map[string]string{"param1": "value2", "param2": "value2"}
+						simulateErr := types.IncorrectSpecError("nv config values rejected by the firmware of device 0000:3b:00.0: -E- param2 depends on param3")
+
+						mockHostUtils.ExpectedCalls = nil
+						mockHostUtils.On("QueryNvConfig", ctx, pciAddress).
+							Return(nvConfig, nil)
+						mockConfigValidation.On("AdvancedPCISettingsEnabled", nvConfig).
+							Return(true)
+						mockConfigValidation.On("ConstructNvParamMapFromTemplate", device, nvConfig).
+							Return(desiredConfig, nil)
+						mockHostUtils.On("SimulateNvConfigParameters", pciAddress, desiredConfig).
+							Return(true, simulateErr)
+
+						reboot, err := manager.ApplyDeviceNvSpec(ctx, device)
+						Expect(reboot).To(BeFalse())
+						Expect(types.IsIncorrectSpecError(err)).To(BeTrue())
+						Expect(err).To(MatchError(ContainSubstring("param2 depends on param3")))
+
+						mockHostUtils.AssertNotCalled(GinkgoT(), "SetNvConfigParameter", mock.Anything, mock.Anything, mock.Anything)
+						mockHostUtils.AssertExpectations(GinkgoT())
+					})
+
 					It("should return error if ConstructNvParamMapFromTemplate fails", func() {
 						nvConfig := types.NvConfigQuery{
 							CurrentConfig:  map[string][]string{"param1": {"value1"}},
@@ -1006,6 +1863,60 @@ var _ = Describe("HostManager", func() {
 						mockConfigValidation.AssertExpectations(GinkgoT())
 					})
 
+					It("should query nv config again if the next boot config is missing a parameter", func() {
+						staleNvConfig := types.NvConfigQuery{
+							CurrentConfig:  map[string][]string{"param1": {"value1"}},
+							NextBootConfig: map[string][]string{"param1": {"value1"}},
+						}
+						nvConfig := types.NvConfigQuery{
+							CurrentConfig:  map[string][]string{"param1": {"value1"}},
+							NextBootConfig: map[string][]string{"param1": {"value1"}, "param2": {"value1"}},
+						}
+						desiredConfig := map[string]string{"param1": "value1", "param2": "value2"}
+
+						mockHostUtils.On("QueryNvConfig", ctx, pciAddress).
+							Return(staleNvConfig, nil).Once()
+						mockHostUtils.On("QueryNvConfig", ctx, pciAddress).
+							Return(nvConfig, nil).Once()
+						mockConfigValidation.On("AdvancedPCISettingsEnabled", staleNvConfig).
+							Return(true)
+						mockConfigValidation.On("ConstructNvParamMapFromTemplate", device, staleNvConfig).
+							Return(desiredConfig, nil)
+						mockConfigValidation.On("ConstructNvParamMapFromTemplate", device, nvConfig).
+							Return(desiredConfig, nil)
+						mockHostUtils.On("SetNvConfigParameter", pciAddress, "param2", "value2").
+							Return(nil)
+
+						reboot, err := manager.ApplyDeviceNvSpec(ctx, device)
+						Expect(reboot).To(BeTrue())
+						Expect(err).To(BeNil())
+
+						mockHostUtils.AssertExpectations(GinkgoT())
+						mockConfigValidation.AssertExpectations(GinkgoT())
+					})
+
+					It("should not query nv config again for the deterministic spec errors", func() {
+						nvConfig := types.NvConfigQuery{
+							CurrentConfig:  map[string][]string{"param1": {"value1"}},
+							NextBootConfig: map[string][]string{"param1": {"value1"}},
+						}
+						specErr := types.IncorrectSpecError("RoceOptimized settings can only be used with link type Ethernet")
+
+						mockHostUtils.On("QueryNvConfig", ctx, pciAddress).
+							Return(nvConfig, nil).Once()
+						mockConfigValidation.On("AdvancedPCISettingsEnabled", nvConfig).
+							Return(true)
+						mockConfigValidation.On("ConstructNvParamMapFromTemplate", device, nvConfig).
+							Return(nil, specErr).Once()
+
+						reboot, err := manager.ApplyDeviceNvSpec(ctx, device)
+						Expect(reboot).To(BeFalse())
+						Expect(err).To(MatchError(specErr))
+
+						mockHostUtils.AssertNumberOfCalls(GinkgoT(), "QueryNvConfig", 1)
+						mockConfigValidation.AssertExpectations(GinkgoT())
+					})
+
 					It("should return error if SetNvConfigParameter fails while applying params", func() {
 						nvConfig := types.NvConfigQuery{
 							CurrentConfig:  map[string][]string{"param1": {"value1"}},
@@ -1057,20 +1968,32 @@ var _ = Describe("HostManager", func() {
 					reboot, err := manager.ApplyDeviceNvSpec(ctx, device)
 					Expect(reboot).To(BeTrue())
 					Expect(err).To(BeNil())
+					Expect(device.Status.NvConfigWriteStats).To(Equal(&v1alpha1.NvConfigWriteStats{
+						Writes: 2,
+						Unconverged: []v1alpha1.NvConfigUnconvergedWrite{
+							{Name: "param1", Value: "newValue3", Writes: 1, BootID: "boot-1"},
+							{Name: "param2", Value: "newValue3", Writes: 1, BootID: "boot-1"},
+						},
+					}))
 
 					mockHostUtils.AssertExpectations(GinkgoT())
 					mockConfigValidation.AssertExpectations(GinkgoT())
 				})
-			})
 
-			Context("when no parameters need to be applied", func() {
-				It("should return true without applying any parameters", func() {
+				It("should count repeated writes of the same value and restart the count for a new value", func() {
 					nvConfig := types.NvConfigQuery{
-						CurrentConfig:  map[string][]string{"param1": {"value1"}},
-						NextBootConfig: map[string][]string{"param1": {"value1"}},
-						DefaultConfig:  map[string][]string{"param1": {"default1"}},
+						CurrentConfig:  map[string][]string{"param1": {"oldValue1"}, "param2": {"oldValue2"}},
+						NextBootConfig: map[string][]string{"param1": {"oldValue1"}, "param2": {"oldValue2"}},
+						DefaultConfig:  map[string][]string{"param1": {"default1"}, "param2": {"default2"}},
+					}
+					desiredConfig := map[string]string{"param1": "newValue1", "param2": "newValue2"}
+					device.Status.NvConfigWriteStats = &v1alpha1.NvConfigWriteStats{
+						Writes: 3,
+						Unconverged: []v1alpha1.NvConfigUnconvergedWrite{
+							{Name: "param1", Value: "newValue1", Writes: 2},
+							{Name: "param2", Value: "staleValue", Writes: 1, Reboots: 2},
+						},
 					}
-					desiredConfig := map[string]string{"param1": "value1"}
 
 					mockHostUtils.On("QueryNvConfig", ctx, pciAddress).
 						Return(nvConfig, nil)
@@ -1078,15 +2001,1344 @@ var _ = Describe("HostManager", func() {
 						Return(true)
 					mockConfigValidation.On("ConstructNvParamMapFromTemplate", device, nvConfig).
 						Return(desiredConfig, nil)
+					mockHostUtils.On("SetNvConfigParameter", pciAddress, "param1", "newValue1").
+						Return(nil)
+					mockHostUtils.On("SetNvConfigParameter", pciAddress, "param2", "newValue2").
+						Return(nil)
 
 					reboot, err := manager.ApplyDeviceNvSpec(ctx, device)
 					Expect(reboot).To(BeTrue())
 					Expect(err).To(BeNil())
+					Expect(device.Status.NvConfigWriteStats.Unconverged).To(Equal([]v1alpha1.NvConfigUnconvergedWrite{
+						{Name: "param1", Value: "newValue1", Writes: 3, BootID: "boot-1"},
+						{Name: "param2", Value: "newValue2", Writes: 1, BootID: "boot-1"},
+					}))
+				})
+			})
+
+			Context("when applying parameters fails midway", func() {
+				var nvConfig types.NvConfigQuery
+
+				BeforeEach(func() {
+					nvConfig = types.NvConfigQuery{
+						CurrentConfig:  map[string][]string{"LINK_TYPE_P1": {"eth", "2"}, "param2": {"oldValue2"}},
+						NextBootConfig: map[string][]string{"LINK_TYPE_P1": {"eth", "2"}, "param2": {"oldValue2"}},
+						DefaultConfig:  map[string][]string{"LINK_TYPE_P1": {"eth", "2"}, "param2": {"default2"}},
+					}
+					desiredConfig := map[string]string{"LINK_TYPE_P1": "1", "param2": "newValue2"}
+
+					mockHostUtils.On("QueryNvConfig", ctx, pciAddress).
+						Return(nvConfig, nil)
+					mockConfigValidation.On("AdvancedPCISettingsEnabled", nvConfig).
+						Return(true)
+					mockConfigValidation.On("ConstructNvParamMapFromTemplate", device, nvConfig).
+						Return(desiredConfig, nil)
+					mockHostUtils.On("SetNvConfigParameter", pciAddress, "LINK_TYPE_P1", "1").
+						Return(nil).Once()
+				})
+
+				It("should restore the previous values of the applied parameters", func() {
+					mockHostUtils.On("SetNvConfigParameter", pciAddress, "param2", "newValue2").
+						Return(errors.New("failed to set param2"))
+					// Numeric value of the parameter is restored
+					mockHostUtils.On("SetNvConfigParameter", pciAddress, "LINK_TYPE_P1", "2").
+						Return(nil)
+
+					reboot, err := manager.ApplyDeviceNvSpec(ctx, device)
+					Expect(reboot).To(BeFalse())
+					Expect(types.IsRolledBackError(err)).To(BeTrue())
+					Expect(err.Error()).To(ContainSubstring("failed to set param2"))
+					Expect(device.Status.NvConfigWriteStats).To(BeNil())
 
 					mockHostUtils.AssertExpectations(GinkgoT())
-					mockConfigValidation.AssertExpectations(GinkgoT())
+				})
+
+				It("should return both errors if the rollback fails", func() {
+					setParamErr := errors.New("failed to set param2")
+					mockHostUtils.On("SetNvConfigParameter", pciAddress, "param2", "newValue2").
+						Return(setParamErr)
+					mockHostUtils.On("SetNvConfigParameter", pciAddress, "LINK_TYPE_P1", "2").
+						Return(errors.New("failed to restore LINK_TYPE_P1"))
+
+					_, err := manager.ApplyDeviceNvSpec(ctx, device)
+					Expect(err).To(MatchError(setParamErr))
+					Expect(types.IsRolledBackError(err)).To(BeFalse())
+					Expect(err.Error()).To(ContainSubstring("failed to restore LINK_TYPE_P1"))
+				})
+
+				It("should not roll back if the host tool got stuck", func() {
+					toolHangErr := types.ToolHangError("mstconfig didn't finish")
+					mockHostUtils.On("SetNvConfigParameter", pciAddress, "param2", "newValue2").
+						Return(toolHangErr)
+
+					_, err := manager.ApplyDeviceNvSpec(ctx, device)
+					Expect(err).To(MatchError(toolHangErr))
+					mockHostUtils.AssertNotCalled(GinkgoT(), "SetNvConfigParameter", pciAddress, "LINK_TYPE_P1", "2")
 				})
 			})
-		})
-	})
-})
+
+			Context("when parameters depend on each other", func() {
+				It("should apply enable flags first and unlocked parameters after re-querying nv config", func() {
+					nvConfig := types.NvConfigQuery{
+						CurrentConfig:  map[string][]string{"PF_BAR2_ENABLE": {"0"}},
+						NextBootConfig: map[string][]string{"PF_BAR2_ENABLE": {"0"}},
+						DefaultConfig:  map[string][]string{"PF_BAR2_ENABLE": {"0"}},
+					}
+					unlockedNvConfig := types.NvConfigQuery{
+						CurrentConfig:  map[string][]string{"PF_BAR2_ENABLE": {"0"}},
+						NextBootConfig: map[string][]string{"PF_BAR2_ENABLE": {"1"}, "PF_BAR2_SIZE": {"0"}},
+						DefaultConfig:  map[string][]string{"PF_BAR2_ENABLE": {"0"}, "PF_BAR2_SIZE": {"0"}},
+					}
+					desiredConfig := map[string]string{"PF_BAR2_ENABLE": "1", "PF_BAR2_SIZE": "4"}
+
+					mockHostUtils.On("QueryNvConfig", ctx, pciAddress).
+						Return(nvConfig, nil).Once()
+					mockConfigValidation.On("AdvancedPCISettingsEnabled", nvConfig).
+						Return(true)
+					mockConfigValidation.On("ConstructNvParamMapFromTemplate", device, nvConfig).
+						Return(desiredConfig, nil)
+					enableCall := mockHostUtils.On("SetNvConfigParameter", pciAddress, "PF_BAR2_ENABLE", "1").
+						Return(nil)
+					mockHostUtils.On("QueryNvConfig", ctx, pciAddress).
+						Return(unlockedNvConfig, nil).Once().NotBefore(enableCall)
+					mockHostUtils.On("SetNvConfigParameter", pciAddress, "PF_BAR2_SIZE", "4").
+						Return(nil)
+
+					reboot, err := manager.ApplyDeviceNvSpec(ctx, device)
+					Expect(reboot).To(BeTrue())
+					Expect(err).To(BeNil())
+
+					mockHostUtils.AssertExpectations(GinkgoT())
+					mockConfigValidation.AssertExpectations(GinkgoT())
+				})
+
+				It("should roll back the first stage and return error if the parameter is not unlocked by its prerequisites", func() {
+					nvConfig := types.NvConfigQuery{
+						NextBootConfig: map[string][]string{"PF_BAR2_ENABLE": {"0"}},
+					}
+					desiredConfig := map[string]string{"PF_BAR2_ENABLE": "1", "PF_BAR2_SIZE": "4"}
+
+					mockHostUtils.On("QueryNvConfig", ctx, pciAddress).
+						Return(nvConfig, nil)
+					mockConfigValidation.On("AdvancedPCISettingsEnabled", nvConfig).
+						Return(true)
+					mockConfigValidation.On("ConstructNvParamMapFromTemplate", device, nvConfig).
+						Return(desiredConfig, nil)
+					enableCall := mockHostUtils.On("SetNvConfigParameter", pciAddress, "PF_BAR2_ENABLE", "1").
+						Return(nil).Once()
+					mockHostUtils.On("SetNvConfigParameter", pciAddress, "PF_BAR2_ENABLE", "0").
+						Return(nil).Once().NotBefore(enableCall)
+
+					reboot, err := manager.ApplyDeviceNvSpec(ctx, device)
+					Expect(reboot).To(BeFalse())
+					Expect(types.IsIncorrectSpecError(err)).To(BeTrue())
+					Expect(err.Error()).To(ContainSubstring("Parameter PF_BAR2_SIZE unsupported"))
+					Expect(err.Error()).To(ContainSubstring("restored previous values of 1 parameters"))
+					Expect(device.Status.NvConfigWriteStats).To(BeNil())
+					mockHostUtils.AssertNotCalled(GinkgoT(), "SetNvConfigParameter", pciAddress, "PF_BAR2_SIZE", "4")
+					mockHostUtils.AssertExpectations(GinkgoT())
+				})
+			})
+
+			Context("when changelog is enabled", func() {
+				var sink *recordingSink
+
+				BeforeEach(func() {
+					sink = &recordingSink{}
+					manager.nodeName = "node-1"
+					manager.changelog = sink
+					device.Name = "node-1-cx6-sn1"
+					device.Status.SerialNumber = "sn1"
+					device.Status.FirmwareVersion = "22.42.1000"
+				})
+
+				It("should record the applied changes", func() {
+					nvConfig := types.NvConfigQuery{
+						CurrentConfig:  map[string][]string{"param1": {"value1"}, "param2": {"value2"}},
+						NextBootConfig: map[string][]string{"param1": {"value1"}, "param2": {"value2"}},
+						DefaultConfig:  map[string][]string{"param1": {"default1"}, "param2": {"default2"}},
+					}
+					desiredConfig := map[string]string{"param1": "value1", "param2": "newValue2"}
+
+					mockHostUtils.On("QueryNvConfig", ctx, pciAddress).
+						Return(nvConfig, nil)
+					mockConfigValidation.On("AdvancedPCISettingsEnabled", nvConfig).
+						Return(true)
+					mockConfigValidation.On("ConstructNvParamMapFromTemplate", device, nvConfig).
+						Return(desiredConfig, nil)
+					mockHostUtils.On("SetNvConfigParameter", pciAddress, "param2", "newValue2").
+						Return(nil)
+
+					reboot, err := manager.ApplyDeviceNvSpec(ctx, device)
+					Expect(reboot).To(BeTrue())
+					Expect(err).To(BeNil())
+
+					Expect(sink.records).To(HaveLen(1))
+					record := sink.records[0]
+					Expect(record.Node).To(Equal("node-1"))
+					Expect(record.Device).To(Equal("node-1-cx6-sn1"))
+					Expect(record.SerialNumber).To(Equal("sn1"))
+					Expect(record.FirmwareVersion).To(Equal("22.42.1000"))
+					Expect(record.ResetToDefault).To(BeFalse())
+					Expect(record.Changes).To(Equal([]changelog.Change{
+						{Parameter: "param2", PreviousValues: []string{"value2"}, Value: "newValue2"},
+					}))
+				})
+
+				It("should record the reset to default", func() {
+					device.Spec.Configuration.ResetToDefault = true
+					mockHostUtils.On("ResetNvConfig", pciAddress).Return(nil)
+					mockHostUtils.On("SetNvConfigParameter", pciAddress, consts.AdvancedPCISettingsParam, consts.NvParamTrue).
+						Return(nil)
+
+					reboot, err := manager.ApplyDeviceNvSpec(ctx, device)
+					Expect(reboot).To(BeTrue())
+					Expect(err).To(BeNil())
+
+					Expect(sink.records).To(HaveLen(1))
+					Expect(sink.records[0].ResetToDefault).To(BeTrue())
+					Expect(device.Status.NvConfigWriteStats).To(Equal(&v1alpha1.NvConfigWriteStats{Writes: 1, Resets: 1}))
+				})
+
+				It("should not record anything if no parameters were applied", func() {
+					nvConfig := types.NvConfigQuery{
+						NextBootConfig: map[string][]string{"param1": {"value1"}},
+					}
+
+					mockHostUtils.On("QueryNvConfig", ctx, pciAddress).
+						Return(nvConfig, nil)
+					mockConfigValidation.On("AdvancedPCISettingsEnabled", nvConfig).
+						Return(true)
+					mockConfigValidation.On("ConstructNvParamMapFromTemplate", device, nvConfig).
+						Return(map[string]string{"param1": "value1"}, nil)
+
+					_, err := manager.ApplyDeviceNvSpec(ctx, device)
+					Expect(err).To(BeNil())
+					Expect(sink.records).To(BeEmpty())
+					Expect(device.Status.NvConfigWriteStats).To(BeNil())
+				})
+
+				It("should not fail if the changelog entry can't be recorded", func() {
+					sink.err = errors.New("sink unavailable")
+					nvConfig := types.NvConfigQuery{
+						NextBootConfig: map[string][]string{"param1": {"value1"}},
+					}
+
+					mockHostUtils.On("QueryNvConfig", ctx, pciAddress).
+						Return(nvConfig, nil)
+					mockConfigValidation.On("AdvancedPCISettingsEnabled", nvConfig).
+						Return(true)
+					mockConfigValidation.On("ConstructNvParamMapFromTemplate", device, nvConfig).
+						Return(map[string]string{"param1": "value2"}, nil)
+					mockHostUtils.On("SetNvConfigParameter", pciAddress, "param1", "value2").
+						Return(nil)
+
+					reboot, err := manager.ApplyDeviceNvSpec(ctx, device)
+					Expect(reboot).To(BeTrue())
+					Expect(err).To(BeNil())
+				})
+			})
+
+			Context("when no parameters need to be applied", func() {
+				It("should return true without applying any parameters", func() {
+					nvConfig := types.NvConfigQuery{
+						CurrentConfig:  map[string][]string{"param1": {"value1"}},
+						NextBootConfig: map[string][]string{"param1": {"value1"}},
+						DefaultConfig:  map[string][]string{"param1": {"default1"}},
+					}
+					desiredConfig := map[string]string{"param1": "value1"}
+
+					mockHostUtils.On("QueryNvConfig", ctx, pciAddress).
+						Return(nvConfig, nil)
+					mockConfigValidation.On("AdvancedPCISettingsEnabled", nvConfig).
+						Return(true)
+					mockConfigValidation.On("ConstructNvParamMapFromTemplate", device, nvConfig).
+						Return(desiredConfig, nil)
+
+					reboot, err := manager.ApplyDeviceNvSpec(ctx, device)
+					Expect(reboot).To(BeTrue())
+					Expect(err).To(BeNil())
+
+					mockHostUtils.AssertExpectations(GinkgoT())
+					mockConfigValidation.AssertExpectations(GinkgoT())
+				})
+			})
+		})
+
+		Describe("RollbackDeviceNvSpec", func() {
+			BeforeEach(func() {
+				device.Status.NvConfigParameters = []v1alpha1.NvConfigParameterStatus{
+					{Name: "LINK_TYPE_P1", DesiredValue: "1", NextBootValues: []string{"eth", "2"}},
+					{Name: "SRIOV_EN", DesiredValue: "1", NextBootValues: []string{"True", "1"}},
+					{Name: "NUM_OF_VFS", DesiredValue: "8"},
+				}
+			})
+
+			It("should restore the validated next boot values of the changed parameters", func() {
+				mockHostUtils.On("SetNvConfigParameter", pciAddress, "LINK_TYPE_P1", "2").
+					Return(nil)
+
+				err := manager.RollbackDeviceNvSpec(ctx, device)
+				Expect(err).NotTo(HaveOccurred())
+				// The rollback restores the values the writes of the spec replaced, it isn't counted as a write of the spec
+				Expect(device.Status.NvConfigWriteStats).To(BeNil())
+
+				mockHostUtils.AssertExpectations(GinkgoT())
+				mockHostUtils.AssertNumberOfCalls(GinkgoT(), "SetNvConfigParameter", 1)
+			})
+
+			It("should return an error if a parameter can't be restored", func() {
+				mockHostUtils.On("SetNvConfigParameter", pciAddress, "LINK_TYPE_P1", "2").
+					Return(errors.New("mlxconfig failed"))
+
+				err := manager.RollbackDeviceNvSpec(ctx, device)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("LINK_TYPE_P1"))
+			})
+
+			It("should skip the parameters set from Secrets", func() {
+				device.Status.NvConfigParameters[0].DesiredValue = consts.RedactedNvParamValue
+				device.Status.NvConfigParameters[0].NextBootValues = []string{consts.RedactedNvParamValue}
+
+				err := manager.RollbackDeviceNvSpec(ctx, device)
+				Expect(err).NotTo(HaveOccurred())
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetNvConfigParameter", mock.Anything, mock.Anything, mock.Anything)
+			})
+
+			It("should not roll back the nv config reset", func() {
+				device.Spec.Configuration.ResetToDefault = true
+
+				err := manager.RollbackDeviceNvSpec(ctx, device)
+				Expect(err).To(HaveOccurred())
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetNvConfigParameter", mock.Anything, mock.Anything, mock.Anything)
+			})
+		})
+	})
+
+	Describe("hostManager.ApplyDeviceRuntimeSpec", func() {
+		var (
+			mockHostUtils        mocks.HostUtils
+			mockConfigValidation mocks.ConfigValidation
+			manager              hostManager
+			device               *v1alpha1.NicDevice
+			pciAddress           string
+			interfaceName        string
+			dcbAppEntries        []string
+			rootQdisc            string
+		)
+
+		devlinkResources := func(size uint64, sizeNew *uint64) map[string]types.DevlinkResource {
+			return map[string]types.DevlinkResource{
+				"/kvd/linear": {Size: size, SizeNew: sizeNew, SizeMin: 0, SizeMax: 4096, SizeGranularity: 128, Unit: "entry"},
+			}
+		}
+
+		BeforeEach(func() {
+			mockHostUtils = mocks.HostUtils{}
+			mockConfigValidation = mocks.ConfigValidation{}
+			manager = hostManager{
+				hostUtils:        &mockHostUtils,
+				configValidation: &mockConfigValidation,
+			}
+			pciAddress = "0000:3b:00.0"
+
+			device = &v1alpha1.NicDevice{
+				Spec: v1alpha1.NicDeviceSpec{
+					Configuration: &v1alpha1.NicDeviceConfigurationSpec{
+						Template: &v1alpha1.ConfigurationTemplateSpec{
+							DevlinkResources: []v1alpha1.DevlinkResourceSpec{{Path: "/kvd/linear", Size: 1024}},
+						},
+					},
+				},
+				Status: v1alpha1.NicDeviceStatus{
+					Ports: []v1alpha1.NicDevicePortSpec{
+						{PCI: pciAddress, NetworkInterface: "eth0"},
+					},
+				},
+			}
+
+			mockConfigValidation.On("RuntimeConfigApplied", device).Return(false, nil)
+			mockConfigValidation.On("CalculateDesiredRuntimeConfig", device).Return(0, "dscp", "0,0,0,1,0,0,0,0")
+			interfaceName = "eth0"
+			mockHostUtils.On("GetInterfaceName", pciAddress).Return(func(string) string { return interfaceName })
+			dcbAppEntries, rootQdisc = nil, "mq"
+			mockHostUtils.On("GetDcbAppEntries", mock.Anything).Return(func(string) []string { return dcbAppEntries }, nil).Maybe()
+			mockHostUtils.On("GetRootQdisc", mock.Anything).Return(func(string) string { return rootQdisc }, nil).Maybe()
+		})
+
+		Context("when network interface was renamed", func() {
+			It("should apply the runtime config to the new interface and update the status", func() {
+				recorder := record.NewFakeRecorder(10)
+				manager.eventRecorder = recorder
+				device.Spec.Configuration.Template.DevlinkResources = nil
+				interfaceName = "enp59s0f0np0"
+				mockHostUtils.On("SetTrustAndPFC", "enp59s0f0np0", "dscp", "0,0,0,1,0,0,0,0").Return(nil)
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetTrustAndPFC", "eth0", mock.Anything, mock.Anything)
+				Expect(device.Status.Ports[0].NetworkInterface).To(Equal("enp59s0f0np0"))
+
+				Expect(recorder.Events).To(HaveLen(1))
+				event := <-recorder.Events
+				Expect(event).To(ContainSubstring(consts.NetworkInterfaceRenamedReason))
+				Expect(event).To(ContainSubstring("renamed from eth0 to enp59s0f0np0"))
+			})
+			It("should keep the last known name if the interface is missing", func() {
+				device.Spec.Configuration.Template.DevlinkResources = nil
+				interfaceName = ""
+				mockHostUtils.On("SetTrustAndPFC", "eth0", "dscp", "0,0,0,1,0,0,0,0").Return(nil)
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				Expect(device.Status.Ports[0].NetworkInterface).To(Equal("eth0"))
+			})
+		})
+
+		Context("when ETS bandwidth shares are requested", func() {
+			It("should allocate the bandwidth after the trust and PFC settings", func() {
+				device.Spec.Configuration.Template.DevlinkResources = nil
+				device.Spec.Configuration.Template.LinkType = consts.Ethernet
+				device.Spec.Configuration.Template.RoceOptimized = &v1alpha1.RoceOptimizedSpec{
+					Enabled: true,
+					Qos:     &v1alpha1.QosSpec{Trust: "dscp", PFC: "0,0,0,1,0,0,0,0", TcBandwidth: "0,0,0,60,40,0,0,0"},
+				}
+				mockHostUtils.On("SetTrustAndPFC", "eth0", "dscp", "0,0,0,1,0,0,0,0").Return(nil)
+				mockHostUtils.On("SetTcBandwidth", "eth0", "0,0,0,60,40,0,0,0").Return(nil).Run(func(args mock.Arguments) {
+					mockHostUtils.AssertCalled(GinkgoT(), "SetTrustAndPFC", "eth0", "dscp", "0,0,0,1,0,0,0,0")
+				})
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				mockHostUtils.AssertExpectations(GinkgoT())
+			})
+			It("should return an error if the bandwidth can't be allocated", func() {
+				device.Spec.Configuration.Template.DevlinkResources = nil
+				device.Spec.Configuration.Template.LinkType = consts.Ethernet
+				device.Spec.Configuration.Template.RoceOptimized = &v1alpha1.RoceOptimizedSpec{
+					Enabled: true,
+					Qos:     &v1alpha1.QosSpec{Trust: "dscp", PFC: "0,0,0,1,0,0,0,0", TcBandwidth: "0,0,0,60,40,0,0,0"},
+				}
+				mockHostUtils.On("SetTrustAndPFC", "eth0", "dscp", "0,0,0,1,0,0,0,0").Return(nil)
+				mockHostUtils.On("SetTcBandwidth", "eth0", "0,0,0,60,40,0,0,0").Return(errors.New("mlnx_qos failed"))
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(MatchError("mlnx_qos failed"))
+			})
+		})
+
+		Context("when congestion control is requested", func() {
+			BeforeEach(func() {
+				device.Spec.Configuration.Template.DevlinkResources = nil
+				device.Spec.Configuration.Template.LinkType = consts.Ethernet
+				device.Spec.Configuration.Template.RoceOptimized = &v1alpha1.RoceOptimizedSpec{
+					Enabled: true,
+					CongestionControl: &v1alpha1.CongestionControlSpec{
+						Ecn:   "0,0,0,1,0,0,0,0",
+						Dcqcn: &v1alpha1.DcqcnSpec{RpgMinRate: ptr.To(100), MinTimeBetweenCnps: ptr.To(4)},
+					},
+				}
+				mockHostUtils.On("SetTrustAndPFC", "eth0", "dscp", "0,0,0,1,0,0,0,0").Return(nil)
+			})
+
+			It("should enable ECN and set the DCQCN parameters", func() {
+				mockHostUtils.On("IsCongestionControlSupported", "eth0").Return(true)
+				mockHostUtils.On("SetEcn", "eth0", "0,0,0,1,0,0,0,0").Return(nil)
+				mockHostUtils.On("SetDcqcnParameter", "eth0", consts.DcqcnRpgMinRateParam, 100).Return(nil)
+				mockHostUtils.On("SetDcqcnParameter", "eth0", consts.DcqcnMinTimeBetweenCnpsParam, 4).Return(nil)
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				mockHostUtils.AssertExpectations(GinkgoT())
+			})
+			It("should return an error if ECN can't be enabled", func() {
+				mockHostUtils.On("IsCongestionControlSupported", "eth0").Return(true)
+				mockHostUtils.On("SetEcn", "eth0", "0,0,0,1,0,0,0,0").Return(errors.New("permission denied"))
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(MatchError("permission denied"))
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetDcqcnParameter", mock.Anything, mock.Anything, mock.Anything)
+			})
+			It("should return IncorrectSpec error without writing the settings if the driver doesn't support congestion control", func() {
+				mockHostUtils.On("IsCongestionControlSupported", "eth0").Return(false)
+
+				err := manager.ApplyDeviceRuntimeSpec(device)
+				Expect(types.IsIncorrectSpecError(err)).To(BeTrue())
+				Expect(err).To(MatchError(ContainSubstring("MOFED")))
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetTrustAndPFC", mock.Anything, mock.Anything, mock.Anything)
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetEcn", mock.Anything, mock.Anything)
+			})
+		})
+
+		Context("when ring sizes are requested", func() {
+			BeforeEach(func() {
+				device.Spec.Configuration.Template.DevlinkResources = nil
+				device.Spec.Configuration.Template.RingSize = &v1alpha1.RingSizeSpec{Rx: 16384, Tx: 2048}
+				mockHostUtils.On("SetTrustAndPFC", "eth0", "dscp", "0,0,0,1,0,0,0,0").Return(nil)
+			})
+
+			It("should set the ring sizes clamped to the maximums of the device", func() {
+				mockHostUtils.On("GetRingSizes", "eth0").Return(types.RingSizes{Rx: 1024, Tx: 1024, RxMax: 8192, TxMax: 8192}, nil)
+				mockHostUtils.On("SetRingSizes", "eth0", 8192, 2048).Return(nil)
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				mockHostUtils.AssertExpectations(GinkgoT())
+			})
+			It("should keep the ring sizes already matching the clamped values", func() {
+				mockHostUtils.On("GetRingSizes", "eth0").Return(types.RingSizes{Rx: 8192, Tx: 2048, RxMax: 8192, TxMax: 8192}, nil)
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetRingSizes", mock.Anything, mock.Anything, mock.Anything)
+			})
+			It("should return an error if the ring sizes can't be set", func() {
+				mockHostUtils.On("GetRingSizes", "eth0").Return(types.RingSizes{Rx: 1024, Tx: 1024, RxMax: 8192, TxMax: 8192}, nil)
+				mockHostUtils.On("SetRingSizes", "eth0", 8192, 2048).Return(errors.New("ethtool failed"))
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(MatchError(ContainSubstring("ethtool failed")))
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetTrustAndPFC", mock.Anything, mock.Anything, mock.Anything)
+			})
+			It("should skip the InfiniBand ports", func() {
+				device.Spec.Configuration.Template.Ports = []v1alpha1.PortConfigurationSpec{{Port: 1, LinkType: consts.Infiniband}}
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				mockHostUtils.AssertNotCalled(GinkgoT(), "GetRingSizes", mock.Anything)
+			})
+		})
+
+		Context("when channels are requested", func() {
+			BeforeEach(func() {
+				device.Spec.Configuration.Template.DevlinkResources = nil
+				device.Spec.Configuration.Template.Channels = &v1alpha1.ChannelsSpec{}
+				mockHostUtils.On("SetTrustAndPFC", "eth0", "dscp", "0,0,0,1,0,0,0,0").Return(nil)
+			})
+
+			It("should default the channels to the CPUs of the device's NUMA node", func() {
+				mockHostUtils.On("GetChannels", "eth0").Return(types.Channels{Combined: 63, CombinedMax: 63}, nil)
+				mockHostUtils.On("GetNumaNodeCPUCount", pciAddress).Return(16, nil)
+				mockHostUtils.On("SetCombinedChannels", "eth0", 16).Return(nil)
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				mockHostUtils.AssertExpectations(GinkgoT())
+			})
+			It("should keep the driver default if the device has no NUMA affinity", func() {
+				mockHostUtils.On("GetChannels", "eth0").Return(types.Channels{Combined: 63, CombinedMax: 63}, nil)
+				mockHostUtils.On("GetNumaNodeCPUCount", pciAddress).Return(0, nil)
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetCombinedChannels", mock.Anything, mock.Anything)
+			})
+			It("should reject the count exceeding the maximum of the device", func() {
+				device.Spec.Configuration.Template.Channels.Combined = 128
+				mockHostUtils.On("GetChannels", "eth0").Return(types.Channels{Combined: 63, CombinedMax: 63}, nil)
+
+				err := manager.ApplyDeviceRuntimeSpec(device)
+				Expect(types.IsIncorrectSpecError(err)).To(BeTrue())
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetCombinedChannels", mock.Anything, mock.Anything)
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetTrustAndPFC", mock.Anything, mock.Anything, mock.Anything)
+			})
+		})
+
+		Context("when QoS settings fail on the second port", func() {
+			const secondPciAddress = "0000:3b:00.1"
+
+			BeforeEach(func() {
+				device.Generation = 3
+				device.Spec.Configuration.Template.DevlinkResources = nil
+				device.Status.Ports = append(device.Status.Ports, v1alpha1.NicDevicePortSpec{PCI: secondPciAddress, NetworkInterface: "eth1"})
+				mockHostUtils.On("GetInterfaceName", secondPciAddress).Return("eth1")
+				mockHostUtils.On("GetHostBootID").Return("boot-1", nil)
+			})
+
+			It("should record the ports with the applied settings", func() {
+				mockHostUtils.On("SetTrustAndPFC", "eth0", "dscp", "0,0,0,1,0,0,0,0").Return(nil)
+				mockHostUtils.On("SetTrustAndPFC", "eth1", "dscp", "0,0,0,1,0,0,0,0").Return(errors.New("device busy"))
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(MatchError("device busy"))
+				Expect(device.Status.PartialRuntimeConfig).To(Equal(&v1alpha1.PartialRuntimeConfigStatus{
+					ObservedGeneration: 3,
+					BootID:             "boot-1",
+					AppliedPorts:       []string{pciAddress},
+					FailedPort:         secondPciAddress,
+					Error:              "device busy",
+				}))
+			})
+			It("should only retry the failed port in the same boot and clear the record", func() {
+				device.Status.PartialRuntimeConfig = &v1alpha1.PartialRuntimeConfigStatus{
+					ObservedGeneration: 3, BootID: "boot-1", AppliedPorts: []string{pciAddress}, FailedPort: secondPciAddress,
+				}
+				mockHostUtils.On("SetTrustAndPFC", "eth1", "dscp", "0,0,0,1,0,0,0,0").Return(nil)
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetTrustAndPFC", "eth0", mock.Anything, mock.Anything)
+				Expect(device.Status.PartialRuntimeConfig).To(BeNil())
+			})
+			It("should report the conflicts of the resumed ports", func() {
+				device.Status.PartialRuntimeConfig = &v1alpha1.PartialRuntimeConfigStatus{
+					ObservedGeneration: 3, BootID: "boot-1", AppliedPorts: []string{pciAddress}, FailedPort: secondPciAddress,
+				}
+				rootQdisc = "mqprio"
+				mockHostUtils.On("SetTrustAndPFC", "eth1", "dscp", "0,0,0,1,0,0,0,0").Return(nil)
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetTrustAndPFC", "eth0", mock.Anything, mock.Anything)
+				condition := meta.FindStatusCondition(device.Status.Conditions, consts.QosConflictCondition)
+				Expect(condition).NotTo(BeNil())
+				Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+				Expect(condition.Message).To(ContainSubstring("Port " + pciAddress + " (eth0)"))
+			})
+			It("should apply the settings to all ports after a reboot or a spec change", func() {
+				device.Status.PartialRuntimeConfig = &v1alpha1.PartialRuntimeConfigStatus{
+					ObservedGeneration: 3, BootID: "boot-0", AppliedPorts: []string{pciAddress}, FailedPort: secondPciAddress,
+				}
+				mockHostUtils.On("SetTrustAndPFC", mock.Anything, "dscp", "0,0,0,1,0,0,0,0").Return(nil)
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				mockHostUtils.AssertCalled(GinkgoT(), "SetTrustAndPFC", "eth0", "dscp", "0,0,0,1,0,0,0,0")
+
+				device.Status.PartialRuntimeConfig = &v1alpha1.PartialRuntimeConfigStatus{
+					ObservedGeneration: 2, BootID: "boot-1", AppliedPorts: []string{pciAddress}, FailedPort: secondPciAddress,
+				}
+				mockHostUtils.Calls = nil
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				mockHostUtils.AssertCalled(GinkgoT(), "SetTrustAndPFC", "eth0", "dscp", "0,0,0,1,0,0,0,0")
+			})
+			It("should not record anything if the first port fails", func() {
+				mockHostUtils.On("SetTrustAndPFC", "eth0", "dscp", "0,0,0,1,0,0,0,0").Return(errors.New("device busy"))
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(MatchError("device busy"))
+				Expect(device.Status.PartialRuntimeConfig).To(BeNil())
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetTrustAndPFC", "eth1", mock.Anything, mock.Anything)
+			})
+		})
+
+		Context("when MTU is requested", func() {
+			BeforeEach(func() {
+				device.Spec.Configuration.Template.DevlinkResources = nil
+				device.Spec.Configuration.Template.Mtu = &v1alpha1.MtuSpec{Size: 9000, PropagateToVfs: true}
+				mockHostUtils.On("SetTrustAndPFC", "eth0", "dscp", "0,0,0,1,0,0,0,0").Return(nil)
+				mockHostUtils.On("GetVfPciAddresses", pciAddress).Return([]string{"0000:3b:00.2", "0000:3b:00.3"}, nil)
+				mockHostUtils.On("GetInterfaceName", "0000:3b:00.2").Return("eth0v0")
+				// VF bound to vfio-pci
+				mockHostUtils.On("GetInterfaceName", "0000:3b:00.3").Return("")
+			})
+
+			It("should set the MTU of the PF before the VFs when it grows", func() {
+				mockHostUtils.On("GetMtu", "eth0").Return(1500, nil)
+				mockHostUtils.On("GetMtu", "eth0v0").Return(1500, nil)
+				var order []string
+				mockHostUtils.On("SetMtu", mock.Anything, 9000).Run(func(args mock.Arguments) {
+					order = append(order, args.String(0))
+				}).Return(nil)
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				Expect(order).To(Equal([]string{"eth0", "eth0v0"}))
+			})
+			It("should set the MTU of the VFs before the PF when it shrinks", func() {
+				device.Spec.Configuration.Template.Mtu.Size = 4200
+				mockHostUtils.On("GetMtu", "eth0").Return(9000, nil)
+				mockHostUtils.On("GetMtu", "eth0v0").Return(9000, nil)
+				var order []string
+				mockHostUtils.On("SetMtu", mock.Anything, 4200).Run(func(args mock.Arguments) {
+					order = append(order, args.String(0))
+				}).Return(nil)
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				Expect(order).To(Equal([]string{"eth0v0", "eth0"}))
+			})
+			It("should only set the MTU of the PF if it's not propagated", func() {
+				device.Spec.Configuration.Template.Ports = []v1alpha1.PortConfigurationSpec{{Port: 1, Mtu: &v1alpha1.MtuSpec{Size: 4200}}}
+				mockHostUtils.On("GetMtu", "eth0").Return(1500, nil)
+				mockHostUtils.On("SetMtu", "eth0", 4200).Return(nil)
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				mockHostUtils.AssertNotCalled(GinkgoT(), "GetVfPciAddresses", mock.Anything)
+				mockHostUtils.AssertNumberOfCalls(GinkgoT(), "SetMtu", 1)
+			})
+			It("should skip the VFs with their own MTU", func() {
+				mockHostUtils.On("GetMtu", "eth0").Return(1500, nil)
+				// MTU set by the SR-IOV network operator
+				mockHostUtils.On("GetMtu", "eth0v0").Return(4000, nil)
+				mockHostUtils.On("SetMtu", "eth0", 9000).Return(nil)
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				mockHostUtils.AssertNumberOfCalls(GinkgoT(), "SetMtu", 1)
+			})
+			It("should return an error if the MTU can't be set", func() {
+				mockHostUtils.On("GetMtu", "eth0").Return(1500, nil)
+				mockHostUtils.On("GetMtu", "eth0v0").Return(1500, nil)
+				mockHostUtils.On("SetMtu", "eth0", 9000).Return(errors.New("invalid argument"))
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(MatchError("failed to set MTU of interface eth0 of port 0000:3b:00.0: invalid argument"))
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetMtu", "eth0v0", mock.Anything)
+			})
+		})
+
+		Context("when sysctls are requested", func() {
+			BeforeEach(func() {
+				device.Spec.Configuration.Template.DevlinkResources = nil
+				device.Spec.Configuration.Template.Sysctls = []v1alpha1.SysctlSpec{
+					{Name: "net.ipv4.tcp_rmem", Value: "4096  131072 6291456"},
+					{Name: "net.ipv4.conf.{interface}.arp_announce", Value: "2"},
+				}
+				mockHostUtils.On("SetTrustAndPFC", "eth0", "dscp", "0,0,0,1,0,0,0,0").Return(nil)
+				DeferCleanup(func() { sysctlClaims = map[string][]sysctlClaim{} })
+			})
+
+			It("should only set the sysctls differing from the current values and record their original values", func() {
+				mockHostUtils.On("GetSysctl", "net.ipv4.tcp_rmem").Return("4096 131072 6291456", nil)
+				mockHostUtils.On("GetSysctl", "net.ipv4.conf.eth0.arp_announce").Return("0", nil)
+				mockHostUtils.On("SetSysctl", "net.ipv4.conf.eth0.arp_announce", "2").Return(nil)
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				mockHostUtils.AssertExpectations(GinkgoT())
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetSysctl", "net.ipv4.tcp_rmem", mock.Anything)
+				Expect(device.Status.AppliedSysctls).To(Equal([]v1alpha1.AppliedSysctlStatus{
+					{Name: "net.ipv4.conf.eth0.arp_announce", Value: "2", OriginalValue: "0"},
+					{Name: "net.ipv4.tcp_rmem", Value: "4096 131072 6291456", OriginalValue: "4096 131072 6291456"},
+				}))
+			})
+			It("should restore the original values of the sysctls the template no longer sets", func() {
+				device.Spec.Configuration.Template.Sysctls = device.Spec.Configuration.Template.Sysctls[:1]
+				device.Status.AppliedSysctls = []v1alpha1.AppliedSysctlStatus{
+					{Name: "net.ipv4.conf.eth0.arp_announce", Value: "2", OriginalValue: "0"},
+					{Name: "net.ipv4.tcp_rmem", Value: "4096 131072 6291456", OriginalValue: "4096 87380 6291456"},
+				}
+				mockHostUtils.On("GetSysctl", "net.ipv4.tcp_rmem").Return("4096 131072 6291456", nil)
+				mockHostUtils.On("SetSysctl", "net.ipv4.conf.eth0.arp_announce", "0").Return(nil)
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				mockHostUtils.AssertExpectations(GinkgoT())
+				Expect(device.Status.AppliedSysctls).To(Equal([]v1alpha1.AppliedSysctlStatus{
+					{Name: "net.ipv4.tcp_rmem", Value: "4096 131072 6291456", OriginalValue: "4096 87380 6291456"},
+				}))
+			})
+			It("should keep the sysctls the template no longer sets while another device sets them", func() {
+				device.Spec.Configuration.Template.Sysctls = device.Spec.Configuration.Template.Sysctls[1:]
+				device.Status.AppliedSysctls = []v1alpha1.AppliedSysctlStatus{
+					{Name: "net.ipv4.tcp_rmem", Value: "4096 131072 6291456", OriginalValue: "4096 87380 6291456"},
+				}
+				sysctlClaims["net.ipv4.tcp_rmem"] = []sysctlClaim{
+					{device: device.Name, value: "4096 131072 6291456", original: "4096 87380 6291456"},
+					{device: "other-device", value: "4096 131072 6291456", original: "4096 87380 6291456"},
+				}
+				mockHostUtils.On("GetSysctl", "net.ipv4.conf.eth0.arp_announce").Return("2", nil)
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetSysctl", mock.Anything, mock.Anything)
+				Expect(sysctlClaims["net.ipv4.tcp_rmem"]).To(HaveLen(1))
+				Expect(device.Status.AppliedSysctls).To(Equal([]v1alpha1.AppliedSysctlStatus{
+					{Name: "net.ipv4.conf.eth0.arp_announce", Value: "2", OriginalValue: "2"},
+				}))
+			})
+			It("should reject a sysctl set to another value for another device of the node", func() {
+				sysctlClaims["net.ipv4.tcp_rmem"] = []sysctlClaim{{device: "other-device", value: "4096 87380 6291456", original: "4096 87380 6291456"}}
+
+				err := manager.ApplyDeviceRuntimeSpec(device)
+				Expect(types.IsIncorrectSpecError(err)).To(BeTrue())
+				Expect(err).To(MatchError(ContainSubstring("sysctl net.ipv4.tcp_rmem is set to \"4096 87380 6291456\" for device other-device")))
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetSysctl", mock.Anything, mock.Anything)
+			})
+			It("should reject the sysctls missing from the host network namespace", func() {
+				mockHostUtils.On("GetSysctl", "net.ipv4.tcp_rmem").Return("4096 131072 6291456", nil)
+				mockHostUtils.On("GetSysctl", "net.ipv4.conf.eth0.arp_announce").Return("", &os.PathError{Op: "open", Err: os.ErrNotExist})
+
+				err := manager.ApplyDeviceRuntimeSpec(device)
+				Expect(types.IsIncorrectSpecError(err)).To(BeTrue())
+				Expect(err).To(MatchError(ContainSubstring("sysctls of the other network namespaces are not supported")))
+			})
+			It("should return an error if the sysctl can't be set", func() {
+				mockHostUtils.On("GetSysctl", "net.ipv4.tcp_rmem").Return("4096 87380 6291456", nil)
+				mockHostUtils.On("SetSysctl", "net.ipv4.tcp_rmem", mock.Anything).Return(errors.New("permission denied"))
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(MatchError("failed to set sysctl net.ipv4.tcp_rmem: permission denied"))
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetTrustAndPFC", mock.Anything, mock.Anything, mock.Anything)
+			})
+		})
+
+		Context("when runtime settings of the configured device drifted", func() {
+			var sink *lifecycleEventsSink
+
+			BeforeEach(func() {
+				sink = &lifecycleEventsSink{}
+				manager.lifecycleEvents = lifecycle.NewEmitter(sink, "node-1")
+				device.Spec.Configuration.Template.DevlinkResources = nil
+				mockHostUtils.On("SetTrustAndPFC", "eth0", "dscp", "0,0,0,1,0,0,0,0").Return(nil)
+			})
+
+			It("should publish the drift before applying the settings again", func() {
+				device.Status.Conditions = []metav1.Condition{{
+					Type: consts.ConfigUpdateInProgressCondition, Status: metav1.ConditionFalse, Reason: consts.UpdateSuccessfulReason}}
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				Eventually(sink.sent).Should(HaveLen(1))
+				Expect(sink.sent()[0].Type).To(Equal(lifecycle.DriftDetectedEvent))
+				Expect(sink.sent()[0].Data.Reason).To(Equal(lifecycle.RuntimeConfigDriftReason))
+			})
+			It("should not report the settings of the device that didn't converge yet", func() {
+				device.Status.Conditions = []metav1.Condition{{
+					Type: consts.ConfigUpdateInProgressCondition, Status: metav1.ConditionTrue, Reason: consts.PendingRebootReason}}
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				Consistently(sink.sent, 100*time.Millisecond).Should(BeEmpty())
+			})
+		})
+
+		Context("when flow steering mode is requested", func() {
+			BeforeEach(func() {
+				device.Spec.Configuration.Template.DevlinkResources = nil
+				device.Spec.Configuration.Template.FlowSteeringMode = consts.FlowSteeringModeSmfs
+				mockHostUtils.On("SetTrustAndPFC", "eth0", "dscp", "0,0,0,1,0,0,0,0").Return(nil)
+			})
+
+			It("should set the mode of the PF in the legacy eswitch mode", func() {
+				mockHostUtils.On("GetDevlinkParam", pciAddress, consts.DevlinkParamFlowSteeringMode).Return("dmfs", nil)
+				mockHostUtils.On("GetEswitchMode", pciAddress).Return("legacy", nil)
+				mockHostUtils.On("SetDevlinkParam", pciAddress, consts.DevlinkParamFlowSteeringMode, "smfs").Return(nil)
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				mockHostUtils.AssertExpectations(GinkgoT())
+			})
+			It("should keep the mode already matching the template", func() {
+				mockHostUtils.On("GetDevlinkParam", pciAddress, consts.DevlinkParamFlowSteeringMode).Return("smfs", nil)
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetDevlinkParam", mock.Anything, mock.Anything, mock.Anything)
+			})
+			It("should return an error if the PF is already in the switchdev mode", func() {
+				mockHostUtils.On("GetDevlinkParam", pciAddress, consts.DevlinkParamFlowSteeringMode).Return("dmfs", nil)
+				mockHostUtils.On("GetEswitchMode", pciAddress).Return(consts.EswitchModeSwitchdev, nil)
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(MatchError(ContainSubstring(
+					"cannot change flow steering mode of port 0000:3b:00.0 from dmfs to smfs in the switchdev eswitch mode")))
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetDevlinkParam", mock.Anything, mock.Anything, mock.Anything)
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetTrustAndPFC", mock.Anything, mock.Anything, mock.Anything)
+			})
+		})
+
+		Context("when eswitch mode is requested", func() {
+			BeforeEach(func() {
+				device.Spec.Configuration.Template.DevlinkResources = nil
+				device.Spec.Configuration.Template.EswitchMode = consts.EswitchModeSwitchdev
+				mockHostUtils.On("SetTrustAndPFC", "eth0", "dscp", "0,0,0,1,0,0,0,0").Return(nil)
+			})
+
+			It("should move the PF to the switchdev mode once the representors are created", func() {
+				mockHostUtils.On("GetEswitchMode", pciAddress).Return(consts.EswitchModeLegacy, nil)
+				mockHostUtils.On("SetEswitchMode", pciAddress, consts.EswitchModeSwitchdev).Return(nil)
+				mockHostUtils.On("GetVfPciAddresses", pciAddress).Return([]string{"0000:3b:00.2", "0000:3b:00.3"}, nil)
+				mockHostUtils.On("GetVfRepresentors", pciAddress).Return([]string{"pf0vf0", "pf0vf1"}, nil)
+				mockHostUtils.On("GetInterfaceName", pciAddress).Return("eth0")
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				Expect(device.Status.Ports[0].EswitchMode).To(Equal(consts.EswitchModeSwitchdev))
+				mockHostUtils.AssertExpectations(GinkgoT())
+			})
+			It("should keep the mode already matching the template", func() {
+				mockHostUtils.On("GetEswitchMode", pciAddress).Return(consts.EswitchModeSwitchdev, nil)
+				mockHostUtils.On("GetVfPciAddresses", pciAddress).Return([]string{}, nil)
+				mockHostUtils.On("GetVfRepresentors", pciAddress).Return([]string{}, nil)
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				Expect(device.Status.Ports[0].EswitchMode).To(Equal(consts.EswitchModeSwitchdev))
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetEswitchMode", mock.Anything, mock.Anything)
+			})
+			It("should skip the PF that is not the eswitch manager", func() {
+				mockHostUtils.On("GetEswitchMode", pciAddress).Return("", nil)
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				Expect(device.Status.Ports[0].EswitchMode).To(BeEmpty())
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetEswitchMode", mock.Anything, mock.Anything)
+			})
+			It("should return RuntimeConfigPendingError until the representors are created", func() {
+				mockHostUtils.On("GetEswitchMode", pciAddress).Return(consts.EswitchModeLegacy, nil)
+				mockHostUtils.On("SetEswitchMode", pciAddress, consts.EswitchModeSwitchdev).Return(nil)
+				mockHostUtils.On("GetVfPciAddresses", pciAddress).Return([]string{"0000:3b:00.2", "0000:3b:00.3"}, nil)
+				mockHostUtils.On("GetVfRepresentors", pciAddress).Return([]string{"pf0vf0"}, nil)
+				mockHostUtils.On("GetInterfaceName", pciAddress).Return("eth0")
+
+				err := manager.ApplyDeviceRuntimeSpec(device)
+				Expect(types.IsRuntimeConfigPendingError(err)).To(BeTrue())
+				Expect(err).To(MatchError(
+					"runtime config pending: waiting for the representors of the VFs of port 0000:3b:00.0 in the switchdev mode, 1 of 2 created"))
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetTrustAndPFC", mock.Anything, mock.Anything, mock.Anything)
+			})
+			It("should return an error if the mode can't be set", func() {
+				mockHostUtils.On("GetEswitchMode", pciAddress).Return(consts.EswitchModeLegacy, nil)
+				mockHostUtils.On("SetEswitchMode", pciAddress, consts.EswitchModeSwitchdev).Return(errors.New("failed to run devlink: Error: devlink: Device busy"))
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(MatchError(
+					"failed to set eswitch mode of port 0000:3b:00.0: failed to run devlink: Error: devlink: Device busy"))
+			})
+		})
+
+		Context("when coalescing settings are requested", func() {
+			BeforeEach(func() {
+				device.Spec.Configuration.Template.DevlinkResources = nil
+				device.Spec.Configuration.Template.Coalescing = &v1alpha1.CoalescingSpec{AdaptiveRx: ptr.To(false), RxUsecs: ptr.To(2)}
+				mockHostUtils.On("SetTrustAndPFC", "eth0", "dscp", "0,0,0,1,0,0,0,0").Return(nil)
+			})
+
+			It("should set the requested parameters and keep the rest", func() {
+				current := types.Coalescing{AdaptiveRx: true, AdaptiveTx: true, RxUsecs: 8, RxFrames: 128, TxUsecs: 16, TxFrames: 32}
+				mockHostUtils.On("GetCoalescing", "eth0").Return(current, nil)
+				mockHostUtils.On("SetCoalescing", "eth0",
+					types.Coalescing{AdaptiveTx: true, RxUsecs: 2, RxFrames: 128, TxUsecs: 16, TxFrames: 32}).Return(nil)
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				mockHostUtils.AssertExpectations(GinkgoT())
+			})
+			It("should keep the coalescing settings already matching the template", func() {
+				mockHostUtils.On("GetCoalescing", "eth0").Return(types.Coalescing{RxUsecs: 2}, nil)
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetCoalescing", mock.Anything, mock.Anything)
+			})
+			It("should return an error if the coalescing settings can't be set", func() {
+				mockHostUtils.On("GetCoalescing", "eth0").Return(types.Coalescing{AdaptiveRx: true}, nil)
+				mockHostUtils.On("SetCoalescing", "eth0", mock.Anything).Return(errors.New("ethtool failed"))
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(MatchError(ContainSubstring("ethtool failed")))
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetTrustAndPFC", mock.Anything, mock.Anything, mock.Anything)
+			})
+		})
+
+		Context("when offloads are requested", func() {
+			BeforeEach(func() {
+				device.Spec.Configuration.Template.DevlinkResources = nil
+				device.Spec.Configuration.Template.Offloads = &v1alpha1.OffloadsSpec{LRO: ptr.To(false), RxGroHw: ptr.To(true), TSO: ptr.To(true)}
+				mockHostUtils.On("SetTrustAndPFC", "eth0", "dscp", "0,0,0,1,0,0,0,0").Return(nil)
+			})
+
+			It("should disable the offloads before enabling the rest", func() {
+				mockHostUtils.On("GetEthtoolFeatures", "eth0").Return(map[string]types.EthtoolFeature{
+					consts.EthtoolFeatureLro: {Enabled: true}, consts.EthtoolFeatureGro: {Enabled: true},
+					consts.EthtoolFeatureTso: {Enabled: true}, consts.EthtoolFeatureRxGroHw: {}}, nil)
+				mockHostUtils.On("SetEthtoolFeature", "eth0", consts.EthtoolFeatureLro, false).Return(nil)
+				mockHostUtils.On("SetEthtoolFeature", "eth0", consts.EthtoolFeatureRxGroHw, true).Return(nil).Run(func(args mock.Arguments) {
+					mockHostUtils.AssertCalled(GinkgoT(), "SetEthtoolFeature", "eth0", consts.EthtoolFeatureLro, false)
+				})
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				mockHostUtils.AssertExpectations(GinkgoT())
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetEthtoolFeature", "eth0", consts.EthtoolFeatureTso, mock.Anything)
+			})
+			It("should return an error if the interface doesn't support the offload", func() {
+				mockHostUtils.On("GetEthtoolFeatures", "eth0").Return(map[string]types.EthtoolFeature{
+					consts.EthtoolFeatureLro: {}, consts.EthtoolFeatureTso: {Enabled: true}}, nil)
+
+				err := manager.ApplyDeviceRuntimeSpec(device)
+				Expect(types.IsIncorrectSpecError(err)).To(BeTrue())
+				Expect(err).To(MatchError(ContainSubstring("interface eth0 of port 0000:3b:00.0 does not support the rx-gro-hw offload")))
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetEthtoolFeature", mock.Anything, mock.Anything, mock.Anything)
+			})
+			It("should return an error if the driver fixed the offload in the other state", func() {
+				mockHostUtils.On("GetEthtoolFeatures", "eth0").Return(map[string]types.EthtoolFeature{
+					consts.EthtoolFeatureLro: {Enabled: true}, consts.EthtoolFeatureTso: {Enabled: true, Fixed: true},
+					consts.EthtoolFeatureRxGroHw: {Fixed: true}}, nil)
+
+				err := manager.ApplyDeviceRuntimeSpec(device)
+				Expect(types.IsIncorrectSpecError(err)).To(BeTrue())
+				Expect(err).To(MatchError(ContainSubstring("rx-gro-hw offload of interface eth0 of port 0000:3b:00.0 is fixed by the driver and can't be changed")))
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetEthtoolFeature", mock.Anything, mock.Anything, mock.Anything)
+			})
+			It("should return an error if the offload can't be set", func() {
+				mockHostUtils.On("GetEthtoolFeatures", "eth0").Return(map[string]types.EthtoolFeature{
+					consts.EthtoolFeatureLro: {}, consts.EthtoolFeatureTso: {Enabled: true}, consts.EthtoolFeatureRxGroHw: {}}, nil)
+				mockHostUtils.On("SetEthtoolFeature", "eth0", consts.EthtoolFeatureRxGroHw, true).Return(
+					errors.New("failed to run ethtool: Could not change any device features"))
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(MatchError(
+					"failed to set offload rx-gro-hw of interface eth0 of port 0000:3b:00.0: failed to run ethtool: Could not change any device features"))
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetTrustAndPFC", mock.Anything, mock.Anything, mock.Anything)
+			})
+		})
+
+		Context("when devlink resource size differs", func() {
+			It("should set the size, reload the device and apply QoS afterwards", func() {
+				mockHostUtils.On("GetDevlinkResources", pciAddress).Return(devlinkResources(2048, nil), nil).Once()
+				mockHostUtils.On("SetDevlinkResourceSize", pciAddress, "/kvd/linear", uint64(1024)).Return(nil)
+				mockHostUtils.On("ReloadDevlinkDevice", pciAddress).Return(nil)
+				mockHostUtils.On("GetDevlinkResources", pciAddress).Return(devlinkResources(1024, nil), nil).Once()
+				mockHostUtils.On("SetTrustAndPFC", "eth0", "dscp", "0,0,0,1,0,0,0,0").Return(nil).Run(func(args mock.Arguments) {
+					mockHostUtils.AssertCalled(GinkgoT(), "ReloadDevlinkDevice", pciAddress)
+				})
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				mockHostUtils.AssertExpectations(GinkgoT())
+			})
+		})
+
+		Context("when devlink resource size is pending reload", func() {
+			It("should only reload the device", func() {
+				size := uint64(1024)
+				mockHostUtils.On("GetDevlinkResources", pciAddress).Return(devlinkResources(2048, &size), nil).Once()
+				mockHostUtils.On("ReloadDevlinkDevice", pciAddress).Return(nil)
+				mockHostUtils.On("GetDevlinkResources", pciAddress).Return(devlinkResources(1024, nil), nil).Once()
+				mockHostUtils.On("SetTrustAndPFC", "eth0", "dscp", "0,0,0,1,0,0,0,0").Return(nil)
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetDevlinkResourceSize", mock.Anything, mock.Anything, mock.Anything)
+			})
+		})
+
+		Context("when devlink resource size is already applied", func() {
+			It("should not reload the device", func() {
+				mockHostUtils.On("GetDevlinkResources", pciAddress).Return(devlinkResources(1024, nil), nil)
+				mockHostUtils.On("SetTrustAndPFC", "eth0", "dscp", "0,0,0,1,0,0,0,0").Return(nil)
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				mockHostUtils.AssertNotCalled(GinkgoT(), "ReloadDevlinkDevice", mock.Anything)
+			})
+		})
+
+		Context("when reload doesn't apply the size", func() {
+			It("should return an error", func() {
+				size := uint64(1024)
+				mockHostUtils.On("GetDevlinkResources", pciAddress).Return(devlinkResources(2048, nil), nil).Once()
+				mockHostUtils.On("SetDevlinkResourceSize", pciAddress, "/kvd/linear", uint64(1024)).Return(nil)
+				mockHostUtils.On("ReloadDevlinkDevice", pciAddress).Return(nil)
+				mockHostUtils.On("GetDevlinkResources", pciAddress).Return(devlinkResources(2048, &size), nil).Once()
+
+				err := manager.ApplyDeviceRuntimeSpec(device)
+				Expect(err).To(MatchError(ContainSubstring("after reload")))
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetTrustAndPFC", mock.Anything, mock.Anything, mock.Anything)
+			})
+		})
+
+		Context("when counters reset is requested", func() {
+			var recorder *record.FakeRecorder
+
+			BeforeEach(func() {
+				recorder = record.NewFakeRecorder(10)
+				manager.eventRecorder = recorder
+				device.Spec.Configuration.Template.DevlinkResources = nil
+				device.Spec.Configuration.Template.RoceOptimized = &v1alpha1.RoceOptimizedSpec{
+					Enabled: true,
+					Qos:     &v1alpha1.QosSpec{Trust: "dscp", PFC: "0,0,0,1,0,0,0,0", ResetCounters: true},
+				}
+			})
+
+			It("should archive and reset the counters after the QoS change", func() {
+				mockHostUtils.On("GetTrustAndPFC", "eth0").Return("pcp", "0,0,0,0,0,0,0,0", nil)
+				mockHostUtils.On("SetTrustAndPFC", "eth0", "dscp", "0,0,0,1,0,0,0,0").Return(nil)
+				mockHostUtils.On("GetPortCounters", "eth0").Return(map[string]uint64{"rx_prio3_pause": 12, "tx_prio3_pause": 0, "rx_discards_phy": 3}, nil)
+				mockHostUtils.On("ResetPortCounters", pciAddress).Return(nil).Run(func(args mock.Arguments) {
+					mockHostUtils.AssertCalled(GinkgoT(), "SetTrustAndPFC", "eth0", "dscp", "0,0,0,1,0,0,0,0")
+				})
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				mockHostUtils.AssertExpectations(GinkgoT())
+
+				Expect(recorder.Events).To(HaveLen(1))
+				event := <-recorder.Events
+				Expect(event).To(ContainSubstring(consts.PortCountersResetReason))
+				Expect(event).To(ContainSubstring("previous values: rx_discards_phy=3, rx_prio3_pause=12"))
+			})
+
+			It("should not reset the counters if QoS didn't change", func() {
+				mockHostUtils.On("GetTrustAndPFC", "eth0").Return("dscp", "0,0,0,1,0,0,0,0", nil)
+				mockHostUtils.On("SetTrustAndPFC", "eth0", "dscp", "0,0,0,1,0,0,0,0").Return(nil)
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				mockHostUtils.AssertNotCalled(GinkgoT(), "ResetPortCounters", mock.Anything)
+				Expect(recorder.Events).To(BeEmpty())
+			})
+
+			It("should not reset the counters if they couldn't be archived", func() {
+				mockHostUtils.On("GetTrustAndPFC", "eth0").Return("pcp", "0,0,0,0,0,0,0,0", nil)
+				mockHostUtils.On("SetTrustAndPFC", "eth0", "dscp", "0,0,0,1,0,0,0,0").Return(nil)
+				mockHostUtils.On("GetPortCounters", "eth0").Return(nil, errors.New("ethtool failed"))
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				mockHostUtils.AssertNotCalled(GinkgoT(), "ResetPortCounters", mock.Anything)
+			})
+		})
+
+		Context("when host QoS settings conflict with the template", func() {
+			var recorder *record.FakeRecorder
+
+			BeforeEach(func() {
+				recorder = record.NewFakeRecorder(10)
+				manager.eventRecorder = recorder
+				device.Spec.Configuration.Template.DevlinkResources = nil
+				device.Spec.Configuration.Template.RoceOptimized = &v1alpha1.RoceOptimizedSpec{
+					Enabled: true,
+					Qos:     &v1alpha1.QosSpec{Trust: "dscp", PFC: "0,0,0,1,0,0,0,0"},
+				}
+				dcbAppEntries = []string{"ethtype-prio 0x8906:3", "dscp-prio 24:3"}
+				rootQdisc = "mqprio"
+				mockHostUtils.On("SetTrustAndPFC", "eth0", "dscp", "0,0,0,1,0,0,0,0").Return(nil)
+			})
+
+			It("should report the conflicts and apply the QoS settings", func() {
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				mockHostUtils.AssertNotCalled(GinkgoT(), "DeleteDcbAppEntry", mock.Anything, mock.Anything)
+				mockHostUtils.AssertNotCalled(GinkgoT(), "DeleteRootQdisc", mock.Anything)
+
+				condition := meta.FindStatusCondition(device.Status.Conditions, consts.QosConflictCondition)
+				Expect(condition).NotTo(BeNil())
+				Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+				Expect(condition.Reason).To(Equal(consts.ConflictingQosConfigReason))
+				Expect(condition.Message).To(ContainSubstring("DCB app entry ethtype-prio 0x8906:3, root qdisc mqprio"))
+				Expect(condition.Message).NotTo(ContainSubstring("dscp-prio"))
+
+				Expect(recorder.Events).To(HaveLen(1))
+				Expect(<-recorder.Events).To(HavePrefix("Warning " + consts.ConflictingQosConfigReason))
+			})
+
+			It("should emit the warning only when the conflicts change", func() {
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				Expect(<-recorder.Events).To(HavePrefix("Warning " + consts.ConflictingQosConfigReason))
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				Expect(recorder.Events).To(BeEmpty())
+
+				rootQdisc = "mq"
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				event := <-recorder.Events
+				Expect(event).To(HavePrefix("Warning " + consts.ConflictingQosConfigReason))
+				Expect(event).NotTo(ContainSubstring("root qdisc"))
+			})
+
+			It("should clear the conflicts if the template takes the ownership", func() {
+				device.Spec.Configuration.Template.RoceOptimized.Qos.TakeOwnership = true
+				mockHostUtils.On("DeleteDcbAppEntry", "eth0", "ethtype-prio 0x8906:3").Return(nil)
+				mockHostUtils.On("DeleteRootQdisc", "eth0").Return(nil).Run(func(args mock.Arguments) {
+					mockHostUtils.AssertNotCalled(GinkgoT(), "SetTrustAndPFC", mock.Anything, mock.Anything, mock.Anything)
+				})
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				mockHostUtils.AssertExpectations(GinkgoT())
+
+				condition := meta.FindStatusCondition(device.Status.Conditions, consts.QosConflictCondition)
+				Expect(condition).NotTo(BeNil())
+				Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+				Expect(condition.Reason).To(Equal(consts.NoQosConflictReason))
+
+				Expect(recorder.Events).To(HaveLen(1))
+				Expect(<-recorder.Events).To(HavePrefix("Normal " + consts.QosConflictClearedReason))
+			})
+
+			It("should fail the apply if a conflict can't be cleared", func() {
+				device.Spec.Configuration.Template.RoceOptimized.Qos.TakeOwnership = true
+				mockHostUtils.On("DeleteDcbAppEntry", "eth0", "ethtype-prio 0x8906:3").Return(errors.New("dcb failed"))
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).NotTo(Succeed())
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetTrustAndPFC", mock.Anything, mock.Anything, mock.Anything)
+			})
+
+			It("should report no conflicts if the host settings don't conflict", func() {
+				dcbAppEntries = []string{"dscp-prio 24:3"}
+				rootQdisc = "mq"
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				condition := meta.FindStatusCondition(device.Status.Conditions, consts.QosConflictCondition)
+				Expect(condition).NotTo(BeNil())
+				Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+				Expect(recorder.Events).To(BeEmpty())
+			})
+		})
+
+		Context("when devlink resource is incorrect", func() {
+			It("should return IncorrectSpecError for an unknown resource", func() {
+				device.Spec.Configuration.Template.DevlinkResources[0].Path = "/unknown"
+				mockHostUtils.On("GetDevlinkResources", pciAddress).Return(devlinkResources(2048, nil), nil)
+
+				err := manager.ApplyDeviceRuntimeSpec(device)
+				Expect(types.IsIncorrectSpecError(err)).To(BeTrue())
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetDevlinkResourceSize", mock.Anything, mock.Anything, mock.Anything)
+			})
+			It("should return IncorrectSpecError for a size out of range", func() {
+				device.Spec.Configuration.Template.DevlinkResources[0].Size = 8192
+				mockHostUtils.On("GetDevlinkResources", pciAddress).Return(devlinkResources(2048, nil), nil)
+
+				err := manager.ApplyDeviceRuntimeSpec(device)
+				Expect(types.IsIncorrectSpecError(err)).To(BeTrue())
+			})
+			It("should return IncorrectSpecError for a size not matching the granularity", func() {
+				device.Spec.Configuration.Template.DevlinkResources[0].Size = 1000
+				mockHostUtils.On("GetDevlinkResources", pciAddress).Return(devlinkResources(2048, nil), nil)
+
+				err := manager.ApplyDeviceRuntimeSpec(device)
+				Expect(types.IsIncorrectSpecError(err)).To(BeTrue())
+			})
+		})
+
+		Context("when runtime VF MSI-X vectors are requested", func() {
+			BeforeEach(func() {
+				device.Spec.Configuration.Template.DevlinkResources = nil
+				device.Spec.Configuration.Template.NumVfs = 2
+				device.Spec.Configuration.Template.VfMsix = &v1alpha1.VfMsixSpec{Dynamic: true, RuntimeVfMsix: 16}
+				mockHostUtils.On("SetTrustAndPFC", "eth0", "dscp", "0,0,0,1,0,0,0,0").Return(nil)
+				mockHostUtils.On("GetVfPciAddresses", pciAddress).Return([]string{"0000:3b:00.2", "0000:3b:00.3"}, nil)
+			})
+
+			It("should assign the vectors to the VFs that differ", func() {
+				mockHostUtils.On("GetVfTotalMsix", pciAddress).Return(64, nil)
+				mockHostUtils.On("GetVfMsixCount", "0000:3b:00.2").Return(8, nil)
+				mockHostUtils.On("GetVfMsixCount", "0000:3b:00.3").Return(16, nil)
+				mockHostUtils.On("GetPCIDriver", "0000:3b:00.2").Return("mlx5_core", nil)
+				mockHostUtils.On("GetInterfaceName", "0000:3b:00.2").Return("eth0v0")
+				mockHostUtils.On("SetVfMsixCount", "0000:3b:00.2", 16).Return(nil)
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				mockHostUtils.AssertExpectations(GinkgoT())
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetVfMsixCount", "0000:3b:00.3", mock.Anything)
+			})
+			It("should skip the VFs in use", func() {
+				mockHostUtils.On("GetVfTotalMsix", pciAddress).Return(64, nil)
+				mockHostUtils.On("GetVfMsixCount", "0000:3b:00.2").Return(8, nil)
+				mockHostUtils.On("GetVfMsixCount", "0000:3b:00.3").Return(8, nil)
+				// Bound to vfio-pci for a VM
+				mockHostUtils.On("GetPCIDriver", "0000:3b:00.2").Return("vfio-pci", nil)
+				// Network interface moved into a pod
+				mockHostUtils.On("GetPCIDriver", "0000:3b:00.3").Return("mlx5_core", nil)
+				mockHostUtils.On("GetInterfaceName", "0000:3b:00.3").Return("")
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetVfMsixCount", mock.Anything, mock.Anything)
+			})
+			It("should return IncorrectSpecError if the PF's pool is too small", func() {
+				mockHostUtils.On("GetVfTotalMsix", pciAddress).Return(24, nil)
+
+				err := manager.ApplyDeviceRuntimeSpec(device)
+				Expect(types.IsIncorrectSpecError(err)).To(BeTrue())
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetVfMsixCount", mock.Anything, mock.Anything)
+			})
+		})
+
+		Context("when representors settings are enabled", func() {
+			BeforeEach(func() {
+				device.Spec.Configuration.Template.DevlinkResources = nil
+				device.Spec.Configuration.Template.Representors = &v1alpha1.RepresentorsSpec{
+					Enabled:  true,
+					Qos:      true,
+					Features: []string{"hw-tc-offload"},
+				}
+				mockHostUtils.On("SetTrustAndPFC", "eth0", "dscp", "0,0,0,1,0,0,0,0").Return(nil)
+			})
+
+			It("should apply the uplink settings to the representors that differ", func() {
+				mockHostUtils.On("GetEswitchMode", pciAddress).Return(consts.EswitchModeSwitchdev, nil)
+				mockHostUtils.On("GetVfRepresentors", pciAddress).Return([]string{"pf0vf0", "pf0vf1"}, nil)
+				mockHostUtils.On("GetMtu", "eth0").Return(9000, nil)
+				mockHostUtils.On("GetTrustAndPFC", "eth0").Return("dscp", "0,0,0,1,0,0,0,0", nil)
+
+				mockHostUtils.On("GetMtu", "pf0vf0").Return(1500, nil)
+				mockHostUtils.On("SetMtu", "pf0vf0", 9000).Return(nil)
+				mockHostUtils.On("GetTrustAndPFC", "pf0vf0").Return("pcp", "0,0,0,0,0,0,0,0", nil)
+				mockHostUtils.On("SetTrustAndPFC", "pf0vf0", "dscp", "0,0,0,1,0,0,0,0").Return(nil)
+				mockHostUtils.On("GetEthtoolFeatures", "pf0vf0").Return(map[string]types.EthtoolFeature{"hw-tc-offload": {}}, nil)
+				mockHostUtils.On("SetEthtoolFeature", "pf0vf0", "hw-tc-offload", true).Return(nil)
+
+				mockHostUtils.On("GetMtu", "pf0vf1").Return(9000, nil)
+				mockHostUtils.On("GetTrustAndPFC", "pf0vf1").Return("dscp", "0,0,0,1,0,0,0,0", nil)
+				mockHostUtils.On("GetEthtoolFeatures", "pf0vf1").Return(map[string]types.EthtoolFeature{"hw-tc-offload": {Enabled: true}}, nil)
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				mockHostUtils.AssertExpectations(GinkgoT())
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetMtu", "pf0vf1", mock.Anything)
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetTrustAndPFC", "pf0vf1", mock.Anything, mock.Anything)
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetEthtoolFeature", "pf0vf1", mock.Anything, mock.Anything)
+			})
+			It("should apply the representors settings if the rest of the runtime config is already applied", func() {
+				mockConfigValidation.ExpectedCalls = nil
+				mockConfigValidation.On("RuntimeConfigApplied", device).Return(true, nil)
+				device.Spec.Configuration.Template.Representors.Mtu = 4000
+				device.Spec.Configuration.Template.Representors.Qos = false
+				device.Spec.Configuration.Template.Representors.Features = nil
+				mockHostUtils.On("GetEswitchMode", pciAddress).Return(consts.EswitchModeSwitchdev, nil)
+				mockHostUtils.On("GetVfRepresentors", pciAddress).Return([]string{"pf0vf0"}, nil)
+				mockHostUtils.On("GetMtu", "pf0vf0").Return(1500, nil)
+				mockHostUtils.On("SetMtu", "pf0vf0", 4000).Return(nil)
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				mockHostUtils.AssertCalled(GinkgoT(), "SetMtu", "pf0vf0", 4000)
+				mockHostUtils.AssertNotCalled(GinkgoT(), "GetMtu", "eth0")
+				mockHostUtils.AssertNotCalled(GinkgoT(), "SetTrustAndPFC", "eth0", mock.Anything, mock.Anything)
+			})
+			It("should skip the PFs in legacy mode", func() {
+				mockHostUtils.On("GetEswitchMode", pciAddress).Return("legacy", nil)
+
+				Expect(manager.ApplyDeviceRuntimeSpec(device)).To(Succeed())
+				mockHostUtils.AssertNotCalled(GinkgoT(), "GetVfRepresentors", mock.Anything)
+			})
+			It("should report the representor that couldn't be configured", func() {
+				device.Spec.Configuration.Template.Representors.Qos = false
+				mockHostUtils.On("GetEswitchMode", pciAddress).Return(consts.EswitchModeSwitchdev, nil)
+				mockHostUtils.On("GetVfRepresentors", pciAddress).Return([]string{"pf0vf0"}, nil)
+				mockHostUtils.On("GetMtu", "eth0").Return(9000, nil)
+				mockHostUtils.On("GetMtu", "pf0vf0").Return(1500, nil)
+				mockHostUtils.On("SetMtu", "pf0vf0", 9000).Return(errors.New("invalid argument"))
+
+				err := manager.ApplyDeviceRuntimeSpec(device)
+				Expect(err).To(MatchError("failed to configure VF representor pf0vf0 of port 0000:3b:00.0: invalid argument"))
+			})
+		})
+	})
+
+	Describe("CollectDiagnostics", func() {
+		It("should describe the failed tool runs and the sysfs state of the device's ports", func() {
+			since := time.Now()
+			device := &v1alpha1.NicDevice{
+				Status: v1alpha1.NicDeviceStatus{
+					SerialNumber:    "mt2232t13210",
+					PartNumber:      "mcx623106an-cdat",
+					FirmwareVersion: "22.41.1000",
+					Ports:           []v1alpha1.NicDevicePortSpec{{PCI: pciAddress, NetworkInterface: "eth1"}},
+				},
+			}
+			failures := []types.ToolFailure{}
+			for code := 1; code <= 4; code++ {
+				failures = append(failures, types.ToolFailure{Command: "mstconfig -d " + pciAddress + " --yes set A=1", ExitCode: code, Time: since})
+			}
+			mockHostUtils.On("GetToolFailures", since, []string{pciAddress, "eth1", ""}).Return(failures)
+			mockHostUtils.On("GetPCIDeviceSysfsState", pciAddress).Return(map[string]string{"driver": "mlx5_core"})
+
+			diagnostics := deviceDiagnostics{}
+			Expect(json.Unmarshal([]byte(manager.CollectDiagnostics(device, since)), &diagnostics)).To(Succeed())
+			Expect(diagnostics.SerialNumber).To(Equal("mt2232t13210"))
+			Expect(diagnostics.FirmwareVersion).To(Equal("22.41.1000"))
+			Expect(diagnostics.ToolFailures).To(HaveLen(3))
+			Expect(diagnostics.ToolFailures[0].ExitCode).To(Equal(2))
+			Expect(diagnostics.ToolFailures[2].ExitCode).To(Equal(4))
+			Expect(diagnostics.Sysfs).To(Equal(map[string]map[string]string{pciAddress: {"driver": "mlx5_core"}}))
+		})
+	})
+
+	Describe("diffCurrentAndNextBootConfig", func() {
+		It("should list parameters that differ between current and next boot configs", func() {
+			nvConfig := types.NvConfigQuery{
+				CurrentConfig:  map[string][]string{"param1": {"false", "0"}, "param2": {"8"}, "param3": {"1"}},
+				NextBootConfig: map[string][]string{"param1": {"true", "1"}, "param2": {"8"}, "param4": {"2"}},
+			}
+
+			Expect(diffCurrentAndNextBootConfig(nvConfig)).To(Equal([]v1alpha1.NvConfigParameterDiff{
+				{Name: "param1", CurrentValues: []string{"false", "0"}, NextBootValues: []string{"true", "1"}},
+				{Name: "param3", CurrentValues: []string{"1"}},
+				{Name: "param4", NextBootValues: []string{"2"}},
+			}))
+		})
+		It("should return nil if configs are equal", func() {
+			nvConfig := types.NvConfigQuery{
+				CurrentConfig:  map[string][]string{"param1": {"8"}},
+				NextBootConfig: map[string][]string{"param1": {"8"}},
+			}
+
+			Expect(diffCurrentAndNextBootConfig(nvConfig)).To(BeNil())
+		})
+		It("should not list the same values reported with another alias or format", func() {
+			nvConfig := types.NvConfigQuery{
+				CurrentConfig:  map[string][]string{"LINK_TYPE_P1": {"ETH", "2"}, "PF_BAR2_SIZE": {"0x10"}, "SRIOV_EN": {"True", "1"}},
+				NextBootConfig: map[string][]string{"LINK_TYPE_P1": {"eth", "2"}, "PF_BAR2_SIZE": {"16"}, "SRIOV_EN": {"1"}},
+			}
+
+			Expect(diffCurrentAndNextBootConfig(nvConfig)).To(BeNil())
+		})
+	})
+})
+
+type recordingSink struct {
+	records []changelog.Record
+	err     error
+}
+
+func (s *recordingSink) Write(_ context.Context, record changelog.Record) error {
+	s.records = append(s.records, record)
+	return s.err
+}
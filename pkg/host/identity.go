@@ -0,0 +1,136 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package host
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+	"github.com/Mellanox/nic-configuration-operator/pkg/types"
+)
+
+// vpdReadAttempts limits the reads of the device's VPD, the reads fail intermittently on some boards
+var vpdReadAttempts = 3
+
+// vpdReadBackoff is the delay before the second read of the device's VPD, doubled before each next read
+var vpdReadBackoff = 500 * time.Millisecond
+
+// deviceIdentity returns the part number, the serial number and the identity source of the device
+// the VPD is read with retries, the identity falls back to the base GUID of the device's flash if all the reads fail,
+// the flash of the restricted devices isn't queried, as it might not be accessible from the VM,
+// empty serial and part numbers are returned for the restricted devices without the VPD instead
+// the firmware query of the fallback is returned as well, so that the device's firmware isn't queried again, nil otherwise
+func (h hostManager) deviceIdentity(pciAddr string, restricted bool) (string, string, string, *types.FirmwareQuery, error) {
+	var err error
+	backoff := vpdReadBackoff
+	for attempt := 1; attempt <= max(vpdReadAttempts, 1); attempt++ {
+		if attempt > 1 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		var partNumber, serialNumber string
+		partNumber, serialNumber, err = h.hostUtils.GetPartAndSerialNumber(pciAddr)
+		if err == nil {
+			return partNumber, serialNumber, "", nil, nil
+		}
+		log.Log.V(2).Info("failed to read VPD of device", "address", pciAddr, "attempt", attempt, "err", err)
+	}
+
+	if restricted {
+		log.Log.Error(err, "failed to read VPD of VF", "address", pciAddr)
+		return "", "", "", nil, nil
+	}
+
+	log.Log.Error(err, "failed to read VPD of device, falling back to its flash identity", "address", pciAddr)
+	query, queryErr := h.hostUtils.QueryFirmware(pciAddr)
+	if queryErr == nil && query.BaseGUID == "" {
+		queryErr = fmt.Errorf("base GUID of device %s is not reported", pciAddr)
+	}
+	if queryErr != nil {
+		log.Log.Error(queryErr, "failed to get flash identity of device", "address", pciAddr)
+		return "", "", "", nil, err
+	}
+
+	return vpdPartNumber(query.PartNumber), consts.FlashSerialNumberPrefix + query.BaseGUID, consts.IdentitySourceFlash, query, nil
+}
+
+// flashPartNumberRevisionSuffix is the placeholder of the board revision in the part number of the flash, e.g. MCX713106AC-VEA_Ax
+const flashPartNumberRevisionSuffix = "_ax"
+
+// vpdPartNumber returns the flash part number in the format of the VPD, lowercase and without the board revision placeholder,
+// so that the part number of a device doesn't change between the discoveries identifying it by the flash and by the VPD
+func vpdPartNumber(flashPartNumber string) string {
+	return strings.TrimSuffix(strings.ToLower(flashPartNumber), flashPartNumberRevisionSuffix)
+}
+
+// unidentifiedDevice returns the status of the device whose VPD and flash couldn't be read, keyed by its PCI address,
+// the NicDevice CR of its ports keeps the identity of the previous discovery
+func (h hostManager) unidentifiedDevice(pciAddr string, deviceType string) (string, v1alpha1.NicDeviceStatus) {
+	return consts.UnidentifiedDevicePrefix + pciAddr, v1alpha1.NicDeviceStatus{
+		Type:           deviceType,
+		IdentitySource: consts.IdentitySourceUnknown,
+		Node:           h.nodeName,
+		Ports: []v1alpha1.NicDevicePortSpec{{
+			PCI:              pciAddr,
+			NetworkInterface: h.hostUtils.GetInterfaceName(pciAddr),
+			RdmaInterface:    h.hostUtils.GetRDMADeviceName(pciAddr),
+		}},
+	}
+}
+
+// mergeFlashIdentifiedDevices moves the ports identified by their flash or not identified at all into the device identified
+// by its VPD that has ports in the same PCI slot, so that a failed VPD read of one function doesn't split the NIC
+func mergeFlashIdentifiedDevices(devices map[string]v1alpha1.NicDeviceStatus) {
+	for flashSerialNumber, flashDevice := range devices {
+		if flashDevice.IdentitySource != consts.IdentitySourceFlash && flashDevice.IdentitySource != consts.IdentitySourceUnknown {
+			continue
+		}
+
+		for serialNumber, device := range devices {
+			if device.IdentitySource != "" || !sharePCISlot(device.Ports, flashDevice.Ports) {
+				continue
+			}
+
+			log.Log.Info("merging ports identified by flash into device", "serialNumber", serialNumber, "guid", flashSerialNumber)
+			device.Ports = append(device.Ports, flashDevice.Ports...)
+			slices.SortFunc(device.Ports, func(a, b v1alpha1.NicDevicePortSpec) int {
+				return strings.Compare(a.PCI, b.PCI)
+			})
+			devices[serialNumber] = device
+			delete(devices, flashSerialNumber)
+			break
+		}
+	}
+}
+
+// sharePCISlot returns true if any of the ports are located in the same PCI slot
+func sharePCISlot(ports []v1alpha1.NicDevicePortSpec, otherPorts []v1alpha1.NicDevicePortSpec) bool {
+	for _, port := range ports {
+		for _, otherPort := range otherPorts {
+			if strings.EqualFold(pciSlot(port.PCI), pciSlot(otherPort.PCI)) {
+				return true
+			}
+		}
+	}
+	return false
+}
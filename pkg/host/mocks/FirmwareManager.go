@@ -0,0 +1,124 @@
+// Code generated by mockery v2.46.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	types "github.com/Mellanox/nic-configuration-operator/pkg/types"
+
+	v1alpha1 "github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+)
+
+// FirmwareManager is an autogenerated mock type for the FirmwareManager type
+type FirmwareManager struct {
+	mock.Mock
+}
+
+// BurnFirmware provides a mock function with given fields: ctx, device, imagePath
+func (_m *FirmwareManager) BurnFirmware(ctx context.Context, device *v1alpha1.NicDevice, imagePath string) error {
+	ret := _m.Called(ctx, device, imagePath)
+
+	if len(ret) == 0 {
+		panic("no return value specified for BurnFirmware")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *v1alpha1.NicDevice, string) error); ok {
+		r0 = rf(ctx, device, imagePath)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// InstallBFB provides a mock function with given fields: ctx, device, bundlePath
+func (_m *FirmwareManager) InstallBFB(ctx context.Context, device *v1alpha1.NicDevice, bundlePath string) error {
+	ret := _m.Called(ctx, device, bundlePath)
+
+	if len(ret) == 0 {
+		panic("no return value specified for InstallBFB")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *v1alpha1.NicDevice, string) error); ok {
+		r0 = rf(ctx, device, bundlePath)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ValidateRequestedBFB provides a mock function with given fields: ctx, device, source, credentials
+func (_m *FirmwareManager) ValidateRequestedBFB(ctx context.Context, device *v1alpha1.NicDevice, source *v1alpha1.NicFirmwareSource, credentials types.RegistryCredentials) (string, error) {
+	ret := _m.Called(ctx, device, source, credentials)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ValidateRequestedBFB")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *v1alpha1.NicDevice, *v1alpha1.NicFirmwareSource, types.RegistryCredentials) (string, error)); ok {
+		return rf(ctx, device, source, credentials)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *v1alpha1.NicDevice, *v1alpha1.NicFirmwareSource, types.RegistryCredentials) string); ok {
+		r0 = rf(ctx, device, source, credentials)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *v1alpha1.NicDevice, *v1alpha1.NicFirmwareSource, types.RegistryCredentials) error); ok {
+		r1 = rf(ctx, device, source, credentials)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ValidateRequestedFirmware provides a mock function with given fields: ctx, device, source, credentials
+func (_m *FirmwareManager) ValidateRequestedFirmware(ctx context.Context, device *v1alpha1.NicDevice, source *v1alpha1.NicFirmwareSource, credentials types.RegistryCredentials) (string, error) {
+	ret := _m.Called(ctx, device, source, credentials)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ValidateRequestedFirmware")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *v1alpha1.NicDevice, *v1alpha1.NicFirmwareSource, types.RegistryCredentials) (string, error)); ok {
+		return rf(ctx, device, source, credentials)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *v1alpha1.NicDevice, *v1alpha1.NicFirmwareSource, types.RegistryCredentials) string); ok {
+		r0 = rf(ctx, device, source, credentials)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *v1alpha1.NicDevice, *v1alpha1.NicFirmwareSource, types.RegistryCredentials) error); ok {
+		r1 = rf(ctx, device, source, credentials)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewFirmwareManager creates a new instance of FirmwareManager. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewFirmwareManager(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *FirmwareManager {
+	mock := &FirmwareManager{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
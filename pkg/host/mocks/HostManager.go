@@ -7,6 +7,8 @@ import (
 
 	mock "github.com/stretchr/testify/mock"
 
+	time "time"
+
 	v1alpha1 "github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
 )
 
@@ -61,9 +63,63 @@ func (_m *HostManager) ApplyDeviceRuntimeSpec(device *v1alpha1.NicDevice) error
 	return r0
 }
 
-// DiscoverNicDevices provides a mock function with given fields:
-func (_m *HostManager) DiscoverNicDevices() (map[string]v1alpha1.NicDeviceStatus, error) {
-	ret := _m.Called()
+// ApplyRepresentorsRuntimeSpec provides a mock function with given fields: device
+func (_m *HostManager) ApplyRepresentorsRuntimeSpec(device *v1alpha1.NicDevice) error {
+	ret := _m.Called(device)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ApplyRepresentorsRuntimeSpec")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*v1alpha1.NicDevice) error); ok {
+		r0 = rf(device)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CollectDiagnostics provides a mock function with given fields: device, since
+func (_m *HostManager) CollectDiagnostics(device *v1alpha1.NicDevice, since time.Time) string {
+	ret := _m.Called(device, since)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CollectDiagnostics")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(*v1alpha1.NicDevice, time.Time) string); ok {
+		r0 = rf(device, since)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// DiscoverEnvironment provides a mock function with given fields: ignoredPCIAddresses
+func (_m *HostManager) DiscoverEnvironment(ignoredPCIAddresses []string) v1alpha1.NodeEnvironment {
+	ret := _m.Called(ignoredPCIAddresses)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DiscoverEnvironment")
+	}
+
+	var r0 v1alpha1.NodeEnvironment
+	if rf, ok := ret.Get(0).(func([]string) v1alpha1.NodeEnvironment); ok {
+		r0 = rf(ignoredPCIAddresses)
+	} else {
+		r0 = ret.Get(0).(v1alpha1.NodeEnvironment)
+	}
+
+	return r0
+}
+
+// DiscoverNicDevices provides a mock function with given fields: ignoredPCIAddresses
+func (_m *HostManager) DiscoverNicDevices(ignoredPCIAddresses []string) (map[string]v1alpha1.NicDeviceStatus, error) {
+	ret := _m.Called(ignoredPCIAddresses)
 
 	if len(ret) == 0 {
 		panic("no return value specified for DiscoverNicDevices")
@@ -71,19 +127,19 @@ func (_m *HostManager) DiscoverNicDevices() (map[string]v1alpha1.NicDeviceStatus
 
 	var r0 map[string]v1alpha1.NicDeviceStatus
 	var r1 error
-	if rf, ok := ret.Get(0).(func() (map[string]v1alpha1.NicDeviceStatus, error)); ok {
-		return rf()
+	if rf, ok := ret.Get(0).(func([]string) (map[string]v1alpha1.NicDeviceStatus, error)); ok {
+		return rf(ignoredPCIAddresses)
 	}
-	if rf, ok := ret.Get(0).(func() map[string]v1alpha1.NicDeviceStatus); ok {
-		r0 = rf()
+	if rf, ok := ret.Get(0).(func([]string) map[string]v1alpha1.NicDeviceStatus); ok {
+		r0 = rf(ignoredPCIAddresses)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(map[string]v1alpha1.NicDeviceStatus)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func() error); ok {
-		r1 = rf()
+	if rf, ok := ret.Get(1).(func([]string) error); ok {
+		r1 = rf(ignoredPCIAddresses)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -109,6 +165,34 @@ func (_m *HostManager) DiscoverOfedVersion() string {
 	return r0
 }
 
+// DiscoverVirtualFunctions provides a mock function with given fields: status
+func (_m *HostManager) DiscoverVirtualFunctions(status *v1alpha1.NicDeviceStatus) {
+	_m.Called(status)
+}
+
+// RefreshPortLinks provides a mock function with given fields: status
+func (_m *HostManager) RefreshPortLinks(status *v1alpha1.NicDeviceStatus) {
+	_m.Called(status)
+}
+
+// RollbackDeviceNvSpec provides a mock function with given fields: ctx, device
+func (_m *HostManager) RollbackDeviceNvSpec(ctx context.Context, device *v1alpha1.NicDevice) error {
+	ret := _m.Called(ctx, device)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RollbackDeviceNvSpec")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *v1alpha1.NicDevice) error); ok {
+		r0 = rf(ctx, device)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // ValidateDeviceNvSpec provides a mock function with given fields: ctx, device
 func (_m *HostManager) ValidateDeviceNvSpec(ctx context.Context, device *v1alpha1.NicDevice) (bool, bool, error) {
 	ret := _m.Called(ctx, device)
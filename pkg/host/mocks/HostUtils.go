@@ -19,62 +19,81 @@ type HostUtils struct {
 	mock.Mock
 }
 
-// GetFirmwareVersionAndPSID provides a mock function with given fields: pciAddr
-func (_m *HostUtils) GetFirmwareVersionAndPSID(pciAddr string) (string, string, error) {
-	ret := _m.Called(pciAddr)
+// BurnFirmware provides a mock function with given fields: ctx, pciAddr, imagePath
+func (_m *HostUtils) BurnFirmware(ctx context.Context, pciAddr string, imagePath string) error {
+	ret := _m.Called(ctx, pciAddr, imagePath)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetFirmwareVersionAndPSID")
+		panic("no return value specified for BurnFirmware")
 	}
 
-	var r0 string
-	var r1 string
-	var r2 error
-	if rf, ok := ret.Get(0).(func(string) (string, string, error)); ok {
-		return rf(pciAddr)
-	}
-	if rf, ok := ret.Get(0).(func(string) string); ok {
-		r0 = rf(pciAddr)
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, pciAddr, imagePath)
 	} else {
-		r0 = ret.Get(0).(string)
+		r0 = ret.Error(0)
 	}
 
-	if rf, ok := ret.Get(1).(func(string) string); ok {
-		r1 = rf(pciAddr)
+	return r0
+}
+
+// DeleteDcbAppEntry provides a mock function with given fields: interfaceName, entry
+func (_m *HostUtils) DeleteDcbAppEntry(interfaceName string, entry string) error {
+	ret := _m.Called(interfaceName, entry)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteDcbAppEntry")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(interfaceName, entry)
 	} else {
-		r1 = ret.Get(1).(string)
+		r0 = ret.Error(0)
 	}
 
-	if rf, ok := ret.Get(2).(func(string) error); ok {
-		r2 = rf(pciAddr)
+	return r0
+}
+
+// DeleteRootQdisc provides a mock function with given fields: interfaceName
+func (_m *HostUtils) DeleteRootQdisc(interfaceName string) error {
+	ret := _m.Called(interfaceName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteRootQdisc")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(interfaceName)
 	} else {
-		r2 = ret.Error(2)
+		r0 = ret.Error(0)
 	}
 
-	return r0, r1, r2
+	return r0
 }
 
-// GetHostUptimeSeconds provides a mock function with given fields:
-func (_m *HostUtils) GetHostUptimeSeconds() (time.Duration, error) {
-	ret := _m.Called()
+// GetChannels provides a mock function with given fields: interfaceName
+func (_m *HostUtils) GetChannels(interfaceName string) (types.Channels, error) {
+	ret := _m.Called(interfaceName)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetHostUptimeSeconds")
+		panic("no return value specified for GetChannels")
 	}
 
-	var r0 time.Duration
+	var r0 types.Channels
 	var r1 error
-	if rf, ok := ret.Get(0).(func() (time.Duration, error)); ok {
-		return rf()
+	if rf, ok := ret.Get(0).(func(string) (types.Channels, error)); ok {
+		return rf(interfaceName)
 	}
-	if rf, ok := ret.Get(0).(func() time.Duration); ok {
-		r0 = rf()
+	if rf, ok := ret.Get(0).(func(string) types.Channels); ok {
+		r0 = rf(interfaceName)
 	} else {
-		r0 = ret.Get(0).(time.Duration)
+		r0 = ret.Get(0).(types.Channels)
 	}
 
-	if rf, ok := ret.Get(1).(func() error); ok {
-		r1 = rf()
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(interfaceName)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -82,63 +101,57 @@ func (_m *HostUtils) GetHostUptimeSeconds() (time.Duration, error) {
 	return r0, r1
 }
 
-// GetInterfaceName provides a mock function with given fields: pciAddr
-func (_m *HostUtils) GetInterfaceName(pciAddr string) string {
-	ret := _m.Called(pciAddr)
+// GetCoalescing provides a mock function with given fields: interfaceName
+func (_m *HostUtils) GetCoalescing(interfaceName string) (types.Coalescing, error) {
+	ret := _m.Called(interfaceName)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetInterfaceName")
+		panic("no return value specified for GetCoalescing")
 	}
 
-	var r0 string
-	if rf, ok := ret.Get(0).(func(string) string); ok {
-		r0 = rf(pciAddr)
-	} else {
-		r0 = ret.Get(0).(string)
+	var r0 types.Coalescing
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (types.Coalescing, error)); ok {
+		return rf(interfaceName)
 	}
-
-	return r0
-}
-
-// GetLinkType provides a mock function with given fields: name
-func (_m *HostUtils) GetLinkType(name string) string {
-	ret := _m.Called(name)
-
-	if len(ret) == 0 {
-		panic("no return value specified for GetLinkType")
+	if rf, ok := ret.Get(0).(func(string) types.Coalescing); ok {
+		r0 = rf(interfaceName)
+	} else {
+		r0 = ret.Get(0).(types.Coalescing)
 	}
 
-	var r0 string
-	if rf, ok := ret.Get(0).(func(string) string); ok {
-		r0 = rf(name)
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(interfaceName)
 	} else {
-		r0 = ret.Get(0).(string)
+		r1 = ret.Error(1)
 	}
 
-	return r0
+	return r0, r1
 }
 
-// GetMaxReadRequestSize provides a mock function with given fields: pciAddr
-func (_m *HostUtils) GetMaxReadRequestSize(pciAddr string) (int, error) {
-	ret := _m.Called(pciAddr)
+// GetDcbAppEntries provides a mock function with given fields: interfaceName
+func (_m *HostUtils) GetDcbAppEntries(interfaceName string) ([]string, error) {
+	ret := _m.Called(interfaceName)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetMaxReadRequestSize")
+		panic("no return value specified for GetDcbAppEntries")
 	}
 
-	var r0 int
+	var r0 []string
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string) (int, error)); ok {
-		return rf(pciAddr)
+	if rf, ok := ret.Get(0).(func(string) ([]string, error)); ok {
+		return rf(interfaceName)
 	}
-	if rf, ok := ret.Get(0).(func(string) int); ok {
-		r0 = rf(pciAddr)
+	if rf, ok := ret.Get(0).(func(string) []string); ok {
+		r0 = rf(interfaceName)
 	} else {
-		r0 = ret.Get(0).(int)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
 	}
 
 	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(pciAddr)
+		r1 = rf(interfaceName)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -146,47 +159,55 @@ func (_m *HostUtils) GetMaxReadRequestSize(pciAddr string) (int, error) {
 	return r0, r1
 }
 
-// GetOfedVersion provides a mock function with given fields:
-func (_m *HostUtils) GetOfedVersion() string {
-	ret := _m.Called()
+// GetDcqcnParameter provides a mock function with given fields: interfaceName, name
+func (_m *HostUtils) GetDcqcnParameter(interfaceName string, name string) (int, error) {
+	ret := _m.Called(interfaceName, name)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetOfedVersion")
+		panic("no return value specified for GetDcqcnParameter")
 	}
 
-	var r0 string
-	if rf, ok := ret.Get(0).(func() string); ok {
-		r0 = rf()
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, string) (int, error)); ok {
+		return rf(interfaceName, name)
+	}
+	if rf, ok := ret.Get(0).(func(string, string) int); ok {
+		r0 = rf(interfaceName, name)
 	} else {
-		r0 = ret.Get(0).(string)
+		r0 = ret.Get(0).(int)
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(interfaceName, name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
-// GetPCIDevices provides a mock function with given fields:
-func (_m *HostUtils) GetPCIDevices() ([]*pci.Device, error) {
-	ret := _m.Called()
+// GetDevlinkParam provides a mock function with given fields: pciAddr, name
+func (_m *HostUtils) GetDevlinkParam(pciAddr string, name string) (string, error) {
+	ret := _m.Called(pciAddr, name)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetPCIDevices")
+		panic("no return value specified for GetDevlinkParam")
 	}
 
-	var r0 []*pci.Device
+	var r0 string
 	var r1 error
-	if rf, ok := ret.Get(0).(func() ([]*pci.Device, error)); ok {
-		return rf()
+	if rf, ok := ret.Get(0).(func(string, string) (string, error)); ok {
+		return rf(pciAddr, name)
 	}
-	if rf, ok := ret.Get(0).(func() []*pci.Device); ok {
-		r0 = rf()
+	if rf, ok := ret.Get(0).(func(string, string) string); ok {
+		r0 = rf(pciAddr, name)
 	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]*pci.Device)
-		}
+		r0 = ret.Get(0).(string)
 	}
 
-	if rf, ok := ret.Get(1).(func() error); ok {
-		r1 = rf()
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(pciAddr, name)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -194,23 +215,25 @@ func (_m *HostUtils) GetPCIDevices() ([]*pci.Device, error) {
 	return r0, r1
 }
 
-// GetPCILinkSpeed provides a mock function with given fields: pciAddr
-func (_m *HostUtils) GetPCILinkSpeed(pciAddr string) (int, error) {
+// GetDevlinkResources provides a mock function with given fields: pciAddr
+func (_m *HostUtils) GetDevlinkResources(pciAddr string) (map[string]types.DevlinkResource, error) {
 	ret := _m.Called(pciAddr)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetPCILinkSpeed")
+		panic("no return value specified for GetDevlinkResources")
 	}
 
-	var r0 int
+	var r0 map[string]types.DevlinkResource
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string) (int, error)); ok {
+	if rf, ok := ret.Get(0).(func(string) (map[string]types.DevlinkResource, error)); ok {
 		return rf(pciAddr)
 	}
-	if rf, ok := ret.Get(0).(func(string) int); ok {
+	if rf, ok := ret.Get(0).(func(string) map[string]types.DevlinkResource); ok {
 		r0 = rf(pciAddr)
 	} else {
-		r0 = ret.Get(0).(int)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]types.DevlinkResource)
+		}
 	}
 
 	if rf, ok := ret.Get(1).(func(string) error); ok {
@@ -222,133 +245,143 @@ func (_m *HostUtils) GetPCILinkSpeed(pciAddr string) (int, error) {
 	return r0, r1
 }
 
-// GetPartAndSerialNumber provides a mock function with given fields: pciAddr
-func (_m *HostUtils) GetPartAndSerialNumber(pciAddr string) (string, string, error) {
-	ret := _m.Called(pciAddr)
+// GetEcn provides a mock function with given fields: interfaceName
+func (_m *HostUtils) GetEcn(interfaceName string) (string, error) {
+	ret := _m.Called(interfaceName)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetPartAndSerialNumber")
+		panic("no return value specified for GetEcn")
 	}
 
 	var r0 string
-	var r1 string
-	var r2 error
-	if rf, ok := ret.Get(0).(func(string) (string, string, error)); ok {
-		return rf(pciAddr)
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (string, error)); ok {
+		return rf(interfaceName)
 	}
 	if rf, ok := ret.Get(0).(func(string) string); ok {
-		r0 = rf(pciAddr)
+		r0 = rf(interfaceName)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
-	if rf, ok := ret.Get(1).(func(string) string); ok {
-		r1 = rf(pciAddr)
-	} else {
-		r1 = ret.Get(1).(string)
-	}
-
-	if rf, ok := ret.Get(2).(func(string) error); ok {
-		r2 = rf(pciAddr)
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(interfaceName)
 	} else {
-		r2 = ret.Error(2)
+		r1 = ret.Error(1)
 	}
 
-	return r0, r1, r2
+	return r0, r1
 }
 
-// GetRDMADeviceName provides a mock function with given fields: pciAddr
-func (_m *HostUtils) GetRDMADeviceName(pciAddr string) string {
+// GetEswitchMode provides a mock function with given fields: pciAddr
+func (_m *HostUtils) GetEswitchMode(pciAddr string) (string, error) {
 	ret := _m.Called(pciAddr)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetRDMADeviceName")
+		panic("no return value specified for GetEswitchMode")
 	}
 
 	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (string, error)); ok {
+		return rf(pciAddr)
+	}
 	if rf, ok := ret.Get(0).(func(string) string); ok {
 		r0 = rf(pciAddr)
 	} else {
 		r0 = ret.Get(0).(string)
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(pciAddr)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
-// GetTrustAndPFC provides a mock function with given fields: interfaceName
-func (_m *HostUtils) GetTrustAndPFC(interfaceName string) (string, string, error) {
+// GetEthtoolFeatures provides a mock function with given fields: interfaceName
+func (_m *HostUtils) GetEthtoolFeatures(interfaceName string) (map[string]types.EthtoolFeature, error) {
 	ret := _m.Called(interfaceName)
 
 	if len(ret) == 0 {
-		panic("no return value specified for GetTrustAndPFC")
+		panic("no return value specified for GetEthtoolFeatures")
 	}
 
-	var r0 string
-	var r1 string
-	var r2 error
-	if rf, ok := ret.Get(0).(func(string) (string, string, error)); ok {
+	var r0 map[string]types.EthtoolFeature
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (map[string]types.EthtoolFeature, error)); ok {
 		return rf(interfaceName)
 	}
-	if rf, ok := ret.Get(0).(func(string) string); ok {
+	if rf, ok := ret.Get(0).(func(string) map[string]types.EthtoolFeature); ok {
 		r0 = rf(interfaceName)
 	} else {
-		r0 = ret.Get(0).(string)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]types.EthtoolFeature)
+		}
 	}
 
-	if rf, ok := ret.Get(1).(func(string) string); ok {
+	if rf, ok := ret.Get(1).(func(string) error); ok {
 		r1 = rf(interfaceName)
 	} else {
-		r1 = ret.Get(1).(string)
-	}
-
-	if rf, ok := ret.Get(2).(func(string) error); ok {
-		r2 = rf(interfaceName)
-	} else {
-		r2 = ret.Error(2)
+		r1 = ret.Error(1)
 	}
 
-	return r0, r1, r2
+	return r0, r1
 }
 
-// IsSriovVF provides a mock function with given fields: pciAddr
-func (_m *HostUtils) IsSriovVF(pciAddr string) bool {
+// GetHealthReporters provides a mock function with given fields: pciAddr
+func (_m *HostUtils) GetHealthReporters(pciAddr string) ([]types.HealthReporter, error) {
 	ret := _m.Called(pciAddr)
 
 	if len(ret) == 0 {
-		panic("no return value specified for IsSriovVF")
+		panic("no return value specified for GetHealthReporters")
 	}
 
-	var r0 bool
-	if rf, ok := ret.Get(0).(func(string) bool); ok {
+	var r0 []types.HealthReporter
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) ([]types.HealthReporter, error)); ok {
+		return rf(pciAddr)
+	}
+	if rf, ok := ret.Get(0).(func(string) []types.HealthReporter); ok {
 		r0 = rf(pciAddr)
 	} else {
-		r0 = ret.Get(0).(bool)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]types.HealthReporter)
+		}
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(pciAddr)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
-// QueryNvConfig provides a mock function with given fields: ctx, pciAddr
-func (_m *HostUtils) QueryNvConfig(ctx context.Context, pciAddr string) (types.NvConfigQuery, error) {
-	ret := _m.Called(ctx, pciAddr)
+// GetHostBootID provides a mock function with given fields:
+func (_m *HostUtils) GetHostBootID() (string, error) {
+	ret := _m.Called()
 
 	if len(ret) == 0 {
-		panic("no return value specified for QueryNvConfig")
+		panic("no return value specified for GetHostBootID")
 	}
 
-	var r0 types.NvConfigQuery
+	var r0 string
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) (types.NvConfigQuery, error)); ok {
-		return rf(ctx, pciAddr)
+	if rf, ok := ret.Get(0).(func() (string, error)); ok {
+		return rf()
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, string) types.NvConfigQuery); ok {
-		r0 = rf(ctx, pciAddr)
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
 	} else {
-		r0 = ret.Get(0).(types.NvConfigQuery)
+		r0 = ret.Get(0).(string)
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
-		r1 = rf(ctx, pciAddr)
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -356,7 +389,1151 @@ func (_m *HostUtils) QueryNvConfig(ctx context.Context, pciAddr string) (types.N
 	return r0, r1
 }
 
-// ResetNicFirmware provides a mock function with given fields: ctx, pciAddr
+// GetHostPrivilegeLevel provides a mock function with given fields: pciAddr
+func (_m *HostUtils) GetHostPrivilegeLevel(pciAddr string) (string, error) {
+	ret := _m.Called(pciAddr)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetHostPrivilegeLevel")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (string, error)); ok {
+		return rf(pciAddr)
+	}
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(pciAddr)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(pciAddr)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetHostUptimeSeconds provides a mock function with given fields:
+func (_m *HostUtils) GetHostUptimeSeconds() (time.Duration, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetHostUptimeSeconds")
+	}
+
+	var r0 time.Duration
+	var r1 error
+	if rf, ok := ret.Get(0).(func() (time.Duration, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() time.Duration); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(time.Duration)
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetHypervisor provides a mock function with given fields:
+func (_m *HostUtils) GetHypervisor() (string, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetHypervisor")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func() (string, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetInterfaceName provides a mock function with given fields: pciAddr
+func (_m *HostUtils) GetInterfaceName(pciAddr string) string {
+	ret := _m.Called(pciAddr)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetInterfaceName")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(pciAddr)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// GetLinkStatus provides a mock function with given fields: interfaceName
+func (_m *HostUtils) GetLinkStatus(interfaceName string) (*types.LinkStatus, error) {
+	ret := _m.Called(interfaceName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetLinkStatus")
+	}
+
+	var r0 *types.LinkStatus
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (*types.LinkStatus, error)); ok {
+		return rf(interfaceName)
+	}
+	if rf, ok := ret.Get(0).(func(string) *types.LinkStatus); ok {
+		r0 = rf(interfaceName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.LinkStatus)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(interfaceName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetLinkType provides a mock function with given fields: name
+func (_m *HostUtils) GetLinkType(name string) string {
+	ret := _m.Called(name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetLinkType")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(name)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// GetMaxReadRequestSize provides a mock function with given fields: pciAddr
+func (_m *HostUtils) GetMaxReadRequestSize(pciAddr string) (int, error) {
+	ret := _m.Called(pciAddr)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetMaxReadRequestSize")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (int, error)); ok {
+		return rf(pciAddr)
+	}
+	if rf, ok := ret.Get(0).(func(string) int); ok {
+		r0 = rf(pciAddr)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(pciAddr)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetMtu provides a mock function with given fields: interfaceName
+func (_m *HostUtils) GetMtu(interfaceName string) (int, error) {
+	ret := _m.Called(interfaceName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetMtu")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (int, error)); ok {
+		return rf(interfaceName)
+	}
+	if rf, ok := ret.Get(0).(func(string) int); ok {
+		r0 = rf(interfaceName)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(interfaceName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetNumaNodeCPUCount provides a mock function with given fields: pciAddr
+func (_m *HostUtils) GetNumaNodeCPUCount(pciAddr string) (int, error) {
+	ret := _m.Called(pciAddr)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetNumaNodeCPUCount")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (int, error)); ok {
+		return rf(pciAddr)
+	}
+	if rf, ok := ret.Get(0).(func(string) int); ok {
+		r0 = rf(pciAddr)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(pciAddr)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOfedVersion provides a mock function with given fields:
+func (_m *HostUtils) GetOfedVersion() string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOfedVersion")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// GetPCIDeviceSysfsState provides a mock function with given fields: pciAddr
+func (_m *HostUtils) GetPCIDeviceSysfsState(pciAddr string) map[string]string {
+	ret := _m.Called(pciAddr)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPCIDeviceSysfsState")
+	}
+
+	var r0 map[string]string
+	if rf, ok := ret.Get(0).(func(string) map[string]string); ok {
+		r0 = rf(pciAddr)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]string)
+		}
+	}
+
+	return r0
+}
+
+// GetPCIDevices provides a mock function with given fields:
+func (_m *HostUtils) GetPCIDevices() ([]*pci.Device, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPCIDevices")
+	}
+
+	var r0 []*pci.Device
+	var r1 error
+	if rf, ok := ret.Get(0).(func() ([]*pci.Device, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() []*pci.Device); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*pci.Device)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPCIDriver provides a mock function with given fields: pciAddr
+func (_m *HostUtils) GetPCIDriver(pciAddr string) (string, error) {
+	ret := _m.Called(pciAddr)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPCIDriver")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (string, error)); ok {
+		return rf(pciAddr)
+	}
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(pciAddr)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(pciAddr)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPCILinkSpeed provides a mock function with given fields: pciAddr
+func (_m *HostUtils) GetPCILinkSpeed(pciAddr string) (int, error) {
+	ret := _m.Called(pciAddr)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPCILinkSpeed")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (int, error)); ok {
+		return rf(pciAddr)
+	}
+	if rf, ok := ret.Get(0).(func(string) int); ok {
+		r0 = rf(pciAddr)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(pciAddr)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPCILinkStatus provides a mock function with given fields: pciAddr
+func (_m *HostUtils) GetPCILinkStatus(pciAddr string) (*types.PCILinkStatus, error) {
+	ret := _m.Called(pciAddr)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPCILinkStatus")
+	}
+
+	var r0 *types.PCILinkStatus
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (*types.PCILinkStatus, error)); ok {
+		return rf(pciAddr)
+	}
+	if rf, ok := ret.Get(0).(func(string) *types.PCILinkStatus); ok {
+		r0 = rf(pciAddr)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.PCILinkStatus)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(pciAddr)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPCITopology provides a mock function with given fields: pciAddr
+func (_m *HostUtils) GetPCITopology(pciAddr string) (*types.PCITopology, error) {
+	ret := _m.Called(pciAddr)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPCITopology")
+	}
+
+	var r0 *types.PCITopology
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (*types.PCITopology, error)); ok {
+		return rf(pciAddr)
+	}
+	if rf, ok := ret.Get(0).(func(string) *types.PCITopology); ok {
+		r0 = rf(pciAddr)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.PCITopology)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(pciAddr)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPartAndSerialNumber provides a mock function with given fields: pciAddr
+func (_m *HostUtils) GetPartAndSerialNumber(pciAddr string) (string, string, error) {
+	ret := _m.Called(pciAddr)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPartAndSerialNumber")
+	}
+
+	var r0 string
+	var r1 string
+	var r2 error
+	if rf, ok := ret.Get(0).(func(string) (string, string, error)); ok {
+		return rf(pciAddr)
+	}
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(pciAddr)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) string); ok {
+		r1 = rf(pciAddr)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	if rf, ok := ret.Get(2).(func(string) error); ok {
+		r2 = rf(pciAddr)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetPortCounters provides a mock function with given fields: interfaceName
+func (_m *HostUtils) GetPortCounters(interfaceName string) (map[string]uint64, error) {
+	ret := _m.Called(interfaceName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPortCounters")
+	}
+
+	var r0 map[string]uint64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (map[string]uint64, error)); ok {
+		return rf(interfaceName)
+	}
+	if rf, ok := ret.Get(0).(func(string) map[string]uint64); ok {
+		r0 = rf(interfaceName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]uint64)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(interfaceName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPortSpeed provides a mock function with given fields: pciAddr
+func (_m *HostUtils) GetPortSpeed(pciAddr string) (int, error) {
+	ret := _m.Called(pciAddr)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPortSpeed")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (int, error)); ok {
+		return rf(pciAddr)
+	}
+	if rf, ok := ret.Get(0).(func(string) int); ok {
+		r0 = rf(pciAddr)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(pciAddr)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPtpClockIndex provides a mock function with given fields: pciAddr
+func (_m *HostUtils) GetPtpClockIndex(pciAddr string) (int, error) {
+	ret := _m.Called(pciAddr)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPtpClockIndex")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (int, error)); ok {
+		return rf(pciAddr)
+	}
+	if rf, ok := ret.Get(0).(func(string) int); ok {
+		r0 = rf(pciAddr)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(pciAddr)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetRDMADeviceName provides a mock function with given fields: pciAddr
+func (_m *HostUtils) GetRDMADeviceName(pciAddr string) string {
+	ret := _m.Called(pciAddr)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRDMADeviceName")
+	}
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(pciAddr)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
+// GetRdmaPort provides a mock function with given fields: rdmaDevice
+func (_m *HostUtils) GetRdmaPort(rdmaDevice string) (*types.RdmaPort, error) {
+	ret := _m.Called(rdmaDevice)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRdmaPort")
+	}
+
+	var r0 *types.RdmaPort
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (*types.RdmaPort, error)); ok {
+		return rf(rdmaDevice)
+	}
+	if rf, ok := ret.Get(0).(func(string) *types.RdmaPort); ok {
+		r0 = rf(rdmaDevice)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.RdmaPort)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(rdmaDevice)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetRingSizes provides a mock function with given fields: interfaceName
+func (_m *HostUtils) GetRingSizes(interfaceName string) (types.RingSizes, error) {
+	ret := _m.Called(interfaceName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRingSizes")
+	}
+
+	var r0 types.RingSizes
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (types.RingSizes, error)); ok {
+		return rf(interfaceName)
+	}
+	if rf, ok := ret.Get(0).(func(string) types.RingSizes); ok {
+		r0 = rf(interfaceName)
+	} else {
+		r0 = ret.Get(0).(types.RingSizes)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(interfaceName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetRootQdisc provides a mock function with given fields: interfaceName
+func (_m *HostUtils) GetRootQdisc(interfaceName string) (string, error) {
+	ret := _m.Called(interfaceName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRootQdisc")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (string, error)); ok {
+		return rf(interfaceName)
+	}
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(interfaceName)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(interfaceName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetRshimDevice provides a mock function with given fields: pciAddr
+func (_m *HostUtils) GetRshimDevice(pciAddr string) (string, error) {
+	ret := _m.Called(pciAddr)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRshimDevice")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (string, error)); ok {
+		return rf(pciAddr)
+	}
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(pciAddr)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(pciAddr)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetSysctl provides a mock function with given fields: name
+func (_m *HostUtils) GetSysctl(name string) (string, error) {
+	ret := _m.Called(name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetSysctl")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (string, error)); ok {
+		return rf(name)
+	}
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(name)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetTcBandwidth provides a mock function with given fields: interfaceName
+func (_m *HostUtils) GetTcBandwidth(interfaceName string) (string, error) {
+	ret := _m.Called(interfaceName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetTcBandwidth")
+	}
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (string, error)); ok {
+		return rf(interfaceName)
+	}
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(interfaceName)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(interfaceName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetTemperature provides a mock function with given fields: pciAddr
+func (_m *HostUtils) GetTemperature(pciAddr string) (int, error) {
+	ret := _m.Called(pciAddr)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetTemperature")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (int, error)); ok {
+		return rf(pciAddr)
+	}
+	if rf, ok := ret.Get(0).(func(string) int); ok {
+		r0 = rf(pciAddr)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(pciAddr)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetToolFailures provides a mock function with given fields: since, identifiers
+func (_m *HostUtils) GetToolFailures(since time.Time, identifiers []string) []types.ToolFailure {
+	ret := _m.Called(since, identifiers)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetToolFailures")
+	}
+
+	var r0 []types.ToolFailure
+	if rf, ok := ret.Get(0).(func(time.Time, []string) []types.ToolFailure); ok {
+		r0 = rf(since, identifiers)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]types.ToolFailure)
+		}
+	}
+
+	return r0
+}
+
+// GetTransceiver provides a mock function with given fields: interfaceName
+func (_m *HostUtils) GetTransceiver(interfaceName string) (*types.Transceiver, error) {
+	ret := _m.Called(interfaceName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetTransceiver")
+	}
+
+	var r0 *types.Transceiver
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (*types.Transceiver, error)); ok {
+		return rf(interfaceName)
+	}
+	if rf, ok := ret.Get(0).(func(string) *types.Transceiver); ok {
+		r0 = rf(interfaceName)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.Transceiver)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(interfaceName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetTrustAndPFC provides a mock function with given fields: interfaceName
+func (_m *HostUtils) GetTrustAndPFC(interfaceName string) (string, string, error) {
+	ret := _m.Called(interfaceName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetTrustAndPFC")
+	}
+
+	var r0 string
+	var r1 string
+	var r2 error
+	if rf, ok := ret.Get(0).(func(string) (string, string, error)); ok {
+		return rf(interfaceName)
+	}
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(interfaceName)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) string); ok {
+		r1 = rf(interfaceName)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	if rf, ok := ret.Get(2).(func(string) error); ok {
+		r2 = rf(interfaceName)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetVfMsixCount provides a mock function with given fields: vfPciAddr
+func (_m *HostUtils) GetVfMsixCount(vfPciAddr string) (int, error) {
+	ret := _m.Called(vfPciAddr)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetVfMsixCount")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (int, error)); ok {
+		return rf(vfPciAddr)
+	}
+	if rf, ok := ret.Get(0).(func(string) int); ok {
+		r0 = rf(vfPciAddr)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(vfPciAddr)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVfPciAddresses provides a mock function with given fields: pciAddr
+func (_m *HostUtils) GetVfPciAddresses(pciAddr string) ([]string, error) {
+	ret := _m.Called(pciAddr)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetVfPciAddresses")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) ([]string, error)); ok {
+		return rf(pciAddr)
+	}
+	if rf, ok := ret.Get(0).(func(string) []string); ok {
+		r0 = rf(pciAddr)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(pciAddr)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVfRepresentors provides a mock function with given fields: pciAddr
+func (_m *HostUtils) GetVfRepresentors(pciAddr string) ([]string, error) {
+	ret := _m.Called(pciAddr)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetVfRepresentors")
+	}
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) ([]string, error)); ok {
+		return rf(pciAddr)
+	}
+	if rf, ok := ret.Get(0).(func(string) []string); ok {
+		r0 = rf(pciAddr)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(pciAddr)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVfTotalMsix provides a mock function with given fields: pciAddr
+func (_m *HostUtils) GetVfTotalMsix(pciAddr string) (int, error) {
+	ret := _m.Called(pciAddr)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetVfTotalMsix")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (int, error)); ok {
+		return rf(pciAddr)
+	}
+	if rf, ok := ret.Get(0).(func(string) int); ok {
+		r0 = rf(pciAddr)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(pciAddr)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// InstallBFB provides a mock function with given fields: ctx, rshimDevice, bfbPath
+func (_m *HostUtils) InstallBFB(ctx context.Context, rshimDevice string, bfbPath string) error {
+	ret := _m.Called(ctx, rshimDevice, bfbPath)
+
+	if len(ret) == 0 {
+		panic("no return value specified for InstallBFB")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, rshimDevice, bfbPath)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// IsCongestionControlSupported provides a mock function with given fields: interfaceName
+func (_m *HostUtils) IsCongestionControlSupported(interfaceName string) bool {
+	ret := _m.Called(interfaceName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IsCongestionControlSupported")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string) bool); ok {
+		r0 = rf(interfaceName)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// IsEswitchManager provides a mock function with given fields: pciAddr
+func (_m *HostUtils) IsEswitchManager(pciAddr string) (bool, error) {
+	ret := _m.Called(pciAddr)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IsEswitchManager")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (bool, error)); ok {
+		return rf(pciAddr)
+	}
+	if rf, ok := ret.Get(0).(func(string) bool); ok {
+		r0 = rf(pciAddr)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(pciAddr)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// IsSriovVF provides a mock function with given fields: pciAddr
+func (_m *HostUtils) IsSriovVF(pciAddr string) bool {
+	ret := _m.Called(pciAddr)
+
+	if len(ret) == 0 {
+		panic("no return value specified for IsSriovVF")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string) bool); ok {
+		r0 = rf(pciAddr)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// QueryFirmware provides a mock function with given fields: pciAddr
+func (_m *HostUtils) QueryFirmware(pciAddr string) (*types.FirmwareQuery, error) {
+	ret := _m.Called(pciAddr)
+
+	if len(ret) == 0 {
+		panic("no return value specified for QueryFirmware")
+	}
+
+	var r0 *types.FirmwareQuery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (*types.FirmwareQuery, error)); ok {
+		return rf(pciAddr)
+	}
+	if rf, ok := ret.Get(0).(func(string) *types.FirmwareQuery); ok {
+		r0 = rf(pciAddr)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.FirmwareQuery)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(pciAddr)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// QueryFirmwareImage provides a mock function with given fields: imagePath
+func (_m *HostUtils) QueryFirmwareImage(imagePath string) (*types.FirmwareQuery, error) {
+	ret := _m.Called(imagePath)
+
+	if len(ret) == 0 {
+		panic("no return value specified for QueryFirmwareImage")
+	}
+
+	var r0 *types.FirmwareQuery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (*types.FirmwareQuery, error)); ok {
+		return rf(imagePath)
+	}
+	if rf, ok := ret.Get(0).(func(string) *types.FirmwareQuery); ok {
+		r0 = rf(imagePath)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*types.FirmwareQuery)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(imagePath)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// QueryNvConfig provides a mock function with given fields: ctx, pciAddr
+func (_m *HostUtils) QueryNvConfig(ctx context.Context, pciAddr string) (types.NvConfigQuery, error) {
+	ret := _m.Called(ctx, pciAddr)
+
+	if len(ret) == 0 {
+		panic("no return value specified for QueryNvConfig")
+	}
+
+	var r0 types.NvConfigQuery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (types.NvConfigQuery, error)); ok {
+		return rf(ctx, pciAddr)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) types.NvConfigQuery); ok {
+		r0 = rf(ctx, pciAddr)
+	} else {
+		r0 = ret.Get(0).(types.NvConfigQuery)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, pciAddr)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ReloadDevlinkDevice provides a mock function with given fields: pciAddr
+func (_m *HostUtils) ReloadDevlinkDevice(pciAddr string) error {
+	ret := _m.Called(pciAddr)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReloadDevlinkDevice")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(pciAddr)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ResetNicFirmware provides a mock function with given fields: ctx, pciAddr
 func (_m *HostUtils) ResetNicFirmware(ctx context.Context, pciAddr string) error {
 	ret := _m.Called(ctx, pciAddr)
 
@@ -392,6 +1569,24 @@ func (_m *HostUtils) ResetNvConfig(pciAddr string) error {
 	return r0
 }
 
+// ResetPortCounters provides a mock function with given fields: pciAddr
+func (_m *HostUtils) ResetPortCounters(pciAddr string) error {
+	ret := _m.Called(pciAddr)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResetPortCounters")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(pciAddr)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // ScheduleReboot provides a mock function with given fields:
 func (_m *HostUtils) ScheduleReboot() error {
 	ret := _m.Called()
@@ -410,6 +1605,150 @@ func (_m *HostUtils) ScheduleReboot() error {
 	return r0
 }
 
+// SetCoalescing provides a mock function with given fields: interfaceName, coalescing
+func (_m *HostUtils) SetCoalescing(interfaceName string, coalescing types.Coalescing) error {
+	ret := _m.Called(interfaceName, coalescing)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetCoalescing")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, types.Coalescing) error); ok {
+		r0 = rf(interfaceName, coalescing)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetCombinedChannels provides a mock function with given fields: interfaceName, combined
+func (_m *HostUtils) SetCombinedChannels(interfaceName string, combined int) error {
+	ret := _m.Called(interfaceName, combined)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetCombinedChannels")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, int) error); ok {
+		r0 = rf(interfaceName, combined)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetDcqcnParameter provides a mock function with given fields: interfaceName, name, value
+func (_m *HostUtils) SetDcqcnParameter(interfaceName string, name string, value int) error {
+	ret := _m.Called(interfaceName, name, value)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetDcqcnParameter")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, int) error); ok {
+		r0 = rf(interfaceName, name, value)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetDevlinkParam provides a mock function with given fields: pciAddr, name, value
+func (_m *HostUtils) SetDevlinkParam(pciAddr string, name string, value string) error {
+	ret := _m.Called(pciAddr, name, value)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetDevlinkParam")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, string) error); ok {
+		r0 = rf(pciAddr, name, value)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetDevlinkResourceSize provides a mock function with given fields: pciAddr, path, size
+func (_m *HostUtils) SetDevlinkResourceSize(pciAddr string, path string, size uint64) error {
+	ret := _m.Called(pciAddr, path, size)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetDevlinkResourceSize")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, uint64) error); ok {
+		r0 = rf(pciAddr, path, size)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetEcn provides a mock function with given fields: interfaceName, ecn
+func (_m *HostUtils) SetEcn(interfaceName string, ecn string) error {
+	ret := _m.Called(interfaceName, ecn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetEcn")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(interfaceName, ecn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetEswitchMode provides a mock function with given fields: pciAddr, mode
+func (_m *HostUtils) SetEswitchMode(pciAddr string, mode string) error {
+	ret := _m.Called(pciAddr, mode)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetEswitchMode")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(pciAddr, mode)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetEthtoolFeature provides a mock function with given fields: interfaceName, feature, enabled
+func (_m *HostUtils) SetEthtoolFeature(interfaceName string, feature string, enabled bool) error {
+	ret := _m.Called(interfaceName, feature, enabled)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetEthtoolFeature")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, bool) error); ok {
+		r0 = rf(interfaceName, feature, enabled)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SetMaxReadRequestSize provides a mock function with given fields: pciAddr, maxReadRequestSize
 func (_m *HostUtils) SetMaxReadRequestSize(pciAddr string, maxReadRequestSize int) error {
 	ret := _m.Called(pciAddr, maxReadRequestSize)
@@ -428,6 +1767,24 @@ func (_m *HostUtils) SetMaxReadRequestSize(pciAddr string, maxReadRequestSize in
 	return r0
 }
 
+// SetMtu provides a mock function with given fields: interfaceName, mtu
+func (_m *HostUtils) SetMtu(interfaceName string, mtu int) error {
+	ret := _m.Called(interfaceName, mtu)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetMtu")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, int) error); ok {
+		r0 = rf(interfaceName, mtu)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SetNvConfigParameter provides a mock function with given fields: pciAddr, paramName, paramValue
 func (_m *HostUtils) SetNvConfigParameter(pciAddr string, paramName string, paramValue string) error {
 	ret := _m.Called(pciAddr, paramName, paramValue)
@@ -446,6 +1803,60 @@ func (_m *HostUtils) SetNvConfigParameter(pciAddr string, paramName string, para
 	return r0
 }
 
+// SetRingSizes provides a mock function with given fields: interfaceName, rx, tx
+func (_m *HostUtils) SetRingSizes(interfaceName string, rx int, tx int) error {
+	ret := _m.Called(interfaceName, rx, tx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetRingSizes")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, int, int) error); ok {
+		r0 = rf(interfaceName, rx, tx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetSysctl provides a mock function with given fields: name, value
+func (_m *HostUtils) SetSysctl(name string, value string) error {
+	ret := _m.Called(name, value)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetSysctl")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(name, value)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetTcBandwidth provides a mock function with given fields: interfaceName, tcBandwidth
+func (_m *HostUtils) SetTcBandwidth(interfaceName string, tcBandwidth string) error {
+	ret := _m.Called(interfaceName, tcBandwidth)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetTcBandwidth")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(interfaceName, tcBandwidth)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SetTrustAndPFC provides a mock function with given fields: interfaceName, trust, pfc
 func (_m *HostUtils) SetTrustAndPFC(interfaceName string, trust string, pfc string) error {
 	ret := _m.Called(interfaceName, trust, pfc)
@@ -464,6 +1875,52 @@ func (_m *HostUtils) SetTrustAndPFC(interfaceName string, trust string, pfc stri
 	return r0
 }
 
+// SetVfMsixCount provides a mock function with given fields: vfPciAddr, count
+func (_m *HostUtils) SetVfMsixCount(vfPciAddr string, count int) error {
+	ret := _m.Called(vfPciAddr, count)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetVfMsixCount")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, int) error); ok {
+		r0 = rf(vfPciAddr, count)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SimulateNvConfigParameters provides a mock function with given fields: pciAddr, params
+func (_m *HostUtils) SimulateNvConfigParameters(pciAddr string, params map[string]string) (bool, error) {
+	ret := _m.Called(pciAddr, params)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SimulateNvConfigParameters")
+	}
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, map[string]string) (bool, error)); ok {
+		return rf(pciAddr, params)
+	}
+	if rf, ok := ret.Get(0).(func(string, map[string]string) bool); ok {
+		r0 = rf(pciAddr, params)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, map[string]string) error); ok {
+		r1 = rf(pciAddr, params)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewHostUtils creates a new instance of HostUtils. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewHostUtils(t interface {
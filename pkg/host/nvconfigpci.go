@@ -0,0 +1,90 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package host
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/types"
+)
+
+// NvConfigPCIAddress returns the PCI address of the function used for nv config operations of the device
+// the forced function is preferred, then the function discovered by the last nv config query and the first port
+func NvConfigPCIAddress(device *v1alpha1.NicDevice) string {
+	return nvConfigPCICandidates(device)[0]
+}
+
+// nvConfigPCICandidates returns the PCI addresses to probe for nv config operations, in the order of preference:
+// the forced function, the function discovered by the last nv config query, the device's ports
+func nvConfigPCICandidates(device *v1alpha1.NicDevice) []string {
+	candidates := []string{}
+
+	if device.Spec.NvConfigPCIFunction != nil {
+		candidates = append(candidates, pciAddressWithFunction(device.Status.Ports[0].PCI, *device.Spec.NvConfigPCIFunction))
+	}
+	if device.Status.NvConfigPCI != "" && !slices.Contains(candidates, device.Status.NvConfigPCI) {
+		candidates = append(candidates, device.Status.NvConfigPCI)
+	}
+	for _, port := range device.Status.Ports {
+		if !slices.Contains(candidates, port.PCI) {
+			candidates = append(candidates, port.PCI)
+		}
+	}
+
+	return candidates
+}
+
+// pciAddressWithFunction replaces the function of the PCI address, e.g. 0000:3b:00.0 -> 0000:3b:00.1
+func pciAddressWithFunction(pciAddr string, function int) string {
+	slot, _, _ := strings.Cut(pciAddr, ".")
+	return slot + "." + strconv.Itoa(function)
+}
+
+// queryNvConfig queries the nv config of the device, probing its PCI functions until one of them responds
+// the responding function is stored in the device's status and used for further nv config operations
+// returns the error of the most preferred function if none of them respond
+func (h hostManager) queryNvConfig(ctx context.Context, device *v1alpha1.NicDevice) (types.NvConfigQuery, error) {
+	var firstErr error
+
+	for _, pciAddr := range nvConfigPCICandidates(device) {
+		nvConfig, err := h.hostUtils.QueryNvConfig(ctx, pciAddr)
+		if err != nil {
+			if types.IsToolHangError(err) {
+				// Probing other functions would most likely hang as well
+				return types.NvConfigQuery{}, err
+			}
+			log.Log.V(2).Info("failed to query nv config, probing next PCI function", "device", device.Name, "pci", pciAddr, "err", err.Error())
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		if device.Status.NvConfigPCI != pciAddr {
+			log.Log.Info("using PCI function for nv config operations", "device", device.Name, "pci", pciAddr)
+			device.Status.NvConfigPCI = pciAddr
+		}
+		return nvConfig, nil
+	}
+
+	return types.NvConfigQuery{}, firstErr
+}
@@ -0,0 +1,239 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package host
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+)
+
+// nvParamType describes how values of a nv config parameter should be interpreted
+type nvParamType int
+
+const (
+	// nvParamTypeUnknown is used for parameters the operator doesn't model, e.g. raw nv config params.
+	// The type of such parameters is inferred from the values reported by mstconfig
+	nvParamTypeUnknown nvParamType = iota
+	nvParamTypeBool
+	nvParamTypeEnum
+	nvParamTypeUint
+	nvParamTypeBitmask
+)
+
+// nvParamTypes contains type metadata for the nv config parameters the operator sets itself
+var nvParamTypes = map[string]nvParamType{
+	consts.SriovEnabledParam:        nvParamTypeBool,
+	consts.SriovNumOfVfsParam:       nvParamTypeUint,
+	consts.NumVfMsixParam:           nvParamTypeUint,
+	consts.DynamicVfMsixTableParam:  nvParamTypeBool,
+	consts.LinkTypeP1Param:          nvParamTypeEnum,
+	consts.LinkTypeP2Param:          nvParamTypeEnum,
+	consts.MaxAccOutReadParam:       nvParamTypeUint,
+	consts.PciWrOrderingParam:       nvParamTypeEnum,
+	consts.PciGenParam:              nvParamTypeUint,
+	consts.PciWidthParam:            nvParamTypeUint,
+	consts.RoceCcPrioMaskP1Param:    nvParamTypeBitmask,
+	consts.RoceCcPrioMaskP2Param:    nvParamTypeBitmask,
+	consts.CnpDscpP1Param:           nvParamTypeUint,
+	consts.CnpDscpP2Param:           nvParamTypeUint,
+	consts.Cnp802pPrioP1Param:       nvParamTypeUint,
+	consts.Cnp802pPrioP2Param:       nvParamTypeUint,
+	consts.AtsEnabledParam:          nvParamTypeBool,
+	consts.AdvancedPCISettingsParam: nvParamTypeBool,
+	consts.BootOptionRomEnP1Param:   nvParamTypeBool,
+	consts.BootOptionRomEnP2Param:   nvParamTypeBool,
+	consts.ExpRomPxeEnableParam:     nvParamTypeBool,
+	consts.ExpRomUefiX86EnableParam: nvParamTypeBool,
+	consts.BootVlanEnP1Param:        nvParamTypeBool,
+	consts.BootVlanEnP2Param:        nvParamTypeBool,
+	consts.BootVlanP1Param:          nvParamTypeUint,
+	consts.BootVlanP2Param:          nvParamTypeUint,
+	consts.BootRetryCntP1Param:      nvParamTypeUint,
+	consts.BootRetryCntP2Param:      nvParamTypeUint,
+	consts.RealTimeClockEnableParam: nvParamTypeBool,
+	consts.FlexParserProfileParam:   nvParamTypeUint,
+	consts.ProgParseGraphParam:      nvParamTypeBool,
+	consts.LogDcrHashTableSizeParam: nvParamTypeUint,
+	consts.DcrLifoSizeParam:         nvParamTypeUint,
+}
+
+var boolTrueAliases = []string{"1", "true", "enabled", "enable", "yes", "on"}
+var boolFalseAliases = []string{"0", "false", "disabled", "disable", "no", "off"}
+
+// getNvParamType returns the type of the nv config parameter
+// if parameter is not known, tries to infer its type from the values reported by the firmware
+func getNvParamType(paramName string, reportedValues []string) nvParamType {
+	if paramType, found := nvParamTypes[paramName]; found {
+		return paramType
+	}
+
+	for _, value := range reportedValues {
+		value = strings.ToLower(value)
+		if value == "true" || value == "false" {
+			return nvParamTypeBool
+		}
+	}
+
+	return nvParamTypeUnknown
+}
+
+// parseNvParamUint parses a numeric nv config value that can be represented in decimal, hex (0x) or binary (0b) notation
+func parseNvParamUint(value string) (uint64, bool) {
+	value = strings.ToLower(value)
+
+	base := 10
+	switch {
+	case strings.HasPrefix(value, "0x"):
+		base = 16
+		value = strings.TrimPrefix(value, "0x")
+	case strings.HasPrefix(value, "0b"):
+		base = 2
+		value = strings.TrimPrefix(value, "0b")
+	}
+
+	parsed, err := strconv.ParseUint(value, base, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return parsed, true
+}
+
+// normalizeNvParamValue converts a nv config value to its canonical form according to the parameter type
+// bool values are converted to 0/1, numeric values to their decimal representation, other values are lowercased
+func normalizeNvParamValue(paramType nvParamType, value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	switch paramType {
+	case nvParamTypeBool:
+		if slices.Contains(boolTrueAliases, value) {
+			return consts.NvParamTrue
+		}
+		if slices.Contains(boolFalseAliases, value) {
+			return consts.NvParamFalse
+		}
+	default:
+		// Enum values are reported both as a string alias and a numeric value, e.g. ETH(2),
+		// so their numeric values are normalized the same way as uint and bitmask values
+		if parsed, ok := parseNvParamUint(value); ok {
+			return strconv.FormatUint(parsed, 10)
+		}
+	}
+
+	return value
+}
+
+// NvParamValueMatches returns true if the desired value of the nv config parameter matches one of the values reported by the firmware
+// reported values can contain both the string alias and the numeric value of the parameter
+func NvParamValueMatches(paramName string, desiredValue string, reportedValues []string) bool {
+	paramType := getNvParamType(paramName, reportedValues)
+	normalizedDesiredValue := normalizeNvParamValue(paramType, desiredValue)
+
+	for _, reportedValue := range reportedValues {
+		if normalizeNvParamValue(paramType, reportedValue) == normalizedDesiredValue {
+			return true
+		}
+	}
+
+	return false
+}
+
+// nvParamDependencies lists nv config parameters that only become writable after the listed parameters are set
+// and can't be matched by the enable flag naming convention
+var nvParamDependencies = map[string][]string{
+	consts.SriovNumOfVfsParam: {consts.SriovEnabledParam},
+	// Per-port enable flags have the port suffix after the _EN suffix
+	consts.BootVlanP1Param: {consts.BootVlanEnP1Param},
+	consts.BootVlanP2Param: {consts.BootVlanEnP2Param},
+}
+
+// enableFlagSuffixes are the suffixes of the nv config parameters that enable a feature,
+// parameters of the feature share the flag's prefix, e.g. PF_BAR2_ENABLE and PF_BAR2_SIZE
+var enableFlagSuffixes = []string{"_EN", "_ENABLE", "_ENABLED"}
+
+// nvParamPrerequisites returns the parameters from the given set that have to be applied before the parameter
+func nvParamPrerequisites(paramName string, params map[string]string) []string {
+	prerequisites := []string{}
+	for candidate := range params {
+		if candidate == paramName {
+			continue
+		}
+
+		if slices.Contains(nvParamDependencies[paramName], candidate) {
+			prerequisites = append(prerequisites, candidate)
+			continue
+		}
+
+		for _, suffix := range enableFlagSuffixes {
+			feature, isFlag := strings.CutSuffix(candidate, suffix)
+			if isFlag && strings.HasPrefix(paramName, feature+"_") {
+				prerequisites = append(prerequisites, candidate)
+				break
+			}
+		}
+	}
+
+	sort.Strings(prerequisites)
+	return prerequisites
+}
+
+// orderNvParams splits the parameters into stages, each stage only depends on the parameters of the previous stages
+// parameters are sorted by name within the stage
+func orderNvParams(params map[string]string) [][]string {
+	stageOf := map[string]int{}
+	stages := [][]string{}
+
+	remaining := map[string][]string{}
+	for param := range params {
+		remaining[param] = nvParamPrerequisites(param, params)
+	}
+
+	for len(remaining) != 0 {
+		stage := []string{}
+		for param, prerequisites := range remaining {
+			ready := true
+			for _, prerequisite := range prerequisites {
+				if _, applied := stageOf[prerequisite]; !applied {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				stage = append(stage, param)
+			}
+		}
+
+		if len(stage) == 0 {
+			// Dependency cycle, the remaining parameters are applied together
+			for param := range remaining {
+				stage = append(stage, param)
+			}
+		}
+
+		sort.Strings(stage)
+		for _, param := range stage {
+			stageOf[param] = len(stages)
+			delete(remaining, param)
+		}
+		stages = append(stages, stage)
+	}
+
+	return stages
+}
@@ -0,0 +1,110 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package host
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+)
+
+var _ = Describe("nv param translation", func() {
+	Describe("normalizeNvParamValue", func() {
+		It("should normalize bool aliases", func() {
+			for _, value := range []string{"True", "1", "ENABLED", "yes"} {
+				Expect(normalizeNvParamValue(nvParamTypeBool, value)).To(Equal(consts.NvParamTrue))
+			}
+			for _, value := range []string{"False", "0", "DISABLED", "no"} {
+				Expect(normalizeNvParamValue(nvParamTypeBool, value)).To(Equal(consts.NvParamFalse))
+			}
+		})
+		It("should normalize numeric values in different notations", func() {
+			Expect(normalizeNvParamValue(nvParamTypeBitmask, "0xff")).To(Equal("255"))
+			Expect(normalizeNvParamValue(nvParamTypeBitmask, "0b1000")).To(Equal("8"))
+			Expect(normalizeNvParamValue(nvParamTypeUint, "044")).To(Equal("44"))
+		})
+		It("should lowercase enum string aliases", func() {
+			Expect(normalizeNvParamValue(nvParamTypeEnum, "ETH")).To(Equal("eth"))
+		})
+	})
+
+	Describe("NvParamValueMatches", func() {
+		It("should match known bool params regardless of the alias", func() {
+			Expect(NvParamValueMatches(consts.SriovEnabledParam, "True", []string{"1"})).To(BeTrue())
+			Expect(NvParamValueMatches(consts.SriovEnabledParam, "1", []string{"true", "1"})).To(BeTrue())
+			Expect(NvParamValueMatches(consts.SriovEnabledParam, "0", []string{"true", "1"})).To(BeFalse())
+		})
+		It("should infer bool type for unknown params from reported values", func() {
+			Expect(NvParamValueMatches("REAL_TIME_CLOCK_ENABLE", "ENABLED", []string{"true", "1"})).To(BeTrue())
+			Expect(NvParamValueMatches("REAL_TIME_CLOCK_ENABLE", "False", []string{"true", "1"})).To(BeFalse())
+		})
+		It("should match enum params by alias or numeric value", func() {
+			Expect(NvParamValueMatches(consts.LinkTypeP1Param, "ETH", []string{"eth", "2"})).To(BeTrue())
+			Expect(NvParamValueMatches(consts.LinkTypeP1Param, "2", []string{"eth", "2"})).To(BeTrue())
+			Expect(NvParamValueMatches(consts.LinkTypeP1Param, "1", []string{"eth", "2"})).To(BeFalse())
+		})
+		It("should match bitmask params in hex notation", func() {
+			Expect(NvParamValueMatches(consts.RoceCcPrioMaskP1Param, "0xff", []string{"255"})).To(BeTrue())
+		})
+	})
+
+	Describe("nvParamPrerequisites", func() {
+		It("should match features by the enable flag prefix", func() {
+			params := map[string]string{"PF_BAR2_ENABLE": "1", "PF_BAR2_SIZE": "4", "PF_LOG_BAR_SIZE": "5"}
+			Expect(nvParamPrerequisites("PF_BAR2_SIZE", params)).To(Equal([]string{"PF_BAR2_ENABLE"}))
+			Expect(nvParamPrerequisites("PF_LOG_BAR_SIZE", params)).To(BeEmpty())
+			Expect(nvParamPrerequisites("PF_BAR2_ENABLE", params)).To(BeEmpty())
+		})
+		It("should use the explicit dependencies", func() {
+			params := map[string]string{consts.SriovEnabledParam: "1", consts.SriovNumOfVfsParam: "8"}
+			Expect(nvParamPrerequisites(consts.SriovNumOfVfsParam, params)).To(Equal([]string{consts.SriovEnabledParam}))
+
+			params = map[string]string{consts.BootVlanEnP1Param: "1", consts.BootVlanP1Param: "100", consts.BootVlanEnP2Param: "1"}
+			Expect(nvParamPrerequisites(consts.BootVlanP1Param, params)).To(Equal([]string{consts.BootVlanEnP1Param}))
+		})
+		It("should ignore prerequisites outside of the set", func() {
+			Expect(nvParamPrerequisites(consts.SriovNumOfVfsParam, map[string]string{consts.SriovNumOfVfsParam: "8"})).To(BeEmpty())
+		})
+	})
+
+	Describe("orderNvParams", func() {
+		It("should apply enable flags before the dependent parameters", func() {
+			params := map[string]string{
+				"PF_BAR2_SIZE":            "4",
+				"PF_BAR2_ENABLE":          "1",
+				consts.SriovNumOfVfsParam: "8",
+				consts.SriovEnabledParam:  "1",
+				consts.AtsEnabledParam:    "0",
+			}
+			Expect(orderNvParams(params)).To(Equal([][]string{
+				{consts.AtsEnabledParam, "PF_BAR2_ENABLE", consts.SriovEnabledParam},
+				{consts.SriovNumOfVfsParam, "PF_BAR2_SIZE"},
+			}))
+		})
+		It("should apply nested dependencies in separate stages", func() {
+			params := map[string]string{"FEATURE_EN": "1", "FEATURE_SUB_EN": "1", "FEATURE_SUB_SIZE": "2"}
+			Expect(orderNvParams(params)).To(Equal([][]string{
+				{"FEATURE_EN"},
+				{"FEATURE_SUB_EN"},
+				{"FEATURE_SUB_SIZE"},
+			}))
+		})
+		It("should return no stages for an empty set", func() {
+			Expect(orderNvParams(map[string]string{})).To(BeEmpty())
+		})
+	})
+})
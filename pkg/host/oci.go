@@ -0,0 +1,385 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package host
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/types"
+)
+
+const ociScheme = "oci://"
+const ociDefaultTag = "latest"
+const ociTitleAnnotation = "org.opencontainers.image.title"
+
+// ociManifestMediaTypes are the manifest types accepted from the registries, artifacts pushed by oras use the OCI one
+var ociManifestMediaTypes = []string{
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// isOCIReference returns true if the binary url references an OCI artifact, e.g. oci://registry.example.com/firmware/cx6:22.41.1000
+func isOCIReference(binUrl string) bool {
+	return strings.HasPrefix(binUrl, ociScheme)
+}
+
+// ociReference is a parsed reference of an OCI artifact
+type ociReference struct {
+	registry   string
+	repository string
+	// reference is the tag or the digest of the artifact
+	reference string
+}
+
+// parseOCIReference parses the oci://<registry>/<repository>[:<tag>|@<digest>] reference
+func parseOCIReference(binUrl string) (ociReference, error) {
+	registry, name, found := strings.Cut(strings.TrimPrefix(binUrl, ociScheme), "/")
+	if !found || registry == "" || name == "" {
+		return ociReference{}, fmt.Errorf("invalid OCI reference %s, expected oci://<registry>/<repository>:<tag>", binUrl)
+	}
+
+	ref := ociReference{registry: registry, repository: name, reference: ociDefaultTag}
+	if repository, digest, found := strings.Cut(name, "@"); found {
+		ref.repository, ref.reference = repository, digest
+	} else if separator := strings.LastIndex(name, ":"); separator > strings.LastIndex(name, "/") {
+		ref.repository, ref.reference = name[:separator], name[separator+1:]
+	}
+	if ref.repository == "" || ref.reference == "" {
+		return ociReference{}, fmt.Errorf("invalid OCI reference %s, expected oci://<registry>/<repository>:<tag>", binUrl)
+	}
+
+	return ref, nil
+}
+
+// ociDescriptor describes a layer of the artifact's manifest
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type ociManifest struct {
+	Layers []ociDescriptor `json:"layers"`
+}
+
+// ociArtifact is a resolved firmware artifact with its layers
+type ociArtifact struct {
+	ref ociReference
+	// binary is the layer with the firmware binary, signature is its detached signature, nil if the artifact has none
+	binary    ociDescriptor
+	signature *ociDescriptor
+	// title is the file name of the firmware binary
+	title string
+	// authorization is the value of the Authorization header accepted by the registry, empty for anonymous access
+	authorization string
+	// credentials of the registry, the blobs can require an authorization the manifest didn't
+	credentials types.RegistryCredentials
+}
+
+// sourceTransport is the http client and the plain http registries of the firmware source being cached
+type sourceTransport struct {
+	client              *http.Client
+	plainHTTPRegistries []string
+}
+
+type sourceTransportKey struct{}
+
+// withSourceTransport returns a context with the http client trusting the source's CA bundle in addition to the system CAs
+// returns types.IncorrectSpecError if the CA bundle has no valid certificate
+func (f *firmwareManager) withSourceTransport(ctx context.Context, source *v1alpha1.NicFirmwareSource) (context.Context, error) {
+	transport := sourceTransport{client: f.client, plainHTTPRegistries: source.Spec.PlainHTTPRegistries}
+	if source.Spec.CABundle != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(source.Spec.CABundle)) {
+			return ctx, types.IncorrectSpecError(fmt.Sprintf("caBundle of NicFirmwareSource %s has no valid PEM certificate", source.Name))
+		}
+
+		httpTransport := http.DefaultTransport.(*http.Transport).Clone()
+		httpTransport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+		transport.client = &http.Client{Timeout: f.client.Timeout, Transport: httpTransport}
+	}
+	return context.WithValue(ctx, sourceTransportKey{}, transport), nil
+}
+
+// sourceTransportFrom returns the transport of the firmware source of the context, the default client if there is none
+func sourceTransportFrom(ctx context.Context, defaultClient *http.Client) sourceTransport {
+	transport, ok := ctx.Value(sourceTransportKey{}).(sourceTransport)
+	if !ok {
+		return sourceTransport{client: defaultClient}
+	}
+	return transport
+}
+
+// ociFileName returns the cache file name of the firmware binary of the OCI artifact
+// the name of the binary is taken from the title of its layer, the artifact is resolved only if it's not cached yet
+func (f *firmwareManager) ociFileName(ctx context.Context, binUrl string, credentials types.RegistryCredentials) (string, error) {
+	_, err := parseOCIReference(binUrl)
+	if err != nil {
+		return "", types.IncorrectSpecError(err.Error())
+	}
+
+	prefix := binaryUrlHash(binUrl) + "-"
+	matches, err := filepath.Glob(filepath.Join(f.cacheConfig.Dir, "*", prefix+"*"))
+	if err != nil {
+		return "", err
+	}
+	for _, match := range matches {
+		name := filepath.Base(match)
+		if firmwareCacheEntryName(name) == name && !strings.HasSuffix(name, firmwareTempFileExtension) {
+			return name, nil
+		}
+	}
+
+	artifact, err := f.resolveOCIArtifact(ctx, binUrl, credentials)
+	if err != nil {
+		return "", err
+	}
+	return prefix + artifact.title, nil
+}
+
+// pullOCIArtifact saves the firmware binary of the OCI artifact to the file path
+// the detached signature of the binary is saved next to it if the artifact has one
+func (f *firmwareManager) pullOCIArtifact(ctx context.Context, binUrl string, filePath string, credentials types.RegistryCredentials) error {
+	log.Log.Info("pulling firmware binary from the OCI registry", "reference", binUrl)
+
+	artifact, err := f.resolveOCIArtifact(ctx, binUrl, credentials)
+	if err != nil {
+		return err
+	}
+
+	if artifact.signature != nil {
+		err = f.pullOCIBlob(ctx, artifact, *artifact.signature, filePath+firmwareSignatureExtension)
+		if err != nil {
+			return err
+		}
+	}
+
+	return f.pullOCIBlob(ctx, artifact, artifact.binary, filePath)
+}
+
+// resolveOCIArtifact fetches the manifest of the artifact and finds the layers of the firmware binary and its signature
+// the binary is the first layer titled with a .bin or .zip file name
+func (f *firmwareManager) resolveOCIArtifact(ctx context.Context, binUrl string, credentials types.RegistryCredentials) (*ociArtifact, error) {
+	ref, err := parseOCIReference(binUrl)
+	if err != nil {
+		return nil, types.IncorrectSpecError(err.Error())
+	}
+	artifact := &ociArtifact{ref: ref, credentials: credentials}
+
+	resp, err := f.ociRequest(ctx, artifact, "manifests/"+ref.reference, strings.Join(ociManifestMediaTypes, ", "))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	manifest := ociManifest{}
+	err = json.NewDecoder(io.LimitReader(resp.Body, 4<<20)).Decode(&manifest)
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest of OCI artifact %s: %w", binUrl, err)
+	}
+
+	for _, layer := range manifest.Layers {
+		title := filepath.Base(layer.Annotations[ociTitleAnnotation])
+		extension := strings.ToLower(filepath.Ext(title))
+		if extension == firmwareImageExtension || extension == firmwareArchiveExtension {
+			artifact.binary, artifact.title = layer, title
+			break
+		}
+	}
+	if artifact.title == "" {
+		return nil, types.IncorrectSpecError(fmt.Sprintf("OCI artifact %s has no layer titled with a %s or %s file name",
+			binUrl, firmwareImageExtension, firmwareArchiveExtension))
+	}
+
+	for _, layer := range manifest.Layers {
+		if filepath.Base(layer.Annotations[ociTitleAnnotation]) == artifact.title+firmwareSignatureExtension {
+			signature := layer
+			artifact.signature = &signature
+			break
+		}
+	}
+
+	return artifact, nil
+}
+
+// pullOCIBlob saves the blob of the artifact to the file path, the blob has to match its digest
+func (f *firmwareManager) pullOCIBlob(ctx context.Context, artifact *ociArtifact, blob ociDescriptor, filePath string) error {
+	algorithm, digest, _ := strings.Cut(blob.Digest, ":")
+	if algorithm != "sha256" {
+		return fmt.Errorf("unsupported digest %s of OCI artifact layer", blob.Digest)
+	}
+
+	resp, err := f.ociRequest(ctx, artifact, "blobs/"+blob.Digest, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	err = saveBinary(filePath, resp.Body, digest)
+	if err != nil {
+		return fmt.Errorf("failed to pull layer %s of OCI artifact %s: %w", blob.Digest, artifact.ref.repository, err)
+	}
+	return nil
+}
+
+// ociRequest sends a GET request for the path of the artifact's repository, over plain http for the source's plain http registries
+// if the registry requires authorization, obtains it with the artifact's registry credentials and stores it in the artifact
+// returns the response with the 200 status
+func (f *firmwareManager) ociRequest(ctx context.Context, artifact *ociArtifact, path string, accept string) (*http.Response, error) {
+	transport := sourceTransportFrom(ctx, f.client)
+	scheme := "https"
+	if slices.Contains(transport.plainHTTPRegistries, artifact.ref.registry) {
+		scheme = "http"
+	}
+	requestUrl := fmt.Sprintf("%s://%s/v2/%s/%s", scheme, artifact.ref.registry, artifact.ref.repository, path)
+	send := func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestUrl, nil)
+		if err != nil {
+			return nil, err
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if artifact.authorization != "" {
+			req.Header.Set("Authorization", artifact.authorization)
+		}
+		return transport.client.Do(req)
+	}
+
+	resp, err := send()
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && artifact.authorization == "" {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+
+		artifact.authorization, err = f.ociAuthorization(ctx, artifact.ref, challenge, artifact.credentials[artifact.ref.registry])
+		if err != nil {
+			return nil, err
+		}
+		resp, err = send()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to get %s of OCI artifact %s/%s, status %d", path, artifact.ref.registry, artifact.ref.repository, resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// ociAuthorization returns the Authorization header for the registry's challenge
+// bearer tokens are requested from the registry's token service, see the distribution token authentication spec
+func (f *firmwareManager) ociAuthorization(ctx context.Context, ref ociReference, challenge string, auth types.RegistryAuth) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	basicAuth := ""
+	if auth.Username != "" || auth.Password != "" {
+		basicAuth = "Basic " + base64.StdEncoding.EncodeToString([]byte(auth.Username+":"+auth.Password))
+	}
+
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if basicAuth == "" {
+			return "", fmt.Errorf("registry %s requires credentials, add a pull secret to the firmware source", ref.registry)
+		}
+		return basicAuth, nil
+	case "bearer":
+	default:
+		return "", fmt.Errorf("unsupported authorization challenge %q of registry %s", challenge, ref.registry)
+	}
+
+	challengeParams := parseChallengeParams(params)
+	tokenUrl, err := url.Parse(challengeParams["realm"])
+	if err != nil || tokenUrl.Host == "" {
+		return "", fmt.Errorf("invalid token realm %q of registry %s", challengeParams["realm"], ref.registry)
+	}
+	query := tokenUrl.Query()
+	if service := challengeParams["service"]; service != "" {
+		query.Set("service", service)
+	}
+	query.Set("scope", fmt.Sprintf("repository:%s:pull", ref.repository))
+	tokenUrl.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenUrl.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if basicAuth != "" {
+		req.Header.Set("Authorization", basicAuth)
+	}
+	resp, err := sourceTransportFrom(ctx, f.client).client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get a pull token of registry %s, status %d", ref.registry, resp.StatusCode)
+	}
+
+	token := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	err = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&token)
+	if err != nil {
+		return "", fmt.Errorf("invalid pull token of registry %s: %w", ref.registry, err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	if token.Token == "" {
+		return "", fmt.Errorf("registry %s returned an empty pull token", ref.registry)
+	}
+
+	return "Bearer " + token.Token, nil
+}
+
+// parseChallengeParams parses the key="value" parameters of the WWW-Authenticate header
+func parseChallengeParams(params string) map[string]string {
+	result := map[string]string{}
+	for params != "" {
+		var key, value string
+		key, params, _ = strings.Cut(strings.TrimLeft(params, ", "), "=")
+		if strings.HasPrefix(params, `"`) {
+			value, params, _ = strings.Cut(params[1:], `"`)
+		} else {
+			value, params, _ = strings.Cut(params, ",")
+		}
+		result[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+	return result
+}
@@ -0,0 +1,331 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package host
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/mock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/host/mocks"
+	"github.com/Mellanox/nic-configuration-operator/pkg/types"
+)
+
+var _ = Describe("parseOCIReference", func() {
+	It("should parse the tag of the reference", func() {
+		ref, err := parseOCIReference("oci://registry.example.com:5000/firmware/cx6:22.41.1000")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ref).To(Equal(ociReference{registry: "registry.example.com:5000", repository: "firmware/cx6", reference: "22.41.1000"}))
+	})
+	It("should parse the digest of the reference", func() {
+		ref, err := parseOCIReference("oci://registry.example.com/firmware/cx6@sha256:abcd")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ref).To(Equal(ociReference{registry: "registry.example.com", repository: "firmware/cx6", reference: "sha256:abcd"}))
+	})
+	It("should default to the latest tag", func() {
+		ref, err := parseOCIReference("oci://registry.example.com:5000/firmware/cx6")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ref.repository).To(Equal("firmware/cx6"))
+		Expect(ref.reference).To(Equal("latest"))
+	})
+	It("should refuse a reference without a repository", func() {
+		_, err := parseOCIReference("oci://registry.example.com")
+		Expect(err).To(HaveOccurred())
+		_, err = parseOCIReference("oci://registry.example.com/firmware/cx6:")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("FirmwareManager with OCI artifacts", func() {
+	const (
+		username = "puller"
+		password = "secret"
+		token    = "pull-token"
+	)
+
+	var (
+		mockHostUtils *mocks.HostUtils
+		manager       *firmwareManager
+		server        *httptest.Server
+		requests      atomic.Int32
+		cacheDir      string
+		device        *v1alpha1.NicDevice
+		source        *v1alpha1.NicFirmwareSource
+		binUrl        string
+		credentials   types.RegistryCredentials
+
+		layers []ociDescriptor
+		blobs  map[string][]byte
+
+		handler            http.HandlerFunc
+		anonymousManifests bool
+	)
+
+	addLayer := func(title string, content []byte) {
+		digest := sha256.Sum256(content)
+		descriptor := ociDescriptor{
+			MediaType:   "application/octet-stream",
+			Digest:      "sha256:" + hex.EncodeToString(digest[:]),
+			Size:        int64(len(content)),
+			Annotations: map[string]string{ociTitleAnnotation: title},
+		}
+		layers = append(layers, descriptor)
+		blobs[descriptor.Digest] = content
+	}
+
+	BeforeEach(func() {
+		requests.Store(0)
+		layers = nil
+		blobs = map[string][]byte{}
+		anonymousManifests = false
+
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			if r.URL.Path == "/token" {
+				user, pass, ok := r.BasicAuth()
+				if !ok || user != username || pass != password {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				Expect(r.URL.Query().Get("scope")).To(Equal("repository:firmware/cx6:pull"))
+				_ = json.NewEncoder(w).Encode(map[string]string{"token": token})
+				return
+			}
+			anonymous := anonymousManifests && strings.Contains(r.URL.Path, "/manifests/")
+			if !anonymous && r.Header.Get("Authorization") != "Bearer "+token {
+				scheme := "https"
+				if r.TLS == nil {
+					scheme = "http"
+				}
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s://%s/token",service="registry"`, scheme, r.Host))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			switch {
+			case r.URL.Path == "/v2/firmware/cx6/manifests/22.41.1000":
+				w.Header().Set("Content-Type", ociManifestMediaTypes[0])
+				_ = json.NewEncoder(w).Encode(ociManifest{Layers: layers})
+			case strings.HasPrefix(r.URL.Path, "/v2/firmware/cx6/blobs/"):
+				blob, found := blobs[strings.TrimPrefix(r.URL.Path, "/v2/firmware/cx6/blobs/")]
+				if !found {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				_, _ = w.Write(blob)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}
+		server = httptest.NewTLSServer(handler)
+		DeferCleanup(server.Close)
+
+		cacheDir = GinkgoT().TempDir()
+		mockHostUtils = &mocks.HostUtils{}
+		mockHostUtils.On("QueryFirmwareImage", mock.MatchedBy(func(path string) bool {
+			return strings.HasSuffix(path, "fw-cx6.bin")
+		})).Return(&types.FirmwareQuery{Version: "22.41.1000", PSID: "mt_0000000222"}, nil)
+		manager = NewFirmwareManager(FirmwareCacheConfig{Dir: cacheDir}, mockHostUtils).(*firmwareManager)
+		manager.client = server.Client()
+
+		registry := strings.TrimPrefix(server.URL, "https://")
+		binUrl = fmt.Sprintf("oci://%s/firmware/cx6:22.41.1000", registry)
+		digest := sha256.Sum256([]byte("cx6 image"))
+		source = &v1alpha1.NicFirmwareSource{
+			ObjectMeta: metav1.ObjectMeta{Name: "fw-source"},
+			Spec: v1alpha1.NicFirmwareSourceSpec{
+				BinUrlSources: []string{binUrl},
+				Verification:  &v1alpha1.FirmwareVerificationSpec{SHA256: map[string]string{binUrl: hex.EncodeToString(digest[:])}},
+			},
+		}
+
+		credentials = types.RegistryCredentials{}
+		auth := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+		Expect(credentials.ParseDockerConfigJSON([]byte(fmt.Sprintf(`{"auths":{"https://%s/v1/":{"auth":"%s"}}}`, registry, auth)))).To(Succeed())
+
+		device = &v1alpha1.NicDevice{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-device"},
+			Status: v1alpha1.NicDeviceStatus{
+				PSID:            "mt_0000000222",
+				FirmwareVersion: "22.39.1002",
+				Ports:           []v1alpha1.NicDevicePortSpec{{PCI: pciAddress}},
+			},
+		}
+	})
+
+	It("should parse the registry credentials of the docker config", func() {
+		registry := strings.TrimPrefix(server.URL, "https://")
+		Expect(credentials).To(Equal(types.RegistryCredentials{registry: {Username: username, Password: password}}))
+	})
+	It("should pull the titled layer of the artifact and reuse it from the cache", func() {
+		addLayer("release-notes.txt", []byte("release notes"))
+		addLayer("fw-cx6.bin", []byte("cx6 image"))
+
+		imagePath, err := manager.ValidateRequestedFirmware(context.Background(), device, source, credentials)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(imagePath).To(HaveSuffix("fw-cx6.bin"))
+		content, err := os.ReadFile(imagePath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(content)).To(Equal("cx6 image"))
+
+		pulled := requests.Load()
+		imagePath, err = manager.ValidateRequestedFirmware(context.Background(), device, source, credentials)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(imagePath).To(HaveSuffix("fw-cx6.bin"))
+		Expect(requests.Load()).To(Equal(pulled))
+	})
+	It("should fail without the registry credentials", func() {
+		addLayer("fw-cx6.bin", []byte("cx6 image"))
+
+		_, err := manager.ValidateRequestedFirmware(context.Background(), device, source, nil)
+		Expect(err).To(HaveOccurred())
+	})
+	It("should return IncorrectSpec error if the artifact has no firmware layer", func() {
+		addLayer("release-notes.txt", []byte("release notes"))
+
+		_, err := manager.ValidateRequestedFirmware(context.Background(), device, source, credentials)
+		Expect(types.IsIncorrectSpecError(err)).To(BeTrue())
+	})
+	It("should refuse the layer not matching its digest", func() {
+		addLayer("fw-cx6.bin", []byte("cx6 image"))
+		blobs[layers[0].Digest] = []byte("tampered image")
+
+		_, err := manager.ValidateRequestedFirmware(context.Background(), device, source, credentials)
+		Expect(err).To(HaveOccurred())
+
+		files, err := filepath.Glob(filepath.Join(cacheDir, "fw-source", "*"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(BeEmpty())
+	})
+
+	It("should authorize the blob requests with the registry credentials", func() {
+		anonymousManifests = true
+		addLayer("fw-cx6.bin", []byte("cx6 image"))
+
+		imagePath, err := manager.ValidateRequestedFirmware(context.Background(), device, source, credentials)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(imagePath).To(HaveSuffix("fw-cx6.bin"))
+	})
+	It("should pull the artifact over plain http from the plain http registries", func() {
+		plainServer := httptest.NewServer(handler)
+		DeferCleanup(plainServer.Close)
+		addLayer("fw-cx6.bin", []byte("cx6 image"))
+
+		registry := strings.TrimPrefix(plainServer.URL, "http://")
+		binUrl = fmt.Sprintf("oci://%s/firmware/cx6:22.41.1000", registry)
+		digest := sha256.Sum256([]byte("cx6 image"))
+		source.Spec.BinUrlSources = []string{binUrl}
+		source.Spec.Verification = &v1alpha1.FirmwareVerificationSpec{SHA256: map[string]string{binUrl: hex.EncodeToString(digest[:])}}
+		credentials[registry] = types.RegistryAuth{Username: username, Password: password}
+
+		_, err := manager.ValidateRequestedFirmware(context.Background(), device, source, credentials)
+		Expect(err).To(HaveOccurred())
+
+		source.Spec.PlainHTTPRegistries = []string{registry}
+		imagePath, err := manager.ValidateRequestedFirmware(context.Background(), device, source, credentials)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(imagePath).To(HaveSuffix("fw-cx6.bin"))
+	})
+
+	Context("when the registry certificate is issued by an internal CA", func() {
+		BeforeEach(func() {
+			manager.client = &http.Client{Timeout: firmwareDownloadTimeout}
+			addLayer("fw-cx6.bin", []byte("cx6 image"))
+		})
+
+		It("should refuse the registry without the CA bundle", func() {
+			_, err := manager.ValidateRequestedFirmware(context.Background(), device, source, credentials)
+			Expect(err).To(MatchError(ContainSubstring("certificate")))
+		})
+		It("should pull the artifact with the CA bundle of the source", func() {
+			source.Spec.CABundle = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+
+			imagePath, err := manager.ValidateRequestedFirmware(context.Background(), device, source, credentials)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(imagePath).To(HaveSuffix("fw-cx6.bin"))
+		})
+		It("should return IncorrectSpec error for an invalid CA bundle", func() {
+			source.Spec.CABundle = "not a certificate"
+
+			_, err := manager.ValidateRequestedFirmware(context.Background(), device, source, credentials)
+			Expect(types.IsIncorrectSpecError(err)).To(BeTrue())
+		})
+	})
+
+	Context("when the source declares a public key", func() {
+		var privateKey *ecdsa.PrivateKey
+
+		BeforeEach(func() {
+			var err error
+			privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+			der, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+			Expect(err).NotTo(HaveOccurred())
+			source.Spec.Verification = &v1alpha1.FirmwareVerificationSpec{
+				PublicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+			}
+		})
+
+		sign := func(content string) []byte {
+			digest := sha256.Sum256([]byte(content))
+			signature, err := ecdsa.SignASN1(rand.Reader, privateKey, digest[:])
+			Expect(err).NotTo(HaveOccurred())
+			return []byte(base64.StdEncoding.EncodeToString(signature))
+		}
+
+		It("should verify the binary with the signature layer", func() {
+			addLayer("fw-cx6.bin", []byte("cx6 image"))
+			addLayer("fw-cx6.bin.sig", sign("cx6 image"))
+
+			imagePath, err := manager.ValidateRequestedFirmware(context.Background(), device, source, credentials)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(imagePath).To(HaveSuffix("fw-cx6.bin"))
+		})
+		It("should refuse the binary with an invalid signature layer", func() {
+			addLayer("fw-cx6.bin", []byte("cx6 image"))
+			addLayer("fw-cx6.bin.sig", sign("other image"))
+
+			_, err := manager.ValidateRequestedFirmware(context.Background(), device, source, credentials)
+			Expect(types.IsVerificationFailedError(err)).To(BeTrue())
+		})
+		It("should refuse the binary if the artifact has no signature layer", func() {
+			addLayer("fw-cx6.bin", []byte("cx6 image"))
+
+			_, err := manager.ValidateRequestedFirmware(context.Background(), device, source, credentials)
+			Expect(types.IsVerificationFailedError(err)).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,109 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package host
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+// FirmwareProgressFunc receives the phase of the firmware update of a device and its progress in percent
+// percent is 0 if the tools don't report the progress of the phase
+type FirmwareProgressFunc func(phase string, percent int)
+
+type firmwareProgressKey struct{}
+
+// WithFirmwareProgress returns a context reporting the firmware update progress of the device to the function
+// the function is called from the goroutines running the host tools, it must not block for long
+func WithFirmwareProgress(ctx context.Context, progress FirmwareProgressFunc) context.Context {
+	return context.WithValue(ctx, firmwareProgressKey{}, progress)
+}
+
+// reportFirmwareProgress passes the progress to the function of the context, if any
+func reportFirmwareProgress(ctx context.Context, phase string, percent int) {
+	progress, ok := ctx.Value(firmwareProgressKey{}).(FirmwareProgressFunc)
+	if ok && progress != nil {
+		progress(phase, percent)
+	}
+}
+
+// toolPercentRegex matches the progress printed by the tools, e.g. "Burning FW image without signatures - 40%"
+var toolPercentRegex = regexp.MustCompile(`(\d{1,3})%`)
+
+// progressWriter keeps the output of a tool and reports the last percentage printed by it
+type progressWriter struct {
+	lock    sync.Mutex
+	output  bytes.Buffer
+	percent int
+	report  func(percent int)
+}
+
+func newProgressWriter(report func(percent int)) *progressWriter {
+	return &progressWriter{percent: -1, report: report}
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.output.Write(p)
+
+	// Progress is updated in place with carriage returns, only the last value of the chunk matters
+	matches := toolPercentRegex.FindAllSubmatch(p, -1)
+	if len(matches) != 0 {
+		percent, err := strconv.Atoi(string(matches[len(matches)-1][1]))
+		if err == nil && percent <= 100 && percent != w.percent {
+			w.percent = percent
+			w.report(percent)
+		}
+	}
+
+	return len(p), nil
+}
+
+// Bytes returns the output written so far
+func (w *progressWriter) Bytes() []byte {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return bytes.Clone(w.output.Bytes())
+}
+
+// progressReader reports the percentage of the known total size read so far
+type progressReader struct {
+	reader  io.Reader
+	total   int64
+	read    int64
+	percent int
+	report  func(percent int)
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+	if r.total > 0 {
+		percent := int(min(r.read*100/r.total, 100))
+		if percent != r.percent {
+			r.percent = percent
+			r.report(percent)
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,197 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package host
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+)
+
+// rshimDevPath contains the rshim devices created by the rshim driver on the host
+var rshimDevPath = filepath.Join(consts.HostPath, "dev")
+
+// bfbInstallTimeout limits the installation of a BFB bundle, including the boot of the installed image on the DPU
+var bfbInstallTimeout = 30 * time.Minute
+
+// bfbInstallPollInterval is the interval of the rshim log checks during the installation of a BFB bundle
+var bfbInstallPollInterval = 10 * time.Second
+
+// bfbReadyMarkers are logged by the DPU to the rshim log once the installed image has booted
+var bfbReadyMarkers = []string{"Linux up", "DPU is ready"}
+
+const rshimDeviceNamePrefix = "pcie-"
+
+// IsBlueField returns true if the device is a BlueField DPU
+func IsBlueField(deviceType string) bool {
+	return strings.EqualFold(deviceType, consts.BlueField2DeviceID) || strings.EqualFold(deviceType, consts.BlueField3DeviceID)
+}
+
+// GetRshimDevice returns the name of the rshim device of the BlueField DPU with the given PCI address, e.g. rshim0
+// the PCI function of the rshim device is located in the same PCI slot as the DPU's ports
+// returns empty string if the rshim device is not found
+func (h *hostUtils) GetRshimDevice(pciAddr string) (string, error) {
+	log.Log.Info("HostUtils.GetRshimDevice()", "pciAddr", pciAddr)
+
+	miscFiles, err := filepath.Glob(filepath.Join(rshimDevPath, "rshim*", "misc"))
+	if err != nil {
+		return "", err
+	}
+
+	slot := pciSlot(pciAddr)
+	for _, miscFile := range miscFiles {
+		content, err := os.ReadFile(miscFile)
+		if err != nil {
+			log.Log.Error(err, "GetRshimDevice(): failed to read rshim misc file", "path", miscFile)
+			continue
+		}
+
+		for _, line := range strings.Split(string(content), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) < 2 || fields[0] != "DEV_NAME" {
+				continue
+			}
+			if strings.EqualFold(pciSlot(strings.TrimPrefix(fields[1], rshimDeviceNamePrefix)), slot) {
+				return filepath.Base(filepath.Dir(miscFile)), nil
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// InstallBFB pushes the BFB bundle to the boot stream of the rshim device and waits for the installed image to boot
+// the ARM side of the DPU is reinstalled, the DPU's ports are down during the installation
+// the share of the bundle pushed to the boot stream is reported to the firmware progress function of the context
+func (h *hostUtils) InstallBFB(ctx context.Context, rshimDevice string, bfbPath string) error {
+	log.Log.Info("HostUtils.InstallBFB()", "rshimDevice", rshimDevice, "bfbPath", bfbPath)
+	err := checkCapabilities(bfbInstallOperation)
+	if err != nil {
+		log.Log.Error(err, "InstallBFB(): can't change the settings of the host")
+		return err
+	}
+	defer startDeviceReset()()
+
+	ctx, cancel := context.WithTimeout(ctx, bfbInstallTimeout)
+	defer cancel()
+
+	miscPath := filepath.Join(rshimDevPath, rshimDevice, "misc")
+	// Log level 2 makes the rshim log messages of the DPU readable from the misc file, with clear on read each read returns
+	// only the messages logged since the previous one, so the ready messages of the previous boots are not seen again
+	for _, setting := range []string{"DISPLAY_LEVEL 2", "CLEAR_ON_READ 1"} {
+		err = os.WriteFile(miscPath, []byte(setting+"\n"), 0)
+		if err != nil {
+			return fmt.Errorf("failed to enable the rshim log of %s: %w", rshimDevice, err)
+		}
+	}
+	// Drops the messages logged before the installation
+	_, err = bfbReadyLogged(miscPath)
+	if err != nil {
+		return err
+	}
+
+	err = pushBFB(ctx, filepath.Join(rshimDevPath, rshimDevice, "boot"), bfbPath)
+	if err != nil {
+		return fmt.Errorf("failed to push BFB bundle to %s: %w", rshimDevice, err)
+	}
+
+	ticker := time.NewTicker(bfbInstallPollInterval)
+	defer ticker.Stop()
+	for {
+		ready, err := bfbReadyLogged(miscPath)
+		if err != nil {
+			return err
+		}
+		if ready {
+			log.Log.Info("BFB bundle installed", "rshimDevice", rshimDevice, "bfbPath", bfbPath)
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("DPU of %s didn't boot the installed BFB bundle: %w", rshimDevice, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// pushBFB copies the bundle to the rshim boot stream, the copy is interrupted when the context is done
+func pushBFB(ctx context.Context, bootPath string, bfbPath string) error {
+	bundle, err := os.Open(bfbPath)
+	if err != nil {
+		return err
+	}
+	defer bundle.Close()
+
+	info, err := bundle.Stat()
+	if err != nil {
+		return err
+	}
+
+	boot, err := os.OpenFile(bootPath, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(boot, &progressReader{
+		reader: &contextReader{ctx: ctx, reader: bundle},
+		total:  info.Size(),
+		report: func(percent int) {
+			reportFirmwareProgress(ctx, consts.FirmwareUpdatePhaseFlashing, percent)
+		},
+	})
+	closeErr := boot.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// bfbReadyLogged returns true if the DPU logged any of the ready messages since the previous read of the rshim log
+func bfbReadyLogged(miscPath string) (bool, error) {
+	content, err := os.ReadFile(miscPath)
+	if err != nil {
+		return false, err
+	}
+
+	for _, marker := range bfbReadyMarkers {
+		if strings.Contains(string(content), marker) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// contextReader stops reading once the context is done
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.reader.Read(p)
+}
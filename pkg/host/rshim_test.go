@@ -0,0 +1,118 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package host
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("rshim", func() {
+	var (
+		h       *hostUtils
+		devPath string
+	)
+
+	createRshimDevice := func(name string, miscContent string) {
+		Expect(os.MkdirAll(filepath.Join(devPath, name), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(devPath, name, "misc"), []byte(miscContent), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(devPath, name, "boot"), nil, 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		h = &hostUtils{}
+		devPath = GinkgoT().TempDir()
+
+		originalDevPath, originalInterval, originalTimeout := rshimDevPath, bfbInstallPollInterval, bfbInstallTimeout
+		rshimDevPath, bfbInstallPollInterval = devPath, 10*time.Millisecond
+		DeferCleanup(func() {
+			rshimDevPath, bfbInstallPollInterval, bfbInstallTimeout = originalDevPath, originalInterval, originalTimeout
+		})
+	})
+
+	Describe("IsBlueField", func() {
+		It("should detect BlueField DPUs by the device type", func() {
+			Expect(IsBlueField("a2d6")).To(BeTrue())
+			Expect(IsBlueField("A2DC")).To(BeTrue())
+			Expect(IsBlueField("1021")).To(BeFalse())
+		})
+	})
+
+	Describe("GetRshimDevice", func() {
+		It("should find the rshim device in the PCI slot of the DPU", func() {
+			createRshimDevice("rshim0", "DISPLAY_LEVEL   0 (0:basic, 1:advanced, 2:log)\nDEV_NAME        pcie-0000:a3:00.2\n")
+			createRshimDevice("rshim1", "DISPLAY_LEVEL   0 (0:basic, 1:advanced, 2:log)\nDEV_NAME        pcie-0000:03:00.2\n")
+
+			rshimDevice, err := h.GetRshimDevice(pciAddress)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rshimDevice).To(Equal("rshim1"))
+		})
+		It("should return empty string if the DPU has no rshim device", func() {
+			createRshimDevice("rshim0", "DEV_NAME        pcie-0000:a3:00.2\n")
+
+			rshimDevice, err := h.GetRshimDevice(pciAddress)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rshimDevice).To(BeEmpty())
+		})
+	})
+
+	Describe("InstallBFB", func() {
+		var bfbPath string
+
+		BeforeEach(func() {
+			createRshimDevice("rshim0", "DEV_NAME        pcie-0000:03:00.2\n")
+			bfbPath = filepath.Join(GinkgoT().TempDir(), "bf-bundle.bfb")
+			Expect(os.WriteFile(bfbPath, []byte("bundle"), 0644)).To(Succeed())
+		})
+
+		It("should push the bundle to the boot stream and wait for the DPU to boot", func() {
+			go func() {
+				defer GinkgoRecover()
+				// The DPU boots after the bundle is pushed
+				Eventually(filepath.Join(devPath, "rshim0", "boot")).Should(BeARegularFile())
+				Eventually(func() ([]byte, error) {
+					return os.ReadFile(filepath.Join(devPath, "rshim0", "boot"))
+				}).Should(Equal([]byte("bundle")))
+				Expect(os.WriteFile(filepath.Join(devPath, "rshim0", "misc"), []byte("INFO[MISC]: Linux up\n"), 0644)).To(Succeed())
+			}()
+
+			Expect(h.InstallBFB(context.Background(), "rshim0", bfbPath)).To(Succeed())
+		})
+		It("should fail if the DPU doesn't boot the installed bundle in time", func() {
+			bfbInstallTimeout = 100 * time.Millisecond
+
+			err := h.InstallBFB(context.Background(), "rshim0", bfbPath)
+			Expect(err).To(MatchError(ContainSubstring("didn't boot the installed BFB bundle")))
+		})
+		It("should not take the ready messages of the previous boots for the boot of the installed bundle", func() {
+			bfbInstallTimeout = 100 * time.Millisecond
+			createRshimDevice("rshim0", "DEV_NAME        pcie-0000:03:00.2\nINFO[MISC]: Linux up\n")
+
+			err := h.InstallBFB(context.Background(), "rshim0", bfbPath)
+			Expect(err).To(MatchError(ContainSubstring("didn't boot the installed BFB bundle")))
+			// The rshim log is cleared on each read
+			Expect(os.ReadFile(filepath.Join(devPath, "rshim0", "misc"))).To(Equal([]byte("CLEAR_ON_READ 1\n")))
+		})
+		It("should fail if the rshim device doesn't exist", func() {
+			Expect(h.InstallBFB(context.Background(), "rshim1", bfbPath)).NotTo(Succeed())
+		})
+	})
+})
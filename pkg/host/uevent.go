@@ -0,0 +1,163 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package host
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Mellanox/nic-configuration-operator/pkg/consts"
+)
+
+// Kernel uevent actions of the devices
+const (
+	UEventActionAdd    = "add"
+	UEventActionRemove = "remove"
+	UEventActionBind   = "bind"
+	UEventActionUnbind = "unbind"
+)
+
+// ueventKernelGroup is the netlink multicast group of the uevents sent by the kernel, udev re-broadcasts them in another group
+const ueventKernelGroup = 1
+
+// ueventReadTimeout limits each read of the uevent socket, so that the subscription notices its cancellation
+var ueventReadTimeout = time.Second
+
+// UEvent is a kernel uevent of a device, as received by udev
+type UEvent struct {
+	Action    string
+	DevPath   string
+	Subsystem string
+	// Env contains the KEY=VALUE properties of the event, e.g. PCI_ID or PCI_SLOT_NAME
+	Env map[string]string
+}
+
+// NvidiaPciDeviceChanged returns true if the event reports an NVIDIA PCI device appearing on the host or disappearing from it,
+// e.g. a hot-plugged device, a device (re)bound to the driver or returning from the firmware reset
+func (e UEvent) NvidiaPciDeviceChanged() bool {
+	if e.Subsystem != "pci" {
+		return false
+	}
+	switch e.Action {
+	case UEventActionAdd, UEventActionRemove, UEventActionBind, UEventActionUnbind:
+	default:
+		return false
+	}
+
+	vendor, _, _ := strings.Cut(e.Env["PCI_ID"], ":")
+	return strings.EqualFold(vendor, consts.MellanoxVendor)
+}
+
+// PciAddress returns the PCI address of the device of the event
+func (e UEvent) PciAddress() string {
+	return e.Env["PCI_SLOT_NAME"]
+}
+
+// IsVirtualFunction returns true if the device of the event is an SR-IOV VF,
+// only known while the device is present on the host, i.e. not for the removed devices
+func (e UEvent) IsVirtualFunction() bool {
+	if e.PciAddress() == "" {
+		return false
+	}
+	_, err := os.Lstat(filepath.Join(pciDevicesPath, e.PciAddress(), "physfn"))
+	return err == nil
+}
+
+// parseUEvent parses a kernel uevent message, "ACTION@DEVPATH" header followed by the KEY=VALUE properties separated by NUL
+// returns false for the messages that are not kernel uevents, e.g. the ones re-broadcast by udev
+func parseUEvent(msg []byte) (UEvent, bool) {
+	fields := bytes.Split(msg, []byte{0})
+	action, devPath, found := strings.Cut(string(fields[0]), "@")
+	if !found || action == "" || devPath == "" {
+		return UEvent{}, false
+	}
+
+	event := UEvent{Action: action, DevPath: devPath, Env: map[string]string{}}
+	for _, field := range fields[1:] {
+		key, value, found := strings.Cut(string(field), "=")
+		if found {
+			event.Env[key] = value
+		}
+	}
+	event.Subsystem = event.Env["SUBSYSTEM"]
+	return event, true
+}
+
+// SubscribeUEvents delivers the kernel uevents of the host's devices to the channel until done is closed
+// the config daemon runs in the host network namespace, where the kernel broadcasts the uevents
+func SubscribeUEvents(ch chan<- UEvent, done <-chan struct{}) error {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		return fmt.Errorf("failed to open uevent socket: %w", err)
+	}
+
+	err = syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK, Groups: ueventKernelGroup})
+	if err == nil {
+		timeout := syscall.NsecToTimeval(ueventReadTimeout.Nanoseconds())
+		err = syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &timeout)
+	}
+	if err != nil {
+		_ = syscall.Close(fd)
+		return fmt.Errorf("failed to subscribe to uevents: %w", err)
+	}
+
+	go func() {
+		defer syscall.Close(fd)
+		defer close(ch)
+
+		buf := make([]byte, 64*1024)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			n, _, err := syscall.Recvfrom(fd, buf, 0)
+			if err == syscall.EAGAIN || err == syscall.EINTR {
+				continue
+			}
+			if err != nil {
+				// Overflown socket drops the events, the periodic discovery catches up on them
+				if err == syscall.ENOBUFS {
+					log.Log.Info("uevent socket overflown, some uevents were dropped")
+					continue
+				}
+				log.Log.Error(err, "failed to read uevents")
+				return
+			}
+
+			event, ok := parseUEvent(buf[:n])
+			if !ok {
+				continue
+			}
+			select {
+			case ch <- event:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,106 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package host
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("uevents", func() {
+	Describe("parseUEvent", func() {
+		It("should parse the kernel uevent", func() {
+			msg := strings.Join([]string{
+				"bind@/devices/pci0000:80/0000:80:01.0/0000:81:00.0",
+				"ACTION=bind",
+				"DEVPATH=/devices/pci0000:80/0000:80:01.0/0000:81:00.0",
+				"SUBSYSTEM=pci",
+				"DRIVER=mlx5_core",
+				"PCI_ID=15B3:101D",
+				"PCI_SLOT_NAME=0000:81:00.0",
+				"SEQNUM=4242",
+			}, "\x00")
+
+			event, ok := parseUEvent([]byte(msg))
+			Expect(ok).To(BeTrue())
+			Expect(event.Action).To(Equal(UEventActionBind))
+			Expect(event.DevPath).To(Equal("/devices/pci0000:80/0000:80:01.0/0000:81:00.0"))
+			Expect(event.Subsystem).To(Equal("pci"))
+			Expect(event.Env).To(HaveKeyWithValue("PCI_SLOT_NAME", "0000:81:00.0"))
+			Expect(event.NvidiaPciDeviceChanged()).To(BeTrue())
+		})
+		It("should skip the messages re-broadcast by udev", func() {
+			_, ok := parseUEvent([]byte("libudev\x00\xfe\xed\xca\xfe"))
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Describe("NvidiaPciDeviceChanged", func() {
+		It("should only report the devices appearing and disappearing", func() {
+			event := UEvent{Action: UEventActionRemove, Subsystem: "pci", Env: map[string]string{"PCI_ID": "15b3:1021"}}
+			Expect(event.NvidiaPciDeviceChanged()).To(BeTrue())
+
+			event.Action = "change"
+			Expect(event.NvidiaPciDeviceChanged()).To(BeFalse())
+		})
+		It("should ignore the devices of other vendors and subsystems", func() {
+			event := UEvent{Action: UEventActionAdd, Subsystem: "pci", Env: map[string]string{"PCI_ID": "8086:1572"}}
+			Expect(event.NvidiaPciDeviceChanged()).To(BeFalse())
+
+			event = UEvent{Action: UEventActionAdd, Subsystem: "net", Env: map[string]string{"INTERFACE": "eth0"}}
+			Expect(event.NvidiaPciDeviceChanged()).To(BeFalse())
+		})
+	})
+
+	Describe("IsVirtualFunction", func() {
+		It("should report the VFs by their physfn link", func() {
+			sysfs := GinkgoT().TempDir()
+			originalPath := pciDevicesPath
+			pciDevicesPath = sysfs
+			DeferCleanup(func() { pciDevicesPath = originalPath })
+
+			Expect(os.MkdirAll(filepath.Join(sysfs, "0000:3b:00.0"), 0755)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(sysfs, "0000:3b:00.2"), 0755)).To(Succeed())
+			Expect(os.Symlink("../0000:3b:00.0", filepath.Join(sysfs, "0000:3b:00.2", "physfn"))).To(Succeed())
+
+			event := UEvent{Action: UEventActionBind, Subsystem: "pci", Env: map[string]string{"PCI_SLOT_NAME": "0000:3b:00.2"}}
+			Expect(event.IsVirtualFunction()).To(BeTrue())
+
+			event.Env["PCI_SLOT_NAME"] = "0000:3b:00.0"
+			Expect(event.IsVirtualFunction()).To(BeFalse())
+		})
+	})
+
+	Describe("DeviceResetInProgress", func() {
+		It("should report the device reset until it settles", func() {
+			originalSettleTime := deviceResetSettleTime
+			deviceResetSettleTime = 100 * time.Millisecond
+			DeferCleanup(func() { deviceResetSettleTime = originalSettleTime })
+
+			done := startDeviceReset()
+			Expect(DeviceResetInProgress()).To(BeTrue())
+
+			done()
+			Expect(DeviceResetInProgress()).To(BeTrue())
+			Eventually(DeviceResetInProgress, time.Second).Should(BeFalse())
+		})
+	})
+})
@@ -17,15 +17,21 @@ package host
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
+	"unicode"
 
 	"github.com/Mellanox/rdmamap"
 	"github.com/jaypipes/ghw"
@@ -38,19 +44,47 @@ import (
 	"github.com/Mellanox/nic-configuration-operator/pkg/types"
 )
 
-const pciDevicesPath = "/sys/bus/pci/devices"
+// pciDevicesPath is a variable so that tests can point it to a fake sysfs tree
+var pciDevicesPath = "/sys/bus/pci/devices"
+
+// netDevicesPath is a variable so that tests can point it to a fake sysfs tree
+var netDevicesPath = "/sys/class/net"
+
+// procSysPath is a variable so that tests can point it to a fake procfs tree
+var procSysPath = "/proc/sys"
+
+// cpuInfoPath is a variable so that tests can point it to a fake procfs tree
+var cpuInfoPath = "/proc/cpuinfo"
+
+// dmiSysVendorPath is a variable so that tests can point it to a fake sysfs tree
+var dmiSysVendorPath = "/sys/class/dmi/id/sys_vendor"
+
+// infinibandDevicesPath is a variable so that tests can point it to a fake sysfs tree
+var infinibandDevicesPath = "/sys/class/infiniband"
+
 const arrayPrefix = "Array"
 
+// lspciAccessDenied is printed by lspci instead of the extended PCI capabilities if CAP_SYS_ADMIN is missing
+const lspciAccessDenied = "<access denied>"
+
 // HostUtils is an interface that contains util functions that perform operations on the actual host
 type HostUtils interface {
 	// GetPCIDevices returns a list of PCI devices on the host
 	GetPCIDevices() ([]*pci.Device, error)
 	// GetPartAndSerialNumber uses mstvpd util to retrieve Part and Serial numbers of the PCI device
 	GetPartAndSerialNumber(pciAddr string) (string, string, error)
-	// GetFirmwareVersionAndPSID uses mstflint tool to retrieve FW version and PSID of the device
-	GetFirmwareVersionAndPSID(pciAddr string) (string, string, error)
+	// QueryFirmware uses mstflint tool to query the firmware of the device: its version, PSID, security attributes and flash identity
+	// the tool is run once, all the attributes are parsed from the same query
+	QueryFirmware(pciAddr string) (*types.FirmwareQuery, error)
+	// QueryFirmwareImage uses mstflint tool to query the version, PSID and security attributes of a firmware image file
+	QueryFirmwareImage(imagePath string) (*types.FirmwareQuery, error)
 	// GetPCILinkSpeed return PCI bus speed in GT/s
 	GetPCILinkSpeed(pciAddr string) (int, error)
+	// GetPCILinkStatus reads the negotiated and supported PCIe link speed and width of the device from sysfs
+	// returns nil if the kernel doesn't report them
+	GetPCILinkStatus(pciAddr string) (*types.PCILinkStatus, error)
+	// GetPCITopology reads the NUMA node, the local CPUs and the PCIe root complex of the device from sysfs
+	GetPCITopology(pciAddr string) (*types.PCITopology, error)
 	// GetMaxReadRequestSize returns MaxReadRequest size for PCI device
 	GetMaxReadRequestSize(pciAddr string) (int, error)
 	// GetTrustAndPFC returns trust and pfc settings for network interface
@@ -61,32 +95,169 @@ type HostUtils interface {
 	GetInterfaceName(pciAddr string) string
 	// GetLinkType return the link type of the net device (Ethernet / Infiniband)
 	GetLinkType(name string) string
+	// GetPortSpeed returns the speed of the PF's network port in Mb/s, e.g. 100000 for 100G
+	// returns 0 if the link is down or its speed is unknown
+	GetPortSpeed(pciAddr string) (int, error)
+	// GetPtpClockIndex returns the index of the PF's PTP hardware clock, e.g. 0 for /dev/ptp0
+	// returns -1 if the PF has no PTP hardware clock
+	GetPtpClockIndex(pciAddr string) (int, error)
 	// IsSriovVF return true if the device is a SRIOV VF, false otherwise
 	IsSriovVF(pciAddr string) bool
 	// QueryNvConfig queries nv config for a mellanox device and returns default, current and next boot configs
 	QueryNvConfig(ctx context.Context, pciAddr string) (types.NvConfigQuery, error)
 	// SetNvConfigParameter sets a nv config parameter for a mellanox device
 	SetNvConfigParameter(pciAddr string, paramName string, paramValue string) error
+	// SimulateNvConfigParameters runs a simulated set of the nv config parameters, the firmware validates the values and their
+	// interdependencies without changing the nv config
+	// returns false if the tooling doesn't support simulated sets
+	// returns types.IncorrectSpecError if the firmware rejects the values
+	SimulateNvConfigParameters(pciAddr string, params map[string]string) (bool, error)
 	// ResetNvConfig resets NIC's nv config
 	ResetNvConfig(pciAddr string) error
+	// GetHostPrivilegeLevel returns the privilege level of the host for the device, e.g. PRIVILEGED or RESTRICTED
+	// restricted hosts can't change the nv config, it's owned by the DPU's ARM side or the BMC
+	GetHostPrivilegeLevel(pciAddr string) (string, error)
 	// ResetNicFirmware resets NIC's firmware
 	// Operation can be long, required context to be able to terminate by timeout
 	// IB devices need to communicate with other nodes for confirmation
 	ResetNicFirmware(ctx context.Context, pciAddr string) error
+	// BurnFirmware burns the firmware image to the PCI device, the new firmware is activated after reboot or FW reset
+	BurnFirmware(ctx context.Context, pciAddr string, imagePath string) error
+	// GetRshimDevice returns the name of the rshim device of the BlueField DPU with the given PCI address, e.g. rshim0
+	// the PCI function of the rshim device is located in the same PCI slot as the DPU's ports
+	// returns empty string if the rshim device is not found
+	GetRshimDevice(pciAddr string) (string, error)
+	// InstallBFB pushes the BFB bundle to the boot stream of the rshim device and waits for the installed image to boot
+	// the ARM side of the DPU is reinstalled, the DPU's ports are down during the installation
+	InstallBFB(ctx context.Context, rshimDevice string, bfbPath string) error
 	// SetMaxReadRequestSize sets max read request size for PCI device
 	SetMaxReadRequestSize(pciAddr string, maxReadRequestSize int) error
 	// SetTrustAndPFC sets trust and PFC settings for a network interface
 	SetTrustAndPFC(interfaceName string, trust string, pfc string) error
+	// GetTcBandwidth returns the ETS bandwidth shares of the traffic classes of a network interface, e.g. 0,0,0,60,40,0,0,0
+	GetTcBandwidth(interfaceName string) (string, error)
+	// SetTcBandwidth allocates the ETS bandwidth shares to the traffic classes of a network interface
+	SetTcBandwidth(interfaceName string, tcBandwidth string) error
+	// GetDcbAppEntries returns the DCB app table of a network interface with dcb, e.g. ethtype-prio 0x8906:3
+	// the entries are prefixed with their dcb category and are accepted by DeleteDcbAppEntry as is
+	GetDcbAppEntries(interfaceName string) ([]string, error)
+	// DeleteDcbAppEntry deletes an entry returned by GetDcbAppEntries from the DCB app table of a network interface
+	DeleteDcbAppEntry(interfaceName string, entry string) error
+	// GetRootQdisc returns the kind of the root qdisc of a network interface, e.g. mq or mqprio
+	GetRootQdisc(interfaceName string) (string, error)
+	// DeleteRootQdisc deletes the root qdisc of a network interface, the kernel restores the default one
+	DeleteRootQdisc(interfaceName string) error
+	// IsCongestionControlSupported returns true if the driver of a network interface exposes the ECN and DCQCN settings
+	IsCongestionControlSupported(interfaceName string) bool
+	// GetEcn returns the priorities of a network interface with ECN enabled, e.g. 0,0,0,1,0,0,0,0
+	GetEcn(interfaceName string) (string, error)
+	// SetEcn enables ECN for the priorities of a network interface, both on the reaction and the notification point
+	SetEcn(interfaceName string, ecn string) error
+	// GetDcqcnParameter returns the value of a DCQCN parameter of a network interface, e.g. roce_rp/rpg_min_rate
+	GetDcqcnParameter(interfaceName string, name string) (int, error)
+	// SetDcqcnParameter sets the value of a DCQCN parameter of a network interface
+	SetDcqcnParameter(interfaceName string, name string, value int) error
+	// GetMtu returns the MTU of a network interface
+	GetMtu(interfaceName string) (int, error)
+	// SetMtu sets the MTU of a network interface
+	SetMtu(interfaceName string, mtu int) error
+	// GetEthtoolFeatures returns the ethtool features of a network interface and their state, keyed by the feature name
+	GetEthtoolFeatures(interfaceName string) (map[string]types.EthtoolFeature, error)
+	// SetEthtoolFeature enables or disables the ethtool feature of a network interface
+	SetEthtoolFeature(interfaceName string, feature string, enabled bool) error
+	// GetTransceiver returns the cable or optical module plugged into the port of a network interface
+	// returns nil if the port's cage is empty
+	GetTransceiver(interfaceName string) (*types.Transceiver, error)
+	// GetLinkStatus returns the operational state, negotiated speed and auto-negotiation of a network interface
+	GetLinkStatus(interfaceName string) (*types.LinkStatus, error)
+	// GetRdmaPort returns the link layer, the GUIDs and the link state of the first port of the RDMA device, e.g. mlx5_0
+	// returns nil if the RDMA device doesn't exist
+	GetRdmaPort(rdmaDevice string) (*types.RdmaPort, error)
+	// GetTemperature returns the ASIC temperature of the device in degrees Celsius
+	GetTemperature(pciAddr string) (int, error)
+	// GetHealthReporters returns the devlink health reporters of the device and of its ports
+	GetHealthReporters(pciAddr string) ([]types.HealthReporter, error)
+	// GetRingSizes returns the current and the maximum ring buffer sizes of a network interface
+	GetRingSizes(interfaceName string) (types.RingSizes, error)
+	// SetRingSizes sets the RX and TX ring buffer sizes of a network interface
+	SetRingSizes(interfaceName string, rx int, tx int) error
+	// GetCoalescing returns the interrupt coalescing settings of a network interface
+	GetCoalescing(interfaceName string) (types.Coalescing, error)
+	// SetCoalescing sets the interrupt coalescing settings of a network interface
+	SetCoalescing(interfaceName string, coalescing types.Coalescing) error
+	// GetChannels returns the current and the maximum combined channel count of a network interface
+	GetChannels(interfaceName string) (types.Channels, error)
+	// SetCombinedChannels sets the combined channel count of a network interface
+	SetCombinedChannels(interfaceName string, combined int) error
+	// GetNumaNodeCPUCount returns the number of CPUs of the NUMA node local to the PCI device, 0 if the device has no NUMA affinity
+	GetNumaNodeCPUCount(pciAddr string) (int, error)
+	// GetSysctl returns the value of the sysctl in the dotted notation, e.g. net.ipv4.tcp_ecn
+	// whitespace-separated values are normalized to single spaces
+	GetSysctl(name string) (string, error)
+	// SetSysctl sets the value of the sysctl in the dotted notation
+	SetSysctl(name string, value string) error
+	// GetEswitchMode returns the eswitch mode of the PF, e.g. legacy or switchdev
+	// returns empty string if the PF is not the eswitch manager
+	GetEswitchMode(pciAddr string) (string, error)
+	// SetEswitchMode sets the eswitch mode of the PF, legacy or switchdev
+	SetEswitchMode(pciAddr string, mode string) error
+	// GetDevlinkParam returns the runtime value of the devlink parameter of the PF, e.g. flow_steering_mode
+	GetDevlinkParam(pciAddr string, name string) (string, error)
+	// SetDevlinkParam sets the runtime value of the devlink parameter of the PF
+	SetDevlinkParam(pciAddr string, name string, value string) error
+	// GetVfRepresentors returns the network interfaces of the VF representors of the PF in switchdev mode
+	GetVfRepresentors(pciAddr string) ([]string, error)
+	// GetVfPciAddresses returns the PCI addresses of the PF's VFs, ordered by the VF index
+	GetVfPciAddresses(pciAddr string) ([]string, error)
+	// GetPCIDriver returns the name of the driver bound to the PCI device, empty if no driver is bound
+	GetPCIDriver(pciAddr string) (string, error)
+	// GetVfMsixCount returns the number of MSI-X vectors of the VF
+	GetVfMsixCount(vfPciAddr string) (int, error)
+	// GetVfTotalMsix returns the number of MSI-X vectors of the PF's pool that can be assigned to its VFs
+	// returns error if the PF doesn't support dynamic VF MSI-X assignment
+	GetVfTotalMsix(pciAddr string) (int, error)
+	// SetVfMsixCount assigns the number of MSI-X vectors to the VF, the VF driver is unbound for the change
+	SetVfMsixCount(vfPciAddr string, count int) error
+	// GetPortCounters returns the physical port and priority counters of a network interface relevant for QoS, keyed by the ethtool counter name
+	// the counters are the ones cleared by ResetPortCounters
+	GetPortCounters(interfaceName string) (map[string]uint64, error)
+	// ResetPortCounters clears the physical port counters of the PCI device
+	ResetPortCounters(pciAddr string) error
+	// GetDevlinkResources returns devlink resources of the PCI device, keyed by the resource path, e.g. /kvd/linear
+	GetDevlinkResources(pciAddr string) (map[string]types.DevlinkResource, error)
+	// SetDevlinkResourceSize sets the size of the devlink resource, the new size takes effect after devlink reload
+	SetDevlinkResourceSize(pciAddr string, path string, size uint64) error
+	// ReloadDevlinkDevice performs devlink reload of the PCI device
+	ReloadDevlinkDevice(pciAddr string) error
+	// IsEswitchManager returns false if the PF is not the eswitch manager of a multi-host NIC,
+	// in this case the nv config of the NIC is owned by another host
+	// returns types.EswitchAccessDeniedError if devlink isn't permitted to query the eswitch
+	IsEswitchManager(pciAddr string) (bool, error)
 	// ScheduleReboot schedules reboot on the host
 	ScheduleReboot() error
 	// GetOfedVersion retrieves installed OFED version
 	GetOfedVersion() string
 	// GetHostUptimeSeconds returns the host uptime in seconds
 	GetHostUptimeSeconds() (time.Duration, error)
+	// GetHostBootID returns the random ID of the current boot of the host, it changes with every reboot
+	GetHostBootID() (string, error)
+	// GetHypervisor returns the DMI system vendor of the VM the host runs in, e.g. QEMU, empty string on bare metal
+	GetHypervisor() (string, error)
+	// GetToolFailures returns the host tool runs that failed after the given time, oldest first
+	// only the runs with one of the identifiers, e.g. a PCI address or a network interface, as an argument are returned
+	GetToolFailures(since time.Time, identifiers []string) []types.ToolFailure
+	// GetPCIDeviceSysfsState returns the sysfs attributes of the PCI device relevant for the diagnostics, keyed by the attribute name
+	// unreadable attributes are skipped
+	GetPCIDeviceSysfsState(pciAddr string) map[string]string
 }
 
 type hostUtils struct {
 	execInterface execUtils.Interface
+	// watchdog is the watchdog of the host tools run with execInterface, nil if the tools are not watched, e.g. in tests
+	watchdog hostToolWatchdog
+
+	simulateSupportOnce sync.Once
+	simulateSupported   bool
 }
 
 // GetPCIDevices returns a list of PCI devices on the host
@@ -137,41 +308,94 @@ func (h *hostUtils) GetPartAndSerialNumber(pciAddr string) (string, string, erro
 	return partNumber, serialNumber, nil
 }
 
-// GetFirmwareVersionAndPSID uses mstflint tool to retrieve FW version and PSID of the device
-func (h *hostUtils) GetFirmwareVersionAndPSID(pciAddr string) (string, string, error) {
-	log.Log.Info("HostUtils.GetFirmwareVersionAndPSID()", "pciAddr", pciAddr)
+// QueryFirmware uses mstflint tool to query the firmware of the device: its version, PSID, security attributes and flash identity
+// the tool is run once, all the attributes are parsed from the same query
+func (h *hostUtils) QueryFirmware(pciAddr string) (*types.FirmwareQuery, error) {
+	log.Log.Info("HostUtils.QueryFirmware()", "pciAddr", pciAddr)
 	cmd := h.execInterface.Command("mstflint", "-d", pciAddr, "q")
 	output, err := cmd.Output()
 	if err != nil {
-		log.Log.Error(err, "GetFirmwareVersionAndPSID(): Failed to run mstflint")
-		return "", "", err
+		log.Log.Error(err, "QueryFirmware(): Failed to run mstflint")
+		return nil, err
 	}
 
-	// Parse the output for PN and SN
-	scanner := bufio.NewScanner(strings.NewReader(string(output)))
-	var firmwareVersion, PSID string
+	return parseFirmwareQuery(output)
+}
 
-	for scanner.Scan() {
-		line := strings.ToLower(scanner.Text())
+// QueryFirmwareImage uses mstflint tool to query the version, PSID and security attributes of a firmware image file
+func (h *hostUtils) QueryFirmwareImage(imagePath string) (*types.FirmwareQuery, error) {
+	log.Log.Info("HostUtils.QueryFirmwareImage()", "imagePath", imagePath)
+	cmd := h.execInterface.Command("mstflint", "-i", imagePath, "q")
+	output, err := cmd.Output()
+	if err != nil {
+		log.Log.Error(err, "QueryFirmwareImage(): Failed to run mstflint")
+		return nil, err
+	}
 
-		if strings.HasPrefix(line, consts.FirmwareVersionPrefix) {
-			firmwareVersion = strings.TrimSpace(strings.TrimPrefix(line, consts.FirmwareVersionPrefix))
-		}
-		if strings.HasPrefix(line, consts.PSIDPrefix) {
-			PSID = strings.TrimSpace(strings.TrimPrefix(line, consts.PSIDPrefix))
+	return parseFirmwareQuery(output)
+}
+
+// parseFirmwareQuery parses the "mstflint q" output of a device or an image file
+// returns an error if the firmware version or the PSID is missing, the rest of the attributes are optional:
+// older mstflint versions don't report the security attributes, the part number isn't reported by all images
+func parseFirmwareQuery(output []byte) (*types.FirmwareQuery, error) {
+	query := &types.FirmwareQuery{}
+	var attributes []string
+	var securityVersion *int
+
+	scanner := bufio.NewScanner(strings.NewReader(string(output)))
+	for scanner.Scan() {
+		line := strings.ToLower(strings.TrimSpace(scanner.Text()))
+
+		switch {
+		case strings.HasPrefix(line, consts.FirmwareVersionPrefix):
+			query.Version = strings.TrimSpace(strings.TrimPrefix(line, consts.FirmwareVersionPrefix))
+		case strings.HasPrefix(line, consts.PSIDPrefix):
+			query.PSID = strings.TrimSpace(strings.TrimPrefix(line, consts.PSIDPrefix))
+		case strings.HasPrefix(line, consts.FlashPartNumberPrefix):
+			query.PartNumber = strings.TrimSpace(strings.TrimPrefix(line, consts.FlashPartNumberPrefix))
+		case strings.HasPrefix(line, consts.BaseGUIDPrefix):
+			// The base GUID is followed by the number of the GUIDs allocated to the device, e.g. "b8cef603000a1b2c 16"
+			fields := strings.Fields(strings.TrimPrefix(line, consts.BaseGUIDPrefix))
+			if len(fields) != 0 {
+				query.BaseGUID = fields[0]
+			}
+		case strings.HasPrefix(line, consts.SecurityAttrsPrefix):
+			attributes = []string{}
+			value := strings.TrimSpace(strings.TrimPrefix(line, consts.SecurityAttrsPrefix))
+			// Firmware without security features reports N/A
+			if value != "n/a" {
+				for _, attribute := range strings.Split(value, ",") {
+					attributes = append(attributes, strings.TrimSpace(attribute))
+				}
+			}
+		case strings.HasPrefix(line, consts.SecurityVerPrefix):
+			version, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, consts.SecurityVerPrefix)))
+			if err == nil {
+				securityVersion = &version
+			}
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		log.Log.Error(err, "GetFirmwareVersionAndPSID(): Error reading mstflint output")
-		return "", "", err
+		log.Log.Error(err, "parseFirmwareQuery(): Error reading mstflint output")
+		return nil, err
 	}
 
-	if firmwareVersion == "" || PSID == "" {
-		return "", "", fmt.Errorf("GetFirmwareVersionAndPSID(): firmware version (%v) or PSID (%v) is empty", firmwareVersion, PSID)
+	if query.Version == "" || query.PSID == "" {
+		return nil, fmt.Errorf("parseFirmwareQuery(): firmware version (%v) or PSID (%v) is empty", query.Version, query.PSID)
+	}
+	if query.BaseGUID == "n/a" {
+		query.BaseGUID = ""
+	}
+	if query.PartNumber == "n/a" {
+		query.PartNumber = ""
+	}
+	if attributes != nil {
+		query.Security = &types.FirmwareSecurity{Attributes: attributes, SecurityVersion: securityVersion}
 	}
 
-	return firmwareVersion, PSID, nil
+	return query, nil
 }
 
 // GetPCILinkSpeed return PCI bus speed in GT/s
@@ -183,6 +407,11 @@ func (h *hostUtils) GetPCILinkSpeed(pciAddr string) (int, error) {
 		log.Log.Error(err, "GetPCILinkSpeed(): Failed to run lspci")
 		return -1, err
 	}
+	if strings.Contains(string(output), lspciAccessDenied) {
+		err = fmt.Errorf("lspci can't read PCI capabilities of device %s, CAP_SYS_ADMIN is required", pciAddr)
+		log.Log.Error(err, "GetPCILinkSpeed(): Failed to run lspci")
+		return -1, err
+	}
 
 	linkSpeedRegexp := regexp.MustCompile(`speed\s+([0-9.]+)gt/s`)
 
@@ -216,6 +445,103 @@ func (h *hostUtils) GetPCILinkSpeed(pciAddr string) (int, error) {
 	return -1, nil
 }
 
+// GetPCILinkStatus reads the negotiated and supported PCIe link speed and width of the device from sysfs
+// returns nil if the kernel doesn't report them
+func (h *hostUtils) GetPCILinkStatus(pciAddr string) (*types.PCILinkStatus, error) {
+	devicePath := filepath.Join(pciDevicesPath, pciAddr)
+
+	readAttribute := func(attribute string) (string, error) {
+		value, err := os.ReadFile(filepath.Join(devicePath, attribute))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(value)), nil
+	}
+	readWidthAttribute := func(attribute string) (int, error) {
+		value, err := readAttribute(attribute)
+		if err != nil {
+			log.Log.Error(err, "GetPCILinkStatus(): failed to read link width", "pciAddr", pciAddr, "attribute", attribute)
+			return 0, err
+		}
+		width, err := strconv.Atoi(value)
+		if err != nil {
+			log.Log.Error(err, "GetPCILinkStatus(): failed to parse link width", "pciAddr", pciAddr, "attribute", attribute)
+			return 0, err
+		}
+		return width, nil
+	}
+
+	speed, err := readAttribute("current_link_speed")
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		log.Log.Error(err, "GetPCILinkStatus(): failed to read link speed", "pciAddr", pciAddr)
+		return nil, err
+	}
+
+	status := &types.PCILinkStatus{Speed: speed}
+	status.MaxSpeed, err = readAttribute("max_link_speed")
+	if err != nil {
+		log.Log.Error(err, "GetPCILinkStatus(): failed to read max link speed", "pciAddr", pciAddr)
+		return nil, err
+	}
+
+	status.Width, err = readWidthAttribute("current_link_width")
+	if err != nil {
+		return nil, err
+	}
+	status.MaxWidth, err = readWidthAttribute("max_link_width")
+	if err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
+
+// GetPCITopology reads the NUMA node, the local CPUs and the PCIe root complex of the device from sysfs
+func (h *hostUtils) GetPCITopology(pciAddr string) (*types.PCITopology, error) {
+	log.Log.Info("HostUtils.GetPCITopology()", "pciAddr", pciAddr)
+
+	devicePath := filepath.Join(pciDevicesPath, pciAddr)
+	topology := &types.PCITopology{NumaNode: -1}
+
+	// Platforms without NUMA report -1 or don't have the attribute
+	numaNode, err := os.ReadFile(filepath.Join(devicePath, "numa_node"))
+	if err != nil && !os.IsNotExist(err) {
+		log.Log.Error(err, "GetPCITopology(): failed to read NUMA node", "pciAddr", pciAddr)
+		return nil, err
+	}
+	if err == nil {
+		topology.NumaNode, err = strconv.Atoi(strings.TrimSpace(string(numaNode)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse NUMA node of device %s: %w", pciAddr, err)
+		}
+	}
+
+	localCPUs, err := os.ReadFile(filepath.Join(devicePath, "local_cpulist"))
+	if err != nil && !os.IsNotExist(err) {
+		log.Log.Error(err, "GetPCITopology(): failed to read local CPUs", "pciAddr", pciAddr)
+		return nil, err
+	}
+	topology.LocalCPUs = strings.TrimSpace(string(localCPUs))
+
+	// The device is linked to its place in the PCIe hierarchy, e.g. /sys/devices/pci0000:3a/0000:3a:00.0/0000:3b:00.0
+	resolvedPath, err := filepath.EvalSymlinks(devicePath)
+	if err != nil {
+		log.Log.Error(err, "GetPCITopology(): failed to resolve device path", "pciAddr", pciAddr)
+		return nil, err
+	}
+	for _, element := range strings.Split(resolvedPath, string(filepath.Separator)) {
+		if strings.HasPrefix(element, "pci") && strings.Contains(element, ":") {
+			topology.RootComplex = element
+			break
+		}
+	}
+
+	return topology, nil
+}
+
 // GetMaxReadRequestSize returns MaxReadRequest size for PCI device
 func (h *hostUtils) GetMaxReadRequestSize(pciAddr string) (int, error) {
 	log.Log.Info("HostUtils.GetMaxReadRequestSize()", "pciAddr", pciAddr)
@@ -225,6 +551,11 @@ func (h *hostUtils) GetMaxReadRequestSize(pciAddr string) (int, error) {
 		log.Log.Error(err, "GetMaxReadRequestSize(): Failed to run lspci")
 		return -1, err
 	}
+	if strings.Contains(string(output), lspciAccessDenied) {
+		err = fmt.Errorf("lspci can't read PCI capabilities of device %s, CAP_SYS_ADMIN is required", pciAddr)
+		log.Log.Error(err, "GetMaxReadRequestSize(): Failed to run lspci")
+		return -1, err
+	}
 
 	maxReadReqRegexp := regexp.MustCompile(consts.MaxReadReqPrefix + `\s+(\d+)\s+bytes`)
 
@@ -264,7 +595,7 @@ func (h *hostUtils) GetTrustAndPFC(interfaceName string) (string, string, error)
 	cmd := h.execInterface.Command("mlnx_qos", "-i", interfaceName)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		err = fmt.Errorf("failed to run mlnx_qos: %s", output)
+		err = fmt.Errorf("failed to run mlnx_qos: %w: %s", err, output)
 		log.Log.Error(err, "GetTrustAndPFC(): Failed to run mlnx_qos")
 		return "", "", err
 	}
@@ -296,6 +627,60 @@ func (h *hostUtils) GetTrustAndPFC(interfaceName string) (string, string, error)
 	return trust, pfc, nil
 }
 
+// GetTcBandwidth returns the ETS bandwidth shares of the traffic classes of a network interface, e.g. 0,0,0,60,40,0,0,0
+// traffic classes not scheduled by ETS, e.g. strict priority ones, have no share
+func (h *hostUtils) GetTcBandwidth(interfaceName string) (string, error) {
+	log.Log.Info("HostUtils.GetTcBandwidth()", "interface", interfaceName)
+	cmd := h.execInterface.Command("mlnx_qos", "-i", interfaceName)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		err = fmt.Errorf("failed to run mlnx_qos: %w: %s", err, output)
+		log.Log.Error(err, "GetTcBandwidth(): Failed to run mlnx_qos")
+		return "", err
+	}
+
+	// Each traffic class is reported as "tc: 3 ratelimit: unlimited, tsa: ets, bw: 60%"
+	shares := make([]string, consts.TrafficClassCount)
+	for i := range shares {
+		shares[i] = "0"
+	}
+	found := false
+
+	scanner := bufio.NewScanner(strings.NewReader(string(output)))
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.ToLower(scanner.Text()))
+		if !strings.HasPrefix(line, consts.TcPrefix) {
+			continue
+		}
+
+		fields := strings.Fields(strings.TrimPrefix(line, consts.TcPrefix))
+		if len(fields) == 0 {
+			continue
+		}
+		tc, err := strconv.Atoi(fields[0])
+		if err != nil || tc < 0 || tc >= consts.TrafficClassCount {
+			continue
+		}
+		found = true
+
+		_, bw, ok := strings.Cut(line, consts.TcBandwidthPrefix)
+		if ok {
+			shares[tc] = strings.TrimSuffix(strings.TrimSpace(bw), "%")
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		log.Log.Error(err, "GetTcBandwidth(): Error reading mlnx_qos output")
+		return "", err
+	}
+
+	if !found {
+		return "", fmt.Errorf("GetTcBandwidth(): traffic classes of interface %s not found", interfaceName)
+	}
+
+	return strings.Join(shares, ","), nil
+}
+
 // GetLinkType return the link type of the net device (Ethernet / Infiniband)
 func (h *hostUtils) GetLinkType(name string) string {
 	log.Log.Info("HostUtils.GetLinkType()", "name", name)
@@ -307,6 +692,61 @@ func (h *hostUtils) GetLinkType(name string) string {
 	return encapTypeToLinkType(link.Attrs().EncapType)
 }
 
+// GetPortSpeed returns the speed of the PF's network port in Mb/s, e.g. 100000 for 100G
+// returns 0 if the link is down or its speed is unknown
+func (h *hostUtils) GetPortSpeed(pciAddr string) (int, error) {
+	log.Log.Info("HostUtils.GetPortSpeed()", "pciAddr", pciAddr)
+
+	names, err := getNetNames(pciAddr)
+	if err != nil {
+		return 0, err
+	}
+	if len(names) == 0 {
+		return 0, fmt.Errorf("no network interface found for device %s", pciAddr)
+	}
+
+	value, err := os.ReadFile(filepath.Join(pciDevicesPath, pciAddr, "net", names[0], "speed"))
+	if err != nil {
+		// Reading the speed fails with EINVAL while the link is down
+		log.Log.V(2).Info("port speed is not available", "pciAddr", pciAddr, "reason", err.Error())
+		return 0, nil
+	}
+
+	speed, err := strconv.Atoi(strings.TrimSpace(string(value)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse port speed of device %s: %w", pciAddr, err)
+	}
+	if speed < 0 {
+		// SPEED_UNKNOWN
+		return 0, nil
+	}
+
+	return speed, nil
+}
+
+// GetPtpClockIndex returns the index of the PF's PTP hardware clock, e.g. 0 for /dev/ptp0
+// returns -1 if the PF has no PTP hardware clock
+func (h *hostUtils) GetPtpClockIndex(pciAddr string) (int, error) {
+	log.Log.Info("HostUtils.GetPtpClockIndex()", "pciAddr", pciAddr)
+
+	// The driver registers the PHC under the PCI device, the same index is reported by ethtool -T
+	clocks, err := filepath.Glob(filepath.Join(pciDevicesPath, pciAddr, "ptp", "ptp*"))
+	if err != nil {
+		return -1, err
+	}
+	if len(clocks) == 0 {
+		log.Log.V(2).Info("PTP hardware clock is not available", "pciAddr", pciAddr)
+		return -1, nil
+	}
+
+	index, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(clocks[0]), "ptp"))
+	if err != nil {
+		return -1, fmt.Errorf("failed to parse PTP hardware clock %s of device %s: %w", filepath.Base(clocks[0]), pciAddr, err)
+	}
+
+	return index, nil
+}
+
 func encapTypeToLinkType(encapType string) string {
 	if encapType == "ether" {
 		return consts.Ethernet
@@ -499,32 +939,154 @@ func (h *hostUtils) SetNvConfigParameter(pciAddr string, paramName string, param
 	log.Log.Info("HostUtils.SetNvConfigParameter()", "pciAddr", pciAddr, "paramName", paramName, "paramValue", paramValue)
 
 	cmd := h.execInterface.Command("mstconfig", "-d", pciAddr, "--yes", "set", paramName+"="+paramValue)
-	_, err := cmd.Output()
+	output, err := cmd.CombinedOutput()
 	if err != nil {
 		log.Log.Error(err, "SetNvConfigParameter(): Failed to run mstconfig")
-		return err
+		return nvConfigWriteError(pciAddr, output, err)
 	}
 	return nil
 }
 
+// nvConfigLockRegex matches the mstconfig errors of the writes rejected because the nv config of the device is held
+// by the BMC or DPU, e.g. "-E- Failed to set configuration: Operation not permitted (restricted host)"
+// generic permission and capability failures of the tool, e.g. "Operation not permitted" opening the device, are not matched
+var nvConfigLockRegex = regexp.MustCompile(`(?i)failed to set configuration:.*(\(restricted host\)|write protected)`)
+
+// nvConfigWriteError returns types.ConfigOwnershipDeniedError if the nv config write failed because of a lock held by the BMC or DPU
+// other errors are returned as is
+func nvConfigWriteError(pciAddr string, output []byte, err error) error {
+	if types.IsToolHangError(err) {
+		return err
+	}
+
+	message := strings.TrimSpace(string(output))
+	if nvConfigLockRegex.MatchString(message) {
+		return types.ConfigOwnershipDeniedError(fmt.Sprintf("nv config of device %s is locked by the BMC or DPU: %s", pciAddr, message))
+	}
+
+	return err
+}
+
+// nvConfigSimulateOption makes mstconfig validate the set values with the firmware without writing them
+// simulated sets are only available in the recent releases of the tools, support is detected from the help output
+const nvConfigSimulateOption = "--simulate"
+
+// SimulateNvConfigParameters runs a simulated set of the nv config parameters, the firmware validates the values and their
+// interdependencies without changing the nv config
+// returns false if the tooling doesn't support simulated sets
+// returns types.IncorrectSpecError if the firmware rejects the values
+func (h *hostUtils) SimulateNvConfigParameters(pciAddr string, params map[string]string) (bool, error) {
+	log.Log.Info("HostUtils.SimulateNvConfigParameters()", "pciAddr", pciAddr, "params", params)
+
+	h.simulateSupportOnce.Do(func() {
+		// Help is printed with a non-zero exit code by some releases
+		output, _ := h.execInterface.Command("mstconfig", "-h").CombinedOutput()
+		h.simulateSupported = strings.Contains(string(output), nvConfigSimulateOption)
+	})
+	if !h.simulateSupported {
+		log.Log.V(2).Info("simulated nv config set is not supported by mstconfig")
+		return false, nil
+	}
+
+	names := make([]string, 0, len(params))
+	for param := range params {
+		names = append(names, param)
+	}
+	sort.Strings(names)
+
+	args := []string{"-d", pciAddr, "--yes", nvConfigSimulateOption, "set"}
+	for _, param := range names {
+		args = append(args, param+"="+params[param])
+	}
+
+	cmd := h.execInterface.Command("mstconfig", args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if types.IsToolHangError(err) {
+			return true, err
+		}
+		err = types.IncorrectSpecError(fmt.Sprintf("nv config values rejected by the firmware of device %s: %s", pciAddr, strings.TrimSpace(string(output))))
+		log.Log.Error(err, "SimulateNvConfigParameters(): simulated mstconfig set failed")
+		return true, err
+	}
+	return true, nil
+}
+
 // ResetNvConfig resets NIC's nv config
 func (h *hostUtils) ResetNvConfig(pciAddr string) error {
 	log.Log.Info("HostUtils.ResetNvConfig()", "pciAddr", pciAddr)
 
 	cmd := h.execInterface.Command("mstconfig", "-d", pciAddr, "--yes", "reset")
-	_, err := cmd.Output()
+	output, err := cmd.CombinedOutput()
 	if err != nil {
 		log.Log.Error(err, "ResetNvConfig(): Failed to run mstconfig")
-		return err
+		return nvConfigWriteError(pciAddr, output, err)
 	}
 	return nil
 }
 
+// hostPrivilegeLevelRegex matches the privilege level in the mstprivhost query output, e.g. "level : RESTRICTED"
+var hostPrivilegeLevelRegex = regexp.MustCompile(`(?mi)^\s*level\s*:\s*(\S+)`)
+
+// GetHostPrivilegeLevel returns the privilege level of the host for the device, e.g. PRIVILEGED or RESTRICTED
+// restricted hosts can't change the nv config, it's owned by the DPU's ARM side or the BMC
+func (h *hostUtils) GetHostPrivilegeLevel(pciAddr string) (string, error) {
+	log.Log.Info("HostUtils.GetHostPrivilegeLevel()", "pciAddr", pciAddr)
+
+	cmd := h.execInterface.Command("mstprivhost", "-d", pciAddr, "query")
+	output, err := cmd.Output()
+	if err != nil {
+		log.Log.Error(err, "GetHostPrivilegeLevel(): Failed to run mstprivhost")
+		return "", err
+	}
+
+	match := hostPrivilegeLevelRegex.FindSubmatch(output)
+	if match == nil {
+		return "", fmt.Errorf("privilege level of device %s not found in mstprivhost output", pciAddr)
+	}
+	return strings.ToUpper(string(match[1])), nil
+}
+
+// deviceResetSettleTime is the time after a device reset during which the reset is still considered in progress,
+// the PFs of the reset device are re-bound to the driver and their uevents are delivered after the reset tool returns
+var deviceResetSettleTime = time.Second * 10
+
+var (
+	deviceResetsLock sync.Mutex
+	// deviceResets is the number of the device resets in progress on the host
+	deviceResets int
+	// deviceResetFinished is the time the last device reset on the host finished
+	deviceResetFinished time.Time
+)
+
+// startDeviceReset records a reset of a device of the host, e.g. a FW reset, the returned function records its end
+func startDeviceReset() func() {
+	deviceResetsLock.Lock()
+	defer deviceResetsLock.Unlock()
+	deviceResets++
+
+	return func() {
+		deviceResetsLock.Lock()
+		defer deviceResetsLock.Unlock()
+		deviceResets--
+		deviceResetFinished = time.Now()
+	}
+}
+
+// DeviceResetInProgress returns true while a device of the host is reset, e.g. by a FW reset or a BFB installation,
+// and for deviceResetSettleTime after, the ports of the device disappear from the host until it is re-bound to the driver
+func DeviceResetInProgress() bool {
+	deviceResetsLock.Lock()
+	defer deviceResetsLock.Unlock()
+	return deviceResets > 0 || time.Since(deviceResetFinished) < deviceResetSettleTime
+}
+
 // ResetNicFirmware resets NIC's firmware
 // Operation can be long, required context to be able to terminate by timeout
 // IB devices need to communicate with other nodes for confirmation
 func (h *hostUtils) ResetNicFirmware(ctx context.Context, pciAddr string) error {
 	log.Log.Info("HostUtils.ResetNicFirmware()", "pciAddr", pciAddr)
+	defer startDeviceReset()()
 
 	cmd := h.execInterface.CommandContext(ctx, "mlxfwreset", "--device", pciAddr, "reset", "--yes")
 	_, err := cmd.Output()
@@ -535,6 +1097,37 @@ func (h *hostUtils) ResetNicFirmware(ctx context.Context, pciAddr string) error
 	return nil
 }
 
+// BurnFirmware burns the firmware image to the PCI device with mstflint
+// the new firmware is activated after reboot or FW reset
+// the burn progress printed by mstflint is reported to the firmware progress function of the context
+// an in-progress burn is never killed, neither by the hard ceiling of the host tools nor by the cancellation of the context
+func (h *hostUtils) BurnFirmware(ctx context.Context, pciAddr string, imagePath string) error {
+	log.Log.Info("HostUtils.BurnFirmware()", "pciAddr", pciAddr, "imagePath", imagePath)
+
+	cmd := h.burnCommand(pciAddr, imagePath)
+	output := newProgressWriter(func(percent int) {
+		reportFirmwareProgress(ctx, consts.FirmwareUpdatePhaseFlashing, percent)
+	})
+	cmd.SetStdout(output)
+	cmd.SetStderr(output)
+	err := cmd.Run()
+	if err != nil {
+		err = fmt.Errorf("failed to burn firmware: %w: %s", err, output.Bytes())
+		log.Log.Error(err, "BurnFirmware(): Failed to run mstflint")
+		return err
+	}
+	return nil
+}
+
+// burnCommand returns the mstflint command burning the image to the device, exempt from the hard ceiling of the host tools
+func (h *hostUtils) burnCommand(pciAddr string, imagePath string) execUtils.Cmd {
+	args := []string{"-d", pciAddr, "-i", imagePath, "-y", "burn"}
+	if h.watchdog == nil {
+		return h.execInterface.Command("mstflint", args...)
+	}
+	return h.watchdog.UntimedCommand("mstflint", args...)
+}
+
 // SetMaxReadRequestSize sets max read request size for PCI device
 func (h *hostUtils) SetMaxReadRequestSize(pciAddr string, maxReadRequestSize int) error {
 	log.Log.Info("HostUtils.SetMaxReadRequestSize()", "pciAddr", pciAddr, "maxReadRequestSize", maxReadRequestSize)
@@ -573,73 +1166,1310 @@ func (h *hostUtils) SetTrustAndPFC(interfaceName string, trust string, pfc strin
 	cmd := h.execInterface.Command("mlnx_qos", "-i", interfaceName, "--trust", trust, "--pfc", pfc)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		err = fmt.Errorf("failed to run mlnx_qos: %s", output)
+		err = fmt.Errorf("failed to run mlnx_qos: %w: %s", err, output)
 		log.Log.Error(err, "SetTrustAndPFC(): Failed to run mlnx_qos")
 		return err
 	}
 	return nil
 }
 
-func (h *hostUtils) ScheduleReboot() error {
-	log.Log.Info("HostUtils.ScheduleReboot()")
-	root, err := os.Open("/")
+// SetTcBandwidth allocates the ETS bandwidth shares to the traffic classes of a network interface,
+// all traffic classes are scheduled by ETS
+func (h *hostUtils) SetTcBandwidth(interfaceName string, tcBandwidth string) error {
+	log.Log.Info("HostUtils.SetTcBandwidth()", "interfaceName", interfaceName, "tcBandwidth", tcBandwidth)
+
+	tsa := strings.TrimSuffix(strings.Repeat("ets,", consts.TrafficClassCount), ",")
+	cmd := h.execInterface.Command("mlnx_qos", "-i", interfaceName, "--tsa", tsa, "--tcbw", tcBandwidth)
+	output, err := cmd.CombinedOutput()
 	if err != nil {
-		log.Log.Error(err, "ScheduleReboot(): Failed to os.Open")
+		err = fmt.Errorf("failed to run mlnx_qos: %w: %s", err, output)
+		log.Log.Error(err, "SetTcBandwidth(): Failed to run mlnx_qos")
 		return err
 	}
+	return nil
+}
 
-	if err := syscall.Chroot(consts.HostPath); err != nil {
-		err := root.Close()
-		if err != nil {
-			log.Log.Error(err, "ScheduleReboot(): Failed to syscall.Chroot")
-			return err
-		}
-		return err
+// dcbAppCategorySuffix ends the category names in the dcb app output, e.g. ethtype-prio
+const dcbAppCategorySuffix = "-prio"
+
+// dcbAppDscpCategory is the dcb app category of the DSCP to priority mappings
+const dcbAppDscpCategory = "dscp-prio"
+
+// GetDcbAppEntries returns the DCB app table of a network interface with dcb, e.g. ethtype-prio 0x8906:3
+// the entries are prefixed with their dcb category and are accepted by DeleteDcbAppEntry as is
+func (h *hostUtils) GetDcbAppEntries(interfaceName string) ([]string, error) {
+	log.Log.Info("HostUtils.GetDcbAppEntries()", "interface", interfaceName)
+	cmd := h.execInterface.Command("dcb", "app", "show", "dev", interfaceName)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		err = fmt.Errorf("failed to run dcb: %w: %s", err, output)
+		log.Log.Error(err, "GetDcbAppEntries(): Failed to run dcb")
+		return nil, err
 	}
 
-	defer func() {
-		if err := root.Close(); err != nil {
-			log.Log.Error(err, "ScheduleReboot(): Failed to os.Close")
-			return
-		}
-		if err := root.Chdir(); err != nil {
-			log.Log.Error(err, "ScheduleReboot(): Failed to os.Chdir")
-			return
+	// Each category is followed by its entries, e.g. "ethtype-prio 0x8906:3 0x8914:3"
+	entries := []string{}
+	category := ""
+	for _, field := range strings.Fields(string(output)) {
+		if strings.HasSuffix(field, dcbAppCategorySuffix) {
+			category = field
+			continue
 		}
-		if err = syscall.Chroot("."); err != nil {
-			log.Log.Error(err, "ScheduleReboot(): Failed to syscall.Chroot")
+		if category == "" {
+			continue
 		}
-	}()
+		entries = append(entries, category+" "+field)
+	}
 
-	cmd := h.execInterface.Command("shutdown", "-r", "now")
-	_, err = cmd.Output()
+	return entries, nil
+}
+
+// DeleteDcbAppEntry deletes an entry returned by GetDcbAppEntries from the DCB app table of a network interface
+func (h *hostUtils) DeleteDcbAppEntry(interfaceName string, entry string) error {
+	log.Log.Info("HostUtils.DeleteDcbAppEntry()", "interface", interfaceName, "entry", entry)
+
+	args := append([]string{"app", "del", "dev", interfaceName}, strings.Fields(entry)...)
+	cmd := h.execInterface.Command("dcb", args...)
+	output, err := cmd.CombinedOutput()
 	if err != nil {
-		log.Log.Error(err, "ScheduleReboot(): Failed to run shutdown -r now")
+		err = fmt.Errorf("failed to run dcb: %w: %s", err, output)
+		log.Log.Error(err, "DeleteDcbAppEntry(): Failed to run dcb")
 		return err
 	}
 	return nil
 }
 
-// GetOfedVersion retrieves installed OFED version
-func (h *hostUtils) GetOfedVersion() string {
-	log.Log.Info("HostUtils.GetOfedVersion()")
-	versionBytes, err := os.ReadFile(filepath.Join(consts.HostPath, consts.Mlx5ModuleVersionPath))
+// GetRootQdisc returns the kind of the root qdisc of a network interface, e.g. mq or mqprio
+func (h *hostUtils) GetRootQdisc(interfaceName string) (string, error) {
+	log.Log.Info("HostUtils.GetRootQdisc()", "interface", interfaceName)
+	cmd := h.execInterface.Command("tc", "qdisc", "show", "dev", interfaceName, "root")
+	output, err := cmd.CombinedOutput()
 	if err != nil {
-		log.Log.Error(err, "GetOfedVersion(): failed to read mlx5_core version file, OFED isn't installed")
-		return ""
+		err = fmt.Errorf("failed to run tc: %w: %s", err, output)
+		log.Log.Error(err, "GetRootQdisc(): Failed to run tc")
+		return "", err
 	}
-	version := strings.TrimSuffix(string(versionBytes), "\n")
-	log.Log.Info("HostUtils.GetOfedVersion(): OFED version", "version", version)
-	return version
+
+	// The qdisc is reported as "qdisc mqprio 8001: root tc 8 map ..."
+	fields := strings.Fields(string(output))
+	if len(fields) < 2 || fields[0] != "qdisc" {
+		return "", fmt.Errorf("root qdisc of interface %s not found in tc output", interfaceName)
+	}
+	return fields[1], nil
 }
 
-// GetHostUptimeSeconds returns the host uptime in seconds
-func (h *hostUtils) GetHostUptimeSeconds() (time.Duration, error) {
-	log.Log.V(2).Info("HostUtils.GetHostUptimeSeconds()")
-	output, err := os.ReadFile("/proc/uptime")
+// DeleteRootQdisc deletes the root qdisc of a network interface, the kernel restores the default one
+func (h *hostUtils) DeleteRootQdisc(interfaceName string) error {
+	log.Log.Info("HostUtils.DeleteRootQdisc()", "interface", interfaceName)
+	cmd := h.execInterface.Command("tc", "qdisc", "del", "dev", interfaceName, "root")
+	output, err := cmd.CombinedOutput()
 	if err != nil {
-		log.Log.Error(err, "HostUtils.GetHostUptimeSeconds(): failed to read the system's uptime")
-		return 0, err
+		err = fmt.Errorf("failed to run tc: %w: %s", err, output)
+		log.Log.Error(err, "DeleteRootQdisc(): Failed to run tc")
+		return err
+	}
+	return nil
+}
+
+// IsCongestionControlSupported returns true if the driver of a network interface exposes the ECN and DCQCN settings
+// in the sysfs ecn directory of the interface, only the MOFED driver does, the inbox mlx5 driver doesn't have it
+func (h *hostUtils) IsCongestionControlSupported(interfaceName string) bool {
+	ecnPath := filepath.Join(netDevicesPath, interfaceName, "ecn")
+	for _, point := range []string{consts.EcnReactionPointDir, consts.EcnNotificationPointDir} {
+		_, err := os.Stat(filepath.Join(ecnPath, point))
+		if err != nil {
+			log.Log.V(2).Info("congestion control settings are not available", "interfaceName", interfaceName, "reason", err.Error())
+			return false
+		}
+	}
+	return true
+}
+
+// GetEcn returns the priorities of a network interface with ECN enabled, e.g. 0,0,0,1,0,0,0,0
+// a priority is reported as enabled only if ECN is enabled both on the reaction and the notification point
+func (h *hostUtils) GetEcn(interfaceName string) (string, error) {
+	log.Log.Info("HostUtils.GetEcn()", "interfaceName", interfaceName)
+
+	ecnPath := filepath.Join(netDevicesPath, interfaceName, "ecn")
+	priorities := make([]string, consts.TrafficClassCount)
+	for i := range priorities {
+		priorities[i] = "1"
+		for _, point := range []string{consts.EcnReactionPointDir, consts.EcnNotificationPointDir} {
+			value, err := os.ReadFile(filepath.Join(ecnPath, point, "enable", strconv.Itoa(i)))
+			if err != nil {
+				log.Log.Error(err, "GetEcn(): failed to read ECN settings", "interfaceName", interfaceName)
+				return "", err
+			}
+			if strings.TrimSpace(string(value)) != "1" {
+				priorities[i] = "0"
+			}
+		}
+	}
+
+	return strings.Join(priorities, ","), nil
+}
+
+// SetEcn enables ECN for the priorities of a network interface, both on the reaction and the notification point
+func (h *hostUtils) SetEcn(interfaceName string, ecn string) error {
+	log.Log.Info("HostUtils.SetEcn()", "interfaceName", interfaceName, "ecn", ecn)
+	err := checkCapabilities(sysfsWriteOperation)
+	if err != nil {
+		log.Log.Error(err, "SetEcn(): can't change the settings of the host")
+		return err
+	}
+
+	priorities := strings.Split(ecn, ",")
+	if len(priorities) != consts.TrafficClassCount {
+		return fmt.Errorf("SetEcn(): expected %d priorities, got %q", consts.TrafficClassCount, ecn)
+	}
+
+	ecnPath := filepath.Join(netDevicesPath, interfaceName, "ecn")
+	for i, enabled := range priorities {
+		for _, point := range []string{consts.EcnReactionPointDir, consts.EcnNotificationPointDir} {
+			err := os.WriteFile(filepath.Join(ecnPath, point, "enable", strconv.Itoa(i)), []byte(enabled), 0644)
+			if err != nil {
+				log.Log.Error(err, "SetEcn(): failed to write ECN settings", "interfaceName", interfaceName)
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// GetDcqcnParameter returns the value of a DCQCN parameter of a network interface, e.g. roce_rp/rpg_min_rate
+func (h *hostUtils) GetDcqcnParameter(interfaceName string, name string) (int, error) {
+	log.Log.Info("HostUtils.GetDcqcnParameter()", "interfaceName", interfaceName, "name", name)
+
+	value, err := os.ReadFile(filepath.Join(netDevicesPath, interfaceName, "ecn", name))
+	if err != nil {
+		log.Log.Error(err, "GetDcqcnParameter(): failed to read DCQCN parameter", "interfaceName", interfaceName, "name", name)
+		return 0, err
+	}
+
+	parameter, err := strconv.Atoi(strings.TrimSpace(string(value)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse DCQCN parameter %s of interface %s: %w", name, interfaceName, err)
+	}
+	return parameter, nil
+}
+
+// SetDcqcnParameter sets the value of a DCQCN parameter of a network interface
+func (h *hostUtils) SetDcqcnParameter(interfaceName string, name string, value int) error {
+	log.Log.Info("HostUtils.SetDcqcnParameter()", "interfaceName", interfaceName, "name", name, "value", value)
+	err := checkCapabilities(sysfsWriteOperation)
+	if err != nil {
+		log.Log.Error(err, "SetDcqcnParameter(): can't change the settings of the host")
+		return err
+	}
+
+	err = os.WriteFile(filepath.Join(netDevicesPath, interfaceName, "ecn", name), []byte(strconv.Itoa(value)), 0644)
+	if err != nil {
+		log.Log.Error(err, "SetDcqcnParameter(): failed to write DCQCN parameter", "interfaceName", interfaceName, "name", name)
+		return err
+	}
+	return nil
+}
+
+// GetMtu returns the MTU of a network interface
+func (h *hostUtils) GetMtu(interfaceName string) (int, error) {
+	link, err := netlink.LinkByName(interfaceName)
+	if err != nil {
+		log.Log.Error(err, "GetMtu(): failed to get link", "interfaceName", interfaceName)
+		return 0, err
+	}
+	return link.Attrs().MTU, nil
+}
+
+// SetMtu sets the MTU of a network interface
+func (h *hostUtils) SetMtu(interfaceName string, mtu int) error {
+	log.Log.Info("HostUtils.SetMtu()", "interfaceName", interfaceName, "mtu", mtu)
+
+	link, err := netlink.LinkByName(interfaceName)
+	if err != nil {
+		log.Log.Error(err, "SetMtu(): failed to get link", "interfaceName", interfaceName)
+		return err
+	}
+	err = netlink.LinkSetMTU(link, mtu)
+	if err != nil {
+		log.Log.Error(err, "SetMtu(): failed to set MTU", "interfaceName", interfaceName)
+		return err
+	}
+	return nil
+}
+
+// GetEthtoolFeatures returns the ethtool features of a network interface and their state, keyed by the feature name
+func (h *hostUtils) GetEthtoolFeatures(interfaceName string) (map[string]types.EthtoolFeature, error) {
+	cmd := h.execInterface.Command("ethtool", "-k", interfaceName)
+	output, err := cmd.Output()
+	if err != nil {
+		log.Log.Error(err, "GetEthtoolFeatures(): Failed to run ethtool")
+		return nil, err
+	}
+
+	// Output has the "Features for <interface>:" header followed by "<feature>: on|off [fixed]" lines
+	features := map[string]types.EthtoolFeature{}
+	for _, line := range strings.Split(string(output), "\n") {
+		name, value, found := strings.Cut(line, ":")
+		if !found || strings.HasPrefix(name, "Features for") {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		features[strings.TrimSpace(name)] = types.EthtoolFeature{
+			Enabled: strings.HasPrefix(value, "on"),
+			Fixed:   strings.HasSuffix(value, "[fixed]"),
+		}
+	}
+	return features, nil
+}
+
+// SetEthtoolFeature enables or disables the ethtool feature of a network interface
+func (h *hostUtils) SetEthtoolFeature(interfaceName string, feature string, enabled bool) error {
+	log.Log.Info("HostUtils.SetEthtoolFeature()", "interfaceName", interfaceName, "feature", feature, "enabled", enabled)
+
+	state := "off"
+	if enabled {
+		state = "on"
+	}
+	cmd := h.execInterface.Command("ethtool", "-K", interfaceName, feature, state)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		err = fmt.Errorf("failed to run ethtool: %w: %s", err, output)
+		log.Log.Error(err, "SetEthtoolFeature(): Failed to run ethtool")
+		return err
+	}
+	return nil
+}
+
+// transceiverSpeedRegex matches the Ethernet speeds in the compliance codes of the module, e.g. 100G Base-CR4
+var transceiverSpeedRegex = regexp.MustCompile(`\b(\d+)G\b`)
+
+// GetTransceiver returns the cable or optical module plugged into the port of a network interface
+// returns nil if the port's cage is empty
+func (h *hostUtils) GetTransceiver(interfaceName string) (*types.Transceiver, error) {
+	cmd := h.execInterface.Command("ethtool", "-m", interfaceName)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		// The EEPROM of an empty cage can't be read
+		if strings.Contains(strings.ToLower(string(output)), "input/output error") {
+			log.Log.V(2).Info("no module plugged into the port", "interfaceName", interfaceName)
+			return nil, nil
+		}
+		err = fmt.Errorf("failed to run ethtool: %w: %s", err, output)
+		log.Log.Error(err, "GetTransceiver(): Failed to run ethtool")
+		return nil, err
+	}
+
+	// Output has "<field> : <value>" lines, coded values are followed by their description, e.g. "0x11 (QSFP28)"
+	describedValue := func(value string) string {
+		start, end := strings.Index(value, "("), strings.LastIndex(value, ")")
+		if start == -1 || end < start {
+			return value
+		}
+		return value[start+1 : end]
+	}
+
+	transceiver := &types.Transceiver{}
+	speeds := map[int]bool{}
+	for _, line := range strings.Split(string(output), "\n") {
+		name, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+
+		switch {
+		case name == "Identifier":
+			transceiver.Type = describedValue(value)
+		case name == "Transmitter technology":
+			transceiver.Technology = describedValue(value)
+		case name == "Vendor name":
+			transceiver.Vendor = value
+		case name == "Vendor PN":
+			transceiver.PartNumber = value
+		case strings.HasPrefix(name, "Length (") && transceiver.Length == "":
+			// Modules report the length for each kind of fiber and the copper cable, the unused ones are 0
+			length, err := strconv.ParseFloat(strings.TrimRightFunc(value, unicode.IsLetter), 64)
+			if err != nil || length != 0 {
+				transceiver.Length = value
+			}
+		case name == "Transceiver type":
+			for _, match := range transceiverSpeedRegex.FindAllStringSubmatch(value, -1) {
+				speed, err := strconv.Atoi(match[1])
+				if err == nil {
+					speeds[speed] = true
+				}
+			}
+		}
+	}
+
+	if transceiver.Type == "" {
+		err = fmt.Errorf("module of interface %s not found in ethtool output", interfaceName)
+		log.Log.Error(err, "GetTransceiver(): Failed to parse ethtool output")
+		return nil, err
+	}
+
+	sortedSpeeds := []int{}
+	for speed := range speeds {
+		sortedSpeeds = append(sortedSpeeds, speed)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(sortedSpeeds)))
+	for _, speed := range sortedSpeeds {
+		transceiver.SupportedSpeeds = append(transceiver.SupportedSpeeds, fmt.Sprintf("%dG", speed))
+	}
+
+	return transceiver, nil
+}
+
+// GetLinkStatus returns the operational state, negotiated speed and auto-negotiation of a network interface
+// the state is read from sysfs, the speed and auto-negotiation are parsed from the ethtool output
+func (h *hostUtils) GetLinkStatus(interfaceName string) (*types.LinkStatus, error) {
+	operState, err := os.ReadFile(filepath.Join(netDevicesPath, interfaceName, "operstate"))
+	if err != nil {
+		log.Log.Error(err, "GetLinkStatus(): failed to read operational state", "interfaceName", interfaceName)
+		return nil, err
+	}
+	link := &types.LinkStatus{OperState: strings.TrimSpace(string(operState))}
+
+	cmd := h.execInterface.Command("ethtool", interfaceName)
+	output, err := cmd.Output()
+	if err != nil {
+		err = fmt.Errorf("failed to run ethtool: %w: %s", err, output)
+		log.Log.Error(err, "GetLinkStatus(): Failed to run ethtool")
+		return nil, err
+	}
+
+	// Output has "<field>: <value>" lines, the speed is "Unknown!" while the link is down
+	for _, line := range strings.Split(string(output), "\n") {
+		name, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+
+		switch name {
+		case "Speed":
+			speed, err := strconv.Atoi(strings.TrimSuffix(value, "Mb/s"))
+			if err == nil && speed > 0 {
+				link.Speed = speed
+			}
+		case "Auto-negotiation":
+			autoNegotiation := value == "on"
+			link.AutoNegotiation = &autoNegotiation
+		}
+	}
+
+	return link, nil
+}
+
+// GetRdmaPort returns the link layer, the GUIDs and the link state of the first port of the RDMA device, e.g. mlx5_0
+// the mlx5 RDMA devices of the PFs have a single port, the attributes are read from sysfs
+// returns nil if the RDMA device doesn't exist
+func (h *hostUtils) GetRdmaPort(rdmaDevice string) (*types.RdmaPort, error) {
+	log.Log.V(2).Info("HostUtils.GetRdmaPort()", "rdmaDevice", rdmaDevice)
+
+	devicePath := filepath.Join(infinibandDevicesPath, rdmaDevice)
+	portPath := filepath.Join(devicePath, "ports", "1")
+
+	nodeGUID, err := os.ReadFile(filepath.Join(devicePath, "node_guid"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		log.Log.Error(err, "GetRdmaPort(): failed to read node GUID", "rdmaDevice", rdmaDevice)
+		return nil, err
+	}
+	port := &types.RdmaPort{NodeGUID: strings.TrimSpace(string(nodeGUID))}
+
+	attributes := map[string]*string{
+		"link_layer": &port.LinkLayer,
+		"state":      &port.State,
+		"phys_state": &port.PhysicalState,
+		"rate":       &port.Rate,
+	}
+	for name, value := range attributes {
+		content, err := os.ReadFile(filepath.Join(portPath, name))
+		if err != nil {
+			log.Log.Error(err, "GetRdmaPort(): failed to read port attribute", "rdmaDevice", rdmaDevice, "attribute", name)
+			return nil, err
+		}
+		*value = strings.TrimSpace(string(content))
+	}
+	// States are reported with their numeric value, e.g. "4: ACTIVE" and "5: LinkUp"
+	for _, state := range []*string{&port.State, &port.PhysicalState} {
+		if _, name, found := strings.Cut(*state, ":"); found {
+			*state = strings.TrimSpace(name)
+		}
+	}
+
+	// Port GUID is the interface ID of the port's link local GID, e.g. fe80:0000:0000:0000:0c42:a103:0016:054c
+	if port.LinkLayer == consts.RdmaLinkLayerInfiniband {
+		content, err := os.ReadFile(filepath.Join(portPath, "gids", "0"))
+		if err != nil {
+			log.Log.Error(err, "GetRdmaPort(): failed to read port GID", "rdmaDevice", rdmaDevice)
+			return nil, err
+		}
+		gid := strings.TrimSpace(string(content))
+		groups := strings.Split(gid, ":")
+		if len(groups) != 8 {
+			return nil, fmt.Errorf("failed to parse GID %s of RDMA device %s", gid, rdmaDevice)
+		}
+		port.PortGUID = strings.Join(groups[4:], ":")
+	}
+
+	return port, nil
+}
+
+// GetTemperature returns the ASIC temperature of the device in degrees Celsius as reported by mstmget_temp
+func (h *hostUtils) GetTemperature(pciAddr string) (int, error) {
+	log.Log.V(2).Info("HostUtils.GetTemperature()", "pciAddr", pciAddr)
+
+	cmd := h.execInterface.Command("mstmget_temp", "-d", pciAddr)
+	output, err := cmd.Output()
+	if err != nil {
+		err = fmt.Errorf("failed to run mstmget_temp: %w: %s", err, output)
+		log.Log.Error(err, "GetTemperature(): Failed to run mstmget_temp")
+		return 0, err
+	}
+
+	// Output is the temperature, padded with whitespace
+	temperature, err := strconv.Atoi(strings.TrimSpace(string(output)))
+	if err != nil {
+		log.Log.Error(err, "GetTemperature(): Failed to parse mstmget_temp output", "output", string(output))
+		return 0, err
+	}
+
+	return temperature, nil
+}
+
+// GetHealthReporters returns the devlink health reporters of the device and of its ports, sorted by name
+func (h *hostUtils) GetHealthReporters(pciAddr string) ([]types.HealthReporter, error) {
+	log.Log.V(2).Info("HostUtils.GetHealthReporters()", "pciAddr", pciAddr)
+
+	devlinkName := "pci/" + pciAddr
+	cmd := h.execInterface.Command("devlink", "health", "show", devlinkName, "-j")
+	output, err := cmd.Output()
+	if err != nil {
+		log.Log.Error(err, "GetHealthReporters(): Failed to run devlink")
+		return nil, err
+	}
+
+	// The reporters of the ports are listed under pci/<address>/<port index> on the newer kernels
+	parsed := struct {
+		Health map[string][]struct {
+			Reporter string `json:"reporter"`
+			State    string `json:"state"`
+			Error    int    `json:"error"`
+			Recover  int    `json:"recover"`
+		} `json:"health"`
+	}{}
+	err = json.Unmarshal(output, &parsed)
+	if err != nil {
+		log.Log.Error(err, "GetHealthReporters(): Failed to parse devlink output", "output", string(output))
+		return nil, err
+	}
+
+	reporters := []types.HealthReporter{}
+	for name, entries := range parsed.Health {
+		if name != devlinkName && !strings.HasPrefix(name, devlinkName+"/") {
+			continue
+		}
+		for _, entry := range entries {
+			reporters = append(reporters, types.HealthReporter{
+				Name:         entry.Reporter,
+				State:        entry.State,
+				ErrorCount:   entry.Error,
+				RecoverCount: entry.Recover,
+			})
+		}
+	}
+	slices.SortStableFunc(reporters, func(a, b types.HealthReporter) int { return strings.Compare(a.Name, b.Name) })
+
+	return reporters, nil
+}
+
+// GetRingSizes returns the current and the maximum ring buffer sizes of a network interface
+func (h *hostUtils) GetRingSizes(interfaceName string) (types.RingSizes, error) {
+	cmd := h.execInterface.Command("ethtool", "-g", interfaceName)
+	output, err := cmd.Output()
+	if err != nil {
+		log.Log.Error(err, "GetRingSizes(): Failed to run ethtool")
+		return types.RingSizes{}, err
+	}
+
+	// Output has the "Pre-set maximums:" and "Current hardware settings:" sections with "RX: <size>" and "TX: <size>" lines
+	sizes := types.RingSizes{}
+	maximums := false
+	for _, line := range strings.Split(string(output), "\n") {
+		name, value, found := strings.Cut(strings.TrimSpace(line), ":")
+		if !found {
+			continue
+		}
+
+		switch name {
+		case "Pre-set maximums":
+			maximums = true
+			continue
+		case "Current hardware settings":
+			maximums = false
+			continue
+		case "RX", "TX":
+		default:
+			continue
+		}
+
+		size, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			continue
+		}
+		switch {
+		case name == "RX" && maximums:
+			sizes.RxMax = size
+		case name == "TX" && maximums:
+			sizes.TxMax = size
+		case name == "RX":
+			sizes.Rx = size
+		default:
+			sizes.Tx = size
+		}
+	}
+
+	if sizes.Rx == 0 && sizes.Tx == 0 {
+		err = fmt.Errorf("ring sizes of interface %s not found in ethtool output", interfaceName)
+		log.Log.Error(err, "GetRingSizes(): Failed to parse ethtool output")
+		return types.RingSizes{}, err
+	}
+	return sizes, nil
+}
+
+// SetRingSizes sets the RX and TX ring buffer sizes of a network interface
+func (h *hostUtils) SetRingSizes(interfaceName string, rx int, tx int) error {
+	log.Log.Info("HostUtils.SetRingSizes()", "interfaceName", interfaceName, "rx", rx, "tx", tx)
+
+	cmd := h.execInterface.Command("ethtool", "-G", interfaceName, "rx", strconv.Itoa(rx), "tx", strconv.Itoa(tx))
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		err = fmt.Errorf("failed to run ethtool: %w: %s", err, output)
+		log.Log.Error(err, "SetRingSizes(): Failed to run ethtool")
+		return err
+	}
+	return nil
+}
+
+// GetCoalescing returns the interrupt coalescing settings of a network interface
+func (h *hostUtils) GetCoalescing(interfaceName string) (types.Coalescing, error) {
+	cmd := h.execInterface.Command("ethtool", "-c", interfaceName)
+	output, err := cmd.Output()
+	if err != nil {
+		log.Log.Error(err, "GetCoalescing(): Failed to run ethtool")
+		return types.Coalescing{}, err
+	}
+
+	// Output has the "Adaptive RX: on  TX: off" line and "<parameter>: <value>" lines, unsupported parameters are reported as n/a
+	coalescing := types.Coalescing{}
+	found := false
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimSpace(line)
+		if adaptive, isAdaptive := strings.CutPrefix(line, "Adaptive "); isAdaptive {
+			fields := strings.Fields(adaptive)
+			for i := 0; i+1 < len(fields); i += 2 {
+				switch fields[i] {
+				case "RX:":
+					coalescing.AdaptiveRx = fields[i+1] == "on"
+				case "TX:":
+					coalescing.AdaptiveTx = fields[i+1] == "on"
+				}
+			}
+			found = true
+			continue
+		}
+
+		name, value, isParam := strings.Cut(line, ":")
+		if !isParam {
+			continue
+		}
+		number, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			continue
+		}
+		switch name {
+		case "rx-usecs":
+			coalescing.RxUsecs = number
+		case "rx-frames":
+			coalescing.RxFrames = number
+		case "tx-usecs":
+			coalescing.TxUsecs = number
+		case "tx-frames":
+			coalescing.TxFrames = number
+		default:
+			continue
+		}
+		found = true
+	}
+
+	if !found {
+		err = fmt.Errorf("coalescing settings of interface %s not found in ethtool output", interfaceName)
+		log.Log.Error(err, "GetCoalescing(): Failed to parse ethtool output")
+		return types.Coalescing{}, err
+	}
+	return coalescing, nil
+}
+
+// SetCoalescing sets the interrupt coalescing settings of a network interface
+func (h *hostUtils) SetCoalescing(interfaceName string, coalescing types.Coalescing) error {
+	log.Log.Info("HostUtils.SetCoalescing()", "interfaceName", interfaceName, "coalescing", coalescing)
+
+	onOff := func(enabled bool) string {
+		if enabled {
+			return "on"
+		}
+		return "off"
+	}
+	cmd := h.execInterface.Command("ethtool", "-C", interfaceName,
+		"adaptive-rx", onOff(coalescing.AdaptiveRx), "adaptive-tx", onOff(coalescing.AdaptiveTx),
+		"rx-usecs", strconv.Itoa(coalescing.RxUsecs), "rx-frames", strconv.Itoa(coalescing.RxFrames),
+		"tx-usecs", strconv.Itoa(coalescing.TxUsecs), "tx-frames", strconv.Itoa(coalescing.TxFrames))
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		err = fmt.Errorf("failed to run ethtool: %w: %s", err, output)
+		log.Log.Error(err, "SetCoalescing(): Failed to run ethtool")
+		return err
+	}
+	return nil
+}
+
+// GetChannels returns the current and the maximum combined channel count of a network interface
+func (h *hostUtils) GetChannels(interfaceName string) (types.Channels, error) {
+	cmd := h.execInterface.Command("ethtool", "-l", interfaceName)
+	output, err := cmd.Output()
+	if err != nil {
+		log.Log.Error(err, "GetChannels(): Failed to run ethtool")
+		return types.Channels{}, err
+	}
+
+	// Output has the "Pre-set maximums:" and "Current hardware settings:" sections with "Combined: <count>" lines
+	channels := types.Channels{}
+	maximums := false
+	for _, line := range strings.Split(string(output), "\n") {
+		name, value, found := strings.Cut(strings.TrimSpace(line), ":")
+		if !found {
+			continue
+		}
+
+		switch name {
+		case "Pre-set maximums":
+			maximums = true
+			continue
+		case "Current hardware settings":
+			maximums = false
+			continue
+		case "Combined":
+		default:
+			continue
+		}
+
+		count, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			continue
+		}
+		if maximums {
+			channels.CombinedMax = count
+		} else {
+			channels.Combined = count
+		}
+	}
+
+	if channels.Combined == 0 {
+		err = fmt.Errorf("combined channels of interface %s not found in ethtool output", interfaceName)
+		log.Log.Error(err, "GetChannels(): Failed to parse ethtool output")
+		return types.Channels{}, err
+	}
+	return channels, nil
+}
+
+// SetCombinedChannels sets the combined channel count of a network interface
+func (h *hostUtils) SetCombinedChannels(interfaceName string, combined int) error {
+	log.Log.Info("HostUtils.SetCombinedChannels()", "interfaceName", interfaceName, "combined", combined)
+
+	cmd := h.execInterface.Command("ethtool", "-L", interfaceName, "combined", strconv.Itoa(combined))
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		err = fmt.Errorf("failed to run ethtool: %w: %s", err, output)
+		log.Log.Error(err, "SetCombinedChannels(): Failed to run ethtool")
+		return err
+	}
+	return nil
+}
+
+// GetNumaNodeCPUCount returns the number of CPUs of the NUMA node local to the PCI device, 0 if the device has no NUMA affinity
+// local_cpulist lists all CPUs of the host for the devices without the NUMA affinity, so the NUMA node is checked first
+func (h *hostUtils) GetNumaNodeCPUCount(pciAddr string) (int, error) {
+	// Platforms without NUMA report -1 or don't have the attribute
+	numaNode, err := os.ReadFile(filepath.Join(pciDevicesPath, pciAddr, "numa_node"))
+	if err != nil && !os.IsNotExist(err) {
+		log.Log.Error(err, "GetNumaNodeCPUCount(): failed to read NUMA node", "device", pciAddr)
+		return 0, err
+	}
+	if err != nil || strings.TrimSpace(string(numaNode)) == "-1" {
+		return 0, nil
+	}
+
+	value, err := os.ReadFile(filepath.Join(pciDevicesPath, pciAddr, "local_cpulist"))
+	if err != nil {
+		log.Log.Error(err, "GetNumaNodeCPUCount(): failed to read local CPU list", "device", pciAddr)
+		return 0, err
+	}
+
+	// CPU list has the "0-15,32-47" format
+	count := 0
+	for _, cpuRange := range strings.Split(strings.TrimSpace(string(value)), ",") {
+		if cpuRange == "" {
+			continue
+		}
+		first, last, isRange := strings.Cut(cpuRange, "-")
+		if !isRange {
+			last = first
+		}
+		start, err := strconv.Atoi(first)
+		if err != nil {
+			return 0, fmt.Errorf("invalid local CPU list %q of device %s: %w", value, pciAddr, err)
+		}
+		end, err := strconv.Atoi(last)
+		if err != nil || end < start {
+			return 0, fmt.Errorf("invalid local CPU list %q of device %s", value, pciAddr)
+		}
+		count += end - start + 1
+	}
+	return count, nil
+}
+
+// sysctlPath returns the procfs path of the sysctl in the dotted notation,
+// slashes in the name stand for the dots in a component, e.g. of a VLAN interface name, as in sysctl(8)
+func sysctlPath(name string) string {
+	path := strings.Map(func(r rune) rune {
+		switch r {
+		case '.':
+			return '/'
+		case '/':
+			return '.'
+		}
+		return r
+	}, name)
+	return filepath.Join(procSysPath, path)
+}
+
+// GetSysctl returns the value of the sysctl in the dotted notation, e.g. net.ipv4.tcp_ecn
+// whitespace-separated values are normalized to single spaces
+func (h *hostUtils) GetSysctl(name string) (string, error) {
+	value, err := os.ReadFile(sysctlPath(name))
+	if err != nil {
+		log.Log.Error(err, "GetSysctl(): failed to read sysctl", "name", name)
+		return "", err
+	}
+	return strings.Join(strings.Fields(string(value)), " "), nil
+}
+
+// SetSysctl sets the value of the sysctl in the dotted notation
+func (h *hostUtils) SetSysctl(name string, value string) error {
+	log.Log.Info("HostUtils.SetSysctl()", "name", name, "value", value)
+	err := checkCapabilities(sysctlWriteOperation)
+	if err != nil {
+		log.Log.Error(err, "SetSysctl(): can't change the settings of the host")
+		return err
+	}
+
+	err = os.WriteFile(sysctlPath(name), []byte(value), 0644)
+	if err != nil {
+		log.Log.Error(err, "SetSysctl(): failed to write sysctl", "name", name)
+		return err
+	}
+	return nil
+}
+
+// qosCounterRegex matches the ethtool counters affected by the QoS settings that are read from the physical port counters
+// of the NIC, the same counters that 'mstlink --pc' clears: the per-priority traffic and pause counters and the _phy
+// counters, e.g. rx_discards_phy or tx_pause_ctrl_phy. Software and buffer counters, e.g. rx_prio3_buf_discard, are not matched
+var qosCounterRegex = regexp.MustCompile(`_phy$|(^|_)prio\d+_(bytes|packets|pause|pause_duration|pause_transition)$`)
+
+// GetPortCounters returns the physical port and priority counters of a network interface relevant for QoS, keyed by the ethtool counter name
+func (h *hostUtils) GetPortCounters(interfaceName string) (map[string]uint64, error) {
+	log.Log.Info("HostUtils.GetPortCounters()", "interfaceName", interfaceName)
+
+	cmd := h.execInterface.Command("ethtool", "-S", interfaceName)
+	output, err := cmd.Output()
+	if err != nil {
+		log.Log.Error(err, "GetPortCounters(): Failed to run ethtool")
+		return nil, err
+	}
+
+	counters := map[string]uint64{}
+	for _, line := range strings.Split(string(output), "\n") {
+		name, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		name = strings.TrimSpace(name)
+		if !qosCounterRegex.MatchString(name) {
+			continue
+		}
+		counter, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			continue
+		}
+		counters[name] = counter
+	}
+
+	return counters, nil
+}
+
+// ResetPortCounters clears the physical port counters of the PCI device
+func (h *hostUtils) ResetPortCounters(pciAddr string) error {
+	log.Log.Info("HostUtils.ResetPortCounters()", "pciAddr", pciAddr)
+
+	cmd := h.execInterface.Command("mstlink", "-d", pciAddr, "--pc")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		err = fmt.Errorf("failed to run mstlink: %w: %s", err, output)
+		log.Log.Error(err, "ResetPortCounters(): Failed to run mstlink")
+		return err
+	}
+	return nil
+}
+
+// devlinkResourceJSON is a single resource in the "devlink resource show -j" output
+type devlinkResourceJSON struct {
+	Name      string                `json:"name"`
+	Size      uint64                `json:"size"`
+	SizeNew   *uint64               `json:"size_new"`
+	SizeMin   uint64                `json:"size_min"`
+	SizeMax   uint64                `json:"size_max"`
+	SizeGran  uint64                `json:"size_gran"`
+	Unit      string                `json:"unit"`
+	Resources []devlinkResourceJSON `json:"resources"`
+}
+
+// flattenDevlinkResources converts the nested devlink resources tree into a map keyed by the resource path
+func flattenDevlinkResources(parentPath string, resources []devlinkResourceJSON, result map[string]types.DevlinkResource) {
+	for _, resource := range resources {
+		path := parentPath + "/" + resource.Name
+		result[path] = types.DevlinkResource{
+			Size:            resource.Size,
+			SizeNew:         resource.SizeNew,
+			SizeMin:         resource.SizeMin,
+			SizeMax:         resource.SizeMax,
+			SizeGranularity: resource.SizeGran,
+			Unit:            resource.Unit,
+		}
+		flattenDevlinkResources(path, resource.Resources, result)
+	}
+}
+
+// GetDevlinkResources returns devlink resources of the PCI device, keyed by the resource path, e.g. /kvd/linear
+func (h *hostUtils) GetDevlinkResources(pciAddr string) (map[string]types.DevlinkResource, error) {
+	log.Log.Info("HostUtils.GetDevlinkResources()", "pciAddr", pciAddr)
+
+	devlinkName := "pci/" + pciAddr
+	cmd := h.execInterface.Command("devlink", "resource", "show", devlinkName, "-j")
+	output, err := cmd.Output()
+	if err != nil {
+		log.Log.Error(err, "GetDevlinkResources(): Failed to run devlink")
+		return nil, err
+	}
+
+	parsed := struct {
+		Resources map[string][]devlinkResourceJSON `json:"resources"`
+	}{}
+	err = json.Unmarshal(output, &parsed)
+	if err != nil {
+		log.Log.Error(err, "GetDevlinkResources(): Failed to parse devlink output", "output", string(output))
+		return nil, err
+	}
+
+	resources := map[string]types.DevlinkResource{}
+	flattenDevlinkResources("", parsed.Resources[devlinkName], resources)
+
+	return resources, nil
+}
+
+// SetDevlinkResourceSize sets the size of the devlink resource, the new size takes effect after devlink reload
+func (h *hostUtils) SetDevlinkResourceSize(pciAddr string, path string, size uint64) error {
+	log.Log.Info("HostUtils.SetDevlinkResourceSize()", "pciAddr", pciAddr, "path", path, "size", size)
+
+	cmd := h.execInterface.Command("devlink", "resource", "set", "pci/"+pciAddr, "path", path, "size", strconv.FormatUint(size, 10))
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		err = fmt.Errorf("failed to run devlink: %w: %s", err, output)
+		log.Log.Error(err, "SetDevlinkResourceSize(): Failed to run devlink")
+		return err
+	}
+	return nil
+}
+
+// ReloadDevlinkDevice performs devlink reload of the PCI device
+func (h *hostUtils) ReloadDevlinkDevice(pciAddr string) error {
+	log.Log.Info("HostUtils.ReloadDevlinkDevice()", "pciAddr", pciAddr)
+
+	cmd := h.execInterface.Command("devlink", "dev", "reload", "pci/"+pciAddr)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		err = fmt.Errorf("failed to run devlink: %w: %s", err, output)
+		log.Log.Error(err, "ReloadDevlinkDevice(): Failed to run devlink")
+		return err
+	}
+	return nil
+}
+
+// eswitchNotPermitted is reported by devlink if the PF doesn't have the eswitch manager capability
+const eswitchNotPermitted = "Operation not permitted"
+
+// IsEswitchManager returns false if the PF is not the eswitch manager of a multi-host NIC,
+// in this case the nv config of the NIC is owned by another host
+// EPERM doesn't tell a PF of another host's eswitch from a missing capability of the config daemon,
+// it's returned as types.EswitchAccessDeniedError instead of skipping the device
+func (h *hostUtils) IsEswitchManager(pciAddr string) (bool, error) {
+	log.Log.Info("HostUtils.IsEswitchManager()", "pciAddr", pciAddr)
+
+	cmd := h.execInterface.Command("devlink", "dev", "eswitch", "show", "pci/"+pciAddr)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if strings.Contains(string(output), eswitchNotPermitted) {
+			err = types.EswitchAccessDeniedError(fmt.Sprintf(
+				"devlink isn't permitted to query the eswitch of %s, the PF may not be the eswitch manager of a multi-host NIC "+
+					"or the config daemon may lack the CAP_NET_ADMIN capability: %s", pciAddr, strings.TrimSpace(string(output))))
+			log.Log.Error(err, "IsEswitchManager(): eswitch query not permitted")
+			return false, err
+		}
+		err = fmt.Errorf("failed to run devlink: %w: %s", err, output)
+		log.Log.Error(err, "IsEswitchManager(): Failed to run devlink")
+		return false, err
+	}
+	return true, nil
+}
+
+// eswitchModeRegex matches the eswitch mode in the devlink output, e.g. "pci/0000:3b:00.0: mode switchdev inline-mode none"
+var eswitchModeRegex = regexp.MustCompile(`(?:^|\s)mode (\S+)`)
+
+// GetEswitchMode returns the eswitch mode of the PF, e.g. legacy or switchdev
+// returns empty string if the PF is not the eswitch manager
+func (h *hostUtils) GetEswitchMode(pciAddr string) (string, error) {
+	log.Log.Info("HostUtils.GetEswitchMode()", "pciAddr", pciAddr)
+
+	cmd := h.execInterface.Command("devlink", "dev", "eswitch", "show", "pci/"+pciAddr)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if strings.Contains(string(output), eswitchNotPermitted) {
+			log.Log.V(2).Info("PF is not the eswitch manager", "pciAddr", pciAddr)
+			return "", nil
+		}
+		err = fmt.Errorf("failed to run devlink: %w: %s", err, output)
+		log.Log.Error(err, "GetEswitchMode(): Failed to run devlink")
+		return "", err
+	}
+
+	match := eswitchModeRegex.FindStringSubmatch(string(output))
+	if match == nil {
+		err = fmt.Errorf("failed to parse eswitch mode of device %s: %s", pciAddr, output)
+		log.Log.Error(err, "GetEswitchMode(): Failed to parse devlink output")
+		return "", err
+	}
+	return match[1], nil
+}
+
+// SetEswitchMode sets the eswitch mode of the PF, legacy or switchdev
+// in the switchdev mode, the driver creates the representors of the PF's VFs asynchronously
+func (h *hostUtils) SetEswitchMode(pciAddr string, mode string) error {
+	log.Log.Info("HostUtils.SetEswitchMode()", "pciAddr", pciAddr, "mode", mode)
+
+	cmd := h.execInterface.Command("devlink", "dev", "eswitch", "set", "pci/"+pciAddr, "mode", mode)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		err = fmt.Errorf("failed to run devlink: %w: %s", err, output)
+		log.Log.Error(err, "SetEswitchMode(): Failed to run devlink")
+		return err
+	}
+	return nil
+}
+
+// devlinkParamJSON is a devlink parameter in the devlink json output
+type devlinkParamJSON struct {
+	Name   string `json:"name"`
+	Values []struct {
+		Cmode string          `json:"cmode"`
+		Value json.RawMessage `json:"value"`
+	} `json:"values"`
+}
+
+// GetDevlinkParam returns the runtime value of the devlink parameter of the PF, e.g. flow_steering_mode
+func (h *hostUtils) GetDevlinkParam(pciAddr string, name string) (string, error) {
+	log.Log.Info("HostUtils.GetDevlinkParam()", "pciAddr", pciAddr, "name", name)
+
+	devlinkName := "pci/" + pciAddr
+	cmd := h.execInterface.Command("devlink", "dev", "param", "show", devlinkName, "name", name, "-j")
+	// Warnings printed to stderr would break the parsing of the JSON output
+	output, err := cmd.Output()
+	if err != nil {
+		err = fmt.Errorf("failed to run devlink: %w: %s", err, bytes.TrimSpace(exitErrorOutput(err)))
+		log.Log.Error(err, "GetDevlinkParam(): Failed to run devlink")
+		return "", err
+	}
+
+	parsed := struct {
+		Param map[string][]devlinkParamJSON `json:"param"`
+	}{}
+	err = json.Unmarshal(output, &parsed)
+	if err != nil {
+		log.Log.Error(err, "GetDevlinkParam(): Failed to parse devlink output", "output", string(output))
+		return "", err
+	}
+
+	for _, param := range parsed.Param[devlinkName] {
+		if param.Name != name {
+			continue
+		}
+		for _, value := range param.Values {
+			if value.Cmode != "runtime" {
+				continue
+			}
+			// String values are quoted, numeric and boolean values are returned as is
+			var str string
+			if json.Unmarshal(value.Value, &str) == nil {
+				return str, nil
+			}
+			return string(value.Value), nil
+		}
+	}
+
+	err = fmt.Errorf("runtime value of devlink param %s of device %s not found", name, pciAddr)
+	log.Log.Error(err, "GetDevlinkParam(): Failed to parse devlink output", "output", string(output))
+	return "", err
+}
+
+// SetDevlinkParam sets the runtime value of the devlink parameter of the PF
+func (h *hostUtils) SetDevlinkParam(pciAddr string, name string, value string) error {
+	log.Log.Info("HostUtils.SetDevlinkParam()", "pciAddr", pciAddr, "name", name, "value", value)
+
+	cmd := h.execInterface.Command("devlink", "dev", "param", "set", "pci/"+pciAddr, "name", name, "value", value, "cmode", "runtime")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		err = fmt.Errorf("failed to run devlink: %w: %s", err, output)
+		log.Log.Error(err, "SetDevlinkParam(): Failed to run devlink")
+		return err
+	}
+	return nil
+}
+
+// vfRepresentorPortNameRegex matches the phys_port_name of the VF representors, e.g. pf0vf3 or c1pf0vf3 on multi-host NICs
+var vfRepresentorPortNameRegex = regexp.MustCompile(`^(c\d+)?pf\d+vf\d+$`)
+
+// GetVfRepresentors returns the network interfaces of the VF representors of the PF in switchdev mode
+// representors are the network interfaces of the PF whose phys_port_name refers to a VF
+func (h *hostUtils) GetVfRepresentors(pciAddr string) ([]string, error) {
+	names, err := getNetNames(pciAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	representors := []string{}
+	for _, name := range names {
+		portName, err := os.ReadFile(filepath.Join(pciDevicesPath, pciAddr, "net", name, "phys_port_name"))
+		if err != nil {
+			// phys_port_name is not readable in the legacy eswitch mode
+			continue
+		}
+		if vfRepresentorPortNameRegex.MatchString(strings.TrimSpace(string(portName))) {
+			representors = append(representors, name)
+		}
+	}
+	return representors, nil
+}
+
+// vfMsixCountRegex matches the MSI-X capability in the lspci output, e.g. MSI-X: Enable+ Count=12 Masked-
+var vfMsixCountRegex = regexp.MustCompile(`MSI-X: Enable[+-] Count=(\d+)`)
+
+// GetVfPciAddresses returns the PCI addresses of the PF's VFs, ordered by the VF index
+// VFs are the virtfn<index> links of the PF in sysfs
+func (h *hostUtils) GetVfPciAddresses(pciAddr string) ([]string, error) {
+	links, err := filepath.Glob(filepath.Join(pciDevicesPath, pciAddr, "virtfn*"))
+	if err != nil {
+		return nil, err
+	}
+
+	vfs := make([]string, len(links))
+	for _, link := range links {
+		index, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(link), "virtfn"))
+		if err != nil || index >= len(links) {
+			return nil, fmt.Errorf("unexpected VF link %s of device %s", link, pciAddr)
+		}
+		target, err := os.Readlink(link)
+		if err != nil {
+			return nil, err
+		}
+		vfs[index] = filepath.Base(target)
+	}
+	return vfs, nil
+}
+
+// GetPCIDriver returns the name of the driver bound to the PCI device, empty if no driver is bound
+// the driver is the target of the device's driver link in sysfs, e.g. mlx5_core or vfio-pci
+func (h *hostUtils) GetPCIDriver(pciAddr string) (string, error) {
+	devicePath := filepath.Join(pciDevicesPath, pciAddr)
+	if _, err := os.Stat(devicePath); err != nil {
+		return "", err
+	}
+
+	driver, err := os.Readlink(filepath.Join(devicePath, "driver"))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return filepath.Base(driver), nil
+}
+
+// GetVfMsixCount returns the number of MSI-X vectors of the VF, as reported by its MSI-X capability
+func (h *hostUtils) GetVfMsixCount(vfPciAddr string) (int, error) {
+	log.Log.Info("HostUtils.GetVfMsixCount()", "vfPciAddr", vfPciAddr)
+	cmd := h.execInterface.Command("lspci", "-vv", "-s", vfPciAddr)
+	output, err := cmd.Output()
+	if err != nil && len(output) == 0 {
+		log.Log.Error(err, "GetVfMsixCount(): Failed to run lspci")
+		return -1, err
+	}
+	if strings.Contains(string(output), lspciAccessDenied) {
+		err = fmt.Errorf("lspci can't read PCI capabilities of device %s, CAP_SYS_ADMIN is required", vfPciAddr)
+		log.Log.Error(err, "GetVfMsixCount(): Failed to run lspci")
+		return -1, err
+	}
+
+	match := vfMsixCountRegex.FindStringSubmatch(string(output))
+	if len(match) != 2 {
+		return -1, fmt.Errorf("MSI-X capability not found for device %s", vfPciAddr)
+	}
+	return strconv.Atoi(match[1])
+}
+
+// GetVfTotalMsix returns the number of MSI-X vectors of the PF's pool that can be assigned to its VFs
+// returns error if the PF doesn't support dynamic VF MSI-X assignment
+func (h *hostUtils) GetVfTotalMsix(pciAddr string) (int, error) {
+	value, err := os.ReadFile(filepath.Join(pciDevicesPath, pciAddr, "sriov_vf_total_msix"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, fmt.Errorf("device %s doesn't support dynamic VF MSI-X assignment", pciAddr)
+		}
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(value)))
+}
+
+// SetVfMsixCount assigns the number of MSI-X vectors to the VF via sysfs
+// the kernel only allows the change while no driver is bound to the VF, so the VF driver is unbound and bound back
+func (h *hostUtils) SetVfMsixCount(vfPciAddr string, count int) error {
+	log.Log.Info("HostUtils.SetVfMsixCount()", "vfPciAddr", vfPciAddr, "count", count)
+	err := checkCapabilities(sysfsWriteOperation)
+	if err != nil {
+		log.Log.Error(err, "SetVfMsixCount(): can't change the settings of the host")
+		return err
+	}
+
+	devicePath := filepath.Join(pciDevicesPath, vfPciAddr)
+	driverPath, err := filepath.EvalSymlinks(filepath.Join(devicePath, "driver"))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if driverPath != "" {
+		err = os.WriteFile(filepath.Join(driverPath, "unbind"), []byte(vfPciAddr), 0200)
+		if err != nil {
+			log.Log.Error(err, "SetVfMsixCount(): failed to unbind VF driver", "vfPciAddr", vfPciAddr, "driver", filepath.Base(driverPath))
+			return err
+		}
+	}
+
+	err = os.WriteFile(filepath.Join(devicePath, "sriov_vf_msix_count"), []byte(strconv.Itoa(count)), 0200)
+	if err != nil {
+		log.Log.Error(err, "SetVfMsixCount(): failed to set VF MSI-X count", "vfPciAddr", vfPciAddr)
+	}
+
+	// The driver is bound back even if the count wasn't changed, so that the VF stays usable
+	if driverPath != "" {
+		bindErr := os.WriteFile(filepath.Join(driverPath, "bind"), []byte(vfPciAddr), 0200)
+		if bindErr != nil {
+			log.Log.Error(bindErr, "SetVfMsixCount(): failed to bind VF driver", "vfPciAddr", vfPciAddr, "driver", filepath.Base(driverPath))
+			if err == nil {
+				err = bindErr
+			}
+		}
+	}
+
+	return err
+}
+
+func (h *hostUtils) ScheduleReboot() error {
+	log.Log.Info("HostUtils.ScheduleReboot()")
+	err := checkCapabilities(rebootOperation)
+	if err != nil {
+		log.Log.Error(err, "ScheduleReboot(): Can't reboot the host")
+		return err
+	}
+
+	root, err := os.Open("/")
+	if err != nil {
+		log.Log.Error(err, "ScheduleReboot(): Failed to os.Open")
+		return err
+	}
+
+	if err := syscall.Chroot(consts.HostPath); err != nil {
+		err := root.Close()
+		if err != nil {
+			log.Log.Error(err, "ScheduleReboot(): Failed to syscall.Chroot")
+			return err
+		}
+		return err
+	}
+
+	defer func() {
+		if err := root.Close(); err != nil {
+			log.Log.Error(err, "ScheduleReboot(): Failed to os.Close")
+			return
+		}
+		if err := root.Chdir(); err != nil {
+			log.Log.Error(err, "ScheduleReboot(): Failed to os.Chdir")
+			return
+		}
+		if err = syscall.Chroot("."); err != nil {
+			log.Log.Error(err, "ScheduleReboot(): Failed to syscall.Chroot")
+		}
+	}()
+
+	cmd := h.execInterface.Command("shutdown", "-r", "now")
+	_, err = cmd.Output()
+	if err != nil {
+		log.Log.Error(err, "ScheduleReboot(): Failed to run shutdown -r now")
+		return err
+	}
+	return nil
+}
+
+// GetOfedVersion retrieves installed OFED version
+func (h *hostUtils) GetOfedVersion() string {
+	log.Log.Info("HostUtils.GetOfedVersion()")
+	versionBytes, err := os.ReadFile(filepath.Join(consts.HostPath, consts.Mlx5ModuleVersionPath))
+	if err != nil {
+		log.Log.Error(err, "GetOfedVersion(): failed to read mlx5_core version file, OFED isn't installed")
+		return ""
+	}
+	version := strings.TrimSuffix(string(versionBytes), "\n")
+	log.Log.Info("HostUtils.GetOfedVersion(): OFED version", "version", version)
+	return version
+}
+
+// GetHostUptimeSeconds returns the host uptime in seconds
+func (h *hostUtils) GetHostUptimeSeconds() (time.Duration, error) {
+	log.Log.V(2).Info("HostUtils.GetHostUptimeSeconds()")
+	output, err := os.ReadFile("/proc/uptime")
+	if err != nil {
+		log.Log.Error(err, "HostUtils.GetHostUptimeSeconds(): failed to read the system's uptime")
+		return 0, err
 	}
 	uptimeStr := strings.Split(string(output), " ")[0]
 	uptimeSeconds, _ := strconv.ParseFloat(uptimeStr, 64)
@@ -647,6 +2477,101 @@ func (h *hostUtils) GetHostUptimeSeconds() (time.Duration, error) {
 	return time.Duration(uptimeSeconds) * time.Second, nil
 }
 
+// GetHostBootID returns the random ID of the current boot of the host, it changes with every reboot
+func (h *hostUtils) GetHostBootID() (string, error) {
+	log.Log.V(2).Info("HostUtils.GetHostBootID()")
+	output, err := os.ReadFile("/proc/sys/kernel/random/boot_id")
+	if err != nil {
+		log.Log.Error(err, "HostUtils.GetHostBootID(): failed to read the boot ID")
+		return "", err
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// knownHypervisorVendors are the DMI system vendors of the VMs, used on the architectures without the hypervisor CPU flag
+var knownHypervisorVendors = []string{"QEMU", "KVM", "VMware, Inc.", "Xen", "innotek GmbH", "Parallels Software International Inc."}
+
+// unknownHypervisor is reported for the VMs that don't report their DMI system vendor
+const unknownHypervisor = "unknown"
+
+// GetHypervisor returns the DMI system vendor of the VM the host runs in, e.g. QEMU, empty string on bare metal
+// the VM is detected by the hypervisor CPU flag or by the known system vendors of the hypervisors
+func (h *hostUtils) GetHypervisor() (string, error) {
+	log.Log.V(2).Info("HostUtils.GetHypervisor()")
+	cpuInfo, err := os.ReadFile(cpuInfoPath)
+	if err != nil {
+		log.Log.Error(err, "HostUtils.GetHypervisor(): failed to read the CPU info")
+		return "", err
+	}
+
+	// DMI is not available on all platforms, the CPU flag is enough to detect the VM
+	vendor := ""
+	data, err := os.ReadFile(dmiSysVendorPath)
+	if err == nil {
+		vendor = strings.TrimSpace(string(data))
+	}
+
+	if !cpuInfoHasHypervisorFlag(string(cpuInfo)) && !slices.Contains(knownHypervisorVendors, vendor) {
+		return "", nil
+	}
+	if vendor == "" {
+		return unknownHypervisor, nil
+	}
+	return vendor, nil
+}
+
+// cpuInfoHasHypervisorFlag returns true if the CPU flags in /proc/cpuinfo contain the hypervisor flag set by the VMs on x86
+func cpuInfoHasHypervisorFlag(cpuInfo string) bool {
+	for _, line := range strings.Split(cpuInfo, "\n") {
+		name, value, found := strings.Cut(line, ":")
+		if !found || strings.TrimSpace(name) != "flags" {
+			continue
+		}
+		if slices.Contains(strings.Fields(value), "hypervisor") {
+			return true
+		}
+	}
+	return false
+}
+
+// GetToolFailures returns the host tool runs that failed after the given time, oldest first
+// only the runs with one of the identifiers, e.g. a PCI address or a network interface, as an argument are returned
+func (h *hostUtils) GetToolFailures(since time.Time, identifiers []string) []types.ToolFailure {
+	if h.watchdog == nil {
+		return nil
+	}
+	return h.watchdog.toolFailures(since, identifiers)
+}
+
+// diagnosticsSysfsAttributes are the sysfs attributes of the PCI device collected for the diagnostics
+var diagnosticsSysfsAttributes = []string{
+	"current_link_speed", "current_link_width", "max_link_speed", "max_link_width",
+	"power_state", "enable", "numa_node", "sriov_numvfs", "sriov_totalvfs",
+}
+
+// GetPCIDeviceSysfsState returns the sysfs attributes of the PCI device relevant for the diagnostics, keyed by the attribute name
+// the bound driver is reported as the driver attribute, unreadable attributes are skipped
+func (h *hostUtils) GetPCIDeviceSysfsState(pciAddr string) map[string]string {
+	devicePath := filepath.Join(pciDevicesPath, pciAddr)
+
+	state := map[string]string{}
+	for _, attribute := range diagnosticsSysfsAttributes {
+		value, err := os.ReadFile(filepath.Join(devicePath, attribute))
+		if err != nil {
+			continue
+		}
+		state[attribute] = strings.TrimSpace(string(value))
+	}
+	driver, err := os.Readlink(filepath.Join(devicePath, "driver"))
+	if err == nil {
+		state["driver"] = filepath.Base(driver)
+	}
+
+	return state
+}
+
 func NewHostUtils() HostUtils {
-	return &hostUtils{execInterface: execUtils.New()}
+	watchdog := newToolWatchdog(hostToolTimeout)
+	return &hostUtils{execInterface: watchdog, watchdog: watchdog}
 }
@@ -19,12 +19,19 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"k8s.io/utils/exec"
 	execTesting "k8s.io/utils/exec/testing"
+	"k8s.io/utils/ptr"
+
+	"github.com/Mellanox/nic-configuration-operator/pkg/types"
 )
 
 const pciAddress = "0000:03:00.0"
@@ -106,20 +113,13 @@ var _ = Describe("HostUtils", func() {
 		})
 	})
 	//nolint:dupl
-	Describe("GetFirmwareVersionAndPSID", func() {
-		It("should return lowercased firmware version and psid", func() {
-			fwVersion := "VeRsIoN"
-			PSID := "PSID"
-
+	Describe("QueryFirmware", func() {
+		runWithOutput := func(output string) (*types.FirmwareQuery, error) {
 			fakeExec := &execTesting.FakeExec{}
 
 			fakeCmd := &execTesting.FakeCmd{}
 			fakeCmd.OutputScript = append(fakeCmd.OutputScript, func() ([]byte, []byte, error) {
-				return []byte("irrelevant line\n" +
-						"FW Version: VeRsIoN\n" +
-						"PSID: PSID\n" +
-						"another irrelevant line"),
-					nil, nil
+				return []byte(output), nil, nil
 			})
 
 			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
@@ -131,24 +131,92 @@ var _ = Describe("HostUtils", func() {
 			h := &hostUtils{
 				execInterface: fakeExec,
 			}
+			return h.QueryFirmware(pciAddress)
+		}
 
-			part, serial, err := h.GetFirmwareVersionAndPSID(pciAddress)
+		It("should return lowercased firmware version and psid", func() {
+			query, err := runWithOutput("irrelevant line\n" +
+				"FW Version: VeRsIoN\n" +
+				"PSID: PSID\n" +
+				"another irrelevant line")
 
 			Expect(err).NotTo(HaveOccurred())
-			Expect(part).To(Equal(strings.ToLower(fwVersion)))
-			Expect(serial).To(Equal(strings.ToLower(PSID)))
+			Expect(query.Version).To(Equal(strings.ToLower("VeRsIoN")))
+			Expect(query.PSID).To(Equal(strings.ToLower("PSID")))
 		})
-		It("should return empty string for both numbers if one is empty", func() {
+		It("should fail if the firmware version or the psid is empty", func() {
+			_, err := runWithOutput("FW Version: VeRsIoN")
+			Expect(err).To(HaveOccurred())
+
+			_, err = runWithOutput("PSID: PSID")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should return security attributes and version", func() {
+			query, err := runWithOutput("FW Version:            22.31.1014\n" +
+				"PSID:                  MT_0000000359\n" +
+				"Security Attributes:   secure-fw, dev\n" +
+				"Security Ver:          3\n")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(query.Security.Attributes).To(Equal([]string{"secure-fw", "dev"}))
+			Expect(*query.Security.SecurityVersion).To(Equal(3))
+		})
+		It("should return empty attributes for firmware without security features", func() {
+			query, err := runWithOutput("FW Version:            16.35.2000\n" +
+				"PSID:                  MT_0000000359\n" +
+				"Security Attributes:   N/A\n")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(query.Security.Attributes).To(BeEmpty())
+			Expect(query.Security.SecurityVersion).To(BeNil())
+		})
+		It("should return nil security if security attributes are not reported", func() {
+			query, err := runWithOutput("FW Version:            16.35.2000\nPSID:   MT_0000000359\n")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(query.Security).To(BeNil())
+		})
+		It("should return the part number and the base GUID", func() {
+			query, err := runWithOutput("FW Version:            28.39.1002\n" +
+				"Base GUID:             B8CEF603000A1B2C        16\n" +
+				"Base MAC:              b8cef60a1b2c            16\n" +
+				"Part Number:           MCX713106AC-VEA_Ax\n" +
+				"PSID:                  MT_0000000838\n")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(query.PartNumber).To(Equal("mcx713106ac-vea_ax"))
+			Expect(query.BaseGUID).To(Equal("b8cef603000a1b2c"))
+		})
+		It("should return an empty base GUID and part number if they are not reported", func() {
+			query, err := runWithOutput("FW Version:            28.39.1002\n" +
+				"PSID:                  MT_0000000838\n" +
+				"Base GUID:             N/A\n" +
+				"Part Number:           N/A\n")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(query.BaseGUID).To(BeEmpty())
+			Expect(query.PartNumber).To(BeEmpty())
+		})
+	})
+	Describe("QueryFirmwareImage", func() {
+		It("should query the firmware image file", func() {
+			imagePath := "/cache/fw.bin"
+
 			fakeExec := &execTesting.FakeExec{}
 
 			fakeCmd := &execTesting.FakeCmd{}
 			fakeCmd.OutputScript = append(fakeCmd.OutputScript, func() ([]byte, []byte, error) {
-				return []byte("FW Version: VeRsIoN"), nil, nil
+				return []byte("Image type:            FS4\n" +
+						"FW Version:            22.41.1000\n" +
+						"PSID:                  MT_0000000359\n" +
+						"Security Attributes:   secure-fw\n" +
+						"Security Ver:          2\n"),
+					nil, nil
 			})
 
 			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
 				Expect(cmd).To(Equal("mstflint"))
-				Expect(args[1]).To(Equal(pciAddress))
+				Expect(args).To(Equal([]string{"-i", imagePath, "q"}))
 				return fakeCmd
 			})
 
@@ -156,28 +224,240 @@ var _ = Describe("HostUtils", func() {
 				execInterface: fakeExec,
 			}
 
-			part, serial, err := h.GetFirmwareVersionAndPSID(pciAddress)
+			query, err := h.QueryFirmwareImage(imagePath)
 
-			Expect(err).To(HaveOccurred())
-			Expect(part).To(Equal(""))
-			Expect(serial).To(Equal(""))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(query.Version).To(Equal("22.41.1000"))
+			Expect(query.PSID).To(Equal("mt_0000000359"))
+			Expect(query.Security.Attributes).To(Equal([]string{"secure-fw"}))
+			Expect(*query.Security.SecurityVersion).To(Equal(2))
+		})
+	})
+	Describe("BurnFirmware", func() {
+		var (
+			fakeExec *execTesting.FakeExec
+			fakeCmd  *execTesting.FakeCmd
+			h        *hostUtils
+		)
 
+		BeforeEach(func() {
+			fakeExec = &execTesting.FakeExec{}
 			fakeCmd = &execTesting.FakeCmd{}
-			fakeCmd.OutputScript = append(fakeCmd.OutputScript, func() ([]byte, []byte, error) {
-				return []byte("PSID: PSID"), nil, nil
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				Expect(cmd).To(Equal("mstflint"))
+				Expect(args).To(Equal([]string{"-d", pciAddress, "-i", "/cache/fw.bin", "-y", "burn"}))
+				return fakeCmd
+			})
+			h = &hostUtils{
+				execInterface: fakeExec,
+			}
+		})
+
+		It("should burn the image with mstflint", func() {
+			fakeCmd.RunScript = append(fakeCmd.RunScript, func() ([]byte, []byte, error) {
+				return []byte("Burning FW image ... OK"), nil, nil
+			})
+
+			Expect(h.BurnFirmware(context.Background(), pciAddress, "/cache/fw.bin")).To(Succeed())
+			Expect(fakeExec.CommandCalls).To(Equal(1))
+		})
+		It("should report the burn progress printed by mstflint", func() {
+			fakeCmd.RunScript = append(fakeCmd.RunScript, func() ([]byte, []byte, error) {
+				fakeCmd.Stdout.Write([]byte("Burning FW image without signatures - 0%\r"))
+				fakeCmd.Stdout.Write([]byte("Burning FW image without signatures - 40%\rBurning FW image without signatures - 41%\r"))
+				return []byte("Burning FW image without signatures - OK\n"), nil, nil
+			})
+
+			reported := []string{}
+			ctx := WithFirmwareProgress(context.Background(), func(phase string, percent int) {
+				reported = append(reported, fmt.Sprintf("%s %d", phase, percent))
+			})
+
+			Expect(h.BurnFirmware(ctx, pciAddress, "/cache/fw.bin")).To(Succeed())
+			Expect(reported).To(Equal([]string{"Flashing 0", "Flashing 41"}))
+		})
+		It("should return the tool output on failure", func() {
+			fakeCmd.RunScript = append(fakeCmd.RunScript, func() ([]byte, []byte, error) {
+				return []byte("-E- PSID mismatch"), nil, errors.New("exit status 1")
+			})
+
+			err := h.BurnFirmware(context.Background(), pciAddress, "/cache/fw.bin")
+			Expect(err).To(MatchError(ContainSubstring("PSID mismatch")))
+		})
+		It("should not subject the burn to the hard ceiling of the host tools", func() {
+			watchdog := newToolWatchdog(hostToolTimeout)
+			h = &hostUtils{execInterface: watchdog, watchdog: watchdog}
+
+			cmd, ok := h.burnCommand(pciAddress, "/cache/fw.bin").(*watchdogCmd)
+			Expect(ok).To(BeTrue())
+			Expect(cmd.untimed).To(BeTrue())
+			Expect(cmd.cmd.Args).To(Equal([]string{"mstflint", "-d", pciAddress, "-i", "/cache/fw.bin", "-y", "burn"}))
+			Expect(cmd.cmd.Cancel).To(BeNil())
+		})
+	})
+	Describe("SetNvConfigParameter", func() {
+		var runMstconfig = func(output string, err error) *hostUtils {
+			fakeExec := &execTesting.FakeExec{}
+			fakeCmd := &execTesting.FakeCmd{}
+			fakeCmd.CombinedOutputScript = append(fakeCmd.CombinedOutputScript, func() ([]byte, []byte, error) {
+				return []byte(output), nil, err
+			})
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				Expect(cmd).To(Equal("mstconfig"))
+				Expect(args).To(Equal([]string{"-d", pciAddress, "--yes", "set", "SRIOV_EN=1"}))
+				return fakeCmd
 			})
+			return &hostUtils{execInterface: fakeExec}
+		}
+
+		It("should set the parameter", func() {
+			h := runMstconfig("Apply new Configuration? (y/n) [n] : y", nil)
+			Expect(h.SetNvConfigParameter(pciAddress, "SRIOV_EN", "1")).To(Succeed())
+		})
+		It("should report the denied ownership if the nv config is write-protected", func() {
+			h := runMstconfig("-E- Failed to set configuration: Operation not permitted (restricted host)", errors.New("exit status 3"))
+
+			err := h.SetNvConfigParameter(pciAddress, "SRIOV_EN", "1")
+			Expect(types.IsConfigOwnershipDeniedError(err)).To(BeTrue())
+			Expect(err).To(MatchError(ContainSubstring("Operation not permitted")))
+		})
+		It("should return other errors as is", func() {
+			h := runMstconfig("-E- The Device doesn't support SRIOV_EN parameter", errors.New("exit status 3"))
+
+			err := h.SetNvConfigParameter(pciAddress, "SRIOV_EN", "1")
+			Expect(err).To(MatchError("exit status 3"))
+		})
+		It("should not report the denied ownership for the permission failures of the tool", func() {
+			for _, output := range []string{
+				"-E- Failed to open the device: Operation not permitted",
+				"-E- Failed to open /dev/mst/mt4125_pciconf0: Permission denied",
+			} {
+				h := runMstconfig(output, errors.New("exit status 1"))
+
+				err := h.SetNvConfigParameter(pciAddress, "SRIOV_EN", "1")
+				Expect(types.IsConfigOwnershipDeniedError(err)).To(BeFalse(), output)
+				Expect(err).To(MatchError("exit status 1"))
+			}
+		})
+		It("should report the denied ownership if the nv config is write protected", func() {
+			h := runMstconfig("-E- Failed to set configuration: NV config is write protected", errors.New("exit status 3"))
 
+			err := h.SetNvConfigParameter(pciAddress, "SRIOV_EN", "1")
+			Expect(types.IsConfigOwnershipDeniedError(err)).To(BeTrue())
+		})
+	})
+	Describe("GetHostPrivilegeLevel", func() {
+		var runMstprivhost = func(output string, err error) *hostUtils {
+			fakeExec := &execTesting.FakeExec{}
+			fakeCmd := &execTesting.FakeCmd{}
+			fakeCmd.OutputScript = append(fakeCmd.OutputScript, func() ([]byte, []byte, error) {
+				return []byte(output), nil, err
+			})
 			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
-				Expect(cmd).To(Equal("mstflint"))
-				Expect(args[1]).To(Equal(pciAddress))
+				Expect(cmd).To(Equal("mstprivhost"))
+				Expect(args).To(Equal([]string{"-d", pciAddress, "query"}))
 				return fakeCmd
 			})
+			return &hostUtils{execInterface: fakeExec}
+		}
 
-			part, serial, err = h.GetFirmwareVersionAndPSID(pciAddress)
+		It("should return the privilege level of the host", func() {
+			h := runMstprivhost("Current host configurations:\n-----------------------------\nlevel                         : RESTRICTED\n", nil)
 
+			level, err := h.GetHostPrivilegeLevel(pciAddress)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(level).To(Equal("RESTRICTED"))
+		})
+		It("should return an error if the level is not reported", func() {
+			h := runMstprivhost("-E- Unsupported device", nil)
+
+			_, err := h.GetHostPrivilegeLevel(pciAddress)
 			Expect(err).To(HaveOccurred())
-			Expect(part).To(Equal(""))
-			Expect(serial).To(Equal(""))
+		})
+	})
+	Describe("SimulateNvConfigParameters", func() {
+		var (
+			fakeExec *execTesting.FakeExec
+			helpCmd  *execTesting.FakeCmd
+			h        *hostUtils
+		)
+
+		BeforeEach(func() {
+			fakeExec = &execTesting.FakeExec{}
+			helpCmd = &execTesting.FakeCmd{}
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				Expect(cmd).To(Equal("mstconfig"))
+				Expect(args).To(Equal([]string{"-h"}))
+				return helpCmd
+			})
+			h = &hostUtils{
+				execInterface: fakeExec,
+			}
+		})
+
+		It("should skip the simulation if mstconfig doesn't support it", func() {
+			helpCmd.CombinedOutputScript = append(helpCmd.CombinedOutputScript, func() ([]byte, []byte, error) {
+				return []byte("    -d|--dev <DEV>    : Perform operation for a specified MST device.\n"), nil, errors.New("exit status 1")
+			})
+
+			simulated, err := h.SimulateNvConfigParameters(pciAddress, map[string]string{"SRIOV_EN": "1"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(simulated).To(BeFalse())
+
+			simulated, err = h.SimulateNvConfigParameters(pciAddress, map[string]string{"SRIOV_EN": "1"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(simulated).To(BeFalse())
+			Expect(fakeExec.CommandCalls).To(Equal(1))
+		})
+		It("should simulate setting all the parameters at once", func() {
+			helpCmd.CombinedOutputScript = append(helpCmd.CombinedOutputScript, func() ([]byte, []byte, error) {
+				return []byte("    --simulate        : Validate the set values with the firmware without writing them.\n"), nil, nil
+			})
+			simulateCmd := &execTesting.FakeCmd{}
+			simulateCmd.CombinedOutputScript = append(simulateCmd.CombinedOutputScript, func() ([]byte, []byte, error) {
+				return []byte("Apply new Configuration? (y/n) [n] : y"), nil, nil
+			})
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				Expect(cmd).To(Equal("mstconfig"))
+				Expect(args).To(Equal([]string{"-d", pciAddress, "--yes", "--simulate", "set", "NUM_OF_VFS=8", "SRIOV_EN=1"}))
+				return simulateCmd
+			})
+
+			simulated, err := h.SimulateNvConfigParameters(pciAddress, map[string]string{"SRIOV_EN": "1", "NUM_OF_VFS": "8"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(simulated).To(BeTrue())
+		})
+		It("should return IncorrectSpec error if the firmware rejects the values", func() {
+			helpCmd.CombinedOutputScript = append(helpCmd.CombinedOutputScript, func() ([]byte, []byte, error) {
+				return []byte("    --simulate\n"), nil, nil
+			})
+			simulateCmd := &execTesting.FakeCmd{}
+			simulateCmd.CombinedOutputScript = append(simulateCmd.CombinedOutputScript, func() ([]byte, []byte, error) {
+				return []byte("-E- NUM_OF_VFS exceeds the maximum supported value\n"), nil, errors.New("exit status 3")
+			})
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				return simulateCmd
+			})
+
+			simulated, err := h.SimulateNvConfigParameters(pciAddress, map[string]string{"NUM_OF_VFS": "1024"})
+			Expect(simulated).To(BeTrue())
+			Expect(types.IsIncorrectSpecError(err)).To(BeTrue())
+			Expect(err).To(MatchError(ContainSubstring("NUM_OF_VFS exceeds the maximum supported value")))
+		})
+		It("should pass tool hang errors through", func() {
+			helpCmd.CombinedOutputScript = append(helpCmd.CombinedOutputScript, func() ([]byte, []byte, error) {
+				return []byte("    --simulate\n"), nil, nil
+			})
+			simulateCmd := &execTesting.FakeCmd{}
+			simulateCmd.CombinedOutputScript = append(simulateCmd.CombinedOutputScript, func() ([]byte, []byte, error) {
+				return nil, nil, types.ToolHangError("mstconfig was killed")
+			})
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				return simulateCmd
+			})
+
+			_, err := h.SimulateNvConfigParameters(pciAddress, map[string]string{"SRIOV_EN": "1"})
+			Expect(types.IsToolHangError(err)).To(BeTrue())
 		})
 	})
 	Describe("GetPCILinkSpeed", func() {
@@ -195,6 +475,31 @@ var _ = Describe("HostUtils", func() {
 			}
 		})
 
+		It("should return an error if lspci can't read PCI capabilities", func() {
+			lspciOutput := `
+03:00.0 Ethernet controller: Mellanox Technologies MT27800 Family [ConnectX-5]
+    Subsystem: Mellanox Technologies Device 0000
+    Capabilities: <access denied>
+`
+
+			fakeCmd := &execTesting.FakeCmd{}
+			fakeCmd.OutputScript = append(fakeCmd.OutputScript,
+				func() ([]byte, []byte, error) {
+					return []byte(lspciOutput), nil, nil
+				},
+			)
+
+			fakeExec.CommandScript = []execTesting.FakeCommandAction{
+				func(cmd string, args ...string) exec.Cmd {
+					return fakeCmd
+				},
+			}
+
+			speed, err := h.GetPCILinkSpeed(pciAddr)
+			Expect(err).To(MatchError(ContainSubstring("CAP_SYS_ADMIN is required")))
+			Expect(speed).To(Equal(-1))
+		})
+
 		It("should return the correct PCI link speed when lspci output is valid", func() {
 			lspciOutput := `
 03:00.0 Ethernet controller: Mellanox Technologies MT27800 Family [ConnectX-5]
@@ -544,20 +849,25 @@ Device type:    ConnectX4
 			Expect(maxReadRequestSize).To(Equal(-1))
 		})
 	})
-	Describe("GetTrustAndPFC", func() {
-		It("should return parsed values", func() {
+	Describe("GetTcBandwidth", func() {
+		It("should return the ETS bandwidth shares of all traffic classes", func() {
 			interfaceName := "enp3s0f0np0"
-			trust := "dscp"
-			pfc := "0,1,0,1,0,1,0,0"
 
 			fakeExec := &execTesting.FakeExec{}
 
 			fakeCmd := &execTesting.FakeCmd{}
 			fakeCmd.CombinedOutputScript = append(fakeCmd.CombinedOutputScript, func() ([]byte, []byte, error) {
-				return []byte("irrelevant line\n" +
-						"Priority trust state: dscp\n" +
-						"           enabled  0   1   0   1   0   1   0   0  \n" +
-						"another irrelevant line"),
+				return []byte("Priority trust state: dscp\n" +
+						"tc: 0 ratelimit: unlimited, tsa: strict\n" +
+						"\t priority:  1\n" +
+						"tc: 1 ratelimit: unlimited, tsa: ets, bw: 0%\n" +
+						"tc: 2 ratelimit: unlimited, tsa: ets, bw: 0%\n" +
+						"tc: 3 ratelimit: unlimited, tsa: ets, bw: 60%\n" +
+						"\t priority:  3\n" +
+						"tc: 4 ratelimit: unlimited, tsa: ets, bw: 40%\n" +
+						"tc: 5 ratelimit: unlimited, tsa: ets, bw: 0%\n" +
+						"tc: 6 ratelimit: unlimited, tsa: ets, bw: 0%\n" +
+						"tc: 7 ratelimit: unlimited, tsa: ets, bw: 0%\n"),
 					nil, nil
 			})
 
@@ -571,28 +881,19 @@ Device type:    ConnectX4
 				execInterface: fakeExec,
 			}
 
-			observedTrust, observedPFC, err := h.GetTrustAndPFC(interfaceName)
-
+			tcBandwidth, err := h.GetTcBandwidth(interfaceName)
 			Expect(err).NotTo(HaveOccurred())
-			Expect(observedTrust).To(Equal(strings.ToLower(trust)))
-			Expect(observedPFC).To(Equal(strings.ToLower(pfc)))
+			Expect(tcBandwidth).To(Equal("0,0,0,60,40,0,0,0"))
 		})
-		It("should return empty string for both numbers if one is empty", func() {
-			interfaceName := "enp3s0f0np0"
-
+		It("should return an error if the traffic classes are not reported", func() {
 			fakeExec := &execTesting.FakeExec{}
 
 			fakeCmd := &execTesting.FakeCmd{}
 			fakeCmd.CombinedOutputScript = append(fakeCmd.CombinedOutputScript, func() ([]byte, []byte, error) {
-				return []byte("irrelevant line\n" +
-						"Priority trust state: dscp\n" +
-						"another irrelevant line"),
-					nil, nil
+				return []byte("Priority trust state: dscp\n"), nil, nil
 			})
 
 			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
-				Expect(cmd).To(Equal("mlnx_qos"))
-				Expect(args).To(Equal([]string{"-i", interfaceName}))
 				return fakeCmd
 			})
 
@@ -600,72 +901,255 @@ Device type:    ConnectX4
 				execInterface: fakeExec,
 			}
 
-			observedTrust, observedPFC, err := h.GetTrustAndPFC(interfaceName)
-
+			_, err := h.GetTcBandwidth("enp3s0f0np0")
 			Expect(err).To(HaveOccurred())
-			Expect(observedTrust).To(Equal(""))
-			Expect(observedPFC).To(Equal(""))
 		})
 	})
-	Describe("SetMaxReadRequestSize", func() {
-		var (
-			h        *hostUtils
-			fakeExec *execTesting.FakeExec
-			pciAddr  string
-		)
 
-		BeforeEach(func() {
-			fakeExec = &execTesting.FakeExec{}
+	Describe("GetDcbAppEntries", func() {
+		It("should return the entries prefixed with their category", func() {
+			interfaceName := "enp3s0f0np0"
 
-			h = &hostUtils{
+			fakeExec := &execTesting.FakeExec{}
+
+			fakeCmd := &execTesting.FakeCmd{}
+			fakeCmd.CombinedOutputScript = append(fakeCmd.CombinedOutputScript, func() ([]byte, []byte, error) {
+				return []byte("default-prio 0\n" +
+						"ethtype-prio 0x8906:3 0x8914:3\n" +
+						"dscp-prio 24:3\n"),
+					nil, nil
+			})
+
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				Expect(cmd).To(Equal("dcb"))
+				Expect(args).To(Equal([]string{"app", "show", "dev", interfaceName}))
+				return fakeCmd
+			})
+
+			h := &hostUtils{
 				execInterface: fakeExec,
 			}
+
+			entries, err := h.GetDcbAppEntries(interfaceName)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(Equal([]string{"default-prio 0", "ethtype-prio 0x8906:3", "ethtype-prio 0x8914:3", "dscp-prio 24:3"}))
 		})
+	})
 
-		Context("with a valid maxReadRequestSize", func() {
-			It("executes the setpci command successfully", func() {
-				maxSize := 256
-				valueToApply := 1
+	Describe("DeleteDcbAppEntry", func() {
+		It("should delete the entry of its category", func() {
+			fakeExec := &execTesting.FakeExec{}
 
-				fakeCmd := &execTesting.FakeCmd{}
-				fakeCmd.OutputScript = append(fakeCmd.OutputScript, func() ([]byte, []byte, error) {
-					return nil, nil, nil
-				})
+			fakeCmd := &execTesting.FakeCmd{}
+			fakeCmd.CombinedOutputScript = append(fakeCmd.CombinedOutputScript, func() ([]byte, []byte, error) {
+				return nil, nil, nil
+			})
 
-				fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
-					Expect(cmd).To(Equal("setpci"))
-					Expect(args).To(Equal([]string{"-s", pciAddr, fmt.Sprintf("CAP_EXP+08.w=%d000:F000", valueToApply)}))
-					return fakeCmd
-				})
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				Expect(cmd).To(Equal("dcb"))
+				Expect(args).To(Equal([]string{"app", "del", "dev", "enp3s0f0np0", "ethtype-prio", "0x8906:3"}))
+				return fakeCmd
+			})
 
-				err := h.SetMaxReadRequestSize(pciAddr, maxSize)
+			h := &hostUtils{
+				execInterface: fakeExec,
+			}
 
-				Expect(err).ToNot(HaveOccurred())
-				Expect(fakeExec.CommandCalls).To(Equal(1))
-			})
+			Expect(h.DeleteDcbAppEntry("enp3s0f0np0", "ethtype-prio 0x8906:3")).To(Succeed())
 		})
+	})
 
-		Context("with an invalid maxReadRequestSize", func() {
-			It("returns an error without executing any command", func() {
-				maxSize := 300
+	Describe("GetRootQdisc", func() {
+		It("should return the kind of the root qdisc", func() {
+			interfaceName := "enp3s0f0np0"
 
-				err := h.SetMaxReadRequestSize(pciAddr, maxSize)
+			fakeExec := &execTesting.FakeExec{}
 
-				Expect(err).To(MatchError(fmt.Sprintf(
-					"unsupported maxReadRequestSize (%d) for pci device (%s). Acceptable values are powers of 2 from 128 to 4096",
-					maxSize, pciAddr,
-				)))
+			fakeCmd := &execTesting.FakeCmd{}
+			fakeCmd.CombinedOutputScript = append(fakeCmd.CombinedOutputScript, func() ([]byte, []byte, error) {
+				return []byte("qdisc mqprio 8001: root tc 8 map 0 1 2 3 4 5 6 7 0 0 0 0 0 0 0 0 hw 1 mode dcb\n"), nil, nil
 			})
-		})
 
-		Context("when the setpci command fails", func() {
-			It("returns an error", func() {
-				maxSize := 4096
-				valueToApply := 5
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				Expect(cmd).To(Equal("tc"))
+				Expect(args).To(Equal([]string{"qdisc", "show", "dev", interfaceName, "root"}))
+				return fakeCmd
+			})
 
-				fakeCmd := &execTesting.FakeCmd{}
-				fakeCmd.OutputScript = append(fakeCmd.OutputScript, func() ([]byte, []byte, error) {
-					return nil, nil, errors.New("some error")
+			h := &hostUtils{
+				execInterface: fakeExec,
+			}
+
+			qdisc, err := h.GetRootQdisc(interfaceName)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(qdisc).To(Equal("mqprio"))
+		})
+		It("should return an error if the qdisc is not reported", func() {
+			fakeExec := &execTesting.FakeExec{}
+
+			fakeCmd := &execTesting.FakeCmd{}
+			fakeCmd.CombinedOutputScript = append(fakeCmd.CombinedOutputScript, func() ([]byte, []byte, error) {
+				return nil, nil, nil
+			})
+
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				return fakeCmd
+			})
+
+			h := &hostUtils{
+				execInterface: fakeExec,
+			}
+
+			_, err := h.GetRootQdisc("enp3s0f0np0")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("SetTcBandwidth", func() {
+		It("should schedule all traffic classes with ETS", func() {
+			fakeExec := &execTesting.FakeExec{}
+
+			fakeCmd := &execTesting.FakeCmd{}
+			fakeCmd.CombinedOutputScript = append(fakeCmd.CombinedOutputScript, func() ([]byte, []byte, error) {
+				return nil, nil, nil
+			})
+
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				Expect(cmd).To(Equal("mlnx_qos"))
+				Expect(args).To(Equal([]string{"-i", "enp3s0f0np0", "--tsa", "ets,ets,ets,ets,ets,ets,ets,ets", "--tcbw", "0,0,0,60,40,0,0,0"}))
+				return fakeCmd
+			})
+
+			h := &hostUtils{
+				execInterface: fakeExec,
+			}
+
+			Expect(h.SetTcBandwidth("enp3s0f0np0", "0,0,0,60,40,0,0,0")).To(Succeed())
+		})
+	})
+
+	Describe("GetTrustAndPFC", func() {
+		It("should return parsed values", func() {
+			interfaceName := "enp3s0f0np0"
+			trust := "dscp"
+			pfc := "0,1,0,1,0,1,0,0"
+
+			fakeExec := &execTesting.FakeExec{}
+
+			fakeCmd := &execTesting.FakeCmd{}
+			fakeCmd.CombinedOutputScript = append(fakeCmd.CombinedOutputScript, func() ([]byte, []byte, error) {
+				return []byte("irrelevant line\n" +
+						"Priority trust state: dscp\n" +
+						"           enabled  0   1   0   1   0   1   0   0  \n" +
+						"another irrelevant line"),
+					nil, nil
+			})
+
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				Expect(cmd).To(Equal("mlnx_qos"))
+				Expect(args).To(Equal([]string{"-i", interfaceName}))
+				return fakeCmd
+			})
+
+			h := &hostUtils{
+				execInterface: fakeExec,
+			}
+
+			observedTrust, observedPFC, err := h.GetTrustAndPFC(interfaceName)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(observedTrust).To(Equal(strings.ToLower(trust)))
+			Expect(observedPFC).To(Equal(strings.ToLower(pfc)))
+		})
+		It("should return empty string for both numbers if one is empty", func() {
+			interfaceName := "enp3s0f0np0"
+
+			fakeExec := &execTesting.FakeExec{}
+
+			fakeCmd := &execTesting.FakeCmd{}
+			fakeCmd.CombinedOutputScript = append(fakeCmd.CombinedOutputScript, func() ([]byte, []byte, error) {
+				return []byte("irrelevant line\n" +
+						"Priority trust state: dscp\n" +
+						"another irrelevant line"),
+					nil, nil
+			})
+
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				Expect(cmd).To(Equal("mlnx_qos"))
+				Expect(args).To(Equal([]string{"-i", interfaceName}))
+				return fakeCmd
+			})
+
+			h := &hostUtils{
+				execInterface: fakeExec,
+			}
+
+			observedTrust, observedPFC, err := h.GetTrustAndPFC(interfaceName)
+
+			Expect(err).To(HaveOccurred())
+			Expect(observedTrust).To(Equal(""))
+			Expect(observedPFC).To(Equal(""))
+		})
+	})
+	Describe("SetMaxReadRequestSize", func() {
+		var (
+			h        *hostUtils
+			fakeExec *execTesting.FakeExec
+			pciAddr  string
+		)
+
+		BeforeEach(func() {
+			fakeExec = &execTesting.FakeExec{}
+
+			h = &hostUtils{
+				execInterface: fakeExec,
+			}
+		})
+
+		Context("with a valid maxReadRequestSize", func() {
+			It("executes the setpci command successfully", func() {
+				maxSize := 256
+				valueToApply := 1
+
+				fakeCmd := &execTesting.FakeCmd{}
+				fakeCmd.OutputScript = append(fakeCmd.OutputScript, func() ([]byte, []byte, error) {
+					return nil, nil, nil
+				})
+
+				fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+					Expect(cmd).To(Equal("setpci"))
+					Expect(args).To(Equal([]string{"-s", pciAddr, fmt.Sprintf("CAP_EXP+08.w=%d000:F000", valueToApply)}))
+					return fakeCmd
+				})
+
+				err := h.SetMaxReadRequestSize(pciAddr, maxSize)
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(fakeExec.CommandCalls).To(Equal(1))
+			})
+		})
+
+		Context("with an invalid maxReadRequestSize", func() {
+			It("returns an error without executing any command", func() {
+				maxSize := 300
+
+				err := h.SetMaxReadRequestSize(pciAddr, maxSize)
+
+				Expect(err).To(MatchError(fmt.Sprintf(
+					"unsupported maxReadRequestSize (%d) for pci device (%s). Acceptable values are powers of 2 from 128 to 4096",
+					maxSize, pciAddr,
+				)))
+			})
+		})
+
+		Context("when the setpci command fails", func() {
+			It("returns an error", func() {
+				maxSize := 4096
+				valueToApply := 5
+
+				fakeCmd := &execTesting.FakeCmd{}
+				fakeCmd.OutputScript = append(fakeCmd.OutputScript, func() ([]byte, []byte, error) {
+					return nil, nil, errors.New("some error")
 				})
 
 				fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
@@ -681,4 +1165,1209 @@ Device type:    ConnectX4
 			})
 		})
 	})
+	Describe("GetPortCounters", func() {
+		It("should return the QoS related physical port counters", func() {
+			interfaceName := "enp3s0f0np0"
+
+			fakeExec := &execTesting.FakeExec{}
+
+			fakeCmd := &execTesting.FakeCmd{}
+			fakeCmd.OutputScript = append(fakeCmd.OutputScript, func() ([]byte, []byte, error) {
+				return []byte("NIC statistics:\n" +
+						"     rx_packets: 1000\n" +
+						"     rx_prio3_bytes: 2048\n" +
+						"     rx_prio3_pause: 12\n" +
+						"     tx_pause_ctrl_phy: 7\n" +
+						"     rx_discards_phy: 3\n" +
+						"     rx_prio3_buf_discard: 5\n" +
+						"     tx_queue_dropped: 6\n" +
+						"     tx_bytes: 4096\n"),
+					nil, nil
+			})
+
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				Expect(cmd).To(Equal("ethtool"))
+				Expect(args).To(Equal([]string{"-S", interfaceName}))
+				return fakeCmd
+			})
+
+			h := &hostUtils{
+				execInterface: fakeExec,
+			}
+
+			counters, err := h.GetPortCounters(interfaceName)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(counters).To(Equal(map[string]uint64{
+				"rx_prio3_bytes":    2048,
+				"rx_prio3_pause":    12,
+				"tx_pause_ctrl_phy": 7,
+				"rx_discards_phy":   3,
+			}))
+		})
+	})
+	Describe("ResetPortCounters", func() {
+		It("should clear the counters with mstlink", func() {
+			pciAddr := "0000:3b:00.0"
+
+			fakeExec := &execTesting.FakeExec{}
+
+			fakeCmd := &execTesting.FakeCmd{}
+			fakeCmd.CombinedOutputScript = append(fakeCmd.CombinedOutputScript, func() ([]byte, []byte, error) {
+				return nil, nil, nil
+			})
+
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				Expect(cmd).To(Equal("mstlink"))
+				Expect(args).To(Equal([]string{"-d", pciAddr, "--pc"}))
+				return fakeCmd
+			})
+
+			h := &hostUtils{
+				execInterface: fakeExec,
+			}
+
+			Expect(h.ResetPortCounters(pciAddr)).To(Succeed())
+			Expect(fakeExec.CommandCalls).To(Equal(1))
+		})
+	})
+	Describe("GetDevlinkResources", func() {
+		It("should return flattened resources with pending sizes", func() {
+			pciAddr := "0000:3b:00.0"
+
+			fakeExec := &execTesting.FakeExec{}
+
+			fakeCmd := &execTesting.FakeCmd{}
+			fakeCmd.OutputScript = append(fakeCmd.OutputScript, func() ([]byte, []byte, error) {
+				return []byte(`{"resources":{"pci/0000:3b:00.0":[{"name":"kvd","size":245760,"unit":"entry",` +
+					`"size_min":0,"size_max":245760,"size_gran":128,"resources":[` +
+					`{"name":"linear","size":98304,"size_new":65536,"unit":"entry","size_min":0,"size_max":245760,"size_gran":128}]}]}}`), nil, nil
+			})
+
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				Expect(cmd).To(Equal("devlink"))
+				Expect(args).To(Equal([]string{"resource", "show", "pci/" + pciAddr, "-j"}))
+				return fakeCmd
+			})
+
+			h := &hostUtils{
+				execInterface: fakeExec,
+			}
+
+			resources, err := h.GetDevlinkResources(pciAddr)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resources).To(HaveLen(2))
+			Expect(resources["/kvd"].Size).To(Equal(uint64(245760)))
+			Expect(resources["/kvd"].SizeNew).To(BeNil())
+			Expect(resources["/kvd"].SizeGranularity).To(Equal(uint64(128)))
+			Expect(resources["/kvd/linear"].Size).To(Equal(uint64(98304)))
+			Expect(*resources["/kvd/linear"].SizeNew).To(Equal(uint64(65536)))
+			Expect(resources["/kvd/linear"].Unit).To(Equal("entry"))
+		})
+		It("should return an error if devlink fails", func() {
+			fakeExec := &execTesting.FakeExec{}
+
+			fakeCmd := &execTesting.FakeCmd{}
+			fakeCmd.OutputScript = append(fakeCmd.OutputScript, func() ([]byte, []byte, error) {
+				return nil, nil, errors.New("some error")
+			})
+
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				return fakeCmd
+			})
+
+			h := &hostUtils{
+				execInterface: fakeExec,
+			}
+
+			_, err := h.GetDevlinkResources("0000:3b:00.0")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Describe("IsEswitchManager", func() {
+		runDevlink := func(output string, err error) *hostUtils {
+			fakeExec := &execTesting.FakeExec{}
+
+			fakeCmd := &execTesting.FakeCmd{}
+			fakeCmd.CombinedOutputScript = append(fakeCmd.CombinedOutputScript, func() ([]byte, []byte, error) {
+				return []byte(output), nil, err
+			})
+
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				Expect(cmd).To(Equal("devlink"))
+				Expect(args).To(Equal([]string{"dev", "eswitch", "show", "pci/0000:3b:00.0"}))
+				return fakeCmd
+			})
+
+			return &hostUtils{execInterface: fakeExec}
+		}
+
+		It("should return true if the eswitch mode can be read", func() {
+			h := runDevlink("pci/0000:3b:00.0: mode legacy inline-mode none encap-mode basic", nil)
+
+			manager, err := h.IsEswitchManager("0000:3b:00.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(manager).To(BeTrue())
+		})
+		It("should return the eswitch access denied error if the eswitch query is not permitted", func() {
+			h := runDevlink("Error: mlx5_core: Operation not permitted.\nkernel answers: Operation not permitted", errors.New("exit status 1"))
+
+			manager, err := h.IsEswitchManager("0000:3b:00.0")
+			Expect(types.IsEswitchAccessDeniedError(err)).To(BeTrue())
+			Expect(err).To(MatchError(ContainSubstring("kernel answers: Operation not permitted")))
+			Expect(manager).To(BeFalse())
+		})
+		It("should return an error if devlink fails", func() {
+			h := runDevlink("Error: devlink: Unknown device", errors.New("exit status 1"))
+
+			_, err := h.IsEswitchManager("0000:3b:00.0")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should keep the tool hang errors of devlink", func() {
+			h := runDevlink("", types.ToolHangError("devlink was killed"))
+
+			_, err := h.IsEswitchManager("0000:3b:00.0")
+			Expect(types.IsToolHangError(err)).To(BeTrue())
+			Expect(err).To(MatchError(ContainSubstring("failed to run devlink")))
+		})
+	})
+	Describe("ReloadDevlinkDevice", func() {
+		It("should keep the tool hang errors of devlink", func() {
+			fakeCmd := &execTesting.FakeCmd{}
+			fakeCmd.CombinedOutputScript = append(fakeCmd.CombinedOutputScript, func() ([]byte, []byte, error) {
+				return nil, nil, types.ToolHangError("devlink was killed")
+			})
+			fakeExec := &execTesting.FakeExec{}
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				Expect(cmd).To(Equal("devlink"))
+				Expect(args).To(Equal([]string{"dev", "reload", "pci/0000:3b:00.0"}))
+				return fakeCmd
+			})
+
+			err := (&hostUtils{execInterface: fakeExec}).ReloadDevlinkDevice("0000:3b:00.0")
+			Expect(types.IsToolHangError(err)).To(BeTrue())
+			Expect(types.IsIncorrectSpecError(err)).To(BeFalse())
+		})
+	})
+
+	Describe("GetPortSpeed", func() {
+		var netPath string
+
+		BeforeEach(func() {
+			sysfs := GinkgoT().TempDir()
+			originalPath := pciDevicesPath
+			pciDevicesPath = sysfs
+			DeferCleanup(func() { pciDevicesPath = originalPath })
+
+			netPath = filepath.Join(sysfs, "0000:3b:00.0", "net", "enp59s0f0np0")
+			Expect(os.MkdirAll(netPath, 0755)).To(Succeed())
+		})
+
+		It("should return the speed of the port in Mb/s", func() {
+			Expect(os.WriteFile(filepath.Join(netPath, "speed"), []byte("400000\n"), 0644)).To(Succeed())
+
+			speed, err := (&hostUtils{}).GetPortSpeed("0000:3b:00.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(speed).To(Equal(400000))
+		})
+		It("should return 0 if the speed is unknown", func() {
+			Expect(os.WriteFile(filepath.Join(netPath, "speed"), []byte("-1\n"), 0644)).To(Succeed())
+
+			speed, err := (&hostUtils{}).GetPortSpeed("0000:3b:00.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(speed).To(Equal(0))
+		})
+		It("should return 0 if the speed is not available", func() {
+			speed, err := (&hostUtils{}).GetPortSpeed("0000:3b:00.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(speed).To(Equal(0))
+		})
+		It("should return an error if the device has no network interface", func() {
+			_, err := (&hostUtils{}).GetPortSpeed("0000:af:00.0")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Describe("GetTemperature", func() {
+		runMgetTemp := func(output string, err error) *hostUtils {
+			fakeExec := &execTesting.FakeExec{}
+			fakeCmd := &execTesting.FakeCmd{}
+			fakeCmd.OutputScript = append(fakeCmd.OutputScript, func() ([]byte, []byte, error) {
+				return []byte(output), nil, err
+			})
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				Expect(cmd).To(Equal("mstmget_temp"))
+				Expect(args).To(Equal([]string{"-d", "0000:3b:00.0"}))
+				return fakeCmd
+			})
+			return &hostUtils{execInterface: fakeExec}
+		}
+
+		It("should return the ASIC temperature", func() {
+			h := runMgetTemp("62            \n", nil)
+
+			Expect(h.GetTemperature("0000:3b:00.0")).To(Equal(62))
+		})
+		It("should return an error if mstmget_temp fails", func() {
+			h := runMgetTemp("-E- Failed to open the device", errors.New("exit status 1"))
+
+			_, err := h.GetTemperature("0000:3b:00.0")
+			Expect(err).To(HaveOccurred())
+		})
+		It("should return an error if the output can't be parsed", func() {
+			h := runMgetTemp("-E- Unsupported device\n", nil)
+
+			_, err := h.GetTemperature("0000:3b:00.0")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("GetHealthReporters", func() {
+		runDevlinkHealth := func(output string, err error) *hostUtils {
+			fakeExec := &execTesting.FakeExec{}
+			fakeCmd := &execTesting.FakeCmd{}
+			fakeCmd.OutputScript = append(fakeCmd.OutputScript, func() ([]byte, []byte, error) {
+				return []byte(output), nil, err
+			})
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				Expect(cmd).To(Equal("devlink"))
+				Expect(args).To(Equal([]string{"health", "show", "pci/0000:3b:00.0", "-j"}))
+				return fakeCmd
+			})
+			return &hostUtils{execInterface: fakeExec}
+		}
+
+		It("should return the reporters of the device and of its ports sorted by name", func() {
+			h := runDevlinkHealth(`{"health":{"pci/0000:3b:00.0":[`+
+				`{"reporter":"fw","state":"healthy","error":0,"recover":0,"auto_dump":true},`+
+				`{"reporter":"fw_fatal","state":"error","error":2,"recover":1,"grace_period":60000,"auto_recover":true,"auto_dump":true}],`+
+				`"pci/0000:3b:00.0/65535":[{"reporter":"tx","state":"healthy","error":3,"recover":3,"grace_period":500,"auto_recover":true}]}}`, nil)
+
+			reporters, err := h.GetHealthReporters("0000:3b:00.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reporters).To(Equal([]types.HealthReporter{
+				{Name: "fw", State: "healthy"},
+				{Name: "fw_fatal", State: "error", ErrorCount: 2, RecoverCount: 1},
+				{Name: "tx", State: "healthy", ErrorCount: 3, RecoverCount: 3},
+			}))
+		})
+		It("should return no reporters if the driver doesn't register them", func() {
+			h := runDevlinkHealth(`{"health":{}}`, nil)
+
+			Expect(h.GetHealthReporters("0000:3b:00.0")).To(BeEmpty())
+		})
+		It("should return an error if devlink fails", func() {
+			h := runDevlinkHealth("", errors.New("exit status 1"))
+
+			_, err := h.GetHealthReporters("0000:3b:00.0")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("GetHypervisor", func() {
+		var dir string
+
+		BeforeEach(func() {
+			dir = GinkgoT().TempDir()
+			originalCPUInfoPath, originalVendorPath := cpuInfoPath, dmiSysVendorPath
+			cpuInfoPath = filepath.Join(dir, "cpuinfo")
+			dmiSysVendorPath = filepath.Join(dir, "sys_vendor")
+			DeferCleanup(func() { cpuInfoPath, dmiSysVendorPath = originalCPUInfoPath, originalVendorPath })
+		})
+
+		It("should return the DMI vendor of the VM with the hypervisor CPU flag", func() {
+			Expect(os.WriteFile(cpuInfoPath, []byte("processor\t: 0\nflags\t\t: fpu vme sse2 hypervisor lahf_lm\n"), 0644)).To(Succeed())
+			Expect(os.WriteFile(dmiSysVendorPath, []byte("QEMU\n"), 0644)).To(Succeed())
+
+			Expect((&hostUtils{}).GetHypervisor()).To(Equal("QEMU"))
+		})
+		It("should return unknown if the VM has no DMI vendor", func() {
+			Expect(os.WriteFile(cpuInfoPath, []byte("flags\t\t: fpu hypervisor\n"), 0644)).To(Succeed())
+
+			Expect((&hostUtils{}).GetHypervisor()).To(Equal("unknown"))
+		})
+		It("should detect the VM by the DMI vendor without the CPU flag", func() {
+			Expect(os.WriteFile(cpuInfoPath, []byte("processor\t: 0\nFeatures\t: fp asimd evtstrm\n"), 0644)).To(Succeed())
+			Expect(os.WriteFile(dmiSysVendorPath, []byte("Xen\n"), 0644)).To(Succeed())
+
+			Expect((&hostUtils{}).GetHypervisor()).To(Equal("Xen"))
+		})
+		It("should return an empty hypervisor on bare metal", func() {
+			Expect(os.WriteFile(cpuInfoPath, []byte("flags\t\t: fpu vme sse2\n"), 0644)).To(Succeed())
+			Expect(os.WriteFile(dmiSysVendorPath, []byte("Dell Inc.\n"), 0644)).To(Succeed())
+
+			Expect((&hostUtils{}).GetHypervisor()).To(BeEmpty())
+		})
+		It("should return an error if the CPU info can't be read", func() {
+			_, err := (&hostUtils{}).GetHypervisor()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Describe("congestion control", func() {
+		var ecnPath string
+
+		BeforeEach(func() {
+			sysfs := GinkgoT().TempDir()
+			originalPath := netDevicesPath
+			netDevicesPath = sysfs
+			DeferCleanup(func() { netDevicesPath = originalPath })
+
+			ecnPath = filepath.Join(sysfs, "eth0", "ecn")
+			for _, point := range []string{"roce_rp", "roce_np"} {
+				Expect(os.MkdirAll(filepath.Join(ecnPath, point, "enable"), 0755)).To(Succeed())
+				for prio := 0; prio < 8; prio++ {
+					Expect(os.WriteFile(filepath.Join(ecnPath, point, "enable", strconv.Itoa(prio)), []byte("0\n"), 0644)).To(Succeed())
+				}
+			}
+		})
+
+		It("should enable ECN on both the reaction and the notification point", func() {
+			h := &hostUtils{}
+			Expect(h.SetEcn("eth0", "0,0,0,1,0,0,0,0")).To(Succeed())
+
+			value, err := os.ReadFile(filepath.Join(ecnPath, "roce_np", "enable", "3"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(value)).To(Equal("1"))
+
+			ecn, err := h.GetEcn("eth0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ecn).To(Equal("0,0,0,1,0,0,0,0"))
+		})
+		It("should report ECN disabled if it is enabled only on the reaction point", func() {
+			Expect(os.WriteFile(filepath.Join(ecnPath, "roce_rp", "enable", "3"), []byte("1\n"), 0644)).To(Succeed())
+
+			ecn, err := (&hostUtils{}).GetEcn("eth0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ecn).To(Equal("0,0,0,0,0,0,0,0"))
+		})
+		It("should report congestion control as supported if the ecn directory is available", func() {
+			Expect((&hostUtils{}).IsCongestionControlSupported("eth0")).To(BeTrue())
+		})
+		It("should report congestion control as not supported without the ecn directory", func() {
+			Expect(os.RemoveAll(filepath.Join(ecnPath, "roce_np"))).To(Succeed())
+
+			Expect((&hostUtils{}).IsCongestionControlSupported("eth0")).To(BeFalse())
+			Expect((&hostUtils{}).IsCongestionControlSupported("eth1")).To(BeFalse())
+		})
+		It("should reject the ECN settings for a wrong number of priorities", func() {
+			Expect((&hostUtils{}).SetEcn("eth0", "0,1")).NotTo(Succeed())
+		})
+		It("should set and return the DCQCN parameters", func() {
+			h := &hostUtils{}
+			Expect(os.WriteFile(filepath.Join(ecnPath, "roce_rp", "rpg_min_rate"), []byte("1\n"), 0644)).To(Succeed())
+			Expect(h.SetDcqcnParameter("eth0", "roce_rp/rpg_min_rate", 100)).To(Succeed())
+
+			value, err := h.GetDcqcnParameter("eth0", "roce_rp/rpg_min_rate")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(value).To(Equal(100))
+		})
+		It("should return an error if the DCQCN parameter is not available", func() {
+			_, err := (&hostUtils{}).GetDcqcnParameter("eth0", "roce_rp/rpg_gd")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Describe("GetPtpClockIndex", func() {
+		var devicePath string
+
+		BeforeEach(func() {
+			sysfs := GinkgoT().TempDir()
+			originalPath := pciDevicesPath
+			pciDevicesPath = sysfs
+			DeferCleanup(func() { pciDevicesPath = originalPath })
+
+			devicePath = filepath.Join(sysfs, "0000:3b:00.0")
+			Expect(os.MkdirAll(devicePath, 0755)).To(Succeed())
+		})
+
+		It("should return the index of the PTP hardware clock", func() {
+			Expect(os.MkdirAll(filepath.Join(devicePath, "ptp", "ptp2"), 0755)).To(Succeed())
+
+			index, err := (&hostUtils{}).GetPtpClockIndex("0000:3b:00.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(index).To(Equal(2))
+		})
+		It("should return -1 if the device has no PTP hardware clock", func() {
+			index, err := (&hostUtils{}).GetPtpClockIndex("0000:3b:00.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(index).To(Equal(-1))
+		})
+	})
+	Describe("GetEswitchMode", func() {
+		runDevlink := func(output string, err error) *hostUtils {
+			fakeExec := &execTesting.FakeExec{}
+			fakeCmd := &execTesting.FakeCmd{
+				CombinedOutputScript: []execTesting.FakeAction{
+					func() ([]byte, []byte, error) { return []byte(output), nil, err },
+				},
+			}
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				Expect(cmd).To(Equal("devlink"))
+				Expect(args).To(Equal([]string{"dev", "eswitch", "show", "pci/0000:3b:00.0"}))
+				return fakeCmd
+			})
+			return &hostUtils{execInterface: fakeExec}
+		}
+
+		It("should return the eswitch mode of the PF", func() {
+			h := runDevlink("pci/0000:3b:00.0: mode switchdev inline-mode none encap-mode basic\n", nil)
+
+			mode, err := h.GetEswitchMode("0000:3b:00.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mode).To(Equal("switchdev"))
+		})
+		It("should return empty mode if the PF is not the eswitch manager", func() {
+			h := runDevlink("Error: devlink: Operation not permitted", errors.New("exit status 1"))
+
+			mode, err := h.GetEswitchMode("0000:3b:00.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mode).To(BeEmpty())
+		})
+		It("should return an error if the mode isn't reported", func() {
+			h := runDevlink("pci/0000:3b:00.0:\n", nil)
+
+			_, err := h.GetEswitchMode("0000:3b:00.0")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Describe("GetDevlinkParam", func() {
+		runDevlink := func(output string, err error) *hostUtils {
+			fakeExec := &execTesting.FakeExec{}
+			fakeCmd := &execTesting.FakeCmd{
+				OutputScript: []execTesting.FakeAction{
+					func() ([]byte, []byte, error) { return []byte(output), nil, err },
+				},
+			}
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				Expect(cmd).To(Equal("devlink"))
+				Expect(args).To(Equal([]string{"dev", "param", "show", "pci/0000:3b:00.0", "name", "flow_steering_mode", "-j"}))
+				return fakeCmd
+			})
+			return &hostUtils{execInterface: fakeExec}
+		}
+
+		It("should return the runtime value of the parameter", func() {
+			h := runDevlink(`{"param":{"pci/0000:3b:00.0":[{"name":"flow_steering_mode","type":"driver-specific",`+
+				`"values":[{"cmode":"runtime","value":"smfs"}]}]}}`, nil)
+
+			value, err := h.GetDevlinkParam("0000:3b:00.0", "flow_steering_mode")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(value).To(Equal("smfs"))
+		})
+		It("should return an error if the parameter has no runtime value", func() {
+			h := runDevlink(`{"param":{"pci/0000:3b:00.0":[{"name":"flow_steering_mode","type":"driver-specific",`+
+				`"values":[{"cmode":"driverinit","value":"smfs"}]}]}}`, nil)
+
+			_, err := h.GetDevlinkParam("0000:3b:00.0", "flow_steering_mode")
+			Expect(err).To(MatchError(ContainSubstring("runtime value of devlink param flow_steering_mode of device 0000:3b:00.0 not found")))
+		})
+		It("should return an error if devlink fails", func() {
+			_, devlinkErr := osexec.Command("sh", "-c", "echo 'Error: devlink: Parameter not found' >&2; exit 1").Output()
+			h := runDevlink("", &exec.ExitErrorWrapper{ExitError: devlinkErr.(*osexec.ExitError)})
+
+			_, err := h.GetDevlinkParam("0000:3b:00.0", "flow_steering_mode")
+			Expect(err).To(MatchError("failed to run devlink: exit status 1: Error: devlink: Parameter not found"))
+		})
+	})
+	Describe("SetDevlinkParam", func() {
+		It("should set the runtime value of the parameter", func() {
+			fakeExec := &execTesting.FakeExec{}
+			fakeCmd := &execTesting.FakeCmd{
+				CombinedOutputScript: []execTesting.FakeAction{
+					func() ([]byte, []byte, error) { return nil, nil, nil },
+				},
+			}
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				Expect(cmd).To(Equal("devlink"))
+				Expect(args).To(Equal([]string{"dev", "param", "set", "pci/0000:3b:00.0",
+					"name", "flow_steering_mode", "value", "smfs", "cmode", "runtime"}))
+				return fakeCmd
+			})
+			h := &hostUtils{execInterface: fakeExec}
+
+			Expect(h.SetDevlinkParam("0000:3b:00.0", "flow_steering_mode", "smfs")).To(Succeed())
+			Expect(fakeExec.CommandCalls).To(Equal(1))
+		})
+	})
+	Describe("SetEswitchMode", func() {
+		It("should set the eswitch mode of the PF", func() {
+			fakeExec := &execTesting.FakeExec{}
+			fakeCmd := &execTesting.FakeCmd{
+				CombinedOutputScript: []execTesting.FakeAction{
+					func() ([]byte, []byte, error) { return nil, nil, nil },
+				},
+			}
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				Expect(cmd).To(Equal("devlink"))
+				Expect(args).To(Equal([]string{"dev", "eswitch", "set", "pci/0000:3b:00.0", "mode", "switchdev"}))
+				return fakeCmd
+			})
+			h := &hostUtils{execInterface: fakeExec}
+
+			Expect(h.SetEswitchMode("0000:3b:00.0", "switchdev")).To(Succeed())
+			Expect(fakeExec.CommandCalls).To(Equal(1))
+		})
+	})
+	Describe("GetVfRepresentors", func() {
+		It("should return the network interfaces of the VF representors", func() {
+			sysfs := GinkgoT().TempDir()
+			originalPath := pciDevicesPath
+			pciDevicesPath = sysfs
+			DeferCleanup(func() { pciDevicesPath = originalPath })
+
+			for name, portName := range map[string]string{"enp59s0f0np0": "p0", "enp59s0f0npf0vf0": "pf0vf0", "eth3": "c1pf0vf1", "eth4": ""} {
+				netPath := filepath.Join(sysfs, "0000:3b:00.0", "net", name)
+				Expect(os.MkdirAll(netPath, 0755)).To(Succeed())
+				if portName != "" {
+					Expect(os.WriteFile(filepath.Join(netPath, "phys_port_name"), []byte(portName+"\n"), 0644)).To(Succeed())
+				}
+			}
+
+			representors, err := (&hostUtils{}).GetVfRepresentors("0000:3b:00.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(representors).To(ConsistOf("enp59s0f0npf0vf0", "eth3"))
+		})
+	})
+	Describe("GetVfPciAddresses", func() {
+		It("should return the VFs ordered by the VF index", func() {
+			sysfs := GinkgoT().TempDir()
+			originalPath := pciDevicesPath
+			pciDevicesPath = sysfs
+			DeferCleanup(func() { pciDevicesPath = originalPath })
+
+			pfPath := filepath.Join(sysfs, "0000:3b:00.0")
+			Expect(os.MkdirAll(pfPath, 0755)).To(Succeed())
+			for i := 0; i < 11; i++ {
+				Expect(os.Symlink(fmt.Sprintf("../0000:3b:%02x.%d", 1+i/8, i%8), filepath.Join(pfPath, fmt.Sprintf("virtfn%d", i)))).To(Succeed())
+			}
+
+			vfs, err := (&hostUtils{}).GetVfPciAddresses("0000:3b:00.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(vfs).To(HaveLen(11))
+			Expect(vfs[0]).To(Equal("0000:3b:01.0"))
+			Expect(vfs[10]).To(Equal("0000:3b:02.2"))
+		})
+	})
+	Describe("GetPCIDriver", func() {
+		var sysfs string
+
+		BeforeEach(func() {
+			sysfs = GinkgoT().TempDir()
+			originalPath := pciDevicesPath
+			pciDevicesPath = sysfs
+			DeferCleanup(func() { pciDevicesPath = originalPath })
+
+			Expect(os.MkdirAll(filepath.Join(sysfs, "0000:3b:00.2"), 0755)).To(Succeed())
+		})
+
+		It("should return the driver bound to the device", func() {
+			Expect(os.Symlink("../../../bus/pci/drivers/vfio-pci", filepath.Join(sysfs, "0000:3b:00.2", "driver"))).To(Succeed())
+
+			Expect((&hostUtils{}).GetPCIDriver("0000:3b:00.2")).To(Equal("vfio-pci"))
+		})
+		It("should return an empty driver if no driver is bound", func() {
+			Expect((&hostUtils{}).GetPCIDriver("0000:3b:00.2")).To(BeEmpty())
+		})
+		It("should return an error if the device doesn't exist", func() {
+			_, err := (&hostUtils{}).GetPCIDriver("0000:3b:00.3")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Describe("GetVfMsixCount", func() {
+		It("should parse the MSI-X table size of the VF", func() {
+			fakeExec := &execTesting.FakeExec{}
+			fakeCmd := &execTesting.FakeCmd{
+				OutputScript: []execTesting.FakeAction{
+					func() ([]byte, []byte, error) {
+						return []byte("0000:3b:00.2 Ethernet controller: Mellanox Technologies ConnectX Family mlx5Gen Virtual Function\n" +
+							"\tCapabilities: [9c] MSI-X: Enable+ Count=12 Masked-\n"), nil, nil
+					},
+				},
+			}
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				Expect(cmd).To(Equal("lspci"))
+				Expect(args).To(Equal([]string{"-vv", "-s", "0000:3b:00.2"}))
+				return fakeCmd
+			})
+
+			count, err := (&hostUtils{execInterface: fakeExec}).GetVfMsixCount("0000:3b:00.2")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(count).To(Equal(12))
+		})
+	})
+	Describe("GetEthtoolFeatures", func() {
+		It("should parse the features and their state", func() {
+			fakeExec := &execTesting.FakeExec{}
+			fakeCmd := &execTesting.FakeCmd{
+				OutputScript: []execTesting.FakeAction{
+					func() ([]byte, []byte, error) {
+						return []byte("Features for pf0vf0:\nrx-checksumming: on\ntx-checksumming: on\n\ttx-checksum-ipv4: off [fixed]\nhw-tc-offload: off\n"), nil, nil
+					},
+				},
+			}
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				Expect(cmd).To(Equal("ethtool"))
+				Expect(args).To(Equal([]string{"-k", "pf0vf0"}))
+				return fakeCmd
+			})
+
+			features, err := (&hostUtils{execInterface: fakeExec}).GetEthtoolFeatures("pf0vf0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(features).To(Equal(map[string]types.EthtoolFeature{
+				"rx-checksumming":  {Enabled: true},
+				"tx-checksumming":  {Enabled: true},
+				"tx-checksum-ipv4": {Fixed: true},
+				"hw-tc-offload":    {},
+			}))
+		})
+	})
+	Describe("GetTransceiver", func() {
+		It("should parse the module EEPROM", func() {
+			fakeExec := &execTesting.FakeExec{}
+			fakeCmd := &execTesting.FakeCmd{
+				CombinedOutputScript: []execTesting.FakeAction{
+					func() ([]byte, []byte, error) {
+						return []byte("\tIdentifier                                : 0x11 (QSFP28)\n" +
+							"\tConnector                                 : 0x23 (No separable connector)\n" +
+							"\tTransceiver type                          : 100G Ethernet: 100G Base-CR4 or 25G Base-CR CA-L\n" +
+							"\tTransceiver type                          : 40G Ethernet: 40G Base-CR4\n" +
+							"\tLength (SMF,km)                           : 0km\n" +
+							"\tLength (OM3 50um)                         : 0m\n" +
+							"\tLength (Copper or Active cable)           : 3m\n" +
+							"\tTransmitter technology                    : 0xa0 (Copper cable unequalized)\n" +
+							"\tVendor name                               : Mellanox\n" +
+							"\tVendor OUI                                : 00:02:c9\n" +
+							"\tVendor PN                                 : MCP1600-C003\n" +
+							"\tVendor SN                                 : MT1234VS01234\n"), nil, nil
+					},
+				},
+			}
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				Expect(cmd).To(Equal("ethtool"))
+				Expect(args).To(Equal([]string{"-m", "eth0"}))
+				return fakeCmd
+			})
+
+			transceiver, err := (&hostUtils{execInterface: fakeExec}).GetTransceiver("eth0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(transceiver).To(Equal(&types.Transceiver{
+				Type:            "QSFP28",
+				Technology:      "Copper cable unequalized",
+				Vendor:          "Mellanox",
+				PartNumber:      "MCP1600-C003",
+				Length:          "3m",
+				SupportedSpeeds: []string{"100G", "40G", "25G"},
+			}))
+		})
+		It("should report the lengths of the cables shorter than 1m", func() {
+			fakeExec := &execTesting.FakeExec{}
+			fakeCmd := &execTesting.FakeCmd{
+				CombinedOutputScript: []execTesting.FakeAction{
+					func() ([]byte, []byte, error) {
+						return []byte("\tIdentifier                                : 0x18 (QSFP-DD Double Density 8X Pluggable Transceiver (INF-8628))\n" +
+							"\tLength (SMF)                              : 0.00km\n" +
+							"\tLength (Copper or Active cable)           : 0.50m\n"), nil, nil
+					},
+				},
+			}
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				return fakeCmd
+			})
+
+			transceiver, err := (&hostUtils{execInterface: fakeExec}).GetTransceiver("eth0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(transceiver.Length).To(Equal("0.50m"))
+		})
+		It("should return nil if the cage is empty", func() {
+			fakeExec := &execTesting.FakeExec{}
+			fakeCmd := &execTesting.FakeCmd{
+				CombinedOutputScript: []execTesting.FakeAction{
+					func() ([]byte, []byte, error) {
+						return []byte("netlink error: Input/output error\n"), nil, errors.New("exit status 1")
+					},
+				},
+			}
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				return fakeCmd
+			})
+
+			transceiver, err := (&hostUtils{execInterface: fakeExec}).GetTransceiver("eth0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(transceiver).To(BeNil())
+		})
+		It("should return an error if the module can't be queried", func() {
+			fakeExec := &execTesting.FakeExec{}
+			fakeCmd := &execTesting.FakeCmd{
+				CombinedOutputScript: []execTesting.FakeAction{
+					func() ([]byte, []byte, error) {
+						return []byte("Cannot get module EEPROM information: Operation not supported\n"), nil, errors.New("exit status 1")
+					},
+				},
+			}
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				return fakeCmd
+			})
+
+			_, err := (&hostUtils{execInterface: fakeExec}).GetTransceiver("eth0")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Describe("GetLinkStatus", func() {
+		var fakeExec *execTesting.FakeExec
+
+		BeforeEach(func() {
+			sysfs := GinkgoT().TempDir()
+			originalPath := netDevicesPath
+			netDevicesPath = sysfs
+			DeferCleanup(func() { netDevicesPath = originalPath })
+
+			Expect(os.MkdirAll(filepath.Join(sysfs, "eth0"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(sysfs, "eth0", "operstate"), []byte("up\n"), 0644)).To(Succeed())
+
+			fakeExec = &execTesting.FakeExec{}
+		})
+
+		It("should report the state, speed and auto-negotiation of the link", func() {
+			fakeCmd := &execTesting.FakeCmd{
+				OutputScript: []execTesting.FakeAction{
+					func() ([]byte, []byte, error) {
+						return []byte("Settings for eth0:\n" +
+							"\tSupported ports: [ FIBRE ]\n" +
+							"\tSpeed: 100000Mb/s\n" +
+							"\tDuplex: Full\n" +
+							"\tAuto-negotiation: on\n" +
+							"\tLink detected: yes\n"), nil, nil
+					},
+				},
+			}
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				Expect(cmd).To(Equal("ethtool"))
+				Expect(args).To(Equal([]string{"eth0"}))
+				return fakeCmd
+			})
+
+			link, err := (&hostUtils{execInterface: fakeExec}).GetLinkStatus("eth0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(link).To(Equal(&types.LinkStatus{OperState: "up", Speed: 100000, AutoNegotiation: ptr.To(true)}))
+		})
+		It("should not report the speed while the link is down", func() {
+			Expect(os.WriteFile(filepath.Join(netDevicesPath, "eth0", "operstate"), []byte("down\n"), 0644)).To(Succeed())
+			fakeCmd := &execTesting.FakeCmd{
+				OutputScript: []execTesting.FakeAction{
+					func() ([]byte, []byte, error) {
+						return []byte("Settings for eth0:\n" +
+							"\tSpeed: Unknown!\n" +
+							"\tAuto-negotiation: off\n" +
+							"\tLink detected: no\n"), nil, nil
+					},
+				},
+			}
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				return fakeCmd
+			})
+
+			link, err := (&hostUtils{execInterface: fakeExec}).GetLinkStatus("eth0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(link).To(Equal(&types.LinkStatus{OperState: "down", AutoNegotiation: ptr.To(false)}))
+		})
+		It("should return an error if the interface doesn't exist", func() {
+			_, err := (&hostUtils{execInterface: fakeExec}).GetLinkStatus("eth1")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Describe("GetRingSizes", func() {
+		It("should parse the current and the maximum ring sizes", func() {
+			fakeExec := &execTesting.FakeExec{}
+			fakeCmd := &execTesting.FakeCmd{
+				OutputScript: []execTesting.FakeAction{
+					func() ([]byte, []byte, error) {
+						return []byte("Ring parameters for eth0:\nPre-set maximums:\nRX:\t\t\t8192\nRX Mini:\t\tn/a\nRX Jumbo:\t\tn/a\n" +
+							"TX:\t\t\t8192\nCurrent hardware settings:\nRX:\t\t\t1024\nRX Mini:\t\tn/a\nRX Jumbo:\t\tn/a\n" +
+							"TX:\t\t\t512\nRX Buf Len:\t\tn/a\nCQE Size:\t\tn/a\nTX Push:\t\toff\n"), nil, nil
+					},
+				},
+			}
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				Expect(cmd).To(Equal("ethtool"))
+				Expect(args).To(Equal([]string{"-g", "eth0"}))
+				return fakeCmd
+			})
+
+			sizes, err := (&hostUtils{execInterface: fakeExec}).GetRingSizes("eth0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sizes).To(Equal(types.RingSizes{Rx: 1024, Tx: 512, RxMax: 8192, TxMax: 8192}))
+		})
+		It("should return an error if the output doesn't contain the ring sizes", func() {
+			fakeExec := &execTesting.FakeExec{}
+			fakeCmd := &execTesting.FakeCmd{
+				OutputScript: []execTesting.FakeAction{
+					func() ([]byte, []byte, error) {
+						return []byte("Ring parameters for eth0:\n"), nil, nil
+					},
+				},
+			}
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				return fakeCmd
+			})
+
+			_, err := (&hostUtils{execInterface: fakeExec}).GetRingSizes("eth0")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Describe("SetRingSizes", func() {
+		It("should set the RX and TX ring sizes", func() {
+			fakeExec := &execTesting.FakeExec{}
+			fakeCmd := &execTesting.FakeCmd{
+				CombinedOutputScript: []execTesting.FakeAction{
+					func() ([]byte, []byte, error) {
+						return nil, nil, nil
+					},
+				},
+			}
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				Expect(cmd).To(Equal("ethtool"))
+				Expect(args).To(Equal([]string{"-G", "eth0", "rx", "8192", "tx", "2048"}))
+				return fakeCmd
+			})
+
+			Expect((&hostUtils{execInterface: fakeExec}).SetRingSizes("eth0", 8192, 2048)).To(Succeed())
+		})
+	})
+	Describe("GetCoalescing", func() {
+		It("should parse the adaptive modes and the usecs and frames parameters", func() {
+			fakeExec := &execTesting.FakeExec{}
+			fakeCmd := &execTesting.FakeCmd{
+				OutputScript: []execTesting.FakeAction{
+					func() ([]byte, []byte, error) {
+						return []byte("Coalesce parameters for eth0:\nAdaptive RX: on  TX: off\nstats-block-usecs: n/a\n" +
+							"sample-interval: n/a\npkt-rate-low: n/a\n\nrx-usecs: 8\nrx-frames: 128\nrx-usecs-irq: n/a\n\n" +
+							"tx-usecs: 16\ntx-frames: 32\ntx-usecs-irq: n/a\n"), nil, nil
+					},
+				},
+			}
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				Expect(cmd).To(Equal("ethtool"))
+				Expect(args).To(Equal([]string{"-c", "eth0"}))
+				return fakeCmd
+			})
+
+			coalescing, err := (&hostUtils{execInterface: fakeExec}).GetCoalescing("eth0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(coalescing).To(Equal(types.Coalescing{AdaptiveRx: true, RxUsecs: 8, RxFrames: 128, TxUsecs: 16, TxFrames: 32}))
+		})
+		It("should return an error if the output doesn't contain the coalescing settings", func() {
+			fakeExec := &execTesting.FakeExec{}
+			fakeCmd := &execTesting.FakeCmd{
+				OutputScript: []execTesting.FakeAction{
+					func() ([]byte, []byte, error) {
+						return []byte("Coalesce parameters for eth0:\n"), nil, nil
+					},
+				},
+			}
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				return fakeCmd
+			})
+
+			_, err := (&hostUtils{execInterface: fakeExec}).GetCoalescing("eth0")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Describe("SetCoalescing", func() {
+		It("should set the adaptive modes and the usecs and frames parameters", func() {
+			fakeExec := &execTesting.FakeExec{}
+			fakeCmd := &execTesting.FakeCmd{
+				CombinedOutputScript: []execTesting.FakeAction{
+					func() ([]byte, []byte, error) {
+						return nil, nil, nil
+					},
+				},
+			}
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				Expect(cmd).To(Equal("ethtool"))
+				Expect(args).To(Equal([]string{"-C", "eth0", "adaptive-rx", "off", "adaptive-tx", "on",
+					"rx-usecs", "0", "rx-frames", "1", "tx-usecs", "16", "tx-frames", "32"}))
+				return fakeCmd
+			})
+
+			Expect((&hostUtils{execInterface: fakeExec}).SetCoalescing("eth0",
+				types.Coalescing{AdaptiveTx: true, RxFrames: 1, TxUsecs: 16, TxFrames: 32})).To(Succeed())
+		})
+	})
+	Describe("GetChannels", func() {
+		It("should parse the current and the maximum combined channels", func() {
+			fakeExec := &execTesting.FakeExec{}
+			fakeCmd := &execTesting.FakeCmd{
+				OutputScript: []execTesting.FakeAction{
+					func() ([]byte, []byte, error) {
+						return []byte("Channel parameters for eth0:\nPre-set maximums:\nRX:\t\tn/a\nTX:\t\tn/a\nOther:\t\tn/a\n" +
+							"Combined:\t63\nCurrent hardware settings:\nRX:\t\tn/a\nTX:\t\tn/a\nOther:\t\tn/a\nCombined:\t8\n"), nil, nil
+					},
+				},
+			}
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				Expect(cmd).To(Equal("ethtool"))
+				Expect(args).To(Equal([]string{"-l", "eth0"}))
+				return fakeCmd
+			})
+
+			channels, err := (&hostUtils{execInterface: fakeExec}).GetChannels("eth0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(channels).To(Equal(types.Channels{Combined: 8, CombinedMax: 63}))
+		})
+		It("should return an error if the output doesn't contain the combined channels", func() {
+			fakeExec := &execTesting.FakeExec{}
+			fakeCmd := &execTesting.FakeCmd{
+				OutputScript: []execTesting.FakeAction{
+					func() ([]byte, []byte, error) {
+						return []byte("Channel parameters for eth0:\n"), nil, nil
+					},
+				},
+			}
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				return fakeCmd
+			})
+
+			_, err := (&hostUtils{execInterface: fakeExec}).GetChannels("eth0")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Describe("SetCombinedChannels", func() {
+		It("should set the combined channels", func() {
+			fakeExec := &execTesting.FakeExec{}
+			fakeCmd := &execTesting.FakeCmd{
+				CombinedOutputScript: []execTesting.FakeAction{
+					func() ([]byte, []byte, error) {
+						return nil, nil, nil
+					},
+				},
+			}
+			fakeExec.CommandScript = append(fakeExec.CommandScript, func(cmd string, args ...string) exec.Cmd {
+				Expect(cmd).To(Equal("ethtool"))
+				Expect(args).To(Equal([]string{"-L", "eth0", "combined", "16"}))
+				return fakeCmd
+			})
+
+			Expect((&hostUtils{execInterface: fakeExec}).SetCombinedChannels("eth0", 16)).To(Succeed())
+		})
+	})
+	Describe("GetNumaNodeCPUCount", func() {
+		var devicePath string
+
+		BeforeEach(func() {
+			sysfs := GinkgoT().TempDir()
+			originalPath := pciDevicesPath
+			pciDevicesPath = sysfs
+			DeferCleanup(func() { pciDevicesPath = originalPath })
+
+			devicePath = filepath.Join(sysfs, "0000:3b:00.0")
+			Expect(os.MkdirAll(devicePath, 0755)).To(Succeed())
+		})
+
+		It("should count the CPUs of the local CPU list", func() {
+			Expect(os.WriteFile(filepath.Join(devicePath, "numa_node"), []byte("0\n"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(devicePath, "local_cpulist"), []byte("0-15,32-47,64\n"), 0644)).To(Succeed())
+
+			count, err := (&hostUtils{}).GetNumaNodeCPUCount("0000:3b:00.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(count).To(Equal(33))
+		})
+		It("should return 0 for the device without the NUMA affinity", func() {
+			Expect(os.WriteFile(filepath.Join(devicePath, "numa_node"), []byte("-1\n"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(devicePath, "local_cpulist"), []byte("0-63\n"), 0644)).To(Succeed())
+
+			count, err := (&hostUtils{}).GetNumaNodeCPUCount("0000:3b:00.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(count).To(BeZero())
+		})
+		It("should return an error for the malformed CPU list", func() {
+			Expect(os.WriteFile(filepath.Join(devicePath, "numa_node"), []byte("0\n"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(devicePath, "local_cpulist"), []byte("15-0\n"), 0644)).To(Succeed())
+
+			_, err := (&hostUtils{}).GetNumaNodeCPUCount("0000:3b:00.0")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Describe("sysctls", func() {
+		BeforeEach(func() {
+			procSys := GinkgoT().TempDir()
+			originalPath := procSysPath
+			procSysPath = procSys
+			DeferCleanup(func() { procSysPath = originalPath })
+
+			Expect(os.MkdirAll(filepath.Join(procSys, "net", "ipv4", "conf", "eth0.100"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(procSys, "net", "ipv4", "tcp_rmem"), []byte("4096\t131072\t6291456\n"), 0644)).To(Succeed())
+		})
+
+		It("should read the sysctl with normalized whitespace", func() {
+			value, err := (&hostUtils{}).GetSysctl("net.ipv4.tcp_rmem")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(value).To(Equal("4096 131072 6291456"))
+		})
+		It("should write the sysctl of the interface with dots in the name", func() {
+			Expect((&hostUtils{}).SetSysctl("net.ipv4.conf.eth0/100.arp_announce", "2")).To(Succeed())
+
+			value, err := os.ReadFile(filepath.Join(procSysPath, "net", "ipv4", "conf", "eth0.100", "arp_announce"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(value)).To(Equal("2"))
+		})
+		It("should return an error for the unknown sysctl", func() {
+			_, err := (&hostUtils{}).GetSysctl("net.ipv4.unknown")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Describe("GetPCILinkStatus", func() {
+		var devicePath string
+
+		BeforeEach(func() {
+			sysfs := GinkgoT().TempDir()
+			originalPath := pciDevicesPath
+			pciDevicesPath = sysfs
+			DeferCleanup(func() { pciDevicesPath = originalPath })
+
+			devicePath = filepath.Join(sysfs, "0000:3b:00.0")
+			Expect(os.MkdirAll(devicePath, 0755)).To(Succeed())
+		})
+
+		It("should return the negotiated and supported link speed and width", func() {
+			Expect(os.WriteFile(filepath.Join(devicePath, "current_link_speed"), []byte("8.0 GT/s PCIe\n"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(devicePath, "current_link_width"), []byte("8\n"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(devicePath, "max_link_speed"), []byte("16.0 GT/s PCIe\n"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(devicePath, "max_link_width"), []byte("16\n"), 0644)).To(Succeed())
+
+			status, err := (&hostUtils{}).GetPCILinkStatus("0000:3b:00.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status).To(Equal(&types.PCILinkStatus{
+				Speed: "8.0 GT/s PCIe", Width: 8, MaxSpeed: "16.0 GT/s PCIe", MaxWidth: 16,
+			}))
+		})
+		It("should return nil if the kernel doesn't report the link", func() {
+			status, err := (&hostUtils{}).GetPCILinkStatus("0000:3b:00.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status).To(BeNil())
+		})
+		It("should return an error if the link width can't be parsed", func() {
+			Expect(os.WriteFile(filepath.Join(devicePath, "current_link_speed"), []byte("8.0 GT/s PCIe\n"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(devicePath, "max_link_speed"), []byte("16.0 GT/s PCIe\n"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(devicePath, "current_link_width"), []byte("x8\n"), 0644)).To(Succeed())
+
+			_, err := (&hostUtils{}).GetPCILinkStatus("0000:3b:00.0")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Describe("GetRdmaPort", func() {
+		var sysfs string
+
+		BeforeEach(func() {
+			sysfs = GinkgoT().TempDir()
+			originalPath := infinibandDevicesPath
+			infinibandDevicesPath = sysfs
+			DeferCleanup(func() { infinibandDevicesPath = originalPath })
+		})
+
+		writeRdmaPort := func(rdmaDevice string, linkLayer string) string {
+			portPath := filepath.Join(sysfs, rdmaDevice, "ports", "1")
+			Expect(os.MkdirAll(filepath.Join(portPath, "gids"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(sysfs, rdmaDevice, "node_guid"), []byte("0c42:a103:0016:054c\n"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(portPath, "link_layer"), []byte(linkLayer+"\n"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(portPath, "state"), []byte("4: ACTIVE\n"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(portPath, "phys_state"), []byte("5: LinkUp\n"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(portPath, "rate"), []byte("200 Gb/sec (4X HDR)\n"), 0644)).To(Succeed())
+			return portPath
+		}
+
+		It("should return the GUIDs and the link of the InfiniBand port", func() {
+			portPath := writeRdmaPort("mlx5_0", "InfiniBand")
+			Expect(os.WriteFile(filepath.Join(portPath, "gids", "0"), []byte("fe80:0000:0000:0000:0c42:a103:0016:054d\n"), 0644)).To(Succeed())
+
+			port, err := (&hostUtils{}).GetRdmaPort("mlx5_0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(port).To(Equal(&types.RdmaPort{
+				LinkLayer:     "InfiniBand",
+				NodeGUID:      "0c42:a103:0016:054c",
+				PortGUID:      "0c42:a103:0016:054d",
+				State:         "ACTIVE",
+				PhysicalState: "LinkUp",
+				Rate:          "200 Gb/sec (4X HDR)",
+			}))
+		})
+		It("should not read the port GUID of the Ethernet port", func() {
+			writeRdmaPort("mlx5_0", "Ethernet")
+
+			port, err := (&hostUtils{}).GetRdmaPort("mlx5_0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(port.LinkLayer).To(Equal("Ethernet"))
+			Expect(port.PortGUID).To(BeEmpty())
+		})
+		It("should return nil if the RDMA device doesn't exist", func() {
+			port, err := (&hostUtils{}).GetRdmaPort("mlx5_0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(port).To(BeNil())
+		})
+		It("should return an error if the GID can't be parsed", func() {
+			portPath := writeRdmaPort("mlx5_0", "InfiniBand")
+			Expect(os.WriteFile(filepath.Join(portPath, "gids", "0"), []byte("fe80::1\n"), 0644)).To(Succeed())
+
+			_, err := (&hostUtils{}).GetRdmaPort("mlx5_0")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Describe("GetPCITopology", func() {
+		var sysfs string
+
+		BeforeEach(func() {
+			sysfs = GinkgoT().TempDir()
+			originalPath := pciDevicesPath
+			pciDevicesPath = filepath.Join(sysfs, "bus")
+			DeferCleanup(func() { pciDevicesPath = originalPath })
+
+			Expect(os.MkdirAll(pciDevicesPath, 0755)).To(Succeed())
+		})
+
+		It("should return the NUMA node, the local CPUs and the root complex of the device", func() {
+			devicePath := filepath.Join(sysfs, "devices", "pci0000:3a", "0000:3a:00.0", "0000:3b:00.0")
+			Expect(os.MkdirAll(devicePath, 0755)).To(Succeed())
+			Expect(os.Symlink(devicePath, filepath.Join(pciDevicesPath, "0000:3b:00.0"))).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(devicePath, "numa_node"), []byte("1\n"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(devicePath, "local_cpulist"), []byte("16-31,48-63\n"), 0644)).To(Succeed())
+
+			topology, err := (&hostUtils{}).GetPCITopology("0000:3b:00.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(topology).To(Equal(&types.PCITopology{NumaNode: 1, LocalCPUs: "16-31,48-63", RootComplex: "pci0000:3a"}))
+		})
+		It("should report no NUMA node if the platform doesn't have one", func() {
+			devicePath := filepath.Join(sysfs, "devices", "pci0000:00", "0000:00:03.0")
+			Expect(os.MkdirAll(devicePath, 0755)).To(Succeed())
+			Expect(os.Symlink(devicePath, filepath.Join(pciDevicesPath, "0000:00:03.0"))).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(devicePath, "numa_node"), []byte("-1\n"), 0644)).To(Succeed())
+
+			topology, err := (&hostUtils{}).GetPCITopology("0000:00:03.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(topology).To(Equal(&types.PCITopology{NumaNode: -1, RootComplex: "pci0000:00"}))
+		})
+		It("should return an error if the device doesn't exist", func() {
+			_, err := (&hostUtils{}).GetPCITopology("0000:3b:00.0")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Describe("GetPCIDeviceSysfsState", func() {
+		It("should return the readable attributes and the bound driver", func() {
+			sysfs := GinkgoT().TempDir()
+			originalPath := pciDevicesPath
+			pciDevicesPath = sysfs
+			DeferCleanup(func() { pciDevicesPath = originalPath })
+
+			devicePath := filepath.Join(sysfs, "0000:3b:00.0")
+			Expect(os.MkdirAll(devicePath, 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(devicePath, "current_link_speed"), []byte("16.0 GT/s PCIe\n"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(devicePath, "sriov_numvfs"), []byte("8\n"), 0644)).To(Succeed())
+			Expect(os.Symlink("../../../../bus/pci/drivers/mlx5_core", filepath.Join(devicePath, "driver"))).To(Succeed())
+
+			h := &hostUtils{}
+			Expect(h.GetPCIDeviceSysfsState("0000:3b:00.0")).To(Equal(map[string]string{
+				"current_link_speed": "16.0 GT/s PCIe",
+				"sriov_numvfs":       "8",
+				"driver":             "mlx5_core",
+			}))
+			Expect(h.GetPCIDeviceSysfsState("0000:af:00.0")).To(BeEmpty())
+		})
+	})
 })
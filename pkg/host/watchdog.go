@@ -0,0 +1,427 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package host
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	osexec "os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	execUtils "k8s.io/utils/exec"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Mellanox/nic-configuration-operator/pkg/types"
+)
+
+// hostToolTimeout is a hard ceiling for a single run of a host tool (mstconfig, mlxfwreset, etc.)
+// even the long operations, like FW reset on IB devices, are expected to finish well before it
+// firmware burns are exempt from it, see UntimedCommand
+var hostToolTimeout = 10 * time.Minute
+
+// maxRecordedToolFailures limits the number of the recent tool failures kept for the diagnostics, the oldest are dropped
+var maxRecordedToolFailures = 32
+
+// toolFailureStderrLimit is the number of the last bytes of the error output kept for a failed tool
+const toolFailureStderrLimit = 512
+
+// sensitiveArgumentRegex matches the names of the tool arguments whose values are redacted in the recorded failures
+var sensitiveArgumentRegex = regexp.MustCompile(`(?i)(password|passwd|secret|token|credential)`)
+
+const redactedValue = "<redacted>"
+
+// trackedProcess describes a running host tool process
+type trackedProcess struct {
+	command string
+	started time.Time
+}
+
+// recordedFailure is a failed tool run with its sanitized arguments
+type recordedFailure struct {
+	args    []string
+	failure types.ToolFailure
+}
+
+// hostToolWatchdog runs the host tools that are exempt from the hard ceiling and reports the recent failed runs,
+// it's implemented by toolWatchdog, which is also the execUtils.Interface of the host tools
+type hostToolWatchdog interface {
+	UntimedCommand(cmd string, args ...string) execUtils.Cmd
+	toolFailures(since time.Time, identifiers []string) []types.ToolFailure
+}
+
+// toolWatchdog is an execUtils.Interface implementation that tracks spawned host tool processes
+// and kills the whole process group of tools that exceed the hard ceiling
+// the recent failed runs are recorded for the diagnostics of the failed operations
+type toolWatchdog struct {
+	timeout time.Duration
+
+	lock      sync.Mutex
+	processes map[int]trackedProcess
+	failures  []recordedFailure
+}
+
+func newToolWatchdog(timeout time.Duration) *toolWatchdog {
+	return &toolWatchdog{timeout: timeout, processes: map[int]trackedProcess{}}
+}
+
+// Command returns a Cmd which runs in its own process group and is killed after the watchdog's timeout
+func (w *toolWatchdog) Command(cmd string, args ...string) execUtils.Cmd {
+	return w.newCmd(osexec.Command(cmd, args...))
+}
+
+// CommandContext returns a Cmd which runs in its own process group and is killed after the watchdog's timeout
+// or when the context becomes done, whichever happens first
+func (w *toolWatchdog) CommandContext(ctx context.Context, cmd string, args ...string) execUtils.Cmd {
+	c := w.newCmd(osexec.CommandContext(ctx, cmd, args...))
+	c.cmd.Cancel = c.killProcessGroup
+	return c
+}
+
+// UntimedCommand returns a Cmd which runs in its own process group and is never killed by the watchdog or a context,
+// it's used for the firmware burns: killing an in-progress burn can leave the flash of the device in an unknown state,
+// while the burn time depends on the flash and the image size. The command is still tracked and its failures are recorded
+func (w *toolWatchdog) UntimedCommand(cmd string, args ...string) execUtils.Cmd {
+	c := w.newCmd(osexec.Command(cmd, args...))
+	c.untimed = true
+	return c
+}
+
+// LookPath wraps os/exec.LookPath
+func (w *toolWatchdog) LookPath(file string) (string, error) {
+	return osexec.LookPath(file)
+}
+
+func (w *toolWatchdog) newCmd(cmd *osexec.Cmd) *watchdogCmd {
+	c := &watchdogCmd{cmd: cmd, watchdog: w}
+	// Tools like mlxfwreset spawn child processes, the whole group needs to be killed to free the device
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return c
+}
+
+// runningTools returns the commands of the currently running host tools with their run time
+func (w *toolWatchdog) runningTools() map[string]time.Duration {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	tools := map[string]time.Duration{}
+	for _, process := range w.processes {
+		tools[process.command] = time.Since(process.started)
+	}
+	return tools
+}
+
+func (w *toolWatchdog) track(pid int, command string) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.processes[pid] = trackedProcess{command: command, started: time.Now()}
+}
+
+func (w *toolWatchdog) untrack(pid int) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	delete(w.processes, pid)
+}
+
+func (w *toolWatchdog) recordFailure(args []string, exitCode int, stderr []byte) {
+	if len(stderr) > toolFailureStderrLimit {
+		stderr = stderr[len(stderr)-toolFailureStderrLimit:]
+	}
+	args = sanitizeArgs(args)
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.failures = append(w.failures, recordedFailure{args: args, failure: types.ToolFailure{
+		Command:  strings.Join(args, " "),
+		ExitCode: exitCode,
+		Stderr:   strings.TrimSpace(string(stderr)),
+		Time:     time.Now(),
+	}})
+	if len(w.failures) > maxRecordedToolFailures {
+		w.failures = w.failures[len(w.failures)-maxRecordedToolFailures:]
+	}
+}
+
+// toolFailures returns the tool failures recorded after the given time, oldest first
+// only the runs with one of the identifiers, e.g. a PCI address or a network interface, as an argument are returned
+func (w *toolWatchdog) toolFailures(since time.Time, identifiers []string) []types.ToolFailure {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	failures := []types.ToolFailure{}
+	for _, recorded := range w.failures {
+		if recorded.failure.Time.Before(since) {
+			continue
+		}
+		if argsReference(recorded.args, identifiers) {
+			failures = append(failures, recorded.failure)
+		}
+	}
+	return failures
+}
+
+// argsReference returns true if one of the arguments is one of the identifiers, e.g. 0000:3b:00.0 or pci/0000:3b:00.0
+func argsReference(args []string, identifiers []string) bool {
+	for _, arg := range args {
+		for _, identifier := range identifiers {
+			if identifier != "" && (arg == identifier || strings.HasSuffix(arg, "/"+identifier)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sanitizeArgs redacts the values of the sensitive arguments, e.g. --password <value> or token=<value>,
+// and the user info of the urls
+func sanitizeArgs(args []string) []string {
+	sanitized := make([]string, len(args))
+	for i, arg := range args {
+		name, _, found := strings.Cut(arg, "=")
+		switch {
+		case i > 0 && strings.HasPrefix(args[i-1], "-") && !strings.Contains(args[i-1], "=") && sensitiveArgumentRegex.MatchString(args[i-1]):
+			arg = redactedValue
+		case found && sensitiveArgumentRegex.MatchString(name):
+			arg = name + "=" + redactedValue
+		case strings.Contains(arg, "://"):
+			parsed, err := url.Parse(arg)
+			if err == nil && parsed.User != nil {
+				parsed.User = url.User(redactedValue)
+				arg = parsed.String()
+			}
+		}
+		sanitized[i] = arg
+	}
+	return sanitized
+}
+
+// watchdogCmd is an execUtils.Cmd implementation supervised by the toolWatchdog
+type watchdogCmd struct {
+	cmd      *osexec.Cmd
+	watchdog *toolWatchdog
+
+	// untimed commands are not killed after the watchdog's timeout
+	untimed bool
+	timer   *time.Timer
+	lock    sync.Mutex
+	hung    bool
+	// errOutput captures the error output of the command, recorded if the command fails
+	errOutput outputBuffer
+}
+
+// outputBuffer is implemented by the writers keeping the output of the command, e.g. bytes.Buffer
+type outputBuffer interface {
+	Bytes() []byte
+}
+
+func (c *watchdogCmd) command() string {
+	return strings.Join(c.cmd.Args, " ")
+}
+
+func (c *watchdogCmd) killProcessGroup() error {
+	if c.cmd.Process == nil {
+		return nil
+	}
+	// Negative pid sends the signal to the whole process group
+	return syscall.Kill(-c.cmd.Process.Pid, syscall.SIGKILL)
+}
+
+func (c *watchdogCmd) onTimeout() {
+	c.lock.Lock()
+	c.hung = true
+	c.lock.Unlock()
+
+	log.Log.Error(nil, "host tool exceeded the hard ceiling, killing its process group",
+		"command", c.command(), "timeout", c.watchdog.timeout)
+	err := c.killProcessGroup()
+	if err != nil {
+		log.Log.Error(err, "failed to kill the process group of the host tool", "command", c.command())
+	}
+}
+
+// Start starts the command and the watchdog timer
+// returns an error without starting the command if the config daemon lacks capabilities required by the tool
+func (c *watchdogCmd) Start() error {
+	err := checkCapabilities(c.cmd.Path, c.cmd.Args[1:]...)
+	if err != nil {
+		return err
+	}
+
+	err = c.cmd.Start()
+	if err != nil {
+		c.watchdog.recordFailure(c.cmd.Args, -1, []byte(err.Error()))
+		return handleExecError(err)
+	}
+
+	c.watchdog.track(c.cmd.Process.Pid, c.command())
+	if !c.untimed {
+		c.timer = time.AfterFunc(c.watchdog.timeout, c.onTimeout)
+	}
+
+	return nil
+}
+
+// Wait waits for the command to exit
+// returns types.ToolHangError if the command was killed by the watchdog
+func (c *watchdogCmd) Wait() error {
+	err := c.cmd.Wait()
+
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	if c.cmd.Process != nil {
+		c.watchdog.untrack(c.cmd.Process.Pid)
+	}
+
+	c.lock.Lock()
+	hung := c.hung
+	c.lock.Unlock()
+	if hung {
+		err = types.ToolHangError(fmt.Sprintf("%s didn't finish in %s and was killed", c.command(), c.watchdog.timeout))
+		c.watchdog.recordFailure(c.cmd.Args, -1, []byte(err.Error()))
+		return err
+	}
+
+	if err != nil {
+		exitCode := -1
+		var exitErr *osexec.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.ExitCode()
+		}
+		var stderr []byte
+		if c.errOutput != nil {
+			stderr = c.errOutput.Bytes()
+		}
+		c.watchdog.recordFailure(c.cmd.Args, exitCode, stderr)
+	}
+
+	return handleExecError(err)
+}
+
+// Run runs the command to the completion
+func (c *watchdogCmd) Run() error {
+	err := c.Start()
+	if err != nil {
+		return err
+	}
+	return c.Wait()
+}
+
+// CombinedOutput runs the command and returns its combined standard output and standard error
+func (c *watchdogCmd) CombinedOutput() ([]byte, error) {
+	var b bytes.Buffer
+	c.cmd.Stdout = &b
+	c.cmd.Stderr = &b
+	c.errOutput = &b
+	err := c.Run()
+	return b.Bytes(), err
+}
+
+// Output runs the command and returns standard output
+func (c *watchdogCmd) Output() ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	c.cmd.Stdout = &stdout
+	if c.cmd.Stderr == nil {
+		c.cmd.Stderr = &stderr
+		c.errOutput = &stderr
+	}
+
+	err := c.Run()
+
+	var exitErr *execUtils.ExitErrorWrapper
+	if errors.As(err, &exitErr) && exitErr.ExitError != nil {
+		exitErr.ExitError.Stderr = stderr.Bytes()
+	}
+
+	return stdout.Bytes(), err
+}
+
+func (c *watchdogCmd) SetDir(dir string) {
+	c.cmd.Dir = dir
+}
+
+func (c *watchdogCmd) SetStdin(in io.Reader) {
+	c.cmd.Stdin = in
+}
+
+func (c *watchdogCmd) SetStdout(out io.Writer) {
+	c.cmd.Stdout = out
+}
+
+func (c *watchdogCmd) SetStderr(out io.Writer) {
+	c.cmd.Stderr = out
+	if buffer, ok := out.(outputBuffer); ok {
+		c.errOutput = buffer
+	}
+}
+
+func (c *watchdogCmd) SetEnv(env []string) {
+	c.cmd.Env = env
+}
+
+func (c *watchdogCmd) StdoutPipe() (io.ReadCloser, error) {
+	return c.cmd.StdoutPipe()
+}
+
+func (c *watchdogCmd) StderrPipe() (io.ReadCloser, error) {
+	return c.cmd.StderrPipe()
+}
+
+// Stop kills the process group of the command
+func (c *watchdogCmd) Stop() {
+	err := c.killProcessGroup()
+	if err != nil {
+		log.Log.Error(err, "failed to stop the host tool", "command", c.command())
+	}
+}
+
+// exitErrorOutput returns the standard error of the host tool that failed in Output(), which is kept in its exit error
+func exitErrorOutput(err error) []byte {
+	var exitErr *execUtils.ExitErrorWrapper
+	if errors.As(err, &exitErr) && exitErr.ExitError != nil {
+		return exitErr.Stderr
+	}
+	return nil
+}
+
+// handleExecError converts os/exec errors the same way the default execUtils implementation does
+func handleExecError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var exitErr *osexec.ExitError
+	var pathErr *fs.PathError
+	var execErr *osexec.Error
+	switch {
+	case errors.As(err, &exitErr):
+		return &execUtils.ExitErrorWrapper{ExitError: exitErr}
+	case errors.As(err, &pathErr):
+		return execUtils.ErrExecutableNotFound
+	case errors.As(err, &execErr) && errors.Is(execErr.Err, osexec.ErrNotFound):
+		return execUtils.ErrExecutableNotFound
+	}
+
+	return err
+}
@@ -0,0 +1,76 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lifecycle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+type httpSink struct {
+	endpoint string
+	client   *http.Client
+}
+
+// NewHTTPSink creates a sink posting each event to the HTTP endpoint in the CloudEvents structured content mode
+func NewHTTPSink(endpoint string) (Sink, error) {
+	if endpoint == "" {
+		return nil, errors.New("endpoint is required for the http lifecycle events sink")
+	}
+	parsed, err := url.Parse(endpoint)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return nil, fmt.Errorf("invalid http lifecycle events endpoint %q", endpoint)
+	}
+
+	return &httpSink{endpoint: endpoint, client: &http.Client{Timeout: sinkRequestTimeout}}, nil
+}
+
+// Send posts the event to the endpoint
+func (s *httpSink) Send(ctx context.Context, event Event) error {
+	cloudEvent, err := toCloudEvent(event)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(cloudEvent)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", cloudEventsContentType)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to post lifecycle event, status %d: %s", resp.StatusCode, message)
+	}
+
+	return nil
+}
@@ -0,0 +1,246 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package lifecycle publishes the configuration lifecycle events of the devices as CloudEvents,
+// so that event-driven automation can react to them without polling the Kubernetes API
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2/event"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+)
+
+const (
+	// SinkHTTP posts each event in the CloudEvents structured JSON format to an HTTP endpoint
+	SinkHTTP = "http"
+	// SinkNATS publishes each event in the CloudEvents structured JSON format to a NATS subject
+	SinkNATS = "nats"
+)
+
+// Types of the lifecycle events
+const (
+	// DeviceDiscoveredEvent is emitted when the config daemon finds a device on the host
+	DeviceDiscoveredEvent = "com.nvidia.nic-configuration.device.discovered"
+	// ApplyStartedEvent is emitted when the configuration of the device starts to be applied
+	ApplyStartedEvent = "com.nvidia.nic-configuration.apply.started"
+	// ApplyFinishedEvent is emitted when the configuration of the device is applied or fails, the reason tells the outcome
+	ApplyFinishedEvent = "com.nvidia.nic-configuration.apply.finished"
+	// RebootRequestedEvent is emitted before the node is rebooted to activate the nv config of the device
+	RebootRequestedEvent = "com.nvidia.nic-configuration.reboot.requested"
+	// RebootCompletedEvent is emitted when the nv config of the device was activated by the reboot
+	RebootCompletedEvent = "com.nvidia.nic-configuration.reboot.completed"
+	// DriftDetectedEvent is emitted when the state of the configured device no longer matches its spec
+	DriftDetectedEvent = "com.nvidia.nic-configuration.drift.detected"
+)
+
+// Reasons of the drift events
+const (
+	// NvConfigDriftReason is set when the nv config or the firmware of the device no longer match the spec
+	NvConfigDriftReason = "NvConfigDrifted"
+	// RuntimeConfigDriftReason is set when the runtime settings of the device were changed on the host and are applied again
+	RuntimeConfigDriftReason = "RuntimeConfigDrifted"
+)
+
+const (
+	cloudEventsSpecVersion = "1.0"
+	cloudEventsContentType = "application/cloudevents+json"
+	sinkRequestTimeout     = 10 * time.Second
+	// sendAttempts is the number of the delivery attempts of an event before it is dropped
+	sendAttempts = 5
+)
+
+var (
+	// eventQueueSize is the number of the events waiting for the delivery, new events are dropped while the queue is full
+	eventQueueSize = 256
+	// sendRetryInterval is the interval before the second delivery attempt of an event, doubled after each failed attempt
+	sendRetryInterval = time.Second
+	// sendMaxRetryInterval limits the interval between the delivery attempts of an event
+	sendMaxRetryInterval = time.Minute
+)
+
+// EventData is the payload of the lifecycle events
+type EventData struct {
+	// Node where the device is located
+	Node string `json:"node"`
+	// Name of the NicDevice CR
+	Device string `json:"device"`
+	// Serial number of the device
+	SerialNumber string `json:"serialNumber,omitempty"`
+	// Part number of the device
+	PartNumber string `json:"partNumber,omitempty"`
+	// Firmware version of the device
+	FirmwareVersion string `json:"firmwareVersion,omitempty"`
+	// Generation of the NicDevice spec the event refers to
+	Generation int64 `json:"generation,omitempty"`
+	// Reason of the event, e.g. the reason of the ConfigUpdateInProgress condition
+	Reason string `json:"reason,omitempty"`
+	// Human-readable details of the event
+	Message string `json:"message,omitempty"`
+}
+
+// Event is a CloudEvents 1.0 event in the structured JSON format
+type Event struct {
+	SpecVersion     string    `json:"specversion"`
+	ID              string    `json:"id"`
+	Source          string    `json:"source"`
+	Type            string    `json:"type"`
+	Subject         string    `json:"subject,omitempty"`
+	Time            time.Time `json:"time"`
+	DataContentType string    `json:"datacontenttype"`
+	Data            EventData `json:"data"`
+}
+
+// toCloudEvent converts the event to the CloudEvents SDK event and validates it against the CloudEvents spec
+func toCloudEvent(event Event) (cloudevents.Event, error) {
+	cloudEvent := cloudevents.New(event.SpecVersion)
+	cloudEvent.SetID(event.ID)
+	cloudEvent.SetSource(event.Source)
+	cloudEvent.SetType(event.Type)
+	cloudEvent.SetSubject(event.Subject)
+	cloudEvent.SetTime(event.Time)
+	err := cloudEvent.SetData(event.DataContentType, event.Data)
+	if err != nil {
+		return cloudevents.Event{}, fmt.Errorf("failed to encode the data of lifecycle event %s: %w", event.ID, err)
+	}
+	err = cloudEvent.Validate()
+	if err != nil {
+		return cloudevents.Event{}, fmt.Errorf("invalid lifecycle event %s: %w", event.ID, err)
+	}
+	return cloudEvent, nil
+}
+
+// Sink delivers the lifecycle events
+type Sink interface {
+	// Send delivers a single event
+	Send(ctx context.Context, event Event) error
+}
+
+// Config describes the lifecycle events sink of the config daemon
+type Config struct {
+	// Sink type: http or nats, lifecycle events are disabled if empty
+	Sink string
+	// Endpoint of the sink, e.g. http://event-gateway.automation:8080/events, nats://nats.automation:4222 or tls://nats.automation:4222
+	Endpoint string
+	// Subject of the NATS messages, defaults to nic-configuration.events
+	Subject string
+	// CredentialsDir is the directory of the mounted Secret with the NATS credentials and TLS files, see the Credentials*Key constants
+	CredentialsDir string
+}
+
+// NewSink creates a lifecycle events sink according to the config
+// returns nil if the lifecycle events are disabled
+func NewSink(config Config) (Sink, error) {
+	switch config.Sink {
+	case "":
+		return nil, nil
+	case SinkHTTP:
+		return NewHTTPSink(config.Endpoint)
+	case SinkNATS:
+		return NewNATSSink(config.Endpoint, config.Subject, config.CredentialsDir)
+	}
+
+	return nil, fmt.Errorf("unsupported lifecycle events sink %q", config.Sink)
+}
+
+// Emitter builds the lifecycle events of the node's devices and sends them to the sink in the background
+// a nil Emitter doesn't emit any events
+type Emitter struct {
+	sink     Sink
+	nodeName string
+	now      func() time.Time
+	// queue holds the events waiting for the delivery, they are sent in the order they were emitted
+	queue chan Event
+}
+
+// NewEmitter creates an Emitter for the devices of the node and starts delivering its events, returns nil if the sink is nil
+func NewEmitter(sink Sink, nodeName string) *Emitter {
+	if sink == nil {
+		return nil
+	}
+	e := &Emitter{sink: sink, nodeName: nodeName, now: time.Now, queue: make(chan Event, eventQueueSize)}
+	go e.run()
+	return e
+}
+
+// run delivers the queued events for the lifetime of the config daemon
+func (e *Emitter) run() {
+	for event := range e.queue {
+		e.send(event)
+	}
+}
+
+// send delivers the event, retrying with a growing interval, the event is dropped after sendAttempts failed attempts
+// the following events wait for the delivery of the event, so that they reach the sink in order
+func (e *Emitter) send(event Event) {
+	interval := sendRetryInterval
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), sinkRequestTimeout)
+		err := e.sink.Send(ctx, event)
+		cancel()
+		if err == nil {
+			return
+		}
+		if attempt == sendAttempts {
+			log.Log.Error(err, "failed to send lifecycle event, dropping it", "type", event.Type, "device", event.Subject, "attempts", attempt)
+			return
+		}
+
+		log.Log.V(2).Info("failed to send lifecycle event, retrying", "type", event.Type, "device", event.Subject, "reason", err.Error())
+		time.Sleep(interval)
+		interval = min(2*interval, sendMaxRetryInterval)
+	}
+}
+
+// Emit queues the lifecycle event of the device for the delivery
+// the event is dropped if the queue is full, failures to deliver the event are logged and don't affect the configuration flow
+func (e *Emitter) Emit(_ context.Context, eventType string, device *v1alpha1.NicDevice, reason string, message string) {
+	if e == nil {
+		return
+	}
+
+	event := Event{
+		SpecVersion:     cloudEventsSpecVersion,
+		ID:              string(uuid.NewUUID()),
+		Source:          "/nic-configuration-operator/nodes/" + e.nodeName,
+		Type:            eventType,
+		Subject:         device.Name,
+		Time:            e.now().UTC(),
+		DataContentType: "application/json",
+		Data: EventData{
+			Node:            e.nodeName,
+			Device:          device.Name,
+			SerialNumber:    device.Status.SerialNumber,
+			PartNumber:      device.Status.PartNumber,
+			FirmwareVersion: device.Status.FirmwareVersion,
+			Generation:      device.Generation,
+			Reason:          reason,
+			Message:         message,
+		},
+	}
+
+	select {
+	case e.queue <- event:
+	default:
+		log.Log.Error(errors.New("lifecycle events queue is full"), "failed to send lifecycle event, dropping it", "type", eventType, "device", device.Name)
+	}
+}
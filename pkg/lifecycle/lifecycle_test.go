@@ -0,0 +1,376 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lifecycle
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+)
+
+type recordingSink struct {
+	lock   sync.Mutex
+	events []Event
+	// failures is the number of the first delivery attempts failing with err, all of them fail if negative
+	failures int
+	err      error
+}
+
+func (s *recordingSink) Send(_ context.Context, event Event) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.events = append(s.events, event)
+	if s.failures == 0 {
+		return nil
+	}
+	s.failures--
+	return s.err
+}
+
+func (s *recordingSink) sent() []Event {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]Event{}, s.events...)
+}
+
+// fakeNatsServer accepts a single client connection, records its CONNECT options and published messages
+// and replies to PING with the given response
+type fakeNatsServer struct {
+	listener net.Listener
+	connect  chan string
+	messages chan string
+}
+
+func newFakeNatsServer(response string) *fakeNatsServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).NotTo(HaveOccurred())
+	DeferCleanup(listener.Close)
+
+	server := &fakeNatsServer{listener: listener, connect: make(chan string, 1), messages: make(chan string, 2)}
+	go func() {
+		defer GinkgoRecover()
+
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		_, _ = conn.Write([]byte(`INFO {"server_id":"fake","max_payload":1048576}` + "\r\n"))
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			switch fields[0] {
+			case "CONNECT":
+				server.connect <- strings.TrimSpace(strings.TrimPrefix(line, "CONNECT"))
+			case "PUB":
+				size, _ := strconv.Atoi(fields[2])
+				payload := make([]byte, size+2)
+				_, _ = io.ReadFull(reader, payload)
+				server.messages <- fields[1] + " " + string(payload[:size])
+			case "PING":
+				_, _ = conn.Write([]byte(response + "\r\n"))
+			}
+		}
+	}()
+
+	return server
+}
+
+func (s *fakeNatsServer) endpoint(credentials string) string {
+	return "nats://" + credentials + s.listener.Addr().String()
+}
+
+var _ = Describe("Lifecycle events", func() {
+	var (
+		ctx    context.Context
+		device *v1alpha1.NicDevice
+		event  Event
+	)
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		device = &v1alpha1.NicDevice{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-1-101d-sn1", Generation: 3},
+			Status: v1alpha1.NicDeviceStatus{
+				SerialNumber:    "sn1",
+				PartNumber:      "pn1",
+				FirmwareVersion: "22.42.1000",
+			},
+		}
+		event = Event{
+			SpecVersion:     "1.0",
+			ID:              "id-1",
+			Source:          "/nic-configuration-operator/nodes/node-1",
+			Type:            ApplyStartedEvent,
+			Subject:         "node-1-101d-sn1",
+			Time:            time.Date(2024, 10, 14, 11, 3, 27, 0, time.UTC),
+			DataContentType: "application/json",
+			Data:            EventData{Node: "node-1", Device: "node-1-101d-sn1"},
+		}
+	})
+
+	Describe("NewSink", func() {
+		It("should return nil if the lifecycle events are disabled", func() {
+			sink, err := NewSink(Config{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sink).To(BeNil())
+			Expect(NewEmitter(sink, "node-1")).To(BeNil())
+		})
+
+		It("should return error for an unsupported sink", func() {
+			_, err := NewSink(Config{Sink: "kafka"})
+			Expect(err).To(MatchError(ContainSubstring("unsupported lifecycle events sink")))
+		})
+
+		It("should validate the endpoints", func() {
+			_, err := NewSink(Config{Sink: SinkHTTP})
+			Expect(err).To(MatchError(ContainSubstring("endpoint is required")))
+			_, err = NewSink(Config{Sink: SinkHTTP, Endpoint: "nats://nats:4222"})
+			Expect(err).To(MatchError(ContainSubstring("invalid http lifecycle events endpoint")))
+			_, err = NewSink(Config{Sink: SinkNATS, Endpoint: "http://nats:4222"})
+			Expect(err).To(MatchError(ContainSubstring("invalid nats lifecycle events endpoint")))
+			_, err = NewSink(Config{Sink: SinkNATS, Endpoint: "nats://nats", Subject: "nic events"})
+			Expect(err).To(MatchError(ContainSubstring("invalid nats subject")))
+		})
+
+		It("should default the NATS subject", func() {
+			sink, err := NewSink(Config{Sink: SinkNATS, Endpoint: "tls://nats.automation"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sink.(*natsSink).subject).To(Equal("nic-configuration.events"))
+		})
+
+		It("should reject the client certificate without its key", func() {
+			dir := GinkgoT().TempDir()
+			Expect(os.WriteFile(filepath.Join(dir, CredentialsCertKey), []byte("cert"), 0600)).To(Succeed())
+
+			_, err := NewSink(Config{Sink: SinkNATS, Endpoint: "nats://nats", CredentialsDir: dir})
+			Expect(err).To(MatchError("lifecycle events credentials have tls.crt without tls.key"))
+		})
+	})
+
+	Describe("Emitter", func() {
+		It("should build the CloudEvent of the device", func() {
+			sink := &recordingSink{}
+			emitter := NewEmitter(sink, "node-1")
+			emitter.now = func() time.Time { return time.Date(2024, 10, 14, 13, 3, 27, 0, time.FixedZone("CEST", 2*3600)) }
+
+			emitter.Emit(ctx, ApplyFinishedEvent, device, "NonVolatileConfigUpdateFailed", "failed to set parameter NUM_OF_VFS")
+
+			Eventually(sink.sent).Should(HaveLen(1))
+			events := sink.sent()
+			Expect(events[0].ID).NotTo(BeEmpty())
+			events[0].ID = ""
+			Expect(events[0]).To(Equal(Event{
+				SpecVersion:     "1.0",
+				Source:          "/nic-configuration-operator/nodes/node-1",
+				Type:            ApplyFinishedEvent,
+				Subject:         "node-1-101d-sn1",
+				Time:            time.Date(2024, 10, 14, 11, 3, 27, 0, time.UTC),
+				DataContentType: "application/json",
+				Data: EventData{
+					Node:            "node-1",
+					Device:          "node-1-101d-sn1",
+					SerialNumber:    "sn1",
+					PartNumber:      "pn1",
+					FirmwareVersion: "22.42.1000",
+					Generation:      3,
+					Reason:          "NonVolatileConfigUpdateFailed",
+					Message:         "failed to set parameter NUM_OF_VFS",
+				},
+			}))
+		})
+
+		It("should give each event a unique id", func() {
+			sink := &recordingSink{}
+			emitter := NewEmitter(sink, "node-1")
+
+			emitter.Emit(ctx, ApplyStartedEvent, device, "", "")
+			emitter.Emit(ctx, ApplyStartedEvent, device, "", "")
+			Eventually(sink.sent).Should(HaveLen(2))
+			Expect(sink.sent()[0].ID).NotTo(Equal(sink.sent()[1].ID))
+		})
+
+		Context("when the delivery fails", func() {
+			BeforeEach(func() {
+				originalInterval := sendRetryInterval
+				sendRetryInterval = 10 * time.Millisecond
+				DeferCleanup(func() { sendRetryInterval = originalInterval })
+			})
+
+			It("should retry the event and keep the order of the events", func() {
+				sink := &recordingSink{failures: 2, err: errors.New("connection refused")}
+				emitter := NewEmitter(sink, "node-1")
+
+				emitter.Emit(ctx, ApplyStartedEvent, device, "", "")
+				emitter.Emit(ctx, ApplyFinishedEvent, device, "", "")
+
+				Eventually(sink.sent).Should(HaveLen(4))
+				types := []string{}
+				for _, event := range sink.sent() {
+					types = append(types, event.Type)
+				}
+				Expect(types).To(Equal([]string{ApplyStartedEvent, ApplyStartedEvent, ApplyStartedEvent, ApplyFinishedEvent}))
+			})
+
+			It("should drop the event after the last attempt", func() {
+				sink := &recordingSink{failures: -1, err: errors.New("connection refused")}
+				NewEmitter(sink, "node-1").Emit(ctx, ApplyStartedEvent, device, "", "")
+
+				Eventually(sink.sent).Should(HaveLen(sendAttempts))
+				Consistently(sink.sent, 100*time.Millisecond).Should(HaveLen(sendAttempts))
+			})
+		})
+
+		It("should ignore the nil emitter", func() {
+			var emitter *Emitter
+			emitter.Emit(ctx, ApplyStartedEvent, device, "", "")
+		})
+	})
+
+	Describe("httpSink", func() {
+		var (
+			server      *httptest.Server
+			contentType string
+			body        []byte
+			status      int
+		)
+
+		BeforeEach(func() {
+			status = http.StatusAccepted
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Expect(r.Method).To(Equal(http.MethodPost))
+				contentType = r.Header.Get("Content-Type")
+				body, _ = io.ReadAll(r.Body)
+				w.WriteHeader(status)
+			}))
+			DeferCleanup(server.Close)
+		})
+
+		It("should post the event in the structured content mode", func() {
+			sink, err := NewHTTPSink(server.URL + "/events")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(sink.Send(ctx, event)).To(Succeed())
+			Expect(contentType).To(Equal("application/cloudevents+json"))
+
+			received := Event{}
+			Expect(json.Unmarshal(body, &received)).To(Succeed())
+			Expect(received).To(Equal(event))
+		})
+
+		It("should return error if the event is rejected", func() {
+			status = http.StatusBadRequest
+			sink, err := NewHTTPSink(server.URL)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(sink.Send(ctx, event)).To(MatchError(ContainSubstring("status 400")))
+		})
+	})
+
+	Describe("natsSink", func() {
+		connectOptions := func(server *fakeNatsServer) map[string]interface{} {
+			connect := map[string]interface{}{}
+			Expect(json.Unmarshal([]byte(<-server.connect), &connect)).To(Succeed())
+			return connect
+		}
+
+		It("should publish the event to the subject in the structured content mode", func() {
+			server := newFakeNatsServer("PONG")
+			sink, err := NewNATSSink(server.endpoint("user:secret@"), "automation.nic", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(sink.Send(ctx, event)).To(Succeed())
+
+			connect := connectOptions(server)
+			Expect(connect).To(HaveKeyWithValue("user", "user"))
+			Expect(connect).To(HaveKeyWithValue("pass", "secret"))
+			Expect(connect).To(HaveKeyWithValue("verbose", false))
+
+			subject, payload, _ := strings.Cut(<-server.messages, " ")
+			Expect(subject).To(Equal("automation.nic"))
+			received := Event{}
+			Expect(json.Unmarshal([]byte(payload), &received)).To(Succeed())
+			Expect(received).To(Equal(event))
+		})
+
+		It("should keep the connection for the next events", func() {
+			server := newFakeNatsServer("PONG")
+			sink, err := NewNATSSink(server.endpoint(""), "", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			// The fake server accepts a single connection
+			Expect(sink.Send(ctx, event)).To(Succeed())
+			Expect(sink.Send(ctx, event)).To(Succeed())
+			Expect(<-server.messages).To(HavePrefix("nic-configuration.events "))
+			Expect(<-server.messages).To(HavePrefix("nic-configuration.events "))
+		})
+
+		It("should pass the token from the credentials Secret", func() {
+			dir := GinkgoT().TempDir()
+			Expect(os.WriteFile(filepath.Join(dir, CredentialsTokenKey), []byte("s3cr3t\n"), 0600)).To(Succeed())
+			server := newFakeNatsServer("PONG")
+			sink, err := NewNATSSink(server.endpoint(""), "", dir)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(sink.Send(ctx, event)).To(Succeed())
+			Expect(connectOptions(server)).To(HaveKeyWithValue("auth_token", "s3cr3t"))
+		})
+
+		It("should pass the user and password from the credentials Secret", func() {
+			dir := GinkgoT().TempDir()
+			Expect(os.WriteFile(filepath.Join(dir, CredentialsUsernameKey), []byte("nic"), 0600)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(dir, CredentialsPasswordKey), []byte("secret"), 0600)).To(Succeed())
+			server := newFakeNatsServer("PONG")
+			sink, err := NewNATSSink(server.endpoint(""), "", dir)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(sink.Send(ctx, event)).To(Succeed())
+			connect := connectOptions(server)
+			Expect(connect).To(HaveKeyWithValue("user", "nic"))
+			Expect(connect).To(HaveKeyWithValue("pass", "secret"))
+		})
+
+		It("should return error if the server rejects the connection", func() {
+			server := newFakeNatsServer("-ERR 'Authorization Violation'")
+			sink, err := NewNATSSink(server.endpoint(""), "", "")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(sink.Send(ctx, event)).To(MatchError(ContainSubstring("Authorization Violation")))
+		})
+	})
+})
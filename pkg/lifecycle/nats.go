@@ -0,0 +1,190 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lifecycle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+)
+
+const natsDefaultSubject = "nic-configuration.events"
+
+// Keys of the lifecycle events credentials Secret mounted to Config.CredentialsDir
+const (
+	// CredentialsTokenKey is the NATS authentication token
+	CredentialsTokenKey = "token"
+	// CredentialsUsernameKey and CredentialsPasswordKey are the NATS user and password
+	CredentialsUsernameKey = "username"
+	CredentialsPasswordKey = "password"
+	// CredentialsNatsCredsKey is the NATS credentials file with the user JWT and the NKey seed
+	CredentialsNatsCredsKey = "nats.creds"
+	// CredentialsCAKey is the CA bundle verifying the certificate of the NATS server
+	CredentialsCAKey = "ca.crt"
+	// CredentialsCertKey and CredentialsKeyKey are the client certificate and key for the mutual TLS
+	CredentialsCertKey = "tls.crt"
+	CredentialsKeyKey  = "tls.key"
+)
+
+type natsSink struct {
+	endpoint string
+	subject  string
+	options  []nats.Option
+
+	lock sync.Mutex
+	conn *nats.Conn
+}
+
+// NewNATSSink creates a sink publishing each event to the NATS subject in the CloudEvents structured content mode
+// the connection is opened with the first event and kept, it reconnects to the server on its own
+// credentials and TLS files are read from credentialsDir, see the Credentials*Key constants, the nats client reads the URL's credentials too
+// tls:// endpoints and endpoints with the CA or client certificate in credentialsDir connect over TLS
+func NewNATSSink(endpoint string, subject string, credentialsDir string) (Sink, error) {
+	if endpoint == "" {
+		return nil, errors.New("endpoint is required for the nats lifecycle events sink")
+	}
+	parsed, err := url.Parse(endpoint)
+	if err != nil || (parsed.Scheme != "nats" && parsed.Scheme != "tls") || parsed.Hostname() == "" {
+		return nil, fmt.Errorf("invalid nats lifecycle events endpoint %q", endpoint)
+	}
+	if subject == "" {
+		subject = natsDefaultSubject
+	}
+	if strings.ContainsAny(subject, " \t\r\n") {
+		return nil, fmt.Errorf("invalid nats subject %q", subject)
+	}
+
+	options := []nats.Option{
+		nats.Name("nic-configuration-daemon"),
+		nats.Timeout(sinkRequestTimeout),
+		// The connection is kept for the lifetime of the daemon, events queued during an outage are retried by the Emitter
+		nats.MaxReconnects(-1),
+	}
+	if credentialsDir != "" {
+		credentialsOptions, err := natsCredentialsOptions(credentialsDir)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, credentialsOptions...)
+	}
+
+	return &natsSink{endpoint: endpoint, subject: subject, options: options}, nil
+}
+
+// natsCredentialsOptions returns the nats options for the credentials and TLS files found in the directory
+func natsCredentialsOptions(dir string) ([]nats.Option, error) {
+	found := func(key string) bool {
+		_, err := os.Stat(filepath.Join(dir, key))
+		return err == nil
+	}
+	read := func(key string) (string, error) {
+		value, err := os.ReadFile(filepath.Join(dir, key))
+		if err != nil {
+			return "", fmt.Errorf("failed to read lifecycle events credentials: %w", err)
+		}
+		return strings.TrimSpace(string(value)), nil
+	}
+
+	options := []nats.Option{}
+	switch {
+	case found(CredentialsNatsCredsKey):
+		options = append(options, nats.UserCredentials(filepath.Join(dir, CredentialsNatsCredsKey)))
+	case found(CredentialsTokenKey):
+		token, err := read(CredentialsTokenKey)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, nats.Token(token))
+	case found(CredentialsUsernameKey):
+		username, err := read(CredentialsUsernameKey)
+		if err != nil {
+			return nil, err
+		}
+		password, err := read(CredentialsPasswordKey)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, nats.UserInfo(username, password))
+	}
+
+	if found(CredentialsCAKey) {
+		options = append(options, nats.RootCAs(filepath.Join(dir, CredentialsCAKey)))
+	}
+	if found(CredentialsCertKey) {
+		if !found(CredentialsKeyKey) {
+			return nil, fmt.Errorf("lifecycle events credentials have %s without %s", CredentialsCertKey, CredentialsKeyKey)
+		}
+		options = append(options, nats.ClientCert(filepath.Join(dir, CredentialsCertKey), filepath.Join(dir, CredentialsKeyKey)))
+	}
+	return options, nil
+}
+
+// Send publishes the event and waits for the server to process it with a flush
+func (s *natsSink) Send(ctx context.Context, event Event) error {
+	cloudEvent, err := toCloudEvent(event)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(cloudEvent)
+	if err != nil {
+		return err
+	}
+
+	conn, err := s.connection()
+	if err != nil {
+		return err
+	}
+
+	err = conn.Publish(s.subject, payload)
+	if err != nil {
+		return fmt.Errorf("failed to publish the lifecycle event: %w", err)
+	}
+	if _, found := ctx.Deadline(); !found {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, sinkRequestTimeout)
+		defer cancel()
+	}
+	err = conn.FlushWithContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to publish the lifecycle event: %w", err)
+	}
+	return nil
+}
+
+// connection returns the connection to the server, connecting if there is no connection yet or it was closed
+func (s *natsSink) connection() (*nats.Conn, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.conn != nil && !s.conn.IsClosed() {
+		return s.conn, nil
+	}
+
+	conn, err := nats.Connect(s.endpoint, s.options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the nats server: %w", err)
+	}
+	s.conn = conn
+	return conn, nil
+}
@@ -0,0 +1,75 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package maintenance
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/types"
+)
+
+const activationWindowTimeFormat = "15:04"
+
+// ActivationWindowOpen checks whether the activation window is open at the given time
+// returns bool - the window is open
+// returns time.Time - start of the current window if it's open, start of the next window otherwise
+// returns error - the window spec is incorrect
+func ActivationWindowOpen(window *v1alpha1.ActivationWindowSpec, now time.Time) (bool, time.Time, error) {
+	location := time.UTC
+	if window.TimeZone != "" {
+		var err error
+		location, err = time.LoadLocation(window.TimeZone)
+		if err != nil {
+			return false, time.Time{}, types.IncorrectSpecError(fmt.Sprintf("unknown activation window time zone %s", window.TimeZone))
+		}
+	}
+
+	start, err := time.Parse(activationWindowTimeFormat, window.Start)
+	if err != nil {
+		return false, time.Time{}, types.IncorrectSpecError(fmt.Sprintf("invalid activation window start %s", window.Start))
+	}
+	end, err := time.Parse(activationWindowTimeFormat, window.End)
+	if err != nil {
+		return false, time.Time{}, types.IncorrectSpecError(fmt.Sprintf("invalid activation window end %s", window.End))
+	}
+
+	length := end.Sub(start)
+	if length <= 0 {
+		// Window ends on the next day
+		length += 24 * time.Hour
+	}
+
+	now = now.In(location)
+	// The window that started yesterday might still be open
+	for day := -1; day <= 7; day++ {
+		windowStart := time.Date(now.Year(), now.Month(), now.Day()+day, start.Hour(), start.Minute(), 0, 0, location)
+		if len(window.Days) != 0 && !slices.Contains(window.Days, v1alpha1.WeekdayEnum(windowStart.Weekday().String())) {
+			continue
+		}
+
+		if !now.Before(windowStart) && now.Before(windowStart.Add(length)) {
+			return true, windowStart, nil
+		}
+		if windowStart.After(now) {
+			return false, windowStart, nil
+		}
+	}
+
+	return false, time.Time{}, types.IncorrectSpecError("activation window never opens")
+}
@@ -0,0 +1,92 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package maintenance
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/Mellanox/nic-configuration-operator/api/v1alpha1"
+	"github.com/Mellanox/nic-configuration-operator/pkg/types"
+)
+
+var _ = Describe("ActivationWindowOpen", func() {
+	// 2024-06-05 is a Wednesday
+	at := func(value string) time.Time {
+		t, err := time.Parse(time.RFC3339, value)
+		Expect(err).NotTo(HaveOccurred())
+		return t
+	}
+
+	It("should report an open window within the same day", func() {
+		window := &v1alpha1.ActivationWindowSpec{Start: "10:00", End: "12:00"}
+
+		open, start, err := ActivationWindowOpen(window, at("2024-06-05T11:30:00Z"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(open).To(BeTrue())
+		Expect(start).To(BeTemporally("==", at("2024-06-05T10:00:00Z")))
+	})
+	It("should return the next window start if the window is closed", func() {
+		window := &v1alpha1.ActivationWindowSpec{Start: "10:00", End: "12:00"}
+
+		open, start, err := ActivationWindowOpen(window, at("2024-06-05T12:00:00Z"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(open).To(BeFalse())
+		Expect(start).To(BeTemporally("==", at("2024-06-06T10:00:00Z")))
+	})
+	It("should handle windows crossing midnight", func() {
+		window := &v1alpha1.ActivationWindowSpec{Start: "22:00", End: "06:00"}
+
+		open, start, err := ActivationWindowOpen(window, at("2024-06-05T03:00:00Z"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(open).To(BeTrue())
+		Expect(start).To(BeTemporally("==", at("2024-06-04T22:00:00Z")))
+
+		open, start, err = ActivationWindowOpen(window, at("2024-06-05T14:00:00Z"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(open).To(BeFalse())
+		Expect(start).To(BeTemporally("==", at("2024-06-05T22:00:00Z")))
+	})
+	It("should only open the window on the given days", func() {
+		window := &v1alpha1.ActivationWindowSpec{Start: "22:00", End: "06:00", Days: []v1alpha1.WeekdayEnum{"Saturday"}}
+
+		open, start, err := ActivationWindowOpen(window, at("2024-06-05T23:00:00Z"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(open).To(BeFalse())
+		Expect(start).To(BeTemporally("==", at("2024-06-08T22:00:00Z")))
+
+		// Window started on Saturday is still open on Sunday morning
+		open, _, err = ActivationWindowOpen(window, at("2024-06-09T05:00:00Z"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(open).To(BeTrue())
+	})
+	It("should use the window's time zone", func() {
+		window := &v1alpha1.ActivationWindowSpec{Start: "22:00", End: "23:00", TimeZone: "Europe/Berlin"}
+
+		// 20:30 UTC is 22:30 in Berlin in summer
+		open, _, err := ActivationWindowOpen(window, at("2024-06-05T20:30:00Z"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(open).To(BeTrue())
+	})
+	It("should return IncorrectSpec error for an unknown time zone", func() {
+		window := &v1alpha1.ActivationWindowSpec{Start: "22:00", End: "23:00", TimeZone: "Mars/Olympus_Mons"}
+
+		_, _, err := ActivationWindowOpen(window, at("2024-06-05T20:30:00Z"))
+		Expect(types.IsIncorrectSpecError(err)).To(BeTrue())
+	})
+})
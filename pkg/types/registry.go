@@ -0,0 +1,70 @@
+/*
+2024 NVIDIA CORPORATION & AFFILIATES
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// RegistryAuth contains the credentials of a container registry
+type RegistryAuth struct {
+	Username string
+	Password string
+}
+
+// RegistryCredentials contains the credentials of the container registries, keyed by the registry host, e.g. registry.example.com:5000
+type RegistryCredentials map[string]RegistryAuth
+
+// dockerConfigJSON is the format of the kubernetes.io/dockerconfigjson secrets
+type dockerConfigJSON struct {
+	Auths map[string]struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Auth     string `json:"auth"`
+	} `json:"auths"`
+}
+
+// ParseDockerConfigJSON adds the credentials of the .dockerconfigjson key of a pull secret to the registry credentials
+func (c RegistryCredentials) ParseDockerConfigJSON(data []byte) error {
+	config := dockerConfigJSON{}
+	err := json.Unmarshal(data, &config)
+	if err != nil {
+		return fmt.Errorf("invalid docker config json: %w", err)
+	}
+
+	for registry, auth := range config.Auths {
+		credentials := RegistryAuth{Username: auth.Username, Password: auth.Password}
+		if auth.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+			if err != nil {
+				return fmt.Errorf("invalid auth of registry %s: %w", registry, err)
+			}
+			credentials.Username, credentials.Password, _ = strings.Cut(string(decoded), ":")
+		}
+		c[registryHost(registry)] = credentials
+	}
+	return nil
+}
+
+// registryHost strips the scheme and the path of the registry in the docker config, e.g. https://index.docker.io/v1/
+func registryHost(registry string) string {
+	registry = strings.TrimPrefix(strings.TrimPrefix(registry, "https://"), "http://")
+	host, _, _ := strings.Cut(registry, "/")
+	return host
+}
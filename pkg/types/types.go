@@ -16,8 +16,9 @@ limitations under the License.
 package types
 
 import (
+	"errors"
 	"fmt"
-	"strings"
+	"time"
 )
 
 // NvConfigQuery contains a nv config query for a single device, values can contain both the string alias and the numeric value
@@ -35,12 +36,307 @@ func NewNvConfigQuery() NvConfigQuery {
 	}
 }
 
+// DevlinkResource contains the size of a single devlink resource of a PCI device as reported by devlink
+type DevlinkResource struct {
+	Size uint64
+	// SizeNew is the size that will take effect after the next devlink reload, nil if no change is pending
+	SizeNew         *uint64
+	SizeMin         uint64
+	SizeMax         uint64
+	SizeGranularity uint64
+	Unit            string
+}
+
+// RingSizes contains the current and the maximum ring buffer sizes of a network interface as reported by ethtool
+type RingSizes struct {
+	Rx    int
+	Tx    int
+	RxMax int
+	TxMax int
+}
+
+// EthtoolFeature contains the state of an ethtool feature of a network interface as reported by ethtool -k
+type EthtoolFeature struct {
+	Enabled bool
+	// Fixed features can't be changed, ethtool reports them with the "[fixed]" marker
+	Fixed bool
+}
+
+// Coalescing contains the interrupt coalescing settings of a network interface as reported by ethtool
+type Coalescing struct {
+	AdaptiveRx bool
+	AdaptiveTx bool
+	RxUsecs    int
+	RxFrames   int
+	TxUsecs    int
+	TxFrames   int
+}
+
+// Channels contains the current and the maximum combined channel count of a network interface as reported by ethtool
+type Channels struct {
+	Combined    int
+	CombinedMax int
+}
+
+// FirmwareSecurity contains the firmware security attributes of a device as reported by mstflint
+type FirmwareSecurity struct {
+	// Attributes of the running firmware, e.g. secure-fw, dev
+	Attributes []string
+	// SecurityVersion of the running firmware, nil if not reported
+	SecurityVersion *int
+}
+
+// FirmwareQuery contains the attributes of the firmware of a device or an image file as reported by mstflint
+type FirmwareQuery struct {
+	// Version of the firmware, e.g. 28.39.1002
+	Version string
+	// PSID the firmware is built for, lowercased, e.g. mt_0000000221
+	PSID string
+	// Security attributes and version of the firmware, nil if not reported
+	Security *FirmwareSecurity
+	// BaseGUID of the device's flash, empty if not reported, e.g. for the image files
+	BaseGUID string
+	// PartNumber of the board the firmware is built for, empty if not reported
+	PartNumber string
+}
+
+// PCILinkStatus contains the PCIe link attributes of a device as reported by sysfs
+type PCILinkStatus struct {
+	// Negotiated link speed, e.g. 16.0 GT/s PCIe
+	Speed string
+	// Negotiated link width in lanes
+	Width int
+	// Highest link speed supported by the device
+	MaxSpeed string
+	// Widest link supported by the device in lanes
+	MaxWidth int
+}
+
+// PCITopology contains the locality of a PCI device as reported by sysfs
+type PCITopology struct {
+	// NUMA node of the device, -1 if the platform doesn't report it
+	NumaNode int
+	// List of the CPUs local to the device, e.g. 0-15,32-47
+	LocalCPUs string
+	// PCIe root complex of the device, e.g. pci0000:3a
+	RootComplex string
+}
+
+// Transceiver describes the cable or optical module plugged into a port as reported by ethtool from its EEPROM
+type Transceiver struct {
+	// Form factor of the module, e.g. QSFP28
+	Type string
+	// Transmitter technology of the module, e.g. Copper cable unequalized
+	Technology string
+	Vendor     string
+	PartNumber string
+	// Length of the cable, e.g. 1m
+	Length string
+	// Ethernet speeds the module is compliant with, e.g. 100G
+	SupportedSpeeds []string
+}
+
+// LinkStatus contains the link attributes of a network interface as reported by the kernel and ethtool
+type LinkStatus struct {
+	// Operational state of the interface, e.g. up, down or lowerlayerdown
+	OperState string
+	// Negotiated speed in Mb/s, 0 if the link is down or the speed is unknown
+	Speed int
+	// AutoNegotiation is nil if ethtool doesn't report it
+	AutoNegotiation *bool
+}
+
+// RdmaPort contains the attributes of the first port of a RDMA device as reported by the kernel
+type RdmaPort struct {
+	// Link layer of the port, InfiniBand or Ethernet
+	LinkLayer string
+	// GUIDs of the node and the port, e.g. 0c42:a103:0016:054c
+	NodeGUID string
+	PortGUID string
+	// Logical and physical state of the port, e.g. ACTIVE and LinkUp
+	State         string
+	PhysicalState string
+	// Signaling rate of the link, e.g. 200 Gb/sec (4X HDR)
+	Rate string
+}
+
+// HealthReporter contains the state of a devlink health reporter of the device
+type HealthReporter struct {
+	// Name of the reporter, e.g. fw, fw_fatal or tx
+	Name string
+	// State of the reporter, healthy or error
+	State string
+	// Number of errors reported since the driver was loaded
+	ErrorCount int
+	// Number of successful recoveries since the driver was loaded
+	RecoverCount int
+}
+
+// ToolFailure describes a failed run of a host tool
+type ToolFailure struct {
+	// Command line of the tool, values of the sensitive arguments are redacted
+	Command string `json:"command"`
+	// ExitCode of the tool, -1 if the tool was killed or didn't start
+	ExitCode int `json:"exitCode"`
+	// Stderr is the tail of the tool's error output
+	Stderr string `json:"stderr,omitempty"`
+	// Time when the tool finished
+	Time time.Time `json:"time"`
+}
+
+// TypedError is an error of a kind reported in the status conditions of the devices, e.g. IncorrectSpec or DeviceToolHang
+// its message starts with the prefix of the kind, the kind is matched with errors.Is, so it's kept when the error is wrapped with %w
+type TypedError struct {
+	Prefix string
+	Msg    string
+}
+
+func (e *TypedError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Prefix, e.Msg)
+}
+
+// Is reports whether the target is a TypedError of the same kind, the message is compared too unless the target's one is empty
+func (e *TypedError) Is(target error) bool {
+	typedTarget, ok := target.(*TypedError)
+	return ok && typedTarget.Prefix == e.Prefix && (typedTarget.Msg == "" || typedTarget.Msg == e.Msg)
+}
+
+// isTypedError returns true if the error or any error it wraps is a TypedError with the prefix
+func isTypedError(err error, prefix string) bool {
+	return errors.Is(err, &TypedError{Prefix: prefix})
+}
+
 const IncorrectSpecErrorPrefix = "incorrect spec"
 
 func IncorrectSpecError(msg string) error {
-	return fmt.Errorf("%s: %s", IncorrectSpecErrorPrefix, msg)
+	return &TypedError{Prefix: IncorrectSpecErrorPrefix, Msg: msg}
 }
 
 func IsIncorrectSpecError(err error) bool {
-	return strings.HasPrefix(err.Error(), IncorrectSpecErrorPrefix)
+	return isTypedError(err, IncorrectSpecErrorPrefix)
+}
+
+const ToolHangErrorPrefix = "device tool hang"
+
+// ToolHangError is returned when a host tool didn't finish in time and was killed by the watchdog
+func ToolHangError(msg string) error {
+	return &TypedError{Prefix: ToolHangErrorPrefix, Msg: msg}
+}
+
+func IsToolHangError(err error) bool {
+	return isTypedError(err, ToolHangErrorPrefix)
+}
+
+const DelegatedToOtherHostErrorPrefix = "delegated to other host"
+
+// DelegatedToOtherHostError is returned when the nv config of a multi-host NIC is owned by another host
+func DelegatedToOtherHostError(msg string) error {
+	return &TypedError{Prefix: DelegatedToOtherHostErrorPrefix, Msg: msg}
+}
+
+func IsDelegatedToOtherHostError(err error) bool {
+	return isTypedError(err, DelegatedToOtherHostErrorPrefix)
+}
+
+const EswitchAccessDeniedErrorPrefix = "eswitch access denied"
+
+// EswitchAccessDeniedError is returned when devlink isn't permitted to query the eswitch of the PF, e.g. the PF isn't
+// the eswitch manager of a multi-host NIC or the config daemon lacks the CAP_NET_ADMIN capability
+func EswitchAccessDeniedError(msg string) error {
+	return &TypedError{Prefix: EswitchAccessDeniedErrorPrefix, Msg: msg}
+}
+
+func IsEswitchAccessDeniedError(err error) bool {
+	return isTypedError(err, EswitchAccessDeniedErrorPrefix)
+}
+
+const NonConvergingErrorPrefix = "nv config not converging"
+
+// NonConvergingError is returned when a nv config parameter was written several times but never took effect after reboot
+func NonConvergingError(msg string) error {
+	return &TypedError{Prefix: NonConvergingErrorPrefix, Msg: msg}
+}
+
+func IsNonConvergingError(err error) bool {
+	return isTypedError(err, NonConvergingErrorPrefix)
+}
+
+const FirmwareMismatchErrorPrefix = "firmware mismatch"
+
+// FirmwareMismatchError is returned when the running firmware of a device doesn't match the version pinned in its spec
+func FirmwareMismatchError(msg string) error {
+	return &TypedError{Prefix: FirmwareMismatchErrorPrefix, Msg: msg}
+}
+
+func IsFirmwareMismatchError(err error) bool {
+	return isTypedError(err, FirmwareMismatchErrorPrefix)
+}
+
+const RolledBackErrorPrefix = "nv config rolled back"
+
+// RolledBackError is returned when applying the nv config failed midway and the applied parameters were restored
+func RolledBackError(msg string) error {
+	return &TypedError{Prefix: RolledBackErrorPrefix, Msg: msg}
+}
+
+func IsRolledBackError(err error) bool {
+	return isTypedError(err, RolledBackErrorPrefix)
+}
+
+const VerificationFailedErrorPrefix = "firmware verification failed"
+
+// VerificationFailedError is returned when a firmware binary doesn't match the checksum or signature declared in its source
+func VerificationFailedError(msg string) error {
+	return &TypedError{Prefix: VerificationFailedErrorPrefix, Msg: msg}
+}
+
+func IsVerificationFailedError(err error) bool {
+	return isTypedError(err, VerificationFailedErrorPrefix)
+}
+
+const FirmwareRejectedErrorPrefix = "firmware rejected"
+
+// FirmwareRejectedError is returned when the device would reject the firmware image, e.g. an unsigned image on a device
+// enforcing signed firmware or an image with a lower security version
+func FirmwareRejectedError(msg string) error {
+	return &TypedError{Prefix: FirmwareRejectedErrorPrefix, Msg: msg}
+}
+
+func IsFirmwareRejectedError(err error) bool {
+	return isTypedError(err, FirmwareRejectedErrorPrefix)
+}
+
+const ConfigOwnershipDeniedErrorPrefix = "nv config ownership denied"
+
+// ConfigOwnershipDeniedError is returned when the nv config of the device is write-protected by the BMC or DPU
+func ConfigOwnershipDeniedError(msg string) error {
+	return &TypedError{Prefix: ConfigOwnershipDeniedErrorPrefix, Msg: msg}
+}
+
+func IsConfigOwnershipDeniedError(err error) bool {
+	return isTypedError(err, ConfigOwnershipDeniedErrorPrefix)
+}
+
+const RuntimeConfigPendingErrorPrefix = "runtime config pending"
+
+// RuntimeConfigPendingError is returned when the runtime settings wait for the host to catch up, e.g. for the driver
+// to create the representors of the VFs after the PF is moved to the switchdev mode, the apply is retried later
+func RuntimeConfigPendingError(msg string) error {
+	return &TypedError{Prefix: RuntimeConfigPendingErrorPrefix, Msg: msg}
+}
+
+func IsRuntimeConfigPendingError(err error) bool {
+	return isTypedError(err, RuntimeConfigPendingErrorPrefix)
+}
+
+const FabricFeatureNotSupportedErrorPrefix = "fabric feature not supported"
+
+// FabricFeatureNotSupportedError is returned when the template requests a fabric feature set, e.g. Spectrum-X,
+// whose nv config parameters are not available on the device or its firmware
+func FabricFeatureNotSupportedError(msg string) error {
+	return &TypedError{Prefix: FabricFeatureNotSupportedErrorPrefix, Msg: msg}
+}
+
+func IsFabricFeatureNotSupportedError(err error) bool {
+	return isTypedError(err, FabricFeatureNotSupportedErrorPrefix)
 }